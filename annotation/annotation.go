@@ -0,0 +1,247 @@
+// Package annotation holds the tags and short notes that users attach to entity IDs, e.g. to mark
+// an entity as "cleared" or "priority". The annotations are persisted in a small Pebble store, so
+// they survive a restart of the web-app and are shared by all of the graphs.
+package annotation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cockroachdb/pebble"
+)
+
+const componentName = "annotation"
+
+// Limits of an annotation
+const (
+	MaxTags       = 10  // Maximum number of tags of an entity
+	MaxTagLength  = 30  // Maximum number of characters of a tag
+	MaxNoteLength = 500 // Maximum number of characters of a note
+)
+
+// Keywords of the annotations of an entity that can be used in the i2 chart config
+const (
+	TagsKeyword = "ANNOTATION-TAGS" // Tags of the entity separated by commas
+	NoteKeyword = "ANNOTATION-NOTE" // Note of the entity
+)
+
+var (
+	ErrEntityIdEmpty = errors.New("entity ID is empty")
+	ErrTooManyTags   = errors.New("too many tags")
+	ErrTagTooLong    = errors.New("tag is too long")
+	ErrInvalidTag    = errors.New("tag contains a comma")
+	ErrNoteTooLong   = errors.New("note is too long")
+	ErrStoreNotOpen  = errors.New("annotation store isn't open")
+)
+
+// An Annotation of an entity.
+type Annotation struct {
+	EntityId string    `json:"entityId"` // ID of the entity
+	Tags     []string  `json:"tags"`     // Tags in alphabetical order (without duplicates)
+	Note     string    `json:"note"`     // Short free-text note
+	Updated  time.Time `json:"updated"`  // When the annotation was last changed
+}
+
+// ParseTags from text of tags separated by commas. The tags are trimmed, in lower case, without
+// duplicates and in alphabetical order.
+func ParseTags(text string) []string {
+
+	unique := map[string]struct{}{}
+	for _, tag := range strings.Split(text, ",") {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if len(tag) > 0 {
+			unique[tag] = struct{}{}
+		}
+	}
+
+	tags := []string{}
+	for tag := range unique {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+
+	return tags
+}
+
+// validate the tags and note of an annotation.
+func validate(tags []string, note string) error {
+
+	if len(tags) > MaxTags {
+		return fmt.Errorf("%w: %v tags (maximum %v)", ErrTooManyTags, len(tags), MaxTags)
+	}
+
+	for _, tag := range tags {
+		if len([]rune(tag)) > MaxTagLength {
+			return fmt.Errorf("%w: %v (maximum %v characters)", ErrTagTooLong, tag, MaxTagLength)
+		}
+
+		if strings.Contains(tag, ",") {
+			return fmt.Errorf("%w: %v", ErrInvalidTag, tag)
+		}
+	}
+
+	if len([]rune(note)) > MaxNoteLength {
+		return fmt.Errorf("%w: maximum %v characters", ErrNoteTooLong, MaxNoteLength)
+	}
+
+	return nil
+}
+
+// An AnnotationStore holds the annotations of the entities in a Pebble store, keyed by entity ID.
+type AnnotationStore struct {
+	folder string     // Location of the Pebble files
+	db     *pebble.DB // Pebble store (nil once closed)
+	lock   sync.Mutex // Mutex for the changes of the annotations
+}
+
+// NewAnnotationStore in the folder, which is created if it doesn't exist.
+func NewAnnotationStore(folder string) (*AnnotationStore, error) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("folder", folder).
+		Msg("Opening annotation Pebble store")
+
+	db, err := pebble.Open(folder, &pebble.Options{})
+	if err != nil {
+		return nil, err
+	}
+
+	return &AnnotationStore{
+		folder: folder,
+		db:     db,
+		lock:   sync.Mutex{},
+	}, nil
+}
+
+// Close the store.
+func (s *AnnotationStore) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.db == nil {
+		return nil
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("folder", s.folder).
+		Msg("Closing annotation Pebble store")
+
+	err := s.db.Close()
+	s.db = nil
+	return err
+}
+
+// Get the annotation of the entity, which is nil if the entity doesn't have an annotation. A nil
+// store doesn't have any annotations.
+func (s *AnnotationStore) Get(entityId string) (*Annotation, error) {
+
+	if s == nil {
+		return nil, nil
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.db == nil {
+		return nil, ErrStoreNotOpen
+	}
+
+	value, closer, err := s.db.Get([]byte(entityId))
+	if err == pebble.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+
+	var annotation Annotation
+	if err := json.Unmarshal(value, &annotation); err != nil {
+		return nil, err
+	}
+
+	return &annotation, nil
+}
+
+// Set the tags and note of the entity, replacing its annotation. The annotation is deleted if there
+// aren't any tags and the note is blank. Returns the annotation (nil if it was deleted).
+func (s *AnnotationStore) Set(entityId string, tags []string, note string) (*Annotation, error) {
+
+	entityId = strings.TrimSpace(entityId)
+	if len(entityId) == 0 {
+		return nil, ErrEntityIdEmpty
+	}
+
+	note = strings.TrimSpace(note)
+	if err := validate(tags, note); err != nil {
+		return nil, err
+	}
+
+	if s == nil {
+		return nil, ErrStoreNotOpen
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.db == nil {
+		return nil, ErrStoreNotOpen
+	}
+
+	if len(tags) == 0 && len(note) == 0 {
+		return nil, s.db.Delete([]byte(entityId), pebble.Sync)
+	}
+
+	annotation := Annotation{
+		EntityId: entityId,
+		Tags:     tags,
+		Note:     note,
+		Updated:  time.Now().UTC(),
+	}
+
+	value, err := json.Marshal(annotation)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Set([]byte(entityId), value, pebble.Sync); err != nil {
+		return nil, err
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("entityId", entityId).
+		Strs("tags", tags).
+		Msg("Annotated entity")
+
+	return &annotation, nil
+}
+
+// Keywords of the annotation of the entity for the i2 chart, which are blank if the entity doesn't
+// have an annotation.
+func (s *AnnotationStore) Keywords(entityId string) (map[string]string, error) {
+
+	annotation, err := s.Get(entityId)
+	if err != nil {
+		return nil, err
+	}
+
+	if annotation == nil {
+		return map[string]string{
+			TagsKeyword: "",
+			NoteKeyword: "",
+		}, nil
+	}
+
+	return map[string]string{
+		TagsKeyword: strings.Join(annotation.Tags, ", "),
+		NoteKeyword: annotation.Note,
+	}, nil
+}
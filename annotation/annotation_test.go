@@ -0,0 +1,103 @@
+package annotation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTags(t *testing.T) {
+	testCases := []struct {
+		text     string
+		expected []string
+	}{
+		{text: "", expected: []string{}},
+		{text: " , ,", expected: []string{}},
+		{text: "priority", expected: []string{"priority"}},
+		{text: " Priority, cleared,priority ", expected: []string{"cleared", "priority"}},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expected, ParseTags(testCase.text))
+	}
+}
+
+func TestAnnotationStore(t *testing.T) {
+
+	folder := t.TempDir()
+	store, err := NewAnnotationStore(folder)
+	assert.NoError(t, err)
+
+	// An entity without an annotation
+	annotation, err := store.Get("e-1")
+	assert.NoError(t, err)
+	assert.Nil(t, annotation)
+
+	keywords, err := store.Keywords("e-1")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{TagsKeyword: "", NoteKeyword: ""}, keywords)
+
+	// Annotate the entity
+	annotation, err = store.Set(" e-1 ", []string{"cleared", "priority"}, " Seen before ")
+	assert.NoError(t, err)
+	assert.Equal(t, "e-1", annotation.EntityId)
+	assert.Equal(t, "Seen before", annotation.Note)
+
+	keywords, err = store.Keywords("e-1")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{TagsKeyword: "cleared, priority", NoteKeyword: "Seen before"},
+		keywords)
+
+	// The annotation is persisted
+	assert.NoError(t, store.Close())
+	store, err = NewAnnotationStore(folder)
+	assert.NoError(t, err)
+	defer store.Close()
+
+	annotation, err = store.Get("e-1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"cleared", "priority"}, annotation.Tags)
+	assert.Equal(t, "Seen before", annotation.Note)
+	assert.False(t, annotation.Updated.IsZero())
+
+	// Clearing the tags and note deletes the annotation
+	annotation, err = store.Set("e-1", []string{}, "")
+	assert.NoError(t, err)
+	assert.Nil(t, annotation)
+
+	annotation, err = store.Get("e-1")
+	assert.NoError(t, err)
+	assert.Nil(t, annotation)
+}
+
+func TestAnnotationStoreInvalid(t *testing.T) {
+
+	store, err := NewAnnotationStore(t.TempDir())
+	assert.NoError(t, err)
+	defer store.Close()
+
+	_, err = store.Set(" ", []string{"priority"}, "")
+	assert.ErrorIs(t, err, ErrEntityIdEmpty)
+
+	_, err = store.Set("e-1", make([]string, MaxTags+1), "")
+	assert.ErrorIs(t, err, ErrTooManyTags)
+
+	_, err = store.Set("e-1", []string{strings.Repeat("a", MaxTagLength+1)}, "")
+	assert.ErrorIs(t, err, ErrTagTooLong)
+
+	_, err = store.Set("e-1", []string{"a,b"}, "")
+	assert.ErrorIs(t, err, ErrInvalidTag)
+
+	_, err = store.Set("e-1", nil, strings.Repeat("a", MaxNoteLength+1))
+	assert.ErrorIs(t, err, ErrNoteTooLong)
+
+	// A nil store doesn't have any annotations
+	var nilStore *AnnotationStore
+	annotation, err := nilStore.Get("e-1")
+	assert.NoError(t, err)
+	assert.Nil(t, annotation)
+
+	_, err = nilStore.Set("e-1", []string{"priority"}, "")
+	assert.ErrorIs(t, err, ErrStoreNotOpen)
+}
@@ -0,0 +1,141 @@
+package bench
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/bfs"
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/set"
+	"github.com/stretchr/testify/assert"
+)
+
+// Benchmarks of path finding, the bipartite to unipartite conversion and the store operations on
+// synthetic graphs. Run with:
+//
+//	go test ./bench -run xxx -bench . -benchmem
+
+// benchmarkSizes of the synthetic graphs.
+var benchmarkSizes = []int{1000, 10000}
+
+// benchmarkDistributions of the synthetic graphs.
+var benchmarkDistributions = []string{DistributionUniform, DistributionPowerLaw}
+
+// newPebbleUnipartite store in a temporary folder that is removed at the end of the benchmark.
+func newPebbleUnipartite(b *testing.B) graphstore.UnipartiteGraphStore {
+	folder, err := os.MkdirTemp("", "bench-pebble")
+	assert.NoError(b, err)
+
+	store, err := graphstore.NewPebbleUnipartiteGraphStore(folder)
+	assert.NoError(b, err)
+	b.Cleanup(func() { store.Destroy() })
+
+	return store
+}
+
+// unipartiteStores to benchmark, keyed by name.
+func unipartiteStores(b *testing.B) map[string]func() graphstore.UnipartiteGraphStore {
+	return map[string]func() graphstore.UnipartiteGraphStore{
+		"memory": func() graphstore.UnipartiteGraphStore {
+			return graphstore.NewInMemoryUnipartiteGraphStore()
+		},
+		"pebble": func() graphstore.UnipartiteGraphStore {
+			return newPebbleUnipartite(b)
+		},
+	}
+}
+
+func BenchmarkAllPaths(b *testing.B) {
+
+	for storeName, newStore := range unipartiteStores(b) {
+		for _, size := range benchmarkSizes {
+			for _, distribution := range benchmarkDistributions {
+
+				spec := GraphSpec{
+					NumberOfEntities: size,
+					AverageDegree:    4,
+					Distribution:     distribution,
+					Seed:             1,
+				}
+
+				name := fmt.Sprintf("%v/%v/%v", storeName, size, distribution)
+				b.Run(name, func(b *testing.B) {
+					store := newStore()
+					assert.NoError(b, GenerateUnipartite(store, spec))
+					b.ResetTimer()
+
+					for i := 0; i < b.N; i++ {
+						root := EntityId(i % size)
+						goal := EntityId((i*7919 + 1) % size)
+						_, err := bfs.AllPaths(store, root, goal, 3)
+						assert.NoError(b, err)
+					}
+				})
+			}
+		}
+	}
+}
+
+func BenchmarkBipartiteToUnipartite(b *testing.B) {
+
+	for _, distribution := range benchmarkDistributions {
+
+		spec := GraphSpec{
+			NumberOfEntities: 1000,
+			AverageDegree:    3,
+			Distribution:     distribution,
+			Seed:             1,
+		}
+
+		b.Run(distribution, func(b *testing.B) {
+			bipartite := graphstore.NewInMemoryBipartiteGraphStore()
+			assert.NoError(b, GenerateBipartite(bipartite, spec, 2000))
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				unipartite := graphstore.NewInMemoryUnipartiteGraphStore()
+				assert.NoError(b, graphstore.BipartiteToUnipartite(bipartite, unipartite,
+					set.NewSet[string](), 4, 100))
+			}
+		})
+	}
+}
+
+func BenchmarkStoreOperations(b *testing.B) {
+
+	for storeName, newStore := range unipartiteStores(b) {
+
+		spec := GraphSpec{
+			NumberOfEntities: 10000,
+			AverageDegree:    4,
+			Distribution:     DistributionPowerLaw,
+			Seed:             1,
+		}
+
+		store := newStore()
+		assert.NoError(b, GenerateUnipartite(store, spec))
+
+		b.Run(storeName+"/AddUndirected", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				assert.NoError(b, store.AddUndirected(EntityId(i%spec.NumberOfEntities),
+					EntityId((i+1)%spec.NumberOfEntities)))
+			}
+		})
+
+		b.Run(storeName+"/EdgeExists", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, err := store.EdgeExists(EntityId(i%spec.NumberOfEntities),
+					EntityId((i+1)%spec.NumberOfEntities))
+				assert.NoError(b, err)
+			}
+		})
+
+		b.Run(storeName+"/EntityIdsAdjacentTo", func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				_, err := store.EntityIdsAdjacentTo(EntityId(i % spec.NumberOfEntities))
+				assert.NoError(b, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package bench
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+)
+
+const componentName = "bench"
+
+// Degree distributions of the synthetic graphs
+const (
+	DistributionUniform  = "uniform"  // Every entity is equally likely to be linked
+	DistributionPowerLaw = "powerlaw" // A few hub entities have most of the links
+)
+
+// powerLawExponent of the Zipf distribution used to pick entities for a power-law graph.
+const powerLawExponent = 1.5
+
+var (
+	ErrInvalidGraphSpec = errors.New("invalid synthetic graph specification")
+	ErrStoreIsNil       = errors.New("graph store is nil")
+)
+
+// A GraphSpec describes a synthetic graph. The same specification (including the seed) always
+// generates the same graph.
+type GraphSpec struct {
+	NumberOfEntities int    // Number of entities in the graph
+	AverageDegree    int    // Approximate average number of connections per entity (unipartite)
+	Distribution     string // Degree distribution (DistributionUniform or DistributionPowerLaw)
+	Seed             int64  // Seed for the random number generator
+}
+
+// validate the graph specification.
+func (g GraphSpec) validate() error {
+	if g.NumberOfEntities < 2 || g.AverageDegree < 1 {
+		return ErrInvalidGraphSpec
+	}
+
+	if g.Distribution != DistributionUniform && g.Distribution != DistributionPowerLaw {
+		return ErrInvalidGraphSpec
+	}
+
+	return nil
+}
+
+// EntityId of the ith entity in a synthetic graph.
+func EntityId(i int) string {
+	return fmt.Sprintf("e-%d", i)
+}
+
+// DocumentId of the ith document in a synthetic graph.
+func DocumentId(i int) string {
+	return fmt.Sprintf("d-%d", i)
+}
+
+// entityPicker returns a function that picks the index of an entity using the distribution. For a
+// power-law distribution, the entities with the lowest indices are the hubs.
+func entityPicker(spec GraphSpec, rng *rand.Rand) func() int {
+
+	if spec.Distribution == DistributionPowerLaw {
+		zipf := rand.NewZipf(rng, powerLawExponent, 1, uint64(spec.NumberOfEntities-1))
+		return func() int {
+			return int(zipf.Uint64())
+		}
+	}
+
+	return func() int {
+		return rng.Intn(spec.NumberOfEntities)
+	}
+}
+
+// GenerateUnipartite adds a synthetic graph to the unipartite store. Every entity is added and
+// approximately NumberOfEntities * AverageDegree / 2 undirected edges are added between them.
+func GenerateUnipartite(store graphstore.UnipartiteGraphStore, spec GraphSpec) error {
+
+	// Preconditions
+	if store == nil {
+		return ErrStoreIsNil
+	}
+
+	if err := spec.validate(); err != nil {
+		return err
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Int("numberOfEntities", spec.NumberOfEntities).
+		Int("averageDegree", spec.AverageDegree).
+		Str("distribution", spec.Distribution).
+		Msg("Generating a synthetic unipartite graph")
+
+	for i := 0; i < spec.NumberOfEntities; i++ {
+		if err := store.AddEntity(EntityId(i)); err != nil {
+			return err
+		}
+	}
+
+	rng := rand.New(rand.NewSource(spec.Seed))
+	pick := entityPicker(spec, rng)
+
+	numberOfEdges := spec.NumberOfEntities * spec.AverageDegree / 2
+	for i := 0; i < numberOfEdges; i++ {
+
+		// Self-connections aren't allowed, so the uniformly chosen entity is at the other end
+		src := pick()
+		dst := rng.Intn(spec.NumberOfEntities)
+		if src == dst {
+			continue
+		}
+
+		if err := store.AddUndirected(EntityId(src), EntityId(dst)); err != nil {
+			return err
+		}
+	}
+
+	return store.Finalise()
+}
+
+// GenerateBipartite adds a synthetic graph to the bipartite store. Each document links
+// AverageDegree entities, which are picked using the distribution.
+func GenerateBipartite(store graphstore.BipartiteGraphStore, spec GraphSpec,
+	numberOfDocuments int) error {
+
+	// Preconditions
+	if store == nil {
+		return ErrStoreIsNil
+	}
+
+	if err := spec.validate(); err != nil {
+		return err
+	}
+
+	if numberOfDocuments < 1 {
+		return ErrInvalidGraphSpec
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Int("numberOfEntities", spec.NumberOfEntities).
+		Int("numberOfDocuments", numberOfDocuments).
+		Int("entitiesPerDocument", spec.AverageDegree).
+		Str("distribution", spec.Distribution).
+		Msg("Generating a synthetic bipartite graph")
+
+	entities := make([]graphstore.Entity, 0, spec.NumberOfEntities)
+	for i := 0; i < spec.NumberOfEntities; i++ {
+		entity, err := graphstore.NewEntity(EntityId(i), "Person", map[string]string{})
+		if err != nil {
+			return err
+		}
+		entities = append(entities, entity)
+	}
+
+	rng := rand.New(rand.NewSource(spec.Seed))
+	pick := entityPicker(spec, rng)
+
+	documents := make([]graphstore.Document, 0, numberOfDocuments)
+	links := []graphstore.Link{}
+
+	for i := 0; i < numberOfDocuments; i++ {
+		document, err := graphstore.NewDocument(DocumentId(i), "Report", map[string]string{})
+		if err != nil {
+			return err
+		}
+		documents = append(documents, document)
+
+		for j := 0; j < spec.AverageDegree; j++ {
+			links = append(links, graphstore.NewLink(EntityId(pick()), DocumentId(i)))
+		}
+	}
+
+	if err := graphstore.BulkLoadBipartiteGraphStore(store, entities, documents, links); err != nil {
+		return err
+	}
+
+	return store.Finalise()
+}
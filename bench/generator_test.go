@@ -0,0 +1,95 @@
+package bench
+
+import (
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraphSpecValidate(t *testing.T) {
+
+	assert.NoError(t, GraphSpec{NumberOfEntities: 10, AverageDegree: 2, Distribution: DistributionUniform}.validate())
+	assert.NoError(t, GraphSpec{NumberOfEntities: 10, AverageDegree: 2, Distribution: DistributionPowerLaw}.validate())
+
+	assert.ErrorIs(t, GraphSpec{NumberOfEntities: 1, AverageDegree: 2, Distribution: DistributionUniform}.validate(), ErrInvalidGraphSpec)
+	assert.ErrorIs(t, GraphSpec{NumberOfEntities: 10, AverageDegree: 0, Distribution: DistributionUniform}.validate(), ErrInvalidGraphSpec)
+	assert.ErrorIs(t, GraphSpec{NumberOfEntities: 10, AverageDegree: 2, Distribution: "normal"}.validate(), ErrInvalidGraphSpec)
+}
+
+func TestGenerateUnipartite(t *testing.T) {
+
+	assert.ErrorIs(t, GenerateUnipartite(nil, GraphSpec{}), ErrStoreIsNil)
+
+	for _, distribution := range []string{DistributionUniform, DistributionPowerLaw} {
+
+		spec := GraphSpec{
+			NumberOfEntities: 100,
+			AverageDegree:    4,
+			Distribution:     distribution,
+			Seed:             1,
+		}
+
+		g1 := graphstore.NewInMemoryUnipartiteGraphStore()
+		assert.NoError(t, GenerateUnipartite(g1, spec))
+
+		numberEntities, err := g1.NumberEntities()
+		assert.NoError(t, err)
+		assert.Equal(t, 100, numberEntities)
+
+		// The same specification generates the same graph
+		g2 := graphstore.NewInMemoryUnipartiteGraphStore()
+		assert.NoError(t, GenerateUnipartite(g2, spec))
+
+		equal, reason, err := graphstore.UnipartiteGraphStoresEqual(g1, g2)
+		assert.NoError(t, err)
+		assert.True(t, equal, reason)
+	}
+}
+
+func TestGenerateUnipartitePowerLawHasHubs(t *testing.T) {
+
+	spec := GraphSpec{
+		NumberOfEntities: 1000,
+		AverageDegree:    4,
+		Distribution:     DistributionPowerLaw,
+		Seed:             1,
+	}
+
+	g := graphstore.NewInMemoryUnipartiteGraphStore()
+	assert.NoError(t, GenerateUnipartite(g, spec))
+
+	hub, err := g.EntityIdsAdjacentTo(EntityId(1))
+	assert.NoError(t, err)
+	assert.Greater(t, hub.Len(), 10*spec.AverageDegree)
+}
+
+func TestGenerateBipartite(t *testing.T) {
+
+	spec := GraphSpec{
+		NumberOfEntities: 50,
+		AverageDegree:    3,
+		Distribution:     DistributionUniform,
+		Seed:             1,
+	}
+
+	assert.ErrorIs(t, GenerateBipartite(nil, spec, 10), ErrStoreIsNil)
+	assert.ErrorIs(t, GenerateBipartite(graphstore.NewInMemoryBipartiteGraphStore(), spec, 0),
+		ErrInvalidGraphSpec)
+
+	g := graphstore.NewInMemoryBipartiteGraphStore()
+	assert.NoError(t, GenerateBipartite(g, spec, 20))
+
+	numberEntities, err := g.NumberOfEntities()
+	assert.NoError(t, err)
+	assert.Equal(t, 50, numberEntities)
+
+	numberDocuments, err := g.NumberOfDocuments()
+	assert.NoError(t, err)
+	assert.Equal(t, 20, numberDocuments)
+
+	document, err := g.GetDocument(DocumentId(0))
+	assert.NoError(t, err)
+	assert.True(t, document.LinkedEntityIds.Len() >= 1)
+	assert.True(t, document.LinkedEntityIds.Len() <= 3)
+}
@@ -0,0 +1,82 @@
+// A budget limits the number of vertices expanded by a query, so that a pathological query (e.g.
+// between entities connected to hubs with many hops) can't consume the CPU of a shared deployment
+// for hours. The budget is enforced as the search expands each vertex. When it is exhausted, the
+// search stops and the paths found between the pairs of entities searched so far are kept.
+
+package bfs
+
+import (
+	"errors"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/set"
+)
+
+var (
+	ErrBudgetExhausted      = errors.New("budget of vertex expansions exhausted")
+	ErrInvalidMaxExpansions = errors.New("invalid maximum number of vertex expansions")
+)
+
+// expansionBudget of a query, i.e. the maximum number of vertex expansions and the number of
+// expansions used so far. A nil budget is unlimited.
+type expansionBudget struct {
+	maxExpansions int
+	used          int
+}
+
+// newExpansionBudget given the maximum number of vertex expansions, where zero means there isn't a
+// limit.
+func newExpansionBudget(maxExpansions int) *expansionBudget {
+	if maxExpansions == 0 {
+		return nil
+	}
+
+	return &expansionBudget{
+		maxExpansions: maxExpansions,
+	}
+}
+
+// spend one vertex expansion of the budget, returning ErrBudgetExhausted if there aren't any left.
+func (b *expansionBudget) spend() error {
+	if b.used >= b.maxExpansions {
+		return ErrBudgetExhausted
+	}
+
+	b.used++
+	return nil
+}
+
+// graph that spends the budget each time a vertex is expanded. The graph itself is returned if the
+// budget is nil.
+func (b *expansionBudget) graph(graph graphstore.UnipartiteGraphStore) graphstore.UnipartiteGraphStore {
+	if b == nil {
+		return graph
+	}
+
+	return &budgetedGraph{
+		UnipartiteGraphStore: graph,
+		budget:               b,
+	}
+}
+
+// budgetedGraph is a unipartite graph that spends the budget on each vertex expansion.
+type budgetedGraph struct {
+	graphstore.UnipartiteGraphStore
+	budget *expansionBudget
+}
+
+// EntityIdsAdjacentTo the entity, spending an expansion of the budget.
+func (g *budgetedGraph) EntityIdsAdjacentTo(entityId string) (*set.Set[string], error) {
+	if err := g.budget.spend(); err != nil {
+		return nil, err
+	}
+	return g.UnipartiteGraphStore.EntityIdsAdjacentTo(entityId)
+}
+
+// EntityIdsConnectedTo the entity, spending an expansion of the budget.
+func (g *budgetedGraph) EntityIdsConnectedTo(entityId string) (*set.Set[string], error) {
+	if err := g.budget.spend(); err != nil {
+		return nil, err
+	}
+	return g.UnipartiteGraphStore.EntityIdsConnectedTo(entityId)
+}
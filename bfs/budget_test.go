@@ -0,0 +1,94 @@
+package bfs
+
+import (
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpansionBudget(t *testing.T) {
+
+	// No limit
+	assert.Nil(t, newExpansionBudget(0))
+
+	graph := graphstore.NewInMemoryUnipartiteGraphStore()
+	assert.NoError(t, graphstore.BuildFromEdgeList(graph, []graphstore.Edge{
+		{V1: "a", V2: "b"},
+	}))
+
+	var unlimited *expansionBudget
+	assert.Equal(t, graph, unlimited.graph(graph))
+
+	// The budget is spent by each expansion, whether or not the edges are directed
+	budget := newExpansionBudget(2)
+	budgeted := budget.graph(graph)
+
+	_, err := budgeted.EntityIdsConnectedTo("a")
+	assert.NoError(t, err)
+
+	_, err = budgeted.EntityIdsAdjacentTo("b")
+	assert.NoError(t, err)
+
+	_, err = budgeted.EntityIdsConnectedTo("a")
+	assert.ErrorIs(t, err, ErrBudgetExhausted)
+
+	// Other reads don't spend the budget
+	found, err := budgeted.HasEntity("a")
+	assert.NoError(t, err)
+	assert.True(t, found)
+}
+
+func TestFindPathsWithBudget(t *testing.T) {
+
+	// Chain of entities:
+	//
+	//   a -- b -- c -- d -- e
+	graph := graphstore.NewInMemoryUnipartiteGraphStore()
+	assert.NoError(t, graphstore.BuildFromEdgeList(graph, []graphstore.Edge{
+		{V1: "a", V2: "b"},
+		{V1: "b", V2: "c"},
+		{V1: "c", V2: "d"},
+		{V1: "d", V2: "e"},
+	}))
+
+	pathFinder, err := NewPathFinder(graph)
+	assert.NoError(t, err)
+	assert.ErrorIs(t, pathFinder.SetMaxExpansions(-1), ErrInvalidMaxExpansions)
+
+	entitySets := []job.EntitySet{
+		{Name: "Set-1", EntityIds: []string{"a"}},
+		{Name: "Set-2", EntityIds: []string{"b", "e"}},
+	}
+
+	// Without a budget, all of the paths are found
+	conns, err := pathFinder.FindPaths(entitySets, 4)
+	assert.NoError(t, err)
+	assert.False(t, conns.BudgetExhausted)
+	assert.Equal(t, 2, conns.NumberOfPaths())
+
+	// A generous budget doesn't change the paths
+	assert.NoError(t, pathFinder.SetMaxExpansions(100))
+	assert.Equal(t, 100, pathFinder.MaxExpansions())
+
+	conns, err = pathFinder.FindPaths(entitySets, 4)
+	assert.NoError(t, err)
+	assert.False(t, conns.BudgetExhausted)
+	assert.Equal(t, 2, conns.NumberOfPaths())
+
+	// The budget is exhausted by the search for the longer path, but the path found before is kept
+	assert.NoError(t, pathFinder.SetMaxExpansions(3))
+
+	conns, err = pathFinder.FindPaths(entitySets, 4)
+	assert.NoError(t, err)
+	assert.True(t, conns.BudgetExhausted)
+
+	paths, err := conns.Paths("a", "b")
+	assert.NoError(t, err)
+	assert.True(t, PathsEqual([]Path{NewPath("a", "b")}, paths))
+
+	paths, err = conns.Paths("a", "e")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(paths))
+}
@@ -0,0 +1,124 @@
+// A unipartite graph can be pruned by collapsing long chains of entities into single edges (see
+// graphstore.PruneUnipartite) and served with its chains expanded (see
+// graphstore.ExpandedUnipartiteGraphStore). The path finder searches the pruned store instead,
+// stepping over a collapsed chain in one go. A step over a chain counts its hops in the original
+// graph against the maximum number of hops, and the entities inside the chain are put back into
+// the route of a path, so the paths found are those of the original graph.
+
+package bfs
+
+import (
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/set"
+)
+
+// A chainedGraph is a unipartite graph with its collapsed chains expanded, which gives access to
+// the pruned store and the chains.
+type chainedGraph interface {
+	graphstore.UnipartiteGraphStore
+	Pruned() graphstore.UnipartiteGraphStore
+	Chains() *graphstore.ChainIndex
+	ChainThrough(entityId string) (graphstore.Chain, int, bool)
+}
+
+// A step from a vertex to another vertex, passing through the entities inside a collapsed chain
+// if the step is over a chain.
+type step struct {
+	via []string // Entities passed through in order (empty for a single hop)
+	to  string   // Vertex reached
+}
+
+// hops of the step in the original graph.
+func (s step) hops() int {
+	return len(s.via) + 1
+}
+
+// route of the step from the vertex.
+func (s step) route(from string) []string {
+	route := append([]string{from}, s.via...)
+	return append(route, s.to)
+}
+
+// singleSteps returns the function that gives the steps from a vertex to each adjacent vertex.
+func singleSteps(adjacent func(string) (*set.Set[string], error)) func(string) ([]step, error) {
+
+	return func(entityId string) ([]step, error) {
+		entityIds, err := adjacent(entityId)
+		if err != nil {
+			return nil, err
+		}
+
+		steps := []step{}
+		for _, adjEntityId := range entityIds.ToSlice() {
+			steps = append(steps, step{to: adjEntityId})
+		}
+
+		return steps, nil
+	}
+}
+
+// chainSteps returns the function that gives the steps from a vertex of a chained graph, where
+// adjacent gives the vertices adjacent to a vertex of the pruned store. A collapsed edge is a step
+// over its chain. The steps from an entity inside a chain run to the ends of the chain.
+func chainSteps(graph chainedGraph, adjacent func(string) (*set.Set[string], error)) func(string) ([]step, error) {
+
+	chains := graph.Chains()
+
+	return func(entityId string) ([]step, error) {
+
+		if chain, index, found := graph.ChainThrough(entityId); found {
+			towardsFrom := []string{}
+			for idx := index - 1; idx >= 0; idx-- {
+				towardsFrom = append(towardsFrom, chain.Via[idx])
+			}
+
+			return []step{
+				{via: towardsFrom, to: chain.From},
+				{via: append([]string{}, chain.Via[index+1:]...), to: chain.To},
+			}, nil
+		}
+
+		entityIds, err := adjacent(entityId)
+		if err != nil {
+			return nil, err
+		}
+
+		steps := []step{}
+		for _, adjEntityId := range entityIds.ToSlice() {
+			if chain, found := chains.Get(entityId, adjEntityId); found {
+				steps = append(steps, step{via: chain.Via, to: adjEntityId})
+			} else {
+				steps = append(steps, step{to: adjEntityId})
+			}
+		}
+
+		return steps, nil
+	}
+}
+
+// prunedSearch is a chained graph whose pruned store is wrapped, e.g. to spend a budget on each
+// vertex expansion of a search.
+type prunedSearch struct {
+	chainedGraph
+	pruned graphstore.UnipartiteGraphStore
+}
+
+// Pruned store (wrapped) of the graph.
+func (p *prunedSearch) Pruned() graphstore.UnipartiteGraphStore {
+	return p.pruned
+}
+
+// wrapSearched wraps the graph whose edges a search follows, i.e. the pruned store of a chained
+// graph or otherwise the graph itself.
+func wrapSearched(graph graphstore.UnipartiteGraphStore,
+	wrap func(graphstore.UnipartiteGraphStore) graphstore.UnipartiteGraphStore) graphstore.UnipartiteGraphStore {
+
+	if chained, ok := graph.(chainedGraph); ok {
+		return &prunedSearch{
+			chainedGraph: chained,
+			pruned:       wrap(chained.Pruned()),
+		}
+	}
+
+	return wrap(graph)
+}
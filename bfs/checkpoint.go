@@ -0,0 +1,124 @@
+package bfs
+
+import (
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cdclaxton/shortest-path-web-app/set"
+)
+
+// A Checkpoint records the progress of a search for the paths between the entities of a job, so
+// that if the search fails part way through (e.g. due to a transient read error) it can be resumed
+// without searching again between the pairs of entities that have already been searched. The paths
+// found before a failure can also be used as partial results.
+type Checkpoint struct {
+	connections *NetworkConnections // Paths found so far (nil if the search hasn't started)
+	searched    *set.Set[string]    // Pairs of entities that have been searched
+}
+
+// NewCheckpoint for a search that hasn't started.
+func NewCheckpoint() *Checkpoint {
+	return &Checkpoint{
+		searched: set.NewSet[string](),
+	}
+}
+
+// pairKey identifies the search from entity1 to entity2.
+func pairKey(entity1 string, entity2 string) string {
+	return entity1 + "\x00" + entity2
+}
+
+// Connections found by the search so far, which are incomplete if the search failed. The
+// connections are nil if the search hasn't started.
+func (c *Checkpoint) Connections() *NetworkConnections {
+	return c.connections
+}
+
+// NumberOfPairsSearched is the number of pairs of entities that have been searched.
+func (c *Checkpoint) NumberOfPairsSearched() int {
+	return c.searched.Len()
+}
+
+// resume the search with the maximum number of hops. The search is restarted if the checkpoint
+// was for a different number of hops. Returns true if the search is resumed.
+func (c *Checkpoint) resume(maxHops int, connections *NetworkConnections) bool {
+
+	if c.connections != nil && c.connections.MaxHops == maxHops {
+		logging.Logger.Info().
+			Str(logging.ComponentField, componentName).
+			Int("numberOfPairsSearched", c.searched.Len()).
+			Msg("Resuming the search for paths from a checkpoint")
+		return true
+	}
+
+	c.Close()
+	c.connections = connections
+	c.searched = set.NewSet[string]()
+	return false
+}
+
+// hasSearched returns true if the paths from entity1 to entity2 have been searched. The checkpoint
+// may be nil, in which case no pairs have been searched.
+func (c *Checkpoint) hasSearched(entity1 string, entity2 string) bool {
+	return c != nil && c.searched.Has(pairKey(entity1, entity2))
+}
+
+// markSearched records that the paths from entity1 to entity2 have been searched.
+func (c *Checkpoint) markSearched(entity1 string, entity2 string) {
+	if c != nil {
+		c.searched.Add(pairKey(entity1, entity2))
+	}
+}
+
+// UnsearchedPairs of entities in the sets that haven't been searched, in the order in which the
+// search walks through them. Unless directed, a pair is only listed once, whichever order it is
+// searched in.
+func (c *Checkpoint) UnsearchedPairs(entitySets []job.EntitySet, directed bool) []job.EntityPair {
+
+	unsearched := []job.EntityPair{}
+	listed := set.NewSet[string]()
+
+	addPairs := func(entitySet1 job.EntitySet, entitySet2 job.EntitySet) {
+		for _, entityId1 := range entitySet1.EntityIds {
+			for _, entityId2 := range entitySet2.EntityIds {
+
+				if entityId1 == entityId2 || listed.Has(pairKey(entityId1, entityId2)) ||
+					c.hasSearched(entityId1, entityId2) ||
+					(!directed && c.hasSearched(entityId2, entityId1)) {
+					continue
+				}
+
+				unsearched = append(unsearched, job.EntityPair{Entity1: entityId1, Entity2: entityId2})
+				listed.Add(pairKey(entityId1, entityId2))
+				if !directed {
+					listed.Add(pairKey(entityId2, entityId1))
+				}
+			}
+		}
+	}
+
+	if len(entitySets) == 1 {
+		addPairs(entitySets[0], entitySets[0])
+		return unsearched
+	}
+
+	for entitySet1Index := range entitySets {
+		for entitySet2Index := range entitySets {
+			if entitySet2Index == entitySet1Index ||
+				(!directed && entitySet2Index < entitySet1Index) {
+				continue
+			}
+			addPairs(entitySets[entitySet1Index], entitySets[entitySet2Index])
+		}
+	}
+
+	return unsearched
+}
+
+// Close the connections held by the checkpoint.
+func (c *Checkpoint) Close() error {
+	if c.connections == nil {
+		return nil
+	}
+
+	return c.connections.Close()
+}
@@ -0,0 +1,136 @@
+package bfs
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+var errTransient = errors.New("transient read error")
+
+// flakyGraphStore fails to read an entity whilst failing is true and counts the reads.
+type flakyGraphStore struct {
+	graphstore.UnipartiteGraphStore
+	entityId string
+	failing  bool
+	reads    map[string]int
+}
+
+func (f *flakyGraphStore) HasEntity(entityId string) (bool, error) {
+	f.reads[entityId] += 1
+
+	if f.failing && entityId == f.entityId {
+		return false, errTransient
+	}
+
+	return f.UnipartiteGraphStore.HasEntity(entityId)
+}
+
+func TestFindPathsWithCheckpoint(t *testing.T) {
+
+	graph := graphstore.NewInMemoryUnipartiteGraphStore()
+	buildTestGraph(t, graph)
+
+	flaky := &flakyGraphStore{
+		UnipartiteGraphStore: graph,
+		entityId:             "10",
+		failing:              true,
+		reads:                map[string]int{},
+	}
+
+	pathFinder, err := NewPathFinder(flaky)
+	assert.NoError(t, err)
+
+	_, err = pathFinder.FindPathsWithCheckpoint(nil, 3, PathConstraints{}, logging.Logger, nil)
+	assert.ErrorIs(t, err, ErrCheckpointIsNil)
+
+	// The search between 1 and 3 succeeds, but fails when it reaches 10
+	entitySets := []job.EntitySet{
+		{Name: "Set-1", EntityIds: []string{"1", "3", "10"}},
+	}
+
+	checkpoint := NewCheckpoint()
+	defer checkpoint.Close()
+	assert.Nil(t, checkpoint.Connections())
+
+	_, err = pathFinder.FindPathsWithCheckpoint(entitySets, 3, PathConstraints{}, logging.Logger,
+		checkpoint)
+	assert.ErrorIs(t, err, errTransient)
+
+	// The partial results hold the paths found before the failure
+	assert.Equal(t, 1, checkpoint.NumberOfPairsSearched())
+	assert.Equal(t, []Path{NewPath("1", "2", "3")}, checkpoint.Connections().Connections["1"]["3"])
+	assert.Equal(t, []job.EntityPair{{Entity1: "1", Entity2: "10"}, {Entity1: "3", Entity2: "10"}},
+		checkpoint.UnsearchedPairs(entitySets, false))
+
+	// Resume the search once the store has recovered, where the pair already searched isn't
+	// searched again
+	flaky.failing = false
+	readsOf1 := flaky.reads["1"]
+
+	conns, err := pathFinder.FindPathsWithCheckpoint(entitySets, 3, PathConstraints{}, logging.Logger,
+		checkpoint)
+	assert.NoError(t, err)
+	assert.Equal(t, readsOf1+1, flaky.reads["1"])
+	assert.Equal(t, 3, checkpoint.NumberOfPairsSearched())
+
+	expected, err := NewPathFinder(graph)
+	assert.NoError(t, err)
+	expectedConns, err := expected.FindPaths(entitySets, 3)
+	assert.NoError(t, err)
+	assert.True(t, expectedConns.Equal(conns))
+
+	// A different number of hops restarts the search
+	conns, err = pathFinder.FindPathsWithCheckpoint(entitySets, 2, PathConstraints{}, logging.Logger,
+		checkpoint)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, conns.MaxHops)
+	assert.Equal(t, 3, checkpoint.NumberOfPairsSearched())
+}
+
+func TestFindPathsWithContext(t *testing.T) {
+
+	graph := graphstore.NewInMemoryUnipartiteGraphStore()
+	buildTestGraph(t, graph)
+
+	pathFinder, err := NewPathFinder(graph)
+	assert.NoError(t, err)
+
+	entitySets := []job.EntitySet{
+		{Name: "Set-1", EntityIds: []string{"1", "3"}},
+		{Name: "Set-2", EntityIds: []string{"2"}},
+	}
+
+	// A search that isn't cancelled finds the paths
+	conns, err := pathFinder.FindPathsWithContext(context.Background(), entitySets, 3,
+		PathConstraints{}, logging.Logger, nil)
+	assert.NoError(t, err)
+	assert.True(t, conns.HasAnyConnections())
+	conns.Close()
+
+	// A cancelled search doesn't search any of the pairs
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	checkpoint := NewCheckpoint()
+	defer checkpoint.Close()
+
+	_, err = pathFinder.FindPathsWithContext(ctx, entitySets, 3, PathConstraints{}, logging.Logger,
+		checkpoint)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Equal(t, 0, checkpoint.NumberOfPairsSearched())
+
+	assert.Equal(t, []job.EntityPair{{Entity1: "1", Entity2: "2"}, {Entity1: "3", Entity2: "2"}},
+		checkpoint.UnsearchedPairs(entitySets, false))
+	assert.Equal(t, []job.EntityPair{
+		{Entity1: "1", Entity2: "2"},
+		{Entity1: "3", Entity2: "2"},
+		{Entity1: "2", Entity2: "1"},
+		{Entity1: "2", Entity2: "3"},
+	}, checkpoint.UnsearchedPairs(entitySets, true))
+}
@@ -0,0 +1,240 @@
+package bfs
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/set"
+)
+
+// PathConstraints restrict the paths found by a query. The zero value finds all paths ignoring the
+// direction of any directed edges.
+type PathConstraints struct {
+	Directed     bool             // Only follow edges in their direction
+	Excluded     *set.Set[string] // Entities that the paths mustn't pass through (nil for none)
+	Waypoints    *set.Set[string] // Entities that the paths must pass through one of (nil for any)
+	MinDocuments int              // Minimum number of documents supporting each edge (0 for any)
+	Temporal     bool             // Only return paths whose edges have documents in date order
+}
+
+// edgeDocuments returns the number of documents supporting the edge from the source to the
+// destination according to the edge's metadata. An edge without metadata (e.g. in a graph built
+// before the metadata was recorded) is supported by at least one document.
+func edgeDocuments(graph graphstore.UnipartiteGraphStore, src string, dst string) (int, error) {
+
+	metadata, err := graph.EdgeMetadata(src, dst)
+	if err != nil {
+		return 0, err
+	}
+
+	if metadata == nil {
+		return 1, nil
+	}
+
+	return metadata.NumberOfDocuments, nil
+}
+
+// adjacent returns the function that gives the vertices adjacent to a vertex of the graph, following
+// the direction of the edges in directed mode.
+func (c PathConstraints) adjacent(graph graphstore.UnipartiteGraphStore) func(string) (*set.Set[string], error) {
+	if c.Directed {
+		return graph.EntityIdsAdjacentTo
+	}
+	return graph.EntityIdsConnectedTo
+}
+
+// isSupported returns true if each edge of the route is supported by the minimum number of
+// documents.
+func (c PathConstraints) isSupported(graph graphstore.UnipartiteGraphStore, route []string) (bool, error) {
+
+	for idx := 1; idx < len(route); idx++ {
+		n, err := edgeDocuments(graph, route[idx-1], route[idx])
+		if err != nil {
+			return false, err
+		}
+
+		if n < c.MinDocuments {
+			return false, nil
+		}
+	}
+
+	return true, nil
+}
+
+// steps returns the function that gives the steps that can be taken from a vertex, i.e. following
+// the direction of the edges in directed mode and only stepping along the edges supported by the
+// minimum number of documents. In a graph with collapsed chains, the pruned store is searched and
+// each chain is stepped over in one go.
+func (c PathConstraints) steps(graph graphstore.UnipartiteGraphStore) func(string) ([]step, error) {
+
+	var steps func(string) ([]step, error)
+	if chained, ok := graph.(chainedGraph); ok {
+		steps = chainSteps(chained, c.adjacent(chained.Pruned()))
+	} else {
+		steps = singleSteps(c.adjacent(graph))
+	}
+
+	if c.MinDocuments <= 1 {
+		return steps
+	}
+
+	return func(entityId string) ([]step, error) {
+		candidates, err := steps(entityId)
+		if err != nil {
+			return nil, err
+		}
+
+		supported := []step{}
+		for _, s := range candidates {
+			ok, err := c.isSupported(graph, s.route(entityId))
+			if err != nil {
+				return nil, err
+			}
+
+			if ok {
+				supported = append(supported, s)
+			}
+		}
+
+		return supported, nil
+	}
+}
+
+// temporalState at the root of a search, which is nil unless the search is temporal.
+func (c PathConstraints) temporalState() *temporalState {
+	if !c.Temporal {
+		return nil
+	}
+	return newTemporalState(c.Directed)
+}
+
+// allPathsMeeting the constraints (other than the waypoints) from a root vertex to a goal vertex up
+// to a maximum depth. In temporal mode, the search doesn't step along links that can't be supported
+// by documents in date order.
+func allPathsMeeting(ctx context.Context, graph graphstore.UnipartiteGraphStore, root string,
+	goal string, maxDepth int, constraints PathConstraints) ([]Path, error) {
+	return allPaths(ctx, graph, root, goal, maxDepth, constraints.steps(graph),
+		constraints.Excluded, constraints.temporalState())
+}
+
+// setLen returns the number of elements in a set that may be nil.
+func setLen(s *set.Set[string]) int {
+	if s == nil {
+		return 0
+	}
+	return s.Len()
+}
+
+// AllPathsWithConstraints finds the paths from a root vertex to a goal vertex up to a maximum depth
+// that meet the constraints. If there are waypoints, the paths are found by composing the paths
+// from the root to each waypoint with the paths from the waypoint to the goal within the maximum
+// depth. In temporal mode, only the paths whose edges can be supported by documents in date order
+// are found. The parts of a path through a waypoint are each found in date order, so the composed
+// paths whose documents aren't in date order across the waypoint are then removed.
+//
+// The function assumes that the root and goal vertices are present in the graph.
+func AllPathsWithConstraints(ctx context.Context, graph graphstore.UnipartiteGraphStore,
+	root string, goal string, maxDepth int, constraints PathConstraints) ([]Path, error) {
+
+	if constraints.Waypoints == nil {
+		return allPathsMeeting(ctx, graph, root, goal, maxDepth, constraints)
+	}
+
+	paths, err := allPathsVia(ctx, graph, root, goal, maxDepth, constraints)
+	if err != nil || !constraints.Temporal {
+		return paths, err
+	}
+
+	return temporalPaths(graph, paths, constraints.Directed)
+}
+
+// isSimplePath returns true if the path doesn't visit a vertex more than once.
+func isSimplePath(route []string) bool {
+	seen := set.NewSet[string]()
+	for _, vertex := range route {
+		if seen.Has(vertex) {
+			return false
+		}
+		seen.Add(vertex)
+	}
+	return true
+}
+
+// allPathsVia finds the paths from a root vertex to a goal vertex up to a maximum depth that pass
+// through at least one of the waypoints. A waypoint must be an intermediate vertex of a path, so a
+// waypoint that is the root or the goal is ignored, as is an excluded waypoint.
+func allPathsVia(ctx context.Context, graph graphstore.UnipartiteGraphStore, root string,
+	goal string, maxDepth int, constraints PathConstraints) ([]Path, error) {
+
+	// Check the root and goal exist (to give the same errors as a search without waypoints)
+	if _, err := allPathsMeeting(ctx, graph, root, goal, 0, constraints); err != nil {
+		return nil, err
+	}
+
+	waypoints := constraints.Waypoints.ToSlice()
+	sort.Strings(waypoints)
+
+	found := set.NewSet[string]()
+	paths := []Path{}
+
+	for _, waypoint := range waypoints {
+		if waypoint == root || waypoint == goal {
+			continue
+		}
+
+		if constraints.Excluded != nil && constraints.Excluded.Has(waypoint) {
+			continue
+		}
+
+		inGraph, err := graph.HasEntity(waypoint)
+		if err != nil {
+			return nil, err
+		}
+		if !inGraph {
+			continue
+		}
+
+		// Each part of the path needs at least one hop
+		toWaypoint, err := allPathsMeeting(ctx, graph, root, waypoint, maxDepth-1, constraints)
+		if err != nil {
+			return nil, err
+		}
+
+		if len(toWaypoint) == 0 {
+			continue
+		}
+
+		fromWaypoint, err := allPathsMeeting(ctx, graph, waypoint, goal, maxDepth-1, constraints)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, first := range toWaypoint {
+			for _, second := range fromWaypoint {
+
+				// Number of hops of the combined path
+				if len(first.Route)+len(second.Route)-2 > maxDepth {
+					continue
+				}
+
+				route := append(append([]string{}, first.Route...), second.Route[1:]...)
+				if !isSimplePath(route) {
+					continue
+				}
+
+				// A path through more than one waypoint is only returned once
+				key := strings.Join(route, "\x00")
+				if found.Has(key) {
+					continue
+				}
+				found.Add(key)
+
+				paths = append(paths, NewPath(route...))
+			}
+		}
+	}
+
+	return paths, nil
+}
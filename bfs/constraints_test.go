@@ -0,0 +1,310 @@
+package bfs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cdclaxton/shortest-path-web-app/set"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsSimplePath(t *testing.T) {
+	assert.True(t, isSimplePath([]string{}))
+	assert.True(t, isSimplePath([]string{"a", "b", "c"}))
+	assert.False(t, isSimplePath([]string{"a", "b", "a"}))
+}
+
+// makeWaypointGraph returns the graph:
+//
+//	a -- b -- c -- d
+//	|              |
+//	w1 ---------- w2
+func makeWaypointGraph(t *testing.T) graphstore.UnipartiteGraphStore {
+	graph := graphstore.NewInMemoryUnipartiteGraphStore()
+	assert.NoError(t, graphstore.BuildFromEdgeList(graph, []graphstore.Edge{
+		{V1: "a", V2: "b"},
+		{V1: "b", V2: "c"},
+		{V1: "c", V2: "d"},
+		{V1: "a", V2: "w1"},
+		{V1: "w1", V2: "w2"},
+		{V1: "w2", V2: "d"},
+	}))
+	return graph
+}
+
+func TestAllPathsWithConstraints(t *testing.T) {
+
+	graph := makeWaypointGraph(t)
+	ctx := context.Background()
+
+	testCases := []struct {
+		description string
+		maxDepth    int
+		constraints PathConstraints
+		expected    []Path
+	}{
+		{
+			description: "no constraints",
+			maxDepth:    3,
+			constraints: PathConstraints{},
+			expected: []Path{
+				NewPath("a", "b", "c", "d"),
+				NewPath("a", "w1", "w2", "d"),
+			},
+		},
+		{
+			description: "one waypoint",
+			maxDepth:    3,
+			constraints: PathConstraints{Waypoints: set.NewPopulatedSet("w1")},
+			expected:    []Path{NewPath("a", "w1", "w2", "d")},
+		},
+		{
+			description: "path through two waypoints is only returned once",
+			maxDepth:    3,
+			constraints: PathConstraints{Waypoints: set.NewPopulatedSet("w1", "w2")},
+			expected:    []Path{NewPath("a", "w1", "w2", "d")},
+		},
+		{
+			description: "waypoint beyond the maximum depth",
+			maxDepth:    2,
+			constraints: PathConstraints{Waypoints: set.NewPopulatedSet("w1")},
+			expected:    []Path{},
+		},
+		{
+			description: "excluded waypoint",
+			maxDepth:    3,
+			constraints: PathConstraints{
+				Waypoints: set.NewPopulatedSet("w1", "b"),
+				Excluded:  set.NewPopulatedSet("w1"),
+			},
+			expected: []Path{NewPath("a", "b", "c", "d")},
+		},
+		{
+			description: "waypoint not in the graph",
+			maxDepth:    3,
+			constraints: PathConstraints{Waypoints: set.NewPopulatedSet("x")},
+			expected:    []Path{},
+		},
+		{
+			description: "waypoint at the end of the path is ignored",
+			maxDepth:    3,
+			constraints: PathConstraints{Waypoints: set.NewPopulatedSet("d")},
+			expected:    []Path{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.description, func(t *testing.T) {
+			actual, err := AllPathsWithConstraints(ctx, graph, "a", "d", testCase.maxDepth,
+				testCase.constraints)
+			assert.NoError(t, err)
+			assert.True(t, PathsEqual(testCase.expected, actual))
+		})
+	}
+
+	// The root must be in the graph
+	_, err := AllPathsWithConstraints(ctx, graph, "x", "d", 3,
+		PathConstraints{Waypoints: set.NewPopulatedSet("w1")})
+	assert.Error(t, err)
+}
+
+func TestFindPathsWithWaypoints(t *testing.T) {
+
+	pathFinder, err := NewPathFinder(makeWaypointGraph(t))
+	assert.NoError(t, err)
+
+	entitySets := []job.EntitySet{
+		{
+			EntityIds: []string{"a", "c"},
+			Name:      "Set-1",
+		},
+		{
+			EntityIds: []string{"d"},
+			Name:      "Set-2",
+		},
+	}
+
+	conns, err := pathFinder.FindPathsWithConstraints(entitySets, 4,
+		PathConstraints{Waypoints: set.NewPopulatedSet("w2")}, logging.Logger)
+	assert.NoError(t, err)
+
+	// c only reaches d through w2 in five hops, which is beyond the maximum
+	expected := map[string]map[string][]Path{
+		"a": {
+			"d": []Path{NewPath("a", "w1", "w2", "d")},
+		},
+	}
+	assert.True(t, connectionsEqual(expected, conns.Connections))
+}
+
+func TestAllPathsWithMinDocuments(t *testing.T) {
+
+	// a -- b -- d is supported by 2 documents per edge and a -- c -- d by 1 document per edge
+	graph := graphstore.NewInMemoryUnipartiteGraphStore()
+	for _, edge := range [][]string{{"a", "b"}, {"b", "d"}, {"a", "c"}, {"c", "d"}} {
+		assert.NoError(t, graph.AddUndirected(edge[0], edge[1]))
+	}
+
+	addDocuments := func(e1 string, e2 string, n int) {
+		for i := 0; i < n; i++ {
+			assert.NoError(t, graph.AddEdgeDocument(e1, e2, time.Time{}))
+			assert.NoError(t, graph.AddEdgeDocument(e2, e1, time.Time{}))
+		}
+	}
+	addDocuments("a", "b", 2)
+	addDocuments("b", "d", 3)
+	addDocuments("a", "c", 1)
+	addDocuments("c", "d", 1)
+
+	ctx := context.Background()
+
+	testCases := []struct {
+		minDocuments int
+		expected     []Path
+	}{
+		{0, []Path{NewPath("a", "b", "d"), NewPath("a", "c", "d")}},
+		{1, []Path{NewPath("a", "b", "d"), NewPath("a", "c", "d")}},
+		{2, []Path{NewPath("a", "b", "d")}},
+		{3, []Path{}},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := AllPathsWithConstraints(ctx, graph, "a", "d", 2,
+			PathConstraints{MinDocuments: testCase.minDocuments})
+		assert.NoError(t, err)
+		assert.True(t, PathsEqual(testCase.expected, actual), testCase.minDocuments)
+	}
+}
+
+func TestAllPathsWithConstraintsThroughCollapsedChains(t *testing.T) {
+
+	day := func(d int) time.Time {
+		return time.Date(2022, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	// Graph before the chain a -- b -- c -- d -- e is collapsed, where the documents of the chain
+	// are in date order from a to e (two per edge) and a -- x isn't supported by a dated document
+	//
+	//   a -- b -- c -- d -- e
+	//   |                   |
+	//   +------- x ---------+
+	//   |                   |
+	//   +------- y ---------+
+	graph := graphstore.NewInMemoryUnipartiteGraphStore()
+	edges := []struct {
+		e1    string
+		e2    string
+		dates []time.Time
+	}{
+		{"a", "b", []time.Time{day(1), day(1)}},
+		{"b", "c", []time.Time{day(2), day(2)}},
+		{"c", "d", []time.Time{day(3), day(3)}},
+		{"d", "e", []time.Time{day(4), day(4)}},
+		{"a", "x", []time.Time{{}}},
+		{"x", "e", []time.Time{day(5)}},
+		{"a", "y", []time.Time{day(1)}},
+		{"y", "e", []time.Time{day(2)}},
+	}
+
+	for _, edge := range edges {
+		assert.NoError(t, graph.AddUndirected(edge.e1, edge.e2))
+		for _, date := range edge.dates {
+			assert.NoError(t, graph.AddEdgeDocument(edge.e1, edge.e2, date))
+			assert.NoError(t, graph.AddEdgeDocument(edge.e2, edge.e1, date))
+		}
+	}
+
+	result, err := graphstore.PruneUnipartite(graph, graphstore.UnipartitePruneOptions{
+		MinChainLength: 3,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.ChainsCollapsed)
+
+	expanded, err := graphstore.NewExpandedUnipartiteGraphStore(graph, result.Chains)
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+	testCases := []struct {
+		description string
+		maxDepth    int
+		constraints PathConstraints
+		expected    []Path
+	}{
+		{
+			description: "Path through the chain",
+			maxDepth:    4,
+			expected: []Path{
+				NewPath("a", "b", "c", "d", "e"),
+				NewPath("a", "x", "e"),
+				NewPath("a", "y", "e"),
+			},
+		},
+		{
+			description: "Path through the chain is too long",
+			maxDepth:    3,
+			expected:    []Path{NewPath("a", "x", "e"), NewPath("a", "y", "e")},
+		},
+		{
+			description: "Path through the chain passes through an excluded entity",
+			maxDepth:    4,
+			constraints: PathConstraints{Excluded: set.NewPopulatedSet("c", "x")},
+			expected:    []Path{NewPath("a", "y", "e")},
+		},
+		{
+			description: "Only the edges of the chain are supported by two documents",
+			maxDepth:    4,
+			constraints: PathConstraints{MinDocuments: 2},
+			expected:    []Path{NewPath("a", "b", "c", "d", "e")},
+		},
+		{
+			description: "Documents in date order",
+			maxDepth:    4,
+			constraints: PathConstraints{Temporal: true},
+			expected:    []Path{NewPath("a", "b", "c", "d", "e"), NewPath("a", "y", "e")},
+		},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := AllPathsWithConstraints(ctx, expanded, "a", "e", testCase.maxDepth,
+			testCase.constraints)
+		assert.NoError(t, err, testCase.description)
+		assert.True(t, PathsEqual(testCase.expected, actual), testCase.description)
+	}
+
+	// Entities inside the chain at the ends of a path
+	actual, err := AllPathsWithConstraints(ctx, expanded, "c", "y", 4, PathConstraints{})
+	assert.NoError(t, err)
+	assert.True(t, PathsEqual([]Path{
+		NewPath("c", "b", "a", "y"),
+		NewPath("c", "d", "e", "y"),
+	}, actual))
+
+	actual, err = AllPathsWithConstraints(ctx, expanded, "b", "d", 4, PathConstraints{})
+	assert.NoError(t, err)
+	assert.True(t, PathsEqual([]Path{
+		NewPath("b", "c", "d"),
+		NewPath("b", "a", "x", "e", "d"),
+		NewPath("b", "a", "y", "e", "d"),
+	}, actual))
+
+	// The search doesn't expand the entities inside the chain
+	var counter *expansionCounter
+	searched := wrapSearched(expanded, func(g graphstore.UnipartiteGraphStore) graphstore.UnipartiteGraphStore {
+		wrapped, c := NewExplanation().counter(g)
+		counter = c
+		return wrapped
+	})
+
+	actual, err = AllPathsWithConstraints(ctx, searched, "a", "e", 4, PathConstraints{})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(actual))
+
+	for _, entityId := range []string{"b", "c", "d"} {
+		_, found := counter.vertices[entityId]
+		assert.False(t, found, entityId)
+	}
+}
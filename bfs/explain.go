@@ -0,0 +1,181 @@
+package bfs
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/set"
+)
+
+// An Explanation records how much of the graph was expanded by the search for the paths between
+// each pair of entities, to help a user understand why a query is slow or finds nothing. A nil
+// explanation doesn't record anything.
+
+// Maximum number of vertices reported as dominating the expansion of a search
+const MaxDominantVertices = 5
+
+// VertexExpansion records how often a vertex was expanded by a search.
+type VertexExpansion struct {
+	EntityId   string
+	Expansions int // Number of times the vertex was expanded (once for each partial path reaching it)
+	Edges      int // Number of edges followed from the vertex over all of its expansions
+}
+
+// PairExplanation of the search for the paths between a pair of entities.
+type PairExplanation struct {
+	Entity1          string
+	Entity2          string
+	NumberOfHops     int               // Maximum number of hops of the search
+	VerticesExpanded int               // Number of vertex expansions
+	EdgesExpanded    int               // Number of edges followed
+	NumberOfPaths    int               // Number of paths found
+	TimeTaken        time.Duration     // Time taken by the search
+	Abandoned        bool              // Was the search abandoned (e.g. the job timed out)?
+	Dominant         []VertexExpansion // Vertices with the most edges followed (most first)
+}
+
+// Explanation of the searches for the paths between the pairs of entities.
+type Explanation struct {
+	Pairs []PairExplanation
+}
+
+// NewExplanation of the searches of a job.
+func NewExplanation() *Explanation {
+	return &Explanation{
+		Pairs: []PairExplanation{},
+	}
+}
+
+// expansionCounter is a unipartite graph that counts the expansions of each vertex by a search.
+type expansionCounter struct {
+	graphstore.UnipartiteGraphStore
+	vertices map[string]*VertexExpansion
+}
+
+// count the expansion of a vertex to its adjacent vertices.
+func (e *expansionCounter) count(entityId string, adjacent *set.Set[string], err error) (
+	*set.Set[string], error) {
+
+	if err != nil {
+		return nil, err
+	}
+
+	expansion, found := e.vertices[entityId]
+	if !found {
+		expansion = &VertexExpansion{EntityId: entityId}
+		e.vertices[entityId] = expansion
+	}
+
+	expansion.Expansions++
+	expansion.Edges += adjacent.Len()
+
+	return adjacent, nil
+}
+
+// EntityIdsAdjacentTo the entity, counting the expansion.
+func (e *expansionCounter) EntityIdsAdjacentTo(entityId string) (*set.Set[string], error) {
+	adjacent, err := e.UnipartiteGraphStore.EntityIdsAdjacentTo(entityId)
+	return e.count(entityId, adjacent, err)
+}
+
+// EntityIdsConnectedTo the entity, counting the expansion.
+func (e *expansionCounter) EntityIdsConnectedTo(entityId string) (*set.Set[string], error) {
+	connected, err := e.UnipartiteGraphStore.EntityIdsConnectedTo(entityId)
+	return e.count(entityId, connected, err)
+}
+
+// counter of the expansions of the search for the paths between a pair of entities, which wraps
+// the graph. The graph itself is returned if the explanation is nil.
+func (e *Explanation) counter(graph graphstore.UnipartiteGraphStore) (
+	graphstore.UnipartiteGraphStore, *expansionCounter) {
+
+	if e == nil {
+		return graph, nil
+	}
+
+	counter := &expansionCounter{
+		UnipartiteGraphStore: graph,
+		vertices:             map[string]*VertexExpansion{},
+	}
+
+	return counter, counter
+}
+
+// record the search for the paths between a pair of entities.
+func (e *Explanation) record(entity1 string, entity2 string, numberOfHops int,
+	counter *expansionCounter, numberOfPaths int, timeTaken time.Duration, abandoned bool) {
+
+	if e == nil || counter == nil {
+		return
+	}
+
+	pair := PairExplanation{
+		Entity1:       entity1,
+		Entity2:       entity2,
+		NumberOfHops:  numberOfHops,
+		NumberOfPaths: numberOfPaths,
+		TimeTaken:     timeTaken,
+		Abandoned:     abandoned,
+		Dominant:      []VertexExpansion{},
+	}
+
+	for _, expansion := range counter.vertices {
+		pair.VerticesExpanded += expansion.Expansions
+		pair.EdgesExpanded += expansion.Edges
+		pair.Dominant = append(pair.Dominant, *expansion)
+	}
+
+	// Order the vertices by the number of edges followed, breaking ties by entity ID
+	sort.Slice(pair.Dominant, func(i, j int) bool {
+		if pair.Dominant[i].Edges != pair.Dominant[j].Edges {
+			return pair.Dominant[i].Edges > pair.Dominant[j].Edges
+		}
+		return pair.Dominant[i].EntityId < pair.Dominant[j].EntityId
+	})
+
+	if len(pair.Dominant) > MaxDominantVertices {
+		pair.Dominant = pair.Dominant[:MaxDominantVertices]
+	}
+
+	e.Pairs = append(e.Pairs, pair)
+}
+
+// Rows of a diagnostics sheet of the explanation, with a header row and a row for each pair of
+// entities in the order they were searched.
+func (e *Explanation) Rows() [][]string {
+
+	rows := [][]string{{
+		"Entity 1", "Entity 2", "Number of hops", "Vertices expanded", "Edges expanded",
+		"Number of paths", "Time taken (ms)", "Abandoned", "Dominant vertices",
+	}}
+
+	for _, pair := range e.Pairs {
+		dominant := []string{}
+		for _, expansion := range pair.Dominant {
+			dominant = append(dominant, fmt.Sprintf("%v (%v edges)", expansion.EntityId, expansion.Edges))
+		}
+
+		abandoned := "No"
+		if pair.Abandoned {
+			abandoned = "Yes"
+		}
+
+		rows = append(rows, []string{
+			pair.Entity1,
+			pair.Entity2,
+			strconv.Itoa(pair.NumberOfHops),
+			strconv.Itoa(pair.VerticesExpanded),
+			strconv.Itoa(pair.EdgesExpanded),
+			strconv.Itoa(pair.NumberOfPaths),
+			strconv.FormatInt(pair.TimeTaken.Milliseconds(), 10),
+			abandoned,
+			strings.Join(dominant, "; "),
+		})
+	}
+
+	return rows
+}
@@ -0,0 +1,92 @@
+package bfs
+
+import (
+	"context"
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+// buildHubGraph with a hub connected to A, B, C and D.
+func buildHubGraph(t *testing.T) graphstore.UnipartiteGraphStore {
+	graph := graphstore.NewInMemoryUnipartiteGraphStore()
+	assert.NoError(t, graphstore.BuildFromEdgeList(graph, []graphstore.Edge{
+		{V1: "A", V2: "Hub"},
+		{V1: "B", V2: "Hub"},
+		{V1: "C", V2: "Hub"},
+		{V1: "D", V2: "Hub"},
+	}))
+	return graph
+}
+
+func TestFindPathsWithExplanation(t *testing.T) {
+
+	pathFinder, err := NewPathFinder(buildHubGraph(t))
+	assert.NoError(t, err)
+
+	entitySets := []job.EntitySet{
+		{Name: "set-1", EntityIds: []string{"A"}},
+		{Name: "set-2", EntityIds: []string{"B", "E"}},
+	}
+
+	explanation := NewExplanation()
+	conns, err := pathFinder.FindPathsWithExplanation(context.Background(), entitySets, 2,
+		PathConstraints{}, logging.Logger, nil, explanation)
+	assert.NoError(t, err)
+	defer conns.Close()
+
+	assert.Equal(t, 2, len(explanation.Pairs))
+
+	// A is expanded to the hub, which is expanded to A, B, C and D
+	pair := explanation.Pairs[0]
+	assert.Equal(t, "A", pair.Entity1)
+	assert.Equal(t, "B", pair.Entity2)
+	assert.Equal(t, 2, pair.NumberOfHops)
+	assert.Equal(t, 2, pair.VerticesExpanded)
+	assert.Equal(t, 5, pair.EdgesExpanded)
+	assert.Equal(t, 1, pair.NumberOfPaths)
+	assert.False(t, pair.Abandoned)
+	assert.Equal(t, []VertexExpansion{
+		{EntityId: "Hub", Expansions: 1, Edges: 4},
+		{EntityId: "A", Expansions: 1, Edges: 1},
+	}, pair.Dominant)
+
+	// E isn't in the graph, so nothing is expanded
+	pair = explanation.Pairs[1]
+	assert.Equal(t, "E", pair.Entity2)
+	assert.Equal(t, 0, pair.VerticesExpanded)
+	assert.Equal(t, 0, pair.NumberOfPaths)
+	assert.Empty(t, pair.Dominant)
+
+	rows := explanation.Rows()
+	assert.Equal(t, 3, len(rows))
+	assert.Equal(t, []string{"A", "B", "2", "2", "5", "1"}, rows[1][:6])
+	assert.Equal(t, "No", rows[1][7])
+	assert.Equal(t, "Hub (4 edges); A (1 edges)", rows[1][8])
+}
+
+func TestFindPathsWithExplanationAbandoned(t *testing.T) {
+
+	pathFinder, err := NewPathFinder(buildHubGraph(t))
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// The search is abandoned before any pair is searched
+	explanation := NewExplanation()
+	_, err = pathFinder.FindPathsWithExplanation(ctx, []job.EntitySet{
+		{Name: "set-1", EntityIds: []string{"A", "B"}},
+	}, 2, PathConstraints{}, logging.Logger, nil, explanation)
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Empty(t, explanation.Pairs)
+
+	// A nil explanation doesn't record anything
+	var nilExplanation *Explanation
+	graph, counter := nilExplanation.counter(pathFinder.graph)
+	assert.Equal(t, pathFinder.graph, graph)
+	nilExplanation.record("A", "B", 2, counter, 0, 0, false)
+}
@@ -0,0 +1,81 @@
+package bfs
+
+// FilterPaths keeps the paths for which the keep function returns true and returns the number of
+// paths that were removed. A pair of entities without any remaining paths is no longer connected,
+// but its entities keep their datasets. Paths that are held on disk are read back and the kept paths
+// are written to a new spill file. If an error occurs, the connections are unchanged.
+func (n *NetworkConnections) FilterPaths(keep func(Path) (bool, error)) (int, error) {
+
+	var spill *PathSpill
+	if n.spill != nil {
+		var err error
+		spill, err = NewPathSpill(n.spillFolder)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	connections := map[string]map[string][]Path{}
+	memoryUsed := 0
+	removed := 0
+
+	fail := func(err error) (int, error) {
+		if spill != nil {
+			spill.Close()
+		}
+		return 0, err
+	}
+
+	for src, destinations := range n.Connections {
+		for dst := range destinations {
+
+			paths, err := n.Paths(src, dst)
+			if err != nil {
+				return fail(err)
+			}
+
+			kept := []Path{}
+			for _, path := range paths {
+				ok, err := keep(path)
+				if err != nil {
+					return fail(err)
+				}
+
+				if ok {
+					kept = append(kept, path)
+				} else {
+					removed++
+				}
+			}
+
+			if len(kept) == 0 {
+				continue
+			}
+
+			if _, found := connections[src]; !found {
+				connections[src] = map[string][]Path{}
+			}
+
+			if spill != nil {
+				connections[src][dst] = nil
+				if err := spill.Append(src, dst, kept); err != nil {
+					return fail(err)
+				}
+			} else {
+				connections[src][dst] = kept
+				memoryUsed += estimatePathsSize(kept)
+			}
+		}
+	}
+
+	if n.spill != nil {
+		if err := n.spill.Close(); err != nil {
+			return fail(err)
+		}
+	}
+
+	n.Connections = connections
+	n.spill = spill
+	n.memoryUsed = memoryUsed
+	return removed, nil
+}
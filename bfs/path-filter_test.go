@@ -0,0 +1,91 @@
+package bfs
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// makeFilterTestConnections with paths from e-1 to e-2 and from e-1 to e-3.
+func makeFilterTestConnections(t *testing.T) *NetworkConnections {
+	conns, err := NewNetworkConnections(3)
+	assert.NoError(t, err)
+
+	assert.NoError(t, conns.AddPaths("e-1", "Set-1", "e-2", "Set-2", []Path{
+		NewPath("e-1", "e-2"),
+		NewPath("e-1", "x-1", "e-2"),
+		NewPath("e-1", "y-1", "e-2"),
+	}))
+	assert.NoError(t, conns.AddPaths("e-1", "Set-1", "e-3", "Set-2", []Path{
+		NewPath("e-1", "y-2", "e-3"),
+	}))
+
+	return conns
+}
+
+// throughX returns true if the path passes through an entity whose ID starts with x.
+func throughX(path Path) (bool, error) {
+	for _, entityId := range path.Route[1 : len(path.Route)-1] {
+		if entityId[0] == 'x' {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func TestFilterPaths(t *testing.T) {
+	conns := makeFilterTestConnections(t)
+
+	removed, err := conns.FilterPaths(throughX)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, removed)
+	assert.Equal(t, 1, conns.NumberOfPaths())
+
+	paths, err := conns.Paths("e-1", "e-2")
+	assert.NoError(t, err)
+	assert.Equal(t, []Path{NewPath("e-1", "x-1", "e-2")}, paths)
+
+	// The entities that are no longer connected keep their datasets
+	connected, err := conns.HasConnection("e-1", "e-3")
+	assert.NoError(t, err)
+	assert.False(t, connected)
+	assert.True(t, conns.EntityIdToSetNames["e-3"].Has("Set-2"))
+
+	// Keeping all of the paths doesn't change the connections
+	removed, err = conns.FilterPaths(func(Path) (bool, error) { return true, nil })
+	assert.NoError(t, err)
+	assert.Equal(t, 0, removed)
+	assert.Equal(t, 1, conns.NumberOfPaths())
+}
+
+func TestFilterPathsError(t *testing.T) {
+	conns := makeFilterTestConnections(t)
+	expected := errors.New("lookup failed")
+
+	_, err := conns.FilterPaths(func(Path) (bool, error) { return false, expected })
+	assert.ErrorIs(t, err, expected)
+	assert.Equal(t, 4, conns.NumberOfPaths())
+}
+
+func TestFilterSpilledPaths(t *testing.T) {
+	conns := makeFilterTestConnections(t)
+	assert.NoError(t, conns.EnableSpill(t.TempDir(), 1))
+	assert.NoError(t, conns.spillToDisk())
+	assert.True(t, conns.IsSpilled())
+	defer conns.Close()
+
+	removed, err := conns.FilterPaths(throughX)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, removed)
+	assert.True(t, conns.IsSpilled())
+	assert.Equal(t, 1, conns.NumberOfPaths())
+
+	paths, err := conns.Paths("e-1", "e-2")
+	assert.NoError(t, err)
+	assert.Equal(t, []Path{NewPath("e-1", "x-1", "e-2")}, paths)
+
+	connected, err := conns.HasConnection("e-1", "e-3")
+	assert.NoError(t, err)
+	assert.False(t, connected)
+}
@@ -1,14 +1,18 @@
 package bfs
 
 import (
+	"context"
 	"errors"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/cdclaxton/shortest-path-web-app/graphstore"
 	"github.com/cdclaxton/shortest-path-web-app/job"
 	"github.com/cdclaxton/shortest-path-web-app/logging"
 	"github.com/cdclaxton/shortest-path-web-app/set"
+	"github.com/cdclaxton/shortest-path-web-app/tombstone"
+	"github.com/rs/zerolog"
 )
 
 // Component name to use in logging
@@ -27,11 +31,23 @@ var (
 	ErrEntitySetsIsEmpty       = errors.New("entity sets is empty")
 	ErrNoEntitiesInEntitySet   = errors.New("no entity IDS in entity set")
 	ErrNoNameForEntitySet      = errors.New("no name for entity set")
+	ErrInvalidMaxPaths         = errors.New("invalid maximum number of paths")
+	ErrTooManyPaths            = errors.New("too many paths found (path explosion)")
+	ErrInvalidSpillThreshold   = errors.New("invalid spill threshold")
+	ErrCheckpointIsNil         = errors.New("checkpoint is nil")
 )
 
-// PathFinder uses an unidirected unipartite graph to find paths from one entity to another.
+// PathFinder uses a unipartite graph to find paths from one entity to another. The paths either
+// ignore the direction of any directed edges or (in directed mode) only follow edges in their
+// direction.
 type PathFinder struct {
-	graph graphstore.UnipartiteGraphStore
+	graph          graphstore.UnipartiteGraphStore
+	maxPaths       int                       // Maximum number of paths to find before giving up (zero means no limit)
+	spillFolder    string                    // Folder for path spill files (empty if spilling is disabled)
+	spillThreshold int                       // Estimated size (bytes) of the paths in memory before spilling to disk
+	sampling       PathSampling              // Limit on the number of paths kept between each pair of entities
+	tombstones     *tombstone.TombstoneStore // Withdrawn entities excluded from the paths (optional)
+	maxExpansions  int                       // Maximum number of vertex expansions of a query (zero means no limit)
 }
 
 // NewPathFinder given a unipartite graph.
@@ -47,10 +63,159 @@ func NewPathFinder(graph graphstore.UnipartiteGraphStore) (*PathFinder, error) {
 	}
 
 	return &PathFinder{
-		graph: graph,
+		graph:    graph,
+		maxPaths: 0,
+		sampling: PathSampling{Strategy: SampleUniform},
 	}, nil
 }
 
+// SetGraph searched by the path finder, e.g. when a new generation of the graph is served.
+func (p *PathFinder) SetGraph(graph graphstore.UnipartiteGraphStore) error {
+
+	// Precondition
+	if graph == nil {
+		return ErrUnipartiteGraphIsNil
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Msg("Setting the graph of the path finder")
+
+	p.graph = graph
+	return nil
+}
+
+// SetMaxPaths sets the maximum number of paths that can be found for a single query before the
+// search is abandoned with ErrTooManyPaths. A value of zero means there is no limit.
+func (p *PathFinder) SetMaxPaths(maxPaths int) error {
+
+	// Precondition
+	if maxPaths < 0 {
+		return ErrInvalidMaxPaths
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("maxPaths", strconv.Itoa(maxPaths)).
+		Msg("Setting the maximum number of paths")
+
+	p.maxPaths = maxPaths
+	return nil
+}
+
+// SetMaxExpansions sets the budget of vertex expansions of a query that finds the paths between
+// entity sets. When the budget is exhausted, the search stops and the query returns the paths found
+// so far. A value of zero means there is no limit.
+func (p *PathFinder) SetMaxExpansions(maxExpansions int) error {
+
+	// Precondition
+	if maxExpansions < 0 {
+		return ErrInvalidMaxExpansions
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Int("maxExpansions", maxExpansions).
+		Msg("Setting the maximum number of vertex expansions")
+
+	p.maxExpansions = maxExpansions
+	return nil
+}
+
+// MaxExpansions of a query (zero means no limit).
+func (p *PathFinder) MaxExpansions() int {
+	return p.maxExpansions
+}
+
+// SetSampling of the paths between each pair of entities found by a job. The paths beyond the
+// maximum number of paths per pair are omitted and recorded in the network connections.
+func (p *PathFinder) SetSampling(sampling PathSampling) error {
+
+	// Precondition
+	if err := sampling.Validate(); err != nil {
+		return err
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Int("maxPathsPerPair", sampling.MaxPathsPerPair).
+		Str("strategy", string(sampling.Strategy)).
+		Msg("Setting the path sampling")
+
+	p.sampling = sampling
+	return nil
+}
+
+// SetTombstones of the withdrawn entities, which are excluded from the paths. A nil store means
+// there aren't any withdrawn entities.
+func (p *PathFinder) SetTombstones(tombstones *tombstone.TombstoneStore) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Bool("tombstones", tombstones != nil).
+		Msg("Setting the tombstones of the path finder")
+
+	p.tombstones = tombstones
+}
+
+// excludeWithdrawn entities from the entity sets and the paths. Returns copies of the entity sets
+// and the constraints.
+func (p *PathFinder) excludeWithdrawn(entitySets []job.EntitySet,
+	constraints PathConstraints) ([]job.EntitySet, PathConstraints) {
+
+	withdrawn := p.tombstones.WithdrawnIds()
+	if withdrawn.Len() == 0 {
+		return entitySets, constraints
+	}
+
+	sets := make([]job.EntitySet, len(entitySets))
+	for idx, entitySet := range entitySets {
+		entityIds := []string{}
+		for _, entityId := range entitySet.EntityIds {
+			if !withdrawn.Has(entityId) {
+				entityIds = append(entityIds, entityId)
+			}
+		}
+
+		sets[idx] = job.EntitySet{
+			Name:      entitySet.Name,
+			EntityIds: entityIds,
+		}
+	}
+
+	if constraints.Excluded != nil {
+		withdrawn = withdrawn.Union(constraints.Excluded)
+	}
+	constraints.Excluded = withdrawn
+
+	return sets, constraints
+}
+
+// Sampling of the paths between each pair of entities.
+func (p *PathFinder) Sampling() PathSampling {
+	return p.sampling
+}
+
+// SetSpill enables the paths for a query to be spilled to a file in the folder once their
+// estimated size in memory exceeds the threshold (in bytes). An empty folder disables spilling.
+func (p *PathFinder) SetSpill(folder string, threshold int) error {
+
+	// Precondition
+	if len(folder) > 0 && threshold < 1 {
+		return ErrInvalidSpillThreshold
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("folder", folder).
+		Str("threshold", strconv.Itoa(threshold)).
+		Msg("Setting the path spill configuration")
+
+	p.spillFolder = folder
+	p.spillThreshold = threshold
+	return nil
+}
+
 // NetworkConnections stores the paths under a given length between entities of interest and it
 // is populated by PathFinder.
 //
@@ -63,10 +228,23 @@ func NewPathFinder(graph graphstore.UnipartiteGraphStore) (*PathFinder, error) {
 //
 // The MaxHops field holds the maximum number of hops from a source entity to a destination
 // entity. It should be a positive integer greater than zero.
+//
+// If spilling is enabled and the paths become too large to hold in memory, the paths are moved
+// to a PathSpill file. The source and destination entities remain in Connections, but with nil
+// paths, so the paths must be read using Paths().
 type NetworkConnections struct {
 	EntityIdToSetNames map[string]*set.Set[string]  // Entity ID to dataset name mapping
 	Connections        map[string]map[string][]Path // Source to destination to list of paths connecting them
 	MaxHops            int                          // Maximum number of hops from source to destination
+	BudgetExhausted    bool                         // Did the search stop because its budget of vertex expansions was exhausted?
+
+	spill          *PathSpill     // Paths held on disk (nil if all paths are in memory)
+	spillFolder    string         // Folder for the spill file (empty if spilling is disabled)
+	spillThreshold int            // Estimated size (bytes) of the in-memory paths before spilling
+	memoryUsed     int            // Estimated size (bytes) of the in-memory paths
+	omitted        map[string]int // Pair of entities to the number of paths omitted by sampling
+	pathsAdded     int            // Number of paths added with AddPaths (for the limit on paths)
+	pairPaths      map[string]int // Pair of entities to the number of paths added with AddPaths
 }
 
 // NewNetworkConnections struct given a maximum number of hops from source to destination.
@@ -84,6 +262,94 @@ func NewNetworkConnections(maxHops int) (*NetworkConnections, error) {
 	}, nil
 }
 
+// EnableSpill allows the paths to be moved to a file in the folder once their estimated size in
+// memory exceeds the threshold (in bytes).
+func (n *NetworkConnections) EnableSpill(folder string, threshold int) error {
+
+	// Precondition
+	if threshold < 1 {
+		return ErrInvalidSpillThreshold
+	}
+
+	n.spillFolder = folder
+	n.spillThreshold = threshold
+	return nil
+}
+
+// IsSpilled returns true if the paths are held on disk.
+func (n *NetworkConnections) IsSpilled() bool {
+	return n.spill != nil
+}
+
+// estimatePathsSize returns the approximate number of bytes used to hold the paths in memory.
+func estimatePathsSize(paths []Path) int {
+	size := 0
+
+	for _, path := range paths {
+		size += 24 // Slice header
+		for _, entityId := range path.Route {
+			size += 16 + len(entityId) // String header and content
+		}
+	}
+
+	return size
+}
+
+// spillToDisk moves the in-memory paths to a spill file.
+func (n *NetworkConnections) spillToDisk() error {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("estimatedBytes", strconv.Itoa(n.memoryUsed)).
+		Str("threshold", strconv.Itoa(n.spillThreshold)).
+		Msg("Spilling paths to disk")
+
+	spill, err := NewPathSpill(n.spillFolder)
+	if err != nil {
+		return err
+	}
+
+	for src, destinations := range n.Connections {
+		for dst, paths := range destinations {
+			if err := spill.Append(src, dst, paths); err != nil {
+				spill.Close()
+				return err
+			}
+		}
+	}
+
+	// Release the in-memory paths, keeping the record of the connection
+	for src, destinations := range n.Connections {
+		for dst := range destinations {
+			n.Connections[src][dst] = nil
+		}
+	}
+
+	n.spill = spill
+	n.memoryUsed = 0
+	return nil
+}
+
+// Paths from the source to the destination entity, which may be read from disk.
+func (n *NetworkConnections) Paths(src string, dst string) ([]Path, error) {
+	if n.spill != nil && n.spill.Has(src, dst) {
+		return n.spill.Paths(src, dst)
+	}
+
+	return n.Connections[src][dst], nil
+}
+
+// Close the network connections, deleting the spill file if there is one.
+func (n *NetworkConnections) Close() error {
+	if n.spill == nil {
+		return nil
+	}
+
+	err := n.spill.Close()
+	n.spill = nil
+	return err
+}
+
 // hasDirectedConnection returns true if there is a directed connection from entity1 to entity2.
 func (n *NetworkConnections) hasDirectedConnection(entity1 string, entity2 string) bool {
 
@@ -98,11 +364,83 @@ func (n *NetworkConnections) hasDirectedConnection(entity1 string, entity2 strin
 	return found
 }
 
+// AddOmittedPaths records the number of paths from entity1 to entity2 that were omitted by
+// sampling.
+func (n *NetworkConnections) AddOmittedPaths(entity1 string, entity2 string, omitted int) {
+	if omitted < 1 {
+		return
+	}
+
+	if n.omitted == nil {
+		n.omitted = map[string]int{}
+	}
+
+	n.omitted[pairKey(entity1, entity2)] += omitted
+}
+
+// OmittedPaths returns the number of paths from entity1 to entity2 that were omitted by sampling.
+func (n *NetworkConnections) OmittedPaths(entity1 string, entity2 string) int {
+	return n.omitted[pairKey(entity1, entity2)]
+}
+
+// NumberOfOmittedPaths returns the total number of paths omitted by sampling.
+func (n *NetworkConnections) NumberOfOmittedPaths() int {
+	total := 0
+	for _, omitted := range n.omitted {
+		total += omitted
+	}
+	return total
+}
+
 // HasAnyConnections returns true if there are any connections.
 func (n *NetworkConnections) HasAnyConnections() bool {
 	return len(n.Connections) > 0
 }
 
+// NumberOfPaths returns the total number of paths between entities.
+func (n *NetworkConnections) NumberOfPaths() int {
+	total := 0
+
+	for _, destinations := range n.Connections {
+		for _, paths := range destinations {
+			total += len(paths)
+		}
+	}
+
+	if n.spill != nil {
+		total += n.spill.NumberOfPaths()
+	}
+
+	return total
+}
+
+// Statistics of the paths, i.e. the shortest distance and number of paths for each pair of
+// entities and a histogram of the number of hops of the paths. Paths that are held on disk are read
+// back.
+func (n *NetworkConnections) Statistics() (*job.PathStatistics, error) {
+
+	statistics := job.NewPathStatistics(n.MaxHops)
+
+	for src, destinations := range n.Connections {
+		for dst := range destinations {
+			paths, err := n.Paths(src, dst)
+			if err != nil {
+				return nil, err
+			}
+
+			hops := make([]int, 0, len(paths))
+			for _, path := range paths {
+				hops = append(hops, len(path.Route)-1)
+			}
+
+			statistics.AddPair(src, dst, hops, n.OmittedPaths(src, dst))
+		}
+	}
+
+	statistics.Sort()
+	return statistics, nil
+}
+
 // HasConnection returns true if entity1 and entity2 are connected by a (calculated) path.
 func (n *NetworkConnections) HasConnection(entity1 string, entity2 string) (bool, error) {
 
@@ -275,15 +613,38 @@ func (n *NetworkConnections) AddPaths(entity1 string, entity1Set string,
 		n.Connections[entity1] = map[string][]Path{}
 	}
 
+	// Keep a running total of the paths so the limit on paths can be checked cheaply. The paths
+	// replace any paths added for the pair, which may have been spilled, so the number of paths of
+	// each pair is recorded separately from the in-memory paths
+	if n.pairPaths == nil {
+		n.pairPaths = map[string]int{}
+	}
+
+	key := pairKey(entity1, entity2)
+	n.pathsAdded += len(paths) - n.pairPaths[key]
+	n.pairPaths[key] = len(paths)
+
+	// If the paths have already been spilled, then write the paths to disk
+	if n.spill != nil {
+		n.Connections[entity1][entity2] = nil
+		return n.spill.Append(entity1, entity2, paths)
+	}
+
 	// Add the connections
 	n.Connections[entity1][entity2] = paths
+	n.memoryUsed += estimatePathsSize(paths)
+
+	// Spill the paths to disk if they are using too much memory
+	if len(n.spillFolder) > 0 && n.memoryUsed > n.spillThreshold {
+		return n.spillToDisk()
+	}
 
 	return nil
 }
 
 // findAllPathsWithResilience to (potentially missing) root and goal vertices.
-func (p *PathFinder) findAllPathsWithResilience(root string, goal string,
-	maxHops int) ([]Path, error) {
+func (p *PathFinder) findAllPathsWithResilience(ctx context.Context, graph graphstore.UnipartiteGraphStore,
+	root string, goal string, maxHops int, constraints PathConstraints) ([]Path, error) {
 
 	// Preconditions
 	if len(root) == 0 {
@@ -299,7 +660,7 @@ func (p *PathFinder) findAllPathsWithResilience(root string, goal string,
 	}
 
 	// Find all paths between the root and the goal entities
-	paths, err := AllPaths(p.graph, root, goal, maxHops)
+	paths, err := AllPathsWithConstraints(ctx, graph, root, goal, maxHops, constraints)
 
 	// If there are no errors, then just return
 	if err == nil {
@@ -315,10 +676,41 @@ func (p *PathFinder) findAllPathsWithResilience(root string, goal string,
 	return paths, err
 }
 
+// PathsBetween two entities given a maximum number of hops, where the search is abandoned with the
+// context's error if the context is cancelled (e.g. because of a timeout). In directed mode, only
+// the paths from the root to the goal are found. If either entity isn't in the graph, there are no
+// paths. ErrTooManyPaths is returned if more paths are found than the maximum number of paths.
+// There aren't any paths to or through a withdrawn entity.
+func (p *PathFinder) PathsBetween(ctx context.Context, root string, goal string, maxHops int,
+	directed bool) ([]Path, error) {
+
+	if p.tombstones.IsWithdrawn(root) || p.tombstones.IsWithdrawn(goal) {
+		return nil, nil
+	}
+
+	paths, err := p.findAllPathsWithResilience(ctx, p.graph, root, goal, maxHops,
+		PathConstraints{Directed: directed, Excluded: p.tombstones.WithdrawnIds()})
+	if err != nil {
+		return nil, err
+	}
+
+	if p.maxPaths > 0 && len(paths) > p.maxPaths {
+		return nil, ErrTooManyPaths
+	}
+
+	return paths, nil
+}
+
 // pathsBetweenEntitySets returns all paths between two sets of entities given a maximum number of
-// hops. The connection between an entity and itself is ignored.
-func (p *PathFinder) pathsBetweenEntitySets(entitySet1 job.EntitySet, entitySet2 job.EntitySet,
-	connections *NetworkConnections) error {
+// hops. The connection between an entity and itself is ignored. In directed mode, only the paths
+// from the entities in the first set to the entities in the second set are found. The paths meet
+// the constraints. The pairs of entities already searched according to the checkpoint (which may be
+// nil) are skipped. The search is abandoned with the context's error if the context is cancelled.
+// The expansion of the search for each pair is recorded in the explanation (which may be nil) and
+// is limited by the budget (which is unlimited if nil).
+func (p *PathFinder) pathsBetweenEntitySets(ctx context.Context, entitySet1 job.EntitySet, entitySet2 job.EntitySet,
+	connections *NetworkConnections, constraints PathConstraints, logger zerolog.Logger,
+	checkpoint *Checkpoint, explanation *Explanation, budget *expansionBudget) error {
 
 	// Preconditions
 	if connections == nil {
@@ -349,11 +741,17 @@ func (p *PathFinder) pathsBetweenEntitySets(entitySet1 job.EntitySet, entitySet2
 				continue
 			}
 
-			// Skip finding paths that have already been found
-			found, err := connections.HasConnection(entityId1, entityId2)
+			// Skip finding paths that have already been found (a path in the opposite direction
+			// doesn't count in directed mode)
+			found := connections.hasDirectedConnection(entityId1, entityId2)
 
-			if err != nil {
-				return err
+			if !constraints.Directed {
+				var err error
+				found, err = connections.HasConnection(entityId1, entityId2)
+
+				if err != nil {
+					return err
+				}
 			}
 
 			if found {
@@ -362,18 +760,56 @@ func (p *PathFinder) pathsBetweenEntitySets(entitySet1 job.EntitySet, entitySet2
 				continue
 			}
 
+			// Skip the pairs searched before the checkpoint (in either order unless directed)
+			if checkpoint.hasSearched(entityId1, entityId2) ||
+				(!constraints.Directed && checkpoint.hasSearched(entityId2, entityId1)) {
+				continue
+			}
+
+			// Stop before searching the pair if the search has been cancelled
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
 			// Find all paths between entities
-			paths, err := p.findAllPathsWithResilience(entityId1, entityId2, connections.MaxHops)
+			startTime := time.Now()
+			var counter *expansionCounter
+			graph := wrapSearched(p.graph, func(searched graphstore.UnipartiteGraphStore) graphstore.UnipartiteGraphStore {
+				wrapped, c := explanation.counter(budget.graph(searched))
+				counter = c
+				return wrapped
+			})
+			paths, err := p.findAllPathsWithResilience(ctx, graph, entityId1, entityId2, connections.MaxHops,
+				constraints)
+			explanation.record(entityId1, entityId2, connections.MaxHops, counter, len(paths),
+				time.Since(startTime), err != nil)
 
 			if err != nil {
 				return err
 			}
+			checkpoint.markSearched(entityId1, entityId2)
+
+			logger.Debug().
+				Str(logging.ComponentField, componentName).
+				Str("entityId1", entityId1).
+				Str("entityId2", entityId2).
+				Int("numberOfHops", connections.MaxHops).
+				Int("numberOfPaths", len(paths)).
+				Str("timeTaken", time.Since(startTime).String()).
+				Msg("Searched for paths between entities")
 
 			if len(paths) > 0 {
+				paths, omitted := SamplePaths(paths, entityId1, entityId2, p.sampling)
 				err := connections.AddPaths(entityId1, entitySet1.Name, entityId2, entitySet2.Name, paths)
 				if err != nil {
 					return err
 				}
+				connections.AddOmittedPaths(entityId1, entityId2, omitted)
+
+				// Abandon the search if the number of paths has exploded
+				if p.maxPaths > 0 && connections.pathsAdded > p.maxPaths {
+					return ErrTooManyPaths
+				}
 			}
 		}
 	}
@@ -383,8 +819,9 @@ func (p *PathFinder) pathsBetweenEntitySets(entitySet1 job.EntitySet, entitySet2
 
 // pathsBetweenAllEntitySets finds the paths (within a given number of hops) between entities
 // in the provided sets.
-func (p *PathFinder) pathsBetweenAllEntitySets(entitySets []job.EntitySet,
-	connections *NetworkConnections) error {
+func (p *PathFinder) pathsBetweenAllEntitySets(ctx context.Context, entitySets []job.EntitySet,
+	connections *NetworkConnections, constraints PathConstraints, logger zerolog.Logger,
+	checkpoint *Checkpoint, explanation *Explanation, budget *expansionBudget) error {
 
 	// Preconditions
 	if entitySets == nil {
@@ -399,17 +836,19 @@ func (p *PathFinder) pathsBetweenAllEntitySets(entitySets []job.EntitySet,
 		return ErrNetworkConnectionsIsNil
 	}
 
-	// Walk through all distinct pairs of entity sets
+	// Walk through all distinct pairs of entity sets (in both orders in directed mode)
 	for entitySet1Index := range entitySets {
 		for entitySet2Index := range entitySets {
 
-			if entitySet2Index <= entitySet1Index {
+			if entitySet2Index == entitySet1Index ||
+				(!constraints.Directed && entitySet2Index < entitySet1Index) {
 				continue
 			}
 
 			// Find the paths between the two entity sets
-			err := p.pathsBetweenEntitySets(entitySets[entitySet1Index],
-				entitySets[entitySet2Index], connections)
+			err := p.pathsBetweenEntitySets(ctx, entitySets[entitySet1Index],
+				entitySets[entitySet2Index], connections, constraints, logger, checkpoint, explanation,
+				budget)
 
 			if err != nil {
 				return err
@@ -423,6 +862,88 @@ func (p *PathFinder) pathsBetweenAllEntitySets(entitySets []job.EntitySet,
 // FindPaths between the entities defined in the sets.
 func (p *PathFinder) FindPaths(entitySets []job.EntitySet, maxHops int) (
 	*NetworkConnections, error) {
+	return p.FindPathsWithLogger(entitySets, maxHops, logging.Logger.Level(zerolog.InfoLevel))
+}
+
+// FindPathsWithLogger finds the paths between the entities defined in the sets, logging the
+// details of the search for each pair of entities at debug level to the logger.
+func (p *PathFinder) FindPathsWithLogger(entitySets []job.EntitySet, maxHops int,
+	logger zerolog.Logger) (*NetworkConnections, error) {
+	return p.findPaths(context.Background(), entitySets, maxHops, PathConstraints{}, logger, nil, nil)
+}
+
+// FindDirectedPaths between the entities defined in the sets, only following edges in their
+// direction.
+func (p *PathFinder) FindDirectedPaths(entitySets []job.EntitySet, maxHops int) (
+	*NetworkConnections, error) {
+	return p.FindDirectedPathsWithLogger(entitySets, maxHops, logging.Logger.Level(zerolog.InfoLevel))
+}
+
+// FindDirectedPathsWithLogger finds the paths between the entities defined in the sets, only
+// following edges in their direction, and logs the details of the search to the logger.
+func (p *PathFinder) FindDirectedPathsWithLogger(entitySets []job.EntitySet, maxHops int,
+	logger zerolog.Logger) (*NetworkConnections, error) {
+	return p.findPaths(context.Background(), entitySets, maxHops, PathConstraints{Directed: true},
+		logger, nil, nil)
+}
+
+// FindPathsAvoiding finds the paths between the entities defined in the sets that don't pass
+// through any of the excluded entities, optionally in directed mode. The excluded entities are
+// only blocked for this query; the graph isn't modified.
+func (p *PathFinder) FindPathsAvoiding(entitySets []job.EntitySet, maxHops int, directed bool,
+	excluded *set.Set[string], logger zerolog.Logger) (*NetworkConnections, error) {
+	return p.findPaths(context.Background(), entitySets, maxHops,
+		PathConstraints{Directed: directed, Excluded: excluded}, logger, nil, nil)
+}
+
+// FindPathsWithConstraints finds the paths between the entities defined in the sets that meet the
+// constraints, e.g. only paths that pass through a waypoint.
+func (p *PathFinder) FindPathsWithConstraints(entitySets []job.EntitySet, maxHops int,
+	constraints PathConstraints, logger zerolog.Logger) (*NetworkConnections, error) {
+	return p.findPaths(context.Background(), entitySets, maxHops, constraints, logger, nil, nil)
+}
+
+// FindPathsWithCheckpoint finds the paths between the entities defined in the sets that meet the
+// constraints, recording its progress in the checkpoint. If the search fails, the checkpoint holds
+// the paths found so far and the search can be resumed by calling this method again with the same
+// checkpoint. The caller is responsible for closing the checkpoint.
+func (p *PathFinder) FindPathsWithCheckpoint(entitySets []job.EntitySet, maxHops int,
+	constraints PathConstraints, logger zerolog.Logger, checkpoint *Checkpoint) (
+	*NetworkConnections, error) {
+
+	// Precondition
+	if checkpoint == nil {
+		return nil, ErrCheckpointIsNil
+	}
+
+	return p.findPaths(context.Background(), entitySets, maxHops, constraints, logger, checkpoint,
+		nil)
+}
+
+// FindPathsWithContext finds the paths between the entities defined in the sets that meet the
+// constraints, where the search is abandoned with the context's error if the context is cancelled
+// (e.g. because the job has timed out). The checkpoint is optional, but without one the pairs of
+// entities searched before the cancellation aren't known.
+func (p *PathFinder) FindPathsWithContext(ctx context.Context, entitySets []job.EntitySet,
+	maxHops int, constraints PathConstraints, logger zerolog.Logger, checkpoint *Checkpoint) (
+	*NetworkConnections, error) {
+	return p.findPaths(ctx, entitySets, maxHops, constraints, logger, checkpoint, nil)
+}
+
+// FindPathsWithExplanation finds the paths between the entities defined in the sets in the same way
+// as FindPathsWithContext and records the expansion of the search for each pair of entities in the
+// explanation.
+func (p *PathFinder) FindPathsWithExplanation(ctx context.Context, entitySets []job.EntitySet,
+	maxHops int, constraints PathConstraints, logger zerolog.Logger, checkpoint *Checkpoint,
+	explanation *Explanation) (*NetworkConnections, error) {
+	return p.findPaths(ctx, entitySets, maxHops, constraints, logger, checkpoint, explanation)
+}
+
+// findPaths between the entities defined in the sets that meet the constraints, resuming from the
+// checkpoint if there is one.
+func (p *PathFinder) findPaths(ctx context.Context, entitySets []job.EntitySet, maxHops int,
+	constraints PathConstraints, logger zerolog.Logger, checkpoint *Checkpoint,
+	explanation *Explanation) (*NetworkConnections, error) {
 
 	// Preconditions
 	if entitySets == nil {
@@ -447,6 +968,9 @@ func (p *PathFinder) FindPaths(entitySets []job.EntitySet, maxHops int) (
 		return nil, ErrInvalidHops
 	}
 
+	// Withdrawn entities aren't searched between or through
+	entitySets, constraints = p.excludeWithdrawn(entitySets, constraints)
+
 	// Log the datasets
 	datasets := []string{}
 	for _, entitySet := range entitySets {
@@ -458,6 +982,11 @@ func (p *PathFinder) FindPaths(entitySets []job.EntitySet, maxHops int) (
 		Str("numberOfHops", strconv.Itoa(maxHops)).
 		Str("numberOfDatasets", strconv.Itoa(len(entitySets))).
 		Strs("datasets", datasets).
+		Bool("directed", constraints.Directed).
+		Int("numberOfExcludedEntities", setLen(constraints.Excluded)).
+		Int("numberOfWaypoints", setLen(constraints.Waypoints)).
+		Int("minDocumentsPerLink", constraints.MinDocuments).
+		Bool("temporal", constraints.Temporal).
 		Msg("Finding paths")
 
 	// New struct to hold the network connections between entities
@@ -466,15 +995,46 @@ func (p *PathFinder) FindPaths(entitySets []job.EntitySet, maxHops int) (
 		return nil, err
 	}
 
+	if len(p.spillFolder) > 0 {
+		if err := connections.EnableSpill(p.spillFolder, p.spillThreshold); err != nil {
+			return nil, err
+		}
+	}
+
+	// Carry on from the paths found before the checkpoint
+	if checkpoint != nil && checkpoint.resume(maxHops, connections) {
+		connections.Close()
+		connections = checkpoint.Connections()
+	}
+
 	// If there is only one entity set, then find the paths between those entities, otherwise
 	// find the paths between pairs of entity sets
+	budget := newExpansionBudget(p.maxExpansions)
 	if len(entitySets) == 1 {
-		err = p.pathsBetweenEntitySets(entitySets[0], entitySets[0], connections)
+		err = p.pathsBetweenEntitySets(ctx, entitySets[0], entitySets[0], connections, constraints,
+			logger, checkpoint, explanation, budget)
 	} else {
-		err = p.pathsBetweenAllEntitySets(entitySets, connections)
+		err = p.pathsBetweenAllEntitySets(ctx, entitySets, connections, constraints, logger,
+			checkpoint, explanation, budget)
+	}
+
+	// The paths found before the budget was exhausted are returned
+	if errors.Is(err, ErrBudgetExhausted) {
+		logging.Logger.Warn().
+			Str(logging.ComponentField, componentName).
+			Int("maxExpansions", p.maxExpansions).
+			Int("numberOfPaths", connections.NumberOfPaths()).
+			Msg("Budget of vertex expansions exhausted, returning the paths found so far")
+
+		connections.BudgetExhausted = true
+		return connections, nil
 	}
 
+	// The paths found so far are kept by the checkpoint
 	if err != nil {
+		if checkpoint == nil {
+			connections.Close()
+		}
 		return nil, err
 	}
 
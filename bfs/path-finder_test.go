@@ -1,11 +1,14 @@
 package bfs
 
 import (
+	"context"
 	"testing"
 
 	"github.com/cdclaxton/shortest-path-web-app/graphstore"
 	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
 	"github.com/cdclaxton/shortest-path-web-app/set"
+	"github.com/cdclaxton/shortest-path-web-app/tombstone"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -43,6 +46,54 @@ func TestNetworkConnections(t *testing.T) {
 	}
 
 	assert.True(t, expected.Equal(n))
+
+	// The running total of the paths matches the number of paths
+	assert.Equal(t, 4, n.pathsAdded)
+	assert.Equal(t, n.NumberOfPaths(), n.pathsAdded)
+}
+
+func TestNetworkConnectionsRunningTotalWithSpill(t *testing.T) {
+
+	n, err := NewNetworkConnections(2)
+	assert.NoError(t, err)
+	defer n.Close()
+	assert.NoError(t, n.EnableSpill(t.TempDir(), 1))
+
+	// The paths are spilled once they are added
+	assert.NoError(t, n.AddPaths("A", "set-A", "B", "set-B",
+		[]Path{NewPath("A", "B"), NewPath("A", "C", "B")}))
+	assert.NoError(t, n.AddPaths("A", "set-A", "C", "set-C", []Path{NewPath("A", "C")}))
+	assert.Equal(t, 3, n.pathsAdded)
+
+	// Adding the paths of a spilled pair again replaces them
+	assert.NoError(t, n.AddPaths("A", "set-A", "B", "set-B", []Path{NewPath("A", "B")}))
+	assert.Equal(t, 2, n.pathsAdded)
+	assert.Equal(t, n.NumberOfPaths(), n.pathsAdded)
+}
+
+func TestNetworkConnectionsStatistics(t *testing.T) {
+
+	n, err := NewNetworkConnections(2)
+	assert.NoError(t, err)
+
+	statistics, err := n.Statistics()
+	assert.NoError(t, err)
+	assert.Equal(t, job.NewPathStatistics(2), statistics)
+
+	n.AddPaths("E", "set-E", "B", "set-B", []Path{NewPath("E", "B"), NewPath("E", "A", "B")})
+	n.AddPaths("A", "set-A", "C", "set-C", []Path{NewPath("A", "D", "C")})
+
+	statistics, err = n.Statistics()
+	assert.NoError(t, err)
+
+	expected := &job.PathStatistics{
+		Pairs: []job.PairStatistics{
+			{Source: "A", Destination: "C", ShortestDistance: 2, NumberOfPaths: 1},
+			{Source: "E", Destination: "B", ShortestDistance: 1, NumberOfPaths: 2},
+		},
+		HopHistogram: []int{1, 2},
+	}
+	assert.Equal(t, expected, statistics)
 }
 
 // Test findAllPathsWithResilience() using the graph:
@@ -101,13 +152,43 @@ func TestFindAllPathsWithResilience(t *testing.T) {
 	}
 
 	for _, testCase := range testCases {
-		actualPaths, err := pathFinder.findAllPathsWithResilience(testCase.root, testCase.goal,
-			testCase.maxHops)
+		actualPaths, err := pathFinder.findAllPathsWithResilience(context.Background(), graph, testCase.root,
+			testCase.goal, testCase.maxHops, PathConstraints{})
 		assert.NoError(t, err)
 		assert.True(t, PathsEqual(testCase.expectedPaths, actualPaths))
 	}
 }
 
+func TestPathsBetween(t *testing.T) {
+
+	graph := graphstore.NewInMemoryUnipartiteGraphStore()
+	buildTestGraph(t, graph)
+
+	pathFinder, err := NewPathFinder(graph)
+	assert.NoError(t, err)
+
+	// Paths between two entities
+	paths, err := pathFinder.PathsBetween(context.Background(), "1", "3", 2, false)
+	assert.NoError(t, err)
+	assert.True(t, PathsEqual([]Path{NewPath("1", "2", "3")}, paths))
+
+	// A missing entity doesn't have any paths
+	paths, err = pathFinder.PathsBetween(context.Background(), "1", "100", 2, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(paths))
+
+	// Too many paths
+	assert.NoError(t, pathFinder.SetMaxPaths(1))
+	_, err = pathFinder.PathsBetween(context.Background(), "1", "5", 4, false)
+	assert.ErrorIs(t, err, ErrTooManyPaths)
+
+	// The search is cancelled
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = pathFinder.PathsBetween(ctx, "1", "3", 2, false)
+	assert.ErrorIs(t, err, context.Canceled)
+}
+
 // Test pathsBetweenEntitySets() using the graph:
 //
 //   1 --- 2 --- 3                   6 (isolated node)
@@ -145,7 +226,8 @@ func TestPathsBetweenEntitySets(t *testing.T) {
 	actualConnections, err := NewNetworkConnections(3)
 	assert.NoError(t, err)
 
-	err = pathFinder.pathsBetweenEntitySets(entitySet1, entitySet2, actualConnections)
+	err = pathFinder.pathsBetweenEntitySets(context.Background(), entitySet1, entitySet2, actualConnections,
+		PathConstraints{}, logging.Logger, nil, nil, nil)
 	assert.NoError(t, err)
 
 	// Check the connections
@@ -225,7 +307,8 @@ func TestPathsBetweenAllEntitySets(t *testing.T) {
 	actualConnections, err := NewNetworkConnections(3)
 	assert.NoError(t, err)
 
-	err = pathFinder.pathsBetweenAllEntitySets(entitySets, actualConnections)
+	err = pathFinder.pathsBetweenAllEntitySets(context.Background(), entitySets, actualConnections, PathConstraints{},
+		logging.Logger, nil, nil, nil)
 	assert.NoError(t, err)
 
 	// Check the connections
@@ -262,6 +345,38 @@ func TestPathsBetweenAllEntitySets(t *testing.T) {
 	assert.True(t, expectedConnections.Equal(actualConnections))
 }
 
+func TestFindPathsTooManyPaths(t *testing.T) {
+
+	// Construct the unipartite graph
+	graph := graphstore.NewInMemoryUnipartiteGraphStore()
+	buildTestGraph(t, graph)
+
+	// Construct a new path finder component
+	pathFinder, err := NewPathFinder(graph)
+	assert.NoError(t, err)
+
+	// Invalid maximum number of paths
+	assert.ErrorIs(t, pathFinder.SetMaxPaths(-1), ErrInvalidMaxPaths)
+	assert.NoError(t, pathFinder.SetMaxPaths(2))
+
+	entitySets := []job.EntitySet{
+		{
+			EntityIds: []string{"1", "3", "9", "10", "A"},
+			Name:      "Set-1",
+		},
+	}
+
+	// Three paths are found with 3 hops, which exceeds the limit
+	conns, err := pathFinder.FindPaths(entitySets, 3)
+	assert.ErrorIs(t, err, ErrTooManyPaths)
+	assert.Nil(t, conns)
+
+	// Two paths are found with 2 hops
+	conns, err = pathFinder.FindPaths(entitySets, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, conns.NumberOfPaths())
+}
+
 // Test FindPaths() using the graph:
 //
 //   1 --- 2 --- 3                   6 (isolated node)
@@ -430,3 +545,191 @@ func TestFindPathsTwoEntitySets(t *testing.T) {
 
 	assert.True(t, expectedConnections.Equal(actualConnections))
 }
+
+// Test FindDirectedPaths() using the graph:
+//
+//	a --> b --> c --- d
+func TestFindDirectedPaths(t *testing.T) {
+
+	graph := graphstore.NewInMemoryUnipartiteGraphStore()
+	assert.NoError(t, graph.AddDirected("a", "b"))
+	assert.NoError(t, graph.AddDirected("b", "c"))
+	assert.NoError(t, graph.AddUndirected("c", "d"))
+
+	pathFinder, err := NewPathFinder(graph)
+	assert.NoError(t, err)
+
+	entitySets := []job.EntitySet{
+		{
+			EntityIds: []string{"a", "d"},
+			Name:      "Set-1",
+		},
+		{
+			EntityIds: []string{"c"},
+			Name:      "Set-2",
+		},
+	}
+
+	// Only the paths that follow the direction of the edges are found
+	conns, err := pathFinder.FindDirectedPaths(entitySets, 3)
+	assert.NoError(t, err)
+
+	expected := map[string]map[string][]Path{
+		"a": {
+			"c": []Path{NewPath("a", "b", "c")},
+		},
+		"c": {
+			"d": []Path{NewPath("c", "d")},
+		},
+		"d": {
+			"c": []Path{NewPath("d", "c")},
+		},
+	}
+	assert.True(t, connectionsEqual(expected, conns.Connections))
+
+	// The direction of the edges is ignored in undirected mode
+	conns, err = pathFinder.FindPaths(entitySets, 3)
+	assert.NoError(t, err)
+
+	expected = map[string]map[string][]Path{
+		"a": {
+			"c": []Path{NewPath("a", "b", "c")},
+		},
+		"d": {
+			"c": []Path{NewPath("d", "c")},
+		},
+	}
+	assert.True(t, connectionsEqual(expected, conns.Connections))
+
+	// A path against the direction of the edges is only found in undirected mode
+	paths, err := AllDirectedPaths(graph, "c", "a", 3)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(paths))
+
+	paths, err = AllPaths(graph, "c", "a", 3)
+	assert.NoError(t, err)
+	assert.True(t, PathsEqual([]Path{NewPath("c", "b", "a")}, paths))
+}
+
+func TestFindPathsAvoiding(t *testing.T) {
+
+	// Graph with a hub (h) and a longer route around it:
+	//
+	//   a -- h -- c
+	//   |         |
+	//   x -- y -- z
+	graph := graphstore.NewInMemoryUnipartiteGraphStore()
+	assert.NoError(t, graphstore.BuildFromEdgeList(graph, []graphstore.Edge{
+		{V1: "a", V2: "h"},
+		{V1: "h", V2: "c"},
+		{V1: "a", V2: "x"},
+		{V1: "x", V2: "y"},
+		{V1: "y", V2: "z"},
+		{V1: "z", V2: "c"},
+	}))
+
+	pathFinder, err := NewPathFinder(graph)
+	assert.NoError(t, err)
+
+	entitySets := []job.EntitySet{
+		{
+			EntityIds: []string{"a", "c", "h"},
+			Name:      "Set-1",
+		},
+	}
+
+	// Without exclusions, one of the paths goes through the hub
+	conns, err := pathFinder.FindPathsAvoiding(entitySets, 4, false, nil, logging.Logger)
+	assert.NoError(t, err)
+	paths, err := conns.Paths("a", "c")
+	assert.NoError(t, err)
+	assert.True(t, PathsEqual([]Path{NewPath("a", "h", "c"), NewPath("a", "x", "y", "z", "c")},
+		paths))
+
+	// Excluding the hub gives the path around it, but the hub can still be an end of a path
+	conns, err = pathFinder.FindPathsAvoiding(entitySets, 4, false, set.NewPopulatedSet("h"),
+		logging.Logger)
+	assert.NoError(t, err)
+
+	expected := map[string]map[string][]Path{
+		"a": {
+			"c": []Path{NewPath("a", "x", "y", "z", "c")},
+			"h": []Path{NewPath("a", "h")},
+		},
+		"c": {
+			"h": []Path{NewPath("c", "h")},
+		},
+	}
+	assert.True(t, connectionsEqual(expected, conns.Connections))
+
+	// The graph isn't modified
+	paths, err = AllPaths(graph, "a", "c", 2)
+	assert.NoError(t, err)
+	assert.True(t, PathsEqual([]Path{NewPath("a", "h", "c")}, paths))
+}
+
+func TestFindPathsWithoutWithdrawn(t *testing.T) {
+
+	// Graph with a hub (h) and a longer route around it:
+	//
+	//   a -- h -- c
+	//   |         |
+	//   x -- y -- z
+	graph := graphstore.NewInMemoryUnipartiteGraphStore()
+	assert.NoError(t, graphstore.BuildFromEdgeList(graph, []graphstore.Edge{
+		{V1: "a", V2: "h"},
+		{V1: "h", V2: "c"},
+		{V1: "a", V2: "x"},
+		{V1: "x", V2: "y"},
+		{V1: "y", V2: "z"},
+		{V1: "z", V2: "c"},
+	}))
+
+	pathFinder, err := NewPathFinder(graph)
+	assert.NoError(t, err)
+
+	tombstones, err := tombstone.NewTombstoneStore(t.TempDir())
+	assert.NoError(t, err)
+	defer tombstones.Close()
+	pathFinder.SetTombstones(tombstones)
+
+	_, err = tombstones.Withdraw("h", "Source record retracted")
+	assert.NoError(t, err)
+
+	entitySets := []job.EntitySet{
+		{
+			EntityIds: []string{"a", "c", "h"},
+			Name:      "Set-1",
+		},
+	}
+
+	// The withdrawn hub is neither an end of a path nor on a path
+	conns, err := pathFinder.FindPaths(entitySets, 4)
+	assert.NoError(t, err)
+
+	expected := map[string]map[string][]Path{
+		"a": {
+			"c": []Path{NewPath("a", "x", "y", "z", "c")},
+		},
+	}
+	assert.True(t, connectionsEqual(expected, conns.Connections))
+
+	// The entity sets aren't modified
+	assert.Equal(t, []string{"a", "c", "h"}, entitySets[0].EntityIds)
+
+	paths, err := pathFinder.PathsBetween(context.Background(), "a", "c", 4, false)
+	assert.NoError(t, err)
+	assert.True(t, PathsEqual([]Path{NewPath("a", "x", "y", "z", "c")}, paths))
+
+	paths, err = pathFinder.PathsBetween(context.Background(), "a", "h", 4, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(paths))
+
+	// Reinstating the hub restores its paths
+	_, err = tombstones.Reinstate("h", "Record restored")
+	assert.NoError(t, err)
+
+	paths, err = pathFinder.PathsBetween(context.Background(), "a", "c", 2, false)
+	assert.NoError(t, err)
+	assert.True(t, PathsEqual([]Path{NewPath("a", "h", "c")}, paths))
+}
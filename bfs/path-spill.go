@@ -0,0 +1,218 @@
+// A PathSpill holds paths on disk for network connections that are too large to hold in memory.
+//
+// The paths are written to an append-only file. Each call to Append writes one record per path,
+// where a record is the number of entities on the path followed by each entity ID (prefixed by
+// its length). All of the numbers are unsigned varints. An in-memory index maps the source and
+// destination entity IDs to the location of their paths in the file, so the paths for a pair of
+// entities can be read back without reading the whole file.
+
+package bfs
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+)
+
+var (
+	ErrPathSpillIsClosed  = errors.New("path spill is closed")
+	ErrMalformedPathSpill = errors.New("malformed path spill record")
+)
+
+// pathSpillEntry locates the paths between two entities in the spill file.
+type pathSpillEntry struct {
+	offset        int64 // Offset of the first path record in the file
+	numberOfPaths int   // Number of consecutive path records
+}
+
+// PathSpill is an append-only file of paths with an index.
+type PathSpill struct {
+	file          *os.File
+	writer        *bufio.Writer
+	offset        int64                                // Offset at which the next record will be written
+	index         map[string]map[string]pathSpillEntry // Source to destination to location of paths
+	numberOfPaths int                                  // Number of (indexed) paths in the file
+}
+
+// NewPathSpill creates a new spill file in the folder.
+func NewPathSpill(folder string) (*PathSpill, error) {
+
+	file, err := os.CreateTemp(folder, "paths-*.spill")
+	if err != nil {
+		return nil, err
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("filepath", file.Name()).
+		Msg("Created path spill file")
+
+	return &PathSpill{
+		file:   file,
+		writer: bufio.NewWriter(file),
+		index:  map[string]map[string]pathSpillEntry{},
+	}, nil
+}
+
+// writeUvarint to the spill file.
+func (p *PathSpill) writeUvarint(value uint64) error {
+	buf := make([]byte, binary.MaxVarintLen64)
+	n := binary.PutUvarint(buf, value)
+
+	_, err := p.writer.Write(buf[:n])
+	p.offset += int64(n)
+	return err
+}
+
+// writePath record to the spill file.
+func (p *PathSpill) writePath(path Path) error {
+
+	if err := p.writeUvarint(uint64(len(path.Route))); err != nil {
+		return err
+	}
+
+	for _, entityId := range path.Route {
+		if err := p.writeUvarint(uint64(len(entityId))); err != nil {
+			return err
+		}
+
+		n, err := p.writer.WriteString(entityId)
+		p.offset += int64(n)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Append the paths from src to dst. Any paths previously appended for the pair are replaced.
+func (p *PathSpill) Append(src string, dst string, paths []Path) error {
+
+	// Precondition
+	if p.file == nil {
+		return ErrPathSpillIsClosed
+	}
+
+	entry := pathSpillEntry{
+		offset:        p.offset,
+		numberOfPaths: len(paths),
+	}
+
+	for _, path := range paths {
+		if err := p.writePath(path); err != nil {
+			return err
+		}
+	}
+
+	if _, found := p.index[src]; !found {
+		p.index[src] = map[string]pathSpillEntry{}
+	}
+
+	if previous, found := p.index[src][dst]; found {
+		p.numberOfPaths -= previous.numberOfPaths
+	}
+
+	p.index[src][dst] = entry
+	p.numberOfPaths += len(paths)
+
+	return nil
+}
+
+// Has returns true if there are paths from src to dst in the spill file.
+func (p *PathSpill) Has(src string, dst string) bool {
+	_, found := p.index[src][dst]
+	return found
+}
+
+// readPath record from the reader.
+func readPath(reader *bufio.Reader) (Path, error) {
+
+	numberOfEntities, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return Path{}, err
+	}
+
+	route := make([]string, numberOfEntities)
+	for idx := range route {
+		length, err := binary.ReadUvarint(reader)
+		if err != nil {
+			return Path{}, err
+		}
+
+		entityId := make([]byte, length)
+		if _, err := io.ReadFull(reader, entityId); err != nil {
+			return Path{}, ErrMalformedPathSpill
+		}
+
+		route[idx] = string(entityId)
+	}
+
+	return Path{Route: route}, nil
+}
+
+// Paths from src to dst read from the spill file. If there are no paths, nil is returned.
+func (p *PathSpill) Paths(src string, dst string) ([]Path, error) {
+
+	// Precondition
+	if p.file == nil {
+		return nil, ErrPathSpillIsClosed
+	}
+
+	entry, found := p.index[src][dst]
+	if !found {
+		return nil, nil
+	}
+
+	// Make sure all of the records have been written before reading
+	if err := p.writer.Flush(); err != nil {
+		return nil, err
+	}
+
+	reader := bufio.NewReader(io.NewSectionReader(p.file, entry.offset, p.offset-entry.offset))
+
+	paths := make([]Path, entry.numberOfPaths)
+	for idx := range paths {
+		path, err := readPath(reader)
+		if err != nil {
+			return nil, err
+		}
+		paths[idx] = path
+	}
+
+	return paths, nil
+}
+
+// NumberOfPaths held in the spill file.
+func (p *PathSpill) NumberOfPaths() int {
+	return p.numberOfPaths
+}
+
+// Close and delete the spill file.
+func (p *PathSpill) Close() error {
+
+	if p.file == nil {
+		return nil
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("filepath", p.file.Name()).
+		Str("sizeBytes", strconv.FormatInt(p.offset, 10)).
+		Msg("Deleting path spill file")
+
+	filepath := p.file.Name()
+	err := p.file.Close()
+	p.file = nil
+
+	if err != nil {
+		return err
+	}
+
+	return os.Remove(filepath)
+}
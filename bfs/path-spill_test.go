@@ -0,0 +1,100 @@
+package bfs
+
+import (
+	"os"
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPathSpill(t *testing.T) {
+	spill, err := NewPathSpill(t.TempDir())
+	assert.NoError(t, err)
+
+	paths1 := []Path{NewPath("e-1", "e-2"), NewPath("e-1", "e-3", "e-2")}
+	paths2 := []Path{NewPath("e-2", "", "entity-with-a-long-identifier")}
+
+	assert.NoError(t, spill.Append("e-1", "e-2", paths1))
+	assert.NoError(t, spill.Append("e-2", "e-4", paths2))
+	assert.Equal(t, 3, spill.NumberOfPaths())
+
+	// Read the paths back
+	actual, err := spill.Paths("e-1", "e-2")
+	assert.NoError(t, err)
+	assert.Equal(t, paths1, actual)
+
+	actual, err = spill.Paths("e-2", "e-4")
+	assert.NoError(t, err)
+	assert.Equal(t, paths2, actual)
+
+	// Paths that don't exist
+	assert.False(t, spill.Has("e-2", "e-1"))
+	actual, err = spill.Paths("e-2", "e-1")
+	assert.NoError(t, err)
+	assert.Nil(t, actual)
+
+	// Replace the paths for a pair
+	assert.NoError(t, spill.Append("e-1", "e-2", paths2))
+	assert.Equal(t, 2, spill.NumberOfPaths())
+
+	actual, err = spill.Paths("e-1", "e-2")
+	assert.NoError(t, err)
+	assert.Equal(t, paths2, actual)
+
+	// Closing the spill deletes the file
+	filepath := spill.file.Name()
+	assert.NoError(t, spill.Close())
+	_, err = os.Stat(filepath)
+	assert.True(t, os.IsNotExist(err))
+
+	_, err = spill.Paths("e-1", "e-2")
+	assert.ErrorIs(t, err, ErrPathSpillIsClosed)
+	assert.ErrorIs(t, spill.Append("e-1", "e-2", paths1), ErrPathSpillIsClosed)
+}
+
+func TestFindPathsWithSpill(t *testing.T) {
+
+	// Construct the unipartite graph
+	graph := graphstore.NewInMemoryUnipartiteGraphStore()
+	buildTestGraph(t, graph)
+
+	entitySets := []job.EntitySet{
+		{
+			EntityIds: []string{"1", "3", "9", "10", "A"},
+			Name:      "Set-1",
+		},
+	}
+
+	// Find the paths in memory
+	pathFinder, err := NewPathFinder(graph)
+	assert.NoError(t, err)
+
+	expected, err := pathFinder.FindPaths(entitySets, 3)
+	assert.NoError(t, err)
+	assert.False(t, expected.IsSpilled())
+
+	// Find the paths with a threshold that forces the paths to be spilled
+	assert.ErrorIs(t, pathFinder.SetSpill(t.TempDir(), 0), ErrInvalidSpillThreshold)
+	assert.NoError(t, pathFinder.SetSpill(t.TempDir(), 1))
+
+	actual, err := pathFinder.FindPaths(entitySets, 3)
+	assert.NoError(t, err)
+	defer actual.Close()
+
+	assert.True(t, actual.IsSpilled())
+	assert.Equal(t, expected.NumberOfPaths(), actual.NumberOfPaths())
+
+	for src, destinations := range expected.Connections {
+		for dst, expectedPaths := range destinations {
+			found, err := actual.HasConnection(src, dst)
+			assert.NoError(t, err)
+			assert.True(t, found)
+
+			paths, err := actual.Paths(src, dst)
+			assert.NoError(t, err)
+			assert.True(t, PathsEqual(expectedPaths, paths))
+		}
+	}
+}
@@ -1,9 +1,11 @@
 package bfs
 
 import (
+	"context"
 	"fmt"
 
 	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/set"
 	"github.com/golang-collections/collections/queue"
 )
 
@@ -94,12 +96,95 @@ const (
 	GoalVertexNotFoundError = "Goal vertex not found"
 )
 
-// AllPaths from a root vertex to a goal vertex up to a maximum depth.
+// AllPaths from a root vertex to a goal vertex up to a maximum depth. The direction of any
+// directed edges is ignored.
 //
 // The function assumes that the root and goal vertices are present in the graph.
 func AllPaths(graph graphstore.UnipartiteGraphStore, root string, goal string,
 	maxDepth int) ([]Path, error) {
 
+	return allPaths(context.Background(), graph, root, goal, maxDepth,
+		PathConstraints{}.steps(graph), nil, nil)
+}
+
+// AllDirectedPaths from a root vertex to a goal vertex up to a maximum depth, only following
+// edges in their direction.
+//
+// The function assumes that the root and goal vertices are present in the graph.
+func AllDirectedPaths(graph graphstore.UnipartiteGraphStore, root string, goal string,
+	maxDepth int) ([]Path, error) {
+
+	return allPaths(context.Background(), graph, root, goal, maxDepth,
+		PathConstraints{Directed: true}.steps(graph), nil, nil)
+}
+
+// AllPathsWithContext from a root vertex to a goal vertex up to a maximum depth, where the search
+// is abandoned with the context's error if the context is cancelled. If directed is true, edges
+// are only followed in their direction.
+//
+// The function assumes that the root and goal vertices are present in the graph.
+func AllPathsWithContext(ctx context.Context, graph graphstore.UnipartiteGraphStore, root string,
+	goal string, maxDepth int, directed bool) ([]Path, error) {
+
+	return AllPathsAvoiding(ctx, graph, root, goal, maxDepth, directed, nil)
+}
+
+// AllPathsAvoiding finds the paths from a root vertex to a goal vertex up to a maximum depth that
+// don't pass through any of the excluded vertices (nil for none). The root and goal can still be
+// excluded vertices, as only the intermediate vertices of a path are checked.
+//
+// The function assumes that the root and goal vertices are present in the graph.
+func AllPathsAvoiding(ctx context.Context, graph graphstore.UnipartiteGraphStore, root string,
+	goal string, maxDepth int, directed bool, excluded *set.Set[string]) ([]Path, error) {
+
+	constraints := PathConstraints{Directed: directed, Excluded: excluded}
+	return allPaths(ctx, graph, root, goal, maxDepth, constraints.steps(graph), excluded, nil)
+}
+
+// isPassable returns true if the step doesn't pass through or reach an excluded vertex (other than
+// the goal) and doesn't revisit a vertex of the node's lineage.
+func isPassable(node *TreeNode, s step, goal string, excluded *set.Set[string]) bool {
+
+	for _, entityId := range s.via {
+		if (excluded != nil && excluded.Has(entityId)) || node.onLineage(entityId) {
+			return false
+		}
+	}
+
+	if excluded != nil && s.to != goal && excluded.Has(s.to) {
+		return false
+	}
+
+	return !node.onLineage(s.to)
+}
+
+// stepUntil the goal, i.e. a step over a chain that passes through the goal ends at the goal.
+func stepUntil(s step, goal string) step {
+	for idx, entityId := range s.via {
+		if entityId == goal {
+			return step{via: s.via[:idx], to: goal}
+		}
+	}
+	return s
+}
+
+// A queued partial path from the root, i.e. its last tree node and (in temporal mode) whether its
+// links can be supported by documents in date order.
+type queued struct {
+	node     *TreeNode
+	temporal *temporalState // Nil if the search isn't temporal
+}
+
+// allPaths from a root vertex to a goal vertex up to a maximum depth, where the steps function
+// returns the steps that can be taken from a vertex and the excluded vertices (if not nil) are
+// never stepped through. A step over a collapsed chain counts the hops of the chain towards the
+// maximum depth. The context is checked before each vertex is expanded.
+//
+// If the temporal state isn't nil, only the paths whose links can be supported by documents in date
+// order are found, as a step is only taken if its links can follow those of the partial path.
+func allPaths(ctx context.Context, graph graphstore.UnipartiteGraphStore, root string, goal string, maxDepth int,
+	steps func(string) ([]step, error), excluded *set.Set[string], temporal *temporalState) ([]Path, error) {
+
 	// Preconditions
 	found, err := graph.HasEntity(root)
 	if err != nil {
@@ -121,65 +206,82 @@ func AllPaths(graph graphstore.UnipartiteGraphStore, root string, goal string,
 		return nil, fmt.Errorf("invalid maximum depth: %v", maxDepth)
 	}
 
-	// Number of steps traversed from root vertex
-	numSteps := 0
-
 	// If the root is the goal, return without traversing the graph
 	treeNode := NewTreeNode(root, root == goal)
 	if treeNode.marked {
 		return []Path{NewPath(root)}, nil
 	}
 
-	// Nodes to spider out from on the current iteration
-	qCurrent := queue.New()
-	qCurrent.Enqueue(treeNode)
-
-	// Nodes to spider out from on the next iteration
-	qNext := queue.New()
+	// Nodes to spider out from, indexed by the number of hops from the root vertex
+	levels := make([]*queue.Queue, maxDepth+1)
+	for idx := range levels {
+		levels[idx] = queue.New()
+	}
+	levels[0].Enqueue(queued{node: treeNode, temporal: temporal})
 
 	// List of complete nodes, i.e. those where the goal has been found
 	complete := []*TreeNode{}
 
-	for numSteps < maxDepth {
+	for numSteps := 0; numSteps < maxDepth; numSteps++ {
+		qCurrent := levels[numSteps]
 		for qCurrent.Len() > 0 {
 
+			// Stop if the search has been cancelled
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
 			// Take a tree node from the queue that represents a vertex
-			node := qCurrent.Dequeue().(*TreeNode)
+			entry := qCurrent.Dequeue().(queued)
+			node := entry.node
 
 			// Check the node
 			if node.marked {
 				return nil, fmt.Errorf("trying to traverse from a marked node: %v", node.name)
 			}
 
-			// Get the vertices adjacent to the node
-			w, err := graph.EntityIdsAdjacentTo(node.name)
+			// Get the steps from the node
+			nodeSteps, err := steps(node.name)
 			if err != nil {
 				return nil, err
 			}
 
-			// Walk through each of the adjacent vertices
-			for _, adjIdentifier := range w.ToSlice() {
+			// Walk through each of the steps
+			for _, s := range nodeSteps {
+				s = stepUntil(s, goal)
+
+				// Don't take a step that is too long
+				depth := numSteps + s.hops()
+				if depth > maxDepth {
+					continue
+				}
 
-				// If the adjacent vertex is a new connection for the node,
+				// If the step doesn't pass through an excluded vertex and reaches a new vertex,
 				// then add it and check whether the goal has been reached
-				if !node.ContainsParentNode(adjIdentifier) {
-					child, err := node.MakeChild(adjIdentifier, adjIdentifier == goal)
+				if !isPassable(node, s, goal, excluded) {
+					continue
+				}
+
+				// In temporal mode, don't take a step whose links can't follow those of the path
+				var childTemporal *temporalState
+				if entry.temporal != nil {
+					childTemporal, err = entry.temporal.next(graph, s.route(node.name))
 					if err != nil {
 						return nil, err
 					}
-
-					if child.marked {
-						complete = append(complete, child)
-					} else {
-						qNext.Enqueue(child)
+					if childTemporal == nil {
+						continue
 					}
 				}
+
+				child := node.makeChildVia(s.to, s.via, s.to == goal)
+				if child.marked {
+					complete = append(complete, child)
+				} else {
+					levels[depth].Enqueue(queued{node: child, temporal: childTemporal})
+				}
 			}
 		}
-
-		qCurrent = qNext
-		qNext = queue.New()
-		numSteps++
 	}
 
 	// Flatten the paths
@@ -0,0 +1,83 @@
+package bfs
+
+import (
+	"errors"
+	"hash/fnv"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// A SamplingStrategy decides which of the paths between a pair of entities are kept when there are
+// more paths than the maximum number of paths per pair.
+type SamplingStrategy string
+
+const (
+	SampleUniform  SamplingStrategy = "uniform"  // Each path is equally likely to be kept
+	SampleShortest SamplingStrategy = "shortest" // The shortest paths are kept, chosen at random from paths of the same length
+)
+
+var ErrInvalidSamplingStrategy = errors.New("invalid path sampling strategy")
+
+// PathSampling limits the number of paths kept between each pair of entities, so that the i2 chart
+// of a job with very densely connected entities stays a manageable size.
+type PathSampling struct {
+	MaxPathsPerPair int              // Maximum number of paths per pair (zero means no limit)
+	Strategy        SamplingStrategy // How the paths to keep are chosen
+}
+
+// Validate the path sampling.
+func (s PathSampling) Validate() error {
+
+	if s.MaxPathsPerPair < 0 {
+		return ErrInvalidMaxPaths
+	}
+
+	if s.Strategy != SampleUniform && s.Strategy != SampleShortest {
+		return ErrInvalidSamplingStrategy
+	}
+
+	return nil
+}
+
+// pathKey of a path for sorting.
+func pathKey(path Path) string {
+	return strings.Join(path.Route, "\x00")
+}
+
+// samplingSeed for the pair of entities, so that the same paths are always kept for a pair.
+func samplingSeed(root string, goal string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(pairKey(root, goal)))
+	return int64(h.Sum64())
+}
+
+// SamplePaths keeps at most the maximum number of paths per pair from the paths between the root
+// and the goal, returning the kept paths and the number of paths that were omitted. The sample
+// only depends on the paths and the entities, so a search always gives the same sample.
+func SamplePaths(paths []Path, root string, goal string, sampling PathSampling) ([]Path, int) {
+
+	if sampling.MaxPathsPerPair == 0 || len(paths) <= sampling.MaxPathsPerPair {
+		return paths, 0
+	}
+
+	// The paths are found in no particular order, so they are sorted before they are shuffled
+	sample := make([]Path, len(paths))
+	copy(sample, paths)
+	sort.Slice(sample, func(i, j int) bool {
+		return pathKey(sample[i]) < pathKey(sample[j])
+	})
+
+	rng := rand.New(rand.NewSource(samplingSeed(root, goal)))
+	rng.Shuffle(len(sample), func(i, j int) {
+		sample[i], sample[j] = sample[j], sample[i]
+	})
+
+	if sampling.Strategy == SampleShortest {
+		sort.SliceStable(sample, func(i, j int) bool {
+			return len(sample[i].Route) < len(sample[j].Route)
+		})
+	}
+
+	return sample[:sampling.MaxPathsPerPair], len(paths) - sampling.MaxPathsPerPair
+}
@@ -0,0 +1,113 @@
+package bfs
+
+import (
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPathSamplingValidate(t *testing.T) {
+	assert.NoError(t, PathSampling{Strategy: SampleUniform}.Validate())
+	assert.NoError(t, PathSampling{MaxPathsPerPair: 10, Strategy: SampleShortest}.Validate())
+	assert.ErrorIs(t, PathSampling{MaxPathsPerPair: -1, Strategy: SampleUniform}.Validate(),
+		ErrInvalidMaxPaths)
+	assert.ErrorIs(t, PathSampling{MaxPathsPerPair: 10}.Validate(), ErrInvalidSamplingStrategy)
+	assert.ErrorIs(t, PathSampling{Strategy: "random"}.Validate(), ErrInvalidSamplingStrategy)
+}
+
+func TestSamplePaths(t *testing.T) {
+
+	paths := []Path{
+		NewPath("s", "a", "g"),
+		NewPath("s", "b", "c", "g"),
+		NewPath("s", "g"),
+		NewPath("s", "d", "g"),
+		NewPath("s", "e", "f", "g"),
+	}
+
+	// No limit or fewer paths than the limit
+	sample, omitted := SamplePaths(paths, "s", "g", PathSampling{Strategy: SampleUniform})
+	assert.Equal(t, paths, sample)
+	assert.Equal(t, 0, omitted)
+
+	sample, omitted = SamplePaths(paths, "s", "g", PathSampling{MaxPathsPerPair: 5,
+		Strategy: SampleUniform})
+	assert.Equal(t, paths, sample)
+	assert.Equal(t, 0, omitted)
+
+	// Uniform sampling gives the same sample regardless of the order of the paths
+	sampling := PathSampling{MaxPathsPerPair: 3, Strategy: SampleUniform}
+	sample, omitted = SamplePaths(paths, "s", "g", sampling)
+	assert.Equal(t, 3, len(sample))
+	assert.Equal(t, 2, omitted)
+
+	reversed := []Path{paths[4], paths[3], paths[2], paths[1], paths[0]}
+	sample2, _ := SamplePaths(reversed, "s", "g", sampling)
+	assert.Equal(t, sample, sample2)
+
+	// The paths passed in are unchanged
+	assert.Equal(t, NewPath("s", "a", "g"), paths[0])
+
+	// Preferring shorter paths
+	sample, omitted = SamplePaths(paths, "s", "g", PathSampling{MaxPathsPerPair: 2,
+		Strategy: SampleShortest})
+	assert.Equal(t, 2, len(sample))
+	assert.Equal(t, 3, omitted)
+	assert.Equal(t, NewPath("s", "g"), sample[0])
+	assert.Equal(t, 3, len(sample[1].Route))
+
+	sample, _ = SamplePaths(paths, "s", "g", PathSampling{MaxPathsPerPair: 4,
+		Strategy: SampleShortest})
+	assert.Equal(t, NewPath("s", "g"), sample[0])
+	assert.ElementsMatch(t, []Path{NewPath("s", "a", "g"), NewPath("s", "d", "g")}, sample[1:3])
+	assert.Equal(t, 4, len(sample[3].Route))
+}
+
+// Test sampling by the path finder using the graph:
+//
+//	s --- g
+//	s --- a --- g
+//	s --- b --- g
+//	s --- c --- g
+func TestFindPathsWithSampling(t *testing.T) {
+
+	graph := graphstore.NewInMemoryUnipartiteGraphStore()
+	assert.NoError(t, graph.AddUndirected("s", "g"))
+	for _, v := range []string{"a", "b", "c"} {
+		assert.NoError(t, graph.AddUndirected("s", v))
+		assert.NoError(t, graph.AddUndirected(v, "g"))
+	}
+
+	pathFinder, err := NewPathFinder(graph)
+	assert.NoError(t, err)
+	assert.Equal(t, PathSampling{Strategy: SampleUniform}, pathFinder.Sampling())
+
+	assert.ErrorIs(t, pathFinder.SetSampling(PathSampling{MaxPathsPerPair: 1}),
+		ErrInvalidSamplingStrategy)
+	assert.NoError(t, pathFinder.SetSampling(PathSampling{MaxPathsPerPair: 2,
+		Strategy: SampleShortest}))
+
+	entitySets := []job.EntitySet{
+		{Name: "Set-1", EntityIds: []string{"s"}},
+		{Name: "Set-2", EntityIds: []string{"g"}},
+	}
+
+	conns, err := pathFinder.FindPaths(entitySets, 2)
+	assert.NoError(t, err)
+
+	paths, err := conns.Paths("s", "g")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(paths))
+	assert.Equal(t, NewPath("s", "g"), paths[0])
+	assert.Equal(t, 2, conns.OmittedPaths("s", "g"))
+	assert.Equal(t, 0, conns.OmittedPaths("g", "s"))
+	assert.Equal(t, 2, conns.NumberOfOmittedPaths())
+
+	statistics, err := conns.Statistics()
+	assert.NoError(t, err)
+	assert.Equal(t, []job.PairStatistics{
+		{Source: "s", Destination: "g", ShortestDistance: 1, NumberOfPaths: 2, OmittedPaths: 2},
+	}, statistics.Pairs)
+}
@@ -0,0 +1,92 @@
+package bfs
+
+import (
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+)
+
+// noDateLimit is later than the date of any document, i.e. it doesn't limit the date of a link.
+var noDateLimit = time.Date(9999, time.December, 31, 0, 0, 0, 0, time.UTC)
+
+// A temporalState is the state of a partial path from the root in temporal mode, i.e. whether its
+// links can be supported by documents in date order and the date of the document chosen for its
+// last link. In date order from the root, the earliest document dated no earlier than the previous
+// link's is chosen for each link, which leaves the most documents for the later links. Unless the
+// search is directed, the documents may instead be in date order from the goal to the root, for
+// which the latest document dated no later than the previous link's is chosen. A link without any
+// dated documents can't be ordered, so a path with such a link isn't temporal.
+type temporalState struct {
+	forward  bool      // Documents can be in date order from the root
+	from     time.Time // Earliest date usable for the next link in date order from the root
+	backward bool      // Documents can be in date order from the goal
+	until    time.Time // Latest date usable for the next link in date order from the goal
+}
+
+// newTemporalState of a path at the root. Only the documents in date order from the root are
+// considered if the search is directed.
+func newTemporalState(directed bool) *temporalState {
+	return &temporalState{
+		forward:  true,
+		backward: !directed,
+		until:    noDateLimit,
+	}
+}
+
+// next state of the partial path after taking the links of the route (which starts at the end of
+// the partial path). Nil is returned if the links can't be supported by documents in date order.
+func (s *temporalState) next(graph graphstore.UnipartiteGraphStore, route []string) (*temporalState, error) {
+
+	next := *s
+
+	for idx := 1; idx < len(route) && (next.forward || next.backward); idx++ {
+		metadata, err := graph.EdgeMetadata(route[idx-1], route[idx])
+		if err != nil {
+			return nil, err
+		}
+
+		if metadata == nil {
+			return nil, nil
+		}
+
+		if next.forward {
+			next.from, next.forward = metadata.EarliestDateFrom(next.from)
+		}
+
+		if next.backward {
+			next.until, next.backward = metadata.LatestDateUntil(next.until)
+		}
+	}
+
+	if !next.forward && !next.backward {
+		return nil, nil
+	}
+
+	return &next, nil
+}
+
+// isTemporalPath returns true if the links of the path are supported by documents in date order.
+// Unless the search is directed, a path is also temporal if its documents are in date order from
+// the goal to the root, as the flow may be in either direction.
+func isTemporalPath(graph graphstore.UnipartiteGraphStore, path Path, directed bool) (bool, error) {
+	state, err := newTemporalState(directed).next(graph, path.Route)
+	return state != nil, err
+}
+
+// temporalPaths returns the paths whose links are supported by documents in date order.
+func temporalPaths(graph graphstore.UnipartiteGraphStore, paths []Path, directed bool) ([]Path, error) {
+
+	temporal := []Path{}
+	for _, path := range paths {
+		ok, err := isTemporalPath(graph, path, directed)
+		if err != nil {
+			return nil, err
+		}
+
+		if ok {
+			temporal = append(temporal, path)
+		}
+	}
+
+	return temporal, nil
+}
@@ -0,0 +1,127 @@
+package bfs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/stretchr/testify/assert"
+)
+
+// makeTemporalGraph where the documents linking a -- b -- c are in date order from a to c and the
+// documents linking a -- d -- c are in date order from c to a.
+func makeTemporalGraph(t *testing.T) *graphstore.InMemoryUnipartiteGraphStore {
+
+	day := func(d int) time.Time {
+		return time.Date(2022, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	graph := graphstore.NewInMemoryUnipartiteGraphStore()
+	edges := []struct {
+		e1    string
+		e2    string
+		dates []time.Time
+	}{
+		{"a", "b", []time.Time{day(1), day(2)}},
+		{"b", "c", []time.Time{day(5)}},
+		{"a", "d", []time.Time{day(3)}},
+		{"d", "c", []time.Time{day(1), day(2)}},
+		{"d", "e", []time.Time{day(4)}},
+		{"c", "e", []time.Time{{}}},
+	}
+
+	for _, edge := range edges {
+		assert.NoError(t, graph.AddUndirected(edge.e1, edge.e2))
+		for _, date := range edge.dates {
+			assert.NoError(t, graph.AddEdgeDocument(edge.e1, edge.e2, date))
+			assert.NoError(t, graph.AddEdgeDocument(edge.e2, edge.e1, date))
+		}
+	}
+
+	return graph
+}
+
+func TestIsTemporalPath(t *testing.T) {
+
+	graph := makeTemporalGraph(t)
+
+	testCases := []struct {
+		path     Path
+		directed bool
+		expected bool
+	}{
+		{NewPath("a", "b", "c"), true, true},
+		{NewPath("c", "b", "a"), true, false}, // Day 5 and then day 1 or 2
+		{NewPath("c", "b", "a"), false, true},
+		{NewPath("a", "d", "c"), true, false},
+		{NewPath("a", "d", "c"), false, true},
+		{NewPath("a", "d", "e"), true, true},   // Day 3 and then day 4
+		{NewPath("d", "c", "e"), false, false}, // No dated documents
+		{NewPath("a"), true, true},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := isTemporalPath(graph, testCase.path, testCase.directed)
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.expected, actual, testCase.path.Route)
+	}
+}
+
+func TestAllPathsWithConstraintsTemporal(t *testing.T) {
+
+	graph := makeTemporalGraph(t)
+	ctx := context.Background()
+
+	paths, err := AllPathsWithConstraints(ctx, graph, "a", "c", 2, PathConstraints{})
+	assert.NoError(t, err)
+	assert.True(t, PathsEqual([]Path{NewPath("a", "b", "c"), NewPath("a", "d", "c")}, paths))
+
+	paths, err = AllPathsWithConstraints(ctx, graph, "a", "c", 2, PathConstraints{Temporal: true})
+	assert.NoError(t, err)
+	assert.True(t, PathsEqual([]Path{NewPath("a", "b", "c"), NewPath("a", "d", "c")}, paths))
+
+	// Only the flow from a to c
+	paths, err = AllPathsWithConstraints(ctx, graph, "a", "c", 2,
+		PathConstraints{Directed: true, Temporal: true})
+	assert.NoError(t, err)
+	assert.True(t, PathsEqual([]Path{NewPath("a", "b", "c")}, paths))
+}
+
+func TestAllPathsWithConstraintsTemporalPrunesSteps(t *testing.T) {
+
+	ctx := context.Background()
+
+	testCases := []struct {
+		constraints       PathConstraints
+		expectedPaths     []Path
+		expectedExpansion int // Number of times c is expanded
+	}{
+		{
+			constraints:       PathConstraints{},
+			expectedPaths:     []Path{NewPath("a", "d", "e"), NewPath("a", "b", "c", "e"), NewPath("a", "d", "c", "e")},
+			expectedExpansion: 2,
+		},
+		{
+			// a -- d -- c isn't in date order from a, so c isn't expanded from it
+			constraints:       PathConstraints{Directed: true, Temporal: true},
+			expectedPaths:     []Path{NewPath("a", "d", "e")},
+			expectedExpansion: 1,
+		},
+		{
+			// a -- d -- c is in date order from c to a
+			constraints:       PathConstraints{Temporal: true},
+			expectedPaths:     []Path{NewPath("a", "d", "e")},
+			expectedExpansion: 2,
+		},
+	}
+
+	for _, testCase := range testCases {
+		graph, counter := NewExplanation().counter(makeTemporalGraph(t))
+
+		paths, err := AllPathsWithConstraints(ctx, graph, "a", "e", 3, testCase.constraints)
+		assert.NoError(t, err)
+		assert.True(t, PathsEqual(testCase.expectedPaths, paths), paths)
+		assert.Equal(t, testCase.expectedExpansion, counter.vertices["c"].Expansions)
+	}
+}
@@ -16,6 +16,7 @@ import (
 // where node 'b' has the parent 'a' and children 'c' and 'd'.
 type TreeNode struct {
 	name     string      // Name of the node from the graph
+	via      []string    // Names passed through from the parent (inside a collapsed chain)
 	parent   *TreeNode   // Parent of the node
 	children []*TreeNode // Children of the node
 	marked   bool        // Boolean flag to 'mark' the node
@@ -50,6 +51,35 @@ func (t *TreeNode) MakeChild(name string, marked bool) (*TreeNode, error) {
 	return node, nil
 }
 
+// makeChildVia makes a child node in the tree that is reached from the node by passing through the
+// names in the via slice. The caller checks the lineage.
+func (t *TreeNode) makeChildVia(name string, via []string, marked bool) *TreeNode {
+	node := NewTreeNode(name, marked)
+	node.via = via
+	node.parent = t
+
+	t.children = append(t.children, node)
+	return node
+}
+
+// onLineage returns true if the node, its parents or the names passed through between them include
+// the name.
+func (t *TreeNode) onLineage(name string) bool {
+	for p := t; p != nil; p = p.parent {
+		if p.name == name {
+			return true
+		}
+
+		for _, passed := range p.via {
+			if passed == name {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
 // Contains a parent node of a given name?
 func (t *TreeNode) ContainsParentNode(name string) bool {
 
@@ -75,6 +105,7 @@ func (t *TreeNode) Flatten() []string {
 	for p != nil {
 		// Prepend the lineage
 		lineage = append([]string{p.name}, lineage...)
+		lineage = append(append([]string{}, p.via...), lineage...)
 		p = p.parent
 	}
 
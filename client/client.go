@@ -0,0 +1,418 @@
+// Package client is a small Go client of the shortest path web-app, so that other services can
+// submit shortest path and spider jobs, poll them until they finish and download their results.
+// The endpoints are described by the OpenAPI specification served at /openapi.json.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Names of the form fields and query parameters of the web-app
+const (
+	numberHopsField      = "numberHops"
+	datasetNameField     = "datasetName"
+	datasetEntitiesField = "datasetEntities"
+	retryField           = "retryWithFewerHops"
+	directedField        = "directed"
+	excludeEntitiesField = "excludeEntities"
+	waypointsField       = "waypoints"
+	pathFilterField      = "pathFilter"
+	pathMatrixField      = "pathMatrix"
+	minDocumentsField    = "minDocumentsPerLink"
+	temporalField        = "temporal"
+	explainField         = "explain"
+	timeoutField         = "timeoutMinutes"
+	numberStepsField     = "numberSteps"
+	seedEntitiesField    = "seedEntities"
+	formatParameter      = "format=json"
+)
+
+// Maximum number of datasets of a shortest path job
+const MaxDatasets = 3
+
+// Default interval between polls of a job's status
+const DefaultPollInterval = time.Second
+
+// States of a job
+const (
+	NotStarted        = "Not started"
+	InProgress        = "In progress"
+	Failed            = "Failed"
+	CompleteResults   = "Complete Results"
+	CompleteNoResults = "Complete No Results"
+	TimedOut          = "Timed out"
+)
+
+var (
+	ErrInvalidBaseUrl      = errors.New("invalid base URL")
+	ErrTooManyDatasets     = errors.New("too many datasets")
+	ErrUnexpectedStatus    = errors.New("unexpected HTTP status")
+	ErrNoLocation          = errors.New("job location not returned")
+	ErrJobFailed           = errors.New("job failed")
+	ErrJobTimedOut         = errors.New("job timed out")
+	ErrNoResults           = errors.New("job has no results")
+	ErrInvalidPollInterval = errors.New("invalid poll interval")
+)
+
+// A Dataset is a named set of entity IDs of a shortest path job.
+type Dataset struct {
+	Name      string   // Name of the dataset
+	EntityIds []string // Entity IDs of the dataset
+}
+
+// JobRequest is a shortest path job to submit.
+type JobRequest struct {
+	NumberHops         int           // Maximum number of hops
+	Datasets           []Dataset     // Datasets from which to find paths (at most MaxDatasets)
+	RetryWithFewerHops bool          // Retry with one fewer hop if there are too many paths
+	Directed           bool          // Only follow the edges in their direction
+	ExcludedEntityIds  []string      // Entity IDs that the paths mustn't pass through
+	Waypoints          []string      // Entity IDs that the paths must pass through one of
+	PathMatrix         bool          // Output a matrix of the connectivity of each pair of entities
+	MinDocuments       int           // Minimum number of documents supporting each link (0 for any)
+	Temporal           bool          // Only find paths whose links have documents in date order
+	Timeout            time.Duration // Timeout of the job in whole minutes (0 for the server's timeout)
+	Explain            bool          // Record the expansion of the search in a diagnostics sheet
+	PathFilter         string        // Keep the paths through an entity matching the expression, e.g. Region = North
+}
+
+// SpiderJobRequest is a spider job to submit.
+type SpiderJobRequest struct {
+	NumberSteps   int           // Number of steps from the seed entities
+	SeedEntityIds []string      // Seed entity IDs
+	Timeout       time.Duration // Timeout of the job in whole minutes (0 for the server's timeout)
+}
+
+// JobStatus is the status of a job.
+type JobStatus struct {
+	Guid     string   `json:"guid"`               // GUID of the job
+	State    string   `json:"state"`              // State of the job
+	Finished bool     `json:"finished"`           // Is the job in an end state?
+	Message  string   `json:"message"`            // State for the user
+	Error    string   `json:"error,omitempty"`    // Reason the job failed
+	Warnings []string `json:"warnings"`           // Warnings, e.g. the job was retried
+	Download string   `json:"download,omitempty"` // URL of the results (relative to the host)
+
+	Diagnostics string   `json:"diagnostics,omitempty"` // URL of the diagnostics of a job in explain mode
+	Published   []string `json:"published,omitempty"`   // URLs of the result files in object storage
+}
+
+// A Client of the web-app.
+type Client struct {
+	baseUrl      *url.URL      // URL of the web-app (or a graph of the web-app)
+	httpClient   *http.Client  // HTTP client that doesn't follow redirects
+	pollInterval time.Duration // Interval between polls of a job's status
+}
+
+// NewClient for the web-app at the base URL, e.g. http://localhost:8090 or
+// http://localhost:8090/g/graphName for a named graph. If the HTTP client is nil, then the default
+// client is used.
+func NewClient(baseUrl string, httpClient *http.Client) (*Client, error) {
+
+	u, err := url.Parse(baseUrl)
+	if err != nil || len(u.Scheme) == 0 || len(u.Host) == 0 {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBaseUrl, baseUrl)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/")
+
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	// The submission endpoints redirect to the job's page, which holds the job's GUID
+	noRedirects := *httpClient
+	noRedirects.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		return http.ErrUseLastResponse
+	}
+
+	return &Client{
+		baseUrl:      u,
+		httpClient:   &noRedirects,
+		pollInterval: DefaultPollInterval,
+	}, nil
+}
+
+// SetPollInterval sets the interval between polls of a job's status.
+func (c *Client) SetPollInterval(interval time.Duration) error {
+	if interval <= 0 {
+		return ErrInvalidPollInterval
+	}
+
+	c.pollInterval = interval
+	return nil
+}
+
+// endpoint URL of the path (and query) relative to the base URL.
+func (c *Client) endpoint(pathAndQuery string) string {
+	return c.baseUrl.String() + pathAndQuery
+}
+
+// do the request and return the response if it has the expected status code.
+func (c *Client) do(req *http.Request, expectedStatus int) (*http.Response, error) {
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode != expectedStatus {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%w: %v %v returned %v", ErrUnexpectedStatus, req.Method,
+			req.URL.Path, resp.StatusCode)
+	}
+
+	return resp, nil
+}
+
+// submit the form to the endpoint and return the GUID of the job.
+func (c *Client) submit(ctx context.Context, endpoint string, form url.Values) (string, error) {
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.endpoint(endpoint),
+		strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.do(req, http.StatusFound)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	location := resp.Header.Get("Location")
+	if len(location) == 0 {
+		return "", ErrNoLocation
+	}
+
+	return path.Base(location), nil
+}
+
+// setTimeout of a job in the form, which is rounded up to whole minutes. A timeout of zero isn't
+// set, so the server's timeout is used.
+func setTimeout(form url.Values, timeout time.Duration) {
+	if timeout > 0 {
+		minutes := (timeout + time.Minute - 1) / time.Minute
+		form.Set(timeoutField, strconv.Itoa(int(minutes)))
+	}
+}
+
+// SubmitJob submits a shortest path job and returns its GUID.
+func (c *Client) SubmitJob(ctx context.Context, request JobRequest) (string, error) {
+
+	if len(request.Datasets) > MaxDatasets {
+		return "", fmt.Errorf("%w: %v given, but the maximum is %v", ErrTooManyDatasets,
+			len(request.Datasets), MaxDatasets)
+	}
+
+	form := url.Values{}
+	form.Set(numberHopsField, strconv.Itoa(request.NumberHops))
+	form.Set(retryField, strconv.FormatBool(request.RetryWithFewerHops))
+	form.Set(directedField, strconv.FormatBool(request.Directed))
+	form.Set(excludeEntitiesField, strings.Join(request.ExcludedEntityIds, "\n"))
+	form.Set(waypointsField, strings.Join(request.Waypoints, "\n"))
+	form.Set(pathMatrixField, strconv.FormatBool(request.PathMatrix))
+
+	if request.Temporal {
+		form.Set(temporalField, "true")
+	}
+
+	if request.Explain {
+		form.Set(explainField, "true")
+	}
+
+	if len(request.PathFilter) > 0 {
+		form.Set(pathFilterField, request.PathFilter)
+	}
+
+	if request.MinDocuments > 0 {
+		form.Set(minDocumentsField, strconv.Itoa(request.MinDocuments))
+	}
+
+	setTimeout(form, request.Timeout)
+
+	for idx, dataset := range request.Datasets {
+		form.Set(fmt.Sprintf("%v%d", datasetNameField, idx+1), dataset.Name)
+		form.Set(fmt.Sprintf("%v%d", datasetEntitiesField, idx+1), strings.Join(dataset.EntityIds, "\n"))
+	}
+
+	return c.submit(ctx, "/upload", form)
+}
+
+// SubmitSpiderJob submits a spider job and returns its GUID.
+func (c *Client) SubmitSpiderJob(ctx context.Context, request SpiderJobRequest) (string, error) {
+
+	form := url.Values{}
+	form.Set(numberStepsField, strconv.Itoa(request.NumberSteps))
+	form.Set(seedEntitiesField, strings.Join(request.SeedEntityIds, "\n"))
+	setTimeout(form, request.Timeout)
+
+	return c.submit(ctx, "/spider-upload", form)
+}
+
+// status of the job at the endpoint.
+func (c *Client) status(ctx context.Context, endpoint string) (*JobStatus, error) {
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		c.endpoint(endpoint+"?"+formatParameter), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.do(req, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var status JobStatus
+	if err := json.NewDecoder(resp.Body).Decode(&status); err != nil {
+		return nil, err
+	}
+
+	return &status, nil
+}
+
+// JobStatus returns the status of a shortest path job.
+func (c *Client) JobStatus(ctx context.Context, guid string) (*JobStatus, error) {
+	return c.status(ctx, "/job/"+url.PathEscape(guid))
+}
+
+// SpiderJobStatus returns the status of a spider job.
+func (c *Client) SpiderJobStatus(ctx context.Context, guid string) (*JobStatus, error) {
+	return c.status(ctx, "/spider-job/"+url.PathEscape(guid))
+}
+
+// wait polls the status until the job finishes or the context is done.
+func (c *Client) wait(ctx context.Context, guid string,
+	status func(context.Context, string) (*JobStatus, error)) (*JobStatus, error) {
+
+	ticker := time.NewTicker(c.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		s, err := status(ctx, guid)
+		if err != nil {
+			return nil, err
+		}
+
+		if s.Finished {
+			return s, nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return s, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// WaitForJob polls a shortest path job until it finishes or the context is done.
+func (c *Client) WaitForJob(ctx context.Context, guid string) (*JobStatus, error) {
+	return c.wait(ctx, guid, c.JobStatus)
+}
+
+// WaitForSpiderJob polls a spider job until it finishes or the context is done.
+func (c *Client) WaitForSpiderJob(ctx context.Context, guid string) (*JobStatus, error) {
+	return c.wait(ctx, guid, c.SpiderJobStatus)
+}
+
+// download the results at the endpoint to the writer.
+func (c *Client) download(ctx context.Context, endpoint string, w io.Writer) error {
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.endpoint(endpoint), nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := c.do(req, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	_, err = io.Copy(w, resp.Body)
+	return err
+}
+
+// DownloadJob writes the Excel file for i2 of a shortest path job to the writer.
+func (c *Client) DownloadJob(ctx context.Context, guid string, w io.Writer) error {
+	return c.download(ctx, "/download/"+url.PathEscape(guid), w)
+}
+
+// DownloadPathMatrix writes the CSV file of the path matrix of a shortest path job to the writer.
+func (c *Client) DownloadPathMatrix(ctx context.Context, guid string, w io.Writer) error {
+	return c.download(ctx, "/download-csv/"+url.PathEscape(guid), w)
+}
+
+// DownloadSpiderJob writes the Excel file for i2 of a spider job to the writer.
+func (c *Client) DownloadSpiderJob(ctx context.Context, guid string, w io.Writer) error {
+	return c.download(ctx, "/spider-download/"+url.PathEscape(guid), w)
+}
+
+// resultOf the finished job, which is an error if the job failed or has no results.
+func resultOf(status *JobStatus) error {
+	switch status.State {
+	case Failed:
+		return fmt.Errorf("%w: %v", ErrJobFailed, status.Error)
+	case TimedOut:
+		return fmt.Errorf("%w: %v", ErrJobTimedOut, status.Error)
+	case CompleteNoResults:
+		return ErrNoResults
+	}
+	return nil
+}
+
+// RunJob submits a shortest path job, waits for it to finish and writes its results to the writer.
+// ErrNoResults is returned if no paths were found.
+func (c *Client) RunJob(ctx context.Context, request JobRequest, w io.Writer) (*JobStatus, error) {
+
+	guid, err := c.SubmitJob(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := c.WaitForJob(ctx, guid)
+	if err != nil {
+		return status, err
+	}
+
+	if err := resultOf(status); err != nil {
+		return status, err
+	}
+
+	return status, c.DownloadJob(ctx, guid, w)
+}
+
+// RunSpiderJob submits a spider job, waits for it to finish and writes its results to the writer.
+// ErrNoResults is returned if the sub-graph is empty.
+func (c *Client) RunSpiderJob(ctx context.Context, request SpiderJobRequest, w io.Writer) (
+	*JobStatus, error) {
+
+	guid, err := c.SubmitSpiderJob(ctx, request)
+	if err != nil {
+		return nil, err
+	}
+
+	status, err := c.WaitForSpiderJob(ctx, guid)
+	if err != nil {
+		return status, err
+	}
+
+	if err := resultOf(status); err != nil {
+		return status, err
+	}
+
+	return status, c.DownloadSpiderJob(ctx, guid, w)
+}
@@ -0,0 +1,224 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testGuid = "cbd5b8ae-58ff-4b1a-a6ba-3d4ee7a1e3c5"
+
+// stubServer is a fake web-app where a job finishes after it has been polled twice.
+type stubServer struct {
+	lock  sync.Mutex
+	form  map[string]string // Form of the last submitted job
+	polls int               // Number of polls of the job's status
+	state string            // State of the job once it finishes
+}
+
+func (s *stubServer) handler() http.Handler {
+
+	mux := http.NewServeMux()
+
+	submit := func(prefix string) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			req.ParseForm()
+
+			s.lock.Lock()
+			s.form = map[string]string{}
+			for key := range req.PostForm {
+				s.form[key] = req.PostForm.Get(key)
+			}
+			s.lock.Unlock()
+
+			http.Redirect(w, req, prefix+testGuid, http.StatusFound)
+		}
+	}
+
+	status := func(download string) http.HandlerFunc {
+		return func(w http.ResponseWriter, req *http.Request) {
+			if !strings.HasSuffix(req.URL.Path, testGuid) || req.URL.Query().Get("format") != "json" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+
+			s.lock.Lock()
+			defer s.lock.Unlock()
+			s.polls += 1
+
+			status := JobStatus{Guid: testGuid, State: InProgress, Warnings: []string{}}
+			if s.polls > 2 {
+				status.State = s.state
+				status.Finished = true
+				if s.state == CompleteResults {
+					status.Download = download + testGuid
+				}
+				if s.state == Failed {
+					status.Error = "out of memory"
+				}
+			}
+
+			json.NewEncoder(w).Encode(status)
+		}
+	}
+
+	download := func(w http.ResponseWriter, req *http.Request) {
+		if !strings.HasSuffix(req.URL.Path, testGuid) {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("excel"))
+	}
+
+	mux.HandleFunc("/g/alpha/upload", submit("/g/alpha/job/"))
+	mux.HandleFunc("/g/alpha/job/", status("/g/alpha/download/"))
+	mux.HandleFunc("/g/alpha/download/", download)
+	mux.HandleFunc("/g/alpha/download-csv/", download)
+	mux.HandleFunc("/g/alpha/spider-upload", submit("/g/alpha/spider-job/"))
+	mux.HandleFunc("/g/alpha/spider-job/", status("/g/alpha/spider-download/"))
+	mux.HandleFunc("/g/alpha/spider-download/", download)
+
+	return mux
+}
+
+func TestNewClient(t *testing.T) {
+
+	_, err := NewClient("localhost", nil)
+	assert.ErrorIs(t, err, ErrInvalidBaseUrl)
+
+	_, err = NewClient("http://%zz", nil)
+	assert.ErrorIs(t, err, ErrInvalidBaseUrl)
+
+	c, err := NewClient("http://localhost:8090/g/alpha/", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://localhost:8090/g/alpha/upload", c.endpoint("/upload"))
+
+	assert.ErrorIs(t, c.SetPollInterval(0), ErrInvalidPollInterval)
+	assert.NoError(t, c.SetPollInterval(time.Millisecond))
+}
+
+func TestRunJob(t *testing.T) {
+
+	stub := &stubServer{state: CompleteResults}
+	ts := httptest.NewServer(stub.handler())
+	defer ts.Close()
+
+	c, err := NewClient(ts.URL+"/g/alpha", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, c.SetPollInterval(time.Millisecond))
+
+	// Too many datasets
+	_, err = c.SubmitJob(context.Background(), JobRequest{
+		NumberHops: 2,
+		Datasets:   []Dataset{{Name: "a"}, {Name: "b"}, {Name: "c"}, {Name: "d"}},
+	})
+	assert.ErrorIs(t, err, ErrTooManyDatasets)
+
+	var buffer bytes.Buffer
+	status, err := c.RunJob(context.Background(), JobRequest{
+		NumberHops: 2,
+		Datasets: []Dataset{
+			{Name: "Dataset-1", EntityIds: []string{"e-1", "e-2"}},
+		},
+		Directed:          true,
+		ExcludedEntityIds: []string{"e-3"},
+	}, &buffer)
+
+	assert.NoError(t, err)
+	assert.Equal(t, testGuid, status.Guid)
+	assert.Equal(t, "/g/alpha/download/"+testGuid, status.Download)
+	assert.Equal(t, "excel", buffer.String())
+
+	assert.Equal(t, map[string]string{
+		"numberHops":         "2",
+		"datasetName1":       "Dataset-1",
+		"datasetEntities1":   "e-1\ne-2",
+		"retryWithFewerHops": "false",
+		"directed":           "true",
+		"excludeEntities":    "e-3",
+		"waypoints":          "",
+		"pathMatrix":         "false",
+	}, stub.form)
+
+	// Path matrix of the job
+	buffer.Reset()
+	assert.NoError(t, c.DownloadPathMatrix(context.Background(), testGuid, &buffer))
+	assert.Equal(t, "excel", buffer.String())
+
+	// Unknown job
+	_, err = c.JobStatus(context.Background(), "1234")
+	assert.ErrorIs(t, err, ErrUnexpectedStatus)
+}
+
+func TestRunSpiderJob(t *testing.T) {
+
+	stub := &stubServer{state: CompleteNoResults}
+	ts := httptest.NewServer(stub.handler())
+	defer ts.Close()
+
+	c, err := NewClient(ts.URL+"/g/alpha", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, c.SetPollInterval(time.Millisecond))
+
+	var buffer bytes.Buffer
+	status, err := c.RunSpiderJob(context.Background(), SpiderJobRequest{
+		NumberSteps:   1,
+		SeedEntityIds: []string{"e-1", "e-4"},
+	}, &buffer)
+
+	assert.ErrorIs(t, err, ErrNoResults)
+	assert.Equal(t, CompleteNoResults, status.State)
+	assert.Equal(t, 0, buffer.Len())
+	assert.Equal(t, map[string]string{"numberSteps": "1", "seedEntities": "e-1\ne-4"}, stub.form)
+
+	// A failed job
+	stub.lock.Lock()
+	stub.polls = 0
+	stub.state = Failed
+	stub.lock.Unlock()
+	_, err = c.RunSpiderJob(context.Background(), SpiderJobRequest{
+		NumberSteps:   1,
+		SeedEntityIds: []string{"e-1"},
+	}, &buffer)
+	assert.ErrorIs(t, err, ErrJobFailed)
+	assert.Contains(t, err.Error(), "out of memory")
+
+	// A job that timed out, where the timeout is rounded up to whole minutes
+	stub.lock.Lock()
+	stub.polls = 0
+	stub.state = TimedOut
+	stub.lock.Unlock()
+	_, err = c.RunSpiderJob(context.Background(), SpiderJobRequest{
+		NumberSteps:   1,
+		SeedEntityIds: []string{"e-1"},
+		Timeout:       90 * time.Second,
+	}, &buffer)
+	assert.ErrorIs(t, err, ErrJobTimedOut)
+	assert.Equal(t, "2", stub.form["timeoutMinutes"])
+}
+
+func TestWaitForJobCancelled(t *testing.T) {
+
+	stub := &stubServer{state: CompleteResults}
+	ts := httptest.NewServer(stub.handler())
+	defer ts.Close()
+
+	c, err := NewClient(ts.URL+"/g/alpha", nil)
+	assert.NoError(t, err)
+	assert.NoError(t, c.SetPollInterval(time.Hour))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	status, err := c.WaitForJob(ctx, testGuid)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, InProgress, status.State)
+}
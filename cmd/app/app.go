@@ -1,25 +1,46 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
+	"fmt"
 	"io"
+	"net"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
+	"github.com/cdclaxton/shortest-path-web-app/annotation"
 	"github.com/cdclaxton/shortest-path-web-app/bfs"
 	"github.com/cdclaxton/shortest-path-web-app/graphbuilder"
+	"github.com/cdclaxton/shortest-path-web-app/graphloader"
+	"github.com/cdclaxton/shortest-path-web-app/i18n"
 	"github.com/cdclaxton/shortest-path-web-app/i2chart"
+	"github.com/cdclaxton/shortest-path-web-app/intake"
+	"github.com/cdclaxton/shortest-path-web-app/job"
 	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cdclaxton/shortest-path-web-app/manifest"
+	"github.com/cdclaxton/shortest-path-web-app/publish"
+	"github.com/cdclaxton/shortest-path-web-app/redaction"
 	"github.com/cdclaxton/shortest-path-web-app/search"
 	"github.com/cdclaxton/shortest-path-web-app/server"
 	"github.com/cdclaxton/shortest-path-web-app/spider"
+	"github.com/cdclaxton/shortest-path-web-app/tombstone"
 )
 
 // Component name used in logging
 const componentName = "application"
 
+// Environment variable holding the token for admin-only features
+const adminTokenEnvVar = "SHORTEST_PATH_ADMIN_TOKEN"
+
+// Environment variable holding the deployment key that signs the manifests of the results
+const manifestKeyEnvVar = "SHORTEST_PATH_MANIFEST_KEY"
+
 // readMessage from a file that gets displayed on the index page.
 func readMessage(filepath string) (string, error) {
 
@@ -37,75 +58,171 @@ func readMessage(filepath string) (string, error) {
 	return string(b), nil
 }
 
-func main() {
+// validateInputData checks the CSV files defined in the data config without building the graphs,
+// prints the report to stdout and returns the exit code.
+func validateInputData(dataConfigPath string) int {
 
-	startTime := time.Now()
+	config, err := graphbuilder.ReadGraphConfigFromJson(dataConfigPath)
+	if err != nil {
+		logging.Logger.Error().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to read data config")
+		return 2
+	}
 
-	logging.Logger.Info().
-		Str(logging.ComponentField, componentName).
-		Msg("Starting shortest path web-app")
+	report := graphloader.ValidateCsvFiles(config.Data.EntitiesFiles, config.Data.DocumentsFiles,
+		config.Data.LinksFiles)
 
-	// Get the config path and the i2 config path
-	dataConfigPath := flag.String("data", "data-config.json", "Path to the config.json file")
-	i2ConfigPath := flag.String("i2", "i2-config.json", "Path to the i2 config.json file")
-	i2SpiderConfigPath := flag.String("i2spider", "i2-spider-config.json", "Path to the i2 spider config.json file")
-	chartFolder := flag.String("folder", "./chartFolder", "Folder for storing generated charts")
-	messagePath := flag.String("message", "message.html", "Path to message to show on index page")
-
-	flag.Parse()
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		logging.Logger.Error().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to write validation report")
+		return 2
+	}
 
-	logging.Logger.Info().
-		Str(logging.ComponentField, componentName).
-		Str("filepath", *dataConfigPath).
-		Msg("Data config filepath")
+	if !report.IsValid() {
+		return 1
+	}
 
-	logging.Logger.Info().
-		Str(logging.ComponentField, componentName).
-		Str("filepath", *i2ConfigPath).
-		Msg("i2 config filepath")
+	return 0
+}
 
-	logging.Logger.Info().
-		Str(logging.ComponentField, componentName).
-		Str("filepath", *i2SpiderConfigPath).
-		Msg("i2 spider config filepath")
+// serverOptions are the settings from the command line that are common to every graph.
+type serverOptions struct {
+	chartFolder            string                      // Folder for storing generated charts
+	maxPaths               int                         // Maximum number of paths for a job
+	maxExpansions          int                         // Maximum number of vertex expansions of a job
+	pathSampling           bfs.PathSampling            // Limit on the number of paths per pair of entities
+	maxChartRows           int                         // Maximum number of rows in an i2 chart
+	spillFolder            string                      // Folder for spilling the paths of large jobs
+	spillThreshold         int                         // Size (bytes) of a job's paths before spilling
+	deploymentKeywordsPath string                      // Path to the deployment keywords (blank for none)
+	maxSeedEntities        int                         // Maximum number of seed entities for a spider job
+	maxDatasets            int                         // Maximum number of datasets of a shortest path job
+	maxDatasetEntities     int                         // Maximum number of entity IDs in a dataset
+	maxEntityPairs         int                         // Maximum number of pairs of entities for a job
+	language               string                      // Default language of the web pages
+	themePath              string                      // Path to the theme (blank for the default)
+	pathQueryTimeout       time.Duration               // Maximum time for a path query
+	duplicateJobWindow     time.Duration               // Time a finished job is a duplicate for
+	profiling              bool                        // Enable the pprof endpoints?
+	jobTemplates           *job.JobTemplateStore       // Saved job templates shared by the graphs
+	cases                  *job.CaseStore              // Cases shared by the graphs
+	watchlists             *job.WatchlistStore         // Watchlists of all of the graphs
+	webhookHosts           []string                    // Hosts the alerts of the watchlists may be posted to
+	corsOrigins            []string                    // Origins that may use the JSON API from a browser
+	annotations            *annotation.AnnotationStore // Tags and notes of the entities (optional)
+	tombstones             *tombstone.TombstoneStore   // Withdrawn entities (optional)
+	entityIdRules          *job.EntityIdRules          // Rules for the entity IDs entered by a user
+	redactor               *redaction.Redactor         // Redacts attributes on the entity page and in charts
+	spiderCaps             spider.SpiderCaps           // Caps on the expansion of a spider job
+	diskQuota              server.DiskQuota            // Disk space the result files may use
+	checkpointJobs         bool                        // Keep the paths found by a failed job for a retry?
+	publisher              publish.Publisher           // Copies the result files to object storage (optional)
+	manifests              *manifest.Generator         // Makes the manifests of the result files (optional)
+	searchLimits           server.SearchLimits         // Permitted numbers of hops and steps
+	jobTimeout             time.Duration               // Default timeout of the jobs (zero for no timeout)
+	generationsFolder      string                      // Folder of the generations of the graphs (blank to disable)
+}
 
-	logging.Logger.Info().
-		Str(logging.ComponentField, componentName).
-		Str("folder", *chartFolder).
-		Msg("i2 chart folder")
+// openGenerations of the graphs defined in the data config, building a new generation if there
+// isn't one or the input files have changed. If the new generation fails to build, the current
+// generation is served. The process exits if there isn't a generation to serve.
+func openGenerations(dataConfigPath string, folder string) (*graphbuilder.Generations,
+	*graphbuilder.GraphBuilder) {
 
-	logging.Logger.Info().
-		Str(logging.ComponentField, componentName).
-		Str("filepath", *messagePath).
-		Msg("index page message path")
+	generations, err := graphbuilder.NewGenerationsFromJson(dataConfigPath, folder)
+	if err != nil {
+		logging.Logger.Fatal().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to open the generations of the graphs")
+	}
 
-	// Read the message to present on the frontend
-	logging.Logger.Info().Str(logging.ComponentField, componentName).Msg("Reading message")
-	msg, err := readMessage(*messagePath)
+	build, err := generations.IsBuildRequired()
 	if err != nil {
 		logging.Logger.Fatal().
 			Str(logging.ComponentField, componentName).
 			Err(err).
-			Msg("Failed to read message file")
+			Msg("Failed to detect whether a new generation of the graphs is required")
 	}
 
-	// Create the bipartite and unipartite graphs
-	logging.Logger.Info().Str(logging.ComponentField, componentName).Msg("Creating bipartite and unipartite graphs")
-	builder, build, err := graphbuilder.NewGraphBuilderFromJson(*dataConfigPath)
+	if build {
+		builder, generation, err := generations.Build()
+		if err == nil {
+			if err := generations.Activate(generation); err != nil {
+				logging.Logger.Fatal().
+					Str(logging.ComponentField, componentName).
+					Err(err).
+					Msg("Failed to activate the new generation of the graphs")
+			}
+			return generations, builder
+		}
+
+		if generations.State().Current == 0 {
+			logging.Logger.Fatal().
+				Str(logging.ComponentField, componentName).
+				Err(err).
+				Msg("Failed to build the first generation of the graphs")
+		}
+
+		logging.Logger.Error().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to build a new generation of the graphs, so serving the current generation")
+	}
+
+	builder, err := generations.Open(generations.State().Current)
 	if err != nil {
 		logging.Logger.Fatal().
 			Str(logging.ComponentField, componentName).
 			Err(err).
-			Msg("Failed to create graph builder")
+			Msg("Failed to open the current generation of the graphs")
 	}
 
+	return generations, builder
+}
+
+// makeJobServer builds (or loads) the graphs defined in the data config and makes a job server for
+// them. The graphs are built in generations in the folder unless it is blank. The process exits if
+// the job server can't be made.
+func makeJobServer(dataConfigPath string, i2ConfigPath string, i2SpiderConfigPath string,
+	msg string, options serverOptions, generationsFolder string) *server.JobServer {
+
+	// Create the bipartite and unipartite graphs
 	logging.Logger.Info().
-		Bool("buildRequired", build).
-		Msg("Unipartite and bipartite graphs built")
+		Str(logging.ComponentField, componentName).
+		Str("filepath", dataConfigPath).
+		Msg("Creating bipartite and unipartite graphs")
+
+	var builder *graphbuilder.GraphBuilder
+	var generations *graphbuilder.Generations
+	var err error
+
+	if len(generationsFolder) > 0 {
+		generations, builder = openGenerations(dataConfigPath, generationsFolder)
+	} else {
+		var build bool
+		builder, build, err = graphbuilder.NewGraphBuilderFromJson(dataConfigPath)
+		if err != nil {
+			logging.Logger.Fatal().
+				Str(logging.ComponentField, componentName).
+				Err(err).
+				Msg("Failed to create graph builder")
+		}
+
+		logging.Logger.Info().
+			Bool("buildRequired", build).
+			Msg("Unipartite and bipartite graphs built")
+	}
 
 	// Create the i2 chart builder
 	logging.Logger.Info().Str(logging.ComponentField, componentName).Msg("Making i2 chart builder")
-	chartBuilder, err := i2chart.NewI2ChartBuilder(*i2ConfigPath)
+	chartBuilder, err := i2chart.NewI2ChartBuilder(i2ConfigPath)
 	if err != nil {
 		logging.Logger.Fatal().
 			Str(logging.ComponentField, componentName).
@@ -113,9 +230,36 @@ func main() {
 			Msg("Failed to create chart builder")
 	}
 
+	err = chartBuilder.SetMaxRows(options.maxChartRows)
+	if err != nil {
+		logging.Logger.Fatal().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to set the maximum number of rows in an i2 chart")
+	}
+
+	// Set the deployment-specific keywords, e.g. base URLs
+	if len(options.deploymentKeywordsPath) > 0 {
+		keywords, err := i2chart.ReadDeploymentKeywords(options.deploymentKeywordsPath)
+		if err != nil {
+			logging.Logger.Fatal().
+				Str(logging.ComponentField, componentName).
+				Err(err).
+				Msg("Failed to read the deployment keywords")
+		}
+
+		err = chartBuilder.SetDeploymentKeywords(keywords)
+		if err != nil {
+			logging.Logger.Fatal().
+				Str(logging.ComponentField, componentName).
+				Err(err).
+				Msg("Failed to set the deployment keywords")
+		}
+	}
+
 	// Create the i2 spider chart builder
 	logging.Logger.Info().Str(logging.ComponentField, componentName).Msg("Making i2 spider chart builder")
-	spiderChartBuilder, err := i2chart.NewSpiderChartBuilder(*i2SpiderConfigPath)
+	spiderChartBuilder, err := i2chart.NewSpiderChartBuilder(i2SpiderConfigPath)
 	if err != nil {
 		logging.Logger.Fatal().
 			Str(logging.ComponentField, componentName).
@@ -123,10 +267,16 @@ func main() {
 			Msg("Failed to create spider chart builder")
 	}
 
+	// Set the redaction rules and the annotations in the i2 chart builders
+	chartBuilder.SetRedactor(options.redactor)
+	chartBuilder.SetAnnotations(options.annotations)
+	spiderChartBuilder.SetRedactor(options.redactor)
+
 	// Set the bipartite graph in the i2 chart builders
 	logging.Logger.Info().Str(logging.ComponentField, componentName).Msg("Setting bipartite graph in chart builders")
 	chartBuilder.SetBipartite(builder.Bipartite)
 	spiderChartBuilder.SetBipartite(builder.Bipartite)
+	chartBuilder.SetUnipartite(builder.Unipartite)
 
 	// Instantiate the path finder
 	logging.Logger.Info().Str(logging.ComponentField, componentName).Msg("Instantiating a path finder")
@@ -138,6 +288,38 @@ func main() {
 			Msg("Failed to create path finder")
 	}
 
+	err = pathFinder.SetMaxPaths(options.maxPaths)
+	if err != nil {
+		logging.Logger.Fatal().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to set the maximum number of paths")
+	}
+
+	err = pathFinder.SetMaxExpansions(options.maxExpansions)
+	if err != nil {
+		logging.Logger.Fatal().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to set the maximum number of vertex expansions")
+	}
+
+	err = pathFinder.SetSampling(options.pathSampling)
+	if err != nil {
+		logging.Logger.Fatal().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to set the path sampling")
+	}
+
+	err = pathFinder.SetSpill(options.spillFolder, options.spillThreshold)
+	if err != nil {
+		logging.Logger.Fatal().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to set the path spill configuration")
+	}
+
 	// Instantiate the spider matcher
 	logging.Logger.Info().Str(logging.ComponentField, componentName).Msg("Instantiating a spider matcher")
 	spider, err := spider.NewSpider(builder.Unipartite)
@@ -147,6 +329,7 @@ func main() {
 			Err(err).
 			Msg("Failed to create spider engine")
 	}
+	spider.SetBipartite(builder.Bipartite)
 
 	// Create the search engine
 	logging.Logger.Info().Str(logging.ComponentField, componentName).Msg("Making entity search engine")
@@ -158,9 +341,18 @@ func main() {
 			Msg("Failed to create search engine")
 	}
 
+	// Suggestions of similar entity IDs are a convenience, so the server can run without them
+	err = searchEngine.BuildSuggestionIndex()
+	if err != nil {
+		logging.Logger.Warn().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to build the entity ID index for suggestions")
+	}
+
 	// Create the job runner
 	logging.Logger.Info().Str(logging.ComponentField, componentName).Msg("Making job runner")
-	runner, err := server.NewJobRunner(pathFinder, chartBuilder, *chartFolder, searchEngine)
+	runner, err := server.NewJobRunner(pathFinder, chartBuilder, options.chartFolder, searchEngine)
 	if err != nil {
 		logging.Logger.Fatal().
 			Str(logging.ComponentField, componentName).
@@ -170,7 +362,7 @@ func main() {
 
 	// Create the spider job runner
 	logging.Logger.Info().Str(logging.ComponentField, componentName).Msg("Making spider job runner")
-	spiderJobRunner, err := server.NewSpiderJobRunner(spider, spiderChartBuilder, *chartFolder)
+	spiderJobRunner, err := server.NewSpiderJobRunner(spider, spiderChartBuilder, options.chartFolder)
 	if err != nil {
 		logging.Logger.Fatal().
 			Str(logging.ComponentField, componentName).
@@ -178,6 +370,51 @@ func main() {
 			Msg("Failed to create spider job runner")
 	}
 
+	// Stop the jobs writing result files when the disk is nearly full
+	if err := runner.SetDiskQuota(options.diskQuota); err != nil {
+		logging.Logger.Fatal().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to set the disk quota of the job runner")
+	}
+
+	if err := spiderJobRunner.SetDiskQuota(options.diskQuota); err != nil {
+		logging.Logger.Fatal().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to set the disk quota of the spider job runner")
+	}
+
+	// Stop the jobs that take too long
+	if err := runner.SetTimeout(options.jobTimeout); err != nil {
+		logging.Logger.Fatal().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to set the timeout of the jobs")
+	}
+
+	if err := spiderJobRunner.SetTimeout(options.jobTimeout); err != nil {
+		logging.Logger.Fatal().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to set the timeout of the spider jobs")
+	}
+
+	// Keep the paths found by a failed job, so it can be retried and its partial results downloaded
+	runner.SetCheckpointing(options.checkpointJobs)
+
+	// Copy the result files to object storage for downstream pipelines
+	runner.SetPublisher(options.publisher)
+	spiderJobRunner.SetPublisher(options.publisher)
+
+	// Write a (signed) manifest alongside each result file, so that the results are tamper-evident
+	runner.SetManifestGenerator(options.manifests)
+	spiderJobRunner.SetManifestGenerator(options.manifests)
+
+	// Record the data drop searched by the jobs, so that each result is traceable to it
+	runner.SetProvenance(builder.Stats.Provenance)
+	spiderJobRunner.SetProvenance(builder.Stats.Provenance)
+
 	// Create the job server
 	logging.Logger.Info().Str(logging.ComponentField, componentName).Msg("Making job server")
 	jobServer, err := server.NewJobServer(runner, spiderJobRunner, msg, builder.Stats)
@@ -188,6 +425,546 @@ func main() {
 			Msg("Failed to create job server")
 	}
 
+	// The admin token is read from the environment so that it isn't visible in the process list
+	jobServer.SetAdminToken(os.Getenv(adminTokenEnvVar))
+	jobServer.SetProfiling(options.profiling)
+
+	err = jobServer.SetMaxSeedEntities(options.maxSeedEntities)
+	if err != nil {
+		logging.Logger.Fatal().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to set the maximum number of seed entities")
+	}
+
+	err = jobServer.SetMaxDatasets(options.maxDatasets)
+	if err != nil {
+		logging.Logger.Fatal().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to set the maximum number of datasets")
+	}
+
+	err = jobServer.SetJobLimits(job.JobLimits{
+		MaxEntityIdsPerDataset: options.maxDatasetEntities,
+		MaxEntityPairs:         options.maxEntityPairs,
+	})
+	if err != nil {
+		logging.Logger.Fatal().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to set the job limits")
+	}
+
+	err = jobServer.SetSearchLimits(options.searchLimits)
+	if err != nil {
+		logging.Logger.Fatal().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to set the search limits")
+	}
+
+	// Queue the jobs and queries whilst the expired documents are pruned from the graph
+	jobServer.SetGraph(builder)
+	if generations != nil {
+		jobServer.SetGenerations(generations)
+	}
+
+	err = jobServer.SetPathQueryTimeout(options.pathQueryTimeout)
+	if err != nil {
+		logging.Logger.Fatal().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to set the path query timeout")
+	}
+
+	err = jobServer.SetDuplicateJobWindow(options.duplicateJobWindow)
+	if err != nil {
+		logging.Logger.Fatal().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to set the duplicate job window")
+	}
+
+	err = jobServer.SetDefaultLanguage(options.language)
+	if err != nil {
+		logging.Logger.Fatal().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to set the default language")
+	}
+
+	if len(options.themePath) > 0 {
+		theme, err := server.ReadTheme(options.themePath)
+		if err != nil {
+			logging.Logger.Fatal().
+				Str(logging.ComponentField, componentName).
+				Err(err).
+				Msg("Failed to read the theme")
+		}
+
+		err = jobServer.SetTheme(theme)
+		if err != nil {
+			logging.Logger.Fatal().
+				Str(logging.ComponentField, componentName).
+				Err(err).
+				Msg("Failed to set the theme")
+		}
+	}
+
+	err = jobServer.SetJobTemplateStore(options.jobTemplates)
+	if err != nil {
+		logging.Logger.Fatal().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to set the job template store")
+	}
+
+	err = jobServer.SetCaseStore(options.cases)
+	if err != nil {
+		logging.Logger.Fatal().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to set the case store")
+	}
+
+	err = jobServer.SetWatchlistStore(options.watchlists)
+	if err != nil {
+		logging.Logger.Fatal().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to set the watchlist store")
+	}
+	jobServer.SetWebhookHosts(options.webhookHosts)
+	jobServer.SetCorsOrigins(options.corsOrigins)
+
+	jobServer.SetEntityIdRules(options.entityIdRules)
+
+	// The entity IDs entered by a user are normalised in the same way as the graph's entity IDs
+	normaliser, err := builder.EntityIdNormaliser()
+	if err != nil {
+		logging.Logger.Fatal().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to read the entity ID normalisation")
+	}
+	jobServer.SetEntityIdNormaliser(normaliser)
+
+	jobServer.SetRedactor(options.redactor)
+	jobServer.SetAnnotationStore(options.annotations)
+	jobServer.SetTombstoneStore(options.tombstones)
+
+	err = jobServer.SetSpiderCaps(options.spiderCaps)
+	if err != nil {
+		logging.Logger.Fatal().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to set the spider caps")
+	}
+
+	return jobServer
+}
+
+// readGraphMessage for a graph's index page, falling back to the default message if the graph
+// doesn't have one.
+func readGraphMessage(graph server.NamedGraphConfig, defaultMessage string) string {
+
+	if len(graph.Message) == 0 {
+		return defaultMessage
+	}
+
+	msg, err := readMessage(graph.Message)
+	if err != nil {
+		logging.Logger.Fatal().
+			Str(logging.ComponentField, componentName).
+			Str("graph", graph.Name).
+			Err(err).
+			Msg("Failed to read message file")
+	}
+
+	return msg
+}
+
+// startGrpcServer serves the gRPC path service of the job server on the port.
+func startGrpcServer(jobServer *server.JobServer, port int) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Int("port", port).
+		Msg("Starting gRPC server")
+
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		logging.Logger.Fatal().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to listen for gRPC requests")
+	}
+
+	go func() {
+		if err := jobServer.NewGrpcServer().Serve(listener); err != nil {
+			logging.Logger.Error().
+				Str(logging.ComponentField, componentName).
+				Err(err).
+				Msg("gRPC server stopped")
+		}
+	}()
+}
+
+// startQueueIntake submits the jobs from the messages in the inbox folder to the job server until
+// the context is done.
+func startQueueIntake(ctx context.Context, jobServer *server.JobServer, inbox string,
+	eventsPath string, maxJobs int) {
+
+	queue, err := intake.NewDirectoryQueue(inbox, eventsPath, intake.DefaultPollInterval)
+	if err != nil {
+		logging.Logger.Fatal().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to make the queue of job requests")
+	}
+
+	go func() {
+		if err := jobServer.ConsumeQueue(ctx, queue, maxJobs); err != nil {
+			logging.Logger.Fatal().
+				Str(logging.ComponentField, componentName).
+				Err(err).
+				Msg("Failed to consume the queue of job requests")
+		}
+	}()
+}
+
+// schedulePruning of the expired documents from the graphs with a retention policy every interval.
+func schedulePruning(servers []*server.JobServer, interval time.Duration) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("interval", interval.String()).
+		Msg("Scheduling the pruning of expired documents")
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for now := range ticker.C {
+			for _, jobServer := range servers {
+				builder := jobServer.Graph()
+				if !builder.HasRetentionPolicy() {
+					continue
+				}
+
+				result, err := builder.PruneExpiredDocuments(now)
+				if err != nil {
+					logging.Logger.Error().
+						Str(logging.ComponentField, componentName).
+						Err(err).
+						Msg("Failed to prune expired documents")
+				} else if result.DocumentsRemoved > 0 {
+					jobServer.RefreshStats()
+				}
+			}
+		}
+	}()
+}
+
+func main() {
+
+	startTime := time.Now()
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Msg("Starting shortest path web-app")
+
+	// Get the config path and the i2 config path
+	dataConfigPath := flag.String("data", "data-config.json", "Path to the config.json file")
+	i2ConfigPath := flag.String("i2", "i2-config.json", "Path to the i2 config.json file")
+	i2SpiderConfigPath := flag.String("i2spider", "i2-spider-config.json", "Path to the i2 spider config.json file")
+	chartFolder := flag.String("folder", "./chartFolder", "Folder for storing generated charts")
+	messagePath := flag.String("message", "message.html", "Path to message to show on index page")
+	maxPaths := flag.Int("maxPaths", 0, "Maximum number of paths for a job (0 for no limit)")
+	maxExpansions := flag.Int("maxExpansions", 0, "Maximum number of vertices expanded by the search of a job before it stops with partial results (0 for no limit)")
+	maxPathsPerPair := flag.Int("maxPathsPerPair", 0, "Maximum number of paths kept between each pair of entities (0 for no limit)")
+	pathSampling := flag.String("pathSampling", string(bfs.SampleUniform), "How the paths kept between a pair of entities are chosen (uniform or shortest)")
+	maxChartRows := flag.Int("maxChartRows", 0, "Maximum number of rows in the i2 chart of a job (0 for no limit)")
+	spillFolder := flag.String("spillFolder", "", "Folder for spilling the paths of large jobs to disk (blank to disable)")
+	spillThreshold := flag.Int("spillThreshold", 256<<20, "Approximate size (bytes) of a job's paths before spilling to disk")
+	validate := flag.Bool("validate", false, "Validate the input CSV files, print a report and exit")
+	deploymentKeywordsPath := flag.String("keywords", "", "Path to a JSON file of deployment keywords for the i2 config (blank for none)")
+	maxSeedEntities := flag.Int("maxSeedEntities", server.DefaultMaxSeedEntities, "Maximum number of seed entities for a spider job")
+	maxDatasets := flag.Int("maxDatasets", server.DefaultMaxDatasets, "Maximum number of datasets of a shortest path job")
+	maxDatasetEntities := flag.Int("maxDatasetEntities", server.DefaultMaxDatasetEntities, "Maximum number of entity IDs in a dataset (0 for no limit)")
+	maxEntityPairs := flag.Int("maxEntityPairs", server.DefaultMaxEntityPairs, "Maximum number of pairs of entities to search between for a job (0 for no limit)")
+	language := flag.String("language", i18n.DefaultLanguage, "Default language of the web pages (en or cy)")
+	themePath := flag.String("theme", "", "Path to a JSON file of the web page theme (blank for the default)")
+	pathQueryTimeout := flag.Duration("pathQueryTimeout", server.DefaultPathQueryTimeout, "Maximum time to search for the paths between two entities on the /path page")
+	duplicateJobWindow := flag.Duration("duplicateJobWindow", server.DefaultDuplicateJobWindow, "Time after a job has finished for which an identical job submitted from the form is taken to its page instead of running (0 to disable)")
+	profiling := flag.Bool("pprof", false, "Enable the pprof profiling endpoints at /debug/pprof/ (requires the admin token)")
+	graphsConfigPath := flag.String("graphs", "", "Path to a JSON file of named graphs to serve (blank to serve the graph in the data config)")
+	jobTemplatesPath := flag.String("jobTemplates", "job-templates.json", "Path to the JSON file of saved job templates (blank to not persist them)")
+	casesPath := flag.String("cases", "cases.json", "Path to the JSON file of cases (blank to not persist them)")
+	watchlistsPath := flag.String("watchlists", "watchlists.json", "Path to the JSON file of watchlists (blank to not persist them)")
+	webhookHosts := flag.String("webhookHosts", "", "Comma-separated hosts the alerts of the watchlists may be posted to (blank to disable webhooks)")
+	corsOrigins := flag.String("corsOrigins", "", "Comma-separated origins (e.g. https://dashboard.example.com) that may use the JSON API from a browser (blank to disable CORS)")
+	annotationsFolder := flag.String("annotations", "", "Folder of the Pebble store of the tags and notes of the entities (blank to disable annotations)")
+	tombstonesFolder := flag.String("tombstones", "", "Folder of the Pebble store of the withdrawn entities (blank to disable the withdrawal of entities)")
+	spiderMaxEntities := flag.Int("spiderMaxEntities", 0, "Maximum number of entities in the sub-graph of a spider job (0 for no limit)")
+	spiderMaxNeighbours := flag.Int("spiderMaxNeighbours", 0, "Maximum number of neighbours of an entity expanded by a spider job (0 for no limit)")
+	entityIdRulesPath := flag.String("entityIdRules", "", "Path to a JSON file of rules for the entity IDs entered by a user (blank for no rules)")
+	redactionPath := flag.String("redaction", "", "Path to a JSON file of the attributes to redact on the entity page and in the i2 charts (blank for none)")
+	grpcPort := flag.Int("grpcPort", 0, "Port of the gRPC path service for the (default) graph (0 to disable)")
+	minFreeDisk := flag.Int64("minFreeDisk", server.DefaultMinFreeDiskBytes, "Minimum free disk space (bytes) for a job to write its results (0 to not check)")
+	resultsQuota := flag.Int64("resultsQuota", 0, "Maximum size (bytes) of the folder of generated charts (0 for no limit)")
+	publishConfigPath := flag.String("publish", "", "Path to a JSON file of the S3-compatible object storage the results are copied to (blank to not copy them)")
+	manifests := flag.Bool("manifests", false, "Write an integrity manifest alongside each result file (signed if "+manifestKeyEnvVar+" is set)")
+	checkpointJobs := flag.Bool("checkpointJobs", false, "Keep the paths found by a failed job, so that it can be retried and its partial results downloaded")
+	minHops := flag.Int("minHops", server.MinimumNumberHops, "Minimum number of hops that can be chosen for a job")
+	maxHops := flag.Int("maxHops", server.MaximumNumberHops, "Maximum number of hops that can be chosen for a job")
+	minSteps := flag.Int("minSteps", server.MinimumNumberSteps, "Minimum number of steps that can be chosen for a spider job")
+	maxSteps := flag.Int("maxSteps", server.MaximumNumberSteps, "Maximum number of steps that can be chosen for a spider job")
+	jobTimeout := flag.Duration("jobTimeout", 0, "Maximum time a job may search for paths or spider (0 for no limit)")
+	generationsFolder := flag.String("generations", "", "Folder in which the graphs are built in generations, so a new data drop can be built and rolled back whilst the current graphs are served (blank to disable)")
+	intakeFolder := flag.String("intakeFolder", "", "Folder of JSON job requests submitted to the (default) graph (blank to disable the queue intake)")
+	intakeEvents := flag.String("intakeEvents", "intake-events.jsonl", "Path to the file the completion events of the jobs from the intake folder are appended to")
+	intakeMaxJobs := flag.Int("intakeMaxJobs", server.DefaultMaxQueueJobs, "Maximum number of jobs from the intake folder in progress at once")
+	pruneInterval := flag.Duration("pruneInterval", 0, "Interval between pruning the expired documents of graphs with a retention policy (0 to only prune at start up)")
+
+	flag.Parse()
+
+	// Perform a dry-run of loading the data if required
+	if *validate {
+		os.Exit(validateInputData(*dataConfigPath))
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("filepath", *dataConfigPath).
+		Msg("Data config filepath")
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("filepath", *i2ConfigPath).
+		Msg("i2 config filepath")
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("filepath", *i2SpiderConfigPath).
+		Msg("i2 spider config filepath")
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("folder", *chartFolder).
+		Msg("i2 chart folder")
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("filepath", *messagePath).
+		Msg("index page message path")
+
+	// Read the message to present on the frontend
+	logging.Logger.Info().Str(logging.ComponentField, componentName).Msg("Reading message")
+	msg, err := readMessage(*messagePath)
+	if err != nil {
+		logging.Logger.Fatal().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to read message file")
+	}
+
+	jobTemplates, err := job.NewJobTemplateStore(*jobTemplatesPath)
+	if err != nil {
+		logging.Logger.Fatal().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to read the job templates")
+	}
+
+	cases, err := job.NewCaseStore(*casesPath)
+	if err != nil {
+		logging.Logger.Fatal().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to read the cases")
+	}
+
+	watchlists, err := job.NewWatchlistStore(*watchlistsPath)
+	if err != nil {
+		logging.Logger.Fatal().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to read the watchlists")
+	}
+
+	var publisher publish.Publisher
+	if len(*publishConfigPath) > 0 {
+		config, err := publish.ReadS3Config(*publishConfigPath)
+		if err != nil {
+			logging.Logger.Fatal().
+				Str(logging.ComponentField, componentName).
+				Err(err).
+				Msg("Failed to read the object storage config")
+		}
+
+		publisher, err = publish.NewS3Publisher(config)
+		if err != nil {
+			logging.Logger.Fatal().
+				Str(logging.ComponentField, componentName).
+				Err(err).
+				Msg("Failed to make the publisher of the results")
+		}
+	}
+
+	// The signing key is read from the environment so that it isn't visible in the process list
+	var manifestGenerator *manifest.Generator
+	if *manifests {
+		manifestGenerator = manifest.NewGenerator([]byte(os.Getenv(manifestKeyEnvVar)))
+	}
+
+	var annotations *annotation.AnnotationStore
+	if len(*annotationsFolder) > 0 {
+		annotations, err = annotation.NewAnnotationStore(*annotationsFolder)
+		if err != nil {
+			logging.Logger.Fatal().
+				Str(logging.ComponentField, componentName).
+				Err(err).
+				Msg("Failed to open the annotation store")
+		}
+	}
+
+	var tombstones *tombstone.TombstoneStore
+	if len(*tombstonesFolder) > 0 {
+		tombstones, err = tombstone.NewTombstoneStore(*tombstonesFolder)
+		if err != nil {
+			logging.Logger.Fatal().
+				Str(logging.ComponentField, componentName).
+				Err(err).
+				Msg("Failed to open the tombstone store")
+		}
+	}
+
+	var entityIdRules *job.EntityIdRules
+	if len(*entityIdRulesPath) > 0 {
+		entityIdRules, err = job.ReadEntityIdRules(*entityIdRulesPath)
+		if err != nil {
+			logging.Logger.Fatal().
+				Str(logging.ComponentField, componentName).
+				Err(err).
+				Msg("Failed to read the entity ID rules")
+		}
+	}
+
+	var redactor *redaction.Redactor
+	if len(*redactionPath) > 0 {
+		redactor, err = redaction.ReadRedactor(*redactionPath)
+		if err != nil {
+			logging.Logger.Fatal().
+				Str(logging.ComponentField, componentName).
+				Err(err).
+				Msg("Failed to read the redaction rules")
+		}
+	}
+
+	options := serverOptions{
+		chartFolder:            *chartFolder,
+		maxPaths:               *maxPaths,
+		maxExpansions:          *maxExpansions,
+		maxChartRows:           *maxChartRows,
+		spillFolder:            *spillFolder,
+		spillThreshold:         *spillThreshold,
+		deploymentKeywordsPath: *deploymentKeywordsPath,
+		maxSeedEntities:        *maxSeedEntities,
+		maxDatasets:            *maxDatasets,
+		maxDatasetEntities:     *maxDatasetEntities,
+		maxEntityPairs:         *maxEntityPairs,
+		language:               *language,
+		themePath:              *themePath,
+		pathQueryTimeout:       *pathQueryTimeout,
+		duplicateJobWindow:     *duplicateJobWindow,
+		profiling:              *profiling,
+		jobTemplates:           jobTemplates,
+		cases:                  cases,
+		watchlists:             watchlists,
+		webhookHosts:           strings.Split(*webhookHosts, ","),
+		corsOrigins:            strings.Split(*corsOrigins, ","),
+		annotations:            annotations,
+		tombstones:             tombstones,
+		entityIdRules:          entityIdRules,
+		redactor:               redactor,
+		spiderCaps: spider.SpiderCaps{
+			MaxEntities:   *spiderMaxEntities,
+			MaxNeighbours: *spiderMaxNeighbours,
+		},
+		diskQuota: server.DiskQuota{
+			MinFreeBytes:   *minFreeDisk,
+			MaxFolderBytes: *resultsQuota,
+		},
+		pathSampling: bfs.PathSampling{
+			MaxPathsPerPair: *maxPathsPerPair,
+			Strategy:        bfs.SamplingStrategy(*pathSampling),
+		},
+		checkpointJobs:    *checkpointJobs,
+		publisher:         publisher,
+		manifests:         manifestGenerator,
+		jobTimeout:        *jobTimeout,
+		generationsFolder: *generationsFolder,
+		searchLimits: server.SearchLimits{
+			MinHops:  *minHops,
+			MaxHops:  *maxHops,
+			MinSteps: *minSteps,
+			MaxSteps: *maxSteps,
+		},
+	}
+
+	// Make a job server for each graph
+	jobServers := []*server.JobServer{}
+	var start func()
+	var defaultJobServer *server.JobServer // Job server of the gRPC path service and the queue intake
+
+	if len(*graphsConfigPath) == 0 {
+		jobServer := makeJobServer(*dataConfigPath, *i2ConfigPath, *i2SpiderConfigPath, msg,
+			options, options.generationsFolder)
+		jobServers = append(jobServers, jobServer)
+		start = jobServer.Start
+		defaultJobServer = jobServer
+
+	} else {
+		graphsConfig, err := server.ReadMultiGraphConfig(*graphsConfigPath)
+		if err != nil {
+			logging.Logger.Fatal().
+				Str(logging.ComponentField, componentName).
+				Err(err).
+				Msg("Failed to read the multi-graph config")
+		}
+
+		servers := map[string]*server.JobServer{}
+		for _, graph := range graphsConfig.Graphs {
+			logging.Logger.Info().
+				Str(logging.ComponentField, componentName).
+				Str("graph", graph.Name).
+				Msg("Making job server for graph")
+
+			// Each graph's generations are in a sub-folder named after the graph
+			generationsFolder := ""
+			if len(options.generationsFolder) > 0 {
+				generationsFolder = filepath.Join(options.generationsFolder, graph.Name)
+			}
+
+			jobServer := makeJobServer(graph.DataConfig, graph.I2Config, graph.I2SpiderConfig,
+				readGraphMessage(graph, msg), options, generationsFolder)
+			servers[graph.Name] = jobServer
+			jobServers = append(jobServers, jobServer)
+		}
+
+		router, err := server.NewGraphRouter(graphsConfig.Default, servers)
+		if err != nil {
+			logging.Logger.Fatal().
+				Str(logging.ComponentField, componentName).
+				Err(err).
+				Msg("Failed to create graph router")
+		}
+		start = router.Start
+		defaultJobServer = servers[graphsConfig.Default]
+	}
+
 	logging.Logger.Info().
 		Str(logging.ComponentField, componentName).
 		Str("startUpTime", time.Since(startTime).String()).
@@ -198,7 +975,20 @@ func main() {
 		Str(logging.ComponentField, componentName).
 		Msg("Starting server")
 
-	go jobServer.Start()
+	go start()
+
+	if *grpcPort > 0 {
+		startGrpcServer(defaultJobServer, *grpcPort)
+	}
+
+	intakeCtx, stopIntake := context.WithCancel(context.Background())
+	if len(*intakeFolder) > 0 {
+		startQueueIntake(intakeCtx, defaultJobServer, *intakeFolder, *intakeEvents, *intakeMaxJobs)
+	}
+
+	if *pruneInterval > 0 {
+		schedulePruning(jobServers, *pruneInterval)
+	}
 
 	stopChan := make(chan os.Signal, 1)
 	signal.Notify(stopChan, os.Interrupt, syscall.SIGTERM, syscall.SIGINT)
@@ -210,6 +1000,27 @@ func main() {
 		Str("signal", sig.String()).
 		Msg("Shutdown signal received")
 
-	builder.Bipartite.Close()
-	builder.Unipartite.Close()
+	stopIntake()
+
+	for _, jobServer := range jobServers {
+		jobServer.CloseGraphs()
+	}
+
+	if annotations != nil {
+		if err := annotations.Close(); err != nil {
+			logging.Logger.Warn().
+				Str(logging.ComponentField, componentName).
+				Err(err).
+				Msg("Failed to close the annotation store")
+		}
+	}
+
+	if tombstones != nil {
+		if err := tombstones.Close(); err != nil {
+			logging.Logger.Warn().
+				Str(logging.ComponentField, componentName).
+				Err(err).
+				Msg("Failed to close the tombstone store")
+		}
+	}
 }
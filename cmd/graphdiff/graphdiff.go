@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"os"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphbuilder"
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+)
+
+// Component name used in logging
+const componentName = "graphDiffTool"
+
+// Graphs that can be compared
+const (
+	graphBipartite  = "bipartite"
+	graphUnipartite = "unipartite"
+	graphBoth       = "both"
+)
+
+// Report of the changes between the old and new graphs.
+type Report struct {
+	Bipartite  *graphstore.BipartiteDiff  `json:"bipartite,omitempty"`
+	Unipartite *graphstore.UnipartiteDiff `json:"unipartite,omitempty"`
+}
+
+// openGraphs given in the data config. Pebble stores are opened read-only, so that the graphs of a
+// running web-app can be compared.
+func openGraphs(dataConfigPath string) *graphbuilder.GraphBuilder {
+
+	config, err := graphbuilder.ReadGraphConfigFromJson(dataConfigPath)
+	if err != nil {
+		logging.Logger.Fatal().
+			Str(logging.ComponentField, componentName).
+			Str("filepath", dataConfigPath).
+			Err(err).
+			Msg("Failed to read graph config")
+	}
+
+	if config.BipartiteConfig.Type == graphbuilder.StorageTypePebble &&
+		config.UnipartiteConfig.Type == graphbuilder.StorageTypePebble {
+		config.BipartiteConfig.ReadOnly = true
+		config.UnipartiteConfig.ReadOnly = true
+	}
+
+	graphs, err := graphbuilder.OpenGraphs(*config)
+	if err != nil {
+		logging.Logger.Fatal().
+			Str(logging.ComponentField, componentName).
+			Str("filepath", dataConfigPath).
+			Err(err).
+			Msg("Failed to open the graphs")
+	}
+
+	return graphs
+}
+
+func main() {
+
+	oldConfigPath := flag.String("old", "", "Path to the data config of the old graphs")
+	newConfigPath := flag.String("new", "", "Path to the data config of the new graphs")
+	graph := flag.String("graph", graphBoth, "Graph to compare (bipartite, unipartite or both)")
+	maxExamples := flag.Int("examples", graphstore.DefaultDiffExamples,
+		"Maximum number of examples of each type of change")
+	outputPath := flag.String("output", "", "Path of the JSON report (standard output if not given)")
+
+	flag.Parse()
+
+	if len(*oldConfigPath) == 0 || len(*newConfigPath) == 0 {
+		logging.Logger.Fatal().
+			Str(logging.ComponentField, componentName).
+			Msg("Both -old and -new must be given")
+	}
+
+	if *graph != graphBipartite && *graph != graphUnipartite && *graph != graphBoth {
+		logging.Logger.Fatal().
+			Str(logging.ComponentField, componentName).
+			Str("graph", *graph).
+			Msg("Invalid graph to compare")
+	}
+
+	oldGraphs := openGraphs(*oldConfigPath)
+	defer oldGraphs.Bipartite.Close()
+	defer oldGraphs.Unipartite.Close()
+
+	newGraphs := openGraphs(*newConfigPath)
+	defer newGraphs.Bipartite.Close()
+	defer newGraphs.Unipartite.Close()
+
+	report := Report{}
+	var err error
+
+	if *graph != graphUnipartite {
+		report.Bipartite, err = graphstore.DiffBipartite(oldGraphs.Bipartite, newGraphs.Bipartite,
+			*maxExamples)
+		if err != nil {
+			logging.Logger.Fatal().
+				Str(logging.ComponentField, componentName).
+				Err(err).
+				Msg("Failed to compare the bipartite graphs")
+		}
+	}
+
+	if *graph != graphBipartite {
+		report.Unipartite, err = graphstore.DiffUnipartite(oldGraphs.Unipartite, newGraphs.Unipartite,
+			*maxExamples)
+		if err != nil {
+			logging.Logger.Fatal().
+				Str(logging.ComponentField, componentName).
+				Err(err).
+				Msg("Failed to compare the unipartite graphs")
+		}
+	}
+
+	output := os.Stdout
+	if len(*outputPath) > 0 {
+		output, err = os.Create(*outputPath)
+		if err != nil {
+			logging.Logger.Fatal().
+				Str(logging.ComponentField, componentName).
+				Str("filepath", *outputPath).
+				Err(err).
+				Msg("Failed to create the report")
+		}
+		defer output.Close()
+	}
+
+	encoder := json.NewEncoder(output)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(report); err != nil {
+		logging.Logger.Fatal().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to write the report")
+	}
+}
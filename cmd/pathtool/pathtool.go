@@ -0,0 +1,554 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/bfs"
+	"github.com/cdclaxton/shortest-path-web-app/graphbuilder"
+	"github.com/cdclaxton/shortest-path-web-app/i2chart"
+	"github.com/cdclaxton/shortest-path-web-app/intake"
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cdclaxton/shortest-path-web-app/server"
+	"github.com/cdclaxton/shortest-path-web-app/set"
+	"github.com/cdclaxton/shortest-path-web-app/spider"
+)
+
+// Component name used in logging
+const componentName = "pathTool"
+
+// Subcommands of the tool
+const (
+	buildCommand       = "build"
+	statsCommand       = "stats"
+	findPathCommand    = "find-path"
+	spiderCommand      = "spider"
+	exportChartCommand = "export-chart"
+)
+
+const usage = `Usage: pathtool <command> [flags]
+
+Commands:
+  build         Build (or load) the graphs defined in a data config
+  stats         Print the statistics of the graphs as JSON
+  find-path     Print the paths between two entities as JSON
+  spider        Spider out from seed entities, printing the connections as JSON or writing an i2 chart
+  export-chart  Run a shortest path job from a JSON job request and write its i2 chart
+
+Run 'pathtool <command> -h' for the flags of a command.
+`
+
+var ErrNoResults = errors.New("no connections were found")
+
+// SpiderResult is the JSON output of the spider command.
+type SpiderResult struct {
+	NumberSteps          int        `json:"numberSteps"`          // Number of steps from the seeds
+	SeedEntitiesNotFound []string   `json:"seedEntitiesNotFound"` // Seeds that aren't in the graph
+	Truncated            bool       `json:"truncated"`            // Did the caps stop the expansion?
+	Connections          [][]string `json:"connections"`          // Pairs of connected entity IDs
+}
+
+// fatal logs the error and exits.
+func fatal(err error, msg string) {
+	logging.Logger.Fatal().
+		Str(logging.ComponentField, componentName).
+		Err(err).
+		Msg(msg)
+}
+
+// isOpenable returns true if the store is persistent and isn't in a temporary folder, i.e. it can
+// be opened without loading the input files.
+func isOpenable(storageType string, folder string) bool {
+	return storageType != graphbuilder.StorageTypeInMemory && folder != graphbuilder.UseTempFolder
+}
+
+// openGraphs given in the data config. Persistent stores that have been built are opened (read-only
+// for Pebble, so that the graphs of a running web-app can be queried), whereas in-memory and
+// temporary stores are loaded from the input files.
+func openGraphs(dataConfigPath string) *graphbuilder.GraphBuilder {
+
+	config, err := graphbuilder.ReadGraphConfigFromJson(dataConfigPath)
+	if err != nil {
+		fatal(err, "Failed to read graph config")
+	}
+
+	if !isOpenable(config.BipartiteConfig.Type, config.BipartiteConfig.Folder) ||
+		!isOpenable(config.UnipartiteConfig.Type, config.UnipartiteConfig.Folder) {
+		builder, _, err := graphbuilder.NewGraphBuilder(*config)
+		if err != nil {
+			fatal(err, "Failed to load the graphs")
+		}
+		return builder
+	}
+
+	if config.BipartiteConfig.Type == graphbuilder.StorageTypePebble &&
+		config.UnipartiteConfig.Type == graphbuilder.StorageTypePebble {
+		config.BipartiteConfig.ReadOnly = true
+		config.UnipartiteConfig.ReadOnly = true
+	}
+
+	builder, err := graphbuilder.OpenGraphs(*config)
+	if err != nil {
+		fatal(err, "Failed to open the graphs")
+	}
+
+	return builder
+}
+
+// closeGraphs of the builder.
+func closeGraphs(builder *graphbuilder.GraphBuilder) {
+	if err := builder.Close(); err != nil {
+		logging.Logger.Warn().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to close the graphs")
+	}
+}
+
+// writeJson to the file at the path or to standard output if the path is blank.
+func writeJson(outputPath string, value interface{}) {
+
+	var output io.Writer = os.Stdout
+	if len(outputPath) > 0 {
+		file, err := os.Create(outputPath)
+		if err != nil {
+			fatal(err, "Failed to create the output file")
+		}
+		defer file.Close()
+		output = file
+	}
+
+	encoder := json.NewEncoder(output)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(value); err != nil {
+		fatal(err, "Failed to write the output")
+	}
+}
+
+// newPathFinder of the graphs with a maximum number of paths (0 for no limit).
+func newPathFinder(builder *graphbuilder.GraphBuilder, maxPaths int) *bfs.PathFinder {
+
+	pathFinder, err := bfs.NewPathFinder(builder.Unipartite)
+	if err != nil {
+		fatal(err, "Failed to create path finder")
+	}
+
+	if err := pathFinder.SetMaxPaths(maxPaths); err != nil {
+		fatal(err, "Failed to set the maximum number of paths")
+	}
+
+	return pathFinder
+}
+
+// runBuild builds the graphs if the input files have changed (or loads them) and prints their
+// statistics.
+func runBuild(args []string) {
+
+	flags := flag.NewFlagSet(buildCommand, flag.ExitOnError)
+	dataConfigPath := flags.String("data", "data-config.json", "Path to the data config")
+	flags.Parse(args)
+
+	builder, build, err := graphbuilder.NewGraphBuilderFromJson(*dataConfigPath)
+	if err != nil {
+		fatal(err, "Failed to build the graphs")
+	}
+	defer closeGraphs(builder)
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Bool("built", build).
+		Msg("Graphs are ready")
+
+	writeJson("", builder.Stats)
+}
+
+// runStats prints the statistics of the graphs.
+func runStats(args []string) {
+
+	flags := flag.NewFlagSet(statsCommand, flag.ExitOnError)
+	dataConfigPath := flags.String("data", "data-config.json", "Path to the data config")
+	outputPath := flags.String("output", "", "Path of the JSON output (standard output if not given)")
+	flags.Parse(args)
+
+	builder := openGraphs(*dataConfigPath)
+	defer closeGraphs(builder)
+
+	if err := builder.CalculateStats(); err != nil {
+		fatal(err, "Failed to calculate the statistics of the graphs")
+	}
+
+	writeJson(*outputPath, builder.Stats)
+}
+
+// sortedRoutes of the paths, shortest first and then alphabetically.
+func sortedRoutes(paths []bfs.Path) [][]string {
+
+	routes := make([][]string, 0, len(paths))
+	for _, path := range paths {
+		routes = append(routes, path.Route)
+	}
+
+	sort.Slice(routes, func(i, j int) bool {
+		if len(routes[i]) != len(routes[j]) {
+			return len(routes[i]) < len(routes[j])
+		}
+		return strings.Join(routes[i], "\x00") < strings.Join(routes[j], "\x00")
+	})
+
+	return routes
+}
+
+// runFindPath prints the paths between two entities in the JSON format of the /path endpoint.
+func runFindPath(args []string) {
+
+	flags := flag.NewFlagSet(findPathCommand, flag.ExitOnError)
+	dataConfigPath := flags.String("data", "data-config.json", "Path to the data config")
+	from := flags.String("from", "", "Entity ID to find the paths from")
+	to := flags.String("to", "", "Entity ID to find the paths to")
+	hops := flags.Int("hops", 3, "Maximum number of hops")
+	directed := flags.Bool("directed", false, "Only follow the edges in their direction")
+	maxPaths := flags.Int("maxPaths", 0, "Maximum number of paths (0 for no limit)")
+	timeout := flags.Duration("timeout", 0, "Maximum time to search for the paths (0 for no limit)")
+	outputPath := flags.String("output", "", "Path of the JSON output (standard output if not given)")
+	flags.Parse(args)
+
+	if len(*from) == 0 || len(*to) == 0 {
+		fatal(errors.New("-from and -to must be given"), "Invalid arguments")
+	}
+
+	builder := openGraphs(*dataConfigPath)
+	defer closeGraphs(builder)
+
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	result := server.PathQueryResult{
+		From:     *from,
+		To:       *to,
+		Hops:     *hops,
+		Directed: *directed,
+		Paths:    [][]string{},
+	}
+
+	paths, err := newPathFinder(builder, *maxPaths).PathsBetween(ctx, *from, *to, *hops, *directed)
+	if err != nil {
+		result.Error = err.Error()
+	} else {
+		result.Paths = sortedRoutes(paths)
+	}
+
+	writeJson(*outputPath, result)
+}
+
+// spiderConnections of the sub-graph as pairs of entity IDs, in alphabetical order.
+func spiderConnections(results *spider.SpiderResults) ([][]string, error) {
+
+	entityIds, err := results.Subgraph.EntityIds()
+	if err != nil {
+		return nil, err
+	}
+
+	connections := [][]string{}
+	for _, entityId := range entityIds.ToSlice() {
+		adjacent, err := results.Subgraph.EntityIdsAdjacentTo(entityId)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, other := range adjacent.ToSlice() {
+			if entityId < other {
+				connections = append(connections, []string{entityId, other})
+			}
+		}
+	}
+
+	sort.Slice(connections, func(i, j int) bool {
+		if connections[i][0] != connections[j][0] {
+			return connections[i][0] < connections[j][0]
+		}
+		return connections[i][1] < connections[j][1]
+	})
+
+	return connections, nil
+}
+
+// runSpider spiders out from the seed entities and prints the connections or writes an i2 chart.
+func runSpider(args []string) {
+
+	flags := flag.NewFlagSet(spiderCommand, flag.ExitOnError)
+	dataConfigPath := flags.String("data", "data-config.json", "Path to the data config")
+	i2SpiderConfigPath := flags.String("i2spider", "i2-spider-config.json", "Path to the i2 spider config (for an Excel output)")
+	seeds := flags.String("seeds", "", "Comma-separated seed entity IDs")
+	steps := flags.Int("steps", 1, "Number of steps from the seed entities")
+	maxEntities := flags.Int("maxEntities", 0, "Maximum number of entities in the sub-graph (0 for no limit)")
+	maxNeighbours := flags.Int("maxNeighbours", 0, "Maximum number of neighbours of an entity expanded (0 for no limit)")
+	outputPath := flags.String("output", "", "Path of the output: an i2 chart if it ends in .xlsx, otherwise JSON (standard output if not given)")
+	flags.Parse(args)
+
+	seedEntities := set.NewSet[string]()
+	for _, seed := range strings.Split(*seeds, ",") {
+		if seed = strings.TrimSpace(seed); len(seed) > 0 {
+			seedEntities.Add(seed)
+		}
+	}
+
+	if seedEntities.Len() == 0 {
+		fatal(errors.New("-seeds must be given"), "Invalid arguments")
+	}
+
+	builder := openGraphs(*dataConfigPath)
+	defer closeGraphs(builder)
+
+	spiderEngine, err := spider.NewSpider(builder.Unipartite)
+	if err != nil {
+		fatal(err, "Failed to create spider engine")
+	}
+	spiderEngine.SetBipartite(builder.Bipartite)
+
+	caps := spider.SpiderCaps{MaxEntities: *maxEntities, MaxNeighbours: *maxNeighbours}
+	results, err := spiderEngine.ExecuteWithCaps(*steps, seedEntities, caps)
+	if err != nil {
+		fatal(err, "Failed to spider from the seed entities")
+	}
+
+	if strings.EqualFold(filepath.Ext(*outputPath), ".xlsx") {
+		chartBuilder, err := i2chart.NewSpiderChartBuilder(*i2SpiderConfigPath)
+		if err != nil {
+			fatal(err, "Failed to create spider chart builder")
+		}
+		chartBuilder.SetBipartite(builder.Bipartite)
+
+		table, err := chartBuilder.Build(results)
+		if err != nil {
+			fatal(err, "Failed to build the i2 chart")
+		}
+
+		if err := i2chart.WriteSheetToExcel(*outputPath, chartBuilder.SheetName(), table, nil); err != nil {
+			fatal(err, "Failed to write the i2 chart")
+		}
+		return
+	}
+
+	connections, err := spiderConnections(results)
+	if err != nil {
+		fatal(err, "Failed to read the connections of the sub-graph")
+	}
+
+	notFound := results.SeedEntitiesNotFound.ToSlice()
+	sort.Strings(notFound)
+
+	writeJson(*outputPath, SpiderResult{
+		NumberSteps:          *steps,
+		SeedEntitiesNotFound: notFound,
+		Truncated:            results.Truncated(),
+		Connections:          connections,
+	})
+}
+
+// readJobConfiguration from a JSON job request in the format of the queue intake.
+func readJobConfiguration(jobPath string) (*job.JobConfiguration, error) {
+
+	content, err := os.ReadFile(jobPath)
+	if err != nil {
+		return nil, err
+	}
+
+	request, err := intake.ParseJobRequest(content)
+	if err != nil {
+		return nil, err
+	}
+
+	conf := job.JobConfiguration{
+		MaxNumberHops:       request.NumberHops,
+		RetryWithFewerHops:  request.RetryWithFewerHops,
+		Directed:            request.Directed,
+		ExcludedEntityIds:   request.ExcludedEntityIds,
+		Waypoints:           request.Waypoints,
+		MinDocumentsPerLink: request.MinDocuments,
+		Temporal:            request.Temporal,
+		Timeout:             time.Duration(request.TimeoutMinutes) * time.Minute,
+	}
+
+	for _, dataset := range request.Datasets {
+		conf.EntitySets = append(conf.EntitySets, job.EntitySet{
+			Name:      dataset.Name,
+			EntityIds: dataset.EntityIds,
+		})
+	}
+
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &conf, nil
+}
+
+// findJobPaths between the entities of the job's datasets.
+func findJobPaths(pathFinder *bfs.PathFinder, conf *job.JobConfiguration) (
+	*bfs.NetworkConnections, error) {
+
+	ctx := context.Background()
+	if conf.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, conf.Timeout)
+		defer cancel()
+	}
+
+	constraints := bfs.PathConstraints{
+		Directed:     conf.Directed,
+		MinDocuments: conf.MinDocumentsPerLink,
+		Temporal:     conf.Temporal,
+	}
+
+	if len(conf.ExcludedEntityIds) > 0 {
+		constraints.Excluded = set.NewPopulatedSet(conf.ExcludedEntityIds...)
+	}
+
+	if len(conf.Waypoints) > 0 {
+		constraints.Waypoints = set.NewPopulatedSet(conf.Waypoints...)
+	}
+
+	find := func(maxHops int) (*bfs.NetworkConnections, error) {
+		return pathFinder.FindPathsWithContext(ctx, conf.EntitySets, maxHops, constraints,
+			logging.Logger, nil)
+	}
+
+	conns, err := find(conf.MaxNumberHops)
+	if errors.Is(err, bfs.ErrTooManyPaths) && conf.RetryWithFewerHops && conf.MaxNumberHops > 1 {
+		logging.Logger.Warn().
+			Str(logging.ComponentField, componentName).
+			Int("retryNumberOfHops", conf.MaxNumberHops-1).
+			Msg("Too many paths found, retrying with fewer hops")
+		return find(conf.MaxNumberHops - 1)
+	}
+
+	return conns, err
+}
+
+// runExportChart runs a shortest path job and writes its i2 chart to an Excel file and an ANX file.
+func runExportChart(args []string) {
+
+	flags := flag.NewFlagSet(exportChartCommand, flag.ExitOnError)
+	dataConfigPath := flags.String("data", "data-config.json", "Path to the data config")
+	i2ConfigPath := flags.String("i2", "i2-config.json", "Path to the i2 config")
+	keywordsPath := flags.String("keywords", "", "Path to a JSON file of deployment keywords for the i2 config (blank for none)")
+	jobPath := flags.String("job", "", "Path to the JSON job request (in the format of the queue intake)")
+	maxPaths := flags.Int("maxPaths", 0, "Maximum number of paths (0 for no limit)")
+	maxChartRows := flags.Int("maxChartRows", 0, "Maximum number of rows in the i2 chart (0 for no limit)")
+	outputPath := flags.String("output", "chart.xlsx", "Path of the Excel file (the ANX file has the same name with the .anx extension)")
+	flags.Parse(args)
+
+	if len(*jobPath) == 0 {
+		fatal(errors.New("-job must be given"), "Invalid arguments")
+	}
+
+	conf, err := readJobConfiguration(*jobPath)
+	if err != nil {
+		fatal(err, "Failed to read the job request")
+	}
+
+	chartBuilder, err := i2chart.NewI2ChartBuilder(*i2ConfigPath)
+	if err != nil {
+		fatal(err, "Failed to create chart builder")
+	}
+
+	if err := chartBuilder.SetMaxRows(*maxChartRows); err != nil {
+		fatal(err, "Failed to set the maximum number of rows in an i2 chart")
+	}
+
+	if len(*keywordsPath) > 0 {
+		keywords, err := i2chart.ReadDeploymentKeywords(*keywordsPath)
+		if err != nil {
+			fatal(err, "Failed to read the deployment keywords")
+		}
+
+		if err := chartBuilder.SetDeploymentKeywords(keywords); err != nil {
+			fatal(err, "Failed to set the deployment keywords")
+		}
+	}
+
+	builder := openGraphs(*dataConfigPath)
+	defer closeGraphs(builder)
+
+	chartBuilder.SetBipartite(builder.Bipartite)
+	chartBuilder.SetUnipartite(builder.Unipartite)
+
+	conns, err := findJobPaths(newPathFinder(builder, *maxPaths), conf)
+	if err != nil {
+		fatal(err, "Failed to find the paths")
+	}
+	defer conns.Close()
+
+	if !conns.HasAnyConnections() {
+		fatal(ErrNoResults, "No i2 chart to export")
+	}
+
+	table, droppedRows, err := chartBuilder.BuildWithLogger(conns, logging.Logger)
+	if err != nil {
+		fatal(err, "Failed to build the i2 chart")
+	}
+
+	var summary [][]string
+	if droppedRows > 0 {
+		summary = i2chart.TruncationSummary(len(table)-1, droppedRows, chartBuilder.MaxRows())
+	}
+
+	if err := i2chart.WriteToExcelWithSummary(*outputPath, table, summary); err != nil {
+		fatal(err, "Failed to write the Excel file")
+	}
+
+	chart, err := chartBuilder.TableToAnx(table)
+	if err != nil {
+		fatal(err, "Failed to convert the i2 chart")
+	}
+
+	anxPath := strings.TrimSuffix(*outputPath, filepath.Ext(*outputPath)) + ".anx"
+	if err := i2chart.WriteToAnx(anxPath, chart); err != nil {
+		fatal(err, "Failed to write the ANX file")
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("excel", *outputPath).
+		Str("anx", anxPath).
+		Int("numberOfPaths", conns.NumberOfPaths()).
+		Msg("Exported the i2 chart")
+}
+
+func main() {
+
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	commands := map[string]func([]string){
+		buildCommand:       runBuild,
+		statsCommand:       runStats,
+		findPathCommand:    runFindPath,
+		spiderCommand:      runSpider,
+		exportChartCommand: runExportChart,
+	}
+
+	command, found := commands[os.Args[1]]
+	if !found {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+
+	command(os.Args[2:])
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphbuilder"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cdclaxton/shortest-path-web-app/snapshot"
+)
+
+// Component name used in logging
+const componentName = "snapshotTool"
+
+func main() {
+
+	dataConfigPath := flag.String("data", "data-config.json", "Path to the config.json file")
+	exportPath := flag.String("export", "", "Path of the snapshot archive to export the graphs to")
+	importPath := flag.String("import", "", "Path of the snapshot archive to import the graphs from")
+
+	flag.Parse()
+
+	if (len(*exportPath) == 0) == (len(*importPath) == 0) {
+		logging.Logger.Fatal().
+			Str(logging.ComponentField, componentName).
+			Msg("Exactly one of -export or -import must be given")
+	}
+
+	// Read the graph config to get the location of the stores
+	config, err := graphbuilder.ReadGraphConfigFromJson(*dataConfigPath)
+	if err != nil {
+		logging.Logger.Fatal().
+			Str(logging.ComponentField, componentName).
+			Str("filepath", *dataConfigPath).
+			Err(err).
+			Msg("Failed to read graph config")
+	}
+
+	if len(*exportPath) > 0 {
+		if err := snapshot.Export(*config, *exportPath); err != nil {
+			logging.Logger.Fatal().
+				Str(logging.ComponentField, componentName).
+				Err(err).
+				Msg("Failed to export snapshot")
+		}
+
+		logging.Logger.Info().
+			Str(logging.ComponentField, componentName).
+			Str("filepath", *exportPath).
+			Msg("Snapshot exported")
+		return
+	}
+
+	manifest, err := snapshot.Import(*importPath, *config)
+	if err != nil {
+		logging.Logger.Fatal().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to import snapshot")
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("filepath", *importPath).
+		Time("dateCreated", manifest.DateCreated).
+		Msg("Snapshot imported")
+}
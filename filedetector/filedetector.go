@@ -9,6 +9,9 @@ import (
 	"io/fs"
 	"os"
 	"path"
+	"path/filepath"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/cdclaxton/shortest-path-web-app/logging"
@@ -29,6 +32,38 @@ type FileSignatureInfo struct {
 	DateCreated time.Time      `json:"dateCreated"` // Date and time the signature was created
 }
 
+// DataProvenance identifies the drop of input data from which a graph was built.
+type DataProvenance struct {
+	Signature   string    `json:"signature"`   // Signature of all of the input files (blank if unknown)
+	SourceFiles []string  `json:"sourceFiles"` // Sorted names of the input files
+	Loaded      time.Time `json:"loaded"`      // When the input files were loaded
+}
+
+// Known returns true if the provenance of the data is known.
+func (d DataProvenance) Known() bool {
+	return len(d.Signature) > 0
+}
+
+// Provenance of the data given the signatures of its files. The signature of the data is a hash of
+// the names and signatures of the files, so it doesn't depend on the folder holding the files.
+func (f *FileSignatureInfo) Provenance() DataProvenance {
+
+	lines := make([]string, 0, len(f.Signatures))
+	sourceFiles := make([]string, 0, len(f.Signatures))
+	for file, signature := range f.Signatures {
+		lines = append(lines, filepath.Base(file)+" "+signature)
+		sourceFiles = append(sourceFiles, filepath.Base(file))
+	}
+	sort.Strings(lines)
+	sort.Strings(sourceFiles)
+
+	return DataProvenance{
+		Signature:   fmt.Sprintf("%x", sha256.Sum256([]byte(strings.Join(lines, "\n")))),
+		SourceFiles: sourceFiles,
+		Loaded:      f.DateCreated.UTC().Round(0),
+	}
+}
+
 // FilesChanged detects whether the a list of files have changed based on their file hash.
 func FilesChanged(filepaths []string, signatureFilepath string) (bool, *FileSignatureInfo, error) {
 
@@ -149,6 +184,11 @@ func WriteFileSignatures(sigInfo *FileSignatureInfo, filepath string) error {
 	return os.WriteFile(filepath, data, 0644)
 }
 
+// ReadFileSignatures reads the file signature information written by WriteFileSignatures.
+func ReadFileSignatures(filepath string) (*FileSignatureInfo, error) {
+	return readFileSignatures(filepath)
+}
+
 // readFileSignatures reads the file signature information from a JSON file.
 func readFileSignatures(filepath string) (*FileSignatureInfo, error) {
 
@@ -184,6 +224,11 @@ func readFileSignatures(filepath string) (*FileSignatureInfo, error) {
 	return &fileSignatureInfo, nil
 }
 
+// GenerateFileSignatures of the files, which are identified by their paths.
+func GenerateFileSignatures(filepaths []string) (*FileSignatureInfo, error) {
+	return generateSignaturesOfFiles(filepaths)
+}
+
 func generateSignaturesOfFiles(filepaths []string) (*FileSignatureInfo, error) {
 
 	sig := FileSignatures{}
@@ -318,3 +318,31 @@ func TestReadFileSignaturesFileDoesNotExist(t *testing.T) {
 	assert.Nil(t, sig)
 	assert.ErrorIs(t, err, ErrSignatureFileDoesNotExist)
 }
+
+func TestProvenance(t *testing.T) {
+
+	sigInfo, err := GenerateFileSignatures([]string{
+		"./test-data/test-3/b.txt",
+		"./test-data/test-3/a.txt",
+	})
+	assert.NoError(t, err)
+
+	provenance := sigInfo.Provenance()
+	assert.True(t, provenance.Known())
+	assert.Equal(t, 64, len(provenance.Signature))
+	assert.Equal(t, []string{"a.txt", "b.txt"}, provenance.SourceFiles)
+	assert.Equal(t, sigInfo.DateCreated.UTC().Round(0), provenance.Loaded)
+
+	// The signature doesn't depend on the folder of the files
+	moved := FileSignatureInfo{Signatures: FileSignatures{}}
+	for file, signature := range sigInfo.Signatures {
+		moved.Signatures[path.Join("/data/drop", path.Base(file))] = signature
+	}
+	assert.Equal(t, provenance.Signature, moved.Provenance().Signature)
+
+	// The signature changes if a file changes
+	moved.Signatures["/data/drop/a.txt"] = "0"
+	assert.NotEqual(t, provenance.Signature, moved.Provenance().Signature)
+
+	assert.False(t, DataProvenance{}.Known())
+}
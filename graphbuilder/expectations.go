@@ -0,0 +1,200 @@
+package graphbuilder
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+)
+
+// The expectations of the graphs catch a broken data drop (e.g. an empty or truncated input file)
+// once the graphs have been built or loaded, rather than silently serving graphs that are missing
+// most of their data.
+
+var (
+	ErrInvalidGraphExpectations = errors.New("invalid graph expectations")
+	ErrGraphExpectationsNotMet  = errors.New("graphs don't meet the expectations")
+)
+
+// Actions if the graphs don't meet the expectations
+const (
+	ExpectationActionFail = "fail" // Fail to build or load the graphs (the default)
+	ExpectationActionWarn = "warn" // Serve the graphs with a warning on the stats page
+)
+
+// EntityTypeExpectation is the expected proportion of the entities in the bipartite graph that are
+// of a type. An entity type with an expectation must be in the graph.
+type EntityTypeExpectation struct {
+	MinProportion float64 `json:"minProportion"` // Minimum proportion of the entities (0 to 1)
+	MaxProportion float64 `json:"maxProportion"` // Maximum proportion of the entities (0 for no maximum)
+}
+
+// GraphExpectations of the graphs built from a data drop.
+type GraphExpectations struct {
+	MinEntities  int                              `json:"minEntities"`  // Minimum number of entities in the bipartite graph
+	MinDocuments int                              `json:"minDocuments"` // Minimum number of documents in the bipartite graph
+	MinEdges     int                              `json:"minEdges"`     // Minimum number of connected pairs of entities in the unipartite graph
+	EntityTypes  map[string]EntityTypeExpectation `json:"entityTypes"`  // Expected entity types
+	Action       string                           `json:"action"`       // Action if the expectations aren't met
+}
+
+// Validate the expectations.
+func (e *GraphExpectations) Validate() error {
+
+	if e.MinEntities < 0 || e.MinDocuments < 0 || e.MinEdges < 0 {
+		return fmt.Errorf("%w: negative minimum", ErrInvalidGraphExpectations)
+	}
+
+	for entityType, expectation := range e.EntityTypes {
+		if expectation.MinProportion < 0 || expectation.MinProportion > 1 ||
+			expectation.MaxProportion < 0 || expectation.MaxProportion > 1 ||
+			(expectation.MaxProportion > 0 && expectation.MaxProportion < expectation.MinProportion) {
+			return fmt.Errorf("%w: proportion of entity type %v", ErrInvalidGraphExpectations, entityType)
+		}
+	}
+
+	if len(e.Action) > 0 && e.Action != ExpectationActionFail && e.Action != ExpectationActionWarn {
+		return fmt.Errorf("%w: unknown action %v", ErrInvalidGraphExpectations, e.Action)
+	}
+
+	return nil
+}
+
+// ReadGraphExpectations from a JSON file.
+func ReadGraphExpectations(filepath string) (*GraphExpectations, error) {
+
+	bytes, err := os.ReadFile(filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	var expectations GraphExpectations
+	if err := json.Unmarshal(bytes, &expectations); err != nil {
+		return nil, err
+	}
+
+	if err := expectations.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &expectations, nil
+}
+
+// CheckExpectations of the graphs, returning a description of each expectation that isn't met.
+func (gb *GraphBuilder) CheckExpectations(expectations *GraphExpectations) ([]string, error) {
+
+	failures := []string{}
+	stats := gb.Stats.Bipartite
+
+	if stats.NumberOfEntities < expectations.MinEntities {
+		failures = append(failures, fmt.Sprintf("%v entities in the bipartite graph (expected at least %v)",
+			stats.NumberOfEntities, expectations.MinEntities))
+	}
+
+	if stats.NumberOfDocuments < expectations.MinDocuments {
+		failures = append(failures, fmt.Sprintf("%v documents in the bipartite graph (expected at least %v)",
+			stats.NumberOfDocuments, expectations.MinDocuments))
+	}
+
+	if expectations.MinEdges > 0 {
+		edges, err := graphstore.CountConnectedPairs(gb.Unipartite)
+		if err != nil {
+			return nil, err
+		}
+
+		if edges < expectations.MinEdges {
+			failures = append(failures, fmt.Sprintf("%v edges in the unipartite graph (expected at least %v)",
+				edges, expectations.MinEdges))
+		}
+	}
+
+	if len(expectations.EntityTypes) > 0 {
+		counts, err := graphstore.CountEntityTypes(gb.Bipartite)
+		if err != nil {
+			return nil, err
+		}
+
+		failures = append(failures, entityTypeFailures(expectations.EntityTypes, counts,
+			stats.NumberOfEntities)...)
+	}
+
+	return failures, nil
+}
+
+// entityTypeFailures of the expected entity types given the number of entities of each type, in
+// the order of the entity types.
+func entityTypeFailures(expected map[string]EntityTypeExpectation, counts map[string]int,
+	numberOfEntities int) []string {
+
+	entityTypes := []string{}
+	for entityType := range expected {
+		entityTypes = append(entityTypes, entityType)
+	}
+	sort.Strings(entityTypes)
+
+	failures := []string{}
+	for _, entityType := range entityTypes {
+		expectation := expected[entityType]
+		count := counts[entityType]
+
+		if count == 0 {
+			failures = append(failures, fmt.Sprintf("no entities of type %v", entityType))
+			continue
+		}
+
+		proportion := float64(count) / float64(numberOfEntities)
+		if proportion < expectation.MinProportion ||
+			(expectation.MaxProportion > 0 && proportion > expectation.MaxProportion) {
+			failures = append(failures, fmt.Sprintf(
+				"%.1f%% of the entities are of type %v (expected %.1f%% to %.1f%%)", 100*proportion,
+				entityType, 100*expectation.MinProportion, 100*maxProportion(expectation)))
+		}
+	}
+
+	return failures
+}
+
+// maxProportion of the entities of a type, where zero means there isn't a maximum.
+func maxProportion(expectation EntityTypeExpectation) float64 {
+	if expectation.MaxProportion == 0 {
+		return 1
+	}
+	return expectation.MaxProportion
+}
+
+// checkExpectations of the graphs in the config's expectations file (if there is one). The graphs
+// fail if they don't meet the expectations, unless the action is to warn, in which case the
+// failures are recorded in the stats.
+func (gb *GraphBuilder) checkExpectations(config GraphConfig) error {
+
+	if len(config.ExpectationsFile) == 0 {
+		return nil
+	}
+
+	expectations, err := ReadGraphExpectations(config.ExpectationsFile)
+	if err != nil {
+		return err
+	}
+
+	failures, err := gb.CheckExpectations(expectations)
+	if err != nil || len(failures) == 0 {
+		return err
+	}
+
+	if expectations.Action == ExpectationActionWarn {
+		logging.Logger.Warn().
+			Str(logging.ComponentField, componentName).
+			Strs("failures", failures).
+			Msg("Graphs don't meet the expectations")
+
+		gb.Stats.Warnings = failures
+		return nil
+	}
+
+	return fmt.Errorf("%w: %v", ErrGraphExpectationsNotMet, strings.Join(failures, "; "))
+}
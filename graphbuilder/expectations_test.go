@@ -0,0 +1,101 @@
+package graphbuilder
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGraphExpectationsValidate(t *testing.T) {
+	testCases := []struct {
+		expectations GraphExpectations
+		valid        bool
+	}{
+		{expectations: GraphExpectations{}, valid: true},
+		{expectations: GraphExpectations{MinEntities: 10, MinDocuments: 5, MinEdges: 1,
+			Action: ExpectationActionWarn}, valid: true},
+		{expectations: GraphExpectations{EntityTypes: map[string]EntityTypeExpectation{
+			"Person": {MinProportion: 0.2, MaxProportion: 0.8}}}, valid: true},
+		{expectations: GraphExpectations{MinEntities: -1}, valid: false},
+		{expectations: GraphExpectations{EntityTypes: map[string]EntityTypeExpectation{
+			"Person": {MinProportion: 1.5}}}, valid: false},
+		{expectations: GraphExpectations{EntityTypes: map[string]EntityTypeExpectation{
+			"Person": {MinProportion: 0.5, MaxProportion: 0.2}}}, valid: false},
+		{expectations: GraphExpectations{Action: "ignore"}, valid: false},
+	}
+
+	for _, testCase := range testCases {
+		err := testCase.expectations.Validate()
+		if testCase.valid {
+			assert.NoError(t, err)
+		} else {
+			assert.ErrorIs(t, err, ErrInvalidGraphExpectations)
+		}
+	}
+}
+
+func TestEntityTypeFailures(t *testing.T) {
+	expected := map[string]EntityTypeExpectation{
+		"Person":  {MinProportion: 0.5},
+		"Vehicle": {MinProportion: 0.1, MaxProportion: 0.2},
+		"Address": {},
+	}
+
+	failures := entityTypeFailures(expected, map[string]int{"Person": 6, "Vehicle": 4}, 10)
+	assert.Equal(t, []string{
+		"no entities of type Address",
+		"40.0% of the entities are of type Vehicle (expected 10.0% to 20.0%)",
+	}, failures)
+
+	failures = entityTypeFailures(expected, map[string]int{"Person": 4, "Vehicle": 1, "Address": 5}, 10)
+	assert.Equal(t, []string{
+		"40.0% of the entities are of type Person (expected 50.0% to 100.0%)",
+	}, failures)
+}
+
+// graphConfigWithExpectations of the in-memory test graphs with the expectations in a file.
+func graphConfigWithExpectations(t *testing.T, expectations string) GraphConfig {
+	configFilepath := "../test-data-sets/set-0/config-inmemory.json"
+
+	config, err := readGraphConfig(configFilepath)
+	assert.NoError(t, err)
+	makePathsRelativeToConfig(configFilepath, config)
+
+	config.ExpectationsFile = filepath.Join(t.TempDir(), "expectations.json")
+	assert.NoError(t, os.WriteFile(config.ExpectationsFile, []byte(expectations), 0644))
+
+	return *config
+}
+
+func TestNewGraphBuilderWithExpectations(t *testing.T) {
+
+	// The expectations are met
+	config := graphConfigWithExpectations(t,
+		`{"minEntities": 4, "minEdges": 1, "entityTypes": {"Person": {"minProportion": 0.9}}}`)
+	builder, _, err := NewGraphBuilder(config)
+	assert.NoError(t, err)
+	assert.Nil(t, builder.Stats.Warnings)
+
+	// The graphs fail to build
+	config = graphConfigWithExpectations(t,
+		`{"minEntities": 100, "entityTypes": {"Vehicle": {}}}`)
+	_, _, err = NewGraphBuilder(config)
+	assert.ErrorIs(t, err, ErrGraphExpectationsNotMet)
+
+	// The graphs are built with warnings
+	config = graphConfigWithExpectations(t,
+		`{"minEntities": 100, "entityTypes": {"Vehicle": {}}, "action": "warn"}`)
+	builder, _, err = NewGraphBuilder(config)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"4 entities in the bipartite graph (expected at least 100)",
+		"no entities of type Vehicle",
+	}, builder.Stats.Warnings)
+
+	// Invalid expectations
+	config = graphConfigWithExpectations(t, `{"action": "ignore"}`)
+	_, _, err = NewGraphBuilder(config)
+	assert.ErrorIs(t, err, ErrInvalidGraphExpectations)
+}
@@ -0,0 +1,357 @@
+package graphbuilder
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+)
+
+// The graphs can be built in generations, so that a new data drop is built into a new versioned
+// folder whilst the current generation is served. Once the new generation has been built and
+// validated it becomes the current generation. The previous generation is kept so that the server
+// can roll back to it and older generations are deleted.
+
+var (
+	ErrGenerationsRequirePersistentStores = errors.New("generations require writable Pebble or bbolt stores")
+	ErrGenerationNotFound                 = errors.New("generation not found")
+	ErrNoPreviousGeneration               = errors.New("no previous generation")
+)
+
+const (
+	generationsStateFile   = "generations.json"  // File recording the current and previous generations
+	generationFolderFormat = "generation-%06d"   // Folder of a generation given its number
+	generationSignatures   = "signatures.json"   // Signature file of a generation
+	generationCheckpoint   = "checkpoint.json"   // Conversion checkpoint file of a generation
+	generationBipartite    = "bipartite"         // Folder of a generation's bipartite store
+	generationUnipartite   = "unipartite"        // Folder of a generation's unipartite store
+	generationFallback     = "unipartite-pebble" // Folder of a generation's fallback unipartite store
+)
+
+// GenerationsState records the current and previous generations (zero if there isn't one).
+type GenerationsState struct {
+	Current  int `json:"current"`
+	Previous int `json:"previous"`
+}
+
+// Generations of the graphs in a folder.
+type Generations struct {
+	folder string      // Folder containing a folder for each generation
+	config GraphConfig // Config of the graphs, whose stores are placed in a generation's folder
+
+	state     GenerationsState
+	stateLock sync.Mutex
+}
+
+// NewGenerations of the graphs defined by the config in the folder, which is created if required.
+func NewGenerations(config GraphConfig, folder string) (*Generations, error) {
+
+	if !isPersistentStorageType(config.BipartiteConfig.Type) ||
+		!isPersistentStorageType(config.UnipartiteConfig.Type) || isReadOnly(config) {
+		return nil, ErrGenerationsRequirePersistentStores
+	}
+
+	if err := os.MkdirAll(folder, 0755); err != nil {
+		return nil, err
+	}
+
+	g := Generations{
+		folder: folder,
+		config: config,
+	}
+
+	bytes, err := os.ReadFile(filepath.Join(folder, generationsStateFile))
+	if err == nil {
+		err = json.Unmarshal(bytes, &g.state)
+	} else if os.IsNotExist(err) {
+		err = nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("folder", folder).
+		Int("current", g.state.Current).
+		Int("previous", g.state.Previous).
+		Msg("Opened the generations of the graphs")
+
+	return &g, nil
+}
+
+// NewGenerationsFromJson config file of the graphs in the folder.
+func NewGenerationsFromJson(configFilepath string, folder string) (*Generations, error) {
+
+	config, err := ReadGraphConfigFromJson(configFilepath)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewGenerations(*config, folder)
+}
+
+// State of the generations.
+func (g *Generations) State() GenerationsState {
+	g.stateLock.Lock()
+	defer g.stateLock.Unlock()
+
+	return g.state
+}
+
+// setState of the generations, which is saved to the state file.
+func (g *Generations) setState(state GenerationsState) error {
+
+	bytes, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	// Replace the state file in one step, so it is never half-written
+	statePath := filepath.Join(g.folder, generationsStateFile)
+	if err := os.WriteFile(statePath+".tmp", bytes, 0644); err != nil {
+		return err
+	}
+
+	if err := os.Rename(statePath+".tmp", statePath); err != nil {
+		return err
+	}
+
+	g.state = state
+	return nil
+}
+
+// generationFolder of the generation.
+func (g *Generations) generationFolder(generation int) string {
+	return filepath.Join(g.folder, fmt.Sprintf(generationFolderFormat, generation))
+}
+
+// generationConfig of the graphs, whose stores and files are in the generation's folder.
+func (g *Generations) generationConfig(generation int) GraphConfig {
+
+	folder := g.generationFolder(generation)
+
+	config := g.config
+	config.BipartiteConfig.Folder = filepath.Join(folder, generationBipartite)
+	config.BipartiteConfig.DeleteFilesInFolder = true
+	config.UnipartiteConfig.Folder = filepath.Join(folder, generationUnipartite)
+	config.UnipartiteConfig.DeleteFilesInFolder = true
+	config.SignatureFile = filepath.Join(folder, generationSignatures)
+
+	if len(config.UnipartiteConfig.FallbackFolder) > 0 {
+		config.UnipartiteConfig.FallbackFolder = filepath.Join(folder, generationFallback)
+	}
+
+	if len(config.ConversionCheckpointFile) > 0 {
+		config.ConversionCheckpointFile = filepath.Join(folder, generationCheckpoint)
+	}
+
+	return config
+}
+
+// List the generations in the folder in ascending order.
+func (g *Generations) List() ([]int, error) {
+
+	entries, err := os.ReadDir(g.folder)
+	if err != nil {
+		return nil, err
+	}
+
+	generations := []int{}
+	for _, entry := range entries {
+		var generation int
+		if !entry.IsDir() {
+			continue
+		}
+
+		if _, err := fmt.Sscanf(entry.Name(), generationFolderFormat, &generation); err == nil &&
+			entry.Name() == fmt.Sprintf(generationFolderFormat, generation) {
+			generations = append(generations, generation)
+		}
+	}
+
+	sort.Ints(generations)
+	return generations, nil
+}
+
+// IsBuildRequired returns true if there isn't a current generation or the input files have changed
+// since the current generation was built.
+func (g *Generations) IsBuildRequired() (bool, error) {
+
+	current := g.State().Current
+	if current == 0 {
+		return true, nil
+	}
+
+	build, _, err := isGraphBuildingRequired(g.generationConfig(current))
+	return build, err
+}
+
+// Build the next generation of the graphs from the input files and validate it. The generation
+// isn't served until it is activated. A generation that fails to build or validate is deleted.
+func (g *Generations) Build() (*GraphBuilder, int, error) {
+
+	generations, err := g.List()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	generation := g.State().Current + 1
+	if len(generations) > 0 && generations[len(generations)-1] >= generation {
+		generation = generations[len(generations)-1] + 1
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Int("generation", generation).
+		Msg("Building a new generation of the graphs")
+
+	folder := g.generationFolder(generation)
+	config := g.generationConfig(generation)
+	for _, storeFolder := range []string{config.BipartiteConfig.Folder, config.UnipartiteConfig.Folder} {
+		if err := os.MkdirAll(storeFolder, 0755); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	builder, _, err := NewGraphBuilder(config)
+	if err == nil {
+		err = validateGeneration(builder)
+		if err != nil {
+			builder.Close()
+		}
+	}
+
+	if err != nil {
+		logging.Logger.Error().
+			Str(logging.ComponentField, componentName).
+			Int("generation", generation).
+			Err(err).
+			Msg("Failed to build the new generation of the graphs")
+
+		os.RemoveAll(folder)
+		return nil, 0, err
+	}
+
+	return builder, generation, nil
+}
+
+// validateGeneration of the graphs by checking the stores can be read. The graph builder has
+// already checked the graphs aren't empty.
+func validateGeneration(builder *GraphBuilder) error {
+
+	if err := graphstore.CheckBipartiteReadable(builder.Bipartite); err != nil {
+		return err
+	}
+
+	return graphstore.CheckUnipartiteReadable(builder.Unipartite)
+}
+
+// Open the graphs of a generation that has already been built.
+func (g *Generations) Open(generation int) (*GraphBuilder, error) {
+
+	if _, err := os.Stat(g.generationFolder(generation)); err != nil {
+		return nil, ErrGenerationNotFound
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Int("generation", generation).
+		Msg("Opening a generation of the graphs")
+
+	config := g.generationConfig(generation)
+	builder, err := loadGraph(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := builder.prepare(config, nil, false); err != nil {
+		builder.Close()
+		return nil, err
+	}
+
+	return builder, nil
+}
+
+// Activate the generation, i.e. make it the current generation and the current generation the
+// previous one.
+func (g *Generations) Activate(generation int) error {
+
+	if _, err := os.Stat(g.generationFolder(generation)); err != nil {
+		return ErrGenerationNotFound
+	}
+
+	g.stateLock.Lock()
+	defer g.stateLock.Unlock()
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Int("generation", generation).
+		Int("previous", g.state.Current).
+		Msg("Activating a generation of the graphs")
+
+	return g.setState(GenerationsState{
+		Current:  generation,
+		Previous: g.state.Current,
+	})
+}
+
+// Rollback to the previous generation, which swaps the current and previous generations. The
+// generation rolled back to is returned.
+func (g *Generations) Rollback() (int, error) {
+
+	g.stateLock.Lock()
+	defer g.stateLock.Unlock()
+
+	if g.state.Previous == 0 {
+		return 0, ErrNoPreviousGeneration
+	}
+
+	logging.Logger.Warn().
+		Str(logging.ComponentField, componentName).
+		Int("generation", g.state.Previous).
+		Int("rolledBackFrom", g.state.Current).
+		Msg("Rolling back to the previous generation of the graphs")
+
+	state := GenerationsState{
+		Current:  g.state.Previous,
+		Previous: g.state.Current,
+	}
+
+	return state.Current, g.setState(state)
+}
+
+// RemoveUnused generations, i.e. all of the generations except the current and previous ones.
+// Their graphs must have been closed.
+func (g *Generations) RemoveUnused() error {
+
+	generations, err := g.List()
+	if err != nil {
+		return err
+	}
+
+	state := g.State()
+	for _, generation := range generations {
+		if generation == state.Current || generation == state.Previous {
+			continue
+		}
+
+		logging.Logger.Info().
+			Str(logging.ComponentField, componentName).
+			Int("generation", generation).
+			Msg("Removing an unused generation of the graphs")
+
+		if err := os.RemoveAll(g.generationFolder(generation)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
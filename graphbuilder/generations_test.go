@@ -0,0 +1,117 @@
+package graphbuilder
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// generationsConfig of the test graphs stored in Pebble.
+func generationsConfig(t *testing.T) GraphConfig {
+	configFilepath := "../test-data-sets/set-0/config-pebble.json"
+
+	config, err := readGraphConfig(configFilepath)
+	assert.NoError(t, err)
+	makePathsRelativeToConfig(configFilepath, config)
+
+	return *config
+}
+
+func TestNewGenerationsRequiresPersistentStores(t *testing.T) {
+	config := generationsConfig(t)
+	config.UnipartiteConfig.Type = StorageTypeInMemory
+
+	_, err := NewGenerations(config, t.TempDir())
+	assert.ErrorIs(t, err, ErrGenerationsRequirePersistentStores)
+
+	config = generationsConfig(t)
+	config.BipartiteConfig.ReadOnly = true
+
+	_, err = NewGenerations(config, t.TempDir())
+	assert.ErrorIs(t, err, ErrGenerationsRequirePersistentStores)
+}
+
+func TestGenerations(t *testing.T) {
+	folder := t.TempDir()
+
+	generations, err := NewGenerations(generationsConfig(t), folder)
+	assert.NoError(t, err)
+	assert.Equal(t, GenerationsState{}, generations.State())
+
+	build, err := generations.IsBuildRequired()
+	assert.NoError(t, err)
+	assert.True(t, build)
+
+	_, err = generations.Rollback()
+	assert.ErrorIs(t, err, ErrNoPreviousGeneration)
+
+	// Build the first generation
+	builder1, generation, err := generations.Build()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, generation)
+	assert.Equal(t, 4, builder1.Stats.Bipartite.NumberOfEntities)
+	assert.NoError(t, generations.Activate(generation))
+	assert.Equal(t, GenerationsState{Current: 1}, generations.State())
+
+	build, err = generations.IsBuildRequired()
+	assert.NoError(t, err)
+	assert.False(t, build)
+
+	// Build two more generations, so the first is no longer needed
+	builder2, generation, err := generations.Build()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, generation)
+	assert.NoError(t, generations.Activate(generation))
+	assert.NoError(t, builder1.Close())
+
+	builder3, generation, err := generations.Build()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, generation)
+	assert.NoError(t, generations.Activate(generation))
+	assert.Equal(t, GenerationsState{Current: 3, Previous: 2}, generations.State())
+
+	assert.NoError(t, generations.RemoveUnused())
+	list, err := generations.List()
+	assert.NoError(t, err)
+	assert.Equal(t, []int{2, 3}, list)
+
+	// Roll back to the second generation
+	generation, err = generations.Rollback()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, generation)
+	assert.Equal(t, GenerationsState{Current: 2, Previous: 3}, generations.State())
+
+	assert.NoError(t, builder2.Close())
+	assert.NoError(t, builder3.Close())
+
+	// The state is persisted and a generation can be reopened
+	generations, err = NewGenerations(generationsConfig(t), folder)
+	assert.NoError(t, err)
+	assert.Equal(t, GenerationsState{Current: 2, Previous: 3}, generations.State())
+
+	builder, err := generations.Open(2)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, builder.Stats.Bipartite.NumberOfEntities)
+	assert.NoError(t, builder.Close())
+
+	_, err = generations.Open(1)
+	assert.ErrorIs(t, err, ErrGenerationNotFound)
+	assert.ErrorIs(t, generations.Activate(1), ErrGenerationNotFound)
+}
+
+func TestGenerationsFailedBuild(t *testing.T) {
+	config := generationsConfig(t)
+	config.Data.EntitiesFiles[0].Path = "missing.csv"
+
+	generations, err := NewGenerations(config, t.TempDir())
+	assert.NoError(t, err)
+
+	_, _, err = generations.Build()
+	assert.Error(t, err)
+
+	// The failed generation is removed
+	list, err := generations.List()
+	assert.NoError(t, err)
+	assert.Empty(t, list)
+	assert.Equal(t, GenerationsState{}, generations.State())
+}
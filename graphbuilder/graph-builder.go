@@ -13,6 +13,8 @@ import (
 	"github.com/cdclaxton/shortest-path-web-app/graphloader"
 	"github.com/cdclaxton/shortest-path-web-app/graphstore"
 	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cdclaxton/shortest-path-web-app/normalisation"
+	"github.com/cdclaxton/shortest-path-web-app/set"
 )
 
 const componentName = "graphBuilder"
@@ -21,21 +23,42 @@ const (
 	DataDirectory            = "data"              // Location for the input entities and document files
 	StorageTypeInMemory      = "memory"            // In-memory storage
 	StorageTypePebble        = "pebble"            // Pebble storage
+	StorageTypeBolt          = "bolt"              // bbolt storage
 	UseTempFolder            = "<TEMP>"            // Denotes that a temporary folder should be made for Pebble files
 	TempBipartiteFolderName  = "pebble-bipartite"  // Temporary folder name (prefix) for the bipartite store
 	TempUnipartiteFolderName = "pebble-unipartite" // Temporary folder name (prefix) for the unipartite store
 )
 
 var (
-	ErrNoEntitiesOrDocuments = errors.New("no entities and/or documents")
+	ErrNoEntitiesOrDocuments     = errors.New("no entities and/or documents")
+	ErrNoRetentionPolicy         = errors.New("no retention policy")
+	ErrBulkIngestRequiresPebble  = errors.New("bulk ingest requires the bipartite graph to be stored in Pebble")
+	ErrBulkIngestRequiresReplace = errors.New("bulk ingest requires the replace entity merge strategy")
+	ErrChainsWithRetention       = errors.New("chains can't be collapsed in a graph with a retention policy")
+	ErrChainsFileNotFound        = errors.New("collapsed chains file doesn't exist")
 )
 
+// isPersistentStorageType returns true if the storage type persists the graph on disk.
+func isPersistentStorageType(storageType string) bool {
+	return storageType == StorageTypePebble || storageType == StorageTypeBolt
+}
+
 // GraphData specifies the location of the input data to read.
 type GraphData struct {
 	EntitiesFiles    []graphloader.EntitiesCsvFile  `json:"entitiesFiles"`
 	DocumentsFiles   []graphloader.DocumentsCsvFile `json:"documentsFiles"`
 	LinksFiles       []graphloader.LinksCsvFile     `json:"linksFiles"`
 	SkipEntitiesFile string                         `json:"skipEntitiesFile"` // File path to the entities to skip
+
+	// Optional mapping of raw entity IDs to resolved IDs
+	EntityIdMappingFile *graphloader.EntityIdMappingFile `json:"entityIdMappingFile,omitempty"`
+
+	// Optional normalisation of the (resolved) entity IDs, which is also applied to the entity IDs
+	// entered by a user
+	EntityIdNormalisation *normalisation.Config `json:"entityIdNormalisation,omitempty"`
+
+	// Optional alias IDs of the entities, e.g. legacy identifiers of merged entities
+	AliasesFile *graphloader.AliasesCsvFile `json:"aliasesFile,omitempty"`
 }
 
 // createTempBipartitePebbleFolder in the default temp directory for the operating system.
@@ -78,6 +101,34 @@ func prepareFolderForStorage(folder string, graphStoreType string, deleteFilesIn
 	return nil
 }
 
+// openBipartiteGraph opens a persistent bipartite graph store in the folder.
+func openBipartiteGraph(storageType string, folder string, readOnly bool,
+	tuning graphstore.PebbleTuning) (graphstore.BipartiteGraphStore, error) {
+	if readOnly {
+		return graphstore.NewReadOnlyPebbleBipartiteGraphStoreWithTuning(folder, tuning)
+	}
+
+	if storageType == StorageTypeBolt {
+		return graphstore.NewBoltBipartiteGraphStore(folder)
+	}
+
+	return graphstore.NewPebbleBipartiteGraphStoreWithTuning(folder, tuning)
+}
+
+// openUnipartiteGraph opens a persistent unipartite graph store in the folder.
+func openUnipartiteGraph(storageType string, folder string, readOnly bool,
+	tuning graphstore.PebbleTuning) (graphstore.UnipartiteGraphStore, error) {
+	if readOnly {
+		return graphstore.NewReadOnlyPebbleUnipartiteGraphStoreWithTuning(folder, tuning)
+	}
+
+	if storageType == StorageTypeBolt {
+		return graphstore.NewBoltUnipartiteGraphStore(folder)
+	}
+
+	return graphstore.NewPebbleUnipartiteGraphStoreWithTuning(folder, tuning)
+}
+
 // makeBipartiteGraph given the bipartite graph storage config.
 func makeBipartiteGraph(config BipartiteGraphConfig) (graphstore.BipartiteGraphStore, error) {
 
@@ -89,7 +140,7 @@ func makeBipartiteGraph(config BipartiteGraphConfig) (graphstore.BipartiteGraphS
 	if config.Type == StorageTypeInMemory {
 		return graphstore.NewInMemoryBipartiteGraphStore(), nil
 
-	} else if config.Type == StorageTypePebble {
+	} else if isPersistentStorageType(config.Type) {
 
 		// If the config specifies that a temporary folder should be used, then make the folder
 		if config.Folder == UseTempFolder {
@@ -111,12 +162,38 @@ func makeBipartiteGraph(config BipartiteGraphConfig) (graphstore.BipartiteGraphS
 			return nil, err
 		}
 
-		return graphstore.NewPebbleBipartiteGraphStore(config.Folder)
+		return openBipartiteGraph(config.Type, config.Folder, false, config.Pebble)
 	}
 
 	return nil, fmt.Errorf("unknown bipartite graph storage type: %v", config.Type)
 }
 
+// enableBulkIngest on the bipartite graph store if required by the config.
+func enableBulkIngest(graph graphstore.BipartiteGraphStore, config GraphConfig) error {
+
+	if !config.BipartiteConfig.BulkIngest {
+		return nil
+	}
+
+	store, ok := graph.(*graphstore.PebbleBipartiteGraphStore)
+	if !ok {
+		return ErrBulkIngestRequiresPebble
+	}
+
+	// Entities can't be merged, because the buffered entities can't be read
+	if len(config.EntityMergeStrategy) > 0 &&
+		config.EntityMergeStrategy != graphloader.MergeStrategyReplace {
+		return ErrBulkIngestRequiresReplace
+	}
+
+	bufferBytes := graphstore.DefaultIngestBufferBytes
+	if config.BipartiteConfig.BulkIngestBufferMB > 0 {
+		bufferBytes = config.BipartiteConfig.BulkIngestBufferMB << 20
+	}
+
+	return store.EnableBulkIngest(bufferBytes)
+}
+
 // makeUnipartiteGraph given the unipartite graph storage config.
 func makeUnipartiteGraph(config UnipartiteGraphConfig) (graphstore.UnipartiteGraphStore, error) {
 
@@ -126,9 +203,13 @@ func makeUnipartiteGraph(config UnipartiteGraphConfig) (graphstore.UnipartiteGra
 		Msg("Making unipartite graph store")
 
 	if config.Type == StorageTypeInMemory {
-		return graphstore.NewInMemoryUnipartiteGraphStore(), nil
+		graph := graphstore.NewInMemoryUnipartiteGraphStore()
+		if err := graph.SetMemoryBudget(int64(config.MemoryBudgetMB) << 20); err != nil {
+			return nil, err
+		}
+		return graph, nil
 
-	} else if config.Type == StorageTypePebble {
+	} else if isPersistentStorageType(config.Type) {
 
 		// If the config specifies that a temporary folder should be used, then make the folder
 		if config.Folder == UseTempFolder {
@@ -150,7 +231,7 @@ func makeUnipartiteGraph(config UnipartiteGraphConfig) (graphstore.UnipartiteGra
 			return nil, err
 		}
 
-		return graphstore.NewPebbleUnipartiteGraphStore(config.Folder)
+		return openUnipartiteGraph(config.Type, config.Folder, false, config.Pebble)
 	}
 
 	return nil, fmt.Errorf("unknown unipartite graph storage type: %v", config.Type)
@@ -158,16 +239,32 @@ func makeUnipartiteGraph(config UnipartiteGraphConfig) (graphstore.UnipartiteGra
 
 // BipartiteGraphConfig to instantiate a bipartite graph store.
 type BipartiteGraphConfig struct {
-	Type                string `json:"type"`                // Backend type (in-memory or Pebble)
-	Folder              string `json:"folder"`              // Folder for the Pebble store
+	Type                string `json:"type"`                // Backend type (in-memory, Pebble or bbolt)
+	Folder              string `json:"folder"`              // Folder for the Pebble or bbolt store
 	DeleteFilesInFolder bool   `json:"deleteFilesInFolder"` // Clear down the folder if it isn't empty
+	ReadOnly            bool   `json:"readOnly"`            // Open a pre-built Pebble store read-only
+	BulkIngest          bool   `json:"bulkIngest"`          // Build a Pebble store by ingesting SSTables
+	BulkIngestBufferMB  int    `json:"bulkIngestBufferMB"`  // Bulk ingest buffer in MB (0 for the default)
+
+	Pebble graphstore.PebbleTuning `json:"pebble"` // Tuning of the Pebble options (optional)
 }
 
 // UnipartiteGraphConfig to instantiate a unipartite graph store.
 type UnipartiteGraphConfig struct {
-	Type                string `json:"type"`                // Backend type (in-memory or Pebble)
-	Folder              string `json:"folder"`              // Folder for the Pebble store
+	Type                string `json:"type"`                // Backend type (in-memory, Pebble or bbolt)
+	Folder              string `json:"folder"`              // Folder for the Pebble or bbolt store
 	DeleteFilesInFolder bool   `json:"deleteFilesInFolder"` // Clear down the folder if it isn't empty
+	ReadOnly            bool   `json:"readOnly"`            // Open a pre-built Pebble store read-only
+	MemoryBudgetMB      int    `json:"memoryBudgetMB"`      // Memory budget of an in-memory store (0 for no limit)
+	FallbackFolder      string `json:"fallbackFolder"`      // Pebble folder if the memory budget is exceeded
+
+	Pebble graphstore.PebbleTuning `json:"pebble"` // Tuning of the Pebble options (optional)
+}
+
+// isReadOnly returns true if the graphs are to be opened in read-only mode, i.e. the graphs have
+// been built by another process and are shared.
+func isReadOnly(config GraphConfig) bool {
+	return config.BipartiteConfig.ReadOnly || config.UnipartiteConfig.ReadOnly
 }
 
 // GraphConfig for the input data, bipartite and unipartite graphs.
@@ -176,12 +273,41 @@ type GraphConfig struct {
 	BipartiteConfig        BipartiteGraphConfig  `json:"bipartiteGraphConfig"`
 	UnipartiteConfig       UnipartiteGraphConfig `json:"unipartiteGraphConfig"`
 	IgnoreInvalidLinks     bool                  `json:"ignoreInvalidLinks"`
+	EntityMergeStrategy    string                `json:"entityMergeStrategy"`
 	NumEntityWorkers       int                   `json:"numEntityWorkers"`
 	NumDocumentWorkers     int                   `json:"numDocumentWorkers"`
 	NumLinkWorkers         int                   `json:"numLinkWorkers"`
 	NumConversionWorkers   int                   `json:"numConversionWorkers"`
 	ConversionJobQueuesize int                   `json:"conversionJobQueueSize"`
 	SignatureFile          string                `json:"signatureFile"`
+	Directed               bool                  `json:"directed"` // Preserve the direction of links
+
+	// Optional document date recorded in the metadata of the unipartite graph's edges
+	DocumentDateAttribute string `json:"documentDateAttribute"`
+	DocumentDateFormat    string `json:"documentDateFormat"`
+
+	// Optional document attribute holding the name of the CSV file the document was loaded from
+	DocumentSourceAttribute string `json:"documentSourceAttribute"`
+
+	// Maximum number of edges remembered so that each edge is written once during the conversion
+	// (0 for the default, -1 to disable)
+	ConversionDedupCapacity int `json:"conversionDedupCapacity"`
+
+	// Optional checkpointing of the bipartite to unipartite conversion for persistent stores
+	ConversionCheckpointFile     string `json:"conversionCheckpointFile"`
+	ConversionCheckpointInterval int    `json:"conversionCheckpointInterval"`
+
+	// Optional faults injected into the loaded graphs (for a staging environment only)
+	FaultInjection *graphstore.FaultConfig `json:"faultInjection"`
+
+	// Optional maximum age of the documents of each type
+	RetentionPolicy *graphstore.RetentionPolicy `json:"retentionPolicy"`
+
+	// Optional JSON file of the expectations of the graphs (relative to the config file)
+	ExpectationsFile string `json:"expectationsFile"`
+
+	// Optional pruning of the unipartite graph after it is built
+	UnipartitePruning *graphstore.UnipartitePruneOptions `json:"unipartitePruning"`
 }
 
 // readGraphConfig from a JSON file.
@@ -250,12 +376,40 @@ func makePathsRelativeToConfig(configFilepath string, graphConfig *GraphConfig)
 	// Skip file
 	graphConfig.Data.SkipEntitiesFile = makePathRelative(
 		graphConfig.Data.SkipEntitiesFile, configFilepath)
+
+	// Entity ID mapping file
+	if graphConfig.Data.EntityIdMappingFile != nil {
+		graphConfig.Data.EntityIdMappingFile.Path = makePathRelative(
+			graphConfig.Data.EntityIdMappingFile.Path, configFilepath)
+	}
+
+	// Aliases file
+	if graphConfig.Data.AliasesFile != nil {
+		graphConfig.Data.AliasesFile.Path = makePathRelative(
+			graphConfig.Data.AliasesFile.Path, configFilepath)
+	}
+
+	// Expectations file (which is alongside the config file rather than the data)
+	if len(graphConfig.ExpectationsFile) > 0 && !filepath.IsAbs(graphConfig.ExpectationsFile) {
+		graphConfig.ExpectationsFile = filepath.Join(filepath.Dir(configFilepath),
+			graphConfig.ExpectationsFile)
+	}
+
+	// Collapsed chains file (which is alongside the config file rather than the data)
+	if graphConfig.UnipartitePruning != nil && len(graphConfig.UnipartitePruning.ChainsFile) > 0 &&
+		!filepath.IsAbs(graphConfig.UnipartitePruning.ChainsFile) {
+		graphConfig.UnipartitePruning.ChainsFile = filepath.Join(filepath.Dir(configFilepath),
+			graphConfig.UnipartitePruning.ChainsFile)
+	}
 }
 
 // GraphStats holds summary information about the bipartite and unipartite graphs.
 type GraphStats struct {
 	Bipartite  graphstore.BipartiteStats
 	Unipartite graphstore.UnipartiteStats
+	Types      graphstore.TypeCounts       // Number of entities and documents of each type
+	Provenance filedetector.DataProvenance // Data drop from which the graphs were built
+	Warnings   []string                    // Expectations of the graphs that aren't met
 }
 
 // GraphBuilder component to build the bipartite and unipartite graphs.
@@ -263,6 +417,155 @@ type GraphBuilder struct {
 	Bipartite  graphstore.BipartiteGraphStore
 	Unipartite graphstore.UnipartiteGraphStore
 	Stats      GraphStats
+	Lock       *graphstore.StoreLock  // Coordinates the readers of the stores with the pruning
+	Chains     *graphstore.ChainIndex // Chains collapsed into edges of the unipartite graph (optional)
+	config     GraphConfig            // Config from which the graphs were built
+}
+
+// defaultCheckpointInterval is the number of documents between conversion checkpoints if the
+// interval isn't specified in the config.
+const defaultCheckpointInterval = 100000
+
+// conversionOptions for the bipartite to unipartite conversion.
+func conversionOptions(config GraphConfig) graphstore.ConversionOptions {
+	return graphstore.ConversionOptions{
+		Directed:      config.Directed,
+		DateAttribute: config.DocumentDateAttribute,
+		DateFormat:    config.DocumentDateFormat,
+		DedupCapacity: config.ConversionDedupCapacity,
+	}
+}
+
+// checkpointConfig for the bipartite to unipartite conversion or nil if checkpointing isn't
+// possible because the graphs aren't persisted.
+func checkpointConfig(config GraphConfig) *graphstore.CheckpointConfig {
+
+	if len(config.ConversionCheckpointFile) == 0 ||
+		!isPersistentStorageType(config.BipartiteConfig.Type) ||
+		!isPersistentStorageType(config.UnipartiteConfig.Type) {
+		return nil
+	}
+
+	interval := config.ConversionCheckpointInterval
+	if interval < 1 {
+		interval = defaultCheckpointInterval
+	}
+
+	return &graphstore.CheckpointConfig{
+		Filepath: config.ConversionCheckpointFile,
+		Interval: interval,
+	}
+}
+
+// canResumeConversion returns true if there is a checkpoint from which the conversion of the
+// bipartite graph to the unipartite graph can be resumed.
+func canResumeConversion(config GraphConfig) (bool, error) {
+
+	checkpointConf := checkpointConfig(config)
+	if checkpointConf == nil {
+		return false, nil
+	}
+
+	checkpoint, err := graphstore.ReadConversionCheckpoint(checkpointConf.Filepath)
+	if err != nil {
+		return false, err
+	}
+
+	return checkpoint != nil, nil
+}
+
+// readEntityResolver from the entity ID mapping file (if one is configured).
+func readEntityResolver(config GraphConfig) (*graphloader.EntityResolver, error) {
+	if config.Data.EntityIdMappingFile == nil {
+		return nil, nil
+	}
+
+	return graphloader.ReadEntityResolver(*config.Data.EntityIdMappingFile)
+}
+
+// readEntityIdNormaliser from the config, or nil if the entity IDs aren't normalised.
+func readEntityIdNormaliser(config GraphConfig) (*normalisation.Normaliser, error) {
+	if config.Data.EntityIdNormalisation == nil {
+		return nil, nil
+	}
+
+	return normalisation.New(*config.Data.EntityIdNormalisation)
+}
+
+// readSkipEntities that aren't transferred to the unipartite graph.
+func readSkipEntities(config GraphConfig) (*set.Set[string], error) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Msg("Reading the entities to skip")
+
+	skipEntities, err := graphloader.ReadSkipEntities(config.Data.SkipEntitiesFile)
+	if err != nil {
+		return nil, err
+	}
+
+	// The entities to skip may use raw entity IDs
+	resolver, err := readEntityResolver(config)
+	if err != nil {
+		return nil, err
+	}
+
+	normaliser, err := readEntityIdNormaliser(config)
+	if err != nil {
+		return nil, err
+	}
+
+	return set.NewPopulatedSet(normaliser.NormaliseAll(resolver.ResolveAll(skipEntities).ToSlice())...), nil
+}
+
+// convertToUnipartite converts the bipartite graph to the unipartite graph.
+func (gb *GraphBuilder) convertToUnipartite(config GraphConfig) error {
+
+	skipEntities, err := readSkipEntities(config)
+	if err != nil {
+		return err
+	}
+
+	// Convert the bipartite graph to a unipartite graph
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Msg("Converting the bipartite graph to a unipartite graph")
+
+	startTime := time.Now()
+	err = graphstore.BipartiteToUnipartiteWithCheckpoints(gb.Bipartite, gb.Unipartite, skipEntities,
+		config.NumConversionWorkers, config.ConversionJobQueuesize, conversionOptions(config),
+		checkpointConfig(config))
+	if err != nil {
+		return err
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("timeTaken", time.Since(startTime).String()).
+		Msg("Time taken to perform bipartite to unipartite conversion")
+
+	return nil
+}
+
+// resumeGraphBuild opens the existing graph stores and resumes the conversion of the bipartite
+// graph to the unipartite graph from the checkpoint.
+func resumeGraphBuild(config GraphConfig) (*GraphBuilder, error) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("checkpointFile", config.ConversionCheckpointFile).
+		Msg("Resuming graph build from conversion checkpoint")
+
+	builder, err := loadGraph(config)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := builder.convertToUnipartite(config); err != nil {
+		return nil, err
+	}
+
+	return builder, nil
 }
 
 func loadAndBuildNewGraph(config GraphConfig) (*GraphBuilder, error) {
@@ -280,6 +583,10 @@ func loadAndBuildNewGraph(config GraphConfig) (*GraphBuilder, error) {
 		return nil, err
 	}
 
+	if err := enableBulkIngest(builder.Bipartite, config); err != nil {
+		return nil, err
+	}
+
 	// Load the bipartite graph based on the files
 	logging.Logger.Info().
 		Str(logging.ComponentField, componentName).
@@ -293,27 +600,44 @@ func loadAndBuildNewGraph(config GraphConfig) (*GraphBuilder, error) {
 		config.IgnoreInvalidLinks,
 		config.NumEntityWorkers, config.NumDocumentWorkers, config.NumLinkWorkers)
 
+	err = bipartiteLoader.SetEntityMergeStrategy(config.EntityMergeStrategy)
+	if err != nil {
+		return nil, err
+	}
+
+	resolver, err := readEntityResolver(config)
+	if err != nil {
+		return nil, err
+	}
+	bipartiteLoader.SetEntityResolver(resolver)
+
+	normaliser, err := readEntityIdNormaliser(config)
+	if err != nil {
+		return nil, err
+	}
+	bipartiteLoader.SetEntityIdNormaliser(normaliser)
+	bipartiteLoader.SetDocumentSourceAttribute(config.DocumentSourceAttribute)
+
 	startTime := time.Now()
 	err = bipartiteLoader.Load()
 	if err != nil {
 		return nil, err
 	}
 
+	// Load the aliases of the entities (which must be in the store)
+	if config.Data.AliasesFile != nil {
+		_, err = graphloader.LoadAliases(builder.Bipartite, *config.Data.AliasesFile, resolver,
+			normaliser)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	logging.Logger.Info().
 		Str(logging.ComponentField, componentName).
 		Str("timeTaken", time.Since(startTime).String()).
 		Msg("Time taken to load the bipartite graph")
 
-	// Read the entities to skip
-	logging.Logger.Info().
-		Str(logging.ComponentField, componentName).
-		Msg("Reading the entities to skip")
-
-	skipEntities, err := graphloader.ReadSkipEntities(config.Data.SkipEntitiesFile)
-	if err != nil {
-		return nil, err
-	}
-
 	// Make the unipartite graph store
 	logging.Logger.Info().
 		Str(logging.ComponentField, componentName).
@@ -325,41 +649,76 @@ func loadAndBuildNewGraph(config GraphConfig) (*GraphBuilder, error) {
 	}
 
 	// Convert the bipartite graph to a unipartite graph
-	logging.Logger.Info().
-		Str(logging.ComponentField, componentName).
-		Msg("Converting the bipartite graph to a unipartite graph")
+	err = builder.convertToUnipartite(config)
+	if errors.Is(err, graphstore.ErrMemoryBudgetExceeded) {
+		err = builder.convertToPebbleFallback(config, err)
+	}
 
-	startTime = time.Now()
-	err = graphstore.BipartiteToUnipartite(builder.Bipartite, builder.Unipartite, skipEntities,
-		config.NumConversionWorkers, config.ConversionJobQueuesize)
 	if err != nil {
 		return nil, err
 	}
 
-	logging.Logger.Info().
+	return &builder, nil
+}
+
+// convertToPebbleFallback converts the bipartite graph to a unipartite graph held in Pebble after
+// the memory budget of the in-memory unipartite store was exceeded. If there isn't a fallback
+// folder, the build fails with a message explaining how to fix the config.
+func (gb *GraphBuilder) convertToPebbleFallback(config GraphConfig, budgetErr error) error {
+
+	if len(config.UnipartiteConfig.FallbackFolder) == 0 {
+		return fmt.Errorf("%w: increase memoryBudgetMB or set fallbackFolder in the "+
+			"unipartiteGraphConfig to build the unipartite graph in Pebble", budgetErr)
+	}
+
+	logging.Logger.Warn().
 		Str(logging.ComponentField, componentName).
-		Str("timeTaken", time.Since(startTime).String()).
-		Msg("Time taken to perform bipartite to unipartite conversion")
+		Int("memoryBudgetMB", config.UnipartiteConfig.MemoryBudgetMB).
+		Str("fallbackFolder", config.UnipartiteConfig.FallbackFolder).
+		Msg("Memory budget exceeded, so switching the unipartite graph to Pebble")
 
-	return &builder, nil
+	if err := gb.Unipartite.Destroy(); err != nil {
+		return err
+	}
+
+	config.UnipartiteConfig = UnipartiteGraphConfig{
+		Type:                StorageTypePebble,
+		Folder:              config.UnipartiteConfig.FallbackFolder,
+		DeleteFilesInFolder: true,
+		Pebble:              config.UnipartiteConfig.Pebble,
+	}
+
+	var err error
+	gb.Unipartite, err = makeUnipartiteGraph(config.UnipartiteConfig)
+	if err != nil {
+		return err
+	}
+
+	return gb.convertToUnipartite(config)
 }
 
 var (
-	ErrBipartiteGraphIsNotPebble  = errors.New("bipartite graph is not stored in Pebble")
-	ErrUnipartiteGraphIsNotPebble = errors.New("unipartite graph is not stored in Pebble")
+	ErrBipartiteGraphIsNotPebble  = errors.New("bipartite graph is not stored in Pebble or bbolt")
+	ErrUnipartiteGraphIsNotPebble = errors.New("unipartite graph is not stored in Pebble or bbolt")
+	ErrReadOnlyRequiresPebble     = errors.New("read-only mode requires both graphs to be stored in Pebble")
 )
 
-// loadGraph from Pebble stores given the config.
+// loadGraph from persistent (Pebble or bbolt) stores given the config.
 func loadGraph(config GraphConfig) (*GraphBuilder, error) {
 
-	if config.BipartiteConfig.Type != StorageTypePebble {
+	if !isPersistentStorageType(config.BipartiteConfig.Type) {
 		return nil, ErrBipartiteGraphIsNotPebble
 	}
 
-	if config.UnipartiteConfig.Type != StorageTypePebble {
+	if !isPersistentStorageType(config.UnipartiteConfig.Type) {
 		return nil, ErrUnipartiteGraphIsNotPebble
 	}
 
+	if isReadOnly(config) && (config.BipartiteConfig.Type != StorageTypePebble ||
+		config.UnipartiteConfig.Type != StorageTypePebble) {
+		return nil, ErrReadOnlyRequiresPebble
+	}
+
 	builder := GraphBuilder{}
 
 	logging.Logger.Info().
@@ -368,7 +727,8 @@ func loadGraph(config GraphConfig) (*GraphBuilder, error) {
 		Msg("Opening bipartite graph store")
 
 	var err error
-	builder.Bipartite, err = graphstore.NewPebbleBipartiteGraphStore(config.BipartiteConfig.Folder)
+	builder.Bipartite, err = openBipartiteGraph(config.BipartiteConfig.Type, config.BipartiteConfig.Folder,
+		isReadOnly(config), config.BipartiteConfig.Pebble)
 	if err != nil {
 		return nil, err
 	}
@@ -378,7 +738,8 @@ func loadGraph(config GraphConfig) (*GraphBuilder, error) {
 		Str("graphStoreType", config.UnipartiteConfig.Type).
 		Msg("Opening unipartite graph store")
 
-	builder.Unipartite, err = graphstore.NewPebbleUnipartiteGraphStore(config.UnipartiteConfig.Folder)
+	builder.Unipartite, err = openUnipartiteGraph(config.UnipartiteConfig.Type, config.UnipartiteConfig.Folder,
+		isReadOnly(config), config.UnipartiteConfig.Pebble)
 	if err != nil {
 		return nil, err
 	}
@@ -386,8 +747,43 @@ func loadGraph(config GraphConfig) (*GraphBuilder, error) {
 	return &builder, nil
 }
 
+// OpenGraphs opens the existing persistent graph stores given in the config without loading or
+// building them, e.g. to inspect the graphs with a tool. The chains collapsed when the unipartite
+// graph was built are read.
+func OpenGraphs(config GraphConfig) (*GraphBuilder, error) {
+
+	builder, err := loadGraph(config)
+	if err != nil || config.UnipartitePruning == nil {
+		return builder, err
+	}
+
+	if err := builder.pruneUnipartite(*config.UnipartitePruning, false); err != nil {
+		builder.Close()
+		return nil, err
+	}
+
+	return builder, nil
+}
+
 func NewGraphBuilder(config GraphConfig) (*GraphBuilder, bool, error) {
 
+	if config.RetentionPolicy != nil {
+		if err := config.RetentionPolicy.Validate(); err != nil {
+			return nil, false, err
+		}
+	}
+
+	if config.UnipartitePruning != nil {
+		if err := config.UnipartitePruning.Validate(); err != nil {
+			return nil, false, err
+		}
+
+		// Recomputing the edges of the pruned documents would bypass the collapsed chains
+		if config.UnipartitePruning.MinChainLength > 0 && config.RetentionPolicy != nil {
+			return nil, false, ErrChainsWithRetention
+		}
+	}
+
 	// Does the graph need loading or building?
 	build, sig, err := isGraphBuildingRequired(config)
 	if err != nil {
@@ -399,8 +795,19 @@ func NewGraphBuilder(config GraphConfig) (*GraphBuilder, bool, error) {
 		Bool("buildRequired", build).
 		Msg("Detected whether graph building is required")
 
-	var builder *GraphBuilder
+	// If a previous build was interrupted during the conversion, resume it
+	resume := false
 	if build {
+		resume, err = canResumeConversion(config)
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	var builder *GraphBuilder
+	if resume {
+		builder, err = resumeGraphBuild(config)
+	} else if build {
 		builder, err = loadAndBuildNewGraph(config)
 	} else {
 		builder, err = loadGraph(config)
@@ -439,49 +846,315 @@ func NewGraphBuilder(config GraphConfig) (*GraphBuilder, bool, error) {
 		}
 	}
 
+	if err := builder.prepare(config, sig, build); err != nil {
+		return nil, false, err
+	}
+
+	return builder, build, nil
+}
+
+// prepare the loaded or built graphs to be served by pruning the expired documents and the
+// unipartite graph, calculating the stats and checking the graphs aren't empty.
+func (gb *GraphBuilder) prepare(config GraphConfig, sig *filedetector.FileSignatureInfo,
+	build bool) error {
+
+	// Exclude the expired documents (a read-only graph is pruned by the process that built it)
+	gb.config = config
+	gb.Lock = graphstore.NewStoreLock()
+	if gb.HasRetentionPolicy() {
+		if _, err := gb.pruneExpiredDocuments(time.Now()); err != nil {
+			return err
+		}
+	}
+
+	// Prune the newly built unipartite graph or read the chains collapsed when it was built
+	if config.UnipartitePruning != nil {
+		if err := gb.pruneUnipartite(*config.UnipartitePruning, build); err != nil {
+			return err
+		}
+	}
+
 	// Calculate graph stats
 	logging.Logger.Info().
 		Str(logging.ComponentField, componentName).
 		Msg("Calculating bipartite and unipartite graph stats")
 
-	err = builder.CalculateStats()
-	if err != nil {
-		return nil, false, err
+	if err := gb.CalculateStats(); err != nil {
+		return err
 	}
 
-	if builder.Stats.Bipartite.NumberOfDocuments == 0 || builder.Stats.Bipartite.NumberOfEntities == 0 ||
-		builder.Stats.Unipartite.NumberOfEntities == 0 {
+	if gb.Stats.Bipartite.NumberOfDocuments == 0 || gb.Stats.Bipartite.NumberOfEntities == 0 ||
+		gb.Stats.Unipartite.NumberOfEntities == 0 {
 
 		logging.Logger.Error().
 			Str(logging.ComponentField, componentName).
-			Int("numDocsInBipartite", builder.Stats.Bipartite.NumberOfDocuments).
-			Int("numEntitiesInBipartite", builder.Stats.Bipartite.NumberOfEntities).
-			Int("numEntitiesInUnipartite", builder.Stats.Unipartite.NumberOfEntities).
+			Int("numDocsInBipartite", gb.Stats.Bipartite.NumberOfDocuments).
+			Int("numEntitiesInBipartite", gb.Stats.Bipartite.NumberOfEntities).
+			Int("numEntitiesInUnipartite", gb.Stats.Unipartite.NumberOfEntities).
 			Msg("No entities and/or documents")
 
-		return nil, false, ErrNoEntitiesOrDocuments
+		return ErrNoEntitiesOrDocuments
 	}
 
-	return builder, build, nil
+	gb.Stats.Provenance = dataProvenance(config, sig, build)
+
+	// Check the graphs look like a complete data drop
+	if err := gb.checkExpectations(config); err != nil {
+		return err
+	}
+
+	// Inject faults into the loaded graphs for a staging environment
+	if config.FaultInjection != nil {
+		if err := gb.injectFaults(*config.FaultInjection); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
-// NewGraphBuilderFromJson returns a constructed GraphBuilder based on the config from a JSON file.
-func NewGraphBuilderFromJson(filepath string) (*GraphBuilder, bool, error) {
+// HasRetentionPolicy returns true if the expired documents are pruned from the graphs, which
+// requires a retention policy and writable graphs.
+func (gb *GraphBuilder) HasRetentionPolicy() bool {
+	return gb.config.RetentionPolicy != nil && !isReadOnly(gb.config)
+}
+
+// pruneExpiredDocuments at time now from the graphs using the retention policy.
+func (gb *GraphBuilder) pruneExpiredDocuments(now time.Time) (graphstore.PruneResult, error) {
+
+	if !gb.HasRetentionPolicy() {
+		return graphstore.PruneResult{}, ErrNoRetentionPolicy
+	}
+
+	skipEntities, err := readSkipEntities(gb.config)
+	if err != nil {
+		return graphstore.PruneResult{}, err
+	}
+
+	return graphstore.PruneExpiredDocuments(gb.Bipartite, gb.Unipartite, skipEntities,
+		conversionOptions(gb.config), *gb.config.RetentionPolicy, now)
+}
+
+// pruneUnipartite graph using the options if it has just been built (and isn't read-only), writing
+// the collapsed chains to the chains file. Otherwise, the chains collapsed when the graph was built
+// are read from the chains file, which must exist. The unipartite graph is then replaced with a view
+// that expands the chains.
+func (gb *GraphBuilder) pruneUnipartite(options graphstore.UnipartitePruneOptions, build bool) error {
+
+	if build && !isReadOnly(gb.config) {
+		result, err := graphstore.PruneUnipartite(gb.Unipartite, options)
+		if err != nil {
+			return err
+		}
+
+		gb.Chains = result.Chains
+		if len(options.ChainsFile) > 0 {
+			if err := graphstore.WriteChainIndex(gb.Chains, options.ChainsFile); err != nil {
+				return err
+			}
+		}
+
+		return gb.expandChains(options.ChainsFile)
+	}
+
+	if options.MinChainLength == 0 {
+		return nil
+	}
+
+	chains, err := graphstore.ReadChainIndex(options.ChainsFile)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %v", ErrChainsFileNotFound, options.ChainsFile)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	gb.Chains = chains
+	return gb.expandChains(options.ChainsFile)
+}
+
+// expandChains collapsed into edges of the unipartite graph by wrapping it in a view that restores
+// the chains, so the collapsed edges are never read as links between the ends of the chains. The
+// path finder still searches the pruned graph. A chain released by a write to the view is removed
+// from the chains file.
+func (gb *GraphBuilder) expandChains(chainsFile string) error {
+
+	if gb.Chains.Len() == 0 {
+		return nil
+	}
 
 	logging.Logger.Info().
 		Str(logging.ComponentField, componentName).
-		Str("filepath", filepath).
-		Msg("Building graph from JSON config file")
+		Int("chains", gb.Chains.Len()).
+		Msg("Expanding the collapsed chains of the unipartite graph")
+
+	expanded, err := graphstore.NewExpandedUnipartiteGraphStore(gb.Unipartite, gb.Chains)
+	if err != nil {
+		return err
+	}
+
+	expanded.SetChainsFile(chainsFile)
+	gb.Unipartite = expanded
+	return nil
+}
+
+// EntityIdNormaliser of the graphs, which should be applied to the entity IDs entered by a user so
+// that they match the entity IDs in the graphs. Returns nil if the entity IDs aren't normalised.
+func (gb *GraphBuilder) EntityIdNormaliser() (*normalisation.Normaliser, error) {
+	return readEntityIdNormaliser(gb.config)
+}
+
+// PruneExpiredDocuments at time now from the graphs using the retention policy and recalculates
+// the graph stats. This is intended to be run on a schedule whilst the graphs are served, so the
+// jobs and queries reading the graphs are queued until the pruning has finished.
+func (gb *GraphBuilder) PruneExpiredDocuments(now time.Time) (graphstore.PruneResult, error) {
+
+	var result graphstore.PruneResult
+	err := gb.Lock.Update(func() error {
+		var err error
+		result, err = gb.pruneExpiredDocuments(now)
+		if err != nil {
+			return err
+		}
+
+		// Pruning keeps the entities, so only the bipartite stats change
+		if result.DocumentsRemoved > 0 {
+			return gb.updateStats(result.StatsChange, graphstore.UnipartiteStats{}, result.TypesChange)
+		}
+
+		return nil
+	})
+
+	return result, err
+}
+
+// RemoveEntity from the graphs whilst they are served, i.e. the entity and its links from the
+// bipartite graph and the entity and its edges from the unipartite graph. The jobs and queries
+// reading the graphs are queued until the entity has been removed. The graph stats aren't updated.
+func (gb *GraphBuilder) RemoveEntity(entityId string) error {
+	return gb.Lock.Update(func() error {
+		if err := gb.Bipartite.RemoveEntity(entityId); err != nil {
+			return err
+		}
+
+		// An entity without any edges isn't in the unipartite graph
+		err := gb.Unipartite.RemoveEntity(entityId)
+		if errors.Is(err, graphstore.ErrEntityNotFound) {
+			return nil
+		}
+
+		return err
+	})
+}
+
+// RemoveDocument and its links from the bipartite graph whilst it is served. The jobs and queries
+// reading the graphs are queued until the document has been removed. The unipartite graph isn't
+// updated, so the edges supported only by the document must be removed with RemoveEdge.
+func (gb *GraphBuilder) RemoveDocument(documentId string) error {
+	return gb.Lock.Update(func() error {
+		return gb.Bipartite.RemoveDocument(documentId)
+	})
+}
+
+// RemoveLink between an entity and a document from the bipartite graph whilst it is served. The
+// jobs and queries reading the graphs are queued until the link has been removed.
+func (gb *GraphBuilder) RemoveLink(link graphstore.Link) error {
+	return gb.Lock.Update(func() error {
+		return gb.Bipartite.RemoveLink(link)
+	})
+}
+
+// RemoveEdge between two entities from the unipartite graph whilst it is served. The jobs and
+// queries reading the graphs are queued until the edge has been removed.
+func (gb *GraphBuilder) RemoveEdge(src string, dst string) error {
+	return gb.Lock.Update(func() error {
+		return gb.Unipartite.RemoveEdge(src, dst)
+	})
+}
+
+// dataProvenance of the graphs. A graph that was built uses the signatures of its input files,
+// which are generated if they weren't needed to detect changes (e.g. the graph is held in memory).
+// A graph that was loaded uses the signature file of its build, so its provenance is unknown if
+// there isn't a signature file.
+func dataProvenance(config GraphConfig, sig *filedetector.FileSignatureInfo,
+	build bool) filedetector.DataProvenance {
+
+	var err error
+	if sig == nil && build {
+		sig, err = filedetector.GenerateFileSignatures(filesToCheck(config.Data))
+	} else if sig == nil && len(config.SignatureFile) > 0 {
+		sig, err = filedetector.ReadFileSignatures(config.SignatureFile)
+	}
+
+	if err != nil || sig == nil {
+		logging.Logger.Warn().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Provenance of the data is unknown")
+
+		return filedetector.DataProvenance{}
+	}
+
+	provenance := sig.Provenance()
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("signature", provenance.Signature).
+		Int("numberOfSourceFiles", len(provenance.SourceFiles)).
+		Time("loaded", provenance.Loaded).
+		Msg("Provenance of the data")
+
+	return provenance
+}
+
+// injectFaults by wrapping the bipartite and unipartite stores with fault injection stores.
+func (g *GraphBuilder) injectFaults(config graphstore.FaultConfig) error {
+
+	bipartite, err := graphstore.NewFaultyBipartiteGraphStore(g.Bipartite, config)
+	if err != nil {
+		return err
+	}
+
+	unipartite, err := graphstore.NewFaultyUnipartiteGraphStore(g.Unipartite, config)
+	if err != nil {
+		return err
+	}
+
+	g.Bipartite = bipartite
+	g.Unipartite = unipartite
+	return nil
+}
+
+// ReadGraphConfigFromJson reads the graph config from a JSON file, making the data file paths
+// relative to the location of the config file.
+func ReadGraphConfigFromJson(filepath string) (*GraphConfig, error) {
 
 	// Read the config from file
 	graphConfig, err := readGraphConfig(filepath)
 	if err != nil {
-		return nil, false, err
+		return nil, err
 	}
 
 	// Modify the data file paths to be based on the location of the config file
 	makePathsRelativeToConfig(filepath, graphConfig)
 
+	return graphConfig, nil
+}
+
+// NewGraphBuilderFromJson returns a constructed GraphBuilder based on the config from a JSON file.
+func NewGraphBuilderFromJson(filepath string) (*GraphBuilder, bool, error) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("filepath", filepath).
+		Msg("Building graph from JSON config file")
+
+	graphConfig, err := ReadGraphConfigFromJson(filepath)
+	if err != nil {
+		return nil, false, err
+	}
+
 	// Instantiate the graph builder
 	return NewGraphBuilder(*graphConfig)
 }
@@ -509,6 +1182,56 @@ func (gb *GraphBuilder) Destroy() error {
 	return gb.Bipartite.Destroy()
 }
 
+// Close the unipartite and bipartite graphs.
+func (gb *GraphBuilder) Close() error {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Msg("Closing the unipartite and bipartite graphs")
+
+	if err := gb.Unipartite.Close(); err != nil {
+		return err
+	}
+
+	return gb.Bipartite.Close()
+}
+
+// UpdateStats of the graphs with the changes made by an incremental update (e.g. pruning
+// documents), rather than recalculating them from the whole of the graphs. The updated stats are
+// recorded in the stores that hold their stats, so that the stats are still known when the graphs
+// are next loaded. The jobs and queries reading the graphs are queued until they are recorded.
+func (gb *GraphBuilder) UpdateStats(bipartiteChange graphstore.BipartiteStats,
+	unipartiteChange graphstore.UnipartiteStats, typesChange graphstore.TypeCounts) error {
+
+	return gb.Lock.Update(func() error {
+		return gb.updateStats(bipartiteChange, unipartiteChange, typesChange)
+	})
+}
+
+// updateStats of the graphs with the changes of an incremental update, where the caller holds the
+// lock of the stores for updating.
+func (gb *GraphBuilder) updateStats(bipartiteChange graphstore.BipartiteStats,
+	unipartiteChange graphstore.UnipartiteStats, typesChange graphstore.TypeCounts) error {
+
+	gb.Stats.Bipartite = gb.Stats.Bipartite.Add(bipartiteChange)
+	gb.Stats.Unipartite = gb.Stats.Unipartite.Add(unipartiteChange)
+	gb.Stats.Types = gb.Stats.Types.Add(typesChange)
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Int("numDocuments", gb.Stats.Bipartite.NumberOfDocuments).
+		Int("numEntities", gb.Stats.Bipartite.NumberOfEntities).
+		Int("numEntitiesInUnipartite", gb.Stats.Unipartite.NumberOfEntities).
+		Msg("Updated the graph stats")
+
+	if err := graphstore.RecordBipartiteStats(gb.Bipartite, gb.Stats.Bipartite,
+		gb.Stats.Types); err != nil {
+		return err
+	}
+
+	return graphstore.RecordUnipartiteStats(gb.Unipartite, gb.Stats.Unipartite)
+}
+
 // CalculateStats for the bipartite and unipartite graphs.
 func (gb *GraphBuilder) CalculateStats() error {
 
@@ -526,6 +1249,12 @@ func (gb *GraphBuilder) CalculateStats() error {
 		Int("numEntitiesWithDocuments", bipartiteStats.NumberOfEntitiesWithDocuments).
 		Msg("Calculated bipartite graph stats")
 
+	// Number of entities and documents of each type
+	types, err := graphstore.CountTypes(gb.Bipartite)
+	if err != nil {
+		return err
+	}
+
 	// Unipartite graph stats
 	unipartiteStats, err := graphstore.CalcUnipartiteStats(gb.Unipartite)
 	if err != nil {
@@ -541,6 +1270,7 @@ func (gb *GraphBuilder) CalculateStats() error {
 	gb.Stats = GraphStats{
 		Bipartite:  bipartiteStats,
 		Unipartite: unipartiteStats,
+		Types:      types,
 	}
 
 	return nil
@@ -1,10 +1,12 @@
 package graphbuilder
 
 import (
+	"encoding/json"
 	"fmt"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/cdclaxton/shortest-path-web-app/graphloader"
 	"github.com/cdclaxton/shortest-path-web-app/graphstore"
@@ -150,6 +152,15 @@ func TestMakePathsRelativeToConfig(t *testing.T) {
 				},
 			},
 			SkipEntitiesFile: "skip.txt",
+			EntityIdMappingFile: &graphloader.EntityIdMappingFile{
+				Path: "mapping.csv",
+			},
+			AliasesFile: &graphloader.AliasesCsvFile{
+				Path: "aliases.csv",
+			},
+		},
+		UnipartitePruning: &graphstore.UnipartitePruneOptions{
+			ChainsFile: "chains.json",
 		},
 	}
 
@@ -170,6 +181,18 @@ func TestMakePathsRelativeToConfig(t *testing.T) {
 	// Check the skip entities file
 	assert.Equal(t, filepath.FromSlash("../config/data/skip.txt"),
 		graphConfig.Data.SkipEntitiesFile)
+
+	// Check the entity ID mapping file
+	assert.Equal(t, filepath.FromSlash("../config/data/mapping.csv"),
+		graphConfig.Data.EntityIdMappingFile.Path)
+
+	// Check the aliases file
+	assert.Equal(t, filepath.FromSlash("../config/data/aliases.csv"),
+		graphConfig.Data.AliasesFile.Path)
+
+	// Check the collapsed chains file (which is alongside the config file)
+	assert.Equal(t, filepath.FromSlash("../config/chains.json"),
+		graphConfig.UnipartitePruning.ChainsFile)
 }
 
 // buildExpectedBipartiteStore for sets 0 and 2
@@ -317,6 +340,10 @@ func TestGraphBuilderValidConfig(t *testing.T) {
 			// Pebble
 			configFilepath: "../test-data-sets/set-0/config-pebble.json",
 		},
+		{
+			// bbolt
+			configFilepath: "../test-data-sets/set-0/config-bolt.json",
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -344,7 +371,18 @@ func TestGraphBuilderValidConfig(t *testing.T) {
 			assert.True(t, equal)
 			assert.Equal(t, "", reason)
 
+			// Check the provenance of the data
+			provenance := graphBuilder.Stats.Provenance
+			assert.Equal(t, 64, len(provenance.Signature))
+			assert.Equal(t, []string{"documents_0.csv", "documents_1.csv", "entities_0.csv",
+				"entities_1.csv", "links_0.csv", "links_1.csv", "skip_entities.txt"},
+				provenance.SourceFiles)
+			assert.False(t, provenance.Loaded.IsZero())
+
 			// Check the stats
+			expectedTypes, err := graphstore.CountTypes(expectedBipartite)
+			assert.NoError(t, err)
+
 			expectedStats := GraphStats{
 				Bipartite: graphstore.BipartiteStats{
 					NumberOfEntities:              4,
@@ -355,6 +393,8 @@ func TestGraphBuilderValidConfig(t *testing.T) {
 				Unipartite: graphstore.UnipartiteStats{
 					NumberOfEntities: 4,
 				},
+				Types:      expectedTypes,
+				Provenance: provenance,
 			}
 			assert.Equal(t, expectedStats, graphBuilder.Stats)
 
@@ -439,3 +479,418 @@ func TestNewGraphBuilderWithLoad(t *testing.T) {
 	assert.NoError(t, os.Mkdir("../working/bipartitePebble", 0644))
 	assert.NoError(t, os.Mkdir("../working/unipartitePebble", 0644))
 }
+
+func TestNewGraphBuilderReadOnly(t *testing.T) {
+	configFilepath := "../test-data-sets/set-0/config-pebble.json"
+
+	// Build the Pebble stores in known folders
+	config, err := readGraphConfig(configFilepath)
+	assert.NoError(t, err)
+	makePathsRelativeToConfig(configFilepath, config)
+
+	config.BipartiteConfig.Folder = t.TempDir()
+	config.UnipartiteConfig.Folder = t.TempDir()
+	config.SignatureFile = filepath.Join(t.TempDir(), "signatures.json")
+
+	graphBuilder, build, err := NewGraphBuilder(*config)
+	assert.NoError(t, err)
+	assert.True(t, build)
+	assert.True(t, graphBuilder.Stats.Provenance.Known())
+
+	assert.NoError(t, graphBuilder.Bipartite.Finalise())
+	assert.NoError(t, graphBuilder.Unipartite.Finalise())
+	assert.NoError(t, graphBuilder.Bipartite.(*graphstore.PebbleBipartiteGraphStore).Close())
+	assert.NoError(t, graphBuilder.Unipartite.(*graphstore.PebbleUnipartiteGraphStore).Close())
+
+	// Open the stores read-only from two 'replicas'
+	config.BipartiteConfig.ReadOnly = true
+	config.UnipartiteConfig.ReadOnly = true
+
+	replica1, build, err := NewGraphBuilder(*config)
+	assert.NoError(t, err)
+	assert.False(t, build)
+
+	replica2, build, err := NewGraphBuilder(*config)
+	assert.NoError(t, err)
+	assert.False(t, build)
+
+	assert.Equal(t, graphBuilder.Stats, replica1.Stats)
+	assert.Equal(t, graphBuilder.Stats, replica2.Stats)
+
+	// The shared stores can't be destroyed
+	assert.ErrorIs(t, replica1.Destroy(), graphstore.ErrStoreIsReadOnly)
+
+	for _, replica := range []*GraphBuilder{replica1, replica2} {
+		assert.NoError(t, replica.Bipartite.(*graphstore.PebbleBipartiteGraphStore).Close())
+		assert.NoError(t, replica.Unipartite.(*graphstore.PebbleUnipartiteGraphStore).Close())
+	}
+
+	// Read-only mode isn't supported for bbolt
+	config.UnipartiteConfig.Type = StorageTypeBolt
+	_, _, err = NewGraphBuilder(*config)
+	assert.ErrorIs(t, err, ErrReadOnlyRequiresPebble)
+}
+
+func TestNewGraphBuilderResumeConversion(t *testing.T) {
+	configFilepath := "../test-data-sets/set-0/config-pebble.json"
+
+	config, err := readGraphConfig(configFilepath)
+	assert.NoError(t, err)
+	makePathsRelativeToConfig(configFilepath, config)
+
+	config.BipartiteConfig.Folder = t.TempDir()
+	config.UnipartiteConfig.Folder = t.TempDir()
+	config.ConversionCheckpointFile = filepath.Join(t.TempDir(), "checkpoint.json")
+	config.ConversionCheckpointInterval = 1
+
+	// Build the graphs, which should remove the checkpoint on completion
+	graphBuilder, build, err := NewGraphBuilder(*config)
+	assert.NoError(t, err)
+	assert.True(t, build)
+
+	_, err = os.Stat(config.ConversionCheckpointFile)
+	assert.True(t, os.IsNotExist(err))
+
+	numberOfDocuments, err := graphBuilder.Bipartite.NumberOfDocuments()
+	assert.NoError(t, err)
+
+	assert.NoError(t, graphBuilder.Unipartite.Clear())
+	assert.NoError(t, graphBuilder.Bipartite.Finalise())
+	assert.NoError(t, graphBuilder.Unipartite.Finalise())
+	assert.NoError(t, graphBuilder.Bipartite.(*graphstore.PebbleBipartiteGraphStore).Close())
+	assert.NoError(t, graphBuilder.Unipartite.(*graphstore.PebbleUnipartiteGraphStore).Close())
+
+	// Simulate a build that was interrupted before any documents were converted
+	checkpoint, err := json.Marshal(graphstore.ConversionCheckpoint{
+		TotalDocuments: numberOfDocuments,
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(config.ConversionCheckpointFile, checkpoint, 0644))
+
+	resume, err := canResumeConversion(*config)
+	assert.NoError(t, err)
+	assert.True(t, resume)
+
+	// The conversion should be resumed using the existing bipartite graph
+	resumed, build, err := NewGraphBuilder(*config)
+	assert.NoError(t, err)
+	assert.True(t, build)
+	assert.Equal(t, graphBuilder.Stats.Bipartite, resumed.Stats.Bipartite)
+	assert.Equal(t, graphBuilder.Stats.Unipartite, resumed.Stats.Unipartite)
+	assert.Equal(t, graphBuilder.Stats.Provenance.Signature, resumed.Stats.Provenance.Signature)
+
+	_, err = os.Stat(config.ConversionCheckpointFile)
+	assert.True(t, os.IsNotExist(err))
+
+	assert.NoError(t, resumed.Bipartite.(*graphstore.PebbleBipartiteGraphStore).Close())
+	assert.NoError(t, resumed.Unipartite.(*graphstore.PebbleUnipartiteGraphStore).Close())
+
+	// Checkpointing isn't possible if a graph is held in memory
+	config.UnipartiteConfig.Type = StorageTypeInMemory
+	assert.Nil(t, checkpointConfig(*config))
+}
+
+func TestNewGraphBuilderWithFaultInjection(t *testing.T) {
+
+	config, err := ReadGraphConfigFromJson("../test-data-sets/set-0/config-inmemory.json")
+	assert.NoError(t, err)
+	config.FaultInjection = &graphstore.FaultConfig{FailEvery: 1}
+
+	// The graphs are loaded before the faults are injected
+	graphBuilder, build, err := NewGraphBuilder(*config)
+	assert.NoError(t, err)
+	assert.True(t, build)
+	defer graphBuilder.Destroy()
+
+	assert.IsType(t, &graphstore.FaultyBipartiteGraphStore{}, graphBuilder.Bipartite)
+	assert.IsType(t, &graphstore.FaultyUnipartiteGraphStore{}, graphBuilder.Unipartite)
+
+	_, err = graphBuilder.Unipartite.HasEntity("e-1")
+	assert.ErrorIs(t, err, graphstore.ErrInjectedFault)
+
+	// Invalid configuration
+	config.FaultInjection = &graphstore.FaultConfig{FailEvery: -1}
+	_, _, err = NewGraphBuilder(*config)
+	assert.ErrorIs(t, err, graphstore.ErrInvalidFaultConfig)
+}
+
+func TestNewGraphBuilderWithMemoryBudget(t *testing.T) {
+
+	config, err := ReadGraphConfigFromJson("../test-data-sets/set-0/config-inmemory.json")
+	assert.NoError(t, err)
+
+	// Invalid memory budget
+	config.UnipartiteConfig.MemoryBudgetMB = -1
+	_, _, err = NewGraphBuilder(*config)
+	assert.ErrorIs(t, err, graphstore.ErrInvalidMemoryBudget)
+
+	// The small graph fits within the budget
+	config.UnipartiteConfig.MemoryBudgetMB = 1
+	graphBuilder, _, err := NewGraphBuilder(*config)
+	assert.NoError(t, err)
+	defer graphBuilder.Destroy()
+
+	// Rebuild the unipartite graph with a budget that is too small
+	inMemory := graphBuilder.Unipartite.(*graphstore.InMemoryUnipartiteGraphStore)
+	assert.NoError(t, inMemory.Clear())
+	assert.NoError(t, inMemory.SetMemoryBudget(200))
+
+	err = graphBuilder.convertToUnipartite(*config)
+	assert.ErrorIs(t, err, graphstore.ErrMemoryBudgetExceeded)
+
+	// Without a fallback folder the build fails with an explanation
+	err = graphBuilder.convertToPebbleFallback(*config, err)
+	assert.ErrorIs(t, err, graphstore.ErrMemoryBudgetExceeded)
+	assert.Contains(t, err.Error(), "fallbackFolder")
+
+	// With a fallback folder the unipartite graph is built in Pebble
+	config.UnipartiteConfig.FallbackFolder = t.TempDir()
+	err = graphBuilder.convertToPebbleFallback(*config, graphstore.ErrMemoryBudgetExceeded)
+	assert.NoError(t, err)
+	assert.IsType(t, &graphstore.PebbleUnipartiteGraphStore{}, graphBuilder.Unipartite)
+
+	found, err := graphBuilder.Unipartite.EdgeExists("e-1", "e-2")
+	assert.NoError(t, err)
+	assert.True(t, found)
+}
+
+func TestNewGraphBuilderWithRetentionPolicy(t *testing.T) {
+
+	config, err := ReadGraphConfigFromJson("../test-data-sets/set-0/config-inmemory.json")
+	assert.NoError(t, err)
+
+	// Without a retention policy, nothing is pruned
+	graphBuilder, _, err := NewGraphBuilder(*config)
+	assert.NoError(t, err)
+	assert.False(t, graphBuilder.HasRetentionPolicy())
+	assert.Equal(t, 4, graphBuilder.Stats.Bipartite.NumberOfDocuments)
+
+	_, err = graphBuilder.PruneExpiredDocuments(time.Now())
+	assert.ErrorIs(t, err, ErrNoRetentionPolicy)
+	assert.NoError(t, graphBuilder.Destroy())
+
+	// Invalid retention policy
+	config.RetentionPolicy = &graphstore.RetentionPolicy{}
+	_, _, err = NewGraphBuilder(*config)
+	assert.ErrorIs(t, err, graphstore.ErrInvalidRetentionPolicy)
+
+	// The documents of type A have expired
+	config.RetentionPolicy = &graphstore.RetentionPolicy{
+		DateAttribute: "Date",
+		DateFormat:    "02/01/2006",
+		MaxAgeDays:    map[string]int{"Doc-type-A": 1},
+	}
+	graphBuilder, _, err = NewGraphBuilder(*config)
+	assert.NoError(t, err)
+	defer graphBuilder.Destroy()
+
+	assert.True(t, graphBuilder.HasRetentionPolicy())
+	assert.Equal(t, 2, graphBuilder.Stats.Bipartite.NumberOfDocuments)
+
+	// A later pruning pass has nothing to remove
+	result, err := graphBuilder.PruneExpiredDocuments(time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.DocumentsRemoved)
+}
+
+func TestPruneExpiredDocumentsUpdatesStats(t *testing.T) {
+
+	config, err := ReadGraphConfigFromJson("../test-data-sets/set-0/config-inmemory.json")
+	assert.NoError(t, err)
+
+	// None of the documents have expired when the graphs are built
+	config.RetentionPolicy = &graphstore.RetentionPolicy{
+		DateAttribute: "Date",
+		DateFormat:    "02/01/2006",
+		MaxAgeDays:    map[string]int{"Doc-type-A": 36500},
+	}
+	graphBuilder, _, err := NewGraphBuilder(*config)
+	assert.NoError(t, err)
+	defer graphBuilder.Destroy()
+	assert.Equal(t, 4, graphBuilder.Stats.Bipartite.NumberOfDocuments)
+	provenance := graphBuilder.Stats.Provenance
+
+	// The stats are updated with the change rather than recalculated
+	result, err := graphBuilder.PruneExpiredDocuments(time.Now().AddDate(200, 0, 0))
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.DocumentsRemoved)
+	assert.Equal(t, 2, graphBuilder.Stats.Bipartite.NumberOfDocuments)
+	assert.Equal(t, provenance, graphBuilder.Stats.Provenance)
+
+	updated := graphBuilder.Stats
+	assert.NoError(t, graphBuilder.CalculateStats())
+	assert.Equal(t, updated.Bipartite, graphBuilder.Stats.Bipartite)
+	assert.Equal(t, updated.Unipartite, graphBuilder.Stats.Unipartite)
+	assert.Equal(t, updated.Types, graphBuilder.Stats.Types)
+}
+
+func TestNewGraphBuilderWithBulkIngest(t *testing.T) {
+	configFilepath := "../test-data-sets/set-0/config-pebble.json"
+
+	config, err := readGraphConfig(configFilepath)
+	assert.NoError(t, err)
+	makePathsRelativeToConfig(configFilepath, config)
+
+	config.BipartiteConfig.Folder = t.TempDir()
+	config.UnipartiteConfig.Folder = t.TempDir()
+
+	expected, build, err := NewGraphBuilder(*config)
+	assert.NoError(t, err)
+	assert.True(t, build)
+	defer expected.Destroy()
+
+	// The graph built by ingesting SSTables is the same
+	config.BipartiteConfig.Folder = t.TempDir()
+	config.UnipartiteConfig.Folder = t.TempDir()
+	config.BipartiteConfig.BulkIngest = true
+	config.BipartiteConfig.BulkIngestBufferMB = 1
+
+	graphBuilder, build, err := NewGraphBuilder(*config)
+	assert.NoError(t, err)
+	assert.True(t, build)
+	defer graphBuilder.Destroy()
+
+	assert.Equal(t, expected.Stats.Bipartite, graphBuilder.Stats.Bipartite)
+	assert.Equal(t, expected.Stats.Unipartite, graphBuilder.Stats.Unipartite)
+
+	equal, err := graphBuilder.Bipartite.Equal(expected.Bipartite)
+	assert.NoError(t, err)
+	assert.True(t, equal)
+
+	// Entities can't be merged
+	config.BipartiteConfig.Folder = t.TempDir()
+	config.UnipartiteConfig.Folder = t.TempDir()
+	config.EntityMergeStrategy = graphloader.MergeStrategyUnion
+	_, _, err = NewGraphBuilder(*config)
+	assert.ErrorIs(t, err, ErrBulkIngestRequiresReplace)
+
+	// Bulk ingest requires Pebble
+	config.EntityMergeStrategy = ""
+	config.BipartiteConfig.Type = StorageTypeInMemory
+	_, _, err = NewGraphBuilder(*config)
+	assert.ErrorIs(t, err, ErrBulkIngestRequiresPebble)
+}
+
+func TestNewGraphBuilderWithUnipartitePruning(t *testing.T) {
+	configFilepath := "../test-data-sets/set-0/config-pebble.json"
+
+	config, err := readGraphConfig(configFilepath)
+	assert.NoError(t, err)
+	makePathsRelativeToConfig(configFilepath, config)
+
+	config.BipartiteConfig.Folder = t.TempDir()
+	config.UnipartiteConfig.Folder = t.TempDir()
+	config.SignatureFile = filepath.Join(t.TempDir(), "signatures.json")
+
+	// Invalid pruning options
+	config.UnipartitePruning = &graphstore.UnipartitePruneOptions{MinChainLength: -1}
+	_, _, err = NewGraphBuilder(*config)
+	assert.ErrorIs(t, err, graphstore.ErrInvalidMinChainLength)
+
+	config.UnipartitePruning = &graphstore.UnipartitePruneOptions{MinChainLength: 2}
+	_, _, err = NewGraphBuilder(*config)
+	assert.ErrorIs(t, err, graphstore.ErrChainsFileRequired)
+
+	chainsFile := filepath.Join(t.TempDir(), "chains.json")
+	config.UnipartitePruning.ChainsFile = chainsFile
+	config.RetentionPolicy = &graphstore.RetentionPolicy{
+		DateAttribute: "Date",
+		DateFormat:    "02/01/2006",
+		MaxAgeDays:    map[string]int{"Doc-type-A": 1},
+	}
+	_, _, err = NewGraphBuilder(*config)
+	assert.ErrorIs(t, err, ErrChainsWithRetention)
+	config.RetentionPolicy = nil
+
+	// The chain e-2 -- e-1 -- e-3 -- e-4 is collapsed when the graph is built, but the graph is
+	// read with the chain expanded
+	graphBuilder, build, err := NewGraphBuilder(*config)
+	assert.NoError(t, err)
+	assert.True(t, build)
+	assert.Equal(t, 4, graphBuilder.Stats.Unipartite.NumberOfEntities)
+
+	connected, err := graphBuilder.Unipartite.EntityIdsConnectedTo("e-2")
+	assert.NoError(t, err)
+	assert.False(t, connected.Has("e-4"))
+	assert.True(t, connected.Has("e-1"))
+
+	chain, found := graphBuilder.Chains.Get("e-2", "e-4")
+	assert.True(t, found)
+	assert.Equal(t, []string{"e-1", "e-3"}, chain.Via)
+
+	assert.NoError(t, graphBuilder.Bipartite.Finalise())
+	assert.NoError(t, graphBuilder.Unipartite.Finalise())
+	assert.NoError(t, graphBuilder.Bipartite.(*graphstore.PebbleBipartiteGraphStore).Close())
+	assert.NoError(t, graphBuilder.Unipartite.Close())
+
+	// The chains are read when the graph is loaded
+	config.BipartiteConfig.ReadOnly = true
+	config.UnipartiteConfig.ReadOnly = true
+
+	replica, build, err := NewGraphBuilder(*config)
+	assert.NoError(t, err)
+	assert.False(t, build)
+	assert.Equal(t, graphBuilder.Chains.Chains(), replica.Chains.Chains())
+	assert.Equal(t, 4, replica.Stats.Unipartite.NumberOfEntities)
+
+	assert.NoError(t, replica.Bipartite.(*graphstore.PebbleBipartiteGraphStore).Close())
+	assert.NoError(t, replica.Unipartite.Close())
+
+	// The graphs aren't loaded without the chains file
+	assert.NoError(t, os.Remove(chainsFile))
+	_, _, err = NewGraphBuilder(*config)
+	assert.ErrorIs(t, err, ErrChainsFileNotFound)
+}
+
+func TestRemoveFromServedGraph(t *testing.T) {
+
+	config, err := ReadGraphConfigFromJson("../test-data-sets/set-0/config-inmemory.json")
+	assert.NoError(t, err)
+
+	graphBuilder, _, err := NewGraphBuilder(*config)
+	assert.NoError(t, err)
+	defer graphBuilder.Destroy()
+
+	// The entity is removed from both graphs
+	assert.NoError(t, graphBuilder.RemoveEntity("e-4"))
+
+	found, err := graphBuilder.Bipartite.HasEntityWithId("e-4")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	found, err = graphBuilder.Unipartite.HasEntity("e-4")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	assert.ErrorIs(t, graphBuilder.RemoveEntity("e-4"), graphstore.ErrEntityNotFound)
+
+	// A removal waits for the readers of the stores to finish
+	graphBuilder.Lock.BeginRead()
+
+	removed := make(chan error)
+	go func() {
+		removed <- graphBuilder.RemoveEdge("e-1", "e-2")
+	}()
+
+	select {
+	case <-removed:
+		t.Error("Edge removed whilst the stores were being read")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	found, err = graphBuilder.Unipartite.EdgeExists("e-1", "e-2")
+	assert.NoError(t, err)
+	assert.True(t, found)
+
+	graphBuilder.Lock.EndRead()
+	assert.NoError(t, <-removed)
+
+	found, err = graphBuilder.Unipartite.EdgeExists("e-1", "e-2")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	// The document and link removals are applied to the bipartite graph
+	assert.NoError(t, graphBuilder.RemoveLink(graphstore.Link{EntityId: "e-1", DocumentId: "d-3"}))
+	assert.NoError(t, graphBuilder.RemoveDocument("d-1"))
+	assert.ErrorIs(t, graphBuilder.RemoveDocument("d-1"), graphstore.ErrDocumentNotFound)
+}
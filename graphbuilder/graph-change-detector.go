@@ -7,13 +7,18 @@ import "github.com/cdclaxton/shortest-path-web-app/filedetector"
 func isGraphBuildingRequired(config GraphConfig) (
 	bool, *filedetector.FileSignatureInfo, error) {
 
-	// Are the bipartite and unipartite graphs backed by Pebble (i.e. persisted)?
-	if config.BipartiteConfig.Type != StorageTypePebble ||
-		config.UnipartiteConfig.Type != StorageTypePebble {
+	// Are the bipartite and unipartite graphs backed by Pebble or bbolt (i.e. persisted)?
+	if !isPersistentStorageType(config.BipartiteConfig.Type) ||
+		!isPersistentStorageType(config.UnipartiteConfig.Type) {
 		return true, nil, nil
 
 	}
 
+	// Read-only graphs are built elsewhere, so the input data may not be available
+	if isReadOnly(config) {
+		return false, nil, nil
+	}
+
 	// Get a slice of all of the files to check
 	filepaths := filesToCheck(config.Data)
 
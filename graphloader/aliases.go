@@ -0,0 +1,124 @@
+// Aliases are other IDs of an entity, such as the legacy identifiers of entities that were merged
+// into it. Unlike entity resolution, which rewrites the IDs as the CSV files are loaded, aliases are
+// held in the bipartite store so that a search using an alias still finds the (merged) entity.
+
+package graphloader
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cdclaxton/shortest-path-web-app/normalisation"
+)
+
+var ErrConflictingAlias = errors.New("alias ID is an alias of more than one entity")
+
+// AliasesCsvFile represents the configuration of a CSV file of alias IDs of entities.
+type AliasesCsvFile struct {
+	Path          string `json:"path"`          // Location of the file
+	AliasIdField  string `json:"aliasIdField"`  // Name of the field holding the alias ID
+	EntityIdField string `json:"entityIdField"` // Name of the field holding the canonical entity ID
+	Delimiter     string `json:"delimiter"`     // Delimiter
+	CsvFormat            // Encoding, quoting and BOM handling
+}
+
+func NewAliasesCsvFile(path string, aliasIdField string, entityIdField string,
+	delimiter string) AliasesCsvFile {
+
+	return AliasesCsvFile{
+		Path:          path,
+		AliasIdField:  aliasIdField,
+		EntityIdField: entityIdField,
+		Delimiter:     delimiter,
+	}
+}
+
+// LoadAliases from the CSV file into the bipartite store, which must already hold the entities,
+// returning the number of aliases loaded. The entity IDs are resolved and normalised in the same
+// way as the entity IDs in the entity and link files, whereas the alias IDs are only normalised.
+// Rows with an empty ID, an alias of itself, an alias that is an entity's ID and an alias of an
+// entity that isn't in the store are skipped. An alias of two different entities is an error.
+func LoadAliases(graphStore graphstore.BipartiteGraphStore, aliasesFile AliasesCsvFile,
+	resolver *EntityResolver, normaliser *normalisation.Normaliser) (int, error) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("filepath", aliasesFile.Path).
+		Msg("Loading aliases CSV file")
+
+	file, reader, header, err := openCsvFile(aliasesFile.Path, aliasesFile.Delimiter,
+		aliasesFile.CsvFormat)
+	if err != nil {
+		return 0, err
+	}
+	defer file.Close()
+
+	fieldToIndex, err := findIndicesOfFields(header,
+		[]string{aliasesFile.AliasIdField, aliasesFile.EntityIdField})
+	if err != nil {
+		return 0, err
+	}
+	aliasIdIndex := fieldToIndex[aliasesFile.AliasIdField]
+	entityIdIndex := fieldToIndex[aliasesFile.EntityIdField]
+
+	aliases := map[string]string{}
+	numberSkipped := 0
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return 0, err
+		}
+
+		if len(record[aliasIdIndex]) == 0 || len(record[entityIdIndex]) == 0 {
+			numberSkipped++
+			continue
+		}
+
+		aliasId := normaliser.Normalise(record[aliasIdIndex])
+		entityId := normaliser.Normalise(resolver.Resolve(record[entityIdIndex]))
+
+		if existing, found := aliases[aliasId]; found {
+			if existing != entityId {
+				return 0, fmt.Errorf("%w: %v is an alias of %v and %v", ErrConflictingAlias,
+					aliasId, existing, entityId)
+			}
+			continue
+		}
+
+		// The alias must not be an entity's ID and its entity must be in the store
+		isEntity, err := graphStore.HasEntityWithId(aliasId)
+		if err != nil {
+			return 0, err
+		}
+
+		hasEntity, err := graphStore.HasEntityWithId(entityId)
+		if err != nil {
+			return 0, err
+		}
+
+		if aliasId == entityId || isEntity || !hasEntity {
+			numberSkipped++
+			continue
+		}
+
+		if err := graphStore.AddAlias(aliasId, entityId); err != nil {
+			return 0, err
+		}
+		aliases[aliasId] = entityId
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("filepath", aliasesFile.Path).
+		Int("numberOfAliases", len(aliases)).
+		Int("numberSkipped", numberSkipped).
+		Msg("Finished loading aliases CSV file")
+
+	return len(aliases), nil
+}
@@ -0,0 +1,66 @@
+package graphloader
+
+import (
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/normalisation"
+	"github.com/stretchr/testify/assert"
+)
+
+// makeAliasedStore with the entities e-1 and e-2.
+func makeAliasedStore(t *testing.T) graphstore.BipartiteGraphStore {
+	g := graphstore.NewInMemoryBipartiteGraphStore()
+
+	for _, entityId := range []string{"e-1", "e-2"} {
+		entity, err := graphstore.NewEntity(entityId, "Person", map[string]string{})
+		assert.NoError(t, err)
+		assert.NoError(t, g.AddEntity(entity))
+	}
+
+	return g
+}
+
+func TestLoadAliases(t *testing.T) {
+
+	aliasesFile := NewAliasesCsvFile("./test-data/aliases.csv", "alias_id", "entity_id", ",")
+	resolver := NewEntityResolver(map[string]string{"x-9": "e-2"})
+	normaliser, err := normalisation.New(normalisation.Config{CaseFold: normalisation.CaseFoldLower})
+	assert.NoError(t, err)
+
+	g := makeAliasedStore(t)
+	n, err := LoadAliases(g, aliasesFile, resolver, normaliser)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, n)
+
+	for _, test := range []struct {
+		entityId    string
+		canonicalId string
+	}{
+		{"old-1", "e-1"},
+		{"old-2", "e-1"}, // normalised alias
+		{"old-3", "old-3"},
+		{"e-2", "e-2"},   // an entity's ID isn't an alias
+		{"old-4", "e-2"}, // resolved entity ID
+		{"old-5", "old-5"},
+	} {
+		canonicalId, err := g.ResolveAlias(test.entityId)
+		assert.NoError(t, err)
+		assert.Equal(t, test.canonicalId, canonicalId, test.entityId)
+	}
+
+	// Missing field
+	aliasesFile.AliasIdField = "id"
+	_, err = LoadAliases(makeAliasedStore(t), aliasesFile, nil, nil)
+	assert.Error(t, err)
+
+	// Missing file
+	_, err = LoadAliases(makeAliasedStore(t), NewAliasesCsvFile("./test-data/missing.csv",
+		"alias_id", "entity_id", ","), nil, nil)
+	assert.Error(t, err)
+
+	// An alias of two different entities
+	_, err = LoadAliases(makeAliasedStore(t), NewAliasesCsvFile("./test-data/aliases_conflict.csv",
+		"alias_id", "entity_id", ","), nil, nil)
+	assert.ErrorIs(t, err, ErrConflictingAlias)
+}
@@ -0,0 +1,130 @@
+// CSV files exported from other systems aren't always UTF-8 and often start with a byte order mark
+// (BOM) or contain stray quotes. The format of a CSV file sets the character encoding of the file,
+// e.g. windows-1252, how quotes are handled and whether a leading BOM is kept. The default format
+// reads UTF-8 with strict quoting and strips the BOM.
+
+package graphloader
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"io"
+	"strings"
+
+	"golang.org/x/text/encoding/htmlindex"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+)
+
+var (
+	ErrInvalidEncoding = errors.New("invalid character encoding")
+	ErrInvalidQuoting  = errors.New("invalid quoting")
+)
+
+// Quoting of the fields in a CSV file.
+const (
+	QuotingStrict = "strict" // Quotes must follow RFC 4180 (default)
+	QuotingLazy   = "lazy"   // Quotes may appear in unquoted fields and unescaped in quoted fields
+	QuotingNone   = "none"   // Quotes are part of the value and fields can't be quoted
+)
+
+// UTF-8 byte order mark
+var utf8Bom = []byte{0xEF, 0xBB, 0xBF}
+
+// Rune that stands in for a literal quote while a file without quoting is parsed (private use)
+const literalQuote = '\uE000'
+
+// CsvFormat of a CSV file. The zero value is the default format.
+type CsvFormat struct {
+	Encoding string `json:"encoding"` // Character encoding, e.g. windows-1252 (blank for UTF-8)
+	Quoting  string `json:"quoting"`  // Quoting (blank for strict)
+	KeepBom  bool   `json:"keepBom"`  // Keep a leading byte order mark?
+}
+
+// Validate the CSV format.
+func (f CsvFormat) Validate() error {
+
+	if len(f.Encoding) > 0 {
+		if _, err := htmlindex.Get(f.Encoding); err != nil {
+			return ErrInvalidEncoding
+		}
+	}
+
+	switch f.Quoting {
+	case "", QuotingStrict, QuotingLazy, QuotingNone:
+		return nil
+	default:
+		return ErrInvalidQuoting
+	}
+}
+
+// A csvRecordReader reads the records from a CSV file in a given format.
+type csvRecordReader struct {
+	reader        *csv.Reader
+	literalQuotes bool // Restore the literal quotes in the fields?
+}
+
+// newCsvReader for the (open) file given the delimiter and the format of the file.
+func newCsvReader(r io.Reader, delimiter string, format CsvFormat) (*csvRecordReader, error) {
+
+	sep, err := parseDelimiter(delimiter)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := format.Validate(); err != nil {
+		return nil, err
+	}
+
+	// Decode the file to UTF-8
+	if len(format.Encoding) > 0 {
+		encoding, _ := htmlindex.Get(format.Encoding)
+		r = encoding.NewDecoder().Reader(r)
+	}
+
+	// Strip the BOM from the decoded text
+	buffered := bufio.NewReader(r)
+	if !format.KeepBom {
+		if prefix, err := buffered.Peek(len(utf8Bom)); err == nil && bytes.Equal(prefix, utf8Bom) {
+			buffered.Discard(len(utf8Bom))
+		}
+	}
+	r = buffered
+
+	// Hide the quotes from the CSV parser
+	literalQuotes := format.Quoting == QuotingNone
+	if literalQuotes {
+		r = transform.NewReader(r, runes.Map(func(c rune) rune {
+			if c == '"' {
+				return literalQuote
+			}
+			return c
+		}))
+	}
+
+	reader := csv.NewReader(r)
+	reader.Comma = sep
+	reader.LazyQuotes = format.Quoting == QuotingLazy
+
+	return &csvRecordReader{
+		reader:        reader,
+		literalQuotes: literalQuotes,
+	}, nil
+}
+
+// Read the next record from the CSV file.
+func (c *csvRecordReader) Read() ([]string, error) {
+
+	record, err := c.reader.Read()
+	if err != nil || !c.literalQuotes {
+		return record, err
+	}
+
+	for idx, field := range record {
+		record[idx] = strings.ReplaceAll(field, string(literalQuote), "\"")
+	}
+
+	return record, nil
+}
@@ -0,0 +1,163 @@
+package graphloader
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/set"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCsvFormatValidate(t *testing.T) {
+	assert.NoError(t, CsvFormat{}.Validate())
+	assert.NoError(t, CsvFormat{Encoding: "windows-1252", Quoting: QuotingLazy}.Validate())
+	assert.NoError(t, CsvFormat{Encoding: "UTF-16LE", Quoting: QuotingNone}.Validate())
+	assert.ErrorIs(t, CsvFormat{Encoding: "klingon"}.Validate(), ErrInvalidEncoding)
+	assert.ErrorIs(t, CsvFormat{Quoting: "double"}.Validate(), ErrInvalidQuoting)
+}
+
+// readAllRecords from the text using the CSV reader.
+func readAllRecords(text string, format CsvFormat) ([][]string, error) {
+
+	reader, err := newCsvReader(strings.NewReader(text), ",", format)
+	if err != nil {
+		return nil, err
+	}
+
+	records := [][]string{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return records, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+}
+
+func TestCsvReader(t *testing.T) {
+	testCases := []struct {
+		description   string
+		text          string
+		format        CsvFormat
+		expected      [][]string
+		expectedError bool
+	}{
+		{
+			description: "UTF-8 BOM is stripped",
+			text:        "\xEF\xBB\xBFid,name\ne-1,Bob\n",
+			format:      CsvFormat{},
+			expected:    [][]string{{"id", "name"}, {"e-1", "Bob"}},
+		},
+		{
+			description: "UTF-8 BOM is kept",
+			text:        "\xEF\xBB\xBFid,name\n",
+			format:      CsvFormat{KeepBom: true},
+			expected:    [][]string{{"\uFEFFid", "name"}},
+		},
+		{
+			description: "Windows-1252",
+			text:        "id,name\ne-1,Ren\xE9e \x80\n",
+			format:      CsvFormat{Encoding: "windows-1252"},
+			expected:    [][]string{{"id", "name"}, {"e-1", "Renée €"}},
+		},
+		{
+			description: "UTF-16 (little endian) with a BOM",
+			text:        "\xFF\xFEi\x00d\x00\n\x00\xE9\x00\n\x00",
+			format:      CsvFormat{Encoding: "utf-16le"},
+			expected:    [][]string{{"id"}, {"é"}},
+		},
+		{
+			description: "Quoted field",
+			text:        "id,name\ne-1,\"Smith, Bob\"\n",
+			format:      CsvFormat{Quoting: QuotingStrict},
+			expected:    [][]string{{"id", "name"}, {"e-1", "Smith, Bob"}},
+		},
+		{
+			description:   "Stray quote with strict quoting",
+			text:          "id,name\ne-1,Bob \"The Builder\"\n",
+			format:        CsvFormat{},
+			expectedError: true,
+		},
+		{
+			description: "Stray quote with lazy quoting",
+			text:        "id,name\ne-1,Bob \"The Builder\"\n",
+			format:      CsvFormat{Quoting: QuotingLazy},
+			expected:    [][]string{{"id", "name"}, {"e-1", "Bob \"The Builder\""}},
+		},
+		{
+			description: "Quotes are literal without quoting",
+			text:        "id,name\ne-1,\"Bob\n\"e-2\",\"Smith\n",
+			format:      CsvFormat{Quoting: QuotingNone},
+			expected: [][]string{{"id", "name"}, {"e-1", "\"Bob"},
+				{"\"e-2\"", "\"Smith"}},
+		},
+		{
+			description:   "Invalid encoding",
+			text:          "id\n",
+			format:        CsvFormat{Encoding: "klingon"},
+			expectedError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		records, err := readAllRecords(testCase.text, testCase.format)
+
+		if testCase.expectedError {
+			assert.Error(t, err, testCase.description)
+			continue
+		}
+
+		assert.NoError(t, err, testCase.description)
+		assert.Equal(t, testCase.expected, records, testCase.description)
+	}
+}
+
+func TestReadEntitiesFileWithFormat(t *testing.T) {
+
+	// Windows-1252 encoded file
+	csv := NewEntitiesCsvFile("./test-data/entities_windows1252.csv", "Person", ",",
+		"entity_id", map[string]string{
+			"first name": "Forename",
+			"last name":  "Surname",
+		})
+	csv.Encoding = "windows-1252"
+
+	entities, err := NewEntitiesCsvFileReader(csv).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, []graphstore.Entity{
+		{
+			Id:                "e-1",
+			EntityType:        "Person",
+			Attributes:        map[string]string{"Forename": "Renée", "Surname": "Müller"},
+			LinkedDocumentIds: set.NewSet[string](),
+		},
+		{
+			Id:                "e-2",
+			EntityType:        "Person",
+			Attributes:        map[string]string{"Forename": "Bob", "Surname": "Smith"},
+			LinkedDocumentIds: set.NewSet[string](),
+		},
+	}, entities)
+
+	// The BOM would otherwise be part of the name of the entity ID field
+	csv = NewEntitiesCsvFile("./test-data/entities_bom.csv", "Person", ",",
+		"entity_id", map[string]string{})
+
+	entities, err = NewEntitiesCsvFileReader(csv).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(entities))
+
+	csv.KeepBom = true
+	_, err = NewEntitiesCsvFileReader(csv).ReadAll()
+	assert.Error(t, err)
+
+	// Invalid quoting
+	csv.Quoting = "double"
+	_, err = NewEntitiesCsvFileReader(csv).ReadAll()
+	assert.ErrorIs(t, err, ErrInvalidQuoting)
+}
@@ -1,7 +1,6 @@
 package graphloader
 
 import (
-	"encoding/csv"
 	"errors"
 	"io"
 	"os"
@@ -13,9 +12,10 @@ import (
 
 // A DocumentsCsvFile specifies the location and format of a CSV file containing documents.
 type DocumentsCsvFile struct {
-	Path             string            `json:"path"`             // Location of the file
-	DocumentType     string            `json:"documentType"`     // Type of documents in the file
-	Delimiter        string            `json:"delimiter"`        // Delimiter
+	Path             string            `json:"path"`         // Location of the file
+	DocumentType     string            `json:"documentType"` // Type of documents in the file
+	Delimiter        string            `json:"delimiter"`    // Delimiter
+	CsvFormat                          // Encoding, quoting and BOM handling
 	DocumentIdField  string            `json:"documentIdField"`  // Name of the field with the document ID
 	FieldToAttribute map[string]string `json:"fieldToAttribute"` // Mapping of field name to attribute
 }
@@ -36,7 +36,7 @@ func NewDocumentsCsvFile(path string, documentType string, delimiter string,
 // DocumentsCsvFileReader reads Documents from a CSV file.
 type DocumentsCsvFileReader struct {
 	documentsCsvFile     DocumentsCsvFile
-	csvReader            *csv.Reader
+	csvReader            *csvRecordReader
 	file                 *os.File
 	documentIdFieldIndex int
 	attributeFieldIndex  map[string]int
@@ -76,17 +76,14 @@ func (reader *DocumentsCsvFileReader) Initialise() error {
 		Str("filepath", reader.documentsCsvFile.Path).
 		Msg("Creating the CSV reader")
 
-	// Parse delimiter
-	delimiter, err := parseDelimiter(reader.documentsCsvFile.Delimiter)
+	// Create the CSV reader
+	reader.csvReader, err = newCsvReader(reader.file, reader.documentsCsvFile.Delimiter,
+		reader.documentsCsvFile.CsvFormat)
 	if err != nil {
 		reader.file.Close()
 		return err
 	}
 
-	// Create the CSV reader
-	reader.csvReader = csv.NewReader(reader.file)
-	reader.csvReader.Comma = delimiter
-
 	logging.Logger.Info().
 		Str(logging.ComponentField, componentName).
 		Str("filepath", reader.documentsCsvFile.Path).
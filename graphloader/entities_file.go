@@ -1,7 +1,6 @@
 package graphloader
 
 import (
-	"encoding/csv"
 	"errors"
 	"io"
 	"os"
@@ -13,9 +12,10 @@ import (
 
 // An EntitiesCsvFile specifies the location and format of a single CSV file containing entities.
 type EntitiesCsvFile struct {
-	Path             string            `json:"path"`             // Location of the file
-	EntityType       string            `json:"entityType"`       // Type of entities in the file
-	Delimiter        string            `json:"delimiter"`        // Delimiter
+	Path             string            `json:"path"`       // Location of the file
+	EntityType       string            `json:"entityType"` // Type of entities in the file
+	Delimiter        string            `json:"delimiter"`  // Delimiter
+	CsvFormat                          // Encoding, quoting and BOM handling
 	EntityIdField    string            `json:"entityIdField"`    // Name of the field with the entity ID
 	FieldToAttribute map[string]string `json:"fieldToAttribute"` // Mapping of field name to attribute
 }
@@ -36,7 +36,7 @@ func NewEntitiesCsvFile(path string, entityType string, delimiter string,
 // An EntitiesCsvFileReader reads and parses entities from a CSV file.
 type EntitiesCsvFileReader struct {
 	entitiesCsvFile     EntitiesCsvFile
-	csvReader           *csv.Reader
+	csvReader           *csvRecordReader
 	file                *os.File
 	entityIdFieldIndex  int
 	attributeFieldIndex map[string]int
@@ -74,17 +74,14 @@ func (reader *EntitiesCsvFileReader) Initialise() error {
 		Str("filepath", reader.entitiesCsvFile.Path).
 		Msg("Creating the CSV reader")
 
-	// Parse delimiter
-	delimiter, err := parseDelimiter(reader.entitiesCsvFile.Delimiter)
+	// Create the CSV reader
+	reader.csvReader, err = newCsvReader(reader.file, reader.entitiesCsvFile.Delimiter,
+		reader.entitiesCsvFile.CsvFormat)
 	if err != nil {
 		reader.file.Close()
 		return err
 	}
 
-	// Create the CSV reader
-	reader.csvReader = csv.NewReader(reader.file)
-	reader.csvReader.Comma = delimiter
-
 	logging.Logger.Info().
 		Str(logging.ComponentField, componentName).
 		Str("filepath", reader.entitiesCsvFile.Path).
@@ -0,0 +1,157 @@
+package graphloader
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cdclaxton/shortest-path-web-app/normalisation"
+	"github.com/cdclaxton/shortest-path-web-app/set"
+)
+
+// Strategies for merging an entity with an entity with the same ID that is already in the store
+const (
+	MergeStrategyReplace         = "replace"         // Last write wins (default)
+	MergeStrategyUnion           = "union"           // Union of the attributes, latest value wins
+	MergeStrategyPreferNonEmpty  = "preferNonEmpty"  // Union of the attributes, first non-empty value wins
+	MergeStrategyErrorOnConflict = "errorOnConflict" // Union of the attributes, fail if values differ
+)
+
+var (
+	ErrUnknownMergeStrategy = errors.New("unknown entity merge strategy")
+	ErrEntityMergeConflict  = errors.New("conflicting entity values")
+)
+
+// ValidateMergeStrategy checks the merge strategy is known. An empty strategy is treated as replace.
+func ValidateMergeStrategy(strategy string) error {
+	switch strategy {
+	case "", MergeStrategyReplace, MergeStrategyUnion, MergeStrategyPreferNonEmpty,
+		MergeStrategyErrorOnConflict:
+		return nil
+	}
+
+	return fmt.Errorf("%w: %v", ErrUnknownMergeStrategy, strategy)
+}
+
+// MergeEntities using the strategy, where existing is the entity already in the store and
+// latest is the entity being added. The entities are not modified.
+func MergeEntities(existing graphstore.Entity, latest graphstore.Entity,
+	strategy string) (graphstore.Entity, error) {
+
+	if err := ValidateMergeStrategy(strategy); err != nil {
+		return graphstore.Entity{}, err
+	}
+
+	if strategy == "" || strategy == MergeStrategyReplace {
+		return latest, nil
+	}
+
+	merged := graphstore.Entity{
+		Id:                latest.Id,
+		EntityType:        latest.EntityType,
+		Attributes:        map[string]string{},
+		LinkedDocumentIds: set.NewSet[string](),
+	}
+
+	if existing.EntityType != latest.EntityType {
+		switch strategy {
+		case MergeStrategyPreferNonEmpty:
+			merged.EntityType = existing.EntityType
+		case MergeStrategyErrorOnConflict:
+			return graphstore.Entity{}, fmt.Errorf("%w: entity %v has types %v and %v",
+				ErrEntityMergeConflict, latest.Id, existing.EntityType, latest.EntityType)
+		}
+	}
+
+	for key, value := range existing.Attributes {
+		merged.Attributes[key] = value
+	}
+
+	for key, value := range latest.Attributes {
+		current, found := merged.Attributes[key]
+		if !found {
+			merged.Attributes[key] = value
+			continue
+		}
+
+		switch strategy {
+		case MergeStrategyUnion:
+			merged.Attributes[key] = value
+		case MergeStrategyPreferNonEmpty:
+			if len(current) == 0 {
+				merged.Attributes[key] = value
+			}
+		case MergeStrategyErrorOnConflict:
+			if len(current) > 0 && len(value) > 0 && current != value {
+				return graphstore.Entity{}, fmt.Errorf("%w: entity %v has %v values %v and %v",
+					ErrEntityMergeConflict, latest.Id, key, current, value)
+			}
+			if len(current) == 0 {
+				merged.Attributes[key] = value
+			}
+		}
+	}
+
+	if existing.LinkedDocumentIds != nil {
+		merged.LinkedDocumentIds = merged.LinkedDocumentIds.Union(existing.LinkedDocumentIds)
+	}
+
+	if latest.LinkedDocumentIds != nil {
+		merged.LinkedDocumentIds = merged.LinkedDocumentIds.Union(latest.LinkedDocumentIds)
+	}
+
+	return merged, nil
+}
+
+// entityMerger adds entities to the bipartite store, merging them with any existing entity with
+// the same (resolved and normalised) ID. The lock ensures that entity workers don't interleave the read and the
+// write.
+type entityMerger struct {
+	graphStore graphstore.BipartiteGraphStore
+	strategy   string
+	resolver   *EntityResolver
+	normaliser *normalisation.Normaliser
+	mu         sync.Mutex
+}
+
+// addEntity to the store (using its resolved and normalised ID), merging it with an existing entity if required.
+func (m *entityMerger) addEntity(entity graphstore.Entity) error {
+
+	entity.Id = m.normaliser.Normalise(m.resolver.Resolve(entity.Id))
+
+	if m.strategy == "" || m.strategy == MergeStrategyReplace {
+		return m.graphStore.AddEntity(entity)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	exists, err := m.graphStore.HasEntityWithId(entity.Id)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return m.graphStore.AddEntity(entity)
+	}
+
+	existing, err := m.graphStore.GetEntity(entity.Id)
+	if err != nil {
+		return err
+	}
+
+	merged, err := MergeEntities(*existing, entity, m.strategy)
+	if err != nil {
+		return err
+	}
+
+	logging.Logger.Debug().
+		Str(logging.ComponentField, componentName).
+		Str("entityId", entity.Id).
+		Str("mergeStrategy", m.strategy).
+		Msg("Merged entity with an existing entity")
+
+	return m.graphStore.AddEntity(merged)
+}
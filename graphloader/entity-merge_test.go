@@ -0,0 +1,146 @@
+package graphloader
+
+import (
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/set"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMergeEntities(t *testing.T) {
+
+	existing := graphstore.Entity{
+		Id:                "e-1",
+		EntityType:        "Person",
+		Attributes:        map[string]string{"Name": "Bob", "Age": "", "Town": "York"},
+		LinkedDocumentIds: set.NewPopulatedSet("d-1"),
+	}
+
+	latest := graphstore.Entity{
+		Id:                "e-1",
+		EntityType:        "Person",
+		Attributes:        map[string]string{"Name": "", "Age": "32", "Job": "Baker"},
+		LinkedDocumentIds: set.NewPopulatedSet("d-2"),
+	}
+
+	testCases := []struct {
+		strategy           string
+		expectedAttributes map[string]string
+	}{
+		{
+			strategy:           MergeStrategyReplace,
+			expectedAttributes: map[string]string{"Name": "", "Age": "32", "Job": "Baker"},
+		},
+		{
+			strategy:           MergeStrategyUnion,
+			expectedAttributes: map[string]string{"Name": "", "Age": "32", "Town": "York", "Job": "Baker"},
+		},
+		{
+			strategy:           MergeStrategyPreferNonEmpty,
+			expectedAttributes: map[string]string{"Name": "Bob", "Age": "32", "Town": "York", "Job": "Baker"},
+		},
+		{
+			strategy:           MergeStrategyErrorOnConflict,
+			expectedAttributes: map[string]string{"Name": "Bob", "Age": "32", "Town": "York", "Job": "Baker"},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.strategy, func(t *testing.T) {
+			merged, err := MergeEntities(existing, latest, testCase.strategy)
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedAttributes, merged.Attributes)
+		})
+	}
+
+	// The linked documents are combined
+	merged, err := MergeEntities(existing, latest, MergeStrategyUnion)
+	assert.NoError(t, err)
+	assert.True(t, set.NewPopulatedSet("d-1", "d-2").Equal(merged.LinkedDocumentIds))
+
+	// The original entities are unchanged
+	assert.Equal(t, "Bob", existing.Attributes["Name"])
+	assert.Equal(t, 3, len(latest.Attributes))
+
+	// Conflicting values
+	latest.Attributes["Town"] = "Leeds"
+	_, err = MergeEntities(existing, latest, MergeStrategyErrorOnConflict)
+	assert.ErrorIs(t, err, ErrEntityMergeConflict)
+
+	latest.EntityType = "Vehicle"
+	merged, err = MergeEntities(existing, latest, MergeStrategyPreferNonEmpty)
+	assert.NoError(t, err)
+	assert.Equal(t, "Person", merged.EntityType)
+
+	latest.Attributes["Town"] = "York"
+	_, err = MergeEntities(existing, latest, MergeStrategyErrorOnConflict)
+	assert.ErrorIs(t, err, ErrEntityMergeConflict)
+
+	// Unknown strategy
+	_, err = MergeEntities(existing, latest, "other")
+	assert.ErrorIs(t, err, ErrUnknownMergeStrategy)
+}
+
+func TestGraphStoreLoaderWithMergeStrategy(t *testing.T) {
+
+	entityFiles := []EntitiesCsvFile{
+		NewEntitiesCsvFile("./test-data/entities_2.csv", "Person", ",", "entity_id",
+			map[string]string{"first name": "Forename", "last name": "Surname"}),
+		NewEntitiesCsvFile("./test-data/entities_5.csv", "Person", ",", "entity_id",
+			map[string]string{"first name": "Forename", "last name": "Surname"}),
+	}
+
+	testCases := []struct {
+		strategy           string
+		expectedAttributes map[string]string
+		expectedError      error
+	}{
+		{
+			strategy:           MergeStrategyReplace,
+			expectedAttributes: map[string]string{"Forename": "", "Surname": "Smyth"},
+		},
+		{
+			strategy:           MergeStrategyUnion,
+			expectedAttributes: map[string]string{"Forename": "", "Surname": "Smyth"},
+		},
+		{
+			strategy:           MergeStrategyPreferNonEmpty,
+			expectedAttributes: map[string]string{"Forename": "Bob", "Surname": "Smith"},
+		},
+		{
+			strategy:      MergeStrategyErrorOnConflict,
+			expectedError: ErrEntityMergeConflict,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.strategy, func(t *testing.T) {
+			g := graphstore.NewInMemoryBipartiteGraphStore()
+
+			// A single entity worker ensures the files are read in order
+			loader := NewGraphStoreLoaderFromCsv(g, entityFiles, []DocumentsCsvFile{},
+				[]LinksCsvFile{}, false, 1, 1, 1)
+			assert.NoError(t, loader.SetEntityMergeStrategy(testCase.strategy))
+
+			err := loader.Load()
+			if testCase.expectedError != nil {
+				assert.ErrorIs(t, err, testCase.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+
+			numberOfEntities, err := g.NumberOfEntities()
+			assert.NoError(t, err)
+			assert.Equal(t, 3, numberOfEntities)
+
+			entity, err := g.GetEntity("e-1")
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedAttributes, entity.Attributes)
+		})
+	}
+
+	loader := NewGraphStoreLoaderFromCsv(graphstore.NewInMemoryBipartiteGraphStore(),
+		entityFiles, []DocumentsCsvFile{}, []LinksCsvFile{}, false, 1, 1, 1)
+	assert.ErrorIs(t, loader.SetEntityMergeStrategy("other"), ErrUnknownMergeStrategy)
+}
@@ -0,0 +1,138 @@
+// Entity resolution maps the IDs of entities from different source systems that refer to the same
+// real-world entity to a single resolved ID. The mapping is applied whilst the CSV files are loaded,
+// so the entities collapse into a single node in the graph without the CSV files being rewritten.
+
+package graphloader
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cdclaxton/shortest-path-web-app/set"
+)
+
+var ErrConflictingEntityIdMapping = errors.New("raw entity ID is mapped to more than one resolved ID")
+
+// EntityIdMappingFile represents the configuration of a CSV file of raw to resolved entity IDs.
+type EntityIdMappingFile struct {
+	Path            string `json:"path"`            // Location of the file
+	RawIdField      string `json:"rawIdField"`      // Name of the field holding the raw entity ID
+	ResolvedIdField string `json:"resolvedIdField"` // Name of the field holding the resolved entity ID
+	Delimiter       string `json:"delimiter"`       // Delimiter
+	CsvFormat              // Encoding, quoting and BOM handling
+}
+
+func NewEntityIdMappingFile(path string, rawIdField string, resolvedIdField string,
+	delimiter string) EntityIdMappingFile {
+
+	return EntityIdMappingFile{
+		Path:            path,
+		RawIdField:      rawIdField,
+		ResolvedIdField: resolvedIdField,
+		Delimiter:       delimiter,
+	}
+}
+
+// EntityResolver maps raw entity IDs to their resolved IDs. A nil resolver leaves IDs unchanged.
+type EntityResolver struct {
+	mapping map[string]string // Raw ID to resolved ID
+}
+
+// NewEntityResolver from a map of raw entity IDs to resolved IDs.
+func NewEntityResolver(mapping map[string]string) *EntityResolver {
+	return &EntityResolver{
+		mapping: mapping,
+	}
+}
+
+// Resolve the entity ID. IDs that aren't in the mapping are returned unchanged.
+func (r *EntityResolver) Resolve(entityId string) string {
+	if r == nil {
+		return entityId
+	}
+
+	if resolvedId, found := r.mapping[entityId]; found {
+		return resolvedId
+	}
+
+	return entityId
+}
+
+// ResolveAll of the entity IDs in the set, returning a new set.
+func (r *EntityResolver) ResolveAll(entityIds *set.Set[string]) *set.Set[string] {
+	resolved := set.NewSet[string]()
+
+	for _, entityId := range entityIds.ToSlice() {
+		resolved.Add(r.Resolve(entityId))
+	}
+
+	return resolved
+}
+
+// Len returns the number of raw IDs in the mapping.
+func (r *EntityResolver) Len() int {
+	if r == nil {
+		return 0
+	}
+
+	return len(r.mapping)
+}
+
+// ReadEntityResolver from a CSV file of raw to resolved entity IDs. Rows with an empty ID are
+// skipped and a raw ID mapped to two different resolved IDs is an error.
+func ReadEntityResolver(mappingFile EntityIdMappingFile) (*EntityResolver, error) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("filepath", mappingFile.Path).
+		Msg("Reading entity ID mapping CSV file")
+
+	file, reader, header, err := openCsvFile(mappingFile.Path, mappingFile.Delimiter,
+		mappingFile.CsvFormat)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	fieldToIndex, err := findIndicesOfFields(header,
+		[]string{mappingFile.RawIdField, mappingFile.ResolvedIdField})
+	if err != nil {
+		return nil, err
+	}
+	rawIdIndex := fieldToIndex[mappingFile.RawIdField]
+	resolvedIdIndex := fieldToIndex[mappingFile.ResolvedIdField]
+
+	mapping := map[string]string{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		rawId := record[rawIdIndex]
+		resolvedId := record[resolvedIdIndex]
+		if len(rawId) == 0 || len(resolvedId) == 0 {
+			continue
+		}
+
+		if existing, found := mapping[rawId]; found && existing != resolvedId {
+			return nil, fmt.Errorf("%w: %v is mapped to %v and %v",
+				ErrConflictingEntityIdMapping, rawId, existing, resolvedId)
+		}
+
+		mapping[rawId] = resolvedId
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("filepath", mappingFile.Path).
+		Str("numberOfMappings", strconv.Itoa(len(mapping))).
+		Msg("Finished reading entity ID mapping CSV file")
+
+	return NewEntityResolver(mapping), nil
+}
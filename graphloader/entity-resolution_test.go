@@ -0,0 +1,99 @@
+package graphloader
+
+import (
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/set"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEntityResolver(t *testing.T) {
+
+	// A nil resolver doesn't change the IDs
+	var resolver *EntityResolver
+	assert.Equal(t, "e-1", resolver.Resolve("e-1"))
+	assert.Equal(t, 0, resolver.Len())
+
+	resolver = NewEntityResolver(map[string]string{"a": "b", "c": "b"})
+	assert.Equal(t, "b", resolver.Resolve("a"))
+	assert.Equal(t, "b", resolver.Resolve("c"))
+	assert.Equal(t, "d", resolver.Resolve("d"))
+	assert.Equal(t, 2, resolver.Len())
+
+	resolved := resolver.ResolveAll(set.NewPopulatedSet("a", "c", "d"))
+	assert.True(t, set.NewPopulatedSet("b", "d").Equal(resolved))
+}
+
+func TestReadEntityResolver(t *testing.T) {
+
+	mappingFile := NewEntityIdMappingFile("./test-data/entity_mapping.csv", "raw_id", "resolved_id", ",")
+	resolver, err := ReadEntityResolver(mappingFile)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, resolver.Len())
+	assert.Equal(t, "e-1", resolver.Resolve("e-3"))
+	assert.Equal(t, "e-2", resolver.Resolve("x-9"))
+
+	// Missing field
+	mappingFile.RawIdField = "id"
+	_, err = ReadEntityResolver(mappingFile)
+	assert.Error(t, err)
+
+	// Missing file
+	_, err = ReadEntityResolver(NewEntityIdMappingFile("./test-data/missing.csv", "raw_id",
+		"resolved_id", ","))
+	assert.Error(t, err)
+
+	// A raw ID mapped to two different IDs
+	_, err = ReadEntityResolver(NewEntityIdMappingFile("./test-data/entity_mapping_conflict.csv",
+		"raw_id", "resolved_id", ","))
+	assert.ErrorIs(t, err, ErrConflictingEntityIdMapping)
+}
+
+func TestGraphStoreLoaderWithEntityResolver(t *testing.T) {
+
+	entityFiles := []EntitiesCsvFile{
+		NewEntitiesCsvFile("./test-data/entities_2.csv", "Person", ",", "entity_id",
+			map[string]string{"first name": "Forename", "last name": "Surname"}),
+		NewEntitiesCsvFile("./test-data/entities_5.csv", "Person", ",", "entity_id",
+			map[string]string{"first name": "Forename", "last name": "Surname"}),
+	}
+
+	documentFiles := []DocumentsCsvFile{
+		NewDocumentsCsvFile("./test-data/documents_2.csv", "Doc", ",", "document_id",
+			map[string]string{"title": "Title"}),
+	}
+
+	linkFiles := []LinksCsvFile{
+		NewLinksCsvFile("./test-data/links_6.csv", "entity_id", "document_id", ","),
+	}
+
+	resolver, err := ReadEntityResolver(NewEntityIdMappingFile("./test-data/entity_mapping.csv",
+		"raw_id", "resolved_id", ","))
+	assert.NoError(t, err)
+
+	g := graphstore.NewInMemoryBipartiteGraphStore()
+	loader := NewGraphStoreLoaderFromCsv(g, entityFiles, documentFiles, linkFiles, false, 1, 1, 1)
+	assert.NoError(t, loader.SetEntityMergeStrategy(MergeStrategyPreferNonEmpty))
+	loader.SetEntityResolver(resolver)
+	assert.NoError(t, loader.Load())
+
+	// e-3 collapses into e-1
+	numberOfEntities, err := g.NumberOfEntities()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, numberOfEntities)
+
+	exists, err := g.HasEntityWithId("e-3")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	entity, err := g.GetEntity("e-1")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"Forename": "Bob", "Surname": "Smith"}, entity.Attributes)
+	assert.True(t, set.NewPopulatedSet("d-1", "d-2").Equal(entity.LinkedDocumentIds))
+
+	// The link from x-9 is made to e-2
+	entity, err = g.GetEntity("e-2")
+	assert.NoError(t, err)
+	assert.True(t, set.NewPopulatedSet("d-1").Equal(entity.LinkedDocumentIds))
+}
@@ -10,11 +10,15 @@
 //
 // If a line in the file contains an inconsistent number of fields, the line
 // is skipped.
+//
+// The optional direction field holds the direction of the link, i.e. whether
+// the entity is the 'source' or the 'destination' of the document (e.g. the
+// payer and payee of a transaction). A line with an invalid direction is
+// skipped.
 
 package graphloader
 
 import (
-	"encoding/csv"
 	"errors"
 	"io"
 	"os"
@@ -29,7 +33,9 @@ type LinksCsvFile struct {
 	Path            string `json:"path"`            // Location of the file
 	EntityIdField   string `json:"entityIdField"`   // Name of the field holding the entity ID
 	DocumentIdField string `json:"documentIdField"` // Name of the field holding the document ID
+	DirectionField  string `json:"directionField"`  // Name of the (optional) field holding the direction
 	Delimiter       string `json:"delimiter"`       // Delimiter
+	CsvFormat              // Encoding, quoting and BOM handling
 }
 
 func NewLinksCsvFile(path string, entityIdField string, documentIdField string,
@@ -46,10 +52,11 @@ func NewLinksCsvFile(path string, entityIdField string, documentIdField string,
 // LinksCsvFileReader iterates through the CSV file producing Link structs.
 type LinksCsvFileReader struct {
 	linksCsvFile         LinksCsvFile
-	csvReader            *csv.Reader
+	csvReader            *csvRecordReader
 	file                 *os.File
 	entityIdFieldIndex   int
 	documentIdFieldIndex int
+	directionFieldIndex  int // Index of the direction field (-1 if there isn't one)
 
 	nextLinks     graphstore.Link // Next link
 	hasNext       bool            // Is there another link?
@@ -60,9 +67,10 @@ type LinksCsvFileReader struct {
 // NewLinksCsvFileReader from the definition of the links CSV file.
 func NewLinksCsvFileReader(csv LinksCsvFile) *LinksCsvFileReader {
 	return &LinksCsvFileReader{
-		linksCsvFile:  csv,
-		numberOfLinks: 0,
-		numberOfRows:  0,
+		linksCsvFile:        csv,
+		directionFieldIndex: -1,
+		numberOfLinks:       0,
+		numberOfRows:        0,
 	}
 }
 
@@ -86,17 +94,14 @@ func (reader *LinksCsvFileReader) Initialise() error {
 		Str("filepath", reader.linksCsvFile.Path).
 		Msg("Creating the CSV file reader")
 
-	// Parse delimiter
-	delimiter, err := parseDelimiter(reader.linksCsvFile.Delimiter)
+	// Create the CSV reader
+	reader.csvReader, err = newCsvReader(reader.file, reader.linksCsvFile.Delimiter,
+		reader.linksCsvFile.CsvFormat)
 	if err != nil {
 		reader.file.Close()
 		return err
 	}
 
-	// Create the CSV reader
-	reader.csvReader = csv.NewReader(reader.file)
-	reader.csvReader.Comma = delimiter
-
 	logging.Logger.Info().
 		Str(logging.ComponentField, componentName).
 		Str("filepath", reader.linksCsvFile.Path).
@@ -116,9 +121,13 @@ func (reader *LinksCsvFileReader) Initialise() error {
 		Str("filepath", reader.linksCsvFile.Path).
 		Msg("Finding indices of the Document ID and the Entity ID")
 
-	// Find the entity ID and document ID field indices
-	fieldToIndex, err := findIndicesOfFields(header,
-		[]string{reader.linksCsvFile.EntityIdField, reader.linksCsvFile.DocumentIdField})
+	// Find the entity ID, document ID and (optional) direction field indices
+	fields := []string{reader.linksCsvFile.EntityIdField, reader.linksCsvFile.DocumentIdField}
+	if len(reader.linksCsvFile.DirectionField) > 0 {
+		fields = append(fields, reader.linksCsvFile.DirectionField)
+	}
+
+	fieldToIndex, err := findIndicesOfFields(header, fields)
 
 	if err != nil {
 		reader.file.Close()
@@ -127,6 +136,9 @@ func (reader *LinksCsvFileReader) Initialise() error {
 
 	reader.entityIdFieldIndex = fieldToIndex[reader.linksCsvFile.EntityIdField]
 	reader.documentIdFieldIndex = fieldToIndex[reader.linksCsvFile.DocumentIdField]
+	if len(reader.linksCsvFile.DirectionField) > 0 {
+		reader.directionFieldIndex = fieldToIndex[reader.linksCsvFile.DirectionField]
+	}
 
 	// Read the first record
 	reader.nextLinks, reader.hasNext = reader.readRecord()
@@ -139,6 +151,7 @@ func (reader *LinksCsvFileReader) readRecord() (graphstore.Link, bool) {
 
 	recordFound := false
 	var record []string
+	direction := graphstore.LinkUndirected
 
 	for !recordFound {
 		var err error
@@ -161,12 +174,28 @@ func (reader *LinksCsvFileReader) readRecord() (graphstore.Link, bool) {
 			continue
 		}
 
+		if reader.directionFieldIndex >= 0 {
+			direction, err = graphstore.ParseLinkDirection(record[reader.directionFieldIndex])
+			if err != nil {
+				logging.Logger.Warn().
+					Str(logging.ComponentField, componentName).
+					Str("filepath", reader.linksCsvFile.Path).
+					Int("lineNumber", reader.numberOfRows).
+					Err(err).
+					Msg("Line has an invalid link direction")
+				continue
+			}
+		}
+
 		recordFound = true
 		reader.numberOfLinks += 1
 	}
 
-	return graphstore.NewLink(record[reader.entityIdFieldIndex], record[reader.documentIdFieldIndex]),
-		true
+	return graphstore.Link{
+		EntityId:   record[reader.entityIdFieldIndex],
+		DocumentId: record[reader.documentIdFieldIndex],
+		Direction:  direction,
+	}, true
 }
 
 // Next links struct from the file.
@@ -131,6 +131,36 @@ func TestReadLinksFile(t *testing.T) {
 			expectedNumberRows:  3,
 			expectedNumberLinks: 2,
 		},
+		{
+			// CSV file has a direction field with an invalid direction
+			csv: LinksCsvFile{
+				Path:            "./test-data/links_7.csv",
+				EntityIdField:   "entity_id",
+				DocumentIdField: "document_id",
+				DirectionField:  "direction",
+				Delimiter:       ",",
+			},
+			expected: []graphstore.Link{
+				{
+					EntityId:   "e-100",
+					DocumentId: "d-3",
+					Direction:  graphstore.LinkSource,
+				},
+				{
+					EntityId:   "e-101",
+					DocumentId: "d-3",
+					Direction:  graphstore.LinkDestination,
+				},
+				{
+					EntityId:   "e-102",
+					DocumentId: "d-3",
+					Direction:  graphstore.LinkUndirected,
+				},
+			},
+			expectedError:       false,
+			expectedNumberRows:  5,
+			expectedNumberLinks: 3,
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -4,11 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"path/filepath"
 	"strings"
 	"sync"
 
 	"github.com/cdclaxton/shortest-path-web-app/graphstore"
 	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cdclaxton/shortest-path-web-app/normalisation"
 )
 
 const componentName = "graphLoader"
@@ -27,10 +29,14 @@ type GraphStoreLoaderFromCsv struct {
 	entityFiles        []EntitiesCsvFile
 	documentFiles      []DocumentsCsvFile
 	linkFiles          []LinksCsvFile
-	ignoreInvalidLinks bool // Ignore links that cannot be created, e.g. due to missing entity or document
-	numEntityWorkers   int  // Number of entity file workers
-	numDocumentWorkers int  // Number of document file workers
-	numLinkWorkers     int  // Number of link file workers
+	ignoreInvalidLinks bool                      // Ignore links that cannot be created, e.g. due to missing entity or document
+	numEntityWorkers   int                       // Number of entity file workers
+	numDocumentWorkers int                       // Number of document file workers
+	numLinkWorkers     int                       // Number of link file workers
+	mergeStrategy      string                    // Strategy for merging entities with the same ID
+	resolver           *EntityResolver           // Optional mapping of raw to resolved entity IDs
+	normaliser         *normalisation.Normaliser // Optional normalisation of the (resolved) entity IDs
+	sourceAttribute    string                    // Optional document attribute holding the source file name
 }
 
 // NewGraphStoreLoaderFromCsv constructs a graph store loader that reads CSV files.
@@ -61,9 +67,65 @@ func NewGraphStoreLoaderFromCsv(graphStore graphstore.BipartiteGraphStore,
 		numEntityWorkers:   numEntityWorkers,
 		numDocumentWorkers: numDocumentWorkers,
 		numLinkWorkers:     numLinkWorkers,
+		mergeStrategy:      MergeStrategyReplace,
 	}
 }
 
+// SetEntityMergeStrategy to use when an entity with the same ID appears more than once.
+func (loader *GraphStoreLoaderFromCsv) SetEntityMergeStrategy(strategy string) error {
+
+	if err := ValidateMergeStrategy(strategy); err != nil {
+		return err
+	}
+
+	if len(strategy) == 0 {
+		strategy = MergeStrategyReplace
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("mergeStrategy", strategy).
+		Msg("Setting the entity merge strategy")
+
+	loader.mergeStrategy = strategy
+	return nil
+}
+
+// SetEntityResolver to rewrite the entity IDs in the entity and link files as they are loaded.
+func (loader *GraphStoreLoaderFromCsv) SetEntityResolver(resolver *EntityResolver) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Int("numberOfMappings", resolver.Len()).
+		Msg("Setting the entity resolver")
+
+	loader.resolver = resolver
+}
+
+// SetEntityIdNormaliser to rewrite the entity IDs in the entity and link files to their canonical
+// form as they are loaded. The IDs are normalised after they have been resolved.
+func (loader *GraphStoreLoaderFromCsv) SetEntityIdNormaliser(normaliser *normalisation.Normaliser) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Bool("normalise", normaliser != nil).
+		Msg("Setting the entity ID normaliser")
+
+	loader.normaliser = normaliser
+}
+
+// SetDocumentSourceAttribute of the documents, which is set to the name of the CSV file each
+// document was loaded from. A blank attribute means the source file isn't recorded.
+func (loader *GraphStoreLoaderFromCsv) SetDocumentSourceAttribute(attribute string) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("sourceAttribute", attribute).
+		Msg("Setting the document source attribute")
+
+	loader.sourceAttribute = attribute
+}
+
 // Load the bipartite graph store from CSV files.
 func (loader *GraphStoreLoaderFromCsv) Load() error {
 
@@ -103,15 +165,23 @@ func (loader *GraphStoreLoaderFromCsv) Load() error {
 	var wg sync.WaitGroup
 
 	// Run the entity file loader workers
+	merger := &entityMerger{
+		graphStore: loader.graphStore,
+		strategy:   loader.mergeStrategy,
+		resolver:   loader.resolver,
+		normaliser: loader.normaliser,
+	}
+
 	for i := 0; i < loader.numEntityWorkers; i++ {
 		wg.Add(1)
-		go entityWorker(ctx, cancelCtx, i, entityFilesChan, errChan, &wg, loader.graphStore)
+		go entityWorker(ctx, cancelCtx, i, entityFilesChan, errChan, &wg, merger)
 	}
 
 	// Run the document file loader workers
 	for i := 0; i < loader.numDocumentWorkers; i++ {
 		wg.Add(1)
-		go documentWorker(ctx, cancelCtx, i, documentFilesChan, errChan, &wg, loader.graphStore)
+		go documentWorker(ctx, cancelCtx, i, documentFilesChan, errChan, &wg, loader.graphStore,
+			loader.sourceAttribute)
 	}
 
 	// Wait until all the entity and document workers have completed
@@ -127,7 +197,8 @@ func (loader *GraphStoreLoaderFromCsv) Load() error {
 	// Run the link file loader workers
 	for i := 0; i < loader.numLinkWorkers; i++ {
 		wg.Add(1)
-		go linkWorker(ctx, cancelCtx, i, linkFileChan, errChan, &wg, loader.graphStore, loader.ignoreInvalidLinks)
+		go linkWorker(ctx, cancelCtx, i, linkFileChan, errChan, &wg, loader.graphStore,
+			loader.ignoreInvalidLinks, loader.resolver, loader.normaliser)
 	}
 
 	// Wait until the link workers have completed
@@ -187,7 +258,7 @@ func linkFilesChannel(files []LinksCsvFile) chan LinksCsvFile {
 }
 
 // loadEntitiesFromFile loads the entities in the CSV file into the bipartite graph store.
-func loadEntitiesFromFile(entityFile EntitiesCsvFile, graphStore graphstore.BipartiteGraphStore) error {
+func loadEntitiesFromFile(entityFile EntitiesCsvFile, merger *entityMerger) error {
 
 	// Create an entities CSV file reader
 	reader := NewEntitiesCsvFileReader(entityFile)
@@ -206,7 +277,7 @@ func loadEntitiesFromFile(entityFile EntitiesCsvFile, graphStore graphstore.Bipa
 			return err
 		}
 
-		if err := graphStore.AddEntity(entity); err != nil {
+		if err := merger.addEntity(entity); err != nil {
 			return err
 		}
 	}
@@ -217,7 +288,7 @@ func loadEntitiesFromFile(entityFile EntitiesCsvFile, graphStore graphstore.Bipa
 // entityWorker is a worker that receives entity file jobs to run.
 func entityWorker(ctx context.Context, cancelCtx context.CancelFunc, workerIdx int,
 	entityFilesChan <-chan EntitiesCsvFile, errChan chan<- error,
-	wg *sync.WaitGroup, graphStore graphstore.BipartiteGraphStore) {
+	wg *sync.WaitGroup, merger *entityMerger) {
 
 	defer wg.Done()
 
@@ -240,7 +311,7 @@ func entityWorker(ctx context.Context, cancelCtx context.CancelFunc, workerIdx i
 		default:
 		}
 
-		err := loadEntitiesFromFile(entityFile, graphStore)
+		err := loadEntitiesFromFile(entityFile, merger)
 		if err != nil {
 			logging.Logger.Error().
 				Str(logging.ComponentField, componentName).
@@ -254,7 +325,10 @@ func entityWorker(ctx context.Context, cancelCtx context.CancelFunc, workerIdx i
 }
 
 // loadDocumentsFromFile loads the documents in the CSV file into the bipartite graph store.
-func loadDocumentsFromFile(documentFile DocumentsCsvFile, graphStore graphstore.BipartiteGraphStore) error {
+func loadDocumentsFromFile(documentFile DocumentsCsvFile, graphStore graphstore.BipartiteGraphStore,
+	sourceAttribute string) error {
+
+	sourceFile := filepath.Base(documentFile.Path)
 
 	// Create a documents CSV file reader
 	reader := NewDocumentsCsvFileReader(documentFile)
@@ -273,6 +347,14 @@ func loadDocumentsFromFile(documentFile DocumentsCsvFile, graphStore graphstore.
 			return err
 		}
 
+		// Record the file the document was loaded from
+		if len(sourceAttribute) > 0 {
+			if document.Attributes == nil {
+				document.Attributes = map[string]string{}
+			}
+			document.Attributes[sourceAttribute] = sourceFile
+		}
+
 		if err := graphStore.AddDocument(document); err != nil {
 			return err
 		}
@@ -284,7 +366,7 @@ func loadDocumentsFromFile(documentFile DocumentsCsvFile, graphStore graphstore.
 // documentWorker is a worker that receives document file jobs to run.
 func documentWorker(ctx context.Context, cancelCtx context.CancelFunc, workerIdx int,
 	documentFilesChan <-chan DocumentsCsvFile, errChan chan<- error,
-	wg *sync.WaitGroup, graphStore graphstore.BipartiteGraphStore) {
+	wg *sync.WaitGroup, graphStore graphstore.BipartiteGraphStore, sourceAttribute string) {
 
 	defer wg.Done()
 
@@ -307,7 +389,7 @@ func documentWorker(ctx context.Context, cancelCtx context.CancelFunc, workerIdx
 		default:
 		}
 
-		err := loadDocumentsFromFile(documentFile, graphStore)
+		err := loadDocumentsFromFile(documentFile, graphStore, sourceAttribute)
 		if err != nil {
 			errChan <- err
 			cancelCtx()
@@ -317,7 +399,7 @@ func documentWorker(ctx context.Context, cancelCtx context.CancelFunc, workerIdx
 
 // loadLinksFromFile loads the links in the CSV file into the bipartite graph store.
 func loadLinksFromFile(linkFile LinksCsvFile, graphStore graphstore.BipartiteGraphStore,
-	ignoreInvalidLinks bool) error {
+	ignoreInvalidLinks bool, resolver *EntityResolver, normaliser *normalisation.Normaliser) error {
 
 	// Create a links CSV file reader
 	reader := NewLinksCsvFileReader(linkFile)
@@ -336,7 +418,8 @@ func loadLinksFromFile(linkFile LinksCsvFile, graphStore graphstore.BipartiteGra
 			return err
 		}
 
-		// Try to add the link
+		// Try to add the link (using the resolved and normalised entity ID)
+		link.EntityId = normaliser.Normalise(resolver.Resolve(link.EntityId))
 		err = graphStore.AddLink(link)
 
 		// If there is an error, handle it if required
@@ -366,7 +449,7 @@ func loadLinksFromFile(linkFile LinksCsvFile, graphStore graphstore.BipartiteGra
 func linkWorker(ctx context.Context, cancelCtx context.CancelFunc, workerIdx int,
 	linkFilesChan <-chan LinksCsvFile, errChan chan<- error,
 	wg *sync.WaitGroup, graphStore graphstore.BipartiteGraphStore,
-	ignoreInvalidLinks bool) {
+	ignoreInvalidLinks bool, resolver *EntityResolver, normaliser *normalisation.Normaliser) {
 
 	defer wg.Done()
 
@@ -389,7 +472,7 @@ func linkWorker(ctx context.Context, cancelCtx context.CancelFunc, workerIdx int
 		default:
 		}
 
-		err := loadLinksFromFile(linkFile, graphStore, ignoreInvalidLinks)
+		err := loadLinksFromFile(linkFile, graphStore, ignoreInvalidLinks, resolver, normaliser)
 		if err != nil {
 			errChan <- err
 			cancelCtx()
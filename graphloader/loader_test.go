@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/normalisation"
 	"github.com/cdclaxton/shortest-path-web-app/set"
 	"github.com/stretchr/testify/assert"
 )
@@ -347,6 +348,50 @@ func TestGraphStoreLoaderFromCsv(t *testing.T) {
 	}
 }
 
+func TestGraphStoreLoaderFromCsvWithSourceAttribute(t *testing.T) {
+	g := graphstore.NewInMemoryBipartiteGraphStore()
+
+	documentFiles := []DocumentsCsvFile{
+		{
+			Path:            testDataSetFolder + "/set-0/data/documents_0.csv",
+			DocumentType:    "Source A",
+			Delimiter:       ",",
+			DocumentIdField: "document ID",
+			FieldToAttribute: map[string]string{
+				"title": "Title",
+			},
+		},
+		{
+			Path:            testDataSetFolder + "/set-0/data/documents_1.csv",
+			DocumentType:    "Source B",
+			Delimiter:       ",",
+			DocumentIdField: "DOCUMENT ID",
+			FieldToAttribute: map[string]string{
+				"title": "Title",
+			},
+		},
+	}
+
+	loader := NewGraphStoreLoaderFromCsv(g, []EntitiesCsvFile{}, documentFiles, []LinksCsvFile{},
+		false, 1, 2, 1)
+	loader.SetDocumentSourceAttribute("Source file")
+	assert.NoError(t, loader.Load())
+
+	expected := map[string]string{
+		"d-1": "documents_0.csv",
+		"d-2": "documents_0.csv",
+		"d-3": "documents_1.csv",
+		"d-4": "documents_1.csv",
+	}
+
+	for documentId, sourceFile := range expected {
+		document, err := g.GetDocument(documentId)
+		assert.NoError(t, err)
+		assert.Equal(t, sourceFile, document.Attributes["Source file"])
+		assert.NotEmpty(t, document.Attributes["Title"])
+	}
+}
+
 func TestParseDelimiter(t *testing.T) {
 
 	// Empty delimiter
@@ -517,3 +562,42 @@ func TestGraphStoreLoaderFromCsvWithInvalidData(t *testing.T) {
 		assert.True(t, found)
 	}
 }
+
+func TestGraphStoreLoaderFromCsvWithNormaliser(t *testing.T) {
+
+	entityFiles := []EntitiesCsvFile{
+		NewEntitiesCsvFile("./test-data/entities_6.csv", "Person", ",", "entity_id",
+			map[string]string{"first name": "Forename", "last name": "Surname"}),
+	}
+
+	documentFiles := []DocumentsCsvFile{
+		NewDocumentsCsvFile("./test-data/documents_2.csv", "Doc", ",", "document_id",
+			map[string]string{"title": "Title"}),
+	}
+
+	linkFiles := []LinksCsvFile{
+		NewLinksCsvFile("./test-data/links_8.csv", "entity_id", "document_id", ","),
+	}
+
+	normaliser, err := normalisation.New(normalisation.Config{
+		StripPunctuation: true,
+		CaseFold:         normalisation.CaseFoldUpper,
+		PhoneCountryCode: "44",
+	})
+	assert.NoError(t, err)
+
+	g := graphstore.NewInMemoryBipartiteGraphStore()
+	loader := NewGraphStoreLoaderFromCsv(g, entityFiles, documentFiles, linkFiles, false, 1, 1, 1)
+	loader.SetEntityIdNormaliser(normaliser)
+	assert.NoError(t, loader.Load())
+
+	// The links use differently formatted entity IDs
+	entity, err := g.GetEntity("P001")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"Forename": "Bob", "Surname": "Smith"}, entity.Attributes)
+	assert.True(t, set.NewPopulatedSet("d-1", "d-2").Equal(entity.LinkedDocumentIds))
+
+	entity, err = g.GetEntity("+441134960000")
+	assert.NoError(t, err)
+	assert.True(t, set.NewPopulatedSet("d-1").Equal(entity.LinkedDocumentIds))
+}
@@ -0,0 +1,301 @@
+// The validator performs a dry-run of loading the entity, document and link CSV files. Rather
+// than adding the data to a graph store, it produces a report of the problems that would cause
+// rows to be skipped or links to be invalid, so the data can be fixed before a (potentially long)
+// graph build.
+
+package graphloader
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cdclaxton/shortest-path-web-app/set"
+)
+
+// Types of file in a validation report
+const (
+	EntitiesFileType  = "entities"
+	DocumentsFileType = "documents"
+	LinksFileType     = "links"
+)
+
+// Maximum number of examples of each type of issue held in a file report
+const maxIssueExamples = 10
+
+// FileReport holds the results of validating a single CSV file.
+type FileReport struct {
+	Path     string `json:"path"`     // Location of the file
+	FileType string `json:"fileType"` // Entities, documents or links
+	Error    string `json:"error"`    // Error preventing the file from being read
+
+	NumberOfRows          int `json:"numberOfRows"`          // Number of rows (excluding the header)
+	NumberOfValidRows     int `json:"numberOfValidRows"`     // Number of rows that would be loaded
+	NumberOfMalformedRows int `json:"numberOfMalformedRows"` // Rows that fail to parse or have no ID
+	NumberOfDuplicateIds  int `json:"numberOfDuplicateIds"`  // IDs already seen in this or another file
+
+	NumberOfLinksMissingEntity   int `json:"numberOfLinksMissingEntity"`   // Links to an unknown entity
+	NumberOfLinksMissingDocument int `json:"numberOfLinksMissingDocument"` // Links to an unknown document
+
+	EmptyAttributes map[string]int `json:"emptyAttributes"` // Attribute name to number of empty values
+	Examples        []string       `json:"examples"`        // Examples of the issues found
+}
+
+// addExample of an issue to the report (if there is space).
+func (f *FileReport) addExample(row int, format string, args ...interface{}) {
+	if len(f.Examples) < maxIssueExamples {
+		f.Examples = append(f.Examples, fmt.Sprintf("row %v: ", row)+fmt.Sprintf(format, args...))
+	}
+}
+
+// HasIssues returns true if the file has any issues.
+func (f *FileReport) HasIssues() bool {
+	return len(f.Error) > 0 || f.NumberOfMalformedRows > 0 || f.NumberOfDuplicateIds > 0 ||
+		f.NumberOfLinksMissingEntity > 0 || f.NumberOfLinksMissingDocument > 0
+}
+
+// ValidationReport holds the results of validating all of the CSV files.
+type ValidationReport struct {
+	Files             []*FileReport `json:"files"`
+	NumberOfEntities  int           `json:"numberOfEntities"`  // Number of unique entity IDs
+	NumberOfDocuments int           `json:"numberOfDocuments"` // Number of unique document IDs
+	NumberOfLinks     int           `json:"numberOfLinks"`     // Number of valid links
+}
+
+// IsValid returns true if none of the files have issues. Empty attributes aren't considered to be
+// an issue as they are common in real data.
+func (v *ValidationReport) IsValid() bool {
+	for _, file := range v.Files {
+		if file.HasIssues() {
+			return false
+		}
+	}
+
+	return true
+}
+
+// openCsvFile for validation and return the reader and the header.
+func openCsvFile(path string, delimiter string, format CsvFormat) (*os.File, *csvRecordReader,
+	[]string, error) {
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	reader, err := newCsvReader(file, delimiter, format)
+	if err != nil {
+		file.Close()
+		return nil, nil, nil, err
+	}
+
+	header, err := reader.Read()
+	if err != nil {
+		file.Close()
+		return nil, nil, nil, err
+	}
+
+	return file, reader, header, nil
+}
+
+// scanRows of the CSV file calling rowFn for each row that parses. Rows that fail to parse are
+// recorded as malformed in the report.
+func scanRows(reader *csvRecordReader, report *FileReport, rowFn func(row int, record []string)) error {
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			return nil
+		}
+
+		report.NumberOfRows += 1
+
+		if err != nil {
+			if _, ok := err.(*csv.ParseError); !ok {
+				return err
+			}
+
+			report.NumberOfMalformedRows += 1
+			report.addExample(report.NumberOfRows, "%v", err)
+			continue
+		}
+
+		rowFn(report.NumberOfRows, record)
+	}
+}
+
+// validateFileWithIds checks a file of entities or documents, adding the IDs to seen.
+func validateFileWithIds(report *FileReport, delimiter string, format CsvFormat, idField string,
+	fieldToAttribute map[string]string, seen *set.Set[string]) error {
+
+	file, reader, header, err := openCsvFile(report.Path, delimiter, format)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	fieldToIndex, err := findIndicesOfFields(header, []string{idField})
+	if err != nil {
+		return err
+	}
+	idIndex := fieldToIndex[idField]
+
+	attributeIndex, err := attributeToFieldIndex(header, fieldToAttribute)
+	if err != nil {
+		return err
+	}
+
+	return scanRows(reader, report, func(row int, record []string) {
+
+		id := record[idIndex]
+		if len(id) == 0 {
+			report.NumberOfMalformedRows += 1
+			report.addExample(row, "empty ID")
+			return
+		}
+
+		if seen.Has(id) {
+			report.NumberOfDuplicateIds += 1
+			report.addExample(row, "duplicate ID %v", id)
+		}
+		seen.Add(id)
+
+		for attribute, idx := range attributeIndex {
+			if len(record[idx]) == 0 {
+				report.EmptyAttributes[attribute] += 1
+			}
+		}
+
+		report.NumberOfValidRows += 1
+	})
+}
+
+// validateLinksFile checks a file of links against the entity and document IDs.
+func validateLinksFile(report *FileReport, linksFile LinksCsvFile, entityIds *set.Set[string],
+	documentIds *set.Set[string]) error {
+
+	file, reader, header, err := openCsvFile(report.Path, linksFile.Delimiter, linksFile.CsvFormat)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	fieldToIndex, err := findIndicesOfFields(header,
+		[]string{linksFile.EntityIdField, linksFile.DocumentIdField})
+	if err != nil {
+		return err
+	}
+	entityIdIndex := fieldToIndex[linksFile.EntityIdField]
+	documentIdIndex := fieldToIndex[linksFile.DocumentIdField]
+
+	return scanRows(reader, report, func(row int, record []string) {
+
+		entityId := record[entityIdIndex]
+		documentId := record[documentIdIndex]
+
+		if len(entityId) == 0 || len(documentId) == 0 {
+			report.NumberOfMalformedRows += 1
+			report.addExample(row, "empty entity or document ID")
+			return
+		}
+
+		valid := true
+		if !entityIds.Has(entityId) {
+			report.NumberOfLinksMissingEntity += 1
+			report.addExample(row, "entity %v not found", entityId)
+			valid = false
+		}
+
+		if !documentIds.Has(documentId) {
+			report.NumberOfLinksMissingDocument += 1
+			report.addExample(row, "document %v not found", documentId)
+			valid = false
+		}
+
+		if valid {
+			report.NumberOfValidRows += 1
+		}
+	})
+}
+
+// newFileReport for the file.
+func newFileReport(path string, fileType string) *FileReport {
+	return &FileReport{
+		Path:            path,
+		FileType:        fileType,
+		EmptyAttributes: map[string]int{},
+		Examples:        []string{},
+	}
+}
+
+// recordFileError in the report, so that validation can continue with the other files.
+func recordFileError(report *FileReport, err error) {
+	if err == nil {
+		return
+	}
+
+	logging.Logger.Warn().
+		Str(logging.ComponentField, componentName).
+		Str("filepath", report.Path).
+		Err(err).
+		Msg("Failed to validate file")
+
+	report.Error = err.Error()
+}
+
+// ValidateCsvFiles reads the entity, document and link CSV files and reports any problems with
+// them. No graph store is modified.
+func ValidateCsvFiles(entityFiles []EntitiesCsvFile, documentFiles []DocumentsCsvFile,
+	linkFiles []LinksCsvFile) *ValidationReport {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Int("numberOfEntityFiles", len(entityFiles)).
+		Int("numberOfDocumentFiles", len(documentFiles)).
+		Int("numberOfLinksFiles", len(linkFiles)).
+		Msg("Validating CSV files")
+
+	report := ValidationReport{
+		Files: []*FileReport{},
+	}
+
+	entityIds := set.NewSet[string]()
+	for _, entityFile := range entityFiles {
+		fileReport := newFileReport(entityFile.Path, EntitiesFileType)
+		recordFileError(fileReport, validateFileWithIds(fileReport, entityFile.Delimiter,
+			entityFile.CsvFormat, entityFile.EntityIdField, entityFile.FieldToAttribute, entityIds))
+		report.Files = append(report.Files, fileReport)
+	}
+
+	documentIds := set.NewSet[string]()
+	for _, documentFile := range documentFiles {
+		fileReport := newFileReport(documentFile.Path, DocumentsFileType)
+		recordFileError(fileReport, validateFileWithIds(fileReport, documentFile.Delimiter,
+			documentFile.CsvFormat, documentFile.DocumentIdField, documentFile.FieldToAttribute,
+			documentIds))
+		report.Files = append(report.Files, fileReport)
+	}
+
+	for _, linkFile := range linkFiles {
+		fileReport := newFileReport(linkFile.Path, LinksFileType)
+		recordFileError(fileReport, validateLinksFile(fileReport, linkFile, entityIds, documentIds))
+		report.Files = append(report.Files, fileReport)
+		report.NumberOfLinks += fileReport.NumberOfValidRows
+	}
+
+	report.NumberOfEntities = entityIds.Len()
+	report.NumberOfDocuments = documentIds.Len()
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("numberOfEntities", strconv.Itoa(report.NumberOfEntities)).
+		Str("numberOfDocuments", strconv.Itoa(report.NumberOfDocuments)).
+		Str("numberOfLinks", strconv.Itoa(report.NumberOfLinks)).
+		Bool("isValid", report.IsValid()).
+		Msg("Validated CSV files")
+
+	return &report
+}
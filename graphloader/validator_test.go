@@ -0,0 +1,85 @@
+package graphloader
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateCsvFiles(t *testing.T) {
+
+	entityFiles := []EntitiesCsvFile{
+		NewEntitiesCsvFile("./test-data/validate_entities.csv", "Person", ",", "entity_id",
+			map[string]string{"first name": "Forename", "last name": "Surname"}),
+	}
+
+	documentFiles := []DocumentsCsvFile{
+		NewDocumentsCsvFile("./test-data/validate_documents.csv", "Doc", ",", "document_id",
+			map[string]string{"title": "Title"}),
+		NewDocumentsCsvFile("./test-data/missing.csv", "Doc", ",", "document_id",
+			map[string]string{}),
+	}
+
+	linkFiles := []LinksCsvFile{
+		NewLinksCsvFile("./test-data/validate_links.csv", "entity_id", "document_id", ","),
+	}
+
+	report := ValidateCsvFiles(entityFiles, documentFiles, linkFiles)
+	assert.False(t, report.IsValid())
+	assert.Equal(t, 4, len(report.Files))
+	assert.Equal(t, 2, report.NumberOfEntities)
+	assert.Equal(t, 2, report.NumberOfDocuments)
+	assert.Equal(t, 2, report.NumberOfLinks)
+
+	// Entities file
+	entities := report.Files[0]
+	assert.Equal(t, EntitiesFileType, entities.FileType)
+	assert.Equal(t, 5, entities.NumberOfRows)
+	assert.Equal(t, 3, entities.NumberOfValidRows)
+	assert.Equal(t, 2, entities.NumberOfMalformedRows)
+	assert.Equal(t, 1, entities.NumberOfDuplicateIds)
+	assert.Equal(t, map[string]int{"Forename": 1}, entities.EmptyAttributes)
+	assert.Equal(t, 3, len(entities.Examples))
+
+	// Documents file (only empty attributes)
+	documents := report.Files[1]
+	assert.False(t, documents.HasIssues())
+	assert.Equal(t, 2, documents.NumberOfRows)
+	assert.Equal(t, map[string]int{"Title": 1}, documents.EmptyAttributes)
+
+	// Missing documents file
+	assert.True(t, len(report.Files[2].Error) > 0)
+	assert.True(t, report.Files[2].HasIssues())
+
+	// Links file
+	links := report.Files[3]
+	assert.Equal(t, LinksFileType, links.FileType)
+	assert.Equal(t, 4, links.NumberOfRows)
+	assert.Equal(t, 2, links.NumberOfValidRows)
+	assert.Equal(t, 1, links.NumberOfLinksMissingEntity)
+	assert.Equal(t, 1, links.NumberOfLinksMissingDocument)
+}
+
+func TestValidateValidCsvFiles(t *testing.T) {
+
+	entityFiles := []EntitiesCsvFile{
+		NewEntitiesCsvFile("./test-data/entities_2.csv", "Person", ",", "entity_id",
+			map[string]string{"first name": "Forename"}),
+	}
+
+	documentFiles := []DocumentsCsvFile{
+		NewDocumentsCsvFile("./test-data/documents_2.csv", "Doc", ",", "document_id",
+			map[string]string{"title": "Title"}),
+	}
+
+	report := ValidateCsvFiles(entityFiles, documentFiles, []LinksCsvFile{})
+	assert.True(t, report.IsValid())
+	assert.Equal(t, 2, report.NumberOfEntities)
+	assert.Equal(t, 2, report.NumberOfDocuments)
+
+	// A missing header field is reported for the file
+	entityFiles[0].EntityIdField = "id"
+	report = ValidateCsvFiles(entityFiles, documentFiles, []LinksCsvFile{})
+	assert.False(t, report.IsValid())
+	assert.Contains(t, report.Files[0].Error, "missing field")
+}
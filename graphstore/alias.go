@@ -0,0 +1,116 @@
+// An entity can have aliases, i.e. other IDs (such as the legacy identifiers of entities that have
+// been merged into it) that resolve to the entity's own ID, its canonical ID. The aliases are held
+// in the bipartite store and GetEntity() consults them transparently, so an entity can be retrieved
+// using any of its IDs. Aliases aren't chained (an alias must resolve directly to an entity) and an
+// entity's own ID takes precedence over an alias with the same ID.
+//
+// In the Pebble and bbolt stores an alias is held as:
+//
+//   a#<alias ID> = <canonical entity ID>
+
+package graphstore
+
+import (
+	"errors"
+)
+
+const aliasPrefix = "a"
+
+var (
+	ErrAliasOfItself = errors.New("alias ID is the same as the entity ID")
+)
+
+// validateAlias checks the alias ID and the canonical entity ID are valid and different.
+func validateAlias(aliasId string, entityId string) error {
+
+	if err := validateEntityId(aliasId); err != nil {
+		return err
+	}
+
+	if err := validateEntityId(entityId); err != nil {
+		return err
+	}
+
+	if aliasId == entityId {
+		return ErrAliasOfItself
+	}
+
+	return nil
+}
+
+// aliasToPebbleKey generates the Pebble (or bbolt) key for an alias ID.
+func aliasToPebbleKey(aliasId string) ([]byte, error) {
+
+	if err := validateEntityId(aliasId); err != nil {
+		return nil, err
+	}
+
+	return []byte(aliasPrefix + separator + aliasId), nil
+}
+
+// resolveAlias returns the canonical ID of the entity ID using the store's functions to check
+// whether an entity has the ID and to look up an alias. An ID that isn't an alias (or is also the
+// ID of an entity) is returned unchanged.
+func resolveAlias(entityId string, hasEntity func(string) (bool, error),
+	lookup func(string) (string, bool, error)) (string, error) {
+
+	// An invalid ID can't be an alias
+	if validateEntityId(entityId) != nil {
+		return entityId, nil
+	}
+
+	found, err := hasEntity(entityId)
+	if err != nil || found {
+		return entityId, err
+	}
+
+	canonicalId, found, err := lookup(entityId)
+	if err != nil {
+		return "", err
+	}
+
+	if !found {
+		return entityId, nil
+	}
+
+	return canonicalId, nil
+}
+
+// getEntityOrAlias gets the entity with the ID using the store's get function and, if there isn't
+// an entity with the ID, gets the entity of which the ID is an alias.
+func getEntityOrAlias(entityId string, get func(string) (*Entity, error),
+	resolve func(string) (string, error)) (*Entity, error) {
+
+	entity, err := get(entityId)
+	if err != ErrEntityNotFound {
+		return entity, err
+	}
+
+	canonicalId, err := resolve(entityId)
+	if err != nil {
+		return nil, err
+	}
+
+	if canonicalId == entityId {
+		return nil, ErrEntityNotFound
+	}
+
+	return get(canonicalId)
+}
+
+// ResolveAliases of the entity IDs, returning the canonical IDs in the same order. An ID that isn't
+// an alias is returned unchanged.
+func ResolveAliases(store BipartiteGraphStore, entityIds []string) ([]string, error) {
+
+	canonicalIds := make([]string, len(entityIds))
+
+	for idx, entityId := range entityIds {
+		canonicalId, err := store.ResolveAlias(entityId)
+		if err != nil {
+			return nil, err
+		}
+		canonicalIds[idx] = canonicalId
+	}
+
+	return canonicalIds, nil
+}
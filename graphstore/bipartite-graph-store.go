@@ -26,6 +26,10 @@ type BipartiteGraphStore interface {
 	AddEntity(Entity) error                             // Add (or update) an entity to the store
 	AddDocument(Document) error                         // Add (or update) a document to the store
 	AddLink(Link) error                                 // Add a link from an entity to a document (by ID)
+	AddAlias(string, string) error                      // Add an alias ID of an entity (by ID)
+	RemoveEntity(string) error                          // Remove an entity and its links
+	RemoveDocument(string) error                        // Remove a document and its links
+	RemoveLink(Link) error                              // Remove the link between an entity and a document
 	Clear() error                                       // Clear the store
 	Close() error                                       // Close the store
 	Destroy() error                                     // Destroy the graph (and any backing files)
@@ -40,6 +44,7 @@ type BipartiteGraphStore interface {
 	NewEntityIdIterator() (EntityIdIterator, error)     // Get an entity ID iterator
 	NumberOfEntities() (int, error)                     // Number of entities in the store
 	NumberOfDocuments() (int, error)                    // Number of documents in the store
+	ResolveAlias(string) (string, error)                // Canonical ID of an entity ID (unchanged if not an alias)
 }
 
 // Error constants
@@ -50,6 +55,7 @@ var (
 	ErrDocumentIsNil     = errors.New("Document is nil")             // Document pointer is nil
 	ErrEntityIdIsEmpty   = errors.New("Entity ID has length zero")   // Empty string
 	ErrDocumentIdIsEmpty = errors.New("Document ID has length zero") // Empty string
+	ErrLinkNotFound      = errors.New("Link not found in bipartite store")
 )
 
 // BulkLoadBipartiteGraphStore given entities, documents and links.
@@ -277,16 +283,38 @@ type BipartiteStats struct {
 	NumberOfDocumentsWithEntities int
 }
 
+// CalcBipartiteStats of the bipartite store, which are only counted if the store doesn't hold them.
 func CalcBipartiteStats(bg BipartiteGraphStore) (BipartiteStats, error) {
+	if counted, ok := bg.(countedBipartiteStore); ok {
+		return counted.countedStats()
+	}
+
+	return countBipartiteStats(bg)
+}
+
+// countBipartiteStats by iterating over the entities and documents of the bipartite store.
+func countBipartiteStats(bg BipartiteGraphStore) (BipartiteStats, error) {
+	stats, _, err := countBipartite(bg)
+	return stats, err
+}
+
+// countBipartite stats and types of the entities and documents of the bipartite store in a
+// single pass over them.
+func countBipartite(bg BipartiteGraphStore) (BipartiteStats, TypeCounts, error) {
+
+	types := TypeCounts{
+		EntityTypes:   map[string]int{},
+		DocumentTypes: map[string]int{},
+	}
 
-	numEntities, numEntitiesWithDocuments, err := calcBipartiteEntityStats(bg)
+	numEntities, numEntitiesWithDocuments, err := calcBipartiteEntityStats(bg, types.EntityTypes)
 	if err != nil {
-		return BipartiteStats{}, err
+		return BipartiteStats{}, TypeCounts{}, err
 	}
 
-	numDocuments, numDocumentsWithEntities, err := calcBipartiteDocumentStats(bg)
+	numDocuments, numDocumentsWithEntities, err := calcBipartiteDocumentStats(bg, types.DocumentTypes)
 	if err != nil {
-		return BipartiteStats{}, err
+		return BipartiteStats{}, TypeCounts{}, err
 	}
 
 	return BipartiteStats{
@@ -294,10 +322,102 @@ func CalcBipartiteStats(bg BipartiteGraphStore) (BipartiteStats, error) {
 		NumberOfEntitiesWithDocuments: numEntitiesWithDocuments,
 		NumberOfDocuments:             numDocuments,
 		NumberOfDocumentsWithEntities: numDocumentsWithEntities,
-	}, nil
+	}, types, nil
+}
+
+// TypeCounts are the number of entities and documents of each type in a bipartite store.
+type TypeCounts struct {
+	EntityTypes   map[string]int // Number of entities of each type
+	DocumentTypes map[string]int // Number of documents of each type
+}
+
+// CountTypes of the entities and documents in the bipartite store, which are only counted if the
+// store doesn't hold them.
+func CountTypes(bg BipartiteGraphStore) (TypeCounts, error) {
+	if counted, ok := bg.(countedBipartiteStore); ok {
+		return counted.countedTypes()
+	}
+
+	_, types, err := countBipartite(bg)
+	return types, err
+}
+
+// addTypeCounts returns the sum of the counts of each type, without the types whose count is zero.
+func addTypeCounts(counts map[string]int, change map[string]int) map[string]int {
+	sum := map[string]int{}
+	for _, c := range []map[string]int{counts, change} {
+		for name, value := range c {
+			sum[name] += value
+		}
+	}
+
+	for name, value := range sum {
+		if value == 0 {
+			delete(sum, name)
+		}
+	}
+
+	return sum
+}
+
+// Add the change (e.g. from removing documents) to the type counts.
+func (t TypeCounts) Add(change TypeCounts) TypeCounts {
+	return TypeCounts{
+		EntityTypes:   addTypeCounts(t.EntityTypes, change.EntityTypes),
+		DocumentTypes: addTypeCounts(t.DocumentTypes, change.DocumentTypes),
+	}
+}
+
+// Add the change (e.g. from removing documents) to the stats.
+func (s BipartiteStats) Add(change BipartiteStats) BipartiteStats {
+	return BipartiteStats{
+		NumberOfEntities:              s.NumberOfEntities + change.NumberOfEntities,
+		NumberOfEntitiesWithDocuments: s.NumberOfEntitiesWithDocuments + change.NumberOfEntitiesWithDocuments,
+		NumberOfDocuments:             s.NumberOfDocuments + change.NumberOfDocuments,
+		NumberOfDocumentsWithEntities: s.NumberOfDocumentsWithEntities + change.NumberOfDocumentsWithEntities,
+	}
+}
+
+// RecordBipartiteStats and type counts that are known to be those of the bipartite store (e.g.
+// they were updated incrementally after a change), so that a store that holds its stats doesn't
+// need to count them.
+func RecordBipartiteStats(bg BipartiteGraphStore, stats BipartiteStats, types TypeCounts) error {
+	if counted, ok := bg.(countedBipartiteStore); ok {
+		return counted.recordStats(stats, types)
+	}
+
+	return nil
+}
+
+// CountEntityTypes in the bipartite store, i.e. the number of entities of each type.
+func CountEntityTypes(bg BipartiteGraphStore) (map[string]int, error) {
+
+	entityIdIter, err := bg.NewEntityIdIterator()
+	if err != nil {
+		return nil, err
+	}
+
+	counts := map[string]int{}
+	for entityIdIter.hasNext() {
+		entityId, err := entityIdIter.nextEntityId()
+		if err != nil {
+			return nil, err
+		}
+
+		entity, err := bg.GetEntity(entityId)
+		if err != nil {
+			return nil, err
+		}
+
+		counts[entity.EntityType] += 1
+	}
+
+	return counts, nil
 }
 
-func calcBipartiteEntityStats(bg BipartiteGraphStore) (int, int, error) {
+// calcBipartiteEntityStats returns the number of entities and the number with documents, counting
+// the entities of each type.
+func calcBipartiteEntityStats(bg BipartiteGraphStore, entityTypes map[string]int) (int, int, error) {
 
 	numberEntities := 0
 	numberEntitiesWithDocuments := 0
@@ -327,12 +447,16 @@ func calcBipartiteEntityStats(bg BipartiteGraphStore) (int, int, error) {
 		if entity.LinkedDocumentIds.Len() > 0 {
 			numberEntitiesWithDocuments += 1
 		}
+
+		entityTypes[entity.EntityType] += 1
 	}
 
 	return numberEntities, numberEntitiesWithDocuments, nil
 }
 
-func calcBipartiteDocumentStats(bg BipartiteGraphStore) (int, int, error) {
+// calcBipartiteDocumentStats returns the number of documents and the number with entities,
+// counting the documents of each type.
+func calcBipartiteDocumentStats(bg BipartiteGraphStore, documentTypes map[string]int) (int, int, error) {
 
 	numberDocuments := 0
 	numberOfDocumentsWithEntities := 0
@@ -362,6 +486,8 @@ func calcBipartiteDocumentStats(bg BipartiteGraphStore) (int, int, error) {
 		if document.LinkedEntityIds.Len() > 0 {
 			numberOfDocumentsWithEntities += 1
 		}
+
+		documentTypes[document.DocumentType] += 1
 	}
 
 	return numberDocuments, numberOfDocumentsWithEntities, nil
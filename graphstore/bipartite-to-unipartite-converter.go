@@ -2,10 +2,14 @@ package graphstore
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io/fs"
+	"os"
 	"strconv"
 	"sync"
+	"time"
 
 	"github.com/cdclaxton/shortest-path-web-app/logging"
 	"github.com/cdclaxton/shortest-path-web-app/set"
@@ -17,14 +21,120 @@ var (
 	ErrEntitiesToSkipIsNil    = errors.New("entities to skip is nil")
 	ErrInvalidNumberOfWorkers = errors.New("invalid number of workers")
 	ErrInvalidJobChannelSize  = errors.New("invalid job chnanel size")
+	ErrInvalidCheckpoint      = errors.New("invalid conversion checkpoint config")
+	ErrCheckpointMismatch     = errors.New("conversion checkpoint doesn't match the bipartite store")
 )
 
+// ConversionCheckpoint records the progress of a bipartite to unipartite conversion, so that the
+// conversion can be resumed if the process dies. The documents are processed in the order given
+// by the bipartite store's document iterator, which must be deterministic (as it is for Pebble and
+// bbolt stores).
+type ConversionCheckpoint struct {
+	DocumentsProcessed int       `json:"documentsProcessed"` // Number of documents fully converted
+	LastDocumentId     string    `json:"lastDocumentId"`     // ID of the last document converted
+	TotalDocuments     int       `json:"totalDocuments"`     // Number of documents in the bipartite store
+	DateCreated        time.Time `json:"dateCreated"`        // Date and time the checkpoint was written
+}
+
+// CheckpointConfig defines where and how often conversion checkpoints are written.
+type CheckpointConfig struct {
+	Filepath string // Location of the checkpoint file
+	Interval int    // Number of documents between checkpoints
+}
+
+// ReadConversionCheckpoint from a JSON file. If the file doesn't exist, nil is returned.
+func ReadConversionCheckpoint(filepath string) (*ConversionCheckpoint, error) {
+
+	content, err := os.ReadFile(filepath)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	checkpoint := ConversionCheckpoint{}
+	if err := json.Unmarshal(content, &checkpoint); err != nil {
+		return nil, err
+	}
+
+	return &checkpoint, nil
+}
+
+// writeConversionCheckpoint to a JSON file. The file is replaced atomically so that a partially
+// written checkpoint is never read.
+func writeConversionCheckpoint(filepath string, checkpoint ConversionCheckpoint) error {
+
+	content, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	tempFilepath := filepath + ".tmp"
+	if err := os.WriteFile(tempFilepath, content, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tempFilepath, filepath)
+}
+
+// ConversionOptions for the bipartite to unipartite conversion.
+type ConversionOptions struct {
+	Directed      bool   // Preserve the direction of links between entities and documents
+	DateAttribute string // Document attribute holding the date recorded in the edge metadata
+	DateFormat    string // Format of the document date in Golang's time format
+	DedupCapacity int    // Edges remembered to skip repeated writes (0 for the default, -1 to disable)
+}
+
+// documentDate returns the date of the document for the edge metadata. If the date isn't known, a
+// zero time is returned.
+func (c ConversionOptions) documentDate(doc *Document) time.Time {
+
+	if len(c.DateAttribute) == 0 || len(c.DateFormat) == 0 {
+		return time.Time{}
+	}
+
+	value, found := doc.Attributes[c.DateAttribute]
+	if !found {
+		return time.Time{}
+	}
+
+	date, err := time.Parse(c.DateFormat, value)
+	if err != nil {
+		return time.Time{}
+	}
+
+	return date
+}
+
 // BipartiteToUnipartite converter to load a unipartite graph from a bipartite graph.
 //
 // The set of skipEntities are those entities that won't be transferred to the unipartite graph.
 func BipartiteToUnipartite(bi BipartiteGraphStore, uni UnipartiteGraphStore,
 	skipEntities *set.Set[string], numWorkers int, jobChannelSize int) error {
 
+	return BipartiteToUnipartiteWithCheckpoints(bi, uni, skipEntities, numWorkers, jobChannelSize,
+		ConversionOptions{}, nil)
+}
+
+// BipartiteToUnipartiteWithCheckpoints converts a bipartite graph to a unipartite graph, writing
+// checkpoints periodically. If the checkpoint file exists, the conversion resumes from the
+// checkpoint, so the unipartite store must hold the partially converted graph. The checkpoint file
+// is deleted once the conversion is complete. If checkpointConfig is nil, checkpointing is
+// disabled.
+//
+// If the Directed option is true, the direction of the links between entities and documents is
+// preserved, i.e. a directed edge is added from each source entity to each destination entity of a
+// document. Otherwise, all of the edges are undirected.
+//
+// Each document linking two entities is recorded in the metadata of the edges between them, along
+// with the document's date if the DateAttribute and DateFormat options are set. When the conversion
+// is resumed, the metadata of the edges of the documents after the checkpoint is rolled back to the
+// documents up to the checkpoint, so a document converted after the checkpoint isn't recorded
+// twice.
+func BipartiteToUnipartiteWithCheckpoints(bi BipartiteGraphStore, uni UnipartiteGraphStore,
+	skipEntities *set.Set[string], numWorkers int, jobChannelSize int, options ConversionOptions,
+	checkpointConfig *CheckpointConfig) error {
+
 	// Preconditions
 	if bi == nil {
 		return ErrBipartiteStoreIsNil
@@ -46,35 +156,107 @@ func BipartiteToUnipartite(bi BipartiteGraphStore, uni UnipartiteGraphStore,
 		return fmt.Errorf("%w: %d", ErrInvalidJobChannelSize, jobChannelSize)
 	}
 
+	if checkpointConfig != nil && (len(checkpointConfig.Filepath) == 0 || checkpointConfig.Interval < 1) {
+		return ErrInvalidCheckpoint
+	}
+
 	logging.Logger.Info().
 		Str(logging.ComponentField, componentName).
 		Str("numberOfWorkers", strconv.Itoa(numWorkers)).
 		Str("jobChannelSize", strconv.Itoa(jobChannelSize)).
+		Bool("directed", options.Directed).
+		Str("dateAttribute", options.DateAttribute).
+		Int("dedupCapacity", options.DedupCapacity).
+		Bool("checkpointing", checkpointConfig != nil).
 		Msg("Starting bipartite to unipartite conversion")
 
+	// Get the total number of documents to process
+	totalDocs, err := bi.NumberOfDocuments()
+	if err != nil {
+		return err
+	}
+
+	// Read the checkpoint to resume from or write the initial checkpoint
+	resume := ConversionCheckpoint{TotalDocuments: totalDocs}
+	if checkpointConfig != nil {
+		previous, err := ReadConversionCheckpoint(checkpointConfig.Filepath)
+		if err != nil {
+			return err
+		}
+
+		if previous != nil {
+			if previous.TotalDocuments != totalDocs {
+				return fmt.Errorf("%w: checkpoint has %v documents, store has %v",
+					ErrCheckpointMismatch, previous.TotalDocuments, totalDocs)
+			}
+
+			logging.Logger.Info().
+				Str(logging.ComponentField, componentName).
+				Str("documentsProcessed", strconv.Itoa(previous.DocumentsProcessed)).
+				Str("totalDocuments", strconv.Itoa(totalDocs)).
+				Msg("Resuming bipartite to unipartite conversion from checkpoint")
+
+			resume = *previous
+
+			if err := rollBackEdgeMetadata(bi, uni, skipEntities, options, resume); err != nil {
+				return err
+			}
+		} else {
+			resume.DateCreated = time.Now()
+			if err := writeConversionCheckpoint(checkpointConfig.Filepath, resume); err != nil {
+				return err
+			}
+		}
+	}
+
 	// Buffered channel on which to place jobs (i.e. documents to process)
 	jobsChan := make(chan conversionJob, jobChannelSize)
 
-	// Channel to hold errors from the generator and workers
-	errChan := make(chan error, numWorkers+1)
+	// Channel to hold errors from the generator, workers and checkpoint writer
+	errChan := make(chan error, numWorkers+2)
+
+	// Channel on which workers report the documents they have converted
+	var progressChan chan conversionJob
+	if checkpointConfig != nil {
+		progressChan = make(chan conversionJob, jobChannelSize)
+	}
+
+	// Edges written by the workers, so that each edge is written once
+	dedup := newEdgeDeduplicator(options.DedupCapacity)
+
+	// Documents recorded in the metadata of the edges by the workers, written once per batch
+	batch := newEdgeMetadataBatch(options.DedupCapacity)
 
 	var wg sync.WaitGroup
 	ctx := context.Background()
 	ctx, cancelFunc := context.WithCancel(ctx)
 
+	// Start the checkpoint writer
+	var checkpointWg sync.WaitGroup
+	if checkpointConfig != nil {
+		checkpointWg.Add(1)
+		go checkpointWriter(&checkpointWg, cancelFunc, progressChan, errChan, uni, batch, *checkpointConfig, resume)
+	}
+
 	// Start the document generator
 	wg.Add(1)
-	go documentGenerator(&wg, ctx, cancelFunc, bi, jobsChan, errChan)
+	go documentGenerator(&wg, ctx, cancelFunc, bi, jobsChan, errChan, resume)
 
 	// Start the workers
 	for workerIdx := 0; workerIdx < numWorkers; workerIdx++ {
 		wg.Add(1)
-		go conversionWorker(workerIdx, &wg, ctx, cancelFunc, jobsChan, errChan, bi, uni, skipEntities)
+		go conversionWorker(workerIdx, &wg, ctx, cancelFunc, jobsChan, progressChan, errChan, bi, uni,
+			skipEntities, options, dedup, batch)
 	}
 
-	// Wait for the document generator and workers to finish
+	// Wait for the document generator and workers to finish, then the checkpoint writer
 	wg.Wait()
 
+	if progressChan != nil {
+		close(progressChan)
+	}
+	checkpointWg.Wait()
+
 	// Check to see if an error occurred
 	select {
 	case msg := <-errChan:
@@ -82,18 +264,181 @@ func BipartiteToUnipartite(bi BipartiteGraphStore, uni UnipartiteGraphStore,
 	default:
 	}
 
-	err := uni.Finalise()
+	if err := batch.flush(uni); err != nil {
+		return err
+	}
+
+	err = uni.Finalise()
 	if err != nil {
 		return err
 	}
 
+	// The conversion is complete, so the checkpoint is no longer required
+	if checkpointConfig != nil {
+		if err := os.Remove(checkpointConfig.Filepath); err != nil {
+			return err
+		}
+	}
+
 	logging.Logger.Info().
 		Str(logging.ComponentField, componentName).
+		Int64("duplicateWritesSkipped", dedup.skipped()).
+		Int64("dedupShardsForgotten", dedup.forgotten()).
+		Int64("metadataDocumentsRecorded", batch.documents()).
+		Int64("metadataWrites", batch.writes()).
 		Msg("Finished bipartite to unipartite conversion")
 
 	return nil
 }
 
+// rollBackEdgeMetadata of the edges between the entities linked by the documents after the
+// checkpoint. Those documents are converted again when the conversion resumes, but some of them may
+// have been recorded in the edge metadata before the conversion stopped, so the metadata of their
+// edges is recomputed from the documents up to the checkpoint. The IDs of the documents after the
+// checkpoint are held in memory.
+func rollBackEdgeMetadata(bi BipartiteGraphStore, uni UnipartiteGraphStore,
+	skipEntities *set.Set[string], options ConversionOptions, resume ConversionCheckpoint) error {
+
+	// Find the documents after the checkpoint
+	pending := set.NewSet[string]()
+	err := forEachDocumentId(bi, func(docIndex int, docId string) error {
+		if docIndex > resume.DocumentsProcessed {
+			pending.Add(docId)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return err
+	}
+
+	// Edges of the documents after the checkpoint that have documents recorded in their metadata
+	rolledBack := map[Edge]*EdgeMetadata{}
+	for _, docId := range pending.ToSlice() {
+		doc, err := bi.GetDocument(docId)
+		if err != nil {
+			return err
+		}
+
+		err = forEachEntityPair(doc, skipEntities, func(e1 string, e2 string) error {
+			edge := Edge{V1: e1, V2: e2}
+			if _, found := rolledBack[edge]; found {
+				return nil
+			}
+
+			metadata, err := uni.EdgeMetadata(e1, e2)
+			if err != nil {
+				return err
+			}
+
+			if metadata != nil {
+				rolledBack[edge] = &EdgeMetadata{}
+			}
+			return nil
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	// Recompute the metadata from the documents up to the checkpoint
+	if len(rolledBack) > 0 {
+		err = forEachDocumentId(bi, func(docIndex int, docId string) error {
+			if docIndex > resume.DocumentsProcessed {
+				return nil
+			}
+
+			doc, err := bi.GetDocument(docId)
+			if err != nil || doc == nil {
+				return err
+			}
+
+			date := options.documentDate(doc)
+			return forEachEntityPair(doc, skipEntities, func(e1 string, e2 string) error {
+				if metadata, found := rolledBack[Edge{V1: e1, V2: e2}]; found {
+					metadata.addDocument(date)
+				}
+				return nil
+			})
+		})
+
+		if err != nil {
+			return err
+		}
+	}
+
+	for edge, metadata := range rolledBack {
+		if metadata.NumberOfDocuments == 0 {
+			metadata = nil
+		}
+
+		if err := uni.SetEdgeMetadata(edge.V1, edge.V2, metadata); err != nil {
+			return err
+		}
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Int("documentsAfterCheckpoint", pending.Len()).
+		Int("edgesRolledBack", len(rolledBack)).
+		Msg("Rolled back the edge metadata of the documents after the checkpoint")
+
+	return nil
+}
+
+// forEachDocumentId of the bipartite store in the order of its iterator, where the index of the
+// first document is 1.
+func forEachDocumentId(bi BipartiteGraphStore, fn func(int, string) error) error {
+
+	it, err := bi.NewDocumentIdIterator()
+	if err != nil {
+		return err
+	}
+
+	docIndex := 0
+	for it.hasNext() {
+		docIndex += 1
+
+		docId, err := it.nextDocumentId()
+		if err != nil {
+			return err
+		}
+
+		if err := fn(docIndex, docId); err != nil {
+			if closer, ok := it.(interface{ close() error }); ok && it.hasNext() {
+				closer.close()
+			}
+			return err
+		}
+	}
+
+	return nil
+}
+
+// forEachEntityPair of a document (which may be nil) whose edge metadata is recorded by the
+// conversion, i.e. each ordered pair of distinct entities that aren't skipped.
+func forEachEntityPair(doc *Document, skipEntities *set.Set[string], fn func(string, string) error) error {
+
+	if doc == nil {
+		return nil
+	}
+
+	for e1 := range doc.LinkedEntityIds.Values {
+		for e2 := range doc.LinkedEntityIds.Values {
+			if e1 == e2 || skipEntities.Has(e1) || skipEntities.Has(e2) {
+				continue
+			}
+
+			if err := fn(e1, e2); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 type conversionJob struct {
 	documentId       string
 	documentIndex    int
@@ -101,8 +446,10 @@ type conversionJob struct {
 }
 
 // documentGenerator places document IDs from the bipartite store onto a job channel for workers.
+// The documents up to the checkpoint to resume from are skipped.
 func documentGenerator(wg *sync.WaitGroup, ctx context.Context, cancelCtx context.CancelFunc,
-	bi BipartiteGraphStore, jobChannel chan<- conversionJob, errChan chan<- error) {
+	bi BipartiteGraphStore, jobChannel chan<- conversionJob, errChan chan<- error,
+	resume ConversionCheckpoint) {
 
 	logging.Logger.Info().
 		Str(logging.ComponentField, componentName).
@@ -111,14 +458,6 @@ func documentGenerator(wg *sync.WaitGroup, ctx context.Context, cancelCtx contex
 	defer wg.Done()
 	defer close(jobChannel)
 
-	// Get the total number of documents to process
-	totalDocs, err := bi.NumberOfDocuments()
-	if err != nil {
-		errChan <- err
-		cancelCtx()
-		return
-	}
-
 	// Iterator to retrieve documents from the bipartite graph store
 	it, err := bi.NewDocumentIdIterator()
 	if err != nil {
@@ -127,6 +466,13 @@ func documentGenerator(wg *sync.WaitGroup, ctx context.Context, cancelCtx contex
 		return
 	}
 
+	// Release the iterator if the generation ends before the iterator is exhausted
+	defer func() {
+		if closer, ok := it.(interface{ close() error }); ok && it.hasNext() {
+			closer.close()
+		}
+	}()
+
 	docIndex := 0
 	for it.hasNext() {
 
@@ -150,10 +496,33 @@ func documentGenerator(wg *sync.WaitGroup, ctx context.Context, cancelCtx contex
 			return
 		}
 
-		jobChannel <- conversionJob{
+		// Skip the documents that were converted before the checkpoint
+		if docIndex < resume.DocumentsProcessed {
+			continue
+		}
+
+		if docIndex == resume.DocumentsProcessed {
+			if docId != resume.LastDocumentId {
+				errChan <- fmt.Errorf("%w: expected document %v at index %v, found %v",
+					ErrCheckpointMismatch, resume.LastDocumentId, docIndex, docId)
+				cancelCtx()
+				return
+			}
+			continue
+		}
+
+		// Don't block if the workers have stopped because of an error
+		select {
+		case jobChannel <- conversionJob{
 			documentId:       docId,
 			documentIndex:    docIndex,
-			numDocsToProcess: totalDocs,
+			numDocsToProcess: resume.TotalDocuments,
+		}:
+		case <-ctx.Done():
+			logging.Logger.Info().
+				Str(logging.ComponentField, componentName).
+				Msg("Document generator received cancel notification")
+			return
 		}
 	}
 
@@ -162,10 +531,166 @@ func documentGenerator(wg *sync.WaitGroup, ctx context.Context, cancelCtx contex
 		Msg("Closing down document generator for bipartite to unipartite conversion")
 }
 
-// conversionWorker receives jobs from a channel and creates links in the unipartite store.
+// checkpointWriter receives the converted documents from the workers and periodically writes a
+// checkpoint. As the workers process documents out of order, the checkpoint records the number of
+// documents up to which all documents have been converted.
+func checkpointWriter(wg *sync.WaitGroup, cancelCtx context.CancelFunc,
+	progressChan <-chan conversionJob, errChan chan<- error, uni UnipartiteGraphStore,
+	batch *edgeMetadataBatch, config CheckpointConfig, resume ConversionCheckpoint) {
+
+	defer wg.Done()
+
+	checkpoint := resume
+	lastWritten := resume.DocumentsProcessed
+	completed := map[int]string{} // Document index to ID of documents converted out of order
+	failed := false
+
+	// Keep reading from the channel, even after a failure, so that the workers don't block
+	for job := range progressChan {
+		if failed {
+			continue
+		}
+
+		completed[job.documentIndex] = job.documentId
+
+		// Advance the checkpoint over the contiguous run of converted documents
+		for {
+			docId, found := completed[checkpoint.DocumentsProcessed+1]
+			if !found {
+				break
+			}
+
+			delete(completed, checkpoint.DocumentsProcessed+1)
+			checkpoint.DocumentsProcessed += 1
+			checkpoint.LastDocumentId = docId
+		}
+
+		if checkpoint.DocumentsProcessed-lastWritten < config.Interval {
+			continue
+		}
+
+		// Make sure the converted documents are persisted before writing the checkpoint (the batched
+		// metadata may include documents after the checkpoint, which are rolled back on resumption)
+		checkpoint.DateCreated = time.Now()
+		err := batch.flush(uni)
+		if err == nil {
+			err = uni.Finalise()
+		}
+		if err == nil {
+			err = writeConversionCheckpoint(config.Filepath, checkpoint)
+		}
+
+		if err != nil {
+			errChan <- err
+			cancelCtx()
+			failed = true
+			continue
+		}
+
+		lastWritten = checkpoint.DocumentsProcessed
+
+		logging.Logger.Info().
+			Str(logging.ComponentField, componentName).
+			Str("documentsProcessed", strconv.Itoa(checkpoint.DocumentsProcessed)).
+			Str("totalDocuments", strconv.Itoa(checkpoint.TotalDocuments)).
+			Msg("Wrote conversion checkpoint")
+	}
+}
+
+// convertDocument adds the entities linked to the document to the unipartite store and records the
+// document in the metadata of the edges. If the Directed option is true, the edges from the
+// document's source entities to its destination entities are directed. The metadata is recorded
+// in both directions regardless, as it describes the documents linking the entities. The edges
+// already written (according to the deduplicator) aren't written again and the metadata is
+// accumulated in the batch.
+func convertDocument(documentId string, bi BipartiteGraphStore, uni UnipartiteGraphStore,
+	skipEntities *set.Set[string], options ConversionOptions, dedup *edgeDeduplicator,
+	batch *edgeMetadataBatch) error {
+
+	// Get the document given its ID
+	doc, err := bi.GetDocument(documentId)
+	if err != nil {
+		return err
+	}
+	if doc == nil {
+		return fmt.Errorf("document doesn't exist with ID: %v", documentId)
+	}
+
+	// If there is just a single entity, add it to the graph
+	if doc.LinkedEntityIds.Len() == 1 {
+		for entityId := range doc.LinkedEntityIds.Values {
+			if dedup.firstSeen(entityKey(entityId)) {
+				uni.AddEntity(entityId)
+			}
+		}
+		return nil
+	}
+
+	// Add the entities to the graph
+	date := options.documentDate(doc)
+	for e1 := range doc.LinkedEntityIds.Values {
+
+		if skipEntities.Has(e1) {
+			continue
+		}
+
+		for e2 := range doc.LinkedEntityIds.Values {
+
+			if skipEntities.Has(e2) || e1 == e2 {
+				continue
+			}
+
+			if err := convertEntityPair(doc, e1, e2, date, uni, options, dedup, batch); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// convertEntityPair records the document in the metadata of the edge from e1 to e2 (the opposite
+// direction is recorded when the entities are visited in the other order) and adds the edge if it
+// hasn't already been written. The deduplicator and the batch may be nil, in which case the
+// metadata is written directly.
+func convertEntityPair(doc *Document, e1 string, e2 string, date time.Time,
+	uni UnipartiteGraphStore, options ConversionOptions, dedup *edgeDeduplicator,
+	batch *edgeMetadataBatch) error {
+
+	if err := batch.add(uni, e1, e2, date); err != nil {
+		return err
+	}
+
+	if options.Directed && doc.IsDirected() {
+		d1, d2 := doc.Direction(e1), doc.Direction(e2)
+
+		// Add the directed link (once, from the source entity)
+		if d1 == LinkSource && d2 == LinkDestination {
+			if !dedup.firstSeen(directedKey(e1, e2)) {
+				return nil
+			}
+			return uni.AddDirected(e1, e2)
+		}
+
+		if d1 == LinkDestination && d2 == LinkSource {
+			return nil
+		}
+	}
+
+	// Add the link (once for both directions)
+	if !dedup.firstSeen(undirectedKey(e1, e2)) {
+		return nil
+	}
+	return uni.AddUndirected(e1, e2)
+}
+
+// conversionWorker receives jobs from a channel and creates links in the unipartite store. If the
+// progress channel isn't nil, each converted document is sent on it.
 func conversionWorker(workerIdx int, wg *sync.WaitGroup, ctx context.Context,
-	cancelCtx context.CancelFunc, jobChannel <-chan conversionJob, errChan chan<- error,
-	bi BipartiteGraphStore, uni UnipartiteGraphStore, skipEntities *set.Set[string]) {
+	cancelCtx context.CancelFunc, jobChannel <-chan conversionJob, progressChan chan<- conversionJob,
+	errChan chan<- error, bi BipartiteGraphStore, uni UnipartiteGraphStore,
+	skipEntities *set.Set[string], options ConversionOptions, dedup *edgeDeduplicator,
+	batch *edgeMetadataBatch) {
 
 	logging.Logger.Info().
 		Str(logging.ComponentField, componentName).
@@ -200,46 +725,14 @@ func conversionWorker(workerIdx int, wg *sync.WaitGroup, ctx context.Context,
 				Msg("Building unipartite graph")
 		}
 
-		// Get the document given its ID
-		doc, err := bi.GetDocument(job.documentId)
-		if err != nil {
+		if err := convertDocument(job.documentId, bi, uni, skipEntities, options, dedup, batch); err != nil {
 			errChan <- err
 			cancelCtx()
 			return
 		}
-		if doc == nil {
-			errChan <- fmt.Errorf("document doesn't exist with ID: %v", job.documentId)
-			cancelCtx()
-			return
-		}
-
-		// If there is just a single entity, add it to the graph
-		if doc.LinkedEntityIds.Len() == 1 {
-			for entityId := range doc.LinkedEntityIds.Values {
-				uni.AddEntity(entityId)
-			}
-			continue
-		}
 
-		// Add the entities to the graph
-		for e1 := range doc.LinkedEntityIds.Values {
-
-			if skipEntities.Has(e1) {
-				continue
-			}
-
-			for e2 := range doc.LinkedEntityIds.Values {
-
-				if !skipEntities.Has(e2) && e1 != e2 {
-					// Add the link
-					err := uni.AddUndirected(e1, e2)
-					if err != nil {
-						errChan <- err
-						cancelCtx()
-						return
-					}
-				}
-			}
+		if progressChan != nil {
+			progressChan <- job
 		}
 
 		numJobsProcessed += 1
@@ -1,7 +1,12 @@
 package graphstore
 
 import (
+	"errors"
+	"fmt"
+	"path"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/cdclaxton/shortest-path-web-app/set"
 	"github.com/stretchr/testify/assert"
@@ -208,6 +213,96 @@ func TestBipartiteToUnipartite(t *testing.T) {
 	}
 }
 
+func TestBipartiteToUnipartiteDirected(t *testing.T) {
+
+	// A payment from e-1 to e-2 with an undirected link to e-3
+	payment, err := NewDocument("doc-1", "payment", map[string]string{})
+	assert.NoError(t, err)
+	payment.AddDirectedEntity("e-1", LinkSource)
+	payment.AddDirectedEntity("e-2", LinkDestination)
+	payment.AddEntity("e-3")
+
+	// A document without directed links
+	meeting, err := NewDocument("doc-2", "meeting", map[string]string{})
+	assert.NoError(t, err)
+	meeting.AddEntity("e-2")
+	meeting.AddEntity("e-4")
+
+	bi := NewInMemoryBipartiteGraphStore()
+	assert.NoError(t, bi.AddDocument(payment))
+	assert.NoError(t, bi.AddDocument(meeting))
+
+	// The direction is preserved
+	directed := NewInMemoryUnipartiteGraphStore()
+	assert.NoError(t, BipartiteToUnipartiteWithCheckpoints(bi, directed, set.NewSet[string](), 2, 2,
+		ConversionOptions{Directed: true}, nil))
+
+	checkConnections(t, directed, []connection{
+		{source: "e-1", destinations: []string{"e-2", "e-3"}},
+		{source: "e-2", destinations: []string{"e-3", "e-4"}},
+		{source: "e-3", destinations: []string{"e-1", "e-2"}},
+		{source: "e-4", destinations: []string{"e-2"}},
+	})
+
+	// The direction is ignored
+	undirected := NewInMemoryUnipartiteGraphStore()
+	assert.NoError(t, BipartiteToUnipartite(bi, undirected, set.NewSet[string](), 2, 2))
+
+	checkConnections(t, undirected, []connection{
+		{source: "e-1", destinations: []string{"e-2", "e-3"}},
+		{source: "e-2", destinations: []string{"e-1", "e-3", "e-4"}},
+	})
+}
+
+func TestBipartiteToUnipartiteEdgeMetadata(t *testing.T) {
+
+	doc1, err := NewDocument("doc-1", "meeting", map[string]string{"Date": "01/02/2022"})
+	assert.NoError(t, err)
+	doc1.AddEntity("e-1")
+	doc1.AddEntity("e-2")
+
+	doc2, err := NewDocument("doc-2", "meeting", map[string]string{"Date": "15/03/2022"})
+	assert.NoError(t, err)
+	doc2.AddEntity("e-1")
+	doc2.AddEntity("e-2")
+	doc2.AddEntity("e-3")
+
+	doc3, err := NewDocument("doc-3", "meeting", map[string]string{"Date": "Unknown"})
+	assert.NoError(t, err)
+	doc3.AddEntity("e-2")
+	doc3.AddEntity("e-3")
+
+	bi := NewInMemoryBipartiteGraphStore()
+	for _, doc := range []Document{doc1, doc2, doc3} {
+		assert.NoError(t, bi.AddDocument(doc))
+	}
+
+	uni := NewInMemoryUnipartiteGraphStore()
+	assert.NoError(t, BipartiteToUnipartiteWithCheckpoints(bi, uni, set.NewSet[string](), 2, 2,
+		ConversionOptions{DateAttribute: "Date", DateFormat: "02/01/2006"}, nil))
+
+	feb := time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC)
+	mar := time.Date(2022, 3, 15, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		src      string
+		dst      string
+		expected *EdgeMetadata
+	}{
+		{"e-1", "e-2", &EdgeMetadata{2, mar, []time.Time{feb, mar}}},
+		{"e-2", "e-1", &EdgeMetadata{2, mar, []time.Time{feb, mar}}},
+		{"e-2", "e-3", &EdgeMetadata{2, mar, []time.Time{mar}}},
+		{"e-1", "e-3", &EdgeMetadata{1, mar, []time.Time{mar}}},
+		{"e-1", "e-4", nil},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := uni.EdgeMetadata(testCase.src, testCase.dst)
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.expected, actual)
+	}
+}
+
 func BenchmarkBipartiteToUnipartite(b *testing.B) {
 
 	documents := []Document{
@@ -278,3 +373,188 @@ func BenchmarkBipartiteToUnipartite(b *testing.B) {
 		checkConnections(b, uni, expectedConnections)
 	}
 }
+
+// failingUnipartiteStore fails when an edge is added from a given entity.
+type failingUnipartiteStore struct {
+	UnipartiteGraphStore
+	failOn string
+}
+
+func (f *failingUnipartiteStore) AddUndirected(src string, dst string) error {
+	if src == f.failOn || dst == f.failOn {
+		return errors.New("simulated failure")
+	}
+
+	return f.UnipartiteGraphStore.AddUndirected(src, dst)
+}
+
+func TestBipartiteToUnipartiteResumeFromCheckpoint(t *testing.T) {
+
+	// Make a bipartite store with a chain of documents (in a store with a deterministic order)
+	bi := newBipartitePebbleStore(t)
+	defer cleanUpBipartitePebbleStore(t, bi)
+
+	for idx := 1; idx <= 20; idx++ {
+		assert.NoError(t, bi.AddDocument(Document{
+			Id:              fmt.Sprintf("doc-%02d", idx),
+			LinkedEntityIds: set.NewPopulatedSet(fmt.Sprintf("e-%d", idx), fmt.Sprintf("e-%d", idx+1)),
+		}))
+	}
+
+	// Expected unipartite graph
+	expected := NewInMemoryUnipartiteGraphStore()
+	assert.NoError(t, BipartiteToUnipartite(bi, expected, set.NewSet[string](), 2, 2))
+
+	checkpointConfig := CheckpointConfig{
+		Filepath: path.Join(t.TempDir(), "checkpoint.json"),
+		Interval: 2,
+	}
+
+	// Invalid checkpoint config
+	uni := NewInMemoryUnipartiteGraphStore()
+	assert.ErrorIs(t, BipartiteToUnipartiteWithCheckpoints(bi, uni, set.NewSet[string](), 1, 1, ConversionOptions{},
+		&CheckpointConfig{Filepath: checkpointConfig.Filepath}), ErrInvalidCheckpoint)
+
+	// The conversion fails part way through
+	failing := &failingUnipartiteStore{UnipartiteGraphStore: uni, failOn: "e-12"}
+	assert.Error(t, BipartiteToUnipartiteWithCheckpoints(bi, failing, set.NewSet[string](), 1, 1, ConversionOptions{},
+		&checkpointConfig))
+
+	checkpoint, err := ReadConversionCheckpoint(checkpointConfig.Filepath)
+	assert.NoError(t, err)
+	assert.NotNil(t, checkpoint)
+	assert.Equal(t, 20, checkpoint.TotalDocuments)
+	assert.Greater(t, checkpoint.DocumentsProcessed, 0)
+	assert.Less(t, checkpoint.DocumentsProcessed, 11)
+	assert.Equal(t, fmt.Sprintf("doc-%02d", checkpoint.DocumentsProcessed), checkpoint.LastDocumentId)
+
+	// Resume the conversion
+	assert.NoError(t, BipartiteToUnipartiteWithCheckpoints(bi, uni, set.NewSet[string](), 2, 2, ConversionOptions{},
+		&checkpointConfig))
+
+	equal, reason, err := UnipartiteGraphStoresEqual(expected, uni)
+	assert.NoError(t, err)
+	assert.True(t, equal, reason)
+
+	// The documents converted after the checkpoint aren't recorded twice in the edge metadata
+	for idx := 1; idx <= 20; idx++ {
+		e1, e2 := fmt.Sprintf("e-%d", idx), fmt.Sprintf("e-%d", idx+1)
+		for _, pair := range [][]string{{e1, e2}, {e2, e1}} {
+			metadata, err := uni.EdgeMetadata(pair[0], pair[1])
+			assert.NoError(t, err)
+			assert.Equal(t, &EdgeMetadata{NumberOfDocuments: 1}, metadata, pair)
+		}
+	}
+
+	// The checkpoint is deleted once the conversion is complete
+	checkpoint, err = ReadConversionCheckpoint(checkpointConfig.Filepath)
+	assert.NoError(t, err)
+	assert.Nil(t, checkpoint)
+}
+
+func TestBipartiteToUnipartiteCheckpointMismatch(t *testing.T) {
+	bi := newBipartitePebbleStore(t)
+	defer cleanUpBipartitePebbleStore(t, bi)
+
+	assert.NoError(t, bi.AddDocument(Document{
+		Id:              "doc-1",
+		LinkedEntityIds: set.NewPopulatedSet("e-1", "e-2"),
+	}))
+
+	checkpointConfig := CheckpointConfig{
+		Filepath: path.Join(t.TempDir(), "checkpoint.json"),
+		Interval: 1,
+	}
+
+	testCases := []ConversionCheckpoint{
+		{DocumentsProcessed: 1, LastDocumentId: "doc-1", TotalDocuments: 2},
+		{DocumentsProcessed: 1, LastDocumentId: "doc-2", TotalDocuments: 1},
+	}
+
+	for _, checkpoint := range testCases {
+		assert.NoError(t, writeConversionCheckpoint(checkpointConfig.Filepath, checkpoint))
+
+		err := BipartiteToUnipartiteWithCheckpoints(bi, NewInMemoryUnipartiteGraphStore(),
+			set.NewSet[string](), 1, 1, ConversionOptions{}, &checkpointConfig)
+		assert.ErrorIs(t, err, ErrCheckpointMismatch)
+	}
+}
+
+// countingUnipartiteStore counts the edges and edge metadata written to the store.
+type countingUnipartiteStore struct {
+	UnipartiteGraphStore
+	numEdgeWrites     int
+	numMetadataWrites int
+	mu                sync.Mutex
+}
+
+func (c *countingUnipartiteStore) AddEdgeDocument(src string, dst string, date time.Time) error {
+	c.mu.Lock()
+	c.numMetadataWrites += 1
+	c.mu.Unlock()
+
+	return c.UnipartiteGraphStore.AddEdgeDocument(src, dst, date)
+}
+
+func (c *countingUnipartiteStore) AddEdgeMetadata(src string, dst string, documents EdgeMetadata) error {
+	c.mu.Lock()
+	c.numMetadataWrites += 1
+	c.mu.Unlock()
+
+	return c.UnipartiteGraphStore.AddEdgeMetadata(src, dst, documents)
+}
+
+func (c *countingUnipartiteStore) AddUndirected(src string, dst string) error {
+	c.mu.Lock()
+	c.numEdgeWrites += 1
+	c.mu.Unlock()
+
+	return c.UnipartiteGraphStore.AddUndirected(src, dst)
+}
+
+func (c *countingUnipartiteStore) AddDirected(src string, dst string) error {
+	c.mu.Lock()
+	c.numEdgeWrites += 1
+	c.mu.Unlock()
+
+	return c.UnipartiteGraphStore.AddDirected(src, dst)
+}
+
+func TestBipartiteToUnipartiteDeduplication(t *testing.T) {
+
+	// Ten documents linking the same three entities
+	bi := NewInMemoryBipartiteGraphStore()
+	for idx := 0; idx < 10; idx++ {
+		doc, err := NewDocument(fmt.Sprintf("doc-%d", idx), "meeting", map[string]string{})
+		assert.NoError(t, err)
+		doc.AddEntity("e-1")
+		doc.AddEntity("e-2")
+		doc.AddEntity("e-3")
+		assert.NoError(t, bi.AddDocument(doc))
+	}
+
+	// Without deduplication, each edge and its metadata in each direction are written for each
+	// document
+	withoutDedup := &countingUnipartiteStore{UnipartiteGraphStore: NewInMemoryUnipartiteGraphStore()}
+	assert.NoError(t, BipartiteToUnipartiteWithCheckpoints(bi, withoutDedup, set.NewSet[string](), 3, 2,
+		ConversionOptions{DedupCapacity: -1}, nil))
+	assert.Equal(t, 60, withoutDedup.numEdgeWrites)
+	assert.Equal(t, 60, withoutDedup.numMetadataWrites)
+
+	// With deduplication, each edge is written once and its metadata once in each direction
+	withDedup := &countingUnipartiteStore{UnipartiteGraphStore: NewInMemoryUnipartiteGraphStore()}
+	assert.NoError(t, BipartiteToUnipartiteWithCheckpoints(bi, withDedup, set.NewSet[string](), 3, 2,
+		ConversionOptions{}, nil))
+	assert.Equal(t, 3, withDedup.numEdgeWrites)
+	assert.Equal(t, 6, withDedup.numMetadataWrites)
+
+	// The graphs are the same, including the edge metadata
+	equal, reason, err := UnipartiteGraphStoresEqual(withoutDedup.UnipartiteGraphStore,
+		withDedup.UnipartiteGraphStore)
+	assert.NoError(t, err)
+	assert.True(t, equal, reason)
+
+	metadata, err := withDedup.EdgeMetadata("e-1", "e-2")
+	assert.NoError(t, err)
+	assert.Equal(t, 10, metadata.NumberOfDocuments)
+}
@@ -0,0 +1,497 @@
+// This implementation of the bipartite graph store uses bbolt as the backend. The key design and
+// the serialisation of the entities and documents are the same as for the Pebble store:
+//
+//   e#<entity ID> = <serialised entity>
+//   d#<document ID> = <serialised document>
+//   edl#<entity ID>#<document ID> = nil
+//   del#<document ID>#<entity ID> = <direction>
+//   a#<alias ID> = <canonical entity ID>
+
+package graphstore
+
+import (
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cdclaxton/shortest-path-web-app/set"
+	bolt "go.etcd.io/bbolt"
+)
+
+// A BoltBipartiteGraphStore is a bipartite graph store backed by the bbolt key-value database.
+type BoltBipartiteGraphStore struct {
+	folder string
+	db     *bolt.DB
+}
+
+// NewBoltBipartiteGraphStore given the dedicated folder where the bbolt file is to be held.
+func NewBoltBipartiteGraphStore(folder string) (*BoltBipartiteGraphStore, error) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("folder", folder).
+		Msg("Opening bipartite bbolt store")
+
+	db, err := openBoltDb(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BoltBipartiteGraphStore{
+		folder: folder,
+		db:     db,
+	}, nil
+}
+
+// putLink stores the entity-document and document-entity link keys.
+func (b *BoltBipartiteGraphStore) putLink(entityId string, documentId string, direction string) error {
+
+	edlKey, err := entityDocumentLinkToPebbleKey(entityId, documentId)
+	if err != nil {
+		return err
+	}
+
+	delKey, err := documentEntityLinkToPebbleKey(documentId, entityId)
+	if err != nil {
+		return err
+	}
+
+	return boltPutAll(b.db, [][]byte{edlKey, delKey}, [][]byte{nil, directionToValue(direction)})
+}
+
+// AddEntity to the bbolt store.
+func (b *BoltBipartiteGraphStore) AddEntity(entity Entity) error {
+
+	key, err := entityIdToPebbleKey(entity.Id)
+	if err != nil {
+		return err
+	}
+
+	pebbleEntity := EntityToPebbleEntity(entity)
+	value, err := entityToPebbleValue(&pebbleEntity)
+	if err != nil {
+		return err
+	}
+
+	// Store the entity and the entity -> document links in a single transaction
+	keys := [][]byte{key}
+	values := [][]byte{value}
+
+	for _, docId := range entity.LinkedDocumentIds.ToSlice() {
+		linkKey, err := entityDocumentLinkToPebbleKey(entity.Id, docId)
+		if err != nil {
+			return err
+		}
+
+		keys = append(keys, linkKey)
+		values = append(values, nil)
+	}
+
+	return boltPutAll(b.db, keys, values)
+}
+
+// AddDocument to the bbolt store.
+func (b *BoltBipartiteGraphStore) AddDocument(document Document) error {
+
+	key, err := documentIdToPebbleKey(document.Id)
+	if err != nil {
+		return err
+	}
+
+	pebbleDocument := DocumentToPebbleDocument(document)
+	value, err := documentToPebbleValue(&pebbleDocument)
+	if err != nil {
+		return err
+	}
+
+	// Store the document and the document -> entity links in a single transaction
+	keys := [][]byte{key}
+	values := [][]byte{value}
+
+	for _, entityId := range document.LinkedEntityIds.ToSlice() {
+		linkKey, err := documentEntityLinkToPebbleKey(document.Id, entityId)
+		if err != nil {
+			return err
+		}
+
+		keys = append(keys, linkKey)
+		values = append(values, directionToValue(document.Directions[entityId]))
+	}
+
+	return boltPutAll(b.db, keys, values)
+}
+
+// AddLink between an entity and a document (by ID).
+func (b *BoltBipartiteGraphStore) AddLink(link Link) error {
+
+	if err := ValidateLinkDirection(link.Direction); err != nil {
+		return err
+	}
+
+	return b.putLink(link.EntityId, link.DocumentId, link.Direction)
+}
+
+// RemoveEntity and its links to documents from the bbolt store.
+func (b *BoltBipartiteGraphStore) RemoveEntity(entityId string) error {
+
+	entity, err := b.getEntity(entityId)
+	if err != nil {
+		return err
+	}
+
+	change, err := entityRemovalKeys(entityId, entity.LinkedDocumentIds)
+	if err != nil {
+		return err
+	}
+
+	return boltApply(b.db, change)
+}
+
+// RemoveDocument and its links to entities from the bbolt store.
+func (b *BoltBipartiteGraphStore) RemoveDocument(documentId string) error {
+
+	document, err := b.GetDocument(documentId)
+	if err != nil {
+		return err
+	}
+
+	change, err := documentRemovalKeys(documentId, document.LinkedEntityIds)
+	if err != nil {
+		return err
+	}
+
+	return boltApply(b.db, change)
+}
+
+// RemoveLink between an entity and a document (by ID) from the bbolt store. The direction of the
+// link is ignored.
+func (b *BoltBipartiteGraphStore) RemoveLink(link Link) error {
+
+	change, err := linkRemovalKeys(link)
+	if err != nil {
+		return err
+	}
+
+	_, found, err := boltGet(b.db, change.deletes[0])
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		return ErrLinkNotFound
+	}
+
+	return boltApply(b.db, change)
+}
+
+// Clear the store.
+func (b *BoltBipartiteGraphStore) Clear() error {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Msg("Clearing the bbolt bipartite graph store")
+
+	return boltClear(b.db)
+}
+
+// Close the bbolt store.
+func (b *BoltBipartiteGraphStore) Close() error {
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Msg("Closing the bbolt bipartite graph store")
+
+	return b.db.Close()
+}
+
+// Destroy the bipartite bbolt store after closing the database.
+func (b *BoltBipartiteGraphStore) Destroy() error {
+	return boltDestroy(b.db, b.folder)
+}
+
+// Equal returns true if two stores have the same contents.
+func (b *BoltBipartiteGraphStore) Equal(other BipartiteGraphStore) (bool, error) {
+	return bipartiteGraphStoresEqual(b, other)
+}
+
+// Finalise the store by syncing the database to disk.
+func (b *BoltBipartiteGraphStore) Finalise() error {
+	return b.db.Sync()
+}
+
+// getDocumentsForEntity returns the IDs of the documents linked to the entity.
+func (b *BoltBipartiteGraphStore) getDocumentsForEntity(entityId string) (*set.Set[string], error) {
+
+	documentIds := set.NewSet[string]()
+	prefix := []byte(entityDocumentLinkPrefix + separator + entityId + separator)
+
+	err := boltScanPrefix(b.db, prefix, func(key []byte) error {
+		retrievedEntityId, documentId, err := pebbleKeyToEntityDocumentLink(key)
+		if err != nil {
+			return err
+		}
+
+		if retrievedEntityId != entityId {
+			return ErrMalformedKey
+		}
+
+		documentIds.Add(documentId)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return documentIds, nil
+}
+
+// getEntitiesForDocument returns the IDs of the entities linked to the document and the
+// directions of the directed links.
+func (b *BoltBipartiteGraphStore) getEntitiesForDocument(docId string) (*set.Set[string],
+	map[string]string, error) {
+
+	entityIds := set.NewSet[string]()
+	directions := map[string]string{}
+	prefix := []byte(documentEntityLinkPrefix + separator + docId + separator)
+
+	err := boltScanPrefixWithValues(b.db, prefix, func(key []byte, value []byte) error {
+		retrievedDocId, entityId, err := pebbleKeyToDocumentEntityLink(key)
+		if err != nil {
+			return err
+		}
+
+		if retrievedDocId != docId {
+			return ErrMalformedKey
+		}
+
+		entityIds.Add(entityId)
+		if len(value) > 0 {
+			directions[entityId] = string(value)
+		}
+		return nil
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return entityIds, directions, nil
+}
+
+// AddAlias of an entity to the bbolt store.
+func (b *BoltBipartiteGraphStore) AddAlias(aliasId string, entityId string) error {
+
+	if err := validateAlias(aliasId, entityId); err != nil {
+		return err
+	}
+
+	key, err := aliasToPebbleKey(aliasId)
+	if err != nil {
+		return err
+	}
+
+	return boltPut(b.db, key, []byte(entityId))
+}
+
+// lookupAlias returns the canonical entity ID of the alias ID and true if the alias exists.
+func (b *BoltBipartiteGraphStore) lookupAlias(aliasId string) (string, bool, error) {
+
+	key, err := aliasToPebbleKey(aliasId)
+	if err != nil {
+		return "", false, err
+	}
+
+	value, found, err := boltGet(b.db, key)
+	return string(value), found, err
+}
+
+// ResolveAlias returns the canonical ID of the entity ID (unchanged if the ID isn't an alias).
+func (b *BoltBipartiteGraphStore) ResolveAlias(entityId string) (string, error) {
+	return resolveAlias(entityId, b.HasEntityWithId, b.lookupAlias)
+}
+
+// GetEntity given its ID or one of its aliases from the bbolt store.
+func (b *BoltBipartiteGraphStore) GetEntity(entityId string) (*Entity, error) {
+	return getEntityOrAlias(entityId, b.getEntity, b.ResolveAlias)
+}
+
+// getEntity given its ID (ignoring aliases) from the bbolt store.
+func (b *BoltBipartiteGraphStore) getEntity(entityId string) (*Entity, error) {
+
+	key, err := entityIdToPebbleKey(entityId)
+	if err != nil {
+		return nil, err
+	}
+
+	value, found, err := boltGet(b.db, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return nil, ErrEntityNotFound
+	}
+
+	entity, err := pebbleValueToEntity(value)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the documents for the entity
+	docs, err := b.getDocumentsForEntity(entityId)
+	if err != nil {
+		return nil, err
+	}
+
+	ent := PebbleEntityToEntity(*entity, docs)
+	return &ent, nil
+}
+
+// GetDocument from the bbolt store given its ID.
+func (b *BoltBipartiteGraphStore) GetDocument(documentId string) (*Document, error) {
+
+	key, err := documentIdToPebbleKey(documentId)
+	if err != nil {
+		return nil, err
+	}
+
+	value, found, err := boltGet(b.db, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return nil, ErrDocumentNotFound
+	}
+
+	document, err := pebbleValueToDocument(value)
+	if err != nil {
+		return nil, err
+	}
+
+	// Get the entities for the document
+	entities, directions, err := b.getEntitiesForDocument(documentId)
+	if err != nil {
+		return nil, err
+	}
+
+	doc := PebbleDocumentToDocument(*document, entities, directions)
+	return &doc, nil
+}
+
+// HasDocument returns true if the store contains the document.
+func (b *BoltBipartiteGraphStore) HasDocument(document *Document) (bool, error) {
+
+	doc, err := b.GetDocument(document.Id)
+	if err == ErrDocumentNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return doc.Equal(document), nil
+}
+
+// HasEntity returns true if the entity exists in the bbolt store.
+func (b *BoltBipartiteGraphStore) HasEntity(entity *Entity) (bool, error) {
+
+	ent, err := b.getEntity(entity.Id)
+	if err == ErrEntityNotFound {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return ent.Equal(entity), nil
+}
+
+// HasEntityWithId returns true if the entity with the ID exists in the bbolt store.
+func (b *BoltBipartiteGraphStore) HasEntityWithId(entityId string) (bool, error) {
+
+	key, err := entityIdToPebbleKey(entityId)
+	if err != nil {
+		return false, err
+	}
+
+	_, found, err := boltGet(b.db, key)
+	return found, err
+}
+
+// BoltDocumentIterator is an iterator for walking through all Documents in the bbolt store.
+type BoltDocumentIterator struct {
+	iter *boltKeyIterator
+}
+
+// nextDocumentId gets the next Document ID from the iterator.
+func (it *BoltDocumentIterator) nextDocumentId() (string, error) {
+	key, err := it.iter.next()
+	if err != nil {
+		return "", err
+	}
+
+	return pebbleKeyToDocumentId(key)
+}
+
+// hasNext returns true if there is another Document ID available.
+func (it *BoltDocumentIterator) hasNext() bool {
+	return it.iter.hasNext()
+}
+
+// NewDocumentIdIterator returns a document ID iterator.
+func (b *BoltBipartiteGraphStore) NewDocumentIdIterator() (DocumentIdIterator, error) {
+	iter, err := newBoltKeyIterator(b.db, []byte(documentPrefix+separator))
+	if err != nil {
+		return nil, err
+	}
+
+	return &BoltDocumentIterator{iter: iter}, nil
+}
+
+// BoltEntityIterator is an iterator for walking through all Entities in the bbolt store.
+type BoltEntityIterator struct {
+	iter *boltKeyIterator
+}
+
+// nextEntityId gets the next Entity ID from the iterator.
+func (it *BoltEntityIterator) nextEntityId() (string, error) {
+	key, err := it.iter.next()
+	if err != nil {
+		return "", err
+	}
+
+	return pebbleKeyToEntityId(key)
+}
+
+// hasNext returns true if there is another Entity ID available.
+func (it *BoltEntityIterator) hasNext() bool {
+	return it.iter.hasNext()
+}
+
+// NewEntityIdIterator returns an entity ID iterator.
+func (b *BoltBipartiteGraphStore) NewEntityIdIterator() (EntityIdIterator, error) {
+	iter, err := newBoltKeyIterator(b.db, []byte(entityPrefix+separator))
+	if err != nil {
+		return nil, err
+	}
+
+	return &BoltEntityIterator{iter: iter}, nil
+}
+
+// countKeys with the given prefix.
+func (b *BoltBipartiteGraphStore) countKeys(prefix string) (int, error) {
+	count := 0
+
+	err := boltScanPrefix(b.db, []byte(prefix), func(key []byte) error {
+		count += 1
+		return nil
+	})
+
+	return count, err
+}
+
+// NumberOfEntities in the bipartite bbolt store.
+func (b *BoltBipartiteGraphStore) NumberOfEntities() (int, error) {
+	return b.countKeys(entityPrefix + separator)
+}
+
+// NumberOfDocuments in the bipartite bbolt store.
+func (b *BoltBipartiteGraphStore) NumberOfDocuments() (int, error) {
+	return b.countKeys(documentPrefix + separator)
+}
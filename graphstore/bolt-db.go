@@ -0,0 +1,296 @@
+// Helper functions shared by the bbolt-backed graph stores.
+//
+// bbolt stores all of its data in a single file and supports a single writer with many concurrent
+// readers. The graph stores use the same key design as the Pebble stores, holding all of the keys
+// in a single bucket so that prefix scans can be used.
+
+package graphstore
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path"
+
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+	bolt "go.etcd.io/bbolt"
+)
+
+// Name of the bbolt database file within the store's folder
+const boltFilename = "graph.db"
+
+// Name of the bucket holding the keys
+var boltBucket = []byte("graph")
+
+// Number of keys to read in one transaction when iterating
+const boltIteratorBatchSize = 1000
+
+// openBoltDb opens (or creates) the bbolt database in the folder.
+func openBoltDb(folder string) (*bolt.DB, error) {
+
+	if len(folder) == 0 {
+		return nil, errors.New("folder name is empty")
+	}
+
+	if err := os.MkdirAll(folder, 0755); err != nil {
+		return nil, err
+	}
+
+	// Syncing is disabled for the same reason the Pebble stores disable the WAL; the data can
+	// always be rebuilt from the source files
+	db, err := bolt.Open(path.Join(folder, boltFilename), 0600, &bolt.Options{
+		NoSync:         true,
+		NoFreelistSync: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Make sure the bucket exists
+	err = db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(boltBucket)
+		return err
+	})
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return db, nil
+}
+
+// boltPut stores the key-value pair.
+func boltPut(db *bolt.DB, key []byte, value []byte) error {
+	return boltPutAll(db, [][]byte{key}, [][]byte{value})
+}
+
+// boltPutAll stores the key-value pairs in a single transaction.
+func boltPutAll(db *bolt.DB, keys [][]byte, values [][]byte) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+		for idx := range keys {
+			value := values[idx]
+			if value == nil {
+				value = []byte{}
+			}
+
+			if err := bucket.Put(keys[idx], value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// boltApply the change in a single transaction.
+func boltApply(db *bolt.DB, change *keyChange) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+		for _, key := range change.deletes {
+			if err := bucket.Delete(key); err != nil {
+				return err
+			}
+		}
+
+		for _, key := range change.puts {
+			if err := bucket.Put(key, []byte{}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// boltUpdate replaces the value for the key with the value returned by fn in a single transaction.
+// The value passed to fn is nil if the key isn't found.
+func boltUpdate(db *bolt.DB, key []byte, fn func(value []byte) ([]byte, error)) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		bucket := tx.Bucket(boltBucket)
+
+		value, err := fn(bucket.Get(key))
+		if err != nil {
+			return err
+		}
+
+		return bucket.Put(key, value)
+	})
+}
+
+// boltGet returns a copy of the value for the key and whether it was found.
+func boltGet(db *bolt.DB, key []byte) ([]byte, bool, error) {
+
+	var value []byte
+	var found bool
+
+	err := db.View(func(tx *bolt.Tx) error {
+		v := tx.Bucket(boltBucket).Get(key)
+		if v != nil {
+			found = true
+			value = append([]byte{}, v...)
+		}
+		return nil
+	})
+
+	return value, found, err
+}
+
+// boltHasPrefix returns true if there is at least one key with the prefix.
+func boltHasPrefix(db *bolt.DB, prefix []byte) (bool, error) {
+
+	var found bool
+
+	err := db.View(func(tx *bolt.Tx) error {
+		k, _ := tx.Bucket(boltBucket).Cursor().Seek(prefix)
+		found = k != nil && bytes.HasPrefix(k, prefix)
+		return nil
+	})
+
+	return found, err
+}
+
+// boltScanPrefix calls fn for each key with the prefix. The key is only valid during the call.
+func boltScanPrefix(db *bolt.DB, prefix []byte, fn func(key []byte) error) error {
+	return db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(boltBucket).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			if err := fn(k); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// boltScanPrefixWithValues calls fn for each key with the prefix and its value. The key and value
+// are only valid during the call.
+func boltScanPrefixWithValues(db *bolt.DB, prefix []byte, fn func(key []byte, value []byte) error) error {
+	return db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(boltBucket).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			if err := fn(k, v); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// boltKeysAfter returns up to limit keys with the prefix that sort after the key after. If after
+// is nil, the keys are read from the start of the prefix.
+func boltKeysAfter(db *bolt.DB, prefix []byte, after []byte, limit int) ([][]byte, error) {
+
+	keys := [][]byte{}
+
+	err := db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(boltBucket).Cursor()
+
+		var k []byte
+		if after == nil {
+			k, _ = c.Seek(prefix)
+		} else {
+			k, _ = c.Seek(after)
+			if k != nil && bytes.Equal(k, after) {
+				k, _ = c.Next()
+			}
+		}
+
+		for ; k != nil && bytes.HasPrefix(k, prefix) && len(keys) < limit; k, _ = c.Next() {
+			keys = append(keys, append([]byte{}, k...))
+		}
+
+		return nil
+	})
+
+	return keys, err
+}
+
+// boltClear deletes all keys by recreating the bucket.
+func boltClear(db *bolt.DB) error {
+	return db.Update(func(tx *bolt.Tx) error {
+		if err := tx.DeleteBucket(boltBucket); err != nil && err != bolt.ErrBucketNotFound {
+			return err
+		}
+		_, err := tx.CreateBucket(boltBucket)
+		return err
+	})
+}
+
+// boltDestroy closes the database and deletes the folder.
+func boltDestroy(db *bolt.DB, folder string) error {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("folder", folder).
+		Msg("Destroying bbolt store")
+
+	if err := db.Close(); err != nil {
+		return err
+	}
+
+	return os.RemoveAll(folder)
+}
+
+// A boltKeyIterator walks through the keys with a given prefix in batches, so that a read
+// transaction isn't held open whilst the caller processes each key.
+type boltKeyIterator struct {
+	db      *bolt.DB
+	prefix  []byte
+	batch   [][]byte // Current batch of keys
+	index   int      // Index of the next key in the batch
+	lastKey []byte   // Last key read from the database
+	done    bool     // Have all keys been read from the database?
+}
+
+// newBoltKeyIterator for keys with the given prefix.
+func newBoltKeyIterator(db *bolt.DB, prefix []byte) (*boltKeyIterator, error) {
+	it := &boltKeyIterator{
+		db:     db,
+		prefix: prefix,
+	}
+
+	return it, it.fill()
+}
+
+// fill the batch of keys from the database if required.
+func (it *boltKeyIterator) fill() error {
+
+	if it.index < len(it.batch) || it.done {
+		return nil
+	}
+
+	keys, err := boltKeysAfter(it.db, it.prefix, it.lastKey, boltIteratorBatchSize)
+	if err != nil {
+		return err
+	}
+
+	it.batch = keys
+	it.index = 0
+
+	if len(keys) < boltIteratorBatchSize {
+		it.done = true
+	}
+
+	if len(keys) > 0 {
+		it.lastKey = keys[len(keys)-1]
+	}
+
+	return nil
+}
+
+// hasNext returns true if there is another key.
+func (it *boltKeyIterator) hasNext() bool {
+	return it.index < len(it.batch)
+}
+
+// next key from the iterator.
+func (it *boltKeyIterator) next() ([]byte, error) {
+
+	if !it.hasNext() {
+		return nil, errors.New("iterator is exhausted")
+	}
+
+	key := it.batch[it.index]
+	it.index += 1
+
+	return key, it.fill()
+}
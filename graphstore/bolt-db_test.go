@@ -0,0 +1,68 @@
+package graphstore
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/set"
+	"github.com/stretchr/testify/assert"
+)
+
+func newUnipartiteBoltStore(t testing.TB) *BoltUnipartiteGraphStore {
+	folder := createTempPebbleFolder(t)
+	store, err := NewBoltUnipartiteGraphStore(folder)
+	assert.NoError(t, err)
+	return store
+}
+
+func cleanUpUnipartiteBoltStore(t testing.TB, store *BoltUnipartiteGraphStore) {
+	assert.NoError(t, store.Destroy())
+}
+
+func newBipartiteBoltStore(t testing.TB) *BoltBipartiteGraphStore {
+	folder := createTempPebbleFolder(t)
+	store, err := NewBoltBipartiteGraphStore(folder)
+	assert.NoError(t, err)
+	return store
+}
+
+func cleanUpBipartiteBoltStore(t testing.TB, store *BoltBipartiteGraphStore) {
+	assert.NoError(t, store.Destroy())
+}
+
+func TestBoltKeyIterator(t *testing.T) {
+	store := newUnipartiteBoltStore(t)
+	defer cleanUpUnipartiteBoltStore(t, store)
+
+	// Empty store
+	iter, err := newBoltKeyIterator(store.db, []byte(nodePrefix+separator))
+	assert.NoError(t, err)
+	assert.False(t, iter.hasNext())
+
+	_, err = iter.next()
+	assert.Error(t, err)
+
+	// Add more entities than fit in a single batch, plus an edge that shouldn't be returned
+	expected := set.NewSet[string]()
+	for idx := 0; idx < boltIteratorBatchSize*2+1; idx++ {
+		id := "e-" + strconv.Itoa(idx)
+		assert.NoError(t, store.AddEntity(id))
+		expected.Add(id)
+	}
+	assert.NoError(t, store.AddDirected("e-0", "e-1"))
+
+	iter, err = newBoltKeyIterator(store.db, []byte(nodePrefix+separator))
+	assert.NoError(t, err)
+
+	actual := set.NewSet[string]()
+	for iter.hasNext() {
+		key, err := iter.next()
+		assert.NoError(t, err)
+
+		node, err := pebbleKeyToNode(key)
+		assert.NoError(t, err)
+		actual.Add(node)
+	}
+
+	assert.True(t, expected.Equal(actual))
+}
@@ -0,0 +1,488 @@
+// A bbolt unipartite graph store holds a graph of a single type of node in a bbolt database. It
+// uses the same key design as the Pebble unipartite graph store:
+//
+// e#<src entity ID>#<dst entity ID>
+// n#<entity ID>
+// r#<dst entity ID>#<src entity ID>
+// m#<src entity ID>#<dst entity ID>
+//
+// bbolt provides faster reads than Pebble at the expense of slower writes, which makes it suitable
+// for deployments where the graph is built infrequently.
+
+package graphstore
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cdclaxton/shortest-path-web-app/set"
+	bolt "go.etcd.io/bbolt"
+)
+
+// A BoltUnipartiteGraphStore is a bbolt-backed unipartite graph store.
+type BoltUnipartiteGraphStore struct {
+	folder string   // Folder for the bbolt file
+	db     *bolt.DB // bbolt database
+}
+
+// NewBoltUnipartiteGraphStore given the folder in which to store the bbolt file.
+func NewBoltUnipartiteGraphStore(folder string) (*BoltUnipartiteGraphStore, error) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("folder", folder).
+		Msg("Opening unipartite bbolt store")
+
+	db, err := openBoltDb(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	return &BoltUnipartiteGraphStore{
+		folder: folder,
+		db:     db,
+	}, nil
+}
+
+// AddEntity to the unipartite graph store.
+func (b *BoltUnipartiteGraphStore) AddEntity(id string) error {
+
+	key, err := nodeToPebbleKey(id)
+	if err != nil {
+		return err
+	}
+
+	return boltPut(b.db, key, nil)
+}
+
+// AddDirected edge between the source (src) and destination (dst) vertices. The destination is
+// added as an entity, so that it is held in the graph even if it has no outgoing edges.
+func (b *BoltUnipartiteGraphStore) AddDirected(src string, dst string) error {
+
+	key, err := edgeToPebbleKey(src, dst)
+	if err != nil {
+		return err
+	}
+
+	reverseKey, err := reverseEdgeToPebbleKey(src, dst)
+	if err != nil {
+		return err
+	}
+
+	nodeKey, err := nodeToPebbleKey(dst)
+	if err != nil {
+		return err
+	}
+
+	return boltPutAll(b.db, [][]byte{key, reverseKey, nodeKey}, [][]byte{nil, nil, nil})
+}
+
+// AddUndirected edge between two entities.
+func (b *BoltUnipartiteGraphStore) AddUndirected(src string, dst string) error {
+
+	key, err := edgeToPebbleKey(src, dst)
+	if err != nil {
+		return err
+	}
+
+	oppositeKey, err := edgeToPebbleKey(dst, src)
+	if err != nil {
+		return err
+	}
+
+	// Add the src --> dst and src <-- dst connections
+	return boltPutAll(b.db, [][]byte{key, oppositeKey}, [][]byte{nil, nil})
+}
+
+// AddEdgeDocument records a document linking the source and destination entities in the metadata
+// of the edge. The date of the document is zero if it isn't known.
+func (b *BoltUnipartiteGraphStore) AddEdgeDocument(src string, dst string, date time.Time) error {
+	document := EdgeMetadata{}
+	document.addDocument(date)
+	return b.AddEdgeMetadata(src, dst, document)
+}
+
+// AddEdgeMetadata records the documents of the metadata (e.g. a batch of documents) linking the
+// source and destination entities in the metadata of the edge.
+func (b *BoltUnipartiteGraphStore) AddEdgeMetadata(src string, dst string, documents EdgeMetadata) error {
+
+	key, err := edgeMetadataToPebbleKey(src, dst)
+	if err != nil {
+		return err
+	}
+
+	// The metadata is read and written in a single transaction
+	return boltUpdate(b.db, key, func(value []byte) ([]byte, error) {
+		metadata := EdgeMetadata{}
+		if value != nil {
+			if metadata, err = decodeEdgeMetadata(value); err != nil {
+				return nil, err
+			}
+		}
+
+		metadata.merge(documents)
+		return encodeEdgeMetadata(metadata), nil
+	})
+}
+
+// SetEdgeMetadata replaces the metadata of the edge from the source to the destination entity. Nil
+// metadata removes it.
+func (b *BoltUnipartiteGraphStore) SetEdgeMetadata(src string, dst string, metadata *EdgeMetadata) error {
+
+	key, err := edgeMetadataToPebbleKey(src, dst)
+	if err != nil {
+		return err
+	}
+
+	if metadata == nil {
+		return boltApply(b.db, &keyChange{deletes: [][]byte{key}})
+	}
+
+	return boltPut(b.db, key, encodeEdgeMetadata(*metadata))
+}
+
+// RemoveEntity and its edges in either direction from the unipartite graph store. The entities it
+// was connected to are kept.
+func (b *BoltUnipartiteGraphStore) RemoveEntity(id string) error {
+
+	found, err := b.HasEntity(id)
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		return entityNotFound(id)
+	}
+
+	connectedIds, err := b.EntityIdsConnectedTo(id)
+	if err != nil {
+		return err
+	}
+
+	change, err := unipartiteEntityRemovalKeys(id, connectedIds)
+	if err != nil {
+		return err
+	}
+
+	return boltApply(b.db, change)
+}
+
+// RemoveEdge between two entities in both directions, including the metadata of the edges. The
+// entities are kept.
+func (b *BoltUnipartiteGraphStore) RemoveEdge(src string, dst string) error {
+
+	forward, err := b.EdgeExists(src, dst)
+	if err != nil {
+		return err
+	}
+
+	backward, err := b.EdgeExists(dst, src)
+	if err != nil {
+		return err
+	}
+
+	if !forward && !backward {
+		return ErrEdgeNotFound
+	}
+
+	change, err := edgeRemovalKeys(src, dst)
+	if err != nil {
+		return err
+	}
+
+	return boltApply(b.db, change)
+}
+
+// Clear down the graph.
+func (b *BoltUnipartiteGraphStore) Clear() error {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Msg("Clearing the bbolt unipartite store")
+
+	return boltClear(b.db)
+}
+
+// Close the bbolt store.
+func (b *BoltUnipartiteGraphStore) Close() error {
+	return b.db.Close()
+}
+
+// Destroy the unipartite bbolt store after closing the database.
+func (b *BoltUnipartiteGraphStore) Destroy() error {
+	return boltDestroy(b.db, b.folder)
+}
+
+// EdgeExists returns true if the two entities are connected.
+func (b *BoltUnipartiteGraphStore) EdgeExists(src string, dst string) (bool, error) {
+
+	key, err := edgeToPebbleKey(src, dst)
+	if err != nil {
+		return false, err
+	}
+
+	_, found, err := boltGet(b.db, key)
+	return found, err
+}
+
+// EdgeMetadata of the edge from the source to the destination entity. If no documents have been
+// recorded for the edge, nil is returned.
+func (b *BoltUnipartiteGraphStore) EdgeMetadata(src string, dst string) (*EdgeMetadata, error) {
+
+	key, err := edgeMetadataToPebbleKey(src, dst)
+	if err != nil {
+		return nil, err
+	}
+
+	value, found, err := boltGet(b.db, key)
+	if err != nil || !found {
+		return nil, err
+	}
+
+	metadata, err := decodeEdgeMetadata(value)
+	if err != nil {
+		return nil, err
+	}
+
+	return &metadata, nil
+}
+
+// EntityIds of the vertices in the graph.
+func (b *BoltUnipartiteGraphStore) EntityIds() (*set.Set[string], error) {
+
+	entityIds := set.NewSet[string]()
+
+	// Entities without edges
+	err := boltScanPrefix(b.db, []byte(nodePrefix+separator), func(key []byte) error {
+		node, err := pebbleKeyToNode(key)
+		if err == nil {
+			entityIds.Add(node)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Entities at the source of an edge
+	err = boltScanPrefix(b.db, []byte(edgePrefix+separator), func(key []byte) error {
+		src, _, err := pebbleKeyToEdge(key)
+		if err == nil {
+			entityIds.Add(src)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return entityIds, nil
+}
+
+// EntityIdsAdjacentTo a given entity.
+func (b *BoltUnipartiteGraphStore) EntityIdsAdjacentTo(id string) (*set.Set[string], error) {
+
+	found, err := b.HasEntity(id)
+	if err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return nil, fmt.Errorf("%w: %s", ErrEntityNotFound, id)
+	}
+
+	adjacentIds := set.NewSet[string]()
+
+	err = boltScanPrefix(b.db, []byte(edgePrefix+separator+id+separator), func(key []byte) error {
+		src, dst, err := pebbleKeyToEdge(key)
+		if err != nil {
+			return err
+		}
+
+		if src != id {
+			return ErrUnexpectedEntityInKey
+		}
+
+		adjacentIds.Add(dst)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return adjacentIds, nil
+}
+
+// EntityIdsConnectedTo a given entity by an edge in either direction.
+func (b *BoltUnipartiteGraphStore) EntityIdsConnectedTo(id string) (*set.Set[string], error) {
+
+	connectedIds, err := b.EntityIdsAdjacentTo(id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = boltScanPrefix(b.db, []byte(reversePrefix+separator+id+separator), func(key []byte) error {
+		src, dst, err := pebbleKeyToReverseEdge(key)
+		if err != nil {
+			return err
+		}
+
+		if dst != id {
+			return ErrUnexpectedEntityInKey
+		}
+
+		connectedIds.Add(src)
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return connectedIds, nil
+}
+
+// Finalise the store by syncing the database to disk.
+func (b *BoltUnipartiteGraphStore) Finalise() error {
+	return b.db.Sync()
+}
+
+// HasEntity returns true if the entity ID is held within the store.
+func (b *BoltUnipartiteGraphStore) HasEntity(id string) (bool, error) {
+
+	key, err := nodeToPebbleKey(id)
+	if err != nil {
+		return false, err
+	}
+
+	// Check whether the entity exists on its own
+	_, found, err := boltGet(b.db, key)
+	if err != nil {
+		return false, err
+	}
+
+	if found {
+		return true, nil
+	}
+
+	// Check whether the entity exists as the source of an edge
+	return boltHasPrefix(b.db, []byte(edgePrefix+separator+id+separator))
+}
+
+// A BoltEdgeIterator walks through the edge keys of the bbolt store and then through the node keys
+// of the entities without outgoing edges.
+type BoltEdgeIterator struct {
+	db    *bolt.DB
+	iter  *boltKeyIterator // Iterator of the keys (nil once exhausted)
+	edges bool             // Is the iterator walking through the edge keys?
+	next  *Edge            // Next edge (nil if there isn't one)
+	err   error            // Error finding the next edge
+}
+
+// advance to the next edge or the next entity without edges.
+func (it *BoltEdgeIterator) advance() {
+
+	it.next = nil
+
+	for it.iter != nil && it.next == nil && it.err == nil {
+
+		if !it.iter.hasNext() {
+			it.iter = nil
+
+			// Walk through the entities once the edges are exhausted
+			if it.edges {
+				it.edges = false
+				it.iter, it.err = newBoltKeyIterator(it.db, []byte(nodePrefix+separator))
+			}
+			continue
+		}
+
+		var key []byte
+		key, it.err = it.iter.next()
+		if it.err != nil {
+			break
+		}
+
+		if it.edges {
+			var src, dst string
+			src, dst, it.err = pebbleKeyToEdge(key)
+			if it.err == nil {
+				it.next = &Edge{V1: src, V2: dst}
+			}
+		} else {
+			var id string
+			var hasEdges bool
+			id, it.err = pebbleKeyToNode(key)
+			if it.err == nil {
+				hasEdges, it.err = boltHasPrefix(it.db, []byte(edgePrefix+separator+id+separator))
+			}
+			if it.err == nil && !hasEdges {
+				it.next = &Edge{V1: id}
+			}
+		}
+	}
+}
+
+// HasNext returns true if the iterator has another edge (or an error to return).
+func (it *BoltEdgeIterator) HasNext() bool {
+	return it.next != nil || it.err != nil
+}
+
+// NextEdge from the iterator.
+func (it *BoltEdgeIterator) NextEdge() (Edge, error) {
+
+	if it.err != nil {
+		err := it.err
+		it.err = nil
+		it.Close()
+		return Edge{}, err
+	}
+
+	if it.next == nil {
+		return Edge{}, errors.New("iterator is exhausted")
+	}
+
+	edge := *it.next
+	it.advance()
+
+	return edge, nil
+}
+
+// Close the iterator. The keys are read in batches, so there isn't a transaction to close.
+func (it *BoltEdgeIterator) Close() error {
+	it.next = nil
+	it.iter = nil
+	return nil
+}
+
+// NewEdgeIterator returns an iterator of the edges in the bbolt store.
+func (b *BoltUnipartiteGraphStore) NewEdgeIterator() (EdgeIterator, error) {
+
+	iter, err := newBoltKeyIterator(b.db, []byte(edgePrefix+separator))
+	if err != nil {
+		return nil, err
+	}
+
+	it := &BoltEdgeIterator{
+		db:    b.db,
+		iter:  iter,
+		edges: true,
+	}
+	it.advance()
+
+	return it, nil
+}
+
+// NumberEntities in the unipartite graph.
+func (b *BoltUnipartiteGraphStore) NumberEntities() (int, error) {
+
+	entityIds, err := b.EntityIds()
+	if err != nil {
+		return 0, err
+	}
+
+	return entityIds.Len(), nil
+}
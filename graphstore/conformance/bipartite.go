@@ -0,0 +1,403 @@
+package conformance
+
+import (
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/set"
+	"github.com/stretchr/testify/assert"
+)
+
+// buildEntities (that are unconnected to documents) for use in tests.
+func buildEntities(t *testing.T) []graphstore.Entity {
+	e1, err := graphstore.NewEntity("e-1", "person", map[string]string{
+		"forename": "Bob", "surname": "Smith"})
+	assert.NoError(t, err)
+
+	e2, err := graphstore.NewEntity("e-2", "person", map[string]string{
+		"forename": "Sarah", "surname": "Thorp"})
+	assert.NoError(t, err)
+
+	return []graphstore.Entity{e1, e2}
+}
+
+func buildDocuments(t *testing.T) []graphstore.Document {
+	d1, err := graphstore.NewDocument("doc-1", "info", map[string]string{"date": "2022-07-22"})
+	assert.NoError(t, err)
+
+	d2, err := graphstore.NewDocument("doc-2", "info", map[string]string{"date": "2022-07-10"})
+	assert.NoError(t, err)
+
+	return []graphstore.Document{d1, d2}
+}
+
+func checkAddEntity(t *testing.T, store graphstore.BipartiteGraphStore) {
+	entities := buildEntities(t)
+
+	// No entities or documents
+	nEntities, err := store.NumberOfEntities()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, nEntities)
+
+	exists, err := store.HasEntityWithId("e-1")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	exists, err = store.HasEntityWithId("e-2")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	nDocuments, err := store.NumberOfDocuments()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, nDocuments)
+
+	// Add an entity
+	assert.NoError(t, store.AddEntity(entities[0]))
+
+	nEntities, err = store.NumberOfEntities()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, nEntities)
+
+	nDocuments, err = store.NumberOfDocuments()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, nDocuments)
+
+	exists, err = store.HasEntityWithId("e-1")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = store.HasEntityWithId("e-2")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	// Try to get the entity from the store that should exist
+	retrieved, err := store.GetEntity(entities[0].Id)
+	assert.NoError(t, err)
+	assert.True(t, entities[0].Equal(retrieved))
+
+	// Try to get an entity that shouldn't exist
+	retrieved, err = store.GetEntity("unknown")
+	assert.Error(t, err)
+	assert.Nil(t, retrieved)
+
+	// Add another entity
+	assert.NoError(t, store.AddEntity(entities[1]))
+
+	exists, err = store.HasEntityWithId("e-1")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	exists, err = store.HasEntityWithId("e-2")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+}
+
+func checkAddDocument(t *testing.T, store graphstore.BipartiteGraphStore) {
+	documents := buildDocuments(t)
+
+	nEntities, err := store.NumberOfEntities()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, nEntities)
+
+	nDocuments, err := store.NumberOfDocuments()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, nDocuments)
+
+	assert.NoError(t, store.AddDocument(documents[0]))
+
+	nEntities, err = store.NumberOfEntities()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, nEntities)
+
+	nDocuments, err = store.NumberOfDocuments()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, nDocuments)
+
+	// Try to get the document from the store that should exist
+	retrieved, err := store.GetDocument(documents[0].Id)
+	assert.NoError(t, err)
+	assert.True(t, documents[0].Equal(retrieved))
+
+	// Try to get a document that shouldn't exist
+	retrieved, err = store.GetDocument("unknown")
+	assert.Equal(t, graphstore.ErrDocumentNotFound, err)
+	assert.Nil(t, retrieved)
+}
+
+func checkAddLink(t *testing.T, store graphstore.BipartiteGraphStore) {
+	entities := buildEntities(t)
+	documents := buildDocuments(t)
+
+	assert.NoError(t, store.AddEntity(entities[0]))
+	assert.NoError(t, store.AddDocument(documents[0]))
+
+	l := graphstore.NewLink(entities[0].Id, documents[0].Id)
+
+	assert.NoError(t, store.AddLink(l))
+
+	e0, err := store.GetEntity(entities[0].Id)
+	assert.NoError(t, err)
+	assert.NotNil(t, e0)
+
+	d0, err := store.GetDocument(documents[0].Id)
+	assert.NoError(t, err)
+	assert.NotNil(t, d0)
+
+	assert.True(t, e0.HasDocument(d0.Id))
+	assert.True(t, d0.HasEntity(e0.Id))
+
+	found, err := store.HasEntityWithId(e0.Id)
+	assert.NoError(t, err)
+	assert.True(t, found)
+}
+
+func checkDuplicateEntity(t *testing.T, store graphstore.BipartiteGraphStore) {
+	entities := buildEntities(t)
+
+	assert.NoError(t, store.AddEntity(entities[0]))
+	assert.NoError(t, store.AddEntity(entities[1]))
+
+	// Try to add the entities again (they will be overwritten)
+	assert.NoError(t, store.AddEntity(entities[0]))
+	assert.NoError(t, store.AddEntity(entities[1]))
+}
+
+func checkDuplicateDocument(t *testing.T, store graphstore.BipartiteGraphStore) {
+	documents := buildDocuments(t)
+
+	assert.NoError(t, store.AddDocument(documents[0]))
+	assert.NoError(t, store.AddDocument(documents[1]))
+
+	// Try to add the documents again (they will be overwritten)
+	assert.NoError(t, store.AddDocument(documents[0]))
+	assert.NoError(t, store.AddDocument(documents[1]))
+}
+
+func checkAllDocumentIds(t *testing.T, store graphstore.BipartiteGraphStore,
+	expected *set.Set[string]) {
+
+	iter, err := store.NewDocumentIdIterator()
+	assert.NoError(t, err)
+
+	// Set of all document IDs
+	actual, err := graphstore.AllDocuments(iter)
+	assert.NoError(t, err)
+
+	// Check the document IDs
+	assert.True(t, expected.Equal(actual))
+}
+
+func checkDocumentIterator(t *testing.T, store graphstore.BipartiteGraphStore) {
+	documents := buildDocuments(t)
+
+	// No documents in the store
+	checkAllDocumentIds(t, store, set.NewSet[string]())
+
+	// One document
+	assert.NoError(t, store.AddDocument(documents[0]))
+	checkAllDocumentIds(t, store, set.NewPopulatedSet("doc-1"))
+
+	// Two documents
+	assert.NoError(t, store.AddDocument(documents[1]))
+	checkAllDocumentIds(t, store, set.NewPopulatedSet("doc-1", "doc-2"))
+
+	// Add a duplicate document
+	assert.NoError(t, store.AddDocument(documents[1]))
+	checkAllDocumentIds(t, store, set.NewPopulatedSet("doc-1", "doc-2"))
+}
+
+func checkAllEntityIds(t *testing.T, store graphstore.BipartiteGraphStore,
+	expected *set.Set[string]) {
+
+	iter, err := store.NewEntityIdIterator()
+	assert.NoError(t, err)
+
+	// Set of all entity IDs
+	actual, err := graphstore.AllEntities(iter)
+	assert.NoError(t, err)
+
+	// Check the entity IDs
+	assert.True(t, expected.Equal(actual))
+}
+
+func checkEntityIterator(t *testing.T, store graphstore.BipartiteGraphStore) {
+	entities := buildEntities(t)
+
+	// No entities in the store
+	checkAllEntityIds(t, store, set.NewSet[string]())
+
+	// Add one entity
+	assert.NoError(t, store.AddEntity(entities[0]))
+	checkAllEntityIds(t, store, set.NewPopulatedSet("e-1"))
+
+	// Add another entity
+	assert.NoError(t, store.AddEntity(entities[1]))
+	checkAllEntityIds(t, store, set.NewPopulatedSet("e-1", "e-2"))
+
+	// Add a duplicate entity
+	assert.NoError(t, store.AddEntity(entities[1]))
+	checkAllEntityIds(t, store, set.NewPopulatedSet("e-1", "e-2"))
+}
+
+func checkDirectedLinks(t *testing.T, store graphstore.BipartiteGraphStore) {
+	entities := buildEntities(t)
+	documents := buildDocuments(t)
+
+	assert.NoError(t, store.AddEntity(entities[0]))
+	assert.NoError(t, store.AddEntity(entities[1]))
+	assert.NoError(t, store.AddDocument(documents[0]))
+
+	l1, err := graphstore.NewDirectedLink(entities[0].Id, documents[0].Id, graphstore.LinkSource)
+	assert.NoError(t, err)
+	assert.NoError(t, store.AddLink(l1))
+
+	l2, err := graphstore.NewDirectedLink(entities[1].Id, documents[0].Id, graphstore.LinkDestination)
+	assert.NoError(t, err)
+	assert.NoError(t, store.AddLink(l2))
+
+	d0, err := store.GetDocument(documents[0].Id)
+	assert.NoError(t, err)
+	assert.True(t, d0.IsDirected())
+	assert.Equal(t, graphstore.LinkSource, d0.Direction(entities[0].Id))
+	assert.Equal(t, graphstore.LinkDestination, d0.Direction(entities[1].Id))
+
+	// A link with an invalid direction can't be added
+	assert.ErrorIs(t, store.AddLink(graphstore.Link{
+		EntityId:   entities[0].Id,
+		DocumentId: documents[0].Id,
+		Direction:  "sideways",
+	}), graphstore.ErrInvalidLinkDirection)
+}
+
+// checkBipartiteRemoval checks the removal of links, documents and entities, including the links
+// held by the other side.
+func checkBipartiteRemoval(t *testing.T, store graphstore.BipartiteGraphStore) {
+	entities := buildEntities(t)
+	documents := buildDocuments(t)
+
+	for _, entity := range entities {
+		assert.NoError(t, store.AddEntity(entity))
+	}
+	for _, document := range documents {
+		assert.NoError(t, store.AddDocument(document))
+	}
+
+	l1, err := graphstore.NewDirectedLink("e-1", "doc-1", graphstore.LinkSource)
+	assert.NoError(t, err)
+	for _, link := range []graphstore.Link{l1, graphstore.NewLink("e-2", "doc-1"), graphstore.NewLink("e-1", "doc-2"),
+		graphstore.NewLink("e-2", "doc-2")} {
+		assert.NoError(t, store.AddLink(link))
+	}
+
+	// Remove a link
+	assert.NoError(t, store.RemoveLink(graphstore.NewLink("e-1", "doc-1")))
+	assert.ErrorIs(t, store.RemoveLink(graphstore.NewLink("e-1", "doc-1")), graphstore.ErrLinkNotFound)
+
+	e1, err := store.GetEntity("e-1")
+	assert.NoError(t, err)
+	assert.Equal(t, set.NewPopulatedSet("doc-2"), e1.LinkedDocumentIds)
+
+	d1, err := store.GetDocument("doc-1")
+	assert.NoError(t, err)
+	assert.Equal(t, set.NewPopulatedSet("e-2"), d1.LinkedEntityIds)
+	assert.False(t, d1.IsDirected())
+
+	// Remove a document
+	assert.NoError(t, store.RemoveDocument("doc-2"))
+	assert.ErrorIs(t, store.RemoveDocument("doc-2"), graphstore.ErrDocumentNotFound)
+
+	_, err = store.GetDocument("doc-2")
+	assert.ErrorIs(t, err, graphstore.ErrDocumentNotFound)
+
+	e1, err = store.GetEntity("e-1")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, e1.LinkedDocumentIds.Len())
+
+	// Remove an entity
+	assert.NoError(t, store.RemoveEntity("e-2"))
+	assert.ErrorIs(t, store.RemoveEntity("e-2"), graphstore.ErrEntityNotFound)
+
+	found, err := store.HasEntityWithId("e-2")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	d1, err = store.GetDocument("doc-1")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, d1.LinkedEntityIds.Len())
+
+	checkAllEntityIds(t, store, set.NewPopulatedSet("e-1"))
+	checkAllDocumentIds(t, store, set.NewPopulatedSet("doc-1"))
+}
+
+// checkAliases checks the aliases of entities are resolved and don't appear as entities.
+func checkAliases(t *testing.T, store graphstore.BipartiteGraphStore) {
+	entities := buildEntities(t)
+
+	for _, entity := range entities {
+		assert.NoError(t, store.AddEntity(entity))
+	}
+	assert.NoError(t, store.AddDocument(buildDocuments(t)[0]))
+	assert.NoError(t, store.AddLink(graphstore.NewLink("e-1", "doc-1")))
+
+	// Invalid aliases
+	assert.Error(t, store.AddAlias("", "e-1"))
+	assert.Error(t, store.AddAlias("old-1", ""))
+	assert.ErrorIs(t, store.AddAlias("e-1", "e-1"), graphstore.ErrAliasOfItself)
+
+	assert.NoError(t, store.AddAlias("old-1", "e-1"))
+	assert.NoError(t, store.AddAlias("old-2", "e-1"))
+
+	// An alias of an entity that is also an entity's ID is ignored
+	assert.NoError(t, store.AddAlias("e-2", "e-1"))
+
+	for _, test := range []struct {
+		entityId    string
+		canonicalId string
+	}{
+		{"e-1", "e-1"},
+		{"e-2", "e-2"},
+		{"old-1", "e-1"},
+		{"old-2", "e-1"},
+		{"unknown", "unknown"},
+		{"", ""},
+	} {
+		canonicalId, err := store.ResolveAlias(test.entityId)
+		assert.NoError(t, err)
+		assert.Equal(t, test.canonicalId, canonicalId)
+	}
+
+	canonicalIds, err := graphstore.ResolveAliases(store, []string{"old-2", "e-2", "unknown"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"e-1", "e-2", "unknown"}, canonicalIds)
+
+	// The entity can be retrieved using an alias
+	entity, err := store.GetEntity("old-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "e-1", entity.Id)
+	assert.Equal(t, set.NewPopulatedSet("doc-1"), entity.LinkedDocumentIds)
+
+	_, err = store.GetEntity("unknown")
+	assert.ErrorIs(t, err, graphstore.ErrEntityNotFound)
+
+	// An alias of an entity that isn't in the store
+	assert.NoError(t, store.AddAlias("old-3", "e-3"))
+	_, err = store.GetEntity("old-3")
+	assert.ErrorIs(t, err, graphstore.ErrEntityNotFound)
+
+	// Aliases aren't entities
+	found, err := store.HasEntityWithId("old-1")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	checkAllEntityIds(t, store, set.NewPopulatedSet("e-1", "e-2"))
+
+	n, err := store.NumberOfEntities()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	// Clearing the store removes the aliases
+	assert.NoError(t, store.Clear())
+	canonicalId, err := store.ResolveAlias("old-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "old-1", canonicalId)
+}
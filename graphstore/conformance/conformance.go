@@ -0,0 +1,110 @@
+// Package conformance holds the behaviour tests that every implementation of the
+// graphstore.BipartiteGraphStore and graphstore.UnipartiteGraphStore interfaces must pass, so that
+// a new backend (or a new operation, such as removal) is checked in the same way for every store.
+//
+// A store's tests run a suite with a factory that makes a new, empty store:
+//
+//	func TestConformance(t *testing.T) {
+//		conformance.RunUnipartite(t, func(t *testing.T) graphstore.UnipartiteGraphStore {
+//			return graphstore.NewInMemoryUnipartiteGraphStore()
+//		})
+//	}
+package conformance
+
+import (
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+)
+
+// A UnipartiteFactory makes a new, empty unipartite store for a test. The factory must tidy up the
+// store when the test finishes, e.g. using t.Cleanup().
+type UnipartiteFactory func(t *testing.T) graphstore.UnipartiteGraphStore
+
+// A BipartiteFactory makes a new, empty bipartite store for a test. The factory must tidy up the
+// store when the test finishes, e.g. using t.Cleanup().
+type BipartiteFactory func(t *testing.T) graphstore.BipartiteGraphStore
+
+// A unipartiteCheck of the behaviour of a unipartite store.
+type unipartiteCheck struct {
+	name  string
+	check func(*testing.T, graphstore.UnipartiteGraphStore)
+}
+
+// A unipartitePairCheck compares two unipartite stores of the same type.
+type unipartitePairCheck struct {
+	name  string
+	check func(*testing.T, graphstore.UnipartiteGraphStore, graphstore.UnipartiteGraphStore)
+}
+
+// A bipartiteCheck of the behaviour of a bipartite store.
+type bipartiteCheck struct {
+	name  string
+	check func(*testing.T, graphstore.BipartiteGraphStore)
+}
+
+// Checks of the unipartite stores
+var unipartiteChecks = []unipartiteCheck{
+	{"SelfConnection", checkSelfConnection},
+	{"SingleEdge", checkSingleEdge},
+	{"Adjacency", checkAdjacency},
+	{"EdgeExists", checkEdgeExists},
+	{"DirectedEdges", checkDirectedEdges},
+	{"EdgeMetadata", checkEdgeMetadata},
+	{"SetEdgeMetadata", checkSetEdgeMetadata},
+	{"Removal", checkUnipartiteRemoval},
+	{"EdgeIterator", checkEdgeIterator},
+	{"Equality", checkEquality},
+}
+
+// Checks that compare two unipartite stores
+var unipartitePairChecks = []unipartitePairCheck{
+	{"Concurrency", checkConcurrency},
+	{"OrderInvariance", checkOrderInvariance},
+	{"ConcurrentLoading", checkConcurrentLoading},
+}
+
+// Checks of the bipartite stores
+var bipartiteChecks = []bipartiteCheck{
+	{"AddEntity", checkAddEntity},
+	{"AddDocument", checkAddDocument},
+	{"AddLink", checkAddLink},
+	{"DirectedLinks", checkDirectedLinks},
+	{"DuplicateEntity", checkDuplicateEntity},
+	{"DuplicateDocument", checkDuplicateDocument},
+	{"DocumentIterator", checkDocumentIterator},
+	{"EntityIterator", checkEntityIterator},
+	{"Removal", checkBipartiteRemoval},
+	{"Aliases", checkAliases},
+}
+
+// RunUnipartite runs the conformance suite against the unipartite stores made by the factory. Each
+// check is a subtest with its own store.
+func RunUnipartite(t *testing.T, factory UnipartiteFactory) {
+
+	for _, c := range unipartiteChecks {
+		check := c.check
+		t.Run(c.name, func(t *testing.T) {
+			check(t, factory(t))
+		})
+	}
+
+	for _, c := range unipartitePairChecks {
+		check := c.check
+		t.Run(c.name, func(t *testing.T) {
+			check(t, factory(t), factory(t))
+		})
+	}
+}
+
+// RunBipartite runs the conformance suite against the bipartite stores made by the factory. Each
+// check is a subtest with its own store.
+func RunBipartite(t *testing.T, factory BipartiteFactory) {
+
+	for _, c := range bipartiteChecks {
+		check := c.check
+		t.Run(c.name, func(t *testing.T) {
+			check(t, factory(t))
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package conformance
+
+import (
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUnipartiteStores(t *testing.T) {
+
+	factories := []struct {
+		description string
+		factory     UnipartiteFactory
+	}{
+		{
+			description: "in-memory",
+			factory: func(t *testing.T) graphstore.UnipartiteGraphStore {
+				return graphstore.NewInMemoryUnipartiteGraphStore()
+			},
+		},
+		{
+			description: "pebble",
+			factory: func(t *testing.T) graphstore.UnipartiteGraphStore {
+				store, err := graphstore.NewPebbleUnipartiteGraphStore(t.TempDir())
+				assert.NoError(t, err)
+				t.Cleanup(func() { assert.NoError(t, store.Destroy()) })
+				return store
+			},
+		},
+		{
+			description: "bolt",
+			factory: func(t *testing.T) graphstore.UnipartiteGraphStore {
+				store, err := graphstore.NewBoltUnipartiteGraphStore(t.TempDir())
+				assert.NoError(t, err)
+				t.Cleanup(func() { assert.NoError(t, store.Destroy()) })
+				return store
+			},
+		},
+		{
+			// A fault injection store that doesn't inject any faults
+			description: "faulty",
+			factory: func(t *testing.T) graphstore.UnipartiteGraphStore {
+				store, err := graphstore.NewFaultyUnipartiteGraphStore(
+					graphstore.NewInMemoryUnipartiteGraphStore(), graphstore.FaultConfig{})
+				assert.NoError(t, err)
+				return store
+			},
+		},
+	}
+
+	for _, f := range factories {
+		factory := f.factory
+		t.Run(f.description, func(t *testing.T) {
+			RunUnipartite(t, factory)
+		})
+	}
+}
+
+func TestBipartiteStores(t *testing.T) {
+
+	factories := []struct {
+		description string
+		factory     BipartiteFactory
+	}{
+		{
+			description: "in-memory",
+			factory: func(t *testing.T) graphstore.BipartiteGraphStore {
+				return graphstore.NewInMemoryBipartiteGraphStore()
+			},
+		},
+		{
+			description: "pebble",
+			factory: func(t *testing.T) graphstore.BipartiteGraphStore {
+				store, err := graphstore.NewPebbleBipartiteGraphStore(t.TempDir())
+				assert.NoError(t, err)
+				t.Cleanup(func() { assert.NoError(t, store.Destroy()) })
+				return store
+			},
+		},
+		{
+			description: "bolt",
+			factory: func(t *testing.T) graphstore.BipartiteGraphStore {
+				store, err := graphstore.NewBoltBipartiteGraphStore(t.TempDir())
+				assert.NoError(t, err)
+				t.Cleanup(func() { assert.NoError(t, store.Destroy()) })
+				return store
+			},
+		},
+		{
+			// A fault injection store that doesn't inject any faults
+			description: "faulty",
+			factory: func(t *testing.T) graphstore.BipartiteGraphStore {
+				store, err := graphstore.NewFaultyBipartiteGraphStore(
+					graphstore.NewInMemoryBipartiteGraphStore(), graphstore.FaultConfig{})
+				assert.NoError(t, err)
+				return store
+			},
+		},
+	}
+
+	for _, f := range factories {
+		factory := f.factory
+		t.Run(f.description, func(t *testing.T) {
+			RunBipartite(t, factory)
+		})
+	}
+}
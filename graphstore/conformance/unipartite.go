@@ -0,0 +1,698 @@
+package conformance
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/set"
+	"github.com/stretchr/testify/assert"
+)
+
+func checkSelfConnection(t *testing.T, g graphstore.UnipartiteGraphStore) {
+	g.Clear()
+	assert.Error(t, g.AddDirected("a", "a"))
+	assert.Error(t, g.AddUndirected("a", "a"))
+}
+
+type connection struct {
+	source       string
+	destinations []string
+}
+
+// checkConnections in a unipartite graph.
+func checkConnections(t testing.TB, g graphstore.UnipartiteGraphStore, conns []connection) {
+	for _, conn := range conns {
+		expected := set.NewPopulatedSet(conn.destinations...)
+		actual, err := g.EntityIdsAdjacentTo(conn.source)
+		assert.NoError(t, err)
+		if !expected.Equal(actual) {
+			fmt.Printf("Source: %v, expected dsts: %v, actual dsts: %v\n", conn.source, expected.String(), actual.String())
+		}
+		assert.True(t, expected.Equal(actual))
+	}
+}
+
+// checkSingleEdge with the structure:
+//
+//	A--B
+func checkSingleEdge(t *testing.T, g graphstore.UnipartiteGraphStore) {
+	g.Clear()
+	assert.NoError(t, g.AddUndirected("A", "B"))
+
+	hasA, err := g.HasEntity("A")
+	assert.NoError(t, err)
+	assert.True(t, hasA)
+
+	hasB, err := g.HasEntity("B")
+	assert.NoError(t, err)
+	assert.True(t, hasB)
+
+	hasC, err := g.HasEntity("C")
+	assert.NoError(t, err)
+	assert.False(t, hasC)
+
+	// Check the entity IDs
+	expectedEntityIds := set.NewPopulatedSet("A", "B")
+	actualEntityIds, err := g.EntityIds()
+	assert.NoError(t, err)
+	assert.True(t, expectedEntityIds.Equal(actualEntityIds))
+
+	expectedConnections := []connection{
+		{
+			source:       "A",
+			destinations: []string{"B"},
+		},
+		{
+			source:       "B",
+			destinations: []string{"A"},
+		},
+	}
+
+	checkConnections(t, g, expectedConnections)
+
+	// Try to get a vertex that doesn't exist
+	_, err = g.EntityIdsAdjacentTo("C")
+	assert.Error(t, err)
+}
+
+// checkAdjacency with the structure:
+//
+//	      A--B----
+//	      |      |
+//	C--D--E--F---G
+//	      |      |
+//	      H-------
+func checkAdjacency(t *testing.T, g graphstore.UnipartiteGraphStore) {
+	g.Clear()
+	assert.NoError(t, g.AddUndirected("A", "B"))
+	assert.NoError(t, g.AddUndirected("A", "E"))
+	assert.NoError(t, g.AddUndirected("B", "G"))
+	assert.NoError(t, g.AddUndirected("C", "D"))
+	assert.NoError(t, g.AddUndirected("D", "E"))
+	assert.NoError(t, g.AddUndirected("E", "F"))
+	assert.NoError(t, g.AddUndirected("E", "H"))
+	assert.NoError(t, g.AddUndirected("F", "G"))
+	assert.NoError(t, g.AddUndirected("H", "G"))
+
+	expectedEntityIds := set.NewPopulatedSet("A", "B", "C", "D", "E", "F", "G", "H")
+	actualEntityIds, err := g.EntityIds()
+	assert.NoError(t, err)
+	assert.True(t, expectedEntityIds.Equal(actualEntityIds))
+
+	expectedConnections := []connection{
+		{
+			source:       "A",
+			destinations: []string{"B", "E"},
+		},
+		{
+			source:       "B",
+			destinations: []string{"A", "G"},
+		},
+		{
+			source:       "C",
+			destinations: []string{"D"},
+		},
+		{
+			source:       "D",
+			destinations: []string{"C", "E"},
+		},
+		{
+			source:       "E",
+			destinations: []string{"A", "D", "F", "H"},
+		},
+		{
+			source:       "F",
+			destinations: []string{"E", "G"},
+		},
+		{
+			source:       "G",
+			destinations: []string{"B", "F", "H"},
+		},
+		{
+			source:       "H",
+			destinations: []string{"E", "G"},
+		},
+	}
+
+	checkConnections(t, g, expectedConnections)
+}
+
+// checkEquality checks situations where the graph should be equal to an in-memory graph.
+//
+// Graph 1 is:
+//
+//	A--B--C
+//
+// Graph 2 is:
+//
+//	A--B--C--D
+//
+// Graph 3 is:
+//
+//	A--B--C
+//	|     |
+//	-------
+func checkEquality(t *testing.T, g1 graphstore.UnipartiteGraphStore) {
+
+	g2 := graphstore.NewInMemoryUnipartiteGraphStore()
+
+	// Test 1
+	assert.NoError(t, g1.Clear())
+	assert.NoError(t, g2.Clear())
+
+	n, err := g1.NumberEntities()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	n, err = g2.NumberEntities()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	// Graph 1
+	assert.NoError(t, g1.AddUndirected("A", "B"))
+	assert.NoError(t, g1.AddUndirected("B", "C"))
+
+	// Graph 1
+	assert.NoError(t, g2.AddUndirected("A", "B"))
+	assert.NoError(t, g2.AddUndirected("B", "C"))
+	equal, reason, err := graphstore.UnipartiteGraphStoresEqual(g1, g2)
+	assert.NoError(t, err)
+	assert.Equal(t, "", reason)
+	assert.True(t, equal)
+
+	// Mutate graph 1 into graph 3
+	assert.NoError(t, g2.AddUndirected("A", "C"))
+	equal, _, err = graphstore.UnipartiteGraphStoresEqual(g1, g2)
+	assert.NoError(t, err)
+	assert.Equal(t, "", reason)
+	assert.False(t, equal)
+
+	// Make graph 2
+	g2.Clear()
+	assert.NoError(t, g2.AddUndirected("A", "B"))
+	assert.NoError(t, g2.AddUndirected("B", "C"))
+	assert.NoError(t, g2.AddUndirected("C", "D"))
+	equal, _, err = graphstore.UnipartiteGraphStoresEqual(g1, g2)
+	assert.NoError(t, err)
+	assert.Equal(t, "", reason)
+	assert.False(t, equal)
+
+	// Graphs that differ by the direction of an edge
+	assert.NoError(t, g1.Clear())
+	assert.NoError(t, g2.Clear())
+	assert.NoError(t, g1.AddDirected("A", "B"))
+	assert.NoError(t, g2.AddDirected("B", "A"))
+	equal, reason, err = graphstore.UnipartiteGraphStoresEqual(g1, g2)
+	assert.NoError(t, err)
+	assert.NotEqual(t, "", reason)
+	assert.False(t, equal)
+
+	// Graphs that differ by an entity without edges
+	assert.NoError(t, g2.Clear())
+	assert.NoError(t, g2.AddDirected("A", "B"))
+	assert.NoError(t, g2.AddEntity("C"))
+	equal, reason, err = graphstore.UnipartiteGraphStoresEqual(g1, g2)
+	assert.NoError(t, err)
+	assert.NotEqual(t, "", reason)
+	assert.False(t, equal)
+
+	assert.NoError(t, g1.AddEntity("C"))
+	equal, reason, err = graphstore.UnipartiteGraphStoresEqual(g1, g2)
+	assert.NoError(t, err)
+	assert.Equal(t, "", reason)
+	assert.True(t, equal)
+}
+
+// checkEdgeExists checks that the vertices are connected as expected.
+//
+//	      A--B
+//	      |
+//	C--D--E
+func checkEdgeExists(t *testing.T, g graphstore.UnipartiteGraphStore) {
+
+	g.Clear()
+
+	assert.NoError(t, g.AddUndirected("A", "B"))
+	assert.NoError(t, g.AddUndirected("A", "E"))
+	assert.NoError(t, g.AddUndirected("C", "D"))
+	assert.NoError(t, g.AddUndirected("D", "E"))
+
+	testCases := []struct {
+		entity1      string
+		entity2      string
+		expectedEdge bool
+	}{
+		{
+			entity1:      "A",
+			entity2:      "B",
+			expectedEdge: true,
+		},
+		{
+			entity1:      "B",
+			entity2:      "A",
+			expectedEdge: true,
+		},
+		{
+			entity1:      "A",
+			entity2:      "E",
+			expectedEdge: true,
+		},
+		{
+			entity1:      "E",
+			entity2:      "A",
+			expectedEdge: true,
+		},
+		{
+			entity1:      "A",
+			entity2:      "D",
+			expectedEdge: false,
+		},
+		{
+			entity1:      "D",
+			entity2:      "A",
+			expectedEdge: false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := g.EdgeExists(testCase.entity1, testCase.entity2)
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.expectedEdge, actual)
+	}
+}
+
+// checkDirectedEdges checks the edges of a graph with directed edges.
+//
+//	A-->B--C
+func checkDirectedEdges(t *testing.T, g graphstore.UnipartiteGraphStore) {
+
+	g.Clear()
+
+	assert.NoError(t, g.AddDirected("A", "B"))
+	assert.NoError(t, g.AddUndirected("B", "C"))
+
+	// The destination of a directed edge is held in the graph
+	found, err := g.HasEntity("B")
+	assert.NoError(t, err)
+	assert.True(t, found)
+
+	checkConnections(t, g, []connection{
+		{source: "A", destinations: []string{"B"}},
+		{source: "B", destinations: []string{"C"}},
+		{source: "C", destinations: []string{"B"}},
+	})
+
+	testCases := []struct {
+		entityId string
+		expected *set.Set[string]
+	}{
+		{entityId: "A", expected: set.NewPopulatedSet("B")},
+		{entityId: "B", expected: set.NewPopulatedSet("A", "C")},
+		{entityId: "C", expected: set.NewPopulatedSet("B")},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := g.EntityIdsConnectedTo(testCase.entityId)
+		assert.NoError(t, err)
+		assert.True(t, testCase.expected.Equal(actual))
+	}
+
+	_, err = g.EntityIdsConnectedTo("D")
+	assert.Error(t, err)
+}
+
+// checkEdgeMetadata checks the recording of documents in the metadata of edges.
+func checkEdgeMetadata(t *testing.T, g graphstore.UnipartiteGraphStore) {
+
+	g.Clear()
+
+	assert.NoError(t, g.AddUndirected("A", "B"))
+
+	// No documents have been recorded
+	metadata, err := g.EdgeMetadata("A", "B")
+	assert.NoError(t, err)
+	assert.Nil(t, metadata)
+
+	// Record documents with and without dates
+	date1 := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	date2 := time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.NoError(t, g.AddEdgeDocument("A", "B", date2))
+	assert.NoError(t, g.AddEdgeDocument("A", "B", time.Time{}))
+	assert.NoError(t, g.AddEdgeDocument("A", "B", date1))
+	assert.NoError(t, g.AddEdgeDocument("B", "A", time.Time{}))
+
+	metadata, err = g.EdgeMetadata("A", "B")
+	assert.NoError(t, err)
+	assert.Equal(t, &graphstore.EdgeMetadata{NumberOfDocuments: 3, LatestDate: date2,
+		Dates: []time.Time{date1, date2}}, metadata)
+
+	metadata, err = g.EdgeMetadata("B", "A")
+	assert.NoError(t, err)
+	assert.Equal(t, &graphstore.EdgeMetadata{NumberOfDocuments: 1}, metadata)
+
+	// Invalid edges
+	assert.Error(t, g.AddEdgeDocument("A", "A", date1))
+	assert.Error(t, g.AddEdgeDocument("", "A", date1))
+
+	// Record a batch of documents
+	date3 := time.Date(2022, 4, 1, 0, 0, 0, 0, time.UTC)
+	assert.NoError(t, g.AddEdgeMetadata("A", "B", graphstore.EdgeMetadata{NumberOfDocuments: 3,
+		LatestDate: date3, Dates: []time.Time{date1, date3}}))
+
+	metadata, err = g.EdgeMetadata("A", "B")
+	assert.NoError(t, err)
+	assert.Equal(t, &graphstore.EdgeMetadata{NumberOfDocuments: 6, LatestDate: date2,
+		Dates: []time.Time{date1, date3, date2}}, metadata)
+
+	// The metadata is removed when the graph is cleared
+	assert.NoError(t, g.Clear())
+	metadata, err = g.EdgeMetadata("A", "B")
+	assert.NoError(t, err)
+	assert.Nil(t, metadata)
+}
+
+// checkSetEdgeMetadata checks the replacement and removal of the metadata of an edge.
+func checkSetEdgeMetadata(t *testing.T, g graphstore.UnipartiteGraphStore) {
+
+	assert.NoError(t, g.Clear())
+	assert.NoError(t, g.AddUndirected("A", "B"))
+
+	date := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+	assert.NoError(t, g.AddEdgeDocument("A", "B", date))
+	assert.NoError(t, g.AddEdgeDocument("A", "B", date))
+
+	replacement := &graphstore.EdgeMetadata{NumberOfDocuments: 1, LatestDate: date,
+		Dates: []time.Time{date}}
+	assert.NoError(t, g.SetEdgeMetadata("A", "B", replacement))
+
+	metadata, err := g.EdgeMetadata("A", "B")
+	assert.NoError(t, err)
+	assert.Equal(t, replacement, metadata)
+
+	// Documents are recorded on top of the replaced metadata
+	assert.NoError(t, g.AddEdgeDocument("A", "B", time.Time{}))
+	metadata, err = g.EdgeMetadata("A", "B")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, metadata.NumberOfDocuments)
+
+	// Nil metadata removes it, but not the edge
+	assert.NoError(t, g.SetEdgeMetadata("A", "B", nil))
+	metadata, err = g.EdgeMetadata("A", "B")
+	assert.NoError(t, err)
+	assert.Nil(t, metadata)
+
+	exists, err := g.EdgeExists("A", "B")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	assert.NoError(t, g.SetEdgeMetadata("B", "C", nil))
+	assert.Error(t, g.SetEdgeMetadata("", "A", replacement))
+}
+
+// checkUnipartiteRemoval checks the removal of edges and entities, including their reverse edges
+// and metadata.
+func checkUnipartiteRemoval(t *testing.T, g graphstore.UnipartiteGraphStore) {
+
+	assert.NoError(t, g.Clear())
+
+	// A -> B (directed), B -- C, C -- D
+	assert.NoError(t, g.AddDirected("A", "B"))
+	assert.NoError(t, g.AddUndirected("B", "C"))
+	assert.NoError(t, g.AddUndirected("C", "D"))
+	assert.NoError(t, g.AddEdgeDocument("B", "C", time.Time{}))
+	assert.NoError(t, g.AddEdgeDocument("C", "B", time.Time{}))
+
+	// Remove an edge
+	assert.NoError(t, g.RemoveEdge("C", "B"))
+	assert.ErrorIs(t, g.RemoveEdge("B", "C"), graphstore.ErrEdgeNotFound)
+
+	checkConnections(t, g, []connection{
+		{source: "A", destinations: []string{"B"}},
+		{source: "B", destinations: []string{}},
+		{source: "C", destinations: []string{"D"}},
+	})
+
+	metadata, err := g.EdgeMetadata("B", "C")
+	assert.NoError(t, err)
+	assert.Nil(t, metadata)
+
+	// Remove an entity at the destination of a directed edge
+	assert.NoError(t, g.RemoveEntity("B"))
+	assert.ErrorIs(t, g.RemoveEntity("B"), graphstore.ErrEntityNotFound)
+
+	found, err := g.HasEntity("B")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	// The entity at the other end of the edge is kept
+	found, err = g.HasEntity("A")
+	assert.NoError(t, err)
+	assert.True(t, found)
+
+	connected, err := g.EntityIdsConnectedTo("A")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, connected.Len())
+
+	// Remove an entity with an undirected edge
+	assert.NoError(t, g.RemoveEntity("D"))
+
+	connected, err = g.EntityIdsConnectedTo("C")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, connected.Len())
+
+	entityIds, err := g.EntityIds()
+	assert.NoError(t, err)
+	assert.True(t, set.NewPopulatedSet("A", "C").Equal(entityIds))
+}
+
+// checkEdgeIterator checks that each directed edge and each entity without outgoing edges is
+// streamed once.
+//
+//	A--B-->C  D
+func checkEdgeIterator(t *testing.T, g graphstore.UnipartiteGraphStore) {
+
+	assert.NoError(t, g.Clear())
+
+	// An empty graph
+	iter, err := g.NewEdgeIterator()
+	assert.NoError(t, err)
+	assert.False(t, iter.HasNext())
+	assert.NoError(t, iter.Close())
+
+	assert.NoError(t, g.AddUndirected("A", "B"))
+	assert.NoError(t, g.AddDirected("B", "C"))
+	assert.NoError(t, g.AddEntity("D"))
+
+	iter, err = g.NewEdgeIterator()
+	assert.NoError(t, err)
+
+	edges := []graphstore.Edge{}
+	for iter.HasNext() {
+		edge, err := iter.NextEdge()
+		assert.NoError(t, err)
+		edges = append(edges, edge)
+	}
+	assert.NoError(t, iter.Close())
+
+	assert.ElementsMatch(t, []graphstore.Edge{
+		{V1: "A", V2: "B"},
+		{V1: "B", V2: "A"},
+		{V1: "B", V2: "C"},
+		{V1: "C"},
+		{V1: "D"},
+	}, edges)
+
+	// An iterator that isn't exhausted can be closed
+	iter, err = g.NewEdgeIterator()
+	assert.NoError(t, err)
+	assert.True(t, iter.HasNext())
+	_, err = iter.NextEdge()
+	assert.NoError(t, err)
+	assert.NoError(t, iter.Close())
+}
+
+// checkConcurrency checks that loading the graph concurrently gives the same graph as loading it
+// without concurrency. The graph that is loaded is the following:
+//
+//	[e-1] ----------- --- [e-2] -------------- [e-3]
+//	  |                     |                     |
+//	  |--------[e-4]--------|                     |
+//	             |                                |
+//	             |                                |
+//	             |------- [e-5] -------------- [e-6]
+func checkConcurrency(t *testing.T, noConcurrency graphstore.UnipartiteGraphStore,
+	withConcurrency graphstore.UnipartiteGraphStore) {
+
+	edges := []graphstore.Edge{
+		{V1: "e1", V2: "e2"},
+		{V1: "e2", V2: "e3"},
+		{V1: "e1", V2: "e4"},
+		{V1: "e2", V2: "e4"},
+		{V1: "e4", V2: "e5"},
+		{V1: "e5", V2: "e6"},
+		{V1: "e6", V2: "e3"},
+	}
+
+	// Shuffle the edges
+	rand.Seed(time.Now().UnixNano())
+	rand.Shuffle(len(edges), func(i, j int) {
+		edges[i], edges[j] = edges[j], edges[i]
+	})
+
+	// Load the unipartite graph store without concurrency
+	for _, edge := range edges {
+		assert.NoError(t, noConcurrency.AddUndirected(edge.V1, edge.V2))
+	}
+
+	// Concurrently load the unipartite graph store
+	midPoint := int(math.Floor(float64(len(edges)) / 2.0))
+	edges1 := edges[:midPoint]
+	edges2 := edges[midPoint:]
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		for _, edge := range edges1 {
+			withConcurrency.AddUndirected(edge.V1, edge.V2)
+		}
+		wg.Done()
+	}()
+
+	wg.Add(1)
+	go func() {
+		for _, edge := range edges2 {
+			withConcurrency.AddUndirected(edge.V1, edge.V2)
+		}
+		wg.Done()
+	}()
+
+	wg.Wait()
+
+	// Check the result is as expected
+	equal, _, err := graphstore.UnipartiteGraphStoresEqual(noConcurrency, withConcurrency)
+	assert.NoError(t, err)
+	assert.True(t, equal)
+}
+
+func randomEntityId(maxId int) string {
+	return fmt.Sprintf("e-%d", rand.Intn(maxId))
+}
+
+func randomEdge(maxId int) graphstore.Edge {
+	for {
+		v1 := randomEntityId(maxId)
+		v2 := randomEntityId(maxId)
+		if v1 != v2 {
+			return graphstore.Edge{
+				V1: v1,
+				V2: v2,
+			}
+		}
+	}
+}
+
+// randomEdges with a maximum entity ID of maxId.
+func randomEdges(maxId int, numEdges int) []graphstore.Edge {
+	edges := make([]graphstore.Edge, numEdges)
+	for i := 0; i < numEdges; i++ {
+		edges[i] = randomEdge(maxId)
+	}
+	return edges
+}
+
+func loadEdges(t *testing.T, uni graphstore.UnipartiteGraphStore, edges []graphstore.Edge) {
+	for _, edge := range edges {
+		err := uni.AddUndirected(edge.V1, edge.V2)
+		assert.NoError(t, err)
+
+		exists, err := uni.EdgeExists(edge.V1, edge.V2)
+		assert.NoError(t, err)
+		assert.True(t, exists)
+	}
+}
+
+func loadEdgesConcurrently(t *testing.T, uni graphstore.UnipartiteGraphStore,
+	edges []graphstore.Edge) {
+
+	// Concurrently load the unipartite graph store
+	midPoint := int(math.Floor(float64(len(edges)) / 2.0))
+	edges1 := edges[:midPoint]
+	edges2 := edges[midPoint:]
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		for _, edge := range edges1 {
+			uni.AddUndirected(edge.V1, edge.V2)
+
+			exists, err := uni.EdgeExists(edge.V1, edge.V2)
+			assert.NoError(t, err)
+			assert.True(t, exists)
+		}
+		wg.Done()
+	}()
+
+	wg.Add(1)
+	go func() {
+		for _, edge := range edges2 {
+			uni.AddUndirected(edge.V1, edge.V2)
+
+			exists, err := uni.EdgeExists(edge.V1, edge.V2)
+			assert.NoError(t, err)
+			assert.True(t, exists)
+		}
+		wg.Done()
+	}()
+
+	wg.Wait()
+}
+
+// checkOrderInvariance checks that loading random edges in a different order gives the same graph.
+func checkOrderInvariance(t *testing.T, graph1 graphstore.UnipartiteGraphStore,
+	graph2 graphstore.UnipartiteGraphStore) {
+
+	maxNumEntities := 1000
+	numConnections := 4000
+
+	// Randomly generate edges to load
+	edges := randomEdges(maxNumEntities, numConnections)
+	loadEdges(t, graph1, edges)
+
+	rand.Shuffle(len(edges), func(i, j int) {
+		edges[i], edges[j] = edges[j], edges[i]
+	})
+
+	loadEdges(t, graph2, edges)
+
+	// Check the result is as expected
+	equal, _, err := graphstore.UnipartiteGraphStoresEqual(graph1, graph2)
+	assert.NoError(t, err)
+	assert.True(t, equal)
+}
+
+// checkConcurrentLoading checks that loading random edges with and without concurrency gives the
+// same graph.
+func checkConcurrentLoading(t *testing.T, graph1 graphstore.UnipartiteGraphStore,
+	graph2 graphstore.UnipartiteGraphStore) {
+
+	maxNumEntities := 1000
+	numConnections := 4000
+
+	// Randomly generate edges to load
+	edges := randomEdges(maxNumEntities, numConnections)
+
+	// Load the unipartite graph store with and without concurrency
+	loadEdges(t, graph1, edges)
+	loadEdgesConcurrently(t, graph2, edges)
+
+	// Check the result is as expected
+	equal, _, err := graphstore.UnipartiteGraphStoresEqual(graph1, graph2)
+	assert.NoError(t, err)
+	assert.True(t, equal)
+}
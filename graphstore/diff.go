@@ -0,0 +1,408 @@
+// A diff of two graph stores reports what changed between two builds of a graph, e.g. when a new
+// data drop changes the results of a search unexpectedly. The stores are walked using their
+// iterators and each item is looked up in the other store, so the stores needn't fit in memory.
+
+package graphstore
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/cdclaxton/shortest-path-web-app/set"
+)
+
+// Default maximum number of examples of each type of change held in a diff
+const DefaultDiffExamples = 20
+
+// Changes to a type of item (e.g. entities) between two stores.
+type Changes struct {
+	Added           int      `json:"added"`           // Number of items only in the new store
+	Removed         int      `json:"removed"`         // Number of items only in the old store
+	Changed         int      `json:"changed"`         // Number of items in both stores that differ
+	AddedExamples   []string `json:"addedExamples"`   // Sorted examples of the added items
+	RemovedExamples []string `json:"removedExamples"` // Sorted examples of the removed items
+	ChangedExamples []string `json:"changedExamples"` // Sorted examples of the changed items
+}
+
+// newChanges with no changes.
+func newChanges() Changes {
+	return Changes{
+		AddedExamples:   []string{},
+		RemovedExamples: []string{},
+		ChangedExamples: []string{},
+	}
+}
+
+// addExample to the examples if there are fewer than the maximum number.
+func addExample(examples []string, example string, maxExamples int) []string {
+	if len(examples) < maxExamples {
+		return append(examples, example)
+	}
+	return examples
+}
+
+// added item.
+func (c *Changes) added(item string, maxExamples int) {
+	c.Added += 1
+	c.AddedExamples = addExample(c.AddedExamples, item, maxExamples)
+}
+
+// removed item.
+func (c *Changes) removed(item string, maxExamples int) {
+	c.Removed += 1
+	c.RemovedExamples = addExample(c.RemovedExamples, item, maxExamples)
+}
+
+// changed item.
+func (c *Changes) changed(item string, maxExamples int) {
+	c.Changed += 1
+	c.ChangedExamples = addExample(c.ChangedExamples, item, maxExamples)
+}
+
+// sortExamples of the changes.
+func (c *Changes) sortExamples() {
+	sort.Strings(c.AddedExamples)
+	sort.Strings(c.RemovedExamples)
+	sort.Strings(c.ChangedExamples)
+}
+
+// None returns true if there are no changes.
+func (c Changes) None() bool {
+	return c.Added == 0 && c.Removed == 0 && c.Changed == 0
+}
+
+// BipartiteDiff of two bipartite stores. An entity or document is changed if its type or
+// attributes differ. A link is identified as "<entity ID> -- <document ID>".
+type BipartiteDiff struct {
+	Entities  Changes `json:"entities"`
+	Documents Changes `json:"documents"`
+	Links     Changes `json:"links"`
+}
+
+// UnipartiteDiff of two unipartite stores. An undirected edge is identified as "<e1> -- <e2>"
+// (with e1 < e2) and a directed edge as "<src> -> <dst>". An edge that changes between directed
+// and undirected is changed.
+type UnipartiteDiff struct {
+	Entities Changes `json:"entities"`
+	Edges    Changes `json:"edges"`
+}
+
+var ErrInvalidDiffExamples = errors.New("invalid maximum number of diff examples")
+
+// linkKey of the link between an entity and a document.
+func linkKey(entityId string, documentId string) string {
+	return fmt.Sprintf("%v -- %v", entityId, documentId)
+}
+
+// getEntityIfExists from the store, returning nil if it doesn't exist.
+func getEntityIfExists(store BipartiteGraphStore, entityId string) (*Entity, error) {
+	entity, err := store.GetEntity(entityId)
+	if errors.Is(err, ErrEntityNotFound) {
+		return nil, nil
+	}
+	return entity, err
+}
+
+// getDocumentIfExists from the store, returning nil if it doesn't exist.
+func getDocumentIfExists(store BipartiteGraphStore, documentId string) (*Document, error) {
+	document, err := store.GetDocument(documentId)
+	if errors.Is(err, ErrDocumentNotFound) {
+		return nil, nil
+	}
+	return document, err
+}
+
+// diffEntities of the bipartite stores, adding the entities in the old store to the changes. If
+// reverse is true, the stores have been swapped and only the added entities are recorded.
+func diffEntities(oldStore BipartiteGraphStore, newStore BipartiteGraphStore, changes *Changes,
+	reverse bool, maxExamples int) error {
+
+	it, err := oldStore.NewEntityIdIterator()
+	if err != nil {
+		return err
+	}
+
+	for it.hasNext() {
+		entityId, err := it.nextEntityId()
+		if err != nil {
+			return err
+		}
+
+		other, err := getEntityIfExists(newStore, entityId)
+		if err != nil {
+			return err
+		}
+
+		if other == nil {
+			if reverse {
+				changes.added(entityId, maxExamples)
+			} else {
+				changes.removed(entityId, maxExamples)
+			}
+			continue
+		}
+
+		if reverse {
+			continue
+		}
+
+		entity, err := getEntityIfExists(oldStore, entityId)
+		if err != nil {
+			return err
+		}
+
+		if entity != nil && (entity.EntityType != other.EntityType ||
+			!attributesEqual(entity.Attributes, other.Attributes)) {
+			changes.changed(entityId, maxExamples)
+		}
+	}
+
+	return nil
+}
+
+// linkedEntities of the document, which is empty if the document is nil.
+func linkedEntities(document *Document) *set.Set[string] {
+	if document == nil || document.LinkedEntityIds == nil {
+		return set.NewSet[string]()
+	}
+	return document.LinkedEntityIds
+}
+
+// diffDocuments of the bipartite stores, adding the documents and links in the old store to the
+// changes. If reverse is true, the stores have been swapped and only the added documents and links
+// are recorded.
+func diffDocuments(oldStore BipartiteGraphStore, newStore BipartiteGraphStore,
+	documentChanges *Changes, linkChanges *Changes, reverse bool, maxExamples int) error {
+
+	it, err := oldStore.NewDocumentIdIterator()
+	if err != nil {
+		return err
+	}
+
+	for it.hasNext() {
+		documentId, err := it.nextDocumentId()
+		if err != nil {
+			return err
+		}
+
+		document, err := getDocumentIfExists(oldStore, documentId)
+		if err != nil {
+			return err
+		}
+
+		other, err := getDocumentIfExists(newStore, documentId)
+		if err != nil {
+			return err
+		}
+
+		if other == nil {
+			if reverse {
+				documentChanges.added(documentId, maxExamples)
+			} else {
+				documentChanges.removed(documentId, maxExamples)
+			}
+		} else if !reverse && document != nil && (document.DocumentType != other.DocumentType ||
+			!attributesEqual(document.Attributes, other.Attributes)) {
+			documentChanges.changed(documentId, maxExamples)
+		}
+
+		// Links of the document that aren't in the other store
+		otherEntities := linkedEntities(other)
+		entityIds := linkedEntities(document).ToSlice()
+		sort.Strings(entityIds)
+
+		for _, entityId := range entityIds {
+			if otherEntities.Has(entityId) {
+				continue
+			}
+
+			if reverse {
+				linkChanges.added(linkKey(entityId, documentId), maxExamples)
+			} else {
+				linkChanges.removed(linkKey(entityId, documentId), maxExamples)
+			}
+		}
+	}
+
+	return nil
+}
+
+// DiffBipartite stores, reporting the changes from the old store to the new store with up to
+// maxExamples examples of each type of change.
+func DiffBipartite(oldStore BipartiteGraphStore, newStore BipartiteGraphStore,
+	maxExamples int) (*BipartiteDiff, error) {
+
+	if oldStore == nil || newStore == nil {
+		return nil, ErrBipartiteStoreIsNil
+	}
+
+	if maxExamples < 0 {
+		return nil, ErrInvalidDiffExamples
+	}
+
+	diff := BipartiteDiff{
+		Entities:  newChanges(),
+		Documents: newChanges(),
+		Links:     newChanges(),
+	}
+
+	// Removed and changed items
+	if err := diffEntities(oldStore, newStore, &diff.Entities, false, maxExamples); err != nil {
+		return nil, err
+	}
+
+	if err := diffDocuments(oldStore, newStore, &diff.Documents, &diff.Links, false, maxExamples); err != nil {
+		return nil, err
+	}
+
+	// Added items
+	if err := diffEntities(newStore, oldStore, &diff.Entities, true, maxExamples); err != nil {
+		return nil, err
+	}
+
+	if err := diffDocuments(newStore, oldStore, &diff.Documents, &diff.Links, true, maxExamples); err != nil {
+		return nil, err
+	}
+
+	diff.Entities.sortExamples()
+	diff.Documents.sortExamples()
+	diff.Links.sortExamples()
+
+	return &diff, nil
+}
+
+// edgeState of the pair of entities in a unipartite store, i.e. "--" for an undirected edge, "->"
+// for a directed edge from e1 to e2, "<-" for a directed edge from e2 to e1 or empty if the
+// entities aren't connected.
+func edgeState(store UnipartiteGraphStore, e1 string, e2 string) (string, error) {
+
+	forward, err := store.EdgeExists(e1, e2)
+	if err != nil {
+		return "", err
+	}
+
+	backward, err := store.EdgeExists(e2, e1)
+	if err != nil {
+		return "", err
+	}
+
+	switch {
+	case forward && backward:
+		return "--", nil
+	case forward:
+		return "->", nil
+	case backward:
+		return "<-", nil
+	}
+
+	return "", nil
+}
+
+// edgeName of the edge between the entities given its state.
+func edgeName(e1 string, e2 string, state string) string {
+	if state == "<-" {
+		return fmt.Sprintf("%v -> %v", e2, e1)
+	}
+	return fmt.Sprintf("%v %v %v", e1, state, e2)
+}
+
+// entityIdsConnectedTo the entity in the store, which is empty if the entity isn't in the store.
+func entityIdsConnectedTo(store UnipartiteGraphStore, entityId string) (*set.Set[string], error) {
+	found, err := store.HasEntity(entityId)
+	if err != nil {
+		return nil, err
+	}
+
+	if !found {
+		return set.NewSet[string](), nil
+	}
+
+	return store.EntityIdsConnectedTo(entityId)
+}
+
+// DiffUnipartite stores, reporting the changes from the old store to the new store with up to
+// maxExamples examples of each type of change.
+func DiffUnipartite(oldStore UnipartiteGraphStore, newStore UnipartiteGraphStore,
+	maxExamples int) (*UnipartiteDiff, error) {
+
+	if oldStore == nil || newStore == nil {
+		return nil, ErrUnipartiteStoreIsNil
+	}
+
+	if maxExamples < 0 {
+		return nil, ErrInvalidDiffExamples
+	}
+
+	diff := UnipartiteDiff{
+		Entities: newChanges(),
+		Edges:    newChanges(),
+	}
+
+	oldIds, err := oldStore.EntityIds()
+	if err != nil {
+		return nil, err
+	}
+
+	newIds, err := newStore.EntityIds()
+	if err != nil {
+		return nil, err
+	}
+
+	allIds := oldIds.Union(newIds).ToSlice()
+	sort.Strings(allIds)
+
+	for _, e1 := range allIds {
+		inOld, inNew := oldIds.Has(e1), newIds.Has(e1)
+		if inOld && !inNew {
+			diff.Entities.removed(e1, maxExamples)
+		} else if !inOld && inNew {
+			diff.Entities.added(e1, maxExamples)
+		}
+
+		oldConnected, err := entityIdsConnectedTo(oldStore, e1)
+		if err != nil {
+			return nil, err
+		}
+
+		newConnected, err := entityIdsConnectedTo(newStore, e1)
+		if err != nil {
+			return nil, err
+		}
+
+		// Each pair of entities is compared once, from the entity with the smaller ID
+		connected := oldConnected.Union(newConnected).ToSlice()
+		sort.Strings(connected)
+
+		for _, e2 := range connected {
+			if e2 <= e1 {
+				continue
+			}
+
+			oldState, err := edgeState(oldStore, e1, e2)
+			if err != nil {
+				return nil, err
+			}
+
+			newState, err := edgeState(newStore, e1, e2)
+			if err != nil {
+				return nil, err
+			}
+
+			switch {
+			case oldState == newState:
+			case oldState == "":
+				diff.Edges.added(edgeName(e1, e2, newState), maxExamples)
+			case newState == "":
+				diff.Edges.removed(edgeName(e1, e2, oldState), maxExamples)
+			default:
+				diff.Edges.changed(edgeName(e1, e2, newState), maxExamples)
+			}
+		}
+	}
+
+	diff.Entities.sortExamples()
+	diff.Edges.sortExamples()
+
+	return &diff, nil
+}
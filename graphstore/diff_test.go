@@ -0,0 +1,144 @@
+package graphstore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// makeDiffBipartite makes a bipartite store with the entities and documents, where each document
+// is given as its ID, type and linked entity IDs.
+func makeDiffBipartite(t *testing.T, store BipartiteGraphStore, entities map[string]string,
+	documents map[string][]string) {
+
+	for id, name := range entities {
+		entity, err := NewEntity(id, "Person", map[string]string{"Name": name})
+		assert.NoError(t, err)
+		assert.NoError(t, store.AddEntity(entity))
+	}
+
+	for id, linked := range documents {
+		doc, err := NewDocument(id, linked[0], map[string]string{})
+		assert.NoError(t, err)
+		assert.NoError(t, store.AddDocument(doc))
+
+		for _, entityId := range linked[1:] {
+			assert.NoError(t, store.AddLink(NewLink(entityId, id)))
+		}
+	}
+
+	assert.NoError(t, store.Finalise())
+}
+
+func TestDiffBipartite(t *testing.T) {
+
+	oldStore := newBipartitePebbleStore(t)
+	defer cleanUpBipartitePebbleStore(t, oldStore)
+
+	makeDiffBipartite(t, oldStore,
+		map[string]string{"e-1": "Bob", "e-2": "Sally", "e-3": "Jim"},
+		map[string][]string{
+			"d-1": {"Report", "e-1", "e-2"},
+			"d-2": {"Report", "e-2", "e-3"},
+		})
+
+	newStore := NewInMemoryBipartiteGraphStore()
+	makeDiffBipartite(t, newStore,
+		map[string]string{"e-1": "Bob", "e-2": "Sal", "e-4": "Fred"},
+		map[string][]string{
+			"d-1": {"Meeting", "e-1", "e-2", "e-4"},
+			"d-3": {"Report", "e-1", "e-4"},
+		})
+
+	_, err := DiffBipartite(oldStore, newStore, -1)
+	assert.ErrorIs(t, err, ErrInvalidDiffExamples)
+
+	_, err = DiffBipartite(nil, newStore, 1)
+	assert.ErrorIs(t, err, ErrBipartiteStoreIsNil)
+
+	diff, err := DiffBipartite(oldStore, newStore, DefaultDiffExamples)
+	assert.NoError(t, err)
+
+	assert.Equal(t, Changes{
+		Added:           1,
+		Removed:         1,
+		Changed:         1,
+		AddedExamples:   []string{"e-4"},
+		RemovedExamples: []string{"e-3"},
+		ChangedExamples: []string{"e-2"},
+	}, diff.Entities)
+
+	assert.Equal(t, Changes{
+		Added:           1,
+		Removed:         1,
+		Changed:         1,
+		AddedExamples:   []string{"d-3"},
+		RemovedExamples: []string{"d-2"},
+		ChangedExamples: []string{"d-1"},
+	}, diff.Documents)
+
+	assert.Equal(t, Changes{
+		Added:           3,
+		Removed:         2,
+		AddedExamples:   []string{"e-1 -- d-3", "e-4 -- d-1", "e-4 -- d-3"},
+		RemovedExamples: []string{"e-2 -- d-2", "e-3 -- d-2"},
+		ChangedExamples: []string{},
+	}, diff.Links)
+
+	// The number of examples is limited
+	diff, err = DiffBipartite(oldStore, newStore, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, diff.Links.Added)
+	assert.Equal(t, 1, len(diff.Links.AddedExamples))
+
+	// A store doesn't differ from itself
+	diff, err = DiffBipartite(oldStore, oldStore, DefaultDiffExamples)
+	assert.NoError(t, err)
+	assert.True(t, diff.Entities.None())
+	assert.True(t, diff.Documents.None())
+	assert.True(t, diff.Links.None())
+}
+
+func TestDiffUnipartite(t *testing.T) {
+
+	oldStore := NewInMemoryUnipartiteGraphStore()
+	assert.NoError(t, oldStore.AddUndirected("e-1", "e-2"))
+	assert.NoError(t, oldStore.AddUndirected("e-2", "e-3"))
+	assert.NoError(t, oldStore.AddDirected("e-4", "e-3"))
+	assert.NoError(t, oldStore.AddEntity("e-5"))
+
+	newStore := newUnipartitePebbleStore(t)
+	defer cleanUpUnipartitePebbleStore(t, newStore)
+	assert.NoError(t, newStore.AddUndirected("e-2", "e-1"))
+	assert.NoError(t, newStore.AddDirected("e-3", "e-2"))
+	assert.NoError(t, newStore.AddUndirected("e-3", "e-4"))
+	assert.NoError(t, newStore.AddUndirected("e-1", "e-6"))
+
+	_, err := DiffUnipartite(oldStore, nil, 1)
+	assert.ErrorIs(t, err, ErrUnipartiteStoreIsNil)
+
+	diff, err := DiffUnipartite(oldStore, newStore, DefaultDiffExamples)
+	assert.NoError(t, err)
+
+	assert.Equal(t, Changes{
+		Added:           1,
+		Removed:         1,
+		AddedExamples:   []string{"e-6"},
+		RemovedExamples: []string{"e-5"},
+		ChangedExamples: []string{},
+	}, diff.Entities)
+
+	assert.Equal(t, Changes{
+		Added:           1,
+		Changed:         2,
+		AddedExamples:   []string{"e-1 -- e-6"},
+		RemovedExamples: []string{},
+		ChangedExamples: []string{"e-3 -- e-4", "e-3 -> e-2"},
+	}, diff.Edges)
+
+	// The changes in the other direction
+	diff, err = DiffUnipartite(newStore, oldStore, DefaultDiffExamples)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"e-1 -- e-6"}, diff.Edges.RemovedExamples)
+	assert.Equal(t, []string{"e-2 -- e-3", "e-4 -> e-3"}, diff.Edges.ChangedExamples)
+}
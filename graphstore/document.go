@@ -13,6 +13,7 @@ type Document struct {
 	DocumentType    string            // Document type
 	Attributes      map[string]string // Document attributes (e.g. name, date)
 	LinkedEntityIds *set.Set[string]  // IDs of entities to which the document is connected
+	Directions      map[string]string // Entity ID to direction of its link (only directed links)
 }
 
 var (
@@ -84,6 +85,41 @@ func (d *Document) AddEntity(id string) {
 	d.LinkedEntityIds.Add(id)
 }
 
+// AddDirectedEntity linked to the document with a given direction.
+func (d *Document) AddDirectedEntity(id string, direction string) {
+	d.LinkedEntityIds.Add(id)
+
+	if direction == LinkUndirected {
+		delete(d.Directions, id)
+		return
+	}
+
+	if d.Directions == nil {
+		d.Directions = map[string]string{}
+	}
+	d.Directions[id] = direction
+}
+
+// RemoveEntity linked to the document, including the direction of its link.
+func (d *Document) RemoveEntity(id string) {
+	d.LinkedEntityIds.Remove(id)
+	delete(d.Directions, id)
+
+	if len(d.Directions) == 0 {
+		d.Directions = nil
+	}
+}
+
+// Direction of the entity's link to the document.
+func (d *Document) Direction(id string) string {
+	return d.Directions[id]
+}
+
+// IsDirected returns true if the document has any directed links.
+func (d *Document) IsDirected() bool {
+	return len(d.Directions) > 0
+}
+
 // HasEntity returns true if the document has the linked entity ID.
 func (d *Document) HasEntity(id string) bool {
 	return d.LinkedEntityIds.Has(id)
@@ -112,5 +148,10 @@ func (e *Document) Equal(other *Document) bool {
 		return false
 	}
 
+	// Check the directions of the links
+	if !attributesEqual(e.Directions, other.Directions) {
+		return false
+	}
+
 	return true
 }
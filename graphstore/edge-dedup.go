@@ -0,0 +1,124 @@
+package graphstore
+
+import (
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+)
+
+// Default maximum number of edges remembered by the deduplication stage of the conversion
+const DefaultDedupCapacity = 1000000
+
+// Number of independently locked shards of the deduplicator, so that workers rarely contend
+const numDedupShards = 64
+
+// An edgeDeduplicator remembers the edges that have been written to the unipartite store during
+// the conversion, so that an edge between two entities linked by many documents is written once.
+//
+// Writing an edge is idempotent, so the deduplicator only saves work and never changes the graph.
+// Its memory is bounded: a shard that is full forgets its edges, after which an edge may be
+// written again. The edges are compared exactly, so an edge is never wrongly skipped.
+type edgeDeduplicator struct {
+	numSkipped   int64 // Number of writes skipped (accessed atomically)
+	numForgotten int64 // Number of times a full shard forgot its edges (accessed atomically)
+	maxPerShard  int
+	shards       [numDedupShards]dedupShard
+}
+
+// dedupShard holds the keys of the edges that hash to it.
+type dedupShard struct {
+	seen map[string]struct{}
+	mu   sync.Mutex
+}
+
+// newEdgeDeduplicator that remembers up to (approximately) capacity edges, where zero means the
+// default capacity. Nil is returned if the capacity is negative, i.e. deduplication is disabled.
+func newEdgeDeduplicator(capacity int) *edgeDeduplicator {
+
+	if capacity < 0 {
+		return nil
+	}
+
+	if capacity == 0 {
+		capacity = DefaultDedupCapacity
+	}
+
+	maxPerShard := capacity / numDedupShards
+	if maxPerShard < 1 {
+		maxPerShard = 1
+	}
+
+	d := edgeDeduplicator{
+		maxPerShard: maxPerShard,
+	}
+
+	for idx := range d.shards {
+		d.shards[idx].seen = map[string]struct{}{}
+	}
+
+	return &d
+}
+
+// undirectedKey of the edge between the entities, which is the same in either direction.
+func undirectedKey(e1 string, e2 string) string {
+	if e2 < e1 {
+		e1, e2 = e2, e1
+	}
+	return "u" + separator + e1 + separator + e2
+}
+
+// directedKey of the edge from src to dst.
+func directedKey(src string, dst string) string {
+	return "d" + separator + src + separator + dst
+}
+
+// entityKey of an entity without edges.
+func entityKey(id string) string {
+	return "n" + separator + id
+}
+
+// firstSeen returns true if the key hasn't been seen before (or has been forgotten), in which
+// case the key is remembered and the caller should write it. It is safe for concurrent use and on
+// a nil deduplicator, which never remembers a key.
+func (d *edgeDeduplicator) firstSeen(key string) bool {
+
+	if d == nil {
+		return true
+	}
+
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	shard := &d.shards[h.Sum32()%numDedupShards]
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	if _, found := shard.seen[key]; found {
+		atomic.AddInt64(&d.numSkipped, 1)
+		return false
+	}
+
+	if len(shard.seen) >= d.maxPerShard {
+		shard.seen = map[string]struct{}{}
+		atomic.AddInt64(&d.numForgotten, 1)
+	}
+
+	shard.seen[key] = struct{}{}
+	return true
+}
+
+// skipped returns the number of writes skipped.
+func (d *edgeDeduplicator) skipped() int64 {
+	if d == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&d.numSkipped)
+}
+
+// forgotten returns the number of times a full shard forgot its edges.
+func (d *edgeDeduplicator) forgotten() int64 {
+	if d == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&d.numForgotten)
+}
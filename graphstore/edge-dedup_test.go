@@ -0,0 +1,52 @@
+package graphstore
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEdgeDeduplicator(t *testing.T) {
+
+	// Disabled
+	var disabled *edgeDeduplicator = newEdgeDeduplicator(-1)
+	assert.Nil(t, disabled)
+	assert.True(t, disabled.firstSeen(undirectedKey("e-1", "e-2")))
+	assert.True(t, disabled.firstSeen(undirectedKey("e-1", "e-2")))
+	assert.Equal(t, int64(0), disabled.skipped())
+
+	// Default capacity
+	d := newEdgeDeduplicator(0)
+	assert.Equal(t, DefaultDedupCapacity/numDedupShards, d.maxPerShard)
+
+	// An undirected edge is the same in either direction, a directed edge isn't
+	assert.True(t, d.firstSeen(undirectedKey("e-1", "e-2")))
+	assert.False(t, d.firstSeen(undirectedKey("e-2", "e-1")))
+	assert.True(t, d.firstSeen(directedKey("e-1", "e-2")))
+	assert.True(t, d.firstSeen(directedKey("e-2", "e-1")))
+	assert.False(t, d.firstSeen(directedKey("e-1", "e-2")))
+	assert.True(t, d.firstSeen(entityKey("e-1")))
+	assert.False(t, d.firstSeen(entityKey("e-1")))
+	assert.Equal(t, int64(3), d.skipped())
+	assert.Equal(t, int64(0), d.forgotten())
+}
+
+func TestEdgeDeduplicatorBoundedMemory(t *testing.T) {
+
+	// One edge per shard
+	d := newEdgeDeduplicator(1)
+	assert.Equal(t, 1, d.maxPerShard)
+
+	for i := 0; i < 1000; i++ {
+		d.firstSeen(undirectedKey("e-1", fmt.Sprintf("e-%d", i)))
+	}
+
+	for idx := range d.shards {
+		assert.LessOrEqual(t, len(d.shards[idx].seen), 1)
+	}
+	assert.Greater(t, d.forgotten(), int64(0))
+
+	// A forgotten edge is written again
+	assert.True(t, d.firstSeen(undirectedKey("e-1", "e-0")))
+}
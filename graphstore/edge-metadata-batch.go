@@ -0,0 +1,147 @@
+// The conversion records each document in the metadata of the edges between the entities it links,
+// in both directions. Recording a document in the store is a read-modify-write, so rather than
+// writing the metadata of every ordered pair of entities of every document, the documents are
+// accumulated in memory and the metadata of an edge is written once per batch.
+
+package graphstore
+
+import (
+	"hash/fnv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// An edgeMetadataBatch accumulates the documents recorded in the metadata of the edges during the
+// conversion, so that the metadata of an edge linked by many documents is written once.
+//
+// Its memory is bounded: a shard that is full writes its edges to the store and starts again. A
+// shard is written whilst it is locked, so once flush returns the documents added before it was
+// called are in the store.
+type edgeMetadataBatch struct {
+	numDocuments int64 // Number of documents recorded (accessed atomically)
+	numWrites    int64 // Number of metadata writes to the store (accessed atomically)
+	maxPerShard  int
+	shards       [numDedupShards]metadataShard
+}
+
+// metadataShard holds the accumulated metadata of the edges that hash to it.
+type metadataShard struct {
+	edges map[Edge]*EdgeMetadata
+	mu    sync.Mutex
+}
+
+// newEdgeMetadataBatch that holds up to (approximately) capacity edges, where zero means the
+// default capacity of the deduplicator. Nil is returned if the capacity is negative, i.e. each
+// document is written to the store directly.
+func newEdgeMetadataBatch(capacity int) *edgeMetadataBatch {
+
+	if capacity < 0 {
+		return nil
+	}
+
+	if capacity == 0 {
+		capacity = DefaultDedupCapacity
+	}
+
+	maxPerShard := capacity / numDedupShards
+	if maxPerShard < 1 {
+		maxPerShard = 1
+	}
+
+	b := edgeMetadataBatch{
+		maxPerShard: maxPerShard,
+	}
+
+	for idx := range b.shards {
+		b.shards[idx].edges = map[Edge]*EdgeMetadata{}
+	}
+
+	return &b
+}
+
+// add a document linking src to dst, where the date of the document is zero if it isn't known. It
+// is safe for concurrent use and on a nil batch, which writes the document to the store directly.
+func (b *edgeMetadataBatch) add(uni UnipartiteGraphStore, src string, dst string, date time.Time) error {
+
+	if b == nil {
+		return uni.AddEdgeDocument(src, dst, date)
+	}
+
+	atomic.AddInt64(&b.numDocuments, 1)
+
+	h := fnv.New32a()
+	h.Write([]byte(directedKey(src, dst)))
+	shard := &b.shards[h.Sum32()%numDedupShards]
+
+	shard.mu.Lock()
+	defer shard.mu.Unlock()
+
+	edge := Edge{V1: src, V2: dst}
+	metadata, found := shard.edges[edge]
+	if !found {
+		if len(shard.edges) >= b.maxPerShard {
+			if err := b.write(uni, shard); err != nil {
+				return err
+			}
+		}
+
+		metadata = &EdgeMetadata{}
+		shard.edges[edge] = metadata
+	}
+
+	metadata.addDocument(date)
+	return nil
+}
+
+// flush the accumulated metadata of all of the edges to the store.
+func (b *edgeMetadataBatch) flush(uni UnipartiteGraphStore) error {
+
+	if b == nil {
+		return nil
+	}
+
+	for idx := range b.shards {
+		shard := &b.shards[idx]
+
+		shard.mu.Lock()
+		err := b.write(uni, shard)
+		shard.mu.Unlock()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// write the accumulated metadata of the (locked) shard to the store and empty the shard.
+func (b *edgeMetadataBatch) write(uni UnipartiteGraphStore, shard *metadataShard) error {
+
+	for edge, metadata := range shard.edges {
+		if err := uni.AddEdgeMetadata(edge.V1, edge.V2, *metadata); err != nil {
+			return err
+		}
+		atomic.AddInt64(&b.numWrites, 1)
+		delete(shard.edges, edge)
+	}
+
+	return nil
+}
+
+// documents returns the number of documents recorded.
+func (b *edgeMetadataBatch) documents() int64 {
+	if b == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&b.numDocuments)
+}
+
+// writes returns the number of metadata writes to the store.
+func (b *edgeMetadataBatch) writes() int64 {
+	if b == nil {
+		return 0
+	}
+	return atomic.LoadInt64(&b.numWrites)
+}
@@ -0,0 +1,87 @@
+package graphstore
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEdgeMetadataBatch(t *testing.T) {
+
+	date1 := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	date2 := time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	// Disabled batch writes each document directly
+	var disabled *edgeMetadataBatch = newEdgeMetadataBatch(-1)
+	assert.Nil(t, disabled)
+
+	direct := NewInMemoryUnipartiteGraphStore()
+	assert.NoError(t, disabled.add(direct, "e-1", "e-2", date1))
+	assert.NoError(t, disabled.add(direct, "e-1", "e-2", date2))
+	assert.NoError(t, disabled.add(direct, "e-1", "e-2", time.Time{}))
+	assert.NoError(t, disabled.flush(direct))
+	assert.Equal(t, int64(0), disabled.writes())
+
+	// Batch with the default capacity
+	b := newEdgeMetadataBatch(0)
+	assert.Equal(t, DefaultDedupCapacity/numDedupShards, b.maxPerShard)
+
+	batched := NewInMemoryUnipartiteGraphStore()
+	assert.NoError(t, b.add(batched, "e-1", "e-2", date1))
+	assert.NoError(t, b.add(batched, "e-1", "e-2", date2))
+	assert.NoError(t, b.add(batched, "e-1", "e-2", time.Time{}))
+
+	// Nothing is written until the batch is flushed
+	metadata, err := batched.EdgeMetadata("e-1", "e-2")
+	assert.NoError(t, err)
+	assert.Nil(t, metadata)
+
+	assert.NoError(t, b.flush(batched))
+	assert.Equal(t, int64(3), b.documents())
+	assert.Equal(t, int64(1), b.writes())
+
+	// The metadata is the same as writing each document directly
+	expected, err := direct.EdgeMetadata("e-1", "e-2")
+	assert.NoError(t, err)
+	metadata, err = batched.EdgeMetadata("e-1", "e-2")
+	assert.NoError(t, err)
+	assert.Equal(t, expected, metadata)
+
+	// A subsequent batch adds to the metadata in the store
+	assert.NoError(t, b.add(batched, "e-1", "e-2", date1))
+	assert.NoError(t, b.flush(batched))
+
+	metadata, err = batched.EdgeMetadata("e-1", "e-2")
+	assert.NoError(t, err)
+	assert.Equal(t, &EdgeMetadata{NumberOfDocuments: 4, LatestDate: date2,
+		Dates: []time.Time{date1, date2}}, metadata)
+}
+
+func TestEdgeMetadataBatchBoundedMemory(t *testing.T) {
+
+	// One edge per shard
+	b := newEdgeMetadataBatch(1)
+	assert.Equal(t, 1, b.maxPerShard)
+
+	uni := NewInMemoryUnipartiteGraphStore()
+	for i := 1; i <= 1000; i++ {
+		assert.NoError(t, b.add(uni, "e-0", fmt.Sprintf("e-%d", i), time.Time{}))
+	}
+
+	for idx := range b.shards {
+		assert.LessOrEqual(t, len(b.shards[idx].edges), 1)
+	}
+	assert.Greater(t, b.writes(), int64(0))
+
+	// Every document is in the store once the batch is flushed
+	assert.NoError(t, b.flush(uni))
+	assert.Equal(t, int64(1000), b.writes())
+
+	for i := 1; i <= 1000; i++ {
+		metadata, err := uni.EdgeMetadata("e-0", fmt.Sprintf("e-%d", i))
+		assert.NoError(t, err)
+		assert.Equal(t, 1, metadata.NumberOfDocuments)
+	}
+}
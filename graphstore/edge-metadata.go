@@ -0,0 +1,169 @@
+package graphstore
+
+import (
+	"encoding/binary"
+	"errors"
+	"sort"
+	"time"
+)
+
+var ErrMalformedEdgeMetadata = errors.New("malformed edge metadata")
+
+// EdgeMetadata summarises the documents supporting an edge in a unipartite graph, so that an edge
+// can be described without looking up its documents in the bipartite graph.
+type EdgeMetadata struct {
+	NumberOfDocuments int         // Number of documents linking the entities
+	LatestDate        time.Time   // Date of the most recent document (zero if not known)
+	Dates             []time.Time // Distinct known dates of the documents in ascending order
+}
+
+// hasDate returns true if the date is one of the dates of the documents.
+func (e *EdgeMetadata) hasDate(date time.Time) bool {
+	idx := sort.Search(len(e.Dates), func(i int) bool { return !e.Dates[i].Before(date) })
+	return idx < len(e.Dates) && e.Dates[idx].Equal(date)
+}
+
+// addDocument to the metadata, where the date of the document is zero if it isn't known.
+func (e *EdgeMetadata) addDocument(date time.Time) {
+	e.NumberOfDocuments += 1
+	if date.After(e.LatestDate) {
+		e.LatestDate = date
+	}
+
+	if date.IsZero() || e.hasDate(date) {
+		return
+	}
+
+	idx := sort.Search(len(e.Dates), func(i int) bool { return e.Dates[i].After(date) })
+	dates := make([]time.Time, 0, len(e.Dates)+1)
+	dates = append(dates, e.Dates[:idx]...)
+	dates = append(dates, date)
+	e.Dates = append(dates, e.Dates[idx:]...)
+}
+
+// merge the documents of the other metadata into the metadata.
+func (e *EdgeMetadata) merge(other EdgeMetadata) {
+	e.NumberOfDocuments += other.NumberOfDocuments
+	if other.LatestDate.After(e.LatestDate) {
+		e.LatestDate = other.LatestDate
+	}
+
+	if len(other.Dates) == 0 {
+		return
+	}
+
+	// Merge the (sorted) distinct dates
+	dates := make([]time.Time, 0, len(e.Dates)+len(other.Dates))
+	i, j := 0, 0
+	for i < len(e.Dates) || j < len(other.Dates) {
+		var date time.Time
+		switch {
+		case j == len(other.Dates) || (i < len(e.Dates) && e.Dates[i].Before(other.Dates[j])):
+			date = e.Dates[i]
+			i++
+		case i == len(e.Dates) || other.Dates[j].Before(e.Dates[i]):
+			date = other.Dates[j]
+			j++
+		default:
+			date = e.Dates[i]
+			i++
+			j++
+		}
+		dates = append(dates, date)
+	}
+
+	e.Dates = dates
+}
+
+// EarliestDateFrom returns the earliest date of a document supporting the edge that isn't before
+// the given date. False is returned if there isn't such a document.
+func (e *EdgeMetadata) EarliestDateFrom(date time.Time) (time.Time, bool) {
+	idx := sort.Search(len(e.Dates), func(i int) bool { return !e.Dates[i].Before(date) })
+	if idx == len(e.Dates) {
+		return time.Time{}, false
+	}
+	return e.Dates[idx], true
+}
+
+// LatestDateUntil returns the latest date of a document supporting the edge that isn't after the
+// given date. False is returned if there isn't such a document.
+func (e *EdgeMetadata) LatestDateUntil(date time.Time) (time.Time, bool) {
+	idx := sort.Search(len(e.Dates), func(i int) bool { return e.Dates[i].After(date) })
+	if idx == 0 {
+		return time.Time{}, false
+	}
+	return e.Dates[idx-1], true
+}
+
+// encodeEdgeMetadata as the number of documents and the Unix time (in seconds) of the latest date,
+// both as varints, followed by the number of dates and the differences between successive dates.
+// A date that isn't known is encoded as zero.
+func encodeEdgeMetadata(metadata EdgeMetadata) []byte {
+
+	var latest int64
+	if !metadata.LatestDate.IsZero() {
+		latest = metadata.LatestDate.Unix()
+	}
+
+	buf := make([]byte, (3+len(metadata.Dates))*binary.MaxVarintLen64)
+	n := binary.PutUvarint(buf, uint64(metadata.NumberOfDocuments))
+	n += binary.PutVarint(buf[n:], latest)
+	n += binary.PutUvarint(buf[n:], uint64(len(metadata.Dates)))
+
+	var previous int64
+	for _, date := range metadata.Dates {
+		n += binary.PutVarint(buf[n:], date.Unix()-previous)
+		previous = date.Unix()
+	}
+
+	return buf[:n]
+}
+
+// decodeEdgeMetadata from its encoded form. The metadata of a graph built before the dates were
+// recorded has no dates.
+func decodeEdgeMetadata(value []byte) (EdgeMetadata, error) {
+
+	numDocs, n := binary.Uvarint(value)
+	if n <= 0 {
+		return EdgeMetadata{}, ErrMalformedEdgeMetadata
+	}
+
+	latest, m := binary.Varint(value[n:])
+	if m <= 0 {
+		return EdgeMetadata{}, ErrMalformedEdgeMetadata
+	}
+	n += m
+
+	metadata := EdgeMetadata{NumberOfDocuments: int(numDocs)}
+	if latest != 0 {
+		metadata.LatestDate = time.Unix(latest, 0).UTC()
+	}
+
+	if n == len(value) {
+		return metadata, nil
+	}
+
+	numDates, m := binary.Uvarint(value[n:])
+	if m <= 0 || numDates > uint64(len(value)) {
+		return EdgeMetadata{}, ErrMalformedEdgeMetadata
+	}
+	n += m
+
+	var previous int64
+	for i := uint64(0); i < numDates; i++ {
+		delta, m := binary.Varint(value[n:])
+		if m <= 0 {
+			return EdgeMetadata{}, ErrMalformedEdgeMetadata
+		}
+		n += m
+
+		previous += delta
+		metadata.Dates = append(metadata.Dates, time.Unix(previous, 0).UTC())
+	}
+
+	if n != len(value) {
+		return EdgeMetadata{}, ErrMalformedEdgeMetadata
+	}
+
+	return metadata, nil
+}
@@ -0,0 +1,132 @@
+package graphstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeDecodeEdgeMetadata(t *testing.T) {
+
+	testCases := []EdgeMetadata{
+		{},
+		{NumberOfDocuments: 1},
+		{NumberOfDocuments: 300, LatestDate: time.Date(2021, 7, 14, 10, 30, 0, 0, time.UTC)},
+		{NumberOfDocuments: 2, LatestDate: time.Date(1950, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{
+			NumberOfDocuments: 4,
+			LatestDate:        time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC),
+			Dates: []time.Time{
+				time.Date(1950, 1, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC),
+				time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC),
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := decodeEdgeMetadata(encodeEdgeMetadata(testCase))
+		assert.NoError(t, err)
+		assert.Equal(t, testCase, actual)
+	}
+
+	// Malformed values
+	for _, value := range [][]byte{nil, {0x80}, {0x01, 0x02, 0x03}} {
+		_, err := decodeEdgeMetadata(value)
+		assert.ErrorIs(t, err, ErrMalformedEdgeMetadata)
+	}
+}
+
+func TestDecodeEdgeMetadataWithoutDates(t *testing.T) {
+
+	// Metadata encoded before the dates were recorded
+	latest := time.Date(2021, 7, 14, 0, 0, 0, 0, time.UTC)
+	value := encodeEdgeMetadata(EdgeMetadata{NumberOfDocuments: 2, LatestDate: latest})
+	value = value[:len(value)-1]
+
+	actual, err := decodeEdgeMetadata(value)
+	assert.NoError(t, err)
+	assert.Equal(t, EdgeMetadata{NumberOfDocuments: 2, LatestDate: latest}, actual)
+}
+
+func TestEdgeMetadataDates(t *testing.T) {
+
+	date1 := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	date2 := time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC)
+	date3 := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	metadata := EdgeMetadata{}
+	for _, date := range []time.Time{date3, date1, {}, date3, date2} {
+		metadata.addDocument(date)
+	}
+
+	assert.Equal(t, 5, metadata.NumberOfDocuments)
+	assert.Equal(t, date3, metadata.LatestDate)
+	assert.Equal(t, []time.Time{date1, date2, date3}, metadata.Dates)
+
+	testCases := []struct {
+		from     time.Time
+		expected time.Time
+		found    bool
+	}{
+		{time.Time{}, date1, true},
+		{date1, date1, true},
+		{date1.Add(time.Hour), date2, true},
+		{date3, date3, true},
+		{date3.Add(time.Hour), time.Time{}, false},
+	}
+
+	for _, testCase := range testCases {
+		actual, found := metadata.EarliestDateFrom(testCase.from)
+		assert.Equal(t, testCase.found, found)
+		assert.Equal(t, testCase.expected, actual)
+	}
+
+	untilTestCases := []struct {
+		until    time.Time
+		expected time.Time
+		found    bool
+	}{
+		{date3.Add(time.Hour), date3, true},
+		{date3, date3, true},
+		{date3.Add(-time.Hour), date2, true},
+		{date1, date1, true},
+		{date1.Add(-time.Hour), time.Time{}, false},
+		{time.Time{}, time.Time{}, false},
+	}
+
+	for _, testCase := range untilTestCases {
+		actual, found := metadata.LatestDateUntil(testCase.until)
+		assert.Equal(t, testCase.found, found)
+		assert.Equal(t, testCase.expected, actual)
+	}
+}
+
+func TestEdgeMetadataMerge(t *testing.T) {
+
+	date1 := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	date2 := time.Date(2022, 2, 1, 0, 0, 0, 0, time.UTC)
+	date3 := time.Date(2022, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	// Merging gives the same metadata as adding the documents one at a time
+	expected := EdgeMetadata{}
+	first, second := EdgeMetadata{}, EdgeMetadata{}
+	for idx, date := range []time.Time{date3, date1, {}, date3, date2, date1} {
+		expected.addDocument(date)
+		if idx%2 == 0 {
+			first.addDocument(date)
+		} else {
+			second.addDocument(date)
+		}
+	}
+
+	first.merge(second)
+	assert.Equal(t, expected, first)
+
+	// Metadata without dates
+	metadata := EdgeMetadata{NumberOfDocuments: 2, LatestDate: date2, Dates: []time.Time{date2}}
+	metadata.merge(EdgeMetadata{NumberOfDocuments: 3})
+	assert.Equal(t, EdgeMetadata{NumberOfDocuments: 5, LatestDate: date2, Dates: []time.Time{date2}},
+		metadata)
+}
@@ -85,6 +85,11 @@ func (e *Entity) AddDocument(id string) {
 	e.LinkedDocumentIds.Add(id)
 }
 
+// RemoveDocument linked to the entity.
+func (e *Entity) RemoveDocument(id string) {
+	e.LinkedDocumentIds.Remove(id)
+}
+
 // HasDocument returns true if the entity has a linked document with the given ID.
 func (e *Entity) HasDocument(id string) bool {
 	return e.LinkedDocumentIds.Has(id)
@@ -0,0 +1,288 @@
+// Existence checks of entities dominate some workloads, e.g. searching for a watchlist of many
+// entity IDs where most of them aren't in the graph. A miss in a Pebble store costs a lookup in
+// each level of the LSM tree (or an iteration for the edges of a unipartite store).
+//
+// Two mechanisms reduce the cost of the misses:
+//
+//   - The SSTables of the Pebble stores hold bloom filters, so that a table without the key is
+//     usually skipped without reading its data blocks.
+//   - An existenceIndex is an in-memory bloom filter of the entity IDs in a store, which is built
+//     when the store is opened and updated as entities are added. An ID that isn't in the index
+//     definitely isn't in the store, so the store isn't read at all.
+//
+// A bloom filter has false positives, but no false negatives. An entity that is removed from a
+// store stays in the index, which only means that a check of it reads the store.
+
+package graphstore
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/bloom"
+)
+
+const (
+	pebbleBloomBitsPerKey      = 10      // Bits per key of the SSTable bloom filters (~1% false positives)
+	existenceIndexBitsPerId    = 10      // Bits per entity ID of the in-memory index
+	existenceIndexHashes       = 7       // Number of hashes (optimal for 10 bits per ID)
+	existenceIndexMinCapacity  = 1 << 16 // Minimum number of IDs of a layer of the index
+	existenceIndexMaxCapacity  = 1 << 30 // Maximum number of IDs of a layer of the index
+	existenceIndexBitsPerWord  = 64      // Bits in each word of a layer
+	existenceIndexInitialRatio = 2       // Capacity of the first layer relative to the IDs at open
+)
+
+// pebbleLevelOptions holds the bloom filter policy of the SSTables of a Pebble store.
+func pebbleLevelOptions() []pebble.LevelOptions {
+	return []pebble.LevelOptions{{
+		FilterPolicy: bloom.FilterPolicy(pebbleBloomBitsPerKey),
+		FilterType:   pebble.TableFilter,
+	}}
+}
+
+// bloomLayer is a fixed-size bloom filter.
+type bloomLayer struct {
+	bits     []uint64 // Bit array
+	capacity int      // Number of IDs that can be added before the false positive rate rises
+	count    int      // Number of IDs added
+}
+
+// newBloomLayer for the number of IDs.
+func newBloomLayer(capacity int) *bloomLayer {
+	numberBits := capacity * existenceIndexBitsPerId
+	numberWords := (numberBits + existenceIndexBitsPerWord - 1) / existenceIndexBitsPerWord
+
+	return &bloomLayer{
+		bits:     make([]uint64, numberWords),
+		capacity: capacity,
+	}
+}
+
+// positions of the bits of the hash using double hashing.
+func (b *bloomLayer) positions(h uint64, fn func(word int, mask uint64) bool) bool {
+	numberBits := uint64(len(b.bits) * existenceIndexBitsPerWord)
+	h1 := h & 0xffffffff
+	h2 := (h >> 32) | 1
+
+	for i := uint64(0); i < existenceIndexHashes; i++ {
+		bit := (h1 + i*h2) % numberBits
+		if !fn(int(bit/existenceIndexBitsPerWord), 1<<(bit%existenceIndexBitsPerWord)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// add the hash to the layer.
+func (b *bloomLayer) add(h uint64) {
+	b.positions(h, func(word int, mask uint64) bool {
+		b.bits[word] |= mask
+		return true
+	})
+	b.count++
+}
+
+// mayContain returns false if the hash definitely hasn't been added to the layer.
+func (b *bloomLayer) mayContain(h uint64) bool {
+	return b.positions(h, func(word int, mask uint64) bool {
+		return b.bits[word]&mask != 0
+	})
+}
+
+// An existenceIndex is a scalable bloom filter of entity IDs. When a layer is full a new layer of
+// double the capacity is added, so the false positive rate stays low as a store grows. A nil index
+// may contain every ID.
+type existenceIndex struct {
+	mu     sync.RWMutex
+	layers []*bloomLayer
+}
+
+// newExistenceIndex for the expected number of entity IDs.
+func newExistenceIndex(expectedIds int) *existenceIndex {
+	capacity := expectedIds * existenceIndexInitialRatio
+	if capacity < existenceIndexMinCapacity {
+		capacity = existenceIndexMinCapacity
+	}
+	if capacity > existenceIndexMaxCapacity {
+		capacity = existenceIndexMaxCapacity
+	}
+
+	return &existenceIndex{
+		layers: []*bloomLayer{newBloomLayer(capacity)},
+	}
+}
+
+// hashId of an entity.
+func hashId(id string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(id))
+	return h.Sum64()
+}
+
+// add the entity ID to the index.
+func (e *existenceIndex) add(id string) {
+	if e == nil {
+		return
+	}
+
+	h := hashId(id)
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	// An ID that may already be present isn't counted again (e.g. the source of several edges)
+	if e.layersMayContain(h) {
+		return
+	}
+
+	last := e.layers[len(e.layers)-1]
+	if last.count >= last.capacity {
+		capacity := last.capacity * 2
+		if capacity > existenceIndexMaxCapacity {
+			capacity = existenceIndexMaxCapacity
+		}
+		last = newBloomLayer(capacity)
+		e.layers = append(e.layers, last)
+	}
+
+	last.add(h)
+}
+
+// mayContain returns false if the entity ID is definitely not in the store.
+func (e *existenceIndex) mayContain(id string) bool {
+	if e == nil {
+		return true
+	}
+
+	h := hashId(id)
+
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	return e.layersMayContain(h)
+}
+
+// layersMayContain returns true if any of the layers may contain the hash.
+func (e *existenceIndex) layersMayContain(h uint64) bool {
+	for _, layer := range e.layers {
+		if layer.mayContain(h) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// sizeBytes of the index's bit arrays.
+func (e *existenceIndex) sizeBytes() int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+
+	size := 0
+	for _, layer := range e.layers {
+		size += len(layer.bits) * 8
+	}
+	return size
+}
+
+// entityIdsWithPrefix calls the function with the entity ID of each key with the prefix.
+func entityIdsWithPrefix(db *pebble.DB, prefix string, keyToId func([]byte) (string, error),
+	fn func(string)) error {
+
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: []byte(prefix + separator),
+		UpperBound: []byte(prefix + separatorPlusOne),
+	})
+
+	var errDuringIteration error
+	for iter.First(); iter.Valid() && errDuringIteration == nil; iter.Next() {
+		var id string
+		id, errDuringIteration = keyToId(iter.Key())
+		if errDuringIteration == nil {
+			fn(id)
+		}
+	}
+
+	if err := iter.Close(); err != nil {
+		return err
+	}
+
+	return errDuringIteration
+}
+
+// buildExistenceIndex of the entity IDs of the keys with the prefixes. The store is read twice,
+// first to size the index and then to populate it.
+func buildExistenceIndex(db *pebble.DB, prefixes map[string]func([]byte) (string, error)) (
+	*existenceIndex, error) {
+
+	start := time.Now()
+
+	numberIds := 0
+	for prefix, keyToId := range prefixes {
+		if err := entityIdsWithPrefix(db, prefix, keyToId, func(string) { numberIds++ }); err != nil {
+			return nil, err
+		}
+	}
+
+	index := newExistenceIndex(numberIds)
+	for prefix, keyToId := range prefixes {
+		if err := entityIdsWithPrefix(db, prefix, keyToId, index.add); err != nil {
+			return nil, err
+		}
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Int("numberOfKeys", numberIds).
+		Int("sizeBytes", index.sizeBytes()).
+		Str("timeTaken", time.Since(start).String()).
+		Msg("Built the entity existence index")
+
+	return index, nil
+}
+
+// sourceOfEdgeKey returns the source entity ID of a unipartite edge key.
+func sourceOfEdgeKey(key []byte) (string, error) {
+	src, _, err := pebbleKeyToEdge(key)
+	return src, err
+}
+
+// openExistenceIndex of the entities of the bipartite store, unless the tuning disables it.
+func (p *PebbleBipartiteGraphStore) openExistenceIndex(tuning PebbleTuning) error {
+	if tuning.DisableExistenceIndex {
+		return nil
+	}
+
+	index, err := buildExistenceIndex(p.db, map[string]func([]byte) (string, error){
+		entityPrefix: pebbleKeyToEntityId,
+	})
+	if err != nil {
+		return err
+	}
+
+	p.index = index
+	return nil
+}
+
+// openExistenceIndex of the entities of the unipartite store (the nodes and the sources of the
+// edges), unless the tuning disables it.
+func (p *PebbleUnipartiteGraphStore) openExistenceIndex(tuning PebbleTuning) error {
+	if tuning.DisableExistenceIndex {
+		return nil
+	}
+
+	index, err := buildExistenceIndex(p.db, map[string]func([]byte) (string, error){
+		nodePrefix: pebbleKeyToNode,
+		edgePrefix: sourceOfEdgeKey,
+	})
+	if err != nil {
+		return err
+	}
+
+	p.index = index
+	return nil
+}
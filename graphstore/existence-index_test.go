@@ -0,0 +1,120 @@
+package graphstore
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExistenceIndex(t *testing.T) {
+
+	// A nil index may contain every ID
+	var disabled *existenceIndex
+	disabled.add("e-1")
+	assert.True(t, disabled.mayContain("e-1"))
+
+	// The index grows beyond its initial capacity without false negatives
+	index := newExistenceIndex(0)
+	numberIds := existenceIndexMinCapacity * 3
+	for i := 0; i < numberIds; i++ {
+		index.add(fmt.Sprintf("e-%d", i))
+	}
+	assert.True(t, len(index.layers) > 1)
+
+	for i := 0; i < numberIds; i++ {
+		assert.True(t, index.mayContain(fmt.Sprintf("e-%d", i)))
+	}
+
+	// Few IDs that weren't added may be present
+	falsePositives := 0
+	for i := 0; i < numberIds; i++ {
+		if index.mayContain(fmt.Sprintf("x-%d", i)) {
+			falsePositives++
+		}
+	}
+	assert.Less(t, float64(falsePositives)/float64(numberIds), 0.05)
+
+	// Adding the same ID again doesn't fill the index
+	count := index.layers[len(index.layers)-1].count
+	index.add("e-1")
+	assert.Equal(t, count, index.layers[len(index.layers)-1].count)
+}
+
+func TestPebbleExistenceIndex(t *testing.T) {
+
+	// Unipartite store
+	folder := createTempPebbleFolder(t)
+	defer deleteTempPebbleFolder(t, folder)
+
+	store, err := NewPebbleUnipartiteGraphStore(folder)
+	assert.NoError(t, err)
+	assert.NotNil(t, store.index)
+	assert.NoError(t, store.AddDirected("e-1", "e-2"))
+	assert.NoError(t, store.AddEntity("e-3"))
+	assert.NoError(t, store.Finalise())
+	assert.NoError(t, store.Close())
+
+	replica, err := NewReadOnlyPebbleUnipartiteGraphStore(folder)
+	assert.NoError(t, err)
+	for _, id := range []string{"e-1", "e-2", "e-3"} {
+		assert.True(t, replica.index.mayContain(id))
+
+		found, err := replica.HasEntity(id)
+		assert.NoError(t, err)
+		assert.True(t, found)
+	}
+
+	found, err := replica.HasEntity("e-4")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	_, err = replica.HasEntity("")
+	assert.ErrorIs(t, err, ErrEmptyEntityId)
+	assert.NoError(t, replica.Close())
+
+	// The index can be disabled
+	unindexed, err := NewReadOnlyPebbleUnipartiteGraphStoreWithTuning(folder,
+		PebbleTuning{DisableExistenceIndex: true})
+	assert.NoError(t, err)
+	assert.Nil(t, unindexed.index)
+	found, err = unindexed.HasEntity("e-1")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.NoError(t, unindexed.Close())
+
+	// Bipartite store in bulk ingest mode
+	bipartiteFolder := createTempPebbleFolder(t)
+	defer deleteTempPebbleFolder(t, bipartiteFolder)
+
+	bipartite, err := NewPebbleBipartiteGraphStore(bipartiteFolder)
+	assert.NoError(t, err)
+	assert.NoError(t, bipartite.EnableBulkIngest(1<<20))
+
+	e1, err := NewEntity("e-1", "Person", map[string]string{})
+	assert.NoError(t, err)
+	assert.NoError(t, bipartite.AddEntity(e1))
+	assert.NoError(t, bipartite.Finalise())
+
+	found, err = bipartite.HasEntityWithId("e-1")
+	assert.NoError(t, err)
+	assert.True(t, found)
+	assert.NoError(t, bipartite.Close())
+
+	bipartite, err = NewReadOnlyPebbleBipartiteGraphStore(bipartiteFolder)
+	assert.NoError(t, err)
+	assert.True(t, bipartite.index.mayContain("e-1"))
+
+	found, err = bipartite.HasEntityWithId("e-1")
+	assert.NoError(t, err)
+	assert.True(t, found)
+
+	found, err = bipartite.HasEntityWithId("e-2")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	found, err = bipartite.HasEntity(&Entity{Id: "e-2"})
+	assert.NoError(t, err)
+	assert.False(t, found)
+	assert.NoError(t, bipartite.Close())
+}
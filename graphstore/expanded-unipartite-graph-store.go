@@ -0,0 +1,481 @@
+// The entities inside a collapsed chain are removed from a pruned unipartite store, so reading the
+// store directly would show an edge between the ends of each chain that isn't in the original
+// graph. An ExpandedUnipartiteGraphStore wraps the pruned store and answers every read as if the
+// chains were still there: the collapsed edges are hidden and the entities inside the chains, their
+// edges and the metadata of the edges are served from the ChainIndex. A write that touches a chain
+// (e.g. removing an entity inside it) first releases the chain back into the pruned store, so the
+// write applies to the original graph. A path search can instead walk the pruned store directly,
+// stepping over each chain in one go (see bfs).
+
+package graphstore
+
+import (
+	"errors"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/set"
+)
+
+// chainPosition of an entity inside a collapsed chain.
+type chainPosition struct {
+	chain Chain // Chain the entity is inside
+	index int   // Index of the entity in the Via entities of the chain
+}
+
+// neighbours of the entity inside the chain, i.e. the entities before and after it.
+func (c chainPosition) neighbours() (string, string) {
+
+	before := c.chain.From
+	if c.index > 0 {
+		before = c.chain.Via[c.index-1]
+	}
+
+	after := c.chain.To
+	if c.index < len(c.chain.Via)-1 {
+		after = c.chain.Via[c.index+1]
+	}
+
+	return before, after
+}
+
+// ExpandedUnipartiteGraphStore is a view of a pruned unipartite store with its chains expanded.
+type ExpandedUnipartiteGraphStore struct {
+	store      UnipartiteGraphStore     // Pruned store
+	chains     *ChainIndex              // Chains collapsed into edges of the pruned store
+	interior   map[string]chainPosition // Entity inside a chain to its position
+	chainsFile string                   // File the chains are written to when they change (optional)
+}
+
+// NewExpandedUnipartiteGraphStore wrapping the pruned store given the chains collapsed into its
+// edges.
+func NewExpandedUnipartiteGraphStore(store UnipartiteGraphStore,
+	chains *ChainIndex) (*ExpandedUnipartiteGraphStore, error) {
+
+	if store == nil {
+		return nil, ErrWrappedStoreIsNil
+	}
+
+	if chains == nil {
+		return nil, ErrChainIndexIsNil
+	}
+
+	interior := map[string]chainPosition{}
+	for _, chain := range chains.Chains() {
+		for idx, entityId := range chain.Via {
+			interior[entityId] = chainPosition{chain: chain, index: idx}
+		}
+	}
+
+	return &ExpandedUnipartiteGraphStore{
+		store:    store,
+		chains:   chains,
+		interior: interior,
+	}, nil
+}
+
+// Pruned store wrapped by the view.
+func (e *ExpandedUnipartiteGraphStore) Pruned() UnipartiteGraphStore {
+	return e.store
+}
+
+// Chains collapsed into edges of the pruned store.
+func (e *ExpandedUnipartiteGraphStore) Chains() *ChainIndex {
+	return e.chains
+}
+
+// SetChainsFile the chains are written to when a write releases a chain, so that the file stays in
+// step with the pruned store.
+func (e *ExpandedUnipartiteGraphStore) SetChainsFile(filepath string) {
+	e.chainsFile = filepath
+}
+
+// ChainThrough returns the chain that the entity is inside and the index of the entity in its Via
+// entities. False is returned if the entity isn't inside a chain.
+func (e *ExpandedUnipartiteGraphStore) ChainThrough(entityId string) (Chain, int, bool) {
+	position, found := e.interior[entityId]
+	return position.chain, position.index, found
+}
+
+// chainsTouching the entities, i.e. the chains they are inside, the chain collapsed into the edge
+// between a pair of them and, if ends is true, the chains they are at the end of.
+func (e *ExpandedUnipartiteGraphStore) chainsTouching(ends bool, entityIds ...string) []Chain {
+
+	chains := []Chain{}
+	for _, entityId := range entityIds {
+		if position, found := e.interior[entityId]; found {
+			chains = append(chains, position.chain)
+		}
+
+		if ends {
+			chains = append(chains, e.chains.from(entityId)...)
+		}
+	}
+
+	if len(entityIds) == 2 {
+		if chain, found := e.chains.Get(entityIds[0], entityIds[1]); found {
+			chains = append(chains, chain)
+		}
+	}
+
+	return chains
+}
+
+// restoreEdgeMetadata of an edge in both directions by adding its documents to the store again.
+func restoreEdgeMetadata(store UnipartiteGraphStore, entity1 string, entity2 string,
+	metadata *EdgeMetadata) error {
+
+	if metadata == nil {
+		return nil
+	}
+
+	// A document with a repeated or unknown date is added without a date
+	dates := append([]time.Time{}, metadata.Dates...)
+	if len(dates) == 0 && !metadata.LatestDate.IsZero() {
+		dates = append(dates, metadata.LatestDate)
+	}
+	for len(dates) < metadata.NumberOfDocuments {
+		dates = append(dates, time.Time{})
+	}
+
+	for _, pair := range [][2]string{{entity1, entity2}, {entity2, entity1}} {
+		for _, date := range dates {
+			if err := store.AddEdgeDocument(pair[0], pair[1], date); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// release the chains back into the pruned store, i.e. replace each collapsed edge with the entities
+// inside the chain and their edges, and forget the chains.
+func (e *ExpandedUnipartiteGraphStore) release(chains []Chain) error {
+
+	released := 0
+	for _, chain := range chains {
+		if _, found := e.chains.Get(chain.From, chain.To); !found {
+			continue
+		}
+
+		err := e.store.RemoveEdge(chain.From, chain.To)
+		if err != nil && !errors.Is(err, ErrEdgeNotFound) {
+			return err
+		}
+
+		route := chain.route()
+		for idx := 1; idx < len(route); idx++ {
+			if err := e.store.AddUndirected(route[idx-1], route[idx]); err != nil {
+				return err
+			}
+
+			if err := restoreEdgeMetadata(e.store, route[idx-1], route[idx], chain.edge(idx-1)); err != nil {
+				return err
+			}
+		}
+
+		e.chains.remove(chain)
+		for _, entityId := range chain.Via {
+			delete(e.interior, entityId)
+		}
+		released++
+	}
+
+	if released == 0 || len(e.chainsFile) == 0 {
+		return nil
+	}
+
+	return WriteChainIndex(e.chains, e.chainsFile)
+}
+
+// expandEnd replaces the other ends of the chains of an entity at the end of chains with the first
+// entity inside each chain.
+func (e *ExpandedUnipartiteGraphStore) expandEnd(entityId string,
+	entityIds *set.Set[string]) *set.Set[string] {
+
+	expanded := set.NewSet[string]()
+	for _, adjEntityId := range entityIds.ToSlice() {
+		if chain, found := e.chains.Get(entityId, adjEntityId); found {
+			expanded.Add(chain.Via[0])
+		} else {
+			expanded.Add(adjEntityId)
+		}
+	}
+
+	return expanded
+}
+
+// AddEntity to the pruned store. An entity inside a chain is already in the graph.
+func (e *ExpandedUnipartiteGraphStore) AddEntity(entity string) error {
+	if _, found := e.interior[entity]; found {
+		return nil
+	}
+	return e.store.AddEntity(entity)
+}
+
+func (e *ExpandedUnipartiteGraphStore) AddDirected(src string, dst string) error {
+	if err := e.release(e.chainsTouching(false, src, dst)); err != nil {
+		return err
+	}
+	return e.store.AddDirected(src, dst)
+}
+
+func (e *ExpandedUnipartiteGraphStore) AddUndirected(entity1 string, entity2 string) error {
+	if err := e.release(e.chainsTouching(false, entity1, entity2)); err != nil {
+		return err
+	}
+	return e.store.AddUndirected(entity1, entity2)
+}
+
+func (e *ExpandedUnipartiteGraphStore) AddEdgeDocument(src string, dst string, date time.Time) error {
+	if err := e.release(e.chainsTouching(false, src, dst)); err != nil {
+		return err
+	}
+	return e.store.AddEdgeDocument(src, dst, date)
+}
+
+func (e *ExpandedUnipartiteGraphStore) AddEdgeMetadata(src string, dst string, documents EdgeMetadata) error {
+	if err := e.release(e.chainsTouching(false, src, dst)); err != nil {
+		return err
+	}
+	return e.store.AddEdgeMetadata(src, dst, documents)
+}
+
+func (e *ExpandedUnipartiteGraphStore) SetEdgeMetadata(src string, dst string, metadata *EdgeMetadata) error {
+	if err := e.release(e.chainsTouching(false, src, dst)); err != nil {
+		return err
+	}
+	return e.store.SetEdgeMetadata(src, dst, metadata)
+}
+
+// RemoveEntity from the graph, releasing the chains it is inside or at the end of first.
+func (e *ExpandedUnipartiteGraphStore) RemoveEntity(entity string) error {
+	if err := e.release(e.chainsTouching(true, entity)); err != nil {
+		return err
+	}
+	return e.store.RemoveEntity(entity)
+}
+
+// RemoveEdge from the graph, releasing the chain it is inside first.
+func (e *ExpandedUnipartiteGraphStore) RemoveEdge(entity1 string, entity2 string) error {
+	if err := e.release(e.chainsTouching(false, entity1, entity2)); err != nil {
+		return err
+	}
+	return e.store.RemoveEdge(entity1, entity2)
+}
+
+// Clear the pruned store and forget the chains.
+func (e *ExpandedUnipartiteGraphStore) Clear() error {
+	e.chains = NewChainIndex()
+	e.interior = map[string]chainPosition{}
+	return e.store.Clear()
+}
+
+func (e *ExpandedUnipartiteGraphStore) Close() error {
+	return e.store.Close()
+}
+
+func (e *ExpandedUnipartiteGraphStore) Destroy() error {
+	return e.store.Destroy()
+}
+
+// EdgeExists returns true if the entities are connected in the original graph.
+func (e *ExpandedUnipartiteGraphStore) EdgeExists(entity1 string, entity2 string) (bool, error) {
+
+	if position, found := e.interior[entity1]; found {
+		before, after := position.neighbours()
+		return entity2 == before || entity2 == after, nil
+	}
+
+	if position, found := e.interior[entity2]; found {
+		before, after := position.neighbours()
+		return entity1 == before || entity1 == after, nil
+	}
+
+	if _, found := e.chains.Get(entity1, entity2); found {
+		return false, nil
+	}
+
+	return e.store.EdgeExists(entity1, entity2)
+}
+
+// EdgeMetadata of an edge of the original graph. The metadata of an edge inside a chain is the same
+// in both directions.
+func (e *ExpandedUnipartiteGraphStore) EdgeMetadata(src string, dst string) (*EdgeMetadata, error) {
+
+	for _, pair := range [][2]string{{src, dst}, {dst, src}} {
+		position, found := e.interior[pair[0]]
+		if !found {
+			continue
+		}
+
+		before, after := position.neighbours()
+		switch pair[1] {
+		case before:
+			return position.chain.edge(position.index), nil
+		case after:
+			return position.chain.edge(position.index + 1), nil
+		default:
+			return nil, nil
+		}
+	}
+
+	if _, found := e.chains.Get(src, dst); found {
+		return nil, nil
+	}
+
+	return e.store.EdgeMetadata(src, dst)
+}
+
+// EntityIds of the original graph.
+func (e *ExpandedUnipartiteGraphStore) EntityIds() (*set.Set[string], error) {
+
+	entityIds, err := e.store.EntityIds()
+	if err != nil {
+		return nil, err
+	}
+
+	expanded := set.NewSet[string]().Union(entityIds)
+	for entityId := range e.interior {
+		expanded.Add(entityId)
+	}
+
+	return expanded, nil
+}
+
+func (e *ExpandedUnipartiteGraphStore) EntityIdsAdjacentTo(entityId string) (*set.Set[string], error) {
+
+	if position, found := e.interior[entityId]; found {
+		before, after := position.neighbours()
+		return set.NewPopulatedSet(before, after), nil
+	}
+
+	entityIds, err := e.store.EntityIdsAdjacentTo(entityId)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.expandEnd(entityId, entityIds), nil
+}
+
+func (e *ExpandedUnipartiteGraphStore) EntityIdsConnectedTo(entityId string) (*set.Set[string], error) {
+
+	if position, found := e.interior[entityId]; found {
+		before, after := position.neighbours()
+		return set.NewPopulatedSet(before, after), nil
+	}
+
+	entityIds, err := e.store.EntityIdsConnectedTo(entityId)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.expandEnd(entityId, entityIds), nil
+}
+
+func (e *ExpandedUnipartiteGraphStore) Finalise() error {
+	return e.store.Finalise()
+}
+
+func (e *ExpandedUnipartiteGraphStore) HasEntity(entityId string) (bool, error) {
+	if _, found := e.interior[entityId]; found {
+		return true, nil
+	}
+	return e.store.HasEntity(entityId)
+}
+
+func (e *ExpandedUnipartiteGraphStore) NumberEntities() (int, error) {
+	n, err := e.store.NumberEntities()
+	if err != nil {
+		return 0, err
+	}
+	return n + len(e.interior), nil
+}
+
+// NewEdgeIterator streams the edges of the original graph, i.e. the edges of the pruned store
+// other than the collapsed edges followed by the edges inside the chains (in both directions).
+func (e *ExpandedUnipartiteGraphStore) NewEdgeIterator() (EdgeIterator, error) {
+
+	iter, err := e.store.NewEdgeIterator()
+	if err != nil {
+		return nil, err
+	}
+
+	chainEdges := []Edge{}
+	for _, chain := range e.chains.Chains() {
+		route := chain.route()
+		for idx := 1; idx < len(route); idx++ {
+			chainEdges = append(chainEdges,
+				Edge{V1: route[idx-1], V2: route[idx]},
+				Edge{V1: route[idx], V2: route[idx-1]})
+		}
+	}
+
+	expanded := &expandedEdgeIterator{
+		iter:       iter,
+		chains:     e.chains,
+		chainEdges: chainEdges,
+	}
+
+	if err := expanded.fill(); err != nil {
+		iter.Close()
+		return nil, err
+	}
+
+	return expanded, nil
+}
+
+// expandedEdgeIterator skips the collapsed edges of the pruned store and then returns the edges
+// inside the chains.
+type expandedEdgeIterator struct {
+	iter       EdgeIterator // Iterator of the pruned store
+	chains     *ChainIndex  // Collapsed chains
+	chainEdges []Edge       // Edges inside the chains still to return
+	next       *Edge        // Next edge of the pruned store (nil if there isn't one)
+}
+
+// fill the next edge of the pruned store that isn't a collapsed edge.
+func (it *expandedEdgeIterator) fill() error {
+
+	it.next = nil
+	for it.iter.HasNext() {
+		edge, err := it.iter.NextEdge()
+		if err != nil {
+			return err
+		}
+
+		if _, found := it.chains.Get(edge.V1, edge.V2); !found {
+			it.next = &edge
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// HasNext returns true if the iterator has another edge.
+func (it *expandedEdgeIterator) HasNext() bool {
+	return it.next != nil || len(it.chainEdges) > 0
+}
+
+// NextEdge from the iterator.
+func (it *expandedEdgeIterator) NextEdge() (Edge, error) {
+
+	if it.next != nil {
+		edge := *it.next
+		return edge, it.fill()
+	}
+
+	if len(it.chainEdges) == 0 {
+		return Edge{}, ErrEdgeNotFound
+	}
+
+	edge := it.chainEdges[0]
+	it.chainEdges = it.chainEdges[1:]
+	return edge, nil
+}
+
+// Close the iterator of the pruned store.
+func (it *expandedEdgeIterator) Close() error {
+	return it.iter.Close()
+}
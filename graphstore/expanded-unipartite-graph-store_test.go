@@ -0,0 +1,198 @@
+package graphstore
+
+import (
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/set"
+	"github.com/stretchr/testify/assert"
+)
+
+// edgesOf the unipartite store sorted by their entities.
+func edgesOf(t *testing.T, graph UnipartiteGraphStore) []Edge {
+
+	edges := []Edge{}
+	assert.NoError(t, forEachEdge(graph, func(edge Edge) (bool, error) {
+		edges = append(edges, edge)
+		return true, nil
+	}))
+
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].V1 == edges[j].V1 {
+			return edges[i].V2 < edges[j].V2
+		}
+		return edges[i].V1 < edges[j].V1
+	})
+
+	return edges
+}
+
+// checkExpandedUnipartite using the graph:
+//
+//	a -- b -- c -- d -- e -- f
+//	     |                   |
+//	     g ----------------- h -- i
+func checkExpandedUnipartite(t *testing.T, graph UnipartiteGraphStore) {
+
+	original := NewInMemoryUnipartiteGraphStore()
+	edges := []Edge{
+		{V1: "a", V2: "b"},
+		{V1: "b", V2: "c"},
+		{V1: "c", V2: "d"},
+		{V1: "d", V2: "e"},
+		{V1: "e", V2: "f"},
+		{V1: "f", V2: "h"},
+		{V1: "b", V2: "g"},
+		{V1: "g", V2: "h"},
+		{V1: "h", V2: "i"},
+	}
+	assert.NoError(t, BuildFromEdgeList(graph, edges))
+	assert.NoError(t, BuildFromEdgeList(original, edges))
+
+	// Two documents link c and d
+	date := time.Date(2022, 8, 1, 0, 0, 0, 0, time.UTC)
+	for _, pair := range [][]string{{"c", "d"}, {"d", "c"}} {
+		assert.NoError(t, graph.AddEdgeDocument(pair[0], pair[1], date))
+		assert.NoError(t, graph.AddEdgeDocument(pair[0], pair[1], date.AddDate(0, 1, 0)))
+	}
+
+	result, err := PruneUnipartite(graph, UnipartitePruneOptions{MinChainLength: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.ChainsCollapsed)
+
+	expanded, err := NewExpandedUnipartiteGraphStore(graph, result.Chains)
+	assert.NoError(t, err)
+	assert.Equal(t, graph, expanded.Pruned())
+
+	// The entities inside the chain are in the view, but not the pruned store
+	entityIds, err := expanded.EntityIds()
+	assert.NoError(t, err)
+	assert.Equal(t, set.NewPopulatedSet("a", "b", "c", "d", "e", "f", "g", "h", "i"), entityIds)
+
+	n, err := expanded.NumberEntities()
+	assert.NoError(t, err)
+	assert.Equal(t, 9, n)
+
+	for _, entityId := range []string{"c", "h"} {
+		found, err := expanded.HasEntity(entityId)
+		assert.NoError(t, err)
+		assert.True(t, found)
+	}
+
+	// The ends of the chain are connected to the chain rather than each other
+	connected, err := expanded.EntityIdsConnectedTo("b")
+	assert.NoError(t, err)
+	assert.Equal(t, set.NewPopulatedSet("a", "c", "g"), connected)
+
+	adjacent, err := expanded.EntityIdsAdjacentTo("h")
+	assert.NoError(t, err)
+	assert.Equal(t, set.NewPopulatedSet("f", "g", "i"), adjacent)
+
+	connected, err = expanded.EntityIdsConnectedTo("d")
+	assert.NoError(t, err)
+	assert.Equal(t, set.NewPopulatedSet("c", "e"), connected)
+
+	for _, pair := range [][]string{{"b", "c"}, {"c", "b"}, {"d", "e"}, {"f", "h"}, {"g", "h"}} {
+		exists, err := expanded.EdgeExists(pair[0], pair[1])
+		assert.NoError(t, err)
+		assert.True(t, exists, pair)
+	}
+
+	for _, pair := range [][]string{{"b", "h"}, {"h", "b"}, {"c", "e"}, {"a", "c"}} {
+		exists, err := expanded.EdgeExists(pair[0], pair[1])
+		assert.NoError(t, err)
+		assert.False(t, exists, pair)
+	}
+
+	// The metadata of the edges inside the chain is kept
+	for _, pair := range [][]string{{"c", "d"}, {"d", "c"}} {
+		metadata, err := expanded.EdgeMetadata(pair[0], pair[1])
+		assert.NoError(t, err)
+		assert.Equal(t, 2, metadata.NumberOfDocuments)
+		assert.Equal(t, date.AddDate(0, 1, 0), metadata.LatestDate)
+	}
+
+	metadata, err := expanded.EdgeMetadata("b", "h")
+	assert.NoError(t, err)
+	assert.Nil(t, metadata)
+
+	// The edges are those of the original graph
+	assert.Equal(t, edgesOf(t, original), edgesOf(t, expanded))
+
+	pairs, err := CountConnectedPairs(expanded)
+	assert.NoError(t, err)
+	assert.Equal(t, len(edges), pairs)
+
+	// Removing an entity inside the chain releases the chain into the pruned store
+	chainsFile := filepath.Join(t.TempDir(), "chains.json")
+	expanded.SetChainsFile(chainsFile)
+
+	assert.NoError(t, expanded.RemoveEntity("f"))
+	assert.NoError(t, original.RemoveEntity("f"))
+	assert.Equal(t, edgesOf(t, original), edgesOf(t, expanded))
+	assert.Equal(t, 0, expanded.Chains().Len())
+
+	found, err := expanded.HasEntity("f")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	metadata, err = graph.EdgeMetadata("c", "d")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, metadata.NumberOfDocuments)
+
+	chains, err := ReadChainIndex(chainsFile)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, chains.Len())
+}
+
+func TestExpandedUnipartiteGraphStoreRemoveEdge(t *testing.T) {
+
+	//	a -- b -- c -- d -- e -- f
+	original := NewInMemoryUnipartiteGraphStore()
+	graph := NewInMemoryUnipartiteGraphStore()
+	edges := []Edge{
+		{V1: "a", V2: "b"},
+		{V1: "b", V2: "c"},
+		{V1: "c", V2: "d"},
+		{V1: "d", V2: "e"},
+		{V1: "e", V2: "f"},
+	}
+	assert.NoError(t, BuildFromEdgeList(graph, edges))
+	assert.NoError(t, BuildFromEdgeList(original, edges))
+
+	result, err := PruneUnipartite(graph, UnipartitePruneOptions{MinChainLength: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.ChainsCollapsed)
+
+	expanded, err := NewExpandedUnipartiteGraphStore(graph, result.Chains)
+	assert.NoError(t, err)
+
+	// The collapsed edge isn't an edge of the original graph
+	assert.ErrorIs(t, expanded.RemoveEdge("a", "f"), ErrEdgeNotFound)
+	assert.Equal(t, edgesOf(t, original), edgesOf(t, expanded))
+
+	assert.NoError(t, expanded.RemoveEdge("c", "d"))
+	assert.NoError(t, original.RemoveEdge("c", "d"))
+	assert.Equal(t, edgesOf(t, original), edgesOf(t, expanded))
+}
+
+func TestExpandedUnipartiteGraphStore(t *testing.T) {
+
+	// In-memory store
+	checkExpandedUnipartite(t, NewInMemoryUnipartiteGraphStore())
+
+	// Pebble store
+	pebbleUnipartite := newUnipartitePebbleStore(t)
+	defer cleanUpUnipartitePebbleStore(t, pebbleUnipartite)
+
+	checkExpandedUnipartite(t, pebbleUnipartite)
+
+	// Invalid arguments
+	_, err := NewExpandedUnipartiteGraphStore(nil, NewChainIndex())
+	assert.ErrorIs(t, err, ErrWrappedStoreIsNil)
+
+	_, err = NewExpandedUnipartiteGraphStore(NewInMemoryUnipartiteGraphStore(), nil)
+	assert.ErrorIs(t, err, ErrChainIndexIsNil)
+}
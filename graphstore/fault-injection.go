@@ -0,0 +1,471 @@
+package graphstore
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cdclaxton/shortest-path-web-app/set"
+)
+
+// The fault injection stores wrap another store and fail every Nth operation and/or add latency to
+// every operation. They are used in tests and in a staging mode to check that store errors are
+// handled gracefully. Clear, Close and Destroy are never failed, so that the stores can be tidied up.
+
+var (
+	ErrInjectedFault      = errors.New("injected fault")
+	ErrInvalidFaultConfig = errors.New("invalid fault injection configuration")
+	ErrWrappedStoreIsNil  = errors.New("wrapped store is nil")
+)
+
+// FaultConfig configures the faults injected into a store.
+type FaultConfig struct {
+	FailEvery int `json:"failEvery"` // Fail every Nth operation (zero never fails)
+	LatencyMs int `json:"latencyMs"` // Latency (in milliseconds) added to every operation
+}
+
+// validate the fault configuration.
+func (f FaultConfig) validate() error {
+	if f.FailEvery < 0 || f.LatencyMs < 0 {
+		return ErrInvalidFaultConfig
+	}
+	return nil
+}
+
+// faultInjector counts the operations and decides whether each one fails.
+type faultInjector struct {
+	config     FaultConfig
+	operations int64 // Number of operations (accessed atomically)
+}
+
+// inject the latency and return an error if the operation should fail.
+func (f *faultInjector) inject() error {
+
+	if f.config.LatencyMs > 0 {
+		time.Sleep(time.Duration(f.config.LatencyMs) * time.Millisecond)
+	}
+
+	n := atomic.AddInt64(&f.operations, 1)
+	if f.config.FailEvery > 0 && n%int64(f.config.FailEvery) == 0 {
+		return ErrInjectedFault
+	}
+
+	return nil
+}
+
+// newFaultInjector after validating the configuration.
+func newFaultInjector(config FaultConfig, storeType string) (*faultInjector, error) {
+
+	if err := config.validate(); err != nil {
+		return nil, err
+	}
+
+	logging.Logger.Warn().
+		Str(logging.ComponentField, componentName).
+		Str("storeType", storeType).
+		Int("failEvery", config.FailEvery).
+		Int("latencyMs", config.LatencyMs).
+		Msg("Injecting faults into the graph store")
+
+	return &faultInjector{config: config}, nil
+}
+
+// FaultyBipartiteGraphStore wraps a bipartite store and injects faults.
+type FaultyBipartiteGraphStore struct {
+	store  BipartiteGraphStore
+	faults *faultInjector
+}
+
+// NewFaultyBipartiteGraphStore wrapping the store.
+func NewFaultyBipartiteGraphStore(store BipartiteGraphStore,
+	config FaultConfig) (*FaultyBipartiteGraphStore, error) {
+
+	if store == nil {
+		return nil, ErrWrappedStoreIsNil
+	}
+
+	faults, err := newFaultInjector(config, "bipartite")
+	if err != nil {
+		return nil, err
+	}
+
+	return &FaultyBipartiteGraphStore{
+		store:  store,
+		faults: faults,
+	}, nil
+}
+
+func (f *FaultyBipartiteGraphStore) AddEntity(entity Entity) error {
+	if err := f.faults.inject(); err != nil {
+		return err
+	}
+	return f.store.AddEntity(entity)
+}
+
+func (f *FaultyBipartiteGraphStore) AddDocument(document Document) error {
+	if err := f.faults.inject(); err != nil {
+		return err
+	}
+	return f.store.AddDocument(document)
+}
+
+func (f *FaultyBipartiteGraphStore) AddLink(link Link) error {
+	if err := f.faults.inject(); err != nil {
+		return err
+	}
+	return f.store.AddLink(link)
+}
+
+func (f *FaultyBipartiteGraphStore) RemoveEntity(entityId string) error {
+	if err := f.faults.inject(); err != nil {
+		return err
+	}
+	return f.store.RemoveEntity(entityId)
+}
+
+func (f *FaultyBipartiteGraphStore) RemoveDocument(documentId string) error {
+	if err := f.faults.inject(); err != nil {
+		return err
+	}
+	return f.store.RemoveDocument(documentId)
+}
+
+func (f *FaultyBipartiteGraphStore) RemoveLink(link Link) error {
+	if err := f.faults.inject(); err != nil {
+		return err
+	}
+	return f.store.RemoveLink(link)
+}
+
+func (f *FaultyBipartiteGraphStore) Clear() error {
+	return f.store.Clear()
+}
+
+func (f *FaultyBipartiteGraphStore) Close() error {
+	return f.store.Close()
+}
+
+func (f *FaultyBipartiteGraphStore) Destroy() error {
+	return f.store.Destroy()
+}
+
+func (f *FaultyBipartiteGraphStore) Equal(other BipartiteGraphStore) (bool, error) {
+	if err := f.faults.inject(); err != nil {
+		return false, err
+	}
+	return f.store.Equal(other)
+}
+
+func (f *FaultyBipartiteGraphStore) Finalise() error {
+	if err := f.faults.inject(); err != nil {
+		return err
+	}
+	return f.store.Finalise()
+}
+
+func (f *FaultyBipartiteGraphStore) AddAlias(aliasId string, entityId string) error {
+	if err := f.faults.inject(); err != nil {
+		return err
+	}
+	return f.store.AddAlias(aliasId, entityId)
+}
+
+func (f *FaultyBipartiteGraphStore) ResolveAlias(entityId string) (string, error) {
+	if err := f.faults.inject(); err != nil {
+		return "", err
+	}
+	return f.store.ResolveAlias(entityId)
+}
+
+func (f *FaultyBipartiteGraphStore) GetEntity(entityId string) (*Entity, error) {
+	if err := f.faults.inject(); err != nil {
+		return nil, err
+	}
+	return f.store.GetEntity(entityId)
+}
+
+func (f *FaultyBipartiteGraphStore) GetDocument(documentId string) (*Document, error) {
+	if err := f.faults.inject(); err != nil {
+		return nil, err
+	}
+	return f.store.GetDocument(documentId)
+}
+
+func (f *FaultyBipartiteGraphStore) HasDocument(document *Document) (bool, error) {
+	if err := f.faults.inject(); err != nil {
+		return false, err
+	}
+	return f.store.HasDocument(document)
+}
+
+func (f *FaultyBipartiteGraphStore) HasEntity(entity *Entity) (bool, error) {
+	if err := f.faults.inject(); err != nil {
+		return false, err
+	}
+	return f.store.HasEntity(entity)
+}
+
+func (f *FaultyBipartiteGraphStore) HasEntityWithId(entityId string) (bool, error) {
+	if err := f.faults.inject(); err != nil {
+		return false, err
+	}
+	return f.store.HasEntityWithId(entityId)
+}
+
+func (f *FaultyBipartiteGraphStore) NewDocumentIdIterator() (DocumentIdIterator, error) {
+	if err := f.faults.inject(); err != nil {
+		return nil, err
+	}
+
+	iter, err := f.store.NewDocumentIdIterator()
+	if err != nil {
+		return nil, err
+	}
+
+	return &faultyDocumentIdIterator{iter: iter, faults: f.faults}, nil
+}
+
+func (f *FaultyBipartiteGraphStore) NewEntityIdIterator() (EntityIdIterator, error) {
+	if err := f.faults.inject(); err != nil {
+		return nil, err
+	}
+
+	iter, err := f.store.NewEntityIdIterator()
+	if err != nil {
+		return nil, err
+	}
+
+	return &faultyEntityIdIterator{iter: iter, faults: f.faults}, nil
+}
+
+func (f *FaultyBipartiteGraphStore) NumberOfEntities() (int, error) {
+	if err := f.faults.inject(); err != nil {
+		return 0, err
+	}
+	return f.store.NumberOfEntities()
+}
+
+func (f *FaultyBipartiteGraphStore) NumberOfDocuments() (int, error) {
+	if err := f.faults.inject(); err != nil {
+		return 0, err
+	}
+	return f.store.NumberOfDocuments()
+}
+
+// faultyDocumentIdIterator injects faults when getting the next document ID.
+type faultyDocumentIdIterator struct {
+	iter   DocumentIdIterator
+	faults *faultInjector
+}
+
+func (f *faultyDocumentIdIterator) nextDocumentId() (string, error) {
+	if err := f.faults.inject(); err != nil {
+		return "", err
+	}
+	return f.iter.nextDocumentId()
+}
+
+func (f *faultyDocumentIdIterator) hasNext() bool {
+	return f.iter.hasNext()
+}
+
+// faultyEntityIdIterator injects faults when getting the next entity ID.
+type faultyEntityIdIterator struct {
+	iter   EntityIdIterator
+	faults *faultInjector
+}
+
+func (f *faultyEntityIdIterator) nextEntityId() (string, error) {
+	if err := f.faults.inject(); err != nil {
+		return "", err
+	}
+	return f.iter.nextEntityId()
+}
+
+func (f *faultyEntityIdIterator) hasNext() bool {
+	return f.iter.hasNext()
+}
+
+// FaultyUnipartiteGraphStore wraps a unipartite store and injects faults.
+type FaultyUnipartiteGraphStore struct {
+	store  UnipartiteGraphStore
+	faults *faultInjector
+}
+
+// NewFaultyUnipartiteGraphStore wrapping the store.
+func NewFaultyUnipartiteGraphStore(store UnipartiteGraphStore,
+	config FaultConfig) (*FaultyUnipartiteGraphStore, error) {
+
+	if store == nil {
+		return nil, ErrWrappedStoreIsNil
+	}
+
+	faults, err := newFaultInjector(config, "unipartite")
+	if err != nil {
+		return nil, err
+	}
+
+	return &FaultyUnipartiteGraphStore{
+		store:  store,
+		faults: faults,
+	}, nil
+}
+
+func (f *FaultyUnipartiteGraphStore) AddEntity(entity string) error {
+	if err := f.faults.inject(); err != nil {
+		return err
+	}
+	return f.store.AddEntity(entity)
+}
+
+func (f *FaultyUnipartiteGraphStore) AddDirected(src string, dst string) error {
+	if err := f.faults.inject(); err != nil {
+		return err
+	}
+	return f.store.AddDirected(src, dst)
+}
+
+func (f *FaultyUnipartiteGraphStore) AddUndirected(entity1 string, entity2 string) error {
+	if err := f.faults.inject(); err != nil {
+		return err
+	}
+	return f.store.AddUndirected(entity1, entity2)
+}
+
+func (f *FaultyUnipartiteGraphStore) AddEdgeDocument(src string, dst string, date time.Time) error {
+	if err := f.faults.inject(); err != nil {
+		return err
+	}
+	return f.store.AddEdgeDocument(src, dst, date)
+}
+
+func (f *FaultyUnipartiteGraphStore) AddEdgeMetadata(src string, dst string, documents EdgeMetadata) error {
+	if err := f.faults.inject(); err != nil {
+		return err
+	}
+	return f.store.AddEdgeMetadata(src, dst, documents)
+}
+
+func (f *FaultyUnipartiteGraphStore) SetEdgeMetadata(src string, dst string, metadata *EdgeMetadata) error {
+	if err := f.faults.inject(); err != nil {
+		return err
+	}
+	return f.store.SetEdgeMetadata(src, dst, metadata)
+}
+
+func (f *FaultyUnipartiteGraphStore) RemoveEntity(entity string) error {
+	if err := f.faults.inject(); err != nil {
+		return err
+	}
+	return f.store.RemoveEntity(entity)
+}
+
+func (f *FaultyUnipartiteGraphStore) RemoveEdge(entity1 string, entity2 string) error {
+	if err := f.faults.inject(); err != nil {
+		return err
+	}
+	return f.store.RemoveEdge(entity1, entity2)
+}
+
+func (f *FaultyUnipartiteGraphStore) Clear() error {
+	return f.store.Clear()
+}
+
+func (f *FaultyUnipartiteGraphStore) Close() error {
+	return f.store.Close()
+}
+
+func (f *FaultyUnipartiteGraphStore) Destroy() error {
+	return f.store.Destroy()
+}
+
+func (f *FaultyUnipartiteGraphStore) EdgeExists(entity1 string, entity2 string) (bool, error) {
+	if err := f.faults.inject(); err != nil {
+		return false, err
+	}
+	return f.store.EdgeExists(entity1, entity2)
+}
+
+func (f *FaultyUnipartiteGraphStore) EdgeMetadata(src string, dst string) (*EdgeMetadata, error) {
+	if err := f.faults.inject(); err != nil {
+		return nil, err
+	}
+	return f.store.EdgeMetadata(src, dst)
+}
+
+func (f *FaultyUnipartiteGraphStore) EntityIds() (*set.Set[string], error) {
+	if err := f.faults.inject(); err != nil {
+		return nil, err
+	}
+	return f.store.EntityIds()
+}
+
+func (f *FaultyUnipartiteGraphStore) EntityIdsAdjacentTo(entityId string) (*set.Set[string], error) {
+	if err := f.faults.inject(); err != nil {
+		return nil, err
+	}
+	return f.store.EntityIdsAdjacentTo(entityId)
+}
+
+func (f *FaultyUnipartiteGraphStore) EntityIdsConnectedTo(entityId string) (*set.Set[string], error) {
+	if err := f.faults.inject(); err != nil {
+		return nil, err
+	}
+	return f.store.EntityIdsConnectedTo(entityId)
+}
+
+func (f *FaultyUnipartiteGraphStore) Finalise() error {
+	if err := f.faults.inject(); err != nil {
+		return err
+	}
+	return f.store.Finalise()
+}
+
+func (f *FaultyUnipartiteGraphStore) NewEdgeIterator() (EdgeIterator, error) {
+	if err := f.faults.inject(); err != nil {
+		return nil, err
+	}
+
+	iter, err := f.store.NewEdgeIterator()
+	if err != nil {
+		return nil, err
+	}
+
+	return &faultyEdgeIterator{iter: iter, faults: f.faults}, nil
+}
+
+// faultyEdgeIterator injects faults when getting the next edge.
+type faultyEdgeIterator struct {
+	iter   EdgeIterator
+	faults *faultInjector
+}
+
+func (f *faultyEdgeIterator) HasNext() bool {
+	return f.iter.HasNext()
+}
+
+func (f *faultyEdgeIterator) NextEdge() (Edge, error) {
+	if err := f.faults.inject(); err != nil {
+		return Edge{}, err
+	}
+	return f.iter.NextEdge()
+}
+
+func (f *faultyEdgeIterator) Close() error {
+	return f.iter.Close()
+}
+
+func (f *FaultyUnipartiteGraphStore) HasEntity(entityId string) (bool, error) {
+	if err := f.faults.inject(); err != nil {
+		return false, err
+	}
+	return f.store.HasEntity(entityId)
+}
+
+func (f *FaultyUnipartiteGraphStore) NumberEntities() (int, error) {
+	if err := f.faults.inject(); err != nil {
+		return 0, err
+	}
+	return f.store.NumberEntities()
+}
@@ -0,0 +1,99 @@
+package graphstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewFaultyGraphStores(t *testing.T) {
+
+	_, err := NewFaultyBipartiteGraphStore(nil, FaultConfig{})
+	assert.ErrorIs(t, err, ErrWrappedStoreIsNil)
+
+	_, err = NewFaultyBipartiteGraphStore(NewInMemoryBipartiteGraphStore(), FaultConfig{FailEvery: -1})
+	assert.ErrorIs(t, err, ErrInvalidFaultConfig)
+
+	_, err = NewFaultyUnipartiteGraphStore(nil, FaultConfig{})
+	assert.ErrorIs(t, err, ErrWrappedStoreIsNil)
+
+	_, err = NewFaultyUnipartiteGraphStore(NewInMemoryUnipartiteGraphStore(), FaultConfig{LatencyMs: -1})
+	assert.ErrorIs(t, err, ErrInvalidFaultConfig)
+}
+
+func TestFaultyUnipartiteGraphStore(t *testing.T) {
+
+	store, err := NewFaultyUnipartiteGraphStore(NewInMemoryUnipartiteGraphStore(),
+		FaultConfig{FailEvery: 3})
+	assert.NoError(t, err)
+
+	// Every third operation fails
+	assert.NoError(t, store.AddUndirected("e-1", "e-2"))
+	assert.NoError(t, store.AddEntity("e-3"))
+	assert.ErrorIs(t, store.AddEntity("e-4"), ErrInjectedFault)
+
+	found, err := store.HasEntity("e-1")
+	assert.NoError(t, err)
+	assert.True(t, found)
+
+	_, err = store.EdgeExists("e-1", "e-2")
+	assert.NoError(t, err)
+
+	_, err = store.EntityIdsAdjacentTo("e-1")
+	assert.ErrorIs(t, err, ErrInjectedFault)
+
+	// The edge iterator also injects faults
+	iter, err := store.NewEdgeIterator()
+	assert.NoError(t, err)
+	assert.True(t, iter.HasNext())
+	_, err = iter.NextEdge()
+	assert.NoError(t, err)
+	_, err = iter.NextEdge()
+	assert.ErrorIs(t, err, ErrInjectedFault)
+	assert.NoError(t, iter.Close())
+
+	// The store can always be cleared
+	assert.NoError(t, store.Clear())
+}
+
+func TestFaultyBipartiteGraphStore(t *testing.T) {
+
+	store, err := NewFaultyBipartiteGraphStore(NewInMemoryBipartiteGraphStore(),
+		FaultConfig{FailEvery: 2})
+	assert.NoError(t, err)
+
+	entity, err := NewEntity("e-1", "Person", map[string]string{})
+	assert.NoError(t, err)
+
+	assert.NoError(t, store.AddEntity(entity))
+
+	_, err = store.GetEntity("e-1")
+	assert.ErrorIs(t, err, ErrInjectedFault)
+
+	retrieved, err := store.GetEntity("e-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "e-1", retrieved.Id)
+
+	// The iterator also injects faults
+	iter, err := store.NewEntityIdIterator()
+	assert.ErrorIs(t, err, ErrInjectedFault)
+	assert.Nil(t, iter)
+
+	iter, err = store.NewEntityIdIterator()
+	assert.NoError(t, err)
+	assert.True(t, iter.hasNext())
+	_, err = iter.nextEntityId()
+	assert.ErrorIs(t, err, ErrInjectedFault)
+}
+
+func TestFaultyGraphStoreLatency(t *testing.T) {
+
+	store, err := NewFaultyUnipartiteGraphStore(NewInMemoryUnipartiteGraphStore(),
+		FaultConfig{LatencyMs: 20})
+	assert.NoError(t, err)
+
+	start := time.Now()
+	assert.NoError(t, store.AddEntity("e-1"))
+	assert.True(t, time.Since(start) >= 20*time.Millisecond)
+}
@@ -0,0 +1,98 @@
+package graphstore
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cockroachdb/pebble"
+)
+
+// healthCheckEntityId is the entity ID looked up to check that a store can be read. It doesn't
+// matter whether the entity exists.
+const healthCheckEntityId = "health-check"
+
+var ErrStoreNotReadable = errors.New("store is not readable")
+
+// A readableChecker reads from its backend directly. It is implemented by the stores whose
+// existence checks may not read the backend, e.g. because of an existence index.
+type readableChecker interface {
+	checkReadable() error
+}
+
+// checkPebbleReadable reads the health check key from the Pebble database.
+func checkPebbleReadable(db *pebble.DB) error {
+	_, closer, err := db.Get([]byte(healthCheckEntityId))
+	if err == pebble.ErrNotFound {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	return closer.Close()
+}
+
+// checkReadable reads from the Pebble database of the bipartite store.
+func (p *PebbleBipartiteGraphStore) checkReadable() error {
+	return checkPebbleReadable(p.db)
+}
+
+// checkReadable reads from the Pebble database of the unipartite store.
+func (p *PebbleUnipartiteGraphStore) checkReadable() error {
+	return checkPebbleReadable(p.db)
+}
+
+// CheckBipartiteReadable performs a trivial read of the bipartite store. A store that has been
+// closed fails the check rather than panicking.
+func CheckBipartiteReadable(store BipartiteGraphStore) (err error) {
+
+	if store == nil {
+		return ErrStoreNotReadable
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrStoreNotReadable, r)
+		}
+	}()
+
+	if checker, ok := store.(readableChecker); ok {
+		if err := checker.checkReadable(); err != nil {
+			return fmt.Errorf("%w: %v", ErrStoreNotReadable, err)
+		}
+		return nil
+	}
+
+	if _, err := store.HasEntityWithId(healthCheckEntityId); err != nil {
+		return fmt.Errorf("%w: %v", ErrStoreNotReadable, err)
+	}
+
+	return nil
+}
+
+// CheckUnipartiteReadable performs a trivial read of the unipartite store. A store that has been
+// closed fails the check rather than panicking.
+func CheckUnipartiteReadable(store UnipartiteGraphStore) (err error) {
+
+	if store == nil {
+		return ErrStoreNotReadable
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("%w: %v", ErrStoreNotReadable, r)
+		}
+	}()
+
+	if checker, ok := store.(readableChecker); ok {
+		if err := checker.checkReadable(); err != nil {
+			return fmt.Errorf("%w: %v", ErrStoreNotReadable, err)
+		}
+		return nil
+	}
+
+	if _, err := store.HasEntity(healthCheckEntityId); err != nil {
+		return fmt.Errorf("%w: %v", ErrStoreNotReadable, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,36 @@
+package graphstore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckReadable(t *testing.T) {
+
+	// In-memory stores
+	assert.NoError(t, CheckBipartiteReadable(NewInMemoryBipartiteGraphStore()))
+	assert.NoError(t, CheckUnipartiteReadable(NewInMemoryUnipartiteGraphStore()))
+
+	assert.ErrorIs(t, CheckBipartiteReadable(nil), ErrStoreNotReadable)
+	assert.ErrorIs(t, CheckUnipartiteReadable(nil), ErrStoreNotReadable)
+
+	// Pebble stores are readable until they are closed
+	bipartiteFolder := createTempPebbleFolder(t)
+	defer deleteTempPebbleFolder(t, bipartiteFolder)
+
+	bipartite, err := NewPebbleBipartiteGraphStore(bipartiteFolder)
+	assert.NoError(t, err)
+	assert.NoError(t, CheckBipartiteReadable(bipartite))
+	assert.NoError(t, bipartite.Close())
+	assert.ErrorIs(t, CheckBipartiteReadable(bipartite), ErrStoreNotReadable)
+
+	unipartiteFolder := createTempPebbleFolder(t)
+	defer deleteTempPebbleFolder(t, unipartiteFolder)
+
+	unipartite, err := NewPebbleUnipartiteGraphStore(unipartiteFolder)
+	assert.NoError(t, err)
+	assert.NoError(t, CheckUnipartiteReadable(unipartite))
+	assert.NoError(t, unipartite.Close())
+	assert.ErrorIs(t, CheckUnipartiteReadable(unipartite), ErrStoreNotReadable)
+}
@@ -9,6 +9,9 @@ type InMemoryBipartiteGraphStore struct {
 
 	muDocuments sync.RWMutex        // Mutex for the documents
 	documents   map[string]Document // Document ID to Document mapping
+
+	muAliases sync.RWMutex      // Mutex for the aliases
+	aliases   map[string]string // Alias ID to canonical entity ID mapping
 }
 
 // NewInMemoryBipartiteGraphStore creates a new in-memory bipartite graph store.
@@ -16,6 +19,7 @@ func NewInMemoryBipartiteGraphStore() *InMemoryBipartiteGraphStore {
 	return &InMemoryBipartiteGraphStore{
 		entities:  map[string]Entity{},
 		documents: map[string]Document{},
+		aliases:   map[string]string{},
 	}
 }
 
@@ -58,8 +62,44 @@ func (store *InMemoryBipartiteGraphStore) Equal(other BipartiteGraphStore) (bool
 	return bipartiteGraphStoresEqual(store, other)
 }
 
-// GetEntity given its ID.
+// AddAlias of an entity to the in-memory graph store (replaces the existing alias if the alias ID
+// already exists).
+func (store *InMemoryBipartiteGraphStore) AddAlias(aliasId string, entityId string) error {
+
+	// Preconditions
+	if err := validateAlias(aliasId, entityId); err != nil {
+		return err
+	}
+
+	store.muAliases.Lock()
+	store.aliases[aliasId] = entityId
+	store.muAliases.Unlock()
+
+	return nil
+}
+
+// lookupAlias returns the canonical entity ID of the alias ID and true if the alias exists.
+func (store *InMemoryBipartiteGraphStore) lookupAlias(aliasId string) (string, bool, error) {
+
+	store.muAliases.RLock()
+	entityId, found := store.aliases[aliasId]
+	store.muAliases.RUnlock()
+
+	return entityId, found, nil
+}
+
+// ResolveAlias returns the canonical ID of the entity ID (unchanged if the ID isn't an alias).
+func (store *InMemoryBipartiteGraphStore) ResolveAlias(entityId string) (string, error) {
+	return resolveAlias(entityId, store.HasEntityWithId, store.lookupAlias)
+}
+
+// GetEntity given its ID or one of its aliases.
 func (store *InMemoryBipartiteGraphStore) GetEntity(entityId string) (*Entity, error) {
+	return getEntityOrAlias(entityId, store.getEntity, store.ResolveAlias)
+}
+
+// getEntity given its ID (ignoring aliases).
+func (store *InMemoryBipartiteGraphStore) getEntity(entityId string) (*Entity, error) {
 
 	// Preconditions
 	err := ValidateEntityId(entityId)
@@ -105,6 +145,10 @@ func (store *InMemoryBipartiteGraphStore) AddLink(link Link) error {
 		return ErrEntityIdIsEmpty
 	}
 
+	if err := ValidateLinkDirection(link.Direction); err != nil {
+		return err
+	}
+
 	err = ValidateDocumentId(link.DocumentId)
 	if err != nil {
 		return ErrDocumentIdIsEmpty
@@ -130,7 +174,99 @@ func (store *InMemoryBipartiteGraphStore) AddLink(link Link) error {
 
 	// Make the connections
 	entity.AddDocument(link.DocumentId)
-	document.AddEntity(link.EntityId)
+	document.AddDirectedEntity(link.EntityId, link.Direction)
+	store.documents[link.DocumentId] = document
+
+	return nil
+}
+
+// RemoveEntity and its links to documents from the graph store.
+func (store *InMemoryBipartiteGraphStore) RemoveEntity(entityId string) error {
+
+	// Preconditions
+	if err := ValidateEntityId(entityId); err != nil {
+		return ErrEntityIdIsEmpty
+	}
+
+	store.muEntities.Lock()
+	store.muDocuments.Lock()
+	defer store.muDocuments.Unlock()
+	defer store.muEntities.Unlock()
+
+	entity, found := store.entities[entityId]
+	if !found {
+		return ErrEntityNotFound
+	}
+
+	// Remove the links from the documents
+	for _, documentId := range entity.LinkedDocumentIds.ToSlice() {
+		if document, found := store.documents[documentId]; found {
+			document.RemoveEntity(entityId)
+			store.documents[documentId] = document
+		}
+	}
+
+	delete(store.entities, entityId)
+	return nil
+}
+
+// RemoveDocument and its links to entities from the graph store.
+func (store *InMemoryBipartiteGraphStore) RemoveDocument(documentId string) error {
+
+	// Preconditions
+	if err := ValidateDocumentId(documentId); err != nil {
+		return ErrDocumentIdIsEmpty
+	}
+
+	store.muEntities.Lock()
+	store.muDocuments.Lock()
+	defer store.muDocuments.Unlock()
+	defer store.muEntities.Unlock()
+
+	document, found := store.documents[documentId]
+	if !found {
+		return ErrDocumentNotFound
+	}
+
+	// Remove the links from the entities
+	for _, entityId := range document.LinkedEntityIds.ToSlice() {
+		if entity, found := store.entities[entityId]; found {
+			entity.RemoveDocument(documentId)
+		}
+	}
+
+	delete(store.documents, documentId)
+	return nil
+}
+
+// RemoveLink between an entity and a document. The direction of the link is ignored.
+func (store *InMemoryBipartiteGraphStore) RemoveLink(link Link) error {
+
+	// Preconditions
+	if err := ValidateEntityId(link.EntityId); err != nil {
+		return ErrEntityIdIsEmpty
+	}
+
+	if err := ValidateDocumentId(link.DocumentId); err != nil {
+		return ErrDocumentIdIsEmpty
+	}
+
+	store.muEntities.Lock()
+	store.muDocuments.Lock()
+	defer store.muDocuments.Unlock()
+	defer store.muEntities.Unlock()
+
+	entity, found := store.entities[link.EntityId]
+	if !found || !entity.HasDocument(link.DocumentId) {
+		return ErrLinkNotFound
+	}
+
+	entity.RemoveDocument(link.DocumentId)
+
+	if document, found := store.documents[link.DocumentId]; found {
+		document.RemoveEntity(link.EntityId)
+		store.documents[link.DocumentId] = document
+	}
 
 	return nil
 }
@@ -160,10 +296,13 @@ func (store *InMemoryBipartiteGraphStore) Clear() error {
 
 	store.muEntities.Lock()
 	store.muDocuments.Lock()
+	store.muAliases.Lock()
 
 	store.entities = map[string]Entity{}
 	store.documents = map[string]Document{}
+	store.aliases = map[string]string{}
 
+	store.muAliases.Unlock()
 	store.muDocuments.Unlock()
 	store.muEntities.Unlock()
 
@@ -203,7 +342,7 @@ func (store *InMemoryBipartiteGraphStore) HasDocument(document *Document) (bool,
 func (store *InMemoryBipartiteGraphStore) HasEntity(entity *Entity) (bool, error) {
 
 	// Try to retrieve the entity from the graph store
-	retrieved, err := store.GetEntity(entity.Id)
+	retrieved, err := store.getEntity(entity.Id)
 	if err != nil {
 		return false, err
 	}
@@ -218,7 +357,7 @@ func (store *InMemoryBipartiteGraphStore) HasEntity(entity *Entity) (bool, error
 func (store *InMemoryBipartiteGraphStore) HasEntityWithId(entityId string) (bool, error) {
 
 	// Try to retrieve the entity from the graph store
-	retrieved, err := store.GetEntity(entityId)
+	retrieved, err := store.getEntity(entityId)
 	if err == ErrEntityNotFound {
 		return false, nil
 	} else if err != nil {
@@ -1,24 +1,104 @@
 package graphstore
 
 import (
+	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/cdclaxton/shortest-path-web-app/logging"
 	"github.com/cdclaxton/shortest-path-web-app/set"
 )
 
+// Approximate number of bytes used by the in-memory unipartite store for each item, excluding the
+// bytes of the entity IDs. The estimates include the overhead of the maps and sets.
+const (
+	vertexOverheadBytes   = 120 // Entry in the vertices map and its empty set
+	edgeOverheadBytes     = 40  // Entry in a set of destinations or sources
+	metadataOverheadBytes = 100 // Entry in the metadata map
+	metadataDateBytes     = 24  // Date in the metadata of an edge
+)
+
+var (
+	ErrMemoryBudgetExceeded = errors.New("memory budget of the in-memory unipartite store exceeded")
+	ErrInvalidMemoryBudget  = errors.New("invalid memory budget")
+)
+
 // InMemoryUnipartiteGraphStore is a thread-safe in-memory unipartite graph store.
 type InMemoryUnipartiteGraphStore struct {
 	mu       sync.RWMutex
-	vertices map[string]*set.Set[string]
+	vertices map[string]*set.Set[string] // Source to destinations
+	incoming map[string]*set.Set[string] // Destination to sources of directed edges
+	metadata map[Edge]EdgeMetadata       // Metadata of the edges
+
+	memoryBudget   int64 // Maximum estimated number of bytes (zero for no limit)
+	estimatedBytes int64 // Estimated number of bytes used by the graph
 }
 
 // Instantiate an in-memory unipartite graph store.
 func NewInMemoryUnipartiteGraphStore() *InMemoryUnipartiteGraphStore {
 	return &InMemoryUnipartiteGraphStore{
 		vertices: map[string]*set.Set[string]{},
+		incoming: map[string]*set.Set[string]{},
+		metadata: map[Edge]EdgeMetadata{},
+	}
+}
+
+// SetMemoryBudget of the store, i.e. the maximum estimated number of bytes the graph can use. Once
+// the budget is exceeded, adding to the graph fails with ErrMemoryBudgetExceeded rather than the
+// process running out of memory. A budget of zero removes the limit.
+func (graph *InMemoryUnipartiteGraphStore) SetMemoryBudget(bytes int64) error {
+
+	// Precondition
+	if bytes < 0 {
+		return ErrInvalidMemoryBudget
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Int64("memoryBudgetBytes", bytes).
+		Msg("Setting the memory budget of the in-memory unipartite store")
+
+	graph.mu.Lock()
+	graph.memoryBudget = bytes
+	graph.mu.Unlock()
+
+	return nil
+}
+
+// EstimatedBytes used by the graph.
+func (graph *InMemoryUnipartiteGraphStore) EstimatedBytes() int64 {
+	graph.mu.RLock()
+	defer graph.mu.RUnlock()
+
+	return graph.estimatedBytes
+}
+
+// reserve the bytes for a new item in the graph. The lock must be held by the caller.
+func (graph *InMemoryUnipartiteGraphStore) reserve(bytes int64) error {
+
+	if graph.memoryBudget > 0 && graph.estimatedBytes+bytes > graph.memoryBudget {
+		return fmt.Errorf("%w: %v bytes used of %v bytes", ErrMemoryBudgetExceeded,
+			graph.estimatedBytes, graph.memoryBudget)
+	}
+
+	graph.estimatedBytes += bytes
+	return nil
+}
+
+// addVertex to the graph if it hasn't been seen before. The lock must be held by the caller.
+func (graph *InMemoryUnipartiteGraphStore) addVertex(entity string) error {
+
+	if _, found := graph.vertices[entity]; found {
+		return nil
+	}
+
+	if err := graph.reserve(int64(vertexOverheadBytes + len(entity))); err != nil {
+		return err
 	}
+
+	graph.vertices[entity] = set.NewSet[string]()
+	return nil
 }
 
 // AddEntity to the in-memory unipartite graph.
@@ -31,17 +111,14 @@ func (graph *InMemoryUnipartiteGraphStore) AddEntity(entity string) error {
 	}
 
 	// If the entity hasn't been seen before, add it to the graph
-	if found, _ := graph.HasEntity(entity); !found {
-		graph.mu.Lock()
-		graph.vertices[entity] = set.NewSet[string]()
-		graph.mu.Unlock()
-	}
+	graph.mu.Lock()
+	defer graph.mu.Unlock()
 
-	return nil
+	return graph.addVertex(entity)
 }
 
-// AddDirected edge between two vertices.
-func (graph *InMemoryUnipartiteGraphStore) AddDirected(src string, dst string) error {
+// addEdge from the source to the destination vertex.
+func (graph *InMemoryUnipartiteGraphStore) addEdge(src string, dst string) error {
 
 	// Preconditions
 	err := ValidateEntityId(src)
@@ -60,13 +137,49 @@ func (graph *InMemoryUnipartiteGraphStore) AddDirected(src string, dst string) e
 
 	// If the source hasn't been seen before, add it to the graph
 	graph.mu.Lock()
-	_, found := graph.vertices[src]
-	if !found {
-		graph.vertices[src] = set.NewSet[string]()
+	defer graph.mu.Unlock()
+
+	if err := graph.addVertex(src); err != nil {
+		return err
 	}
+
 	x := graph.vertices[src]
-	x.Add(dst)
-	graph.mu.Unlock()
+	if !x.Has(dst) {
+		if err := graph.reserve(int64(edgeOverheadBytes + len(dst))); err != nil {
+			return err
+		}
+		x.Add(dst)
+	}
+
+	return nil
+}
+
+// AddDirected edge between two vertices. The destination is added as an entity, so that it is
+// held in the graph even if it has no outgoing edges.
+func (graph *InMemoryUnipartiteGraphStore) AddDirected(src string, dst string) error {
+
+	// Validation of src and dst is performed in the call to addEdge
+	if err := graph.addEdge(src, dst); err != nil {
+		return err
+	}
+
+	graph.mu.Lock()
+	defer graph.mu.Unlock()
+
+	if err := graph.addVertex(dst); err != nil {
+		return err
+	}
+
+	if _, found := graph.incoming[dst]; !found {
+		graph.incoming[dst] = set.NewSet[string]()
+	}
+
+	if !graph.incoming[dst].Has(src) {
+		if err := graph.reserve(int64(edgeOverheadBytes + len(src))); err != nil {
+			return err
+		}
+		graph.incoming[dst].Add(src)
+	}
 
 	return nil
 }
@@ -75,16 +188,169 @@ func (graph *InMemoryUnipartiteGraphStore) AddDirected(src string, dst string) e
 func (graph *InMemoryUnipartiteGraphStore) AddUndirected(v1 string, v2 string) error {
 
 	// Preconditions
-	// Validation of v1 and v2 is performed in the call to AddDirected
+	// Validation of v1 and v2 is performed in the call to addEdge
 
 	// Add the connection v1 ---> v2
-	err := graph.AddDirected(v1, v2)
+	err := graph.addEdge(v1, v2)
 	if err != nil {
 		return err
 	}
 
 	// Add the connection v1 <--- v2
-	return graph.AddDirected(v2, v1)
+	return graph.addEdge(v2, v1)
+}
+
+// AddEdgeDocument records a document linking the source and destination entities in the metadata
+// of the edge. The date of the document is zero if it isn't known.
+func (graph *InMemoryUnipartiteGraphStore) AddEdgeDocument(src string, dst string, date time.Time) error {
+	document := EdgeMetadata{}
+	document.addDocument(date)
+	return graph.AddEdgeMetadata(src, dst, document)
+}
+
+// AddEdgeMetadata records the documents of the metadata (e.g. a batch of documents) linking the
+// source and destination entities in the metadata of the edge.
+func (graph *InMemoryUnipartiteGraphStore) AddEdgeMetadata(src string, dst string,
+	documents EdgeMetadata) error {
+
+	// Preconditions
+	if err := ValidateEntityId(src); err != nil {
+		return err
+	}
+
+	if err := ValidateEntityId(dst); err != nil {
+		return err
+	}
+
+	if src == dst {
+		return ErrSelfLoop
+	}
+
+	edge := Edge{V1: src, V2: dst}
+
+	graph.mu.Lock()
+	defer graph.mu.Unlock()
+
+	metadata, found := graph.metadata[edge]
+	if !found {
+		if err := graph.reserve(int64(metadataOverheadBytes + len(src) + len(dst))); err != nil {
+			return err
+		}
+	}
+
+	newDates := 0
+	for _, date := range documents.Dates {
+		if !metadata.hasDate(date) {
+			newDates++
+		}
+	}
+
+	if newDates > 0 {
+		if err := graph.reserve(int64(metadataDateBytes * newDates)); err != nil {
+			return err
+		}
+	}
+
+	metadata.merge(documents)
+	graph.metadata[edge] = metadata
+
+	return nil
+}
+
+// release the bytes of an item removed from the graph. The lock must be held by the caller.
+func (graph *InMemoryUnipartiteGraphStore) release(bytes int64) {
+	graph.estimatedBytes -= bytes
+	if graph.estimatedBytes < 0 {
+		graph.estimatedBytes = 0
+	}
+}
+
+// removeEdge from the source to the destination, including its metadata. The lock must be held by
+// the caller.
+func (graph *InMemoryUnipartiteGraphStore) removeEdge(src string, dst string) {
+
+	if destinations, found := graph.vertices[src]; found && destinations.Has(dst) {
+		destinations.Remove(dst)
+		graph.release(int64(edgeOverheadBytes + len(dst)))
+	}
+
+	if sources, found := graph.incoming[dst]; found && sources.Has(src) {
+		sources.Remove(src)
+		graph.release(int64(edgeOverheadBytes + len(src)))
+
+		if sources.Len() == 0 {
+			delete(graph.incoming, dst)
+		}
+	}
+
+	if metadata, found := graph.metadata[Edge{V1: src, V2: dst}]; found {
+		delete(graph.metadata, Edge{V1: src, V2: dst})
+		graph.release(int64(metadataOverheadBytes + len(src) + len(dst) +
+			metadataDateBytes*len(metadata.Dates)))
+	}
+}
+
+// RemoveEntity and its edges in either direction from the graph. The entities it was connected to
+// are kept.
+func (graph *InMemoryUnipartiteGraphStore) RemoveEntity(entityId string) error {
+
+	// Preconditions
+	if err := ValidateEntityId(entityId); err != nil {
+		return err
+	}
+
+	graph.mu.Lock()
+	defer graph.mu.Unlock()
+
+	destinations, found := graph.vertices[entityId]
+	if !found {
+		return entityNotFound(entityId)
+	}
+
+	for _, dst := range destinations.ToSlice() {
+		graph.removeEdge(entityId, dst)
+		graph.removeEdge(dst, entityId)
+	}
+
+	if sources, found := graph.incoming[entityId]; found {
+		for _, src := range sources.ToSlice() {
+			graph.removeEdge(src, entityId)
+		}
+	}
+
+	delete(graph.vertices, entityId)
+	graph.release(int64(vertexOverheadBytes + len(entityId)))
+
+	return nil
+}
+
+// RemoveEdge between two entities in both directions, including the metadata of the edges. The
+// entities are kept.
+func (graph *InMemoryUnipartiteGraphStore) RemoveEdge(src string, dst string) error {
+
+	// Preconditions
+	if err := ValidateEntityId(src); err != nil {
+		return err
+	}
+
+	if err := ValidateEntityId(dst); err != nil {
+		return err
+	}
+
+	graph.mu.Lock()
+	defer graph.mu.Unlock()
+
+	forward := graph.vertices[src] != nil && graph.vertices[src].Has(dst)
+	backward := graph.vertices[dst] != nil && graph.vertices[dst].Has(src)
+
+	if !forward && !backward {
+		return ErrEdgeNotFound
+	}
+
+	graph.removeEdge(src, dst)
+	graph.removeEdge(dst, src)
+
+	return nil
 }
 
 // Clear the in-memory unipartite graph store.
@@ -96,6 +362,9 @@ func (graph *InMemoryUnipartiteGraphStore) Clear() error {
 
 	graph.mu.Lock()
 	graph.vertices = map[string]*set.Set[string]{}
+	graph.incoming = map[string]*set.Set[string]{}
+	graph.metadata = map[Edge]EdgeMetadata{}
+	graph.estimatedBytes = 0
 	graph.mu.Unlock()
 
 	return nil
@@ -149,6 +418,74 @@ func (graph *InMemoryUnipartiteGraphStore) EdgeExists(entity1 string, entity2 st
 	return edgeExists, nil
 }
 
+// SetEdgeMetadata replaces the metadata of the edge from the source to the destination entity. Nil
+// metadata removes it.
+func (graph *InMemoryUnipartiteGraphStore) SetEdgeMetadata(src string, dst string,
+	metadata *EdgeMetadata) error {
+
+	// Preconditions
+	if err := ValidateEntityId(src); err != nil {
+		return err
+	}
+
+	if err := ValidateEntityId(dst); err != nil {
+		return err
+	}
+
+	edge := Edge{V1: src, V2: dst}
+
+	graph.mu.Lock()
+	defer graph.mu.Unlock()
+
+	if previous, found := graph.metadata[edge]; found {
+		graph.release(int64(metadataOverheadBytes + len(src) + len(dst) +
+			metadataDateBytes*len(previous.Dates)))
+		delete(graph.metadata, edge)
+	}
+
+	if metadata == nil {
+		return nil
+	}
+
+	if err := graph.reserve(int64(metadataOverheadBytes + len(src) + len(dst) +
+		metadataDateBytes*len(metadata.Dates))); err != nil {
+		return err
+	}
+
+	graph.metadata[edge] = EdgeMetadata{
+		NumberOfDocuments: metadata.NumberOfDocuments,
+		LatestDate:        metadata.LatestDate,
+		Dates:             append([]time.Time{}, metadata.Dates...),
+	}
+
+	return nil
+}
+
+// EdgeMetadata of the edge from the source to the destination entity. If no documents have been
+// recorded for the edge, nil is returned.
+func (graph *InMemoryUnipartiteGraphStore) EdgeMetadata(src string, dst string) (*EdgeMetadata, error) {
+
+	// Preconditions
+	if err := ValidateEntityId(src); err != nil {
+		return nil, err
+	}
+
+	if err := ValidateEntityId(dst); err != nil {
+		return nil, err
+	}
+
+	graph.mu.RLock()
+	metadata, found := graph.metadata[Edge{V1: src, V2: dst}]
+	graph.mu.RUnlock()
+
+	if !found {
+		return nil, nil
+	}
+
+	// The dates are replaced (not modified) when a document is added, so they can be shared
+	return &metadata, nil
+}
+
 // EntityIdsAdjacentTo a given vertex with a given entity ID.
 func (graph *InMemoryUnipartiteGraphStore) EntityIdsAdjacentTo(entityId string) (*set.Set[string], error) {
 
@@ -169,6 +506,25 @@ func (graph *InMemoryUnipartiteGraphStore) EntityIdsAdjacentTo(entityId string)
 	return entityIds, nil
 }
 
+// EntityIdsConnectedTo a given vertex by an edge in either direction.
+func (graph *InMemoryUnipartiteGraphStore) EntityIdsConnectedTo(entityId string) (*set.Set[string], error) {
+
+	adjacent, err := graph.EntityIdsAdjacentTo(entityId)
+	if err != nil {
+		return nil, err
+	}
+
+	graph.mu.RLock()
+	defer graph.mu.RUnlock()
+
+	sources, found := graph.incoming[entityId]
+	if !found {
+		return adjacent, nil
+	}
+
+	return adjacent.Union(sources), nil
+}
+
 // EntityIds held within the graph.
 func (graph *InMemoryUnipartiteGraphStore) EntityIds() (*set.Set[string], error) {
 
@@ -199,6 +555,80 @@ func (graph *InMemoryUnipartiteGraphStore) HasEntity(id string) (bool, error) {
 	return found, nil
 }
 
+// An InMemoryEdgeIterator walks through the edges of the vertices that were in the in-memory store
+// when the iterator was made.
+type InMemoryEdgeIterator struct {
+	graph   *InMemoryUnipartiteGraphStore
+	sources []string // Entity IDs of the vertices
+	index   int      // Index of the next vertex
+	pending []Edge   // Edges of the current vertex that haven't been returned
+}
+
+// fill the pending edges from the next vertex with edges (or without edges).
+func (it *InMemoryEdgeIterator) fill() {
+
+	for len(it.pending) == 0 && it.index < len(it.sources) {
+		src := it.sources[it.index]
+		it.index += 1
+
+		it.graph.mu.RLock()
+		destinations, found := it.graph.vertices[src]
+		if found && destinations.Len() == 0 {
+			it.pending = append(it.pending, Edge{V1: src})
+		} else if found {
+			for _, dst := range destinations.ToSlice() {
+				it.pending = append(it.pending, Edge{V1: src, V2: dst})
+			}
+		}
+		it.graph.mu.RUnlock()
+	}
+}
+
+// HasNext returns true if the iterator has another edge.
+func (it *InMemoryEdgeIterator) HasNext() bool {
+	return len(it.pending) > 0
+}
+
+// NextEdge from the iterator.
+func (it *InMemoryEdgeIterator) NextEdge() (Edge, error) {
+
+	if !it.HasNext() {
+		return Edge{}, errors.New("iterator is exhausted")
+	}
+
+	edge := it.pending[0]
+	it.pending = it.pending[1:]
+	it.fill()
+
+	return edge, nil
+}
+
+// Close the iterator.
+func (it *InMemoryEdgeIterator) Close() error {
+	it.pending = nil
+	it.index = len(it.sources)
+	return nil
+}
+
+// NewEdgeIterator returns an iterator of the edges of the vertices currently in the store.
+func (graph *InMemoryUnipartiteGraphStore) NewEdgeIterator() (EdgeIterator, error) {
+
+	graph.mu.RLock()
+	sources := make([]string, 0, len(graph.vertices))
+	for id := range graph.vertices {
+		sources = append(sources, id)
+	}
+	graph.mu.RUnlock()
+
+	it := &InMemoryEdgeIterator{
+		graph:   graph,
+		sources: sources,
+	}
+	it.fill()
+
+	return it, nil
+}
+
 // NumberEntities in the store.
 func (graph *InMemoryUnipartiteGraphStore) NumberEntities() (int, error) {
 
@@ -0,0 +1,50 @@
+package graphstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInMemoryUnipartiteMemoryBudget(t *testing.T) {
+
+	graph := NewInMemoryUnipartiteGraphStore()
+	assert.ErrorIs(t, graph.SetMemoryBudget(-1), ErrInvalidMemoryBudget)
+
+	// Without a budget, the estimated size still grows
+	assert.NoError(t, graph.AddEntity("e-1"))
+	assert.Equal(t, int64(vertexOverheadBytes+3), graph.EstimatedBytes())
+
+	// Adding the same entity again doesn't use any more memory
+	assert.NoError(t, graph.AddEntity("e-1"))
+	assert.Equal(t, int64(vertexOverheadBytes+3), graph.EstimatedBytes())
+
+	// An undirected edge adds a vertex and two set entries
+	assert.NoError(t, graph.AddUndirected("e-1", "e-2"))
+	expected := int64(2*(vertexOverheadBytes+3) + 2*(edgeOverheadBytes+3))
+	assert.Equal(t, expected, graph.EstimatedBytes())
+
+	assert.NoError(t, graph.AddEdgeDocument("e-1", "e-2", time.Time{}))
+	expected += int64(metadataOverheadBytes + 6)
+	assert.Equal(t, expected, graph.EstimatedBytes())
+
+	assert.NoError(t, graph.AddEdgeDocument("e-1", "e-2", time.Time{}))
+	assert.Equal(t, expected, graph.EstimatedBytes())
+
+	// Exceed the budget
+	assert.NoError(t, graph.SetMemoryBudget(expected+vertexOverheadBytes+3))
+	assert.NoError(t, graph.AddEntity("e-3"))
+	assert.ErrorIs(t, graph.AddEntity("e-4"), ErrMemoryBudgetExceeded)
+	assert.ErrorIs(t, graph.AddUndirected("e-1", "e-3"), ErrMemoryBudgetExceeded)
+	assert.ErrorIs(t, graph.AddDirected("e-2", "e-3"), ErrMemoryBudgetExceeded)
+
+	found, err := graph.HasEntity("e-4")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	// Clearing the graph frees the memory
+	assert.NoError(t, graph.Clear())
+	assert.Equal(t, int64(0), graph.EstimatedBytes())
+	assert.NoError(t, graph.AddEntity("e-4"))
+}
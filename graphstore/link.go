@@ -1,14 +1,67 @@
 package graphstore
 
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Directions of an entity's link to a document. The direction is used to create directed edges
+// in the unipartite graph, e.g. from the payer to the payee of a transaction.
+const (
+	LinkUndirected  = ""            // Entity isn't at either end of a directed edge
+	LinkSource      = "source"      // Entity is the source of the document's directed edges
+	LinkDestination = "destination" // Entity is the destination of the document's directed edges
+)
+
+var ErrInvalidLinkDirection = errors.New("invalid link direction")
+
 // Link represents that an entity ID was found in a document with a given ID.
 type Link struct {
 	EntityId   string
 	DocumentId string
+	Direction  string // Direction of the link (LinkUndirected, LinkSource or LinkDestination)
 }
 
 func NewLink(entityId string, documentId string) Link {
 	return Link{
 		EntityId:   entityId,
 		DocumentId: documentId,
+		Direction:  LinkUndirected,
+	}
+}
+
+// NewDirectedLink between an entity and a document with a given direction.
+func NewDirectedLink(entityId string, documentId string, direction string) (Link, error) {
+
+	// Precondition
+	if err := ValidateLinkDirection(direction); err != nil {
+		return Link{}, err
 	}
+
+	return Link{
+		EntityId:   entityId,
+		DocumentId: documentId,
+		Direction:  direction,
+	}, nil
+}
+
+// ValidateLinkDirection returns an error if the direction isn't recognised.
+func ValidateLinkDirection(direction string) error {
+	switch direction {
+	case LinkUndirected, LinkSource, LinkDestination:
+		return nil
+	}
+
+	return fmt.Errorf("%w: %v", ErrInvalidLinkDirection, direction)
+}
+
+// ParseLinkDirection from a value in a data file, e.g. 'Source' or ' destination '.
+func ParseLinkDirection(value string) (string, error) {
+	direction := strings.ToLower(strings.TrimSpace(value))
+	if err := ValidateLinkDirection(direction); err != nil {
+		return LinkUndirected, err
+	}
+
+	return direction, nil
 }
@@ -15,7 +15,13 @@
 //
 // Document-entity links are stored as:
 //
-//   del#<document ID>#<entity ID> = nil
+//   del#<document ID>#<entity ID> = <direction>
+//
+// where the direction is nil for an undirected link.
+//
+// Aliases of entities are stored as:
+//
+//   a#<alias ID> = <canonical entity ID>
 
 package graphstore
 
@@ -51,8 +57,12 @@ var (
 
 // A PebbleBipartiteGraphStore is a bipartite graph store backed by the Pebble key-value database.
 type PebbleBipartiteGraphStore struct {
-	folder string
-	db     *pebble.DB
+	folder   string
+	db       *pebble.DB
+	readOnly bool            // Was the store opened in read-only mode?
+	ingester *pebbleIngester // Buffers the keys in bulk ingest mode (nil otherwise)
+	index    *existenceIndex // Entity IDs that may be in the store (nil if disabled)
+	counts   *pebbleCounts   // Counts of the entities and documents
 }
 
 type PebbleEntity struct {
@@ -92,17 +102,31 @@ func DocumentToPebbleDocument(d Document) PebbleDocument {
 	}
 }
 
-func PebbleDocumentToDocument(pebbleDocument PebbleDocument, entities *set.Set[string]) Document {
+func PebbleDocumentToDocument(pebbleDocument PebbleDocument, entities *set.Set[string],
+	directions map[string]string) Document {
+
+	// Documents without directed links don't hold the directions
+	if len(directions) == 0 {
+		directions = nil
+	}
+
 	return Document{
 		Id:              pebbleDocument.Id,
 		DocumentType:    pebbleDocument.DocumentType,
 		Attributes:      pebbleDocument.Attributes,
 		LinkedEntityIds: entities,
+		Directions:      directions,
 	}
 }
 
 // NewPebbleBipartiteGraphStore given the dedicated folder where the Pebble files are to be held.
 func NewPebbleBipartiteGraphStore(folder string) (*PebbleBipartiteGraphStore, error) {
+	return NewPebbleBipartiteGraphStoreWithTuning(folder, PebbleTuning{})
+}
+
+// NewPebbleBipartiteGraphStoreWithTuning given the folder and the tuning of the Pebble options.
+func NewPebbleBipartiteGraphStoreWithTuning(folder string, tuning PebbleTuning) (
+	*PebbleBipartiteGraphStore, error) {
 
 	if len(folder) == 0 {
 		return nil, errors.New("folder name is empty")
@@ -113,7 +137,7 @@ func NewPebbleBipartiteGraphStore(folder string) (*PebbleBipartiteGraphStore, er
 		Str("folder", folder).
 		Msg("Opening bipartite Pebble store")
 
-	db, err := pebble.Open(folder, &pebble.Options{
+	options := &pebble.Options{
 		FS: vfs.Default,
 		L0CompactionThreshold:       2,
 		L0StopWritesThreshold:       1000,
@@ -122,7 +146,16 @@ func NewPebbleBipartiteGraphStore(folder string) (*PebbleBipartiteGraphStore, er
 		MemTableSize:                64 << 20, // 64 MB
 		MemTableStopWritesThreshold: 4,
 		DisableWAL:                  true,
-	})
+		Levels:                      pebbleLevelOptions(),
+	}
+
+	release, err := tuning.apply(options)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	db, err := pebble.Open(folder, options)
 	if err != nil {
 		return nil, err
 	}
@@ -130,6 +163,12 @@ func NewPebbleBipartiteGraphStore(folder string) (*PebbleBipartiteGraphStore, er
 	store := PebbleBipartiteGraphStore{
 		folder: folder,
 		db:     db,
+		counts: newPebbleCounts(),
+	}
+
+	if err := store.openExistenceIndex(tuning); err != nil {
+		db.Close()
+		return nil, err
 	}
 
 	return &store, nil
@@ -141,10 +180,26 @@ func (p *PebbleBipartiteGraphStore) Close() error {
 		Str(logging.ComponentField, componentName).
 		Msg("Closing the Pebble bipartite graph store")
 
+	// Keys that haven't been ingested are lost
+	if p.ingester != nil {
+		if err := p.ingester.discard(); err != nil {
+			return err
+		}
+		p.ingester = nil
+	}
+
 	return p.db.Close()
 }
 
 func (p *PebbleBipartiteGraphStore) Finalise() error {
+	if p.readOnly {
+		return nil
+	}
+
+	if err := p.finishBulkIngest(); err != nil {
+		return err
+	}
+
 	return p.db.Flush()
 }
 
@@ -378,23 +433,35 @@ func (p *PebbleBipartiteGraphStore) putEntityDocumentLink(entityId string, docum
 		return err
 	}
 
-	return p.db.Set(key, nil, pebble.NoSync)
+	return p.set(key, nil)
 }
 
-func (p *PebbleBipartiteGraphStore) putDocumentEntityLink(documentId string, entityId string) error {
+func (p *PebbleBipartiteGraphStore) putDocumentEntityLink(documentId string, entityId string,
+	direction string) error {
+
 	// Store the entity <- document link
 	key, err := documentEntityLinkToPebbleKey(documentId, entityId)
 	if err != nil {
 		return err
 	}
 
-	return p.db.Set(key, nil, pebble.NoSync)
+	return p.set(key, directionToValue(direction))
+}
+
+// directionToValue returns the value of a document-entity link key given the link's direction.
+func directionToValue(direction string) []byte {
+	if direction == LinkUndirected {
+		return nil
+	}
+
+	return []byte(direction)
 }
 
-func (p *PebbleBipartiteGraphStore) putEntitiesForDocument(docId string, entities *set.Set[string]) error {
+func (p *PebbleBipartiteGraphStore) putEntitiesForDocument(docId string, entities *set.Set[string],
+	directions map[string]string) error {
 
 	for _, entityId := range entities.ToSlice() {
-		if err := p.putDocumentEntityLink(docId, entityId); err != nil {
+		if err := p.putDocumentEntityLink(docId, entityId, directions[entityId]); err != nil {
 			return err
 		}
 	}
@@ -402,9 +469,13 @@ func (p *PebbleBipartiteGraphStore) putEntitiesForDocument(docId string, entitie
 	return nil
 }
 
-func (p *PebbleBipartiteGraphStore) getEntitiesForDocument(docId string) (*set.Set[string], error) {
+// getEntitiesForDocument returns the IDs of the entities linked to the document and the
+// directions of the directed links.
+func (p *PebbleBipartiteGraphStore) getEntitiesForDocument(docId string) (*set.Set[string],
+	map[string]string, error) {
 
 	entityIds := set.NewSet[string]()
+	directions := map[string]string{}
 
 	iterOptions := &pebble.IterOptions{
 		LowerBound: []byte(documentEntityLinkPrefix + separator + docId + separator),
@@ -423,18 +494,21 @@ func (p *PebbleBipartiteGraphStore) getEntitiesForDocument(docId string) (*set.S
 			errDuringIteration = ErrMalformedKey
 		} else {
 			entityIds.Add(entityId)
+			if len(iter.Value()) > 0 {
+				directions[entityId] = string(iter.Value())
+			}
 		}
 	}
 
 	if err := iter.Close(); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	if errDuringIteration != nil {
-		return nil, errDuringIteration
+		return nil, nil, errDuringIteration
 	}
 
-	return entityIds, nil
+	return entityIds, directions, nil
 }
 
 func (p *PebbleBipartiteGraphStore) putDocumentsForEntity(entityId string, documents *set.Set[string]) error {
@@ -498,7 +572,8 @@ func (p *PebbleBipartiteGraphStore) putPebbleEntity(entity PebbleEntity) error {
 	}
 
 	// Store
-	return p.db.Set(key, value, pebble.NoSync)
+	p.index.add(entity.Id)
+	return p.set(key, value)
 }
 
 // AddEntity to the Pebble store.
@@ -531,7 +606,7 @@ func (p *PebbleBipartiteGraphStore) putPebbleDocument(document PebbleDocument) e
 	}
 
 	// Store
-	return p.db.Set(key, value, pebble.NoSync)
+	return p.set(key, value)
 }
 
 // AddDocument to the Pebble store.
@@ -546,22 +621,150 @@ func (p *PebbleBipartiteGraphStore) AddDocument(document Document) error {
 	}
 
 	// Store the associated entities
-	return p.putEntitiesForDocument(document.Id, document.LinkedEntityIds)
+	return p.putEntitiesForDocument(document.Id, document.LinkedEntityIds, document.Directions)
 }
 
 // AddLink between an entity and a document (by ID).
 func (p *PebbleBipartiteGraphStore) AddLink(link Link) error {
 
+	if err := ValidateLinkDirection(link.Direction); err != nil {
+		return err
+	}
+
 	err := p.putEntityDocumentLink(link.EntityId, link.DocumentId)
 	if err != nil {
 		return err
 	}
 
-	return p.putDocumentEntityLink(link.DocumentId, link.EntityId)
+	return p.putDocumentEntityLink(link.DocumentId, link.EntityId, link.Direction)
+}
+
+// RemoveEntity and its links to documents from the Pebble store.
+func (p *PebbleBipartiteGraphStore) RemoveEntity(entityId string) error {
+
+	if p.readOnly {
+		return ErrStoreIsReadOnly
+	}
+
+	entity, err := p.getEntity(entityId)
+	if err != nil {
+		return err
+	}
+
+	change, err := entityRemovalKeys(entityId, entity.LinkedDocumentIds)
+	if err != nil {
+		return err
+	}
+
+	if err := p.counts.invalidate(p.db); err != nil {
+		return err
+	}
+
+	return pebbleApply(p.db, change)
+}
+
+// RemoveDocument and its links to entities from the Pebble store.
+func (p *PebbleBipartiteGraphStore) RemoveDocument(documentId string) error {
+
+	if p.readOnly {
+		return ErrStoreIsReadOnly
+	}
+
+	document, err := p.GetDocument(documentId)
+	if err != nil {
+		return err
+	}
+
+	change, err := documentRemovalKeys(documentId, document.LinkedEntityIds)
+	if err != nil {
+		return err
+	}
+
+	if err := p.counts.invalidate(p.db); err != nil {
+		return err
+	}
+
+	return pebbleApply(p.db, change)
+}
+
+// RemoveLink between an entity and a document (by ID) from the Pebble store. The direction of the
+// link is ignored.
+func (p *PebbleBipartiteGraphStore) RemoveLink(link Link) error {
+
+	if p.readOnly {
+		return ErrStoreIsReadOnly
+	}
+
+	change, err := linkRemovalKeys(link)
+	if err != nil {
+		return err
+	}
+
+	_, closer, err := p.db.Get(change.deletes[0])
+	if err == pebble.ErrNotFound {
+		return ErrLinkNotFound
+	} else if err != nil {
+		return err
+	}
+
+	if err := closer.Close(); err != nil {
+		return err
+	}
+
+	if err := p.counts.invalidate(p.db); err != nil {
+		return err
+	}
+
+	return pebbleApply(p.db, change)
+}
+
+// AddAlias of an entity to the Pebble store.
+func (p *PebbleBipartiteGraphStore) AddAlias(aliasId string, entityId string) error {
+
+	if err := validateAlias(aliasId, entityId); err != nil {
+		return err
+	}
+
+	key, err := aliasToPebbleKey(aliasId)
+	if err != nil {
+		return err
+	}
+
+	return p.set(key, []byte(entityId))
 }
 
-// GetEntity given its ID from the Pebble store.
+// lookupAlias returns the canonical entity ID of the alias ID and true if the alias exists.
+func (p *PebbleBipartiteGraphStore) lookupAlias(aliasId string) (string, bool, error) {
+
+	key, err := aliasToPebbleKey(aliasId)
+	if err != nil {
+		return "", false, err
+	}
+
+	value, closer, err := p.db.Get(key)
+	if err == pebble.ErrNotFound {
+		return "", false, nil
+	} else if err != nil {
+		return "", false, err
+	}
+
+	defer closer.Close()
+
+	return string(value), true, nil
+}
+
+// ResolveAlias returns the canonical ID of the entity ID (unchanged if the ID isn't an alias).
+func (p *PebbleBipartiteGraphStore) ResolveAlias(entityId string) (string, error) {
+	return resolveAlias(entityId, p.HasEntityWithId, p.lookupAlias)
+}
+
+// GetEntity given its ID or one of its aliases from the Pebble store.
 func (p *PebbleBipartiteGraphStore) GetEntity(entityId string) (*Entity, error) {
+	return getEntityOrAlias(entityId, p.getEntity, p.ResolveAlias)
+}
+
+// getEntity given its ID (ignoring aliases) from the Pebble store.
+func (p *PebbleBipartiteGraphStore) getEntity(entityId string) (*Entity, error) {
 
 	// Get the entity from the Pebble store
 	key, err := entityIdToPebbleKey(entityId)
@@ -620,12 +823,12 @@ func (p *PebbleBipartiteGraphStore) GetDocument(documentId string) (*Document, e
 	}
 
 	// Got the entities for the document
-	entities, err := p.getEntitiesForDocument(documentId)
+	entities, directions, err := p.getEntitiesForDocument(documentId)
 	if err != nil {
 		return nil, err
 	}
 
-	doc := PebbleDocumentToDocument(*document, entities)
+	doc := PebbleDocumentToDocument(*document, entities, directions)
 
 	return &doc, nil
 }
@@ -647,8 +850,12 @@ func (p *PebbleBipartiteGraphStore) HasDocument(document *Document) (bool, error
 // HasEntity returns true if the entity exists in the Pebble store.
 func (p *PebbleBipartiteGraphStore) HasEntity(entity *Entity) (bool, error) {
 
+	if !p.index.mayContain(entity.Id) {
+		return false, nil
+	}
+
 	// Get the entity from the store
-	ent, err := p.GetEntity(entity.Id)
+	ent, err := p.getEntity(entity.Id)
 	if err == ErrEntityNotFound {
 		return false, nil
 	} else if err != nil {
@@ -665,6 +872,10 @@ func (p *PebbleBipartiteGraphStore) HasEntityWithId(entityId string) (bool, erro
 		return false, err
 	}
 
+	if !p.index.mayContain(entityId) {
+		return false, nil
+	}
+
 	_, closer, err := p.db.Get(key)
 	if err != nil {
 		if err == pebble.ErrNotFound {
@@ -762,8 +973,13 @@ func (p *PebbleBipartiteGraphStore) NewDocumentIdIterator() (DocumentIdIterator,
 	return &documentIdIterator, err
 }
 
-// NumberOfDocuments in the Pebble bipartite store.
+// NumberOfDocuments in the Pebble bipartite store, which are only counted if the store has changed.
 func (p *PebbleBipartiteGraphStore) NumberOfDocuments() (int, error) {
+	return p.counts.count(p.db, p.readOnly, countDocuments, p.countDocuments)
+}
+
+// countDocuments in the Pebble bipartite store.
+func (p *PebbleBipartiteGraphStore) countDocuments() (int, error) {
 	nDocuments := 0
 
 	iter, err := p.NewDocumentIdIterator()
@@ -862,8 +1078,13 @@ func (p *PebbleBipartiteGraphStore) NewEntityIdIterator() (EntityIdIterator, err
 	return &entityIdIterator, err
 }
 
-// NumberOfEntities in the bipartite Pebble store.
+// NumberOfEntities in the bipartite Pebble store, which are only counted if the store has changed.
 func (p *PebbleBipartiteGraphStore) NumberOfEntities() (int, error) {
+	return p.counts.count(p.db, p.readOnly, countEntities, p.countEntities)
+}
+
+// countEntities in the bipartite Pebble store.
+func (p *PebbleBipartiteGraphStore) countEntities() (int, error) {
 	nEntities := 0
 
 	iter, err := p.NewEntityIdIterator()
@@ -894,6 +1115,22 @@ func (p *PebbleBipartiteGraphStore) Clear() error {
 		Str(logging.ComponentField, componentName).
 		Msg("Clearing the Pebble bipartite graph store")
 
+	if p.readOnly {
+		return ErrStoreIsReadOnly
+	}
+
+	if err := p.counts.invalidate(p.db); err != nil {
+		return err
+	}
+
+	// Discard the keys that haven't been ingested
+	if p.ingester != nil {
+		if err := p.ingester.discard(); err != nil {
+			return err
+		}
+		p.ingester = nil
+	}
+
 	var deleteError error
 
 	// As soon as there is an error when deleting a key, stop the iteration
@@ -918,6 +1155,11 @@ func (p *PebbleBipartiteGraphStore) Destroy() error {
 		Str(logging.ComponentField, componentName).
 		Msg("Destroying the Pebble bipartite graph store")
 
+	// A read-only store may be shared, so its files must not be deleted
+	if p.readOnly {
+		return ErrStoreIsReadOnly
+	}
+
 	// Close down the Pebble database
 	err := p.Close()
 	if err != nil {
@@ -0,0 +1,300 @@
+// Counting the entities or documents of a Pebble store means iterating over all of their keys,
+// which takes minutes for a large store. Once they have been counted, the counts are therefore
+// held in memory and persisted in the store under the key:
+//
+//   s#counts = <JSON object of the counts by name>
+//
+// so that a store that is reopened (e.g. when the web-app restarts without a rebuild) doesn't count
+// them again. Any change to the store invalidates the counts and deletes the key, and the counts
+// are reconciled with the store (i.e. counted again) the next time they are needed, unless the
+// counts after the change are recorded (e.g. they were updated incrementally when documents were
+// pruned). A store is usually loaded once and then queried many times, so it is only counted once.
+
+package graphstore
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cockroachdb/pebble"
+)
+
+const (
+	countsPrefix = "s"
+
+	// Names of the counts
+	countEntities              = "entities"
+	countEntitiesWithDocuments = "entitiesWithDocuments"
+	countDocuments             = "documents"
+	countDocumentsWithEntities = "documentsWithEntities"
+	countTypes                 = "types" // Marks that the counts of each type are held
+
+	// Prefixes of the names of the counts of each type
+	entityTypeCountPrefix   = "entityType" + separator
+	documentTypeCountPrefix = "documentType" + separator
+)
+
+// bipartiteCountNames are the names of the counts of a bipartite store that are always held.
+var bipartiteCountNames = []string{countEntities, countEntitiesWithDocuments, countDocuments,
+	countDocumentsWithEntities, countTypes}
+
+// countsKey is the Pebble key of the persisted counts.
+var countsKey = []byte(countsPrefix + separator + "counts")
+
+// pebbleCounts of a Pebble store that are valid until the store changes.
+type pebbleCounts struct {
+	mu        sync.Mutex
+	values    map[string]int // Counts that are valid
+	persisted bool           // May the store hold persisted counts?
+}
+
+// newPebbleCounts of a store that has just been opened.
+func newPebbleCounts() *pebbleCounts {
+	return &pebbleCounts{
+		values:    map[string]int{},
+		persisted: true,
+	}
+}
+
+// invalidate the counts because the store is being changed.
+func (c *pebbleCounts) invalidate(db *pebble.DB) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.values) > 0 {
+		c.values = map[string]int{}
+	}
+
+	if !c.persisted {
+		return nil
+	}
+
+	if err := db.Delete(countsKey, pebble.NoSync); err != nil {
+		return err
+	}
+
+	c.persisted = false
+	return nil
+}
+
+// readPersisted counts from the store.
+func (c *pebbleCounts) readPersisted(db *pebble.DB) error {
+
+	value, closer, err := db.Get(countsKey)
+	if err == pebble.ErrNotFound {
+		c.persisted = false
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer closer.Close()
+
+	return json.Unmarshal(value, &c.values)
+}
+
+// get all of the counts, counting them with the function if any of the named counts aren't valid.
+// The counts are persisted unless the store is read-only.
+func (c *pebbleCounts) get(db *pebble.DB, readOnly bool, names []string,
+	count func() (map[string]int, error)) (map[string]int, error) {
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.values) == 0 && c.persisted {
+		if err := c.readPersisted(db); err != nil {
+			return nil, err
+		}
+	}
+
+	if !c.hasAll(names) {
+		counted, err := count()
+		if err != nil {
+			return nil, err
+		}
+
+		logging.Logger.Info().
+			Str(logging.ComponentField, componentName).
+			Interface("counts", counted).
+			Msg("Counted the keys of the Pebble store")
+
+		for name, value := range counted {
+			c.values[name] = value
+		}
+
+		if !readOnly {
+			if err := c.persist(db); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	counts := map[string]int{}
+	for name, value := range c.values {
+		counts[name] = value
+	}
+
+	return counts, nil
+}
+
+// count a single value with the function, unless it is valid.
+func (c *pebbleCounts) count(db *pebble.DB, readOnly bool, name string,
+	count func() (int, error)) (int, error) {
+
+	counts, err := c.get(db, readOnly, []string{name}, func() (map[string]int, error) {
+		value, err := count()
+		if err != nil {
+			return nil, err
+		}
+		return map[string]int{name: value}, nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return counts[name], nil
+}
+
+// record the counts, which are known to be those of the store, and persist them unless the store
+// is read-only.
+func (c *pebbleCounts) record(db *pebble.DB, readOnly bool, counts map[string]int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.values = map[string]int{}
+	for name, value := range counts {
+		c.values[name] = value
+	}
+
+	if readOnly {
+		return nil
+	}
+
+	return c.persist(db)
+}
+
+// persist the counts in the store.
+func (c *pebbleCounts) persist(db *pebble.DB) error {
+
+	value, err := json.Marshal(c.values)
+	if err != nil {
+		return err
+	}
+
+	if err := db.Set(countsKey, value, pebble.NoSync); err != nil {
+		return err
+	}
+
+	c.persisted = true
+	return nil
+}
+
+// hasAll returns true if all of the named counts are valid.
+func (c *pebbleCounts) hasAll(names []string) bool {
+	for _, name := range names {
+		if _, found := c.values[name]; !found {
+			return false
+		}
+	}
+	return true
+}
+
+// A countedBipartiteStore holds the statistics of its graph without counting them every time.
+type countedBipartiteStore interface {
+	countedStats() (BipartiteStats, error)
+	countedTypes() (TypeCounts, error)
+	recordStats(stats BipartiteStats, types TypeCounts) error
+}
+
+// A countedUnipartiteStore holds the statistics of its graph without counting them every time.
+type countedUnipartiteStore interface {
+	recordStats(stats UnipartiteStats) error
+}
+
+// bipartiteStatsToCounts by the names of the counts.
+func bipartiteStatsToCounts(stats BipartiteStats, types TypeCounts) map[string]int {
+	counts := map[string]int{
+		countEntities:              stats.NumberOfEntities,
+		countEntitiesWithDocuments: stats.NumberOfEntitiesWithDocuments,
+		countDocuments:             stats.NumberOfDocuments,
+		countDocumentsWithEntities: stats.NumberOfDocumentsWithEntities,
+		countTypes:                 1,
+	}
+
+	for entityType, value := range types.EntityTypes {
+		counts[entityTypeCountPrefix+entityType] = value
+	}
+
+	for documentType, value := range types.DocumentTypes {
+		counts[documentTypeCountPrefix+documentType] = value
+	}
+
+	return counts
+}
+
+// countsToTypeCounts extracts the counts of each type from the counts.
+func countsToTypeCounts(counts map[string]int) TypeCounts {
+	types := TypeCounts{
+		EntityTypes:   map[string]int{},
+		DocumentTypes: map[string]int{},
+	}
+
+	for name, value := range counts {
+		if strings.HasPrefix(name, entityTypeCountPrefix) {
+			types.EntityTypes[strings.TrimPrefix(name, entityTypeCountPrefix)] = value
+		} else if strings.HasPrefix(name, documentTypeCountPrefix) {
+			types.DocumentTypes[strings.TrimPrefix(name, documentTypeCountPrefix)] = value
+		}
+	}
+
+	return types
+}
+
+// recordStats of the Pebble bipartite store.
+func (p *PebbleBipartiteGraphStore) recordStats(stats BipartiteStats, types TypeCounts) error {
+	return p.counts.record(p.db, p.readOnly, bipartiteStatsToCounts(stats, types))
+}
+
+// recordStats of the Pebble unipartite store.
+func (p *PebbleUnipartiteGraphStore) recordStats(stats UnipartiteStats) error {
+	return p.counts.record(p.db, p.readOnly, map[string]int{countEntities: stats.NumberOfEntities})
+}
+
+// bipartiteCounts of the Pebble bipartite store, including the counts of each type.
+func (p *PebbleBipartiteGraphStore) bipartiteCounts() (map[string]int, error) {
+	return p.counts.get(p.db, p.readOnly, bipartiteCountNames, func() (map[string]int, error) {
+		stats, types, err := countBipartite(p)
+		if err != nil {
+			return nil, err
+		}
+
+		return bipartiteStatsToCounts(stats, types), nil
+	})
+}
+
+// countedTypes of the entities and documents of the Pebble bipartite store.
+func (p *PebbleBipartiteGraphStore) countedTypes() (TypeCounts, error) {
+	counts, err := p.bipartiteCounts()
+	if err != nil {
+		return TypeCounts{}, err
+	}
+
+	return countsToTypeCounts(counts), nil
+}
+
+// countedStats of the Pebble bipartite store.
+func (p *PebbleBipartiteGraphStore) countedStats() (BipartiteStats, error) {
+
+	counts, err := p.bipartiteCounts()
+	if err != nil {
+		return BipartiteStats{}, err
+	}
+
+	return BipartiteStats{
+		NumberOfEntities:              counts[countEntities],
+		NumberOfEntitiesWithDocuments: counts[countEntitiesWithDocuments],
+		NumberOfDocuments:             counts[countDocuments],
+		NumberOfDocumentsWithEntities: counts[countDocumentsWithEntities],
+	}, nil
+}
@@ -0,0 +1,121 @@
+package graphstore
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/stretchr/testify/assert"
+)
+
+// hasPersistedCounts returns true if the Pebble database holds the counts.
+func hasPersistedCounts(t *testing.T, db *pebble.DB) bool {
+	_, closer, err := db.Get(countsKey)
+	if err == pebble.ErrNotFound {
+		return false
+	}
+	assert.NoError(t, err)
+	assert.NoError(t, closer.Close())
+	return true
+}
+
+func TestPebbleBipartiteCounts(t *testing.T) {
+
+	folder := createTempPebbleFolder(t)
+	defer deleteTempPebbleFolder(t, folder)
+
+	store, err := NewPebbleBipartiteGraphStore(folder)
+	assert.NoError(t, err)
+
+	for _, entityId := range []string{"e-1", "e-2"} {
+		entity, err := NewEntity(entityId, "Person", map[string]string{})
+		assert.NoError(t, err)
+		assert.NoError(t, store.AddEntity(entity))
+	}
+
+	doc, err := NewDocument("d-1", "Doc", map[string]string{})
+	assert.NoError(t, err)
+	assert.NoError(t, store.AddDocument(doc))
+	assert.NoError(t, store.AddLink(NewLink("e-1", "d-1")))
+	assert.NoError(t, store.Finalise())
+	assert.False(t, hasPersistedCounts(t, store.db))
+
+	// Counting the store persists the counts
+	expected := BipartiteStats{
+		NumberOfEntities:              2,
+		NumberOfEntitiesWithDocuments: 1,
+		NumberOfDocuments:             1,
+		NumberOfDocumentsWithEntities: 1,
+	}
+
+	stats, err := CalcBipartiteStats(store)
+	assert.NoError(t, err)
+	assert.Equal(t, expected, stats)
+	assert.True(t, hasPersistedCounts(t, store.db))
+
+	numberEntities, err := store.NumberOfEntities()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, numberEntities)
+
+	// A change invalidates the counts
+	assert.NoError(t, store.RemoveEntity("e-2"))
+	assert.False(t, hasPersistedCounts(t, store.db))
+
+	numberEntities, err = store.NumberOfEntities()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, numberEntities)
+
+	numberDocuments, err := store.NumberOfDocuments()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, numberDocuments)
+
+	// The persisted counts are read when the store is reopened, even if they are wrong
+	assert.NoError(t, store.db.Set(countsKey, []byte(`{"entities":10}`), pebble.NoSync))
+	assert.NoError(t, store.Finalise())
+	assert.NoError(t, store.Close())
+
+	readOnly, err := NewReadOnlyPebbleBipartiteGraphStore(folder)
+	assert.NoError(t, err)
+
+	numberEntities, err = readOnly.NumberOfEntities()
+	assert.NoError(t, err)
+	assert.Equal(t, 10, numberEntities)
+
+	// The counts that aren't persisted are counted
+	stats, err = CalcBipartiteStats(readOnly)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, stats.NumberOfEntities)
+	assert.NoError(t, readOnly.Close())
+}
+
+func TestPebbleUnipartiteCounts(t *testing.T) {
+
+	folder := createTempPebbleFolder(t)
+	defer deleteTempPebbleFolder(t, folder)
+
+	store, err := NewPebbleUnipartiteGraphStore(folder)
+	assert.NoError(t, err)
+	assert.NoError(t, store.AddUndirected("e-1", "e-2"))
+
+	numberEntities, err := store.NumberEntities()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, numberEntities)
+	assert.True(t, hasPersistedCounts(t, store.db))
+
+	assert.NoError(t, store.AddEntity("e-3"))
+	assert.False(t, hasPersistedCounts(t, store.db))
+
+	numberEntities, err = store.NumberEntities()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, numberEntities)
+
+	assert.NoError(t, store.RemoveEntity("e-3"))
+	numberEntities, err = store.NumberEntities()
+	assert.NoError(t, err)
+	assert.Equal(t, 2, numberEntities)
+
+	assert.NoError(t, store.Clear())
+	numberEntities, err = store.NumberEntities()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, numberEntities)
+	assert.NoError(t, store.Close())
+}
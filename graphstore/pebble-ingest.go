@@ -0,0 +1,482 @@
+// Bulk ingestion into a Pebble store. When a large graph is built from scratch, writing every key
+// with a Set call means Pebble spends most of the build rewriting the keys during compactions.
+// In bulk ingest mode the keys are instead buffered in memory, spilled to sorted run files when
+// the buffer is full, merged into sorted SSTables and ingested in a single step when the store is
+// finalised.
+//
+// The keys aren't visible to reads until the store is finalised, so a store in bulk ingest mode
+// must only be written to during the load.
+
+package graphstore
+
+import (
+	"bufio"
+	"bytes"
+	"container/heap"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/objstorage/objstorageprovider"
+	"github.com/cockroachdb/pebble/sstable"
+	"github.com/cockroachdb/pebble/vfs"
+)
+
+// Default size (bytes) of the buffer of keys held in memory before a run is spilled to disk
+const DefaultIngestBufferBytes = 64 << 20 // 64 MB
+
+// Maximum size (bytes) of an SSTable written for ingestion
+const maxIngestTableBytes = 256 << 20 // 256 MB
+
+var (
+	ErrInvalidIngestBuffer   = errors.New("invalid bulk ingest buffer size")
+	ErrBulkIngestInProgress  = errors.New("bulk ingest already enabled")
+	ErrMalformedIngestRecord = errors.New("malformed bulk ingest record")
+)
+
+// ingestRecord is a key and value to ingest.
+type ingestRecord struct {
+	key   []byte
+	value []byte
+}
+
+// A pebbleIngester buffers the keys to ingest into a Pebble database, spilling them to sorted run
+// files in its folder. It is safe for concurrent use.
+type pebbleIngester struct {
+	folder      string         // Temporary folder of the run files and SSTables
+	bufferBytes int            // Maximum size (bytes) of the buffer before it is spilled
+	buffer      []ingestRecord // Records in the order they were added
+	size        int            // Size (bytes) of the buffered records
+	runs        []string       // Paths of the run files in the order they were written
+	mu          sync.Mutex
+}
+
+// newPebbleIngester that spills the runs to a temporary folder alongside the Pebble folder, so
+// that the SSTables can be linked rather than copied into the database.
+func newPebbleIngester(pebbleFolder string, bufferBytes int) (*pebbleIngester, error) {
+
+	if bufferBytes <= 0 {
+		return nil, ErrInvalidIngestBuffer
+	}
+
+	folder, err := os.MkdirTemp(filepath.Dir(filepath.Clean(pebbleFolder)),
+		filepath.Base(pebbleFolder)+"-ingest-")
+	if err != nil {
+		return nil, err
+	}
+
+	return &pebbleIngester{
+		folder:      folder,
+		bufferBytes: bufferBytes,
+		buffer:      []ingestRecord{},
+		runs:        []string{},
+	}, nil
+}
+
+// set the key to the value. A later value for the same key replaces an earlier one.
+func (in *pebbleIngester) set(key []byte, value []byte) error {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	in.buffer = append(in.buffer, ingestRecord{
+		key:   append([]byte{}, key...),
+		value: append([]byte{}, value...),
+	})
+	in.size += len(key) + len(value)
+
+	if in.size >= in.bufferBytes {
+		return in.spill()
+	}
+
+	return nil
+}
+
+// sortRecords by key, keeping only the last record added for each key.
+func sortRecords(records []ingestRecord) []ingestRecord {
+
+	sort.SliceStable(records, func(i, j int) bool {
+		return bytes.Compare(records[i].key, records[j].key) < 0
+	})
+
+	unique := records[:0]
+	for _, record := range records {
+		if len(unique) > 0 && bytes.Equal(unique[len(unique)-1].key, record.key) {
+			unique[len(unique)-1] = record
+		} else {
+			unique = append(unique, record)
+		}
+	}
+
+	return unique
+}
+
+// writeRecord to the writer as the length-prefixed key and value.
+func writeRecord(w *bufio.Writer, record ingestRecord) error {
+	lengths := make([]byte, 2*binary.MaxVarintLen64)
+	n := binary.PutUvarint(lengths, uint64(len(record.key)))
+	n += binary.PutUvarint(lengths[n:], uint64(len(record.value)))
+
+	for _, b := range [][]byte{lengths[:n], record.key, record.value} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readRecord from the reader. io.EOF is returned if there are no more records.
+func readRecord(r *bufio.Reader) (ingestRecord, error) {
+	keyLength, err := binary.ReadUvarint(r)
+	if err != nil {
+		return ingestRecord{}, err
+	}
+
+	valueLength, err := binary.ReadUvarint(r)
+	if err != nil {
+		return ingestRecord{}, fmt.Errorf("%w: %v", ErrMalformedIngestRecord, err)
+	}
+
+	record := ingestRecord{
+		key:   make([]byte, keyLength),
+		value: make([]byte, valueLength),
+	}
+
+	if _, err := io.ReadFull(r, record.key); err != nil {
+		return ingestRecord{}, fmt.Errorf("%w: %v", ErrMalformedIngestRecord, err)
+	}
+
+	if _, err := io.ReadFull(r, record.value); err != nil {
+		return ingestRecord{}, fmt.Errorf("%w: %v", ErrMalformedIngestRecord, err)
+	}
+
+	return record, nil
+}
+
+// spill the buffered records to a sorted run file. The lock must be held.
+func (in *pebbleIngester) spill() error {
+
+	if len(in.buffer) == 0 {
+		return nil
+	}
+
+	records := sortRecords(in.buffer)
+
+	path := filepath.Join(in.folder, fmt.Sprintf("run-%06d", len(in.runs)))
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+
+	w := bufio.NewWriter(file)
+	for _, record := range records {
+		if err := writeRecord(w, record); err != nil {
+			file.Close()
+			return err
+		}
+	}
+
+	if err := w.Flush(); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	logging.Logger.Debug().
+		Str(logging.ComponentField, componentName).
+		Str("path", path).
+		Int("numberOfRecords", len(records)).
+		Msg("Spilled bulk ingest run")
+
+	in.runs = append(in.runs, path)
+	in.buffer = []ingestRecord{}
+	in.size = 0
+
+	return nil
+}
+
+// runReader reads the records of a run file in key order.
+type runReader struct {
+	index   int // Index of the run, where a later run replaces the values of an earlier run
+	file    *os.File
+	reader  *bufio.Reader
+	current ingestRecord
+}
+
+// next record of the run, returning false at the end of the run.
+func (r *runReader) next() (bool, error) {
+	record, err := readRecord(r.reader)
+	if err == io.EOF {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	r.current = record
+	return true, nil
+}
+
+// runHeap orders the run readers by their current key, then with the latest run first.
+type runHeap []*runReader
+
+func (h runHeap) Len() int { return len(h) }
+
+func (h runHeap) Less(i, j int) bool {
+	c := bytes.Compare(h[i].current.key, h[j].current.key)
+	if c != 0 {
+		return c < 0
+	}
+	return h[i].index > h[j].index
+}
+
+func (h runHeap) Swap(i, j int) { h[i], h[j] = h[j], h[i] }
+
+func (h *runHeap) Push(x any) { *h = append(*h, x.(*runReader)) }
+
+func (h *runHeap) Pop() any {
+	old := *h
+	n := len(old)
+	item := old[n-1]
+	*h = old[:n-1]
+	return item
+}
+
+// tableWriter writes the merged records to SSTables of up to the maximum size.
+type tableWriter struct {
+	folder  string
+	options sstable.WriterOptions
+	writer  *sstable.Writer
+	paths   []string
+}
+
+// set the key to the value in the current SSTable, starting a new table if required.
+func (t *tableWriter) set(key []byte, value []byte) error {
+
+	if t.writer != nil && t.writer.EstimatedSize() >= maxIngestTableBytes {
+		if err := t.close(); err != nil {
+			return err
+		}
+	}
+
+	if t.writer == nil {
+		path := filepath.Join(t.folder, fmt.Sprintf("table-%06d.sst", len(t.paths)))
+		file, err := vfs.Default.Create(path)
+		if err != nil {
+			return err
+		}
+
+		t.writer = sstable.NewWriter(objstorageprovider.NewFileWritable(file), t.options)
+		t.paths = append(t.paths, path)
+	}
+
+	return t.writer.Set(key, value)
+}
+
+// close the current SSTable.
+func (t *tableWriter) close() error {
+	if t.writer == nil {
+		return nil
+	}
+
+	err := t.writer.Close()
+	t.writer = nil
+	return err
+}
+
+// mergeRuns into SSTables, returning the paths of the tables.
+func (in *pebbleIngester) mergeRuns(options sstable.WriterOptions) ([]string, error) {
+
+	h := runHeap{}
+	defer func() {
+		for _, r := range h {
+			r.file.Close()
+		}
+	}()
+
+	for idx, path := range in.runs {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+
+		r := &runReader{
+			index:  idx,
+			file:   file,
+			reader: bufio.NewReader(file),
+		}
+
+		found, err := r.next()
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+
+		if found {
+			h = append(h, r)
+		} else {
+			file.Close()
+		}
+	}
+	heap.Init(&h)
+
+	tables := tableWriter{
+		folder:  in.folder,
+		options: options,
+		paths:   []string{},
+	}
+
+	var previous []byte
+	for h.Len() > 0 {
+		r := h[0]
+
+		// The latest run's value for a key is taken first, so the others are skipped
+		if previous == nil || !bytes.Equal(previous, r.current.key) {
+			if err := tables.set(r.current.key, r.current.value); err != nil {
+				tables.close()
+				return nil, err
+			}
+			previous = r.current.key
+		}
+
+		found, err := r.next()
+		if err != nil {
+			tables.close()
+			return nil, err
+		}
+
+		if found {
+			heap.Fix(&h, 0)
+		} else {
+			r.file.Close()
+			heap.Pop(&h)
+		}
+	}
+
+	if err := tables.close(); err != nil {
+		return nil, err
+	}
+
+	return tables.paths, nil
+}
+
+// ingest the records into the database and remove the temporary folder.
+func (in *pebbleIngester) ingest(db *pebble.DB) error {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	defer os.RemoveAll(in.folder)
+
+	if err := in.spill(); err != nil {
+		return err
+	}
+
+	start := time.Now()
+	levelOptions := pebbleLevelOptions()[0]
+	paths, err := in.mergeRuns(sstable.WriterOptions{
+		TableFormat:  db.FormatMajorVersion().MaxTableFormat(),
+		FilterPolicy: levelOptions.FilterPolicy,
+		FilterType:   levelOptions.FilterType,
+	})
+	if err != nil {
+		return err
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Int("numberOfRuns", len(in.runs)).
+		Int("numberOfTables", len(paths)).
+		Str("timeTaken", time.Since(start).String()).
+		Msg("Merged the bulk ingest runs into SSTables")
+
+	if len(paths) == 0 {
+		return nil
+	}
+
+	return db.Ingest(paths)
+}
+
+// discard the buffered records and remove the temporary folder.
+func (in *pebbleIngester) discard() error {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+
+	in.buffer = []ingestRecord{}
+	in.runs = []string{}
+	return os.RemoveAll(in.folder)
+}
+
+// EnableBulkIngest buffers the keys written to the store until the store is finalised, when they
+// are ingested as SSTables. The buffer holds up to bufferBytes of keys and values in memory. Only
+// the replace entity merge strategy can be used, as the buffered keys can't be read.
+func (p *PebbleBipartiteGraphStore) EnableBulkIngest(bufferBytes int) error {
+
+	if p.readOnly {
+		return ErrStoreIsReadOnly
+	}
+
+	if p.ingester != nil {
+		return ErrBulkIngestInProgress
+	}
+
+	ingester, err := newPebbleIngester(p.folder, bufferBytes)
+	if err != nil {
+		return err
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("folder", p.folder).
+		Int("bufferBytes", bufferBytes).
+		Str("ingestFolder", ingester.folder).
+		Msg("Enabled bulk ingest mode for the Pebble bipartite store")
+
+	p.ingester = ingester
+	return nil
+}
+
+// set the key to the value, either directly or via the bulk ingester.
+func (p *PebbleBipartiteGraphStore) set(key []byte, value []byte) error {
+	if err := p.counts.invalidate(p.db); err != nil {
+		return err
+	}
+
+	if p.ingester != nil {
+		return p.ingester.set(key, value)
+	}
+
+	return p.db.Set(key, value, pebble.NoSync)
+}
+
+// finishBulkIngest ingests the buffered keys (if bulk ingest is enabled) and leaves bulk ingest
+// mode.
+func (p *PebbleBipartiteGraphStore) finishBulkIngest() error {
+	if p.ingester == nil {
+		return nil
+	}
+
+	ingester := p.ingester
+	p.ingester = nil
+
+	start := time.Now()
+	if err := ingester.ingest(p.db); err != nil {
+		return err
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("folder", p.folder).
+		Str("timeTaken", time.Since(start).String()).
+		Msg("Ingested the bulk loaded keys into the Pebble bipartite store")
+
+	return nil
+}
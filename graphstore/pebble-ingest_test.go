@@ -0,0 +1,161 @@
+package graphstore
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSortRecords(t *testing.T) {
+	records := []ingestRecord{
+		{[]byte("b"), []byte("1")},
+		{[]byte("a"), []byte("2")},
+		{[]byte("b"), []byte("3")},
+		{[]byte("c"), []byte("4")},
+		{[]byte("a"), []byte("5")},
+	}
+
+	expected := []ingestRecord{
+		{[]byte("a"), []byte("5")},
+		{[]byte("b"), []byte("3")},
+		{[]byte("c"), []byte("4")},
+	}
+
+	assert.Equal(t, expected, sortRecords(records))
+}
+
+func TestIngestRecordRoundTrip(t *testing.T) {
+	records := []ingestRecord{
+		{[]byte("e#1"), []byte{}},
+		{[]byte("d#2"), []byte("value")},
+	}
+
+	var buffer bytes.Buffer
+	w := bufio.NewWriter(&buffer)
+	for _, record := range records {
+		assert.NoError(t, writeRecord(w, record))
+	}
+	assert.NoError(t, w.Flush())
+
+	r := bufio.NewReader(&buffer)
+	for _, expected := range records {
+		actual, err := readRecord(r)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	}
+
+	_, err := readRecord(r)
+	assert.Equal(t, io.EOF, err)
+
+	// Truncated record
+	r = bufio.NewReader(bytes.NewReader([]byte{3, 0, 'e'}))
+	_, err = readRecord(r)
+	assert.ErrorIs(t, err, ErrMalformedIngestRecord)
+}
+
+// loadTestBipartite graph into the store, adding some of the entities twice.
+func loadTestBipartite(t *testing.T, store BipartiteGraphStore) {
+	for i := 0; i < 50; i++ {
+		entity, err := NewEntity(fmt.Sprintf("e-%d", i), "Person", map[string]string{
+			"Name": fmt.Sprintf("Person %d", i),
+		})
+		assert.NoError(t, err)
+		assert.NoError(t, store.AddEntity(entity))
+	}
+
+	// Later values replace the earlier values
+	for i := 0; i < 50; i += 7 {
+		entity, err := NewEntity(fmt.Sprintf("e-%d", i), "Person", map[string]string{
+			"Name": fmt.Sprintf("Updated %d", i),
+		})
+		assert.NoError(t, err)
+		assert.NoError(t, store.AddEntity(entity))
+	}
+
+	for i := 0; i < 20; i++ {
+		doc, err := NewDocument(fmt.Sprintf("d-%d", i), "Report", map[string]string{})
+		assert.NoError(t, err)
+		assert.NoError(t, store.AddDocument(doc))
+
+		assert.NoError(t, store.AddLink(NewLink(fmt.Sprintf("e-%d", i), doc.Id)))
+		assert.NoError(t, store.AddLink(NewLink(fmt.Sprintf("e-%d", i+1), doc.Id)))
+	}
+
+	assert.NoError(t, store.Finalise())
+}
+
+func TestPebbleBipartiteBulkIngest(t *testing.T) {
+
+	expected := NewInMemoryBipartiteGraphStore()
+	loadTestBipartite(t, expected)
+
+	store := newBipartitePebbleStore(t)
+	defer cleanUpBipartitePebbleStore(t, store)
+
+	// A small buffer spills many runs
+	assert.Equal(t, ErrInvalidIngestBuffer, store.EnableBulkIngest(0))
+	assert.NoError(t, store.EnableBulkIngest(256))
+	assert.Equal(t, ErrBulkIngestInProgress, store.EnableBulkIngest(256))
+	ingestFolder := store.ingester.folder
+
+	// The keys aren't visible until the store is finalised
+	entity, err := NewEntity("e-0", "Person", map[string]string{})
+	assert.NoError(t, err)
+	assert.NoError(t, store.AddEntity(entity))
+
+	found, err := store.HasEntityWithId("e-0")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	loadTestBipartite(t, store)
+	assert.Nil(t, store.ingester)
+
+	equal, err := store.Equal(expected)
+	assert.NoError(t, err)
+	assert.True(t, equal)
+
+	updated, err := store.GetEntity("e-7")
+	assert.NoError(t, err)
+	assert.Equal(t, "Updated 7", updated.Attributes["Name"])
+
+	// The temporary folder is removed
+	_, err = os.Stat(ingestFolder)
+	assert.True(t, os.IsNotExist(err))
+
+	// After finalising, the keys are written directly
+	doc, err := NewDocument("d-100", "Report", map[string]string{})
+	assert.NoError(t, err)
+	assert.NoError(t, store.AddDocument(doc))
+
+	found, err = store.HasDocument(&doc)
+	assert.NoError(t, err)
+	assert.True(t, found)
+}
+
+func TestPebbleBipartiteBulkIngestDiscarded(t *testing.T) {
+	store := newBipartitePebbleStore(t)
+	defer cleanUpBipartitePebbleStore(t, store)
+
+	assert.NoError(t, store.EnableBulkIngest(DefaultIngestBufferBytes))
+	ingestFolder := store.ingester.folder
+
+	entity, err := NewEntity("e-1", "Person", map[string]string{})
+	assert.NoError(t, err)
+	assert.NoError(t, store.AddEntity(entity))
+
+	// Clearing the store discards the keys that haven't been ingested
+	assert.NoError(t, store.Clear())
+	assert.NoError(t, store.Finalise())
+
+	n, err := store.NumberOfEntities()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+
+	_, err = os.Stat(ingestFolder)
+	assert.True(t, os.IsNotExist(err))
+}
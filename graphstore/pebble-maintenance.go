@@ -0,0 +1,194 @@
+// Maintenance of the Pebble stores. After several incremental loads (or the removal of documents)
+// a Pebble store can hold a lot of obsolete data on disk until the data is compacted. The store's
+// report shows its size on disk, the number of keys for each key prefix and Pebble's metrics, and
+// a manual compaction can be triggered.
+
+package graphstore
+
+import (
+	"io/fs"
+	"path/filepath"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cockroachdb/pebble"
+)
+
+// PrefixUsage of the keys with a prefix in a Pebble store.
+type PrefixUsage struct {
+	Prefix       string `json:"prefix"`       // Key prefix, e.g. e
+	Description  string `json:"description"`  // What the keys hold, e.g. edges
+	NumberOfKeys int    `json:"numberOfKeys"` // Number of keys with the prefix
+	DiskBytes    uint64 `json:"diskBytes"`    // Estimated size (bytes) of the keys on disk
+}
+
+// A StoreReport describes the usage of the disk by a Pebble store.
+type StoreReport struct {
+	Folder    string        `json:"folder"`    // Folder of the Pebble files
+	ReadOnly  bool          `json:"readOnly"`  // Was the store opened in read-only mode?
+	DiskBytes int64         `json:"diskBytes"` // Size (bytes) of the files in the folder
+	Prefixes  []PrefixUsage `json:"prefixes"`  // Usage of each key prefix
+	Metrics   string        `json:"metrics"`   // Pebble's metrics as a table
+}
+
+// A MaintainableStore is a graph store that can report its usage of the disk and be compacted.
+type MaintainableStore interface {
+	Report() (*StoreReport, error) // Report the usage of the disk
+	Compact() error                // Compact all of the keys
+}
+
+// keyPrefix of a Pebble store and a description of what the keys hold.
+type keyPrefix struct {
+	prefix      string
+	description string
+}
+
+// Key prefixes of the Pebble bipartite store
+var bipartiteKeyPrefixes = []keyPrefix{
+	{entityPrefix, "entities"},
+	{documentPrefix, "documents"},
+	{entityDocumentLinkPrefix, "entity to document links"},
+	{documentEntityLinkPrefix, "document to entity links"},
+}
+
+// Key prefixes of the Pebble unipartite store
+var unipartiteKeyPrefixes = []keyPrefix{
+	{nodePrefix, "entities without an outgoing edge"},
+	{edgePrefix, "edges"},
+	{reversePrefix, "reverse directed edges"},
+	{metadataPrefix, "edge metadata"},
+}
+
+// folderSize returns the total size (bytes) of the files in the folder.
+func folderSize(folder string) (int64, error) {
+	var size int64
+
+	err := filepath.WalkDir(folder, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		size += info.Size()
+		return nil
+	})
+
+	return size, err
+}
+
+// countKeys in the range from lower (inclusive) to upper (exclusive).
+func countKeys(db *pebble.DB, lower []byte, upper []byte) (int, error) {
+
+	iter := db.NewIter(&pebble.IterOptions{
+		LowerBound: lower,
+		UpperBound: upper,
+	})
+
+	count := 0
+	for iter.First(); iter.Valid(); iter.Next() {
+		count += 1
+	}
+
+	return count, iter.Close()
+}
+
+// pebbleReport of the Pebble database in the folder with the key prefixes.
+func pebbleReport(db *pebble.DB, folder string, readOnly bool,
+	prefixes []keyPrefix) (*StoreReport, error) {
+
+	diskBytes, err := folderSize(folder)
+	if err != nil {
+		return nil, err
+	}
+
+	report := StoreReport{
+		Folder:    folder,
+		ReadOnly:  readOnly,
+		DiskBytes: diskBytes,
+		Prefixes:  []PrefixUsage{},
+		Metrics:   db.Metrics().String(),
+	}
+
+	for _, p := range prefixes {
+		lower := []byte(p.prefix + separator)
+		upper := []byte(p.prefix + separatorPlusOne)
+
+		numberOfKeys, err := countKeys(db, lower, upper)
+		if err != nil {
+			return nil, err
+		}
+
+		usage, err := db.EstimateDiskUsage(lower, upper)
+		if err != nil {
+			return nil, err
+		}
+
+		report.Prefixes = append(report.Prefixes, PrefixUsage{
+			Prefix:       p.prefix,
+			Description:  p.description,
+			NumberOfKeys: numberOfKeys,
+			DiskBytes:    usage,
+		})
+	}
+
+	return &report, nil
+}
+
+// compactPebble compacts all of the keys of the Pebble database, which removes the obsolete data
+// from the disk.
+func compactPebble(db *pebble.DB, folder string) error {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("folder", folder).
+		Msg("Compacting the Pebble store")
+
+	start := time.Now()
+	if err := db.Compact([]byte{}, []byte{0xff}, true); err != nil {
+		return err
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("folder", folder).
+		Str("timeTaken", time.Since(start).String()).
+		Msg("Compacted the Pebble store")
+
+	return nil
+}
+
+// Report the usage of the disk by the Pebble bipartite store.
+func (p *PebbleBipartiteGraphStore) Report() (*StoreReport, error) {
+	return pebbleReport(p.db, p.folder, p.readOnly, bipartiteKeyPrefixes)
+}
+
+// Compact all of the keys of the Pebble bipartite store.
+func (p *PebbleBipartiteGraphStore) Compact() error {
+	if p.readOnly {
+		return ErrStoreIsReadOnly
+	}
+
+	return compactPebble(p.db, p.folder)
+}
+
+// Report the usage of the disk by the Pebble unipartite store.
+func (p *PebbleUnipartiteGraphStore) Report() (*StoreReport, error) {
+	return pebbleReport(p.db, p.folder, p.readOnly, unipartiteKeyPrefixes)
+}
+
+// Compact all of the keys of the Pebble unipartite store.
+func (p *PebbleUnipartiteGraphStore) Compact() error {
+	if p.readOnly {
+		return ErrStoreIsReadOnly
+	}
+
+	return compactPebble(p.db, p.folder)
+}
@@ -0,0 +1,102 @@
+package graphstore
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// prefixKeys returns the number of keys for each prefix in the report.
+func prefixKeys(report *StoreReport) map[string]int {
+	keys := map[string]int{}
+	for _, usage := range report.Prefixes {
+		keys[usage.Prefix] = usage.NumberOfKeys
+	}
+	return keys
+}
+
+func TestPebbleBipartiteMaintenance(t *testing.T) {
+	store := newBipartitePebbleStore(t)
+	defer cleanUpBipartitePebbleStore(t, store)
+
+	var _ MaintainableStore = store
+
+	for _, entityId := range []string{"e-1", "e-2"} {
+		entity, err := NewEntity(entityId, "Person", map[string]string{})
+		assert.NoError(t, err)
+		assert.NoError(t, store.AddEntity(entity))
+	}
+
+	doc, err := NewDocument("d-1", "Doc", map[string]string{})
+	assert.NoError(t, err)
+	assert.NoError(t, store.AddDocument(doc))
+	assert.NoError(t, store.AddLink(NewLink("e-1", "d-1")))
+	assert.NoError(t, store.Finalise())
+
+	report, err := store.Report()
+	assert.NoError(t, err)
+	assert.Equal(t, store.folder, report.Folder)
+	assert.False(t, report.ReadOnly)
+	assert.True(t, report.DiskBytes > 0)
+	assert.NotEmpty(t, report.Metrics)
+	assert.Equal(t, map[string]int{
+		entityPrefix:             2,
+		documentPrefix:           1,
+		entityDocumentLinkPrefix: 1,
+		documentEntityLinkPrefix: 1,
+	}, prefixKeys(report))
+
+	// Compaction doesn't change the data
+	assert.NoError(t, store.RemoveEntity("e-2"))
+	assert.NoError(t, store.Compact())
+
+	report, err = store.Report()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, prefixKeys(report)[entityPrefix])
+
+	found, err := store.HasEntityWithId("e-1")
+	assert.NoError(t, err)
+	assert.True(t, found)
+}
+
+func TestPebbleUnipartiteMaintenance(t *testing.T) {
+
+	folder := createTempPebbleFolder(t)
+	defer deleteTempPebbleFolder(t, folder)
+
+	store, err := NewPebbleUnipartiteGraphStore(folder)
+	assert.NoError(t, err)
+
+	var _ MaintainableStore = store
+
+	assert.NoError(t, store.AddUndirected("e-1", "e-2"))
+	assert.NoError(t, store.AddDirected("e-2", "e-3"))
+	assert.NoError(t, store.AddEntity("e-4"))
+	assert.NoError(t, store.Finalise())
+
+	report, err := store.Report()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]int{
+		nodePrefix:     2,
+		edgePrefix:     3,
+		reversePrefix:  1,
+		metadataPrefix: 0,
+	}, prefixKeys(report))
+
+	assert.NoError(t, store.Compact())
+	exists, err := store.EdgeExists("e-1", "e-2")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	assert.NoError(t, store.Close())
+
+	// A read-only store can be reported on, but not compacted
+	replica, err := NewReadOnlyPebbleUnipartiteGraphStore(folder)
+	assert.NoError(t, err)
+	defer replica.Close()
+
+	report, err = replica.Report()
+	assert.NoError(t, err)
+	assert.True(t, report.ReadOnly)
+	assert.Equal(t, 3, prefixKeys(report)[edgePrefix])
+	assert.ErrorIs(t, replica.Compact(), ErrStoreIsReadOnly)
+}
@@ -0,0 +1,136 @@
+// Read-only Pebble stores allow several processes (e.g. replicas of the web-app) to serve queries
+// from the same pre-built Pebble folder, or from a snapshot of one.
+//
+// Pebble takes an exclusive lock on its folder when a database is opened, even in read-only mode,
+// so only one process could use a folder at a time. A read-only store skips the lock instead. This
+// is safe because a read-only store never writes to the folder. However, no process may write to
+// the folder whilst read-only stores have it open.
+
+package graphstore
+
+import (
+	"errors"
+	"io"
+
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cockroachdb/pebble"
+	"github.com/cockroachdb/pebble/vfs"
+)
+
+var (
+	ErrStoreIsReadOnly = errors.New("graph store is read-only")
+)
+
+// readOnlyFS is a Pebble file system that doesn't lock the database folder.
+type readOnlyFS struct {
+	vfs.FS
+}
+
+// noOpLock is returned in place of a file lock.
+type noOpLock struct{}
+
+// Close the (no-op) lock.
+func (noOpLock) Close() error {
+	return nil
+}
+
+// Lock returns a lock that doesn't prevent other processes from opening the folder.
+func (readOnlyFS) Lock(name string) (io.Closer, error) {
+	return noOpLock{}, nil
+}
+
+// openReadOnlyPebbleDb opens an existing Pebble database in the folder for reading.
+func openReadOnlyPebbleDb(folder string, tuning PebbleTuning) (*pebble.DB, error) {
+
+	if len(folder) == 0 {
+		return nil, errors.New("folder name is empty")
+	}
+
+	options := &pebble.Options{
+		FS:               readOnlyFS{vfs.Default},
+		ReadOnly:         true,
+		ErrorIfNotExists: true,
+		DisableWAL:       true,
+		Levels:           pebbleLevelOptions(),
+	}
+
+	release, err := tuning.apply(options)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return pebble.Open(folder, options)
+}
+
+// NewReadOnlyPebbleUnipartiteGraphStore opens the existing Pebble unipartite store in the folder
+// for reading only.
+func NewReadOnlyPebbleUnipartiteGraphStore(folder string) (*PebbleUnipartiteGraphStore, error) {
+	return NewReadOnlyPebbleUnipartiteGraphStoreWithTuning(folder, PebbleTuning{})
+}
+
+// NewReadOnlyPebbleUnipartiteGraphStoreWithTuning opens the existing Pebble unipartite store in the
+// folder for reading only with the tuning of the Pebble options, e.g. a larger block cache.
+func NewReadOnlyPebbleUnipartiteGraphStoreWithTuning(folder string, tuning PebbleTuning) (
+	*PebbleUnipartiteGraphStore, error) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("folder", folder).
+		Msg("Opening unipartite Pebble store in read-only mode")
+
+	db, err := openReadOnlyPebbleDb(folder, tuning)
+	if err != nil {
+		return nil, err
+	}
+
+	store := &PebbleUnipartiteGraphStore{
+		folder:   folder,
+		db:       db,
+		readOnly: true,
+		counts:   newPebbleCounts(),
+	}
+
+	if err := store.openExistenceIndex(tuning); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return store, nil
+}
+
+// NewReadOnlyPebbleBipartiteGraphStore opens the existing Pebble bipartite store in the folder
+// for reading only.
+func NewReadOnlyPebbleBipartiteGraphStore(folder string) (*PebbleBipartiteGraphStore, error) {
+	return NewReadOnlyPebbleBipartiteGraphStoreWithTuning(folder, PebbleTuning{})
+}
+
+// NewReadOnlyPebbleBipartiteGraphStoreWithTuning opens the existing Pebble bipartite store in the
+// folder for reading only with the tuning of the Pebble options, e.g. a larger block cache.
+func NewReadOnlyPebbleBipartiteGraphStoreWithTuning(folder string, tuning PebbleTuning) (
+	*PebbleBipartiteGraphStore, error) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("folder", folder).
+		Msg("Opening bipartite Pebble store in read-only mode")
+
+	db, err := openReadOnlyPebbleDb(folder, tuning)
+	if err != nil {
+		return nil, err
+	}
+
+	store := &PebbleBipartiteGraphStore{
+		folder:   folder,
+		db:       db,
+		readOnly: true,
+		counts:   newPebbleCounts(),
+	}
+
+	if err := store.openExistenceIndex(tuning); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return store, nil
+}
@@ -0,0 +1,112 @@
+package graphstore
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadOnlyPebbleUnipartiteGraphStore(t *testing.T) {
+
+	// Build a unipartite store and close it
+	folder := createTempPebbleFolder(t)
+	defer deleteTempPebbleFolder(t, folder)
+
+	store, err := NewPebbleUnipartiteGraphStore(folder)
+	assert.NoError(t, err)
+	assert.NoError(t, store.AddUndirected("e-1", "e-2"))
+	assert.NoError(t, store.AddEntity("e-3"))
+	assert.NoError(t, store.Finalise())
+	assert.NoError(t, store.Close())
+
+	// Open the same folder twice in read-only mode (as two replicas would)
+	replica1, err := NewReadOnlyPebbleUnipartiteGraphStore(folder)
+	assert.NoError(t, err)
+	replica2, err := NewReadOnlyPebbleUnipartiteGraphStore(folder)
+	assert.NoError(t, err)
+
+	for _, replica := range []*PebbleUnipartiteGraphStore{replica1, replica2} {
+		exists, err := replica.EdgeExists("e-1", "e-2")
+		assert.NoError(t, err)
+		assert.True(t, exists)
+
+		numberEntities, err := replica.NumberEntities()
+		assert.NoError(t, err)
+		assert.Equal(t, 3, numberEntities)
+
+		// The store can't be modified
+		assert.Error(t, replica.AddEntity("e-4"))
+		assert.ErrorIs(t, replica.RemoveEntity("e-1"), ErrStoreIsReadOnly)
+		assert.ErrorIs(t, replica.RemoveEdge("e-1", "e-2"), ErrStoreIsReadOnly)
+		assert.ErrorIs(t, replica.Clear(), ErrStoreIsReadOnly)
+		assert.ErrorIs(t, replica.Destroy(), ErrStoreIsReadOnly)
+		assert.NoError(t, replica.Finalise())
+	}
+
+	assert.NoError(t, replica1.Close())
+	assert.NoError(t, replica2.Close())
+
+	// The files must still exist
+	_, err = os.Stat(folder)
+	assert.NoError(t, err)
+}
+
+func TestReadOnlyPebbleBipartiteGraphStore(t *testing.T) {
+
+	// Build a bipartite store and close it
+	folder := createTempPebbleFolder(t)
+	defer deleteTempPebbleFolder(t, folder)
+
+	store, err := NewPebbleBipartiteGraphStore(folder)
+	assert.NoError(t, err)
+
+	e1, err := NewEntity("e-1", "Person", map[string]string{"Name": "Bob Smith"})
+	assert.NoError(t, err)
+	assert.NoError(t, store.AddEntity(e1))
+
+	d1, err := NewDocument("d-1", "Doc", map[string]string{"Title": "Summary"})
+	assert.NoError(t, err)
+	assert.NoError(t, store.AddDocument(d1))
+	assert.NoError(t, store.AddLink(NewLink("e-1", "d-1")))
+	assert.NoError(t, store.Finalise())
+	assert.NoError(t, store.Close())
+
+	// Open the same folder twice in read-only mode
+	replica1, err := NewReadOnlyPebbleBipartiteGraphStore(folder)
+	assert.NoError(t, err)
+	replica2, err := NewReadOnlyPebbleBipartiteGraphStore(folder)
+	assert.NoError(t, err)
+
+	for _, replica := range []*PebbleBipartiteGraphStore{replica1, replica2} {
+		entity, err := replica.GetEntity("e-1")
+		assert.NoError(t, err)
+		assert.True(t, entity.LinkedDocumentIds.Has("d-1"))
+
+		numberDocuments, err := replica.NumberOfDocuments()
+		assert.NoError(t, err)
+		assert.Equal(t, 1, numberDocuments)
+
+		// The store can't be modified
+		assert.Error(t, replica.AddEntity(e1))
+		assert.ErrorIs(t, replica.RemoveEntity("e-1"), ErrStoreIsReadOnly)
+		assert.ErrorIs(t, replica.RemoveDocument("d-1"), ErrStoreIsReadOnly)
+		assert.ErrorIs(t, replica.RemoveLink(NewLink("e-1", "d-1")), ErrStoreIsReadOnly)
+		assert.ErrorIs(t, replica.Clear(), ErrStoreIsReadOnly)
+		assert.ErrorIs(t, replica.Destroy(), ErrStoreIsReadOnly)
+	}
+
+	assert.NoError(t, replica1.Close())
+	assert.NoError(t, replica2.Close())
+}
+
+func TestReadOnlyPebbleStoreMissingFolder(t *testing.T) {
+	folder := createTempPebbleFolder(t)
+	deleteTempPebbleFolder(t, folder)
+
+	_, err := NewReadOnlyPebbleUnipartiteGraphStore(folder)
+	assert.Error(t, err)
+
+	_, err = NewReadOnlyPebbleBipartiteGraphStore("")
+	assert.Error(t, err)
+}
@@ -0,0 +1,87 @@
+package graphstore
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/cockroachdb/pebble"
+)
+
+// Compression algorithms of the blocks of a Pebble store
+const (
+	CompressionSnappy = "snappy" // Pebble's default
+	CompressionZstd   = "zstd"   // Smaller files at the cost of more CPU
+	CompressionNone   = "none"
+)
+
+var ErrInvalidPebbleTuning = errors.New("invalid Pebble tuning")
+
+// pebbleCompressions are the Pebble compression algorithms by name
+var pebbleCompressions = map[string]pebble.Compression{
+	CompressionSnappy: pebble.SnappyCompression,
+	CompressionZstd:   pebble.ZstdCompression,
+	CompressionNone:   pebble.NoCompression,
+}
+
+// PebbleTuning of the options of a Pebble store, where a zero value leaves an option at the
+// store's default. The block cache is the most effective for a read-heavy store on a machine with
+// spare memory, as Pebble's default cache is only 8 MB.
+type PebbleTuning struct {
+	CacheSizeMB    int    `json:"cacheSizeMB"`    // Size of the block cache (0 for the default)
+	MemTableSizeMB int    `json:"memTableSizeMB"` // Size of each memtable (0 for the default)
+	Compression    string `json:"compression"`    // snappy, zstd or none (blank for the default)
+	MaxOpenFiles   int    `json:"maxOpenFiles"`   // Maximum number of open files (0 for the default)
+
+	// Don't hold the in-memory index of the entity IDs, e.g. to save memory for a huge store
+	DisableExistenceIndex bool `json:"disableExistenceIndex"`
+}
+
+// Validate the tuning.
+func (t PebbleTuning) Validate() error {
+
+	if t.CacheSizeMB < 0 || t.MemTableSizeMB < 0 || t.MaxOpenFiles < 0 {
+		return fmt.Errorf("%w: sizes and the maximum number of open files can't be negative",
+			ErrInvalidPebbleTuning)
+	}
+
+	if _, found := pebbleCompressions[t.Compression]; len(t.Compression) > 0 && !found {
+		return fmt.Errorf("%w: unknown compression: %v", ErrInvalidPebbleTuning, t.Compression)
+	}
+
+	return nil
+}
+
+// apply the tuning to the options. If there is a block cache, the caller must call the returned
+// function after opening the store, as Pebble holds its own reference to the cache.
+func (t PebbleTuning) apply(options *pebble.Options) (func(), error) {
+
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+
+	if t.MemTableSizeMB > 0 {
+		options.MemTableSize = t.MemTableSizeMB << 20
+	}
+
+	if t.MaxOpenFiles > 0 {
+		options.MaxOpenFiles = t.MaxOpenFiles
+	}
+
+	// The last level's options are used for any levels that aren't given
+	if len(t.Compression) > 0 {
+		if len(options.Levels) == 0 {
+			options.Levels = []pebble.LevelOptions{{}}
+		}
+		for idx := range options.Levels {
+			options.Levels[idx].Compression = pebbleCompressions[t.Compression]
+		}
+	}
+
+	if t.CacheSizeMB == 0 {
+		return func() {}, nil
+	}
+
+	cache := pebble.NewCache(int64(t.CacheSizeMB) << 20)
+	options.Cache = cache
+	return cache.Unref, nil
+}
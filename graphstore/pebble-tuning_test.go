@@ -0,0 +1,90 @@
+package graphstore
+
+import (
+	"testing"
+
+	"github.com/cockroachdb/pebble"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPebbleTuningValidate(t *testing.T) {
+	assert.NoError(t, PebbleTuning{}.Validate())
+	assert.NoError(t, PebbleTuning{CacheSizeMB: 1024, MemTableSizeMB: 128, Compression: CompressionZstd,
+		MaxOpenFiles: 5000}.Validate())
+
+	assert.ErrorIs(t, PebbleTuning{CacheSizeMB: -1}.Validate(), ErrInvalidPebbleTuning)
+	assert.ErrorIs(t, PebbleTuning{MemTableSizeMB: -1}.Validate(), ErrInvalidPebbleTuning)
+	assert.ErrorIs(t, PebbleTuning{MaxOpenFiles: -1}.Validate(), ErrInvalidPebbleTuning)
+	assert.ErrorIs(t, PebbleTuning{Compression: "lz4"}.Validate(), ErrInvalidPebbleTuning)
+}
+
+func TestPebbleTuningApply(t *testing.T) {
+
+	// Without any tuning, the options are unchanged
+	options := &pebble.Options{MemTableSize: 64 << 20}
+	release, err := PebbleTuning{}.apply(options)
+	assert.NoError(t, err)
+	release()
+	assert.Equal(t, &pebble.Options{MemTableSize: 64 << 20}, options)
+
+	tuning := PebbleTuning{
+		CacheSizeMB:    16,
+		MemTableSizeMB: 32,
+		Compression:    CompressionNone,
+		MaxOpenFiles:   2000,
+	}
+
+	release, err = tuning.apply(options)
+	assert.NoError(t, err)
+	defer release()
+
+	assert.Equal(t, int64(16<<20), options.Cache.MaxSize())
+	assert.Equal(t, 32<<20, options.MemTableSize)
+	assert.Equal(t, 2000, options.MaxOpenFiles)
+	assert.Equal(t, pebble.NoCompression, options.EnsureDefaults().Level(6).Compression)
+
+	_, err = PebbleTuning{Compression: "lz4"}.apply(options)
+	assert.ErrorIs(t, err, ErrInvalidPebbleTuning)
+}
+
+func TestTunedPebbleStores(t *testing.T) {
+
+	folder := createTempPebbleFolder(t)
+	defer deleteTempPebbleFolder(t, folder)
+
+	tuning := PebbleTuning{CacheSizeMB: 16, Compression: CompressionZstd}
+
+	_, err := NewPebbleUnipartiteGraphStoreWithTuning(folder, PebbleTuning{CacheSizeMB: -1})
+	assert.ErrorIs(t, err, ErrInvalidPebbleTuning)
+
+	store, err := NewPebbleUnipartiteGraphStoreWithTuning(folder, tuning)
+	assert.NoError(t, err)
+	assert.NoError(t, store.AddUndirected("e-1", "e-2"))
+	assert.NoError(t, store.Finalise())
+	assert.NoError(t, store.Close())
+
+	replica, err := NewReadOnlyPebbleUnipartiteGraphStoreWithTuning(folder, tuning)
+	assert.NoError(t, err)
+	exists, err := replica.EdgeExists("e-1", "e-2")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	assert.NoError(t, replica.Close())
+
+	bipartiteFolder := createTempPebbleFolder(t)
+	defer deleteTempPebbleFolder(t, bipartiteFolder)
+
+	bipartite, err := NewPebbleBipartiteGraphStoreWithTuning(bipartiteFolder, tuning)
+	assert.NoError(t, err)
+	entity, err := NewEntity("e-1", "Person", map[string]string{})
+	assert.NoError(t, err)
+	assert.NoError(t, bipartite.AddEntity(entity))
+	assert.NoError(t, bipartite.Finalise())
+	assert.NoError(t, bipartite.Close())
+
+	readOnly, err := NewReadOnlyPebbleBipartiteGraphStoreWithTuning(bipartiteFolder, tuning)
+	assert.NoError(t, err)
+	found, err := readOnly.GetEntity("e-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "Person", found.EntityType)
+	assert.NoError(t, readOnly.Close())
+}
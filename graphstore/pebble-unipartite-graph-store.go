@@ -15,14 +15,30 @@
 // entity without a connection:
 //
 // n#<entity ID>
+//
+// A directed edge (i.e. one without an edge in the opposite direction) is also stored in reverse,
+// so that the entities with an edge to an entity can be found:
+//
+// r#<dst entity ID>#<src entity ID>
+//
+// The metadata of an edge (the number of documents linking the entities and the date of the most
+// recent document) is populated during the bipartite to unipartite conversion and held in:
+//
+// m#<src entity ID>#<dst entity ID>
+//
+// The metadata is held separately from the edge so that adding an edge doesn't need to read the
+// existing value.
 
 package graphstore
 
 import (
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"os"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/cdclaxton/shortest-path-web-app/logging"
 	"github.com/cdclaxton/shortest-path-web-app/set"
@@ -33,6 +49,8 @@ import (
 const (
 	nodePrefix       = "n"
 	edgePrefix       = "e"
+	reversePrefix    = "r"
+	metadataPrefix   = "m"
 	separator        = "#"
 	separatorPlusOne = "$"
 )
@@ -47,12 +65,31 @@ var (
 
 // A PebbleUnipartiteGraphStore is a Pebble-backed unipartite graph store.
 type PebbleUnipartiteGraphStore struct {
-	folder string     // Folder for the Pebble files
-	db     *pebble.DB // Pebble database
+	folder   string     // Folder for the Pebble files
+	db       *pebble.DB // Pebble database
+	readOnly bool       // Was the store opened in read-only mode?
+
+	// Entity IDs that may be in the store (nil if disabled)
+	index *existenceIndex
+
+	// Count of the entities
+	counts *pebbleCounts
+
+	// Locks for updating the edge metadata, where the lock for an edge is chosen by its key
+	metadataLocks [numMetadataLocks]sync.Mutex
 }
 
+// Number of locks for updating the edge metadata concurrently
+const numMetadataLocks = 64
+
 // NewPebbleUnipartiteGraphStore given the folder in which to store the Pebble files.
 func NewPebbleUnipartiteGraphStore(folder string) (*PebbleUnipartiteGraphStore, error) {
+	return NewPebbleUnipartiteGraphStoreWithTuning(folder, PebbleTuning{})
+}
+
+// NewPebbleUnipartiteGraphStoreWithTuning given the folder and the tuning of the Pebble options.
+func NewPebbleUnipartiteGraphStoreWithTuning(folder string, tuning PebbleTuning) (
+	*PebbleUnipartiteGraphStore, error) {
 
 	if len(folder) == 0 {
 		return nil, errors.New("folder name is empty")
@@ -63,7 +100,7 @@ func NewPebbleUnipartiteGraphStore(folder string) (*PebbleUnipartiteGraphStore,
 		Str("folder", folder).
 		Msg("Opening unipartite Pebble store")
 
-	db, err := pebble.Open(folder, &pebble.Options{
+	options := &pebble.Options{
 		FS:                          vfs.Default,
 		L0CompactionThreshold:       2,
 		L0StopWritesThreshold:       1000,
@@ -72,7 +109,16 @@ func NewPebbleUnipartiteGraphStore(folder string) (*PebbleUnipartiteGraphStore,
 		MemTableSize:                64 << 20, // 64 MB
 		MemTableStopWritesThreshold: 4,
 		DisableWAL:                  true,
-	})
+		Levels:                      pebbleLevelOptions(),
+	}
+
+	release, err := tuning.apply(options)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	db, err := pebble.Open(folder, options)
 	if err != nil {
 		return nil, err
 	}
@@ -80,6 +126,12 @@ func NewPebbleUnipartiteGraphStore(folder string) (*PebbleUnipartiteGraphStore,
 	store := PebbleUnipartiteGraphStore{
 		folder: folder,
 		db:     db,
+		counts: newPebbleCounts(),
+	}
+
+	if err := store.openExistenceIndex(tuning); err != nil {
+		db.Close()
+		return nil, err
 	}
 
 	return &store, nil
@@ -97,6 +149,14 @@ func (p *PebbleUnipartiteGraphStore) Clear() error {
 		Str(logging.ComponentField, componentName).
 		Msg("Clearing the Pebble unipartite store")
 
+	if p.readOnly {
+		return ErrStoreIsReadOnly
+	}
+
+	if err := p.counts.invalidate(p.db); err != nil {
+		return err
+	}
+
 	var deleteError error
 
 	// As soon as there is an error when deleting a key, stop the iteration
@@ -121,6 +181,11 @@ func (p *PebbleUnipartiteGraphStore) Destroy() error {
 		Str(logging.ComponentField, componentName).
 		Msg("Destroying the Pebble unipartite store")
 
+	// A read-only store may be shared, so its files must not be deleted
+	if p.readOnly {
+		return ErrStoreIsReadOnly
+	}
+
 	err := p.Close()
 	if err != nil {
 		return err
@@ -130,6 +195,10 @@ func (p *PebbleUnipartiteGraphStore) Destroy() error {
 }
 
 func (p *PebbleUnipartiteGraphStore) Finalise() error {
+	if p.readOnly {
+		return nil
+	}
+
 	return p.db.Flush()
 }
 
@@ -188,6 +257,51 @@ func pebbleKeyToEdge(key []byte) (string, string, error) {
 	return src, dst, nil
 }
 
+// reverseEdgeToPebbleKey returns the Pebble key for the reverse of a directed edge.
+func reverseEdgeToPebbleKey(src string, dst string) ([]byte, error) {
+
+	key, err := edgeToPebbleKey(dst, src)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(reversePrefix), key[len(edgePrefix):]...), nil
+}
+
+// pebbleKeyToReverseEdge returns the source and destination nodes for a key representing the
+// reverse of a directed edge.
+func pebbleKeyToReverseEdge(key []byte) (string, string, error) {
+
+	if !strings.HasPrefix(string(key), reversePrefix+separator) {
+		return "", "", fmt.Errorf("%w: %v is not a reverse edge", ErrMalformedKey, string(key))
+	}
+
+	dst, src, err := pebbleKeyToEdge(append([]byte(edgePrefix), key[len(reversePrefix):]...))
+	if err != nil {
+		return "", "", err
+	}
+
+	return src, dst, nil
+}
+
+// edgeMetadataToPebbleKey returns the Pebble key for the metadata of an edge.
+func edgeMetadataToPebbleKey(src string, dst string) ([]byte, error) {
+
+	key, err := edgeToPebbleKey(src, dst)
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(metadataPrefix), key[len(edgePrefix):]...), nil
+}
+
+// metadataLockIndex returns the index of the lock to hold when updating the metadata with the key.
+func metadataLockIndex(key []byte) int {
+	h := fnv.New32a()
+	h.Write(key)
+	return int(h.Sum32() % numMetadataLocks)
+}
+
 // nodeToPebbleKey returns the Pebble key for a node.
 func nodeToPebbleKey(node string) ([]byte, error) {
 
@@ -227,31 +341,129 @@ func (p *PebbleUnipartiteGraphStore) AddEntity(id string) error {
 		return err
 	}
 
+	if err := p.counts.invalidate(p.db); err != nil {
+		return err
+	}
+
+	p.index.add(id)
 	return p.db.Set(key, nil, pebble.NoSync)
 }
 
-// AddDirected edge between the source (src) and destination (dst) vertices.
-func (p *PebbleUnipartiteGraphStore) AddDirected(src string, dst string) error {
+// putEdge stores the key for an edge between the source (src) and destination (dst) vertices.
+func (p *PebbleUnipartiteGraphStore) putEdge(src string, dst string) error {
 
 	key, err := edgeToPebbleKey(src, dst)
 	if err != nil {
 		return err
 	}
 
+	if err := p.counts.invalidate(p.db); err != nil {
+		return err
+	}
+
+	p.index.add(src)
 	return p.db.Set(key, nil, pebble.NoSync)
 }
 
+// AddDirected edge between the source (src) and destination (dst) vertices. The destination is
+// added as an entity, so that it is held in the graph even if it has no outgoing edges.
+func (p *PebbleUnipartiteGraphStore) AddDirected(src string, dst string) error {
+
+	if err := p.putEdge(src, dst); err != nil {
+		return err
+	}
+
+	key, err := reverseEdgeToPebbleKey(src, dst)
+	if err != nil {
+		return err
+	}
+
+	if err := p.db.Set(key, nil, pebble.NoSync); err != nil {
+		return err
+	}
+
+	return p.AddEntity(dst)
+}
+
 // AddUndirected edge between two entities.
 func (p *PebbleUnipartiteGraphStore) AddUndirected(src string, dst string) error {
 
 	// Add the src --> dst connection
-	err := p.AddDirected(src, dst)
+	err := p.putEdge(src, dst)
 	if err != nil {
 		return err
 	}
 
 	// Add the src <-- dst connection
-	return p.AddDirected(dst, src)
+	return p.putEdge(dst, src)
+}
+
+// RemoveEntity and its edges in either direction from the unipartite graph store. The entities it
+// was connected to are kept.
+func (p *PebbleUnipartiteGraphStore) RemoveEntity(id string) error {
+
+	if p.readOnly {
+		return ErrStoreIsReadOnly
+	}
+
+	found, err := p.HasEntity(id)
+	if err != nil {
+		return err
+	}
+
+	if !found {
+		return entityNotFound(id)
+	}
+
+	connectedIds, err := p.EntityIdsConnectedTo(id)
+	if err != nil {
+		return err
+	}
+
+	change, err := unipartiteEntityRemovalKeys(id, connectedIds)
+	if err != nil {
+		return err
+	}
+
+	if err := p.counts.invalidate(p.db); err != nil {
+		return err
+	}
+
+	return pebbleApply(p.db, change)
+}
+
+// RemoveEdge between two entities in both directions, including the metadata of the edges. The
+// entities are kept.
+func (p *PebbleUnipartiteGraphStore) RemoveEdge(src string, dst string) error {
+
+	if p.readOnly {
+		return ErrStoreIsReadOnly
+	}
+
+	forward, err := p.EdgeExists(src, dst)
+	if err != nil {
+		return err
+	}
+
+	backward, err := p.EdgeExists(dst, src)
+	if err != nil {
+		return err
+	}
+
+	if !forward && !backward {
+		return ErrEdgeNotFound
+	}
+
+	change, err := edgeRemovalKeys(src, dst)
+	if err != nil {
+		return err
+	}
+
+	if err := p.counts.invalidate(p.db); err != nil {
+		return err
+	}
+
+	return pebbleApply(p.db, change)
 }
 
 // EdgeExists returns true if the two entities are connected.
@@ -279,6 +491,97 @@ func (p *PebbleUnipartiteGraphStore) EdgeExists(src string, dst string) (bool, e
 	return true, nil
 }
 
+// AddEdgeDocument records a document linking the source and destination entities in the metadata
+// of the edge. The date of the document is zero if it isn't known.
+func (p *PebbleUnipartiteGraphStore) AddEdgeDocument(src string, dst string, date time.Time) error {
+	document := EdgeMetadata{}
+	document.addDocument(date)
+	return p.AddEdgeMetadata(src, dst, document)
+}
+
+// AddEdgeMetadata records the documents of the metadata (e.g. a batch of documents) linking the
+// source and destination entities in the metadata of the edge.
+func (p *PebbleUnipartiteGraphStore) AddEdgeMetadata(src string, dst string, documents EdgeMetadata) error {
+
+	key, err := edgeMetadataToPebbleKey(src, dst)
+	if err != nil {
+		return err
+	}
+
+	// The metadata is read, updated and written, so concurrent updates to an edge are serialised
+	lock := &p.metadataLocks[metadataLockIndex(key)]
+	lock.Lock()
+	defer lock.Unlock()
+
+	metadata, err := p.getEdgeMetadata(key)
+	if err != nil {
+		return err
+	}
+
+	if metadata == nil {
+		metadata = &EdgeMetadata{}
+	}
+	metadata.merge(documents)
+
+	return p.db.Set(key, encodeEdgeMetadata(*metadata), pebble.NoSync)
+}
+
+// SetEdgeMetadata replaces the metadata of the edge from the source to the destination entity. Nil
+// metadata removes it.
+func (p *PebbleUnipartiteGraphStore) SetEdgeMetadata(src string, dst string, metadata *EdgeMetadata) error {
+
+	key, err := edgeMetadataToPebbleKey(src, dst)
+	if err != nil {
+		return err
+	}
+
+	lock := &p.metadataLocks[metadataLockIndex(key)]
+	lock.Lock()
+	defer lock.Unlock()
+
+	if metadata == nil {
+		return p.db.Delete(key, pebble.NoSync)
+	}
+
+	return p.db.Set(key, encodeEdgeMetadata(*metadata), pebble.NoSync)
+}
+
+// getEdgeMetadata given its key. If the metadata isn't found, nil is returned.
+func (p *PebbleUnipartiteGraphStore) getEdgeMetadata(key []byte) (*EdgeMetadata, error) {
+
+	value, closer, err := p.db.Get(key)
+	if err == pebble.ErrNotFound {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	metadata, err := decodeEdgeMetadata(value)
+	if err2 := closer.Close(); err == nil {
+		err = err2
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &metadata, nil
+}
+
+// EdgeMetadata of the edge from the source to the destination entity. If no documents have been
+// recorded for the edge, nil is returned.
+func (p *PebbleUnipartiteGraphStore) EdgeMetadata(src string, dst string) (*EdgeMetadata, error) {
+
+	key, err := edgeMetadataToPebbleKey(src, dst)
+	if err != nil {
+		return nil, err
+	}
+
+	return p.getEdgeMetadata(key)
+}
+
 // entityIdsOfNodes returns the entity IDs of nodes.
 func (p *PebbleUnipartiteGraphStore) entityIdsOfNodes() (*set.Set[string], error) {
 
@@ -445,6 +748,14 @@ func (p *PebbleUnipartiteGraphStore) hasEdgeWithSource(id string) (bool, error)
 // HasEntity returns true if the entity ID is held within the backend.
 func (p *PebbleUnipartiteGraphStore) HasEntity(id string) (bool, error) {
 
+	if err := validateEntityId(id); err != nil {
+		return false, err
+	}
+
+	if !p.index.mayContain(id) {
+		return false, nil
+	}
+
 	// Check whether the entity exists on its own
 	found, err := p.hasNode(id)
 	if err != nil {
@@ -463,8 +774,124 @@ func (p *PebbleUnipartiteGraphStore) HasEntity(id string) (bool, error) {
 	return p.hasEdgeWithSource(id)
 }
 
-// NumberEntities in the unipartite graph.
+// A PebbleEdgeIterator walks through the edge keys of the Pebble store and then through the node
+// keys of the entities without outgoing edges.
+type PebbleEdgeIterator struct {
+	store *PebbleUnipartiteGraphStore
+	iter  *pebble.Iterator // Pebble iterator (nil once exhausted)
+	edges bool             // Is the iterator walking through the edge keys?
+	next  *Edge            // Next edge (nil if there isn't one)
+	err   error            // Error finding the next edge
+}
+
+// newPebbleIter for the keys with the prefix, positioned at the first key.
+func (p *PebbleUnipartiteGraphStore) newPebbleIter(prefix string) *pebble.Iterator {
+	iter := p.db.NewIter(&pebble.IterOptions{
+		LowerBound: []byte(prefix + separator),
+		UpperBound: []byte(prefix + separatorPlusOne),
+	})
+	iter.First()
+	return iter
+}
+
+// advance to the next edge or the next entity without edges.
+func (it *PebbleEdgeIterator) advance() {
+
+	it.next = nil
+
+	for it.iter != nil && it.next == nil && it.err == nil {
+
+		if !it.iter.Valid() {
+			it.err = it.iter.Close()
+			it.iter = nil
+
+			// Walk through the entities once the edges are exhausted
+			if it.edges && it.err == nil {
+				it.edges = false
+				it.iter = it.store.newPebbleIter(nodePrefix)
+			}
+			continue
+		}
+
+		if it.edges {
+			var src, dst string
+			src, dst, it.err = pebbleKeyToEdge(it.iter.Key())
+			if it.err == nil {
+				it.next = &Edge{V1: src, V2: dst}
+			}
+		} else {
+			var id string
+			var hasEdges bool
+			id, it.err = pebbleKeyToNode(it.iter.Key())
+			if it.err == nil {
+				hasEdges, it.err = it.store.hasEdgeWithSource(id)
+			}
+			if it.err == nil && !hasEdges {
+				it.next = &Edge{V1: id}
+			}
+		}
+
+		it.iter.Next()
+	}
+}
+
+// HasNext returns true if the iterator has another edge (or an error to return).
+func (it *PebbleEdgeIterator) HasNext() bool {
+	return it.next != nil || it.err != nil
+}
+
+// NextEdge from the iterator.
+func (it *PebbleEdgeIterator) NextEdge() (Edge, error) {
+
+	if it.err != nil {
+		err := it.err
+		it.err = nil
+		it.Close()
+		return Edge{}, err
+	}
+
+	if it.next == nil {
+		return Edge{}, errors.New("iterator is exhausted")
+	}
+
+	edge := *it.next
+	it.advance()
+
+	return edge, nil
+}
+
+// Close the iterator.
+func (it *PebbleEdgeIterator) Close() error {
+	it.next = nil
+
+	if it.iter != nil {
+		err := it.iter.Close()
+		it.iter = nil
+		return err
+	}
+	return nil
+}
+
+// NewEdgeIterator returns an iterator of the edges in the Pebble store.
+func (p *PebbleUnipartiteGraphStore) NewEdgeIterator() (EdgeIterator, error) {
+
+	it := &PebbleEdgeIterator{
+		store: p,
+		iter:  p.newPebbleIter(edgePrefix),
+		edges: true,
+	}
+	it.advance()
+
+	return it, nil
+}
+
+// NumberEntities in the unipartite graph, which are only counted if the store has changed.
 func (p *PebbleUnipartiteGraphStore) NumberEntities() (int, error) {
+	return p.counts.count(p.db, p.readOnly, countEntities, p.countEntities)
+}
+
+// countEntities in the unipartite graph.
+func (p *PebbleUnipartiteGraphStore) countEntities() (int, error) {
 
 	entityIds, err := p.EntityIds()
 	if err != nil {
@@ -473,3 +900,42 @@ func (p *PebbleUnipartiteGraphStore) NumberEntities() (int, error) {
 
 	return entityIds.Len(), nil
 }
+
+// EntityIdsConnectedTo a given entity by an edge in either direction.
+func (p *PebbleUnipartiteGraphStore) EntityIdsConnectedTo(id string) (*set.Set[string], error) {
+
+	connectedIds, err := p.EntityIdsAdjacentTo(id)
+	if err != nil {
+		return nil, err
+	}
+
+	iterOptions := &pebble.IterOptions{
+		LowerBound: []byte(reversePrefix + separator + id + separator),
+		UpperBound: []byte(reversePrefix + separator + id + separatorPlusOne),
+	}
+
+	iter := p.db.NewIter(iterOptions)
+	var errDuringIteration error
+	for iter.First(); iter.Valid() && errDuringIteration == nil; iter.Next() {
+		var src, dst string
+		src, dst, errDuringIteration = pebbleKeyToReverseEdge(iter.Key())
+
+		if errDuringIteration == nil {
+			if dst != id {
+				errDuringIteration = ErrUnexpectedEntityInKey
+			} else {
+				connectedIds.Add(src)
+			}
+		}
+	}
+
+	if err := iter.Close(); err != nil {
+		return nil, err
+	}
+
+	if errDuringIteration != nil {
+		return nil, errDuringIteration
+	}
+
+	return connectedIds, nil
+}
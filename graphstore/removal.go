@@ -0,0 +1,189 @@
+// Removing an entity, document, link or edge from a key-value backed store deletes several keys,
+// e.g. the entity-document and document-entity link keys of an entity. The keys are gathered into a
+// keyChange, which the Pebble and bbolt stores apply in a single batch or transaction, so that a
+// removal isn't left half done. This supports GDPR-style deletions without rebuilding the graph.
+
+package graphstore
+
+import (
+	"fmt"
+
+	"github.com/cdclaxton/shortest-path-web-app/set"
+	"github.com/cockroachdb/pebble"
+)
+
+// A keyChange holds the keys to delete from a store and the keys (with empty values) to put.
+type keyChange struct {
+	deletes [][]byte
+	puts    [][]byte
+}
+
+// remove the key from the store.
+func (k *keyChange) remove(key []byte, err error) error {
+	if err != nil {
+		return err
+	}
+
+	k.deletes = append(k.deletes, key)
+	return nil
+}
+
+// keep the key in the store.
+func (k *keyChange) keep(key []byte, err error) error {
+	if err != nil {
+		return err
+	}
+
+	k.puts = append(k.puts, key)
+	return nil
+}
+
+// entityRemovalKeys of an entity of a bipartite store linked to the documents.
+func entityRemovalKeys(entityId string, documentIds *set.Set[string]) (*keyChange, error) {
+
+	change := &keyChange{}
+	if err := change.remove(entityIdToPebbleKey(entityId)); err != nil {
+		return nil, err
+	}
+
+	for _, documentId := range documentIds.ToSlice() {
+		if err := change.remove(entityDocumentLinkToPebbleKey(entityId, documentId)); err != nil {
+			return nil, err
+		}
+
+		if err := change.remove(documentEntityLinkToPebbleKey(documentId, entityId)); err != nil {
+			return nil, err
+		}
+	}
+
+	return change, nil
+}
+
+// documentRemovalKeys of a document of a bipartite store linked to the entities.
+func documentRemovalKeys(documentId string, entityIds *set.Set[string]) (*keyChange, error) {
+
+	change := &keyChange{}
+	if err := change.remove(documentIdToPebbleKey(documentId)); err != nil {
+		return nil, err
+	}
+
+	for _, entityId := range entityIds.ToSlice() {
+		if err := change.remove(entityDocumentLinkToPebbleKey(entityId, documentId)); err != nil {
+			return nil, err
+		}
+
+		if err := change.remove(documentEntityLinkToPebbleKey(documentId, entityId)); err != nil {
+			return nil, err
+		}
+	}
+
+	return change, nil
+}
+
+// linkRemovalKeys of the link between an entity and a document of a bipartite store.
+func linkRemovalKeys(link Link) (*keyChange, error) {
+
+	change := &keyChange{}
+	if err := change.remove(entityDocumentLinkToPebbleKey(link.EntityId, link.DocumentId)); err != nil {
+		return nil, err
+	}
+
+	if err := change.remove(documentEntityLinkToPebbleKey(link.DocumentId, link.EntityId)); err != nil {
+		return nil, err
+	}
+
+	return change, nil
+}
+
+// addEdgeRemovalKeys for the edges in both directions between two entities of a unipartite store,
+// with their reverse and metadata keys. The entities are kept as nodes, so that they stay in the
+// graph if they no longer have any edges.
+func addEdgeRemovalKeys(change *keyChange, src string, dst string) error {
+
+	for _, edge := range []Edge{{V1: src, V2: dst}, {V1: dst, V2: src}} {
+		if err := change.remove(edgeToPebbleKey(edge.V1, edge.V2)); err != nil {
+			return err
+		}
+
+		if err := change.remove(reverseEdgeToPebbleKey(edge.V1, edge.V2)); err != nil {
+			return err
+		}
+
+		if err := change.remove(edgeMetadataToPebbleKey(edge.V1, edge.V2)); err != nil {
+			return err
+		}
+	}
+
+	if err := change.keep(nodeToPebbleKey(src)); err != nil {
+		return err
+	}
+
+	return change.keep(nodeToPebbleKey(dst))
+}
+
+// edgeRemovalKeys of the edges between two entities of a unipartite store.
+func edgeRemovalKeys(src string, dst string) (*keyChange, error) {
+
+	change := &keyChange{}
+	if err := addEdgeRemovalKeys(change, src, dst); err != nil {
+		return nil, err
+	}
+
+	return change, nil
+}
+
+// unipartiteEntityRemovalKeys of an entity of a unipartite store connected to the entities by an
+// edge in either direction. The connected entities are kept.
+func unipartiteEntityRemovalKeys(entityId string, connectedIds *set.Set[string]) (*keyChange, error) {
+
+	change := &keyChange{}
+
+	for _, connectedId := range connectedIds.ToSlice() {
+		if err := addEdgeRemovalKeys(change, entityId, connectedId); err != nil {
+			return nil, err
+		}
+	}
+
+	// The removed entity isn't kept as a node
+	nodeKey, err := nodeToPebbleKey(entityId)
+	if err != nil {
+		return nil, err
+	}
+
+	puts := [][]byte{}
+	for _, key := range change.puts {
+		if string(key) != string(nodeKey) {
+			puts = append(puts, key)
+		}
+	}
+	change.puts = puts
+	change.deletes = append(change.deletes, nodeKey)
+
+	return change, nil
+}
+
+// entityNotFound error for the entity ID.
+func entityNotFound(entityId string) error {
+	return fmt.Errorf("%w: %v", ErrEntityNotFound, entityId)
+}
+
+// pebbleApply the change to the Pebble database in a single batch.
+func pebbleApply(db *pebble.DB, change *keyChange) error {
+
+	batch := db.NewBatch()
+	defer batch.Close()
+
+	for _, key := range change.deletes {
+		if err := batch.Delete(key, nil); err != nil {
+			return err
+		}
+	}
+
+	for _, key := range change.puts {
+		if err := batch.Set(key, nil, nil); err != nil {
+			return err
+		}
+	}
+
+	return batch.Commit(pebble.NoSync)
+}
@@ -0,0 +1,250 @@
+package graphstore
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cdclaxton/shortest-path-web-app/set"
+)
+
+var ErrInvalidRetentionPolicy = errors.New("invalid retention policy")
+
+// RetentionPolicy defines the maximum age of the documents of each type. Documents older than the
+// maximum age of their type are pruned from the graphs. Documents of types without a maximum age,
+// and documents whose date isn't known, are kept.
+type RetentionPolicy struct {
+	DateAttribute string         `json:"dateAttribute"` // Document attribute holding the date
+	DateFormat    string         `json:"dateFormat"`    // Format of the date in Golang's time format
+	MaxAgeDays    map[string]int `json:"maxAgeDays"`    // Document type to maximum age in days
+}
+
+// Validate the retention policy.
+func (r RetentionPolicy) Validate() error {
+
+	if len(r.DateAttribute) == 0 || len(r.DateFormat) == 0 {
+		return fmt.Errorf("%w: date attribute and format are required", ErrInvalidRetentionPolicy)
+	}
+
+	if len(r.MaxAgeDays) == 0 {
+		return fmt.Errorf("%w: no maximum ages", ErrInvalidRetentionPolicy)
+	}
+
+	for documentType, days := range r.MaxAgeDays {
+		if days < 1 {
+			return fmt.Errorf("%w: invalid maximum age of %v: %d", ErrInvalidRetentionPolicy,
+				documentType, days)
+		}
+	}
+
+	return nil
+}
+
+// isExpired returns true if the document is older than the maximum age of its type at time now.
+func (r RetentionPolicy) isExpired(doc *Document, now time.Time) bool {
+
+	days, found := r.MaxAgeDays[doc.DocumentType]
+	if !found {
+		return false
+	}
+
+	value, found := doc.Attributes[r.DateAttribute]
+	if !found {
+		return false
+	}
+
+	date, err := time.Parse(r.DateFormat, value)
+	if err != nil {
+		return false
+	}
+
+	return date.Before(now.AddDate(0, 0, -days))
+}
+
+// PruneResult summarises a pruning pass.
+type PruneResult struct {
+	DocumentsRemoved int            // Number of expired documents removed from the bipartite store
+	PairsRecomputed  int            // Number of pairs of entities whose edges were recomputed
+	StatsChange      BipartiteStats // Change to the stats of the bipartite store
+	TypesChange      TypeCounts     // Change to the type counts of the bipartite store
+}
+
+// PruneExpiredDocuments removes the documents that have expired at time now from the bipartite
+// store and recomputes the unipartite edges between the entities they linked. An edge is removed
+// if no remaining document links its entities, otherwise its metadata is rebuilt from the
+// remaining documents. The options and skipEntities must be those used to build the unipartite
+// graph.
+func PruneExpiredDocuments(bi BipartiteGraphStore, uni UnipartiteGraphStore,
+	skipEntities *set.Set[string], options ConversionOptions, policy RetentionPolicy,
+	now time.Time) (PruneResult, error) {
+
+	// Preconditions
+	if bi == nil {
+		return PruneResult{}, ErrBipartiteStoreIsNil
+	}
+
+	if uni == nil {
+		return PruneResult{}, ErrUnipartiteStoreIsNil
+	}
+
+	if skipEntities == nil {
+		return PruneResult{}, ErrEntitiesToSkipIsNil
+	}
+
+	if err := policy.Validate(); err != nil {
+		return PruneResult{}, err
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Time("now", now).
+		Msg("Pruning expired documents")
+
+	expired, err := expiredDocuments(bi, policy, now)
+	if err != nil {
+		return PruneResult{}, err
+	}
+
+	// Remove the expired documents, collecting the pairs of entities they linked
+	pairs := map[[2]string]struct{}{}
+	linkedEntityIds := set.NewSet[string]()
+	change := BipartiteStats{}
+	typesChange := TypeCounts{}
+	for _, doc := range expired {
+		if typesChange.DocumentTypes == nil {
+			typesChange.DocumentTypes = map[string]int{}
+		}
+		typesChange.DocumentTypes[doc.DocumentType] -= 1
+
+		change.NumberOfDocuments -= 1
+		if doc.LinkedEntityIds.Len() > 0 {
+			change.NumberOfDocumentsWithEntities -= 1
+		}
+		linkedEntityIds.AddAll(doc.LinkedEntityIds.ToSlice())
+
+		for e1 := range doc.LinkedEntityIds.Values {
+			for e2 := range doc.LinkedEntityIds.Values {
+				if e1 < e2 && !skipEntities.Has(e1) && !skipEntities.Has(e2) {
+					pairs[[2]string{e1, e2}] = struct{}{}
+				}
+			}
+		}
+
+		if err := bi.RemoveDocument(doc.Id); err != nil {
+			return PruneResult{}, err
+		}
+	}
+
+	// Entities are kept, but they may no longer be linked to any documents
+	for entityId := range linkedEntityIds.Values {
+		entity, err := bi.GetEntity(entityId)
+		if errors.Is(err, ErrEntityNotFound) {
+			continue
+		} else if err != nil {
+			return PruneResult{}, err
+		}
+
+		if entity.LinkedDocumentIds.Len() == 0 {
+			change.NumberOfEntitiesWithDocuments -= 1
+		}
+	}
+
+	// Recompute the edges between the pairs of entities from the remaining documents
+	for pair := range pairs {
+		if err := recomputeEdges(pair[0], pair[1], bi, uni, options); err != nil {
+			return PruneResult{}, err
+		}
+	}
+
+	if err := bi.Finalise(); err != nil {
+		return PruneResult{}, err
+	}
+
+	if err := uni.Finalise(); err != nil {
+		return PruneResult{}, err
+	}
+
+	result := PruneResult{
+		DocumentsRemoved: len(expired),
+		PairsRecomputed:  len(pairs),
+		StatsChange:      change,
+		TypesChange:      typesChange,
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Int("documentsRemoved", result.DocumentsRemoved).
+		Int("pairsRecomputed", result.PairsRecomputed).
+		Msg("Finished pruning expired documents")
+
+	return result, nil
+}
+
+// expiredDocuments in the bipartite store. The documents are collected before any are removed, so
+// that the store isn't modified whilst it is iterated over.
+func expiredDocuments(bi BipartiteGraphStore, policy RetentionPolicy, now time.Time) ([]*Document, error) {
+
+	it, err := bi.NewDocumentIdIterator()
+	if err != nil {
+		return nil, err
+	}
+
+	expired := []*Document{}
+	for it.hasNext() {
+		docId, err := it.nextDocumentId()
+		if err != nil {
+			return nil, err
+		}
+
+		doc, err := bi.GetDocument(docId)
+		if err != nil {
+			return nil, err
+		}
+
+		if policy.isExpired(doc, now) {
+			expired = append(expired, doc)
+		}
+	}
+
+	return expired, nil
+}
+
+// recomputeEdges between two entities by removing their edges and converting the documents that
+// still link them.
+func recomputeEdges(e1 string, e2 string, bi BipartiteGraphStore, uni UnipartiteGraphStore,
+	options ConversionOptions) error {
+
+	if err := uni.RemoveEdge(e1, e2); err != nil && !errors.Is(err, ErrEdgeNotFound) {
+		return err
+	}
+
+	entity, err := bi.GetEntity(e1)
+	if errors.Is(err, ErrEntityNotFound) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+
+	for docId := range entity.LinkedDocumentIds.Values {
+		doc, err := bi.GetDocument(docId)
+		if err != nil {
+			return err
+		}
+
+		if !doc.LinkedEntityIds.Has(e2) {
+			continue
+		}
+
+		date := options.documentDate(doc)
+		if err := convertEntityPair(doc, e1, e2, date, uni, options, nil, nil); err != nil {
+			return err
+		}
+
+		if err := convertEntityPair(doc, e2, e1, date, uni, options, nil, nil); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
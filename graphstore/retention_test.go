@@ -0,0 +1,190 @@
+package graphstore
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/set"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetentionPolicyValidate(t *testing.T) {
+
+	valid := RetentionPolicy{
+		DateAttribute: "Date",
+		DateFormat:    "02/01/2006",
+		MaxAgeDays:    map[string]int{"meeting": 365},
+	}
+	assert.NoError(t, valid.Validate())
+
+	testCases := []RetentionPolicy{
+		{DateFormat: "02/01/2006", MaxAgeDays: map[string]int{"meeting": 365}},
+		{DateAttribute: "Date", MaxAgeDays: map[string]int{"meeting": 365}},
+		{DateAttribute: "Date", DateFormat: "02/01/2006"},
+		{DateAttribute: "Date", DateFormat: "02/01/2006", MaxAgeDays: map[string]int{"meeting": 0}},
+	}
+
+	for _, testCase := range testCases {
+		assert.ErrorIs(t, testCase.Validate(), ErrInvalidRetentionPolicy)
+	}
+}
+
+// checkPruneExpiredDocuments using the graph:
+//
+//	[e-1] --- [doc-1 (expired)] --- [e-2]
+//	[e-1, e-2, e-3] --- [doc-2]
+//	[e-3] --- [doc-3 (expired)] --- [e-4]
+//	[e-4] --- [doc-4 (old, but no maximum age)] --- [e-5]
+//	[e-6] --- [doc-5 (expired)]
+func checkPruneExpiredDocuments(t *testing.T, bi BipartiteGraphStore, uni UnipartiteGraphStore) {
+
+	documents := []struct {
+		id           string
+		documentType string
+		date         string
+		entityIds    []string
+	}{
+		{"doc-1", "meeting", "01/01/2020", []string{"e-1", "e-2"}},
+		{"doc-2", "meeting", "01/05/2022", []string{"e-1", "e-2", "e-3"}},
+		{"doc-3", "meeting", "01/01/2021", []string{"e-3", "e-4"}},
+		{"doc-4", "report", "01/01/2000", []string{"e-4", "e-5"}},
+		{"doc-5", "meeting", "01/01/2019", []string{"e-6"}},
+	}
+
+	for _, entityId := range []string{"e-1", "e-2", "e-3", "e-4", "e-5", "e-6"} {
+		entity, err := NewEntity(entityId, "person", map[string]string{})
+		assert.NoError(t, err)
+		assert.NoError(t, bi.AddEntity(entity))
+	}
+
+	for _, d := range documents {
+		doc, err := NewDocument(d.id, d.documentType, map[string]string{"Date": d.date})
+		assert.NoError(t, err)
+		assert.NoError(t, bi.AddDocument(doc))
+
+		for _, entityId := range d.entityIds {
+			assert.NoError(t, bi.AddLink(NewLink(entityId, d.id)))
+		}
+	}
+	assert.NoError(t, bi.Finalise())
+
+	options := ConversionOptions{DateAttribute: "Date", DateFormat: "02/01/2006"}
+	assert.NoError(t, BipartiteToUnipartiteWithCheckpoints(bi, uni, set.NewSet[string](), 2, 2,
+		options, nil))
+
+	policy := RetentionPolicy{
+		DateAttribute: "Date",
+		DateFormat:    "02/01/2006",
+		MaxAgeDays:    map[string]int{"meeting": 365},
+	}
+	now := time.Date(2022, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	statsBefore, err := CalcBipartiteStats(bi)
+	assert.NoError(t, err)
+
+	typesBefore, err := CountTypes(bi)
+	assert.NoError(t, err)
+
+	result, err := PruneExpiredDocuments(bi, uni, set.NewSet[string](), options, policy, now)
+	assert.NoError(t, err)
+	assert.Equal(t, PruneResult{
+		DocumentsRemoved: 3,
+		PairsRecomputed:  2,
+		StatsChange: BipartiteStats{
+			NumberOfEntitiesWithDocuments: -1,
+			NumberOfDocuments:             -3,
+			NumberOfDocumentsWithEntities: -3,
+		},
+		TypesChange: TypeCounts{
+			DocumentTypes: map[string]int{"meeting": -3},
+		},
+	}, result)
+
+	// The change to the stats is that of the store
+	statsAfter := statsBefore.Add(result.StatsChange)
+	typesAfter := typesBefore.Add(result.TypesChange)
+	assert.NoError(t, RecordBipartiteStats(bi, statsAfter, typesAfter))
+
+	counted, countedTypes, err := countBipartite(bi)
+	assert.NoError(t, err)
+	assert.Equal(t, counted, statsAfter)
+	assert.Equal(t, countedTypes, typesAfter)
+
+	recorded, err := CalcBipartiteStats(bi)
+	assert.NoError(t, err)
+	assert.Equal(t, statsAfter, recorded)
+
+	// The expired documents are removed from the bipartite store
+	_, err = bi.GetDocument("doc-1")
+	assert.ErrorIs(t, err, ErrDocumentNotFound)
+
+	_, err = bi.GetDocument("doc-3")
+	assert.ErrorIs(t, err, ErrDocumentNotFound)
+
+	entity, err := bi.GetEntity("e-1")
+	assert.NoError(t, err)
+	assert.Equal(t, set.NewPopulatedSet("doc-2"), entity.LinkedDocumentIds)
+
+	// The edge metadata is rebuilt from the remaining documents
+	may := time.Date(2022, 5, 1, 0, 0, 0, 0, time.UTC)
+	millennium := time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+	testCases := []struct {
+		src      string
+		dst      string
+		expected *EdgeMetadata
+	}{
+		{"e-1", "e-2", &EdgeMetadata{1, may, []time.Time{may}}},
+		{"e-2", "e-1", &EdgeMetadata{1, may, []time.Time{may}}},
+		{"e-1", "e-3", &EdgeMetadata{1, may, []time.Time{may}}},
+		{"e-3", "e-4", nil},
+		{"e-4", "e-5", &EdgeMetadata{1, millennium, []time.Time{millennium}}},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := uni.EdgeMetadata(testCase.src, testCase.dst)
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.expected, actual)
+	}
+
+	// Edges without a remaining document are removed, but the entities remain
+	connected, err := uni.EdgeExists("e-3", "e-4")
+	assert.NoError(t, err)
+	assert.False(t, connected)
+
+	connected, err = uni.EdgeExists("e-1", "e-2")
+	assert.NoError(t, err)
+	assert.True(t, connected)
+
+	entityIds, err := uni.EntityIds()
+	assert.NoError(t, err)
+	assert.True(t, entityIds.Has("e-4"))
+
+	// Pruning again doesn't remove anything
+	result, err = PruneExpiredDocuments(bi, uni, set.NewSet[string](), options, policy, now)
+	assert.NoError(t, err)
+	assert.Equal(t, PruneResult{}, result)
+}
+
+func TestPruneExpiredDocuments(t *testing.T) {
+
+	// In-memory stores
+	checkPruneExpiredDocuments(t, NewInMemoryBipartiteGraphStore(), NewInMemoryUnipartiteGraphStore())
+
+	// Pebble stores
+	pebbleBipartite := newBipartitePebbleStore(t)
+	defer cleanUpBipartitePebbleStore(t, pebbleBipartite)
+
+	pebbleUnipartite := newUnipartitePebbleStore(t)
+	defer cleanUpUnipartitePebbleStore(t, pebbleUnipartite)
+
+	checkPruneExpiredDocuments(t, pebbleBipartite, pebbleUnipartite)
+
+	// Invalid arguments
+	_, err := PruneExpiredDocuments(nil, NewInMemoryUnipartiteGraphStore(), set.NewSet[string](),
+		ConversionOptions{}, RetentionPolicy{}, time.Now())
+	assert.ErrorIs(t, err, ErrBipartiteStoreIsNil)
+
+	_, err = PruneExpiredDocuments(NewInMemoryBipartiteGraphStore(), NewInMemoryUnipartiteGraphStore(),
+		set.NewSet[string](), ConversionOptions{}, RetentionPolicy{}, time.Now())
+	assert.ErrorIs(t, err, ErrInvalidRetentionPolicy)
+}
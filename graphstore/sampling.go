@@ -0,0 +1,110 @@
+// A sample of a unipartite graph is a random set of its entities and the edges between them (the
+// induced subgraph), which is small enough to visualise when exploring a newly loaded graph.
+
+package graphstore
+
+import (
+	"errors"
+	"math/rand"
+	"sort"
+)
+
+var ErrInvalidSampleSize = errors.New("invalid number of entities to sample")
+
+// A SampledEdge is an edge between two entities of a sample. An edge that is in both directions is
+// undirected.
+type SampledEdge struct {
+	V1       string
+	V2       string
+	Directed bool
+}
+
+// A GraphSample is a random subgraph of a unipartite graph.
+type GraphSample struct {
+	EntityIds []string      // Sampled entity IDs, sorted
+	Edges     []SampledEdge // Edges between the sampled entities, sorted
+}
+
+// SampleGraph chooses the number of entities from the graph at random (or all of the entities if
+// the graph is smaller) along with the edges between them. The same source of random numbers always
+// gives the same sample of a graph.
+func SampleGraph(graph UnipartiteGraphStore, numberEntities int, rng *rand.Rand) (*GraphSample, error) {
+
+	// Preconditions
+	if graph == nil {
+		return nil, errors.New("unipartite graph store is nil")
+	}
+
+	if numberEntities < 1 {
+		return nil, ErrInvalidSampleSize
+	}
+
+	if rng == nil {
+		return nil, errors.New("random number generator is nil")
+	}
+
+	entityIds, err := graph.EntityIds()
+	if err != nil {
+		return nil, err
+	}
+
+	// The entity IDs are in no particular order, so they are sorted before they are sampled
+	candidates := entityIds.ToSlice()
+	sort.Strings(candidates)
+
+	if numberEntities > len(candidates) {
+		numberEntities = len(candidates)
+	}
+
+	// Partial Fisher-Yates shuffle to choose the entities
+	for idx := 0; idx < numberEntities; idx++ {
+		chosen := idx + rng.Intn(len(candidates)-idx)
+		candidates[idx], candidates[chosen] = candidates[chosen], candidates[idx]
+	}
+
+	sampled := candidates[:numberEntities]
+	sort.Strings(sampled)
+
+	inSample := make(map[string]struct{}, len(sampled))
+	for _, entityId := range sampled {
+		inSample[entityId] = struct{}{}
+	}
+
+	// Find the edges between the sampled entities
+	directed := map[Edge]struct{}{}
+	for _, entityId := range sampled {
+		adjacent, err := graph.EntityIdsAdjacentTo(entityId)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, adjacentId := range adjacent.ToSlice() {
+			if _, found := inSample[adjacentId]; found && adjacentId != entityId {
+				directed[Edge{V1: entityId, V2: adjacentId}] = struct{}{}
+			}
+		}
+	}
+
+	edges := []SampledEdge{}
+	for edge := range directed {
+		_, reverse := directed[Edge{V1: edge.V2, V2: edge.V1}]
+
+		if !reverse {
+			edges = append(edges, SampledEdge{V1: edge.V1, V2: edge.V2, Directed: true})
+		} else if edge.V1 < edge.V2 {
+			edges = append(edges, SampledEdge{V1: edge.V1, V2: edge.V2})
+		}
+	}
+
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].V1 != edges[j].V1 {
+			return edges[i].V1 < edges[j].V1
+		}
+		return edges[i].V2 < edges[j].V2
+	})
+
+	return &GraphSample{
+		EntityIds: sampled,
+		Edges:     edges,
+	}, nil
+}
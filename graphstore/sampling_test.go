@@ -0,0 +1,92 @@
+package graphstore
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSampleGraphPreconditions(t *testing.T) {
+	graph := NewInMemoryUnipartiteGraphStore()
+	rng := rand.New(rand.NewSource(1))
+
+	_, err := SampleGraph(nil, 1, rng)
+	assert.Error(t, err)
+
+	_, err = SampleGraph(graph, 0, rng)
+	assert.ErrorIs(t, err, ErrInvalidSampleSize)
+
+	_, err = SampleGraph(graph, 1, nil)
+	assert.Error(t, err)
+}
+
+func TestSampleWholeGraph(t *testing.T) {
+	graph := NewInMemoryUnipartiteGraphStore()
+	assert.NoError(t, graph.AddUndirected("e-1", "e-2"))
+	assert.NoError(t, graph.AddUndirected("e-2", "e-3"))
+	assert.NoError(t, graph.AddDirected("e-3", "e-1"))
+	assert.NoError(t, graph.AddEntity("e-4"))
+
+	// The sample is the whole graph if it has fewer entities than requested
+	sample, err := SampleGraph(graph, 10, rand.New(rand.NewSource(1)))
+	assert.NoError(t, err)
+	assert.Equal(t, &GraphSample{
+		EntityIds: []string{"e-1", "e-2", "e-3", "e-4"},
+		Edges: []SampledEdge{
+			{V1: "e-1", V2: "e-2"},
+			{V1: "e-2", V2: "e-3"},
+			{V1: "e-3", V2: "e-1", Directed: true},
+		},
+	}, sample)
+
+	// Empty graph
+	sample, err = SampleGraph(NewInMemoryUnipartiteGraphStore(), 10, rand.New(rand.NewSource(1)))
+	assert.NoError(t, err)
+	assert.Empty(t, sample.EntityIds)
+	assert.Empty(t, sample.Edges)
+}
+
+func TestSampleGraphInducedEdges(t *testing.T) {
+	graph := NewInMemoryUnipartiteGraphStore()
+	edges, err := edgeStringsToEdges([]string{
+		"e1-e2", "e2-e3", "e3-e4", "e4-e5", "e5-e1", "e1-e3",
+	}, "-")
+	assert.NoError(t, err)
+	assert.NoError(t, BuildFromEdgeList(graph, edges))
+
+	for seed := int64(0); seed < 20; seed++ {
+		sample, err := SampleGraph(graph, 3, rand.New(rand.NewSource(seed)))
+		assert.NoError(t, err)
+		assert.Len(t, sample.EntityIds, 3)
+
+		// Only the edges between the sampled entities are in the sample
+		expected := []SampledEdge{}
+		for _, edge := range edges {
+			v1, v2 := edge.V1, edge.V2
+			if v1 > v2 {
+				v1, v2 = v2, v1
+			}
+
+			if contains(sample.EntityIds, v1) && contains(sample.EntityIds, v2) {
+				expected = append(expected, SampledEdge{V1: v1, V2: v2})
+			}
+		}
+		assert.ElementsMatch(t, expected, sample.Edges)
+
+		// The same seed gives the same sample
+		again, err := SampleGraph(graph, 3, rand.New(rand.NewSource(seed)))
+		assert.NoError(t, err)
+		assert.Equal(t, sample, again)
+	}
+}
+
+// contains returns true if the value is in the slice.
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,57 @@
+package graphstore
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// A StoreLock coordinates the jobs and queries reading the stores of a graph with an update that
+// writes to the stores whilst they are served (e.g. pruning the expired documents), so that a
+// reader never sees a half-updated graph. Readers share the lock. An update waits for the readers
+// to finish and new readers are queued until the update has finished. A nil lock doesn't
+// coordinate anything.
+type StoreLock struct {
+	lock     sync.RWMutex
+	updating int32 // Number of updates waiting for or holding the lock (accessed atomically)
+}
+
+// NewStoreLock for the stores of a graph.
+func NewStoreLock() *StoreLock {
+	return &StoreLock{}
+}
+
+// BeginRead of the stores, which waits for an update to finish. A reader must not begin to read
+// again before it ends its read, otherwise it can deadlock with a waiting update.
+func (s *StoreLock) BeginRead() {
+	if s != nil {
+		s.lock.RLock()
+	}
+}
+
+// EndRead of the stores.
+func (s *StoreLock) EndRead() {
+	if s != nil {
+		s.lock.RUnlock()
+	}
+}
+
+// Update the stores using the function once the readers have finished.
+func (s *StoreLock) Update(update func() error) error {
+	if s == nil {
+		return update()
+	}
+
+	atomic.AddInt32(&s.updating, 1)
+	defer atomic.AddInt32(&s.updating, -1)
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return update()
+}
+
+// Updating returns true if an update is waiting for the readers to finish or is writing to the
+// stores, i.e. new readers will be queued.
+func (s *StoreLock) Updating() bool {
+	return s != nil && atomic.LoadInt32(&s.updating) > 0
+}
@@ -0,0 +1,68 @@
+package graphstore
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNilStoreLock(t *testing.T) {
+	var lock *StoreLock
+
+	lock.BeginRead()
+	lock.EndRead()
+	assert.False(t, lock.Updating())
+
+	err := errors.New("update failed")
+	assert.Equal(t, err, lock.Update(func() error { return err }))
+}
+
+func TestStoreLockQueuesReaders(t *testing.T) {
+	lock := NewStoreLock()
+	assert.False(t, lock.Updating())
+
+	// The update waits for the reader to finish
+	lock.BeginRead()
+
+	updated := make(chan bool)
+	release := make(chan bool)
+	go func() {
+		lock.Update(func() error {
+			updated <- true
+			<-release
+			return nil
+		})
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	assert.True(t, lock.Updating())
+	select {
+	case <-updated:
+		assert.Fail(t, "Update didn't wait for the reader")
+	default:
+	}
+
+	lock.EndRead()
+	<-updated
+
+	// A new reader waits for the update to finish
+	read := make(chan bool)
+	go func() {
+		lock.BeginRead()
+		read <- true
+		lock.EndRead()
+	}()
+
+	time.Sleep(10 * time.Millisecond)
+	select {
+	case <-read:
+		assert.Fail(t, "Reader didn't wait for the update")
+	default:
+	}
+
+	close(release)
+	<-read
+	assert.False(t, lock.Updating())
+}
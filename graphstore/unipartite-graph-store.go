@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/cdclaxton/shortest-path-web-app/set"
 )
@@ -35,22 +36,43 @@ func edgeStringsToEdges(definitions []string, edgeSeparator string) ([]Edge, err
 	return edges, nil
 }
 
+// An EdgeIterator streams the edges of a unipartite store, so that the edges (and the entity IDs)
+// of a large graph don't need to be held in memory. Each directed edge is returned once, so an
+// undirected edge is returned in both directions. An entity without any outgoing edges is returned
+// once as an edge with an empty destination (V2), so that the iterator covers the whole graph. The
+// iterator must be closed if it isn't exhausted.
+type EdgeIterator interface {
+	HasNext() bool           // Does the iterator have another edge?
+	NextEdge() (Edge, error) // Get the next edge
+	Close() error            // Release the resources held by the iterator
+}
+
 // A UnipartiteGraphStore represents the store of a graph composed of a single type of vertex.
 type UnipartiteGraphStore interface {
-	AddEntity(string) error                               // Add an entity
-	AddDirected(string, string) error                     // Add a directed edge between two entities
-	AddUndirected(string, string) error                   // Add an undirected edge between two entities
-	Clear() error                                         // Clear down the graph
-	Close() error                                         // Close the graph
-	Destroy() error                                       // Destroy the graph (and any backing files)
-	EdgeExists(string, string) (bool, error)              // Are the two entities connected?
-	EntityIds() (*set.Set[string], error)                 // All entity IDs in the graph
-	EntityIdsAdjacentTo(string) (*set.Set[string], error) // Entity IDs adjacent to a given entity ID
-	Finalise() error                                      // Run any tidy up actions
-	HasEntity(string) (bool, error)                       // Does the store contain the entity?
-	NumberEntities() (int, error)                         // Number of entities in the store
+	AddEntity(string) error                                // Add an entity
+	AddDirected(string, string) error                      // Add a directed edge between two entities
+	AddUndirected(string, string) error                    // Add an undirected edge between two entities
+	AddEdgeDocument(string, string, time.Time) error       // Record a document linking two entities
+	AddEdgeMetadata(string, string, EdgeMetadata) error    // Record the documents of the metadata on an edge
+	SetEdgeMetadata(string, string, *EdgeMetadata) error   // Replace the metadata of an edge (nil to remove it)
+	RemoveEntity(string) error                             // Remove an entity and its edges
+	RemoveEdge(string, string) error                       // Remove the edges between two entities
+	Clear() error                                          // Clear down the graph
+	Close() error                                          // Close the graph
+	Destroy() error                                        // Destroy the graph (and any backing files)
+	EdgeExists(string, string) (bool, error)               // Are the two entities connected?
+	EdgeMetadata(string, string) (*EdgeMetadata, error)    // Metadata of an edge (nil if not recorded)
+	EntityIds() (*set.Set[string], error)                  // All entity IDs in the graph
+	EntityIdsAdjacentTo(string) (*set.Set[string], error)  // Entity IDs adjacent to a given entity ID
+	EntityIdsConnectedTo(string) (*set.Set[string], error) // Entity IDs with an edge in either direction
+	Finalise() error                                       // Run any tidy up actions
+	HasEntity(string) (bool, error)                        // Does the store contain the entity?
+	NewEdgeIterator() (EdgeIterator, error)                // Stream the edges of the graph
+	NumberEntities() (int, error)                          // Number of entities in the store
 }
 
+var ErrEdgeNotFound = errors.New("edge not found in unipartite store")
+
 // BuildFromEdgeList builds the graph from an undirected edge list.
 func BuildFromEdgeList(graph UnipartiteGraphStore, edges []Edge) error {
 
@@ -74,7 +96,55 @@ func BuildFromEdgeList(graph UnipartiteGraphStore, edges []Edge) error {
 	return nil
 }
 
-// UnipartiteGraphStoresEqual returns true if two unipartite graph stores are identical.
+// forEachEdge of the unipartite store, streamed by an edge iterator. The iterator is closed if the
+// function returns an error or stops early by returning false.
+func forEachEdge(graph UnipartiteGraphStore, fn func(edge Edge) (bool, error)) error {
+
+	iter, err := graph.NewEdgeIterator()
+	if err != nil {
+		return err
+	}
+	defer iter.Close()
+
+	for iter.HasNext() {
+		edge, err := iter.NextEdge()
+		if err != nil {
+			return err
+		}
+
+		more, err := fn(edge)
+		if err != nil || !more {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// hasEdgeOf the other store, where an edge without a destination is an entity without outgoing
+// edges.
+func hasEdgeOf(graph UnipartiteGraphStore, edge Edge) (bool, error) {
+
+	if len(edge.V2) > 0 {
+		return graph.EdgeExists(edge.V1, edge.V2)
+	}
+
+	found, err := graph.HasEntity(edge.V1)
+	if err != nil || !found {
+		return false, err
+	}
+
+	adjacent, err := graph.EntityIdsAdjacentTo(edge.V1)
+	if err != nil {
+		return false, err
+	}
+
+	return adjacent.Len() == 0, nil
+}
+
+// UnipartiteGraphStoresEqual returns true if the two unipartite stores hold the same entities and
+// edges. The edges are streamed, so the graphs aren't held in memory: each edge of g1 must be in
+// g2 and the stores must have the same number of edges.
 func UnipartiteGraphStoresEqual(g1 UnipartiteGraphStore, g2 UnipartiteGraphStore) (bool, string, error) {
 
 	// Preconditions
@@ -86,46 +156,76 @@ func UnipartiteGraphStoresEqual(g1 UnipartiteGraphStore, g2 UnipartiteGraphStore
 		return false, "", errors.New("graph store g2 is nil")
 	}
 
-	// Get lists of entity IDs
-	entityIds1, err := g1.EntityIds()
-	if err != nil {
-		return false, "", err
+	// Check each edge of g1 is in g2
+	numberEdges1 := 0
+	reason := ""
+	err := forEachEdge(g1, func(edge Edge) (bool, error) {
+		found, err := hasEdgeOf(g2, edge)
+		if err != nil {
+			return false, err
+		}
+
+		if !found && len(edge.V2) == 0 {
+			reason = fmt.Sprintf("different connections: %s\n", edge.V1)
+		} else if !found {
+			reason = fmt.Sprintf("edge missing in g2: %s -> %s\n", edge.V1, edge.V2)
+		}
+
+		numberEdges1 += 1
+		return found, nil
+	})
+
+	if err != nil || len(reason) > 0 {
+		return false, reason, err
 	}
 
-	entityIds2, err := g2.EntityIds()
+	// As the edges of g1 are in g2, g2 can only differ by having more edges
+	numberEdges2 := 0
+	err = forEachEdge(g2, func(edge Edge) (bool, error) {
+		numberEdges2 += 1
+		return true, nil
+	})
+
 	if err != nil {
 		return false, "", err
 	}
 
-	// Check whether the entity IDs are identical
-	if !entityIds1.Equal(entityIds2) {
-		if entityIds1.Len() < 10 && entityIds2.Len() < 10 {
-			fmt.Printf("entity IDs: %v vs %v\n", entityIds1.Values, entityIds2.Values)
-		}
-
-		return false, fmt.Sprintf("different entity IDs (%d vs %d)", entityIds1.Len(), entityIds2.Len()), nil
+	if numberEdges1 != numberEdges2 {
+		return false, fmt.Sprintf("different number of edges (%d vs %d)", numberEdges1, numberEdges2), nil
 	}
 
-	// Check the connections
-	for _, entityId := range entityIds1.ToSlice() {
+	return true, "", nil
+}
 
-		// Connections from the entity
-		conns1, err := g1.EntityIdsAdjacentTo(entityId)
-		if err != nil {
-			return false, fmt.Sprintf("entity missing in g1: %s\n", entityId), nil
+// CountConnectedPairs of entities in the unipartite store, i.e. the number of edges ignoring their
+// direction.
+func CountConnectedPairs(ug UnipartiteGraphStore) (int, error) {
+
+	pairs := 0
+	err := forEachEdge(ug, func(edge Edge) (bool, error) {
+		if len(edge.V2) == 0 {
+			return true, nil
 		}
 
-		conns2, err := g2.EntityIdsAdjacentTo(entityId)
-		if err != nil {
-			return false, fmt.Sprintf("entity missing in g2: %s\n", entityId), nil
+		// Each pair is counted from the entity with the lower ID, unless there is only an edge
+		// from the entity with the higher ID
+		if edge.V1 < edge.V2 {
+			pairs += 1
+			return true, nil
 		}
 
-		if !conns1.Equal(conns2) {
-			return false, fmt.Sprintf("different connections: %s (%d vs %d)\n", entityId, conns1.Len(), conns2.Len()), nil
+		reverse, err := ug.EdgeExists(edge.V2, edge.V1)
+		if err == nil && !reverse {
+			pairs += 1
 		}
+		return true, err
+	})
+
+	if err != nil {
+		return 0, err
 	}
 
-	return true, "", nil
+	return pairs, nil
 }
 
 type UnipartiteStats struct {
@@ -143,3 +243,21 @@ func CalcUnipartiteStats(ug UnipartiteGraphStore) (UnipartiteStats, error) {
 		NumberOfEntities: numEntities,
 	}, nil
 }
+
+// Add the change (e.g. from an incremental update) to the stats.
+func (s UnipartiteStats) Add(change UnipartiteStats) UnipartiteStats {
+	return UnipartiteStats{
+		NumberOfEntities: s.NumberOfEntities + change.NumberOfEntities,
+	}
+}
+
+// RecordUnipartiteStats that are known to be those of the unipartite store (e.g. they were
+// updated incrementally after a change), so that a store that holds its stats doesn't need to
+// count them.
+func RecordUnipartiteStats(ug UnipartiteGraphStore, stats UnipartiteStats) error {
+	if counted, ok := ug.(countedUnipartiteStore); ok {
+		return counted.recordStats(stats)
+	}
+
+	return nil
+}
@@ -0,0 +1,497 @@
+// The unipartite graph of a sparse-but-long dataset can hold many entities that never help a
+// search: isolated entities without any edges and long chains of entities with only two neighbours,
+// which a path can only pass straight through. Pruning removes the isolated entities and can
+// collapse each chain into a single edge between its ends, shrinking the store.
+//
+// A collapsed chain is recorded in a ChainIndex, which holds the entities the chain passed
+// through and the metadata of its edges. The entities inside a collapsed chain are removed from the
+// unipartite graph, so the chains must be saved to a file and the pruned store must only be read
+// through an ExpandedUnipartiteGraphStore, which restores the chains.
+
+package graphstore
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cdclaxton/shortest-path-web-app/set"
+)
+
+var (
+	ErrInvalidMinChainLength = errors.New("invalid minimum chain length")
+	ErrChainIndexIsNil       = errors.New("chain index is nil")
+	ErrChainsFileRequired    = errors.New("chains file is required to collapse chains")
+)
+
+// UnipartitePruneOptions select how the unipartite graph is pruned.
+type UnipartitePruneOptions struct {
+	Isolated       bool   `json:"isolated"`       // Remove the entities without any edges
+	MinChainLength int    `json:"minChainLength"` // Minimum entities inside a chain to collapse it (0 to keep the chains)
+	ChainsFile     string `json:"chainsFile"`     // JSON file of the collapsed chains (required to collapse chains)
+}
+
+// Validate the prune options. The chains file is required to collapse chains, as the entities
+// inside the chains are removed from the store.
+func (o UnipartitePruneOptions) Validate() error {
+	if err := validateMinChainLength(o.MinChainLength); err != nil {
+		return err
+	}
+
+	if o.MinChainLength > 0 && len(o.ChainsFile) == 0 {
+		return ErrChainsFileRequired
+	}
+
+	return nil
+}
+
+// validateMinChainLength returns an error if the minimum chain length is negative.
+func validateMinChainLength(minChainLength int) error {
+	if minChainLength < 0 {
+		return fmt.Errorf("%w: %d", ErrInvalidMinChainLength, minChainLength)
+	}
+	return nil
+}
+
+// A Chain of entities that has been collapsed into an edge between its ends.
+type Chain struct {
+	From string   `json:"from"` // Entity at one end of the chain
+	To   string   `json:"to"`   // Entity at the other end of the chain
+	Via  []string `json:"via"`  // Entities inside the chain in order from From to To
+
+	// Metadata of each edge of the chain in order from From to To (nil if not recorded)
+	Edges []*EdgeMetadata `json:"edges,omitempty"`
+}
+
+// Hops of the chain between its ends.
+func (c Chain) Hops() int {
+	return len(c.Via) + 1
+}
+
+// route through the chain from From to To.
+func (c Chain) route() []string {
+	route := []string{c.From}
+	route = append(route, c.Via...)
+	return append(route, c.To)
+}
+
+// edge metadata of the hop of the chain with the given index (nil if not recorded).
+func (c Chain) edge(index int) *EdgeMetadata {
+	if index < 0 || index >= len(c.Edges) {
+		return nil
+	}
+	return c.Edges[index]
+}
+
+// reversed chain, i.e. from its To entity to its From entity.
+func (c Chain) reversed() Chain {
+	via := make([]string, len(c.Via))
+	for idx, entityId := range c.Via {
+		via[len(c.Via)-1-idx] = entityId
+	}
+
+	var edges []*EdgeMetadata
+	if c.Edges != nil {
+		edges = make([]*EdgeMetadata, len(c.Edges))
+		for idx, metadata := range c.Edges {
+			edges[len(c.Edges)-1-idx] = metadata
+		}
+	}
+
+	return Chain{
+		From:  c.To,
+		To:    c.From,
+		Via:   via,
+		Edges: edges,
+	}
+}
+
+// A ChainIndex holds the collapsed chains of a unipartite graph keyed by their ends. A nil index
+// doesn't have any chains.
+type ChainIndex struct {
+	chains map[string]map[string]Chain // From to To to the chain in that direction
+}
+
+// NewChainIndex without any chains.
+func NewChainIndex() *ChainIndex {
+	return &ChainIndex{
+		chains: map[string]map[string]Chain{},
+	}
+}
+
+// add the chain to the index in both directions.
+func (c *ChainIndex) add(chain Chain) {
+	for _, ch := range []Chain{chain, chain.reversed()} {
+		if _, found := c.chains[ch.From]; !found {
+			c.chains[ch.From] = map[string]Chain{}
+		}
+		c.chains[ch.From][ch.To] = ch
+	}
+}
+
+// remove the chain from the index in both directions.
+func (c *ChainIndex) remove(chain Chain) {
+	for _, ends := range [][2]string{{chain.From, chain.To}, {chain.To, chain.From}} {
+		delete(c.chains[ends[0]], ends[1])
+		if len(c.chains[ends[0]]) == 0 {
+			delete(c.chains, ends[0])
+		}
+	}
+}
+
+// from returns the chains with an end at the entity, oriented away from it.
+func (c *ChainIndex) from(entityId string) []Chain {
+	if c == nil {
+		return []Chain{}
+	}
+
+	chains := []Chain{}
+	for _, chain := range c.chains[entityId] {
+		chains = append(chains, chain)
+	}
+
+	return chains
+}
+
+// Get the chain collapsed into the edge from one entity to another, oriented in that direction.
+// False is returned if the edge isn't a collapsed chain.
+func (c *ChainIndex) Get(from string, to string) (Chain, bool) {
+	if c == nil {
+		return Chain{}, false
+	}
+
+	chain, found := c.chains[from][to]
+	return chain, found
+}
+
+// Chains in the index sorted by their ends, each in one direction only.
+func (c *ChainIndex) Chains() []Chain {
+	if c == nil {
+		return []Chain{}
+	}
+
+	chains := []Chain{}
+	for from, destinations := range c.chains {
+		for to, chain := range destinations {
+			if from < to {
+				chains = append(chains, chain)
+			}
+		}
+	}
+
+	sort.Slice(chains, func(i, j int) bool {
+		if chains[i].From == chains[j].From {
+			return chains[i].To < chains[j].To
+		}
+		return chains[i].From < chains[j].From
+	})
+
+	return chains
+}
+
+// Len returns the number of chains in the index.
+func (c *ChainIndex) Len() int {
+	if c == nil {
+		return 0
+	}
+
+	n := 0
+	for _, destinations := range c.chains {
+		n += len(destinations)
+	}
+
+	return n / 2
+}
+
+// ExpandRoute of a path through the unipartite graph, replacing each collapsed edge with the
+// entities inside its chain.
+func (c *ChainIndex) ExpandRoute(route []string) []string {
+	if c == nil || len(route) < 2 {
+		return route
+	}
+
+	expanded := []string{route[0]}
+	for idx := 1; idx < len(route); idx++ {
+		if chain, found := c.Get(route[idx-1], route[idx]); found {
+			expanded = append(expanded, chain.Via...)
+		}
+		expanded = append(expanded, route[idx])
+	}
+
+	return expanded
+}
+
+// WriteChainIndex to a JSON file.
+func WriteChainIndex(index *ChainIndex, filepath string) error {
+
+	if index == nil {
+		return ErrChainIndexIsNil
+	}
+
+	content, err := json.MarshalIndent(index.Chains(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath, content, 0644)
+}
+
+// ReadChainIndex from a JSON file.
+func ReadChainIndex(filepath string) (*ChainIndex, error) {
+
+	content, err := os.ReadFile(filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	chains := []Chain{}
+	if err := json.Unmarshal(content, &chains); err != nil {
+		return nil, err
+	}
+
+	index := NewChainIndex()
+	for _, chain := range chains {
+		index.add(chain)
+	}
+
+	return index, nil
+}
+
+// UnipartitePruneResult summarises the pruning of a unipartite graph.
+type UnipartitePruneResult struct {
+	IsolatedRemoved int         // Number of isolated entities removed
+	ChainsCollapsed int         // Number of chains collapsed into an edge
+	ChainEntities   int         // Number of entities removed from inside the chains
+	Chains          *ChainIndex // Collapsed chains
+}
+
+// isChainLink returns true if the entity has exactly two neighbours, both connected by undirected
+// edges, so a path can only pass straight through it. The neighbours are returned.
+func isChainLink(graph UnipartiteGraphStore, entityId string) (bool, []string, error) {
+
+	connected, err := graph.EntityIdsConnectedTo(entityId)
+	if err != nil || connected.Len() != 2 {
+		return false, nil, err
+	}
+
+	adjacent, err := graph.EntityIdsAdjacentTo(entityId)
+	if err != nil || !adjacent.Equal(connected) {
+		return false, nil, err
+	}
+
+	neighbours := connected.ToSlice()
+	sort.Strings(neighbours)
+
+	for _, neighbour := range neighbours {
+		back, err := graph.EntityIdsAdjacentTo(neighbour)
+		if err != nil || !back.Has(entityId) {
+			return false, nil, err
+		}
+	}
+
+	return true, neighbours, nil
+}
+
+// walkChain from a chain link towards one of its neighbours, returning the chain links passed
+// through (in order) and the entity at the end of the chain. The walk stops at an entity that isn't
+// a chain link or at the start (if the chain is a cycle).
+func walkChain(graph UnipartiteGraphStore, start string, next string) ([]string, string, error) {
+
+	links := []string{}
+	previous := start
+
+	for next != start {
+		isLink, neighbours, err := isChainLink(graph, next)
+		if err != nil {
+			return nil, "", err
+		}
+
+		if !isLink {
+			return links, next, nil
+		}
+
+		links = append(links, next)
+		if neighbours[0] == previous {
+			previous, next = next, neighbours[1]
+		} else {
+			previous, next = next, neighbours[0]
+		}
+	}
+
+	return links, start, nil
+}
+
+// findChain through the chain link, returning the chain (which is empty if the chain link is part
+// of a cycle) and the chain links inside it.
+func findChain(graph UnipartiteGraphStore, entityId string, neighbours []string) (Chain, error) {
+
+	before, from, err := walkChain(graph, entityId, neighbours[0])
+	if err != nil || from == entityId {
+		return Chain{}, err
+	}
+
+	after, to, err := walkChain(graph, entityId, neighbours[1])
+	if err != nil {
+		return Chain{}, err
+	}
+
+	via := []string{}
+	for idx := len(before) - 1; idx >= 0; idx-- {
+		via = append(via, before[idx])
+	}
+	via = append(via, entityId)
+	via = append(via, after...)
+
+	return Chain{From: from, To: to, Via: via}, nil
+}
+
+// collapseChains of chain links with at least the minimum number of entities inside them into an
+// edge between their ends. A chain isn't collapsed if its ends are the same entity or are already
+// connected, as the edge would be lost.
+func collapseChains(graph UnipartiteGraphStore, minChainLength int,
+	result *UnipartitePruneResult) error {
+
+	entityIds, err := graph.EntityIds()
+	if err != nil {
+		return err
+	}
+
+	sortedIds := entityIds.ToSlice()
+	sort.Strings(sortedIds)
+
+	visited := set.NewSet[string]()
+	for _, entityId := range sortedIds {
+
+		if visited.Has(entityId) {
+			continue
+		}
+
+		isLink, neighbours, err := isChainLink(graph, entityId)
+		if err != nil {
+			return err
+		}
+		if !isLink {
+			continue
+		}
+
+		chain, err := findChain(graph, entityId, neighbours)
+		if err != nil {
+			return err
+		}
+		visited.AddAll(chain.Via)
+		visited.Add(entityId)
+
+		if len(chain.Via) == 0 || len(chain.Via) < minChainLength || chain.From == chain.To {
+			continue
+		}
+
+		connected, err := graph.EdgeExists(chain.From, chain.To)
+		if err != nil {
+			return err
+		}
+		if connected {
+			continue
+		}
+
+		// Keep the metadata of the edges, which are removed with the entities inside the chain
+		route := chain.route()
+		chain.Edges = make([]*EdgeMetadata, len(route)-1)
+		for idx := 1; idx < len(route); idx++ {
+			chain.Edges[idx-1], err = graph.EdgeMetadata(route[idx-1], route[idx])
+			if err != nil {
+				return err
+			}
+		}
+
+		for _, link := range chain.Via {
+			if err := graph.RemoveEntity(link); err != nil {
+				return err
+			}
+		}
+
+		if err := graph.AddUndirected(chain.From, chain.To); err != nil {
+			return err
+		}
+
+		result.Chains.add(chain)
+		result.ChainsCollapsed++
+		result.ChainEntities += len(chain.Via)
+	}
+
+	return nil
+}
+
+// removeIsolated entities without any edges from the graph.
+func removeIsolated(graph UnipartiteGraphStore, result *UnipartitePruneResult) error {
+
+	entityIds, err := graph.EntityIds()
+	if err != nil {
+		return err
+	}
+
+	for _, entityId := range entityIds.ToSlice() {
+		connected, err := graph.EntityIdsConnectedTo(entityId)
+		if err != nil {
+			return err
+		}
+
+		if connected.Len() > 0 {
+			continue
+		}
+
+		if err := graph.RemoveEntity(entityId); err != nil {
+			return err
+		}
+		result.IsolatedRemoved++
+	}
+
+	return nil
+}
+
+// PruneUnipartite graph using the options, collapsing the chains before removing the isolated
+// entities. The chains file isn't written.
+func PruneUnipartite(graph UnipartiteGraphStore, options UnipartitePruneOptions) (
+	UnipartitePruneResult, error) {
+
+	result := UnipartitePruneResult{
+		Chains: NewChainIndex(),
+	}
+
+	if graph == nil {
+		return result, ErrUnipartiteStoreIsNil
+	}
+
+	if err := validateMinChainLength(options.MinChainLength); err != nil {
+		return result, err
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Bool("isolated", options.Isolated).
+		Int("minChainLength", options.MinChainLength).
+		Msg("Pruning the unipartite graph")
+
+	if options.MinChainLength > 0 {
+		if err := collapseChains(graph, options.MinChainLength, &result); err != nil {
+			return result, err
+		}
+	}
+
+	if options.Isolated {
+		if err := removeIsolated(graph, &result); err != nil {
+			return result, err
+		}
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Int("isolatedRemoved", result.IsolatedRemoved).
+		Int("chainsCollapsed", result.ChainsCollapsed).
+		Int("chainEntitiesRemoved", result.ChainEntities).
+		Msg("Pruned the unipartite graph")
+
+	return result, nil
+}
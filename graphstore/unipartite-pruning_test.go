@@ -0,0 +1,174 @@
+package graphstore
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/set"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChainIndex(t *testing.T) {
+
+	// A nil index doesn't have any chains
+	var empty *ChainIndex
+	_, found := empty.Get("a", "b")
+	assert.False(t, found)
+	assert.Equal(t, 0, empty.Len())
+	assert.Equal(t, []string{"a", "b"}, empty.ExpandRoute([]string{"a", "b"}))
+
+	index := NewChainIndex()
+	index.add(Chain{From: "a", To: "e", Via: []string{"b", "c", "d"}})
+	index.add(Chain{From: "p", To: "s", Via: []string{"q", "r"}, Edges: []*EdgeMetadata{
+		{NumberOfDocuments: 1}, nil, {NumberOfDocuments: 3},
+	}})
+
+	// The chain is oriented in the direction requested
+	chain, found := index.Get("a", "e")
+	assert.True(t, found)
+	assert.Equal(t, Chain{From: "a", To: "e", Via: []string{"b", "c", "d"}}, chain)
+	assert.Equal(t, 4, chain.Hops())
+
+	chain, found = index.Get("e", "a")
+	assert.True(t, found)
+	assert.Equal(t, Chain{From: "e", To: "a", Via: []string{"d", "c", "b"}}, chain)
+
+	_, found = index.Get("a", "b")
+	assert.False(t, found)
+	assert.Equal(t, 2, index.Len())
+
+	// The metadata of the edges is reversed with the chain
+	chain, found = index.Get("s", "p")
+	assert.True(t, found)
+	assert.Equal(t, 3, chain.edge(0).NumberOfDocuments)
+	assert.Nil(t, chain.edge(1))
+	assert.Equal(t, 1, chain.edge(2).NumberOfDocuments)
+	assert.Nil(t, chain.edge(3))
+
+	// Routes are expanded through the collapsed edges
+	assert.Equal(t, []string{"x", "e", "d", "c", "b", "a"}, index.ExpandRoute([]string{"x", "e", "a"}))
+
+	// The index is read back from its file
+	file := filepath.Join(t.TempDir(), "chains.json")
+	assert.NoError(t, WriteChainIndex(index, file))
+
+	read, err := ReadChainIndex(file)
+	assert.NoError(t, err)
+	assert.Equal(t, index.Chains(), read.Chains())
+
+	assert.ErrorIs(t, WriteChainIndex(nil, file), ErrChainIndexIsNil)
+}
+
+// checkPruneUnipartite using the graph:
+//
+//	a -- b -- c -- d -- e -- f
+//	     |                   |
+//	     g ----------------- h -- i -- j
+//
+//	k -- l -- k2         x (isolated)
+//	p -- q -- r -- p     (triangle)
+func checkPruneUnipartite(t *testing.T, graph UnipartiteGraphStore) {
+
+	assert.NoError(t, BuildFromEdgeList(graph, []Edge{
+		{V1: "a", V2: "b"},
+		{V1: "b", V2: "c"},
+		{V1: "c", V2: "d"},
+		{V1: "d", V2: "e"},
+		{V1: "e", V2: "f"},
+		{V1: "f", V2: "h"},
+		{V1: "b", V2: "g"},
+		{V1: "g", V2: "h"},
+		{V1: "h", V2: "i"},
+		{V1: "i", V2: "j"},
+		{V1: "k", V2: "l"},
+		{V1: "l", V2: "k2"},
+		{V1: "p", V2: "q"},
+		{V1: "q", V2: "r"},
+		{V1: "r", V2: "p"},
+	}))
+	assert.NoError(t, graph.AddEntity("x"))
+
+	// Only chains with at least three entities inside them are collapsed
+	result, err := PruneUnipartite(graph, UnipartitePruneOptions{
+		Isolated:       true,
+		MinChainLength: 3,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.IsolatedRemoved)
+	assert.Equal(t, 1, result.ChainsCollapsed)
+	assert.Equal(t, 4, result.ChainEntities)
+
+	chain, found := result.Chains.Get("b", "h")
+	assert.True(t, found)
+	assert.Equal(t, []string{"c", "d", "e", "f"}, chain.Via)
+	assert.Equal(t, chain.Hops(), len(chain.Edges))
+
+	entityIds, err := graph.EntityIds()
+	assert.NoError(t, err)
+	assert.Equal(t, set.NewPopulatedSet("a", "b", "g", "h", "i", "j", "k", "l", "k2", "p", "q",
+		"r"), entityIds)
+
+	// The collapsed chain is an undirected edge between its ends
+	for _, edge := range [][]string{{"b", "h"}, {"h", "b"}} {
+		connected, err := graph.EdgeExists(edge[0], edge[1])
+		assert.NoError(t, err)
+		assert.True(t, connected)
+	}
+
+	// Pruning again doesn't change the graph
+	result, err = PruneUnipartite(graph, UnipartitePruneOptions{
+		Isolated:       true,
+		MinChainLength: 3,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, result.IsolatedRemoved)
+	assert.Equal(t, 0, result.ChainsCollapsed)
+}
+
+func TestPruneUnipartite(t *testing.T) {
+
+	// In-memory store
+	checkPruneUnipartite(t, NewInMemoryUnipartiteGraphStore())
+
+	// Pebble store
+	pebbleUnipartite := newUnipartitePebbleStore(t)
+	defer cleanUpUnipartitePebbleStore(t, pebbleUnipartite)
+
+	checkPruneUnipartite(t, pebbleUnipartite)
+
+	// Invalid arguments
+	_, err := PruneUnipartite(nil, UnipartitePruneOptions{})
+	assert.ErrorIs(t, err, ErrUnipartiteStoreIsNil)
+
+	_, err = PruneUnipartite(NewInMemoryUnipartiteGraphStore(),
+		UnipartitePruneOptions{MinChainLength: -1})
+	assert.ErrorIs(t, err, ErrInvalidMinChainLength)
+
+	// The chains file is required to collapse chains
+	assert.NoError(t, UnipartitePruneOptions{Isolated: true}.Validate())
+	assert.NoError(t, UnipartitePruneOptions{MinChainLength: 2, ChainsFile: "chains.json"}.Validate())
+	assert.ErrorIs(t, UnipartitePruneOptions{MinChainLength: -1}.Validate(), ErrInvalidMinChainLength)
+	assert.ErrorIs(t, UnipartitePruneOptions{MinChainLength: 2}.Validate(), ErrChainsFileRequired)
+}
+
+func TestPruneUnipartiteKeepsDirectedChains(t *testing.T) {
+
+	// A chain with a directed edge can't be collapsed into an undirected edge
+	graph := NewInMemoryUnipartiteGraphStore()
+	assert.NoError(t, graph.AddUndirected("a", "b"))
+	assert.NoError(t, graph.AddDirected("b", "c"))
+	assert.NoError(t, graph.AddUndirected("c", "d"))
+	assert.NoError(t, graph.AddUndirected("d", "e"))
+
+	result, err := PruneUnipartite(graph, UnipartitePruneOptions{MinChainLength: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.ChainsCollapsed)
+
+	chain, found := result.Chains.Get("c", "e")
+	assert.True(t, found)
+	assert.Equal(t, []string{"d"}, chain.Via)
+
+	connected, err := graph.EdgeExists("b", "c")
+	assert.NoError(t, err)
+	assert.True(t, connected)
+}
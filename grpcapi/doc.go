@@ -0,0 +1,6 @@
+// Package grpcapi holds the gRPC service definition of the path service and the code generated
+// from it. To regenerate the code after changing path_service.proto, run go generate with protoc,
+// protoc-gen-go and protoc-gen-go-grpc on the path.
+package grpcapi
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative path_service.proto
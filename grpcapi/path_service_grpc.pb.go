@@ -0,0 +1,231 @@
+// The path service finds the paths between entities and grows a graph from an entity, backed by the
+// same job runners as the web-app.
+
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.3.0
+// - protoc             (unknown)
+// source: path_service.proto
+
+package grpcapi
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.32.0 or later.
+const _ = grpc.SupportPackageIsVersion7
+
+const (
+	PathService_SubmitJob_FullMethodName    = "/shortestpath.v1.PathService/SubmitJob"
+	PathService_GetJob_FullMethodName       = "/shortestpath.v1.PathService/GetJob"
+	PathService_FindPaths_FullMethodName    = "/shortestpath.v1.PathService/FindPaths"
+	PathService_SpiderEntity_FullMethodName = "/shortestpath.v1.PathService/SpiderEntity"
+)
+
+// PathServiceClient is the client API for PathService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+type PathServiceClient interface {
+	// Submit a job to find the shortest paths between the entities of the datasets.
+	SubmitJob(ctx context.Context, in *SubmitJobRequest, opts ...grpc.CallOption) (*SubmitJobResponse, error)
+	// Get the status of a job.
+	GetJob(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (*Job, error)
+	// Find the paths between two entities whilst the caller waits.
+	FindPaths(ctx context.Context, in *FindPathsRequest, opts ...grpc.CallOption) (*FindPathsResponse, error)
+	// Find the connections between the entities within a number of steps of an entity.
+	SpiderEntity(ctx context.Context, in *SpiderEntityRequest, opts ...grpc.CallOption) (*SpiderEntityResponse, error)
+}
+
+type pathServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewPathServiceClient(cc grpc.ClientConnInterface) PathServiceClient {
+	return &pathServiceClient{cc}
+}
+
+func (c *pathServiceClient) SubmitJob(ctx context.Context, in *SubmitJobRequest, opts ...grpc.CallOption) (*SubmitJobResponse, error) {
+	out := new(SubmitJobResponse)
+	err := c.cc.Invoke(ctx, PathService_SubmitJob_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pathServiceClient) GetJob(ctx context.Context, in *GetJobRequest, opts ...grpc.CallOption) (*Job, error) {
+	out := new(Job)
+	err := c.cc.Invoke(ctx, PathService_GetJob_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pathServiceClient) FindPaths(ctx context.Context, in *FindPathsRequest, opts ...grpc.CallOption) (*FindPathsResponse, error) {
+	out := new(FindPathsResponse)
+	err := c.cc.Invoke(ctx, PathService_FindPaths_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *pathServiceClient) SpiderEntity(ctx context.Context, in *SpiderEntityRequest, opts ...grpc.CallOption) (*SpiderEntityResponse, error) {
+	out := new(SpiderEntityResponse)
+	err := c.cc.Invoke(ctx, PathService_SpiderEntity_FullMethodName, in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PathServiceServer is the server API for PathService service.
+// All implementations must embed UnimplementedPathServiceServer
+// for forward compatibility
+type PathServiceServer interface {
+	// Submit a job to find the shortest paths between the entities of the datasets.
+	SubmitJob(context.Context, *SubmitJobRequest) (*SubmitJobResponse, error)
+	// Get the status of a job.
+	GetJob(context.Context, *GetJobRequest) (*Job, error)
+	// Find the paths between two entities whilst the caller waits.
+	FindPaths(context.Context, *FindPathsRequest) (*FindPathsResponse, error)
+	// Find the connections between the entities within a number of steps of an entity.
+	SpiderEntity(context.Context, *SpiderEntityRequest) (*SpiderEntityResponse, error)
+	mustEmbedUnimplementedPathServiceServer()
+}
+
+// UnimplementedPathServiceServer must be embedded to have forward compatible implementations.
+type UnimplementedPathServiceServer struct {
+}
+
+func (UnimplementedPathServiceServer) SubmitJob(context.Context, *SubmitJobRequest) (*SubmitJobResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SubmitJob not implemented")
+}
+func (UnimplementedPathServiceServer) GetJob(context.Context, *GetJobRequest) (*Job, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetJob not implemented")
+}
+func (UnimplementedPathServiceServer) FindPaths(context.Context, *FindPathsRequest) (*FindPathsResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FindPaths not implemented")
+}
+func (UnimplementedPathServiceServer) SpiderEntity(context.Context, *SpiderEntityRequest) (*SpiderEntityResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SpiderEntity not implemented")
+}
+func (UnimplementedPathServiceServer) mustEmbedUnimplementedPathServiceServer() {}
+
+// UnsafePathServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to PathServiceServer will
+// result in compilation errors.
+type UnsafePathServiceServer interface {
+	mustEmbedUnimplementedPathServiceServer()
+}
+
+func RegisterPathServiceServer(s grpc.ServiceRegistrar, srv PathServiceServer) {
+	s.RegisterService(&PathService_ServiceDesc, srv)
+}
+
+func _PathService_SubmitJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SubmitJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PathServiceServer).SubmitJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PathService_SubmitJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PathServiceServer).SubmitJob(ctx, req.(*SubmitJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PathService_GetJob_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetJobRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PathServiceServer).GetJob(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PathService_GetJob_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PathServiceServer).GetJob(ctx, req.(*GetJobRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PathService_FindPaths_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FindPathsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PathServiceServer).FindPaths(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PathService_FindPaths_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PathServiceServer).FindPaths(ctx, req.(*FindPathsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _PathService_SpiderEntity_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SpiderEntityRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(PathServiceServer).SpiderEntity(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: PathService_SpiderEntity_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(PathServiceServer).SpiderEntity(ctx, req.(*SpiderEntityRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// PathService_ServiceDesc is the grpc.ServiceDesc for PathService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var PathService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "shortestpath.v1.PathService",
+	HandlerType: (*PathServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "SubmitJob",
+			Handler:    _PathService_SubmitJob_Handler,
+		},
+		{
+			MethodName: "GetJob",
+			Handler:    _PathService_GetJob_Handler,
+		},
+		{
+			MethodName: "FindPaths",
+			Handler:    _PathService_FindPaths_Handler,
+		},
+		{
+			MethodName: "SpiderEntity",
+			Handler:    _PathService_SpiderEntity_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "path_service.proto",
+}
@@ -0,0 +1,309 @@
+// Package i18n translates the user-facing text of the web-app. Each supported language has a
+// bundle of message keys to text, which is embedded in the binary. The language for a request is
+// negotiated from its Accept-Language header, falling back to a configurable default language.
+
+package i18n
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+)
+
+// Component name used in logging
+const componentName = "i18n"
+
+// Language used if no other language is configured or requested
+const DefaultLanguage = "en"
+
+var ErrUnsupportedLanguage = errors.New("unsupported language")
+
+//go:embed locales/*.json
+var localesFS embed.FS
+
+// A Bundle maps message keys to the text in a single language. The text may contain fmt verbs
+// that are populated with the message's arguments.
+type Bundle map[string]string
+
+// readBundles of all the languages in the embedded locales folder.
+func readBundles() (map[string]Bundle, error) {
+
+	entries, err := localesFS.ReadDir("locales")
+	if err != nil {
+		return nil, err
+	}
+
+	bundles := map[string]Bundle{}
+	for _, entry := range entries {
+		content, err := localesFS.ReadFile(path.Join("locales", entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		bundle := Bundle{}
+		if err := json.Unmarshal(content, &bundle); err != nil {
+			return nil, fmt.Errorf("unable to read locale %v: %w", entry.Name(), err)
+		}
+
+		language := strings.TrimSuffix(entry.Name(), path.Ext(entry.Name()))
+		bundles[language] = bundle
+	}
+
+	return bundles, nil
+}
+
+// A Translator translates message keys into the text for a language.
+type Translator struct {
+	bundles         map[string]Bundle // Language to bundle
+	defaultLanguage string            // Language to use if the requested one isn't supported
+}
+
+// NewTranslator with the embedded language bundles and the default language.
+func NewTranslator(defaultLanguage string) (*Translator, error) {
+
+	bundles, err := readBundles()
+	if err != nil {
+		return nil, err
+	}
+
+	translator := &Translator{
+		bundles:         bundles,
+		defaultLanguage: DefaultLanguage,
+	}
+
+	if err := translator.SetDefaultLanguage(defaultLanguage); err != nil {
+		return nil, err
+	}
+
+	return translator, nil
+}
+
+// SetDefaultLanguage used when a request doesn't specify a supported language.
+func (t *Translator) SetDefaultLanguage(language string) error {
+
+	if _, found := t.bundles[language]; !found {
+		return fmt.Errorf("%w: %v", ErrUnsupportedLanguage, language)
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("language", language).
+		Msg("Setting the default language")
+
+	t.defaultLanguage = language
+	return nil
+}
+
+// DefaultLanguage returns the language used if a request doesn't specify a supported language.
+func (t *Translator) DefaultLanguage() string {
+	return t.defaultLanguage
+}
+
+// Languages returns the sorted list of supported languages.
+func (t *Translator) Languages() []string {
+	languages := []string{}
+	for language := range t.bundles {
+		languages = append(languages, language)
+	}
+
+	sort.Strings(languages)
+	return languages
+}
+
+// languagePreference is a language from an Accept-Language header and its quality value.
+type languagePreference struct {
+	language string
+	quality  float64
+}
+
+// parseAcceptLanguage header into the languages in order of preference.
+func parseAcceptLanguage(acceptLanguage string) []languagePreference {
+
+	preferences := []languagePreference{}
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		language := strings.ToLower(strings.TrimSpace(fields[0]))
+		if len(language) == 0 {
+			continue
+		}
+
+		quality := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if value, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64); err == nil {
+					quality = value
+				}
+			}
+		}
+
+		if quality > 0 {
+			preferences = append(preferences, languagePreference{language, quality})
+		}
+	}
+
+	sort.SliceStable(preferences, func(i, j int) bool {
+		return preferences[i].quality > preferences[j].quality
+	})
+
+	return preferences
+}
+
+// Negotiate the language to use given the Accept-Language header of a request. A regional
+// variant (e.g. cy-GB) matches its primary language (cy).
+func (t *Translator) Negotiate(acceptLanguage string) string {
+
+	for _, preference := range parseAcceptLanguage(acceptLanguage) {
+		if _, found := t.bundles[preference.language]; found {
+			return preference.language
+		}
+
+		primary := strings.Split(preference.language, "-")[0]
+		if _, found := t.bundles[primary]; found {
+			return primary
+		}
+	}
+
+	return t.defaultLanguage
+}
+
+// lookup the text for the key, falling back to the default language and then English.
+func (t *Translator) lookup(language string, key string) (string, bool) {
+	for _, lang := range []string{language, t.defaultLanguage, DefaultLanguage} {
+		if text, found := t.bundles[lang][key]; found {
+			return text, true
+		}
+	}
+
+	return "", false
+}
+
+// Translate the message key into the language. Arguments that are errors are also translated.
+// If the key isn't known, the key is returned.
+func (t *Translator) Translate(language string, key string, args ...interface{}) string {
+
+	text, found := t.lookup(language, key)
+	if !found {
+		logging.Logger.Warn().
+			Str(logging.ComponentField, componentName).
+			Str("language", language).
+			Str("key", key).
+			Msg("Translation not found")
+		return key
+	}
+
+	if len(args) == 0 {
+		return text
+	}
+
+	translatedArgs := make([]interface{}, len(args))
+	for idx, arg := range args {
+		if err, ok := arg.(error); ok {
+			translatedArgs[idx] = t.TranslateError(language, err)
+		} else {
+			translatedArgs[idx] = arg
+		}
+	}
+
+	return fmt.Sprintf(text, translatedArgs...)
+}
+
+// TranslateMessage into the language. A nil message is translated to an empty string.
+func (t *Translator) TranslateMessage(language string, message *Message) string {
+	if message == nil {
+		return ""
+	}
+
+	return t.Translate(language, message.Key, message.Args...)
+}
+
+// TranslateMessages into the language.
+func (t *Translator) TranslateMessages(language string, messages []*Message) []string {
+	translated := make([]string, 0, len(messages))
+	for _, message := range messages {
+		translated = append(translated, t.TranslateMessage(language, message))
+	}
+
+	return translated
+}
+
+// TranslateError into the language if it is (or wraps) a Message. Other errors can't be
+// translated, so their text is returned.
+func (t *Translator) TranslateError(language string, err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var message *Message
+	if errors.As(err, &message) {
+		return t.TranslateMessage(language, message)
+	}
+
+	return err.Error()
+}
+
+// A Message is user-facing text that is translated when it is presented. It implements the error
+// interface, so that user-facing errors can be translated.
+type Message struct {
+	Key  string        // Key of the text in the language bundles
+	Args []interface{} // Arguments for the text
+	Err  error         // Optional error that the message describes
+}
+
+// NewMessage with the key and its arguments.
+func NewMessage(key string, args ...interface{}) *Message {
+	return &Message{
+		Key:  key,
+		Args: args,
+	}
+}
+
+// Wrap an error with a message, so that errors.Is still matches the error.
+func Wrap(err error, key string, args ...interface{}) *Message {
+	return &Message{
+		Key:  key,
+		Args: args,
+		Err:  err,
+	}
+}
+
+// English translator used for the text of a Message when it is treated as an error
+var (
+	englishTranslator     *Translator
+	englishTranslatorOnce sync.Once
+)
+
+// Error returns the message in English.
+func (m *Message) Error() string {
+
+	englishTranslatorOnce.Do(func() {
+		translator, err := NewTranslator(DefaultLanguage)
+		if err != nil {
+			logging.Logger.Error().
+				Str(logging.ComponentField, componentName).
+				Err(err).
+				Msg("Failed to create the English translator")
+			return
+		}
+		englishTranslator = translator
+	})
+
+	if englishTranslator == nil {
+		return m.Key
+	}
+
+	return englishTranslator.TranslateMessage(DefaultLanguage, m)
+}
+
+// Unwrap returns the error that the message describes (if any).
+func (m *Message) Unwrap() error {
+	return m.Err
+}
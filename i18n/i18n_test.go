@@ -0,0 +1,158 @@
+package i18n
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTranslator(t *testing.T) {
+	translator, err := NewTranslator(DefaultLanguage)
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultLanguage, translator.DefaultLanguage())
+	assert.Equal(t, []string{"cy", "en"}, translator.Languages())
+
+	translator, err = NewTranslator("cy")
+	assert.NoError(t, err)
+	assert.Equal(t, "cy", translator.DefaultLanguage())
+
+	_, err = NewTranslator("fr")
+	assert.ErrorIs(t, err, ErrUnsupportedLanguage)
+}
+
+func TestBundlesHaveSameKeys(t *testing.T) {
+	bundles, err := readBundles()
+	assert.NoError(t, err)
+
+	english, found := bundles[DefaultLanguage]
+	assert.True(t, found)
+
+	for language, bundle := range bundles {
+		for key := range english {
+			assert.Contains(t, bundle, key, fmt.Sprintf("%v is missing %v", language, key))
+		}
+		for key := range bundle {
+			assert.Contains(t, english, key, fmt.Sprintf("%v has unknown key %v", language, key))
+		}
+	}
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	testCases := []struct {
+		acceptLanguage string
+		expected       []languagePreference
+	}{
+		{
+			acceptLanguage: "",
+			expected:       []languagePreference{},
+		},
+		{
+			acceptLanguage: "cy",
+			expected:       []languagePreference{{"cy", 1.0}},
+		},
+		{
+			acceptLanguage: "en;q=0.8, cy-GB",
+			expected:       []languagePreference{{"cy-gb", 1.0}, {"en", 0.8}},
+		},
+		{
+			acceptLanguage: "fr, de;q=0, en;q=0.5",
+			expected:       []languagePreference{{"fr", 1.0}, {"en", 0.5}},
+		},
+		{
+			acceptLanguage: "cy;q=abc",
+			expected:       []languagePreference{{"cy", 1.0}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.acceptLanguage, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, parseAcceptLanguage(testCase.acceptLanguage))
+		})
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	translator, err := NewTranslator(DefaultLanguage)
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		acceptLanguage string
+		expected       string
+	}{
+		{"", "en"},
+		{"cy", "cy"},
+		{"CY-gb", "cy"},
+		{"fr", "en"},
+		{"fr, cy;q=0.5", "cy"},
+		{"en;q=0.5, cy;q=0.9", "cy"},
+		{"cy;q=0", "en"},
+		{"*", "en"},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.acceptLanguage, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, translator.Negotiate(testCase.acceptLanguage))
+		})
+	}
+
+	// Unsupported languages fall back to the configured default
+	assert.NoError(t, translator.SetDefaultLanguage("cy"))
+	assert.Equal(t, "cy", translator.Negotiate("fr"))
+	assert.Equal(t, "en", translator.Negotiate("en-US"))
+}
+
+func TestTranslate(t *testing.T) {
+	translator, err := NewTranslator(DefaultLanguage)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "Statistics", translator.Translate("en", "stats.title"))
+	assert.Equal(t, "Ystadegau", translator.Translate("cy", "stats.title"))
+
+	// Unsupported language
+	assert.Equal(t, "Statistics", translator.Translate("fr", "stats.title"))
+
+	// Unknown key
+	assert.Equal(t, "unknown.key", translator.Translate("cy", "unknown.key"))
+
+	// Arguments, including a message as an error
+	assert.Equal(t, "invalid number of hops: 7", translator.Translate("en", "error.invalidNumberOfHops", 7))
+	assert.Equal(t, "gwall wrth ddosrannu set ddata: nid oes enw gan y set ddata",
+		translator.Translate("cy", "error.datasetParse", NewMessage("error.datasetNoName")))
+}
+
+func TestTranslateError(t *testing.T) {
+	translator, err := NewTranslator(DefaultLanguage)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "", translator.TranslateError("cy", nil))
+	assert.Equal(t, "", translator.TranslateMessage("cy", nil))
+	assert.Equal(t, []string{}, translator.TranslateMessages("cy", nil))
+	assert.Equal(t, []string{"invalid number of hops: 7"}, translator.TranslateMessages("en",
+		[]*Message{NewMessage("error.invalidNumberOfHops", 7)}))
+
+	// An error that isn't a message can't be translated
+	assert.Equal(t, "disk full", translator.TranslateError("cy", errors.New("disk full")))
+
+	// A message that is wrapped by another error
+	message := NewMessage("error.noSeedEntities")
+	wrapped := fmt.Errorf("wrapped: %w", message)
+	assert.Equal(t, "dim endidau hadu", translator.TranslateError("cy", wrapped))
+}
+
+func TestMessage(t *testing.T) {
+	sentinel := errors.New("too many")
+	message := Wrap(sentinel, "error.tooManySeedEntities", 3, 2)
+
+	assert.Equal(t, "too many seed entities: 3 provided, maximum is 2", message.Error())
+	assert.ErrorIs(t, message, sentinel)
+
+	// A message that is a sentinel error
+	sentinelMessage := NewMessage("error.noDatasets")
+	assert.ErrorIs(t, Wrap(sentinelMessage, "error.datasetParse", sentinelMessage), sentinelMessage)
+	assert.Nil(t, sentinelMessage.Unwrap())
+
+	// A message with an unknown key
+	assert.Equal(t, "unknown.key", NewMessage("unknown.key").Error())
+}
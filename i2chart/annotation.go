@@ -0,0 +1,29 @@
+package i2chart
+
+import (
+	"github.com/cdclaxton/shortest-path-web-app/annotation"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+)
+
+// SetAnnotations whose tags and note of each entity can be used in the entity specifications with
+// the ANNOTATION-TAGS and ANNOTATION-NOTE keywords. A nil store means no entity has an annotation.
+func (i *I2ChartBuilder) SetAnnotations(annotations *annotation.AnnotationStore) {
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Bool("annotations", annotations != nil).
+		Msg("Setting the annotations in the i2 chart builder")
+	i.annotations = annotations
+}
+
+// entityKeywords of the row for an entity, which take precedence over the entity's annotation and
+// the deployment keywords.
+func (i *I2ChartBuilder) entityKeywords(entityId string, rowKeywords map[string]string) (
+	map[string]string, error) {
+
+	annotationKeywords, err := i.annotations.Keywords(entityId)
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeKeywords(mergeKeywords(i.deploymentKeywords, annotationKeywords), rowKeywords), nil
+}
@@ -0,0 +1,41 @@
+package i2chart
+
+import (
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/annotation"
+	"github.com/cdclaxton/shortest-path-web-app/graphbuilder"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRowLinkingEntitiesWithAnnotations(t *testing.T) {
+
+	graphBuilder, _, err := graphbuilder.NewGraphBuilderFromJson("../test-data-sets/set-1/data-config.json")
+	assert.NoError(t, err)
+
+	chartBuilder, err := NewI2ChartBuilder("../test-data-sets/set-1/i2-config.json")
+	assert.NoError(t, err)
+	chartBuilder.SetBipartite(graphBuilder.Bipartite)
+
+	chartBuilder.config.Entities["Person"]["description"] = "<ID> [<ANNOTATION-TAGS>] <ANNOTATION-NOTE>"
+
+	// Without an annotation store, the keywords are blank
+	row, err := chartBuilder.rowLinkingEntities("e-1", "e-2", map[string]string{}, map[string]string{}, nil,
+		nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "e-1 [] ", row[4])
+
+	annotations, err := annotation.NewAnnotationStore(t.TempDir())
+	assert.NoError(t, err)
+	defer annotations.Close()
+
+	_, err = annotations.Set("e-1", []string{"cleared", "priority"}, "Seen before")
+	assert.NoError(t, err)
+	chartBuilder.SetAnnotations(annotations)
+
+	row, err = chartBuilder.rowLinkingEntities("e-1", "e-2", map[string]string{}, map[string]string{}, nil,
+		nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "e-1 [cleared, priority] Seen before", row[4])
+	assert.Equal(t, "e-2 [] ", row[9])
+}
@@ -0,0 +1,297 @@
+package i2chart
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+
+	"github.com/cdclaxton/shortest-path-web-app/bfs"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+)
+
+// Default columns of the i2 chart config used to build an ANX chart.
+const (
+	defaultAnxIdentityColumn    = "id"
+	defaultAnxIconColumn        = "icon"
+	defaultAnxLabelColumn       = "label"
+	defaultAnxDescriptionColumn = "description"
+)
+
+var (
+	ErrAnxIdentityColumnNotFound = errors.New("identity column for ANX chart not found")
+	ErrAnxMalformedTable         = errors.New("malformed i2 chart table")
+	ErrAnxDisabled               = errors.New("ANX chart disabled as the identity column isn't a chart column")
+)
+
+// AnxSpec maps the i2 chart config columns to the properties of an entity in an i2 Analyst's
+// Notebook chart. Empty fields take their default values.
+type AnxSpec struct {
+	IdentityColumn    string `json:"identityColumn"`    // Column holding the unique identity
+	IconColumn        string `json:"iconColumn"`        // Column holding the i2 icon type
+	LabelColumn       string `json:"labelColumn"`       // Column holding the entity label
+	DescriptionColumn string `json:"descriptionColumn"` // Column holding the entity description
+}
+
+// withDefaults returns the ANX spec with empty fields set to their defaults.
+func (a AnxSpec) withDefaults() AnxSpec {
+	if len(a.IdentityColumn) == 0 {
+		a.IdentityColumn = defaultAnxIdentityColumn
+	}
+
+	if len(a.IconColumn) == 0 {
+		a.IconColumn = defaultAnxIconColumn
+	}
+
+	if len(a.LabelColumn) == 0 {
+		a.LabelColumn = defaultAnxLabelColumn
+	}
+
+	if len(a.DescriptionColumn) == 0 {
+		a.DescriptionColumn = defaultAnxDescriptionColumn
+	}
+
+	return a
+}
+
+// anxSpecIssues returns the issues with the ANX spec given the columns of the i2 chart config, i.e.
+// the columns set in the spec that aren't chart columns. The default columns needn't exist.
+func anxSpecIssues(spec AnxSpec, columns []string) []string {
+
+	specColumns := []struct {
+		property string
+		column   string
+	}{
+		{"identity", spec.IdentityColumn},
+		{"icon", spec.IconColumn},
+		{"label", spec.LabelColumn},
+		{"description", spec.DescriptionColumn},
+	}
+
+	issues := []string{}
+	for _, specColumn := range specColumns {
+		if len(specColumn.column) > 0 && columnIndex(columns, specColumn.column) == -1 {
+			issues = append(issues, fmt.Sprintf("ANX %v column %v is not a chart column",
+				specColumn.property, specColumn.column))
+		}
+	}
+
+	return issues
+}
+
+// anxEnabled returns true if an ANX chart can be built with the spec from a table with the columns,
+// i.e. the identity column is one of the columns.
+func anxEnabled(spec AnxSpec, columns []string) bool {
+	return columnIndex(columns, spec.withDefaults().IdentityColumn) != -1
+}
+
+// AnxIconStyle is the icon for an entity.
+type AnxIconStyle struct {
+	Type string `xml:"Type,attr"`
+}
+
+// AnxIcon wraps the icon style of an entity.
+type AnxIcon struct {
+	IconStyle AnxIconStyle `xml:"IconStyle"`
+}
+
+// AnxEntity is an entity on an i2 chart.
+type AnxEntity struct {
+	EntityId        string  `xml:"EntityId,attr"`
+	Identity        string  `xml:"Identity,attr"`
+	LabelIsIdentity bool    `xml:"LabelIsIdentity,attr"`
+	Icon            AnxIcon `xml:"Icon"`
+}
+
+// AnxEnd wraps an entity.
+type AnxEnd struct {
+	Entity AnxEntity `xml:"Entity"`
+}
+
+// AnxLinkStyle is the style of a link.
+type AnxLinkStyle struct {
+	ArrowStyle string `xml:"ArrowStyle,attr"`
+	Type       string `xml:"Type,attr"`
+}
+
+// AnxLink connects two entities on an i2 chart.
+type AnxLink struct {
+	End1Id    string       `xml:"End1Id,attr"`
+	End2Id    string       `xml:"End2Id,attr"`
+	LinkStyle AnxLinkStyle `xml:"LinkStyle"`
+}
+
+// AnxChartItem is either an entity (End) or a link.
+type AnxChartItem struct {
+	Label       string   `xml:"Label,attr"`
+	Description string   `xml:"Description,attr,omitempty"`
+	End         *AnxEnd  `xml:"End,omitempty"`
+	Link        *AnxLink `xml:"Link,omitempty"`
+}
+
+// AnxChart is an i2 Analyst's Notebook chart in its XML (ANX) form.
+type AnxChart struct {
+	XMLName   xml.Name       `xml:"Chart"`
+	ChartItem []AnxChartItem `xml:"ChartItemCollection>ChartItem"`
+}
+
+// columnIndex returns the index of the column in columns or -1 if it isn't present.
+func columnIndex(columns []string, column string) int {
+	for idx, c := range columns {
+		if c == column {
+			return idx
+		}
+	}
+
+	return -1
+}
+
+// fieldOrEmpty returns the field at index idx or an empty string if the index is invalid.
+func fieldOrEmpty(fields []string, idx int) string {
+	if idx < 0 || idx >= len(fields) {
+		return ""
+	}
+
+	return fields[idx]
+}
+
+// anxEntityItem makes an ANX chart item for an entity given its fields.
+func anxEntityItem(entityId string, fields []string, columns []string, spec AnxSpec) AnxChartItem {
+	return AnxChartItem{
+		Label:       fieldOrEmpty(fields, columnIndex(columns, spec.LabelColumn)),
+		Description: fieldOrEmpty(fields, columnIndex(columns, spec.DescriptionColumn)),
+		End: &AnxEnd{
+			Entity: AnxEntity{
+				EntityId:        entityId,
+				Identity:        fieldOrEmpty(fields, columnIndex(columns, spec.IdentityColumn)),
+				LabelIsIdentity: false,
+				Icon: AnxIcon{
+					IconStyle: AnxIconStyle{
+						Type: fieldOrEmpty(fields, columnIndex(columns, spec.IconColumn)),
+					},
+				},
+			},
+		},
+	}
+}
+
+// tableToAnx converts the table of an i2 chart (as produced by Build) into an ANX chart. The
+// first row of the table is expected to be the header.
+func tableToAnx(table [][]string, columns []string, spec AnxSpec) (*AnxChart, error) {
+
+	// Preconditions
+	if len(table) == 0 {
+		return nil, ErrAnxMalformedTable
+	}
+
+	spec = spec.withDefaults()
+	identityIdx := columnIndex(columns, spec.IdentityColumn)
+	if identityIdx == -1 {
+		return nil, ErrAnxIdentityColumnNotFound
+	}
+
+	// Expected number of fields in each row (two entities and a link label)
+	numberFields := len(columns)*2 + 1
+
+	entityItems := []AnxChartItem{}
+	linkItems := []AnxChartItem{}
+
+	// Mapping of an entity's identity to its ID in the chart
+	identityToEntityId := map[string]string{}
+
+	// addEntity to the chart if it hasn't already been added and return its ID in the chart
+	addEntity := func(fields []string) string {
+		identity := fields[identityIdx]
+
+		if entityId, found := identityToEntityId[identity]; found {
+			return entityId
+		}
+
+		entityId := "entity-" + strconv.Itoa(len(identityToEntityId)+1)
+		identityToEntityId[identity] = entityId
+		entityItems = append(entityItems, anxEntityItem(entityId, fields, columns, spec))
+
+		return entityId
+	}
+
+	// Walk through each row, skipping the header
+	for rowIdx, row := range table[1:] {
+		if len(row) != numberFields {
+			return nil, fmt.Errorf("%w: row %v has %v fields, expected %v",
+				ErrAnxMalformedTable, rowIdx+1, len(row), numberFields)
+		}
+
+		end1Id := addEntity(row[:len(columns)])
+		end2Id := addEntity(row[len(columns) : 2*len(columns)])
+
+		linkItems = append(linkItems, AnxChartItem{
+			Label: row[len(row)-1],
+			Link: &AnxLink{
+				End1Id: end1Id,
+				End2Id: end2Id,
+				LinkStyle: AnxLinkStyle{
+					ArrowStyle: "ArrowNone",
+					Type:       "Link",
+				},
+			},
+		})
+	}
+
+	// Entities must be defined before the links that reference them
+	return &AnxChart{
+		ChartItem: append(entityItems, linkItems...),
+	}, nil
+}
+
+// BuildAnx builds an i2 Analyst's Notebook chart (ANX) from the network connections.
+func (i *I2ChartBuilder) BuildAnx(conns *bfs.NetworkConnections) (*AnxChart, error) {
+
+	// Build the i2 chart as a table
+	table, err := i.Build(conns)
+	if err != nil {
+		return nil, err
+	}
+
+	return i.TableToAnx(table)
+}
+
+// AnxEnabled returns true if ANX charts can be built, i.e. the identity column of the ANX spec is
+// one of the chart columns.
+func (i *I2ChartBuilder) AnxEnabled() bool {
+	return anxEnabled(i.config.Anx, i.config.Columns)
+}
+
+// TableToAnx converts a table produced by Build into an i2 Analyst's Notebook chart (ANX).
+func (i *I2ChartBuilder) TableToAnx(table [][]string) (*AnxChart, error) {
+	if !i.AnxEnabled() {
+		return nil, ErrAnxDisabled
+	}
+	return tableToAnx(table, i.config.Columns, i.config.Anx)
+}
+
+// WriteToAnx writes the ANX chart to the file at filepath.
+func WriteToAnx(filepath string, chart *AnxChart) error {
+
+	// Preconditions
+	if len(filepath) == 0 {
+		return errors.New("filepath is empty")
+	}
+
+	if chart == nil {
+		return errors.New("chart to write is nil")
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("filepath", filepath).
+		Str("numberOfChartItems", strconv.Itoa(len(chart.ChartItem))).
+		Msg("Writing ANX file")
+
+	content, err := xml.MarshalIndent(chart, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath, append([]byte(xml.Header), content...), 0644)
+}
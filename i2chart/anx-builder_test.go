@@ -0,0 +1,154 @@
+package i2chart
+
+import (
+	"encoding/xml"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/bfs"
+	"github.com/cdclaxton/shortest-path-web-app/graphbuilder"
+	"github.com/cdclaxton/shortest-path-web-app/set"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAnxSpecWithDefaults(t *testing.T) {
+	spec := AnxSpec{LabelColumn: "name"}.withDefaults()
+
+	assert.Equal(t, AnxSpec{
+		IdentityColumn:    "id",
+		IconColumn:        "icon",
+		LabelColumn:       "name",
+		DescriptionColumn: "description",
+	}, spec)
+}
+
+func TestAnxSpecIssues(t *testing.T) {
+	columns := []string{"icon", "id", "label"}
+
+	// The default columns needn't exist
+	assert.Empty(t, anxSpecIssues(AnxSpec{}, columns))
+	assert.Empty(t, anxSpecIssues(AnxSpec{IdentityColumn: "id", LabelColumn: "label"}, columns))
+
+	// The columns that are set must exist
+	assert.Equal(t, []string{
+		"ANX identity column key is not a chart column",
+		"ANX description column notes is not a chart column",
+	}, anxSpecIssues(AnxSpec{IdentityColumn: "key", DescriptionColumn: "notes"}, columns))
+}
+
+func TestAnxEnabled(t *testing.T) {
+	assert.True(t, anxEnabled(AnxSpec{}, []string{"icon", "id", "label"}))
+	assert.False(t, anxEnabled(AnxSpec{}, []string{"icon", "key", "label"}))
+	assert.True(t, anxEnabled(AnxSpec{IdentityColumn: "key"}, []string{"icon", "key", "label"}))
+
+	// An ANX chart isn't built if it is disabled
+	builder := I2ChartBuilder{config: I2ChartConfig{Columns: []string{"icon", "key", "label"}}}
+	assert.False(t, builder.AnxEnabled())
+	_, err := builder.TableToAnx([][]string{{"header"}})
+	assert.ErrorIs(t, err, ErrAnxDisabled)
+}
+
+func TestTableToAnx(t *testing.T) {
+	columns := []string{"icon", "id", "label"}
+
+	testCases := []struct {
+		table         [][]string
+		spec          AnxSpec
+		expectedError error
+		expected      *AnxChart
+	}{
+		{
+			// Empty table
+			table:         [][]string{},
+			expectedError: ErrAnxMalformedTable,
+		},
+		{
+			// Identity column not found
+			table:         [][]string{header(columns)},
+			spec:          AnxSpec{IdentityColumn: "missing"},
+			expectedError: ErrAnxIdentityColumnNotFound,
+		},
+		{
+			// Row with too few fields
+			table:         [][]string{header(columns), {"Person", "e-1"}},
+			expectedError: ErrAnxMalformedTable,
+		},
+		{
+			// Header only
+			table:    [][]string{header(columns)},
+			expected: &AnxChart{ChartItem: []AnxChartItem{}},
+		},
+		{
+			// Two links sharing an entity
+			table: [][]string{
+				header(columns),
+				{"Person", "e-1", "Bob", "Person", "e-2", "Sally", "2 docs"},
+				{"Location", "e-3", "Field Drive", "Person", "e-1", "Bob", "1 docs"},
+			},
+			expected: &AnxChart{ChartItem: []AnxChartItem{
+				anxEntityItem("entity-1", []string{"Person", "e-1", "Bob"}, columns, AnxSpec{}.withDefaults()),
+				anxEntityItem("entity-2", []string{"Person", "e-2", "Sally"}, columns, AnxSpec{}.withDefaults()),
+				anxEntityItem("entity-3", []string{"Location", "e-3", "Field Drive"}, columns, AnxSpec{}.withDefaults()),
+				{
+					Label: "2 docs",
+					Link: &AnxLink{End1Id: "entity-1", End2Id: "entity-2",
+						LinkStyle: AnxLinkStyle{ArrowStyle: "ArrowNone", Type: "Link"}},
+				},
+				{
+					Label: "1 docs",
+					Link: &AnxLink{End1Id: "entity-3", End2Id: "entity-1",
+						LinkStyle: AnxLinkStyle{ArrowStyle: "ArrowNone", Type: "Link"}},
+				},
+			}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		actual, err := tableToAnx(testCase.table, columns, testCase.spec)
+		assert.ErrorIs(t, err, testCase.expectedError)
+		assert.Equal(t, testCase.expected, actual)
+	}
+}
+
+func TestBuildAndWriteAnx(t *testing.T) {
+
+	// Make the bipartite graph store
+	dataFilepath := "../test-data-sets/set-1/data-config.json"
+	graphBuilder, _, err := graphbuilder.NewGraphBuilderFromJson(dataFilepath)
+	assert.NoError(t, err)
+
+	// Make the i2 chart builder
+	chartBuilder, err := NewI2ChartBuilder("../test-data-sets/set-1/i2-config.json")
+	assert.NoError(t, err)
+	chartBuilder.SetBipartite(graphBuilder.Bipartite)
+
+	conns := &bfs.NetworkConnections{
+		EntityIdToSetNames: map[string]*set.Set[string]{
+			"e-1": set.NewPopulatedSet("Dataset-A"),
+		},
+		Connections: map[string]map[string][]bfs.Path{
+			"e-1": {"e-2": {{
+				Route: []string{"e-1", "e-2"},
+			}}},
+		},
+	}
+
+	chart, err := chartBuilder.BuildAnx(conns)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(chart.ChartItem))
+	assert.Equal(t, "Smith, Bob [Dataset-A]", chart.ChartItem[0].Label)
+	assert.Equal(t, "Person", chart.ChartItem[0].End.Entity.Icon.IconStyle.Type)
+	assert.Equal(t, "2 docs (Doc-A, Doc-B; 06/08/2022 - 07/08/2022)", chart.ChartItem[2].Label)
+
+	// Write the chart to file and read it back
+	filepath := path.Join(t.TempDir(), "chart.anx")
+	assert.NoError(t, WriteToAnx(filepath, chart))
+
+	content, err := os.ReadFile(filepath)
+	assert.NoError(t, err)
+
+	actual := AnxChart{}
+	assert.NoError(t, xml.Unmarshal(content, &actual))
+	assert.Equal(t, chart.ChartItem, actual.ChartItem)
+}
@@ -10,10 +10,13 @@ import (
 	"strconv"
 	"strings"
 
+	"github.com/cdclaxton/shortest-path-web-app/annotation"
 	"github.com/cdclaxton/shortest-path-web-app/bfs"
 	"github.com/cdclaxton/shortest-path-web-app/graphstore"
 	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cdclaxton/shortest-path-web-app/redaction"
 	"github.com/cdclaxton/shortest-path-web-app/set"
+	"github.com/rs/zerolog"
 	"golang.org/x/exp/maps"
 )
 
@@ -21,15 +24,123 @@ const componentName = "i2chart"
 
 // Keywords used in the configuration of an i2 chart.
 const (
-	entityIdKeyword       = "ID"
-	entitySetNamesKeyword = "ENTITY-SET-NAMES"
+	entityIdKeyword        = "ID"
+	entitySetNamesKeyword  = "ENTITY-SET-NAMES"
+	entitySetNames1Keyword = "ENTITY-SET-NAMES-1" // Entity set names of the first entity of a link
+	entitySetNames2Keyword = "ENTITY-SET-NAMES-2" // Entity set names of the second entity of a link
 )
 
+// Separator between the parts of a link label for documents of different types
+const linkLabelSeparator = "; "
+
+var ErrInvalidMaxRows = errors.New("invalid maximum number of rows")
+
 // LinksSpec represents the specification of a link between two entities in i2.
 type LinksSpec struct {
 	Label         string `json:"label"`         // Specification of the label connecting entities
 	DateAttribute string `json:"dateAttribute"` // Attribute holding the document date
 	DateFormat    string `json:"dateFormat"`    // Format of the document date
+
+	// Optional other formats of the document date and separators of a date range
+	DateFormats         []string `json:"dateFormats"`
+	DateRangeSeparators []string `json:"dateRangeSeparators"`
+
+	// Optional attribute holding the name of the file each document was loaded from
+	SourceAttribute string `json:"sourceAttribute"`
+
+	// Optional label specifications for specific document types
+	DocumentTypes map[string]DocumentTypeLinkSpec `json:"documentTypes"`
+}
+
+// DocumentTypeLinkSpec is the specification of the label for documents of a given type. Empty
+// fields fall back to those in the LinksSpec.
+type DocumentTypeLinkSpec struct {
+	Label           string `json:"label"`           // Specification of the label
+	DateAttribute   string `json:"dateAttribute"`   // Attribute holding the document date
+	DateFormat      string `json:"dateFormat"`      // Format of the document date
+	SourceAttribute string `json:"sourceAttribute"` // Attribute holding the document's source file
+
+	// Optional other formats of the document date and separators of a date range
+	DateFormats         []string `json:"dateFormats"`
+	DateRangeSeparators []string `json:"dateRangeSeparators"`
+}
+
+// dateParser for the document dates, which shows the dates in the date format (or the first of the
+// other formats if there isn't a date format).
+func (s DocumentTypeLinkSpec) dateParser() dateParser {
+	return newDateParser(append([]string{s.DateFormat}, s.DateFormats...), s.DateRangeSeparators)
+}
+
+// forDocumentType returns the link specification for documents of the given type and whether
+// the document type has its own specification.
+func (l LinksSpec) forDocumentType(documentType string) (DocumentTypeLinkSpec, bool) {
+
+	spec := DocumentTypeLinkSpec{
+		Label:               l.Label,
+		DateAttribute:       l.DateAttribute,
+		DateFormat:          l.DateFormat,
+		SourceAttribute:     l.SourceAttribute,
+		DateFormats:         l.DateFormats,
+		DateRangeSeparators: l.DateRangeSeparators,
+	}
+
+	typeSpec, found := l.DocumentTypes[documentType]
+	if !found {
+		return spec, false
+	}
+
+	if len(typeSpec.Label) > 0 {
+		spec.Label = typeSpec.Label
+	}
+
+	if len(typeSpec.DateAttribute) > 0 {
+		spec.DateAttribute = typeSpec.DateAttribute
+	}
+
+	// The date formats of the document type replace all of the formats
+	if len(typeSpec.DateFormat) > 0 || len(typeSpec.DateFormats) > 0 {
+		spec.DateFormat = typeSpec.DateFormat
+		spec.DateFormats = typeSpec.DateFormats
+	}
+
+	if len(typeSpec.DateRangeSeparators) > 0 {
+		spec.DateRangeSeparators = typeSpec.DateRangeSeparators
+	}
+
+	if len(typeSpec.SourceAttribute) > 0 {
+		spec.SourceAttribute = typeSpec.SourceAttribute
+	}
+
+	return spec, true
+}
+
+// An EntitySetStyle replaces the specification of some of the fields of the entities in an entity
+// set (i.e. a user's dataset), e.g. so that the entities in a watchlist have a different icon or
+// colour on the chart.
+type EntitySetStyle struct {
+	EntitySet   string            `json:"entitySet"`   // Name of the entity set
+	EntityTypes []string          `json:"entityTypes"` // Entity types to style (all types if empty)
+	Fields      map[string]string `json:"fields"`      // Specification of each field to replace
+}
+
+// appliesTo returns true if the style applies to an entity of the type in the entity sets.
+func (e EntitySetStyle) appliesTo(entityType string, entitySets *set.Set[string]) bool {
+
+	if entitySets == nil || !entitySets.Has(e.EntitySet) {
+		return false
+	}
+
+	if len(e.EntityTypes) == 0 {
+		return true
+	}
+
+	for _, styledType := range e.EntityTypes {
+		if styledType == entityType {
+			return true
+		}
+	}
+
+	return false
 }
 
 // An entity is the specification of the fields for a given entity type. By making this field
@@ -39,6 +150,38 @@ type I2ChartConfig struct {
 	Entities          map[string]map[string]string `json:"entities"`          // Specification for each entity type
 	Links             LinksSpec                    `json:"links"`             // Link specification
 	AttributeNotKnown string                       `json:"attributeNotKnown"` // Label to use for an unknown attribute
+	Anx               AnxSpec                      `json:"anx"`               // Optional mapping of columns for ANX output
+	EntitySetStyles   []EntitySetStyle             `json:"entitySetStyles"`   // Optional styles for entities in entity sets
+}
+
+// entitySpecInSets returns the specification of the entity types where the fields of the given
+// entity type are styled for the entity sets. If an entity is in more than one styled entity set,
+// the style that is first in the config takes precedence.
+func (c I2ChartConfig) entitySpecInSets(entityType string,
+	entitySets *set.Set[string]) map[string]map[string]string {
+
+	fieldSpecs, found := c.Entities[entityType]
+	if !found || len(c.EntitySetStyles) == 0 {
+		return c.Entities
+	}
+
+	styled := map[string]string{}
+	for column, spec := range fieldSpecs {
+		styled[column] = spec
+	}
+
+	for idx := len(c.EntitySetStyles) - 1; idx >= 0; idx-- {
+		style := c.EntitySetStyles[idx]
+		if !style.appliesTo(entityType, entitySets) {
+			continue
+		}
+
+		for column, spec := range style.Fields {
+			styled[column] = spec
+		}
+	}
+
+	return map[string]map[string]string{entityType: styled}
 }
 
 // readI2Config in a JSON file.
@@ -132,13 +275,58 @@ func validateI2Config(config I2ChartConfig) (bool, []string) {
 		return false, []string{"Attribute not known field is blank"}
 	}
 
+	// Do the entity set styles only replace existing columns?
+	styleIssues := []string{}
+	for idx, style := range config.EntitySetStyles {
+		if len(style.EntitySet) == 0 {
+			msg := fmt.Sprintf("Entity set style %v has no entity set", idx)
+			styleIssues = append(styleIssues, msg)
+		}
+
+		if len(style.Fields) == 0 {
+			msg := fmt.Sprintf("Entity set style for %v has no fields", style.EntitySet)
+			styleIssues = append(styleIssues, msg)
+		}
+
+		for column := range style.Fields {
+			if !expectedEntityColumns.Has(column) {
+				msg := fmt.Sprintf("Entity set style for %v has extra column %v", style.EntitySet,
+					column)
+				styleIssues = append(styleIssues, msg)
+			}
+		}
+
+		for _, entityType := range style.EntityTypes {
+			if _, found := config.Entities[entityType]; !found {
+				msg := fmt.Sprintf("Entity set style for %v has unknown entity type %v",
+					style.EntitySet, entityType)
+				styleIssues = append(styleIssues, msg)
+			}
+		}
+	}
+
+	if len(styleIssues) != 0 {
+		sort.Strings(styleIssues)
+		return false, styleIssues
+	}
+
+	// Are the columns of the ANX spec chart columns?
+	if anxIssues := anxSpecIssues(config.Anx, config.Columns); len(anxIssues) != 0 {
+		return false, anxIssues
+	}
+
 	return true, nil
 }
 
 // An I2ChartBuilder builds an i2 chart given a bipartite graph store and config.
 type I2ChartBuilder struct {
-	config    I2ChartConfig                  // Configuration for the output
-	bipartite graphstore.BipartiteGraphStore // Bipartite store
+	config             I2ChartConfig                   // Configuration for the output
+	bipartite          graphstore.BipartiteGraphStore  // Bipartite store
+	unipartite         graphstore.UnipartiteGraphStore // Optional unipartite store with edge metadata
+	deploymentKeywords map[string]string               // Keywords defined for the deployment
+	maxRows            int                             // Maximum number of rows (0 for no limit)
+	redactor           *redaction.Redactor             // Redacts attributes in the chart (optional)
+	annotations        *annotation.AnnotationStore     // Tags and notes of the entities (optional)
 }
 
 func NewI2ChartBuilder(filepath string) (*I2ChartBuilder, error) {
@@ -157,6 +345,15 @@ func NewI2ChartBuilder(filepath string) (*I2ChartBuilder, error) {
 			strings.Join(reasons, "; "))
 	}
 
+	// The default identity column of an ANX chart may not be a chart column, in which case ANX
+	// charts aren't built
+	if !anxEnabled(config.Anx, config.Columns) {
+		logging.Logger.Warn().
+			Str(logging.ComponentField, componentName).
+			Str("identityColumn", config.Anx.withDefaults().IdentityColumn).
+			Msg("ANX charts are disabled as the identity column isn't a chart column")
+	}
+
 	return &I2ChartBuilder{
 		config: *config,
 	}, nil
@@ -170,6 +367,38 @@ func (i *I2ChartBuilder) SetBipartite(bipartite graphstore.BipartiteGraphStore)
 	i.bipartite = bipartite
 }
 
+// SetUnipartite graph store used by the i2 chart builder to make link labels from the metadata of
+// the edges, rather than looking up each document in the bipartite store.
+func (i *I2ChartBuilder) SetUnipartite(unipartite graphstore.UnipartiteGraphStore) {
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Msg("Setting unipartite graph store in the i2 chart builder")
+	i.unipartite = unipartite
+}
+
+// SetMaxRows sets the maximum number of rows (excluding the header) in a chart, as i2 can fail to
+// import a very large chart. A limit of zero means there is no limit.
+func (i *I2ChartBuilder) SetMaxRows(maxRows int) error {
+
+	// Precondition
+	if maxRows < 0 {
+		return ErrInvalidMaxRows
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Int("maxRows", maxRows).
+		Msg("Setting the maximum number of rows in the i2 chart")
+
+	i.maxRows = maxRows
+	return nil
+}
+
+// MaxRows in a chart (0 for no limit).
+func (i *I2ChartBuilder) MaxRows() int {
+	return i.maxRows
+}
+
 // header of the i2 chart.
 func header(entityColumns []string) []string {
 
@@ -228,20 +457,69 @@ func documentsLinkingEntities(entity1 *graphstore.Entity, entity2 *graphstore.En
 	return docs, nil
 }
 
-// substituteForLink creates the link text.
-func substituteForLink(docs []*graphstore.Document, spec LinksSpec,
-	missingAttribute string) (string, error) {
+// substituteForDocs creates the link text for the documents using the specification.
+func substituteForDocs(docs []*graphstore.Document, spec DocumentTypeLinkSpec,
+	missingAttribute string, deploymentKeywords map[string]string) (string, error) {
 
-	// Keywords for the documents
-	keywordToValue := keywordsForDocs(docs, spec.DateAttribute, spec.DateFormat)
+	// Keywords for the documents, where the summary keywords take precedence over the attributes,
+	// which take precedence over the deployment keywords
+	keywordToValue := mergeKeywords(mergeKeywords(deploymentKeywords, documentAttributes(docs, ", ")),
+		keywordsForDocs(docs, spec.DateAttribute, spec.dateParser(), spec.SourceAttribute))
 
 	return Substitute(spec.Label, keywordToValue, missingAttribute)
 }
 
+// substituteForLink creates the link text. Documents of a type with its own specification are
+// summarised separately from the other documents.
+func substituteForLink(docs []*graphstore.Document, spec LinksSpec,
+	missingAttribute string, deploymentKeywords map[string]string) (string, error) {
+
+	defaultSpec, _ := spec.forDocumentType("")
+	if len(spec.DocumentTypes) == 0 || len(docs) == 0 {
+		return substituteForDocs(docs, defaultSpec, missingAttribute, deploymentKeywords)
+	}
+
+	// Group the documents by the specification to use
+	defaultDocs := []*graphstore.Document{}
+	docsByType := map[string][]*graphstore.Document{}
+	for _, doc := range docs {
+		if _, found := spec.DocumentTypes[doc.DocumentType]; found {
+			docsByType[doc.DocumentType] = append(docsByType[doc.DocumentType], doc)
+		} else {
+			defaultDocs = append(defaultDocs, doc)
+		}
+	}
+
+	parts := []string{}
+	if len(defaultDocs) > 0 {
+		part, err := substituteForDocs(defaultDocs, defaultSpec, missingAttribute,
+			deploymentKeywords)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, part)
+	}
+
+	documentTypes := maps.Keys(docsByType)
+	sort.Strings(documentTypes)
+
+	for _, documentType := range documentTypes {
+		typeSpec, _ := spec.forDocumentType(documentType)
+		part, err := substituteForDocs(docsByType[documentType], typeSpec, missingAttribute,
+			deploymentKeywords)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, part)
+	}
+
+	return strings.Join(parts, linkLabelSeparator), nil
+}
+
 // makeLinkLabel between two entities.
 func makeLinkLabel(entity1 *graphstore.Entity, entity2 *graphstore.Entity,
 	bipartite graphstore.BipartiteGraphStore, spec LinksSpec,
-	missingAttribute string) (string, error) {
+	missingAttribute string, deploymentKeywords map[string]string) (string, error) {
 
 	// Documents linking the two entities
 	docs, err := documentsLinkingEntities(entity1, entity2, bipartite)
@@ -250,7 +528,80 @@ func makeLinkLabel(entity1 *graphstore.Entity, entity2 *graphstore.Entity,
 	}
 
 	// Build the link label
-	return substituteForLink(docs, spec, missingAttribute)
+	return substituteForLink(docs, spec, missingAttribute, deploymentKeywords)
+}
+
+// canUseEdgeMetadata returns true if the link label can be made from the metadata of an edge, i.e.
+// there are no document type specific labels and the label only uses the keywords that can be
+// found from the metadata or the deployment keywords.
+func canUseEdgeMetadata(spec LinksSpec, deploymentKeywords map[string]string) bool {
+
+	if len(spec.DocumentTypes) > 0 {
+		return false
+	}
+
+	keywords, err := findKeywords(spec.Label)
+	if err != nil {
+		return false
+	}
+
+	for _, keyword := range keywords {
+		keyword = strings.TrimSuffix(strings.TrimPrefix(keyword, "<"), ">")
+		if keyword == numDocsKeyword || keyword == latestDateKeyword {
+			continue
+		}
+
+		if _, found := deploymentKeywords[keyword]; !found {
+			return false
+		}
+	}
+
+	return true
+}
+
+// makeLinkLabelFromMetadata of the edge between two entities.
+func makeLinkLabelFromMetadata(metadata *graphstore.EdgeMetadata, spec LinksSpec,
+	missingAttribute string, deploymentKeywords map[string]string) (string, error) {
+
+	latestDate := ""
+	if !metadata.LatestDate.IsZero() {
+		parser := newDateParser(append([]string{spec.DateFormat}, spec.DateFormats...), nil)
+		latestDate = parser.format(metadata.LatestDate)
+	}
+
+	keywordToValue := mergeKeywords(deploymentKeywords, map[string]string{
+		numDocsKeyword:    fmt.Sprintf("%d", metadata.NumberOfDocuments),
+		latestDateKeyword: latestDate,
+	})
+
+	return Substitute(spec.Label, keywordToValue, missingAttribute)
+}
+
+// linkLabel between two entities, which is made from the metadata of the edge if possible. The
+// keywords of the row (e.g. the entity set names of each entity) take precedence over the
+// deployment keywords.
+func (i *I2ChartBuilder) linkLabel(entity1 *graphstore.Entity, entity2 *graphstore.Entity,
+	rowKeywords map[string]string) (string, error) {
+
+	keywords := mergeKeywords(i.deploymentKeywords, rowKeywords)
+
+	// The latest date in the metadata is taken from the documents, so it mustn't be used if the
+	// date attribute is redacted
+	if i.unipartite != nil && canUseEdgeMetadata(i.config.Links, keywords) &&
+		!i.redactor.IsRedacted(i.config.Links.DateAttribute) {
+		metadata, err := i.unipartite.EdgeMetadata(entity1.Id, entity2.Id)
+		if err != nil {
+			return "", err
+		}
+
+		if metadata != nil {
+			return makeLinkLabelFromMetadata(metadata, i.config.Links, i.config.AttributeNotKnown,
+				keywords)
+		}
+	}
+
+	return makeLinkLabel(entity1, entity2, i.store(), i.config.Links,
+		i.config.AttributeNotKnown, keywords)
 }
 
 // mergeKeywords creates a map of keywords from m1 and m2.
@@ -316,10 +667,12 @@ func makeI2Entity(entity *graphstore.Entity, columns []string,
 	return fields, nil
 }
 
-// rowLinkingEntities given the specification for a row and the data.
+// rowLinkingEntities given the specification for a row and the data. The entity sets of each
+// entity (which may be nil) are used to style the entities.
 func (i *I2ChartBuilder) rowLinkingEntities(entityId1 string, entityId2 string,
 	keywordToValueEntity1 map[string]string,
-	keywordToValueEntity2 map[string]string) ([]string, error) {
+	keywordToValueEntity2 map[string]string,
+	entitySets1 *set.Set[string], entitySets2 *set.Set[string]) ([]string, error) {
 
 	// Preconditions
 	if i.bipartite == nil {
@@ -327,7 +680,8 @@ func (i *I2ChartBuilder) rowLinkingEntities(entityId1 string, entityId2 string,
 	}
 
 	// Get the entities from the store
-	entity1, err := i.bipartite.GetEntity(entityId1)
+	bipartite := i.store()
+	entity1, err := bipartite.GetEntity(entityId1)
 	if err != nil {
 		return nil, err
 	}
@@ -335,7 +689,7 @@ func (i *I2ChartBuilder) rowLinkingEntities(entityId1 string, entityId2 string,
 		return nil, fmt.Errorf("entity with ID %v not found in bipartite store", entityId1)
 	}
 
-	entity2, err := i.bipartite.GetEntity(entityId2)
+	entity2, err := bipartite.GetEntity(entityId2)
 	if err != nil {
 		return nil, err
 	}
@@ -347,8 +701,14 @@ func (i *I2ChartBuilder) rowLinkingEntities(entityId1 string, entityId2 string,
 	row := make([]string, len(i.config.Columns)*2+1)
 
 	// Add the fields for entity 1
+	keywords1, err := i.entityKeywords(entityId1, keywordToValueEntity1)
+	if err != nil {
+		return nil, err
+	}
+
 	entity1Fields, err := makeI2Entity(entity1, i.config.Columns,
-		i.config.Entities, i.config.AttributeNotKnown, keywordToValueEntity1)
+		i.config.entitySpecInSets(entity1.EntityType, entitySets1), i.config.AttributeNotKnown,
+		keywords1)
 
 	if err != nil {
 		return nil, err
@@ -359,8 +719,14 @@ func (i *I2ChartBuilder) rowLinkingEntities(entityId1 string, entityId2 string,
 	}
 
 	// Add the fields for entity 2
+	keywords2, err := i.entityKeywords(entityId2, keywordToValueEntity2)
+	if err != nil {
+		return nil, err
+	}
+
 	entity2Fields, err := makeI2Entity(entity2, i.config.Columns,
-		i.config.Entities, i.config.AttributeNotKnown, keywordToValueEntity2)
+		i.config.entitySpecInSets(entity2.EntityType, entitySets2), i.config.AttributeNotKnown,
+		keywords2)
 
 	if err != nil {
 		return nil, err
@@ -370,9 +736,11 @@ func (i *I2ChartBuilder) rowLinkingEntities(entityId1 string, entityId2 string,
 		row[idx+len(i.config.Columns)] = entity2Fields[idx]
 	}
 
-	// Add the link
-	linkLabel, err := makeLinkLabel(entity1, entity2, i.bipartite, i.config.Links,
-		i.config.AttributeNotKnown)
+	// Add the link, which can show the entity sets of both entities
+	linkLabel, err := i.linkLabel(entity1, entity2, map[string]string{
+		entitySetNames1Keyword: keywordToValueEntity1[entitySetNamesKeyword],
+		entitySetNames2Keyword: keywordToValueEntity2[entitySetNamesKeyword],
+	})
 
 	if err != nil {
 		return nil, err
@@ -406,16 +774,27 @@ func buildDatasetKeywords(entityId string, conns *bfs.NetworkConnections) (map[s
 }
 
 // Build the rows of the i2 chart from the network connections. The entity details are held
-// within the bipartite graph store.
+// within the bipartite graph store. If there is a maximum number of rows, the rows beyond the
+// limit are dropped.
 func (i *I2ChartBuilder) Build(conns *bfs.NetworkConnections) ([][]string, error) {
+	rows, _, err := i.BuildWithLogger(conns, logging.Logger.Level(zerolog.InfoLevel))
+	return rows, err
+}
+
+// BuildWithLogger builds the rows of the i2 chart from the network connections, logging the
+// decision made for each pair of entities at debug level to the logger. The number of rows dropped
+// because of the maximum number of rows is also returned. As the connections are walked in sorted
+// order, the same rows are always kept.
+func (i *I2ChartBuilder) BuildWithLogger(conns *bfs.NetworkConnections,
+	logger zerolog.Logger) ([][]string, int, error) {
 
 	// Preconditions
 	if i.bipartite == nil {
-		return nil, errors.New("bipartite graph store is not defined")
+		return nil, 0, errors.New("bipartite graph store is not defined")
 	}
 
 	if conns == nil {
-		return nil, errors.New("nil connections passed to Build")
+		return nil, 0, errors.New("nil connections passed to Build")
 	}
 
 	logging.Logger.Info().
@@ -428,6 +807,7 @@ func (i *I2ChartBuilder) Build(conns *bfs.NetworkConnections) ([][]string, error
 	i2Graph := graphstore.NewInMemoryUnipartiteGraphStore()
 
 	rows := [][]string{}
+	droppedRows := 0
 
 	// Add the header row
 	rows = append(rows, header(i.config.Columns))
@@ -445,8 +825,11 @@ func (i *I2ChartBuilder) Build(conns *bfs.NetworkConnections) ([][]string, error
 
 		for _, destinationVertex := range destinationVertices {
 
-			// Sort the paths
-			paths := conns.Connections[sourceVertex][destinationVertex]
+			// Get the paths (which may be streamed from disk) and sort them
+			paths, err := conns.Paths(sourceVertex, destinationVertex)
+			if err != nil {
+				return nil, 0, err
+			}
 
 			sort.Slice(paths, func(i, j int) bool {
 				pi := paths[i].Start() + "->" + paths[i].End()
@@ -458,9 +841,9 @@ func (i *I2ChartBuilder) Build(conns *bfs.NetworkConnections) ([][]string, error
 
 				// Check the path is valid
 				if len(path.Route) == 0 {
-					return nil, errors.New("path with no entities encountered")
+					return nil, 0, errors.New("path with no entities encountered")
 				} else if len(path.Route) == 1 {
-					return nil, errors.New("path has just one entity")
+					return nil, 0, errors.New("path has just one entity")
 				}
 
 				// Walk through each pair of entities on the path
@@ -472,30 +855,50 @@ func (i *I2ChartBuilder) Build(conns *bfs.NetworkConnections) ([][]string, error
 					// need to be added to the i2 chart
 					exists, err := i2Graph.EdgeExists(src, dst)
 					if err != nil {
-						return nil, err
+						return nil, 0, err
 					}
 					if exists {
+						logger.Debug().
+							Str(logging.ComponentField, componentName).
+							Str("entityId1", src).
+							Str("entityId2", dst).
+							Msg("Skipping row as the entities are already linked on the chart")
+						continue
+					}
+
+					// Once the chart is full, the rows are only counted
+					if i.maxRows > 0 && len(rows)-1 >= i.maxRows {
+						droppedRows += 1
+						i2Graph.AddUndirected(src, dst)
 						continue
 					}
 
 					// Build the keywords
 					keywordToValueEntity1, err := buildDatasetKeywords(src, conns)
 					if err != nil {
-						return nil, err
+						return nil, 0, err
 					}
 					keywordToValueEntity2, err := buildDatasetKeywords(dst, conns)
 					if err != nil {
-						return nil, err
+						return nil, 0, err
 					}
 
 					// Create the row
 					row, err := i.rowLinkingEntities(src, dst, keywordToValueEntity1,
-						keywordToValueEntity2)
+						keywordToValueEntity2, conns.EntityIdToSetNames[src],
+						conns.EntityIdToSetNames[dst])
 					if err != nil {
-						return nil, err
+						return nil, 0, err
 					}
 					rows = append(rows, row)
 
+					logger.Debug().
+						Str(logging.ComponentField, componentName).
+						Str("entityId1", src).
+						Str("entityId2", dst).
+						Str("link", row[len(row)-1]).
+						Msg("Added row to chart")
+
 					// Record that the row contains linked entities (so it doesn't get duplicated
 					// later)
 					i2Graph.AddUndirected(src, dst)
@@ -505,5 +908,13 @@ func (i *I2ChartBuilder) Build(conns *bfs.NetworkConnections) ([][]string, error
 		}
 	}
 
-	return rows, nil
+	if droppedRows > 0 {
+		logging.Logger.Warn().
+			Str(logging.ComponentField, componentName).
+			Int("maxRows", i.maxRows).
+			Int("droppedRows", droppedRows).
+			Msg("Rows dropped from the i2 chart as it has too many rows")
+	}
+
+	return rows, droppedRows, nil
 }
@@ -2,10 +2,12 @@ package i2chart
 
 import (
 	"testing"
+	"time"
 
 	"github.com/cdclaxton/shortest-path-web-app/bfs"
 	"github.com/cdclaxton/shortest-path-web-app/graphbuilder"
 	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
 	"github.com/cdclaxton/shortest-path-web-app/set"
 	"github.com/stretchr/testify/assert"
 )
@@ -49,6 +51,16 @@ func TestValidateI2Config(t *testing.T) {
 			isValid:       false,
 			numberReasons: 1,
 		},
+		{
+			filepath:      "./test-data/i2-invalid-config-5.json",
+			isValid:       false,
+			numberReasons: 2,
+		},
+		{
+			filepath:      "./test-data/i2-config-styles.json",
+			isValid:       true,
+			numberReasons: 0,
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -212,10 +224,96 @@ func TestSubstituteForLink(t *testing.T) {
 			missingAttribute: "MISSING",
 			expectedLabel:    "2; Type-A, Type-B; 05/09/2022 - 06/09/2022; MISSING",
 		},
+		{
+			// Document type specific labels using the document attributes
+			docs: []*graphstore.Document{
+				{
+					DocumentType: "Call",
+					Attributes:   map[string]string{"date": "06/09/2022", "duration": "5"},
+				},
+				{
+					DocumentType: "Payment",
+					Attributes:   map[string]string{"when": "2022-09-04", "amount": "100"},
+				},
+				{
+					DocumentType: "Payment",
+					Attributes:   map[string]string{"when": "2022-09-05", "amount": "20"},
+				},
+				{
+					DocumentType: "Type-B",
+					Attributes:   map[string]string{"date": "05/09/2022"},
+				},
+			},
+			spec: LinksSpec{
+				Label:         "<NUM-DOCS> docs <DOCUMENT-DATE-RANGE>",
+				DateAttribute: "date",
+				DateFormat:    "02/01/2006",
+				DocumentTypes: map[string]DocumentTypeLinkSpec{
+					"Call": {
+						Label: "Call <DOCUMENT-DATE-RANGE> (<duration> mins)",
+					},
+					"Payment": {
+						Label:         "<NUM-DOCS> payments of <amount> (<DOCUMENT-DATE-RANGE>)",
+						DateAttribute: "when",
+						DateFormat:    "2006-01-02",
+					},
+				},
+			},
+			missingAttribute: "MISSING",
+			expectedLabel: "1 docs 05/09/2022; Call 06/09/2022 (5 mins); " +
+				"2 payments of 100, 20 (2022-09-04 - 2022-09-05)",
+		},
+		{
+			// Document type specifications that aren't used
+			docs: []*graphstore.Document{
+				{
+					DocumentType: "Type-A",
+					Attributes:   map[string]string{"date": "06/09/2022"},
+				},
+			},
+			spec: LinksSpec{
+				Label:         "<NUM-DOCS>; <DOCUMENT-TYPES>; <DOCUMENT-DATE-RANGE>",
+				DateAttribute: "date",
+				DateFormat:    "02/01/2006",
+				DocumentTypes: map[string]DocumentTypeLinkSpec{
+					"Call": {
+						Label: "Call",
+					},
+				},
+			},
+			missingAttribute: "MISSING",
+			expectedLabel:    "1; Type-A; 06/09/2022",
+		},
+		{
+			// Dates in a mixture of formats, including a range
+			docs: []*graphstore.Document{
+				{
+					DocumentType: "Type-A",
+					Attributes:   map[string]string{"date": " 06/09/2022 "},
+				},
+				{
+					DocumentType: "Type-A",
+					Attributes:   map[string]string{"date": "2021-03"},
+				},
+				{
+					DocumentType: "Type-A",
+					Attributes:   map[string]string{"date": "2022-08/2022-10"},
+				},
+			},
+			spec: LinksSpec{
+				Label:         "<NUM-DOCS> docs <DOCUMENT-DATE-RANGE> (latest <LATEST-DOCUMENT-DATE>)",
+				DateAttribute: "date",
+				DateFormat:    "02/01/2006",
+				DateFormats:   []string{"2006-01-02", "2006-01"},
+			},
+			missingAttribute: "MISSING",
+			expectedLabel:    "3 docs 01/03/2021 - 01/10/2022 (latest 01/10/2022)",
+		},
 	}
 
 	for _, testCase := range testCases {
-		actual, err := substituteForLink(testCase.docs, testCase.spec, testCase.missingAttribute)
+		actual, err := substituteForLink(testCase.docs, testCase.spec, testCase.missingAttribute,
+			nil)
 		assert.NoError(t, err)
 		assert.Equal(t, testCase.expectedLabel, actual)
 	}
@@ -267,7 +365,7 @@ func TestMakeLinkLabel(t *testing.T) {
 		assert.NotNil(t, entity2)
 
 		// Make the link label
-		actual, err := makeLinkLabel(entity1, entity2, bipartite, spec, missingAttribute)
+		actual, err := makeLinkLabel(entity1, entity2, bipartite, spec, missingAttribute, nil)
 		assert.NoError(t, err)
 
 		// Check the label
@@ -276,6 +374,100 @@ func TestMakeLinkLabel(t *testing.T) {
 
 }
 
+func TestCanUseEdgeMetadata(t *testing.T) {
+
+	testCases := []struct {
+		spec     LinksSpec
+		expected bool
+	}{
+		{spec: LinksSpec{Label: "<NUM-DOCS> docs"}, expected: true},
+		{spec: LinksSpec{Label: "<NUM-DOCS> docs (<LATEST-DOCUMENT-DATE>) <BASE-URL>"}, expected: true},
+		{spec: LinksSpec{Label: "Linked"}, expected: true},
+		{spec: LinksSpec{Label: "<NUM-DOCS> docs (<DOCUMENT-DATE-RANGE>)"}, expected: false},
+		{spec: LinksSpec{Label: "<NUM-DOCS> docs, <Amount>"}, expected: false},
+		{
+			spec: LinksSpec{
+				Label:         "<NUM-DOCS> docs",
+				DocumentTypes: map[string]DocumentTypeLinkSpec{"Call": {Label: "<NUM-DOCS> calls"}},
+			},
+			expected: false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		actual := canUseEdgeMetadata(testCase.spec, map[string]string{"BASE-URL": "http://a"})
+		assert.Equal(t, testCase.expected, actual)
+	}
+}
+
+func TestLinkLabelFromEdgeMetadata(t *testing.T) {
+
+	bipartite := makeBipartiteStore(t)
+
+	spec := LinksSpec{
+		Label:         "<NUM-DOCS> docs, latest <LATEST-DOCUMENT-DATE>",
+		DateAttribute: "Date",
+		DateFormat:    "02/01/2006",
+	}
+
+	// Convert the bipartite graph, recording the document dates in the edge metadata
+	unipartite := graphstore.NewInMemoryUnipartiteGraphStore()
+	assert.NoError(t, graphstore.BipartiteToUnipartiteWithCheckpoints(bipartite, unipartite,
+		set.NewSet[string](), 1, 1, graphstore.ConversionOptions{
+			DateAttribute: spec.DateAttribute,
+			DateFormat:    spec.DateFormat,
+		}, nil))
+
+	builder := I2ChartBuilder{
+		config:     I2ChartConfig{Links: spec, AttributeNotKnown: "MISSING"},
+		bipartite:  bipartite,
+		unipartite: unipartite,
+	}
+
+	testCases := []struct {
+		entityId1     string
+		entityId2     string
+		expectedLabel string
+	}{
+		{entityId1: "e-1", entityId2: "e-2", expectedLabel: "2 docs, latest 07/08/2022"},
+		{entityId1: "e-1", entityId2: "e-3", expectedLabel: "1 docs, latest 09/08/2022"},
+		{entityId1: "e-3", entityId2: "e-4", expectedLabel: "1 docs, latest 10/08/2022"},
+	}
+
+	for _, testCase := range testCases {
+		entity1, err := bipartite.GetEntity(testCase.entityId1)
+		assert.NoError(t, err)
+
+		entity2, err := bipartite.GetEntity(testCase.entityId2)
+		assert.NoError(t, err)
+
+		// The label from the metadata is the same as the label from the documents
+		actual, err := builder.linkLabel(entity1, entity2, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.expectedLabel, actual)
+
+		expected, err := makeLinkLabel(entity1, entity2, bipartite, spec, "MISSING", nil)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	}
+
+	// The metadata is used in preference to the documents
+	builder.unipartite = graphstore.NewInMemoryUnipartiteGraphStore()
+	assert.NoError(t, builder.unipartite.AddEdgeDocument("e-1", "e-2", time.Time{}))
+
+	entity1, _ := bipartite.GetEntity("e-1")
+	entity2, _ := bipartite.GetEntity("e-2")
+	actual, err := builder.linkLabel(entity1, entity2, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "1 docs, latest ", actual)
+
+	// The documents are used if there isn't any metadata
+	entity3, _ := bipartite.GetEntity("e-3")
+	actual, err = builder.linkLabel(entity1, entity3, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "1 docs, latest 09/08/2022", actual)
+}
+
 func TestMakeI2Entity(t *testing.T) {
 
 	entity := graphstore.Entity{
@@ -436,7 +628,7 @@ func TestRowLinkingEntities(t *testing.T) {
 
 	for _, testCase := range testCases {
 		row, err := chartBuilder.rowLinkingEntities(testCase.entityId1,
-			testCase.entityId2, keywordToValue1, keywordToValue2)
+			testCase.entityId2, keywordToValue1, keywordToValue2, nil, nil)
 
 		if testCase.expectedError {
 			assert.Error(t, err)
@@ -448,6 +640,121 @@ func TestRowLinkingEntities(t *testing.T) {
 	}
 }
 
+func TestRowLinkingEntitiesWithProvenance(t *testing.T) {
+
+	config, err := graphbuilder.ReadGraphConfigFromJson("../test-data-sets/set-1/data-config.json")
+	assert.NoError(t, err)
+	config.DocumentSourceAttribute = "Source file"
+
+	graphBuilder, _, err := graphbuilder.NewGraphBuilder(*config)
+	assert.NoError(t, err)
+
+	chartBuilder, err := NewI2ChartBuilder("../test-data-sets/set-1/i2-config.json")
+	assert.NoError(t, err)
+	chartBuilder.SetBipartite(graphBuilder.Bipartite)
+
+	chartBuilder.config.Links.SourceAttribute = "Source file"
+	chartBuilder.config.Links.Label = "<ENTITY-SET-NAMES-1> to <ENTITY-SET-NAMES-2> via <SOURCE-FILES>"
+
+	row, err := chartBuilder.rowLinkingEntities("e-1", "e-2",
+		map[string]string{entitySetNamesKeyword: "Dataset 1"},
+		map[string]string{entitySetNamesKeyword: "Dataset 1, Dataset 2"}, nil, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "Dataset 1 to Dataset 1, Dataset 2 via documents-A.csv, documents-B.csv",
+		row[len(row)-1])
+}
+
+func TestEntitySpecInSets(t *testing.T) {
+
+	config, err := readI2Config("./test-data/i2-config-styles.json")
+	assert.NoError(t, err)
+
+	testCases := []struct {
+		description string
+		entityType  string
+		entitySets  *set.Set[string]
+		expectedTo  map[string]string
+	}{
+		{
+			description: "not in an entity set",
+			entityType:  "Person",
+			entitySets:  nil,
+			expectedTo:  map[string]string{"icon": "Person"},
+		},
+		{
+			description: "in an entity set without a style",
+			entityType:  "Person",
+			entitySets:  set.NewPopulatedSet("Set-A"),
+			expectedTo:  map[string]string{"icon": "Person"},
+		},
+		{
+			description: "style restricted to the entity type",
+			entityType:  "Person",
+			entitySets:  set.NewPopulatedSet("Watchlist A"),
+			expectedTo:  map[string]string{"icon": "Warning"},
+		},
+		{
+			description: "style restricted to a different entity type",
+			entityType:  "Address",
+			entitySets:  set.NewPopulatedSet("Watchlist A"),
+			expectedTo:  map[string]string{"icon": "Location"},
+		},
+		{
+			description: "style for all entity types",
+			entityType:  "Address",
+			entitySets:  set.NewPopulatedSet("Watchlist B"),
+			expectedTo: map[string]string{
+				"icon":        "Flag",
+				"description": "On watchlist B: <ID>",
+			},
+		},
+		{
+			description: "first style takes precedence",
+			entityType:  "Person",
+			entitySets:  set.NewPopulatedSet("Watchlist A", "Watchlist B"),
+			expectedTo: map[string]string{
+				"icon":        "Warning",
+				"description": "On watchlist B: <ID>",
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.description, func(t *testing.T) {
+			spec := config.entitySpecInSets(testCase.entityType, testCase.entitySets)
+			for column, expected := range testCase.expectedTo {
+				assert.Equal(t, expected, spec[testCase.entityType][column])
+			}
+			assert.Equal(t, config.Entities[testCase.entityType]["id"],
+				spec[testCase.entityType]["id"])
+		})
+	}
+
+	// The config isn't modified
+	assert.Equal(t, "Person", config.Entities["Person"]["icon"])
+}
+
+func TestRowLinkingEntitiesWithStyles(t *testing.T) {
+
+	graphBuilder, _, err := graphbuilder.NewGraphBuilderFromJson("../test-data-sets/set-1/data-config.json")
+	assert.NoError(t, err)
+
+	chartBuilder, err := NewI2ChartBuilder("./test-data/i2-config-styles.json")
+	assert.NoError(t, err)
+	chartBuilder.SetBipartite(graphBuilder.Bipartite)
+
+	row, err := chartBuilder.rowLinkingEntities("e-1", "e-3",
+		map[string]string{entitySetNamesKeyword: "Watchlist A"},
+		map[string]string{entitySetNamesKeyword: "Watchlist B"},
+		set.NewPopulatedSet("Watchlist A"), set.NewPopulatedSet("Watchlist B"))
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{
+		"Warning", "e-1", "Smith, Bob [Watchlist A]", "Watchlist A", "Bob Smith can be found at http://network-display/e-1",
+		"Flag", "e-3", "31 Field Drive, EH36 5PB [Watchlist B]", "Watchlist B", "On watchlist B: e-3",
+		"1 docs (Doc-A; 09/08/2022)"}, row)
+}
+
 func TestBuildDatasetKeywords(t *testing.T) {
 
 	conns := bfs.NetworkConnections{
@@ -623,3 +930,49 @@ func TestBuild(t *testing.T) {
 
 	}
 }
+
+func TestBuildWithMaxRows(t *testing.T) {
+
+	graphBuilder, _, err := graphbuilder.NewGraphBuilderFromJson("../test-data-sets/set-1/data-config.json")
+	assert.NoError(t, err)
+
+	chartBuilder, err := NewI2ChartBuilder("../test-data-sets/set-1/i2-config.json")
+	assert.NoError(t, err)
+	chartBuilder.SetBipartite(graphBuilder.Bipartite)
+
+	assert.ErrorIs(t, chartBuilder.SetMaxRows(-1), ErrInvalidMaxRows)
+
+	conns := &bfs.NetworkConnections{
+		EntityIdToSetNames: map[string]*set.Set[string]{
+			"e-1": set.NewPopulatedSet("Dataset-A"),
+			"e-4": set.NewPopulatedSet("Dataset-A"),
+		},
+		Connections: map[string]map[string][]bfs.Path{
+			"e-1": {"e-4": {{
+				Route: []string{"e-1", "e-3", "e-4"},
+			}}},
+			"e-4": {"e-1": {{
+				Route: []string{"e-4", "e-3", "e-1"},
+			}}},
+		},
+	}
+
+	// Without a limit
+	rows, droppedRows, err := chartBuilder.BuildWithLogger(conns, logging.Logger)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(rows))
+	assert.Equal(t, 0, droppedRows)
+
+	// With a limit, the first rows are kept and the duplicate link isn't counted as dropped
+	assert.NoError(t, chartBuilder.SetMaxRows(1))
+	truncated, droppedRows, err := chartBuilder.BuildWithLogger(conns, logging.Logger)
+	assert.NoError(t, err)
+	assert.Equal(t, rows[:2], truncated)
+	assert.Equal(t, 1, droppedRows)
+
+	// A limit that isn't reached
+	assert.NoError(t, chartBuilder.SetMaxRows(2))
+	actual, err := chartBuilder.Build(conns)
+	assert.NoError(t, err)
+	assert.Equal(t, rows, actual)
+}
@@ -0,0 +1,71 @@
+// Deployment keywords are defined when the server starts rather than in the i2 chart config, e.g.
+// the base URL of a system that displays an entity. This allows the same i2 chart config to be
+// used across different environments.
+
+package i2chart
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+)
+
+var ErrInvalidDeploymentKeyword = errors.New("invalid deployment keyword")
+
+// validateDeploymentKeywords to ensure they can be used in a substitution.
+func validateDeploymentKeywords(keywords map[string]string) error {
+	for keyword := range keywords {
+		if len(strings.TrimSpace(keyword)) == 0 || strings.ContainsAny(keyword, "<>") {
+			return fmt.Errorf("%w: '%v'", ErrInvalidDeploymentKeyword, keyword)
+		}
+	}
+
+	return nil
+}
+
+// ReadDeploymentKeywords from a JSON file containing an object of keywords to values, e.g.
+// {"BASE-URL": "http://network-display"}.
+func ReadDeploymentKeywords(filepath string) (map[string]string, error) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("filepath", filepath).
+		Msg("Reading deployment keywords from JSON file")
+
+	content, err := os.ReadFile(filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	keywords := map[string]string{}
+	if err := json.Unmarshal(content, &keywords); err != nil {
+		return nil, err
+	}
+
+	if err := validateDeploymentKeywords(keywords); err != nil {
+		return nil, err
+	}
+
+	return keywords, nil
+}
+
+// SetDeploymentKeywords that can be used in the entity and link specifications. The keywords have
+// the lowest precedence, so an entity or document attribute with the same name is used instead.
+func (i *I2ChartBuilder) SetDeploymentKeywords(keywords map[string]string) error {
+
+	if err := validateDeploymentKeywords(keywords); err != nil {
+		return err
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Int("numberOfKeywords", len(keywords)).
+		Msg("Setting the deployment keywords in the i2 chart builder")
+
+	i.deploymentKeywords = mergeKeywords(keywords, nil)
+	return nil
+}
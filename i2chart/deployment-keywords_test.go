@@ -0,0 +1,58 @@
+package i2chart
+
+import (
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphbuilder"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReadDeploymentKeywords(t *testing.T) {
+	keywords, err := ReadDeploymentKeywords("./test-data/deployment-keywords.json")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"BASE-URL": "https://network-display.example",
+		"CASE-REF": "OP-123",
+	}, keywords)
+
+	_, err = ReadDeploymentKeywords("./test-data/deployment-keywords-invalid.json")
+	assert.ErrorIs(t, err, ErrInvalidDeploymentKeyword)
+
+	_, err = ReadDeploymentKeywords("./test-data/missing.json")
+	assert.Error(t, err)
+}
+
+func TestRowLinkingEntitiesWithDeploymentKeywords(t *testing.T) {
+
+	graphBuilder, _, err := graphbuilder.NewGraphBuilderFromJson("../test-data-sets/set-1/data-config.json")
+	assert.NoError(t, err)
+
+	chartBuilder, err := NewI2ChartBuilder("../test-data-sets/set-1/i2-config.json")
+	assert.NoError(t, err)
+	chartBuilder.SetBipartite(graphBuilder.Bipartite)
+
+	assert.ErrorIs(t, chartBuilder.SetDeploymentKeywords(map[string]string{"": "x"}),
+		ErrInvalidDeploymentKeyword)
+
+	keywords := map[string]string{
+		"BASE-URL": "https://network-display.example",
+		"CASE-REF": "OP-123",
+		"Forename": "Overridden",
+	}
+	assert.NoError(t, chartBuilder.SetDeploymentKeywords(keywords))
+
+	// Changing the map after it has been set has no effect
+	keywords["CASE-REF"] = "OP-999"
+
+	chartBuilder.config.Entities["Person"]["description"] = "<Forename> at <BASE-URL>/<ID>"
+	chartBuilder.config.Links.Label = "<NUM-DOCS> docs [<CASE-REF>]"
+
+	row, err := chartBuilder.rowLinkingEntities("e-1", "e-2", map[string]string{}, map[string]string{}, nil,
+		nil)
+	assert.NoError(t, err)
+
+	// Entity attributes take precedence over the deployment keywords
+	assert.Equal(t, "Bob at https://network-display.example/e-1", row[4])
+	assert.Equal(t, "Sally at https://network-display.example/e-2", row[9])
+	assert.Equal(t, "2 docs [OP-123]", row[10])
+}
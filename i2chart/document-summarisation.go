@@ -23,6 +23,8 @@ const (
 	numDocsKeyword      = "NUM-DOCS"
 	docTypesKeyword     = "DOCUMENT-TYPES"
 	docDateRangeKeyword = "DOCUMENT-DATE-RANGE"
+	latestDateKeyword   = "LATEST-DOCUMENT-DATE"
+	sourceFilesKeyword  = "SOURCE-FILES"
 )
 
 // Maximum document age for it to be retained
@@ -71,47 +73,51 @@ func parseDate(date string, format string) (time.Time, bool) {
 	return parsed, true
 }
 
-// dateRange in the form (min - max).
-func dateRange(dates []string, format string) string {
+// dateRange in the form (min - max), where a date can itself be a range.
+func dateRange(dates []string, parser dateParser) string {
 
 	// Parse each of the dates
-	parsedDates := []time.Time{}
+	starts := []time.Time{}
+	ends := []time.Time{}
 	for _, date := range dates {
-		parsed, use := parseDate(date, format)
+		start, end, use := parser.parse(date)
 
 		if use {
-			parsedDates = append(parsedDates, parsed)
+			starts = append(starts, start)
+			ends = append(ends, end)
 		}
 	}
 
-	if len(parsedDates) == 0 {
+	if len(starts) == 0 {
 		return ""
-	} else if len(parsedDates) == 1 {
-		return parsedDates[0].Format(format)
+	} else if len(starts) == 1 && starts[0].Equal(ends[0]) {
+		return parser.format(starts[0])
 	}
 
-	// Sort the dates
-	sort.Slice(parsedDates, func(i, j int) bool {
-		return parsedDates[i].Before(parsedDates[j])
-	})
-
-	// Earliest and latest dates
-	earliest := parsedDates[0].Format(format)
-	latest := parsedDates[len(parsedDates)-1].Format(format)
+	// Earliest start and latest end of the dates
+	earliest, latest := starts[0], ends[0]
+	for idx := range starts {
+		if starts[idx].Before(earliest) {
+			earliest = starts[idx]
+		}
+		if ends[idx].After(latest) {
+			latest = ends[idx]
+		}
+	}
 
 	// Return a string of the date range
-	return fmt.Sprintf("%v - %v", earliest, latest)
+	return fmt.Sprintf("%v - %v", parser.format(earliest), parser.format(latest))
 }
 
-// documentDates as a range if there is a date attribute and a date format.
+// documentDates as a range if there is a date attribute and an accepted date format.
 func documentDates(docs []*graphstore.Document, dateAttribute string,
-	dateFormat string) string {
+	parser dateParser) string {
 
 	if len(docs) == 0 {
 		return ""
 	}
 
-	if len(dateAttribute) == 0 || len(dateFormat) == 0 {
+	if len(dateAttribute) == 0 || !parser.canParse() {
 		return ""
 	}
 
@@ -127,16 +133,91 @@ func documentDates(docs []*graphstore.Document, dateAttribute string,
 	}
 
 	// Return the date range
-	return dateRange(dates, dateFormat)
+	return dateRange(dates, parser)
+}
+
+// latestDocumentDate of the documents (the end of a date range) if there is a date attribute and
+// an accepted date format.
+func latestDocumentDate(docs []*graphstore.Document, dateAttribute string,
+	parser dateParser) string {
+
+	if len(dateAttribute) == 0 || !parser.canParse() {
+		return ""
+	}
+
+	latest := time.Time{}
+	for _, doc := range docs {
+		_, end, use := parser.parse(doc.Attributes[dateAttribute])
+		if use && end.After(latest) {
+			latest = end
+		}
+	}
+
+	if latest.IsZero() {
+		return ""
+	}
+
+	return parser.format(latest)
+}
+
+// sourceFiles from which the documents were loaded, sorted and joined using the separator, if
+// there is a source attribute.
+func sourceFiles(docs []*graphstore.Document, sourceAttribute string, separator string) string {
+
+	if len(sourceAttribute) == 0 {
+		return ""
+	}
+
+	files := set.NewSet[string]()
+	for _, doc := range docs {
+		if file, found := doc.Attributes[sourceAttribute]; found && len(file) > 0 {
+			files.Add(file)
+		}
+	}
+
+	filesSlice := files.ToSlice()
+	sort.Strings(filesSlice)
+
+	return strings.Join(filesSlice, separator)
+}
+
+// documentAttributes of the documents, where the unique values of each attribute are sorted and
+// joined using the separator. Attributes whose names can't be used as a keyword are ignored.
+func documentAttributes(docs []*graphstore.Document, separator string) map[string]string {
+
+	attributeToValues := map[string]*set.Set[string]{}
+	for _, doc := range docs {
+		for attribute, value := range doc.Attributes {
+			if len(value) == 0 || len(attribute) == 0 || strings.ContainsAny(attribute, "<>") {
+				continue
+			}
+
+			if _, found := attributeToValues[attribute]; !found {
+				attributeToValues[attribute] = set.NewSet[string]()
+			}
+			attributeToValues[attribute].Add(value)
+		}
+	}
+
+	attributes := map[string]string{}
+	for attribute, values := range attributeToValues {
+		valuesSlice := values.ToSlice()
+		sort.Strings(valuesSlice)
+		attributes[attribute] = strings.Join(valuesSlice, separator)
+	}
+
+	return attributes
 }
 
 // keywordsForDocs summarises the key properties of a list of documents.
 func keywordsForDocs(docs []*graphstore.Document, dateAttribute string,
-	dateFormat string) map[string]string {
+	parser dateParser, sourceAttribute string) map[string]string {
 
 	return map[string]string{
 		numDocsKeyword:      fmt.Sprintf("%d", len(docs)),
 		docTypesKeyword:     documentTypes(docs, ", "),
-		docDateRangeKeyword: documentDates(docs, dateAttribute, dateFormat),
+		docDateRangeKeyword: documentDates(docs, dateAttribute, parser),
+		latestDateKeyword:   latestDocumentDate(docs, dateAttribute, parser),
+		sourceFilesKeyword:  sourceFiles(docs, sourceAttribute, ", "),
 	}
 }
@@ -129,7 +129,7 @@ func TestDateRange(t *testing.T) {
 	}
 
 	for _, testCase := range testCases {
-		actual := dateRange(testCase.dates, testCase.format)
+		actual := dateRange(testCase.dates, newDateParser([]string{testCase.format}, nil))
 		assert.Equal(t, testCase.expected, actual)
 	}
 }
@@ -211,7 +211,8 @@ func TestDocumentDates(t *testing.T) {
 	}
 
 	for _, testCase := range testCases {
-		actual := documentDates(testCase.docs, testCase.dateAttribute, testCase.dateFormat)
+		actual := documentDates(testCase.docs, testCase.dateAttribute,
+			newDateParser([]string{testCase.dateFormat}, nil))
 		assert.Equal(t, testCase.expected, actual)
 	}
 }
@@ -238,6 +239,8 @@ func TestKeywordsForDoc(t *testing.T) {
 				numDocsKeyword:      "1",
 				docTypesKeyword:     "Type-A",
 				docDateRangeKeyword: "",
+				latestDateKeyword:   "",
+				sourceFilesKeyword:  "",
 			},
 		},
 		{
@@ -255,6 +258,8 @@ func TestKeywordsForDoc(t *testing.T) {
 				numDocsKeyword:      "1",
 				docTypesKeyword:     "Type-A",
 				docDateRangeKeyword: "04/09/2022",
+				latestDateKeyword:   "04/09/2022",
+				sourceFilesKeyword:  "",
 			},
 		},
 		{
@@ -277,12 +282,48 @@ func TestKeywordsForDoc(t *testing.T) {
 				numDocsKeyword:      "2",
 				docTypesKeyword:     "Type-A, Type-B",
 				docDateRangeKeyword: "01/02/2021 - 04/09/2022",
+				latestDateKeyword:   "04/09/2022",
+				sourceFilesKeyword:  "",
 			},
 		},
 	}
 
 	for _, testCase := range testCases {
-		actual := keywordsForDocs(testCase.docs, testCase.dateAttribute, testCase.dateFormat)
+		actual := keywordsForDocs(testCase.docs, testCase.dateAttribute,
+			newDateParser([]string{testCase.dateFormat}, nil), "")
 		assert.Equal(t, testCase.expected, actual)
 	}
 }
+
+func TestSourceFiles(t *testing.T) {
+	docs := []*graphstore.Document{
+		{Attributes: map[string]string{"source": "calls-2.csv"}},
+		{Attributes: map[string]string{"source": "calls-1.csv"}},
+		{Attributes: map[string]string{"source": "calls-2.csv"}},
+		{Attributes: map[string]string{"source": ""}},
+		{Attributes: map[string]string{}},
+	}
+
+	assert.Equal(t, "calls-1.csv, calls-2.csv", sourceFiles(docs, "source", ", "))
+	assert.Equal(t, "", sourceFiles(docs, "", ", "))
+	assert.Equal(t, "", sourceFiles(docs, "missing", ", "))
+	assert.Equal(t, "", sourceFiles(nil, "source", ", "))
+}
+
+func TestDocumentAttributes(t *testing.T) {
+	docs := []*graphstore.Document{
+		{
+			Attributes: map[string]string{"amount": "20", "currency": "GBP", "<bad>": "x"},
+		},
+		{
+			Attributes: map[string]string{"amount": "100", "currency": "GBP", "note": ""},
+		},
+	}
+
+	expected := map[string]string{
+		"amount":   "100, 20",
+		"currency": "GBP",
+	}
+	assert.Equal(t, expected, documentAttributes(docs, ", "))
+	assert.Equal(t, map[string]string{}, documentAttributes(nil, ", "))
+}
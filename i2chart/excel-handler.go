@@ -3,7 +3,10 @@ package i2chart
 import (
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/cdclaxton/shortest-path-web-app/logging"
 	"github.com/xuri/excelize/v2"
@@ -40,26 +43,65 @@ func excelCellIndex(columnIndex int, rowIndex int) (string, error) {
 	return fmt.Sprintf("%v%v", columnLetter, rowIndex+1), nil
 }
 
-// WriteToExcel writes the rows to the Excel file at filepath.
-func WriteToExcel(filepath string, rows [][]string) error {
+// Name of the sheet holding the summary of the chart
+const summarySheetName = "Summary"
+
+// TruncationSummary of a chart where rows were dropped as the chart had too many rows, as rows to
+// write to the summary sheet.
+func TruncationSummary(numberOfRows int, droppedRows int, maxRows int) [][]string {
+	return [][]string{
+		{"Warning", "The chart has been truncated as it has too many rows for i2"},
+		{"Rows on the chart", strconv.Itoa(numberOfRows)},
+		{"Rows dropped", strconv.Itoa(droppedRows)},
+		{"Maximum number of rows", strconv.Itoa(maxRows)},
+	}
+}
 
-	// Preconditions
-	if len(filepath) == 0 {
-		return errors.New("filepath is empty")
+// IncompleteSummary of a chart made from the paths found before a job failed, as rows to write to
+// the summary sheet.
+func IncompleteSummary(reason string) [][]string {
+	return [][]string{
+		{"Warning", "The results are incomplete as the job failed before all of the paths were found"},
+		{"Reason", reason},
 	}
+}
 
-	if rows == nil {
-		return errors.New("rows to write is nil")
+// OverlapSummary of the entity IDs in more than one of the datasets of a job (entity ID to the names
+// of its datasets), as rows to write to the summary sheet. There aren't any rows if no entity IDs
+// overlap.
+func OverlapSummary(overlaps map[string][]string) [][]string {
+	if len(overlaps) == 0 {
+		return nil
 	}
 
-	logging.Logger.Info().
-		Str(logging.ComponentField, componentName).
-		Str("filepath", filepath).
-		Str("numberOfRows", strconv.Itoa(len(rows))).
-		Msg("Writing Excel file")
+	entityIds := []string{}
+	for entityId := range overlaps {
+		entityIds = append(entityIds, entityId)
+	}
+	sort.Strings(entityIds)
 
-	// Create a new in-memory Excel file
-	f := excelize.NewFile()
+	rows := [][]string{
+		{"Warning", "Entities are in more than one dataset, so their connections to themselves were skipped"},
+		{"Entities in more than one dataset", strconv.Itoa(len(entityIds))},
+	}
+	for _, entityId := range entityIds {
+		rows = append(rows, []string{entityId, strings.Join(overlaps[entityId], ", ")})
+	}
+
+	return rows
+}
+
+// ProvenanceSummary of the data from which a chart was made, as rows to write to the summary sheet.
+func ProvenanceSummary(signature string, sourceFiles []string, loaded time.Time) [][]string {
+	return [][]string{
+		{"Data signature", signature},
+		{"Source files", strings.Join(sourceFiles, ", ")},
+		{"Data loaded", loaded.Format(time.RFC3339)},
+	}
+}
+
+// writeRows to the sheet of the Excel file.
+func writeRows(f *excelize.File, sheetName string, rows [][]string) error {
 
 	// Walk through each row
 	for rowIdx, row := range rows {
@@ -81,7 +123,91 @@ func WriteToExcel(filepath string, rows [][]string) error {
 			}
 
 			// Write the value to the cell
-			f.SetCellValue("Sheet1", cellIndex, value)
+			f.SetCellValue(sheetName, cellIndex, value)
+		}
+	}
+
+	return nil
+}
+
+// WriteToExcel writes the rows to the Excel file at filepath.
+func WriteToExcel(filepath string, rows [][]string) error {
+	return WriteToExcelWithSummary(filepath, rows, nil)
+}
+
+// WriteToExcelWithSummary writes the rows to the Excel file at filepath and the summary (if it
+// isn't empty) to a separate sheet. The rows are always in the first sheet, so that the import
+// specification can find them.
+func WriteToExcelWithSummary(filepath string, rows [][]string, summary [][]string) error {
+	return WriteSheetToExcel(filepath, excelSheetName, rows, summary)
+}
+
+// WriteSheetToExcel writes the rows to the first sheet, called sheetName, of the Excel file at
+// filepath and the summary (if it isn't empty) to a separate sheet.
+func WriteSheetToExcel(filepath string, sheetName string, rows [][]string, summary [][]string) error {
+	return WriteSheetsToExcel(filepath, []Sheet{{Name: sheetName, Rows: rows}}, summary)
+}
+
+// A Sheet of an Excel file.
+type Sheet struct {
+	Name string     // Name of the sheet
+	Rows [][]string // Rows of the sheet
+}
+
+// WriteSheetsToExcel writes the sheets in order to the Excel file at filepath and the summary (if it
+// isn't empty) to a separate, final sheet.
+func WriteSheetsToExcel(filepath string, sheets []Sheet, summary [][]string) error {
+
+	// Preconditions
+	if len(filepath) == 0 {
+		return errors.New("filepath is empty")
+	}
+
+	if len(sheets) == 0 {
+		return errors.New("no sheets to write")
+	}
+
+	names := map[string]bool{}
+	for _, sheet := range sheets {
+		if len(sheet.Name) == 0 || sheet.Name == summarySheetName || names[sheet.Name] {
+			return errors.New("invalid sheet name")
+		}
+		names[sheet.Name] = true
+
+		if sheet.Rows == nil {
+			return errors.New("rows to write is nil")
+		}
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("filepath", filepath).
+		Str("sheetName", sheets[0].Name).
+		Str("numberOfRows", strconv.Itoa(len(sheets[0].Rows))).
+		Int("numberOfSheets", len(sheets)).
+		Bool("summary", len(summary) > 0).
+		Msg("Writing Excel file")
+
+	// Create a new in-memory Excel file, where the first sheet is renamed if necessary
+	f := excelize.NewFile()
+	if sheets[0].Name != excelSheetName {
+		f.SetSheetName(excelSheetName, sheets[0].Name)
+	}
+
+	for idx, sheet := range sheets {
+		if idx > 0 {
+			f.NewSheet(sheet.Name)
+		}
+
+		if err := writeRows(f, sheet.Name, sheet.Rows); err != nil {
+			return err
+		}
+	}
+
+	if len(summary) > 0 {
+		f.NewSheet(summarySheetName)
+		if err := writeRows(f, summarySheetName, summary); err != nil {
+			return err
 		}
 	}
 
@@ -5,8 +5,10 @@ import (
 	"os"
 	"path"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
 )
 
 func TestColumnIndexToLetter(t *testing.T) {
@@ -204,3 +206,99 @@ func TestWriteToExcel(t *testing.T) {
 
 	assert.NoError(t, os.RemoveAll(dir))
 }
+
+func TestWriteToExcelWithSummary(t *testing.T) {
+
+	filepath := path.Join(t.TempDir(), "test.xlsx")
+	rows := [][]string{
+		{"CellA1", "CellB1"},
+		{"CellA2", "CellB2"},
+	}
+	summary := TruncationSummary(1, 10, 1)
+
+	assert.NoError(t, WriteToExcelWithSummary(filepath, rows, summary))
+
+	actualRows, err := ReadFromExcel(filepath, excelSheetName)
+	assert.NoError(t, err)
+	assert.Equal(t, rows, actualRows)
+
+	actualSummary, err := ReadFromExcel(filepath, summarySheetName)
+	assert.NoError(t, err)
+	assert.Equal(t, summary, actualSummary)
+	assert.Equal(t, []string{"Rows dropped", "10"}, actualSummary[2])
+
+	// Summary of the provenance of the data
+	loaded := time.Date(2023, 4, 5, 6, 7, 8, 0, time.UTC)
+	assert.Equal(t, [][]string{
+		{"Data signature", "abc"},
+		{"Source files", "a.csv, b.csv"},
+		{"Data loaded", "2023-04-05T06:07:08Z"},
+	}, ProvenanceSummary("abc", []string{"a.csv", "b.csv"}, loaded))
+
+	// Summary of the entities in more than one dataset
+	assert.Nil(t, OverlapSummary(map[string][]string{}))
+	assert.Equal(t, [][]string{
+		{"Warning", "Entities are in more than one dataset, so their connections to themselves were skipped"},
+		{"Entities in more than one dataset", "2"},
+		{"e-1", "Set-1, Set-2"},
+		{"e-3", "Set-2, Set-3"},
+	}, OverlapSummary(map[string][]string{
+		"e-3": {"Set-2", "Set-3"},
+		"e-1": {"Set-1", "Set-2"},
+	}))
+
+	// Without a summary, there isn't a summary sheet
+	assert.NoError(t, WriteToExcelWithSummary(filepath, rows, nil))
+	_, err = ReadFromExcel(filepath, summarySheetName)
+	assert.Error(t, err)
+}
+
+func TestWriteSheetToExcel(t *testing.T) {
+
+	filepath := path.Join(t.TempDir(), "test.xlsx")
+	rows := [][]string{
+		{"CellA1", "CellB1"},
+	}
+
+	// Invalid sheet names
+	assert.Error(t, WriteSheetToExcel(filepath, "", rows, nil))
+	assert.Error(t, WriteSheetToExcel(filepath, summarySheetName, rows, nil))
+
+	assert.NoError(t, WriteSheetToExcel(filepath, "Spider", rows, TruncationSummary(1, 1, 1)))
+
+	actualRows, err := ReadFromExcel(filepath, "Spider")
+	assert.NoError(t, err)
+	assert.Equal(t, rows, actualRows)
+
+	// The default sheet has been renamed
+	_, err = ReadFromExcel(filepath, excelSheetName)
+	assert.Error(t, err)
+}
+
+func TestWriteSheetsToExcel(t *testing.T) {
+
+	filepath := path.Join(t.TempDir(), "test.xlsx")
+	sheets := []Sheet{
+		{Name: "Spider", Rows: [][]string{{"CellA1", "CellB1"}}},
+		{Name: "Step 1", Rows: [][]string{{"CellA1"}, {"CellA2"}}},
+	}
+
+	// Invalid sheets
+	assert.Error(t, WriteSheetsToExcel(filepath, nil, nil))
+	assert.Error(t, WriteSheetsToExcel(filepath, []Sheet{sheets[0], sheets[0]}, nil))
+	assert.Error(t, WriteSheetsToExcel(filepath, []Sheet{sheets[0], {Name: "Step 1"}}, nil))
+
+	assert.NoError(t, WriteSheetsToExcel(filepath, sheets, IncompleteSummary("timeout")))
+
+	for _, sheet := range sheets {
+		actualRows, err := ReadFromExcel(filepath, sheet.Name)
+		assert.NoError(t, err)
+		assert.Equal(t, sheet.Rows, actualRows)
+	}
+
+	// The summary is the last sheet
+	f, err := excelize.OpenFile(filepath)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Spider", "Step 1", summarySheetName}, f.GetSheetList())
+	assert.NoError(t, f.Close())
+}
@@ -0,0 +1,206 @@
+package i2chart
+
+import (
+	"encoding/xml"
+	"errors"
+)
+
+// Name of the sheet in the Excel file written by WriteToExcel
+const excelSheetName = "Sheet1"
+
+// Names of the chart profiles that have an import specification
+const (
+	ShortestPathImportSpecName = "shortest-path"
+	SpiderImportSpecName       = "spider"
+)
+
+var ErrImportSpecIsNil = errors.New("import specification is nil")
+
+// ImportSpecColumn is a column in the Excel file.
+type ImportSpecColumn struct {
+	Index int    `xml:"Index,attr"` // Zero-based index of the column
+	Name  string `xml:"Name,attr"`  // Name of the column in the header row
+}
+
+// ImportSpecAttribute maps a column to an i2 attribute of an entity.
+type ImportSpecAttribute struct {
+	Name   string `xml:"Name,attr"`   // Name of the attribute class
+	Column string `xml:"Column,attr"` // Column holding the attribute value
+}
+
+// ImportSpecEntity maps the columns to the properties of one end of a link.
+type ImportSpecEntity struct {
+	End               int                   `xml:"End,attr"` // 1 or 2
+	IdentityColumn    string                `xml:"IdentityColumn"`
+	TypeColumn        string                `xml:"TypeColumn,omitempty"`
+	IconColumn        string                `xml:"IconColumn,omitempty"`
+	LabelColumn       string                `xml:"LabelColumn,omitempty"`
+	DescriptionColumn string                `xml:"DescriptionColumn,omitempty"`
+	Attributes        []ImportSpecAttribute `xml:"Attributes>Attribute,omitempty"`
+}
+
+// ImportSpecLink maps the columns to the properties of the link between the entities.
+type ImportSpecLink struct {
+	LabelColumn string `xml:"LabelColumn,omitempty"`
+	ArrowStyle  string `xml:"ArrowStyle"`
+}
+
+// ImportSpec is an i2 Analyst's Notebook import specification for the Excel file generated for
+// a chart profile. It is generated from the config, so it always matches the column layout.
+type ImportSpec struct {
+	XMLName        xml.Name           `xml:"ImportSpecification"`
+	Name           string             `xml:"Name,attr"`
+	SheetName      string             `xml:"SheetName,attr"`
+	FirstRowHeader bool               `xml:"FirstRowIsHeader,attr"`
+	Columns        []ImportSpecColumn `xml:"Columns>Column"`
+	Entities       []ImportSpecEntity `xml:"Entities>Entity"`
+	Link           ImportSpecLink     `xml:"Link"`
+}
+
+// newImportSpec with the columns from the header row of the Excel file.
+func newImportSpec(name string, headerRow []string) *ImportSpec {
+
+	columns := make([]ImportSpecColumn, len(headerRow))
+	for idx, column := range headerRow {
+		columns[idx] = ImportSpecColumn{
+			Index: idx,
+			Name:  column,
+		}
+	}
+
+	return &ImportSpec{
+		Name:           name,
+		SheetName:      excelSheetName,
+		FirstRowHeader: true,
+		Columns:        columns,
+		Link: ImportSpecLink{
+			ArrowStyle: "ArrowNone",
+		},
+	}
+}
+
+// entityColumnName is the name of the column in the header for the entity at the end (1 or 2).
+func entityColumnName(column string, end int) string {
+	if len(column) == 0 {
+		return ""
+	}
+
+	return header([]string{column})[end-1]
+}
+
+// ImportSpec generates the import specification for the shortest path chart.
+func (i *I2ChartBuilder) ImportSpec() *ImportSpec {
+
+	spec := newImportSpec(ShortestPathImportSpecName, header(i.config.Columns))
+	anx := i.config.Anx.withDefaults()
+
+	// Columns that aren't mapped to an entity property become attributes
+	mapped := map[string]bool{
+		anx.IdentityColumn:    true,
+		anx.IconColumn:        true,
+		anx.LabelColumn:       true,
+		anx.DescriptionColumn: true,
+	}
+
+	// optionalColumn returns the column if it is present in the config
+	optionalColumn := func(column string) string {
+		if columnIndex(i.config.Columns, column) == -1 {
+			return ""
+		}
+		return column
+	}
+
+	for end := 1; end <= 2; end++ {
+		entity := ImportSpecEntity{
+			End:               end,
+			IdentityColumn:    entityColumnName(anx.IdentityColumn, end),
+			IconColumn:        entityColumnName(optionalColumn(anx.IconColumn), end),
+			LabelColumn:       entityColumnName(optionalColumn(anx.LabelColumn), end),
+			DescriptionColumn: entityColumnName(optionalColumn(anx.DescriptionColumn), end),
+		}
+
+		for _, column := range i.config.Columns {
+			if mapped[column] {
+				continue
+			}
+
+			entity.Attributes = append(entity.Attributes, ImportSpecAttribute{
+				Name:   column,
+				Column: entityColumnName(column, end),
+			})
+		}
+
+		spec.Entities = append(spec.Entities, entity)
+	}
+
+	spec.Link.LabelColumn = "Link"
+
+	return spec
+}
+
+// ImportSpec generates the import specification for the spider chart. The ID, Type, Icon and Label
+// columns of the sheet are mapped to the properties of the entities and the other columns become
+// attributes.
+func (s *SpiderChartBuilder) ImportSpec() *ImportSpec {
+
+	headerRow := s.sheet.header()
+	spec := newImportSpec(SpiderImportSpecName, headerRow)
+	spec.SheetName = s.sheet.Name
+
+	// Column for the entity at the end (1 or 2) if it is present in the sheet
+	column := func(name string, end int) string {
+		idx := columnIndex(s.sheet.Columns, name)
+		if idx == -1 {
+			return ""
+		}
+		return headerRow[(end-1)*len(s.sheet.Columns)+idx]
+	}
+
+	mapped := map[string]bool{
+		spiderIdColumn:    true,
+		spiderTypeColumn:  true,
+		spiderIconColumn:  true,
+		spiderLabelColumn: true,
+	}
+
+	for end := 1; end <= 2; end++ {
+		entity := ImportSpecEntity{
+			End:            end,
+			IdentityColumn: column(spiderIdColumn, end),
+			TypeColumn:     column(spiderTypeColumn, end),
+			IconColumn:     column(spiderIconColumn, end),
+			LabelColumn:    column(spiderLabelColumn, end),
+		}
+
+		for _, name := range s.sheet.Columns {
+			if mapped[name] {
+				continue
+			}
+
+			entity.Attributes = append(entity.Attributes, ImportSpecAttribute{
+				Name:   name,
+				Column: column(name, end),
+			})
+		}
+
+		spec.Entities = append(spec.Entities, entity)
+	}
+
+	return spec
+}
+
+// MarshalImportSpec to its XML form.
+func MarshalImportSpec(spec *ImportSpec) ([]byte, error) {
+
+	// Precondition
+	if spec == nil {
+		return nil, ErrImportSpecIsNil
+	}
+
+	content, err := xml.MarshalIndent(spec, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+
+	return append([]byte(xml.Header), content...), nil
+}
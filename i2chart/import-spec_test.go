@@ -0,0 +1,105 @@
+package i2chart
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImportSpecForShortestPathChart(t *testing.T) {
+	builder, err := NewI2ChartBuilder("./test-data/i2-config-1.json")
+	assert.NoError(t, err)
+
+	spec := builder.ImportSpec()
+	assert.Equal(t, ShortestPathImportSpecName, spec.Name)
+	assert.Equal(t, excelSheetName, spec.SheetName)
+
+	// The columns must match the header of the chart
+	assert.Equal(t, 11, len(spec.Columns))
+	assert.Equal(t, ImportSpecColumn{Index: 0, Name: "Entity-icon-1"}, spec.Columns[0])
+	assert.Equal(t, ImportSpecColumn{Index: 10, Name: "Link"}, spec.Columns[10])
+
+	expectedEntities := []ImportSpecEntity{
+		{
+			End:               1,
+			IdentityColumn:    "Entity-id-1",
+			IconColumn:        "Entity-icon-1",
+			LabelColumn:       "Entity-label-1",
+			DescriptionColumn: "Entity-description-1",
+			Attributes: []ImportSpecAttribute{
+				{Name: "entitySets", Column: "Entity-entitySets-1"},
+			},
+		},
+		{
+			End:               2,
+			IdentityColumn:    "Entity-id-2",
+			IconColumn:        "Entity-icon-2",
+			LabelColumn:       "Entity-label-2",
+			DescriptionColumn: "Entity-description-2",
+			Attributes: []ImportSpecAttribute{
+				{Name: "entitySets", Column: "Entity-entitySets-2"},
+			},
+		},
+	}
+	assert.Equal(t, expectedEntities, spec.Entities)
+	assert.Equal(t, "Link", spec.Link.LabelColumn)
+
+	// Changing the config changes the specification
+	builder.config.Columns = []string{"id", "label"}
+	spec = builder.ImportSpec()
+	assert.Equal(t, 5, len(spec.Columns))
+	assert.Equal(t, "", spec.Entities[0].IconColumn)
+	assert.Equal(t, "", spec.Entities[0].DescriptionColumn)
+	assert.Nil(t, spec.Entities[0].Attributes)
+}
+
+func TestImportSpecForSpiderChart(t *testing.T) {
+	builder, err := NewSpiderChartBuilder("./test-data/spider-i2-config-1.json")
+	assert.NoError(t, err)
+
+	spec := builder.ImportSpec()
+	assert.Equal(t, SpiderImportSpecName, spec.Name)
+
+	// The columns must match the header of the chart
+	headerRow := []string{"ID-1", "Type-1", "Icon-1", "Label-1", "Seed-1",
+		"ID-2", "Type-2", "Icon-2", "Label-2", "Seed-2"}
+	for idx, column := range headerRow {
+		assert.Equal(t, ImportSpecColumn{Index: idx, Name: column}, spec.Columns[idx])
+	}
+
+	assert.Equal(t, 2, len(spec.Entities))
+	assert.Equal(t, "ID-2", spec.Entities[1].IdentityColumn)
+	assert.Equal(t, "Type-2", spec.Entities[1].TypeColumn)
+	assert.Equal(t, "", spec.Link.LabelColumn)
+	assert.Equal(t, []ImportSpecAttribute{{Name: "Seed", Column: "Seed-2"}}, spec.Entities[1].Attributes)
+
+	// Configured sheet, where the columns that aren't properties become attributes
+	builder, err = NewSpiderChartBuilder("./test-data/spider-i2-config-sheet.json")
+	assert.NoError(t, err)
+
+	spec = builder.ImportSpec()
+	assert.Equal(t, "Spider", spec.SheetName)
+	assert.Equal(t, ImportSpecColumn{Index: 4, Name: "Label-2"}, spec.Columns[4])
+	assert.Equal(t, "ID-2", spec.Entities[1].IdentityColumn)
+	assert.Equal(t, "Label-2", spec.Entities[1].LabelColumn)
+	assert.Equal(t, "", spec.Entities[1].TypeColumn)
+	assert.Equal(t, []ImportSpecAttribute{
+		{Name: "Name", Column: "Name-2"},
+		{Name: "Seed", Column: "Seed-2"},
+	}, spec.Entities[1].Attributes)
+}
+
+func TestMarshalImportSpec(t *testing.T) {
+	_, err := MarshalImportSpec(nil)
+	assert.ErrorIs(t, err, ErrImportSpecIsNil)
+
+	builder, err := NewSpiderChartBuilder("./test-data/spider-i2-config-1.json")
+	assert.NoError(t, err)
+
+	content, err := MarshalImportSpec(builder.ImportSpec())
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(content), "<?xml"))
+	assert.True(t, strings.Contains(string(content), `<Column Index="0" Name="ID-1"></Column>`))
+	assert.True(t, strings.Contains(string(content), `<Attribute Name="Seed" Column="Seed-1"></Attribute>`))
+}
@@ -0,0 +1,98 @@
+// Document dates in real data are often written in a mixture of formats and sometimes as a range,
+// e.g. "2022-08/2022-09". A link specification can accept several date formats, where the first
+// format is used to show the dates on the chart. A date that doesn't match any of the formats as a
+// whole is tried as a range split at each of the range separators.
+
+package i2chart
+
+import (
+	"strings"
+	"time"
+)
+
+// Separators between the start and end of a date range if the link specification doesn't set them
+var defaultDateRangeSeparators = []string{"/", " - ", " to "}
+
+// A dateParser parses the dates of documents in any of the accepted formats.
+type dateParser struct {
+	formats    []string // Accepted formats, where the first is used to show the dates
+	separators []string // Separators between the start and end of a date range
+}
+
+// newDateParser given the accepted formats (blank formats are ignored) and the range separators
+// (the default separators are used if there aren't any).
+func newDateParser(formats []string, separators []string) dateParser {
+
+	accepted := []string{}
+	for _, format := range formats {
+		if len(format) > 0 {
+			accepted = append(accepted, format)
+		}
+	}
+
+	if len(separators) == 0 {
+		separators = defaultDateRangeSeparators
+	}
+
+	return dateParser{
+		formats:    accepted,
+		separators: separators,
+	}
+}
+
+// canParse returns true if the parser has at least one accepted format.
+func (d dateParser) canParse() bool {
+	return len(d.formats) > 0
+}
+
+// format the date using the first accepted format.
+func (d dateParser) format(date time.Time) string {
+	if !d.canParse() {
+		return ""
+	}
+	return date.Format(d.formats[0])
+}
+
+// parseSingle date using the first accepted format that matches.
+func (d dateParser) parseSingle(value string) (time.Time, bool) {
+	for _, format := range d.formats {
+		if parsed, use := parseDate(value, format); use {
+			return parsed, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// parse the date or date range leniently (ignoring surrounding and repeated whitespace), returning
+// the start and end of the range. A single date is a range that starts and ends on the date.
+func (d dateParser) parse(value string) (time.Time, time.Time, bool) {
+
+	value = strings.Join(strings.Fields(value), " ")
+
+	if parsed, use := d.parseSingle(value); use {
+		return parsed, parsed, true
+	}
+
+	// Try to split the value into the start and end of a range at each occurrence of a separator
+	for _, separator := range d.separators {
+		offset := 0
+		for {
+			idx := strings.Index(value[offset:], separator)
+			if idx < 0 {
+				break
+			}
+			idx += offset
+
+			start, startUse := d.parseSingle(strings.TrimSpace(value[:idx]))
+			end, endUse := d.parseSingle(strings.TrimSpace(value[idx+len(separator):]))
+			if startUse && endUse && !end.Before(start) {
+				return start, end, true
+			}
+
+			offset = idx + 1
+		}
+	}
+
+	return time.Time{}, time.Time{}, false
+}
@@ -0,0 +1,88 @@
+package i2chart
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDateParser(t *testing.T) {
+
+	parser := newDateParser([]string{"", "02/01/2006", "2006-01-02", "Jan 2006"}, nil)
+	assert.True(t, parser.canParse())
+
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+	}
+
+	testCases := []struct {
+		value         string
+		expectedUse   bool
+		expectedStart time.Time
+		expectedEnd   time.Time
+	}{
+		{"04/09/2022", true, date(2022, 9, 4), date(2022, 9, 4)},
+		{"  2022-09-04 ", true, date(2022, 9, 4), date(2022, 9, 4)},
+		{"AUG  2022", true, date(2022, 8, 1), date(2022, 8, 1)},
+		{"04/09/2022/06/09/2022", true, date(2022, 9, 4), date(2022, 9, 6)},
+		{"2022-08-01 - 2022-09-30", true, date(2022, 8, 1), date(2022, 9, 30)},
+		{"Aug 2022 to 04/09/2022", true, date(2022, 8, 1), date(2022, 9, 4)},
+		{"2022-09-30 - 2022-08-01", false, time.Time{}, time.Time{}}, // ends before it starts
+		{"04/09/1800/06/09/2022", false, time.Time{}, time.Time{}},   // starts too far in the past
+		{"05 Sept 2022", false, time.Time{}, time.Time{}},
+		{"", false, time.Time{}, time.Time{}},
+	}
+
+	for _, testCase := range testCases {
+		start, end, use := parser.parse(testCase.value)
+		assert.Equal(t, testCase.expectedUse, use, testCase.value)
+		assert.Equal(t, testCase.expectedStart, start, testCase.value)
+		assert.Equal(t, testCase.expectedEnd, end, testCase.value)
+	}
+
+	// The dates are shown in the first accepted format
+	assert.Equal(t, "04/09/2022", parser.format(date(2022, 9, 4)))
+	assert.Equal(t, "01/08/2022 - 30/09/2022",
+		dateRange([]string{"2022-08-01 - 2022-09-30"}, parser))
+	assert.Equal(t, "01/08/2022 - 04/09/2022",
+		dateRange([]string{"04/09/2022", "Aug 2022", "invalid"}, parser))
+
+	// The range separators can be replaced
+	parser = newDateParser([]string{"2006-01"}, []string{" until "})
+	_, _, use := parser.parse("2022-08/2022-09")
+	assert.False(t, use)
+
+	start, end, use := parser.parse("2022-08 until 2022-09")
+	assert.True(t, use)
+	assert.Equal(t, date(2022, 8, 1), start)
+	assert.Equal(t, date(2022, 9, 1), end)
+
+	// Without an accepted format, dates can't be parsed
+	parser = newDateParser([]string{""}, nil)
+	assert.False(t, parser.canParse())
+	assert.Equal(t, "", parser.format(date(2022, 9, 4)))
+}
+
+func TestDateFormatsForDocumentType(t *testing.T) {
+
+	spec := LinksSpec{
+		DateFormat:  "02/01/2006",
+		DateFormats: []string{"2006-01"},
+		DocumentTypes: map[string]DocumentTypeLinkSpec{
+			"Call":    {DateFormats: []string{"2006-01-02"}},
+			"Meeting": {DateRangeSeparators: []string{" until "}},
+		},
+	}
+
+	// The date formats of a document type replace all of the formats
+	typeSpec, _ := spec.forDocumentType("Call")
+	assert.Equal(t, []string{"2006-01-02"}, typeSpec.dateParser().formats)
+
+	typeSpec, _ = spec.forDocumentType("Meeting")
+	assert.Equal(t, []string{"02/01/2006", "2006-01"}, typeSpec.dateParser().formats)
+	assert.Equal(t, []string{" until "}, typeSpec.dateParser().separators)
+
+	typeSpec, _ = spec.forDocumentType("Other")
+	assert.Equal(t, defaultDateRangeSeparators, typeSpec.dateParser().separators)
+}
@@ -0,0 +1,37 @@
+package i2chart
+
+import (
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cdclaxton/shortest-path-web-app/redaction"
+)
+
+// SetRedactor whose rules redact the attributes of the entities and documents in the charts. A nil
+// redactor doesn't redact any attributes.
+func (i *I2ChartBuilder) SetRedactor(redactor *redaction.Redactor) {
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Bool("redaction", redactor != nil).
+		Msg("Setting the redactor in the i2 chart builder")
+	i.redactor = redactor
+}
+
+// store from which the entities and documents in the chart are read, which redacts their attributes.
+func (i *I2ChartBuilder) store() graphstore.BipartiteGraphStore {
+	return i.redactor.Bipartite(i.bipartite)
+}
+
+// SetRedactor whose rules redact the attributes of the entities in the spider charts. A nil
+// redactor doesn't redact any attributes.
+func (s *SpiderChartBuilder) SetRedactor(redactor *redaction.Redactor) {
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Bool("redaction", redactor != nil).
+		Msg("Setting the redactor in the spider i2 chart builder")
+	s.redactor = redactor
+}
+
+// store from which the entities in the spider chart are read, which redacts their attributes.
+func (s *SpiderChartBuilder) store() graphstore.BipartiteGraphStore {
+	return s.redactor.Bipartite(s.bipartite)
+}
@@ -0,0 +1,115 @@
+package i2chart
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphbuilder"
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/redaction"
+	"github.com/cdclaxton/shortest-path-web-app/set"
+	"github.com/cdclaxton/shortest-path-web-app/spider"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRowLinkingEntitiesWithRedaction(t *testing.T) {
+
+	graphBuilder, _, err := graphbuilder.NewGraphBuilderFromJson("../test-data-sets/set-1/data-config.json")
+	assert.NoError(t, err)
+
+	chartBuilder, err := NewI2ChartBuilder("../test-data-sets/set-1/i2-config.json")
+	assert.NoError(t, err)
+	chartBuilder.SetBipartite(graphBuilder.Bipartite)
+
+	redactor, err := redaction.NewRedactor(redaction.RulesConfig{
+		Attributes: []string{"DOB", "Title"},
+	})
+	assert.NoError(t, err)
+	chartBuilder.SetRedactor(redactor)
+
+	chartBuilder.config.Entities["Person"]["description"] = "<Forename> <DOB>"
+	chartBuilder.config.Links.Label = "<NUM-DOCS> docs: <Title>"
+
+	row, err := chartBuilder.rowLinkingEntities("e-1", "e-2", map[string]string{}, map[string]string{}, nil,
+		nil)
+	assert.NoError(t, err)
+
+	assert.Equal(t, "Bob [REDACTED]", row[4])
+	assert.Equal(t, "Sally [REDACTED]", row[9])
+	assert.Equal(t, "2 docs: [REDACTED]", row[10])
+
+	// The values are still in the store
+	entity, err := graphBuilder.Bipartite.GetEntity("e-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "03/04/1981", entity.Attributes["DOB"])
+}
+
+func TestLinkLabelWithRedactedDate(t *testing.T) {
+
+	bipartite := makeBipartiteStore(t)
+
+	spec := LinksSpec{
+		Label:         "<NUM-DOCS> docs, latest <LATEST-DOCUMENT-DATE>",
+		DateAttribute: "Date",
+		DateFormat:    "02/01/2006",
+	}
+
+	unipartite := graphstore.NewInMemoryUnipartiteGraphStore()
+	assert.NoError(t, unipartite.AddEdgeDocument("e-1", "e-2",
+		time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)))
+
+	builder := I2ChartBuilder{
+		config:     I2ChartConfig{Links: spec, AttributeNotKnown: "MISSING"},
+		bipartite:  bipartite,
+		unipartite: unipartite,
+	}
+
+	entity1, err := bipartite.GetEntity("e-1")
+	assert.NoError(t, err)
+	entity2, err := bipartite.GetEntity("e-2")
+	assert.NoError(t, err)
+
+	actual, err := builder.linkLabel(entity1, entity2, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "1 docs, latest 01/01/2000", actual)
+
+	// The latest date in the metadata isn't used if the date attribute is redacted
+	redactor, err := redaction.NewRedactor(redaction.RulesConfig{Attributes: []string{"Date"}})
+	assert.NoError(t, err)
+	builder.SetRedactor(redactor)
+
+	actual, err = builder.linkLabel(entity1, entity2, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "2 docs, latest ", actual)
+}
+
+func TestSpiderBuildChartWithRedaction(t *testing.T) {
+
+	s, err := NewSpiderChartBuilder("./test-data/spider-i2-config-1.json")
+	assert.NoError(t, err)
+	s.SetBipartite(makeBipartiteStore(t))
+
+	redactor, err := redaction.NewRedactor(redaction.RulesConfig{
+		Patterns:    []string{"Name$"},
+		Placeholder: "***",
+	})
+	assert.NoError(t, err)
+	s.SetRedactor(redactor)
+
+	subgraph := graphstore.NewInMemoryUnipartiteGraphStore()
+	subgraph.AddUndirected("e-1", "e-2")
+
+	actual, err := s.Build(&spider.SpiderResults{
+		NumberSteps:          1,
+		Subgraph:             subgraph,
+		SeedEntities:         set.NewPopulatedSet("e-1"),
+		SeedEntitiesNotFound: set.NewSet[string](),
+	})
+	assert.NoError(t, err)
+
+	expected := [][]string{
+		{"ID-1", "Type-1", "Icon-1", "Label-1", "Seed-1", "ID-2", "Type-2", "Icon-2", "Label-2", "Seed-2"},
+		{"e-1", "Person", "Anonymous", "***", "TRUE", "e-2", "Person", "Anonymous", "***", "FALSE"},
+	}
+	assert.Equal(t, expected, actual)
+}
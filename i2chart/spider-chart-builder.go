@@ -3,13 +3,16 @@ package i2chart
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
 	"os"
 	"sort"
 	"strconv"
+	"strings"
 
 	"github.com/cdclaxton/shortest-path-web-app/graphstore"
 	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cdclaxton/shortest-path-web-app/redaction"
 	"github.com/cdclaxton/shortest-path-web-app/set"
 	"github.com/cdclaxton/shortest-path-web-app/spider"
 )
@@ -18,6 +21,55 @@ var (
 	ErrBipartiteIsNil     = errors.New("bipartite graph store is nil")
 	ErrSpiderResultsIsNil = errors.New("spider results is nil")
 	ErrEntityIsEmpty      = errors.New("entity ID is empty")
+
+	ErrInvalidSpiderSheetName      = errors.New("invalid spider sheet name")
+	ErrSpiderIdColumnMissing       = errors.New("spider sheet doesn't have an ID column")
+	ErrDuplicateSpiderColumn       = errors.New("duplicate spider sheet column")
+	ErrTooManySpiderColumns        = errors.New("too many spider sheet columns")
+	ErrSpiderColumnNoTemplate      = errors.New("spider sheet column doesn't have a template")
+	ErrSpiderTemplateUnknownColumn = errors.New("spider sheet template for a column that isn't present")
+)
+
+// Keywords of the properties of an entity that can be used in the templates of the spider sheet, in
+// addition to the entity's attributes.
+const (
+	spiderIdKeyword    = "ID"
+	spiderTypeKeyword  = "TYPE"
+	spiderIconKeyword  = "ICON"
+	spiderLabelKeyword = "LABEL"
+	spiderSeedKeyword  = "SEED"
+)
+
+// Columns of the spider sheet that are mapped to the properties of an entity in i2
+const (
+	spiderIdColumn    = "ID"
+	spiderTypeColumn  = "Type"
+	spiderIconColumn  = "Icon"
+	spiderLabelColumn = "Label"
+	spiderSeedColumn  = "Seed"
+)
+
+// Maximum length of the name of an Excel sheet and the characters that aren't allowed in it
+const (
+	maxSheetNameLength     = 31
+	illegalSheetCharacters = `[]:*?/\`
+)
+
+// Prefix of the name of the sheet of each step of spidering, e.g. "Step 1"
+const spiderStepSheetPrefix = "Step "
+
+// Columns of the spider sheet and their templates if they aren't configured
+var (
+	defaultSpiderColumns = []string{spiderIdColumn, spiderTypeColumn, spiderIconColumn,
+		spiderLabelColumn, spiderSeedColumn}
+
+	defaultSpiderTemplates = map[string]string{
+		spiderIdColumn:    "<" + spiderIdKeyword + ">",
+		spiderTypeColumn:  "<" + spiderTypeKeyword + ">",
+		spiderIconColumn:  "<" + spiderIconKeyword + ">",
+		spiderLabelColumn: "<" + spiderLabelKeyword + ">",
+		spiderSeedColumn:  "<" + spiderSeedKeyword + ">",
+	}
 )
 
 type SpiderEntityConfig struct {
@@ -25,11 +77,107 @@ type SpiderEntityConfig struct {
 	Label string `json:"label"` // Label to use in i2
 }
 
+// SpiderSheetConfig is the optional layout of the sheet of a spider chart, so that it can match a
+// local i2 import specification. The value of each column of an entity is made from a template
+// containing keywords, as per the shortest path chart.
+type SpiderSheetConfig struct {
+	Name      string                       `json:"name"`      // Name of the sheet
+	Columns   []string                     `json:"columns"`   // Ordered list of columns for each entity
+	Templates map[string]string            `json:"templates"` // Template of each column
+	Entities  map[string]map[string]string `json:"entities"`  // Templates that replace those of an entity type
+}
+
+// withDefaults returns the sheet config with empty fields set to their defaults.
+func (c SpiderSheetConfig) withDefaults() SpiderSheetConfig {
+	if len(c.Name) == 0 {
+		c.Name = excelSheetName
+	}
+
+	if len(c.Columns) == 0 {
+		c.Columns = defaultSpiderColumns
+	}
+
+	templates := map[string]string{}
+	for column, template := range defaultSpiderTemplates {
+		templates[column] = template
+	}
+	for column, template := range c.Templates {
+		templates[column] = template
+	}
+	c.Templates = templates
+
+	return c
+}
+
+// validate the sheet config (after the defaults have been set).
+func (c SpiderSheetConfig) validate() error {
+
+	if len(c.Name) > maxSheetNameLength || strings.ContainsAny(c.Name, illegalSheetCharacters) ||
+		strings.HasPrefix(c.Name, spiderStepSheetPrefix) {
+		return fmt.Errorf("%w: %v", ErrInvalidSpiderSheetName, c.Name)
+	}
+
+	if len(c.Columns)*2 > len(columnLetters) {
+		return fmt.Errorf("%w: %v", ErrTooManySpiderColumns, len(c.Columns))
+	}
+
+	if columnIndex(c.Columns, spiderIdColumn) == -1 {
+		return ErrSpiderIdColumnMissing
+	}
+
+	seen := set.NewSet[string]()
+	for _, column := range c.Columns {
+		if seen.Has(column) {
+			return fmt.Errorf("%w: %v", ErrDuplicateSpiderColumn, column)
+		}
+		seen.Add(column)
+
+		// Each column needs a template for entities whose types don't have one
+		if _, found := c.Templates[column]; !found {
+			return fmt.Errorf("%w: %v", ErrSpiderColumnNoTemplate, column)
+		}
+	}
+
+	for entityType, templates := range c.Entities {
+		for column := range templates {
+			if !seen.Has(column) {
+				return fmt.Errorf("%w: %v (entity type %v)", ErrSpiderTemplateUnknownColumn,
+					column, entityType)
+			}
+		}
+	}
+
+	return nil
+}
+
+// template of the column for an entity of the type.
+func (c SpiderSheetConfig) template(entityType string, column string) string {
+	if template, found := c.Entities[entityType][column]; found {
+		return template
+	}
+
+	return c.Templates[column]
+}
+
+// header row of the sheet, where each column is suffixed with the end of the link (1 or 2).
+func (c SpiderSheetConfig) header() []string {
+	row := []string{}
+
+	for end := 1; end <= 2; end++ {
+		for _, column := range c.Columns {
+			row = append(row, column+"-"+strconv.Itoa(end))
+		}
+	}
+
+	return row
+}
+
 type SpiderI2ChartConfig struct {
 	EntityConfig           map[string]SpiderEntityConfig `json:"entities"` // Key is the entity type
 	UnknownEntityTypeIcon  string                        `json:"unknownEntityTypeIcon"`
 	UnknownEntityTypeLabel string                        `json:"unknownEntityTypeLabel"`
 	MissingAttribute       string                        `json:"missingAttribute"`
+	Sheet                  SpiderSheetConfig             `json:"sheet"` // Optional layout of the sheet
 }
 
 // readSpiderI2ChartConfig reads the i2 chart config for spidering from a JSON file.
@@ -68,7 +216,9 @@ func readSpiderI2ChartConfig(filepath string) (*SpiderI2ChartConfig, error) {
 
 type SpiderChartBuilder struct {
 	config    SpiderI2ChartConfig
+	sheet     SpiderSheetConfig              // Layout of the sheet with the defaults set
 	bipartite graphstore.BipartiteGraphStore // Bipartite store
+	redactor  *redaction.Redactor            // Redacts attributes in the chart (optional)
 }
 
 func NewSpiderChartBuilder(filepath string) (*SpiderChartBuilder, error) {
@@ -79,11 +229,22 @@ func NewSpiderChartBuilder(filepath string) (*SpiderChartBuilder, error) {
 		return nil, err
 	}
 
+	sheet := config.Sheet.withDefaults()
+	if err := sheet.validate(); err != nil {
+		return nil, err
+	}
+
 	return &SpiderChartBuilder{
 		config: *config,
+		sheet:  sheet,
 	}, nil
 }
 
+// SheetName is the name of the sheet of the Excel file holding the chart.
+func (s *SpiderChartBuilder) SheetName() string {
+	return s.sheet.Name
+}
+
 // SetBipartite graph store used by the i2 chart builder.
 func (s *SpiderChartBuilder) SetBipartite(bipartite graphstore.BipartiteGraphStore) {
 	logging.Logger.Info().
@@ -105,6 +266,34 @@ type EntityForI2 struct {
 	entityIcon   string
 	entityLabel  string
 	isSeedEntity string
+	attributes   map[string]string // Attributes of the entity for the sheet's templates
+}
+
+// keywords of the entity that can be used in the templates of the sheet.
+func (e *EntityForI2) keywords() map[string]string {
+	keywords := mergeKeywords(e.attributes, nil)
+	keywords[spiderIdKeyword] = e.entityId
+	keywords[spiderTypeKeyword] = e.entityType
+	keywords[spiderIconKeyword] = e.entityIcon
+	keywords[spiderLabelKeyword] = e.entityLabel
+	keywords[spiderSeedKeyword] = e.isSeedEntity
+	return keywords
+}
+
+// fields of the entity for the columns of the sheet.
+func (e *EntityForI2) fields(sheet SpiderSheetConfig, missingAttribute string) ([]string, error) {
+	keywords := e.keywords()
+
+	fields := make([]string, len(sheet.Columns))
+	for idx, column := range sheet.Columns {
+		field, err := Substitute(sheet.template(e.entityType, column), keywords, missingAttribute)
+		if err != nil {
+			return nil, err
+		}
+		fields[idx] = field
+	}
+
+	return fields, nil
 }
 
 type RowForI2 struct {
@@ -127,6 +316,22 @@ func (r *RowForI2) Serialise() []string {
 	}
 }
 
+// serialiseForSheet serialises the row with the layout of the sheet.
+func (r *RowForI2) serialiseForSheet(sheet SpiderSheetConfig, missingAttribute string) ([]string, error) {
+
+	fields1, err := r.entity1.fields(sheet, missingAttribute)
+	if err != nil {
+		return nil, err
+	}
+
+	fields2, err := r.entity2.fields(sheet, missingAttribute)
+	if err != nil {
+		return nil, err
+	}
+
+	return append(fields1, fields2...), nil
+}
+
 func makeEntityForI2(bipartite graphstore.BipartiteGraphStore, entityId string,
 	entityIsSeed bool, config SpiderI2ChartConfig) (EntityForI2, error) {
 
@@ -176,6 +381,7 @@ func makeEntityForI2(bipartite graphstore.BipartiteGraphStore, entityId string,
 		entityIcon:   entityIcon,
 		entityLabel:  entityLabel,
 		isSeedEntity: entitySeed,
+		attributes:   entity.Attributes,
 	}, nil
 }
 
@@ -213,8 +419,9 @@ func makeSpiderRow(bipartite graphstore.BipartiteGraphStore, entityId string,
 }
 
 // Build the rows of the i2 chart.
-// The structure is:
-//   entity ID, type, icon, label, seed, entity ID, type, icon, label, seed
+// The structure is the columns of the sheet for each entity, which by default is:
+//
+//	entity ID, type, icon, label, seed, entity ID, type, icon, label, seed
 func (s *SpiderChartBuilder) Build(results *spider.SpiderResults) ([][]string, error) {
 
 	if s.bipartite == nil {
@@ -236,23 +443,7 @@ func (s *SpiderChartBuilder) Build(results *spider.SpiderResults) ([][]string, e
 	rows := [][]string{}
 
 	// Add the header row
-	headerRow := RowForI2{
-		entity1: EntityForI2{
-			entityId:     "ID-1",
-			entityType:   "Type-1",
-			entityIcon:   "Icon-1",
-			entityLabel:  "Label-1",
-			isSeedEntity: "Seed-1",
-		},
-		entity2: EntityForI2{
-			entityId:     "ID-2",
-			entityType:   "Type-2",
-			entityIcon:   "Icon-2",
-			entityLabel:  "Label-2",
-			isSeedEntity: "Seed-2",
-		},
-	}
-	rows = append(rows, headerRow.Serialise())
+	rows = append(rows, s.sheet.header())
 
 	// Get a sorted list of entity IDs to ensure the rows are always in the same order
 	unsortedEntityIds, err := results.Subgraph.EntityIds()
@@ -279,7 +470,7 @@ func (s *SpiderChartBuilder) Build(results *spider.SpiderResults) ([][]string, e
 			entityIsSeed := results.SeedEntities.Has(entityId)
 			adjEntityIsSeed := results.SeedEntities.Has(adjEntityId)
 
-			row, err := makeSpiderRow(s.bipartite,
+			row, err := makeSpiderRow(s.store(),
 				entityId, entityIsSeed,
 				adjEntityId, adjEntityIsSeed,
 				s.config)
@@ -288,9 +479,60 @@ func (s *SpiderChartBuilder) Build(results *spider.SpiderResults) ([][]string, e
 				return nil, err
 			}
 
-			rows = append(rows, row.Serialise())
+			fields, err := row.serialiseForSheet(s.sheet, s.config.MissingAttribute)
+			if err != nil {
+				return nil, err
+			}
+
+			rows = append(rows, fields)
 		}
 	}
 
 	return rows, nil
 }
+
+// BuildSteps builds a sheet for each step of spidering, holding the entities first reached on the
+// step, where each row is the link from the entity that introduced the new entity (in the columns
+// of the first end) to the new entity. A step that didn't reach any new entities only has the
+// header row.
+func (s *SpiderChartBuilder) BuildSteps(results *spider.SpiderResults) ([]Sheet, error) {
+
+	if s.bipartite == nil {
+		return nil, ErrBipartiteIsNil
+	}
+
+	if results == nil {
+		return nil, ErrSpiderResultsIsNil
+	}
+
+	sheets := []Sheet{}
+	for idx, stepEntities := range results.Steps {
+
+		rows := [][]string{s.sheet.header()}
+		for _, stepEntity := range stepEntities {
+
+			row, err := makeSpiderRow(s.store(),
+				stepEntity.IntroducedBy, results.SeedEntities.Has(stepEntity.IntroducedBy),
+				stepEntity.EntityId, results.SeedEntities.Has(stepEntity.EntityId),
+				s.config)
+
+			if err != nil {
+				return nil, err
+			}
+
+			fields, err := row.serialiseForSheet(s.sheet, s.config.MissingAttribute)
+			if err != nil {
+				return nil, err
+			}
+
+			rows = append(rows, fields)
+		}
+
+		sheets = append(sheets, Sheet{
+			Name: spiderStepSheetPrefix + strconv.Itoa(idx+1),
+			Rows: rows,
+		})
+	}
+
+	return sheets, nil
+}
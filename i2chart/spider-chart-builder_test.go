@@ -116,6 +116,7 @@ func TestMakeEntityForI2(t *testing.T) {
 				entityIcon:   "Anonymous",
 				entityLabel:  "Bob Smith",
 				isSeedEntity: "FALSE",
+				attributes:   map[string]string{"Full Name": "Bob Smith"},
 			},
 			errorExpected: false,
 		},
@@ -139,6 +140,7 @@ func TestMakeEntityForI2(t *testing.T) {
 				entityIcon:   "Anonymous",
 				entityLabel:  "Bob Smith",
 				isSeedEntity: "TRUE",
+				attributes:   map[string]string{"Full Name": "Bob Smith"},
 			},
 			errorExpected: false,
 		},
@@ -163,6 +165,7 @@ func TestMakeEntityForI2(t *testing.T) {
 				entityIcon:   "UNKNOWN-ICON",
 				entityLabel:  "UNKNOWN-LABEL",
 				isSeedEntity: "FALSE",
+				attributes:   map[string]string{"Full Name": "Bob Smith"},
 			},
 			errorExpected: false,
 		},
@@ -263,6 +266,7 @@ func TestMakeSpiderRow(t *testing.T) {
 					entityIcon:   "Anonymous",
 					entityLabel:  "Bob Smith",
 					isSeedEntity: "TRUE",
+					attributes:   map[string]string{"Full Name": "Bob Smith"},
 				},
 				entity2: EntityForI2{
 					entityId:     "e-2",
@@ -270,6 +274,7 @@ func TestMakeSpiderRow(t *testing.T) {
 					entityIcon:   "Anonymous",
 					entityLabel:  "Sally Jones",
 					isSeedEntity: "FALSE",
+					attributes:   map[string]string{"Full Name": "Sally Jones"},
 				},
 			},
 			errorExpected: false,
@@ -384,3 +389,139 @@ func TestBuildChart(t *testing.T) {
 		}
 	}
 }
+
+func TestSpiderSheetConfig(t *testing.T) {
+
+	// Defaults
+	sheet := SpiderSheetConfig{}.withDefaults()
+	assert.NoError(t, sheet.validate())
+	assert.Equal(t, excelSheetName, sheet.Name)
+	assert.Equal(t, defaultSpiderColumns, sheet.Columns)
+	assert.Equal(t, []string{"ID-1", "Type-1", "Icon-1", "Label-1", "Seed-1",
+		"ID-2", "Type-2", "Icon-2", "Label-2", "Seed-2"}, sheet.header())
+
+	testCases := []struct {
+		sheet    SpiderSheetConfig
+		expected error
+	}{
+		{
+			sheet:    SpiderSheetConfig{Name: "Spider[1]"},
+			expected: ErrInvalidSpiderSheetName,
+		},
+		{
+			sheet:    SpiderSheetConfig{Name: "A sheet name that is far too long for Excel"},
+			expected: ErrInvalidSpiderSheetName,
+		},
+		{
+			sheet:    SpiderSheetConfig{Name: "Step 1"},
+			expected: ErrInvalidSpiderSheetName,
+		},
+		{
+			sheet:    SpiderSheetConfig{Columns: []string{"Label", "Seed"}},
+			expected: ErrSpiderIdColumnMissing,
+		},
+		{
+			sheet:    SpiderSheetConfig{Columns: []string{"ID", "Label", "ID"}},
+			expected: ErrDuplicateSpiderColumn,
+		},
+		{
+			sheet:    SpiderSheetConfig{Columns: []string{"ID", "Name"}},
+			expected: ErrSpiderColumnNoTemplate,
+		},
+		{
+			sheet: SpiderSheetConfig{
+				Columns:  []string{"ID", "Label"},
+				Entities: map[string]map[string]string{"Person": {"Name": "<Full Name>"}},
+			},
+			expected: ErrSpiderTemplateUnknownColumn,
+		},
+		{
+			sheet: SpiderSheetConfig{Columns: []string{"ID", "Type", "Icon", "Label", "Seed",
+				"ID-a", "ID-b", "ID-c", "ID-d", "ID-e", "ID-f", "ID-g", "ID-h", "ID-i"}},
+			expected: ErrTooManySpiderColumns,
+		},
+	}
+
+	for _, testCase := range testCases {
+		assert.ErrorIs(t, testCase.sheet.withDefaults().validate(), testCase.expected)
+	}
+
+	// The templates of an entity type replace those for all entity types
+	sheet = SpiderSheetConfig{
+		Templates: map[string]string{"Label": "<LABEL>!"},
+		Entities:  map[string]map[string]string{"Person": {"Label": "<Full Name>"}},
+	}.withDefaults()
+	assert.Equal(t, "<Full Name>", sheet.template("Person", "Label"))
+	assert.Equal(t, "<LABEL>!", sheet.template("Address", "Label"))
+	assert.Equal(t, "<ID>", sheet.template("Address", "ID"))
+}
+
+func TestBuildChartWithSheet(t *testing.T) {
+
+	_, err := NewSpiderChartBuilder("./test-data/does-not-exist.json")
+	assert.Error(t, err)
+
+	s, err := NewSpiderChartBuilder("./test-data/spider-i2-config-sheet.json")
+	assert.NoError(t, err)
+	assert.Equal(t, "Spider", s.SheetName())
+	s.SetBipartite(makeBipartiteStore(t))
+
+	subgraph := graphstore.NewInMemoryUnipartiteGraphStore()
+	subgraph.AddUndirected("e-1", "e-2")
+
+	rows, err := s.Build(&spider.SpiderResults{
+		NumberSteps:          1,
+		Subgraph:             subgraph,
+		SeedEntities:         set.NewPopulatedSet("e-1"),
+		SeedEntitiesNotFound: set.NewSet[string](),
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"Label-1", "ID-1", "Name-1", "Seed-1", "Label-2", "ID-2", "Name-2", "Seed-2"},
+		{"Bob Smith (e-1)", "e-1", "Bob Smith", "TRUE", "Sally Jones (e-2)", "e-2", "Sally Jones", "FALSE"},
+	}, rows)
+}
+
+func TestBuildSteps(t *testing.T) {
+
+	s, err := NewSpiderChartBuilder("./test-data/spider-i2-config-1.json")
+	assert.NoError(t, err)
+
+	results := &spider.SpiderResults{
+		NumberSteps:          2,
+		Subgraph:             graphstore.NewInMemoryUnipartiteGraphStore(),
+		SeedEntities:         set.NewPopulatedSet("e-1"),
+		SeedEntitiesNotFound: set.NewSet[string](),
+		Steps: [][]spider.StepEntity{
+			{{EntityId: "e-2", IntroducedBy: "e-1"}, {EntityId: "e-3", IntroducedBy: "e-1"}},
+			{},
+		},
+	}
+
+	// Preconditions
+	_, err = s.BuildSteps(results)
+	assert.ErrorIs(t, err, ErrBipartiteIsNil)
+
+	s.SetBipartite(makeBipartiteStore(t))
+	_, err = s.BuildSteps(nil)
+	assert.ErrorIs(t, err, ErrSpiderResultsIsNil)
+
+	header := []string{"ID-1", "Type-1", "Icon-1", "Label-1", "Seed-1", "ID-2", "Type-2", "Icon-2", "Label-2", "Seed-2"}
+
+	sheets, err := s.BuildSteps(results)
+	assert.NoError(t, err)
+	assert.Equal(t, []Sheet{
+		{
+			Name: "Step 1",
+			Rows: [][]string{
+				header,
+				{"e-1", "Person", "Anonymous", "Bob Smith", "TRUE", "e-2", "Person", "Anonymous", "Sally Jones", "FALSE"},
+				{"e-1", "Person", "Anonymous", "Bob Smith", "TRUE", "e-3", "Person", "Anonymous", "Sandra Jackson", "FALSE"},
+			},
+		},
+		{
+			Name: "Step 2",
+			Rows: [][]string{header},
+		},
+	}, sheets)
+}
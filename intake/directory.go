@@ -0,0 +1,162 @@
+package intake
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+)
+
+const componentName = "intake"
+
+// Constants of a directory queue
+const (
+	DefaultPollInterval = time.Second // Interval between looking for new messages in the inbox
+	messageExtension    = ".json"     // Extension of the files of the messages
+)
+
+var (
+	ErrInboxInvalid        = errors.New("inbox of the queue must be a folder")
+	ErrEventsPathEmpty     = errors.New("path of the events of the queue is empty")
+	ErrInvalidPollInterval = errors.New("poll interval of the queue must be positive")
+)
+
+// A DirectoryQueue receives each message from a JSON file in an inbox folder and appends each
+// event as a line of JSON to a file. A file is deleted from the inbox when its message is
+// acknowledged, so the messages that were in progress are received again after a restart.
+//
+// A file should be written under another extension and then renamed to .json, so that a partly
+// written message isn't received. The messages are received in the order of their filenames.
+type DirectoryQueue struct {
+	inbox        string              // Folder of the message files
+	eventsPath   string              // File to which the events are appended
+	pollInterval time.Duration       // Interval between looking for new messages
+	received     map[string]struct{} // Messages received and not yet acknowledged
+	lock         sync.Mutex          // Mutex for the received messages and the events file
+}
+
+// NewDirectoryQueue with messages in the inbox folder and events appended to the file.
+func NewDirectoryQueue(inbox string, eventsPath string, pollInterval time.Duration) (
+	*DirectoryQueue, error) {
+
+	// Preconditions
+	info, err := os.Stat(inbox)
+	if err != nil || !info.IsDir() {
+		return nil, ErrInboxInvalid
+	}
+
+	if len(eventsPath) == 0 {
+		return nil, ErrEventsPathEmpty
+	}
+
+	if pollInterval <= 0 {
+		return nil, ErrInvalidPollInterval
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("inbox", inbox).
+		Str("events", eventsPath).
+		Str("pollInterval", pollInterval.String()).
+		Msg("Making the directory queue")
+
+	return &DirectoryQueue{
+		inbox:        inbox,
+		eventsPath:   eventsPath,
+		pollInterval: pollInterval,
+		received:     map[string]struct{}{},
+	}, nil
+}
+
+// next message in the inbox that hasn't been received. Returns false if there isn't one.
+func (q *DirectoryQueue) next() (Message, bool, error) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	entries, err := os.ReadDir(q.inbox)
+	if err != nil {
+		return Message{}, false, err
+	}
+
+	// The entries are sorted by filename
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, messageExtension) {
+			continue
+		}
+
+		if _, found := q.received[name]; found {
+			continue
+		}
+
+		body, err := os.ReadFile(filepath.Join(q.inbox, name))
+		if err != nil {
+			return Message{}, false, err
+		}
+
+		q.received[name] = struct{}{}
+		return Message{Id: name, Body: body}, true, nil
+	}
+
+	return Message{}, false, nil
+}
+
+// Receive the next message from the inbox, waiting until there is one or the context is done.
+func (q *DirectoryQueue) Receive(ctx context.Context) (Message, error) {
+
+	ticker := time.NewTicker(q.pollInterval)
+	defer ticker.Stop()
+
+	for {
+		msg, found, err := q.next()
+		if err != nil || found {
+			return msg, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return Message{}, ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Ack the message by deleting its file from the inbox.
+func (q *DirectoryQueue) Ack(ctx context.Context, msg Message) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if _, found := q.received[msg.Id]; !found {
+		return ErrMessageNotReceived
+	}
+
+	if err := os.Remove(filepath.Join(q.inbox, msg.Id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	delete(q.received, msg.Id)
+	return nil
+}
+
+// Publish the event by appending it as a line to the events file.
+func (q *DirectoryQueue) Publish(ctx context.Context, event []byte) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	file, err := os.OpenFile(q.eventsPath, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(append(append([]byte{}, event...), '\n')); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,85 @@
+package intake
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewDirectoryQueue(t *testing.T) {
+
+	folder := t.TempDir()
+	eventsPath := filepath.Join(folder, "events.jsonl")
+
+	_, err := NewDirectoryQueue(filepath.Join(folder, "missing"), eventsPath, time.Second)
+	assert.ErrorIs(t, err, ErrInboxInvalid)
+
+	_, err = NewDirectoryQueue(folder, "", time.Second)
+	assert.ErrorIs(t, err, ErrEventsPathEmpty)
+
+	_, err = NewDirectoryQueue(folder, eventsPath, 0)
+	assert.ErrorIs(t, err, ErrInvalidPollInterval)
+}
+
+func TestDirectoryQueue(t *testing.T) {
+
+	inbox := t.TempDir()
+	eventsPath := filepath.Join(t.TempDir(), "events.jsonl")
+
+	queue, err := NewDirectoryQueue(inbox, eventsPath, 10*time.Millisecond)
+	assert.NoError(t, err)
+
+	// Files that aren't messages are ignored
+	assert.NoError(t, os.WriteFile(filepath.Join(inbox, "b.json"), []byte(`{"requestId": "b"}`), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(inbox, "a.json"), []byte(`{"requestId": "a"}`), 0644))
+	assert.NoError(t, os.WriteFile(filepath.Join(inbox, "c.json.tmp"), []byte(`{`), 0644))
+
+	// The messages are received in filename order
+	ctx := context.Background()
+	msg1, err := queue.Receive(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, Message{Id: "a.json", Body: []byte(`{"requestId": "a"}`)}, msg1)
+
+	msg2, err := queue.Receive(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "b.json", msg2.Id)
+
+	// There isn't another message
+	timeout, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	_, err = queue.Receive(timeout)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// A message is received once it is written
+	go func() {
+		time.Sleep(20 * time.Millisecond)
+		os.Rename(filepath.Join(inbox, "c.json.tmp"), filepath.Join(inbox, "c.json"))
+	}()
+	msg3, err := queue.Receive(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "c.json", msg3.Id)
+
+	// Acknowledging a message deletes its file
+	assert.NoError(t, queue.Ack(ctx, msg1))
+	assert.NoFileExists(t, filepath.Join(inbox, "a.json"))
+	assert.ErrorIs(t, queue.Ack(ctx, msg1), ErrMessageNotReceived)
+
+	// A message that wasn't acknowledged is received again by a new queue
+	restarted, err := NewDirectoryQueue(inbox, eventsPath, 10*time.Millisecond)
+	assert.NoError(t, err)
+	msg, err := restarted.Receive(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, "b.json", msg.Id)
+
+	// The events are appended as lines
+	assert.NoError(t, queue.Publish(ctx, []byte(`{"requestId":"a"}`)))
+	assert.NoError(t, queue.Publish(ctx, []byte(`{"requestId":"b"}`)))
+
+	content, err := os.ReadFile(eventsPath)
+	assert.NoError(t, err)
+	assert.Equal(t, "{\"requestId\":\"a\"}\n{\"requestId\":\"b\"}\n", string(content))
+}
@@ -0,0 +1,93 @@
+// Package intake defines the messages of a queue from which shortest path jobs are submitted and
+// to which their completion events are published, so that automated systems can enqueue many
+// small path queries without calling the HTTP endpoints.
+package intake
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInvalidJobRequest  = errors.New("invalid job request")
+	ErrMessageNotReceived = errors.New("message was not received from the queue")
+)
+
+// A Message received from a queue.
+type Message struct {
+	Id   string // Identifier of the message in the queue
+	Body []byte // JSON job request
+}
+
+// A Queue of job requests and completion events. A message that is received but not acknowledged
+// is redelivered by the queue, e.g. after a restart, so a job is submitted at least once.
+type Queue interface {
+
+	// Receive the next message, blocking until there is one or the context is done.
+	Receive(ctx context.Context) (Message, error)
+
+	// Ack the message, i.e. it has been handled and mustn't be redelivered.
+	Ack(ctx context.Context, msg Message) error
+
+	// Publish the (JSON) completion event of a job.
+	Publish(ctx context.Context, event []byte) error
+}
+
+// A Dataset is a named set of entity IDs of a job request.
+type Dataset struct {
+	Name      string   `json:"name"`      // Name of the dataset
+	EntityIds []string `json:"entityIds"` // Entity IDs of the dataset
+}
+
+// A JobRequest is a shortest path job read from a queue.
+type JobRequest struct {
+	RequestId          string    `json:"requestId"`          // Identifier of the request, returned in the event
+	NumberHops         int       `json:"numberHops"`         // Maximum number of hops
+	Datasets           []Dataset `json:"datasets"`           // Datasets from which to find paths
+	RetryWithFewerHops bool      `json:"retryWithFewerHops"` // Retry with one fewer hop if there are too many paths
+	Directed           bool      `json:"directed"`           // Only follow the edges in their direction
+	ExcludedEntityIds  []string  `json:"excludedEntityIds"`  // Entity IDs that the paths mustn't pass through
+	Waypoints          []string  `json:"waypoints"`          // Entity IDs that the paths must pass through one of
+	PathMatrix         bool      `json:"pathMatrix"`         // Output a matrix of the connectivity of each pair of entities
+	MinDocuments       int       `json:"minDocuments"`       // Minimum number of documents supporting each link (0 for any)
+	Temporal           bool      `json:"temporal"`           // Only find paths whose links have documents in date order
+	TimeoutMinutes     int       `json:"timeoutMinutes"`     // Timeout of the job (0 for the server's timeout)
+	PathFilter         string    `json:"pathFilter"`         // Keep the paths through an entity matching the expression
+}
+
+// ParseJobRequest from the JSON body of a message. Fields that aren't known are rejected, so that
+// a misspelt option isn't silently ignored.
+func ParseJobRequest(body []byte) (*JobRequest, error) {
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.DisallowUnknownFields()
+
+	var request JobRequest
+	if err := decoder.Decode(&request); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidJobRequest, err)
+	}
+
+	if request.MinDocuments < 0 || request.TimeoutMinutes < 0 {
+		return nil, fmt.Errorf("%w: negative minimum documents or timeout", ErrInvalidJobRequest)
+	}
+
+	return &request, nil
+}
+
+// A CompletionEvent of a job submitted from a queue. An event is published for a request that was
+// rejected, in which case it doesn't have a GUID.
+type CompletionEvent struct {
+	RequestId string   `json:"requestId"`           // Identifier of the request
+	Guid      string   `json:"guid,omitempty"`      // GUID of the job (blank if the request was rejected)
+	State     string   `json:"state"`               // End state of the job (or rejected)
+	Error     string   `json:"error,omitempty"`     // Reason the job failed or was rejected
+	Warnings  []string `json:"warnings,omitempty"`  // Warnings, e.g. the job was retried
+	Download  string   `json:"download,omitempty"`  // URL of the results (relative to the host)
+	Published []string `json:"published,omitempty"` // URLs of the result files in object storage
+}
+
+// RejectedState of a request that couldn't be submitted as a job
+const RejectedState = "rejected"
@@ -0,0 +1,38 @@
+package intake
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseJobRequest(t *testing.T) {
+
+	request, err := ParseJobRequest([]byte(`{
+		"requestId": "r-1",
+		"numberHops": 2,
+		"datasets": [{"name": "Dataset-1", "entityIds": ["e-1", "e-2"]}],
+		"directed": true,
+		"timeoutMinutes": 5
+	}`))
+	assert.NoError(t, err)
+	assert.Equal(t, &JobRequest{
+		RequestId:      "r-1",
+		NumberHops:     2,
+		Datasets:       []Dataset{{Name: "Dataset-1", EntityIds: []string{"e-1", "e-2"}}},
+		Directed:       true,
+		TimeoutMinutes: 5,
+	}, request)
+
+	// Not JSON
+	_, err = ParseJobRequest([]byte("e-1, e-2"))
+	assert.ErrorIs(t, err, ErrInvalidJobRequest)
+
+	// Misspelt field
+	_, err = ParseJobRequest([]byte(`{"numberHop": 2}`))
+	assert.ErrorIs(t, err, ErrInvalidJobRequest)
+
+	// Negative timeout
+	_, err = ParseJobRequest([]byte(`{"numberHops": 2, "timeoutMinutes": -1}`))
+	assert.ErrorIs(t, err, ErrInvalidJobRequest)
+}
@@ -0,0 +1,275 @@
+package job
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Limits on the text of a case
+const (
+	MaxCaseNameLength = 100  // Maximum number of characters in the name of a case
+	MaxCaseNoteLength = 4000 // Maximum number of characters in a note
+)
+
+var (
+	ErrCaseNameEmpty      = errors.New("case name is empty")
+	ErrCaseNameTooLong    = errors.New("case name is too long")
+	ErrCaseNotFound       = errors.New("case not found")
+	ErrCaseNoteEmpty      = errors.New("case note is empty")
+	ErrCaseNoteTooLong    = errors.New("case note is too long")
+	ErrCaseJobGuidIsEmpty = errors.New("case job GUID is empty")
+)
+
+// A CaseJob is a shortest path or spider job attached to a case.
+type CaseJob struct {
+	Guid   string    `json:"guid"`   // GUID of the job
+	Spider bool      `json:"spider"` // Is the job a spider job?
+	Added  time.Time `json:"added"`  // When the job was attached
+}
+
+// A CaseNote is free text added to a case.
+type CaseNote struct {
+	Text  string    `json:"text"`  // Text of the note
+	Added time.Time `json:"added"` // When the note was added
+}
+
+// A Case groups the jobs and notes of an investigation.
+type Case struct {
+	Id      string     `json:"id"`      // Unique identifier of the case
+	Name    string     `json:"name"`    // Name given by the user
+	Created time.Time  `json:"created"` // When the case was created
+	Jobs    []CaseJob  `json:"jobs"`    // Jobs in the order they were attached
+	Notes   []CaseNote `json:"notes"`   // Notes in the order they were added
+}
+
+// copy of the case that doesn't share the jobs and notes.
+func (c Case) copy() Case {
+	c.Jobs = append([]CaseJob{}, c.Jobs...)
+	c.Notes = append([]CaseNote{}, c.Notes...)
+	return c
+}
+
+// A CaseStore holds the cases. If the store has a file, the cases are persisted in it as JSON
+// whenever they change.
+type CaseStore struct {
+	filepath string          // Location of the JSON file (empty if not persisted)
+	cases    map[string]Case // Case ID to case
+	lock     sync.RWMutex    // Mutex for the cases
+}
+
+// NewCaseStore backed by the JSON file at filepath. The cases in the file are read if it exists. If
+// the filepath is empty, the cases are only held in memory.
+func NewCaseStore(filepath string) (*CaseStore, error) {
+
+	store := &CaseStore{
+		filepath: filepath,
+		cases:    map[string]Case{},
+		lock:     sync.RWMutex{},
+	}
+
+	if len(filepath) == 0 {
+		return store, nil
+	}
+
+	content, err := os.ReadFile(filepath)
+	if os.IsNotExist(err) {
+		return store, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	cases := []Case{}
+	if err := json.Unmarshal(content, &cases); err != nil {
+		return nil, err
+	}
+
+	for _, c := range cases {
+		store.cases[c.Id] = c
+	}
+
+	return store, nil
+}
+
+// validateText returns the text without surrounding whitespace if it isn't empty and isn't longer
+// than the maximum length.
+func validateText(text string, maxLength int, errEmpty error, errTooLong error) (string, error) {
+	text = strings.TrimSpace(text)
+
+	if len(text) == 0 {
+		return "", errEmpty
+	}
+
+	if len([]rune(text)) > maxLength {
+		return "", errTooLong
+	}
+
+	return text, nil
+}
+
+// list the cases, most recently created first. The read lock must be held.
+func (s *CaseStore) list() []Case {
+	cases := make([]Case, 0, len(s.cases))
+	for _, c := range s.cases {
+		cases = append(cases, c.copy())
+	}
+
+	sort.Slice(cases, func(i, j int) bool {
+		if cases[i].Created.Equal(cases[j].Created) {
+			return cases[i].Id < cases[j].Id
+		}
+		return cases[i].Created.After(cases[j].Created)
+	})
+
+	return cases
+}
+
+// persist the cases to the JSON file (if there is one). The file is replaced atomically, so a
+// failure doesn't lose the existing cases. The lock must be held.
+func (s *CaseStore) persist() error {
+	if len(s.filepath) == 0 {
+		return nil
+	}
+
+	content, err := json.MarshalIndent(s.list(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tempFile, err := os.CreateTemp(filepath.Dir(s.filepath), filepath.Base(s.filepath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempFile.Name())
+
+	if _, err := tempFile.Write(content); err != nil {
+		tempFile.Close()
+		return err
+	}
+
+	if err := tempFile.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tempFile.Name(), s.filepath)
+}
+
+// update the case with the ID using the function, restoring the case if it can't be persisted.
+func (s *CaseStore) update(id string, fn func(c *Case)) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	previous, found := s.cases[id]
+	if !found {
+		return ErrCaseNotFound
+	}
+
+	updated := previous.copy()
+	fn(&updated)
+	s.cases[id] = updated
+
+	if err := s.persist(); err != nil {
+		s.cases[id] = previous
+		return err
+	}
+
+	return nil
+}
+
+// Create a case with the name.
+func (s *CaseStore) Create(name string) (Case, error) {
+
+	// Precondition
+	name, err := validateText(name, MaxCaseNameLength, ErrCaseNameEmpty, ErrCaseNameTooLong)
+	if err != nil {
+		return Case{}, err
+	}
+
+	c := Case{
+		Id:      uuid.New().String(),
+		Name:    name,
+		Created: time.Now().UTC(),
+		Jobs:    []CaseJob{},
+		Notes:   []CaseNote{},
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.cases[c.Id] = c
+	if err := s.persist(); err != nil {
+		delete(s.cases, c.Id)
+		return Case{}, err
+	}
+
+	return c.copy(), nil
+}
+
+// Get the case with the ID.
+func (s *CaseStore) Get(id string) (Case, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	c, found := s.cases[id]
+	if !found {
+		return Case{}, ErrCaseNotFound
+	}
+
+	return c.copy(), nil
+}
+
+// List the cases, most recently created first.
+func (s *CaseStore) List() []Case {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.list()
+}
+
+// AddJob to the case with the ID. A job that is already attached to the case isn't added again.
+func (s *CaseStore) AddJob(id string, guid string, spider bool) error {
+
+	// Precondition
+	guid = strings.TrimSpace(guid)
+	if len(guid) == 0 {
+		return ErrCaseJobGuidIsEmpty
+	}
+
+	return s.update(id, func(c *Case) {
+		for _, j := range c.Jobs {
+			if j.Guid == guid {
+				return
+			}
+		}
+
+		c.Jobs = append(c.Jobs, CaseJob{
+			Guid:   guid,
+			Spider: spider,
+			Added:  time.Now().UTC(),
+		})
+	})
+}
+
+// AddNote to the case with the ID.
+func (s *CaseStore) AddNote(id string, text string) error {
+
+	// Precondition
+	text, err := validateText(text, MaxCaseNoteLength, ErrCaseNoteEmpty, ErrCaseNoteTooLong)
+	if err != nil {
+		return err
+	}
+
+	return s.update(id, func(c *Case) {
+		c.Notes = append(c.Notes, CaseNote{
+			Text:  text,
+			Added: time.Now().UTC(),
+		})
+	})
+}
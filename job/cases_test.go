@@ -0,0 +1,100 @@
+package job
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCaseStoreInMemory(t *testing.T) {
+
+	store, err := NewCaseStore("")
+	assert.NoError(t, err)
+	assert.Equal(t, []Case{}, store.List())
+
+	// Invalid names
+	_, err = store.Create(" ")
+	assert.ErrorIs(t, err, ErrCaseNameEmpty)
+
+	_, err = store.Create(strings.Repeat("a", MaxCaseNameLength+1))
+	assert.ErrorIs(t, err, ErrCaseNameTooLong)
+
+	// Create a case
+	c, err := store.Create(" Operation A ")
+	assert.NoError(t, err)
+	assert.Equal(t, "Operation A", c.Name)
+	assert.Equal(t, []CaseJob{}, c.Jobs)
+	assert.False(t, c.Created.IsZero())
+
+	// Attach jobs, where a job is only attached once
+	assert.NoError(t, store.AddJob(c.Id, "guid-1", false))
+	assert.NoError(t, store.AddJob(c.Id, " guid-2 ", true))
+	assert.NoError(t, store.AddJob(c.Id, "guid-1", false))
+	assert.ErrorIs(t, store.AddJob(c.Id, " ", false), ErrCaseJobGuidIsEmpty)
+	assert.ErrorIs(t, store.AddJob("missing", "guid-3", false), ErrCaseNotFound)
+
+	// Add notes
+	assert.NoError(t, store.AddNote(c.Id, "First note"))
+	assert.ErrorIs(t, store.AddNote(c.Id, "  "), ErrCaseNoteEmpty)
+	assert.ErrorIs(t, store.AddNote(c.Id, strings.Repeat("a", MaxCaseNoteLength+1)),
+		ErrCaseNoteTooLong)
+	assert.ErrorIs(t, store.AddNote("missing", "Note"), ErrCaseNotFound)
+
+	c, err = store.Get(c.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(c.Jobs))
+	assert.Equal(t, "guid-1", c.Jobs[0].Guid)
+	assert.False(t, c.Jobs[0].Spider)
+	assert.Equal(t, "guid-2", c.Jobs[1].Guid)
+	assert.True(t, c.Jobs[1].Spider)
+	assert.Equal(t, 1, len(c.Notes))
+	assert.Equal(t, "First note", c.Notes[0].Text)
+
+	// The case returned is a copy
+	c.Jobs[0].Guid = "changed"
+	c2, err := store.Get(c.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, "guid-1", c2.Jobs[0].Guid)
+
+	_, err = store.Get("missing")
+	assert.ErrorIs(t, err, ErrCaseNotFound)
+}
+
+func TestCaseStorePersisted(t *testing.T) {
+
+	storeFile := filepath.Join(t.TempDir(), "cases.json")
+
+	store, err := NewCaseStore(storeFile)
+	assert.NoError(t, err)
+
+	c, err := store.Create("Operation A")
+	assert.NoError(t, err)
+	assert.NoError(t, store.AddJob(c.Id, "guid-1", false))
+	assert.NoError(t, store.AddNote(c.Id, "A note"))
+
+	// The cases are read from the file
+	store2, err := NewCaseStore(storeFile)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(store2.List()))
+
+	c2, err := store2.Get(c.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, "Operation A", c2.Name)
+	assert.Equal(t, "guid-1", c2.Jobs[0].Guid)
+	assert.Equal(t, "A note", c2.Notes[0].Text)
+
+	// Invalid file
+	assert.NoError(t, os.WriteFile(storeFile, []byte("not JSON"), 0644))
+	_, err = NewCaseStore(storeFile)
+	assert.Error(t, err)
+
+	// The folder for the file doesn't exist, so the case isn't created
+	store3, err := NewCaseStore(filepath.Join(t.TempDir(), "missing", "cases.json"))
+	assert.NoError(t, err)
+	_, err = store3.Create("Operation A")
+	assert.Error(t, err)
+	assert.Equal(t, []Case{}, store3.List())
+}
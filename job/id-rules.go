@@ -0,0 +1,202 @@
+package job
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+
+	"github.com/cdclaxton/shortest-path-web-app/i18n"
+)
+
+var (
+	ErrInvalidEntityIdRules = errors.New("invalid entity ID rules")
+	ErrInvalidEntityIds     = errors.New("invalid entity IDs")
+)
+
+// Reasons an entity ID is invalid, which are keys of the text in the language bundles
+const (
+	EntityIdTooShort = "entityIdRules.tooShort"
+	EntityIdTooLong  = "entityIdRules.tooLong"
+	EntityIdNoScheme = "entityIdRules.noScheme"
+)
+
+// An EntityIdScheme is a known format of entity ID, e.g. a person reference.
+type EntityIdScheme struct {
+	Name    string `json:"name"`    // Name of the scheme
+	Pattern string `json:"pattern"` // Regular expression that the whole ID must match
+}
+
+// EntityIdRulesConfig defines the rules that an entity ID entered by a user should pass. A length
+// of zero means there is no limit and an ID only needs to match a scheme if there are any.
+type EntityIdRulesConfig struct {
+	MinLength int              `json:"minLength"` // Minimum number of characters
+	MaxLength int              `json:"maxLength"` // Maximum number of characters
+	Schemes   []EntityIdScheme `json:"schemes"`   // Known formats of entity ID
+}
+
+// EntityIdRules check the entity IDs entered by a user, so that typos can be reported rather than
+// silently producing no results. Nil rules accept any entity ID.
+type EntityIdRules struct {
+	minLength int              // Minimum number of characters (0 for no limit)
+	maxLength int              // Maximum number of characters (0 for no limit)
+	schemes   []*regexp.Regexp // Patterns of the known formats of entity ID
+}
+
+// An InvalidEntityId is an entity ID that failed the rules.
+type InvalidEntityId struct {
+	EntityId string // Entity ID entered by the user
+	Reason   string // Key of the reason in the language bundles
+}
+
+// InvalidEntityIdsError holds the entity IDs of an entity set that failed the rules.
+type InvalidEntityIdsError struct {
+	EntitySetName string            // Name of the entity set (blank for the seed entities)
+	EntityIds     []InvalidEntityId // Entity IDs that failed the rules
+}
+
+func (e *InvalidEntityIdsError) Error() string {
+	return fmt.Sprintf("%v: %v in %v", ErrInvalidEntityIds, len(e.EntityIds), e.EntitySetName)
+}
+
+func (e *InvalidEntityIdsError) Unwrap() error {
+	return ErrInvalidEntityIds
+}
+
+// NewEntityIdRules from the config.
+func NewEntityIdRules(config EntityIdRulesConfig) (*EntityIdRules, error) {
+
+	if config.MinLength < 0 || config.MaxLength < 0 ||
+		(config.MaxLength > 0 && config.MinLength > config.MaxLength) {
+		return nil, fmt.Errorf("%w: invalid length limits", ErrInvalidEntityIdRules)
+	}
+
+	schemes := []*regexp.Regexp{}
+	for _, scheme := range config.Schemes {
+		if len(scheme.Pattern) == 0 {
+			return nil, fmt.Errorf("%w: scheme %v has no pattern", ErrInvalidEntityIdRules,
+				scheme.Name)
+		}
+
+		re, err := regexp.Compile("^(?:" + scheme.Pattern + ")$")
+		if err != nil {
+			return nil, fmt.Errorf("%w: scheme %v: %v", ErrInvalidEntityIdRules, scheme.Name, err)
+		}
+		schemes = append(schemes, re)
+	}
+
+	return &EntityIdRules{
+		minLength: config.MinLength,
+		maxLength: config.MaxLength,
+		schemes:   schemes,
+	}, nil
+}
+
+// ReadEntityIdRules from a JSON file.
+func ReadEntityIdRules(filepath string) (*EntityIdRules, error) {
+
+	bytes, err := os.ReadFile(filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	var config EntityIdRulesConfig
+	if err := json.Unmarshal(bytes, &config); err != nil {
+		return nil, err
+	}
+
+	return NewEntityIdRules(config)
+}
+
+// reason the entity ID fails the rules, or blank if it passes.
+func (r *EntityIdRules) reason(entityId string) string {
+
+	length := len([]rune(entityId))
+	if r.minLength > 0 && length < r.minLength {
+		return EntityIdTooShort
+	}
+
+	if r.maxLength > 0 && length > r.maxLength {
+		return EntityIdTooLong
+	}
+
+	if len(r.schemes) == 0 {
+		return ""
+	}
+
+	for _, scheme := range r.schemes {
+		if scheme.MatchString(entityId) {
+			return ""
+		}
+	}
+
+	return EntityIdNoScheme
+}
+
+// InvalidEntityIds returns the entity IDs that fail the rules in the order they were given.
+func (r *EntityIdRules) InvalidEntityIds(entityIds []string) []InvalidEntityId {
+
+	invalid := []InvalidEntityId{}
+	if r == nil {
+		return invalid
+	}
+
+	for _, entityId := range entityIds {
+		if reason := r.reason(entityId); len(reason) > 0 {
+			invalid = append(invalid, InvalidEntityId{
+				EntityId: entityId,
+				Reason:   reason,
+			})
+		}
+	}
+
+	return invalid
+}
+
+// Check the entity IDs of each entity set pass the rules. The error is a message for the user that
+// wraps an InvalidEntityIdsError for the first entity set with invalid entity IDs.
+func (r *EntityIdRules) Check(j *JobConfiguration) error {
+
+	if j == nil {
+		return ErrJobConfigurationIsNil
+	}
+
+	for _, entitySet := range j.EntitySets {
+		invalid := r.InvalidEntityIds(entitySet.EntityIds)
+		if len(invalid) > 0 {
+			return i18n.Wrap(&InvalidEntityIdsError{
+				EntitySetName: entitySet.Name,
+				EntityIds:     invalid,
+			}, "error.invalidEntityIds", len(invalid), entitySet.Name)
+		}
+	}
+
+	return nil
+}
+
+// CheckSpider checks the seed entity IDs of a spider job pass the rules. The error is a message for
+// the user that wraps an InvalidEntityIdsError.
+func (r *EntityIdRules) CheckSpider(s *SpiderJobConfiguration) error {
+
+	if s == nil {
+		return ErrConfigIsNil
+	}
+
+	if s.SeedEntities == nil {
+		return ErrSeedEntitiesIsNil
+	}
+
+	entityIds := s.SeedEntities.ToSlice()
+	sort.Strings(entityIds)
+
+	invalid := r.InvalidEntityIds(entityIds)
+	if len(invalid) > 0 {
+		return i18n.Wrap(&InvalidEntityIdsError{
+			EntityIds: invalid,
+		}, "error.invalidSeedEntityIds", len(invalid))
+	}
+
+	return nil
+}
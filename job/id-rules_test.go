@@ -0,0 +1,159 @@
+package job
+
+import (
+	"errors"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/set"
+	"github.com/stretchr/testify/assert"
+)
+
+func makeEntityIdRules(t *testing.T) *EntityIdRules {
+	rules, err := NewEntityIdRules(EntityIdRulesConfig{
+		MinLength: 3,
+		MaxLength: 8,
+		Schemes: []EntityIdScheme{
+			{Name: "entity", Pattern: "e-[0-9]+"},
+			{Name: "person", Pattern: "P[0-9]{4}"},
+		},
+	})
+	assert.NoError(t, err)
+	return rules
+}
+
+func TestNewEntityIdRules(t *testing.T) {
+	testCases := []struct {
+		description string
+		config      EntityIdRulesConfig
+		valid       bool
+	}{
+		{
+			description: "no rules",
+			config:      EntityIdRulesConfig{},
+			valid:       true,
+		},
+		{
+			description: "negative minimum length",
+			config:      EntityIdRulesConfig{MinLength: -1},
+			valid:       false,
+		},
+		{
+			description: "minimum length greater than the maximum",
+			config:      EntityIdRulesConfig{MinLength: 5, MaxLength: 4},
+			valid:       false,
+		},
+		{
+			description: "scheme without a pattern",
+			config:      EntityIdRulesConfig{Schemes: []EntityIdScheme{{Name: "a"}}},
+			valid:       false,
+		},
+		{
+			description: "scheme with an invalid pattern",
+			config:      EntityIdRulesConfig{Schemes: []EntityIdScheme{{Name: "a", Pattern: "[a-"}}},
+			valid:       false,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.description, func(t *testing.T) {
+			_, err := NewEntityIdRules(testCase.config)
+			if testCase.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, ErrInvalidEntityIdRules)
+			}
+		})
+	}
+}
+
+func TestInvalidEntityIds(t *testing.T) {
+	rules := makeEntityIdRules(t)
+
+	actual := rules.InvalidEntityIds([]string{"e-1", "e1", "P1234", "e-123456789", "P12345", "e-2x"})
+	expected := []InvalidEntityId{
+		{EntityId: "e1", Reason: EntityIdTooShort},
+		{EntityId: "e-123456789", Reason: EntityIdTooLong},
+		{EntityId: "P12345", Reason: EntityIdNoScheme},
+		{EntityId: "e-2x", Reason: EntityIdNoScheme},
+	}
+	assert.Equal(t, expected, actual)
+
+	// Nil rules accept everything
+	var noRules *EntityIdRules
+	assert.Equal(t, []InvalidEntityId{}, noRules.InvalidEntityIds([]string{"x"}))
+}
+
+func TestEntityIdRulesCheck(t *testing.T) {
+	rules := makeEntityIdRules(t)
+
+	assert.ErrorIs(t, rules.Check(nil), ErrJobConfigurationIsNil)
+
+	conf := &JobConfiguration{
+		EntitySets: []EntitySet{
+			{Name: "D1", EntityIds: []string{"e-1", "e-2"}},
+			{Name: "D2", EntityIds: []string{"P1234", "e-3x"}},
+		},
+	}
+
+	err := rules.Check(conf)
+	assert.ErrorIs(t, err, ErrInvalidEntityIds)
+
+	var invalidErr *InvalidEntityIdsError
+	assert.True(t, errors.As(err, &invalidErr))
+	assert.Equal(t, "D2", invalidErr.EntitySetName)
+	assert.Equal(t, []InvalidEntityId{{EntityId: "e-3x", Reason: EntityIdNoScheme}},
+		invalidErr.EntityIds)
+
+	conf.EntitySets[1].EntityIds = []string{"P1234"}
+	assert.NoError(t, rules.Check(conf))
+
+	// Nil rules accept everything
+	var noRules *EntityIdRules
+	conf.EntitySets[1].EntityIds = []string{"x"}
+	assert.NoError(t, noRules.Check(conf))
+}
+
+func TestEntityIdRulesCheckSpider(t *testing.T) {
+	rules := makeEntityIdRules(t)
+
+	assert.ErrorIs(t, rules.CheckSpider(nil), ErrConfigIsNil)
+	assert.ErrorIs(t, rules.CheckSpider(&SpiderJobConfiguration{}), ErrSeedEntitiesIsNil)
+
+	seeds := set.NewSet[string]()
+	seeds.AddAll([]string{"e-1", "z-2", "a"})
+	err := rules.CheckSpider(&SpiderJobConfiguration{SeedEntities: seeds})
+
+	var invalidErr *InvalidEntityIdsError
+	assert.True(t, errors.As(err, &invalidErr))
+	assert.Equal(t, []InvalidEntityId{
+		{EntityId: "a", Reason: EntityIdTooShort},
+		{EntityId: "z-2", Reason: EntityIdNoScheme},
+	}, invalidErr.EntityIds)
+
+	assert.NoError(t, rules.CheckSpider(&SpiderJobConfiguration{
+		SeedEntities: set.NewPopulatedSet("e-1"),
+	}))
+}
+
+func TestReadEntityIdRules(t *testing.T) {
+	folder := t.TempDir()
+
+	filepath := path.Join(folder, "rules.json")
+	assert.NoError(t, os.WriteFile(filepath,
+		[]byte(`{"minLength": 3, "schemes": [{"name": "entity", "pattern": "e-[0-9]+"}]}`), 0644))
+
+	rules, err := ReadEntityIdRules(filepath)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, rules.minLength)
+	assert.Len(t, rules.schemes, 1)
+
+	invalidPath := path.Join(folder, "invalid.json")
+	assert.NoError(t, os.WriteFile(invalidPath, []byte(`{"minLength": -1}`), 0644))
+	_, err = ReadEntityIdRules(invalidPath)
+	assert.ErrorIs(t, err, ErrInvalidEntityIdRules)
+
+	_, err = ReadEntityIdRules(path.Join(folder, "missing.json"))
+	assert.Error(t, err)
+}
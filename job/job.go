@@ -2,10 +2,13 @@ package job
 
 import (
 	"errors"
+	"reflect"
 	"strings"
 	"time"
 
+	"github.com/cdclaxton/shortest-path-web-app/filedetector"
 	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/i18n"
 	"github.com/cdclaxton/shortest-path-web-app/search"
 	"github.com/google/uuid"
 )
@@ -21,6 +24,8 @@ var (
 	ErrEntitySetNoEntityIDs = errors.New("entity set doesn't have any entity IDs")
 	ErrInvalidNumberOfHops  = errors.New("invalid number of hops")
 	ErrNoEntitySets         = errors.New("no entity sets")
+	ErrInvalidMinDocuments  = errors.New("invalid minimum number of documents per link")
+	ErrInvalidTimeout       = errors.New("invalid job timeout")
 )
 
 // Validate the EntitySet.
@@ -49,8 +54,19 @@ func (e *EntitySet) Validate() error {
 
 // JobConfiguration specifies all of the necessary details of the job.
 type JobConfiguration struct {
-	MaxNumberHops int         // Number of steps from a root to a goal to search
-	EntitySets    []EntitySet // Sets of entities from which to find paths
+	MaxNumberHops       int           // Number of steps from a root to a goal to search
+	EntitySets          []EntitySet   // Sets of entities from which to find paths
+	RetryWithFewerHops  bool          // Retry with one fewer hop if there are too many paths
+	Directed            bool          // Only find paths that follow the direction of the edges
+	VerboseLogging      bool          // Log debug detail for this job
+	ExcludedEntityIds   []string      // Entity IDs that the paths mustn't pass through
+	Waypoints           []string      // Entity IDs that the paths must pass through one of (optional)
+	PathMatrix          bool          // Output a matrix of the connectivity of each pair of entities instead of an i2 chart
+	MinDocumentsPerLink int           // Minimum number of documents supporting each link of a path (0 for any)
+	Temporal            bool          // Only find paths whose links are supported by documents in date order
+	Timeout             time.Duration // Maximum time to find the paths (0 for the server's timeout)
+	Explain             bool          // Record the expansion of the search for each pair of entities
+	PathFilter          string        // Keep the paths through an entity matching the expression (optional)
 }
 
 // NewJobConfiguration given the entitySets to find paths between and the number of hops.
@@ -80,6 +96,14 @@ func (j *JobConfiguration) Validate() error {
 		return ErrNoEntitySets
 	}
 
+	if j.MinDocumentsPerLink < 0 {
+		return ErrInvalidMinDocuments
+	}
+
+	if j.Timeout < 0 {
+		return ErrInvalidTimeout
+	}
+
 	for _, entitySet := range j.EntitySets {
 		err := entitySet.Validate()
 		if err != nil {
@@ -87,9 +111,31 @@ func (j *JobConfiguration) Validate() error {
 		}
 	}
 
+	for _, entityId := range j.ExcludedEntityIds {
+		if err := graphstore.ValidateEntityId(entityId); err != nil {
+			return err
+		}
+	}
+
+	for _, entityId := range j.Waypoints {
+		if err := graphstore.ValidateEntityId(entityId); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// IsIdentical returns true if the other job configuration is the same, e.g. the same job was
+// submitted twice.
+func (j *JobConfiguration) IsIdentical(other *JobConfiguration) bool {
+	if j == nil || other == nil {
+		return j == other
+	}
+
+	return reflect.DeepEqual(*j, *other)
+}
+
 // A JobState represents the current state of the job.
 type JobState string
 
@@ -99,8 +145,18 @@ const (
 	Failed            JobState = "Failed"
 	CompleteResults   JobState = "Complete Results"
 	CompleteNoResults JobState = "Complete No Results"
+	TimedOut          JobState = "Timed out"
 )
 
+// Runnable is implemented by each type of job, so that the job runners can share the registry
+// and the state machine of the jobs.
+type Runnable interface {
+	HasValidGuid() bool            // Is the GUID of the job valid?
+	Id() string                    // GUID of the job
+	CurrentProgress() *JobProgress // Progress of the job
+	SetError(err error)            // Record the error that occurred during processing
+}
+
 // JobProgress records salient information about the job's status and timing.
 type JobProgress struct {
 	State     JobState
@@ -116,14 +172,38 @@ func NewJobProgress() JobProgress {
 	}
 }
 
+// An EntityPair is a pair of entities searched between by a job.
+type EntityPair struct {
+	Entity1 string
+	Entity2 string
+}
+
+// A PublishedResult is a result file of a job that was copied to object storage.
+type PublishedResult struct {
+	Filename string // Name of the result file, e.g. {guid}.xlsx
+	Url      string // URL of the object
+}
+
 type Job struct {
-	GUID          string            // Unique ID for the job
-	Configuration *JobConfiguration // Configuration, i.e. what job to perform
-	Progress      JobProgress       // Progress of the job
-	ResultFile    string            // Location of the result file for download
-	Message       string            // Message to present to the user
-	Error         error             // Error (if one occurs during processing of the job)
-	EntityResults map[string]search.EntitySearchResult
+	GUID              string            // Unique ID for the job
+	Configuration     *JobConfiguration // Configuration, i.e. what job to perform
+	Progress          JobProgress       // Progress of the job
+	ResultFile        string            // Location of the result file for download
+	AnxResultFile     string            // Location of the ANX chart file for download
+	SummaryFile       string            // Location of the summary of the connections for comparison
+	CsvResultFile     string            // Location of the CSV file of a path matrix for download
+	PartialResultFile string            // Location of the incomplete results of a failed or retried job for download
+	DiagnosticsFile   string            // Location of the diagnostics sheet of the search for download
+	ManifestFile      string            // Location of the integrity manifest of the result file for download
+	Message           string            // Message to present to the user
+	Warnings          []*i18n.Message   // Warnings to present to the user, e.g. the job was retried
+	Error             error             // Error (if one occurs during processing of the job)
+	EntityResults     map[string]search.EntitySearchResult
+	Provenance        filedetector.DataProvenance // Data drop searched by the job
+	Statistics        *PathStatistics             // Statistics of the paths found (nil until the paths are found)
+	UnsearchedPairs   []EntityPair                // Pairs of entities not searched before the job timed out
+	PublishedResults  []PublishedResult           // Result files copied to object storage
+	Overlaps          []DatasetOverlap            // Entity IDs in more than one of the datasets
 }
 
 // GenerateGuid generates a GUID for the job identifier.
@@ -144,6 +224,7 @@ func NewJob(conf *JobConfiguration) (Job, error) {
 		Configuration: conf,
 		Progress:      NewJobProgress(),
 		ResultFile:    "",
+		Overlaps:      FindDatasetOverlaps(conf.EntitySets),
 	}, nil
 }
 
@@ -151,3 +232,18 @@ func NewJob(conf *JobConfiguration) (Job, error) {
 func (j *Job) HasValidGuid() bool {
 	return len(j.GUID) == 36
 }
+
+// Id of the job, i.e. its GUID.
+func (j *Job) Id() string {
+	return j.GUID
+}
+
+// CurrentProgress of the job, which is updated by the job runner.
+func (j *Job) CurrentProgress() *JobProgress {
+	return &j.Progress
+}
+
+// SetError that occurred during processing of the job.
+func (j *Job) SetError(err error) {
+	j.Error = err
+}
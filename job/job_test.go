@@ -0,0 +1,46 @@
+package job
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsIdentical(t *testing.T) {
+
+	makeConf := func() *JobConfiguration {
+		return &JobConfiguration{
+			MaxNumberHops: 2,
+			EntitySets: []EntitySet{
+				{Name: "Set-1", EntityIds: []string{"e-1", "e-2"}},
+				{Name: "Set-2", EntityIds: []string{"e-3"}},
+			},
+			ExcludedEntityIds: []string{"e-9"},
+			Timeout:           time.Minute,
+		}
+	}
+
+	conf := makeConf()
+	assert.True(t, conf.IsIdentical(makeConf()))
+
+	other := makeConf()
+	other.VerboseLogging = true
+	assert.False(t, conf.IsIdentical(other))
+
+	other = makeConf()
+	other.MaxNumberHops = 3
+	assert.False(t, conf.IsIdentical(other))
+
+	other = makeConf()
+	other.EntitySets[1].EntityIds = []string{"e-3", "e-4"}
+	assert.False(t, conf.IsIdentical(other))
+
+	other = makeConf()
+	other.PathFilter = "Region = North"
+	assert.False(t, conf.IsIdentical(other))
+
+	assert.False(t, conf.IsIdentical(nil))
+	var none *JobConfiguration
+	assert.True(t, none.IsIdentical(nil))
+}
@@ -0,0 +1,76 @@
+package job
+
+import (
+	"errors"
+
+	"github.com/cdclaxton/shortest-path-web-app/i18n"
+)
+
+var (
+	ErrInvalidJobLimits      = errors.New("invalid job limits")
+	ErrTooManyEntityIds      = errors.New("too many entity IDs in a dataset")
+	ErrTooManyEntityPairs    = errors.New("too many pairs of entities")
+	ErrJobConfigurationIsNil = errors.New("job configuration is nil")
+)
+
+// JobLimits restrict the size of a job to prevent a combinatorial explosion of pair-wise searches.
+// A limit of zero means there is no limit.
+type JobLimits struct {
+	MaxEntityIdsPerDataset int // Maximum number of entity IDs in a dataset
+	MaxEntityPairs         int // Maximum number of pairs of entities to search between
+}
+
+// Validate the limits.
+func (l JobLimits) Validate() error {
+	if l.MaxEntityIdsPerDataset < 0 || l.MaxEntityPairs < 0 {
+		return ErrInvalidJobLimits
+	}
+
+	return nil
+}
+
+// NumberOfEntityPairs returns the number of pairs of entities that will be searched between. If
+// there is one entity set, it is the number of ordered pairs of entities within the set, otherwise
+// it is the number of pairs of entities from different sets.
+func (j *JobConfiguration) NumberOfEntityPairs() int {
+
+	if len(j.EntitySets) == 1 {
+		n := len(j.EntitySets[0].EntityIds)
+		return n * (n - 1)
+	}
+
+	pairs := 0
+	for idx1 := range j.EntitySets {
+		for idx2 := idx1 + 1; idx2 < len(j.EntitySets); idx2++ {
+			pairs += len(j.EntitySets[idx1].EntityIds) * len(j.EntitySets[idx2].EntityIds)
+		}
+	}
+
+	return pairs
+}
+
+// Check the job configuration is within the limits. The error is a message for the user.
+func (l JobLimits) Check(j *JobConfiguration) error {
+
+	if j == nil {
+		return ErrJobConfigurationIsNil
+	}
+
+	if l.MaxEntityIdsPerDataset > 0 {
+		for _, entitySet := range j.EntitySets {
+			if len(entitySet.EntityIds) > l.MaxEntityIdsPerDataset {
+				return i18n.Wrap(ErrTooManyEntityIds, "error.tooManyEntityIds", entitySet.Name,
+					len(entitySet.EntityIds), l.MaxEntityIdsPerDataset)
+			}
+		}
+	}
+
+	if l.MaxEntityPairs > 0 {
+		if pairs := j.NumberOfEntityPairs(); pairs > l.MaxEntityPairs {
+			return i18n.Wrap(ErrTooManyEntityPairs, "error.tooManyEntityPairs", pairs,
+				l.MaxEntityPairs)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,80 @@
+package job
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNumberOfEntityPairs(t *testing.T) {
+	testCases := []struct {
+		description string
+		entitySets  []EntitySet
+		expected    int
+	}{
+		{
+			description: "one entity set with one entity",
+			entitySets: []EntitySet{
+				{Name: "D1", EntityIds: []string{"e-1"}},
+			},
+			expected: 0,
+		},
+		{
+			description: "one entity set",
+			entitySets: []EntitySet{
+				{Name: "D1", EntityIds: []string{"e-1", "e-2", "e-3"}},
+			},
+			expected: 6,
+		},
+		{
+			description: "two entity sets",
+			entitySets: []EntitySet{
+				{Name: "D1", EntityIds: []string{"e-1", "e-2"}},
+				{Name: "D2", EntityIds: []string{"e-3", "e-4", "e-5"}},
+			},
+			expected: 6,
+		},
+		{
+			description: "three entity sets",
+			entitySets: []EntitySet{
+				{Name: "D1", EntityIds: []string{"e-1", "e-2"}},
+				{Name: "D2", EntityIds: []string{"e-3", "e-4", "e-5"}},
+				{Name: "D3", EntityIds: []string{"e-6"}},
+			},
+			expected: 11,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.description, func(t *testing.T) {
+			conf := JobConfiguration{MaxNumberHops: 1, EntitySets: testCase.entitySets}
+			assert.Equal(t, testCase.expected, conf.NumberOfEntityPairs())
+		})
+	}
+}
+
+func TestJobLimits(t *testing.T) {
+	assert.NoError(t, JobLimits{}.Validate())
+	assert.ErrorIs(t, JobLimits{MaxEntityIdsPerDataset: -1}.Validate(), ErrInvalidJobLimits)
+	assert.ErrorIs(t, JobLimits{MaxEntityPairs: -1}.Validate(), ErrInvalidJobLimits)
+
+	conf := &JobConfiguration{
+		MaxNumberHops: 2,
+		EntitySets: []EntitySet{
+			{Name: "D1", EntityIds: []string{"e-1", "e-2"}},
+			{Name: "D2", EntityIds: []string{"e-3", "e-4", "e-5"}},
+		},
+	}
+
+	assert.ErrorIs(t, JobLimits{}.Check(nil), ErrJobConfigurationIsNil)
+	assert.NoError(t, JobLimits{}.Check(conf))
+	assert.NoError(t, JobLimits{MaxEntityIdsPerDataset: 3, MaxEntityPairs: 6}.Check(conf))
+
+	err := JobLimits{MaxEntityIdsPerDataset: 2}.Check(conf)
+	assert.ErrorIs(t, err, ErrTooManyEntityIds)
+	assert.Equal(t, "dataset D2 has 3 entity IDs, but the maximum is 2. "+
+		"Please split the entity IDs into smaller jobs.", err.Error())
+
+	err = JobLimits{MaxEntityPairs: 5}.Check(conf)
+	assert.ErrorIs(t, err, ErrTooManyEntityPairs)
+}
@@ -0,0 +1,51 @@
+package job
+
+import "sort"
+
+// A DatasetOverlap is an entity ID that appears in more than one of the datasets (entity sets) of
+// a job. The connection between the entity and itself is skipped, so an analyst who pasted
+// overlapping lists is told about it rather than misreading the missing self-connections.
+type DatasetOverlap struct {
+	EntityId string   // Entity ID in more than one dataset
+	Datasets []string // Names of the datasets holding the entity ID in the order they were submitted
+}
+
+// FindDatasetOverlaps returns the entity IDs that appear in more than one of the entity sets,
+// sorted by entity ID. An entity ID repeated within a single entity set isn't an overlap.
+func FindDatasetOverlaps(entitySets []EntitySet) []DatasetOverlap {
+
+	// Entity ID to the indices of the entity sets holding it
+	entitySetsOfEntity := map[string][]int{}
+	for idx, entitySet := range entitySets {
+		for _, entityId := range entitySet.EntityIds {
+			indices := entitySetsOfEntity[entityId]
+			if len(indices) > 0 && indices[len(indices)-1] == idx {
+				continue
+			}
+			entitySetsOfEntity[entityId] = append(indices, idx)
+		}
+	}
+
+	overlaps := []DatasetOverlap{}
+	for entityId, indices := range entitySetsOfEntity {
+		if len(indices) < 2 {
+			continue
+		}
+
+		datasets := make([]string, len(indices))
+		for i, idx := range indices {
+			datasets[i] = entitySets[idx].Name
+		}
+
+		overlaps = append(overlaps, DatasetOverlap{
+			EntityId: entityId,
+			Datasets: datasets,
+		})
+	}
+
+	sort.Slice(overlaps, func(i, j int) bool {
+		return overlaps[i].EntityId < overlaps[j].EntityId
+	})
+
+	return overlaps
+}
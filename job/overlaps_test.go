@@ -0,0 +1,37 @@
+package job
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindDatasetOverlaps(t *testing.T) {
+
+	// No overlaps
+	assert.Equal(t, []DatasetOverlap{}, FindDatasetOverlaps([]EntitySet{
+		{Name: "Set-1", EntityIds: []string{"e-1", "e-2", "e-1"}},
+		{Name: "Set-2", EntityIds: []string{"e-3"}},
+	}))
+
+	// Entity IDs in more than one dataset, including an entity ID repeated within a dataset
+	entitySets := []EntitySet{
+		{Name: "Set-1", EntityIds: []string{"e-4", "e-1", "e-1"}},
+		{Name: "Set-2", EntityIds: []string{"e-2", "e-1"}},
+		{Name: "Set-3", EntityIds: []string{"e-1", "e-2", "e-4"}},
+	}
+
+	assert.Equal(t, []DatasetOverlap{
+		{EntityId: "e-1", Datasets: []string{"Set-1", "Set-2", "Set-3"}},
+		{EntityId: "e-2", Datasets: []string{"Set-2", "Set-3"}},
+		{EntityId: "e-4", Datasets: []string{"Set-1", "Set-3"}},
+	}, FindDatasetOverlaps(entitySets))
+
+	// The overlaps are found when the job is created
+	conf, err := NewJobConfiguration(entitySets, 2)
+	assert.NoError(t, err)
+
+	j, err := NewJob(conf)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(j.Overlaps))
+}
@@ -2,8 +2,12 @@ package job
 
 import (
 	"errors"
+	"strings"
+	"time"
 
+	"github.com/cdclaxton/shortest-path-web-app/filedetector"
 	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/i18n"
 	"github.com/cdclaxton/shortest-path-web-app/set"
 )
 
@@ -12,12 +16,18 @@ var (
 	ErrNoSeedEntities     = errors.New("no seed entities")
 	ErrSeedEntitiesIsNil  = errors.New("seed entities is nil")
 	ErrConfigIsNil        = errors.New("spider config is nil")
+	ErrInvalidSpiderCaps  = errors.New("invalid spider caps")
+	ErrInvalidStepTypes   = errors.New("invalid entity types of the steps")
 )
 
 // SpiderJobConfiguration holds the data for running spidering.
 type SpiderJobConfiguration struct {
-	NumberSteps  int              // Number of steps from the seed entities
-	SeedEntities *set.Set[string] // Seed entities
+	NumberSteps   int              // Number of steps from the seed entities
+	SeedEntities  *set.Set[string] // Seed entities
+	MaxEntities   int              // Maximum number of entities in the sub-graph (0 for no limit)
+	MaxNeighbours int              // Maximum number of neighbours expanded per entity (0 for no limit)
+	Timeout       time.Duration    // Maximum time to spider (0 for the server's timeout)
+	StepTypes     [][]string       // Entity types each step can add, starting with the first step (empty for any type)
 }
 
 func (s *SpiderJobConfiguration) Equal(s2 *SpiderJobConfiguration) bool {
@@ -27,7 +37,26 @@ func (s *SpiderJobConfiguration) Equal(s2 *SpiderJobConfiguration) bool {
 	}
 
 	return s.SeedEntities.Equal(s2.SeedEntities) &&
-		s.NumberSteps == s2.NumberSteps
+		s.NumberSteps == s2.NumberSteps &&
+		s.MaxEntities == s2.MaxEntities &&
+		s.MaxNeighbours == s2.MaxNeighbours &&
+		s.Timeout == s2.Timeout &&
+		stepTypesEqual(s.StepTypes, s2.StepTypes)
+}
+
+// stepTypesEqual returns true if the entity types of the steps are the same.
+func stepTypesEqual(t1 [][]string, t2 [][]string) bool {
+	if len(t1) != len(t2) {
+		return false
+	}
+
+	for idx := range t1 {
+		if !set.NewPopulatedSet(t1[idx]...).Equal(set.NewPopulatedSet(t2[idx]...)) {
+			return false
+		}
+	}
+
+	return true
 }
 
 // isValid returns an error if the spider job configuration is invalid.
@@ -42,6 +71,26 @@ func (s *SpiderJobConfiguration) isValid() error {
 		return ErrSeedEntitiesIsNil
 	}
 
+	if s.MaxEntities < 0 || s.MaxNeighbours < 0 {
+		return ErrInvalidSpiderCaps
+	}
+
+	if s.Timeout < 0 {
+		return ErrInvalidTimeout
+	}
+
+	if len(s.StepTypes) > s.NumberSteps {
+		return ErrInvalidStepTypes
+	}
+
+	for _, entityTypes := range s.StepTypes {
+		for _, entityType := range entityTypes {
+			if len(strings.TrimSpace(entityType)) == 0 {
+				return ErrInvalidStepTypes
+			}
+		}
+	}
+
 	// Check there are seed entities and that each entity ID is valid
 	if s.SeedEntities.Len() == 0 {
 		return ErrNoSeedEntities
@@ -76,12 +125,17 @@ func NewSpiderJobConfiguration(numberSteps int, seedEntities *set.Set[string]) (
 }
 
 type SpiderJob struct {
-	GUID          string                  // Unique ID for the job
-	Configuration *SpiderJobConfiguration // Configuration
-	Progress      JobProgress             // Progress of the job
-	ResultFile    string                  // Location of the result file for download
-	Message       string                  // Message to present to the user
-	Error         error                   // Error (if one occurs during processing of the job)
+	GUID          string                      // Unique ID for the job
+	Configuration *SpiderJobConfiguration     // Configuration
+	Progress      JobProgress                 // Progress of the job
+	ResultFile    string                      // Location of the result file for download
+	ManifestFile  string                      // Location of the integrity manifest of the result file for download
+	Message       string                      // Message to present to the user
+	Warnings      []*i18n.Message             // Warnings to present to the user, e.g. the caps were reached
+	Error         error                       // Error (if one occurs during processing of the job)
+	Provenance    filedetector.DataProvenance // Data drop searched by the job
+
+	PublishedResults []PublishedResult // Result files copied to object storage
 }
 
 // NewSpiderJob creates a new spidering job.
@@ -107,3 +161,18 @@ func NewSpiderJob(conf *SpiderJobConfiguration) (SpiderJob, error) {
 func (j *SpiderJob) HasValidGuid() bool {
 	return len(j.GUID) == 36
 }
+
+// Id of the spider job, i.e. its GUID.
+func (j *SpiderJob) Id() string {
+	return j.GUID
+}
+
+// CurrentProgress of the spider job, which is updated by the job runner.
+func (j *SpiderJob) CurrentProgress() *JobProgress {
+	return &j.Progress
+}
+
+// SetError that occurred during processing of the spider job.
+func (j *SpiderJob) SetError(err error) {
+	j.Error = err
+}
@@ -94,6 +94,50 @@ func TestNewSpiderJob(t *testing.T) {
 			},
 			errorExpected: false,
 		},
+		{
+			// Invalid caps
+			conf: &SpiderJobConfiguration{
+				NumberSteps:   1,
+				SeedEntities:  set.NewPopulatedSet("e-1"),
+				MaxNeighbours: -1,
+			},
+			errorExpected: true,
+		},
+		{
+			conf: &SpiderJobConfiguration{
+				NumberSteps:   1,
+				SeedEntities:  set.NewPopulatedSet("e-1"),
+				MaxEntities:   10,
+				MaxNeighbours: 2,
+			},
+			errorExpected: false,
+		},
+		{
+			conf: &SpiderJobConfiguration{
+				NumberSteps:  2,
+				SeedEntities: set.NewPopulatedSet("e-1"),
+				StepTypes:    [][]string{{"Person"}, {"Address", "Vehicle"}},
+			},
+			errorExpected: false,
+		},
+		{
+			// More entity types than steps
+			conf: &SpiderJobConfiguration{
+				NumberSteps:  1,
+				SeedEntities: set.NewPopulatedSet("e-1"),
+				StepTypes:    [][]string{{"Person"}, {"Address"}},
+			},
+			errorExpected: true,
+		},
+		{
+			// Blank entity type
+			conf: &SpiderJobConfiguration{
+				NumberSteps:  1,
+				SeedEntities: set.NewPopulatedSet("e-1"),
+				StepTypes:    [][]string{{" "}},
+			},
+			errorExpected: true,
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -109,3 +153,24 @@ func TestNewSpiderJob(t *testing.T) {
 		}
 	}
 }
+
+func TestSpiderJobConfigurationEqualStepTypes(t *testing.T) {
+	conf1 := &SpiderJobConfiguration{
+		NumberSteps:  2,
+		SeedEntities: set.NewPopulatedSet("e-1"),
+		StepTypes:    [][]string{{"Person"}, {"Address", "Vehicle"}},
+	}
+
+	conf2 := &SpiderJobConfiguration{
+		NumberSteps:  2,
+		SeedEntities: set.NewPopulatedSet("e-1"),
+		StepTypes:    [][]string{{"Person"}, {"Vehicle", "Address"}},
+	}
+	assert.True(t, conf1.Equal(conf2))
+
+	conf2.StepTypes = [][]string{{"Person"}}
+	assert.False(t, conf1.Equal(conf2))
+
+	conf2.StepTypes = [][]string{{"Person"}, {"Address"}}
+	assert.False(t, conf1.Equal(conf2))
+}
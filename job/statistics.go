@@ -0,0 +1,88 @@
+package job
+
+import "sort"
+
+// PairStatistics of the paths found from a source entity to a destination entity.
+type PairStatistics struct {
+	Source           string `json:"source"`           // Entity ID of the source
+	Destination      string `json:"destination"`      // Entity ID of the destination
+	ShortestDistance int    `json:"shortestDistance"` // Fewest hops of a path between the entities
+	NumberOfPaths    int    `json:"numberOfPaths"`    // Number of paths between the entities
+	OmittedPaths     int    `json:"omittedPaths"`     // Number of paths omitted by sampling
+}
+
+// PathStatistics of the paths found by a job, which help to decide whether increasing the number
+// of hops is worthwhile.
+type PathStatistics struct {
+	Pairs        []PairStatistics `json:"pairs"`        // Sorted by source then destination
+	HopHistogram []int            `json:"hopHistogram"` // Number of paths with i+1 hops at index i
+}
+
+// NewPathStatistics for paths with up to maxHops hops.
+func NewPathStatistics(maxHops int) *PathStatistics {
+	if maxHops < 0 {
+		maxHops = 0
+	}
+
+	return &PathStatistics{
+		Pairs:        []PairStatistics{},
+		HopHistogram: make([]int, maxHops),
+	}
+}
+
+// AddPair of entities given the number of hops of each path between them and the number of paths
+// omitted by sampling.
+func (p *PathStatistics) AddPair(source string, destination string, pathHops []int, omitted int) {
+
+	pair := PairStatistics{
+		Source:        source,
+		Destination:   destination,
+		NumberOfPaths: len(pathHops),
+		OmittedPaths:  omitted,
+	}
+
+	for _, hops := range pathHops {
+		if pair.ShortestDistance == 0 || hops < pair.ShortestDistance {
+			pair.ShortestDistance = hops
+		}
+
+		if hops < 1 {
+			continue
+		}
+
+		for len(p.HopHistogram) < hops {
+			p.HopHistogram = append(p.HopHistogram, 0)
+		}
+		p.HopHistogram[hops-1] += 1
+	}
+
+	p.Pairs = append(p.Pairs, pair)
+}
+
+// Sort the pairs by the source and then the destination.
+func (p *PathStatistics) Sort() {
+	sort.Slice(p.Pairs, func(i, j int) bool {
+		if p.Pairs[i].Source != p.Pairs[j].Source {
+			return p.Pairs[i].Source < p.Pairs[j].Source
+		}
+		return p.Pairs[i].Destination < p.Pairs[j].Destination
+	})
+}
+
+// NumberOfOmittedPaths by sampling.
+func (p *PathStatistics) NumberOfOmittedPaths() int {
+	total := 0
+	for _, pair := range p.Pairs {
+		total += pair.OmittedPaths
+	}
+	return total
+}
+
+// NumberOfPaths in the histogram.
+func (p *PathStatistics) NumberOfPaths() int {
+	total := 0
+	for _, count := range p.HopHistogram {
+		total += count
+	}
+	return total
+}
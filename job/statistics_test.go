@@ -0,0 +1,36 @@
+package job
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPathStatistics(t *testing.T) {
+
+	statistics := NewPathStatistics(3)
+	assert.Equal(t, []int{0, 0, 0}, statistics.HopHistogram)
+	assert.Equal(t, 0, statistics.NumberOfPaths())
+
+	statistics.AddPair("e-2", "e-3", []int{3}, 0)
+	statistics.AddPair("e-1", "e-2", []int{2, 1, 2}, 4)
+
+	// A path longer than expected extends the histogram
+	statistics.AddPair("e-1", "e-4", []int{4}, 0)
+	statistics.Sort()
+
+	expected := &PathStatistics{
+		Pairs: []PairStatistics{
+			{Source: "e-1", Destination: "e-2", ShortestDistance: 1, NumberOfPaths: 3, OmittedPaths: 4},
+			{Source: "e-1", Destination: "e-4", ShortestDistance: 4, NumberOfPaths: 1},
+			{Source: "e-2", Destination: "e-3", ShortestDistance: 3, NumberOfPaths: 1},
+		},
+		HopHistogram: []int{1, 2, 1, 1},
+	}
+	assert.Equal(t, expected, statistics)
+	assert.Equal(t, 5, statistics.NumberOfPaths())
+	assert.Equal(t, 4, statistics.NumberOfOmittedPaths())
+
+	// Invalid number of hops
+	assert.Equal(t, []int{}, NewPathStatistics(-1).HopHistogram)
+}
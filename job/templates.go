@@ -0,0 +1,209 @@
+package job
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Maximum number of characters in the name of a job template
+const MaxJobTemplateNameLength = 100
+
+var (
+	ErrJobTemplateNameEmpty   = errors.New("job template name is empty")
+	ErrJobTemplateNameTooLong = errors.New("job template name is too long")
+	ErrJobTemplateNotFound    = errors.New("job template not found")
+)
+
+// A JobTemplate is a job configuration saved under a name, so that it can be run again.
+type JobTemplate struct {
+	Name          string           `json:"name"`          // Name given by the user
+	Configuration JobConfiguration `json:"configuration"` // Configuration of the job
+	Saved         time.Time        `json:"saved"`         // When the template was saved
+}
+
+// A JobTemplateStore holds the job templates. If the store has a file, the templates are persisted
+// in it as JSON whenever they change.
+type JobTemplateStore struct {
+	filepath  string                 // Location of the JSON file (empty if not persisted)
+	templates map[string]JobTemplate // Name to template
+	lock      sync.RWMutex           // Mutex for the templates
+}
+
+// NewJobTemplateStore backed by the JSON file at filepath. The templates in the file are read if it
+// exists. If the filepath is empty, the templates are only held in memory.
+func NewJobTemplateStore(filepath string) (*JobTemplateStore, error) {
+
+	store := &JobTemplateStore{
+		filepath:  filepath,
+		templates: map[string]JobTemplate{},
+		lock:      sync.RWMutex{},
+	}
+
+	if len(filepath) == 0 {
+		return store, nil
+	}
+
+	content, err := os.ReadFile(filepath)
+	if os.IsNotExist(err) {
+		return store, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	templates := []JobTemplate{}
+	if err := json.Unmarshal(content, &templates); err != nil {
+		return nil, err
+	}
+
+	for _, template := range templates {
+		store.templates[template.Name] = template
+	}
+
+	return store, nil
+}
+
+// validateJobTemplateName returns the name without surrounding whitespace if it is valid.
+func validateJobTemplateName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+
+	if len(name) == 0 {
+		return "", ErrJobTemplateNameEmpty
+	}
+
+	if len([]rune(name)) > MaxJobTemplateNameLength {
+		return "", ErrJobTemplateNameTooLong
+	}
+
+	return name, nil
+}
+
+// list the templates sorted by name. The read lock must be held.
+func (s *JobTemplateStore) list() []JobTemplate {
+	templates := make([]JobTemplate, 0, len(s.templates))
+	for _, template := range s.templates {
+		templates = append(templates, template)
+	}
+
+	sort.Slice(templates, func(i, j int) bool {
+		return templates[i].Name < templates[j].Name
+	})
+
+	return templates
+}
+
+// persist the templates to the JSON file (if there is one). The file is replaced atomically, so a
+// failure doesn't lose the existing templates. The lock must be held.
+func (s *JobTemplateStore) persist() error {
+	if len(s.filepath) == 0 {
+		return nil
+	}
+
+	content, err := json.MarshalIndent(s.list(), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tempFile, err := os.CreateTemp(filepath.Dir(s.filepath), filepath.Base(s.filepath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempFile.Name())
+
+	if _, err := tempFile.Write(content); err != nil {
+		tempFile.Close()
+		return err
+	}
+
+	if err := tempFile.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tempFile.Name(), s.filepath)
+}
+
+// Save the job configuration under the name, replacing any template with the same name.
+func (s *JobTemplateStore) Save(name string, conf *JobConfiguration) error {
+
+	// Preconditions
+	name, err := validateJobTemplateName(name)
+	if err != nil {
+		return err
+	}
+
+	if conf == nil {
+		return ErrJobConfigurationIsNil
+	}
+
+	if err := conf.Validate(); err != nil {
+		return err
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	previous, existed := s.templates[name]
+	s.templates[name] = JobTemplate{
+		Name:          name,
+		Configuration: *conf,
+		Saved:         time.Now().UTC(),
+	}
+
+	// Restore the previous state if the templates can't be persisted
+	if err := s.persist(); err != nil {
+		if existed {
+			s.templates[name] = previous
+		} else {
+			delete(s.templates, name)
+		}
+		return err
+	}
+
+	return nil
+}
+
+// Get the template with the name.
+func (s *JobTemplateStore) Get(name string) (JobTemplate, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	template, found := s.templates[strings.TrimSpace(name)]
+	if !found {
+		return JobTemplate{}, ErrJobTemplateNotFound
+	}
+
+	return template, nil
+}
+
+// List the templates sorted by name.
+func (s *JobTemplateStore) List() []JobTemplate {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.list()
+}
+
+// Delete the template with the name.
+func (s *JobTemplateStore) Delete(name string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	name = strings.TrimSpace(name)
+	template, found := s.templates[name]
+	if !found {
+		return ErrJobTemplateNotFound
+	}
+
+	delete(s.templates, name)
+	if err := s.persist(); err != nil {
+		s.templates[name] = template
+		return err
+	}
+
+	return nil
+}
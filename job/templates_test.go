@@ -0,0 +1,94 @@
+package job
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func makeTestJobConfiguration(t *testing.T, numberHops int) *JobConfiguration {
+	conf, err := NewJobConfiguration([]EntitySet{
+		{Name: "Dataset-1", EntityIds: []string{"e-1", "e-2"}},
+		{Name: "Dataset-2", EntityIds: []string{"e-3"}},
+	}, numberHops)
+	assert.NoError(t, err)
+
+	return conf
+}
+
+func TestJobTemplateStoreInMemory(t *testing.T) {
+
+	store, err := NewJobTemplateStore("")
+	assert.NoError(t, err)
+	assert.Equal(t, []JobTemplate{}, store.List())
+
+	// Invalid templates
+	conf := makeTestJobConfiguration(t, 2)
+	assert.ErrorIs(t, store.Save(" ", conf), ErrJobTemplateNameEmpty)
+	assert.ErrorIs(t, store.Save(strings.Repeat("a", MaxJobTemplateNameLength+1), conf),
+		ErrJobTemplateNameTooLong)
+	assert.ErrorIs(t, store.Save("Name", nil), ErrJobConfigurationIsNil)
+	assert.ErrorIs(t, store.Save("Name", &JobConfiguration{}), ErrInvalidNumberOfHops)
+
+	// Save templates
+	assert.NoError(t, store.Save(" Weekly ", conf))
+	assert.NoError(t, store.Save("Daily", makeTestJobConfiguration(t, 1)))
+
+	templates := store.List()
+	assert.Equal(t, 2, len(templates))
+	assert.Equal(t, "Daily", templates[0].Name)
+	assert.Equal(t, "Weekly", templates[1].Name)
+
+	template, err := store.Get("Weekly")
+	assert.NoError(t, err)
+	assert.Equal(t, *conf, template.Configuration)
+	assert.False(t, template.Saved.IsZero())
+
+	// Replace a template
+	assert.NoError(t, store.Save("Weekly", makeTestJobConfiguration(t, 3)))
+	template, err = store.Get("Weekly")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, template.Configuration.MaxNumberHops)
+
+	// Delete a template
+	assert.NoError(t, store.Delete("Weekly"))
+	_, err = store.Get("Weekly")
+	assert.ErrorIs(t, err, ErrJobTemplateNotFound)
+	assert.ErrorIs(t, store.Delete("Weekly"), ErrJobTemplateNotFound)
+	assert.Equal(t, 1, len(store.List()))
+}
+
+func TestJobTemplateStorePersisted(t *testing.T) {
+
+	storeFile := filepath.Join(t.TempDir(), "job-templates.json")
+
+	// The file doesn't exist until a template is saved
+	store, err := NewJobTemplateStore(storeFile)
+	assert.NoError(t, err)
+	assert.NoError(t, store.Save("Weekly", makeTestJobConfiguration(t, 2)))
+	assert.NoError(t, store.Save("Daily", makeTestJobConfiguration(t, 1)))
+	assert.NoError(t, store.Delete("Daily"))
+
+	// The templates are read from the file
+	store2, err := NewJobTemplateStore(storeFile)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(store2.List()))
+
+	template, err := store2.Get("Weekly")
+	assert.NoError(t, err)
+	assert.Equal(t, *makeTestJobConfiguration(t, 2), template.Configuration)
+
+	// Invalid file
+	assert.NoError(t, os.WriteFile(storeFile, []byte("not JSON"), 0644))
+	_, err = NewJobTemplateStore(storeFile)
+	assert.Error(t, err)
+
+	// The folder for the file doesn't exist
+	store3, err := NewJobTemplateStore(filepath.Join(t.TempDir(), "missing", "templates.json"))
+	assert.NoError(t, err)
+	assert.Error(t, store3.Save("Weekly", makeTestJobConfiguration(t, 2)))
+	assert.Equal(t, []JobTemplate{}, store3.List())
+}
@@ -0,0 +1,373 @@
+package job
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Limits of a watchlist
+const (
+	MaxWatchlistNameLength = 100 // Maximum number of characters in the name of a watchlist
+	MinWatchlistEntities   = 2   // Minimum number of entities in a watchlist
+	MaxWatchlistEntities   = 100 // Maximum number of entities in a watchlist
+	MaxWatchlistAlerts     = 50  // Maximum number of alerts kept for a watchlist
+)
+
+var (
+	ErrWatchlistNameEmpty    = errors.New("watchlist name is empty")
+	ErrWatchlistNameTooLong  = errors.New("watchlist name is too long")
+	ErrWatchlistNotFound     = errors.New("watchlist not found")
+	ErrWatchlistEntities     = errors.New("invalid number of entities in a watchlist")
+	ErrWatchlistInvalidHops  = errors.New("invalid number of hops for a watchlist")
+	ErrWatchlistCheckIsStale = errors.New("watchlist check is older than the last check")
+)
+
+// A WatchlistAlert records the pairs of entities that became connected when a watchlist was
+// checked.
+type WatchlistAlert struct {
+	Checked     time.Time    `json:"checked"`     // When the watchlist was checked
+	Connections []EntityPair `json:"connections"` // Pairs of entities that became connected
+}
+
+// A Watchlist is a set of entities that is searched between whenever the data of its graph is
+// reloaded, so that the user is alerted when entities that weren't connected become connected.
+type Watchlist struct {
+	Id          string           `json:"id"`          // Unique identifier of the watchlist
+	Graph       string           `json:"graph"`       // Base path of the graph searched
+	Name        string           `json:"name"`        // Name given by the user
+	EntityIds   []string         `json:"entityIds"`   // Entities to search between
+	MaxHops     int              `json:"maxHops"`     // Maximum number of hops between entities
+	WebhookUrl  string           `json:"webhookUrl"`  // URL to post the alerts to (blank for none)
+	Created     time.Time        `json:"created"`     // When the watchlist was created
+	LastChecked time.Time        `json:"lastChecked"` // When last checked (zero if never checked)
+	Connected   []EntityPair     `json:"connected"`   // Pairs of entities connected at the last check
+	Alerts      []WatchlistAlert `json:"alerts"`      // Alerts, most recent first
+	Unread      int              `json:"unread"`      // Number of alerts not acknowledged
+}
+
+// copy of the watchlist that doesn't share the entities, connections and alerts.
+func (w Watchlist) copy() Watchlist {
+	w.EntityIds = append([]string{}, w.EntityIds...)
+	w.Connected = append([]EntityPair{}, w.Connected...)
+	w.Alerts = append([]WatchlistAlert{}, w.Alerts...)
+	return w
+}
+
+// Checked returns true if the watchlist has been checked at least once.
+func (w Watchlist) Checked() bool {
+	return !w.LastChecked.IsZero()
+}
+
+// SortEntityPairs so that the first entity of each pair is before the second and the pairs are in
+// alphabetical order. Duplicate pairs are removed.
+func SortEntityPairs(pairs []EntityPair) []EntityPair {
+
+	unique := map[EntityPair]struct{}{}
+	for _, pair := range pairs {
+		if pair.Entity2 < pair.Entity1 {
+			pair.Entity1, pair.Entity2 = pair.Entity2, pair.Entity1
+		}
+		unique[pair] = struct{}{}
+	}
+
+	sorted := make([]EntityPair, 0, len(unique))
+	for pair := range unique {
+		sorted = append(sorted, pair)
+	}
+
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Entity1 == sorted[j].Entity1 {
+			return sorted[i].Entity2 < sorted[j].Entity2
+		}
+		return sorted[i].Entity1 < sorted[j].Entity1
+	})
+
+	return sorted
+}
+
+// A WatchlistStore holds the watchlists of all of the graphs. If the store has a file, the
+// watchlists are persisted in it as JSON whenever they change.
+type WatchlistStore struct {
+	filepath   string               // Location of the JSON file (empty if not persisted)
+	watchlists map[string]Watchlist // Watchlist ID to watchlist
+	lock       sync.RWMutex         // Mutex for the watchlists
+}
+
+// NewWatchlistStore backed by the JSON file at filepath. The watchlists in the file are read if it
+// exists. If the filepath is empty, the watchlists are only held in memory.
+func NewWatchlistStore(filepath string) (*WatchlistStore, error) {
+
+	store := &WatchlistStore{
+		filepath:   filepath,
+		watchlists: map[string]Watchlist{},
+		lock:       sync.RWMutex{},
+	}
+
+	if len(filepath) == 0 {
+		return store, nil
+	}
+
+	content, err := os.ReadFile(filepath)
+	if os.IsNotExist(err) {
+		return store, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	watchlists := []Watchlist{}
+	if err := json.Unmarshal(content, &watchlists); err != nil {
+		return nil, err
+	}
+
+	for _, w := range watchlists {
+		store.watchlists[w.Id] = w
+	}
+
+	return store, nil
+}
+
+// list the watchlists of the graph, most recently created first. All of the watchlists are listed
+// if the graph is nil. The read lock must be held.
+func (s *WatchlistStore) list(graph *string) []Watchlist {
+	watchlists := []Watchlist{}
+	for _, w := range s.watchlists {
+		if graph == nil || w.Graph == *graph {
+			watchlists = append(watchlists, w.copy())
+		}
+	}
+
+	sort.Slice(watchlists, func(i, j int) bool {
+		if watchlists[i].Created.Equal(watchlists[j].Created) {
+			return watchlists[i].Id < watchlists[j].Id
+		}
+		return watchlists[i].Created.After(watchlists[j].Created)
+	})
+
+	return watchlists
+}
+
+// persist the watchlists to the JSON file (if there is one). The file is replaced atomically, so a
+// failure doesn't lose the existing watchlists. The lock must be held.
+func (s *WatchlistStore) persist() error {
+	if len(s.filepath) == 0 {
+		return nil
+	}
+
+	content, err := json.MarshalIndent(s.list(nil), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tempFile, err := os.CreateTemp(filepath.Dir(s.filepath), filepath.Base(s.filepath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tempFile.Name())
+
+	if _, err := tempFile.Write(content); err != nil {
+		tempFile.Close()
+		return err
+	}
+
+	if err := tempFile.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tempFile.Name(), s.filepath)
+}
+
+// update the watchlist with the ID using the function, restoring the watchlist if it can't be
+// persisted.
+func (s *WatchlistStore) update(id string, fn func(w *Watchlist) error) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	previous, found := s.watchlists[id]
+	if !found {
+		return ErrWatchlistNotFound
+	}
+
+	updated := previous.copy()
+	if err := fn(&updated); err != nil {
+		return err
+	}
+	s.watchlists[id] = updated
+
+	if err := s.persist(); err != nil {
+		s.watchlists[id] = previous
+		return err
+	}
+
+	return nil
+}
+
+// Create a watchlist of the graph with the base path. The entity IDs must not contain duplicates.
+func (s *WatchlistStore) Create(graph string, name string, entityIds []string, maxHops int,
+	webhookUrl string) (Watchlist, error) {
+
+	// Preconditions
+	name, err := validateText(name, MaxWatchlistNameLength, ErrWatchlistNameEmpty,
+		ErrWatchlistNameTooLong)
+	if err != nil {
+		return Watchlist{}, err
+	}
+
+	if len(entityIds) < MinWatchlistEntities || len(entityIds) > MaxWatchlistEntities {
+		return Watchlist{}, ErrWatchlistEntities
+	}
+
+	if maxHops <= 0 {
+		return Watchlist{}, ErrWatchlistInvalidHops
+	}
+
+	w := Watchlist{
+		Id:         uuid.New().String(),
+		Graph:      graph,
+		Name:       name,
+		EntityIds:  append([]string{}, entityIds...),
+		MaxHops:    maxHops,
+		WebhookUrl: webhookUrl,
+		Created:    time.Now().UTC(),
+		Connected:  []EntityPair{},
+		Alerts:     []WatchlistAlert{},
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.watchlists[w.Id] = w
+	if err := s.persist(); err != nil {
+		delete(s.watchlists, w.Id)
+		return Watchlist{}, err
+	}
+
+	return w.copy(), nil
+}
+
+// Get the watchlist with the ID.
+func (s *WatchlistStore) Get(id string) (Watchlist, error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	w, found := s.watchlists[id]
+	if !found {
+		return Watchlist{}, ErrWatchlistNotFound
+	}
+
+	return w.copy(), nil
+}
+
+// List the watchlists of the graph with the base path, most recently created first.
+func (s *WatchlistStore) List(graph string) []Watchlist {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	return s.list(&graph)
+}
+
+// NumberUnread returns the number of alerts of the graph's watchlists that haven't been
+// acknowledged.
+func (s *WatchlistStore) NumberUnread(graph string) int {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+
+	unread := 0
+	for _, w := range s.watchlists {
+		if w.Graph == graph {
+			unread += w.Unread
+		}
+	}
+
+	return unread
+}
+
+// Delete the watchlist with the ID.
+func (s *WatchlistStore) Delete(id string) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	previous, found := s.watchlists[id]
+	if !found {
+		return ErrWatchlistNotFound
+	}
+
+	delete(s.watchlists, id)
+	if err := s.persist(); err != nil {
+		s.watchlists[id] = previous
+		return err
+	}
+
+	return nil
+}
+
+// Acknowledge the alerts of the watchlist with the ID.
+func (s *WatchlistStore) Acknowledge(id string) error {
+	return s.update(id, func(w *Watchlist) error {
+		w.Unread = 0
+		return nil
+	})
+}
+
+// RecordCheck of the watchlist with the ID, where connected are the pairs of entities that are
+// connected in the graph. Returns the pairs that weren't connected at the last check, which are
+// recorded as an alert. The first check only records the pairs that are connected, so it never
+// alerts.
+func (s *WatchlistStore) RecordCheck(id string, connected []EntityPair, checked time.Time) (
+	[]EntityPair, error) {
+
+	connected = SortEntityPairs(connected)
+	newPairs := []EntityPair{}
+
+	err := s.update(id, func(w *Watchlist) error {
+		if checked.Before(w.LastChecked) {
+			return ErrWatchlistCheckIsStale
+		}
+
+		if w.Checked() {
+			previous := map[EntityPair]struct{}{}
+			for _, pair := range w.Connected {
+				previous[pair] = struct{}{}
+			}
+
+			for _, pair := range connected {
+				if _, found := previous[pair]; !found {
+					newPairs = append(newPairs, pair)
+				}
+			}
+		}
+
+		if len(newPairs) > 0 {
+			alert := WatchlistAlert{
+				Checked:     checked.UTC(),
+				Connections: newPairs,
+			}
+
+			w.Alerts = append([]WatchlistAlert{alert}, w.Alerts...)
+			if len(w.Alerts) > MaxWatchlistAlerts {
+				w.Alerts = w.Alerts[:MaxWatchlistAlerts]
+			}
+
+			w.Unread += 1
+			if w.Unread > len(w.Alerts) {
+				w.Unread = len(w.Alerts)
+			}
+		}
+
+		w.LastChecked = checked.UTC()
+		w.Connected = connected
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return newPairs, nil
+}
@@ -0,0 +1,175 @@
+package job
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSortEntityPairs(t *testing.T) {
+	pairs := []EntityPair{
+		{Entity1: "e-3", Entity2: "e-1"},
+		{Entity1: "e-1", Entity2: "e-2"},
+		{Entity1: "e-1", Entity2: "e-3"},
+	}
+
+	assert.Equal(t, []EntityPair{
+		{Entity1: "e-1", Entity2: "e-2"},
+		{Entity1: "e-1", Entity2: "e-3"},
+	}, SortEntityPairs(pairs))
+
+	assert.Equal(t, []EntityPair{}, SortEntityPairs(nil))
+}
+
+func TestWatchlistStoreCreate(t *testing.T) {
+
+	store, err := NewWatchlistStore("")
+	assert.NoError(t, err)
+	assert.Equal(t, []Watchlist{}, store.List("/"))
+
+	entityIds := []string{"e-1", "e-2"}
+
+	_, err = store.Create("/", " ", entityIds, 2, "")
+	assert.ErrorIs(t, err, ErrWatchlistNameEmpty)
+
+	_, err = store.Create("/", strings.Repeat("a", MaxWatchlistNameLength+1), entityIds, 2, "")
+	assert.ErrorIs(t, err, ErrWatchlistNameTooLong)
+
+	_, err = store.Create("/", "Name", []string{"e-1"}, 2, "")
+	assert.ErrorIs(t, err, ErrWatchlistEntities)
+
+	_, err = store.Create("/", "Name", entityIds, 0, "")
+	assert.ErrorIs(t, err, ErrWatchlistInvalidHops)
+
+	w, err := store.Create("/", " Watchlist A ", entityIds, 2, "http://localhost/hook")
+	assert.NoError(t, err)
+	assert.Equal(t, "Watchlist A", w.Name)
+	assert.Equal(t, entityIds, w.EntityIds)
+	assert.False(t, w.Checked())
+
+	// The watchlists are listed by graph
+	_, err = store.Create("/graph/b", "Watchlist B", entityIds, 2, "")
+	assert.NoError(t, err)
+
+	watchlists := store.List("/")
+	assert.Equal(t, 1, len(watchlists))
+	assert.Equal(t, w.Id, watchlists[0].Id)
+
+	// Delete the watchlist
+	assert.NoError(t, store.Delete(w.Id))
+	assert.ErrorIs(t, store.Delete(w.Id), ErrWatchlistNotFound)
+
+	_, err = store.Get(w.Id)
+	assert.ErrorIs(t, err, ErrWatchlistNotFound)
+	assert.Equal(t, 1, len(store.List("/graph/b")))
+}
+
+func TestWatchlistStoreRecordCheck(t *testing.T) {
+
+	store, err := NewWatchlistStore("")
+	assert.NoError(t, err)
+
+	w, err := store.Create("/", "Watchlist", []string{"e-1", "e-2", "e-3"}, 2, "")
+	assert.NoError(t, err)
+
+	_, err = store.RecordCheck("missing", nil, time.Now())
+	assert.ErrorIs(t, err, ErrWatchlistNotFound)
+
+	// The first check sets the baseline without an alert
+	t1 := time.Now()
+	newPairs, err := store.RecordCheck(w.Id, []EntityPair{{Entity1: "e-2", Entity2: "e-1"}}, t1)
+	assert.NoError(t, err)
+	assert.Equal(t, []EntityPair{}, newPairs)
+
+	w, err = store.Get(w.Id)
+	assert.NoError(t, err)
+	assert.True(t, w.Checked())
+	assert.Equal(t, []EntityPair{{Entity1: "e-1", Entity2: "e-2"}}, w.Connected)
+	assert.Equal(t, 0, len(w.Alerts))
+	assert.Equal(t, 0, store.NumberUnread("/"))
+
+	// A pair that becomes connected raises an alert
+	t2 := t1.Add(time.Minute)
+	connected := []EntityPair{{Entity1: "e-1", Entity2: "e-2"}, {Entity1: "e-3", Entity2: "e-1"}}
+	newPairs, err = store.RecordCheck(w.Id, connected, t2)
+	assert.NoError(t, err)
+	assert.Equal(t, []EntityPair{{Entity1: "e-1", Entity2: "e-3"}}, newPairs)
+	assert.Equal(t, 1, store.NumberUnread("/"))
+	assert.Equal(t, 0, store.NumberUnread("/graph/b"))
+
+	// The same connections don't raise another alert
+	newPairs, err = store.RecordCheck(w.Id, connected, t2.Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, []EntityPair{}, newPairs)
+
+	w, err = store.Get(w.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(w.Alerts))
+	assert.Equal(t, []EntityPair{{Entity1: "e-1", Entity2: "e-3"}}, w.Alerts[0].Connections)
+	assert.Equal(t, 1, w.Unread)
+
+	// A check older than the last check isn't recorded
+	_, err = store.RecordCheck(w.Id, nil, t1)
+	assert.ErrorIs(t, err, ErrWatchlistCheckIsStale)
+
+	// Acknowledge the alert
+	assert.NoError(t, store.Acknowledge(w.Id))
+	assert.Equal(t, 0, store.NumberUnread("/"))
+	assert.ErrorIs(t, store.Acknowledge("missing"), ErrWatchlistNotFound)
+}
+
+func TestWatchlistStoreAlertsAreCapped(t *testing.T) {
+
+	store, err := NewWatchlistStore("")
+	assert.NoError(t, err)
+
+	w, err := store.Create("/", "Watchlist", []string{"e-1", "e-2"}, 2, "")
+	assert.NoError(t, err)
+
+	// Alternate between the entities being connected and not connected
+	checked := time.Now()
+	pairs := []EntityPair{{Entity1: "e-1", Entity2: "e-2"}}
+	for i := 0; i < 2*MaxWatchlistAlerts+4; i++ {
+		connected := pairs
+		if i%2 == 0 {
+			connected = nil
+		}
+
+		_, err := store.RecordCheck(w.Id, connected, checked)
+		assert.NoError(t, err)
+		checked = checked.Add(time.Second)
+	}
+
+	w, err = store.Get(w.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, MaxWatchlistAlerts, len(w.Alerts))
+	assert.Equal(t, MaxWatchlistAlerts, w.Unread)
+}
+
+func TestWatchlistStorePersisted(t *testing.T) {
+
+	location := filepath.Join(t.TempDir(), "watchlists.json")
+
+	store, err := NewWatchlistStore(location)
+	assert.NoError(t, err)
+
+	w, err := store.Create("/", "Watchlist", []string{"e-1", "e-2"}, 3, "")
+	assert.NoError(t, err)
+
+	_, err = store.RecordCheck(w.Id, []EntityPair{{Entity1: "e-1", Entity2: "e-2"}}, time.Now())
+	assert.NoError(t, err)
+
+	// The watchlists are read back from the file
+	store2, err := NewWatchlistStore(location)
+	assert.NoError(t, err)
+
+	w2, err := store2.Get(w.Id)
+	assert.NoError(t, err)
+	assert.Equal(t, "Watchlist", w2.Name)
+	assert.Equal(t, 3, w2.MaxHops)
+	assert.True(t, w2.Checked())
+	assert.Equal(t, []EntityPair{{Entity1: "e-1", Entity2: "e-2"}}, w2.Connected)
+}
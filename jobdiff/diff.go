@@ -0,0 +1,146 @@
+package jobdiff
+
+import "sort"
+
+// Types of change in the rows of a difference
+const (
+	NewConnectionChange     = "New connection"
+	DroppedConnectionChange = "Dropped connection"
+	PathAddedChange         = "Path added"
+	PathRemovedChange       = "Path removed"
+)
+
+// Header of the rows of a difference
+var DiffHeader = []string{"Change", "Entity 1", "Entity 2", "Path"}
+
+// A ChangedConnection is a connection found by both jobs, but with different paths.
+type ChangedConnection struct {
+	Entity1      string   // Entity ID that is first alphabetically
+	Entity2      string   // Other entity ID
+	AddedPaths   []string // Paths only found by the later job
+	RemovedPaths []string // Paths only found by the earlier job
+}
+
+// A Diff between the results of an earlier and a later job.
+type Diff struct {
+	NewConnections               []Connection        // Connections only found by the later job
+	DroppedConnections           []Connection        // Connections only found by the earlier job
+	ChangedConnections           []ChangedConnection // Connections found by both jobs with different paths
+	NumberOfUnchangedConnections int                 // Number of connections with the same paths
+}
+
+// HasChanges returns true if the results of the jobs are different.
+func (d *Diff) HasChanges() bool {
+	return len(d.NewConnections) > 0 || len(d.DroppedConnections) > 0 ||
+		len(d.ChangedConnections) > 0
+}
+
+// difference returns the sorted items of a that aren't in b.
+func difference(a []string, b []string) []string {
+	inB := map[string]struct{}{}
+	for _, item := range b {
+		inB[item] = struct{}{}
+	}
+
+	result := []string{}
+	for _, item := range a {
+		if _, found := inB[item]; !found {
+			result = append(result, item)
+		}
+	}
+
+	sort.Strings(result)
+	return result
+}
+
+// Compare the results of an earlier job with a later job.
+func Compare(before *ResultSummary, after *ResultSummary) (*Diff, error) {
+
+	// Preconditions
+	if before == nil || after == nil {
+		return nil, ErrResultSummaryIsNil
+	}
+
+	beforeConnections := map[string]Connection{}
+	for _, connection := range before.Connections {
+		beforeConnections[connection.key()] = connection
+	}
+
+	diff := Diff{
+		NewConnections:     []Connection{},
+		DroppedConnections: []Connection{},
+		ChangedConnections: []ChangedConnection{},
+	}
+
+	afterKeys := map[string]struct{}{}
+	for _, connection := range after.Connections {
+		afterKeys[connection.key()] = struct{}{}
+
+		previous, found := beforeConnections[connection.key()]
+		if !found {
+			diff.NewConnections = append(diff.NewConnections, connection)
+			continue
+		}
+
+		added := difference(connection.Paths, previous.Paths)
+		removed := difference(previous.Paths, connection.Paths)
+		if len(added) == 0 && len(removed) == 0 {
+			diff.NumberOfUnchangedConnections += 1
+			continue
+		}
+
+		diff.ChangedConnections = append(diff.ChangedConnections, ChangedConnection{
+			Entity1:      connection.Entity1,
+			Entity2:      connection.Entity2,
+			AddedPaths:   added,
+			RemovedPaths: removed,
+		})
+	}
+
+	for _, connection := range before.Connections {
+		if _, found := afterKeys[connection.key()]; !found {
+			diff.DroppedConnections = append(diff.DroppedConnections, connection)
+		}
+	}
+
+	sortConnections(diff.NewConnections)
+	sortConnections(diff.DroppedConnections)
+	sort.Slice(diff.ChangedConnections, func(i, j int) bool {
+		c1, c2 := diff.ChangedConnections[i], diff.ChangedConnections[j]
+		if c1.Entity1 != c2.Entity1 {
+			return c1.Entity1 < c2.Entity1
+		}
+		return c1.Entity2 < c2.Entity2
+	})
+
+	return &diff, nil
+}
+
+// Rows of the difference (including a header) with one row per path, e.g. for an Excel file.
+func (d *Diff) Rows() [][]string {
+
+	rows := [][]string{DiffHeader}
+
+	for _, connection := range d.NewConnections {
+		for _, path := range connection.Paths {
+			rows = append(rows, []string{NewConnectionChange, connection.Entity1, connection.Entity2, path})
+		}
+	}
+
+	for _, connection := range d.DroppedConnections {
+		for _, path := range connection.Paths {
+			rows = append(rows, []string{DroppedConnectionChange, connection.Entity1, connection.Entity2, path})
+		}
+	}
+
+	for _, connection := range d.ChangedConnections {
+		for _, path := range connection.AddedPaths {
+			rows = append(rows, []string{PathAddedChange, connection.Entity1, connection.Entity2, path})
+		}
+		for _, path := range connection.RemovedPaths {
+			rows = append(rows, []string{PathRemovedChange, connection.Entity1, connection.Entity2, path})
+		}
+	}
+
+	return rows
+}
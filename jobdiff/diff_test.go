@@ -0,0 +1,68 @@
+package jobdiff
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompare(t *testing.T) {
+
+	_, err := Compare(nil, &ResultSummary{})
+	assert.ErrorIs(t, err, ErrResultSummaryIsNil)
+
+	before := &ResultSummary{
+		Connections: []Connection{
+			{Entity1: "e-1", Entity2: "e-2", Paths: []string{"e-1 -> e-2"}},
+			{Entity1: "e-1", Entity2: "e-3", Paths: []string{"e-1 -> e-3", "e-1 -> e-5 -> e-3"}},
+			{Entity1: "e-2", Entity2: "e-3", Paths: []string{"e-2 -> e-3"}},
+		},
+	}
+
+	after := &ResultSummary{
+		Connections: []Connection{
+			{Entity1: "e-1", Entity2: "e-2", Paths: []string{"e-1 -> e-2"}},
+			{Entity1: "e-1", Entity2: "e-3", Paths: []string{"e-1 -> e-3", "e-1 -> e-6 -> e-3"}},
+			{Entity1: "e-1", Entity2: "e-4", Paths: []string{"e-1 -> e-4"}},
+		},
+	}
+
+	diff, err := Compare(before, after)
+	assert.NoError(t, err)
+	assert.True(t, diff.HasChanges())
+
+	expected := &Diff{
+		NewConnections: []Connection{
+			{Entity1: "e-1", Entity2: "e-4", Paths: []string{"e-1 -> e-4"}},
+		},
+		DroppedConnections: []Connection{
+			{Entity1: "e-2", Entity2: "e-3", Paths: []string{"e-2 -> e-3"}},
+		},
+		ChangedConnections: []ChangedConnection{
+			{
+				Entity1:      "e-1",
+				Entity2:      "e-3",
+				AddedPaths:   []string{"e-1 -> e-6 -> e-3"},
+				RemovedPaths: []string{"e-1 -> e-5 -> e-3"},
+			},
+		},
+		NumberOfUnchangedConnections: 1,
+	}
+	assert.Equal(t, expected, diff)
+
+	expectedRows := [][]string{
+		DiffHeader,
+		{NewConnectionChange, "e-1", "e-4", "e-1 -> e-4"},
+		{DroppedConnectionChange, "e-2", "e-3", "e-2 -> e-3"},
+		{PathAddedChange, "e-1", "e-3", "e-1 -> e-6 -> e-3"},
+		{PathRemovedChange, "e-1", "e-3", "e-1 -> e-5 -> e-3"},
+	}
+	assert.Equal(t, expectedRows, diff.Rows())
+
+	// A job compared with itself
+	diff, err = Compare(after, after)
+	assert.NoError(t, err)
+	assert.False(t, diff.HasChanges())
+	assert.Equal(t, 3, diff.NumberOfUnchangedConnections)
+	assert.Equal(t, [][]string{DiffHeader}, diff.Rows())
+}
@@ -0,0 +1,108 @@
+package jobdiff
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/cdclaxton/shortest-path-web-app/bfs"
+	"github.com/cdclaxton/shortest-path-web-app/set"
+)
+
+var ErrEntityHasNoDataset = errors.New("entity of a connection has no dataset")
+
+// Merge the summaries of several jobs, so that a connection found by more than one job appears once
+// with the paths found by any of the jobs.
+func Merge(summaries ...*ResultSummary) (*ResultSummary, error) {
+
+	// Connection key to the connection and its set of paths
+	connections := map[string]Connection{}
+	paths := map[string]*set.Set[string]{}
+
+	for _, summary := range summaries {
+
+		// Precondition
+		if summary == nil {
+			return nil, ErrResultSummaryIsNil
+		}
+
+		for _, connection := range summary.Connections {
+			key := connection.key()
+			if _, found := connections[key]; !found {
+				connections[key] = Connection{
+					Entity1: connection.Entity1,
+					Entity2: connection.Entity2,
+				}
+				paths[key] = set.NewSet[string]()
+			}
+
+			for _, path := range connection.Paths {
+				paths[key].Add(path)
+			}
+		}
+	}
+
+	merged := ResultSummary{Connections: make([]Connection, 0, len(connections))}
+	for key, connection := range connections {
+		connection.Paths = paths[key].ToSlice()
+		sort.Strings(connection.Paths)
+		merged.Connections = append(merged.Connections, connection)
+	}
+
+	sortConnections(merged.Connections)
+	return &merged, nil
+}
+
+// ToNetworkConnections converts the summary back to network connections, e.g. so that an i2 chart
+// can be built from the connections found by several jobs. The entitySets maps the ID of each
+// entity of interest to the names of the datasets in which it appears.
+func ToNetworkConnections(summary *ResultSummary, entitySets map[string]*set.Set[string],
+	maxHops int) (*bfs.NetworkConnections, error) {
+
+	// Precondition
+	if summary == nil {
+		return nil, ErrResultSummaryIsNil
+	}
+
+	conns, err := bfs.NewNetworkConnections(maxHops)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, connection := range summary.Connections {
+		paths := make([]bfs.Path, 0, len(connection.Paths))
+		for _, path := range connection.Paths {
+			paths = append(paths, bfs.NewPath(strings.Split(path, PathSeparator)...))
+		}
+
+		datasets1, found1 := entitySets[connection.Entity1]
+		datasets2, found2 := entitySets[connection.Entity2]
+		if !found1 || !found2 || datasets1.Len() == 0 || datasets2.Len() == 0 {
+			return nil, ErrEntityHasNoDataset
+		}
+
+		names1 := datasets1.ToSlice()
+		sort.Strings(names1)
+		names2 := datasets2.ToSlice()
+		sort.Strings(names2)
+
+		err := conns.AddPaths(connection.Entity1, names1[0], connection.Entity2, names2[0], paths)
+		if err != nil {
+			return nil, err
+		}
+
+		// Record the other datasets in which the entities appear
+		for _, name := range names1[1:] {
+			if err := conns.AddEntity(connection.Entity1, name); err != nil {
+				return nil, err
+			}
+		}
+		for _, name := range names2[1:] {
+			if err := conns.AddEntity(connection.Entity2, name); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return conns, nil
+}
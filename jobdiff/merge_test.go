@@ -0,0 +1,90 @@
+package jobdiff
+
+import (
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/bfs"
+	"github.com/cdclaxton/shortest-path-web-app/set"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMerge(t *testing.T) {
+
+	summary1 := &ResultSummary{
+		Connections: []Connection{
+			{Entity1: "e-1", Entity2: "e-2", Paths: []string{"e-1 -> e-2"}},
+			{Entity1: "e-1", Entity2: "e-4", Paths: []string{"e-1 -> e-4"}},
+		},
+	}
+
+	summary2 := &ResultSummary{
+		Connections: []Connection{
+			{Entity1: "e-1", Entity2: "e-2", Paths: []string{"e-1 -> e-2", "e-1 -> e-3 -> e-2"}},
+			{Entity1: "e-0", Entity2: "e-5", Paths: []string{"e-0 -> e-5"}},
+		},
+	}
+
+	merged, err := Merge(summary1, summary2)
+	assert.NoError(t, err)
+
+	expected := &ResultSummary{
+		Connections: []Connection{
+			{Entity1: "e-0", Entity2: "e-5", Paths: []string{"e-0 -> e-5"}},
+			{Entity1: "e-1", Entity2: "e-2", Paths: []string{"e-1 -> e-2", "e-1 -> e-3 -> e-2"}},
+			{Entity1: "e-1", Entity2: "e-4", Paths: []string{"e-1 -> e-4"}},
+		},
+	}
+	assert.Equal(t, expected, merged)
+
+	// No summaries
+	merged, err = Merge()
+	assert.NoError(t, err)
+	assert.Equal(t, &ResultSummary{Connections: []Connection{}}, merged)
+
+	_, err = Merge(summary1, nil)
+	assert.ErrorIs(t, err, ErrResultSummaryIsNil)
+}
+
+func TestToNetworkConnections(t *testing.T) {
+
+	summary := &ResultSummary{
+		Connections: []Connection{
+			{Entity1: "e-1", Entity2: "e-2", Paths: []string{"e-1 -> e-2", "e-1 -> e-3 -> e-2"}},
+		},
+	}
+
+	entitySets := map[string]*set.Set[string]{
+		"e-1": set.NewPopulatedSet("B", "A"),
+		"e-2": set.NewPopulatedSet("C"),
+	}
+
+	conns, err := ToNetworkConnections(summary, entitySets, 2)
+	assert.NoError(t, err)
+
+	expected, err := bfs.NewNetworkConnections(2)
+	assert.NoError(t, err)
+	assert.NoError(t, expected.AddPaths("e-1", "A", "e-2", "C", []bfs.Path{
+		bfs.NewPath("e-1", "e-2"),
+		bfs.NewPath("e-1", "e-3", "e-2"),
+	}))
+	assert.NoError(t, expected.AddEntity("e-1", "B"))
+	assert.True(t, expected.Equal(conns))
+
+	// The summary is the same once converted back
+	actual, err := Summarise(conns)
+	assert.NoError(t, err)
+	assert.Equal(t, summary, actual)
+
+	// An entity without a dataset
+	_, err = ToNetworkConnections(summary, map[string]*set.Set[string]{
+		"e-1": set.NewPopulatedSet("A"),
+	}, 2)
+	assert.ErrorIs(t, err, ErrEntityHasNoDataset)
+
+	// Invalid arguments
+	_, err = ToNetworkConnections(nil, entitySets, 2)
+	assert.ErrorIs(t, err, ErrResultSummaryIsNil)
+
+	_, err = ToNetworkConnections(summary, entitySets, 0)
+	assert.ErrorIs(t, err, bfs.ErrInvalidHops)
+}
@@ -0,0 +1,164 @@
+// A ResultSummary records the connections found by a shortest path job, so that the results of two
+// jobs can be compared after their paths have been released.
+
+package jobdiff
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/cdclaxton/shortest-path-web-app/bfs"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+)
+
+// Component name used in logging
+const componentName = "jobdiff"
+
+// Separator between the entities of a path when it is written as a string
+const PathSeparator = " -> "
+
+var (
+	ErrNetworkConnectionsIsNil = errors.New("network connections is nil")
+	ErrResultSummaryIsNil      = errors.New("result summary is nil")
+)
+
+// A Connection between two entities of interest and the paths connecting them. The entities are
+// ordered, so that a connection found in either direction is the same connection.
+type Connection struct {
+	Entity1 string   `json:"entity1"` // Entity ID that is first alphabetically
+	Entity2 string   `json:"entity2"` // Other entity ID
+	Paths   []string `json:"paths"`   // Sorted paths from Entity1 to Entity2
+}
+
+// key of the connection for comparison.
+func (c Connection) key() string {
+	return c.Entity1 + "\x00" + c.Entity2
+}
+
+// A ResultSummary holds the connections found by a job.
+type ResultSummary struct {
+	Connections []Connection `json:"connections"` // Sorted by Entity1 then Entity2
+}
+
+// pathToString from the first entity of a connection to the second.
+func pathToString(path bfs.Path, reverse bool) string {
+	route := make([]string, len(path.Route))
+	copy(route, path.Route)
+
+	if reverse {
+		for i, j := 0, len(route)-1; i < j; i, j = i+1, j-1 {
+			route[i], route[j] = route[j], route[i]
+		}
+	}
+
+	return strings.Join(route, PathSeparator)
+}
+
+// Summarise the network connections. Paths that are held on disk are read back.
+func Summarise(conns *bfs.NetworkConnections) (*ResultSummary, error) {
+
+	// Precondition
+	if conns == nil {
+		return nil, ErrNetworkConnectionsIsNil
+	}
+
+	// Entity1 to Entity2 to the set of paths
+	connections := map[string]map[string]map[string]struct{}{}
+
+	for src, destinations := range conns.Connections {
+		for dst := range destinations {
+			paths, err := conns.Paths(src, dst)
+			if err != nil {
+				return nil, err
+			}
+
+			entity1, entity2, reverse := src, dst, false
+			if dst < src {
+				entity1, entity2, reverse = dst, src, true
+			}
+
+			if _, found := connections[entity1]; !found {
+				connections[entity1] = map[string]map[string]struct{}{}
+			}
+			if _, found := connections[entity1][entity2]; !found {
+				connections[entity1][entity2] = map[string]struct{}{}
+			}
+
+			for _, path := range paths {
+				connections[entity1][entity2][pathToString(path, reverse)] = struct{}{}
+			}
+		}
+	}
+
+	summary := ResultSummary{Connections: []Connection{}}
+	for entity1, others := range connections {
+		for entity2, paths := range others {
+			connection := Connection{
+				Entity1: entity1,
+				Entity2: entity2,
+				Paths:   make([]string, 0, len(paths)),
+			}
+
+			for path := range paths {
+				connection.Paths = append(connection.Paths, path)
+			}
+			sort.Strings(connection.Paths)
+
+			summary.Connections = append(summary.Connections, connection)
+		}
+	}
+
+	sortConnections(summary.Connections)
+	return &summary, nil
+}
+
+// sortConnections by the first entity and then the second entity.
+func sortConnections(connections []Connection) {
+	sort.Slice(connections, func(i, j int) bool {
+		if connections[i].Entity1 != connections[j].Entity1 {
+			return connections[i].Entity1 < connections[j].Entity1
+		}
+		return connections[i].Entity2 < connections[j].Entity2
+	})
+}
+
+// WriteSummary to a JSON file.
+func WriteSummary(filepath string, summary *ResultSummary) error {
+
+	// Precondition
+	if summary == nil {
+		return ErrResultSummaryIsNil
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("filepath", filepath).
+		Int("numberOfConnections", len(summary.Connections)).
+		Msg("Writing result summary")
+
+	content, err := json.Marshal(summary)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath, content, 0644)
+}
+
+// ReadSummary from a JSON file.
+func ReadSummary(filepath string) (*ResultSummary, error) {
+
+	content, err := os.ReadFile(filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := ResultSummary{}
+	if err := json.Unmarshal(content, &summary); err != nil {
+		return nil, err
+	}
+
+	return &summary, nil
+}
@@ -0,0 +1,74 @@
+package jobdiff
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/bfs"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSummarise(t *testing.T) {
+
+	_, err := Summarise(nil)
+	assert.ErrorIs(t, err, ErrNetworkConnectionsIsNil)
+
+	conns, err := bfs.NewNetworkConnections(3)
+	assert.NoError(t, err)
+
+	// The same connection found in both directions
+	assert.NoError(t, conns.AddPaths("e-2", "A", "e-1", "B", []bfs.Path{
+		bfs.NewPath("e-2", "e-3", "e-1"),
+	}))
+	assert.NoError(t, conns.AddPaths("e-1", "B", "e-2", "A", []bfs.Path{
+		bfs.NewPath("e-1", "e-3", "e-2"),
+		bfs.NewPath("e-1", "e-2"),
+	}))
+	assert.NoError(t, conns.AddPaths("e-1", "B", "e-4", "A", []bfs.Path{
+		bfs.NewPath("e-1", "e-4"),
+	}))
+
+	summary, err := Summarise(conns)
+	assert.NoError(t, err)
+
+	expected := &ResultSummary{
+		Connections: []Connection{
+			{Entity1: "e-1", Entity2: "e-2", Paths: []string{"e-1 -> e-2", "e-1 -> e-3 -> e-2"}},
+			{Entity1: "e-1", Entity2: "e-4", Paths: []string{"e-1 -> e-4"}},
+		},
+	}
+	assert.Equal(t, expected, summary)
+
+	// No connections
+	conns, err = bfs.NewNetworkConnections(1)
+	assert.NoError(t, err)
+	summary, err = Summarise(conns)
+	assert.NoError(t, err)
+	assert.Equal(t, &ResultSummary{Connections: []Connection{}}, summary)
+}
+
+func TestReadWriteSummary(t *testing.T) {
+
+	summary := &ResultSummary{
+		Connections: []Connection{
+			{Entity1: "e-1", Entity2: "e-2", Paths: []string{"e-1 -> e-2"}},
+		},
+	}
+
+	summaryFile := filepath.Join(t.TempDir(), "summary.json")
+	assert.ErrorIs(t, WriteSummary(summaryFile, nil), ErrResultSummaryIsNil)
+	assert.NoError(t, WriteSummary(summaryFile, summary))
+
+	actual, err := ReadSummary(summaryFile)
+	assert.NoError(t, err)
+	assert.Equal(t, summary, actual)
+
+	// Invalid files
+	_, err = ReadSummary(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+
+	assert.NoError(t, os.WriteFile(summaryFile, []byte("not JSON"), 0644))
+	_, err = ReadSummary(summaryFile)
+	assert.Error(t, err)
+}
@@ -19,3 +19,17 @@ func init() {
 
 // Key for logging the component name
 const ComponentField = "component"
+
+// Key for logging the GUID of a job
+const JobGUIDField = "jobGUID"
+
+// NewJobLogger returns a logger that tags each line with the job's GUID. Debug and trace lines
+// are only logged if verbose logging has been requested for the job.
+func NewJobLogger(guid string, verbose bool) zerolog.Logger {
+	level := zerolog.InfoLevel
+	if verbose {
+		level = zerolog.TraceLevel
+	}
+
+	return Logger.Level(level).With().Str(JobGUIDField, guid).Logger()
+}
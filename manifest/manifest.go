@@ -0,0 +1,218 @@
+// Package manifest makes the integrity manifest of the result file of a job, so that results
+// attached to a case file are tamper-evident. The manifest holds the SHA-256 digest of the result
+// file, the digest of the job's configuration, the signature of the graph data searched by the job
+// and when the manifest was made. It is optionally signed with HMAC-SHA256 using a deployment key,
+// so that a changed manifest (or a manifest made elsewhere) can be detected by the key's holder.
+package manifest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Algorithm used to sign a manifest
+const SignatureAlgorithm = "HMAC-SHA256"
+
+// Errors
+var (
+	ErrEmptyKey    = errors.New("manifest signing key is empty")
+	ErrNotSigned   = errors.New("manifest isn't signed")
+	ErrEmptyGuid   = errors.New("manifest GUID is empty")
+	ErrFileChanged = errors.New("result file doesn't match the manifest")
+)
+
+// A Manifest of the result file of a job.
+type Manifest struct {
+	Guid               string    `json:"guid"`                         // GUID of the job
+	ResultFile         string    `json:"resultFile"`                   // Name of the result file
+	ResultSha256       string    `json:"resultSha256"`                 // Hex SHA-256 digest of the result file
+	ConfigSha256       string    `json:"configSha256"`                 // Hex SHA-256 digest of the job's configuration as JSON
+	GraphSignature     string    `json:"graphSignature"`               // Signature of the graph data searched
+	Created            time.Time `json:"created"`                      // When the manifest was made
+	SignatureAlgorithm string    `json:"signatureAlgorithm,omitempty"` // Algorithm of the signature (blank if unsigned)
+	Signature          string    `json:"signature,omitempty"`          // Hex signature of the rest of the manifest
+}
+
+// fileSha256 returns the hex SHA-256 digest of the file.
+func fileSha256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// New manifest of the result file of the job with the configuration (which must be serialisable
+// as JSON) that searched the graph data with the signature.
+func New(guid string, resultFile string, config interface{}, graphSignature string,
+	created time.Time) (*Manifest, error) {
+
+	if len(guid) == 0 {
+		return nil, ErrEmptyGuid
+	}
+
+	resultSha256, err := fileSha256(resultFile)
+	if err != nil {
+		return nil, err
+	}
+
+	configJson, err := json.Marshal(config)
+	if err != nil {
+		return nil, err
+	}
+	configSha256 := sha256.Sum256(configJson)
+
+	return &Manifest{
+		Guid:           guid,
+		ResultFile:     filepath.Base(resultFile),
+		ResultSha256:   resultSha256,
+		ConfigSha256:   hex.EncodeToString(configSha256[:]),
+		GraphSignature: graphSignature,
+		Created:        created.UTC(),
+	}, nil
+}
+
+// signature of the manifest (excluding any existing signature) with the key.
+func (m *Manifest) signature(key []byte) (string, error) {
+	unsigned := *m
+	unsigned.SignatureAlgorithm = SignatureAlgorithm
+	unsigned.Signature = ""
+
+	content, err := json.Marshal(unsigned)
+	if err != nil {
+		return "", err
+	}
+
+	mac := hmac.New(sha256.New, key)
+	mac.Write(content)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// Sign the manifest with the key.
+func (m *Manifest) Sign(key []byte) error {
+	if len(key) == 0 {
+		return ErrEmptyKey
+	}
+
+	signature, err := m.signature(key)
+	if err != nil {
+		return err
+	}
+
+	m.SignatureAlgorithm = SignatureAlgorithm
+	m.Signature = signature
+	return nil
+}
+
+// Verify returns true if the manifest was signed with the key and hasn't been changed since.
+func (m *Manifest) Verify(key []byte) (bool, error) {
+	if len(key) == 0 {
+		return false, ErrEmptyKey
+	}
+
+	if len(m.Signature) == 0 {
+		return false, ErrNotSigned
+	}
+
+	if m.SignatureAlgorithm != SignatureAlgorithm {
+		return false, nil
+	}
+
+	expected, err := m.signature(key)
+	if err != nil {
+		return false, err
+	}
+
+	return hmac.Equal([]byte(expected), []byte(m.Signature)), nil
+}
+
+// VerifyFile checks that the result file at the path matches the manifest.
+func (m *Manifest) VerifyFile(path string) error {
+	digest, err := fileSha256(path)
+	if err != nil {
+		return err
+	}
+
+	if digest != m.ResultSha256 {
+		return fmt.Errorf("%w: %v", ErrFileChanged, path)
+	}
+
+	return nil
+}
+
+// Write the manifest as JSON to the file at the path.
+func (m *Manifest) Write(path string) error {
+	content, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, content, 0644)
+}
+
+// Read a manifest from the JSON file at the path.
+func Read(path string) (*Manifest, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	m := Manifest{}
+	if err := json.Unmarshal(content, &m); err != nil {
+		return nil, err
+	}
+
+	return &m, nil
+}
+
+// A Generator makes the manifests of the result files of jobs, signing them if it has a key. A nil
+// generator doesn't make manifests.
+type Generator struct {
+	key []byte // Deployment key that signs the manifests (optional)
+}
+
+// NewGenerator of manifests signed with the key (or unsigned if the key is empty).
+func NewGenerator(key []byte) *Generator {
+	return &Generator{
+		key: key,
+	}
+}
+
+// Signed returns true if the generator signs the manifests.
+func (g *Generator) Signed() bool {
+	return g != nil && len(g.key) > 0
+}
+
+// Generate the manifest of the result file of a job and write it to the manifest path.
+func (g *Generator) Generate(manifestPath string, guid string, resultFile string,
+	config interface{}, graphSignature string) (*Manifest, error) {
+
+	m, err := New(guid, resultFile, config, graphSignature, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	if g.Signed() {
+		if err := m.Sign(g.key); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, m.Write(manifestPath)
+}
@@ -0,0 +1,115 @@
+package manifest
+
+import (
+	"io/ioutil"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// writeResultFile with the content in the folder.
+func writeResultFile(t *testing.T, folder string, content string) string {
+	filepath := path.Join(folder, "result.xlsx")
+	assert.NoError(t, ioutil.WriteFile(filepath, []byte(content), 0644))
+	return filepath
+}
+
+func TestNew(t *testing.T) {
+	folder := t.TempDir()
+	resultFile := writeResultFile(t, folder, "results")
+	created := time.Date(2022, 3, 4, 5, 6, 7, 0, time.UTC)
+
+	_, err := New("", resultFile, nil, "sig", created)
+	assert.ErrorIs(t, err, ErrEmptyGuid)
+
+	_, err = New("1234", path.Join(folder, "missing.xlsx"), nil, "sig", created)
+	assert.Error(t, err)
+
+	m, err := New("1234", resultFile, map[string]int{"hops": 2}, "sig", created)
+	assert.NoError(t, err)
+	assert.Equal(t, &Manifest{
+		Guid:           "1234",
+		ResultFile:     "result.xlsx",
+		ResultSha256:   "c099142bc3186ded72786ba27e9ea6d2da240fb9fd3fe79b479ecf8e734b2850",
+		ConfigSha256:   m.ConfigSha256,
+		GraphSignature: "sig",
+		Created:        created,
+	}, m)
+	assert.Len(t, m.ConfigSha256, 64)
+
+	// The digest of the configuration depends on the configuration
+	other, err := New("1234", resultFile, map[string]int{"hops": 3}, "sig", created)
+	assert.NoError(t, err)
+	assert.Equal(t, m.ResultSha256, other.ResultSha256)
+	assert.NotEqual(t, m.ConfigSha256, other.ConfigSha256)
+
+	// The result file hasn't changed
+	assert.NoError(t, m.VerifyFile(resultFile))
+
+	writeResultFile(t, folder, "changed")
+	assert.ErrorIs(t, m.VerifyFile(resultFile), ErrFileChanged)
+}
+
+func TestSignAndVerify(t *testing.T) {
+	resultFile := writeResultFile(t, t.TempDir(), "results")
+	key := []byte("deployment-key")
+
+	m, err := New("1234", resultFile, nil, "sig", time.Now())
+	assert.NoError(t, err)
+
+	_, err = m.Verify(key)
+	assert.ErrorIs(t, err, ErrNotSigned)
+	assert.ErrorIs(t, m.Sign(nil), ErrEmptyKey)
+
+	assert.NoError(t, m.Sign(key))
+	assert.Equal(t, SignatureAlgorithm, m.SignatureAlgorithm)
+	assert.Len(t, m.Signature, 64)
+
+	valid, err := m.Verify(key)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	// A different key
+	valid, err = m.Verify([]byte("another-key"))
+	assert.NoError(t, err)
+	assert.False(t, valid)
+
+	// A changed manifest
+	m.GraphSignature = "other"
+	valid, err = m.Verify(key)
+	assert.NoError(t, err)
+	assert.False(t, valid)
+}
+
+func TestGenerator(t *testing.T) {
+	folder := t.TempDir()
+	resultFile := writeResultFile(t, folder, "results")
+	manifestFile := path.Join(folder, "manifest.json")
+
+	var none *Generator
+	assert.False(t, none.Signed())
+	assert.False(t, NewGenerator(nil).Signed())
+
+	// Unsigned
+	m, err := NewGenerator(nil).Generate(manifestFile, "1234", resultFile, nil, "sig")
+	assert.NoError(t, err)
+	assert.Empty(t, m.Signature)
+
+	// Signed
+	key := []byte("deployment-key")
+	m, err = NewGenerator(key).Generate(manifestFile, "1234", resultFile, nil, "sig")
+	assert.NoError(t, err)
+
+	read, err := Read(manifestFile)
+	assert.NoError(t, err)
+	assert.Equal(t, m, read)
+
+	valid, err := read.Verify(key)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	_, err = Read(path.Join(folder, "missing.json"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,156 @@
+// Package normalisation rewrites entity IDs to a canonical form, so that IDs that only differ in
+// their formatting (e.g. the case of the letters or the spacing of a phone number) are the same. The
+// same rules are applied to the entity IDs in the CSV files when the graph is loaded and to the
+// entity IDs that a user enters, so that the two still match.
+package normalisation
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Case folding of the entity IDs
+const (
+	CaseFoldNone  = ""      // The case is unchanged
+	CaseFoldLower = "lower" // The letters are made lowercase
+	CaseFoldUpper = "upper" // The letters are made uppercase
+)
+
+// Number of digits of a phone number in international format (excluding the '+')
+const (
+	minPhoneDigits = 7
+	maxPhoneDigits = 15
+)
+
+var ErrInvalidNormalisation = errors.New("invalid entity ID normalisation")
+
+// phoneCharacters of an entity ID that looks like a phone number, e.g. "+44 (0)113 496-0000".
+var phoneCharacters = regexp.MustCompile(`^\+?[0-9 ()\-.]+$`)
+
+// countryCodePattern of a valid country calling code.
+var countryCodePattern = regexp.MustCompile(`^[1-9][0-9]{0,2}$`)
+
+// Config defines the rules that normalise an entity ID. The rules are applied in the order of the
+// fields.
+type Config struct {
+	Trim             bool   `json:"trim"`             // Remove leading and trailing whitespace
+	PhoneCountryCode string `json:"phoneCountryCode"` // Country code of national phone numbers (blank to leave phone numbers unchanged)
+	StripPunctuation bool   `json:"stripPunctuation"` // Remove punctuation (except from phone numbers)
+	CaseFold         string `json:"caseFold"`         // Case folding (blank, lower or upper)
+}
+
+// A Normaliser rewrites entity IDs to their canonical form. A nil normaliser leaves the IDs
+// unchanged.
+type Normaliser struct {
+	config Config
+}
+
+// New normaliser from the config.
+func New(config Config) (*Normaliser, error) {
+
+	if config.CaseFold != CaseFoldNone && config.CaseFold != CaseFoldLower &&
+		config.CaseFold != CaseFoldUpper {
+		return nil, fmt.Errorf("%w: unknown case folding %v", ErrInvalidNormalisation, config.CaseFold)
+	}
+
+	config.PhoneCountryCode = strings.TrimPrefix(strings.TrimSpace(config.PhoneCountryCode), "+")
+	if len(config.PhoneCountryCode) > 0 && !countryCodePattern.MatchString(config.PhoneCountryCode) {
+		return nil, fmt.Errorf("%w: invalid phone country code %v", ErrInvalidNormalisation,
+			config.PhoneCountryCode)
+	}
+
+	return &Normaliser{
+		config: config,
+	}, nil
+}
+
+// Normalise the entity ID. If the rules would leave nothing of the ID, it is returned unchanged.
+func (n *Normaliser) Normalise(entityId string) string {
+	if n == nil {
+		return entityId
+	}
+
+	normalised := entityId
+	if n.config.Trim {
+		normalised = strings.TrimSpace(normalised)
+	}
+
+	phoneNumber, isPhoneNumber := n.canonicalPhoneNumber(normalised)
+	if isPhoneNumber {
+		normalised = phoneNumber
+	} else if n.config.StripPunctuation {
+		normalised = strings.Map(func(r rune) rune {
+			if unicode.IsPunct(r) {
+				return -1
+			}
+			return r
+		}, normalised)
+	}
+
+	switch n.config.CaseFold {
+	case CaseFoldLower:
+		normalised = strings.ToLower(normalised)
+	case CaseFoldUpper:
+		normalised = strings.ToUpper(normalised)
+	}
+
+	if len(normalised) == 0 {
+		return entityId
+	}
+
+	return normalised
+}
+
+// NormaliseAll of the entity IDs, returning a new slice in the same order.
+func (n *Normaliser) NormaliseAll(entityIds []string) []string {
+	if n == nil || entityIds == nil {
+		return entityIds
+	}
+
+	normalised := make([]string, len(entityIds))
+	for idx, entityId := range entityIds {
+		normalised[idx] = n.Normalise(entityId)
+	}
+
+	return normalised
+}
+
+// canonicalPhoneNumber returns the entity ID in international format (e.g. +441134960000) if it
+// looks like a phone number, i.e. it starts with a '+', an international prefix (00) or a national
+// trunk prefix (0). A national number has the configured country code added.
+func (n *Normaliser) canonicalPhoneNumber(entityId string) (string, bool) {
+
+	text := strings.TrimSpace(entityId)
+	if len(n.config.PhoneCountryCode) == 0 || !phoneCharacters.MatchString(text) {
+		return "", false
+	}
+
+	// A national number written with its country code, e.g. +44 (0)113, drops the trunk prefix
+	text = strings.Replace(text, "(0)", "", 1)
+
+	digits := strings.Map(func(r rune) rune {
+		if r >= '0' && r <= '9' {
+			return r
+		}
+		return -1
+	}, text)
+
+	switch {
+	case strings.HasPrefix(text, "+"):
+	case strings.HasPrefix(digits, "00"):
+		digits = digits[2:]
+	case strings.HasPrefix(digits, "0"):
+		digits = n.config.PhoneCountryCode + digits[1:]
+	default:
+		return "", false
+	}
+
+	if len(digits) < minPhoneDigits || len(digits) > maxPhoneDigits || digits[0] == '0' {
+		return "", false
+	}
+
+	return "+" + digits, true
+}
@@ -0,0 +1,76 @@
+package normalisation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewInvalid(t *testing.T) {
+	testCases := []Config{
+		{CaseFold: "title"},
+		{PhoneCountryCode: "0"},
+		{PhoneCountryCode: "1234"},
+		{PhoneCountryCode: "UK"},
+	}
+
+	for _, config := range testCases {
+		_, err := New(config)
+		assert.ErrorIs(t, err, ErrInvalidNormalisation)
+	}
+
+	normaliser, err := New(Config{PhoneCountryCode: " +44 "})
+	assert.NoError(t, err)
+	assert.Equal(t, "44", normaliser.config.PhoneCountryCode)
+}
+
+func TestNormalise(t *testing.T) {
+	testCases := []struct {
+		config   Config
+		entityId string
+		expected string
+	}{
+		{Config{}, " e-1 ", " e-1 "},
+		{Config{Trim: true}, " e-1\t", "e-1"},
+		{Config{CaseFold: CaseFoldLower}, "ABC-1", "abc-1"},
+		{Config{CaseFold: CaseFoldUpper}, "abc-1", "ABC-1"},
+		{Config{StripPunctuation: true}, "AB.12-34/5", "AB12345"},
+		{Config{StripPunctuation: true}, "---", "---"},
+		{Config{StripPunctuation: true, CaseFold: CaseFoldLower}, "P-12.A", "p12a"},
+
+		// Phone numbers
+		{Config{PhoneCountryCode: "44"}, "0113 496 0000", "+441134960000"},
+		{Config{PhoneCountryCode: "44"}, "(0113) 496-0000", "+441134960000"},
+		{Config{PhoneCountryCode: "44"}, "+44 (0)113 496 0000", "+441134960000"},
+		{Config{PhoneCountryCode: "44"}, "+44 113 496 0000", "+441134960000"},
+		{Config{PhoneCountryCode: "44"}, "0033 1 23 45 67 89", "+33123456789"},
+		{Config{PhoneCountryCode: "44", StripPunctuation: true}, "+1 (555) 010-9999", "+15550109999"},
+		{Config{PhoneCountryCode: "44", Trim: true}, " 01134960000 ", "+441134960000"},
+
+		// Not phone numbers
+		{Config{PhoneCountryCode: "44"}, "1134960000", "1134960000"},
+		{Config{PhoneCountryCode: "44"}, "0123", "0123"},
+		{Config{PhoneCountryCode: "44"}, "0113 496 0000 ext 1", "0113 496 0000 ext 1"},
+		{Config{PhoneCountryCode: "44", StripPunctuation: true}, "0-1", "01"},
+		{Config{}, "0113 496 0000", "0113 496 0000"},
+	}
+
+	for _, testCase := range testCases {
+		normaliser, err := New(testCase.config)
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.expected, normaliser.Normalise(testCase.entityId), testCase.entityId)
+	}
+}
+
+func TestNormaliseAll(t *testing.T) {
+	normaliser, err := New(Config{Trim: true, CaseFold: CaseFoldUpper})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"E-1", "E-2"}, normaliser.NormaliseAll([]string{"e-1 ", " E-2"}))
+	assert.Nil(t, normaliser.NormaliseAll(nil))
+
+	// A nil normaliser doesn't change the entity IDs
+	var none *Normaliser
+	assert.Equal(t, " e-1", none.Normalise(" e-1"))
+	assert.Equal(t, []string{" e-1"}, none.NormaliseAll([]string{" e-1"}))
+}
@@ -0,0 +1,170 @@
+// Package pathfilter keeps the paths of a shortest path job that pass through an entity whose
+// attributes match a filter expression, e.g. to keep only the paths through entities in a
+// particular region. The filter is applied to the paths once they have been found, looking up the
+// attributes of their intermediate entities in the bipartite store.
+package pathfilter
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/cdclaxton/shortest-path-web-app/bfs"
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+)
+
+// An Operator compares the value of an attribute with the value of a condition.
+type Operator string
+
+const (
+	Equals    Operator = "="        // The value is the same (ignoring case)
+	NotEquals Operator = "!="       // The value is different or the entity doesn't have the attribute
+	Contains  Operator = "contains" // The value contains the text (ignoring case)
+)
+
+var (
+	ErrInvalidFilter  = errors.New("invalid path filter")
+	ErrBipartiteIsNil = errors.New("bipartite store is nil")
+)
+
+// conditionSeparator splits the conditions of an expression, i.e. "and" or a new line.
+var conditionSeparator = regexp.MustCompile(`(?i)\s+and\s+|\r?\n`)
+
+// conditionPattern of a condition, e.g. "Region = North" or "Address contains Leeds".
+var conditionPattern = regexp.MustCompile(`(?i)^(.+?)\s*(!=|=|\scontains\s)\s*(.+)$`)
+
+// A Condition on an attribute of an entity.
+type Condition struct {
+	Attribute string   // Name of the attribute
+	Operator  Operator // Comparison of the attribute's value
+	Value     string   // Value to compare with
+}
+
+// String representation of the condition.
+func (c Condition) String() string {
+	return fmt.Sprintf("%v %v %v", c.Attribute, c.Operator, c.Value)
+}
+
+// Matches returns true if the attributes meet the condition.
+func (c Condition) Matches(attributes map[string]string) bool {
+
+	value, found := attributes[c.Attribute]
+
+	switch c.Operator {
+	case Equals:
+		return found && strings.EqualFold(strings.TrimSpace(value), c.Value)
+	case NotEquals:
+		return !found || !strings.EqualFold(strings.TrimSpace(value), c.Value)
+	case Contains:
+		return found && strings.Contains(strings.ToLower(value), strings.ToLower(c.Value))
+	}
+
+	return false
+}
+
+// A Filter matches the entities that meet all of its conditions.
+type Filter struct {
+	Conditions []Condition
+}
+
+// Parse the filter expression, i.e. one or more conditions separated by "and" or new lines. A
+// condition is an attribute name, an operator (=, != or contains) and a value.
+func Parse(expression string) (*Filter, error) {
+
+	filter := Filter{Conditions: []Condition{}}
+
+	for _, part := range conditionSeparator.Split(strings.TrimSpace(expression), -1) {
+		part = strings.TrimSpace(part)
+		if len(part) == 0 {
+			continue
+		}
+
+		matches := conditionPattern.FindStringSubmatch(part)
+		if matches == nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFilter, part)
+		}
+
+		attribute := strings.TrimSpace(matches[1])
+		value := strings.TrimSpace(matches[3])
+		if len(attribute) == 0 || len(value) == 0 {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidFilter, part)
+		}
+
+		filter.Conditions = append(filter.Conditions, Condition{
+			Attribute: attribute,
+			Operator:  Operator(strings.ToLower(strings.TrimSpace(matches[2]))),
+			Value:     value,
+		})
+	}
+
+	if len(filter.Conditions) == 0 {
+		return nil, fmt.Errorf("%w: no conditions", ErrInvalidFilter)
+	}
+
+	return &filter, nil
+}
+
+// String representation of the filter, which can be parsed.
+func (f *Filter) String() string {
+	conditions := []string{}
+	for _, condition := range f.Conditions {
+		conditions = append(conditions, condition.String())
+	}
+	return strings.Join(conditions, " and ")
+}
+
+// Matches returns true if the entity meets all of the conditions.
+func (f *Filter) Matches(entity *graphstore.Entity) bool {
+	for _, condition := range f.Conditions {
+		if !condition.Matches(entity.Attributes) {
+			return false
+		}
+	}
+	return true
+}
+
+// Apply the filter to the paths, keeping the paths with an intermediate entity that matches the
+// filter. A path without any intermediate entities (i.e. of one hop) is removed. An entity that
+// isn't in the bipartite store doesn't match. Returns the number of paths that were removed.
+func (f *Filter) Apply(conns *bfs.NetworkConnections, bipartite graphstore.BipartiteGraphStore) (int, error) {
+
+	// Preconditions
+	if conns == nil {
+		return 0, errors.New("network connections is nil")
+	}
+
+	if bipartite == nil {
+		return 0, ErrBipartiteIsNil
+	}
+
+	// An entity is often on many paths, so whether it matches is only looked up once
+	matched := map[string]bool{}
+
+	matches := func(entityId string) (bool, error) {
+		if result, found := matched[entityId]; found {
+			return result, nil
+		}
+
+		entity, err := bipartite.GetEntity(entityId)
+		if errors.Is(err, graphstore.ErrEntityNotFound) {
+			matched[entityId] = false
+			return false, nil
+		} else if err != nil {
+			return false, err
+		}
+
+		matched[entityId] = f.Matches(entity)
+		return matched[entityId], nil
+	}
+
+	return conns.FilterPaths(func(path bfs.Path) (bool, error) {
+		for idx := 1; idx < len(path.Route)-1; idx++ {
+			ok, err := matches(path.Route[idx])
+			if err != nil || ok {
+				return ok, err
+			}
+		}
+		return false, nil
+	})
+}
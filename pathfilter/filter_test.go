@@ -0,0 +1,137 @@
+package pathfilter
+
+import (
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/bfs"
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		expression string
+		expected   []Condition
+	}{
+		{"Region = North", []Condition{{"Region", Equals, "North"}}},
+		{"Region=North", []Condition{{"Region", Equals, "North"}}},
+		{" Region != North ", []Condition{{"Region", NotEquals, "North"}}},
+		{"Full address CONTAINS Leeds", []Condition{{"Full address", Contains, "Leeds"}}},
+		{"Note = a=b", []Condition{{"Note", Equals, "a=b"}}},
+		{
+			"Region = North and Status != Closed",
+			[]Condition{{"Region", Equals, "North"}, {"Status", NotEquals, "Closed"}},
+		},
+		{
+			"Region = North\n\nAddress contains Leeds AND Status=Open",
+			[]Condition{{"Region", Equals, "North"}, {"Address", Contains, "Leeds"}, {"Status", Equals, "Open"}},
+		},
+	}
+
+	for _, testCase := range testCases {
+		filter, err := Parse(testCase.expression)
+		assert.NoError(t, err, testCase.expression)
+		assert.Equal(t, testCase.expected, filter.Conditions, testCase.expression)
+
+		// The string representation can be parsed
+		again, err := Parse(filter.String())
+		assert.NoError(t, err)
+		assert.Equal(t, filter, again)
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, expression := range []string{"", " \n ", "Region", "= North", "Region =", "Region contains",
+		"Region = North and Status"} {
+		_, err := Parse(expression)
+		assert.ErrorIs(t, err, ErrInvalidFilter, expression)
+	}
+}
+
+func TestConditionMatches(t *testing.T) {
+	attributes := map[string]string{
+		"Region":  " north ",
+		"Address": "1 High Street, Leeds",
+	}
+
+	testCases := []struct {
+		condition Condition
+		expected  bool
+	}{
+		{Condition{"Region", Equals, "North"}, true},
+		{Condition{"Region", Equals, "South"}, false},
+		{Condition{"Status", Equals, "Open"}, false},
+		{Condition{"Region", NotEquals, "North"}, false},
+		{Condition{"Region", NotEquals, "South"}, true},
+		{Condition{"Status", NotEquals, "Open"}, true},
+		{Condition{"Address", Contains, "leeds"}, true},
+		{Condition{"Address", Contains, "York"}, false},
+		{Condition{"Status", Contains, "Open"}, false},
+		{Condition{"Region", Operator("~"), "North"}, false},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expected, testCase.condition.Matches(attributes),
+			testCase.condition.String())
+	}
+}
+
+// makeBipartite store with entities in the regions.
+func makeBipartite(t *testing.T, regions map[string]string) graphstore.BipartiteGraphStore {
+	bipartite := graphstore.NewInMemoryBipartiteGraphStore()
+	for entityId, region := range regions {
+		entity, err := graphstore.NewEntity(entityId, "Person", map[string]string{"Region": region})
+		assert.NoError(t, err)
+		assert.NoError(t, bipartite.AddEntity(entity))
+	}
+	return bipartite
+}
+
+func TestApply(t *testing.T) {
+	bipartite := makeBipartite(t, map[string]string{
+		"e-1": "North",
+		"e-2": "North",
+		"e-3": "North",
+		"m-1": "North",
+		"m-2": "South",
+		"m-3": "South",
+	})
+
+	conns, err := bfs.NewNetworkConnections(3)
+	assert.NoError(t, err)
+	assert.NoError(t, conns.AddPaths("e-1", "Set-1", "e-2", "Set-2", []bfs.Path{
+		bfs.NewPath("e-1", "e-2"),
+		bfs.NewPath("e-1", "m-1", "e-2"),
+		bfs.NewPath("e-1", "m-2", "e-2"),
+		bfs.NewPath("e-1", "m-2", "m-1", "e-2"),
+		bfs.NewPath("e-1", "unknown", "e-2"),
+	}))
+	assert.NoError(t, conns.AddPaths("e-1", "Set-1", "e-3", "Set-2", []bfs.Path{
+		bfs.NewPath("e-1", "m-3", "e-3"),
+	}))
+
+	filter, err := Parse("Region = north")
+	assert.NoError(t, err)
+
+	// The source and destination entities don't count, only the intermediate entities
+	removed, err := filter.Apply(conns, bipartite)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, removed)
+
+	paths, err := conns.Paths("e-1", "e-2")
+	assert.NoError(t, err)
+	assert.Equal(t, []bfs.Path{
+		bfs.NewPath("e-1", "m-1", "e-2"),
+		bfs.NewPath("e-1", "m-2", "m-1", "e-2"),
+	}, paths)
+
+	connected, err := conns.HasConnection("e-1", "e-3")
+	assert.NoError(t, err)
+	assert.False(t, connected)
+
+	// Preconditions
+	_, err = filter.Apply(nil, bipartite)
+	assert.Error(t, err)
+	_, err = filter.Apply(conns, nil)
+	assert.ErrorIs(t, err, ErrBipartiteIsNil)
+}
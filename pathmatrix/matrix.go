@@ -0,0 +1,198 @@
+// Package pathmatrix summarises the connectivity of each pair of the entities of a shortest path
+// job, i.e. whether they are connected, their shortest distance and their number of paths, which is
+// much more compact than an i2 chart when crossing large datasets.
+package pathmatrix
+
+import (
+	"encoding/csv"
+	"errors"
+	"os"
+	"strconv"
+
+	"github.com/cdclaxton/shortest-path-web-app/bfs"
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+)
+
+// Component name used in logging
+const componentName = "pathmatrix"
+
+// Values of the connected column
+const (
+	ConnectedValue    = "Yes"
+	NotConnectedValue = "No"
+)
+
+// Header of the rows of a matrix
+var Header = []string{"Entity 1", "Dataset 1", "Entity 2", "Dataset 2", "Connected",
+	"Shortest distance (hops)", "Number of paths"}
+
+var (
+	ErrNetworkConnectionsIsNil = errors.New("network connections is nil")
+	ErrNoEntitySets            = errors.New("no entity sets")
+)
+
+// A Pair of entities of a job and their connectivity.
+type Pair struct {
+	Entity1          string // Entity ID from the first dataset
+	Dataset1         string // Name of the first dataset
+	Entity2          string // Entity ID from the second dataset
+	Dataset2         string // Name of the second dataset
+	NumberOfPaths    int    // Number of paths between the entities
+	ShortestDistance int    // Number of hops of the shortest path (0 if not connected)
+}
+
+// Connected returns true if there is at least one path between the entities.
+func (p Pair) Connected() bool {
+	return p.NumberOfPaths > 0
+}
+
+// pairKey identifies a pair of entities, which is independent of their order unless directed.
+func pairKey(entity1 string, entity2 string, directed bool) string {
+	if !directed && entity2 < entity1 {
+		entity1, entity2 = entity2, entity1
+	}
+	return entity1 + "\x00" + entity2
+}
+
+// addPairs between the entities of the two entity sets that haven't already been added.
+func addPairs(entitySet1 job.EntitySet, entitySet2 job.EntitySet, directed bool,
+	seen map[string]struct{}, pairs []Pair) []Pair {
+
+	for _, entityId1 := range entitySet1.EntityIds {
+		for _, entityId2 := range entitySet2.EntityIds {
+
+			// Ignore self-connections
+			if entityId1 == entityId2 {
+				continue
+			}
+
+			key := pairKey(entityId1, entityId2, directed)
+			if _, found := seen[key]; found {
+				continue
+			}
+			seen[key] = struct{}{}
+
+			pairs = append(pairs, Pair{
+				Entity1:  entityId1,
+				Dataset1: entitySet1.Name,
+				Entity2:  entityId2,
+				Dataset2: entitySet2.Name,
+			})
+		}
+	}
+
+	return pairs
+}
+
+// entityPairs of the job in the same order as the path finder searches them, i.e. the pairs within
+// a single dataset or the pairs between each two datasets (in both orders in directed mode).
+func entityPairs(entitySets []job.EntitySet, directed bool) []Pair {
+
+	seen := map[string]struct{}{}
+	pairs := []Pair{}
+
+	if len(entitySets) == 1 {
+		return addPairs(entitySets[0], entitySets[0], directed, seen, pairs)
+	}
+
+	for idx1 := range entitySets {
+		for idx2 := range entitySets {
+			if idx2 == idx1 || (!directed && idx2 < idx1) {
+				continue
+			}
+			pairs = addPairs(entitySets[idx1], entitySets[idx2], directed, seen, pairs)
+		}
+	}
+
+	return pairs
+}
+
+// Build the matrix of the pairs of entities of the job from the paths that were found. In directed
+// mode only the paths from the first entity to the second are counted. Paths that are held on disk
+// are read back.
+func Build(entitySets []job.EntitySet, directed bool, conns *bfs.NetworkConnections) ([]Pair, error) {
+
+	// Preconditions
+	if conns == nil {
+		return nil, ErrNetworkConnectionsIsNil
+	}
+
+	if len(entitySets) == 0 {
+		return nil, ErrNoEntitySets
+	}
+
+	pairs := entityPairs(entitySets, directed)
+
+	for idx := range pairs {
+		paths, err := conns.Paths(pairs[idx].Entity1, pairs[idx].Entity2)
+		if err != nil {
+			return nil, err
+		}
+
+		if !directed {
+			reversed, err := conns.Paths(pairs[idx].Entity2, pairs[idx].Entity1)
+			if err != nil {
+				return nil, err
+			}
+			paths = append(paths, reversed...)
+		}
+
+		pairs[idx].NumberOfPaths = len(paths)
+		for _, path := range paths {
+			hops := len(path.Route) - 1
+			if pairs[idx].ShortestDistance == 0 || hops < pairs[idx].ShortestDistance {
+				pairs[idx].ShortestDistance = hops
+			}
+		}
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Int("numberOfPairs", len(pairs)).
+		Msg("Built path matrix")
+
+	return pairs, nil
+}
+
+// Rows of the matrix with a header, where the shortest distance is blank if the entities aren't
+// connected.
+func Rows(pairs []Pair) [][]string {
+
+	rows := [][]string{Header}
+
+	for _, pair := range pairs {
+		connected, distance := NotConnectedValue, ""
+		if pair.Connected() {
+			connected, distance = ConnectedValue, strconv.Itoa(pair.ShortestDistance)
+		}
+
+		rows = append(rows, []string{pair.Entity1, pair.Dataset1, pair.Entity2, pair.Dataset2,
+			connected, distance, strconv.Itoa(pair.NumberOfPaths)})
+	}
+
+	return rows
+}
+
+// WriteCsv writes the rows to the CSV file at filepath.
+func WriteCsv(filepath string, rows [][]string) error {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("filepath", filepath).
+		Int("numberOfRows", len(rows)).
+		Msg("Writing CSV file")
+
+	file, err := os.Create(filepath)
+	if err != nil {
+		return err
+	}
+
+	writer := csv.NewWriter(file)
+	if err := writer.WriteAll(rows); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
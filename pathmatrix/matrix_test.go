@@ -0,0 +1,102 @@
+package pathmatrix
+
+import (
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/bfs"
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEntityPairs(t *testing.T) {
+
+	// Single dataset
+	pairs := entityPairs([]job.EntitySet{
+		{Name: "A", EntityIds: []string{"e-1", "e-2", "e-3"}},
+	}, false)
+	assert.Equal(t, []Pair{
+		{Entity1: "e-1", Dataset1: "A", Entity2: "e-2", Dataset2: "A"},
+		{Entity1: "e-1", Dataset1: "A", Entity2: "e-3", Dataset2: "A"},
+		{Entity1: "e-2", Dataset1: "A", Entity2: "e-3", Dataset2: "A"},
+	}, pairs)
+
+	// Two datasets sharing an entity
+	entitySets := []job.EntitySet{
+		{Name: "A", EntityIds: []string{"e-1", "e-2"}},
+		{Name: "B", EntityIds: []string{"e-2", "e-3"}},
+	}
+	assert.Equal(t, []Pair{
+		{Entity1: "e-1", Dataset1: "A", Entity2: "e-2", Dataset2: "B"},
+		{Entity1: "e-1", Dataset1: "A", Entity2: "e-3", Dataset2: "B"},
+		{Entity1: "e-2", Dataset1: "A", Entity2: "e-3", Dataset2: "B"},
+	}, entityPairs(entitySets, false))
+
+	// In directed mode, both directions are pairs
+	assert.Equal(t, 6, len(entityPairs(entitySets, true)))
+}
+
+func TestBuild(t *testing.T) {
+
+	entitySets := []job.EntitySet{
+		{Name: "A", EntityIds: []string{"e-1"}},
+		{Name: "B", EntityIds: []string{"e-2", "e-4"}},
+	}
+
+	_, err := Build(entitySets, false, nil)
+	assert.ErrorIs(t, err, ErrNetworkConnectionsIsNil)
+
+	conns, err := bfs.NewNetworkConnections(3)
+	assert.NoError(t, err)
+
+	_, err = Build(nil, false, conns)
+	assert.ErrorIs(t, err, ErrNoEntitySets)
+
+	// Paths found in the opposite direction to the pair
+	assert.NoError(t, conns.AddPaths("e-2", "B", "e-1", "A", []bfs.Path{
+		bfs.NewPath("e-2", "e-3", "e-1"),
+		bfs.NewPath("e-2", "e-1"),
+	}))
+
+	pairs, err := Build(entitySets, false, conns)
+	assert.NoError(t, err)
+	assert.Equal(t, []Pair{
+		{Entity1: "e-1", Dataset1: "A", Entity2: "e-2", Dataset2: "B", NumberOfPaths: 2, ShortestDistance: 1},
+		{Entity1: "e-1", Dataset1: "A", Entity2: "e-4", Dataset2: "B"},
+	}, pairs)
+
+	assert.Equal(t, [][]string{
+		Header,
+		{"e-1", "A", "e-2", "B", ConnectedValue, "1", "2"},
+		{"e-1", "A", "e-4", "B", NotConnectedValue, "", "0"},
+	}, Rows(pairs))
+
+	// In directed mode, only the paths from the first entity count
+	pairs, err = Build(entitySets, true, conns)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(pairs))
+	assert.Equal(t, 0, pairs[0].NumberOfPaths)
+	assert.Equal(t, Pair{Entity1: "e-2", Dataset1: "B", Entity2: "e-1", Dataset2: "A",
+		NumberOfPaths: 2, ShortestDistance: 1}, pairs[2])
+}
+
+func TestWriteCsv(t *testing.T) {
+
+	rows := [][]string{Header, {"e-1", "A", "e-2", "B", ConnectedValue, "1", "2"}}
+
+	csvFile := filepath.Join(t.TempDir(), "matrix.csv")
+	assert.NoError(t, WriteCsv(csvFile, rows))
+
+	file, err := os.Open(csvFile)
+	assert.NoError(t, err)
+	defer file.Close()
+
+	read, err := csv.NewReader(file).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, rows, read)
+
+	// Folder doesn't exist
+	assert.Error(t, WriteCsv(filepath.Join(t.TempDir(), "missing", "matrix.csv"), rows))
+}
@@ -0,0 +1,254 @@
+// Package publish copies the result files of completed jobs to S3-compatible object storage, so
+// that downstream pipelines can pick up the results without scraping the download endpoints.
+package publish
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/i2chart"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+)
+
+const componentName = "publish"
+
+// Keywords that can be used in the template of an object's key
+const (
+	GuidKeyword      = "GUID"      // GUID of the job
+	KindKeyword      = "KIND"      // Type of the job, e.g. shortest-path or spider
+	DateKeyword      = "DATE"      // Date the job completed (YYYY-MM-DD)
+	FilenameKeyword  = "FILENAME"  // Name of the result file, e.g. {guid}.xlsx
+	ExtensionKeyword = "EXTENSION" // Extension of the result file without the dot, e.g. xlsx
+)
+
+// DefaultKeyTemplate of the key of an object if the config doesn't set one
+const DefaultKeyTemplate = "<KIND>/<DATE>/<GUID>/<FILENAME>"
+
+// Environment variables holding the credentials if they aren't in the config
+const (
+	accessKeyIdEnv     = "AWS_ACCESS_KEY_ID"
+	secretAccessKeyEnv = "AWS_SECRET_ACCESS_KEY"
+	sessionTokenEnv    = "AWS_SESSION_TOKEN"
+)
+
+var (
+	ErrEndpointInvalid  = errors.New("object storage endpoint must be an http or https URL")
+	ErrRegionEmpty      = errors.New("object storage region is empty")
+	ErrBucketEmpty      = errors.New("object storage bucket is empty")
+	ErrCredentialsEmpty = errors.New("object storage credentials are empty")
+	ErrKeyEmpty         = errors.New("object key is empty")
+	ErrUploadFailed     = errors.New("failed to upload the object")
+)
+
+// A Publisher copies a result file to object storage.
+type Publisher interface {
+
+	// Publish the file at the local path under the key made from the keywords. Returns the URL of
+	// the object.
+	Publish(ctx context.Context, localPath string, keywords map[string]string) (string, error)
+}
+
+// S3Config is the configuration of an S3-compatible object store, read from a JSON file.
+type S3Config struct {
+	Endpoint        string `json:"endpoint"`        // e.g. https://s3.eu-west-2.amazonaws.com
+	Region          string `json:"region"`          // e.g. eu-west-2
+	Bucket          string `json:"bucket"`          // Bucket the results are copied to
+	KeyTemplate     string `json:"keyTemplate"`     // Template of the key (blank for the default)
+	PublicUrl       string `json:"publicUrl"`       // Base URL of the objects shown to users (blank for the endpoint)
+	AccessKeyId     string `json:"accessKeyId"`     // Access key (blank to read AWS_ACCESS_KEY_ID)
+	SecretAccessKey string `json:"secretAccessKey"` // Secret key (blank to read AWS_SECRET_ACCESS_KEY)
+	SessionToken    string `json:"sessionToken"`    // Session token (blank to read AWS_SESSION_TOKEN)
+}
+
+// ReadS3Config from a JSON file. Credentials missing from the file are read from the environment.
+func ReadS3Config(filepath string) (S3Config, error) {
+
+	content, err := os.ReadFile(filepath)
+	if err != nil {
+		return S3Config{}, err
+	}
+
+	var config S3Config
+	if err := json.Unmarshal(content, &config); err != nil {
+		return S3Config{}, err
+	}
+
+	if len(config.AccessKeyId) == 0 && len(config.SecretAccessKey) == 0 {
+		config.AccessKeyId = os.Getenv(accessKeyIdEnv)
+		config.SecretAccessKey = os.Getenv(secretAccessKeyEnv)
+		config.SessionToken = os.Getenv(sessionTokenEnv)
+	}
+
+	return config, nil
+}
+
+// An S3Publisher copies the result files to an S3-compatible object store using path-style
+// requests (i.e. {endpoint}/{bucket}/{key}), which are supported by AWS S3 and by MinIO.
+type S3Publisher struct {
+	config     S3Config
+	endpoint   *url.URL
+	httpClient *http.Client
+}
+
+// NewS3Publisher from the config.
+func NewS3Publisher(config S3Config) (*S3Publisher, error) {
+
+	// Preconditions
+	endpoint, err := url.Parse(strings.TrimSuffix(config.Endpoint, "/"))
+	if err != nil || (endpoint.Scheme != "http" && endpoint.Scheme != "https") ||
+		len(endpoint.Host) == 0 {
+		return nil, ErrEndpointInvalid
+	}
+
+	if len(config.Region) == 0 {
+		return nil, ErrRegionEmpty
+	}
+
+	if len(config.Bucket) == 0 {
+		return nil, ErrBucketEmpty
+	}
+
+	if len(config.AccessKeyId) == 0 || len(config.SecretAccessKey) == 0 {
+		return nil, ErrCredentialsEmpty
+	}
+
+	if len(config.KeyTemplate) == 0 {
+		config.KeyTemplate = DefaultKeyTemplate
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("endpoint", endpoint.String()).
+		Str("bucket", config.Bucket).
+		Str("keyTemplate", config.KeyTemplate).
+		Msg("Publishing the results to object storage")
+
+	return &S3Publisher{
+		config:     config,
+		endpoint:   endpoint,
+		httpClient: &http.Client{},
+	}, nil
+}
+
+// Keywords of a result file of a job for the template of the object's key.
+func Keywords(kind string, guid string, localPath string, completed time.Time) map[string]string {
+	filename := filepath.Base(localPath)
+
+	return map[string]string{
+		GuidKeyword:      guid,
+		KindKeyword:      kind,
+		DateKeyword:      completed.UTC().Format("2006-01-02"),
+		FilenameKeyword:  filename,
+		ExtensionKeyword: strings.TrimPrefix(filepath.Ext(filename), "."),
+	}
+}
+
+// Key of the object made from the keywords. Keywords that aren't known are removed.
+func (p *S3Publisher) Key(keywords map[string]string) (string, error) {
+
+	key, err := i2chart.Substitute(p.config.KeyTemplate, keywords, "")
+	if err != nil {
+		return "", err
+	}
+
+	key = strings.TrimPrefix(key, "/")
+	if len(key) == 0 {
+		return "", ErrKeyEmpty
+	}
+
+	return key, nil
+}
+
+// objectUrl of the object with the key, which is relative to the public URL if there is one.
+func (p *S3Publisher) objectUrl(key string) string {
+	if len(p.config.PublicUrl) > 0 {
+		return strings.TrimSuffix(p.config.PublicUrl, "/") + "/" + encodePath(key)
+	}
+
+	return p.endpoint.String() + "/" + encodePath(p.config.Bucket) + "/" + encodePath(key)
+}
+
+// contentType of the file from its extension.
+func contentType(localPath string) string {
+	if kind := mime.TypeByExtension(filepath.Ext(localPath)); len(kind) > 0 {
+		return kind
+	}
+	return "application/octet-stream"
+}
+
+// Publish the file at the local path under the key made from the keywords.
+func (p *S3Publisher) Publish(ctx context.Context, localPath string,
+	keywords map[string]string) (string, error) {
+
+	key, err := p.Key(keywords)
+	if err != nil {
+		return "", err
+	}
+
+	file, err := os.Open(localPath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return "", err
+	}
+
+	// The payload is hashed for the signature and then read again for the upload
+	payloadHash, err := hashReader(file)
+	if err != nil {
+		return "", err
+	}
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	target := p.endpoint.String() + "/" + encodePath(p.config.Bucket) + "/" + encodePath(key)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, target, file)
+	if err != nil {
+		return "", err
+	}
+
+	req.ContentLength = info.Size()
+	req.Header.Set("Content-Type", contentType(localPath))
+
+	credentials := credentials{
+		accessKeyId:     p.config.AccessKeyId,
+		secretAccessKey: p.config.SecretAccessKey,
+		sessionToken:    p.config.SessionToken,
+	}
+	signRequest(req, payloadHash, credentials, p.config.Region, time.Now())
+
+	resp, err := p.httpClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return "", fmt.Errorf("%w: status %v: %v", ErrUploadFailed, resp.StatusCode,
+			strings.TrimSpace(string(body)))
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("filepath", localPath).
+		Str("key", key).
+		Msg("Published the result file")
+
+	return p.objectUrl(key), nil
+}
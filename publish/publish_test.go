@@ -0,0 +1,145 @@
+package publish
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// validConfig of an object store at the endpoint.
+func validConfig(endpoint string) S3Config {
+	return S3Config{
+		Endpoint:        endpoint,
+		Region:          "eu-west-2",
+		Bucket:          "results",
+		AccessKeyId:     "id",
+		SecretAccessKey: "secret",
+	}
+}
+
+func TestNewS3Publisher(t *testing.T) {
+
+	config := validConfig("ftp://localhost")
+	_, err := NewS3Publisher(config)
+	assert.ErrorIs(t, err, ErrEndpointInvalid)
+
+	config = validConfig("http://localhost:9000")
+	config.Region = ""
+	_, err = NewS3Publisher(config)
+	assert.ErrorIs(t, err, ErrRegionEmpty)
+
+	config = validConfig("http://localhost:9000")
+	config.Bucket = ""
+	_, err = NewS3Publisher(config)
+	assert.ErrorIs(t, err, ErrBucketEmpty)
+
+	config = validConfig("http://localhost:9000")
+	config.SecretAccessKey = ""
+	_, err = NewS3Publisher(config)
+	assert.ErrorIs(t, err, ErrCredentialsEmpty)
+
+	publisher, err := NewS3Publisher(validConfig("http://localhost:9000/"))
+	assert.NoError(t, err)
+	assert.Equal(t, DefaultKeyTemplate, publisher.config.KeyTemplate)
+}
+
+func TestReadS3Config(t *testing.T) {
+
+	t.Setenv(accessKeyIdEnv, "env-id")
+	t.Setenv(secretAccessKeyEnv, "env-secret")
+	t.Setenv(sessionTokenEnv, "")
+
+	location := filepath.Join(t.TempDir(), "publish.json")
+	content := `{"endpoint": "http://localhost:9000", "region": "eu-west-2", "bucket": "results"}`
+	assert.NoError(t, os.WriteFile(location, []byte(content), 0644))
+
+	// The credentials are read from the environment
+	config, err := ReadS3Config(location)
+	assert.NoError(t, err)
+	assert.Equal(t, "results", config.Bucket)
+	assert.Equal(t, "env-id", config.AccessKeyId)
+	assert.Equal(t, "env-secret", config.SecretAccessKey)
+
+	_, err = ReadS3Config(filepath.Join(t.TempDir(), "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestKey(t *testing.T) {
+
+	config := validConfig("http://localhost:9000")
+	config.KeyTemplate = "/jobs/<KIND>/<DATE>/<GUID>.<EXTENSION><UNKNOWN>"
+	publisher, err := NewS3Publisher(config)
+	assert.NoError(t, err)
+
+	completed := time.Date(2026, 10, 16, 23, 30, 0, 0, time.UTC)
+	keywords := Keywords("spider", "guid-1", "/tmp/charts/guid-1.xlsx", completed)
+	assert.Equal(t, "guid-1.xlsx", keywords[FilenameKeyword])
+
+	key, err := publisher.Key(keywords)
+	assert.NoError(t, err)
+	assert.Equal(t, "jobs/spider/2026-10-16/guid-1.xlsx", key)
+
+	publisher.config.KeyTemplate = "<UNKNOWN>"
+	_, err = publisher.Key(keywords)
+	assert.ErrorIs(t, err, ErrKeyEmpty)
+}
+
+func TestPublish(t *testing.T) {
+
+	// Object store that records the object uploaded
+	var method, path, authorization, contentType string
+	var body []byte
+	status := http.StatusOK
+
+	store := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		method = req.Method
+		path = req.URL.EscapedPath()
+		authorization = req.Header.Get("Authorization")
+		contentType = req.Header.Get("Content-Type")
+		body, _ = io.ReadAll(req.Body)
+		w.WriteHeader(status)
+	}))
+	defer store.Close()
+
+	publisher, err := NewS3Publisher(validConfig(store.URL))
+	assert.NoError(t, err)
+
+	localPath := filepath.Join(t.TempDir(), "guid 1.csv")
+	assert.NoError(t, os.WriteFile(localPath, []byte("a,b\n"), 0644))
+
+	completed := time.Date(2026, 10, 16, 9, 0, 0, 0, time.UTC)
+	keywords := Keywords("shortest-path", "guid-1", localPath, completed)
+
+	objectUrl, err := publisher.Publish(context.Background(), localPath, keywords)
+	assert.NoError(t, err)
+	assert.Equal(t, http.MethodPut, method)
+	assert.Equal(t, "/results/shortest-path/2026-10-16/guid-1/guid%201.csv", path)
+	assert.Equal(t, "a,b\n", string(body))
+	assert.True(t, strings.HasPrefix(contentType, "text/csv"))
+	assert.True(t, strings.HasPrefix(authorization, "AWS4-HMAC-SHA256 Credential=id/"))
+	assert.Equal(t, store.URL+path, objectUrl)
+
+	// The object's URL is relative to the public URL if there is one
+	publisher.config.PublicUrl = "https://results.example.com/"
+	objectUrl, err = publisher.Publish(context.Background(), localPath, keywords)
+	assert.NoError(t, err)
+	assert.Equal(t, "https://results.example.com/shortest-path/2026-10-16/guid-1/guid%201.csv",
+		objectUrl)
+
+	// The object store rejects the upload
+	status = http.StatusForbidden
+	_, err = publisher.Publish(context.Background(), localPath, keywords)
+	assert.ErrorIs(t, err, ErrUploadFailed)
+
+	// The file doesn't exist
+	_, err = publisher.Publish(context.Background(), localPath+".missing", keywords)
+	assert.Error(t, err)
+}
@@ -0,0 +1,128 @@
+package publish
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Formats of the times in a signature
+const (
+	amzDateFormat   = "20060102T150405Z"
+	amzDayFormat    = "20060102"
+	signatureScheme = "AWS4-HMAC-SHA256"
+	signedService   = "s3"
+)
+
+// credentials to sign a request.
+type credentials struct {
+	accessKeyId     string
+	secretAccessKey string
+	sessionToken    string // Optional
+}
+
+// hashReader returns the hex-encoded SHA-256 hash of the content of the reader.
+func hashReader(r io.Reader) (string, error) {
+	hash := sha256.New()
+	if _, err := io.Copy(hash, r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// hmacSha256 of the data with the key.
+func hmacSha256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// encodePath of an object, i.e. URI-encode everything except the unreserved characters and the
+// separators.
+func encodePath(path string) string {
+	var builder strings.Builder
+	for _, b := range []byte(path) {
+		if (b >= 'A' && b <= 'Z') || (b >= 'a' && b <= 'z') || (b >= '0' && b <= '9') ||
+			b == '-' || b == '_' || b == '.' || b == '~' || b == '/' {
+			builder.WriteByte(b)
+		} else {
+			fmt.Fprintf(&builder, "%%%02X", b)
+		}
+	}
+	return builder.String()
+}
+
+// canonicalHeaders of the request, i.e. the host and the headers that are set, in lower case and in
+// alphabetical order. Returns the canonical headers and the names of the signed headers.
+func canonicalHeaders(req *http.Request) (string, string) {
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var builder strings.Builder
+	for _, name := range names {
+		builder.WriteString(name + ":" + headers[name] + "\n")
+	}
+
+	return builder.String(), strings.Join(names, ";")
+}
+
+// signRequest with AWS Signature Version 4, where the payload hash is the hex-encoded SHA-256 hash
+// of the body. All of the headers set on the request are signed, so they must not change after
+// signing.
+func signRequest(req *http.Request, payloadHash string, creds credentials, region string,
+	now time.Time) {
+
+	now = now.UTC()
+	amzDate := now.Format(amzDateFormat)
+	day := now.Format(amzDayFormat)
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if len(creds.sessionToken) > 0 {
+		req.Header.Set("X-Amz-Security-Token", creds.sessionToken)
+	}
+
+	headers, signedHeaders := canonicalHeaders(req)
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		encodePath(req.URL.Path),
+		req.URL.Query().Encode(),
+		headers,
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := strings.Join([]string{day, region, signedService, "aws4_request"}, "/")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		signatureScheme,
+		amzDate,
+		scope,
+		hex.EncodeToString(requestHash[:]),
+	}, "\n")
+
+	key := hmacSha256([]byte("AWS4"+creds.secretAccessKey), day)
+	key = hmacSha256(key, region)
+	key = hmacSha256(key, signedService)
+	key = hmacSha256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSha256(key, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("%v Credential=%v/%v, SignedHeaders=%v, Signature=%v",
+		signatureScheme, creds.accessKeyId, scope, signedHeaders, signature))
+}
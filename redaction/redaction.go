@@ -0,0 +1,198 @@
+// Package redaction replaces the values of sensitive attributes of entities and documents with a
+// placeholder when they are shown to a user, i.e. on the entity page and in the i2 charts. The
+// values are kept in the graph stores, so they can still be searched for.
+package redaction
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/set"
+)
+
+// Default text that replaces the value of a redacted attribute
+const DefaultPlaceholder = "[REDACTED]"
+
+var ErrInvalidRedactionRules = errors.New("invalid redaction rules")
+
+// RulesConfig defines the attributes (of entities and documents) whose values are redacted.
+type RulesConfig struct {
+	Attributes  []string `json:"attributes"`  // Names of the attributes to redact
+	Patterns    []string `json:"patterns"`    // Regular expressions matching the names to redact
+	Placeholder string   `json:"placeholder"` // Text replacing a value (blank for the default)
+}
+
+// A Redactor replaces the values of the attributes that match its rules. A nil redactor doesn't
+// redact any attributes.
+type Redactor struct {
+	attributes  *set.Set[string] // Names of the attributes to redact
+	patterns    []*regexp.Regexp // Patterns matching the names of the attributes to redact
+	placeholder string           // Text replacing a value
+}
+
+// NewRedactor from the config.
+func NewRedactor(config RulesConfig) (*Redactor, error) {
+
+	attributes := set.NewSet[string]()
+	for _, attribute := range config.Attributes {
+		if len(attribute) == 0 {
+			return nil, fmt.Errorf("%w: empty attribute name", ErrInvalidRedactionRules)
+		}
+		attributes.Add(attribute)
+	}
+
+	patterns := []*regexp.Regexp{}
+	for _, pattern := range config.Patterns {
+		if len(pattern) == 0 {
+			return nil, fmt.Errorf("%w: empty pattern", ErrInvalidRedactionRules)
+		}
+
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("%w: pattern %v: %v", ErrInvalidRedactionRules, pattern, err)
+		}
+		patterns = append(patterns, re)
+	}
+
+	placeholder := config.Placeholder
+	if len(placeholder) == 0 {
+		placeholder = DefaultPlaceholder
+	}
+
+	return &Redactor{
+		attributes:  attributes,
+		patterns:    patterns,
+		placeholder: placeholder,
+	}, nil
+}
+
+// ReadRedactor from a JSON file of the rules.
+func ReadRedactor(filepath string) (*Redactor, error) {
+
+	bytes, err := os.ReadFile(filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	var config RulesConfig
+	if err := json.Unmarshal(bytes, &config); err != nil {
+		return nil, err
+	}
+
+	return NewRedactor(config)
+}
+
+// Placeholder that replaces the value of a redacted attribute.
+func (r *Redactor) Placeholder() string {
+	if r == nil {
+		return DefaultPlaceholder
+	}
+	return r.placeholder
+}
+
+// IsRedacted returns true if the value of the attribute should be redacted.
+func (r *Redactor) IsRedacted(attribute string) bool {
+
+	if r == nil {
+		return false
+	}
+
+	if r.attributes.Has(attribute) {
+		return true
+	}
+
+	for _, pattern := range r.patterns {
+		if pattern.MatchString(attribute) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Redact the attributes, returning a copy with the values of the redacted attributes replaced by
+// the placeholder. The attributes are returned unchanged if nothing is redacted.
+func (r *Redactor) Redact(attributes map[string]string) map[string]string {
+
+	if r == nil || attributes == nil {
+		return attributes
+	}
+
+	redacted := make(map[string]string, len(attributes))
+	for attribute, value := range attributes {
+		if r.IsRedacted(attribute) {
+			redacted[attribute] = r.placeholder
+		} else {
+			redacted[attribute] = value
+		}
+	}
+
+	return redacted
+}
+
+// Entity with its attributes redacted. The entity in the store isn't modified.
+func (r *Redactor) Entity(entity *graphstore.Entity) *graphstore.Entity {
+
+	if r == nil || entity == nil {
+		return entity
+	}
+
+	redacted := *entity
+	redacted.Attributes = r.Redact(entity.Attributes)
+	return &redacted
+}
+
+// Document with its attributes redacted. The document in the store isn't modified.
+func (r *Redactor) Document(document *graphstore.Document) *graphstore.Document {
+
+	if r == nil || document == nil {
+		return document
+	}
+
+	redacted := *document
+	redacted.Attributes = r.Redact(document.Attributes)
+	return &redacted
+}
+
+// redactingBipartiteStore wraps a bipartite store and redacts the attributes of the entities and
+// documents it returns. All other operations are passed to the wrapped store.
+type redactingBipartiteStore struct {
+	graphstore.BipartiteGraphStore
+	redactor *Redactor
+}
+
+// GetEntity from the wrapped store with its attributes redacted.
+func (s *redactingBipartiteStore) GetEntity(entityId string) (*graphstore.Entity, error) {
+	entity, err := s.BipartiteGraphStore.GetEntity(entityId)
+	if err != nil {
+		return nil, err
+	}
+	return s.redactor.Entity(entity), nil
+}
+
+// GetDocument from the wrapped store with its attributes redacted.
+func (s *redactingBipartiteStore) GetDocument(documentId string) (*graphstore.Document, error) {
+	document, err := s.BipartiteGraphStore.GetDocument(documentId)
+	if err != nil {
+		return nil, err
+	}
+	return s.redactor.Document(document), nil
+}
+
+// Bipartite store whose entities and documents have their attributes redacted. The store is
+// returned unchanged if the redactor is nil.
+func (r *Redactor) Bipartite(store graphstore.BipartiteGraphStore) graphstore.BipartiteGraphStore {
+
+	if r == nil || store == nil {
+		return store
+	}
+
+	return &redactingBipartiteStore{
+		BipartiteGraphStore: store,
+		redactor:            r,
+	}
+}
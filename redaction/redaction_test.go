@@ -0,0 +1,125 @@
+package redaction
+
+import (
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRedactor(t *testing.T) {
+	testCases := []struct {
+		config RulesConfig
+		valid  bool
+	}{
+		{RulesConfig{}, true},
+		{RulesConfig{Attributes: []string{"DOB"}, Patterns: []string{"(?i)passport"}}, true},
+		{RulesConfig{Attributes: []string{""}}, false},
+		{RulesConfig{Patterns: []string{""}}, false},
+		{RulesConfig{Patterns: []string{"[a-"}}, false},
+	}
+
+	for _, testCase := range testCases {
+		redactor, err := NewRedactor(testCase.config)
+		if testCase.valid {
+			assert.NoError(t, err)
+			assert.Equal(t, DefaultPlaceholder, redactor.Placeholder())
+		} else {
+			assert.ErrorIs(t, err, ErrInvalidRedactionRules)
+			assert.Nil(t, redactor)
+		}
+	}
+}
+
+func TestReadRedactor(t *testing.T) {
+	redactor, err := ReadRedactor("./test-data/rules.json")
+	assert.NoError(t, err)
+	assert.Equal(t, "WITHHELD", redactor.Placeholder())
+
+	assert.True(t, redactor.IsRedacted("DOB"))
+	assert.False(t, redactor.IsRedacted("dob"))
+	assert.True(t, redactor.IsRedacted("Passport number"))
+	assert.False(t, redactor.IsRedacted("Forename"))
+
+	_, err = ReadRedactor("./test-data/missing.json")
+	assert.Error(t, err)
+}
+
+func TestRedact(t *testing.T) {
+	attributes := map[string]string{
+		"Forename": "Bob",
+		"DOB":      "03/04/1981",
+	}
+
+	// A nil redactor doesn't redact anything
+	var redactor *Redactor
+	assert.False(t, redactor.IsRedacted("DOB"))
+	assert.Equal(t, attributes, redactor.Redact(attributes))
+
+	redactor, err := NewRedactor(RulesConfig{Attributes: []string{"DOB"}})
+	assert.NoError(t, err)
+
+	expected := map[string]string{
+		"Forename": "Bob",
+		"DOB":      DefaultPlaceholder,
+	}
+	assert.Equal(t, expected, redactor.Redact(attributes))
+
+	// The attributes aren't modified
+	assert.Equal(t, "03/04/1981", attributes["DOB"])
+	assert.Nil(t, redactor.Redact(nil))
+}
+
+func TestRedactingBipartiteStore(t *testing.T) {
+	store := graphstore.NewInMemoryBipartiteGraphStore()
+
+	entity, err := graphstore.NewEntity("e-1", "Person", map[string]string{
+		"Forename": "Bob",
+		"DOB":      "03/04/1981",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, store.AddEntity(entity))
+
+	document, err := graphstore.NewDocument("d-1", "Report", map[string]string{
+		"Title":    "Summary",
+		"Passport": "123456789",
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, store.AddDocument(document))
+	assert.NoError(t, store.AddLink(graphstore.NewLink("e-1", "d-1")))
+
+	// A nil redactor returns the store
+	var redactor *Redactor
+	assert.Equal(t, store, redactor.Bipartite(store))
+
+	redactor, err = NewRedactor(RulesConfig{
+		Attributes: []string{"DOB"},
+		Patterns:   []string{"^Pass"},
+	})
+	assert.NoError(t, err)
+	redacted := redactor.Bipartite(store)
+
+	actualEntity, err := redacted.GetEntity("e-1")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"Forename": "Bob",
+		"DOB":      DefaultPlaceholder,
+	}, actualEntity.Attributes)
+	assert.True(t, actualEntity.LinkedDocumentIds.Has("d-1"))
+
+	actualDocument, err := redacted.GetDocument("d-1")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{
+		"Title":    "Summary",
+		"Passport": DefaultPlaceholder,
+	}, actualDocument.Attributes)
+
+	// The values are still in the store
+	storedEntity, err := store.GetEntity("e-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "03/04/1981", storedEntity.Attributes["DOB"])
+
+	n, err := redacted.NumberOfEntities()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+}
@@ -0,0 +1,113 @@
+package search
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+)
+
+// Header of the bulk search report
+var BulkReportHeader = []string{"Entity ID", "In bipartite", "In unipartite", "Entity type",
+	"Attributes", "Number of documents", "Degree", "Suggestions"}
+
+// BulkEntity is the summary of an entity in a bulk search.
+type BulkEntity struct {
+	EntityId          string      // Entity ID searched for
+	InBipartite       bool        // Is the entity in the bipartite store?
+	InUnipartite      bool        // Is the entity in the unipartite store?
+	EntityType        string      // Entity type from the bipartite store
+	Attributes        []Attribute // Sorted attributes from the bipartite store
+	NumberOfDocuments int         // Number of documents linked to the entity in the bipartite store
+	Degree            int         // Number of entities connected to the entity in the unipartite store
+	Suggestions       []string    // Similar entity IDs if the entity wasn't found
+}
+
+// BulkSearch for the entities in the bipartite and unipartite stores, returning a summary of each
+// entity in the order given.
+func (es *EntitySearch) BulkSearch(entityIds []string) ([]BulkEntity, error) {
+
+	results := []BulkEntity{}
+
+	for _, entityId := range entityIds {
+		result := BulkEntity{EntityId: entityId}
+
+		// The stores are searched using the canonical ID if the entity ID is an alias
+		canonicalId, err := es.Bipartite.ResolveAlias(entityId)
+		if err != nil {
+			return nil, err
+		}
+
+		entity, err := es.Bipartite.GetEntity(canonicalId)
+		if err == nil {
+			result.InBipartite = true
+			result.EntityType = entity.EntityType
+			result.Attributes = convertAndSortAttributes(entity.Attributes)
+			if entity.LinkedDocumentIds != nil {
+				result.NumberOfDocuments = entity.LinkedDocumentIds.Len()
+			}
+		} else if err != graphstore.ErrEntityNotFound {
+			return nil, err
+		}
+
+		result.InUnipartite, err = es.Unipartite.HasEntity(canonicalId)
+		if err != nil {
+			return nil, err
+		}
+
+		if result.InUnipartite {
+			connected, err := es.Unipartite.EntityIdsConnectedTo(canonicalId)
+			if err != nil {
+				return nil, err
+			}
+			result.Degree = connected.Len()
+		}
+
+		if !result.InBipartite && !result.InUnipartite {
+			result.Suggestions = es.Suggest(entityId)
+		}
+
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// attributeSummary of the attributes, e.g. "Forename: Bob; Surname: Smith".
+func attributeSummary(attributes []Attribute) string {
+	parts := make([]string, len(attributes))
+	for idx, attribute := range attributes {
+		parts[idx] = fmt.Sprintf("%v: %v", attribute.Key, attribute.Value)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// yesNo for a boolean in the report.
+func yesNo(value bool) string {
+	if value {
+		return "Yes"
+	}
+	return "No"
+}
+
+// BulkReportRows of the results of a bulk search, including the header.
+func BulkReportRows(results []BulkEntity) [][]string {
+
+	rows := [][]string{BulkReportHeader}
+
+	for _, result := range results {
+		rows = append(rows, []string{
+			result.EntityId,
+			yesNo(result.InBipartite),
+			yesNo(result.InUnipartite),
+			result.EntityType,
+			attributeSummary(result.Attributes),
+			strconv.Itoa(result.NumberOfDocuments),
+			strconv.Itoa(result.Degree),
+			strings.Join(result.Suggestions, ", "),
+		})
+	}
+
+	return rows
+}
@@ -0,0 +1,44 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphbuilder"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBulkSearch(t *testing.T) {
+
+	graphBuilder, _, err := graphbuilder.NewGraphBuilderFromJson("../test-data-sets/set-0/config-inmemory.json")
+	assert.NoError(t, err)
+	defer graphBuilder.Destroy()
+
+	engine, err := NewEntitySearch(graphBuilder.Bipartite, graphBuilder.Unipartite)
+	assert.NoError(t, err)
+
+	results, err := engine.BulkSearch([]string{"e-5", "e-1"})
+	assert.NoError(t, err)
+
+	expected := []BulkEntity{
+		{
+			EntityId: "e-5",
+		},
+		{
+			EntityId:          "e-1",
+			InBipartite:       true,
+			InUnipartite:      true,
+			EntityType:        "Person",
+			Attributes:        []Attribute{{Key: "Full Name", Value: "Bob Smith"}},
+			NumberOfDocuments: 3,
+			Degree:            2,
+		},
+	}
+	assert.Equal(t, expected, results)
+
+	rows := BulkReportRows(results)
+	assert.Equal(t, [][]string{
+		BulkReportHeader,
+		{"e-5", "No", "No", "", "", "0", "0", ""},
+		{"e-1", "Yes", "Yes", "Person", "Full Name: Bob Smith", "3", "2", ""},
+	}, rows)
+}
@@ -0,0 +1,96 @@
+package search
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/cdclaxton/shortest-path-web-app/set"
+)
+
+// Constants associated with paging the documents and entities linked to an entity
+const (
+	DefaultPageSize = 100  // Number of results on a page if the limit isn't given
+	MaxPageSize     = 1000 // Maximum number of results on a page
+)
+
+var ErrInvalidPage = errors.New("invalid page")
+
+// A Page of results to return, where a limit of zero returns all of the results.
+type Page struct {
+	Offset int // Number of results to skip
+	Limit  int // Maximum number of results to return
+}
+
+// AllResults is the page containing every result.
+var AllResults = Page{}
+
+// NewPage given the offset and the limit.
+func NewPage(offset int, limit int) (Page, error) {
+
+	// Preconditions
+	if offset < 0 || limit < 1 || limit > MaxPageSize {
+		return Page{}, ErrInvalidPage
+	}
+
+	return Page{
+		Offset: offset,
+		Limit:  limit,
+	}, nil
+}
+
+// PageDetails describes the page of results that was returned.
+type PageDetails struct {
+	Offset         int  // Number of results skipped
+	Limit          int  // Maximum number of results on the page (zero if unlimited)
+	Total          int  // Total number of results
+	First          int  // Position of the first result on the page (from 1, zero if empty)
+	Last           int  // Position of the last result on the page (zero if empty)
+	HasPrevious    bool // Is there a page before this one?
+	HasNext        bool // Is there a page after this one?
+	PreviousOffset int  // Offset of the previous page
+	NextOffset     int  // Offset of the next page
+}
+
+// sortedIds from the set.
+func sortedIds(ids *set.Set[string]) []string {
+	result := ids.ToSlice()
+	sort.Strings(result)
+	return result
+}
+
+// apply the page to the sorted IDs, returning the IDs on the page.
+func (p Page) apply(ids []string) ([]string, PageDetails) {
+
+	start := p.Offset
+	if start > len(ids) {
+		start = len(ids)
+	}
+
+	end := len(ids)
+	if p.Limit > 0 && start+p.Limit < end {
+		end = start + p.Limit
+	}
+
+	details := PageDetails{
+		Offset:      p.Offset,
+		Limit:       p.Limit,
+		Total:       len(ids),
+		HasPrevious: start > 0,
+		HasNext:     end < len(ids),
+		NextOffset:  end,
+	}
+
+	if end > start {
+		details.First = start + 1
+		details.Last = end
+	}
+
+	if details.HasPrevious {
+		details.PreviousOffset = start - p.Limit
+		if details.PreviousOffset < 0 {
+			details.PreviousOffset = 0
+		}
+	}
+
+	return ids[start:end], details
+}
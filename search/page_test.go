@@ -0,0 +1,109 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewPage(t *testing.T) {
+
+	_, err := NewPage(-1, 10)
+	assert.ErrorIs(t, err, ErrInvalidPage)
+
+	_, err = NewPage(0, 0)
+	assert.ErrorIs(t, err, ErrInvalidPage)
+
+	_, err = NewPage(0, MaxPageSize+1)
+	assert.ErrorIs(t, err, ErrInvalidPage)
+
+	page, err := NewPage(10, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, Page{Offset: 10, Limit: 5}, page)
+}
+
+func TestApplyPage(t *testing.T) {
+
+	ids := []string{"e-1", "e-2", "e-3", "e-4", "e-5"}
+
+	testCases := []struct {
+		page            Page
+		expectedIds     []string
+		expectedDetails PageDetails
+	}{
+		{
+			// All of the results
+			page:        AllResults,
+			expectedIds: ids,
+			expectedDetails: PageDetails{
+				Total:      5,
+				First:      1,
+				Last:       5,
+				NextOffset: 5,
+			},
+		},
+		{
+			// First page
+			page:        Page{Offset: 0, Limit: 2},
+			expectedIds: []string{"e-1", "e-2"},
+			expectedDetails: PageDetails{
+				Limit:      2,
+				Total:      5,
+				First:      1,
+				Last:       2,
+				HasNext:    true,
+				NextOffset: 2,
+			},
+		},
+		{
+			// Middle page
+			page:        Page{Offset: 2, Limit: 2},
+			expectedIds: []string{"e-3", "e-4"},
+			expectedDetails: PageDetails{
+				Offset:         2,
+				Limit:          2,
+				Total:          5,
+				First:          3,
+				Last:           4,
+				HasPrevious:    true,
+				HasNext:        true,
+				PreviousOffset: 0,
+				NextOffset:     4,
+			},
+		},
+		{
+			// Last page
+			page:        Page{Offset: 4, Limit: 2},
+			expectedIds: []string{"e-5"},
+			expectedDetails: PageDetails{
+				Offset:         4,
+				Limit:          2,
+				Total:          5,
+				First:          5,
+				Last:           5,
+				HasPrevious:    true,
+				PreviousOffset: 2,
+				NextOffset:     5,
+			},
+		},
+		{
+			// Beyond the last page
+			page:        Page{Offset: 10, Limit: 2},
+			expectedIds: []string{},
+			expectedDetails: PageDetails{
+				Offset:         10,
+				Limit:          2,
+				Total:          5,
+				HasPrevious:    true,
+				PreviousOffset: 3,
+				NextOffset:     5,
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		actualIds, actualDetails := testCase.page.apply(ids)
+		assert.Equal(t, testCase.expectedIds, actualIds)
+		assert.Equal(t, testCase.expectedDetails, actualDetails)
+	}
+}
@@ -15,6 +15,7 @@ const componentName = "search"
 type EntitySearch struct {
 	Bipartite  graphstore.BipartiteGraphStore
 	Unipartite graphstore.UnipartiteGraphStore
+	index      *IdIndex // Index for suggesting similar entity IDs (optional)
 }
 
 // NewEntitySearch given the bipartite and unipartite stores.
@@ -40,22 +41,49 @@ func NewEntitySearch(bipartite graphstore.BipartiteGraphStore,
 	}, nil
 }
 
+// BuildSuggestionIndex builds the index of entity IDs used to suggest similar entity IDs when an
+// entity isn't found. It should be called once the stores have been loaded.
+func (es *EntitySearch) BuildSuggestionIndex() error {
+
+	index, err := BuildIdIndex(es.Bipartite, es.Unipartite)
+	if err != nil {
+		return err
+	}
+
+	es.index = index
+	return nil
+}
+
+// Suggest entity IDs similar to an entity ID. No suggestions are made if the index hasn't been
+// built.
+func (es *EntitySearch) Suggest(entityId string) []string {
+	return es.index.Suggest(entityId)
+}
+
 // EntitySearchResult for a single entity.
 type EntitySearchResult struct {
 	InUnipartite bool
 	InBipartite  bool
+	AliasOf      string   `json:",omitempty"` // Canonical entity ID if the entity ID is an alias
+	Suggestions  []string `json:",omitempty"` // Similar entity IDs if the entity wasn't found
 }
 
-// Search for entities given their IDs in the bipartite and unipartite stores.
+// Search for entities given their IDs (or aliases) in the bipartite and unipartite stores.
 func (es *EntitySearch) Search(entityIds []string) (map[string]EntitySearchResult, error) {
 
 	searchResult := map[string]EntitySearchResult{}
 
-	for _, entityId := range entityIds {
+	for _, requestedId := range entityIds {
+
+		// Resolve the entity ID if it is an alias
+		entityId, err := es.Bipartite.ResolveAlias(requestedId)
+		if err != nil {
+			return nil, err
+		}
 
 		// Try to find the entity in the bipartite graph
 		var entityInBipartite bool
-		_, err := es.Bipartite.GetEntity(entityId)
+		_, err = es.Bipartite.GetEntity(entityId)
 		if err == graphstore.ErrEntityNotFound {
 			entityInBipartite = false
 		} else if err != nil {
@@ -70,10 +98,20 @@ func (es *EntitySearch) Search(entityIds []string) (map[string]EntitySearchResul
 			return nil, err
 		}
 
-		searchResult[entityId] = EntitySearchResult{
+		result := EntitySearchResult{
 			InUnipartite: entityInUnipartite,
 			InBipartite:  entityInBipartite,
 		}
+
+		if entityId != requestedId {
+			result.AliasOf = entityId
+		}
+
+		if !entityInUnipartite && !entityInBipartite {
+			result.Suggestions = es.Suggest(entityId)
+		}
+
+		searchResult[requestedId] = result
 	}
 
 	return searchResult, nil
@@ -101,10 +139,11 @@ type BipartiteDocument struct {
 
 // BipartiteDetails for an entity derived from the bipartite store.
 type BipartiteDetails struct {
-	InBipartite      bool                // Is the entity in the bipartite store?
-	EntityType       string              // Entity type, e.g. Person
-	EntityAttributes []Attribute         // Sorted list of entity attributes
-	LinkedDocuments  []BipartiteDocument // Sorted list of documents linked to the entity
+	InBipartite         bool                // Is the entity in the bipartite store?
+	EntityType          string              // Entity type, e.g. Person
+	EntityAttributes    []Attribute         // Sorted list of entity attributes
+	LinkedDocuments     []BipartiteDocument // Sorted page of documents linked to the entity
+	LinkedDocumentsPage PageDetails         // Page of the linked documents
 }
 
 // EntityPresence holds whether the entity exists in the bipartite and unipartite stores.
@@ -116,11 +155,14 @@ type EntityPresence struct {
 
 // SearchEntity is the result of search for an entity in the bipartite and unipartite stores.
 type SearchEntity struct {
-	EntityId         string           // Unique entity ID
-	Error            ErrorDetails     // Error that occurred whilst finding the entity
-	BipartiteDetails BipartiteDetails // Entity information from the bipartite store
-	InUnipartite     bool             // Is the entity in the unipartite store?
-	LinkedEntities   []EntityPresence // Entities linked to the entity of interest
+	EntityId           string           // Unique entity ID
+	Alias              string           // Entity ID searched for if it is an alias of the entity
+	Error              ErrorDetails     // Error that occurred whilst finding the entity
+	BipartiteDetails   BipartiteDetails // Entity information from the bipartite store
+	InUnipartite       bool             // Is the entity in the unipartite store?
+	Suggestions        []string         // Similar entity IDs if the entity wasn't found
+	LinkedEntities     []EntityPresence // Page of entities linked to the entity of interest
+	LinkedEntitiesPage PageDetails      // Page of the linked entities
 }
 
 // NewSearchEntity instantiates a SearchEntity struct for a given entity ID.
@@ -152,13 +194,16 @@ func convertAndSortAttributes(attributes map[string]string) []Attribute {
 	return result
 }
 
-// extractDocuments from the bipartite store given their document IDs.
-func (es *EntitySearch) extractDocuments(docIds *set.Set[string]) ([]BipartiteDocument, error) {
+// extractDocuments from the bipartite store given their document IDs. Only the documents on the
+// page are retrieved.
+func (es *EntitySearch) extractDocuments(docIds *set.Set[string], page Page) ([]BipartiteDocument,
+	PageDetails, error) {
 
 	docs := []BipartiteDocument{}
+	pageDocIds, details := page.apply(sortedIds(docIds))
 
 	// Get each of the documents
-	for _, docId := range docIds.ToSlice() {
+	for _, docId := range pageDocIds {
 
 		// Try to get the document from the bipartite store
 		doc, err := es.Bipartite.GetDocument(docId)
@@ -176,7 +221,7 @@ func (es *EntitySearch) extractDocuments(docIds *set.Set[string]) ([]BipartiteDo
 		} else if err != nil {
 
 			// An error occurred trying to get the document
-			return []BipartiteDocument{}, err
+			return []BipartiteDocument{}, PageDetails{}, err
 		}
 
 		// The document was retrieved successfully
@@ -193,11 +238,12 @@ func (es *EntitySearch) extractDocuments(docIds *set.Set[string]) ([]BipartiteDo
 		return docs[i].DocumentId < docs[j].DocumentId
 	})
 
-	return docs, nil
+	return docs, details, nil
 }
 
 // entityToBipartiteDetails gets the entity information from the bipartite store.
-func (es *EntitySearch) entityToBipartiteDetails(bipartiteEntity *graphstore.Entity) (BipartiteDetails, error) {
+func (es *EntitySearch) entityToBipartiteDetails(bipartiteEntity *graphstore.Entity,
+	page Page) (BipartiteDetails, error) {
 
 	// Preconditions
 	if bipartiteEntity == nil {
@@ -210,16 +256,17 @@ func (es *EntitySearch) entityToBipartiteDetails(bipartiteEntity *graphstore.Ent
 	}
 
 	// Extract the documents associated with the entity from the bipartite store
-	documents, err := es.extractDocuments(bipartiteEntity.LinkedDocumentIds)
+	documents, details, err := es.extractDocuments(bipartiteEntity.LinkedDocumentIds, page)
 	if err != nil {
 		return BipartiteDetails{}, err
 	}
 
 	return BipartiteDetails{
-		InBipartite:      true,
-		EntityType:       bipartiteEntity.EntityType,
-		EntityAttributes: convertAndSortAttributes(bipartiteEntity.Attributes),
-		LinkedDocuments:  documents,
+		InBipartite:         true,
+		EntityType:          bipartiteEntity.EntityType,
+		EntityAttributes:    convertAndSortAttributes(bipartiteEntity.Attributes),
+		LinkedDocuments:     documents,
+		LinkedDocumentsPage: details,
 	}, nil
 }
 
@@ -255,19 +302,27 @@ func (es *EntitySearch) entityIdsFromBipartite(entityId string) *set.Set[string]
 
 // linkedEntityPresence returns the entity existence for entities linked to a central entity.
 func (es *EntitySearch) linkedEntityPresence(entityId string) ([]EntityPresence, error) {
+	presence, _, err := es.linkedEntityPresencePage(entityId, AllResults)
+	return presence, err
+}
+
+// linkedEntityPresencePage returns the entity existence for the page of entities linked to a
+// central entity. The presence is only checked for the entities on the page.
+func (es *EntitySearch) linkedEntityPresencePage(entityId string, page Page) ([]EntityPresence,
+	PageDetails, error) {
 
 	// Is the entity in the unipartite graph store?
 	inUnipartite, err := es.Unipartite.HasEntity(entityId)
 	if err != nil {
-		return []EntityPresence{}, err
+		return []EntityPresence{}, PageDetails{}, err
 	}
 
-	// Get the adjacent entity IDs from the unipartite store
+	// Get the connected entity IDs from the unipartite store (in either direction)
 	var entityIds *set.Set[string]
 	if inUnipartite {
-		entityIds, err = es.Unipartite.EntityIdsAdjacentTo(entityId)
+		entityIds, err = es.Unipartite.EntityIdsConnectedTo(entityId)
 		if err != nil {
-			return []EntityPresence{}, err
+			return []EntityPresence{}, PageDetails{}, err
 		}
 	} else {
 		entityIds = set.NewSet[string]()
@@ -275,25 +330,23 @@ func (es *EntitySearch) linkedEntityPresence(entityId string) ([]EntityPresence,
 
 	// Get the entities connected to the entity of interest from the bipartite graph store
 	entityIdsFromBipartite := es.entityIdsFromBipartite(entityId)
-	if err != nil {
-		return []EntityPresence{}, err
-	}
-
 	entityIds.AddAll(entityIdsFromBipartite.ToSlice())
 
-	// Determine whether the entities can be found in the unipartite and bipartite graphs
+	// Determine whether the entities on the page can be found in the unipartite and bipartite
+	// graphs
 	presence := []EntityPresence{}
+	pageEntityIds, details := page.apply(sortedIds(entityIds))
 
-	for _, connectionEntityId := range entityIds.ToSlice() {
+	for _, connectionEntityId := range pageEntityIds {
 
 		connectionInUnipartite, err := es.Unipartite.HasEntity(connectionEntityId)
 		if err != nil {
-			return []EntityPresence{}, err
+			return []EntityPresence{}, PageDetails{}, err
 		}
 
 		connectionInBipartite, err := es.Bipartite.HasEntityWithId(connectionEntityId)
 		if err != nil {
-			return []EntityPresence{}, err
+			return []EntityPresence{}, PageDetails{}, err
 		}
 
 		presence = append(presence, EntityPresence{
@@ -303,16 +356,45 @@ func (es *EntitySearch) linkedEntityPresence(entityId string) ([]EntityPresence,
 		})
 	}
 
-	// Sort the entities by ID
-	sort.Slice(presence, func(i, j int) bool {
-		return presence[i].EntityId < presence[j].EntityId
-	})
-
-	return presence, nil
+	return presence, details, nil
 }
 
-// GetEntity looks for an entity in the bipartite and unipartite stores.
+// GetEntity looks for an entity in the bipartite and unipartite stores, returning all of its
+// linked documents and entities.
 func (es *EntitySearch) GetEntity(entityId string) SearchEntity {
+	return es.GetEntityPage(entityId, AllResults, AllResults)
+}
+
+// GetEntityPage looks for an entity in the bipartite and unipartite stores, returning a page of its
+// linked documents and a page of its linked entities. The totals are given in the page details. If
+// the entity isn't in either store, then similar entity IDs are suggested.
+func (es *EntitySearch) GetEntityPage(entityId string, documents Page, entities Page) SearchEntity {
+
+	// Resolve the entity ID if it is an alias
+	canonicalId, err := es.Bipartite.ResolveAlias(entityId)
+	if err != nil {
+		entity := NewSearchEntity(entityId)
+		entity.Error = ErrorDetails{
+			ErrorOccurred: true,
+			ErrorMessage:  err.Error(),
+		}
+		return entity
+	}
+
+	entity := es.getEntityPage(canonicalId, documents, entities)
+	if canonicalId != entityId {
+		entity.Alias = entityId
+	}
+
+	if !entity.Error.ErrorOccurred && !entity.InUnipartite && !entity.BipartiteDetails.InBipartite {
+		entity.Suggestions = es.Suggest(entityId)
+	}
+
+	return entity
+}
+
+// getEntityPage looks for an entity in the bipartite and unipartite stores.
+func (es *EntitySearch) getEntityPage(entityId string, documents Page, entities Page) SearchEntity {
 
 	entity := NewSearchEntity(entityId)
 
@@ -331,7 +413,7 @@ func (es *EntitySearch) GetEntity(entityId string) SearchEntity {
 
 	} else {
 		// Entity exists in the bipartite store
-		entity.BipartiteDetails, err = es.entityToBipartiteDetails(bipartiteEntity)
+		entity.BipartiteDetails, err = es.entityToBipartiteDetails(bipartiteEntity, documents)
 		if err != nil {
 			entity.Error = ErrorDetails{
 				ErrorOccurred: true,
@@ -350,7 +432,8 @@ func (es *EntitySearch) GetEntity(entityId string) SearchEntity {
 	}
 
 	// Get the linked entities by checking the unipartite and bipartite stores
-	entity.LinkedEntities, err = es.linkedEntityPresence(entityId)
+	entity.LinkedEntities, entity.LinkedEntitiesPage, err = es.linkedEntityPresencePage(entityId,
+		entities)
 	if err != nil {
 		entity.Error = ErrorDetails{
 			ErrorOccurred: true,
@@ -130,6 +130,54 @@ func TestSearch(t *testing.T) {
 	}
 }
 
+func TestSearchWithAliases(t *testing.T) {
+
+	graphBuilder, _, err := graphbuilder.NewGraphBuilderFromJson(
+		"../test-data-sets/set-0/config-inmemory.json")
+	assert.NoError(t, err)
+	defer graphBuilder.Destroy()
+
+	assert.NoError(t, graphBuilder.Bipartite.AddAlias("old-1", "e-1"))
+
+	engine, err := NewEntitySearch(graphBuilder.Bipartite, graphBuilder.Unipartite)
+	assert.NoError(t, err)
+
+	// Search
+	actual, err := engine.Search([]string{"old-1", "e-1"})
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]EntitySearchResult{
+		"old-1": {
+			InUnipartite: true,
+			InBipartite:  true,
+			AliasOf:      "e-1",
+		},
+		"e-1": {
+			InUnipartite: true,
+			InBipartite:  true,
+		},
+	}, actual)
+
+	// Entity page
+	expected := engine.GetEntity("e-1")
+	assert.Empty(t, expected.Alias)
+
+	searchResult := engine.GetEntity("old-1")
+	assert.Equal(t, "e-1", searchResult.EntityId)
+	assert.Equal(t, "old-1", searchResult.Alias)
+
+	searchResult.Alias = ""
+	assert.Equal(t, expected, searchResult)
+
+	// Bulk search
+	bulk, err := engine.BulkSearch([]string{"old-1"})
+	assert.NoError(t, err)
+	assert.Len(t, bulk, 1)
+	assert.Equal(t, "old-1", bulk[0].EntityId)
+	assert.True(t, bulk[0].InBipartite)
+	assert.True(t, bulk[0].InUnipartite)
+	assert.Equal(t, 2, bulk[0].Degree)
+}
+
 func TestConvertAndSortAttributes(t *testing.T) {
 
 	testCases := []struct {
@@ -265,9 +313,10 @@ func TestExtractDocuments(t *testing.T) {
 		engine, err := NewEntitySearch(graphBuilder.Bipartite, graphBuilder.Unipartite)
 		assert.NoError(t, err)
 
-		actual, err := engine.extractDocuments(documentIds)
+		actual, details, err := engine.extractDocuments(documentIds, AllResults)
 		assert.NoError(t, err)
 		assert.Equal(t, expected, actual)
+		assert.Equal(t, len(expected), details.Total)
 
 		// Destroy the graph databases
 		graphBuilder.Destroy()
@@ -370,6 +419,12 @@ func TestEntityToBipartiteDetails(t *testing.T) {
 				Attributes:   []Attribute{},
 			},
 		},
+		LinkedDocumentsPage: PageDetails{
+			Total:      3,
+			First:      1,
+			Last:       3,
+			NextOffset: 3,
+		},
 	}
 
 	for _, backend := range backends {
@@ -387,10 +442,10 @@ func TestEntityToBipartiteDetails(t *testing.T) {
 		assert.NoError(t, err)
 
 		// Try to get an entity that doesn't exist
-		_, err = engine.entityToBipartiteDetails(&entityNotInStore)
+		_, err = engine.entityToBipartiteDetails(&entityNotInStore, AllResults)
 		assert.Error(t, err)
 
-		actual, err := engine.entityToBipartiteDetails(&entity)
+		actual, err := engine.entityToBipartiteDetails(&entity, AllResults)
 		assert.NoError(t, err)
 		assert.Equal(t, expected, actual)
 
@@ -601,6 +656,12 @@ func TestEntitySearch(t *testing.T) {
 						},
 					},
 				},
+				LinkedDocumentsPage: PageDetails{
+					Total:      3,
+					First:      1,
+					Last:       3,
+					NextOffset: 3,
+				},
 			},
 			InUnipartite: true,
 			LinkedEntities: []EntityPresence{
@@ -615,9 +676,41 @@ func TestEntitySearch(t *testing.T) {
 					InUnipartite: true,
 				},
 			},
+			LinkedEntitiesPage: PageDetails{
+				Total:      2,
+				First:      1,
+				Last:       2,
+				NextOffset: 2,
+			},
 		}
 		assert.Equal(t, expected, searchResult)
 
+		// Get a page of the linked documents and entities
+		searchResult = engine.GetEntityPage("e-1", Page{Offset: 1, Limit: 1}, Page{Offset: 0, Limit: 1})
+		assert.False(t, searchResult.Error.ErrorOccurred)
+		assert.Equal(t, 1, len(searchResult.BipartiteDetails.LinkedDocuments))
+		assert.Equal(t, "d-2", searchResult.BipartiteDetails.LinkedDocuments[0].DocumentId)
+		assert.Equal(t, PageDetails{
+			Offset:         1,
+			Limit:          1,
+			Total:          3,
+			First:          2,
+			Last:           2,
+			HasPrevious:    true,
+			HasNext:        true,
+			PreviousOffset: 0,
+			NextOffset:     2,
+		}, searchResult.BipartiteDetails.LinkedDocumentsPage)
+		assert.Equal(t, []EntityPresence{
+			{
+				EntityId:     "e-2",
+				InBipartite:  true,
+				InUnipartite: true,
+			},
+		}, searchResult.LinkedEntities)
+		assert.Equal(t, 2, searchResult.LinkedEntitiesPage.Total)
+		assert.True(t, searchResult.LinkedEntitiesPage.HasNext)
+
 		// Destroy the graph databases
 		graphBuilder.Destroy()
 	}
@@ -0,0 +1,180 @@
+package search
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cdclaxton/shortest-path-web-app/set"
+)
+
+// Constants associated with suggesting similar entity IDs
+const (
+	MaxSuggestions        = 5 // Maximum number of suggestions for an entity ID
+	MaxSuggestionDistance = 2 // Maximum edit distance between an entity ID and a suggestion
+)
+
+var ErrIdIndexIsNil = errors.New("entity ID index is nil")
+
+// An IdIndex holds the entity IDs in the stores so that similar entity IDs can be suggested when an
+// entity ID isn't found. IDs are compared after normalisation (lower case without whitespace).
+type IdIndex struct {
+	normalised map[string][]string // Normalised ID to the entity IDs
+	byLength   map[int][]string    // Normalised IDs by their length in runes
+}
+
+// normaliseId by converting it to lower case and removing whitespace.
+func normaliseId(entityId string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return -1
+		}
+		return unicode.ToLower(r)
+	}, entityId)
+}
+
+// NewIdIndex from a set of entity IDs.
+func NewIdIndex(entityIds *set.Set[string]) *IdIndex {
+
+	index := &IdIndex{
+		normalised: map[string][]string{},
+		byLength:   map[int][]string{},
+	}
+
+	if entityIds == nil {
+		return index
+	}
+
+	for _, entityId := range entityIds.ToSlice() {
+		key := normaliseId(entityId)
+		if _, found := index.normalised[key]; !found {
+			length := len([]rune(key))
+			index.byLength[length] = append(index.byLength[length], key)
+		}
+		index.normalised[key] = append(index.normalised[key], entityId)
+	}
+
+	return index
+}
+
+// BuildIdIndex from the entity IDs in the bipartite and unipartite stores.
+func BuildIdIndex(bipartite graphstore.BipartiteGraphStore,
+	unipartite graphstore.UnipartiteGraphStore) (*IdIndex, error) {
+
+	iter, err := bipartite.NewEntityIdIterator()
+	if err != nil {
+		return nil, err
+	}
+
+	entityIds, err := graphstore.AllEntities(iter)
+	if err != nil {
+		return nil, err
+	}
+
+	unipartiteIds, err := unipartite.EntityIds()
+	if err != nil {
+		return nil, err
+	}
+	entityIds.AddAll(unipartiteIds.ToSlice())
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Int("numberOfEntityIds", entityIds.Len()).
+		Msg("Built the entity ID index")
+
+	return NewIdIndex(entityIds), nil
+}
+
+// minInt returns the smaller of two integers.
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// editDistance between two strings (Levenshtein distance). If the distance exceeds the maximum,
+// then the maximum plus one is returned.
+func editDistance(a []rune, b []rune, maximum int) int {
+
+	previous := make([]int, len(b)+1)
+	current := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		current[0] = i
+		rowMinimum := current[0]
+
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+
+			current[j] = minInt(minInt(previous[j]+1, current[j-1]+1), previous[j-1]+cost)
+			rowMinimum = minInt(rowMinimum, current[j])
+		}
+
+		if rowMinimum > maximum {
+			return maximum + 1
+		}
+		previous, current = current, previous
+	}
+
+	return minInt(previous[len(b)], maximum+1)
+}
+
+// A suggestion of an entity ID and its distance from the entity ID that wasn't found.
+type suggestion struct {
+	entityId string
+	distance int
+}
+
+// Suggest entity IDs similar to the entity ID, i.e. those that differ by case, whitespace or a
+// small edit distance, closest first. The entity ID itself is never suggested.
+func (idx *IdIndex) Suggest(entityId string) []string {
+
+	if idx == nil {
+		return nil
+	}
+
+	key := []rune(normaliseId(entityId))
+	candidates := []suggestion{}
+
+	for length := len(key) - MaxSuggestionDistance; length <= len(key)+MaxSuggestionDistance; length++ {
+		for _, other := range idx.byLength[length] {
+			distance := editDistance(key, []rune(other), MaxSuggestionDistance)
+			if distance > MaxSuggestionDistance {
+				continue
+			}
+
+			for _, id := range idx.normalised[other] {
+				if id != entityId {
+					candidates = append(candidates, suggestion{entityId: id, distance: distance})
+				}
+			}
+		}
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		if candidates[i].distance != candidates[j].distance {
+			return candidates[i].distance < candidates[j].distance
+		}
+		return candidates[i].entityId < candidates[j].entityId
+	})
+
+	suggestions := []string{}
+	for _, candidate := range candidates {
+		if len(suggestions) == MaxSuggestions {
+			break
+		}
+		suggestions = append(suggestions, candidate.entityId)
+	}
+
+	return suggestions
+}
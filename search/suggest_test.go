@@ -0,0 +1,113 @@
+package search
+
+import (
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphbuilder"
+	"github.com/cdclaxton/shortest-path-web-app/set"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormaliseId(t *testing.T) {
+	assert.Equal(t, "", normaliseId(""))
+	assert.Equal(t, "e-1", normaliseId("E-1"))
+	assert.Equal(t, "e-1", normaliseId(" E -\t1 "))
+}
+
+func TestEditDistance(t *testing.T) {
+	testCases := []struct {
+		a        string
+		b        string
+		maximum  int
+		expected int
+	}{
+		{a: "", b: "", maximum: 2, expected: 0},
+		{a: "abc", b: "abc", maximum: 2, expected: 0},
+		{a: "abc", b: "abd", maximum: 2, expected: 1},
+		{a: "abc", b: "ab", maximum: 2, expected: 1},
+		{a: "abc", b: "abcd", maximum: 2, expected: 1},
+		{a: "abc", b: "bac", maximum: 2, expected: 2},
+		{a: "abc", b: "xyz", maximum: 2, expected: 3},
+		{a: "abcdef", b: "", maximum: 2, expected: 3},
+	}
+
+	for _, testCase := range testCases {
+		actual := editDistance([]rune(testCase.a), []rune(testCase.b), testCase.maximum)
+		assert.Equal(t, testCase.expected, actual, "%v vs %v", testCase.a, testCase.b)
+	}
+}
+
+func TestIdIndexSuggest(t *testing.T) {
+	index := NewIdIndex(set.NewPopulatedSet("E-100", "e-101", "e-102", "e-999", "f-100",
+		"person 1", "e-1000000"))
+
+	testCases := []struct {
+		description string
+		entityId    string
+		expected    []string
+	}{
+		{
+			description: "exact match is not suggested",
+			entityId:    "e-999",
+			expected:    []string{},
+		},
+		{
+			description: "different case and small edit distance",
+			entityId:    "e-100",
+			expected:    []string{"E-100", "e-101", "e-102", "f-100"},
+		},
+		{
+			description: "whitespace",
+			entityId:    "person1",
+			expected:    []string{"person 1"},
+		},
+		{
+			description: "nothing similar",
+			entityId:    "xyz",
+			expected:    []string{},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.description, func(t *testing.T) {
+			assert.Equal(t, testCase.expected, index.Suggest(testCase.entityId))
+		})
+	}
+
+	// Closest first and limited in number
+	index = NewIdIndex(set.NewPopulatedSet("a-10", "a-11", "a-12", "a-13", "a-14", "a-15", "A-1"))
+	assert.Equal(t, []string{"A-1", "a-10", "a-11", "a-12", "a-13"}, index.Suggest("a-1"))
+
+	// Nil index
+	var noIndex *IdIndex
+	assert.Nil(t, noIndex.Suggest("a-1"))
+}
+
+func TestEntitySearchSuggestions(t *testing.T) {
+
+	graphBuilder, _, err := graphbuilder.NewGraphBuilderFromJson(
+		"../test-data-sets/set-0/config-inmemory.json")
+	assert.NoError(t, err)
+	defer graphBuilder.Destroy()
+
+	engine, err := NewEntitySearch(graphBuilder.Bipartite, graphBuilder.Unipartite)
+	assert.NoError(t, err)
+
+	// No suggestions before the index is built
+	actual, err := engine.Search([]string{"E-1"})
+	assert.NoError(t, err)
+	assert.Nil(t, actual["E-1"].Suggestions)
+
+	assert.NoError(t, engine.BuildSuggestionIndex())
+
+	actual, err = engine.Search([]string{"E-1", "e-1"})
+	assert.NoError(t, err)
+	assert.Contains(t, actual["E-1"].Suggestions, "e-1")
+	assert.Nil(t, actual["e-1"].Suggestions)
+
+	entity := engine.GetEntity("E-1")
+	assert.Contains(t, entity.Suggestions, "e-1")
+
+	entity = engine.GetEntity("e-1")
+	assert.Nil(t, entity.Suggestions)
+}
@@ -0,0 +1,64 @@
+// The entity IDs of a job can be aliases of entities (e.g. legacy identifiers of merged entities),
+// which are resolved to the canonical entity IDs when the job runs, so the paths are found between
+// the entities in the graph. The aliases are resolved once the job can read the stores, so a job
+// submitted whilst the stores are updated is still queued.
+
+package server
+
+import (
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/job"
+)
+
+// resolveAliases in the entity sets, excluded entities and waypoints of the job's configuration.
+// The job's configuration is replaced by a copy holding the canonical entity IDs.
+func (j *JobRunner) resolveAliases(j1 *job.Job) error {
+
+	bipartite := j.searchEngine.Bipartite
+	conf := *j1.Configuration
+
+	conf.EntitySets = make([]job.EntitySet, len(j1.Configuration.EntitySets))
+	for idx, entitySet := range j1.Configuration.EntitySets {
+		entityIds, err := resolveOptionalAliases(bipartite, entitySet.EntityIds)
+		if err != nil {
+			return err
+		}
+
+		conf.EntitySets[idx] = job.EntitySet{
+			Name:      entitySet.Name,
+			EntityIds: entityIds,
+		}
+	}
+
+	var err error
+	if conf.ExcludedEntityIds, err = resolveOptionalAliases(bipartite,
+		conf.ExcludedEntityIds); err != nil {
+		return err
+	}
+
+	if conf.Waypoints, err = resolveOptionalAliases(bipartite, conf.Waypoints); err != nil {
+		return err
+	}
+
+	j.jobsLock.Lock()
+	j1.Configuration = &conf
+	j.jobsLock.Unlock()
+
+	return nil
+}
+
+// resolveOptionalAliases of the entity IDs, removing any duplicates. A nil slice is unchanged.
+func resolveOptionalAliases(bipartite graphstore.BipartiteGraphStore, entityIds []string) (
+	[]string, error) {
+
+	if len(entityIds) == 0 {
+		return entityIds, nil
+	}
+
+	resolved, err := graphstore.ResolveAliases(bipartite, entityIds)
+	if err != nil {
+		return nil, err
+	}
+
+	return uniqueEntityIds(resolved), nil
+}
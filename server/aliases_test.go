@@ -0,0 +1,46 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUploadWithAliases(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	bipartite := server.runner.searchEngine.Bipartite
+	assert.NoError(t, bipartite.AddAlias("old-1", "e-1"))
+	assert.NoError(t, bipartite.AddAlias("old-2", "e-2"))
+
+	// Upload a form with aliases of the entities
+	form := buildFormData(1, "Dataset-1", "old-1, e-1, old-2", "", "", "", "")
+	form.Add(ExcludeEntitiesInputName, "old-2")
+	req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(form.Encode()))
+	req.Form = form
+
+	w := httptest.NewRecorder()
+	server.handleUpload(w, req)
+	assert.Equal(t, http.StatusFound, w.Code)
+
+	guid := extractGuidFromLocation(t, w.Result().Header.Get("Location"))
+	waitForJobsToFinish(server.runner)
+
+	// The job finds the paths between the canonical entities
+	j1, err := server.runner.GetJob(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"e-1", "e-2"}, j1.Configuration.EntitySets[0].EntityIds)
+	assert.Equal(t, []string{"e-2"}, j1.Configuration.ExcludedEntityIds)
+
+	// The entity page of an alias shows the entity
+	w = getPage(server.Routes(), "/entity/old-1")
+	body := w.Body.String()
+	assert.Contains(t, body, "Entity e-1")
+	assert.Contains(t, body, "old-1 is an alias of this entity.")
+}
@@ -0,0 +1,131 @@
+// Annotations are tags and short notes that users attach to entity IDs, e.g. to mark an entity as
+// "cleared" or "priority". They are shown and edited on the entity page and can be included in the
+// i2 charts with the ANNOTATION-TAGS and ANNOTATION-NOTE keywords.
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/cdclaxton/shortest-path-web-app/annotation"
+	"github.com/cdclaxton/shortest-path-web-app/i18n"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+)
+
+// Names of the inputs and URLs for annotations
+const (
+	AnnotationEntityIdInputName = "entityId" // ID of the entity to annotate
+	AnnotationTagsInputName     = "tags"     // Tags separated by commas
+	AnnotationNoteInputName     = "note"     // Short note
+	annotateEntityUrl           = "/annotate-entity"
+	annotationTimeFormat        = "2006-01-02 15:04:05 MST"
+)
+
+// SetAnnotationStore in which the tags and notes of the entities are saved. A nil store disables
+// annotations.
+func (j *JobServer) SetAnnotationStore(store *annotation.AnnotationStore) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Bool("annotations", store != nil).
+		Msg("Setting the annotation store")
+
+	j.annotations = store
+}
+
+// annotationContext for the entity page, which is nil if annotations are disabled.
+func (j *JobServer) annotationContext(entityId string) map[string]interface{} {
+
+	if j.annotations == nil {
+		return nil
+	}
+
+	ctx := map[string]interface{}{
+		"entityId": entityId,
+		"tags":     []string{},
+	}
+
+	a, err := j.annotations.Get(entityId)
+	if err != nil {
+		logging.Logger.Warn().
+			Str(logging.ComponentField, componentName).
+			Str("entityId", entityId).
+			Err(err).
+			Msg("Failed to read the annotation of the entity")
+
+		return ctx
+	}
+
+	if a != nil {
+		ctx["tags"] = a.Tags
+		ctx["tagsText"] = strings.Join(a.Tags, ", ")
+		ctx["note"] = a.Note
+		ctx["updated"] = a.Updated.Format(annotationTimeFormat)
+	}
+
+	return ctx
+}
+
+// annotationError returns the HTTP status and the error to show the user for an error from the
+// annotation store.
+func annotationError(err error) (int, error) {
+	switch {
+	case errors.Is(err, annotation.ErrEntityIdEmpty):
+		return http.StatusBadRequest, i18n.Wrap(err, "error.annotationEntityId")
+	case errors.Is(err, annotation.ErrTooManyTags), errors.Is(err, annotation.ErrTagTooLong),
+		errors.Is(err, annotation.ErrInvalidTag):
+		return http.StatusBadRequest, i18n.Wrap(err, "error.annotationTags", annotation.MaxTags,
+			annotation.MaxTagLength)
+	case errors.Is(err, annotation.ErrNoteTooLong):
+		return http.StatusBadRequest, i18n.Wrap(err, "error.annotationNote", annotation.MaxNoteLength)
+	}
+
+	return http.StatusInternalServerError, err
+}
+
+// handleAnnotateEntity sets the tags and note of an entity and returns to the entity's page.
+func (j *JobServer) handleAnnotateEntity(w http.ResponseWriter, req *http.Request) {
+
+	if req.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if j.annotations == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	entityId := strings.TrimSpace(req.FormValue(AnnotationEntityIdInputName))
+	tags := annotation.ParseTags(req.FormValue(AnnotationTagsInputName))
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("entityId", entityId).
+		Strs("tags", tags).
+		Msg("Received request to annotate an entity")
+
+	_, err := j.annotations.Set(entityId, tags, req.FormValue(AnnotationNoteInputName))
+	if err != nil {
+		settings := j.pageSettings(w, req)
+		status, err := annotationError(err)
+
+		template := j.inputProblemTemplate
+		if status == http.StatusInternalServerError {
+			template = j.errorTemplate
+		}
+
+		w.WriteHeader(status)
+		page := j.render(template, settings, map[string]string{
+			"reason": j.translator.TranslateError(settings.language, err),
+		})
+		fmt.Fprint(w, page)
+		return
+	}
+
+	http.Redirect(w, req, j.basePath+"/entity/"+url.PathEscape(entityId), http.StatusFound)
+}
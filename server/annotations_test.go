@@ -0,0 +1,77 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/annotation"
+	"github.com/stretchr/testify/assert"
+)
+
+// annotationForm to set the tags and note of an entity.
+func annotationForm(entityId string, tags string, note string) url.Values {
+	form := url.Values{}
+	form.Set(AnnotationEntityIdInputName, entityId)
+	form.Set(AnnotationTagsInputName, tags)
+	form.Set(AnnotationNoteInputName, note)
+	return form
+}
+
+func TestAnnotateEntity(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	handler := server.Routes()
+
+	// Annotations are disabled without a store
+	w := postForm(handler, annotateEntityUrl, annotationForm("e-1", "priority", ""))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.NotContains(t, getPage(handler, "/entity/e-1").Body.String(), `name="tags"`)
+
+	store, err := annotation.NewAnnotationStore(t.TempDir())
+	assert.NoError(t, err)
+	defer store.Close()
+	server.SetAnnotationStore(store)
+
+	// The entity page has a form to annotate the entity
+	page := getPage(handler, "/entity/e-1").Body.String()
+	assert.Contains(t, page, `action="../annotate-entity"`)
+	assert.Contains(t, page, `name="entityId" value="e-1"`)
+
+	// Annotate the entity
+	w = postForm(handler, annotateEntityUrl, annotationForm("e-1", "Priority, cleared", "Seen before"))
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "/entity/e-1", w.Header().Get("Location"))
+
+	a, err := store.Get("e-1")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"cleared", "priority"}, a.Tags)
+	assert.Equal(t, "Seen before", a.Note)
+
+	page = getPage(handler, "/entity/e-1").Body.String()
+	assert.Contains(t, page, `<strong class="govuk-tag govuk-tag--blue">cleared</strong>`)
+	assert.Contains(t, page, `value="cleared, priority"`)
+	assert.Contains(t, page, "Seen before")
+
+	// Invalid annotations
+	w = postForm(handler, annotateEntityUrl, annotationForm(" ", "priority", ""))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = postForm(handler, annotateEntityUrl, annotationForm("e-1", "",
+		strings.Repeat("a", annotation.MaxNoteLength+1)))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "longer than 500 characters")
+
+	assert.Equal(t, http.StatusMethodNotAllowed, getPage(handler, annotateEntityUrl).Code)
+
+	// Removing the tags and note deletes the annotation
+	w = postForm(handler, annotateEntityUrl, annotationForm("e-1", "", ""))
+	assert.Equal(t, http.StatusFound, w.Code)
+
+	a, err = store.Get("e-1")
+	assert.NoError(t, err)
+	assert.Nil(t, a)
+}
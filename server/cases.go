@@ -0,0 +1,395 @@
+// Cases group the shortest path and spider jobs of an investigation with free-text notes, so that
+// analysts can see all of the results of an investigation on one page rather than tracking GUIDs.
+
+package server
+
+import (
+	"archive/zip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/cdclaxton/shortest-path-web-app/i18n"
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+)
+
+// Names of the inputs and URLs for cases
+const (
+	CaseIdInputName       = "caseId"   // ID of a case
+	CaseNameInputName     = "caseName" // Name of a new case
+	CaseNoteInputName     = "note"     // Text of a note
+	casesUrl              = "/cases"
+	caseUrl               = "/case/"
+	caseDownloadUrl       = "/case-download/"
+	caseNotesFilename     = "notes.txt"
+	caseTimeFormat        = "2006-01-02 15:04:05 MST"
+	caseJobUnavailableKey = "cases.unavailable" // Translation key of a job no longer held
+)
+
+var ErrCaseStoreIsNil = errors.New("case store is nil")
+
+// SetCaseStore in which the cases are saved.
+func (j *JobServer) SetCaseStore(store *job.CaseStore) error {
+
+	// Precondition
+	if store == nil {
+		return ErrCaseStoreIsNil
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Int("numberOfCases", len(store.List())).
+		Msg("Setting the case store")
+
+	j.cases = store
+	return nil
+}
+
+// CaseDisplay is a case in the list of cases presented to the user.
+type CaseDisplay struct {
+	Name         string
+	Url          string
+	Created      string
+	NumberOfJobs int
+}
+
+// CaseNoteDisplay is a note of a case presented to the user.
+type CaseNoteDisplay struct {
+	Text  string
+	Added string
+}
+
+// prepareCases for display.
+func (j *JobServer) prepareCases(cases []job.Case) []CaseDisplay {
+
+	display := []CaseDisplay{}
+	for _, c := range cases {
+		display = append(display, CaseDisplay{
+			Name:         c.Name,
+			Url:          j.basePath + caseUrl + c.Id,
+			Created:      c.Created.Format(caseTimeFormat),
+			NumberOfJobs: len(c.Jobs),
+		})
+	}
+
+	return display
+}
+
+// prepareCaseJobs for display in the language. Jobs that are no longer held by the job runners are
+// shown as unavailable.
+func (j *JobServer) prepareCaseJobs(jobs []job.CaseJob, language string) []MyJobDisplay {
+
+	display := []MyJobDisplay{}
+	for _, cj := range jobs {
+
+		item, found := j.jobDisplay(cj.Guid, cj.Spider, language)
+		if !found {
+			item.Url = ""
+			item.State = j.translator.Translate(language, caseJobUnavailableKey)
+		}
+
+		item.Submitted = cj.Added.Format(caseTimeFormat)
+		display = append(display, item)
+	}
+
+	return display
+}
+
+// prepareCaseNotes for display.
+func prepareCaseNotes(notes []job.CaseNote) []CaseNoteDisplay {
+
+	display := []CaseNoteDisplay{}
+	for _, note := range notes {
+		display = append(display, CaseNoteDisplay{
+			Text:  note.Text,
+			Added: note.Added.Format(caseTimeFormat),
+		})
+	}
+
+	return display
+}
+
+// renderCaseProblem writes the page for a problem with a request about a case.
+func (j *JobServer) renderCaseProblem(w http.ResponseWriter, settings pageSettings, status int,
+	err error) {
+
+	template := j.inputProblemTemplate
+	if status == http.StatusInternalServerError {
+		template = j.errorTemplate
+	}
+
+	w.WriteHeader(status)
+	page := j.render(template, settings, map[string]string{
+		"reason": j.translator.TranslateError(settings.language, err),
+	})
+	fmt.Fprint(w, page)
+}
+
+// caseError returns the HTTP status and the error to show the user for an error from the case
+// store.
+func caseError(err error, caseId string) (int, error) {
+	switch {
+	case errors.Is(err, job.ErrCaseNotFound):
+		return http.StatusNotFound, i18n.Wrap(err, "error.caseNotFound", caseId)
+	case errors.Is(err, job.ErrCaseNameEmpty), errors.Is(err, job.ErrCaseNameTooLong):
+		return http.StatusBadRequest, i18n.Wrap(err, "error.caseName", job.MaxCaseNameLength)
+	case errors.Is(err, job.ErrCaseNoteEmpty), errors.Is(err, job.ErrCaseNoteTooLong):
+		return http.StatusBadRequest, i18n.Wrap(err, "error.caseNote", job.MaxCaseNoteLength)
+	}
+
+	return http.StatusInternalServerError, err
+}
+
+// handleCases returns the page listing the cases.
+func (j *JobServer) handleCases(w http.ResponseWriter, req *http.Request) {
+
+	settings := j.pageSettings(w, req)
+
+	page := j.render(j.casesTemplate, settings, map[string]interface{}{
+		"cases": j.prepareCases(j.cases.List()),
+	})
+	fmt.Fprint(w, withCsrfToken(w, req, page))
+}
+
+// handleCreateCase creates a case and redirects to its page.
+func (j *JobServer) handleCreateCase(w http.ResponseWriter, req *http.Request) {
+
+	settings := j.pageSettings(w, req)
+
+	if req.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := req.FormValue(CaseNameInputName)
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("caseName", name).
+		Msg("Creating case")
+
+	c, err := j.cases.Create(name)
+	if err != nil {
+		status, err := caseError(err, "")
+		j.renderCaseProblem(w, settings, status, err)
+		return
+	}
+
+	http.Redirect(w, req, j.basePath+caseUrl+c.Id, http.StatusFound)
+}
+
+// handleCase returns the page of a case, i.e. its jobs and notes.
+func (j *JobServer) handleCase(w http.ResponseWriter, req *http.Request) {
+
+	settings := j.pageSettings(w, req)
+	caseId := strings.TrimPrefix(req.URL.Path, caseUrl)
+
+	c, err := j.cases.Get(caseId)
+	if err != nil {
+		status, err := caseError(err, caseId)
+		j.renderCaseProblem(w, settings, status, err)
+		return
+	}
+
+	page := j.render(j.caseTemplate, settings, map[string]interface{}{
+		"id":       c.Id,
+		"name":     c.Name,
+		"created":  c.Created.Format(caseTimeFormat),
+		"jobs":     j.prepareCaseJobs(c.Jobs, settings.language),
+		"notes":    prepareCaseNotes(c.Notes),
+		"mergeUrl": j.caseMergeUrl(c.Jobs),
+	})
+	fmt.Fprint(w, withCsrfToken(w, req, page))
+}
+
+// handleAddCaseJob attaches a shortest path or spider job to a case and redirects to the case's
+// page. The type of the job is found from the job runner that holds it.
+func (j *JobServer) handleAddCaseJob(w http.ResponseWriter, req *http.Request) {
+
+	settings := j.pageSettings(w, req)
+
+	if req.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	caseId := req.FormValue(CaseIdInputName)
+	guid := strings.TrimSpace(req.FormValue(JobGuidInputName))
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str(loggingGUIDField, guid).
+		Str("caseId", caseId).
+		Msg("Attaching job to case")
+
+	spider := false
+	if _, err := j.runner.GetJob(guid); err != nil {
+		if _, err := j.spiderRunner.GetJob(guid); err != nil {
+			j.renderCaseProblem(w, settings, http.StatusNotFound,
+				i18n.Wrap(err, "error.jobNotFound", guid))
+			return
+		}
+		spider = true
+	}
+
+	if err := j.cases.AddJob(caseId, guid, spider); err != nil {
+		status, err := caseError(err, caseId)
+		j.renderCaseProblem(w, settings, status, err)
+		return
+	}
+
+	http.Redirect(w, req, j.basePath+caseUrl+caseId, http.StatusFound)
+}
+
+// handleAddCaseNote adds a note to a case and redirects to the case's page.
+func (j *JobServer) handleAddCaseNote(w http.ResponseWriter, req *http.Request) {
+
+	settings := j.pageSettings(w, req)
+
+	if req.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	caseId := req.FormValue(CaseIdInputName)
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("caseId", caseId).
+		Msg("Adding note to case")
+
+	if err := j.cases.AddNote(caseId, req.FormValue(CaseNoteInputName)); err != nil {
+		status, err := caseError(err, caseId)
+		j.renderCaseProblem(w, settings, status, err)
+		return
+	}
+
+	http.Redirect(w, req, j.basePath+caseUrl+caseId, http.StatusFound)
+}
+
+// caseResultFile of a job attached to a case and the name of the file in the combined download. A
+// blank location is returned if the job isn't held by the job runners or has no results.
+func (j *JobServer) caseResultFile(cj job.CaseJob) (string, string) {
+
+	if cj.Spider {
+		j1, err := j.spiderRunner.GetJob(cj.Guid)
+		if err != nil || j1.Progress.State != job.CompleteResults {
+			return "", ""
+		}
+		return j1.ResultFile, "spider-" + cj.Guid + ".xlsx"
+	}
+
+	j1, err := j.runner.GetJob(cj.Guid)
+	if err != nil || j1.Progress.State != job.CompleteResults {
+		return "", ""
+	}
+	return j1.ResultFile, "shortest-path-" + cj.Guid + ".xlsx"
+}
+
+// caseMergeUrl returns the relative URL to download the chart merged from the case's shortest path
+// jobs with results, or an empty string if there aren't enough jobs to merge.
+func (j *JobServer) caseMergeUrl(jobs []job.CaseJob) string {
+
+	guids := []string{}
+	for _, cj := range jobs {
+		if location, _ := j.caseResultFile(cj); !cj.Spider && len(location) > 0 {
+			guids = append(guids, cj.Guid)
+		}
+	}
+
+	if len(guids) < minimumMergeJobs {
+		return ""
+	}
+
+	return ".." + mergeDownloadUrl + "?" + mergeQuery(guids)
+}
+
+// writeCaseNotes to the writer, one note per paragraph.
+func writeCaseNotes(w io.Writer, c job.Case) error {
+
+	if _, err := fmt.Fprintf(w, "%v\n\n", c.Name); err != nil {
+		return err
+	}
+
+	for _, note := range c.Notes {
+		if _, err := fmt.Fprintf(w, "[%v]\n%v\n\n", note.Added.Format(caseTimeFormat),
+			note.Text); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// writeCaseArchive writes a zip file of the results of the case's jobs and its notes.
+func (j *JobServer) writeCaseArchive(w io.Writer, c job.Case) error {
+
+	archive := zip.NewWriter(w)
+
+	notes, err := archive.Create(caseNotesFilename)
+	if err != nil {
+		return err
+	}
+
+	if err := writeCaseNotes(notes, c); err != nil {
+		return err
+	}
+
+	for _, cj := range c.Jobs {
+		location, name := j.caseResultFile(cj)
+		if len(location) == 0 {
+			continue
+		}
+
+		file, err := os.Open(location)
+		if err != nil {
+			return err
+		}
+
+		entry, err := archive.Create(name)
+		if err == nil {
+			_, err = io.Copy(entry, file)
+		}
+		file.Close()
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return archive.Close()
+}
+
+// handleCaseDownload returns a zip file of the results of the case's jobs and its notes.
+func (j *JobServer) handleCaseDownload(w http.ResponseWriter, req *http.Request) {
+
+	settings := j.pageSettings(w, req)
+	caseId := strings.TrimPrefix(req.URL.Path, caseDownloadUrl)
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("caseId", caseId).
+		Msg("Received request at /case-download")
+
+	c, err := j.cases.Get(caseId)
+	if err != nil {
+		status, err := caseError(err, caseId)
+		j.renderCaseProblem(w, settings, status, err)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=case-%v.zip", c.Id))
+	w.Header().Set("Content-Type", "application/zip")
+
+	if err := j.writeCaseArchive(w, c); err != nil {
+		logging.Logger.Error().
+			Str(logging.ComponentField, componentName).
+			Str("caseId", caseId).
+			Err(err).
+			Msg("Failed to write the results of the case")
+	}
+}
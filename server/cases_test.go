@@ -0,0 +1,123 @@
+package server
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCases(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	handler := server.Routes()
+
+	// No cases
+	w := getPage(handler, "/cases")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "No cases have been created yet.")
+
+	// Invalid name
+	w = postForm(handler, "/create-case", url.Values{CaseNameInputName: {" "}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// Create a case
+	w = postForm(handler, "/create-case", url.Values{CaseNameInputName: {"Operation A"}})
+	assert.Equal(t, http.StatusFound, w.Code)
+	location := w.Result().Header.Get("Location")
+	assert.True(t, strings.HasPrefix(location, caseUrl))
+	caseId := strings.TrimPrefix(location, caseUrl)
+
+	w = getPage(handler, "/cases")
+	assert.Contains(t, w.Body.String(), "Operation A")
+	assert.Contains(t, w.Body.String(), `<a href="/case/`+caseId+`"`)
+
+	w = getPage(handler, location)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "No jobs have been added to this case yet.")
+
+	// Run a shortest path job and a spider job
+	w = postForm(handler, "/upload", buildFormData(1, "Dataset-1", "e-1, e-2", "", "", "", ""))
+	guid := extractGuidFromLocation(t, w.Result().Header.Get("Location"))
+
+	w = postForm(handler, "/spider-upload", buildSpiderFormData(1, "e-1"))
+	spiderGuid := extractSpiderGuidFromLocation(t, w.Result().Header.Get("Location"))
+
+	waitForJobsToFinish(server.runner)
+	waitForSpiderJobsToFinish(server.spiderRunner)
+
+	// Attach the jobs to the case
+	for _, g := range []string{guid, spiderGuid} {
+		w = postForm(handler, "/add-case-job", url.Values{CaseIdInputName: {caseId},
+			JobGuidInputName: {g}})
+		assert.Equal(t, http.StatusFound, w.Code)
+		assert.Equal(t, location, w.Result().Header.Get("Location"))
+	}
+
+	w = postForm(handler, "/add-case-job", url.Values{CaseIdInputName: {caseId},
+		JobGuidInputName: {"1234"}})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = postForm(handler, "/add-case-job", url.Values{CaseIdInputName: {"missing"},
+		JobGuidInputName: {guid}})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Add a note
+	w = postForm(handler, "/add-case-note", url.Values{CaseIdInputName: {caseId},
+		CaseNoteInputName: {"Links found via the address"}})
+	assert.Equal(t, http.StatusFound, w.Code)
+
+	w = postForm(handler, "/add-case-note", url.Values{CaseIdInputName: {caseId},
+		CaseNoteInputName: {""}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// The case's page shows the jobs and notes
+	w = getPage(handler, location)
+	body := w.Body.String()
+	assert.Contains(t, body, `<a href="/job/`+guid+`" class="govuk-link">View</a>`)
+	assert.Contains(t, body, `<a href="/spider-job/`+spiderGuid+`" class="govuk-link">View</a>`)
+	assert.Contains(t, body, "Links found via the address")
+
+	// The combined download holds the results of both jobs and the notes
+	w = getPage(handler, caseDownloadUrl+caseId)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/zip", w.Header().Get("Content-Type"))
+
+	archive, err := zip.NewReader(bytes.NewReader(w.Body.Bytes()), int64(w.Body.Len()))
+	assert.NoError(t, err)
+
+	files := map[string]*zip.File{}
+	for _, file := range archive.File {
+		files[file.Name] = file
+	}
+	assert.Equal(t, 3, len(files))
+	assert.Contains(t, files, "shortest-path-"+guid+".xlsx")
+	assert.Contains(t, files, "spider-"+spiderGuid+".xlsx")
+
+	notes, err := files[caseNotesFilename].Open()
+	assert.NoError(t, err)
+	content, err := io.ReadAll(notes)
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "Operation A")
+	assert.Contains(t, string(content), "Links found via the address")
+
+	// Case not found
+	w = getPage(handler, "/case/missing")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "case missing not found")
+
+	w = getPage(handler, caseDownloadUrl+"missing")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// The forms must be posted
+	w = getPage(handler, "/create-case")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+}
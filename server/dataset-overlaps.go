@@ -0,0 +1,58 @@
+// Analysts often paste overlapping lists of entity IDs into the datasets of a job. The connection
+// between an entity and itself is skipped, so the entity IDs in more than one dataset are found when
+// the job is submitted and shown on the job's page and in the summary sheet of its results.
+
+package server
+
+import (
+	"strings"
+
+	"github.com/cdclaxton/shortest-path-web-app/i2chart"
+	"github.com/cdclaxton/shortest-path-web-app/job"
+)
+
+// Maximum number of entities in more than one dataset shown on a page
+const MaxOverlapsShown = 100
+
+// overlapSummary of the entity IDs in more than one dataset, as rows for the summary sheet of a
+// job's Excel file. There aren't any rows if the datasets don't overlap.
+func overlapSummary(overlaps []job.DatasetOverlap) [][]string {
+
+	datasets := map[string][]string{}
+	for _, overlap := range overlaps {
+		datasets[overlap.EntityId] = overlap.Datasets
+	}
+
+	return i2chart.OverlapSummary(datasets)
+}
+
+// overlapsContext for the page of a job, which shows the entity IDs in more than one dataset (up
+// to a maximum). The context is nil if the datasets don't overlap.
+func (j *JobServer) overlapsContext(j1 *job.Job, language string) map[string]interface{} {
+
+	if len(j1.Overlaps) == 0 {
+		return nil
+	}
+
+	overlaps := j1.Overlaps
+	ctx := map[string]interface{}{
+		"number": len(overlaps),
+	}
+
+	if len(overlaps) > MaxOverlapsShown {
+		ctx["more"] = j.translator.Translate(language, "overlaps.more", MaxOverlapsShown,
+			len(overlaps))
+		overlaps = overlaps[:MaxOverlapsShown]
+	}
+
+	entities := []map[string]string{}
+	for _, overlap := range overlaps {
+		entities = append(entities, map[string]string{
+			"entityId": overlap.EntityId,
+			"datasets": strings.Join(overlap.Datasets, ", "),
+		})
+	}
+	ctx["entities"] = entities
+
+	return ctx
+}
@@ -0,0 +1,72 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/i2chart"
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobWithOverlappingDatasets(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	// The entity e-2 is in both datasets
+	w := postForm(server.Routes(), "/upload", buildFormData(1, "Dataset-1", "e-1, e-2",
+		"Dataset-2", "e-2, e-3", "", ""))
+	assert.Equal(t, http.StatusFound, w.Code)
+
+	waitForJobsToFinish(server.runner)
+	guid := extractGuidFromLocation(t, w.Header().Get("Location"))
+
+	j1, err := server.runner.GetJob(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, job.CompleteResults, j1.Progress.State)
+	assert.Equal(t, []job.DatasetOverlap{
+		{EntityId: "e-2", Datasets: []string{"Dataset-1", "Dataset-2"}},
+	}, j1.Overlaps)
+
+	// The overlap is in the summary sheet of the results
+	summary, err := i2chart.ReadFromExcel(j1.ResultFile, "Summary")
+	assert.NoError(t, err)
+	assert.Equal(t, i2chart.OverlapSummary(map[string][]string{
+		"e-2": {"Dataset-1", "Dataset-2"},
+	}), summary)
+
+	// The overlap is shown on the job's page
+	body := getPage(server.Routes(), "/job/"+guid).Body.String()
+	assert.Contains(t, body, "Entities in more than one dataset (1)")
+	assert.Contains(t, body, "Dataset-1, Dataset-2")
+
+	// A job without overlapping datasets doesn't show the table
+	guid = submitAndWait(t, server, "e-1, e-2")
+
+	body = getPage(server.Routes(), "/job/"+guid).Body.String()
+	assert.NotContains(t, body, "Entities in more than one dataset")
+}
+
+func TestOverlapsContext(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	// Only the first overlaps are shown
+	overlaps := []job.DatasetOverlap{}
+	for idx := 0; idx < MaxOverlapsShown+1; idx++ {
+		overlaps = append(overlaps, job.DatasetOverlap{
+			EntityId: "e-1",
+			Datasets: []string{"Set-1", "Set-2"},
+		})
+	}
+
+	ctx := server.overlapsContext(&job.Job{Overlaps: overlaps}, "en")
+	assert.Equal(t, MaxOverlapsShown+1, ctx["number"])
+	assert.Equal(t, MaxOverlapsShown, len(ctx["entities"].([]map[string]string)))
+	assert.Contains(t, ctx["more"], "Only the first 100 of the 101 entities")
+
+	assert.Nil(t, server.overlapsContext(&job.Job{}, "en"))
+	assert.Nil(t, overlapSummary(nil))
+}
@@ -0,0 +1,84 @@
+// The number of datasets of a shortest path job is configurable. The index page initially shows
+// the first few datasets and the user can add more, up to the server's maximum, with the "add
+// another dataset" button. Each dataset's fields are numbered from one (e.g. datasetName1,
+// datasetEntities1 and datasetFile1).
+
+package server
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+)
+
+const (
+	DefaultMaxDatasets      = 3           // Default maximum number of datasets of a job
+	MaxConfigurableDatasets = 100         // Largest maximum number of datasets that can be configured
+	initialDatasets         = 3           // Number of datasets initially shown on the index page
+	newDatasetIndex         = "__INDEX__" // Placeholder of the index of a dataset added by the user
+)
+
+var ErrInvalidMaxDatasets = errors.New("invalid maximum number of datasets")
+
+// SetMaxDatasets sets the maximum number of datasets of a shortest path job.
+func (j *JobServer) SetMaxDatasets(maxDatasets int) error {
+
+	// Precondition
+	if maxDatasets < 1 || maxDatasets > MaxConfigurableDatasets {
+		return ErrInvalidMaxDatasets
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Int("maxDatasets", maxDatasets).
+		Msg("Setting the maximum number of datasets")
+
+	j.maxDatasets = maxDatasets
+	return j.cachePages()
+}
+
+// DatasetDisplay is a dataset on the index page's form.
+type DatasetDisplay struct {
+	Index     string // Index of the dataset's fields
+	Optional  bool   // Is the dataset optional?
+	Name      string // Name of the dataset
+	EntityIds string // Entity IDs of the dataset, one per line
+}
+
+// newDatasetDisplay with the index.
+func newDatasetDisplay(index int) DatasetDisplay {
+	return DatasetDisplay{
+		Index:    strconv.Itoa(index),
+		Optional: index > 1,
+	}
+}
+
+// prepareDatasets to show on the index page's form, pre-populated with the datasets of the job
+// configuration (if it isn't nil). At least the initial number of datasets are shown.
+func prepareDatasets(conf *job.JobConfiguration, maxDatasets int) []DatasetDisplay {
+
+	numberDatasets := initialDatasets
+	if conf != nil && len(conf.EntitySets) > numberDatasets {
+		numberDatasets = len(conf.EntitySets)
+	}
+	if numberDatasets > maxDatasets {
+		numberDatasets = maxDatasets
+	}
+
+	datasets := []DatasetDisplay{}
+	for idx := 0; idx < numberDatasets; idx++ {
+		dataset := newDatasetDisplay(idx + 1)
+
+		if conf != nil && idx < len(conf.EntitySets) {
+			dataset.Name = conf.EntitySets[idx].Name
+			dataset.EntityIds = strings.Join(conf.EntitySets[idx].EntityIds, "\n")
+		}
+
+		datasets = append(datasets, dataset)
+	}
+
+	return datasets
+}
@@ -0,0 +1,94 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetMaxDatasets(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+	assert.Equal(t, DefaultMaxDatasets, server.maxDatasets)
+
+	for _, invalid := range []int{-1, 0, MaxConfigurableDatasets + 1} {
+		assert.ErrorIs(t, server.SetMaxDatasets(invalid), ErrInvalidMaxDatasets)
+	}
+	assert.Equal(t, DefaultMaxDatasets, server.maxDatasets)
+
+	assert.NoError(t, server.SetMaxDatasets(5))
+	assert.Equal(t, 5, server.maxDatasets)
+
+	// The index page allows the user to add datasets up to the maximum
+	body := getPage(server.Routes(), "/").Body.String()
+	assert.Contains(t, body, `data-max-datasets="5"`)
+	assert.Contains(t, body, "Add another dataset")
+	assert.Contains(t, body, "Dataset 1\n")
+	assert.Contains(t, body, "Dataset 3 (Optional)")
+	assert.NotContains(t, body, `name="datasetName4"`)
+	assert.Contains(t, body, `name="datasetName`+newDatasetIndex+`"`)
+}
+
+// buildDatasetsFormData with the number of datasets.
+func buildDatasetsFormData(numberDatasets int) url.Values {
+	form := url.Values{}
+	form.Add(NumberHopsInputName, "1")
+	for idx := 1; idx <= numberDatasets; idx++ {
+		form.Add(DatasetNameInputName+strconv.Itoa(idx), fmt.Sprintf("Dataset-%d", idx))
+		form.Add(DatasetEntitiesInputName+strconv.Itoa(idx), fmt.Sprintf("e-%d", idx))
+	}
+	return form
+}
+
+func TestExtractManyDatasetsFromForm(t *testing.T) {
+	testCases := []struct {
+		numberDatasets int
+		maxDatasets    int
+		expected       int
+	}{
+		{1, 3, 1},
+		{3, 3, 3},
+		{5, 3, 3},
+		{5, 5, 5},
+		{12, 20, 12},
+	}
+
+	for _, testCase := range testCases {
+		form := buildDatasetsFormData(testCase.numberDatasets)
+		req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(form.Encode()))
+		req.Form = form
+
+		conf, err := extractJobConfigurationFromForm(req, testCase.maxDatasets, job.JobLimits{},
+			DefaultSearchLimits, nil)
+		assert.NoError(t, err)
+		assert.Len(t, conf.EntitySets, testCase.expected)
+		assert.Equal(t, fmt.Sprintf("Dataset-%d", testCase.expected),
+			conf.EntitySets[testCase.expected-1].Name)
+	}
+}
+
+func TestCountEntitiesOfAddedDataset(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+	assert.NoError(t, server.SetMaxDatasets(5))
+	handler := server.Routes()
+
+	form := url.Values{}
+	form.Add(DatasetIndexInputName, "5")
+	form.Add(DatasetEntitiesInputName+"5", "e-1, e-2")
+	w := postForm(handler, "/count-entities", form)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	form.Set(DatasetIndexInputName, "6")
+	w = postForm(handler, "/count-entities", form)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,139 @@
+// A disk quota stops a job from writing its result files when the volume of the results folder is
+// nearly full or the folder has reached its quota. The job fails with a message for the user and an
+// alert is logged, rather than the job producing truncated Excel files.
+
+package server
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/cdclaxton/shortest-path-web-app/i18n"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+)
+
+// Default minimum free space on the volume of the results folder
+const DefaultMinFreeDiskBytes = 64 << 20
+
+// Field of a log entry that marks it as an alert for the administrator
+const loggingAlertField = "alert"
+
+var (
+	ErrInvalidDiskQuota     = errors.New("invalid disk quota")
+	ErrFreeSpaceUnknown     = errors.New("free disk space is unknown on this platform")
+	ErrDiskSpaceLow         = errors.New("free disk space is below the minimum")
+	ErrResultsQuotaExceeded = errors.New("results folder has reached its quota")
+)
+
+// A DiskQuota limits the disk space used by the result files of the jobs.
+type DiskQuota struct {
+	MinFreeBytes   int64 // Minimum free space on the volume of the results folder (0 to not check)
+	MaxFolderBytes int64 // Maximum size of the results folder (0 for no limit)
+}
+
+// Validate the disk quota.
+func (q DiskQuota) Validate() error {
+	if q.MinFreeBytes < 0 || q.MaxFolderBytes < 0 {
+		return ErrInvalidDiskQuota
+	}
+
+	return nil
+}
+
+// folderSize is the total size of the files in the folder and its sub-folders.
+func folderSize(folder string) (int64, error) {
+	var size int64
+
+	err := filepath.WalkDir(folder, func(_ string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if entry.IsDir() {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+
+		size += info.Size()
+		return nil
+	})
+
+	return size, err
+}
+
+// Check there is space in the folder to write a result file.
+func (q DiskQuota) Check(folder string) error {
+
+	if q.MinFreeBytes > 0 {
+		free, err := freeDiskSpace(folder)
+
+		switch {
+		case errors.Is(err, ErrFreeSpaceUnknown):
+			logging.Logger.Warn().
+				Str(logging.ComponentField, componentName).
+				Str("folder", folder).
+				Msg("Unable to check the free disk space")
+		case err != nil:
+			return err
+		case free < q.MinFreeBytes:
+			logging.Logger.Error().
+				Str(logging.ComponentField, componentName).
+				Bool(loggingAlertField, true).
+				Str("folder", folder).
+				Int64("freeBytes", free).
+				Int64("minFreeBytes", q.MinFreeBytes).
+				Msg("Free disk space for the results is below the minimum")
+
+			return i18n.Wrap(ErrDiskSpaceLow, "error.diskSpaceLow")
+		}
+	}
+
+	if q.MaxFolderBytes > 0 {
+		size, err := folderSize(folder)
+		if err != nil {
+			return err
+		}
+
+		if size >= q.MaxFolderBytes {
+			logging.Logger.Error().
+				Str(logging.ComponentField, componentName).
+				Bool(loggingAlertField, true).
+				Str("folder", folder).
+				Int64("folderBytes", size).
+				Int64("maxFolderBytes", q.MaxFolderBytes).
+				Msg("Results folder has reached its quota")
+
+			return i18n.Wrap(ErrResultsQuotaExceeded, "error.resultsQuotaExceeded")
+		}
+	}
+
+	return nil
+}
+
+// writeResultFile at filepath in the folder using the write function if there is space. If the
+// write fails, e.g. as the volume filled up, the partly written file is removed.
+func (q DiskQuota) writeResultFile(folder string, filepath string, write func() error) error {
+
+	if err := q.Check(folder); err != nil {
+		return err
+	}
+
+	if err := write(); err != nil {
+		if removeErr := os.Remove(filepath); removeErr != nil && !errors.Is(removeErr, fs.ErrNotExist) {
+			logging.Logger.Warn().
+				Str(logging.ComponentField, componentName).
+				Str("filepath", filepath).
+				Err(removeErr).
+				Msg("Failed to remove a partly written result file")
+		}
+		return err
+	}
+
+	return nil
+}
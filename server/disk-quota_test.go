@@ -0,0 +1,120 @@
+package server
+
+import (
+	"errors"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/cdclaxton/shortest-path-web-app/set"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiskQuotaCheck(t *testing.T) {
+
+	assert.ErrorIs(t, DiskQuota{MinFreeBytes: -1}.Validate(), ErrInvalidDiskQuota)
+	assert.ErrorIs(t, DiskQuota{MaxFolderBytes: -1}.Validate(), ErrInvalidDiskQuota)
+	assert.NoError(t, DiskQuota{}.Validate())
+
+	folder := t.TempDir()
+	assert.NoError(t, os.WriteFile(path.Join(folder, "a.xlsx"), make([]byte, 100), 0644))
+	assert.NoError(t, os.Mkdir(path.Join(folder, "spill"), 0755))
+	assert.NoError(t, os.WriteFile(path.Join(folder, "spill", "b.bin"), make([]byte, 50), 0644))
+
+	size, err := folderSize(folder)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(150), size)
+
+	// No checks
+	assert.NoError(t, DiskQuota{}.Check(folder))
+
+	// Quota of the folder
+	assert.NoError(t, DiskQuota{MaxFolderBytes: 151}.Check(folder))
+	assert.ErrorIs(t, DiskQuota{MaxFolderBytes: 150}.Check(folder), ErrResultsQuotaExceeded)
+
+	// Free disk space (which can't be checked on some platforms)
+	free, err := freeDiskSpace(folder)
+	if errors.Is(err, ErrFreeSpaceUnknown) {
+		t.Skip("free disk space is unknown on this platform")
+	}
+	assert.NoError(t, err)
+	assert.True(t, free > 0)
+
+	assert.NoError(t, DiskQuota{MinFreeBytes: 1}.Check(folder))
+	assert.ErrorIs(t, DiskQuota{MinFreeBytes: free * 1000}.Check(folder), ErrDiskSpaceLow)
+}
+
+func TestWriteResultFile(t *testing.T) {
+
+	folder := t.TempDir()
+	filepath := path.Join(folder, "result.xlsx")
+
+	// A partly written file is removed
+	failure := errors.New("no space left on device")
+	err := DiskQuota{}.writeResultFile(folder, filepath, func() error {
+		assert.NoError(t, os.WriteFile(filepath, []byte("partial"), 0644))
+		return failure
+	})
+	assert.ErrorIs(t, err, failure)
+	assert.NoFileExists(t, filepath)
+
+	// The file isn't written if the folder has reached its quota
+	written := false
+	err = DiskQuota{MaxFolderBytes: 1}.writeResultFile(folder, filepath, func() error {
+		written = true
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.True(t, written)
+
+	assert.NoError(t, os.WriteFile(path.Join(folder, "other.xlsx"), []byte("data"), 0644))
+	written = false
+	err = DiskQuota{MaxFolderBytes: 1}.writeResultFile(folder, filepath, func() error {
+		written = true
+		return nil
+	})
+	assert.ErrorIs(t, err, ErrResultsQuotaExceeded)
+	assert.False(t, written)
+}
+
+func TestJobsWithDiskQuota(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	assert.ErrorIs(t, server.runner.SetDiskQuota(DiskQuota{MaxFolderBytes: -1}), ErrInvalidDiskQuota)
+	assert.ErrorIs(t, server.spiderRunner.SetDiskQuota(DiskQuota{MinFreeBytes: -1}), ErrInvalidDiskQuota)
+
+	// The results folder already holds a file, so it has reached its quota
+	assert.NoError(t, os.WriteFile(path.Join(server.runner.folder, "old.xlsx"), []byte("data"), 0644))
+	quota := DiskQuota{MaxFolderBytes: 1}
+	assert.NoError(t, server.runner.SetDiskQuota(quota))
+	assert.NoError(t, server.spiderRunner.SetDiskQuota(quota))
+
+	// Shortest path job
+	guid := submitAndWait(t, server, "e-1, e-2")
+
+	j1, err := server.runner.GetJob(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, job.Failed, j1.Progress.State)
+	assert.ErrorIs(t, j1.Error, ErrResultsQuotaExceeded)
+	assert.NoFileExists(t, makeExcelFilepath(server.runner.folder, guid))
+
+	// The user is shown the reason on the results page
+	w := getPage(server.Routes(), "/job/"+guid)
+	assert.Contains(t, w.Body.String(), "The folder of results has reached its quota")
+
+	// Spider job
+	guid, err = server.spiderRunner.Submit(&job.SpiderJobConfiguration{
+		NumberSteps:  1,
+		SeedEntities: set.NewPopulatedSet("e-1"),
+	})
+	assert.NoError(t, err)
+	waitForSpiderJobsToFinish(server.spiderRunner)
+
+	j2, err := server.spiderRunner.GetJob(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, job.Failed, j2.Progress.State)
+	assert.ErrorIs(t, j2.Error, ErrResultsQuotaExceeded)
+}
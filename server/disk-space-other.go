@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package server
+
+// freeDiskSpace isn't available on this platform, so the free space isn't checked.
+func freeDiskSpace(folder string) (int64, error) {
+	return 0, ErrFreeSpaceUnknown
+}
@@ -0,0 +1,119 @@
+// Analysts sometimes submit the same job twice, e.g. by double-clicking the submit button, which
+// doubles the load of an expensive search. A job that is identical to a job that is running or that
+// finished recently (and searched the same data) isn't launched; the user is taken to the existing
+// job's page with a notice and the option to run the job again anyway.
+
+package server
+
+import (
+	"errors"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+)
+
+// Constants associated with the detection of duplicate jobs
+const (
+	AllowDuplicateInputName   = "allowDuplicate" // Run the job even if it is a duplicate
+	DuplicateInputName        = "duplicate"      // Query parameter of a job page reached from a duplicate
+	DefaultDuplicateJobWindow = 10 * time.Minute // Default time a finished job is a duplicate for
+)
+
+var ErrInvalidDuplicateJobWindow = errors.New("invalid duplicate job window")
+
+// SetDuplicateJobWindow sets the time after a job has finished for which an identical job is a
+// duplicate. A running job is always a duplicate, unless the window is zero, which disables the
+// detection of duplicate jobs.
+func (j *JobServer) SetDuplicateJobWindow(window time.Duration) error {
+
+	// Precondition
+	if window < 0 {
+		return ErrInvalidDuplicateJobWindow
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("duplicateJobWindow", window.String()).
+		Msg("Setting the duplicate job window")
+
+	j.duplicateJobWindow = window
+	return nil
+}
+
+// isDuplicateCandidate returns true if a new job that is identical to the job would be a duplicate,
+// i.e. the job hasn't finished or it found paths within the window before now.
+func isDuplicateCandidate(j1 *job.Job, window time.Duration, now time.Time) bool {
+	switch j1.Progress.State {
+	case job.NotStarted, job.InProgress:
+		return true
+	case job.CompleteResults, job.CompleteNoResults:
+		return now.Sub(j1.Progress.EndTime) <= window
+	}
+
+	return false
+}
+
+// findDuplicate returns the GUID of the most recently started job that the job configuration would
+// duplicate. The caller must hold the submitLock.
+func (j *JobRunner) findDuplicate(jobConf *job.JobConfiguration, window time.Duration) (
+	string, bool) {
+
+	j.jobsLock.RLock()
+	defer j.jobsLock.RUnlock()
+
+	now := time.Now()
+	var duplicate *job.Job
+
+	for _, j1 := range j.jobs {
+		if !isDuplicateCandidate(j1, window, now) ||
+			j1.Provenance.Signature != j.provenance.Signature ||
+			!j1.Provenance.Loaded.Equal(j.provenance.Loaded) ||
+			!j1.Configuration.IsIdentical(jobConf) {
+			continue
+		}
+
+		if duplicate == nil || j1.Progress.StartTime.After(duplicate.Progress.StartTime) {
+			duplicate = j1
+		}
+	}
+
+	if duplicate == nil {
+		return "", false
+	}
+
+	return duplicate.GUID, true
+}
+
+// SubmitUnlessDuplicate submits the job unless it would duplicate a job that is running or that
+// finished within the window, in which case the GUID of the existing job is returned. A zero window
+// disables the detection of duplicates.
+func (j *JobRunner) SubmitUnlessDuplicate(jobConf *job.JobConfiguration, window time.Duration) (
+	string, bool, error) {
+
+	// Preconditions
+	if jobConf == nil {
+		return InvalidGUID, false, ErrJobConfIsNil
+	}
+
+	if window == 0 {
+		guid, err := j.Submit(jobConf)
+		return guid, false, err
+	}
+
+	// Jobs submitted at the same time (e.g. by a double-click) are checked one at a time
+	j.submitLock.Lock()
+	defer j.submitLock.Unlock()
+
+	if guid, found := j.findDuplicate(jobConf, window); found {
+		logging.Logger.Info().
+			Str(logging.ComponentField, componentName).
+			Str(loggingGUIDField, guid).
+			Msg("Job is a duplicate of an existing job")
+
+		return guid, true, nil
+	}
+
+	guid, err := j.Submit(jobConf)
+	return guid, false, err
+}
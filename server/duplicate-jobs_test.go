@@ -0,0 +1,106 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aymerick/raymond"
+	"github.com/cdclaxton/shortest-path-web-app/filedetector"
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSetDuplicateJobWindow(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	assert.Equal(t, DefaultDuplicateJobWindow, server.duplicateJobWindow)
+	assert.ErrorIs(t, server.SetDuplicateJobWindow(-time.Second), ErrInvalidDuplicateJobWindow)
+	assert.NoError(t, server.SetDuplicateJobWindow(0))
+	assert.Equal(t, time.Duration(0), server.duplicateJobWindow)
+}
+
+func TestIsDuplicateCandidate(t *testing.T) {
+
+	now := time.Now()
+	testCases := []struct {
+		state    job.JobState
+		endTime  time.Time
+		expected bool
+	}{
+		{job.NotStarted, time.Time{}, true},
+		{job.InProgress, time.Time{}, true},
+		{job.CompleteResults, now.Add(-time.Minute), true},
+		{job.CompleteNoResults, now.Add(-time.Minute), true},
+		{job.CompleteResults, now.Add(-time.Hour), false},
+		{job.Failed, now, false},
+		{job.TimedOut, now, false},
+	}
+
+	for _, testCase := range testCases {
+		j1 := &job.Job{Progress: job.JobProgress{State: testCase.state, EndTime: testCase.endTime}}
+		assert.Equal(t, testCase.expected, isDuplicateCandidate(j1, 10*time.Minute, now),
+			testCase.state)
+	}
+}
+
+// uploadJob from the form and return the location of the job's page.
+func uploadJob(t *testing.T, server *JobServer, numberHops int, allowDuplicate bool) string {
+	form := buildFormData(numberHops, "Dataset-1", "e-1, e-2", "", "", "", "")
+	if allowDuplicate {
+		form.Add(AllowDuplicateInputName, "true")
+	}
+
+	w := postForm(server.Routes(), "/upload", form)
+	assert.Equal(t, http.StatusFound, w.Code)
+	waitForJobsToFinish(server.runner)
+
+	return w.Result().Header.Get("Location")
+}
+
+func TestUploadDuplicateJob(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+	handler := server.Routes()
+
+	location := uploadJob(t, server, 2, false)
+	guid := extractGuidFromLocation(t, location)
+	assert.NotContains(t, getPage(handler, location).Body.String(), "An identical job")
+
+	// The identical job isn't run, so the user is taken to the existing job's page
+	duplicate := uploadJob(t, server, 2, false)
+	assert.Equal(t, "/job/"+guid+"?"+DuplicateInputName+"=true", duplicate)
+	assert.Len(t, server.runner.jobs, 1)
+
+	body := getPage(handler, duplicate).Body.String()
+	assert.Contains(t, body, raymond.Escape("An identical job was submitted recently"))
+	assert.Contains(t, body, "../?rerun="+guid+"&amp;allowDuplicate=true")
+
+	// The pre-populated form runs the job even though it is identical
+	body = getPage(handler, "/?rerun="+guid+"&allowDuplicate=true").Body.String()
+	assert.Contains(t, body, `name="allowDuplicate" value="true"`)
+	assert.NotContains(t, getPage(handler, "/?rerun="+guid).Body.String(), `name="allowDuplicate"`)
+
+	again := uploadJob(t, server, 2, true)
+	assert.NotEqual(t, guid, extractGuidFromLocation(t, again))
+	assert.False(t, strings.Contains(again, DuplicateInputName))
+
+	// A job with a different configuration isn't a duplicate
+	different := uploadJob(t, server, 3, false)
+	assert.False(t, strings.Contains(different, DuplicateInputName))
+	assert.Len(t, server.runner.jobs, 3)
+
+	// A job searching different data isn't a duplicate
+	server.runner.SetProvenance(filedetector.DataProvenance{Signature: "1234"})
+	assert.False(t, strings.Contains(uploadJob(t, server, 2, false), DuplicateInputName))
+	assert.Len(t, server.runner.jobs, 4)
+
+	// A job that finished before the window isn't a duplicate
+	assert.NoError(t, server.SetDuplicateJobWindow(time.Nanosecond))
+	assert.False(t, strings.Contains(uploadJob(t, server, 2, false), DuplicateInputName))
+	assert.Len(t, server.runner.jobs, 5)
+}
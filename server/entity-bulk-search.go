@@ -0,0 +1,154 @@
+// A bulk search checks which of a large list of entity IDs are in the graph stores and summarises
+// each entity in an Excel report, rather than submitting a job just to see which entities are known.
+
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+
+	"github.com/cdclaxton/shortest-path-web-app/i18n"
+	"github.com/cdclaxton/shortest-path-web-app/i2chart"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cdclaxton/shortest-path-web-app/normalisation"
+	"github.com/cdclaxton/shortest-path-web-app/search"
+)
+
+// Constants associated with the bulk search of entities
+const (
+	BulkSearchEntitiesInputName = "entityIds"  // Name of the textbox containing the entity IDs
+	BulkSearchFileInputName     = "entityFile" // Name of the file input containing entity IDs
+	MaxBulkSearchEntityIds      = 100000       // Maximum number of entity IDs in a bulk search
+	bulkSearchUrl               = "/bulk-search"
+	bulkSearchFilename          = "entity-search.xlsx"
+)
+
+// readBulkSearchEntityIds from the textbox and the uploaded file, removing any duplicates.
+func readBulkSearchEntityIds(req *http.Request, normaliser *normalisation.Normaliser) ([]string,
+	error) {
+
+	if err := parseUploadForm(req, MaxUploadSize); err != nil {
+		return nil, i18n.Wrap(err, "error.unableToParseForm", err)
+	}
+
+	entityIds := splitEntityIDs(req.FormValue(BulkSearchEntitiesInputName), normaliser)
+
+	fileContents, err := readEntitiesFile(req, BulkSearchFileInputName)
+	if err != nil {
+		return nil, i18n.Wrap(err, "error.datasetFile", err)
+	}
+
+	entityIds = uniqueEntityIds(append(entityIds, splitEntityIDs(fileContents, normaliser)...))
+
+	if len(entityIds) == 0 {
+		return nil, i18n.NewMessage("error.noBulkSearchEntityIds")
+	}
+
+	if len(entityIds) > MaxBulkSearchEntityIds {
+		return nil, i18n.NewMessage("error.tooManyBulkSearchEntityIds", len(entityIds),
+			MaxBulkSearchEntityIds)
+	}
+
+	return entityIds, nil
+}
+
+// handleBulkSearch returns the bulk search page (GET) or the Excel report of the entities (POST).
+func (j *JobServer) handleBulkSearch(w http.ResponseWriter, req *http.Request) {
+
+	settings := j.pageSettings(w, req)
+	description := j.translator.Translate(settings.language, "bulkSearch.description",
+		MaxBulkSearchEntityIds)
+
+	if req.Method != http.MethodPost {
+		fmt.Fprint(w, withCsrfToken(w, req, j.render(j.bulkSearchTemplate, settings, map[string]interface{}{
+			"description": description,
+		})))
+		return
+	}
+
+	req.Body = http.MaxBytesReader(w, req.Body, MaxUploadSize)
+
+	entityIds, err := readBulkSearchEntityIds(req, j.entityIdNormaliser)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, withCsrfToken(w, req, j.render(j.bulkSearchTemplate, settings, map[string]interface{}{
+			"description": description,
+			"error":       j.translator.TranslateError(settings.language, err),
+		})))
+		return
+	}
+
+	if !j.checkCsrfToken(w, req) {
+		return
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Int("numberOfEntityIds", len(entityIds)).
+		Msg("Received request for a bulk search of entities")
+
+	folder, err := os.MkdirTemp(j.runner.folder, "bulk-search-")
+	if err == nil {
+		defer os.RemoveAll(folder)
+		err = j.writeBulkSearch(w, entityIds, path.Join(folder, bulkSearchFilename))
+	}
+
+	if err != nil {
+		logging.Logger.Error().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to write the bulk search of entities")
+
+		w.WriteHeader(http.StatusInternalServerError)
+		page := j.render(j.errorTemplate, settings, map[string]string{
+			"reason": j.translator.TranslateError(settings.language, err),
+		})
+		fmt.Fprint(w, page)
+	}
+}
+
+// bulkSearch for the entities, redacting their attributes.
+func (j *JobServer) bulkSearch(entityIds []string) ([]search.BulkEntity, error) {
+
+	results, err := j.runner.searchEngine.BulkSearch(entityIds)
+	if err != nil {
+		return nil, err
+	}
+
+	if j.redactor != nil {
+		for idx := range results {
+			results[idx].Attributes = j.redactAttributes(results[idx].Attributes)
+		}
+	}
+
+	return results, nil
+}
+
+// writeBulkSearch report of the entities to the response as an Excel file. The Excel file is
+// written to the filepath first, as the Excel writer requires a filepath.
+func (j *JobServer) writeBulkSearch(w http.ResponseWriter, entityIds []string,
+	filepath string) error {
+
+	results, err := j.bulkSearch(entityIds)
+	if err != nil {
+		return err
+	}
+
+	if err := i2chart.WriteToExcel(filepath, search.BulkReportRows(results)); err != nil {
+		return err
+	}
+
+	file, err := os.Open(filepath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%v\"", bulkSearchFilename))
+	w.Header().Set("Content-Type", excelContentType)
+	_, err = io.Copy(w, file)
+	return err
+}
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/i2chart"
+	"github.com/cdclaxton/shortest-path-web-app/redaction"
+	"github.com/cdclaxton/shortest-path-web-app/search"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBulkSearch(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	redactor, err := redaction.NewRedactor(redaction.RulesConfig{Attributes: []string{"DOB"}})
+	assert.NoError(t, err)
+	server.SetRedactor(redactor)
+
+	handler := server.Routes()
+
+	// Page with the form
+	w := getPage(handler, "/bulk-search")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `name="entityIds"`)
+
+	// No entity IDs
+	w = postForm(handler, "/bulk-search", url.Values{BulkSearchEntitiesInputName: {" "}})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "no entity IDs were given")
+
+	// Report of the entities
+	w = postForm(handler, "/bulk-search", url.Values{
+		BulkSearchEntitiesInputName: {"e-1, e-1000\ne-1"},
+	})
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, excelContentType, w.Header().Get("Content-Type"))
+
+	filepath := path.Join(t.TempDir(), "report.xlsx")
+	assert.NoError(t, os.WriteFile(filepath, w.Body.Bytes(), 0600))
+
+	rows, err := i2chart.ReadFromExcel(filepath, "Sheet1")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(rows))
+	assert.Equal(t, search.BulkReportHeader, rows[0])
+
+	assert.Equal(t, []string{"e-1", "Yes", "Yes", "Person"}, rows[1][:4])
+	assert.Contains(t, rows[1][4], "DOB: [REDACTED]")
+	assert.Contains(t, rows[1][4], "Forename: Bob")
+
+	assert.Equal(t, []string{"e-1000", "No", "No"}, rows[2][:3])
+}
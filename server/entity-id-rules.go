@@ -0,0 +1,41 @@
+package server
+
+import (
+	"errors"
+
+	"github.com/cdclaxton/shortest-path-web-app/job"
+)
+
+// Maximum number of invalid entity IDs listed on the input problem page
+const maxInvalidEntityIdsShown = 100
+
+// inputProblemContext for the input problem page. If the problem is that entity IDs failed the
+// entity ID rules, each entity ID is listed with the reason it is invalid.
+func (j *JobServer) inputProblemContext(language string, err error) map[string]interface{} {
+
+	ctx := map[string]interface{}{
+		"reason": j.translator.TranslateError(language, err),
+	}
+
+	var invalidErr *job.InvalidEntityIdsError
+	if !errors.As(err, &invalidErr) {
+		return ctx
+	}
+
+	invalid := []map[string]string{}
+	for idx, entityId := range invalidErr.EntityIds {
+		if idx == maxInvalidEntityIdsShown {
+			ctx["moreInvalidEntityIds"] = j.translator.Translate(language,
+				"entityIdRules.more", len(invalidErr.EntityIds)-maxInvalidEntityIdsShown)
+			break
+		}
+
+		invalid = append(invalid, map[string]string{
+			"entityId": entityId.EntityId,
+			"reason":   j.translator.Translate(language, entityId.Reason),
+		})
+	}
+	ctx["invalidEntityIds"] = invalid
+
+	return ctx
+}
@@ -0,0 +1,225 @@
+// The neighbourhood of an entity is the subgraph of entities within one or two steps of it. It is
+// found using the spider engine with the entity as the only seed, so that an analyst can explore the
+// context of an entity directly from its page.
+
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/cdclaxton/shortest-path-web-app/i18n"
+	"github.com/cdclaxton/shortest-path-web-app/i2chart"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cdclaxton/shortest-path-web-app/set"
+	"github.com/cdclaxton/shortest-path-web-app/spider"
+)
+
+// Constants associated with the neighbourhood of an entity
+const (
+	NeighbourhoodInputName = "neighbourhood" // Number of steps from the entity
+	MaxNeighbourhoodSteps  = 2               // Maximum number of steps from the entity
+	maxNeighbourhoodRows   = 500             // Maximum number of connections shown on the page
+	neighbourhoodUrl       = "/entity-neighbourhood/"
+)
+
+// A NeighbourhoodConnection is a connection between two entities in the neighbourhood.
+type NeighbourhoodConnection struct {
+	Entity1 string
+	Entity2 string
+}
+
+// parseNeighbourhoodSteps from the URL's query parameters. Zero steps are returned if the
+// neighbourhood isn't requested.
+func parseNeighbourhoodSteps(req *http.Request) (int, error) {
+
+	value := req.URL.Query().Get(NeighbourhoodInputName)
+	if len(value) == 0 {
+		return 0, nil
+	}
+
+	steps, err := strconv.Atoi(value)
+	if err != nil || steps < 1 || steps > MaxNeighbourhoodSteps {
+		return 0, i18n.NewMessage("error.invalidNeighbourhood", value, MaxNeighbourhoodSteps)
+	}
+
+	return steps, nil
+}
+
+// neighbourhood of the entity within the number of steps.
+func (j *JobServer) neighbourhood(entityId string, steps int) (*spider.SpiderResults, error) {
+	return j.spiderRunner.spider.Execute(steps, set.NewPopulatedSet(entityId))
+}
+
+// neighbourhoodConnections in the subgraph, sorted by entity ID. Each connection is only given once.
+func neighbourhoodConnections(results *spider.SpiderResults) ([]NeighbourhoodConnection, error) {
+
+	entityIds, err := results.Subgraph.EntityIds()
+	if err != nil {
+		return nil, err
+	}
+
+	connections := []NeighbourhoodConnection{}
+	for _, entityId := range entityIds.ToSlice() {
+
+		adjacent, err := results.Subgraph.EntityIdsAdjacentTo(entityId)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, adjacentId := range adjacent.ToSlice() {
+			if entityId < adjacentId {
+				connections = append(connections, NeighbourhoodConnection{
+					Entity1: entityId,
+					Entity2: adjacentId,
+				})
+			}
+		}
+	}
+
+	sort.Slice(connections, func(i, j int) bool {
+		if connections[i].Entity1 != connections[j].Entity1 {
+			return connections[i].Entity1 < connections[j].Entity1
+		}
+		return connections[i].Entity2 < connections[j].Entity2
+	})
+
+	return connections, nil
+}
+
+// neighbourhoodContext for the entity page. An empty context is returned if the neighbourhood
+// isn't requested.
+func (j *JobServer) neighbourhoodContext(req *http.Request, entityId string,
+	language string) map[string]interface{} {
+
+	ctx := map[string]interface{}{}
+
+	steps, err := parseNeighbourhoodSteps(req)
+	if err == nil && steps == 0 {
+		return ctx
+	}
+
+	var connections []NeighbourhoodConnection
+	if err == nil {
+		var results *spider.SpiderResults
+		results, err = j.neighbourhood(entityId, steps)
+		if err == nil {
+			connections, err = neighbourhoodConnections(results)
+		}
+	}
+
+	if err != nil {
+		logging.Logger.Warn().
+			Str(logging.ComponentField, componentName).
+			Str("entityID", entityId).
+			Err(err).
+			Msg("Failed to find the neighbourhood of the entity")
+
+		ctx["error"] = j.translator.TranslateError(language, err)
+		return ctx
+	}
+
+	ctx["steps"] = steps
+	ctx["connections"] = connections
+	ctx["download"] = fmt.Sprintf("..%v%v?%v=%v", neighbourhoodUrl, entityId,
+		NeighbourhoodInputName, steps)
+
+	if len(connections) > maxNeighbourhoodRows {
+		ctx["connections"] = connections[:maxNeighbourhoodRows]
+		ctx["truncated"] = j.translator.Translate(language, "entity.neighbourhoodTruncated",
+			maxNeighbourhoodRows)
+	}
+
+	if len(connections) > 0 {
+		ctx["summary"] = j.translator.Translate(language, "entity.neighbourhoodFound",
+			len(connections), steps)
+	} else {
+		ctx["summary"] = j.translator.Translate(language, "entity.neighbourhoodNotFound", steps)
+	}
+
+	return ctx
+}
+
+// handleNeighbourhoodDownload returns the neighbourhood of an entity as an Excel file for i2.
+func (j *JobServer) handleNeighbourhoodDownload(w http.ResponseWriter, req *http.Request) {
+
+	entityId := strings.TrimPrefix(req.URL.Path, neighbourhoodUrl)
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("entityID", entityId).
+		Msg("Received request to download the neighbourhood of an entity")
+	settings := j.pageSettings(w, req)
+
+	steps, err := parseNeighbourhoodSteps(req)
+	if err == nil && steps == 0 {
+		err = i18n.NewMessage("error.invalidNeighbourhood", "", MaxNeighbourhoodSteps)
+	}
+
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		page := j.render(j.inputProblemTemplate, settings, map[string]string{
+			"reason": j.translator.TranslateError(settings.language, err),
+		})
+		fmt.Fprint(w, page)
+		return
+	}
+
+	folder, err := os.MkdirTemp(j.spiderRunner.folder, "neighbourhood-")
+	if err == nil {
+		defer os.RemoveAll(folder)
+		err = j.writeNeighbourhood(w, entityId, steps, path.Join(folder, "neighbourhood.xlsx"))
+	}
+
+	if err != nil {
+		logging.Logger.Error().
+			Str(logging.ComponentField, componentName).
+			Str("entityID", entityId).
+			Err(err).
+			Msg("Failed to write the neighbourhood of the entity")
+
+		w.WriteHeader(http.StatusInternalServerError)
+		page := j.render(j.errorTemplate, settings, map[string]string{
+			"reason": j.translator.TranslateError(settings.language, err),
+		})
+		fmt.Fprint(w, page)
+	}
+}
+
+// writeNeighbourhood of the entity to the response as an Excel file. The Excel file is written to
+// the filepath first, as the Excel writer requires a filepath.
+func (j *JobServer) writeNeighbourhood(w http.ResponseWriter, entityId string, steps int,
+	filepath string) error {
+
+	results, err := j.neighbourhood(entityId, steps)
+	if err != nil {
+		return err
+	}
+
+	table, err := j.spiderRunner.chartBuilder.Build(results)
+	if err != nil {
+		return err
+	}
+
+	if err := i2chart.WriteToExcel(filepath, table); err != nil {
+		return err
+	}
+
+	file, err := os.Open(filepath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	filename := fmt.Sprintf("neighbourhood - %v - %v steps.xlsx", entityId, steps)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%v\"", filename))
+	w.Header().Set("Content-Type", excelContentType)
+	_, err = io.Copy(w, file)
+	return err
+}
@@ -0,0 +1,88 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNeighbourhoodSteps(t *testing.T) {
+
+	testCases := []struct {
+		query         string
+		expectedSteps int
+		expectError   bool
+	}{
+		{query: "", expectedSteps: 0},
+		{query: "?neighbourhood=1", expectedSteps: 1},
+		{query: "?neighbourhood=2", expectedSteps: 2},
+		{query: "?neighbourhood=0", expectError: true},
+		{query: "?neighbourhood=3", expectError: true},
+		{query: "?neighbourhood=a", expectError: true},
+	}
+
+	for _, testCase := range testCases {
+		req := httptest.NewRequest(http.MethodGet, "/entity/e-1"+testCase.query, nil)
+		steps, err := parseNeighbourhoodSteps(req)
+
+		if testCase.expectError {
+			assert.Error(t, err)
+		} else {
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedSteps, steps)
+		}
+	}
+}
+
+func TestEntityNeighbourhood(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	handler := server.Routes()
+
+	// The neighbourhood isn't shown by default
+	w := getPage(handler, "/entity/e-1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, `href="e-1?neighbourhood=1"`)
+	assert.NotContains(t, body, "connections found")
+
+	// One step from the entity
+	w = getPage(handler, "/entity/e-1?neighbourhood=1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	body = w.Body.String()
+	assert.Contains(t, body, "2 connections found within 1 steps of the entity")
+	assert.Contains(t, body, `<a href="e-3">e-3</a>`)
+	assert.NotContains(t, body, `<a href="e-4">e-4</a>`)
+	assert.Contains(t, body, `href="../entity-neighbourhood/e-1?neighbourhood=1"`)
+
+	// Two steps from the entity
+	w = getPage(handler, "/entity/e-1?neighbourhood=2")
+	assert.Equal(t, http.StatusOK, w.Code)
+	body = w.Body.String()
+	assert.Contains(t, body, "3 connections found within 2 steps of the entity")
+	assert.Contains(t, body, `<a href="e-4">e-4</a>`)
+
+	// An entity without any connections
+	w = getPage(handler, "/entity/e-100?neighbourhood=1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "No connections found within 1 steps of the entity")
+
+	// Invalid number of steps
+	w = getPage(handler, "/entity/e-1?neighbourhood=3")
+	assert.Contains(t, w.Body.String(), "invalid neighbourhood")
+
+	// Download the neighbourhood
+	w = getPage(handler, "/entity-neighbourhood/e-1?neighbourhood=2")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, excelContentType, w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "neighbourhood - e-1 - 2 steps.xlsx")
+	assert.True(t, w.Body.Len() > 0)
+
+	w = getPage(handler, "/entity-neighbourhood/e-1")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
@@ -0,0 +1,95 @@
+// The documents and entities linked to an entity are shown a page at a time, so that the page for a
+// super-node (an entity with thousands of links) remains a manageable size.
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/cdclaxton/shortest-path-web-app/i18n"
+	"github.com/cdclaxton/shortest-path-web-app/search"
+)
+
+// Constants associated with paging the entity page
+const (
+	EntityDocumentsOffsetInputName = "documentsOffset" // Offset of the linked documents
+	EntityEntitiesOffsetInputName  = "entitiesOffset"  // Offset of the linked entities
+	EntityPageSizeInputName        = "limit"           // Number of linked documents and entities
+)
+
+// parseIntParameter from the URL's query parameters, returning the default value if it is blank.
+func parseIntParameter(values url.Values, name string, defaultValue int) (int, error) {
+
+	value := values.Get(name)
+	if len(value) == 0 {
+		return defaultValue, nil
+	}
+
+	result, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, i18n.NewMessage("error.invalidPage", name, value)
+	}
+
+	return result, nil
+}
+
+// parseEntityPages for the linked documents and the linked entities.
+func parseEntityPages(req *http.Request) (search.Page, search.Page, error) {
+
+	values := req.URL.Query()
+
+	limit, err := parseIntParameter(values, EntityPageSizeInputName, search.DefaultPageSize)
+	if err != nil {
+		return search.Page{}, search.Page{}, err
+	}
+
+	pages := []search.Page{}
+	for _, name := range []string{EntityDocumentsOffsetInputName, EntityEntitiesOffsetInputName} {
+
+		offset, err := parseIntParameter(values, name, 0)
+		if err != nil {
+			return search.Page{}, search.Page{}, err
+		}
+
+		page, err := search.NewPage(offset, limit)
+		if err != nil {
+			return search.Page{}, search.Page{}, i18n.Wrap(err, "error.invalidPageSize",
+				search.MaxPageSize)
+		}
+
+		pages = append(pages, page)
+	}
+
+	return pages[0], pages[1], nil
+}
+
+// pagerContext for the previous and next links of a table on the entity page. The links keep the
+// other query parameters, so that paging through one table doesn't reset the other.
+func (j *JobServer) pagerContext(req *http.Request, entityId string, name string,
+	details search.PageDetails, language string) map[string]interface{} {
+
+	link := func(offset int) string {
+		values := req.URL.Query()
+		values.Set(name, strconv.Itoa(offset))
+		return fmt.Sprintf("%v?%v", url.PathEscape(entityId), values.Encode())
+	}
+
+	ctx := map[string]interface{}{}
+	if details.Total > 0 {
+		ctx["summary"] = j.translator.Translate(language, "entity.showing", details.First,
+			details.Last, details.Total)
+	}
+
+	if details.HasPrevious {
+		ctx["previous"] = link(details.PreviousOffset)
+	}
+
+	if details.HasNext {
+		ctx["next"] = link(details.NextOffset)
+	}
+
+	return ctx
+}
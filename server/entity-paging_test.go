@@ -0,0 +1,94 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/search"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseEntityPages(t *testing.T) {
+
+	testCases := []struct {
+		query             string
+		expectedDocuments search.Page
+		expectedEntities  search.Page
+		expectError       bool
+	}{
+		{
+			query:             "",
+			expectedDocuments: search.Page{Offset: 0, Limit: search.DefaultPageSize},
+			expectedEntities:  search.Page{Offset: 0, Limit: search.DefaultPageSize},
+		},
+		{
+			query:             "?documentsOffset=10&entitiesOffset=20&limit=5",
+			expectedDocuments: search.Page{Offset: 10, Limit: 5},
+			expectedEntities:  search.Page{Offset: 20, Limit: 5},
+		},
+		{
+			query:       "?documentsOffset=a",
+			expectError: true,
+		},
+		{
+			query:       "?entitiesOffset=-1",
+			expectError: true,
+		},
+		{
+			query:       "?limit=0",
+			expectError: true,
+		},
+		{
+			query:       "?limit=1001",
+			expectError: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		req := httptest.NewRequest(http.MethodGet, "/entity/e-1"+testCase.query, nil)
+		documents, entities, err := parseEntityPages(req)
+
+		if testCase.expectError {
+			assert.Error(t, err)
+		} else {
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedDocuments, documents)
+			assert.Equal(t, testCase.expectedEntities, entities)
+		}
+	}
+}
+
+func TestEntityPaging(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	handler := server.Routes()
+
+	// All of the links fit on the page by default
+	w := getPage(handler, "/entity/e-1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, "Showing 1 to 3 of 3.")
+	assert.Contains(t, body, "Showing 1 to 2 of 2.")
+	assert.NotContains(t, body, "Next</a>")
+
+	// One link on each page
+	w = getPage(handler, "/entity/e-1?limit=1&documentsOffset=1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	body = w.Body.String()
+	assert.Contains(t, body, "Showing 2 to 2 of 3.")
+	assert.Contains(t, body, `href="e-1?documentsOffset=0&amp;limit=1"`)
+	assert.Contains(t, body, `href="e-1?documentsOffset=2&amp;limit=1"`)
+	assert.Contains(t, body, "Showing 1 to 1 of 2.")
+	assert.Contains(t, body, `href="e-1?documentsOffset=1&amp;entitiesOffset=1&amp;limit=1"`)
+	assert.Contains(t, body, `<a href="e-2">e-2</a>`)
+	assert.NotContains(t, body, `<a href="e-3">e-3</a>`)
+
+	// Invalid page
+	w = getPage(handler, "/entity/e-1?limit=0")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid page")
+}
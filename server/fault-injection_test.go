@@ -0,0 +1,50 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/bfs"
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestServerWithFailingStore(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	// Every operation on the unipartite store fails
+	faulty, err := graphstore.NewFaultyUnipartiteGraphStore(server.runner.searchEngine.Unipartite,
+		graphstore.FaultConfig{FailEvery: 1})
+	assert.NoError(t, err)
+
+	server.runner.searchEngine.Unipartite = faulty
+	server.runner.pathFinder, err = bfs.NewPathFinder(faulty)
+	assert.NoError(t, err)
+
+	handler := server.Routes()
+
+	// The entity page shows the error
+	w := getPage(handler, "/entity/e-1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "injected fault")
+
+	// The path query fails
+	w = getPage(handler, "/path?from=e-1&to=e-2&format=json")
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Contains(t, w.Body.String(), "injected fault")
+
+	// The web-app isn't ready
+	w = getPage(handler, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	// A job fails rather than crashing the runner
+	w = postForm(handler, "/upload", buildFormData(1, "Dataset-1", "e-1, e-2", "", "", "", ""))
+	assert.Equal(t, http.StatusFound, w.Code)
+	waitForJobsToFinish(server.runner)
+
+	w = getPage(handler, w.Header().Get("Location"))
+	assert.Contains(t, w.Body.String(), "Job failed")
+}
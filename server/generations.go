@@ -0,0 +1,397 @@
+// The graphs of a job server can be built in generations (see graphbuilder.Generations). An admin
+// can build a new generation from the input files, which is switched to once it has been built and
+// validated, so a bad data drop doesn't stop the current generation being served. The previous
+// generation is kept open, so an admin can roll back to it instantly.
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphbuilder"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cdclaxton/shortest-path-web-app/search"
+	"github.com/cdclaxton/shortest-path-web-app/spider"
+)
+
+// Constants associated with the generations endpoint
+const (
+	generationsUrl           = "/admin/generations"
+	GenerationActionInput    = "action"   // Name of the input holding the action to perform
+	generationActionBuild    = "build"    // Build a new generation and switch to it
+	generationActionRollback = "rollback" // Roll back to the previous generation
+)
+
+var (
+	ErrGenerationsDisabled     = errors.New("the graphs aren't built in generations")
+	ErrGenerationBuilding      = errors.New("a new generation is already being built")
+	ErrGraphBuilderIsNil       = errors.New("graph builder is nil")
+	ErrUnknownGenerationAction = errors.New("unknown generation action")
+)
+
+// GenerationsResponse is the JSON response of the generations endpoint.
+type GenerationsResponse struct {
+	Current        int    `json:"current"`                  // Generation being served
+	Previous       int    `json:"previous"`                 // Generation that can be rolled back to (zero if none)
+	Building       bool   `json:"building"`                 // Is a new generation being built?
+	LastBuildError string `json:"lastBuildError,omitempty"` // Reason the last build failed
+	Error          string `json:"error,omitempty"`          // Reason the request failed
+}
+
+// graphGenerations served by a job server.
+type graphGenerations struct {
+	generations    *graphbuilder.Generations
+	previous       *graphbuilder.GraphBuilder // Graph of the previous generation (nil if not opened)
+	building       bool                       // Is a new generation being built?
+	lastBuildError error                      // Reason the last build failed (nil if it didn't)
+	lock           sync.Mutex                 // Serialises the switches between generations
+}
+
+// SetGraph served by the job server. The jobs and queries are coordinated with the updates of the
+// graph using the graph's store lock.
+func (j *JobServer) SetGraph(builder *graphbuilder.GraphBuilder) {
+	j.graphLock.Lock()
+	j.graph = builder
+	j.graphLock.Unlock()
+
+	j.SetStoreLock(builder.Lock)
+}
+
+// Graph served by the job server, which changes if a new generation is switched to.
+func (j *JobServer) Graph() *graphbuilder.GraphBuilder {
+	j.graphLock.RLock()
+	defer j.graphLock.RUnlock()
+
+	return j.graph
+}
+
+// RefreshStats shown on the stats page from those of the graph, e.g. after the graph has been
+// pruned and its stats updated.
+func (j *JobServer) RefreshStats() {
+	j.storeLock.BeginRead()
+	defer j.storeLock.EndRead()
+
+	j.graphLock.Lock()
+	defer j.graphLock.Unlock()
+
+	if j.graph != nil {
+		j.stats = j.graph.Stats
+	}
+}
+
+// SetGenerations of the graph, which enables the generations endpoint. The graph of the current
+// generation must have been set.
+func (j *JobServer) SetGenerations(generations *graphbuilder.Generations) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Bool("enabled", generations != nil).
+		Msg("Setting the generations of the graph")
+
+	j.generations = &graphGenerations{generations: generations}
+}
+
+// CloseGraphs served by the job server, including the previous generation if it is open.
+func (j *JobServer) CloseGraphs() error {
+
+	if j.generations != nil {
+		j.generations.lock.Lock()
+		defer j.generations.lock.Unlock()
+
+		if j.generations.previous != nil {
+			if err := j.generations.previous.Close(); err != nil {
+				return err
+			}
+			j.generations.previous = nil
+		}
+	}
+
+	if graph := j.Graph(); graph != nil {
+		return graph.Close()
+	}
+
+	return nil
+}
+
+// graphComponents that read a graph, which are made before a switch to the graph so the switch
+// itself can't fail.
+type graphComponents struct {
+	searchEngine *search.EntitySearch
+	spider       *spider.Spider
+}
+
+// makeGraphComponents that read the graph.
+func makeGraphComponents(builder *graphbuilder.GraphBuilder) (*graphComponents, error) {
+
+	if builder == nil {
+		return nil, ErrGraphBuilderIsNil
+	}
+
+	searchEngine, err := search.NewEntitySearch(builder.Bipartite, builder.Unipartite)
+	if err != nil {
+		return nil, err
+	}
+
+	// Suggestions of similar entity IDs are a convenience, so the graph can be served without them
+	if err := searchEngine.BuildSuggestionIndex(); err != nil {
+		logging.Logger.Warn().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to build the entity ID index for suggestions")
+	}
+
+	spider, err := spider.NewSpider(builder.Unipartite)
+	if err != nil {
+		return nil, err
+	}
+	spider.SetBipartite(builder.Bipartite)
+
+	return &graphComponents{
+		searchEngine: searchEngine,
+		spider:       spider,
+	}, nil
+}
+
+// switchGraph served by the job server to the graph, once the running jobs and queries have
+// finished reading the current graph, and then check the watchlists in the background. The graph
+// that was served is returned.
+func (j *JobServer) switchGraph(builder *graphbuilder.GraphBuilder,
+	components *graphComponents) *graphbuilder.GraphBuilder {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Msg("Switching the graph served by the job server")
+
+	// The pruning of the new graph is coordinated with the jobs and queries using the same lock
+	builder.Lock = j.storeLock
+
+	var old *graphbuilder.GraphBuilder
+	j.storeLock.Update(func() error {
+		j.runner.pathFinder.SetGraph(builder.Unipartite)
+		j.runner.chartBuilder.SetBipartite(builder.Bipartite)
+		j.runner.chartBuilder.SetUnipartite(builder.Unipartite)
+		j.runner.searchEngine = components.searchEngine
+		j.runner.SetProvenance(builder.Stats.Provenance)
+
+		j.spiderRunner.spider = components.spider
+		j.spiderRunner.spider.SetTombstones(j.tombstones)
+		j.spiderRunner.chartBuilder.SetBipartite(builder.Bipartite)
+		j.spiderRunner.SetProvenance(builder.Stats.Provenance)
+
+		j.graphLock.Lock()
+		old = j.graph
+		j.graph = builder
+		j.stats = builder.Stats
+		j.graphLock.Unlock()
+
+		return nil
+	})
+
+	// The watchlists are searched between in the new graph to alert on any new connections
+	go j.checkWatchlists()
+
+	return old
+}
+
+// activateGeneration that has been built, i.e. switch to its graph and keep the graph that was
+// served as the previous generation. The generation before that is closed and deleted. An error is
+// only returned if the graph wasn't switched to.
+func (j *JobServer) activateGeneration(builder *graphbuilder.GraphBuilder, generation int) error {
+
+	components, err := makeGraphComponents(builder)
+	if err != nil {
+		return err
+	}
+
+	j.generations.lock.Lock()
+	defer j.generations.lock.Unlock()
+
+	if err := j.generations.generations.Activate(generation); err != nil {
+		return err
+	}
+
+	unused := j.generations.previous
+	j.generations.previous = j.switchGraph(builder, components)
+
+	if unused != nil {
+		err = unused.Close()
+	}
+
+	if err == nil {
+		err = j.generations.generations.RemoveUnused()
+	}
+
+	if err != nil {
+		logging.Logger.Error().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to remove the unused generation of the graph")
+	}
+
+	return nil
+}
+
+// buildGeneration of the graph from the input files and switch to it if it is valid.
+func (j *JobServer) buildGeneration() {
+
+	builder, generation, err := j.generations.generations.Build()
+	if err == nil {
+		err = j.activateGeneration(builder, generation)
+		if err != nil {
+			builder.Close()
+		}
+	}
+
+	if err != nil {
+		logging.Logger.Error().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to build and switch to a new generation of the graph")
+	}
+
+	j.generations.lock.Lock()
+	defer j.generations.lock.Unlock()
+
+	j.generations.building = false
+	j.generations.lastBuildError = err
+}
+
+// startBuildingGeneration in the background unless a generation is already being built.
+func (j *JobServer) startBuildingGeneration() error {
+	j.generations.lock.Lock()
+	defer j.generations.lock.Unlock()
+
+	if j.generations.building {
+		return ErrGenerationBuilding
+	}
+
+	j.generations.building = true
+	go j.buildGeneration()
+
+	return nil
+}
+
+// rollbackGeneration to the previous generation, whose graph is opened if it isn't already open.
+func (j *JobServer) rollbackGeneration() error {
+
+	j.generations.lock.Lock()
+	defer j.generations.lock.Unlock()
+
+	state := j.generations.generations.State()
+	if state.Previous == 0 {
+		return graphbuilder.ErrNoPreviousGeneration
+	}
+
+	previous := j.generations.previous
+	if previous == nil {
+		var err error
+		previous, err = j.generations.generations.Open(state.Previous)
+		if err != nil {
+			return err
+		}
+	}
+
+	components, err := makeGraphComponents(previous)
+	if err == nil {
+		_, err = j.generations.generations.Rollback()
+	}
+
+	if err != nil {
+		if j.generations.previous == nil {
+			previous.Close()
+		}
+		return err
+	}
+
+	j.generations.previous = j.switchGraph(previous, components)
+	return nil
+}
+
+// generationsResponse with the state of the generations.
+func (j *JobServer) generationsResponse() GenerationsResponse {
+
+	j.generations.lock.Lock()
+	defer j.generations.lock.Unlock()
+
+	state := j.generations.generations.State()
+	response := GenerationsResponse{
+		Current:  state.Current,
+		Previous: state.Previous,
+		Building: j.generations.building,
+	}
+
+	if j.generations.lastBuildError != nil {
+		response.LastBuildError = j.generations.lastBuildError.Error()
+	}
+
+	return response
+}
+
+// writeGenerations response as JSON with the HTTP status code.
+func writeGenerations(w http.ResponseWriter, code int, response GenerationsResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logging.Logger.Error().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to write the generations response")
+	}
+}
+
+// handleGenerations returns the state of the generations. Posting the build action starts building
+// a new generation and posting the rollback action switches to the previous generation.
+func (j *JobServer) handleGenerations(w http.ResponseWriter, req *http.Request) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("method", req.Method).
+		Msg("Received request at " + generationsUrl)
+
+	if j.generations == nil {
+		writeGenerations(w, http.StatusNotFound, GenerationsResponse{
+			Error: ErrGenerationsDisabled.Error(),
+		})
+		return
+	}
+
+	code := http.StatusOK
+	var err error
+
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		switch req.FormValue(GenerationActionInput) {
+		case generationActionBuild:
+			code = http.StatusAccepted
+			err = j.startBuildingGeneration()
+		case generationActionRollback:
+			err = j.rollbackGeneration()
+		default:
+			code = http.StatusBadRequest
+			err = ErrUnknownGenerationAction
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	response := j.generationsResponse()
+	if err != nil {
+		logging.Logger.Error().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to perform the generation action")
+
+		if code != http.StatusBadRequest {
+			code = http.StatusConflict
+		}
+		response.Error = err.Error()
+	}
+
+	writeGenerations(w, code, response)
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphbuilder"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGenerationsEndpoint(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+	server.SetAdminToken("secret")
+
+	send := func(method string, action string) (int, GenerationsResponse) {
+		form := url.Values{}
+		form.Set(GenerationActionInput, action)
+		req := httptest.NewRequest(method, generationsUrl, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set(AdminTokenHeader, "secret")
+		w := httptest.NewRecorder()
+		server.Routes().ServeHTTP(w, req)
+
+		response := GenerationsResponse{}
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		return w.Code, response
+	}
+
+	// The graphs aren't built in generations
+	code, response := send(http.MethodGet, "")
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Equal(t, ErrGenerationsDisabled.Error(), response.Error)
+
+	// Serve the first generation of the graphs
+	generations, err := graphbuilder.NewGenerationsFromJson(
+		"../test-data-sets/set-0/config-pebble.json", t.TempDir())
+	assert.NoError(t, err)
+
+	first, generation, err := generations.Build()
+	assert.NoError(t, err)
+	assert.NoError(t, generations.Activate(generation))
+
+	server.SetGraph(first)
+	server.SetGenerations(generations)
+	defer func() { assert.NoError(t, server.CloseGraphs()) }()
+
+	code, response = send(http.MethodGet, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, GenerationsResponse{Current: 1}, response)
+
+	code, response = send(http.MethodPost, generationActionRollback)
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Equal(t, "no previous generation", response.Error)
+
+	code, _ = send(http.MethodPost, "unknown")
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	// Build the second generation, which is switched to
+	code, response = send(http.MethodPost, generationActionBuild)
+	assert.Equal(t, http.StatusAccepted, code)
+	assert.True(t, response.Building)
+
+	for response.Building {
+		time.Sleep(10 * time.Millisecond)
+		_, response = send(http.MethodGet, "")
+	}
+	assert.Equal(t, GenerationsResponse{Current: 2, Previous: 1}, response)
+
+	second := server.Graph()
+	assert.NotEqual(t, first, second)
+	assert.Equal(t, second.Bipartite, server.runner.searchEngine.Bipartite)
+	assert.Equal(t, second.Unipartite, server.runner.searchEngine.Unipartite)
+
+	paths, err := server.runner.pathFinder.PathsBetween(context.Background(), "e-1", "e-2", 1, false)
+	assert.NoError(t, err)
+	assert.NotNil(t, paths)
+
+	// Roll back to the first generation
+	code, response = send(http.MethodPost, generationActionRollback)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, GenerationsResponse{Current: 1, Previous: 2}, response)
+	assert.Equal(t, first, server.Graph())
+	assert.Equal(t, first.Bipartite, server.runner.searchEngine.Bipartite)
+}
@@ -0,0 +1,245 @@
+// The graph router serves several named graphs from one server. Each graph has its own job server
+// (and hence its own stores, job runners and i2 config) under /g/{graphName}/, whilst the default
+// graph is also served from the root so that existing URLs keep working.
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+)
+
+// Prefix of the URL of a named graph
+const graphUrlPrefix = "/g/"
+
+var (
+	ErrNoGraphs              = errors.New("no graphs")
+	ErrInvalidGraphName      = errors.New("invalid graph name")
+	ErrDuplicateGraphName    = errors.New("duplicate graph name")
+	ErrDefaultGraphNotFound  = errors.New("default graph not found")
+	ErrGraphJobServerIsNil   = errors.New("job server for the graph is nil")
+	ErrGraphConfigIncomplete = errors.New("graph config is incomplete")
+)
+
+// Graph names are used in URLs, so they are restricted to safe characters
+var graphNameRegex = regexp.MustCompile("^[A-Za-z0-9_-]{1,64}$")
+
+// NamedGraphConfig holds the config files of a named graph.
+type NamedGraphConfig struct {
+	Name           string `json:"name"`     // Name of the graph used in its URL
+	DataConfig     string `json:"data"`     // Path to the data config
+	I2Config       string `json:"i2"`       // Path to the i2 config
+	I2SpiderConfig string `json:"i2spider"` // Path to the i2 spider config
+	Message        string `json:"message"`  // Path to a message for the index page (optional)
+}
+
+// MultiGraphConfig holds the config of the graphs served by one server.
+type MultiGraphConfig struct {
+	Default string             `json:"default"` // Name of the graph served from the root
+	Graphs  []NamedGraphConfig `json:"graphs"`  // Config of each graph
+}
+
+// validateGraphNames checks the names are valid and unique and that the default graph exists.
+func validateGraphNames(defaultGraph string, names []string) error {
+
+	if len(names) == 0 {
+		return ErrNoGraphs
+	}
+
+	seen := map[string]bool{}
+	for _, name := range names {
+		if !graphNameRegex.MatchString(name) {
+			return fmt.Errorf("%w: '%v'", ErrInvalidGraphName, name)
+		}
+
+		if seen[name] {
+			return fmt.Errorf("%w: %v", ErrDuplicateGraphName, name)
+		}
+		seen[name] = true
+	}
+
+	if !seen[defaultGraph] {
+		return fmt.Errorf("%w: '%v'", ErrDefaultGraphNotFound, defaultGraph)
+	}
+
+	return nil
+}
+
+// Validate the multi-graph config.
+func (c MultiGraphConfig) Validate() error {
+
+	names := []string{}
+	for _, graph := range c.Graphs {
+		if len(graph.DataConfig) == 0 || len(graph.I2Config) == 0 || len(graph.I2SpiderConfig) == 0 {
+			return fmt.Errorf("%w: %v", ErrGraphConfigIncomplete, graph.Name)
+		}
+		names = append(names, graph.Name)
+	}
+
+	return validateGraphNames(c.Default, names)
+}
+
+// ReadMultiGraphConfig from a JSON file and validate it.
+func ReadMultiGraphConfig(filepath string) (*MultiGraphConfig, error) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("filepath", filepath).
+		Msg("Reading the multi-graph config")
+
+	bytes, err := os.ReadFile(filepath)
+	if err != nil {
+		return nil, err
+	}
+
+	var config MultiGraphConfig
+	if err := json.Unmarshal(bytes, &config); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+// A graphLink is shown in the header of the pages to select a graph.
+type graphLink struct {
+	Name    string // Name of the graph
+	Url     string // URL of the graph's index page
+	Current bool   // Is the page for this graph?
+}
+
+// graphBasePath is the path under which the graph is served. The default graph's base path is
+// blank, as its pages are served from the root.
+func graphBasePath(name string, defaultGraph string) string {
+	if name == defaultGraph {
+		return ""
+	}
+	return graphUrlPrefix + name
+}
+
+// setGraph the job server serves, where the names of all the graphs are given for the graph
+// selector. The pages are re-cached as they contain the selector.
+func (j *JobServer) setGraph(name string, defaultGraph string, names []string) error {
+
+	j.basePath = graphBasePath(name, defaultGraph)
+
+	j.graphLinks = []graphLink{}
+	for _, other := range names {
+		j.graphLinks = append(j.graphLinks, graphLink{
+			Name:    other,
+			Url:     graphBasePath(other, defaultGraph) + "/",
+			Current: other == name,
+		})
+	}
+
+	return j.cachePages()
+}
+
+// A GraphRouter routes requests to the job server of each graph.
+type GraphRouter struct {
+	defaultGraph string                // Name of the graph served from the root
+	names        []string              // Names of the graphs in alphabetical order
+	servers      map[string]*JobServer // Job server for each graph
+}
+
+// NewGraphRouter given the job server for each graph and the name of the graph to serve from the
+// root.
+func NewGraphRouter(defaultGraph string, servers map[string]*JobServer) (*GraphRouter, error) {
+
+	names := []string{}
+	for name, server := range servers {
+		if server == nil {
+			return nil, fmt.Errorf("%w: %v", ErrGraphJobServerIsNil, name)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	if err := validateGraphNames(defaultGraph, names); err != nil {
+		return nil, err
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("defaultGraph", defaultGraph).
+		Strs("graphs", names).
+		Msg("Making the graph router")
+
+	for _, name := range names {
+		if err := servers[name].setGraph(name, defaultGraph, names); err != nil {
+			return nil, err
+		}
+	}
+
+	return &GraphRouter{
+		defaultGraph: defaultGraph,
+		names:        names,
+		servers:      servers,
+	}, nil
+}
+
+// handleHealthz reports whether the job runners of every graph are responsive.
+func (g *GraphRouter) handleHealthz(w http.ResponseWriter, req *http.Request) {
+	checks := map[string]error{}
+	for _, name := range g.names {
+		for check, err := range g.servers[name].healthChecks() {
+			checks[name+"/"+check] = err
+		}
+	}
+
+	writeHealth(w, checks)
+}
+
+// handleReadyz reports whether the graph stores of every graph can be read.
+func (g *GraphRouter) handleReadyz(w http.ResponseWriter, req *http.Request) {
+	checks := map[string]error{}
+	for _, name := range g.names {
+		for check, err := range g.servers[name].readinessChecks() {
+			checks[name+"/"+check] = err
+		}
+	}
+
+	writeHealth(w, checks)
+}
+
+// Routes of the graphs. The pages of each graph are served under /g/{graphName}/ and the default
+// graph's pages, the static content and the theme are served from the root.
+func (g *GraphRouter) Routes() http.Handler {
+
+	mux := http.NewServeMux()
+
+	for _, name := range g.names {
+		prefix := graphUrlPrefix + name
+		mux.Handle(prefix+"/", http.StripPrefix(prefix, g.servers[name].Routes()))
+	}
+
+	mux.HandleFunc("/healthz", g.handleHealthz)
+	mux.HandleFunc("/readyz", g.handleReadyz)
+
+	defaultRoutes := g.servers[g.defaultGraph].Routes()
+	mux.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+		if strings.HasPrefix(req.URL.Path, graphUrlPrefix) {
+			http.NotFound(w, req)
+			return
+		}
+		defaultRoutes.ServeHTTP(w, req)
+	})
+
+	return mux
+}
+
+// Start the server of the graphs.
+func (g *GraphRouter) Start() {
+	http.ListenAndServe(":8090", g.Routes())
+}
@@ -0,0 +1,171 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateGraphNames(t *testing.T) {
+
+	testCases := []struct {
+		description  string
+		defaultGraph string
+		names        []string
+		expected     error
+	}{
+		{
+			description:  "no graphs",
+			defaultGraph: "a",
+			names:        []string{},
+			expected:     ErrNoGraphs,
+		},
+		{
+			description:  "invalid name",
+			defaultGraph: "a",
+			names:        []string{"a", "b/c"},
+			expected:     ErrInvalidGraphName,
+		},
+		{
+			description:  "duplicate name",
+			defaultGraph: "a",
+			names:        []string{"a", "a"},
+			expected:     ErrDuplicateGraphName,
+		},
+		{
+			description:  "default graph not found",
+			defaultGraph: "c",
+			names:        []string{"a", "b"},
+			expected:     ErrDefaultGraphNotFound,
+		},
+		{
+			description:  "valid names",
+			defaultGraph: "b",
+			names:        []string{"a", "b", "team_C-1"},
+			expected:     nil,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.description, func(t *testing.T) {
+			assert.ErrorIs(t, validateGraphNames(testCase.defaultGraph, testCase.names),
+				testCase.expected)
+		})
+	}
+}
+
+func TestReadMultiGraphConfig(t *testing.T) {
+
+	folder := t.TempDir()
+	filepath := path.Join(folder, "graphs.json")
+
+	config := MultiGraphConfig{
+		Default: "alpha",
+		Graphs: []NamedGraphConfig{
+			{
+				Name:           "alpha",
+				DataConfig:     "alpha/data-config.json",
+				I2Config:       "alpha/i2-config.json",
+				I2SpiderConfig: "alpha/i2-spider-config.json",
+			},
+		},
+	}
+
+	bytes, err := json.Marshal(config)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(filepath, bytes, 0644))
+
+	actual, err := ReadMultiGraphConfig(filepath)
+	assert.NoError(t, err)
+	assert.Equal(t, config, *actual)
+
+	// A graph without an i2 config
+	config.Graphs[0].I2Config = ""
+	bytes, err = json.Marshal(config)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(filepath, bytes, 0644))
+
+	_, err = ReadMultiGraphConfig(filepath)
+	assert.ErrorIs(t, err, ErrGraphConfigIncomplete)
+
+	// File doesn't exist
+	_, err = ReadMultiGraphConfig(path.Join(folder, "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestGraphRouter(t *testing.T) {
+
+	alpha := makeJobServer(t)
+	defer cleanUpJobRunner(t, alpha.runner)
+
+	beta := makeJobServer(t)
+	defer cleanUpJobRunner(t, beta.runner)
+
+	// Invalid routers
+	_, err := NewGraphRouter("alpha", map[string]*JobServer{"alpha": nil})
+	assert.ErrorIs(t, err, ErrGraphJobServerIsNil)
+
+	_, err = NewGraphRouter("gamma", map[string]*JobServer{"alpha": alpha, "beta": beta})
+	assert.ErrorIs(t, err, ErrDefaultGraphNotFound)
+
+	router, err := NewGraphRouter("alpha", map[string]*JobServer{"alpha": alpha, "beta": beta})
+	assert.NoError(t, err)
+	handler := router.Routes()
+
+	// The default graph is served from the root and the pages link to each graph
+	w := getPage(handler, "/")
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, `<a href="/" class="govuk-header__link" aria-current="page">alpha</a>`)
+	assert.Contains(t, body, `<a href="/g/beta/" class="govuk-header__link">beta</a>`)
+
+	w = getPage(handler, "/g/beta/")
+	assert.Equal(t, http.StatusOK, w.Code)
+	body = w.Body.String()
+	assert.Contains(t, body, `<a href="/g/beta/" class="govuk-header__link govuk-header__link--homepage">`)
+	assert.Contains(t, body, `<a href="/g/beta/" class="govuk-header__link" aria-current="page">beta</a>`)
+
+	// A job submitted to a graph runs on that graph's job runner
+	form := buildFormData(1, "Dataset-1", "e-1, e-2", "", "", "", "")
+	w = postForm(handler, "/g/beta/upload", form)
+	assert.Equal(t, http.StatusFound, w.Code)
+
+	location := w.Result().Header.Get("Location")
+	assert.True(t, strings.HasPrefix(location, "/g/beta/job/"))
+	guid := strings.TrimPrefix(location, "/g/beta/job/")
+	waitForJobsToFinish(beta.runner)
+
+	w = getPage(handler, location)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	w = getPage(handler, "/job/"+guid)
+	assert.Contains(t, w.Body.String(), "Job not found")
+
+	// The default graph can also be reached by its name
+	w = postForm(handler, "/g/alpha/upload", form)
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.True(t, strings.HasPrefix(w.Result().Header.Get("Location"), "/job/"))
+	waitForJobsToFinish(alpha.runner)
+
+	// Unknown graph
+	w = getPage(handler, "/g/gamma/")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// The health checks cover every graph
+	w = getPage(handler, "/readyz")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var response HealthResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, healthStatusOk, response.Status)
+	assert.Equal(t, healthStatusOk, response.Checks["beta/unipartiteStore"])
+
+	w = getPage(handler, "/healthz")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "alpha/jobRunner")
+}
@@ -0,0 +1,252 @@
+// A graph sample is a random set of entities from the unipartite graph and the edges between them.
+// It lets analysts and admins eyeball the structure of a newly loaded graph, either drawn on the
+// sample page or downloaded as JSON or GraphML (e.g. for Gephi).
+
+package server
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/i18n"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+)
+
+// Constants associated with the graph sample
+const (
+	SampleEntitiesInputName = "entities" // Number of entities to sample
+	SampleSeedInputName     = "seed"     // Seed of the random sample
+	DefaultSampleEntities   = 100        // Number of entities sampled if it isn't given
+	MaxSampleEntities       = 1000       // Maximum number of entities in a sample
+	graphSampleUrl          = "/graph-sample"
+	graphmlFormat           = "graphml"
+	graphmlContentType      = "application/graphml+xml"
+	graphmlNamespace        = "http://graphml.graphdrawing.org/xmlns"
+)
+
+// A graphSampleQuery is a request for a sample of the graph.
+type graphSampleQuery struct {
+	entities int   // Number of entities to sample
+	seed     int64 // Seed of the random sample
+}
+
+// GraphSampleEdge is an edge of the JSON response of a graph sample.
+type GraphSampleEdge struct {
+	Source   string `json:"source"`   // Entity ID the edge is from
+	Target   string `json:"target"`   // Entity ID the edge is to
+	Directed bool   `json:"directed"` // Is the edge only in one direction?
+}
+
+// GraphSampleResult is the JSON response of a graph sample.
+type GraphSampleResult struct {
+	Seed     int64             `json:"seed"`            // Seed to request the same sample again
+	Entities []string          `json:"entities"`        // Sampled entity IDs
+	Edges    []GraphSampleEdge `json:"edges"`           // Edges between the sampled entities
+	Error    string            `json:"error,omitempty"` // Reason the sample failed
+}
+
+// parseGraphSampleQuery from the URL's query parameters. A random seed is chosen if it isn't given.
+func parseGraphSampleQuery(req *http.Request) (graphSampleQuery, error) {
+
+	query := graphSampleQuery{
+		entities: DefaultSampleEntities,
+		seed:     time.Now().UnixNano(),
+	}
+
+	if value := req.URL.Query().Get(SampleEntitiesInputName); len(value) > 0 {
+		entities, err := strconv.Atoi(value)
+		if err != nil || entities < 1 || entities > MaxSampleEntities {
+			return query, i18n.NewMessage("error.invalidSampleEntities", value, MaxSampleEntities)
+		}
+		query.entities = entities
+	}
+
+	if value := req.URL.Query().Get(SampleSeedInputName); len(value) > 0 {
+		seed, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return query, i18n.NewMessage("error.invalidSampleSeed", value)
+		}
+		query.seed = seed
+	}
+
+	return query, nil
+}
+
+// sampleGraph takes a sample of the unipartite graph.
+func (j *JobServer) sampleGraph(query graphSampleQuery) (*graphstore.GraphSample, error) {
+	return graphstore.SampleGraph(j.runner.searchEngine.Unipartite, query.entities,
+		rand.New(rand.NewSource(query.seed)))
+}
+
+// newGraphSampleResult for the JSON response.
+func newGraphSampleResult(seed int64, sample *graphstore.GraphSample) GraphSampleResult {
+
+	result := GraphSampleResult{
+		Seed:     seed,
+		Entities: []string{},
+		Edges:    []GraphSampleEdge{},
+	}
+
+	if sample == nil {
+		return result
+	}
+
+	result.Entities = append(result.Entities, sample.EntityIds...)
+	for _, edge := range sample.Edges {
+		result.Edges = append(result.Edges, GraphSampleEdge{
+			Source:   edge.V1,
+			Target:   edge.V2,
+			Directed: edge.Directed,
+		})
+	}
+
+	return result
+}
+
+// handleGraphSample returns a sample of the graph as an HTML page, as JSON if the format is json or
+// as a GraphML file if the format is graphml.
+func (j *JobServer) handleGraphSample(w http.ResponseWriter, req *http.Request) {
+
+	settings := j.pageSettings(w, req)
+	format := req.URL.Query().Get(JobFormatInputName)
+
+	query, err := parseGraphSampleQuery(req)
+	status := http.StatusBadRequest
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Int("entities", query.entities).
+		Int64("seed", query.seed).
+		Str("format", format).
+		Msg("Received request for a sample of the graph")
+
+	var sample *graphstore.GraphSample
+	if err == nil {
+		status = http.StatusOK
+		sample, err = j.sampleGraph(query)
+		if err != nil {
+			status = http.StatusInternalServerError
+			logging.Logger.Error().
+				Str(logging.ComponentField, componentName).
+				Err(err).
+				Msg("Failed to sample the graph")
+		}
+	}
+
+	// Each request without a seed gives a different sample
+	w.Header().Set("Cache-Control", "no-store")
+
+	switch {
+	case format == jsonFormat:
+		result := newGraphSampleResult(query.seed, sample)
+		result.Error = j.translator.TranslateError(settings.language, err)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(result)
+
+	case format == graphmlFormat && err == nil:
+		filename := fmt.Sprintf("graph-sample-%v.graphml", query.seed)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%v\"", filename))
+		w.Header().Set("Content-Type", graphmlContentType)
+		if err := writeGraphml(w, sample); err != nil {
+			logging.Logger.Error().
+				Str(logging.ComponentField, componentName).
+				Err(err).
+				Msg("Failed to write the sample of the graph as GraphML")
+		}
+
+	default:
+		ctx := map[string]interface{}{
+			"entities":    query.entities,
+			"seed":        query.seed,
+			"maxEntities": MaxSampleEntities,
+		}
+
+		if err != nil {
+			ctx["error"] = j.translator.TranslateError(settings.language, err)
+		} else {
+			ctx["sampled"] = true
+			ctx["summary"] = j.translator.Translate(settings.language, "graphSample.summary",
+				len(sample.EntityIds), len(sample.Edges))
+			ctx["query"] = fmt.Sprintf("%v=%v&%v=%v", SampleEntitiesInputName, query.entities,
+				SampleSeedInputName, query.seed)
+		}
+
+		w.WriteHeader(status)
+		fmt.Fprint(w, j.render(j.graphSampleTemplate, settings, ctx))
+	}
+}
+
+// graphmlDocument is the root of a GraphML file.
+type graphmlDocument struct {
+	XMLName xml.Name     `xml:"graphml"`
+	Xmlns   string       `xml:"xmlns,attr"`
+	Graph   graphmlGraph `xml:"graph"`
+}
+
+// graphmlGraph holds the nodes and edges.
+type graphmlGraph struct {
+	Id          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphmlNode `xml:"node"`
+	Edges       []graphmlEdge `xml:"edge"`
+}
+
+// graphmlNode is an entity.
+type graphmlNode struct {
+	Id string `xml:"id,attr"`
+}
+
+// graphmlEdge is an edge between two entities.
+type graphmlEdge struct {
+	Source   string `xml:"source,attr"`
+	Target   string `xml:"target,attr"`
+	Directed bool   `xml:"directed,attr"`
+}
+
+// writeGraphml of the sample of the graph to the writer.
+func writeGraphml(w io.Writer, sample *graphstore.GraphSample) error {
+
+	document := graphmlDocument{
+		Xmlns: graphmlNamespace,
+		Graph: graphmlGraph{
+			Id:          "sample",
+			EdgeDefault: "undirected",
+			Nodes:       []graphmlNode{},
+			Edges:       []graphmlEdge{},
+		},
+	}
+
+	for _, entityId := range sample.EntityIds {
+		document.Graph.Nodes = append(document.Graph.Nodes, graphmlNode{Id: entityId})
+	}
+
+	for _, edge := range sample.Edges {
+		document.Graph.Edges = append(document.Graph.Edges, graphmlEdge{
+			Source:   edge.V1,
+			Target:   edge.V2,
+			Directed: edge.Directed,
+		})
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	encoder := xml.NewEncoder(w)
+	encoder.Indent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
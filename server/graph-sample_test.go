@@ -0,0 +1,132 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"testing"
+
+	"github.com/aymerick/raymond"
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteGraphml(t *testing.T) {
+	sample := &graphstore.GraphSample{
+		EntityIds: []string{"e-1", "e-2", "e-3"},
+		Edges: []graphstore.SampledEdge{
+			{V1: "e-1", V2: "e-2"},
+			{V1: "e-2", V2: "e-3", Directed: true},
+		},
+	}
+
+	var buffer bytes.Buffer
+	assert.NoError(t, writeGraphml(&buffer, sample))
+
+	document := graphmlDocument{}
+	assert.NoError(t, xml.Unmarshal(buffer.Bytes(), &document))
+	assert.Equal(t, graphmlNamespace, document.Xmlns)
+	assert.Equal(t, []graphmlNode{{Id: "e-1"}, {Id: "e-2"}, {Id: "e-3"}}, document.Graph.Nodes)
+	assert.Equal(t, []graphmlEdge{
+		{Source: "e-1", Target: "e-2"},
+		{Source: "e-2", Target: "e-3", Directed: true},
+	}, document.Graph.Edges)
+}
+
+func TestGraphSample(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+	handler := server.Routes()
+
+	// The page draws a sample of the graph
+	w := getPage(handler, "/graph-sample?entities=3&seed=42")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	body := w.Body.String()
+	assert.Contains(t, body, "Sample of the graph")
+	assert.Contains(t, body, "3 entities and")
+	assert.Contains(t, body, "Seed: 42.")
+	assert.Contains(t, body, `id="graphSample"`)
+
+	// Sample as JSON
+	w = getPage(handler, "/graph-sample?entities=3&seed=42&format=json")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	result := GraphSampleResult{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, int64(42), result.Seed)
+	assert.Len(t, result.Entities, 3)
+	assert.Empty(t, result.Error)
+
+	for _, edge := range result.Edges {
+		assert.Contains(t, result.Entities, edge.Source)
+		assert.Contains(t, result.Entities, edge.Target)
+
+		connected, err := server.runner.searchEngine.Unipartite.EdgeExists(edge.Source, edge.Target)
+		assert.NoError(t, err)
+		assert.True(t, connected)
+	}
+
+	// The same seed gives the same sample
+	again := GraphSampleResult{}
+	w = getPage(handler, "/graph-sample?entities=3&seed=42&format=json")
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &again))
+	assert.Equal(t, result, again)
+
+	// A random seed is chosen if it isn't given
+	w = getPage(handler, "/graph-sample?format=json")
+	assert.Equal(t, http.StatusOK, w.Code)
+	again = GraphSampleResult{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &again))
+	assert.NotZero(t, again.Seed)
+
+	// Sample as GraphML
+	w = getPage(handler, "/graph-sample?entities=3&seed=42&format=graphml")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, graphmlContentType, w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "graph-sample-42.graphml")
+
+	document := graphmlDocument{}
+	assert.NoError(t, xml.Unmarshal(w.Body.Bytes(), &document))
+	assert.Len(t, document.Graph.Nodes, 3)
+	assert.Len(t, document.Graph.Edges, len(result.Edges))
+}
+
+func TestGraphSampleInvalidQuery(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+	handler := server.Routes()
+
+	testCases := []struct {
+		url      string
+		expected string
+	}{
+		{"/graph-sample?entities=0", "invalid number of entities '0', it must be between 1 and 1000"},
+		{"/graph-sample?entities=1001", "invalid number of entities '1001', it must be between 1 and 1000"},
+		{"/graph-sample?entities=x", "invalid number of entities 'x'"},
+		{"/graph-sample?seed=1.5", "invalid seed '1.5', it must be a whole number"},
+	}
+
+	for _, testCase := range testCases {
+		w := getPage(handler, testCase.url)
+		assert.Equal(t, http.StatusBadRequest, w.Code, testCase.url)
+		assert.Contains(t, w.Body.String(), raymond.Escape(testCase.expected), testCase.url)
+		assert.NotContains(t, w.Body.String(), `id="graphSample"`, testCase.url)
+
+		w = getPage(handler, testCase.url+"&format=json")
+		assert.Equal(t, http.StatusBadRequest, w.Code, testCase.url)
+		result := GraphSampleResult{}
+		assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		assert.Contains(t, result.Error, testCase.expected, testCase.url)
+		assert.Empty(t, result.Entities, testCase.url)
+
+		// A GraphML file isn't returned for an invalid query
+		w = getPage(handler, testCase.url+"&format=graphml")
+		assert.Equal(t, http.StatusBadRequest, w.Code, testCase.url)
+		assert.Empty(t, w.Header().Get("Content-Disposition"), testCase.url)
+	}
+}
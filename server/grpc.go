@@ -0,0 +1,269 @@
+// The gRPC path service exposes job submission, job status, path queries and spidering from an
+// entity on a separate port, for services that prefer gRPC to the HTML endpoints. It is backed by
+// the same job runners as the web pages, so the jobs appear on both.
+
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/grpcapi"
+	"github.com/cdclaxton/shortest-path-web-app/i18n"
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cdclaxton/shortest-path-web-app/set"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Metadata key holding the caller's preferred languages
+const acceptLanguageMetadata = "accept-language"
+
+// grpcJobStates are the gRPC states of the job states
+var grpcJobStates = map[job.JobState]grpcapi.JobState{
+	job.NotStarted:        grpcapi.JobState_JOB_STATE_NOT_STARTED,
+	job.InProgress:        grpcapi.JobState_JOB_STATE_IN_PROGRESS,
+	job.Failed:            grpcapi.JobState_JOB_STATE_FAILED,
+	job.CompleteResults:   grpcapi.JobState_JOB_STATE_COMPLETE_RESULTS,
+	job.CompleteNoResults: grpcapi.JobState_JOB_STATE_COMPLETE_NO_RESULTS,
+	job.TimedOut:          grpcapi.JobState_JOB_STATE_FAILED, // The error explains the timeout
+}
+
+// A PathService serves the gRPC path service using a job server's runners.
+type PathService struct {
+	grpcapi.UnimplementedPathServiceServer
+	server *JobServer
+}
+
+// NewGrpcServer with the path service of the job server registered.
+func (j *JobServer) NewGrpcServer() *grpc.Server {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Msg("Making the gRPC server")
+
+	grpcServer := grpc.NewServer()
+	grpcapi.RegisterPathServiceServer(grpcServer, &PathService{server: j})
+	return grpcServer
+}
+
+// language of the caller from the context's metadata.
+func (p *PathService) language(ctx context.Context) string {
+	acceptLanguage := ""
+	if md, found := metadata.FromIncomingContext(ctx); found {
+		acceptLanguage = strings.Join(md.Get(acceptLanguageMetadata), ",")
+	}
+
+	return p.server.translator.Negotiate(acceptLanguage)
+}
+
+// statusError with the code and the error translated into the caller's language.
+func (p *PathService) statusError(ctx context.Context, code codes.Code, err error) error {
+	return status.Error(code, p.server.translator.TranslateError(p.language(ctx), err))
+}
+
+// jobConfiguration from the request, which is checked against the job server's limits and rules.
+func (p *PathService) jobConfiguration(req *grpcapi.SubmitJobRequest) (*job.JobConfiguration, error) {
+
+	numberHops, err := p.server.searchLimits.parseHops(strconv.Itoa(int(req.GetNumberHops())))
+	if err != nil {
+		return nil, err
+	}
+
+	conf := job.JobConfiguration{
+		MaxNumberHops:      numberHops,
+		EntitySets:         []job.EntitySet{},
+		RetryWithFewerHops: req.GetRetryWithFewerHops(),
+		Directed:           req.GetDirected(),
+		ExcludedEntityIds:  p.server.entityIdNormaliser.NormaliseAll(req.GetExcludedEntityIds()),
+		Waypoints:          p.server.entityIdNormaliser.NormaliseAll(req.GetWaypoints()),
+	}
+
+	for _, dataset := range req.GetDatasets() {
+		name := strings.TrimSpace(dataset.GetName())
+		if len(name) == 0 {
+			return nil, ErrDatasetNoName
+		}
+
+		if len(dataset.GetEntityIds()) == 0 {
+			return nil, ErrDatasetNoEntities
+		}
+
+		conf.EntitySets = append(conf.EntitySets, job.EntitySet{
+			Name:      name,
+			EntityIds: p.server.entityIdNormaliser.NormaliseAll(dataset.GetEntityIds()),
+		})
+	}
+
+	if len(conf.EntitySets) == 0 {
+		return nil, i18n.NewMessage("error.noDatasets")
+	}
+
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := p.server.jobLimits.Check(&conf); err != nil {
+		return nil, err
+	}
+
+	if err := p.server.entityIdRules.Check(&conf); err != nil {
+		return nil, err
+	}
+
+	return &conf, nil
+}
+
+// SubmitJob to find the shortest paths between the entities of the datasets.
+func (p *PathService) SubmitJob(ctx context.Context, req *grpcapi.SubmitJobRequest) (
+	*grpcapi.SubmitJobResponse, error) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Int("numberOfDatasets", len(req.GetDatasets())).
+		Msg("Received gRPC request to submit a job")
+
+	conf, err := p.jobConfiguration(req)
+	if err != nil {
+		return nil, p.statusError(ctx, codes.InvalidArgument, err)
+	}
+
+	guid, err := p.server.runner.Submit(conf)
+	if err != nil {
+		return nil, p.statusError(ctx, codes.Internal, err)
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str(loggingGUIDField, guid).
+		Msg("Job successfully submitted using gRPC")
+
+	return &grpcapi.SubmitJobResponse{Guid: guid}, nil
+}
+
+// GetJob returns the status of a job.
+func (p *PathService) GetJob(ctx context.Context, req *grpcapi.GetJobRequest) (*grpcapi.Job, error) {
+
+	j1, err := p.server.runner.GetJob(req.GetGuid())
+	if err != nil {
+		return nil, p.statusError(ctx, codes.NotFound,
+			i18n.Wrap(err, "error.jobNotFound", req.GetGuid()))
+	}
+
+	language := p.language(ctx)
+	response := &grpcapi.Job{
+		Guid:     j1.GUID,
+		State:    grpcJobStates[j1.Progress.State],
+		Finished: isFinishedState(j1.Progress.State),
+		Error:    p.server.translator.TranslateError(language, j1.Error),
+		Warnings: p.server.translator.TranslateMessages(language, j1.Warnings),
+	}
+
+	if j1.Progress.State == job.CompleteResults {
+		response.DownloadPath = fmt.Sprintf("%v/download/%v", p.server.basePath, j1.GUID)
+	}
+
+	return response, nil
+}
+
+// FindPaths between two entities whilst the caller waits.
+func (p *PathService) FindPaths(ctx context.Context, req *grpcapi.FindPathsRequest) (
+	*grpcapi.FindPathsResponse, error) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("from", req.GetFrom()).
+		Str("to", req.GetTo()).
+		Msg("Received gRPC request to find the paths between two entities")
+
+	query := pathQuery{
+		from:     strings.TrimSpace(req.GetFrom()),
+		to:       strings.TrimSpace(req.GetTo()),
+		hops:     p.server.searchLimits.defaultHops(defaultPathQueryHops),
+		directed: req.GetDirected(),
+	}
+
+	if len(query.from) == 0 || len(query.to) == 0 {
+		return nil, p.statusError(ctx, codes.InvalidArgument, i18n.NewMessage("error.pathEntityBlank"))
+	}
+
+	if req.GetHops() != 0 {
+		hops, err := p.server.searchLimits.parseHops(strconv.Itoa(int(req.GetHops())))
+		if err != nil {
+			return nil, p.statusError(ctx, codes.InvalidArgument, err)
+		}
+		query.hops = hops
+	}
+
+	routes, httpStatus, err := p.server.runPathQuery(ctx, query)
+	switch {
+	case httpStatus == http.StatusServiceUnavailable:
+		return nil, p.statusError(ctx, codes.DeadlineExceeded, err)
+	case httpStatus == http.StatusBadRequest:
+		return nil, p.statusError(ctx, codes.ResourceExhausted, err)
+	case err != nil:
+		return nil, p.statusError(ctx, codes.Internal, err)
+	}
+
+	response := &grpcapi.FindPathsResponse{
+		Paths: make([]*grpcapi.Path, 0, len(routes)),
+	}
+	for _, route := range routes {
+		response.Paths = append(response.Paths, &grpcapi.Path{EntityIds: route})
+	}
+
+	return response, nil
+}
+
+// SpiderEntity finds the connections between the entities within the number of steps of an entity.
+func (p *PathService) SpiderEntity(ctx context.Context, req *grpcapi.SpiderEntityRequest) (
+	*grpcapi.SpiderEntityResponse, error) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("entityID", req.GetEntityId()).
+		Int32("steps", req.GetSteps()).
+		Msg("Received gRPC request to spider from an entity")
+
+	if err := graphstore.ValidateEntityId(req.GetEntityId()); err != nil {
+		return nil, p.statusError(ctx, codes.InvalidArgument, err)
+	}
+
+	steps, err := p.server.searchLimits.parseSteps(strconv.Itoa(int(req.GetSteps())))
+	if err != nil {
+		return nil, p.statusError(ctx, codes.InvalidArgument, err)
+	}
+
+	p.server.storeLock.BeginRead()
+	results, err := p.server.spiderRunner.spider.ExecuteWithCaps(steps,
+		set.NewPopulatedSet(req.GetEntityId()), p.server.spiderCaps)
+	p.server.storeLock.EndRead()
+	if err != nil {
+		return nil, p.statusError(ctx, codes.Internal, err)
+	}
+
+	connections, err := neighbourhoodConnections(results)
+	if err != nil {
+		return nil, p.statusError(ctx, codes.Internal, err)
+	}
+
+	response := &grpcapi.SpiderEntityResponse{
+		Connections: make([]*grpcapi.Connection, 0, len(connections)),
+		Truncated:   results.Truncated(),
+	}
+	for _, connection := range connections {
+		response.Connections = append(response.Connections, &grpcapi.Connection{
+			EntityId_1: connection.Entity1,
+			EntityId_2: connection.Entity2,
+		})
+	}
+
+	return response, nil
+}
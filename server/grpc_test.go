@@ -0,0 +1,147 @@
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/grpcapi"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// makeGrpcClient of the job server's path service over an in-memory connection.
+func makeGrpcClient(t *testing.T, server *JobServer) (grpcapi.PathServiceClient, func()) {
+
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := server.NewGrpcServer()
+	go grpcServer.Serve(listener)
+
+	conn, err := grpc.Dial("bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+
+	return grpcapi.NewPathServiceClient(conn), func() {
+		conn.Close()
+		grpcServer.Stop()
+	}
+}
+
+func TestGrpcJob(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	client, closeClient := makeGrpcClient(t, server)
+	defer closeClient()
+
+	ctx := context.Background()
+
+	// Invalid number of hops
+	_, err := client.SubmitJob(ctx, &grpcapi.SubmitJobRequest{
+		NumberHops: 20,
+		Datasets:   []*grpcapi.Dataset{{Name: "Dataset-1", EntityIds: []string{"e-1", "e-2"}}},
+	})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// No datasets
+	_, err = client.SubmitJob(ctx, &grpcapi.SubmitJobRequest{NumberHops: 1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// Valid job
+	submitted, err := client.SubmitJob(ctx, &grpcapi.SubmitJobRequest{
+		NumberHops: 1,
+		Datasets:   []*grpcapi.Dataset{{Name: "Dataset-1", EntityIds: []string{"e-1", "e-2"}}},
+	})
+	assert.NoError(t, err)
+	waitForJobsToFinish(server.runner)
+
+	j, err := client.GetJob(ctx, &grpcapi.GetJobRequest{Guid: submitted.GetGuid()})
+	assert.NoError(t, err)
+	assert.Equal(t, grpcapi.JobState_JOB_STATE_COMPLETE_RESULTS, j.GetState())
+	assert.True(t, j.GetFinished())
+	assert.Equal(t, "/download/"+submitted.GetGuid(), j.GetDownloadPath())
+
+	// The job is also on the web pages
+	_, err = server.runner.GetJob(submitted.GetGuid())
+	assert.NoError(t, err)
+
+	// Job not found, with the error in the caller's language
+	_, err = client.GetJob(ctx, &grpcapi.GetJobRequest{Guid: "1234"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.Equal(t, "job 1234 not found", status.Convert(err).Message())
+
+	welsh := metadata.AppendToOutgoingContext(ctx, acceptLanguageMetadata, "cy")
+	_, err = client.GetJob(welsh, &grpcapi.GetJobRequest{Guid: "1234"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+	assert.NotEqual(t, "job 1234 not found", status.Convert(err).Message())
+}
+
+func TestGrpcFindPaths(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	client, closeClient := makeGrpcClient(t, server)
+	defer closeClient()
+
+	ctx := context.Background()
+
+	response, err := client.FindPaths(ctx, &grpcapi.FindPathsRequest{From: "e-1", To: "e-4", Hops: 2})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(response.GetPaths()))
+	assert.Equal(t, []string{"e-1", "e-3", "e-4"}, response.GetPaths()[0].GetEntityIds())
+
+	// Not within the number of hops
+	response, err = client.FindPaths(ctx, &grpcapi.FindPathsRequest{From: "e-1", To: "e-4", Hops: 1})
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(response.GetPaths()))
+
+	// Missing entity
+	_, err = client.FindPaths(ctx, &grpcapi.FindPathsRequest{From: "e-1"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// Invalid number of hops
+	_, err = client.FindPaths(ctx, &grpcapi.FindPathsRequest{From: "e-1", To: "e-4", Hops: 20})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestGrpcSpiderEntity(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	client, closeClient := makeGrpcClient(t, server)
+	defer closeClient()
+
+	ctx := context.Background()
+
+	response, err := client.SpiderEntity(ctx, &grpcapi.SpiderEntityRequest{EntityId: "e-1", Steps: 1})
+	assert.NoError(t, err)
+	assert.False(t, response.GetTruncated())
+
+	connections := map[string]string{}
+	for _, connection := range response.GetConnections() {
+		connections[connection.GetEntityId_2()] = connection.GetEntityId_1()
+	}
+	assert.Equal(t, map[string]string{"e-2": "e-1", "e-3": "e-1"}, connections)
+
+	// Invalid number of steps
+	_, err = client.SpiderEntity(ctx, &grpcapi.SpiderEntityRequest{EntityId: "e-1", Steps: 100})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	// Invalid entity ID
+	_, err = client.SpiderEntity(ctx, &grpcapi.SpiderEntityRequest{Steps: 1})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,119 @@
+// The health endpoints let an orchestrator such as Kubernetes know whether the web-app is alive
+// (/healthz) and whether it is ready to serve a loaded graph (/readyz).
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+)
+
+// Constants associated with the health checks
+const (
+	healthCheckTimeout = 2 * time.Second // Maximum time for a job runner to respond
+	healthStatusOk     = "ok"
+	healthStatusFailed = "failed"
+)
+
+var ErrJobRunnerNotResponsive = errors.New("job runner is not responsive")
+
+// HealthResponse is the JSON response to a health check.
+type HealthResponse struct {
+	Status string            `json:"status"` // ok or failed
+	Checks map[string]string `json:"checks"` // Result of each check
+}
+
+// lockResponsive returns true if the read lock can be acquired within the timeout.
+func lockResponsive(timeout time.Duration, locks ...*sync.RWMutex) bool {
+
+	done := make(chan struct{})
+	go func() {
+		for _, lock := range locks {
+			lock.RLock()
+			lock.RUnlock()
+		}
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
+
+// writeHealth writes the result of the checks as JSON, where the status is ok only if every check
+// passed.
+func writeHealth(w http.ResponseWriter, checks map[string]error) {
+
+	response := HealthResponse{
+		Status: healthStatusOk,
+		Checks: map[string]string{},
+	}
+
+	for name, err := range checks {
+		if err != nil {
+			logging.Logger.Warn().
+				Str(logging.ComponentField, componentName).
+				Str("check", name).
+				Err(err).
+				Msg("Health check failed")
+
+			response.Status = healthStatusFailed
+			response.Checks[name] = err.Error()
+		} else {
+			response.Checks[name] = healthStatusOk
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if response.Status != healthStatusOk {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(response)
+}
+
+// healthChecks of whether the job runners are responsive.
+func (j *JobServer) healthChecks() map[string]error {
+
+	checks := map[string]error{
+		"jobRunner":       nil,
+		"spiderJobRunner": nil,
+	}
+
+	if !j.runner.responsive(healthCheckTimeout) {
+		checks["jobRunner"] = ErrJobRunnerNotResponsive
+	}
+
+	if !j.spiderRunner.responsive(healthCheckTimeout) {
+		checks["spiderJobRunner"] = ErrJobRunnerNotResponsive
+	}
+
+	return checks
+}
+
+// readinessChecks of whether the graph stores are open and can be read.
+func (j *JobServer) readinessChecks() map[string]error {
+	return map[string]error{
+		"bipartiteStore":  graphstore.CheckBipartiteReadable(j.runner.searchEngine.Bipartite),
+		"unipartiteStore": graphstore.CheckUnipartiteReadable(j.runner.searchEngine.Unipartite),
+	}
+}
+
+// handleHealthz reports whether the job runners are responsive (liveness).
+func (j *JobServer) handleHealthz(w http.ResponseWriter, req *http.Request) {
+	writeHealth(w, j.healthChecks())
+}
+
+// handleReadyz reports whether the graph stores are open and can be read (readiness).
+func (j *JobServer) handleReadyz(w http.ResponseWriter, req *http.Request) {
+	writeHealth(w, j.readinessChecks())
+}
@@ -0,0 +1,81 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLockResponsive(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	assert.True(t, server.runner.responsive(time.Second))
+	assert.True(t, server.spiderRunner.responsive(time.Second))
+
+	// A job runner holding its lock isn't responsive
+	server.runner.jobsLock.Lock()
+	assert.False(t, server.runner.responsive(10*time.Millisecond))
+	server.runner.jobsLock.Unlock()
+}
+
+func TestHealthEndpoints(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	handler := server.Routes()
+
+	// Liveness
+	w := getPage(handler, "/healthz")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var response HealthResponse
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, HealthResponse{
+		Status: "ok",
+		Checks: map[string]string{
+			"jobRunner":       "ok",
+			"spiderJobRunner": "ok",
+		},
+	}, response)
+
+	// Readiness
+	w = getPage(handler, "/readyz")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	response = HealthResponse{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, HealthResponse{
+		Status: "ok",
+		Checks: map[string]string{
+			"bipartiteStore":  "ok",
+			"unipartiteStore": "ok",
+		},
+	}, response)
+
+	// The stores aren't readable once they have been closed
+	folder := t.TempDir()
+	closed, err := graphstore.NewPebbleBipartiteGraphStore(folder)
+	assert.NoError(t, err)
+	assert.NoError(t, closed.Close())
+
+	bipartite := server.runner.searchEngine.Bipartite
+	server.runner.searchEngine.Bipartite = closed
+	defer func() { server.runner.searchEngine.Bipartite = bipartite }()
+
+	w = getPage(handler, "/readyz")
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	response = HealthResponse{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, "failed", response.Status)
+	assert.Equal(t, "ok", response.Checks["unipartiteStore"])
+	assert.Contains(t, response.Checks["bipartiteStore"], "store is not readable")
+}
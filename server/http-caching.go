@@ -0,0 +1,203 @@
+// Analysts on low-bandwidth links find large result pages slow, so the responses of the server are
+// made smaller and cacheable:
+//
+//   - HTML, JSON, CSS, JavaScript, CSV and plain text responses are compressed with gzip if the
+//     client accepts it. Other responses (e.g. Excel files, which are already compressed, and the
+//     server-sent job events) are passed through unchanged.
+//   - The static assets served from the embedded filesystem can be cached by the browser.
+//   - The cached index pages and the theme's CSS have an ETag, so a browser that already holds the
+//     page is told that it hasn't changed rather than being sent it again.
+
+package server
+
+import (
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// staticAssetMaxAge is how long a browser may cache a static asset. The assets are embedded in the
+// binary, so they only change when the web-app is upgraded.
+const staticAssetMaxAge = 24 * time.Hour
+
+// compressibleContentTypes are the content types of the responses that are compressed.
+var compressibleContentTypes = []string{
+	"text/html",
+	"text/css",
+	"text/csv",
+	"text/plain",
+	"text/javascript",
+	"application/javascript",
+	"application/json",
+	graphmlContentType,
+}
+
+// gzipWriters are reused between responses, as a gzip.Writer is expensive to allocate.
+var gzipWriters = sync.Pool{
+	New: func() interface{} {
+		return gzip.NewWriter(nil)
+	},
+}
+
+// isCompressible returns true if a response with the content type should be compressed.
+func isCompressible(contentType string) bool {
+	for _, compressible := range compressibleContentTypes {
+		if strings.HasPrefix(contentType, compressible) {
+			return true
+		}
+	}
+	return false
+}
+
+// acceptsGzip returns true if the client accepts a gzip-compressed response.
+func acceptsGzip(req *http.Request) bool {
+	for _, encoding := range strings.Split(req.Header.Get("Accept-Encoding"), ",") {
+		encoding = strings.TrimSpace(encoding)
+		if encoding == "gzip" || (strings.HasPrefix(encoding, "gzip;") && !strings.HasSuffix(encoding, "q=0")) {
+			return true
+		}
+	}
+	return false
+}
+
+// gzipResponseWriter compresses the response if its content type is compressible. Whether to
+// compress is decided when the status code is written, as the handler sets the content type first.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	method  string       // Method of the request
+	decided bool         // Has it been decided whether to compress?
+	gz      *gzip.Writer // Writer of the compressed response (nil if it isn't compressed)
+}
+
+// decide whether to compress the response with the status code.
+func (g *gzipResponseWriter) decide(code int) {
+	if g.decided {
+		return
+	}
+	g.decided = true
+
+	header := g.Header()
+	if g.method == http.MethodHead || code < http.StatusOK || code == http.StatusNoContent ||
+		code == http.StatusPartialContent || code == http.StatusNotModified ||
+		len(header.Get("Content-Encoding")) > 0 || !isCompressible(header.Get("Content-Type")) {
+		return
+	}
+
+	header.Set("Content-Encoding", "gzip")
+	header.Del("Content-Length")
+
+	g.gz = gzipWriters.Get().(*gzip.Writer)
+	g.gz.Reset(g.ResponseWriter)
+}
+
+// WriteHeader with the status code.
+func (g *gzipResponseWriter) WriteHeader(code int) {
+	g.decide(code)
+	g.ResponseWriter.WriteHeader(code)
+}
+
+// Write the body of the response, compressing it if required.
+func (g *gzipResponseWriter) Write(b []byte) (int, error) {
+	if !g.decided {
+		if len(g.Header().Get("Content-Type")) == 0 {
+			g.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		g.WriteHeader(http.StatusOK)
+	}
+
+	if g.gz == nil {
+		return g.ResponseWriter.Write(b)
+	}
+	return g.gz.Write(b)
+}
+
+// Flush the response to the client (e.g. for the server-sent job events).
+func (g *gzipResponseWriter) Flush() {
+	if g.gz != nil {
+		g.gz.Flush()
+	}
+
+	if flusher, ok := g.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// close the compressed response.
+func (g *gzipResponseWriter) close() error {
+	if g.gz == nil {
+		return nil
+	}
+
+	err := g.gz.Close()
+	g.gz.Reset(nil)
+	gzipWriters.Put(g.gz)
+	g.gz = nil
+	return err
+}
+
+// compressResponses of the handler with gzip if the client accepts it.
+func compressResponses(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		if !acceptsGzip(req) {
+			handler.ServeHTTP(w, req)
+			return
+		}
+
+		gw := &gzipResponseWriter{ResponseWriter: w, method: req.Method}
+		defer gw.close()
+
+		handler.ServeHTTP(gw, req)
+	})
+}
+
+// cacheStaticAssets served by the handler in the browser.
+func cacheStaticAssets(handler http.Handler) http.Handler {
+	cacheControl := fmt.Sprintf("public, max-age=%d", int(staticAssetMaxAge.Seconds()))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Cache-Control", cacheControl)
+		handler.ServeHTTP(w, req)
+	})
+}
+
+// pageETag of the content of a page. The ETag is weak, as the page may be compressed.
+func pageETag(page string) string {
+	hash := sha256.Sum256([]byte(page))
+	return `W/"` + hex.EncodeToString(hash[:16]) + `"`
+}
+
+// etagMatches returns true if the If-None-Match header matches the ETag.
+func etagMatches(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// writeCachedPage with an ETag, or tell the client that its copy of the page hasn't changed. The
+// browser must check the page is unchanged each time it is shown, as the page depends on the
+// language and dark mode settings.
+func writeCachedPage(w http.ResponseWriter, req *http.Request, page string) {
+	etag := pageETag(page)
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Add("Vary", "Accept-Language, Cookie")
+
+	if etagMatches(req.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	fmt.Fprint(w, page)
+}
@@ -0,0 +1,142 @@
+package server
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAcceptsGzip(t *testing.T) {
+	testCases := []struct {
+		acceptEncoding string
+		expected       bool
+	}{
+		{"", false},
+		{"gzip", true},
+		{"gzip, deflate, br", true},
+		{"deflate, gzip;q=0.8", true},
+		{"gzip;q=0", false},
+		{"deflate", false},
+	}
+
+	for _, testCase := range testCases {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("Accept-Encoding", testCase.acceptEncoding)
+		assert.Equal(t, testCase.expected, acceptsGzip(req), testCase.acceptEncoding)
+	}
+}
+
+func TestEtagMatches(t *testing.T) {
+	etag := pageETag("page")
+	assert.Equal(t, etag, pageETag("page"))
+	assert.NotEqual(t, etag, pageETag("other page"))
+
+	assert.False(t, etagMatches("", etag))
+	assert.True(t, etagMatches(etag, etag))
+	assert.True(t, etagMatches(`"abc", `+etag, etag))
+	assert.True(t, etagMatches("*", etag))
+	assert.False(t, etagMatches(pageETag("other page"), etag))
+}
+
+// getCompressedPage from the handler, accepting a gzip-compressed response.
+func getCompressedPage(handler http.Handler, url string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+// decompress the body of the response.
+func decompress(t *testing.T, w *httptest.ResponseRecorder) string {
+	reader, err := gzip.NewReader(w.Body)
+	assert.NoError(t, err)
+	body, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	return string(body)
+}
+
+func TestCompressResponses(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+	handler := server.Routes()
+
+	// HTML is compressed if the client accepts it
+	w := getCompressedPage(handler, "/stats/")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Contains(t, w.Header().Values("Vary"), "Accept-Encoding")
+	assert.Contains(t, decompress(t, w), "Statistics")
+
+	// JSON is compressed
+	w = getCompressedPage(handler, "/stats/?format=json")
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Contains(t, decompress(t, w), "numberOfEntities")
+
+	// Responses aren't compressed if the client doesn't accept it
+	w = getPage(handler, "/stats/")
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Contains(t, w.Body.String(), "Statistics")
+
+	// Images aren't compressed
+	w = getCompressedPage(handler, "/theme/logo")
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+}
+
+func TestCacheStaticAssets(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+	handler := server.Routes()
+
+	w := getPage(handler, "/job-events.js")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "public, max-age=86400", w.Header().Get("Cache-Control"))
+
+	// The index page isn't a static asset
+	w = getPage(handler, "/")
+	assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
+}
+
+func TestIndexPageETag(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+	handler := server.Routes()
+
+	for _, url := range []string{"/", "/spider", "/theme.css"} {
+		w := getPage(handler, url)
+		assert.Equal(t, http.StatusOK, w.Code)
+		etag := w.Header().Get("ETag")
+		assert.NotEmpty(t, etag)
+		assert.NotEmpty(t, w.Body.String())
+		cookies := w.Result().Cookies()
+
+		// The page isn't sent again if the browser holds it (the index pages hold the browser's CSRF
+		// token)
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		for _, cookie := range cookies {
+			req.AddCookie(cookie)
+		}
+		req.Header.Set("If-None-Match", etag)
+		req.Header.Set("Accept-Encoding", "gzip")
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotModified, w.Code)
+		assert.Empty(t, w.Body.String())
+		assert.Empty(t, w.Header().Get("Content-Encoding"))
+
+		// The page is sent if the browser holds a different version
+		req = httptest.NewRequest(http.MethodGet, url, nil)
+		req.Header.Set("If-None-Match", pageETag("old page"))
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.NotEmpty(t, w.Body.String())
+	}
+}
@@ -0,0 +1,231 @@
+// Comparing jobs reports the connections that are new, dropped or have different paths in a later
+// job compared with an earlier job, e.g. to see what changed since the last data load.
+
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+
+	"github.com/cdclaxton/shortest-path-web-app/i18n"
+	"github.com/cdclaxton/shortest-path-web-app/i2chart"
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/cdclaxton/shortest-path-web-app/jobdiff"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+)
+
+// Constants associated with comparing jobs
+const (
+	CompareBeforeInputName = "before" // GUID of the earlier job
+	CompareAfterInputName  = "after"  // GUID of the later job
+	maxComparisonRows      = 500      // Maximum number of connections shown in each table of the page
+	excelContentType       = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+)
+
+// loadSummary of the connections found by a completed job.
+func (j *JobServer) loadSummary(guid string) (*jobdiff.ResultSummary, error) {
+	return j.readJobSummary(guid, "error.jobNotComparable")
+}
+
+// readJobSummary of the connections found by a completed job. The message with the key
+// noSummaryKey is returned if the job doesn't have a summary.
+func (j *JobServer) readJobSummary(guid string, noSummaryKey string) (*jobdiff.ResultSummary, error) {
+
+	j1, err := j.runner.GetJob(guid)
+	if err != nil {
+		return nil, i18n.Wrap(err, "error.jobNotFound", guid)
+	}
+
+	finished, err := j.runner.IsJobFinished(guid)
+	if err != nil {
+		return nil, err
+	}
+
+	if !finished || j1.Progress.State == job.Failed || j1.Progress.State == job.TimedOut {
+		return nil, i18n.NewMessage("error.jobNotComplete", guid)
+	}
+
+	if len(j1.SummaryFile) == 0 {
+		return nil, i18n.NewMessage(noSummaryKey, guid)
+	}
+
+	return jobdiff.ReadSummary(j1.SummaryFile)
+}
+
+// compareJobs in the request, i.e. the earlier and later jobs.
+func (j *JobServer) compareJobs(req *http.Request) (*jobdiff.Diff, error) {
+
+	before, err := j.loadSummary(req.URL.Query().Get(CompareBeforeInputName))
+	if err != nil {
+		return nil, err
+	}
+
+	after, err := j.loadSummary(req.URL.Query().Get(CompareAfterInputName))
+	if err != nil {
+		return nil, err
+	}
+
+	return jobdiff.Compare(before, after)
+}
+
+// ConnectionDisplay is a connection presented to the user.
+type ConnectionDisplay struct {
+	Entity1       string
+	Entity2       string
+	NumberOfPaths int
+}
+
+// ChangedConnectionDisplay is a connection with different paths presented to the user.
+type ChangedConnectionDisplay struct {
+	Entity1      string
+	Entity2      string
+	AddedPaths   []string
+	RemovedPaths []string
+}
+
+// prepareConnections for display, limiting the number of connections.
+func prepareConnections(connections []jobdiff.Connection) []ConnectionDisplay {
+	display := []ConnectionDisplay{}
+	for idx, connection := range connections {
+		if idx >= maxComparisonRows {
+			break
+		}
+
+		display = append(display, ConnectionDisplay{
+			Entity1:       connection.Entity1,
+			Entity2:       connection.Entity2,
+			NumberOfPaths: len(connection.Paths),
+		})
+	}
+
+	return display
+}
+
+// prepareChangedConnections for display, limiting the number of connections.
+func prepareChangedConnections(connections []jobdiff.ChangedConnection) []ChangedConnectionDisplay {
+	display := []ChangedConnectionDisplay{}
+	for idx, connection := range connections {
+		if idx >= maxComparisonRows {
+			break
+		}
+
+		display = append(display, ChangedConnectionDisplay(connection))
+	}
+
+	return display
+}
+
+// handleCompare returns the page comparing two jobs.
+func (j *JobServer) handleCompare(w http.ResponseWriter, req *http.Request) {
+
+	before := req.URL.Query().Get(CompareBeforeInputName)
+	after := req.URL.Query().Get(CompareAfterInputName)
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("before", before).
+		Str("after", after).
+		Msg("Received request to compare jobs")
+	settings := j.pageSettings(w, req)
+
+	diff, err := j.compareJobs(req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		page := j.render(j.inputProblemTemplate, settings, map[string]string{
+			"reason": j.translator.TranslateError(settings.language, err),
+		})
+		fmt.Fprint(w, page)
+		return
+	}
+
+	// Warn the user if not all of the connections are shown
+	truncated := ""
+	if len(diff.NewConnections) > maxComparisonRows ||
+		len(diff.DroppedConnections) > maxComparisonRows ||
+		len(diff.ChangedConnections) > maxComparisonRows {
+		truncated = j.translator.Translate(settings.language, "compare.truncated", maxComparisonRows)
+	}
+
+	page := j.render(j.compareTemplate, settings, map[string]interface{}{
+		"before":             before,
+		"after":              after,
+		"hasChanges":         diff.HasChanges(),
+		"numberOfNew":        len(diff.NewConnections),
+		"numberOfDropped":    len(diff.DroppedConnections),
+		"numberOfChanged":    len(diff.ChangedConnections),
+		"numberOfUnchanged":  diff.NumberOfUnchangedConnections,
+		"newConnections":     prepareConnections(diff.NewConnections),
+		"droppedConnections": prepareConnections(diff.DroppedConnections),
+		"changedConnections": prepareChangedConnections(diff.ChangedConnections),
+		"truncated":          truncated,
+	})
+	fmt.Fprint(w, page)
+}
+
+// handleCompareDownload returns the comparison of two jobs as an Excel file.
+func (j *JobServer) handleCompareDownload(w http.ResponseWriter, req *http.Request) {
+
+	before := req.URL.Query().Get(CompareBeforeInputName)
+	after := req.URL.Query().Get(CompareAfterInputName)
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("before", before).
+		Str("after", after).
+		Msg("Received request to download the comparison of jobs")
+	settings := j.pageSettings(w, req)
+
+	diff, err := j.compareJobs(req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		page := j.render(j.inputProblemTemplate, settings, map[string]string{
+			"reason": j.translator.TranslateError(settings.language, err),
+		})
+		fmt.Fprint(w, page)
+		return
+	}
+
+	folder, err := os.MkdirTemp(j.runner.folder, "compare-")
+	if err == nil {
+		defer os.RemoveAll(folder)
+		err = j.writeComparison(w, diff, path.Join(folder, "comparison.xlsx"), before, after)
+	}
+
+	if err != nil {
+		logging.Logger.Error().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to write the comparison of jobs")
+
+		w.WriteHeader(http.StatusInternalServerError)
+		page := j.render(j.errorTemplate, settings, map[string]string{
+			"reason": j.translator.TranslateError(settings.language, err),
+		})
+		fmt.Fprint(w, page)
+	}
+}
+
+// writeComparison of the jobs to the response as an Excel file. The Excel file is written to the
+// filepath first, as the Excel writer requires a filepath.
+func (j *JobServer) writeComparison(w http.ResponseWriter, diff *jobdiff.Diff, filepath string,
+	before string, after string) error {
+
+	if err := i2chart.WriteToExcel(filepath, diff.Rows()); err != nil {
+		return err
+	}
+
+	file, err := os.Open(filepath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	filename := fmt.Sprintf("shortest-path-comparison - %v - %v.xlsx", before, after)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"%v\"", filename))
+	w.Header().Set("Content-Type", excelContentType)
+	_, err = io.Copy(w, file)
+	return err
+}
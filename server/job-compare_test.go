@@ -0,0 +1,81 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/stretchr/testify/assert"
+)
+
+// submitAndWait submits a job using the form and returns its GUID once it has finished.
+func submitAndWait(t *testing.T, server *JobServer, entityIds string) string {
+	w := postForm(server.Routes(), "/upload", buildFormData(1, "Dataset-1", entityIds, "", "", "", ""))
+	assert.Equal(t, http.StatusFound, w.Code)
+
+	waitForJobsToFinish(server.runner)
+	return extractGuidFromLocation(t, w.Header().Get("Location"))
+}
+
+func TestCompareJobs(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	handler := server.Routes()
+
+	noResults := submitAndWait(t, server, "e-100, e-102")
+	results := submitAndWait(t, server, "e-1, e-2")
+
+	// The results page has a form to compare the job with an earlier job
+	w := getPage(handler, "/job/"+results)
+	assert.Contains(t, w.Body.String(), `<input type="hidden" name="after" value="`+results+`" />`)
+
+	// New connections
+	w = getPage(handler, "/compare?before="+noResults+"&after="+results)
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, "Comparison of jobs")
+	assert.Contains(t, body, "<td class=\"govuk-table__cell\">e-1</td>")
+	assert.Contains(t, body, "<td class=\"govuk-table__cell\">e-2</td>")
+	assert.Contains(t, body, "compare-download?before="+noResults+"&amp;after="+results)
+
+	// Dropped connections
+	w = getPage(handler, "/compare?before="+results+"&after="+noResults)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Dropped connections")
+
+	// No changes
+	w = getPage(handler, "/compare?before="+results+"&after="+results)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "The jobs found the same connections and paths.")
+
+	// Download the changes
+	w = getPage(handler, "/compare-download?before="+noResults+"&after="+results)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, excelContentType, w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "shortest-path-comparison")
+	assert.True(t, w.Body.Len() > 0)
+
+	// A job that doesn't exist
+	w = getPage(handler, "/compare?before=1234&after="+results)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "job 1234 not found")
+
+	w = getPage(handler, "/compare-download?before="+results+"&after=1234")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// A job that hasn't finished
+	conf, err := job.NewJobConfiguration([]job.EntitySet{
+		{Name: "Dataset-1", EntityIds: []string{"e-1", "e-2"}},
+	}, 1)
+	assert.NoError(t, err)
+	j1, err := job.NewJob(conf)
+	assert.NoError(t, err)
+	assert.NoError(t, server.runner.addJob(&j1))
+
+	w = getPage(handler, "/compare?before="+j1.GUID+"&after="+results)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "hasn&apos;t completed successfully")
+}
@@ -0,0 +1,233 @@
+// Job events are changes in the state of a job that are streamed to the browser using server-sent
+// events, so that the processing page can show the results as soon as the job finishes.
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+)
+
+// Constants associated with the job events
+const (
+	jobEventsSuffix         = "/events"        // Suffix of a job's URL for its events
+	jobEventsBufferSize     = 8                // Number of events buffered for each subscriber
+	jobEventsKeepAlive      = 30 * time.Second // Interval between comments that keep the stream open
+	progressEventName       = "progress"       // Name of the event sent when a job changes state
+	finishedEventName       = "finished"       // Name of the event sent when a job finishes
+	eventStreamContentType  = "text/event-stream"
+	jobStateTranslationBase = "jobState."
+)
+
+// A JobEvent is a change in the state of a job.
+type JobEvent struct {
+	State    job.JobState `json:"state"`    // State of the job
+	Finished bool         `json:"finished"` // Is the job in an end state?
+	Message  string       `json:"message"`  // State for the user in their language
+}
+
+// isFinishedState returns true if the job state is an end state.
+func isFinishedState(state job.JobState) bool {
+	return state == job.Failed || state == job.CompleteNoResults || state == job.CompleteResults ||
+		state == job.TimedOut
+}
+
+// newJobEvent for the job's state.
+func newJobEvent(state job.JobState) JobEvent {
+	return JobEvent{
+		State:    state,
+		Finished: isFinishedState(state),
+	}
+}
+
+// jobStateKeys are the translation keys of the job states
+var jobStateKeys = map[job.JobState]string{
+	job.NotStarted:        jobStateTranslationBase + "notStarted",
+	job.InProgress:        jobStateTranslationBase + "inProgress",
+	job.Failed:            jobStateTranslationBase + "failed",
+	job.CompleteResults:   jobStateTranslationBase + "completeResults",
+	job.CompleteNoResults: jobStateTranslationBase + "completeNoResults",
+	job.TimedOut:          jobStateTranslationBase + "timedOut",
+}
+
+// A jobEventBroker passes the events of jobs to the subscribers of each job. Publishing never
+// blocks, so an event is dropped for a subscriber that isn't keeping up.
+type jobEventBroker struct {
+	subscribers map[string]map[chan JobEvent]struct{} // Job GUID to subscribers
+	lock        sync.Mutex                            // Mutex for the subscribers
+}
+
+// newJobEventBroker without any subscribers.
+func newJobEventBroker() *jobEventBroker {
+	return &jobEventBroker{
+		subscribers: map[string]map[chan JobEvent]struct{}{},
+		lock:        sync.Mutex{},
+	}
+}
+
+// subscribe to the events of a job.
+func (b *jobEventBroker) subscribe(guid string) chan JobEvent {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	events := make(chan JobEvent, jobEventsBufferSize)
+
+	if _, found := b.subscribers[guid]; !found {
+		b.subscribers[guid] = map[chan JobEvent]struct{}{}
+	}
+	b.subscribers[guid][events] = struct{}{}
+
+	return events
+}
+
+// unsubscribe from the events of a job.
+func (b *jobEventBroker) unsubscribe(guid string, events chan JobEvent) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	delete(b.subscribers[guid], events)
+	if len(b.subscribers[guid]) == 0 {
+		delete(b.subscribers, guid)
+	}
+}
+
+// numberOfSubscribers to the events of a job.
+func (b *jobEventBroker) numberOfSubscribers(guid string) int {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	return len(b.subscribers[guid])
+}
+
+// publish an event to the subscribers of a job.
+func (b *jobEventBroker) publish(guid string, event JobEvent) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	for events := range b.subscribers[guid] {
+		select {
+		case events <- event:
+		default:
+			logging.Logger.Warn().
+				Str(logging.ComponentField, componentName).
+				Str(loggingGUIDField, guid).
+				Str("state", string(event.State)).
+				Msg("Dropped job event for a slow subscriber")
+		}
+	}
+}
+
+// writeJobEvent to the event stream.
+func writeJobEvent(w http.ResponseWriter, event JobEvent) error {
+
+	name := progressEventName
+	if event.Finished {
+		name = finishedEventName
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "event: %v\ndata: %s\n\n", name, data)
+	return err
+}
+
+// serveJobEvents streams the current state of the job followed by its events until the job
+// finishes or the client disconnects.
+func (j *JobServer) serveJobEvents(w http.ResponseWriter, req *http.Request, guid string,
+	events <-chan JobEvent, current JobEvent) {
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	language := j.pageSettings(w, req).language
+	send := func(event JobEvent) bool {
+		event.Message = j.translator.Translate(language, jobStateKeys[event.State])
+		if err := writeJobEvent(w, event); err != nil {
+			logging.Logger.Warn().
+				Str(logging.ComponentField, componentName).
+				Str(loggingGUIDField, guid).
+				Err(err).
+				Msg("Failed to write job event")
+			return false
+		}
+		flusher.Flush()
+		return true
+	}
+
+	w.Header().Set("Content-Type", eventStreamContentType)
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	if !send(current) || current.Finished {
+		return
+	}
+
+	keepAlive := time.NewTicker(jobEventsKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-req.Context().Done():
+			return
+
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+
+		case event := <-events:
+			if !send(event) || event.Finished {
+				return
+			}
+		}
+	}
+}
+
+// handleJobEvents streams the events of a shortest path job.
+func (j *JobServer) handleJobEvents(w http.ResponseWriter, req *http.Request, guid string) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str(loggingGUIDField, guid).
+		Msg("Received request for job events")
+
+	events, current, err := j.runner.Subscribe(guid)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	defer j.runner.Unsubscribe(guid, events)
+
+	j.serveJobEvents(w, req, guid, events, current)
+}
+
+// spiderHandleJobEvents streams the events of a spider job.
+func (j *JobServer) spiderHandleJobEvents(w http.ResponseWriter, req *http.Request, guid string) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str(loggingGUIDField, guid).
+		Msg("Received request for spider job events")
+
+	events, current, err := j.spiderRunner.Subscribe(guid)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusNotFound)
+		return
+	}
+	defer j.spiderRunner.Unsubscribe(guid, events)
+
+	j.serveJobEvents(w, req, guid, events, current)
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobEventBroker(t *testing.T) {
+
+	broker := newJobEventBroker()
+
+	// Publishing without any subscribers doesn't block
+	broker.publish("1234", newJobEvent(job.InProgress))
+
+	events1 := broker.subscribe("1234")
+	events2 := broker.subscribe("1234")
+	other := broker.subscribe("5678")
+	assert.Equal(t, 2, broker.numberOfSubscribers("1234"))
+
+	broker.publish("1234", newJobEvent(job.InProgress))
+	assert.Equal(t, JobEvent{State: job.InProgress}, <-events1)
+	assert.Equal(t, JobEvent{State: job.InProgress}, <-events2)
+	assert.Equal(t, 0, len(other))
+
+	broker.unsubscribe("1234", events1)
+	broker.publish("1234", newJobEvent(job.CompleteResults))
+	assert.Equal(t, 0, len(events1))
+	assert.Equal(t, JobEvent{State: job.CompleteResults, Finished: true}, <-events2)
+
+	// Events are dropped for a subscriber that isn't keeping up
+	for idx := 0; idx < 2*jobEventsBufferSize; idx++ {
+		broker.publish("1234", newJobEvent(job.InProgress))
+	}
+	assert.Equal(t, jobEventsBufferSize, len(events2))
+
+	broker.unsubscribe("1234", events2)
+	broker.unsubscribe("5678", other)
+	assert.Equal(t, 0, broker.numberOfSubscribers("1234"))
+	assert.Equal(t, 0, len(broker.subscribers))
+}
+
+func TestWriteJobEvent(t *testing.T) {
+
+	w := httptest.NewRecorder()
+	assert.NoError(t, writeJobEvent(w, JobEvent{State: job.InProgress, Message: "In progress"}))
+	assert.Equal(t,
+		"event: progress\ndata: {\"state\":\"In progress\",\"finished\":false,\"message\":\"In progress\"}\n\n",
+		w.Body.String())
+
+	w = httptest.NewRecorder()
+	assert.NoError(t, writeJobEvent(w, newJobEvent(job.Failed)))
+	assert.True(t, strings.HasPrefix(w.Body.String(), "event: finished\n"))
+}
+
+func TestHandleJobEventsNotFound(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	handler := server.Routes()
+
+	for _, url := range []string{"/job/1234/events", "/spider-job/1234/events"} {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	}
+}
+
+func TestHandleJobEventsFinishedJob(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	// Submit a job and wait for it to finish
+	form := buildFormData(1, "Dataset-1", "e-1, e-2", "", "", "", "")
+	req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(form.Encode()))
+	req.Form = form
+
+	w := httptest.NewRecorder()
+	server.handleUpload(w, req)
+	assert.Equal(t, http.StatusFound, w.Code)
+
+	location := w.Result().Header.Get("Location")
+	waitForJobsToFinish(server.runner)
+
+	// The events of a finished job are just its final state
+	req = httptest.NewRequest(http.MethodGet, location+"/events", nil)
+	req.Header.Set("Accept-Language", "cy")
+	w = httptest.NewRecorder()
+	server.Routes().ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, eventStreamContentType, w.Header().Get("Content-Type"))
+	assert.Equal(t, "event: finished\n"+
+		"data: {\"state\":\"Complete Results\",\"finished\":true,\"message\":\"Wedi'i chwblhau gyda chanlyniadau\"}\n\n",
+		w.Body.String())
+	assert.Equal(t, 0, server.runner.events.numberOfSubscribers(extractGuidFromLocation(t, location)))
+}
+
+func TestHandleJobEventsStream(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	// Add a job without executing it, so that its state can be changed by the test
+	conf := &job.JobConfiguration{
+		EntitySets:    []job.EntitySet{{Name: "Dataset-1", EntityIds: []string{"e-1", "e-2"}}},
+		MaxNumberHops: 1,
+	}
+	j1, err := job.NewJob(conf)
+	assert.NoError(t, err)
+	assert.NoError(t, server.runner.addJob(&j1))
+
+	httpServer := httptest.NewServer(server.Routes())
+	defer httpServer.Close()
+
+	resp, err := http.Get(httpServer.URL + "/job/" + j1.GUID + "/events")
+	assert.NoError(t, err)
+	defer resp.Body.Close()
+
+	reader := bufio.NewReader(resp.Body)
+	readEvent := func() string {
+		lines := []string{}
+		for {
+			line, err := reader.ReadString('\n')
+			assert.NoError(t, err)
+			if line == "\n" {
+				return strings.Join(lines, "")
+			}
+			lines = append(lines, line)
+		}
+	}
+
+	// The current state is sent first
+	assert.True(t, strings.Contains(readEvent(), `"state":"Not started"`))
+
+	// Then the job's events until it finishes
+	server.runner.goingToExecuteJob(j1.GUID)
+	server.runner.setJobToInProgress(&j1)
+	event := readEvent()
+	assert.True(t, strings.HasPrefix(event, "event: progress\n"))
+	assert.True(t, strings.Contains(event, `"message":"In progress"`))
+
+	server.runner.setJobToCompleteNoResults(&j1)
+	event = readEvent()
+	assert.True(t, strings.HasPrefix(event, "event: finished\n"))
+	assert.True(t, strings.Contains(event, `"state":"Complete No Results"`))
+}
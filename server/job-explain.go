@@ -0,0 +1,116 @@
+// A job can be run in explain mode, which records how many vertices and edges the search expanded
+// for each pair of entities, which vertices dominated the expansion and the time taken. The
+// diagnostics sheet helps a user to understand why a job is slow or doesn't find any paths.
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/cdclaxton/shortest-path-web-app/bfs"
+	"github.com/cdclaxton/shortest-path-web-app/i2chart"
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+)
+
+// Constants associated with explain mode
+const (
+	ExplainInputName          = "explain"        // Name of the checkbox to run a job in explain mode
+	diagnosticsSheetName      = "Diagnostics"    // Name of the sheet of the diagnostics Excel file
+	diagnosticsFilenamePrefix = "diagnostics - " // Prefix of the filename of the diagnostics
+)
+
+// newExplanation of the search of the job, which is nil unless the job is in explain mode.
+func newExplanation(j1 *job.Job) *bfs.Explanation {
+	if !j1.Configuration.Explain {
+		return nil
+	}
+	return bfs.NewExplanation()
+}
+
+// makeDiagnosticsFilepath for storage of the diagnostics sheet of a job.
+func makeDiagnosticsFilepath(folder string, guid string) string {
+	return path.Join(folder, fmt.Sprintf("%v.diagnostics.xlsx", guid))
+}
+
+// writeDiagnostics of the job's search to an Excel file. The diagnostics are written whether or not
+// the search succeeded, as they are most useful for a job that failed or timed out.
+func (j *JobRunner) writeDiagnostics(j1 *job.Job, explanation *bfs.Explanation) {
+
+	if explanation == nil {
+		return
+	}
+
+	filepath := makeDiagnosticsFilepath(j.folder, j1.GUID)
+	err := j.diskQuota.writeResultFile(j.folder, filepath, func() error {
+		return i2chart.WriteSheetToExcel(filepath, diagnosticsSheetName, explanation.Rows(),
+			provenanceSummary(j1.Provenance))
+	})
+
+	if err != nil {
+		logging.Logger.Warn().
+			Str(logging.ComponentField, componentName).
+			Str(loggingGUIDField, j1.GUID).
+			Err(err).
+			Msg("Failed to write the diagnostics of the job")
+		return
+	}
+
+	j.jobsLock.Lock()
+	defer j.jobsLock.Unlock()
+
+	j1.DiagnosticsFile = filepath
+}
+
+// buildDiagnosticsFilename for the Excel file of the diagnostics of a job.
+func buildDiagnosticsFilename(jobConf *job.JobConfiguration) (string, error) {
+	filename, err := buildFilename(jobConf)
+	if err != nil {
+		return "", err
+	}
+
+	return diagnosticsFilenamePrefix + filename, nil
+}
+
+// handleDownloadDiagnostics returns the Excel file of the diagnostics of a job in explain mode.
+func (j *JobServer) handleDownloadDiagnostics(w http.ResponseWriter, req *http.Request) {
+
+	// Extract the guid
+	guid := strings.TrimPrefix(req.URL.Path, "/download-diagnostics/")
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str(loggingGUIDField, guid).
+		Msg("Received request at /download-diagnostics")
+
+	j1, err := j.runner.GetJob(guid)
+	if err != nil || len(j1.DiagnosticsFile) == 0 {
+
+		logging.Logger.Info().
+			Str(logging.ComponentField, componentName).
+			Str(loggingGUIDField, guid).
+			Msg("Job or diagnostics not found")
+
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	// Make the filename
+	filename, err := buildDiagnosticsFilename(j1.Configuration)
+	if err != nil {
+
+		logging.Logger.Error().
+			Str(logging.ComponentField, componentName).
+			Str(loggingGUIDField, guid).
+			Err(err).
+			Msg("Failed to build filename")
+
+		filename = "diagnostics.xlsx"
+	}
+
+	j.serveJobFile(w, req, guid, j1.DiagnosticsFile, filename, excelContentType, j.jobFailedTemplate,
+		"error.readExcelFile")
+}
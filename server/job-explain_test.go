@@ -0,0 +1,60 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/i2chart"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobInExplainMode(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	handler := server.Routes()
+
+	// A job that isn't in explain mode doesn't have diagnostics
+	w := postForm(handler, "/upload", buildFormData(2, "Dataset-1", "e-1, e-2", "", "", "", ""))
+	assert.Equal(t, http.StatusFound, w.Code)
+	waitForJobsToFinish(server.runner)
+
+	guid := strings.TrimPrefix(w.Header().Get("Location"), "/job/")
+	j1, err := server.runner.GetJob(guid)
+	assert.NoError(t, err)
+	assert.Empty(t, j1.DiagnosticsFile)
+	assert.Equal(t, http.StatusNotFound, getPage(handler, "/download-diagnostics/"+guid).Code)
+
+	// Run the job in explain mode
+	form := buildFormData(2, "Dataset-1", "e-1, e-2", "", "", "", "")
+	form.Set(ExplainInputName, "true")
+
+	w = postForm(handler, "/upload", form)
+	assert.Equal(t, http.StatusFound, w.Code)
+	waitForJobsToFinish(server.runner)
+
+	guid = strings.TrimPrefix(w.Header().Get("Location"), "/job/")
+	j1, err = server.runner.GetJob(guid)
+	assert.NoError(t, err)
+	assert.True(t, j1.Configuration.Explain)
+	assert.NotEmpty(t, j1.DiagnosticsFile)
+
+	// The diagnostics sheet has a row for the pair of entities
+	rows, err := i2chart.ReadFromExcel(j1.DiagnosticsFile, diagnosticsSheetName)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(rows))
+	assert.Equal(t, []string{"e-1", "e-2", "2"}, rows[1][:3])
+
+	// The diagnostics can be downloaded from the results page and the job's status
+	page := getPage(handler, "/job/"+guid)
+	assert.Contains(t, page.Body.String(), "../download-diagnostics/"+guid)
+
+	w = getPage(handler, "/download-diagnostics/"+guid)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, excelContentType, w.Header().Get("Content-Type"))
+
+	status := getPage(handler, "/job/"+guid+"?format=json")
+	assert.Contains(t, status.Body.String(), `"diagnostics":"/download-diagnostics/`+guid+`"`)
+}
@@ -0,0 +1,171 @@
+// Merging jobs produces a single i2 chart from the connections found by several completed shortest
+// path jobs, so that an investigation spanning multiple searches yields one chart.
+
+package server
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+
+	"github.com/cdclaxton/shortest-path-web-app/i18n"
+	"github.com/cdclaxton/shortest-path-web-app/i2chart"
+	"github.com/cdclaxton/shortest-path-web-app/jobdiff"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cdclaxton/shortest-path-web-app/set"
+)
+
+// Constants associated with merging jobs
+const (
+	mergeDownloadUrl    = "/merge-download"
+	minimumMergeJobs    = 2 // Minimum number of different jobs to merge
+	mergedChartFilename = "shortest-path-merged.xlsx"
+)
+
+// mergeQuery returns the query string of the URL to download the merged chart of the jobs.
+func mergeQuery(guids []string) string {
+	values := url.Values{}
+	for _, guid := range guids {
+		values.Add(JobGuidInputName, guid)
+	}
+	return values.Encode()
+}
+
+// uniqueGuids in the order they first appear.
+func uniqueGuids(guids []string) []string {
+	seen := set.NewSet[string]()
+	unique := []string{}
+	for _, guid := range guids {
+		if !seen.Has(guid) {
+			seen.Add(guid)
+			unique = append(unique, guid)
+		}
+	}
+	return unique
+}
+
+// mergeJobs returns the rows of the i2 chart made from the connections found by the jobs, with the
+// number of rows dropped because the chart is too large. A link between two entities found by more
+// than one job only appears once on the chart.
+func (j *JobServer) mergeJobs(guids []string) ([][]string, int, error) {
+
+	// Precondition
+	guids = uniqueGuids(guids)
+	if len(guids) < minimumMergeJobs {
+		return nil, 0, i18n.NewMessage("error.mergeTooFewJobs", minimumMergeJobs)
+	}
+
+	summaries := []*jobdiff.ResultSummary{}
+	entitySets := map[string]*set.Set[string]{}
+	maxHops := 1
+
+	for _, guid := range guids {
+		summary, err := j.readJobSummary(guid, "error.jobNotMergeable")
+		if err != nil {
+			return nil, 0, err
+		}
+		summaries = append(summaries, summary)
+
+		j1, err := j.runner.GetJob(guid)
+		if err != nil {
+			return nil, 0, i18n.Wrap(err, "error.jobNotFound", guid)
+		}
+
+		if j1.Configuration.MaxNumberHops > maxHops {
+			maxHops = j1.Configuration.MaxNumberHops
+		}
+
+		for _, entitySet := range j1.Configuration.EntitySets {
+			for _, entityId := range entitySet.EntityIds {
+				if _, found := entitySets[entityId]; !found {
+					entitySets[entityId] = set.NewSet[string]()
+				}
+				entitySets[entityId].Add(entitySet.Name)
+			}
+		}
+	}
+
+	merged, err := jobdiff.Merge(summaries...)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	conns, err := jobdiff.ToNetworkConnections(merged, entitySets, maxHops)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer conns.Close()
+
+	return j.runner.chartBuilder.BuildWithLogger(conns, logging.Logger)
+}
+
+// handleMergeDownload returns the i2 chart merged from the jobs in the request as an Excel file.
+func (j *JobServer) handleMergeDownload(w http.ResponseWriter, req *http.Request) {
+
+	guids := req.URL.Query()[JobGuidInputName]
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Strs("guids", guids).
+		Msg("Received request to download the merged chart of jobs")
+	settings := j.pageSettings(w, req)
+
+	rows, droppedRows, err := j.mergeJobs(guids)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		page := j.render(j.inputProblemTemplate, settings, map[string]string{
+			"reason": j.translator.TranslateError(settings.language, err),
+		})
+		fmt.Fprint(w, page)
+		return
+	}
+
+	folder, err := os.MkdirTemp(j.runner.folder, "merge-")
+	if err == nil {
+		defer os.RemoveAll(folder)
+		err = writeMergedChart(w, rows, droppedRows, j.runner.chartBuilder.MaxRows(),
+			path.Join(folder, mergedChartFilename))
+	}
+
+	if err != nil {
+		logging.Logger.Error().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to write the merged chart of jobs")
+
+		w.WriteHeader(http.StatusInternalServerError)
+		page := j.render(j.errorTemplate, settings, map[string]string{
+			"reason": j.translator.TranslateError(settings.language, err),
+		})
+		fmt.Fprint(w, page)
+	}
+}
+
+// writeMergedChart to the response as an Excel file. The Excel file is written to the filepath
+// first, as the Excel writer requires a filepath.
+func writeMergedChart(w http.ResponseWriter, rows [][]string, droppedRows int, maxRows int,
+	filepath string) error {
+
+	var summary [][]string
+	if droppedRows > 0 {
+		summary = i2chart.TruncationSummary(len(rows)-1, droppedRows, maxRows)
+	}
+
+	if err := i2chart.WriteToExcelWithSummary(filepath, rows, summary); err != nil {
+		return err
+	}
+
+	file, err := os.Open(filepath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%v", mergedChartFilename))
+	w.Header().Set("Content-Type", excelContentType)
+	_, err = io.Copy(w, file)
+	return err
+}
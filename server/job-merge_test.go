@@ -0,0 +1,78 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+)
+
+// readChartRows from the Excel file in the body of the response.
+func readChartRows(t *testing.T, content []byte) [][]string {
+	f, err := excelize.OpenReader(bytes.NewReader(content))
+	assert.NoError(t, err)
+	defer f.Close()
+
+	rows, err := f.GetRows(f.GetSheetName(0))
+	assert.NoError(t, err)
+	return rows
+}
+
+func TestMergeJobs(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	handler := server.Routes()
+
+	// The same job is run twice
+	assert.NoError(t, server.SetDuplicateJobWindow(0))
+
+	noResults := submitAndWait(t, server, "e-100, e-102")
+	results1 := submitAndWait(t, server, "e-1, e-2")
+	results2 := submitAndWait(t, server, "e-1, e-2")
+
+	// The chart of a single job
+	w := getPage(handler, "/download/"+results1)
+	assert.Equal(t, http.StatusOK, w.Code)
+	expected := readChartRows(t, w.Body.Bytes())
+	assert.True(t, len(expected) > 1)
+
+	// The links found by both jobs only appear once
+	w = getPage(handler, mergeDownloadUrl+"?"+mergeQuery([]string{results1, results2, noResults}))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, excelContentType, w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Header().Get("Content-Disposition"), mergedChartFilename)
+	assert.Equal(t, expected, readChartRows(t, w.Body.Bytes()))
+
+	// Too few jobs
+	w = getPage(handler, mergeDownloadUrl+"?"+mergeQuery([]string{results1, results1}))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "at least 2 different completed jobs")
+
+	// A job that doesn't exist
+	w = getPage(handler, mergeDownloadUrl+"?"+mergeQuery([]string{results1, "1234"}))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "job 1234 not found")
+
+	// A case with the jobs offers the merged chart
+	w = postForm(handler, "/create-case", url.Values{CaseNameInputName: {"Operation A"}})
+	location := w.Result().Header.Get("Location")
+	caseId := strings.TrimPrefix(location, caseUrl)
+
+	w = postForm(handler, "/add-case-job", url.Values{CaseIdInputName: {caseId},
+		JobGuidInputName: {results1}})
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.NotContains(t, getPage(handler, location).Body.String(), "merge-download")
+
+	w = postForm(handler, "/add-case-job", url.Values{CaseIdInputName: {caseId},
+		JobGuidInputName: {results2}})
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Contains(t, getPage(handler, location).Body.String(),
+		"../merge-download?guid="+results1+"&amp;guid="+results2)
+}
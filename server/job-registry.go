@@ -0,0 +1,260 @@
+// The job runners share a registry of their jobs, which holds the jobs, the number of jobs being
+// executed and the subscribers to the events of the jobs. The registry implements the state
+// machine of a job: a job that hasn't started is set to in progress and then to one of the end
+// states, with an event published for each change. A new type of job therefore only needs to make
+// its job from its configuration and to execute it.
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aymerick/raymond"
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+)
+
+var ErrInvalidJobTransition = errors.New("invalid change of the state of a job")
+
+// A jobRegistry holds the jobs of a type (e.g. *job.Job) for a job runner.
+type jobRegistry[J job.Runnable] struct {
+	kind string // Type of the jobs for logging, e.g. "spider job"
+
+	jobs     map[string]J // Jobs (mapping of guid to job)
+	jobsLock sync.RWMutex // Mutex for the jobs map and the state of the jobs
+
+	numberJobsExecuting     int          // Number of jobs being executed
+	numberJobsExecutingLock sync.RWMutex // Mutex for the numberJobsExecuting
+
+	events *jobEventBroker // Subscribers to the events of jobs
+}
+
+// newJobRegistry without any jobs.
+func newJobRegistry[J job.Runnable](kind string) jobRegistry[J] {
+	return jobRegistry[J]{
+		kind:                    kind,
+		jobs:                    map[string]J{},
+		jobsLock:                sync.RWMutex{},
+		numberJobsExecuting:     0,
+		numberJobsExecutingLock: sync.RWMutex{},
+		events:                  newJobEventBroker(),
+	}
+}
+
+// goingToExecuteJob increments the number of jobs executing.
+func (r *jobRegistry[J]) goingToExecuteJob(guid string) {
+	r.numberJobsExecutingLock.Lock()
+	defer r.numberJobsExecutingLock.Unlock()
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str(loggingGUIDField, guid).
+		Str("kind", r.kind).
+		Msg("Going to execute job")
+
+	r.numberJobsExecuting += 1
+}
+
+// finishedExecutingJob decrements the number of jobs executing.
+func (r *jobRegistry[J]) finishedExecutingJob(guid string) {
+	r.numberJobsExecutingLock.Lock()
+	defer r.numberJobsExecutingLock.Unlock()
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str(loggingGUIDField, guid).
+		Str("kind", r.kind).
+		Msg("Finished executing job")
+
+	r.numberJobsExecuting -= 1
+}
+
+// GetNumberJobsExecuting returns the number of jobs being executed when the lock is acquired.
+func (r *jobRegistry[J]) GetNumberJobsExecuting() int {
+	r.numberJobsExecutingLock.RLock()
+	defer r.numberJobsExecutingLock.RUnlock()
+
+	return r.numberJobsExecuting
+}
+
+// addJob to the map of jobs once the write lock has been acquired.
+func (r *jobRegistry[J]) addJob(j1 J) error {
+	r.jobsLock.Lock()
+	defer r.jobsLock.Unlock()
+
+	if !j1.HasValidGuid() {
+		return ErrInvalidGuid
+	}
+
+	r.jobs[j1.Id()] = j1
+	return nil
+}
+
+// submit the job for execution by the function (in a go routine).
+func (r *jobRegistry[J]) submit(j1 J, execute func(guid string)) (string, error) {
+
+	if err := r.addJob(j1); err != nil {
+		return InvalidGUID, err
+	}
+
+	r.goingToExecuteJob(j1.Id())
+	go execute(j1.Id())
+
+	return j1.Id(), nil
+}
+
+// start the job, i.e. set it to in progress. A job that has already started isn't changed.
+func (r *jobRegistry[J]) start(j1 J) error {
+	r.jobsLock.Lock()
+	defer r.jobsLock.Unlock()
+
+	progress := j1.CurrentProgress()
+	if progress.State != job.NotStarted {
+		return r.invalidTransition(j1, job.InProgress)
+	}
+
+	progress.StartTime = time.Now()
+	progress.State = job.InProgress
+
+	r.events.publish(j1.Id(), newJobEvent(progress.State))
+	return nil
+}
+
+// finish the job in an end state, where the update records the outcome in the job (e.g. its result
+// file) and may be nil. The error is recorded in the job unless it is nil. A job that isn't in
+// progress isn't changed.
+func (r *jobRegistry[J]) finish(j1 J, state job.JobState, err error, update func()) error {
+	r.jobsLock.Lock()
+	defer r.jobsLock.Unlock()
+
+	progress := j1.CurrentProgress()
+	if progress.State != job.InProgress || !isFinishedState(state) {
+		return r.invalidTransition(j1, state)
+	}
+
+	progress.EndTime = time.Now()
+	progress.State = state
+
+	if err != nil {
+		j1.SetError(err)
+	}
+
+	if update != nil {
+		update()
+	}
+
+	r.events.publish(j1.Id(), newJobEvent(progress.State))
+	r.finishedExecutingJob(j1.Id())
+	return nil
+}
+
+// invalidTransition of the job to the state, which is logged, as it is a bug in the job runner.
+func (r *jobRegistry[J]) invalidTransition(j1 J, state job.JobState) error {
+
+	logging.Logger.Error().
+		Str(logging.ComponentField, componentName).
+		Str(loggingGUIDField, j1.Id()).
+		Str("kind", r.kind).
+		Str("from", string(j1.CurrentProgress().State)).
+		Str("to", string(state)).
+		Msg("Invalid change of the state of a job")
+
+	return ErrInvalidJobTransition
+}
+
+// GetJob from the job runner in a thread-safe manner. The returned job should not be modified.
+func (r *jobRegistry[J]) GetJob(guid string) (J, error) {
+
+	// Get a lock to be able to read the jobs map
+	r.jobsLock.RLock()
+	defer r.jobsLock.RUnlock()
+
+	// Try to fetch the job
+	j1, found := r.jobs[guid]
+	if !found {
+		var none J
+		return none, ErrJobNotFound
+	}
+
+	return j1, nil
+}
+
+// IsJobFinished given the job's GUID.
+func (r *jobRegistry[J]) IsJobFinished(guid string) (bool, error) {
+
+	// Get a lock to be able to read the jobs map
+	r.jobsLock.RLock()
+	defer r.jobsLock.RUnlock()
+
+	// Try to fetch the job
+	j1, found := r.jobs[guid]
+	if !found {
+		return false, ErrJobNotFound
+	}
+
+	// If the job is in an end state, it is finished
+	return isFinishedState(j1.CurrentProgress().State), nil
+}
+
+// Subscribe to the events of a job. The current state of the job is returned, so that no events
+// are missed between reading the state and subscribing.
+func (r *jobRegistry[J]) Subscribe(guid string) (chan JobEvent, JobEvent, error) {
+
+	// Get a lock so that the job's state can't change whilst subscribing
+	r.jobsLock.RLock()
+	defer r.jobsLock.RUnlock()
+
+	j1, found := r.jobs[guid]
+	if !found {
+		return nil, JobEvent{}, ErrJobNotFound
+	}
+
+	return r.events.subscribe(guid), newJobEvent(j1.CurrentProgress().State), nil
+}
+
+// Unsubscribe from the events of a job.
+func (r *jobRegistry[J]) Unsubscribe(guid string, events chan JobEvent) {
+	r.events.unsubscribe(guid, events)
+}
+
+// responsive returns true if the registry isn't deadlocked.
+func (r *jobRegistry[J]) responsive(timeout time.Duration) bool {
+	return lockResponsive(timeout, &r.jobsLock, &r.numberJobsExecutingLock)
+}
+
+// serveJobFile sends a file of a job (e.g. its results) for download with the filename. The page
+// for a failed job is shown with the reason if the file can't be read.
+func (j *JobServer) serveJobFile(w http.ResponseWriter, req *http.Request, guid string,
+	filepath string, filename string, contentType string, failedTemplate *raymond.Template,
+	reasonKey string) {
+
+	file, err := os.Open(filepath)
+	if err != nil {
+
+		logging.Logger.Error().
+			Str(logging.ComponentField, componentName).
+			Str(loggingGUIDField, guid).
+			Str("filepath", filepath).
+			Msg("Failed to read the file of the job")
+
+		settings := j.pageSettings(w, req)
+
+		page := j.render(failedTemplate, settings, map[string]string{
+			"reason": j.translator.Translate(settings.language, reasonKey, guid),
+		})
+
+		fmt.Fprint(w, page)
+		return
+	}
+	defer file.Close()
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%v", filename))
+	w.Header().Set("Content-Type", contentType)
+	io.Copy(w, file)
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/cdclaxton/shortest-path-web-app/set"
+	"github.com/stretchr/testify/assert"
+)
+
+// makeSpiderJob for testing the registry.
+func makeSpiderJob(t *testing.T) *job.SpiderJob {
+	conf, err := job.NewSpiderJobConfiguration(1, set.NewPopulatedSet("e-1"))
+	assert.NoError(t, err)
+
+	j1, err := job.NewSpiderJob(conf)
+	assert.NoError(t, err)
+	return &j1
+}
+
+func TestJobRegistrySubmit(t *testing.T) {
+
+	registry := newJobRegistry[*job.SpiderJob]("spider job")
+
+	// A job with an invalid GUID isn't added
+	invalid := makeSpiderJob(t)
+	invalid.GUID = "1234"
+	guid, err := registry.submit(invalid, func(string) {})
+	assert.ErrorIs(t, err, ErrInvalidGuid)
+	assert.Equal(t, InvalidGUID, guid)
+	assert.Equal(t, 0, registry.GetNumberJobsExecuting())
+
+	// The job is executed once it has been added
+	executed := make(chan string)
+	j1 := makeSpiderJob(t)
+	guid, err = registry.submit(j1, func(guid string) { executed <- guid })
+	assert.NoError(t, err)
+	assert.Equal(t, j1.GUID, guid)
+	assert.Equal(t, j1.GUID, <-executed)
+	assert.Equal(t, 1, registry.GetNumberJobsExecuting())
+
+	found, err := registry.GetJob(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, j1, found)
+
+	_, err = registry.GetJob("unknown")
+	assert.ErrorIs(t, err, ErrJobNotFound)
+
+	_, err = registry.IsJobFinished("unknown")
+	assert.ErrorIs(t, err, ErrJobNotFound)
+}
+
+func TestJobRegistryStateMachine(t *testing.T) {
+
+	registry := newJobRegistry[*job.SpiderJob]("spider job")
+
+	j1 := makeSpiderJob(t)
+	guid, err := registry.submit(j1, func(string) {})
+	assert.NoError(t, err)
+
+	events, current, err := registry.Subscribe(guid)
+	assert.NoError(t, err)
+	defer registry.Unsubscribe(guid, events)
+	assert.Equal(t, job.NotStarted, current.State)
+
+	// A job that hasn't started can't finish
+	assert.ErrorIs(t, registry.finish(j1, job.CompleteResults, nil, nil), ErrInvalidJobTransition)
+	assert.Equal(t, job.NotStarted, j1.Progress.State)
+
+	assert.NoError(t, registry.start(j1))
+	assert.Equal(t, job.InProgress, j1.Progress.State)
+	assert.False(t, j1.Progress.StartTime.IsZero())
+	assert.Equal(t, JobEvent{State: job.InProgress}, <-events)
+
+	// A job can't be started twice or finish in a state that isn't an end state
+	assert.ErrorIs(t, registry.start(j1), ErrInvalidJobTransition)
+	assert.ErrorIs(t, registry.finish(j1, job.NotStarted, nil, nil), ErrInvalidJobTransition)
+
+	finished, err := registry.IsJobFinished(guid)
+	assert.NoError(t, err)
+	assert.False(t, finished)
+
+	// The job fails, recording the error and the update
+	failure := errors.New("failure")
+	assert.NoError(t, registry.finish(j1, job.Failed, failure, func() {
+		j1.Message = "message"
+	}))
+	assert.Equal(t, job.Failed, j1.Progress.State)
+	assert.False(t, j1.Progress.EndTime.IsZero())
+	assert.Equal(t, failure, j1.Error)
+	assert.Equal(t, "message", j1.Message)
+	assert.Equal(t, JobEvent{State: job.Failed, Finished: true}, <-events)
+	assert.Equal(t, 0, registry.GetNumberJobsExecuting())
+
+	finished, err = registry.IsJobFinished(guid)
+	assert.NoError(t, err)
+	assert.True(t, finished)
+
+	// A finished job can't finish again
+	assert.ErrorIs(t, registry.finish(j1, job.CompleteResults, nil, nil), ErrInvalidJobTransition)
+	assert.Equal(t, 0, registry.GetNumberJobsExecuting())
+}
@@ -0,0 +1,115 @@
+// A failed job can be retried. If checkpointing is enabled, the retry resumes the search for paths
+// from where it failed and the paths found before the failure can be downloaded as partial results.
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/cdclaxton/shortest-path-web-app/i18n"
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+)
+
+// Prefix of the filename of the partial results of a failed job
+const partialFilenamePrefix = "partial - "
+
+// handleRetry retries a failed job and redirects to the job's page.
+func (j *JobServer) handleRetry(w http.ResponseWriter, req *http.Request) {
+
+	settings := j.pageSettings(w, req)
+
+	if req.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Extract the guid
+	guid := strings.TrimPrefix(req.URL.Path, "/retry/")
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str(loggingGUIDField, guid).
+		Msg("Received request at /retry")
+
+	err := j.runner.Retry(guid)
+	if errors.Is(err, ErrJobNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		page := j.render(j.inputProblemTemplate, settings, map[string]string{
+			"reason": j.translator.TranslateError(settings.language,
+				i18n.Wrap(err, "error.jobNotFound", guid)),
+		})
+		fmt.Fprint(w, page)
+		return
+	} else if errors.Is(err, ErrJobNotRetryable) {
+		w.WriteHeader(http.StatusConflict)
+		page := j.render(j.inputProblemTemplate, settings, map[string]string{
+			"reason": j.translator.TranslateError(settings.language,
+				i18n.Wrap(err, "error.jobNotRetryable", guid)),
+		})
+		fmt.Fprint(w, page)
+		return
+	} else if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		page := j.render(j.errorTemplate, settings, map[string]string{
+			"reason": j.translator.TranslateError(settings.language, err),
+		})
+		fmt.Fprint(w, page)
+		return
+	}
+
+	http.Redirect(w, req, fmt.Sprintf("%v/job/%v", j.basePath, guid), http.StatusFound)
+}
+
+// buildPartialFilename for the Excel file of the partial results of a failed job.
+func buildPartialFilename(jobConf *job.JobConfiguration) (string, error) {
+	filename, err := buildFilename(jobConf)
+	if err != nil {
+		return "", err
+	}
+
+	return partialFilenamePrefix + filename, nil
+}
+
+// handleDownloadPartial returns the Excel file of the partial results of a failed job.
+func (j *JobServer) handleDownloadPartial(w http.ResponseWriter, req *http.Request) {
+
+	// Extract the guid
+	guid := strings.TrimPrefix(req.URL.Path, "/download-partial/")
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str(loggingGUIDField, guid).
+		Msg("Received request at /download-partial")
+
+	j1, err := j.runner.GetJob(guid)
+	if err != nil || len(j1.PartialResultFile) == 0 {
+
+		logging.Logger.Info().
+			Str(logging.ComponentField, componentName).
+			Str(loggingGUIDField, guid).
+			Msg("Job or partial results not found")
+
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	// Make the filename
+	filename, err := buildPartialFilename(j1.Configuration)
+	if err != nil {
+
+		logging.Logger.Error().
+			Str(logging.ComponentField, componentName).
+			Str(loggingGUIDField, guid).
+			Err(err).
+			Msg("Failed to build filename")
+
+		filename = "partial-results.xlsx"
+	}
+
+	j.serveJobFile(w, req, guid, j1.PartialResultFile, filename, excelContentType, j.jobFailedTemplate,
+		"error.readExcelFile")
+}
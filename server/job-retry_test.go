@@ -0,0 +1,168 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"sync"
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/bfs"
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/stretchr/testify/assert"
+	"github.com/xuri/excelize/v2"
+)
+
+var errTransientRead = errors.New("transient read error")
+
+// flakyStore fails to read an entity whilst failing is set.
+type flakyStore struct {
+	graphstore.UnipartiteGraphStore
+	entityId string
+	failing  bool
+	lock     sync.Mutex
+}
+
+func (f *flakyStore) setFailing(failing bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.failing = failing
+}
+
+func (f *flakyStore) HasEntity(entityId string) (bool, error) {
+	f.lock.Lock()
+	failing := f.failing
+	f.lock.Unlock()
+
+	if failing && entityId == f.entityId {
+		return false, errTransientRead
+	}
+
+	return f.UnipartiteGraphStore.HasEntity(entityId)
+}
+
+func TestRetryJobWithCheckpoint(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	// Reading e-4 fails, so the job fails after finding the paths between e-1 and e-2
+	flaky := &flakyStore{
+		UnipartiteGraphStore: server.runner.searchEngine.Unipartite,
+		entityId:             "e-4",
+		failing:              true,
+	}
+
+	var err error
+	server.runner.pathFinder, err = bfs.NewPathFinder(flaky)
+	assert.NoError(t, err)
+	server.runner.SetCheckpointing(true)
+
+	handler := server.Routes()
+
+	w := postForm(handler, "/upload", buildFormData(1, "Dataset-1", "e-1, e-2, e-4", "", "", "", ""))
+	guid := extractGuidFromLocation(t, w.Result().Header.Get("Location"))
+	waitForJobsToFinish(server.runner)
+
+	j1, err := server.runner.GetJob(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, job.Failed, j1.Progress.State)
+	partialFile := j1.PartialResultFile
+	assert.FileExists(t, partialFile)
+
+	// The job's page offers the partial results and a retry
+	w = getPage(handler, "/job/"+guid)
+	assert.Contains(t, w.Body.String(), "../download-partial/"+guid)
+	assert.Contains(t, w.Body.String(), "../retry/"+guid)
+
+	var status JobStatus
+	assert.NoError(t, json.Unmarshal(getPage(handler, "/job/"+guid+"?format=json").Body.Bytes(), &status))
+	assert.Equal(t, "/download-partial/"+guid, status.Partial)
+
+	// The partial results are flagged as incomplete
+	w = getPage(handler, "/download-partial/"+guid)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Disposition"), partialFilenamePrefix)
+
+	f, err := excelize.OpenFile(partialFile)
+	assert.NoError(t, err)
+	summary, err := f.GetRows("Summary")
+	assert.NoError(t, err)
+	assert.Equal(t, "Warning", summary[0][0])
+	assert.Contains(t, summary[1][1], errTransientRead.Error())
+	assert.NoError(t, f.Close())
+
+	// A job that hasn't failed can't be retried
+	w = postForm(handler, "/upload", buildFormData(1, "Dataset-1", "e-1, e-2", "", "", "", ""))
+	complete := extractGuidFromLocation(t, w.Result().Header.Get("Location"))
+	waitForJobsToFinish(server.runner)
+
+	w = postForm(handler, "/retry/"+complete, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	w = postForm(handler, "/retry/1234", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = getPage(handler, "/retry/"+guid)
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+	// Retry once the store has recovered
+	flaky.setFailing(false)
+
+	w = postForm(handler, "/retry/"+guid, nil)
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "/job/"+guid, w.Header().Get("Location"))
+	waitForJobsToFinish(server.runner)
+
+	j1, err = server.runner.GetJob(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, job.CompleteResults, j1.Progress.State)
+	assert.Nil(t, j1.Error)
+	assert.Empty(t, j1.PartialResultFile)
+	assert.Empty(t, server.runner.checkpoints)
+
+	_, err = os.Stat(partialFile)
+	assert.True(t, os.IsNotExist(err))
+
+	w = getPage(handler, "/download-partial/"+guid)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestFailedJobWithoutCheckpoint(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	flaky := &flakyStore{
+		UnipartiteGraphStore: server.runner.searchEngine.Unipartite,
+		entityId:             "e-4",
+		failing:              true,
+	}
+
+	var err error
+	server.runner.pathFinder, err = bfs.NewPathFinder(flaky)
+	assert.NoError(t, err)
+
+	handler := server.Routes()
+
+	w := postForm(handler, "/upload", buildFormData(1, "Dataset-1", "e-1, e-2, e-4", "", "", "", ""))
+	guid := extractGuidFromLocation(t, w.Result().Header.Get("Location"))
+	waitForJobsToFinish(server.runner)
+
+	// There aren't any partial results, but the job can still be retried from the start
+	w = getPage(handler, "/job/"+guid)
+	assert.NotContains(t, w.Body.String(), "../download-partial/"+guid)
+	assert.Contains(t, w.Body.String(), "../retry/"+guid)
+
+	flaky.setFailing(false)
+	assert.NoError(t, server.runner.Retry(guid))
+	waitForJobsToFinish(server.runner)
+
+	j1, err := server.runner.GetJob(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, job.CompleteResults, j1.Progress.State)
+}
@@ -30,11 +30,13 @@ func makeJobRunner(t *testing.T) (*JobRunner, *SpiderJobRunner) {
 	// Entity search engine
 	searchEngine, err := search.NewEntitySearch(builder.Bipartite, builder.Unipartite)
 	assert.NoError(t, err)
+	assert.NoError(t, searchEngine.BuildSuggestionIndex())
 
 	// Instantiate the i2 chart builder
 	chartBuilder, err := i2chart.NewI2ChartBuilder(i2ConfigFilepath)
 	assert.NoError(t, err)
 	chartBuilder.SetBipartite(builder.Bipartite)
+	chartBuilder.SetUnipartite(builder.Unipartite)
 
 	// Instantiate the path finder
 	pathFinder, err := bfs.NewPathFinder(builder.Unipartite)
@@ -55,6 +57,7 @@ func makeJobRunner(t *testing.T) (*JobRunner, *SpiderJobRunner) {
 	// Make a spider job runner
 	spider, err := spider.NewSpider(builder.Unipartite)
 	assert.NoError(t, err)
+	spider.SetBipartite(builder.Bipartite)
 
 	spiderChartBuilder, err := i2chart.NewSpiderChartBuilder(spiderI2ConfigFilepath)
 	assert.NoError(t, err)
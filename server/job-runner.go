@@ -1,18 +1,30 @@
 package server
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
 	"path"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/cdclaxton/shortest-path-web-app/bfs"
+	"github.com/cdclaxton/shortest-path-web-app/filedetector"
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/i18n"
 	"github.com/cdclaxton/shortest-path-web-app/i2chart"
 	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/cdclaxton/shortest-path-web-app/jobdiff"
 	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cdclaxton/shortest-path-web-app/manifest"
+	"github.com/cdclaxton/shortest-path-web-app/pathfilter"
+	"github.com/cdclaxton/shortest-path-web-app/pathmatrix"
+	"github.com/cdclaxton/shortest-path-web-app/publish"
 	"github.com/cdclaxton/shortest-path-web-app/search"
+	"github.com/cdclaxton/shortest-path-web-app/set"
+	"github.com/rs/zerolog"
 	"golang.org/x/exp/maps"
 )
 
@@ -24,6 +36,7 @@ var (
 	ErrFolderDoesNotExist = errors.New("i2 chart folder doesn't exist")
 	ErrInvalidGuid        = errors.New("invalid GUID")
 	ErrSearchEngineIsNil  = errors.New("search engine is nil")
+	ErrJobNotRetryable    = errors.New("only a failed job can be retried")
 )
 
 // GUID returned on failure (instead of an empty string)
@@ -32,19 +45,59 @@ const InvalidGUID = "invalid-guid"
 // Message to display to the user when no paths between entities were found
 const noPathsMessage = "Sorry, no paths were found between entities. Maybe increase the number of hops."
 
+// truncatedWarning builds the warning to display to the user when rows were dropped from the chart.
+func truncatedWarning(numberOfRows int, droppedRows int) *i18n.Message {
+	return i18n.NewMessage("job.truncatedWarning", numberOfRows, droppedRows)
+}
+
+// anxWarning builds the warning to display to the user when the ANX chart couldn't be written.
+func anxWarning() *i18n.Message {
+	return i18n.NewMessage("job.anxWarning")
+}
+
+// filteredWarning builds the warning to display to the user when paths were removed by the job's
+// path filter.
+func filteredWarning(removed int, filter string) *i18n.Message {
+	return i18n.NewMessage("job.filteredWarning", removed, filter)
+}
+
+// sampledWarning builds the warning to display to the user when paths were omitted by sampling.
+func sampledWarning(maxPathsPerPair int, omitted int) *i18n.Message {
+	return i18n.NewMessage("job.sampledWarning", maxPathsPerPair, omitted)
+}
+
+// budgetWarning builds the warning to display to the user when the search was stopped because the
+// job's budget of vertex expansions was exhausted.
+func budgetWarning(maxExpansions int) *i18n.Message {
+	return i18n.NewMessage("job.budgetWarning", maxExpansions)
+}
+
+// retryWarning builds the warning to display to the user when the job was retried with fewer hops.
+func retryWarning(requestedHops int, retryHops int, err error) *i18n.Message {
+	return i18n.NewMessage("job.retryWarning", requestedHops, err, retryHops)
+}
+
 // A JobRunner is responsible for finding the paths and generating an Excel file for i2.
 type JobRunner struct {
 	pathFinder   *bfs.PathFinder         // Path finder
 	chartBuilder *i2chart.I2ChartBuilder // i2 chart builder
 	folder       string                  // Location for the Excel files
 
-	jobs     map[string]*job.Job // Jobs (mapping of guid to job)
-	jobsLock sync.RWMutex        // Mutex for the jobs map
-
-	numberJobsExecuting     int          // Number of jobs being executed
-	numberJobsExecutingLock sync.RWMutex // Mutex for the numberJobsExecuting
+	jobRegistry[*job.Job] // Jobs, their states and the subscribers to their events
 
 	searchEngine *search.EntitySearch
+	provenance   filedetector.DataProvenance // Data drop searched by the jobs
+	diskQuota    DiskQuota                   // Disk space the result files may use
+
+	checkpointing bool                       // Record the progress of the jobs' searches
+	checkpoints   map[string]*bfs.Checkpoint // Progress of the searches of jobs (guarded by jobsLock)
+
+	timeout   time.Duration         // Default timeout of the jobs (zero for no timeout)
+	storeLock *graphstore.StoreLock // Queues the jobs whilst the stores are updated (optional)
+	publisher publish.Publisher     // Copies the result files to object storage (optional)
+	manifests *manifest.Generator   // Makes the manifests of the result files (optional)
+
+	submitLock sync.Mutex // Serialises the check for a duplicate job and its submission
 }
 
 // NewJobRunner instantiates a new JobRunner struct.
@@ -70,62 +123,63 @@ func NewJobRunner(pathFinder *bfs.PathFinder, chartBuilder *i2chart.I2ChartBuild
 
 	// Return a constructed job runner
 	return &JobRunner{
-		pathFinder:              pathFinder,
-		chartBuilder:            chartBuilder,
-		folder:                  folder,
-		jobs:                    map[string]*job.Job{},
-		jobsLock:                sync.RWMutex{},
-		numberJobsExecuting:     0,
-		numberJobsExecutingLock: sync.RWMutex{},
-		searchEngine:            searchEngine,
+		pathFinder:   pathFinder,
+		chartBuilder: chartBuilder,
+		folder:       folder,
+		jobRegistry:  newJobRegistry[*job.Job]("job"),
+		searchEngine: searchEngine,
+		checkpoints:  map[string]*bfs.Checkpoint{},
 	}, nil
 }
 
-// goingToExecuteJob increments the number of jobs executing.
-func (j *JobRunner) goingToExecuteJob(guid string) {
-	j.numberJobsExecutingLock.Lock()
-	defer j.numberJobsExecutingLock.Unlock()
+// SetProvenance of the data searched by the jobs, which is recorded with each job.
+func (j *JobRunner) SetProvenance(provenance filedetector.DataProvenance) {
 
 	logging.Logger.Info().
 		Str(logging.ComponentField, componentName).
-		Str(loggingGUIDField, guid).
-		Msg("Going to execute job")
+		Str("signature", provenance.Signature).
+		Msg("Setting the provenance of the data")
 
-	j.numberJobsExecuting += 1
+	j.provenance = provenance
 }
 
-// finishedExecutingJob decrements the number of jobs executing.
-func (j *JobRunner) finishedExecutingJob(guid string) {
-	j.numberJobsExecutingLock.Lock()
-	defer j.numberJobsExecutingLock.Unlock()
+// SetDiskQuota of the result files of the jobs.
+func (j *JobRunner) SetDiskQuota(quota DiskQuota) error {
+	if err := quota.Validate(); err != nil {
+		return err
+	}
 
 	logging.Logger.Info().
 		Str(logging.ComponentField, componentName).
-		Str(loggingGUIDField, guid).
-		Msg("Finished executing job")
+		Int64("minFreeBytes", quota.MinFreeBytes).
+		Int64("maxFolderBytes", quota.MaxFolderBytes).
+		Msg("Setting the disk quota of the job runner")
 
-	j.numberJobsExecuting -= 1
+	j.diskQuota = quota
+	return nil
 }
 
-// GetNumberJobsExecuting returns the number of jobs being executed when the lock is acquired.
-func (j *JobRunner) GetNumberJobsExecuting() int {
-	j.numberJobsExecutingLock.RLock()
-	defer j.numberJobsExecutingLock.RUnlock()
+// SetCheckpointing of the searches of the jobs. If enabled, the paths found by a job that fails are
+// kept, so that they can be downloaded as partial results and the job can be retried without
+// searching again between the entities that were searched before the failure.
+func (j *JobRunner) SetCheckpointing(enabled bool) {
 
-	return j.numberJobsExecuting
-}
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Bool("enabled", enabled).
+		Msg("Setting the checkpointing of jobs")
 
-// addJob to the map of jobs once the write lock has been acquired.
-func (j *JobRunner) addJob(j1 *job.Job) error {
-	j.jobsLock.Lock()
-	defer j.jobsLock.Unlock()
+	j.checkpointing = enabled
+}
 
-	if !j1.HasValidGuid() {
-		return ErrInvalidGuid
+// provenanceSummary of the data searched by a job, as rows for the summary sheet of its Excel file.
+// There aren't any rows if the provenance is unknown.
+func provenanceSummary(provenance filedetector.DataProvenance) [][]string {
+	if !provenance.Known() {
+		return nil
 	}
 
-	j.jobs[j1.GUID] = j1
-	return nil
+	return i2chart.ProvenanceSummary(provenance.Signature, provenance.SourceFiles, provenance.Loaded)
 }
 
 // Submit the job for execution.
@@ -141,84 +195,74 @@ func (j *JobRunner) Submit(jobConf *job.JobConfiguration) (string, error) {
 	if err != nil {
 		return InvalidGUID, err
 	}
+	job.Provenance = j.provenance
 
-	// Add the job to the job runner's storage
-	err = j.addJob(&job)
-	if err != nil {
-		return InvalidGUID, err
-	}
-
-	// Execute the job (in a go routine)
-	j.goingToExecuteJob(job.GUID)
-	go j.executeJob(job.GUID)
-
-	return job.GUID, nil
+	// Add the job to the job runner's storage and execute it
+	return j.submit(&job, j.executeJob)
 }
 
 // setJobToInProgress sets the job to in progress (i.e. started).
 func (j *JobRunner) setJobToInProgress(j1 *job.Job) {
-	j.jobsLock.Lock()
-	defer j.jobsLock.Unlock()
-
 	logging.Logger.Info().
 		Str(logging.ComponentField, componentName).
 		Str(loggingGUIDField, j1.GUID).
 		Msg("Setting job to in progress")
 
-	j1.Progress.StartTime = time.Now()
-	j1.Progress.State = job.InProgress
+	j.start(j1)
 }
 
 // setJobToFailed sets the job to failed and stores the error in the job.
 func (j *JobRunner) setJobToFailed(failedJob *job.Job, err error) {
-	j.jobsLock.Lock()
-	defer j.jobsLock.Unlock()
-
 	logging.Logger.Info().
 		Str(logging.ComponentField, componentName).
 		Str(loggingGUIDField, failedJob.GUID).
 		Str("error", err.Error()).
 		Msg("Setting job to failed")
 
-	failedJob.Progress.State = job.Failed
-	failedJob.Progress.EndTime = time.Now()
-	failedJob.Error = err
-
-	j.finishedExecutingJob(failedJob.GUID)
+	j.finish(failedJob, job.Failed, err, nil)
 }
 
 // setJobToComplete sets the job to complete (finished) where there were results.
-func (j *JobRunner) setJobToCompleteResults(j1 *job.Job, filepath string) {
-	j.jobsLock.Lock()
-	defer j.jobsLock.Unlock()
-
+func (j *JobRunner) setJobToCompleteResults(j1 *job.Job, filepath string, anxFilepath string) {
 	logging.Logger.Info().
 		Str(logging.ComponentField, componentName).
 		Str(loggingGUIDField, j1.GUID).
 		Msg("Setting job to complete with results")
 
-	j1.Progress.EndTime = time.Now()
-	j1.Progress.State = job.CompleteResults
-	j1.ResultFile = filepath
+	manifestFile, manifestWarnings := writeManifest(j.manifests, j.diskQuota, j.folder, j1.GUID,
+		filepath, j1.Configuration, j1.Provenance)
+
+	published, warnings := publishResults(j.publisher, shortestPathKind, j1.GUID,
+		[]string{filepath, anxFilepath, j1.CsvResultFile, manifestFile})
 
-	j.finishedExecutingJob(j1.GUID)
+	j.finish(j1, job.CompleteResults, nil, func() {
+		j1.ResultFile = filepath
+		j1.AnxResultFile = anxFilepath
+		j1.ManifestFile = manifestFile
+		j1.PublishedResults = published
+		j1.Warnings = append(j1.Warnings, manifestWarnings...)
+		j1.Warnings = append(j1.Warnings, warnings...)
+	})
 }
 
 // setJobToCompleteNoResults sets the job to complete (finished) where there weren't any results.
 func (j *JobRunner) setJobToCompleteNoResults(j1 *job.Job) {
-	j.jobsLock.Lock()
-	defer j.jobsLock.Unlock()
-
 	logging.Logger.Info().
 		Str(logging.ComponentField, componentName).
 		Str(loggingGUIDField, j1.GUID).
 		Msg("Setting job to complete with no results")
 
-	j1.Progress.EndTime = time.Now()
-	j1.Progress.State = job.CompleteNoResults
-	j1.Message = noPathsMessage
+	j.finish(j1, job.CompleteNoResults, nil, func() {
+		j1.Message = noPathsMessage
+	})
+}
 
-	j.finishedExecutingJob(j1.GUID)
+// addJobWarning adds a warning to present to the user.
+func (j *JobRunner) addJobWarning(j1 *job.Job, warning *i18n.Message) {
+	j.jobsLock.Lock()
+	defer j.jobsLock.Unlock()
+
+	j1.Warnings = append(j1.Warnings, warning)
 }
 
 // makeExcelFilepath for storage of the Excel file.
@@ -226,6 +270,113 @@ func makeExcelFilepath(folder string, guid string) string {
 	return path.Join(folder, fmt.Sprintf("%v.xlsx", guid))
 }
 
+// makePartialFilepath for storage of the Excel file of the partial results of a failed job.
+func makePartialFilepath(folder string, guid string) string {
+	return path.Join(folder, fmt.Sprintf("%v.partial.xlsx", guid))
+}
+
+// makeSummaryFilepath for storage of the summary of the connections.
+func makeSummaryFilepath(folder string, guid string) string {
+	return path.Join(folder, fmt.Sprintf("%v.summary.json", guid))
+}
+
+// writeSummary of the connections found by the job. The job can't be compared with another job if
+// the summary can't be written, but the job itself can still succeed.
+func (j *JobRunner) writeSummary(j1 *job.Job, conns *bfs.NetworkConnections) {
+
+	summary, err := jobdiff.Summarise(conns)
+	if err == nil {
+		filepath := makeSummaryFilepath(j.folder, j1.GUID)
+		err = j.diskQuota.writeResultFile(j.folder, filepath, func() error {
+			return jobdiff.WriteSummary(filepath, summary)
+		})
+		if err == nil {
+			j.jobsLock.Lock()
+			j1.SummaryFile = filepath
+			j.jobsLock.Unlock()
+			return
+		}
+	}
+
+	logging.Logger.Warn().
+		Str(logging.ComponentField, componentName).
+		Str(loggingGUIDField, j1.GUID).
+		Err(err).
+		Msg("Failed to write the summary of the connections")
+}
+
+// recordStatistics of the paths found by the job. The job doesn't fail if the statistics can't be
+// calculated, as they are only informational.
+func (j *JobRunner) recordStatistics(j1 *job.Job, conns *bfs.NetworkConnections) {
+
+	statistics, err := conns.Statistics()
+	if err != nil {
+		logging.Logger.Warn().
+			Str(logging.ComponentField, componentName).
+			Str(loggingGUIDField, j1.GUID).
+			Err(err).
+			Msg("Failed to calculate the statistics of the paths")
+		return
+	}
+
+	j.jobsLock.Lock()
+	j1.Statistics = statistics
+	j.jobsLock.Unlock()
+}
+
+// makeCsvFilepath for storage of the CSV file of a path matrix.
+func makeCsvFilepath(folder string, guid string) string {
+	return path.Join(folder, fmt.Sprintf("%v.csv", guid))
+}
+
+// writePathMatrix of the job as Excel and CSV files instead of building the i2 chart. The job is
+// complete with results if there is at least one pair of entities, even if they aren't connected.
+func (j *JobRunner) writePathMatrix(j1 *job.Job, conns *bfs.NetworkConnections) {
+
+	pairs, err := pathmatrix.Build(j1.Configuration.EntitySets, j1.Configuration.Directed, conns)
+	if err != nil {
+		j.setJobToFailed(j1, err)
+		return
+	}
+
+	if len(pairs) == 0 {
+		j.setJobToCompleteNoResults(j1)
+		return
+	}
+
+	rows := pathmatrix.Rows(pairs)
+
+	filepath := makeExcelFilepath(j.folder, j1.GUID)
+	err = j.diskQuota.writeResultFile(j.folder, filepath, func() error {
+		summary := append(overlapSummary(j1.Overlaps), provenanceSummary(j1.Provenance)...)
+		return i2chart.WriteToExcelWithSummary(filepath, rows, summary)
+	})
+	if err != nil {
+		j.setJobToFailed(j1, err)
+		return
+	}
+
+	csvFilepath := makeCsvFilepath(j.folder, j1.GUID)
+	err = j.diskQuota.writeResultFile(j.folder, csvFilepath, func() error {
+		return pathmatrix.WriteCsv(csvFilepath, rows)
+	})
+	if err != nil {
+		j.setJobToFailed(j1, err)
+		return
+	}
+
+	j.jobsLock.Lock()
+	j1.CsvResultFile = csvFilepath
+	j.jobsLock.Unlock()
+
+	j.setJobToCompleteResults(j1, filepath, "")
+}
+
+// makeAnxFilepath for storage of the ANX chart file.
+func makeAnxFilepath(folder string, guid string) string {
+	return path.Join(folder, fmt.Sprintf("%v.anx", guid))
+}
+
 func (j *JobRunner) entitySearch(j1 *job.Job) error {
 
 	j1.EntityResults = map[string]search.EntitySearchResult{}
@@ -244,6 +395,188 @@ func (j *JobRunner) entitySearch(j1 *job.Job) error {
 	return nil
 }
 
+// findPathsWithHops for the job given the maximum number of hops, in directed mode if requested.
+// The paths avoid the job's excluded entities, pass through one of its waypoints (if any) and only
+// follow the links supported by its minimum number of documents. In temporal mode, the documents
+// supporting the links of a path must be in date order. The search is abandoned if the context is
+// cancelled.
+func (j *JobRunner) findPathsWithHops(ctx context.Context, j1 *job.Job, maxHops int,
+	logger zerolog.Logger, explanation *bfs.Explanation) (*bfs.NetworkConnections, error) {
+
+	constraints := bfs.PathConstraints{
+		Directed:     j1.Configuration.Directed,
+		MinDocuments: j1.Configuration.MinDocumentsPerLink,
+		Temporal:     j1.Configuration.Temporal,
+	}
+
+	if len(j1.Configuration.ExcludedEntityIds) > 0 {
+		constraints.Excluded = set.NewPopulatedSet(j1.Configuration.ExcludedEntityIds...)
+	}
+
+	if len(j1.Configuration.Waypoints) > 0 {
+		constraints.Waypoints = set.NewPopulatedSet(j1.Configuration.Waypoints...)
+	}
+
+	return j.pathFinder.FindPathsWithExplanation(ctx, j1.Configuration.EntitySets, maxHops,
+		constraints, logger, j.checkpoint(j1), explanation)
+}
+
+// filterPaths of the job, keeping the paths with an intermediate entity whose attributes match the
+// job's path filter (if it has one). The user is warned how many paths were removed.
+func (j *JobRunner) filterPaths(j1 *job.Job, conns *bfs.NetworkConnections) error {
+
+	if len(j1.Configuration.PathFilter) == 0 {
+		return nil
+	}
+
+	filter, err := pathfilter.Parse(j1.Configuration.PathFilter)
+	if err != nil {
+		return err
+	}
+
+	removed, err := filter.Apply(conns, j.searchEngine.Bipartite)
+	if err != nil {
+		return err
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str(loggingGUIDField, j1.GUID).
+		Str("pathFilter", j1.Configuration.PathFilter).
+		Int("removedPaths", removed).
+		Msg("Filtered the paths of the job")
+
+	if removed > 0 {
+		j.addJobWarning(j1, filteredWarning(removed, j1.Configuration.PathFilter))
+	}
+
+	return nil
+}
+
+// checkpoint of the job's search, which is made if the job doesn't have one. A job only has a
+// checkpoint if checkpointing is enabled, the job has a timeout (so that the pairs of entities that
+// weren't searched are known) or the job is retried with fewer hops (so that the paths found before
+// there were too many are kept). Returns nil if the job doesn't need a checkpoint.
+func (j *JobRunner) checkpoint(j1 *job.Job) *bfs.Checkpoint {
+	if !j.checkpointing && jobTimeout(j.timeout, j1.Configuration.Timeout) == 0 &&
+		!j1.Configuration.RetryWithFewerHops {
+		return nil
+	}
+	guid := j1.GUID
+
+	j.jobsLock.Lock()
+	defer j.jobsLock.Unlock()
+
+	checkpoint, found := j.checkpoints[guid]
+	if !found {
+		checkpoint = bfs.NewCheckpoint()
+		j.checkpoints[guid] = checkpoint
+	}
+
+	return checkpoint
+}
+
+// removeCheckpoint of the job once its search has finished. The connections held by the checkpoint
+// are returned by the search, so they aren't closed.
+func (j *JobRunner) removeCheckpoint(guid string) {
+	j.jobsLock.Lock()
+	defer j.jobsLock.Unlock()
+
+	delete(j.checkpoints, guid)
+}
+
+// discardCheckpoint of a job whose search has failed and won't be resumed.
+func (j *JobRunner) discardCheckpoint(guid string) {
+	j.jobsLock.Lock()
+	defer j.jobsLock.Unlock()
+
+	if checkpoint, found := j.checkpoints[guid]; found {
+		checkpoint.Close()
+		delete(j.checkpoints, guid)
+	}
+}
+
+// writePartialResults of a job that failed whilst finding the paths (or that is retried with fewer
+// hops), from the paths found before the failure. The Excel file's summary flags that the results
+// are incomplete. Nothing is written if the job doesn't have a checkpoint or no paths were found.
+func (j *JobRunner) writePartialResults(j1 *job.Job, reason error, logger zerolog.Logger) {
+
+	j.jobsLock.RLock()
+	checkpoint, found := j.checkpoints[j1.GUID]
+	j.jobsLock.RUnlock()
+
+	if !found || checkpoint.Connections() == nil || !checkpoint.Connections().HasAnyConnections() {
+		return
+	}
+
+	var rows [][]string
+	var err error
+
+	if j1.Configuration.PathMatrix {
+		var pairs []pathmatrix.Pair
+		pairs, err = pathmatrix.Build(j1.Configuration.EntitySets, j1.Configuration.Directed,
+			checkpoint.Connections())
+		rows = pathmatrix.Rows(pairs)
+	} else {
+		rows, _, err = j.chartBuilder.BuildWithLogger(checkpoint.Connections(), logger)
+	}
+
+	if err == nil {
+		summary := append(i2chart.IncompleteSummary(reason.Error()), overlapSummary(j1.Overlaps)...)
+		summary = append(summary, provenanceSummary(j1.Provenance)...)
+
+		filepath := makePartialFilepath(j.folder, j1.GUID)
+		err = j.diskQuota.writeResultFile(j.folder, filepath, func() error {
+			return i2chart.WriteToExcelWithSummary(filepath, rows, summary)
+		})
+		if err == nil {
+			j.jobsLock.Lock()
+			j1.PartialResultFile = filepath
+			j.jobsLock.Unlock()
+			return
+		}
+	}
+
+	logging.Logger.Warn().
+		Str(logging.ComponentField, componentName).
+		Str(loggingGUIDField, j1.GUID).
+		Err(err).
+		Msg("Failed to write the partial results of the job")
+}
+
+// findPaths for the job, optionally retrying with one fewer hop if there are too many paths. The
+// paths found with the requested number of hops before there were too many are kept as the job's
+// partial results.
+func (j *JobRunner) findPaths(ctx context.Context, j1 *job.Job, logger zerolog.Logger,
+	explanation *bfs.Explanation) (*bfs.NetworkConnections, error) {
+
+	maxHops := j1.Configuration.MaxNumberHops
+	conns, err := j.findPathsWithHops(ctx, j1, maxHops, logger, explanation)
+
+	// Only retry on a path explosion if the user has requested it
+	if !errors.Is(err, bfs.ErrTooManyPaths) || !j1.Configuration.RetryWithFewerHops || maxHops <= 1 {
+		return conns, err
+	}
+
+	logging.Logger.Warn().
+		Str(logging.ComponentField, componentName).
+		Str(loggingGUIDField, j1.GUID).
+		Str("numberOfHops", strconv.Itoa(maxHops)).
+		Str("retryNumberOfHops", strconv.Itoa(maxHops-1)).
+		Msg("Too many paths found, retrying with fewer hops")
+
+	// The retry starts the checkpoint again, so the paths found so far are written first
+	j.writePartialResults(j1, err, logger)
+
+	conns, retryErr := j.findPathsWithHops(ctx, j1, maxHops-1, logger, explanation)
+	if retryErr != nil {
+		return nil, fmt.Errorf("%v hops: %v; %v hops: %w", maxHops, err, maxHops-1, retryErr)
+	}
+
+	j.addJobWarning(j1, retryWarning(maxHops, maxHops-1, err))
+	return conns, nil
+}
+
 // executeJob given the GUID of the job to execute.
 func (j *JobRunner) executeJob(guid string) {
 
@@ -257,16 +590,72 @@ func (j *JobRunner) executeJob(guid string) {
 		return
 	}
 
+	// Wait for an update of the stores to finish, so the job doesn't read a half-updated graph
+	j.waitForStores(guid)
+	defer j.storeLock.EndRead()
+
 	// Set the job to in progress
 	j.setJobToInProgress(job)
 
-	// Find the paths between entities
-	conns, err := j.pathFinder.FindPaths(job.Configuration.EntitySets, job.Configuration.MaxNumberHops)
-	if err != nil {
+	// Resolve the entity IDs that are aliases of entities
+	if err := j.resolveAliases(job); err != nil {
 		j.setJobToFailed(job, err)
 		return
 	}
 
+	// Logger for the detail of the job, which is only output if verbose logging was requested
+	logger := logging.NewJobLogger(guid, job.Configuration.VerboseLogging)
+
+	// Find the paths between entities, which are abandoned if the job times out
+	timeout := jobTimeout(j.timeout, job.Configuration.Timeout)
+	ctx, cancel := jobContext(timeout)
+	defer cancel()
+
+	// Record the expansion of the search if the job is in explain mode
+	explanation := newExplanation(job)
+
+	conns, err := j.findPaths(ctx, job, logger, explanation)
+	j.writeDiagnostics(job, explanation)
+
+	if err != nil && isTimeout(err) {
+		reason := timedOutError(timeout)
+		j.writePartialResults(job, reason, logger)
+		j.setJobToTimedOut(job, timeout, j.unsearchedPairs(job))
+		j.discardCheckpoint(guid)
+		return
+	} else if err != nil {
+		j.writePartialResults(job, err, logger)
+		j.setJobToFailed(job, err)
+		if !j.checkpointing {
+			j.discardCheckpoint(guid)
+		}
+		return
+	}
+	defer conns.Close()
+	j.removeCheckpoint(guid)
+
+	// Warn the user if the results are partial because the search was stopped
+	if conns.BudgetExhausted {
+		j.addJobWarning(job, budgetWarning(j.pathFinder.MaxExpansions()))
+	}
+
+	// Keep the paths through an entity that matches the job's filter
+	if err := j.filterPaths(job, conns); err != nil {
+		j.setJobToFailed(job, err)
+		return
+	}
+
+	// Summarise the connections, so the job can be compared with another job
+	j.writeSummary(job, conns)
+
+	// Record the statistics of the paths for the results page
+	j.recordStatistics(job, conns)
+
+	// Warn the user if paths between densely connected entities were omitted
+	if omitted := conns.NumberOfOmittedPaths(); omitted > 0 {
+		j.addJobWarning(job, sampledWarning(j.pathFinder.Sampling().MaxPathsPerPair, omitted))
+	}
+
 	// Search for the entities in the graph stores to provide diagnostic information
 	err = j.entitySearch(job)
 	if err != nil {
@@ -274,6 +663,12 @@ func (j *JobRunner) executeJob(guid string) {
 		return
 	}
 
+	// A path matrix replaces the i2 chart
+	if job.Configuration.PathMatrix {
+		j.writePathMatrix(job, conns)
+		return
+	}
+
 	// If there aren't any connections, there's no need to build the i2 chart
 	if !conns.HasAnyConnections() {
 		j.setJobToCompleteNoResults(job)
@@ -281,61 +676,124 @@ func (j *JobRunner) executeJob(guid string) {
 	}
 
 	// Build the i2 chart (as a table)
-	table, err := j.chartBuilder.Build(conns)
+	table, droppedRows, err := j.chartBuilder.BuildWithLogger(conns, logger)
 	if err != nil {
 		j.setJobToFailed(job, err)
 		return
 	}
 
+	// If the chart has too many rows, the user is warned and the Excel file has a summary
+	var summary [][]string
+	if droppedRows > 0 {
+		numberOfRows := len(table) - 1
+		summary = i2chart.TruncationSummary(numberOfRows, droppedRows, j.chartBuilder.MaxRows())
+		j.addJobWarning(job, truncatedWarning(numberOfRows, droppedRows))
+	}
+	summary = append(summary, overlapSummary(job.Overlaps)...)
+	summary = append(summary, provenanceSummary(job.Provenance)...)
+
 	// Make the filepath for the Excel file
 	filepath := makeExcelFilepath(j.folder, guid)
 
 	// Save the table in an Excel file
-	err = i2chart.WriteToExcel(filepath, table)
+	err = j.diskQuota.writeResultFile(j.folder, filepath, func() error {
+		return i2chart.WriteToExcelWithSummary(filepath, table, summary)
+	})
 	if err != nil {
 		j.setJobToFailed(job, err)
 		return
 	}
 
-	j.setJobToCompleteResults(job, filepath)
+	// Convert the table to an i2 chart and save it in an ANX file (if ANX charts are enabled)
+	anxFilepath := ""
+	if j.chartBuilder.AnxEnabled() {
+		anxFilepath = j.writeAnx(job, table)
+	}
+
+	j.setJobToCompleteResults(job, filepath, anxFilepath)
 }
 
-// GetJob from the job runner in a thread-safe manner. The returned job should not be modified.
-func (j *JobRunner) GetJob(guid string) (*job.Job, error) {
+// writeAnx converts the table of the job's chart to an ANX chart and saves it. The Excel file holds
+// the results, so if the ANX chart can't be built or saved the user is warned, rather than the job
+// failing, and a blank filepath is returned so the ANX file isn't offered for download.
+func (j *JobRunner) writeAnx(j1 *job.Job, table [][]string) string {
 
-	// Get a lock to be able to read the jobs map
-	j.jobsLock.RLock()
-	defer j.jobsLock.RUnlock()
+	anxFilepath := makeAnxFilepath(j.folder, j1.GUID)
 
-	// Try to fetch the job
-	job, found := j.jobs[guid]
-	if !found {
-		return nil, ErrJobNotFound
+	chart, err := j.chartBuilder.TableToAnx(table)
+	if err == nil {
+		err = j.diskQuota.writeResultFile(j.folder, anxFilepath, func() error {
+			return i2chart.WriteToAnx(anxFilepath, chart)
+		})
 	}
 
-	return job, nil
-}
+	if err != nil {
+		logging.Logger.Warn().
+			Str(logging.ComponentField, componentName).
+			Str(loggingGUIDField, j1.GUID).
+			Err(err).
+			Msg("Failed to write the ANX chart of the job")
 
-// IsJobFinished given the job's GUID.
-func (j *JobRunner) IsJobFinished(guid string) (bool, error) {
+		j.addJobWarning(j1, anxWarning())
+		return ""
+	}
 
-	// Get a lock to be able to read the jobs map
-	j.jobsLock.RLock()
-	defer j.jobsLock.RUnlock()
+	return anxFilepath
+}
+
+// resetFailedJob so that it can be executed again. Returns the location of the job's partial results
+// (if any), which are replaced when the job is executed.
+func (j *JobRunner) resetFailedJob(guid string) (string, error) {
+	j.jobsLock.Lock()
+	defer j.jobsLock.Unlock()
 
-	// Try to fetch the job
 	j1, found := j.jobs[guid]
 	if !found {
-		return false, ErrJobNotFound
+		return "", ErrJobNotFound
 	}
 
-	// If the job is in an end state, it is finished
-	if j1.Progress.State == job.Failed ||
-		j1.Progress.State == job.CompleteNoResults ||
-		j1.Progress.State == job.CompleteResults {
+	if j1.Progress.State != job.Failed {
+		return "", ErrJobNotRetryable
+	}
 
-		return true, nil
-	} else {
-		return false, nil
+	partialFile := j1.PartialResultFile
+
+	j1.Progress = job.NewJobProgress()
+	j1.Error = nil
+	j1.Warnings = nil
+	j1.PartialResultFile = ""
+	j1.DiagnosticsFile = ""
+
+	j.events.publish(guid, newJobEvent(j1.Progress.State))
+	return partialFile, nil
+}
+
+// Retry a failed job. If the job has a checkpoint, the search for paths resumes from where it
+// failed.
+func (j *JobRunner) Retry(guid string) error {
+
+	partialFile, err := j.resetFailedJob(guid)
+	if err != nil {
+		return err
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str(loggingGUIDField, guid).
+		Msg("Retrying job")
+
+	if len(partialFile) > 0 {
+		if err := os.Remove(partialFile); err != nil {
+			logging.Logger.Warn().
+				Str(logging.ComponentField, componentName).
+				Str(loggingGUIDField, guid).
+				Err(err).
+				Msg("Failed to remove the partial results of the job")
+		}
 	}
+
+	j.goingToExecuteJob(guid)
+	go j.executeJob(guid)
+
+	return nil
 }
@@ -3,11 +3,15 @@ package server
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/cdclaxton/shortest-path-web-app/bfs"
+	"github.com/cdclaxton/shortest-path-web-app/filedetector"
+	"github.com/cdclaxton/shortest-path-web-app/i18n"
 	"github.com/cdclaxton/shortest-path-web-app/i2chart"
 	"github.com/cdclaxton/shortest-path-web-app/job"
 	"github.com/cdclaxton/shortest-path-web-app/search"
+	"github.com/cdclaxton/shortest-path-web-app/set"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -47,6 +51,11 @@ func TestMakeExcelFilepath(t *testing.T) {
 	assert.Equal(t, "../data/output/1234.xlsx", result)
 }
 
+func TestMakeAnxFilepath(t *testing.T) {
+	result := makeAnxFilepath("../data/output", "1234")
+	assert.Equal(t, "../data/output/1234.anx", result)
+}
+
 func checkJob(t *testing.T, j1 *job.Job,
 	expectedGUID string, expectedConfiguration *job.JobConfiguration,
 	expectedJobState job.JobState, shouldHaveResultsFile bool,
@@ -72,8 +81,10 @@ func checkJob(t *testing.T, j1 *job.Job,
 	// Check whether there is a results file to download
 	if shouldHaveResultsFile {
 		assert.True(t, len(j1.ResultFile) > 0)
+		assert.True(t, len(j1.AnxResultFile) > 0)
 	} else {
 		assert.Equal(t, "", j1.ResultFile)
+		assert.Equal(t, "", j1.AnxResultFile)
 	}
 
 	// Check the message
@@ -163,3 +174,164 @@ func TestSubmitJob(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, expectedTable, actualTable)
 }
+
+func TestSubmitJobRetryWithFewerHops(t *testing.T) {
+	runner, _ := makeJobRunner(t)
+	defer cleanUpJobRunner(t, runner)
+
+	// With 3 hops there are three paths and with 2 hops there are two paths
+	// The graph can be found in ../test-data-sets/set-1/readme.md
+	assert.NoError(t, runner.pathFinder.SetMaxPaths(2))
+
+	entitySets := []job.EntitySet{
+		{
+			Name:      "Set-1",
+			EntityIds: []string{"e-1", "e-2", "e-4"},
+		},
+	}
+
+	// Without a retry, the job should fail
+	conf, err := job.NewJobConfiguration(entitySets, 3)
+	assert.NoError(t, err)
+
+	guid, err := runner.Submit(conf)
+	assert.NoError(t, err)
+	waitForJobsToFinish(runner)
+
+	j1, err := runner.GetJob(guid)
+	assert.NoError(t, err)
+	checkJob(t, j1, guid, conf, job.Failed, false, "", true)
+	assert.ErrorIs(t, j1.Error, bfs.ErrTooManyPaths)
+	assert.Empty(t, j1.Warnings)
+
+	// With a retry, the job should complete with the results for 2 hops
+	conf.RetryWithFewerHops = true
+
+	guid, err = runner.Submit(conf)
+	assert.NoError(t, err)
+	waitForJobsToFinish(runner)
+
+	j1, err = runner.GetJob(guid)
+	assert.NoError(t, err)
+	checkJob(t, j1, guid, conf, job.CompleteResults, true, "", false)
+	assert.Equal(t, []*i18n.Message{retryWarning(3, 2, bfs.ErrTooManyPaths)}, j1.Warnings)
+
+	// The paths found with 3 hops before there were too many are kept as partial results
+	assert.FileExists(t, j1.PartialResultFile)
+
+	summary, err := i2chart.ReadFromExcel(j1.PartialResultFile, "Summary")
+	assert.NoError(t, err)
+	assert.Contains(t, summary, []string{"Reason", bfs.ErrTooManyPaths.Error()})
+}
+
+func TestJobWithExhaustedBudget(t *testing.T) {
+	runner, _ := makeJobRunner(t)
+	defer cleanUpJobRunner(t, runner)
+
+	entitySets := []job.EntitySet{
+		{
+			Name:      "Set-1",
+			EntityIds: []string{"e-1", "e-2", "e-4"},
+		},
+	}
+
+	conf, err := job.NewJobConfiguration(entitySets, 3)
+	assert.NoError(t, err)
+
+	// The search is stopped before any paths are found, but the job doesn't fail
+	assert.NoError(t, runner.pathFinder.SetMaxExpansions(1))
+
+	guid, err := runner.Submit(conf)
+	assert.NoError(t, err)
+	waitForJobsToFinish(runner)
+
+	j1, err := runner.GetJob(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, job.CompleteNoResults, j1.Progress.State)
+	assert.Equal(t, []*i18n.Message{budgetWarning(1)}, j1.Warnings)
+
+	// A budget large enough for the search doesn't warn the user
+	assert.NoError(t, runner.pathFinder.SetMaxExpansions(1000))
+
+	guid, err = runner.Submit(conf)
+	assert.NoError(t, err)
+	waitForJobsToFinish(runner)
+
+	j1, err = runner.GetJob(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, job.CompleteResults, j1.Progress.State)
+	assert.Empty(t, j1.Warnings)
+}
+
+func TestJobWithTruncatedChart(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	assert.NoError(t, server.runner.chartBuilder.SetMaxRows(1))
+
+	// The entities are linked by two rows, so one is dropped
+	guid := submitAndWait(t, server, "e-1, e-2, e-3")
+
+	j1, err := server.runner.GetJob(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, job.CompleteResults, j1.Progress.State)
+	assert.Equal(t, []*i18n.Message{truncatedWarning(1, 1)}, j1.Warnings)
+
+	rows, err := i2chart.ReadFromExcel(j1.ResultFile, "Sheet1")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(rows))
+
+	summary, err := i2chart.ReadFromExcel(j1.ResultFile, "Summary")
+	assert.NoError(t, err)
+	assert.Equal(t, i2chart.TruncationSummary(1, 1, 1), summary)
+
+	// The warning is shown on the results page
+	w := getPage(server.Routes(), "/job/"+guid)
+	assert.Contains(t, w.Body.String(), "The chart has been limited to 1 rows, so 1 rows were dropped.")
+}
+
+func TestJobsWithProvenance(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	provenance := filedetector.DataProvenance{
+		Signature:   "abc123",
+		SourceFiles: []string{"documents.csv", "links.csv"},
+		Loaded:      time.Date(2023, 5, 1, 9, 30, 0, 0, time.UTC),
+	}
+	server.runner.SetProvenance(provenance)
+	server.spiderRunner.SetProvenance(provenance)
+
+	expected := i2chart.ProvenanceSummary(provenance.Signature, provenance.SourceFiles, provenance.Loaded)
+
+	// Shortest path job
+	guid := submitAndWait(t, server, "e-1, e-2")
+
+	j1, err := server.runner.GetJob(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, job.CompleteResults, j1.Progress.State)
+	assert.Equal(t, provenance, j1.Provenance)
+
+	summary, err := i2chart.ReadFromExcel(j1.ResultFile, "Summary")
+	assert.NoError(t, err)
+	assert.Equal(t, expected, summary)
+
+	// Spider job
+	guid, err = server.spiderRunner.Submit(&job.SpiderJobConfiguration{
+		NumberSteps:  1,
+		SeedEntities: set.NewPopulatedSet("e-1"),
+	})
+	assert.NoError(t, err)
+	waitForSpiderJobsToFinish(server.spiderRunner)
+
+	j2, err := server.spiderRunner.GetJob(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, job.CompleteResults, j2.Progress.State)
+	assert.Equal(t, provenance, j2.Provenance)
+
+	summary, err = i2chart.ReadFromExcel(j2.ResultFile, "Summary")
+	assert.NoError(t, err)
+	assert.Equal(t, expected, summary)
+}
@@ -0,0 +1,65 @@
+// The statistics of the paths found by a job are shown on its results page, i.e. a histogram of
+// the number of hops of the paths and the shortest distance and number of paths for each pair of
+// entities. They help analysts to decide whether increasing the number of hops is worthwhile.
+
+package server
+
+import (
+	"github.com/cdclaxton/shortest-path-web-app/job"
+)
+
+// Maximum number of pairs of entities shown in the table of the results page
+const maxStatisticsPairs = 500
+
+// HopCountDisplay is a bar of the histogram of the number of hops of the paths.
+type HopCountDisplay struct {
+	Hops          int
+	NumberOfPaths int
+	Percentage    int // Percentage of the paths with the number of hops
+}
+
+// StatisticsDisplay holds the statistics of the paths presented to the user.
+type StatisticsDisplay struct {
+	Histogram []HopCountDisplay
+	Pairs     []job.PairStatistics
+	Truncated string // Warning that not all of the pairs are shown (if required)
+	Sampled   bool   // Were any of the paths omitted by sampling?
+}
+
+// prepareStatistics of the paths for display in the language. Nil is returned if the job doesn't
+// have statistics.
+func (j *JobServer) prepareStatistics(statistics *job.PathStatistics,
+	language string) *StatisticsDisplay {
+
+	if statistics == nil {
+		return nil
+	}
+
+	display := StatisticsDisplay{
+		Histogram: []HopCountDisplay{},
+		Pairs:     statistics.Pairs,
+		Sampled:   statistics.NumberOfOmittedPaths() > 0,
+	}
+
+	total := statistics.NumberOfPaths()
+	for idx, count := range statistics.HopHistogram {
+		percentage := 0
+		if total > 0 {
+			percentage = (100 * count) / total
+		}
+
+		display.Histogram = append(display.Histogram, HopCountDisplay{
+			Hops:          idx + 1,
+			NumberOfPaths: count,
+			Percentage:    percentage,
+		})
+	}
+
+	if len(display.Pairs) > maxStatisticsPairs {
+		display.Pairs = display.Pairs[:maxStatisticsPairs]
+		display.Truncated = j.translator.Translate(language, "statistics.truncated",
+			maxStatisticsPairs, len(statistics.Pairs))
+	}
+
+	return &display
+}
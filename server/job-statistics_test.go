@@ -0,0 +1,69 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobStatistics(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	handler := server.Routes()
+
+	guid := submitAndWait(t, server, "e-1, e-2")
+
+	j1, err := server.runner.GetJob(guid)
+	assert.NoError(t, err)
+	assert.NotNil(t, j1.Statistics)
+	assert.Equal(t, 1, len(j1.Statistics.HopHistogram))
+	assert.True(t, len(j1.Statistics.Pairs) > 0)
+
+	// The results page shows the statistics
+	w := getPage(handler, "/job/"+guid)
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, "Paths by number of hops")
+	assert.Contains(t, body, "Shortest distance (hops)")
+	assert.Contains(t, body, "width: 100%")
+
+	// The statistics are in the job's status
+	w = getPage(handler, "/job/"+guid+"?format=json")
+	var status JobStatus
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.Equal(t, j1.Statistics, status.Statistics)
+}
+
+func TestPrepareStatistics(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	assert.Nil(t, server.prepareStatistics(nil, "en"))
+
+	statistics := job.NewPathStatistics(2)
+	for idx := 0; idx < maxStatisticsPairs+1; idx++ {
+		statistics.AddPair("e-1", strings.Repeat("e", idx+1), []int{2}, 0)
+	}
+	statistics.AddPair("e-1", "e-0", []int{1, 2, 2}, 0)
+
+	display := server.prepareStatistics(statistics, "en")
+	assert.Equal(t, []HopCountDisplay{
+		{Hops: 1, NumberOfPaths: 1, Percentage: 0},
+		{Hops: 2, NumberOfPaths: maxStatisticsPairs + 3, Percentage: 99},
+	}, display.Histogram)
+	assert.Equal(t, maxStatisticsPairs, len(display.Pairs))
+	assert.Contains(t, display.Truncated, "Only the first 500 of 502 pairs")
+	assert.False(t, display.Sampled)
+
+	// Paths omitted by sampling
+	statistics.AddPair("e-2", "e-3", []int{1}, 10)
+	assert.True(t, server.prepareStatistics(statistics, "en").Sampled)
+}
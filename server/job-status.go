@@ -0,0 +1,136 @@
+// The status of a job can be returned as JSON (by adding format=json to the job's URL), so that other
+// services can poll a job they submitted and download its results when it finishes.
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/cdclaxton/shortest-path-web-app/i18n"
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+)
+
+// JobFormatInputName is the name of the query parameter for the format of a job's status
+const JobFormatInputName = "format"
+
+// JobStatus is the status of a shortest path or spider job returned as JSON.
+type JobStatus struct {
+	Guid     string       `json:"guid"`               // GUID of the job
+	State    job.JobState `json:"state"`              // State of the job
+	Finished bool         `json:"finished"`           // Is the job in an end state?
+	Message  string       `json:"message"`            // State for the user in their language
+	Error    string       `json:"error,omitempty"`    // Reason the job failed or couldn't be found
+	Warnings []string     `json:"warnings"`           // Warnings, e.g. the job was retried
+	Download string       `json:"download,omitempty"` // URL of the Excel file of the results
+	Partial  string       `json:"partial,omitempty"`  // URL of the partial results of a failed or retried job
+
+	Diagnostics string   `json:"diagnostics,omitempty"` // URL of the diagnostics of a job in explain mode
+	Published   []string `json:"published,omitempty"`   // URLs of the result files in object storage
+
+	Statistics *job.PathStatistics `json:"statistics,omitempty"` // Statistics of the paths found
+}
+
+// wantsJobStatus returns true if the job's status was requested as JSON.
+func wantsJobStatus(req *http.Request) bool {
+	return req.URL.Query().Get(JobFormatInputName) == jsonFormat
+}
+
+// writeJobStatus as JSON with the HTTP status code.
+func writeJobStatus(w http.ResponseWriter, code int, status JobStatus) {
+	if status.Warnings == nil {
+		status.Warnings = []string{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(status); err != nil {
+		logging.Logger.Error().
+			Str(logging.ComponentField, componentName).
+			Str(loggingGUIDField, status.Guid).
+			Err(err).
+			Msg("Failed to write the job status")
+	}
+}
+
+// jobNotFoundStatus is the status of a job that couldn't be found.
+func (j *JobServer) jobNotFoundStatus(guid string, language string) JobStatus {
+	return JobStatus{
+		Guid:  guid,
+		Error: j.translator.TranslateError(language, i18n.Wrap(ErrJobNotFound, "error.jobNotFound", guid)),
+	}
+}
+
+// handleJobStatus returns the status of a shortest path job as JSON.
+func (j *JobServer) handleJobStatus(w http.ResponseWriter, req *http.Request, guid string) {
+
+	settings := j.pageSettings(w, req)
+
+	j1, err := j.runner.GetJob(guid)
+	if err != nil {
+		writeJobStatus(w, http.StatusNotFound, j.jobNotFoundStatus(guid, settings.language))
+		return
+	}
+
+	status := JobStatus{
+		Guid:     guid,
+		State:    j1.Progress.State,
+		Finished: isFinishedState(j1.Progress.State),
+		Message:  j.translator.Translate(settings.language, jobStateKeys[j1.Progress.State]),
+		Error:    j.translator.TranslateError(settings.language, j1.Error),
+		Warnings: j.translator.TranslateMessages(settings.language, j1.Warnings),
+
+		Statistics: j1.Statistics,
+	}
+
+	if j1.Progress.State == job.CompleteResults {
+		status.Download = fmt.Sprintf("%v/download/%v", j.basePath, guid)
+	}
+
+	if len(j1.PartialResultFile) > 0 {
+		status.Partial = fmt.Sprintf("%v/download-partial/%v", j.basePath, guid)
+	}
+
+	if len(j1.DiagnosticsFile) > 0 {
+		status.Diagnostics = fmt.Sprintf("%v/download-diagnostics/%v", j.basePath, guid)
+	}
+
+	if len(j1.PublishedResults) > 0 {
+		status.Published = publishedUrls(j1.PublishedResults)
+	}
+
+	writeJobStatus(w, http.StatusOK, status)
+}
+
+// spiderHandleJobStatus returns the status of a spider job as JSON.
+func (j *JobServer) spiderHandleJobStatus(w http.ResponseWriter, req *http.Request, guid string) {
+
+	settings := j.pageSettings(w, req)
+
+	j1, err := j.spiderRunner.GetJob(guid)
+	if err != nil {
+		writeJobStatus(w, http.StatusNotFound, j.jobNotFoundStatus(guid, settings.language))
+		return
+	}
+
+	status := JobStatus{
+		Guid:     guid,
+		State:    j1.Progress.State,
+		Finished: isFinishedState(j1.Progress.State),
+		Message:  j.translator.Translate(settings.language, jobStateKeys[j1.Progress.State]),
+		Error:    j.translator.TranslateError(settings.language, j1.Error),
+		Warnings: j.translator.TranslateMessages(settings.language, j1.Warnings),
+	}
+
+	if j1.Progress.State == job.CompleteResults {
+		status.Download = fmt.Sprintf("%v/spider-download/%v", j.basePath, guid)
+	}
+
+	if len(j1.PublishedResults) > 0 {
+		status.Published = publishedUrls(j1.PublishedResults)
+	}
+
+	writeJobStatus(w, http.StatusOK, status)
+}
@@ -0,0 +1,276 @@
+// Job templates are job configurations saved under a name, so that analysts can run a job again
+// (e.g. with a different number of hops) without re-entering the entity IDs.
+
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/cdclaxton/shortest-path-web-app/i18n"
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+)
+
+// Names of the inputs and query parameters for job templates
+const (
+	JobTemplateNameInputName = "templateName" // Name of a job template
+	JobGuidInputName         = "guid"         // GUID of the job to save as a template
+	RerunInputName           = "rerun"        // GUID of the job to pre-populate the index page with
+	TemplateInputName        = "template"     // Name of the template to pre-populate the index page with
+	jobTemplatesUrl          = "/job-templates"
+	jobTemplateTimeFormat    = "2006-01-02 15:04:05 MST"
+)
+
+var ErrJobTemplateStoreIsNil = errors.New("job template store is nil")
+
+// SetJobTemplateStore in which the job templates are saved.
+func (j *JobServer) SetJobTemplateStore(store *job.JobTemplateStore) error {
+
+	// Precondition
+	if store == nil {
+		return ErrJobTemplateStoreIsNil
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Int("numberOfTemplates", len(store.List())).
+		Msg("Setting the job template store")
+
+	j.jobTemplates = store
+	return nil
+}
+
+// prepareForm returns the values to pre-populate the index page's form with the job configuration.
+func prepareForm(conf *job.JobConfiguration, source string) map[string]interface{} {
+
+	form := map[string]interface{}{
+		"source":             source,
+		"numberHops":         conf.MaxNumberHops,
+		"retryWithFewerHops": conf.RetryWithFewerHops,
+		"directed":           conf.Directed,
+		"pathMatrix":         conf.PathMatrix,
+		"temporal":           conf.Temporal,
+		"explain":            conf.Explain,
+	}
+
+	if conf.MinDocumentsPerLink > 0 {
+		form["minDocumentsPerLink"] = conf.MinDocumentsPerLink
+	}
+
+	if conf.Timeout > 0 {
+		form["timeoutMinutes"] = int(conf.Timeout.Minutes())
+	}
+
+	if len(conf.ExcludedEntityIds) > 0 {
+		form["excludeEntities"] = strings.Join(conf.ExcludedEntityIds, "\n")
+	}
+
+	if len(conf.Waypoints) > 0 {
+		form["waypoints"] = strings.Join(conf.Waypoints, "\n")
+	}
+
+	if len(conf.PathFilter) > 0 {
+		form["pathFilter"] = conf.PathFilter
+	}
+
+	return form
+}
+
+// prepopulatedConfiguration returns the job configuration to pre-populate the index page with and
+// a description of where it came from, i.e. a previous job or a saved template.
+func (j *JobServer) prepopulatedConfiguration(req *http.Request, language string) (
+	*job.JobConfiguration, string, error) {
+
+	if guid := req.URL.Query().Get(RerunInputName); len(guid) > 0 {
+		j1, err := j.runner.GetJob(guid)
+		if err != nil {
+			return nil, "", i18n.Wrap(err, "error.jobNotFound", guid)
+		}
+
+		return j1.Configuration, j.translator.Translate(language, "jobTemplates.fromJob", guid), nil
+	}
+
+	name := req.URL.Query().Get(TemplateInputName)
+	template, err := j.jobTemplates.Get(name)
+	if err != nil {
+		return nil, "", i18n.Wrap(err, "error.jobTemplateNotFound", name)
+	}
+
+	return &template.Configuration,
+		j.translator.Translate(language, "jobTemplates.fromTemplate", template.Name), nil
+}
+
+// isPrepopulated returns true if the index page should be pre-populated for the request.
+func isPrepopulated(req *http.Request) bool {
+	query := req.URL.Query()
+	return len(query.Get(RerunInputName)) > 0 || len(query.Get(TemplateInputName)) > 0
+}
+
+// prepopulatedIndex returns the index page with the form pre-populated from a previous job or a
+// saved template.
+func (j *JobServer) prepopulatedIndex(w http.ResponseWriter, req *http.Request) {
+
+	settings := j.pageSettings(w, req)
+
+	conf, source, err := j.prepopulatedConfiguration(req, settings.language)
+	if err != nil {
+		logging.Logger.Warn().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Unable to pre-populate the index page")
+
+		w.WriteHeader(http.StatusNotFound)
+		page := j.render(j.inputProblemTemplate, settings, map[string]string{
+			"reason": j.translator.TranslateError(settings.language, err),
+		})
+		fmt.Fprint(w, page)
+		return
+	}
+
+	form := prepareForm(conf, source)
+	if req.URL.Query().Get(AllowDuplicateInputName) == "true" {
+		form[AllowDuplicateInputName] = true
+	}
+
+	ctx := j.indexContext()
+	ctx["form"] = form
+	ctx["datasets"] = prepareDatasets(conf, j.maxDatasets)
+
+	page := j.render(j.indexTemplate, settings, ctx)
+	fmt.Fprint(w, withCsrfToken(w, req, page))
+}
+
+// JobTemplateDisplay is a job template presented to the user.
+type JobTemplateDisplay struct {
+	Name         string
+	NumberOfHops int
+	Datasets     string
+	Saved        string
+	LoadUrl      string
+}
+
+// prepareJobTemplates for display.
+func prepareJobTemplates(templates []job.JobTemplate) []JobTemplateDisplay {
+
+	display := []JobTemplateDisplay{}
+	for _, template := range templates {
+		datasets := []string{}
+		for _, entitySet := range template.Configuration.EntitySets {
+			datasets = append(datasets, fmt.Sprintf("%v (%d)", entitySet.Name, len(entitySet.EntityIds)))
+		}
+
+		display = append(display, JobTemplateDisplay{
+			Name:         template.Name,
+			NumberOfHops: template.Configuration.MaxNumberHops,
+			Datasets:     strings.Join(datasets, ", "),
+			Saved:        template.Saved.Format(jobTemplateTimeFormat),
+			LoadUrl:      "./?" + TemplateInputName + "=" + url.QueryEscape(template.Name),
+		})
+	}
+
+	return display
+}
+
+// handleJobTemplates returns the page listing the saved job templates.
+func (j *JobServer) handleJobTemplates(w http.ResponseWriter, req *http.Request) {
+
+	settings := j.pageSettings(w, req)
+
+	page := j.render(j.jobTemplatesTemplate, settings, map[string]interface{}{
+		"templates": prepareJobTemplates(j.jobTemplates.List()),
+	})
+	fmt.Fprint(w, page)
+}
+
+// handleSaveJobTemplate saves the configuration of a job as a template.
+func (j *JobServer) handleSaveJobTemplate(w http.ResponseWriter, req *http.Request) {
+
+	settings := j.pageSettings(w, req)
+
+	if req.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	guid := req.FormValue(JobGuidInputName)
+	name := req.FormValue(JobTemplateNameInputName)
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str(loggingGUIDField, guid).
+		Str("templateName", name).
+		Msg("Saving job as a template")
+
+	j1, err := j.runner.GetJob(guid)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		page := j.render(j.inputProblemTemplate, settings, map[string]string{
+			"reason": j.translator.TranslateError(settings.language,
+				i18n.Wrap(err, "error.jobNotFound", guid)),
+		})
+		fmt.Fprint(w, page)
+		return
+	}
+
+	err = j.jobTemplates.Save(name, j1.Configuration)
+	if errors.Is(err, job.ErrJobTemplateNameEmpty) || errors.Is(err, job.ErrJobTemplateNameTooLong) {
+		w.WriteHeader(http.StatusBadRequest)
+		page := j.render(j.inputProblemTemplate, settings, map[string]string{
+			"reason": j.translator.TranslateError(settings.language,
+				i18n.Wrap(err, "error.jobTemplateName", job.MaxJobTemplateNameLength)),
+		})
+		fmt.Fprint(w, page)
+		return
+	} else if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		page := j.render(j.errorTemplate, settings, map[string]string{
+			"reason": j.translator.TranslateError(settings.language, err),
+		})
+		fmt.Fprint(w, page)
+		return
+	}
+
+	http.Redirect(w, req, j.basePath+jobTemplatesUrl, http.StatusFound)
+}
+
+// handleDeleteJobTemplate deletes a saved job template.
+func (j *JobServer) handleDeleteJobTemplate(w http.ResponseWriter, req *http.Request) {
+
+	settings := j.pageSettings(w, req)
+
+	if req.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := req.FormValue(JobTemplateNameInputName)
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("templateName", name).
+		Msg("Deleting job template")
+
+	err := j.jobTemplates.Delete(name)
+	if errors.Is(err, job.ErrJobTemplateNotFound) {
+		w.WriteHeader(http.StatusNotFound)
+		page := j.render(j.inputProblemTemplate, settings, map[string]string{
+			"reason": j.translator.TranslateError(settings.language,
+				i18n.Wrap(err, "error.jobTemplateNotFound", name)),
+		})
+		fmt.Fprint(w, page)
+		return
+	} else if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		page := j.render(j.errorTemplate, settings, map[string]string{
+			"reason": j.translator.TranslateError(settings.language, err),
+		})
+		fmt.Fprint(w, page)
+		return
+	}
+
+	http.Redirect(w, req, j.basePath+jobTemplatesUrl, http.StatusFound)
+}
@@ -0,0 +1,230 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPrepareForm(t *testing.T) {
+
+	conf := &job.JobConfiguration{
+		MaxNumberHops:      3,
+		RetryWithFewerHops: true,
+		EntitySets: []job.EntitySet{
+			{Name: "Dataset-1", EntityIds: []string{"e-1", "e-2"}},
+			{Name: "Dataset-2", EntityIds: []string{"e-3"}},
+		},
+	}
+
+	expected := map[string]interface{}{
+		"source":             "From job 1234",
+		"numberHops":         3,
+		"retryWithFewerHops": true,
+		"directed":           false,
+		"pathMatrix":         false,
+		"temporal":           false,
+		"explain":            false,
+	}
+
+	assert.Equal(t, expected, prepareForm(conf, "From job 1234"))
+
+	// Entities to avoid
+	conf.ExcludedEntityIds = []string{"e-8", "e-9"}
+	expected["excludeEntities"] = "e-8\ne-9"
+	assert.Equal(t, expected, prepareForm(conf, "From job 1234"))
+
+	// Entities to pass through
+	conf.Waypoints = []string{"e-7"}
+	expected["waypoints"] = "e-7"
+	assert.Equal(t, expected, prepareForm(conf, "From job 1234"))
+
+	// Path matrix
+	conf.PathMatrix = true
+	expected["pathMatrix"] = true
+	assert.Equal(t, expected, prepareForm(conf, "From job 1234"))
+
+	// Explain mode
+	conf.Explain = true
+	expected["explain"] = true
+	assert.Equal(t, expected, prepareForm(conf, "From job 1234"))
+
+	// Path filter
+	conf.PathFilter = "Region = North"
+	expected["pathFilter"] = "Region = North"
+	assert.Equal(t, expected, prepareForm(conf, "From job 1234"))
+}
+
+func TestPrepareDatasets(t *testing.T) {
+
+	// The initial datasets are shown without a job configuration
+	expected := []DatasetDisplay{
+		{Index: "1", Optional: false},
+		{Index: "2", Optional: true},
+		{Index: "3", Optional: true},
+	}
+	assert.Equal(t, expected, prepareDatasets(nil, DefaultMaxDatasets))
+	assert.Equal(t, expected[:2], prepareDatasets(nil, 2))
+
+	// The datasets are pre-populated from the job configuration
+	conf := &job.JobConfiguration{
+		EntitySets: []job.EntitySet{
+			{Name: "Dataset-1", EntityIds: []string{"e-1", "e-2"}},
+			{Name: "Dataset-2", EntityIds: []string{"e-3"}},
+		},
+	}
+
+	expected[0].Name = "Dataset-1"
+	expected[0].EntityIds = "e-1\ne-2"
+	expected[1].Name = "Dataset-2"
+	expected[1].EntityIds = "e-3"
+	assert.Equal(t, expected, prepareDatasets(conf, DefaultMaxDatasets))
+
+	// All of the datasets of the job configuration are shown, up to the maximum
+	for idx := 3; idx <= 5; idx++ {
+		conf.EntitySets = append(conf.EntitySets, job.EntitySet{
+			Name:      fmt.Sprintf("Dataset-%d", idx),
+			EntityIds: []string{fmt.Sprintf("e-%d", idx)},
+		})
+	}
+
+	datasets := prepareDatasets(conf, 10)
+	assert.Len(t, datasets, 5)
+	assert.Equal(t, DatasetDisplay{Index: "5", Optional: true, Name: "Dataset-5", EntityIds: "e-5"},
+		datasets[4])
+	assert.Len(t, prepareDatasets(conf, 4), 4)
+}
+
+func TestSetJobTemplateStore(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	assert.ErrorIs(t, server.SetJobTemplateStore(nil), ErrJobTemplateStoreIsNil)
+
+	store, err := job.NewJobTemplateStore("")
+	assert.NoError(t, err)
+	assert.NoError(t, server.SetJobTemplateStore(store))
+}
+
+// postForm to the handler and return the response.
+func postForm(handler http.Handler, url string, form url.Values) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, url, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+// getPage from the handler and return the response.
+func getPage(handler http.Handler, url string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, url, nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestRerunAndJobTemplates(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	handler := server.Routes()
+
+	// Run a job
+	form := buildFormData(2, "Dataset-1", "e-1, e-2", "", "", "", "")
+	w := postForm(handler, "/upload", form)
+	assert.Equal(t, http.StatusFound, w.Code)
+
+	location := w.Result().Header.Get("Location")
+	guid := extractGuidFromLocation(t, location)
+	waitForJobsToFinish(server.runner)
+
+	// The results page can re-run the job or save it as a template
+	w = getPage(handler, location)
+	assert.Contains(t, w.Body.String(), `href="../?rerun=`+guid+`"`)
+	assert.Contains(t, w.Body.String(), `action="../save-job-template"`)
+
+	// Re-running the job pre-populates the index page
+	w = getPage(handler, "/?rerun="+guid)
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, "The form has been filled in from job "+guid)
+	assert.Contains(t, body, `<option value="2" selected>2</option>`)
+	assert.Contains(t, body, `value="Dataset-1"`)
+	assert.Contains(t, body, ">e-1\ne-2</textarea>")
+
+	// The index page isn't pre-populated by default
+	w = getPage(handler, "/")
+	assert.NotContains(t, w.Body.String(), " selected>")
+	assert.NotContains(t, w.Body.String(), `value="Dataset-1"`)
+
+	// Re-run a job that doesn't exist
+	w = getPage(handler, "/?rerun=1234")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "job 1234 not found")
+
+	// There aren't any templates
+	w = getPage(handler, "/job-templates")
+	assert.Contains(t, w.Body.String(), "A job can be saved as a template from its results page")
+
+	// Save the job as a template
+	w = postForm(handler, "/save-job-template", url.Values{
+		JobGuidInputName:         {guid},
+		JobTemplateNameInputName: {"Weekly <check>"},
+	})
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, "/job-templates", w.Header().Get("Location"))
+
+	w = getPage(handler, "/job-templates")
+	body = w.Body.String()
+	assert.Contains(t, body, "Weekly &lt;check&gt;")
+	assert.Contains(t, body, "Dataset-1 (2)")
+	assert.Contains(t, body, `href="./?template=Weekly+%3Ccheck%3E"`)
+
+	// Load the template
+	w = getPage(handler, "/?template="+url.QueryEscape("Weekly <check>"))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "The form has been filled in from the saved template Weekly &lt;check&gt;")
+	assert.Contains(t, w.Body.String(), `value="Dataset-1"`)
+
+	// Invalid requests to save a template
+	w = getPage(handler, "/save-job-template")
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+
+	w = postForm(handler, "/save-job-template", url.Values{
+		JobGuidInputName:         {guid},
+		JobTemplateNameInputName: {" "},
+	})
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = postForm(handler, "/save-job-template", url.Values{
+		JobGuidInputName:         {"1234"},
+		JobTemplateNameInputName: {"Daily"},
+	})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// Delete the template
+	w = postForm(handler, "/delete-job-template", url.Values{
+		JobTemplateNameInputName: {"Weekly <check>"},
+	})
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, 0, len(server.jobTemplates.List()))
+
+	w = postForm(handler, "/delete-job-template", url.Values{
+		JobTemplateNameInputName: {"Weekly <check>"},
+	})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = getPage(handler, "/?template=Weekly")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Contains(t, w.Body.String(), "job template Weekly not found")
+}
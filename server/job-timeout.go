@@ -0,0 +1,160 @@
+// A job can be given a wall-clock timeout, so that a job searching between densely connected
+// entities doesn't run for hours. The server has a default timeout and a job can ask for a shorter
+// one. The search is cancelled via its context when the timeout expires and the job is timed out.
+
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/i18n"
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+)
+
+// Constants associated with the timeout of a job
+const (
+	TimeoutInputName        = "timeoutMinutes" // Name of the input for a job's timeout (in minutes)
+	MaxUnsearchedPairsShown = 100              // Maximum number of unsearched pairs shown on a page
+)
+
+// jobTimeout of a job given the server's default timeout and the timeout requested for the job. A
+// job can't have a longer timeout than the default. Zero means there isn't a timeout.
+func jobTimeout(defaultTimeout time.Duration, requested time.Duration) time.Duration {
+	if requested > 0 && (defaultTimeout == 0 || requested < defaultTimeout) {
+		return requested
+	}
+	return defaultTimeout
+}
+
+// jobContext for executing a job with the timeout (zero for no timeout).
+func jobContext(timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout == 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+// isTimeout returns true if the error is due to the job's timeout expiring.
+func isTimeout(err error) bool {
+	return errors.Is(err, context.DeadlineExceeded)
+}
+
+// timedOutError records the timeout of a job as the job's error.
+func timedOutError(timeout time.Duration) error {
+	return i18n.Wrap(context.DeadlineExceeded, "error.jobTimedOut", timeout)
+}
+
+// parseTimeout of a job in minutes from the request. A blank timeout means the server's timeout.
+func parseTimeout(req *http.Request) (time.Duration, error) {
+
+	value := strings.TrimSpace(req.FormValue(TimeoutInputName))
+	if len(value) == 0 {
+		return 0, nil
+	}
+
+	minutes, err := strconv.Atoi(value)
+	if err != nil || minutes < 1 {
+		return 0, i18n.NewMessage("error.invalidTimeout", value)
+	}
+
+	return time.Duration(minutes) * time.Minute, nil
+}
+
+// SetTimeout of the jobs (zero for no timeout).
+func (j *JobRunner) SetTimeout(timeout time.Duration) error {
+	if timeout < 0 {
+		return job.ErrInvalidTimeout
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("timeout", timeout.String()).
+		Msg("Setting the timeout of jobs")
+
+	j.timeout = timeout
+	return nil
+}
+
+// SetTimeout of the spider jobs (zero for no timeout).
+func (j *SpiderJobRunner) SetTimeout(timeout time.Duration) error {
+	if timeout < 0 {
+		return job.ErrInvalidTimeout
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("timeout", timeout.String()).
+		Msg("Setting the timeout of spider jobs")
+
+	j.timeout = timeout
+	return nil
+}
+
+// setJobToTimedOut sets the job to timed out and records the pairs of entities that weren't
+// searched.
+func (j *JobRunner) setJobToTimedOut(j1 *job.Job, timeout time.Duration, unsearched []job.EntityPair) {
+	logging.Logger.Warn().
+		Str(logging.ComponentField, componentName).
+		Str(loggingGUIDField, j1.GUID).
+		Str("timeout", timeout.String()).
+		Int("numberOfUnsearchedPairs", len(unsearched)).
+		Msg("Setting job to timed out")
+
+	j.finish(j1, job.TimedOut, timedOutError(timeout), func() {
+		j1.UnsearchedPairs = unsearched
+	})
+}
+
+// setJobToTimedOut sets the spider job to timed out.
+func (j *SpiderJobRunner) setJobToTimedOut(j1 *job.SpiderJob, timeout time.Duration) {
+	logging.Logger.Warn().
+		Str(logging.ComponentField, componentName).
+		Str(loggingGUIDField, j1.GUID).
+		Str("timeout", timeout.String()).
+		Msg("Setting spider job to timed out")
+
+	j.finish(j1, job.TimedOut, timedOutError(timeout), nil)
+}
+
+// unsearchedPairs of a job that timed out, which are read from the job's checkpoint.
+func (j *JobRunner) unsearchedPairs(j1 *job.Job) []job.EntityPair {
+	j.jobsLock.RLock()
+	checkpoint, found := j.checkpoints[j1.GUID]
+	j.jobsLock.RUnlock()
+
+	if !found {
+		return nil
+	}
+
+	return checkpoint.UnsearchedPairs(j1.Configuration.EntitySets, j1.Configuration.Directed)
+}
+
+// timedOutContext for the page of a job that timed out, which shows the pairs of entities that
+// weren't searched (up to a maximum).
+func (j *JobServer) timedOutContext(j1 *job.Job, language string) map[string]interface{} {
+
+	pairs := j1.UnsearchedPairs
+	ctx := map[string]interface{}{
+		"guid":                  j1.GUID,
+		"reason":                j.translator.TranslateError(language, j1.Error),
+		"partial":               len(j1.PartialResultFile) > 0,
+		"diagnostics":           len(j1.DiagnosticsFile) > 0,
+		"numberUnsearchedPairs": len(pairs),
+		"overlaps":              j.overlapsContext(j1, language),
+	}
+
+	if len(pairs) > MaxUnsearchedPairsShown {
+		ctx["morePairs"] = j.translator.Translate(language, "jobTimedOut.moreUnsearchedPairs",
+			MaxUnsearchedPairsShown, len(pairs))
+		pairs = pairs[:MaxUnsearchedPairsShown]
+	}
+	ctx["unsearchedPairs"] = pairs
+
+	return ctx
+}
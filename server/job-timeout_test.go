@@ -0,0 +1,123 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/bfs"
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/i18n"
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/cdclaxton/shortest-path-web-app/spider"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobTimeout(t *testing.T) {
+	assert.Equal(t, time.Duration(0), jobTimeout(0, 0))
+	assert.Equal(t, time.Minute, jobTimeout(0, time.Minute))
+	assert.Equal(t, time.Hour, jobTimeout(time.Hour, 0))
+	assert.Equal(t, time.Minute, jobTimeout(time.Hour, time.Minute))
+	assert.Equal(t, time.Hour, jobTimeout(time.Hour, 2*time.Hour))
+}
+
+func TestParseTimeout(t *testing.T) {
+	testCases := []struct {
+		value           string
+		expectedTimeout time.Duration
+		expectedError   error
+	}{
+		{value: "", expectedTimeout: 0},
+		{value: " 5 ", expectedTimeout: 5 * time.Minute},
+		{value: "0", expectedError: i18n.NewMessage("error.invalidTimeout", "0")},
+		{value: "-1", expectedError: i18n.NewMessage("error.invalidTimeout", "-1")},
+		{value: "ten", expectedError: i18n.NewMessage("error.invalidTimeout", "ten")},
+	}
+
+	for _, testCase := range testCases {
+		form := url.Values{}
+		form.Set(TimeoutInputName, testCase.value)
+		req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+		timeout, err := parseTimeout(req)
+		assert.Equal(t, testCase.expectedError, err)
+		assert.Equal(t, testCase.expectedTimeout, timeout)
+	}
+}
+
+// slowStore of the job server, where every read of the unipartite store takes 50ms.
+func slowStore(t *testing.T, server *JobServer) graphstore.UnipartiteGraphStore {
+	slow, err := graphstore.NewFaultyUnipartiteGraphStore(server.runner.searchEngine.Unipartite,
+		graphstore.FaultConfig{LatencyMs: 50})
+	assert.NoError(t, err)
+	return slow
+}
+
+func TestJobTimesOut(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	var err error
+	server.runner.pathFinder, err = bfs.NewPathFinder(slowStore(t, server))
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, server.runner.SetTimeout(-time.Second), job.ErrInvalidTimeout)
+	assert.NoError(t, server.runner.SetTimeout(20*time.Millisecond))
+
+	handler := server.Routes()
+	w := postForm(handler, "/upload", buildFormData(2, "Dataset-1", "e-1, e-2, e-3", "", "", "", ""))
+	assert.Equal(t, http.StatusFound, w.Code)
+	waitForJobsToFinish(server.runner)
+
+	location := w.Header().Get("Location")
+	j1, err := server.runner.GetJob(strings.TrimPrefix(location, "/job/"))
+	assert.NoError(t, err)
+	assert.Equal(t, job.TimedOut, j1.Progress.State)
+	assert.NotEmpty(t, j1.UnsearchedPairs)
+	assert.Empty(t, server.runner.checkpoints)
+
+	// The job's page explains which pairs weren't searched
+	w = getPage(handler, location)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Job timed out")
+	assert.Contains(t, w.Body.String(), "Pairs of entities that weren&apos;t searched")
+
+	// The job's status
+	w = getPage(handler, location+"?format=json")
+	assert.Contains(t, w.Body.String(), `"state":"Timed out"`)
+	assert.Contains(t, w.Body.String(), `"finished":true`)
+}
+
+func TestSpiderJobTimesOut(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	var err error
+	server.spiderRunner.spider, err = spider.NewSpider(slowStore(t, server))
+	assert.NoError(t, err)
+
+	// The job's own timeout is used, as the server doesn't have one
+	form := buildSpiderFormData(1, "e-1")
+	form.Set(TimeoutInputName, "1")
+	req := httptest.NewRequest(http.MethodPost, "/spider-upload", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	conf, err := extractSpiderJobConfigurationFromForm(req, DefaultMaxSeedEntities,
+		DefaultSearchLimits, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, conf.Timeout)
+
+	assert.NoError(t, server.spiderRunner.SetTimeout(20*time.Millisecond))
+
+	w := postForm(server.Routes(), "/spider-upload", buildSpiderFormData(1, "e-1"))
+	assert.Equal(t, http.StatusFound, w.Code)
+	waitForSpiderJobsToFinish(server.spiderRunner)
+
+	w = getPage(server.Routes(), w.Header().Get("Location")+"?format=json")
+	assert.Contains(t, w.Body.String(), `"state":"Timed out"`)
+}
@@ -0,0 +1,136 @@
+// The maintenance endpoint reports the usage of the disk by the Pebble graph stores and lets an
+// admin compact them, e.g. after several incremental loads have bloated the stores, without
+// shelling into the container.
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+)
+
+// Constants associated with the maintenance endpoint
+const (
+	maintenanceUrl           = "/admin/maintenance"
+	MaintenanceActionInput   = "action"  // Name of the input holding the action to perform
+	maintenanceActionCompact = "compact" // Compact the stores
+)
+
+// MaintenanceResponse is the JSON response of the maintenance endpoint.
+type MaintenanceResponse struct {
+	Compacted bool                               `json:"compacted"`       // Were the stores compacted?
+	Stores    map[string]*graphstore.StoreReport `json:"stores"`          // Store name to its report
+	Error     string                             `json:"error,omitempty"` // Reason the request failed
+}
+
+// maintainableStores of the graph, by name. Stores that can't be maintained (e.g. in-memory stores)
+// are omitted.
+func (j *JobServer) maintainableStores() map[string]graphstore.MaintainableStore {
+
+	stores := map[string]graphstore.MaintainableStore{}
+
+	if store, ok := j.runner.searchEngine.Bipartite.(graphstore.MaintainableStore); ok {
+		stores["bipartite"] = store
+	}
+
+	// The pruned store is maintained rather than the view with the collapsed chains expanded
+	unipartite := j.runner.searchEngine.Unipartite
+	if expanded, ok := unipartite.(*graphstore.ExpandedUnipartiteGraphStore); ok {
+		unipartite = expanded.Pruned()
+	}
+
+	if store, ok := unipartite.(graphstore.MaintainableStore); ok {
+		stores["unipartite"] = store
+	}
+
+	return stores
+}
+
+// writeMaintenance response as JSON with the HTTP status code.
+func writeMaintenance(w http.ResponseWriter, code int, response MaintenanceResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logging.Logger.Error().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to write the maintenance response")
+	}
+}
+
+// handleMaintenance returns the reports of the stores. Posting the compact action compacts the
+// stores first.
+func (j *JobServer) handleMaintenance(w http.ResponseWriter, req *http.Request) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("method", req.Method).
+		Msg("Received request at " + maintenanceUrl)
+
+	response := MaintenanceResponse{
+		Stores: map[string]*graphstore.StoreReport{},
+	}
+
+	stores := j.maintainableStores()
+
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if action := req.FormValue(MaintenanceActionInput); action != maintenanceActionCompact {
+			response.Error = "unknown action: " + action
+			writeMaintenance(w, http.StatusBadRequest, response)
+			return
+		}
+
+		// The jobs and queries are queued whilst the stores are compacted
+		var failed string
+		err := j.storeLock.Update(func() error {
+			for name, store := range stores {
+				if err := store.Compact(); err != nil {
+					failed = name
+					return err
+				}
+			}
+			return nil
+		})
+
+		if err != nil {
+			logging.Logger.Error().
+				Str(logging.ComponentField, componentName).
+				Str("store", failed).
+				Err(err).
+				Msg("Failed to compact the store")
+
+			code := http.StatusInternalServerError
+			if errors.Is(err, graphstore.ErrStoreIsReadOnly) {
+				code = http.StatusConflict
+			}
+
+			response.Error = failed + ": " + err.Error()
+			writeMaintenance(w, code, response)
+			return
+		}
+		response.Compacted = len(stores) > 0
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	for name, store := range stores {
+		report, err := store.Report()
+		if err != nil {
+			response.Error = name + ": " + err.Error()
+			writeMaintenance(w, http.StatusInternalServerError, response)
+			return
+		}
+		response.Stores[name] = report
+	}
+
+	writeMaintenance(w, http.StatusOK, response)
+}
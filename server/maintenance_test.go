@@ -0,0 +1,80 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMaintenanceEndpoint(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+	server.SetAdminToken("secret")
+
+	send := func(method string, token string, form url.Values) (int, MaintenanceResponse) {
+		req := httptest.NewRequest(method, maintenanceUrl, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if len(token) > 0 {
+			req.Header.Set(AdminTokenHeader, token)
+		}
+		w := httptest.NewRecorder()
+		server.Routes().ServeHTTP(w, req)
+
+		response := MaintenanceResponse{}
+		if w.Code != http.StatusForbidden && w.Code != http.StatusMethodNotAllowed {
+			assert.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		}
+		return w.Code, response
+	}
+
+	// The endpoint requires the admin token
+	code, _ := send(http.MethodGet, "", url.Values{})
+	assert.Equal(t, http.StatusForbidden, code)
+
+	// The in-memory stores can't be maintained
+	code, response := send(http.MethodGet, "secret", url.Values{})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 0, len(response.Stores))
+
+	// Replace the bipartite store with a Pebble store
+	store, err := graphstore.NewPebbleBipartiteGraphStore(t.TempDir())
+	assert.NoError(t, err)
+	defer store.Close()
+
+	entity, err := graphstore.NewEntity("e-1", "Person", map[string]string{})
+	assert.NoError(t, err)
+	assert.NoError(t, store.AddEntity(entity))
+	assert.NoError(t, store.Finalise())
+
+	original := server.runner.searchEngine.Bipartite
+	server.runner.searchEngine.Bipartite = store
+	defer func() { server.runner.searchEngine.Bipartite = original }()
+
+	code, response = send(http.MethodGet, "secret", url.Values{})
+	assert.Equal(t, http.StatusOK, code)
+	assert.False(t, response.Compacted)
+	assert.Equal(t, 1, len(response.Stores))
+	assert.Equal(t, 1, response.Stores["bipartite"].Prefixes[0].NumberOfKeys)
+
+	// Compact the store
+	code, response = send(http.MethodPost, "secret", url.Values{MaintenanceActionInput: {"compact"}})
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, response.Compacted)
+	assert.Equal(t, 1, response.Stores["bipartite"].Prefixes[0].NumberOfKeys)
+
+	// Unknown action
+	code, response = send(http.MethodPost, "secret", url.Values{MaintenanceActionInput: {"defrag"}})
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, response.Error, "defrag")
+
+	// Unsupported method
+	code, _ = send(http.MethodDelete, "secret", url.Values{})
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+}
@@ -0,0 +1,214 @@
+// The job history lists the shortest path and spider jobs submitted from a browser, so that an
+// analyst can get back to their results after closing the tab. A browser is identified by a session
+// cookie holding a random ID, so no login is required.
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/google/uuid"
+)
+
+// Constants associated with the job history
+const (
+	SessionCookieName   = "session"                 // Name of the cookie holding the session ID
+	sessionCookieAge    = 30 * 24 * time.Hour       // Lifetime of the session cookie
+	maxJobsPerSession   = 100                       // Maximum number of jobs remembered per session
+	myJobsUrl           = "/my-jobs"                // URL of the job history page
+	myJobsTimeFormat    = "2006-01-02 15:04:05 MST" // Format of the time a job was submitted
+	sessionIdLength     = 36                        // Length of a session ID (a UUID)
+	spiderJobHistoryKey = "myJobs.spider"           // Translation key of the spider job type
+	pathJobHistoryKey   = "myJobs.shortestPath"     // Translation key of the shortest path job type
+)
+
+// A sessionJob is a job submitted from a session.
+type sessionJob struct {
+	guid      string    // GUID of the job
+	spider    bool      // Is the job a spider job?
+	submitted time.Time // Time the job was submitted
+}
+
+// A jobHistory holds the jobs submitted from each session.
+type jobHistory struct {
+	sessions map[string][]sessionJob // Session ID to the jobs in the order they were submitted
+	lock     sync.RWMutex            // Mutex for the sessions map
+}
+
+// newJobHistory without any sessions.
+func newJobHistory() *jobHistory {
+	return &jobHistory{
+		sessions: map[string][]sessionJob{},
+	}
+}
+
+// add the job to the session's history. Only the most recent jobs are remembered.
+func (h *jobHistory) add(sessionId string, j1 sessionJob) {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+
+	jobs := append(h.sessions[sessionId], j1)
+	if len(jobs) > maxJobsPerSession {
+		jobs = jobs[len(jobs)-maxJobsPerSession:]
+	}
+
+	h.sessions[sessionId] = jobs
+}
+
+// list the jobs submitted from the session, most recent first.
+func (h *jobHistory) list(sessionId string) []sessionJob {
+	h.lock.RLock()
+	defer h.lock.RUnlock()
+
+	jobs := make([]sessionJob, len(h.sessions[sessionId]))
+	copy(jobs, h.sessions[sessionId])
+
+	sort.SliceStable(jobs, func(i, j int) bool {
+		return jobs[i].submitted.After(jobs[j].submitted)
+	})
+
+	return jobs
+}
+
+// readSessionId from the request's cookie. A blank ID is returned if there isn't a valid session.
+func readSessionId(req *http.Request) string {
+	cookie, err := req.Cookie(SessionCookieName)
+	if err != nil || len(cookie.Value) != sessionIdLength {
+		return ""
+	}
+
+	if _, err := uuid.Parse(cookie.Value); err != nil {
+		return ""
+	}
+
+	return cookie.Value
+}
+
+// sessionId of the browser, where a new session is started if the browser doesn't have one.
+func sessionId(w http.ResponseWriter, req *http.Request) string {
+
+	if id := readSessionId(req); len(id) > 0 {
+		return id
+	}
+
+	id := uuid.New().String()
+	http.SetCookie(w, &http.Cookie{
+		Name:     SessionCookieName,
+		Value:    id,
+		Path:     "/",
+		MaxAge:   int(sessionCookieAge.Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return id
+}
+
+// recordJob submitted from the browser in its session's history.
+func (j *JobServer) recordJob(w http.ResponseWriter, req *http.Request, guid string, spider bool) {
+	j.history.add(sessionId(w, req), sessionJob{
+		guid:      guid,
+		spider:    spider,
+		submitted: time.Now(),
+	})
+}
+
+// MyJobDisplay is a job in the history presented to the user.
+type MyJobDisplay struct {
+	Type        string // Type of job
+	Guid        string // GUID of the job
+	Url         string // URL of the job's page
+	Description string // Summary of the job's configuration
+	State       string // State of the job
+	Submitted   string // Time the job was submitted
+}
+
+// describeJob summarises the configuration of a shortest path job.
+func describeJob(conf *job.JobConfiguration) string {
+	datasets := []string{}
+	for _, entitySet := range conf.EntitySets {
+		datasets = append(datasets, fmt.Sprintf("%v (%d)", entitySet.Name, len(entitySet.EntityIds)))
+	}
+
+	return strings.Join(datasets, ", ")
+}
+
+// describeSpiderJob summarises the configuration of a spider job.
+func describeSpiderJob(conf *job.SpiderJobConfiguration) string {
+	seeds := conf.SeedEntities.ToSlice()
+	sort.Strings(seeds)
+
+	return strings.Join(seeds, ", ")
+}
+
+// jobDisplay for the job with the GUID in the language. False is returned if the job is no longer
+// held by the job runners.
+func (j *JobServer) jobDisplay(guid string, spider bool, language string) (MyJobDisplay, bool) {
+
+	item := MyJobDisplay{Guid: guid}
+
+	if spider {
+		j1, err := j.spiderRunner.GetJob(guid)
+		if err != nil {
+			return item, false
+		}
+
+		item.Type = j.translator.Translate(language, spiderJobHistoryKey)
+		item.Url = fmt.Sprintf("%v/spider-job/%v", j.basePath, guid)
+		item.Description = describeSpiderJob(j1.Configuration)
+		item.State = j.translator.Translate(language, jobStateKeys[j1.Progress.State])
+	} else {
+		j1, err := j.runner.GetJob(guid)
+		if err != nil {
+			return item, false
+		}
+
+		item.Type = j.translator.Translate(language, pathJobHistoryKey)
+		item.Url = fmt.Sprintf("%v/job/%v", j.basePath, guid)
+		item.Description = describeJob(j1.Configuration)
+		item.State = j.translator.Translate(language, jobStateKeys[j1.Progress.State])
+	}
+
+	return item, true
+}
+
+// prepareMyJobs for display in the language. Jobs that are no longer held by the job runners are
+// skipped.
+func (j *JobServer) prepareMyJobs(jobs []sessionJob, language string) []MyJobDisplay {
+
+	display := []MyJobDisplay{}
+	for _, sj := range jobs {
+
+		item, found := j.jobDisplay(sj.guid, sj.spider, language)
+		if !found {
+			continue
+		}
+
+		item.Submitted = sj.submitted.Format(myJobsTimeFormat)
+		display = append(display, item)
+	}
+
+	return display
+}
+
+// handleMyJobs returns the page listing the jobs submitted from the browser.
+func (j *JobServer) handleMyJobs(w http.ResponseWriter, req *http.Request) {
+
+	settings := j.pageSettings(w, req)
+
+	jobs := []sessionJob{}
+	if id := readSessionId(req); len(id) > 0 {
+		jobs = j.history.list(id)
+	}
+
+	page := j.render(j.myJobsTemplate, settings, map[string]interface{}{
+		"jobs": j.prepareMyJobs(jobs, settings.language),
+	})
+	fmt.Fprint(w, page)
+}
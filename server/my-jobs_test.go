@@ -0,0 +1,106 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobHistory(t *testing.T) {
+
+	history := newJobHistory()
+	assert.Equal(t, []sessionJob{}, history.list("s-1"))
+
+	start := time.Now()
+	for idx := 0; idx < maxJobsPerSession+2; idx++ {
+		history.add("s-1", sessionJob{
+			guid:      strings.Repeat("a", idx+1),
+			submitted: start.Add(time.Duration(idx) * time.Second),
+		})
+	}
+	history.add("s-2", sessionJob{guid: "b", spider: true, submitted: start})
+
+	// Only the most recent jobs are remembered, most recent first
+	jobs := history.list("s-1")
+	assert.Len(t, jobs, maxJobsPerSession)
+	assert.Equal(t, strings.Repeat("a", maxJobsPerSession+2), jobs[0].guid)
+	assert.Equal(t, strings.Repeat("a", 3), jobs[maxJobsPerSession-1].guid)
+
+	assert.Equal(t, []sessionJob{{guid: "b", spider: true, submitted: start}}, history.list("s-2"))
+}
+
+// requestWithCookies makes a request to the handler with the cookies.
+func requestWithCookies(handler http.Handler, req *http.Request,
+	cookies []*http.Cookie) *httptest.ResponseRecorder {
+
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
+
+func TestMyJobs(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	handler := server.Routes()
+
+	// Without a session there aren't any jobs
+	w := getPage(handler, "/my-jobs")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "No jobs have been submitted from this browser yet.")
+
+	// Submitting a job starts a session
+	w = postForm(handler, "/upload", buildFormData(1, "Dataset-1", "e-1, e-2", "", "", "", ""))
+	assert.Equal(t, http.StatusFound, w.Code)
+	guid := extractGuidFromLocation(t, w.Result().Header.Get("Location"))
+
+	cookies := w.Result().Cookies()
+	assert.Len(t, cookies, 1)
+	assert.Equal(t, SessionCookieName, cookies[0].Name)
+
+	// A spider job submitted from the same browser (with the CSRF token of the spider page) is added
+	// to the session
+	w = requestWithCookies(handler, httptest.NewRequest(http.MethodGet, "/spider", nil), cookies)
+	csrfCookies := w.Result().Cookies()
+	assert.Len(t, csrfCookies, 1)
+	assert.Equal(t, CsrfCookieName, csrfCookies[0].Name)
+
+	form := buildSpiderFormData(1, "e-1")
+	form.Set(CsrfTokenInputName, csrfCookies[0].Value)
+	req := httptest.NewRequest(http.MethodPost, "/spider-upload", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	w = requestWithCookies(handler, req, append(cookies, csrfCookies...))
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Len(t, w.Result().Cookies(), 0)
+	spiderGuid := extractSpiderGuidFromLocation(t, w.Result().Header.Get("Location"))
+
+	waitForJobsToFinish(server.runner)
+	waitForSpiderJobsToFinish(server.spiderRunner)
+
+	w = requestWithCookies(handler, httptest.NewRequest(http.MethodGet, "/my-jobs", nil), cookies)
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, `<a href="/job/`+guid+`" class="govuk-link">View</a>`)
+	assert.Contains(t, body, `<a href="/spider-job/`+spiderGuid+`" class="govuk-link">View</a>`)
+	assert.Contains(t, body, "Dataset-1 (2)")
+	assert.Contains(t, body, "Complete with results")
+
+	// Another browser doesn't see the jobs
+	w = getPage(handler, "/my-jobs")
+	assert.NotContains(t, w.Body.String(), guid)
+
+	// An invalid session cookie is ignored
+	w = requestWithCookies(handler, httptest.NewRequest(http.MethodGet, "/my-jobs", nil),
+		[]*http.Cookie{{Name: SessionCookieName, Value: "not-a-session"}})
+	assert.Contains(t, w.Body.String(), "No jobs have been submitted from this browser yet.")
+}
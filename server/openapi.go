@@ -0,0 +1,616 @@
+// The OpenAPI specification describes the endpoints that other services can use to run jobs and
+// query the graph. It is generated from the definitions of the endpoints below, where the schemas of
+// the JSON responses are derived from the Go types the handlers encode, so the two can't drift apart.
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+)
+
+// Constants associated with the OpenAPI specification
+const (
+	openApiVersion     = "3.0.3"
+	openApiTitle       = "Shortest path web-app"
+	openApiDescription = "Find the shortest paths between entities and grow a graph from seed entities."
+	openApiSpecVersion = "1.0.0"
+	openApiUrl         = "/openapi.json"
+)
+
+// OpenApiDocument is the root of an OpenAPI specification.
+type OpenApiDocument struct {
+	OpenApi    string                                  `json:"openapi"`
+	Info       OpenApiInfo                             `json:"info"`
+	Servers    []OpenApiServer                         `json:"servers"`
+	Paths      map[string]map[string]*OpenApiOperation `json:"paths"`
+	Components OpenApiComponents                       `json:"components"`
+}
+
+// OpenApiInfo describes the API.
+type OpenApiInfo struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Version     string `json:"version"`
+}
+
+// OpenApiServer is the URL the API is served from.
+type OpenApiServer struct {
+	Url string `json:"url"`
+}
+
+// OpenApiComponents holds the schemas that are referenced by the operations.
+type OpenApiComponents struct {
+	Schemas map[string]*OpenApiSchema `json:"schemas"`
+}
+
+// OpenApiOperation is an HTTP method on a path.
+type OpenApiOperation struct {
+	OperationId string                      `json:"operationId"`
+	Summary     string                      `json:"summary"`
+	Parameters  []OpenApiParameter          `json:"parameters,omitempty"`
+	RequestBody *OpenApiRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*OpenApiResponse `json:"responses"`
+}
+
+// OpenApiParameter is a path or query parameter.
+type OpenApiParameter struct {
+	Name        string         `json:"name"`
+	In          string         `json:"in"`
+	Description string         `json:"description"`
+	Required    bool           `json:"required"`
+	Schema      *OpenApiSchema `json:"schema"`
+}
+
+// OpenApiRequestBody is the body of a request.
+type OpenApiRequestBody struct {
+	Required bool                         `json:"required"`
+	Content  map[string]*OpenApiMediaType `json:"content"`
+}
+
+// OpenApiResponse is a response to an operation.
+type OpenApiResponse struct {
+	Description string                       `json:"description"`
+	Headers     map[string]*OpenApiHeader    `json:"headers,omitempty"`
+	Content     map[string]*OpenApiMediaType `json:"content,omitempty"`
+}
+
+// OpenApiHeader is a header of a response.
+type OpenApiHeader struct {
+	Description string         `json:"description"`
+	Schema      *OpenApiSchema `json:"schema"`
+}
+
+// OpenApiMediaType is the schema of a body with a content type.
+type OpenApiMediaType struct {
+	Schema *OpenApiSchema `json:"schema"`
+}
+
+// OpenApiSchema is the schema of a value.
+type OpenApiSchema struct {
+	Ref                  string                    `json:"$ref,omitempty"`
+	Type                 string                    `json:"type,omitempty"`
+	Format               string                    `json:"format,omitempty"`
+	Description          string                    `json:"description,omitempty"`
+	Properties           map[string]*OpenApiSchema `json:"properties,omitempty"`
+	Required             []string                  `json:"required,omitempty"`
+	Items                *OpenApiSchema            `json:"items,omitempty"`
+	AdditionalProperties *OpenApiSchema            `json:"additionalProperties,omitempty"`
+}
+
+// An apiField is a parameter or form field of an endpoint.
+type apiField struct {
+	name        string // Name of the field
+	description string // Description of the field
+	kind        string // OpenAPI type of the field
+	format      string // OpenAPI format of the field (optional)
+	required    bool   // Must the field be given?
+}
+
+// An apiResponse is a response of an endpoint.
+type apiResponse struct {
+	code        int         // HTTP status code
+	description string      // Description of the response
+	contentType string      // Content type of the body (blank if there isn't one)
+	body        interface{} // Value of the Go type encoded as JSON (nil for other content types)
+	redirect    bool        // Is the response a redirect with a Location header?
+}
+
+// An apiEndpoint is an HTTP method on a path of the API.
+type apiEndpoint struct {
+	operationId string        // Unique name of the operation
+	method      string        // HTTP method
+	path        string        // Path, where {guid} is the GUID of a job
+	summary     string        // Summary of the endpoint
+	query       []apiField    // Query parameters
+	form        []apiField    // Fields of a form body
+	responses   []apiResponse // Responses
+}
+
+// Parameters and responses shared by the endpoints
+var (
+	guidField = apiField{
+		name:        "guid",
+		description: "GUID of the job",
+		kind:        "string",
+		required:    true,
+	}
+	jsonFormatField = apiField{
+		name:        JobFormatInputName,
+		description: "Format of the response, which must be json",
+		kind:        "string",
+		required:    true,
+	}
+	inputProblemResponse = apiResponse{
+		code:        http.StatusBadRequest,
+		description: "The input is invalid and the reason is given on an HTML page",
+		contentType: "text/html",
+	}
+	jobNotFoundResponse = apiResponse{
+		code:        http.StatusNotFound,
+		description: "The job couldn't be found or doesn't have results",
+	}
+)
+
+// apiEndpoints are the endpoints described by the OpenAPI specification.
+var apiEndpoints = []apiEndpoint{
+	{
+		operationId: "submitJob",
+		method:      http.MethodPost,
+		path:        "/upload",
+		summary:     "Submit a job to find the shortest paths between the entities of the datasets",
+		form: []apiField{
+			{name: NumberHopsInputName, description: "Maximum number of hops", kind: "integer", required: true},
+			{name: DatasetNameInputName + "1", description: "Name of the first dataset", kind: "string", required: true},
+			{name: DatasetEntitiesInputName + "1", description: "Entity IDs of the first dataset separated by commas or new lines", kind: "string", required: true},
+			{name: DatasetNameInputName + "2", description: "Name of the second dataset", kind: "string"},
+			{name: DatasetEntitiesInputName + "2", description: "Entity IDs of the second dataset", kind: "string"},
+			{name: DatasetNameInputName + "3", description: "Name of the third dataset", kind: "string"},
+			{name: DatasetEntitiesInputName + "3", description: "Entity IDs of the third dataset (further datasets are numbered 4, 5, ... up to the server's maximum)", kind: "string"},
+			{name: RetryInputName, description: "Retry with fewer hops if there are too many paths", kind: "boolean"},
+			{name: DirectedInputName, description: "Only follow the edges in their direction", kind: "boolean"},
+			{name: ExcludeEntitiesInputName, description: "Entity IDs the paths mustn't pass through", kind: "string"},
+			{name: WaypointsInputName, description: "Entity IDs the paths must pass through one of", kind: "string"},
+			{name: PathFilterInputName, description: "Only keep the paths through an entity whose attributes match the expression, e.g. Region = North", kind: "string"},
+			{name: MinDocumentsInputName, description: "Minimum number of documents supporting each link of a path", kind: "integer"},
+			{name: TemporalInputName, description: "Only find paths whose links are supported by documents in date order", kind: "boolean"},
+			{name: ExplainInputName, description: "Record the expansion of the search for each pair of entities in a diagnostics sheet", kind: "boolean"},
+			{name: PathMatrixInputName, description: "Output a matrix of the connectivity of each pair of entities instead of an i2 chart", kind: "boolean"},
+			{name: TimeoutInputName, description: "Timeout of the job in minutes (at most the server's timeout)", kind: "integer"},
+			{name: AllowDuplicateInputName, description: "Run the job even if it is identical to a job that is running or finished recently", kind: "boolean"},
+		},
+		responses: []apiResponse{
+			{code: http.StatusFound, description: "The job was submitted (or is identical to a recent job) and the Location is the job's page", redirect: true},
+			inputProblemResponse,
+		},
+	},
+	{
+		operationId: "getJobStatus",
+		method:      http.MethodGet,
+		path:        "/job/{guid}",
+		summary:     "Get the status of a shortest path job",
+		query:       []apiField{jsonFormatField},
+		responses: []apiResponse{
+			{code: http.StatusOK, description: "Status of the job", contentType: "application/json", body: JobStatus{}},
+			{code: http.StatusNotFound, description: "The job couldn't be found", contentType: "application/json", body: JobStatus{}},
+		},
+	},
+	{
+		operationId: "downloadJobResults",
+		method:      http.MethodGet,
+		path:        "/download/{guid}",
+		summary:     "Download the Excel file for i2 of a shortest path job",
+		responses: []apiResponse{
+			{code: http.StatusOK, description: "Excel file of the results", contentType: excelContentType},
+			jobNotFoundResponse,
+		},
+	},
+	{
+		operationId: "downloadJobManifest",
+		method:      http.MethodGet,
+		path:        "/download/{guid}/manifest",
+		summary:     "Download the integrity manifest of the results of a shortest path job",
+		responses: []apiResponse{
+			{code: http.StatusOK, description: "Manifest of the result file (if manifests are enabled)", contentType: "application/json"},
+			jobNotFoundResponse,
+		},
+	},
+	{
+		operationId: "downloadJobPathMatrix",
+		method:      http.MethodGet,
+		path:        "/download-csv/{guid}",
+		summary:     "Download the CSV file of the path matrix of a shortest path job",
+		responses: []apiResponse{
+			{code: http.StatusOK, description: "CSV file of the path matrix", contentType: csvContentType},
+			jobNotFoundResponse,
+		},
+	},
+	{
+		operationId: "downloadJobPartialResults",
+		method:      http.MethodGet,
+		path:        "/download-partial/{guid}",
+		summary:     "Download the Excel file of the partial results of a failed or retried shortest path job",
+		responses: []apiResponse{
+			{code: http.StatusOK, description: "Excel file of the paths found before the job failed", contentType: excelContentType},
+			jobNotFoundResponse,
+		},
+	},
+	{
+		operationId: "downloadJobDiagnostics",
+		method:      http.MethodGet,
+		path:        "/download-diagnostics/{guid}",
+		summary:     "Download the Excel file of the diagnostics of a shortest path job run in explain mode",
+		responses: []apiResponse{
+			{code: http.StatusOK, description: "Excel file of the expansion of the search for each pair of entities", contentType: excelContentType},
+			jobNotFoundResponse,
+		},
+	},
+	{
+		operationId: "retryJob",
+		method:      http.MethodPost,
+		path:        "/retry/{guid}",
+		summary:     "Retry a failed shortest path job, resuming from its checkpoint if it has one",
+		responses: []apiResponse{
+			{code: http.StatusFound, description: "The job was retried and the Location is the job's page", redirect: true},
+			{code: http.StatusNotFound, description: "The job couldn't be found", contentType: "text/html"},
+			{code: http.StatusConflict, description: "The job hasn't failed, so it can't be retried", contentType: "text/html"},
+		},
+	},
+	{
+		operationId: "submitSpiderJob",
+		method:      http.MethodPost,
+		path:        "/spider-upload",
+		summary:     "Submit a job to grow a graph from the seed entities",
+		form: []apiField{
+			{name: NumberStepsInputName, description: "Number of steps from the seed entities", kind: "integer", required: true},
+			{name: SeedEntitiesInputName, description: "Seed entity IDs separated by commas or new lines", kind: "string", required: true},
+			{name: StepTypesInputName + "1", description: "Entity types the first step can add separated by commas (blank for any type)", kind: "string"},
+			{name: StepTypesInputName + "2", description: "Entity types the second step can add", kind: "string"},
+			{name: StepTypesInputName + "3", description: "Entity types the third step can add", kind: "string"},
+			{name: TimeoutInputName, description: "Timeout of the job in minutes (at most the server's timeout)", kind: "integer"},
+		},
+		responses: []apiResponse{
+			{code: http.StatusFound, description: "The job was submitted and the Location is the job's page", redirect: true},
+			inputProblemResponse,
+		},
+	},
+	{
+		operationId: "getSpiderJobStatus",
+		method:      http.MethodGet,
+		path:        "/spider-job/{guid}",
+		summary:     "Get the status of a spider job",
+		query:       []apiField{jsonFormatField},
+		responses: []apiResponse{
+			{code: http.StatusOK, description: "Status of the job", contentType: "application/json", body: JobStatus{}},
+			{code: http.StatusNotFound, description: "The job couldn't be found", contentType: "application/json", body: JobStatus{}},
+		},
+	},
+	{
+		operationId: "downloadSpiderJobResults",
+		method:      http.MethodGet,
+		path:        "/spider-download/{guid}",
+		summary:     "Download the Excel file for i2 of a spider job",
+		responses: []apiResponse{
+			{code: http.StatusOK, description: "Excel file of the results", contentType: excelContentType},
+			jobNotFoundResponse,
+		},
+	},
+	{
+		operationId: "downloadSpiderJobManifest",
+		method:      http.MethodGet,
+		path:        "/spider-download/{guid}/manifest",
+		summary:     "Download the integrity manifest of the results of a spider job",
+		responses: []apiResponse{
+			{code: http.StatusOK, description: "Manifest of the result file (if manifests are enabled)", contentType: "application/json"},
+			jobNotFoundResponse,
+		},
+	},
+	{
+		operationId: "findPaths",
+		method:      http.MethodGet,
+		path:        "/path",
+		summary:     "Find the paths between two entities",
+		query: []apiField{
+			{name: PathFromInputName, description: "Entity ID to find the paths from", kind: "string", required: true},
+			{name: PathToInputName, description: "Entity ID to find the paths to", kind: "string", required: true},
+			{name: PathHopsInputName, description: "Maximum number of hops", kind: "integer"},
+			{name: DirectedInputName, description: "Only follow the edges in their direction", kind: "boolean"},
+			jsonFormatField,
+		},
+		responses: []apiResponse{
+			{code: http.StatusOK, description: "Paths between the entities", contentType: "application/json", body: PathQueryResult{}},
+			{code: http.StatusBadRequest, description: "The query is invalid", contentType: "application/json", body: PathQueryResult{}},
+		},
+	},
+	{
+		operationId: "bulkSearchEntities",
+		method:      http.MethodPost,
+		path:        "/bulk-search",
+		summary:     "Check which entities are in the graph stores and summarise them in an Excel file",
+		form: []apiField{
+			{name: BulkSearchEntitiesInputName, description: "Entity IDs separated by commas or new lines", kind: "string", required: true},
+		},
+		responses: []apiResponse{
+			{code: http.StatusOK, description: "Excel file summarising each entity", contentType: excelContentType},
+			{code: http.StatusBadRequest, description: "The entity IDs are invalid and the reason is given on an HTML page", contentType: "text/html"},
+		},
+	},
+	{
+		operationId: "sampleGraph",
+		method:      http.MethodGet,
+		path:        graphSampleUrl,
+		summary:     "Get a random sample of the entities of the graph and the edges between them",
+		query: []apiField{
+			{name: JobFormatInputName, description: "Format of the response, which must be json or graphml", kind: "string", required: true},
+			{name: SampleEntitiesInputName, description: "Number of entities to sample", kind: "integer"},
+			{name: SampleSeedInputName, description: "Seed of the random sample, to get the same sample again", kind: "integer"},
+		},
+		responses: []apiResponse{
+			{code: http.StatusOK, description: "Sample of the graph (as GraphML if it is requested)", contentType: "application/json", body: GraphSampleResult{}},
+			{code: http.StatusBadRequest, description: "The query is invalid", contentType: "application/json", body: GraphSampleResult{}},
+		},
+	},
+	{
+		operationId: "getStats",
+		method:      http.MethodGet,
+		path:        "/stats/",
+		summary:     "Get the statistics of the graphs, including the number of entities and documents of each type",
+		query: []apiField{
+			jsonFormatField,
+		},
+		responses: []apiResponse{
+			{code: http.StatusOK, description: "Statistics of the graphs (as HTML unless JSON is requested)", contentType: "application/json", body: StatsResponse{}},
+		},
+	},
+	{
+		operationId: "getLiveness",
+		method:      http.MethodGet,
+		path:        "/healthz",
+		summary:     "Check the job runners are responsive",
+		responses: []apiResponse{
+			{code: http.StatusOK, description: "Healthy", contentType: "application/json", body: HealthResponse{}},
+			{code: http.StatusServiceUnavailable, description: "Unhealthy", contentType: "application/json", body: HealthResponse{}},
+		},
+	},
+	{
+		operationId: "getReadiness",
+		method:      http.MethodGet,
+		path:        "/readyz",
+		summary:     "Check the graph stores can be read",
+		responses: []apiResponse{
+			{code: http.StatusOK, description: "Ready", contentType: "application/json", body: HealthResponse{}},
+			{code: http.StatusServiceUnavailable, description: "Not ready", contentType: "application/json", body: HealthResponse{}},
+		},
+	},
+}
+
+// schemaForField of an endpoint.
+func schemaForField(field apiField) *OpenApiSchema {
+	return &OpenApiSchema{
+		Type:   field.kind,
+		Format: field.format,
+	}
+}
+
+// jsonFieldName of a struct field and whether it is always present in the JSON. A blank name is
+// returned if the field isn't encoded.
+func jsonFieldName(field reflect.StructField) (string, bool) {
+
+	if !field.IsExported() {
+		return "", false
+	}
+
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+
+	parts := strings.Split(tag, ",")
+	name := parts[0]
+	if len(name) == 0 {
+		name = field.Name
+	}
+
+	omitEmpty := false
+	for _, option := range parts[1:] {
+		if option == "omitempty" {
+			omitEmpty = true
+		}
+	}
+
+	return name, !omitEmpty
+}
+
+// schemaForType derives the schema of the JSON encoding of the Go type. Structs are added to the
+// schemas and referenced by name.
+func schemaForType(t reflect.Type, schemas map[string]*OpenApiSchema) *OpenApiSchema {
+
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == reflect.TypeOf(time.Time{}) {
+		return &OpenApiSchema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return &OpenApiSchema{Type: "string"}
+	case reflect.Bool:
+		return &OpenApiSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return &OpenApiSchema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &OpenApiSchema{Type: "number"}
+	case reflect.Slice, reflect.Array:
+		return &OpenApiSchema{Type: "array", Items: schemaForType(t.Elem(), schemas)}
+	case reflect.Map:
+		return &OpenApiSchema{Type: "object", AdditionalProperties: schemaForType(t.Elem(), schemas)}
+	case reflect.Struct:
+		ref := &OpenApiSchema{Ref: "#/components/schemas/" + t.Name()}
+		if _, found := schemas[t.Name()]; found {
+			return ref
+		}
+
+		schema := &OpenApiSchema{
+			Type:       "object",
+			Properties: map[string]*OpenApiSchema{},
+		}
+		schemas[t.Name()] = schema
+
+		for idx := 0; idx < t.NumField(); idx++ {
+			name, required := jsonFieldName(t.Field(idx))
+			if len(name) == 0 {
+				continue
+			}
+
+			schema.Properties[name] = schemaForType(t.Field(idx).Type, schemas)
+			if required {
+				schema.Required = append(schema.Required, name)
+			}
+		}
+
+		return ref
+	default:
+		return &OpenApiSchema{}
+	}
+}
+
+// operation of the endpoint, where the schemas of the response bodies are added to the schemas.
+func (e apiEndpoint) operation(schemas map[string]*OpenApiSchema) *OpenApiOperation {
+
+	op := &OpenApiOperation{
+		OperationId: e.operationId,
+		Summary:     e.summary,
+		Responses:   map[string]*OpenApiResponse{},
+	}
+
+	if strings.Contains(e.path, "{"+guidField.name+"}") {
+		op.Parameters = append(op.Parameters, OpenApiParameter{
+			Name:        guidField.name,
+			In:          "path",
+			Description: guidField.description,
+			Required:    true,
+			Schema:      schemaForField(guidField),
+		})
+	}
+
+	for _, field := range e.query {
+		op.Parameters = append(op.Parameters, OpenApiParameter{
+			Name:        field.name,
+			In:          "query",
+			Description: field.description,
+			Required:    field.required,
+			Schema:      schemaForField(field),
+		})
+	}
+
+	if len(e.form) > 0 {
+		body := &OpenApiSchema{
+			Type:       "object",
+			Properties: map[string]*OpenApiSchema{},
+		}
+
+		for _, field := range e.form {
+			schema := schemaForField(field)
+			schema.Description = field.description
+			body.Properties[field.name] = schema
+
+			if field.required {
+				body.Required = append(body.Required, field.name)
+			}
+		}
+
+		op.RequestBody = &OpenApiRequestBody{
+			Required: true,
+			Content: map[string]*OpenApiMediaType{
+				"application/x-www-form-urlencoded": {Schema: body},
+			},
+		}
+	}
+
+	for _, response := range e.responses {
+		r := &OpenApiResponse{
+			Description: response.description,
+		}
+
+		if response.redirect {
+			r.Headers = map[string]*OpenApiHeader{
+				"Location": {
+					Description: "URL of the job's page, which ends with its GUID",
+					Schema:      &OpenApiSchema{Type: "string"},
+				},
+			}
+		}
+
+		if len(response.contentType) > 0 {
+			schema := &OpenApiSchema{Type: "string"}
+			if response.body != nil {
+				schema = schemaForType(reflect.TypeOf(response.body), schemas)
+			} else if response.contentType == excelContentType {
+				schema.Format = "binary"
+			}
+
+			r.Content = map[string]*OpenApiMediaType{
+				response.contentType: {Schema: schema},
+			}
+		}
+
+		op.Responses[fmt.Sprintf("%d", response.code)] = r
+	}
+
+	return op
+}
+
+// buildOpenApiDocument for the endpoints served under the base path.
+func buildOpenApiDocument(endpoints []apiEndpoint, basePath string) *OpenApiDocument {
+
+	server := basePath
+	if len(server) == 0 {
+		server = "/"
+	}
+
+	doc := &OpenApiDocument{
+		OpenApi: openApiVersion,
+		Info: OpenApiInfo{
+			Title:       openApiTitle,
+			Description: openApiDescription,
+			Version:     openApiSpecVersion,
+		},
+		Servers: []OpenApiServer{{Url: server}},
+		Paths:   map[string]map[string]*OpenApiOperation{},
+		Components: OpenApiComponents{
+			Schemas: map[string]*OpenApiSchema{},
+		},
+	}
+
+	for _, endpoint := range endpoints {
+		if _, found := doc.Paths[endpoint.path]; !found {
+			doc.Paths[endpoint.path] = map[string]*OpenApiOperation{}
+		}
+
+		doc.Paths[endpoint.path][strings.ToLower(endpoint.method)] =
+			endpoint.operation(doc.Components.Schemas)
+	}
+
+	return doc
+}
+
+// handleOpenApi returns the OpenAPI specification of the endpoints.
+func (j *JobServer) handleOpenApi(w http.ResponseWriter, req *http.Request) {
+
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(buildOpenApiDocument(apiEndpoints, j.basePath)); err != nil {
+		logging.Logger.Error().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to write the OpenAPI specification")
+	}
+}
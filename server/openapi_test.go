@@ -0,0 +1,170 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/client"
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchemaForType(t *testing.T) {
+
+	schemas := map[string]*OpenApiSchema{}
+	ref := schemaForType(reflect.TypeOf(JobStatus{}), schemas)
+	assert.Equal(t, "#/components/schemas/JobStatus", ref.Ref)
+
+	schema := schemas["JobStatus"]
+	assert.Equal(t, "object", schema.Type)
+	assert.Equal(t, &OpenApiSchema{Type: "string"}, schema.Properties["state"])
+	assert.Equal(t, &OpenApiSchema{Type: "boolean"}, schema.Properties["finished"])
+	assert.Equal(t, &OpenApiSchema{Type: "array", Items: &OpenApiSchema{Type: "string"}},
+		schema.Properties["warnings"])
+	assert.Equal(t, []string{"guid", "state", "finished", "message", "warnings"}, schema.Required)
+
+	// Maps and nested slices
+	schemaForType(reflect.TypeOf(HealthResponse{}), schemas)
+	assert.Equal(t, &OpenApiSchema{Type: "object", AdditionalProperties: &OpenApiSchema{Type: "string"}},
+		schemas["HealthResponse"].Properties["checks"])
+
+	schemaForType(reflect.TypeOf(PathQueryResult{}), schemas)
+	assert.Equal(t, &OpenApiSchema{Type: "array", Items: &OpenApiSchema{Type: "array",
+		Items: &OpenApiSchema{Type: "string"}}}, schemas["PathQueryResult"].Properties["paths"])
+
+	assert.Equal(t, &OpenApiSchema{Type: "string", Format: "date-time"},
+		schemaForType(reflect.TypeOf(time.Time{}), schemas))
+}
+
+func TestHandleOpenApi(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	w := getPage(server.Routes(), "/openapi.json")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var doc OpenApiDocument
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &doc))
+	assert.Equal(t, openApiVersion, doc.OpenApi)
+	assert.Equal(t, []OpenApiServer{{Url: "/"}}, doc.Servers)
+
+	// Each endpoint is in the specification
+	for _, endpoint := range apiEndpoints {
+		methods, found := doc.Paths[endpoint.path]
+		assert.True(t, found, endpoint.path)
+		assert.Contains(t, methods, map[string]string{
+			http.MethodGet: "get", http.MethodPost: "post"}[endpoint.method])
+	}
+
+	submit := doc.Paths["/upload"]["post"]
+	form := submit.RequestBody.Content["application/x-www-form-urlencoded"].Schema
+	assert.Contains(t, form.Properties, NumberHopsInputName)
+	assert.Contains(t, form.Required, DatasetEntitiesInputName+"1")
+	assert.Contains(t, submit.Responses["302"].Headers, "Location")
+
+	status := doc.Paths["/job/{guid}"]["get"]
+	assert.Equal(t, "guid", status.Parameters[0].Name)
+	assert.Equal(t, "path", status.Parameters[0].In)
+	assert.Equal(t, "#/components/schemas/JobStatus",
+		status.Responses["200"].Content["application/json"].Schema.Ref)
+	assert.Contains(t, doc.Components.Schemas, "JobStatus")
+
+	// A named graph's specification is served from its base path
+	assert.Equal(t, []OpenApiServer{{Url: "/g/beta"}},
+		buildOpenApiDocument(apiEndpoints, "/g/beta").Servers)
+}
+
+func TestJobStatus(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	handler := server.Routes()
+
+	// Job not found
+	w := getPage(handler, "/job/1234?format=json")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	var status JobStatus
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+	assert.Equal(t, "1234", status.Guid)
+	assert.Equal(t, "job 1234 not found", status.Error)
+
+	w = getPage(handler, "/spider-job/1234?format=json")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// A job with results
+	w = postForm(handler, "/upload", buildFormData(1, "Dataset-1", "e-1, e-2", "", "", "", ""))
+	guid := extractGuidFromLocation(t, w.Result().Header.Get("Location"))
+	waitForJobsToFinish(server.runner)
+
+	w = getPage(handler, "/job/"+guid+"?format=json")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	var complete JobStatus
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &complete))
+	assert.Equal(t, JobStatus{
+		Guid:     guid,
+		State:    job.CompleteResults,
+		Finished: true,
+		Message:  "Complete with results",
+		Warnings: []string{},
+		Download: "/download/" + guid,
+		Statistics: &job.PathStatistics{
+			Pairs: []job.PairStatistics{
+				{Source: "e-1", Destination: "e-2", ShortestDistance: 1, NumberOfPaths: 1},
+			},
+			HopHistogram: []int{1},
+		},
+	}, complete)
+}
+
+func TestClientAgainstServer(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	ts := httptest.NewServer(server.Routes())
+	defer ts.Close()
+
+	c, err := client.NewClient(ts.URL, nil)
+	assert.NoError(t, err)
+	assert.NoError(t, c.SetPollInterval(10*time.Millisecond))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	// Shortest path job
+	var buffer bytes.Buffer
+	status, err := c.RunJob(ctx, client.JobRequest{
+		NumberHops: 1,
+		Datasets:   []client.Dataset{{Name: "Dataset-1", EntityIds: []string{"e-1", "e-2"}}},
+	}, &buffer)
+	assert.NoError(t, err)
+	assert.Equal(t, client.CompleteResults, status.State)
+	assert.True(t, buffer.Len() > 0)
+
+	// Spider job without results
+	buffer.Reset()
+	_, err = c.RunSpiderJob(ctx, client.SpiderJobRequest{
+		NumberSteps:   1,
+		SeedEntityIds: []string{"e-100"},
+	}, &buffer)
+	assert.ErrorIs(t, err, client.ErrNoResults)
+
+	// Invalid job
+	_, err = c.SubmitJob(ctx, client.JobRequest{NumberHops: 1})
+	assert.ErrorIs(t, err, client.ErrUnexpectedStatus)
+}
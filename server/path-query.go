@@ -0,0 +1,220 @@
+// The path query finds the paths between exactly two entities whilst the user waits, which answers
+// the common question "are these two entities connected?" without the dataset and job machinery.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/bfs"
+	"github.com/cdclaxton/shortest-path-web-app/i18n"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+)
+
+// Constants associated with the path query
+const (
+	PathFromInputName       = "from"           // Entity ID to find the paths from
+	PathToInputName         = "to"             // Entity ID to find the paths to
+	PathHopsInputName       = "hops"           // Maximum number of hops
+	PathFormatInputName     = "format"         // Format of the response (json or blank for HTML)
+	DefaultPathQueryTimeout = 10 * time.Second // Default maximum time to search for the paths
+	defaultPathQueryHops    = 3                // Number of hops if it isn't given
+	maxPathQueryRows        = 100              // Maximum number of paths shown on the page
+	jsonFormat              = "json"
+)
+
+var ErrInvalidPathQueryTimeout = errors.New("invalid path query timeout")
+
+// A pathQuery is a request for the paths between two entities.
+type pathQuery struct {
+	from     string // Entity ID to find the paths from
+	to       string // Entity ID to find the paths to
+	hops     int    // Maximum number of hops
+	directed bool   // Only follow the edges in their direction?
+}
+
+// PathQueryResult is the JSON response to a path query.
+type PathQueryResult struct {
+	From     string     `json:"from"`            // Entity ID the paths are from
+	To       string     `json:"to"`              // Entity ID the paths are to
+	Hops     int        `json:"hops"`            // Maximum number of hops
+	Directed bool       `json:"directed"`        // Were the edges only followed in their direction?
+	Paths    [][]string `json:"paths"`           // Entity IDs of each path
+	Error    string     `json:"error,omitempty"` // Reason the query failed
+}
+
+// SetPathQueryTimeout sets the maximum time to search for the paths between two entities.
+func (j *JobServer) SetPathQueryTimeout(timeout time.Duration) error {
+
+	// Precondition
+	if timeout <= 0 {
+		return ErrInvalidPathQueryTimeout
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("pathQueryTimeout", timeout.String()).
+		Msg("Setting the path query timeout")
+
+	j.pathQueryTimeout = timeout
+	return nil
+}
+
+// parsePathQuery from the URL's query parameters.
+func parsePathQuery(req *http.Request, limits SearchLimits) (pathQuery, error) {
+
+	values := req.URL.Query()
+	query := pathQuery{
+		from:     strings.TrimSpace(values.Get(PathFromInputName)),
+		to:       strings.TrimSpace(values.Get(PathToInputName)),
+		hops:     limits.defaultHops(defaultPathQueryHops),
+		directed: values.Get(DirectedInputName) == "true",
+	}
+
+	if len(query.from) == 0 || len(query.to) == 0 {
+		return query, i18n.NewMessage("error.pathEntityBlank")
+	}
+
+	if hops := values.Get(PathHopsInputName); len(hops) > 0 {
+		value, err := limits.parseHops(hops)
+		if err != nil {
+			return query, err
+		}
+		query.hops = value
+	}
+
+	return query, nil
+}
+
+// sortedRoutes of the paths, shortest first and then alphabetically.
+func sortedRoutes(paths []bfs.Path) [][]string {
+
+	routes := make([][]string, 0, len(paths))
+	for _, path := range paths {
+		routes = append(routes, path.Route)
+	}
+
+	sort.Slice(routes, func(i, j int) bool {
+		if len(routes[i]) != len(routes[j]) {
+			return len(routes[i]) < len(routes[j])
+		}
+		return strings.Join(routes[i], "\x00") < strings.Join(routes[j], "\x00")
+	})
+
+	return routes
+}
+
+// runPathQuery with the server's timeout. The status code to return is given with any error.
+func (j *JobServer) runPathQuery(ctx context.Context, query pathQuery) ([][]string, int, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, j.pathQueryTimeout)
+	defer cancel()
+
+	j.storeLock.BeginRead()
+	defer j.storeLock.EndRead()
+
+	paths, err := j.runner.pathFinder.PathsBetween(ctx, query.from, query.to, query.hops,
+		query.directed)
+
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return nil, http.StatusServiceUnavailable, i18n.NewMessage("error.pathQueryTimeout",
+			j.pathQueryTimeout.String())
+	case errors.Is(err, bfs.ErrTooManyPaths):
+		return nil, http.StatusBadRequest, i18n.Wrap(err, "error.tooManyPaths")
+	case err != nil:
+		return nil, http.StatusInternalServerError, err
+	}
+
+	return sortedRoutes(paths), http.StatusOK, nil
+}
+
+// handlePath finds the paths between two entities and returns them as an HTML page or as JSON if
+// the format is json. Without any entities, the HTML page just shows the form.
+func (j *JobServer) handlePath(w http.ResponseWriter, req *http.Request) {
+
+	settings := j.pageSettings(w, req)
+	asJson := req.URL.Query().Get(PathFormatInputName) == jsonFormat
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("from", req.URL.Query().Get(PathFromInputName)).
+		Str("to", req.URL.Query().Get(PathToInputName)).
+		Bool("json", asJson).
+		Msg("Received request to find the paths between two entities")
+
+	query, err := parsePathQuery(req, j.searchLimits)
+	status := http.StatusBadRequest
+
+	var routes [][]string
+	if err == nil {
+		routes, status, err = j.runPathQuery(req.Context(), query)
+	}
+
+	if err != nil && status == http.StatusInternalServerError {
+		logging.Logger.Error().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to find the paths between two entities")
+	}
+
+	if asJson {
+		result := PathQueryResult{
+			From:     query.from,
+			To:       query.to,
+			Hops:     query.hops,
+			Directed: query.directed,
+			Paths:    routes,
+			Error:    j.translator.TranslateError(settings.language, err),
+		}
+		if result.Paths == nil {
+			result.Paths = [][]string{}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(result)
+		return
+	}
+
+	ctx := map[string]interface{}{
+		"from":       query.from,
+		"to":         query.to,
+		"hops":       query.hops,
+		"directed":   query.directed,
+		"hopOptions": j.searchLimits.HopOptions(),
+	}
+
+	searched := len(query.from) > 0 || len(query.to) > 0
+	if searched && err != nil {
+		ctx["error"] = j.translator.TranslateError(settings.language, err)
+	} else if searched {
+		ctx["searched"] = true
+		ctx["paths"] = routes
+		if len(routes) > maxPathQueryRows {
+			ctx["paths"] = routes[:maxPathQueryRows]
+			ctx["truncated"] = j.translator.Translate(settings.language, "path.truncated",
+				maxPathQueryRows)
+		}
+
+		if len(routes) > 0 {
+			ctx["summary"] = j.translator.Translate(settings.language, "path.found", len(routes),
+				query.from, query.to, query.hops)
+		} else {
+			ctx["summary"] = j.translator.Translate(settings.language, "path.notFound", query.from,
+				query.to, query.hops)
+		}
+	}
+
+	if searched {
+		w.WriteHeader(status)
+	}
+	fmt.Fprint(w, j.render(j.pathTemplate, settings, ctx))
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPathQuery(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	handler := server.Routes()
+
+	assert.ErrorIs(t, server.SetPathQueryTimeout(0), ErrInvalidPathQueryTimeout)
+	assert.NoError(t, server.SetPathQueryTimeout(time.Minute))
+
+	// The form is shown without any entities
+	w := getPage(handler, "/path")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "Paths between two entities")
+	assert.NotContains(t, w.Body.String(), "govuk-error-summary")
+
+	// Paths as an HTML page
+	w = getPage(handler, "/path?from=e-1&to=e-2&hops=2")
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, "paths found between e-1 and e-2 within 2 hops")
+	assert.Contains(t, body, `<a href="entity/e-1" class="govuk-link">e-1</a> &rarr; <a href="entity/e-2" class="govuk-link">e-2</a>`)
+	assert.Contains(t, body, `<option value="2" selected>2</option>`)
+
+	// Paths as JSON
+	w = getPage(handler, "/path?from=e-1&to=e-2&hops=1&format=json")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	result := PathQueryResult{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, PathQueryResult{
+		From:  "e-1",
+		To:    "e-2",
+		Hops:  1,
+		Paths: [][]string{{"e-1", "e-2"}},
+	}, result)
+
+	// Entities that aren't connected
+	w = getPage(handler, "/path?from=e-1&to=e-1000&format=json")
+	assert.Equal(t, http.StatusOK, w.Code)
+	result = PathQueryResult{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, 3, result.Hops)
+	assert.Equal(t, [][]string{}, result.Paths)
+
+	w = getPage(handler, "/path?from=e-1&to=e-1000")
+	assert.Contains(t, w.Body.String(), "No paths found between e-1 and e-1000 within 3 hops")
+
+	// Invalid queries
+	w = getPage(handler, "/path?from=e-1&format=json")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	result = PathQueryResult{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, "the from and to entity IDs must both be given", result.Error)
+
+	w = getPage(handler, "/path?from=e-1&to=e-2&hops=9")
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "invalid number of hops: 9")
+}
@@ -0,0 +1,50 @@
+// The pprof profiling endpoints are only registered if profiling is enabled and they can only be
+// used with the admin token, as the profiles reveal details of the server.
+
+package server
+
+import (
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+)
+
+// profilingUrl is the prefix of the pprof endpoints.
+const profilingUrl = "/debug/pprof/"
+
+// SetProfiling enables or disables the pprof endpoints. The endpoints also require the admin token.
+func (j *JobServer) SetProfiling(enabled bool) {
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Bool("profilingEnabled", enabled).
+		Msg("Setting whether the profiling endpoints are enabled")
+
+	j.profiling = enabled
+}
+
+// adminOnly wraps the handler so that it can only be used with the admin token.
+func (j *JobServer) adminOnly(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if !j.isAdmin(req) {
+			logging.Logger.Warn().
+				Str(logging.ComponentField, componentName).
+				Str("url", req.URL.Path).
+				Msg("Request to an admin-only endpoint without the admin token")
+
+			http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+			return
+		}
+
+		handler(w, req)
+	}
+}
+
+// registerProfiling endpoints with the router.
+func (j *JobServer) registerProfiling(mux *http.ServeMux) {
+	mux.HandleFunc(profilingUrl, j.adminOnly(pprof.Index))
+	mux.HandleFunc(profilingUrl+"cmdline", j.adminOnly(pprof.Cmdline))
+	mux.HandleFunc(profilingUrl+"profile", j.adminOnly(pprof.Profile))
+	mux.HandleFunc(profilingUrl+"symbol", j.adminOnly(pprof.Symbol))
+	mux.HandleFunc(profilingUrl+"trace", j.adminOnly(pprof.Trace))
+}
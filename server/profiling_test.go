@@ -0,0 +1,50 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProfilingEndpoints(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	getWithToken := func(url string, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		if len(token) > 0 {
+			req.Header.Set(AdminTokenHeader, token)
+		}
+		w := httptest.NewRecorder()
+		server.Routes().ServeHTTP(w, req)
+		return w
+	}
+
+	// The endpoints aren't registered by default
+	server.SetAdminToken("secret")
+	w := getWithToken("/debug/pprof/", "secret")
+	assert.NotContains(t, w.Body.String(), "goroutine")
+
+	// The endpoints require the admin token
+	server.SetProfiling(true)
+	w = getWithToken("/debug/pprof/", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = getWithToken("/debug/pprof/", "wrong")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+
+	w = getWithToken("/debug/pprof/", "secret")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "goroutine")
+
+	w = getWithToken("/debug/pprof/heap?debug=1", "secret")
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	// Without an admin token, the endpoints can't be used
+	server.SetAdminToken("")
+	w = getWithToken("/debug/pprof/", "")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
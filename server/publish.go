@@ -0,0 +1,109 @@
+// The result files of the completed jobs can be copied to S3-compatible object storage by a
+// publisher (see the publish package), so that they can feed downstream pipelines. The URLs of the
+// objects are shown on the results page of a job and are included in its JSON status.
+
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/i18n"
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cdclaxton/shortest-path-web-app/publish"
+)
+
+// Types of the jobs in the keys of the published results
+const (
+	shortestPathKind = "shortest-path"
+	spiderKind       = "spider"
+)
+
+// publishTimeout is the maximum time to copy the result files of a job to object storage
+const publishTimeout = 5 * time.Minute
+
+// publishWarning builds the warning to display to the user when result files couldn't be published.
+func publishWarning(failed int) *i18n.Message {
+	return i18n.NewMessage("job.publishWarning", failed)
+}
+
+// publishResults of a job with the publisher, which is nil if the results aren't published. A job
+// doesn't fail if its results can't be published, so a failure is logged and returned as a warning
+// for the user. Blank filepaths are ignored.
+func publishResults(publisher publish.Publisher, kind string, guid string, filepaths []string) (
+	[]job.PublishedResult, []*i18n.Message) {
+
+	if publisher == nil {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+
+	completed := time.Now()
+	published := []job.PublishedResult{}
+	failed := 0
+
+	for _, filepath := range filepaths {
+		if len(filepath) == 0 {
+			continue
+		}
+
+		keywords := publish.Keywords(kind, guid, filepath, completed)
+		url, err := publisher.Publish(ctx, filepath, keywords)
+		if err != nil {
+			logging.Logger.Error().
+				Str(logging.ComponentField, componentName).
+				Str(loggingGUIDField, guid).
+				Str("filepath", filepath).
+				Err(err).
+				Msg("Failed to publish the result file of the job")
+
+			failed += 1
+			continue
+		}
+
+		published = append(published, job.PublishedResult{
+			Filename: keywords[publish.FilenameKeyword],
+			Url:      url,
+		})
+	}
+
+	if failed > 0 {
+		return published, []*i18n.Message{publishWarning(failed)}
+	}
+
+	return published, nil
+}
+
+// publishedUrls of the result files of a job.
+func publishedUrls(published []job.PublishedResult) []string {
+	urls := []string{}
+	for _, result := range published {
+		urls = append(urls, result.Url)
+	}
+	return urls
+}
+
+// SetPublisher of the result files of the jobs (nil to not publish them).
+func (j *JobRunner) SetPublisher(publisher publish.Publisher) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Bool("enabled", publisher != nil).
+		Msg("Setting the publisher of the results of the jobs")
+
+	j.publisher = publisher
+}
+
+// SetPublisher of the result files of the spider jobs (nil to not publish them).
+func (j *SpiderJobRunner) SetPublisher(publisher publish.Publisher) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Bool("enabled", publisher != nil).
+		Msg("Setting the publisher of the results of the spider jobs")
+
+	j.publisher = publisher
+}
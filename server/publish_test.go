@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/cdclaxton/shortest-path-web-app/publish"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakePublisher records the files published and fails for the files with the extension.
+type fakePublisher struct {
+	failExtension string
+	published     []string
+	lock          sync.Mutex
+}
+
+func (p *fakePublisher) Publish(ctx context.Context, localPath string,
+	keywords map[string]string) (string, error) {
+
+	if filepath.Ext(localPath) == p.failExtension {
+		return "", errors.New("upload failed")
+	}
+
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.published = append(p.published, localPath)
+
+	return "https://results.example.com/" + keywords[publish.KindKeyword] + "/" +
+		keywords[publish.FilenameKeyword], nil
+}
+
+func TestPublishResults(t *testing.T) {
+
+	// Nothing is published without a publisher
+	published, warnings := publishResults(nil, shortestPathKind, "guid-1", []string{"a.xlsx"})
+	assert.Nil(t, published)
+	assert.Nil(t, warnings)
+
+	// A file that fails to publish is a warning
+	publisher := &fakePublisher{failExtension: ".anx"}
+	published, warnings = publishResults(publisher, spiderKind, "guid-1",
+		[]string{"/tmp/guid-1.xlsx", "", "/tmp/guid-1.anx"})
+
+	assert.Equal(t, []job.PublishedResult{{
+		Filename: "guid-1.xlsx",
+		Url:      "https://results.example.com/spider/guid-1.xlsx",
+	}}, published)
+	assert.Equal(t, []string{"/tmp/guid-1.xlsx"}, publisher.published)
+	assert.Equal(t, 1, len(warnings))
+	assert.Equal(t, "job.publishWarning", warnings[0].Key)
+}
+
+func TestPublishJobResults(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	publisher := &fakePublisher{failExtension: ".anx"}
+	server.runner.SetPublisher(publisher)
+	server.spiderRunner.SetPublisher(publisher)
+
+	handler := server.Routes()
+
+	// Shortest path job, where the ANX file fails to publish
+	w := postForm(handler, "/upload", buildFormData(2, "Dataset-1", "e-1, e-2", "", "", "", ""))
+	assert.Equal(t, http.StatusFound, w.Code)
+	guid := extractGuidFromLocation(t, w.Result().Header.Get("Location"))
+	waitForJobsToFinish(server.runner)
+
+	j1, err := server.runner.GetJob(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, job.CompleteResults, j1.Progress.State)
+	assert.Equal(t, []job.PublishedResult{{
+		Filename: guid + ".xlsx",
+		Url:      "https://results.example.com/shortest-path/" + guid + ".xlsx",
+	}}, j1.PublishedResults)
+
+	page := getPage(handler, "/job/"+guid).Body.String()
+	assert.Contains(t, page, `href="https://results.example.com/shortest-path/`+guid+`.xlsx"`)
+	assert.Contains(t, page, "1 of the result files couldn")
+
+	status := getPage(handler, "/job/"+guid+"?format=json").Body.String()
+	assert.Contains(t, status,
+		`"published":["https://results.example.com/shortest-path/`+guid+`.xlsx"]`)
+
+	// Spider job
+	w = postForm(handler, "/spider-upload", buildSpiderFormData(1, "e-1"))
+	spiderGuid := extractSpiderGuidFromLocation(t, w.Result().Header.Get("Location"))
+	waitForSpiderJobsToFinish(server.spiderRunner)
+
+	page = getPage(handler, "/spider-job/"+spiderGuid).Body.String()
+	assert.Contains(t, page, `href="https://results.example.com/spider/`+spiderGuid+`.xlsx"`)
+}
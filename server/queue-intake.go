@@ -0,0 +1,248 @@
+// Queue intake submits the shortest path jobs read from a message queue and publishes an event
+// with the result locations when each job finishes, so that automated systems can enqueue many
+// small path queries without calling the HTTP endpoints. The number of jobs from the queue in
+// progress at once is capped, so a backlog on the queue doesn't swamp the job runner.
+
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/i18n"
+	"github.com/cdclaxton/shortest-path-web-app/intake"
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cdclaxton/shortest-path-web-app/pathfilter"
+)
+
+// Constants of the queue intake
+const (
+	DefaultMaxQueueJobs   = 10              // Default maximum number of jobs from the queue in progress
+	queueRetryInterval    = 5 * time.Second // Interval before receiving again after the queue fails
+	loggingRequestIdField = "requestId"
+)
+
+var ErrInvalidMaxQueueJobs = errors.New("maximum number of jobs from the queue must be positive")
+
+// queueJobConfiguration from the request, which is checked against the job server's limits and
+// rules.
+func (j *JobServer) queueJobConfiguration(request *intake.JobRequest) (*job.JobConfiguration, error) {
+
+	numberHops, err := j.searchLimits.parseHops(strconv.Itoa(request.NumberHops))
+	if err != nil {
+		return nil, err
+	}
+
+	conf := job.JobConfiguration{
+		MaxNumberHops:       numberHops,
+		EntitySets:          []job.EntitySet{},
+		RetryWithFewerHops:  request.RetryWithFewerHops,
+		Directed:            request.Directed,
+		ExcludedEntityIds:   j.entityIdNormaliser.NormaliseAll(request.ExcludedEntityIds),
+		Waypoints:           j.entityIdNormaliser.NormaliseAll(request.Waypoints),
+		PathMatrix:          request.PathMatrix,
+		MinDocumentsPerLink: request.MinDocuments,
+		Temporal:            request.Temporal,
+		Timeout:             time.Duration(request.TimeoutMinutes) * time.Minute,
+	}
+
+	if expression := strings.TrimSpace(request.PathFilter); len(expression) > 0 {
+		filter, err := pathfilter.Parse(expression)
+		if err != nil {
+			return nil, err
+		}
+		conf.PathFilter = filter.String()
+	}
+
+	for _, dataset := range request.Datasets {
+		name := strings.TrimSpace(dataset.Name)
+		if len(name) == 0 {
+			return nil, ErrDatasetNoName
+		}
+
+		entityIds := uniqueEntityIds(j.entityIdNormaliser.NormaliseAll(dataset.EntityIds))
+		if len(entityIds) == 0 {
+			return nil, ErrDatasetNoEntities
+		}
+
+		conf.EntitySets = append(conf.EntitySets, job.EntitySet{
+			Name:      name,
+			EntityIds: entityIds,
+		})
+	}
+
+	if len(conf.EntitySets) == 0 {
+		return nil, i18n.NewMessage("error.noDatasets")
+	}
+
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := j.jobLimits.Check(&conf); err != nil {
+		return nil, err
+	}
+
+	if err := j.entityIdRules.Check(&conf); err != nil {
+		return nil, err
+	}
+
+	return &conf, nil
+}
+
+// waitForQueueJob to finish, returning its completion event.
+func (j *JobServer) waitForQueueJob(ctx context.Context, requestId string, guid string) (
+	intake.CompletionEvent, error) {
+
+	events, current, err := j.runner.Subscribe(guid)
+	if err != nil {
+		return intake.CompletionEvent{}, err
+	}
+	defer j.runner.Unsubscribe(guid, events)
+
+	for !current.Finished {
+		select {
+		case <-ctx.Done():
+			return intake.CompletionEvent{}, ctx.Err()
+		case current = <-events:
+		}
+	}
+
+	j1, err := j.runner.GetJob(guid)
+	if err != nil {
+		return intake.CompletionEvent{}, err
+	}
+
+	language := j.translator.DefaultLanguage()
+	event := intake.CompletionEvent{
+		RequestId: requestId,
+		Guid:      guid,
+		State:     string(j1.Progress.State),
+		Error:     j.translator.TranslateError(language, j1.Error),
+		Warnings:  j.translator.TranslateMessages(language, j1.Warnings),
+		Published: publishedUrls(j1.PublishedResults),
+	}
+
+	if j1.Progress.State == job.CompleteResults {
+		event.Download = fmt.Sprintf("%v/download/%v", j.basePath, guid)
+	}
+
+	return event, nil
+}
+
+// handleQueueMessage by submitting its job and, once the job has finished, publishing the job's
+// completion event and acknowledging the message. A message that isn't a valid job request is
+// acknowledged after publishing an event explaining why it was rejected.
+func (j *JobServer) handleQueueMessage(ctx context.Context, queue intake.Queue, msg intake.Message) {
+
+	logger := logging.Logger.With().
+		Str(logging.ComponentField, componentName).
+		Str("messageId", msg.Id).
+		Logger()
+
+	var event intake.CompletionEvent
+	request, err := intake.ParseJobRequest(msg.Body)
+
+	var conf *job.JobConfiguration
+	if err == nil {
+		event.RequestId = request.RequestId
+		conf, err = j.queueJobConfiguration(request)
+	}
+
+	var guid string
+	if err == nil {
+		guid, err = j.runner.Submit(conf)
+	}
+
+	if err != nil {
+		logger.Warn().Err(err).Msg("Rejected the job request from the queue")
+		event.State = intake.RejectedState
+		event.Error = j.translator.TranslateError(j.translator.DefaultLanguage(), err)
+
+	} else {
+		logger.Info().
+			Str(loggingRequestIdField, event.RequestId).
+			Str(loggingGUIDField, guid).
+			Msg("Submitted the job from the queue")
+
+		event, err = j.waitForQueueJob(ctx, event.RequestId, guid)
+		if err != nil {
+			// The message isn't acknowledged, so it is received again
+			logger.Error().Err(err).Str(loggingGUIDField, guid).Msg("Failed to wait for the job")
+			return
+		}
+	}
+
+	body, err := json.Marshal(event)
+	if err != nil {
+		logger.Error().Err(err).Msg("Failed to encode the completion event")
+		return
+	}
+
+	if err := queue.Publish(ctx, body); err != nil {
+		logger.Error().Err(err).Msg("Failed to publish the completion event")
+		return
+	}
+
+	if err := queue.Ack(ctx, msg); err != nil {
+		logger.Error().Err(err).Msg("Failed to acknowledge the message")
+	}
+}
+
+// ConsumeQueue submits the jobs read from the queue until the context is done, with at most
+// maxJobs of them in progress at once.
+func (j *JobServer) ConsumeQueue(ctx context.Context, queue intake.Queue, maxJobs int) error {
+
+	// Preconditions
+	if maxJobs <= 0 {
+		return ErrInvalidMaxQueueJobs
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Int("maxJobs", maxJobs).
+		Msg("Consuming job requests from the queue")
+
+	slots := make(chan struct{}, maxJobs)
+
+	for {
+		// Wait for a job to finish before receiving another message
+		select {
+		case <-ctx.Done():
+			return nil
+		case slots <- struct{}{}:
+		}
+
+		msg, err := queue.Receive(ctx)
+		if err != nil {
+			<-slots
+
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			logging.Logger.Error().
+				Str(logging.ComponentField, componentName).
+				Err(err).
+				Msg("Failed to receive a message from the queue")
+
+			select {
+			case <-ctx.Done():
+				return nil
+			case <-time.After(queueRetryInterval):
+			}
+			continue
+		}
+
+		go func() {
+			defer func() { <-slots }()
+			j.handleQueueMessage(ctx, queue, msg)
+		}()
+	}
+}
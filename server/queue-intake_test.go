@@ -0,0 +1,126 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/intake"
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/stretchr/testify/assert"
+)
+
+// channelQueue delivers the messages sent on a channel and records the acknowledgements and events.
+type channelQueue struct {
+	messages chan intake.Message
+	acked    []string
+	events   []intake.CompletionEvent
+	lock     sync.Mutex
+}
+
+func newChannelQueue() *channelQueue {
+	return &channelQueue{messages: make(chan intake.Message, 10)}
+}
+
+func (q *channelQueue) Receive(ctx context.Context) (intake.Message, error) {
+	select {
+	case <-ctx.Done():
+		return intake.Message{}, ctx.Err()
+	case msg := <-q.messages:
+		return msg, nil
+	}
+}
+
+func (q *channelQueue) Ack(ctx context.Context, msg intake.Message) error {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.acked = append(q.acked, msg.Id)
+	return nil
+}
+
+func (q *channelQueue) Publish(ctx context.Context, event []byte) error {
+	var decoded intake.CompletionEvent
+	if err := json.Unmarshal(event, &decoded); err != nil {
+		return err
+	}
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.events = append(q.events, decoded)
+	return nil
+}
+
+// numberOfEvents published to the queue.
+func (q *channelQueue) numberOfEvents() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return len(q.events)
+}
+
+func TestConsumeQueueInvalidMaxJobs(t *testing.T) {
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	assert.ErrorIs(t, server.ConsumeQueue(context.Background(), newChannelQueue(), 0),
+		ErrInvalidMaxQueueJobs)
+}
+
+func TestConsumeQueue(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	publisher := &fakePublisher{}
+	server.runner.SetPublisher(publisher)
+
+	queue := newChannelQueue()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	stopped := make(chan error)
+	go func() {
+		stopped <- server.ConsumeQueue(ctx, queue, 2)
+	}()
+
+	queue.messages <- intake.Message{Id: "m-1", Body: []byte(`{
+		"requestId": "r-1",
+		"numberHops": 2,
+		"datasets": [{"name": "Dataset-1", "entityIds": ["e-1", "e-2"]}]
+	}`)}
+	queue.messages <- intake.Message{Id: "m-2", Body: []byte(`{"requestId": "r-2", "numberHops": 2}`)}
+	queue.messages <- intake.Message{Id: "m-3", Body: []byte(`not a job`)}
+
+	assert.Eventually(t, func() bool { return queue.numberOfEvents() == 3 },
+		5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	assert.NoError(t, <-stopped)
+
+	events := map[string]intake.CompletionEvent{}
+	for _, event := range queue.events {
+		events[event.RequestId] = event
+	}
+
+	// The job completed with results
+	completed := events["r-1"]
+	assert.NotEmpty(t, completed.Guid)
+	assert.Equal(t, string(job.CompleteResults), completed.State)
+	assert.Equal(t, "/download/"+completed.Guid, completed.Download)
+	assert.Equal(t, []string{"https://results.example.com/shortest-path/" + completed.Guid + ".xlsx",
+		"https://results.example.com/shortest-path/" + completed.Guid + ".anx"}, completed.Published)
+
+	j1, err := server.runner.GetJob(completed.Guid)
+	assert.NoError(t, err)
+	assert.Equal(t, job.CompleteResults, j1.Progress.State)
+
+	// The job without datasets was rejected
+	assert.Equal(t, intake.RejectedState, events["r-2"].State)
+	assert.Empty(t, events["r-2"].Guid)
+	assert.NotEmpty(t, events["r-2"].Error)
+
+	// The message that isn't a job was rejected
+	assert.Equal(t, intake.RejectedState, events[""].State)
+
+	assert.ElementsMatch(t, []string{"m-1", "m-2", "m-3"}, queue.acked)
+}
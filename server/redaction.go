@@ -0,0 +1,54 @@
+package server
+
+import (
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cdclaxton/shortest-path-web-app/redaction"
+	"github.com/cdclaxton/shortest-path-web-app/search"
+)
+
+// SetRedactor whose rules redact the attributes of the entity and its documents on the entity page.
+// A nil redactor doesn't redact any attributes.
+func (j *JobServer) SetRedactor(redactor *redaction.Redactor) {
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Bool("redaction", redactor != nil).
+		Msg("Setting the redactor")
+
+	j.redactor = redactor
+}
+
+// redactAttributes returns a copy of the attributes with the values of the redacted attributes
+// replaced by the placeholder.
+func (j *JobServer) redactAttributes(attributes []search.Attribute) []search.Attribute {
+
+	redacted := make([]search.Attribute, len(attributes))
+	for idx, attribute := range attributes {
+		redacted[idx] = attribute
+		if j.redactor.IsRedacted(attribute.Key) {
+			redacted[idx].Value = j.redactor.Placeholder()
+		}
+	}
+
+	return redacted
+}
+
+// redactEntityPage redacts the attributes of the entity and its linked documents.
+func (j *JobServer) redactEntityPage(entity search.SearchEntity) search.SearchEntity {
+
+	if j.redactor == nil {
+		return entity
+	}
+
+	details := entity.BipartiteDetails
+	details.EntityAttributes = j.redactAttributes(details.EntityAttributes)
+
+	documents := make([]search.BipartiteDocument, len(details.LinkedDocuments))
+	for idx, document := range details.LinkedDocuments {
+		documents[idx] = document
+		documents[idx].Attributes = j.redactAttributes(document.Attributes)
+	}
+	details.LinkedDocuments = documents
+
+	entity.BipartiteDetails = details
+	return entity
+}
@@ -0,0 +1,47 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/redaction"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEntityPageRedaction(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	handler := server.Routes()
+
+	// No attributes are redacted by default
+	w := getPage(handler, "/entity/e-1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, "<b>DOB</b>: 03/04/1981")
+	assert.Contains(t, body, "<b>Title</b>: Summary 1")
+
+	redactor, err := redaction.NewRedactor(redaction.RulesConfig{
+		Attributes:  []string{"DOB"},
+		Patterns:    []string{"^Tit"},
+		Placeholder: "WITHHELD",
+	})
+	assert.NoError(t, err)
+	server.SetRedactor(redactor)
+
+	w = getPage(handler, "/entity/e-1")
+	assert.Equal(t, http.StatusOK, w.Code)
+	body = w.Body.String()
+	assert.Contains(t, body, "<b>Forename</b>: Bob")
+	assert.Contains(t, body, "<b>DOB</b>: WITHHELD")
+	assert.NotContains(t, body, "03/04/1981")
+	assert.Contains(t, body, "<b>Title</b>: WITHHELD")
+	assert.Contains(t, body, "<b>Date</b>: 06/08/2022")
+
+	// The entity can still be found
+	entity, err := server.runner.searchEngine.Bipartite.GetEntity("e-1")
+	assert.NoError(t, err)
+	assert.Equal(t, "03/04/1981", entity.Attributes["DOB"])
+}
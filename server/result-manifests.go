@@ -0,0 +1,129 @@
+// The result file of a completed job can have an integrity manifest (see the manifest package),
+// optionally signed with a deployment key, so that results attached to a case file are
+// tamper-evident. The manifest is written alongside the result file, published with it and can be
+// downloaded from /download/{guid}/manifest (or /spider-download/{guid}/manifest).
+
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+
+	"github.com/cdclaxton/shortest-path-web-app/filedetector"
+	"github.com/cdclaxton/shortest-path-web-app/i18n"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cdclaxton/shortest-path-web-app/manifest"
+)
+
+// Suffix of a job's download URL for the manifest of its result file
+const manifestSuffix = "/manifest"
+
+// makeManifestFilepath for storage of the manifest of the result file of a job.
+func makeManifestFilepath(folder string, guid string) string {
+	return path.Join(folder, fmt.Sprintf("%v.manifest.json", guid))
+}
+
+// writeManifest of the result file of a job, returning the location of the manifest or a blank
+// location if the generator is nil. A job doesn't fail if its manifest can't be written, so a
+// failure is logged and returned as a warning for the user.
+func writeManifest(generator *manifest.Generator, quota DiskQuota, folder string, guid string,
+	resultFile string, config interface{}, provenance filedetector.DataProvenance) (
+	string, []*i18n.Message) {
+
+	if generator == nil {
+		return "", nil
+	}
+
+	filepath := makeManifestFilepath(folder, guid)
+	err := quota.writeResultFile(folder, filepath, func() error {
+		_, err := generator.Generate(filepath, guid, resultFile, config, provenance.Signature)
+		return err
+	})
+
+	if err != nil {
+		logging.Logger.Error().
+			Str(logging.ComponentField, componentName).
+			Str(loggingGUIDField, guid).
+			Err(err).
+			Msg("Failed to write the manifest of the result file of the job")
+
+		return "", []*i18n.Message{i18n.NewMessage("job.manifestWarning")}
+	}
+
+	return filepath, nil
+}
+
+// SetManifestGenerator of the manifests of the result files of the jobs (nil to not make them).
+func (j *JobRunner) SetManifestGenerator(generator *manifest.Generator) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Bool("enabled", generator != nil).
+		Bool("signed", generator.Signed()).
+		Msg("Setting the manifest generator of the results of the jobs")
+
+	j.manifests = generator
+}
+
+// SetManifestGenerator of the manifests of the result files of the spider jobs (nil to not make
+// them).
+func (j *SpiderJobRunner) SetManifestGenerator(generator *manifest.Generator) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Bool("enabled", generator != nil).
+		Bool("signed", generator.Signed()).
+		Msg("Setting the manifest generator of the results of the spider jobs")
+
+	j.manifests = generator
+}
+
+// handleDownloadManifest of the result file of a job.
+func (j *JobServer) handleDownloadManifest(w http.ResponseWriter, req *http.Request, guid string) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str(loggingGUIDField, guid).
+		Msg("Received request for the manifest of a job")
+
+	j1, err := j.runner.GetJob(guid)
+	if err != nil || len(j1.ManifestFile) == 0 {
+
+		logging.Logger.Info().
+			Str(logging.ComponentField, componentName).
+			Str(loggingGUIDField, guid).
+			Msg("Job or manifest not found")
+
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	j.serveJobFile(w, req, guid, j1.ManifestFile, guid+".manifest.json", "application/json",
+		j.jobFailedTemplate, "error.readManifestFile")
+}
+
+// spiderHandleDownloadManifest of the result file of a spider job.
+func (j *JobServer) spiderHandleDownloadManifest(w http.ResponseWriter, req *http.Request,
+	guid string) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str(loggingGUIDField, guid).
+		Msg("Received request for the manifest of a spider job")
+
+	j1, err := j.spiderRunner.GetJob(guid)
+	if err != nil || len(j1.ManifestFile) == 0 {
+
+		logging.Logger.Info().
+			Str(logging.ComponentField, componentName).
+			Str(loggingGUIDField, guid).
+			Msg("Spider job or manifest not found")
+
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	j.serveJobFile(w, req, guid, j1.ManifestFile, "spider-"+guid+".manifest.json",
+		"application/json", j.spiderJobFailedTemplate, "error.readManifestFile")
+}
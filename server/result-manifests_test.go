@@ -0,0 +1,88 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/filedetector"
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/cdclaxton/shortest-path-web-app/manifest"
+	"github.com/cdclaxton/shortest-path-web-app/set"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteManifestDisabled(t *testing.T) {
+	filepath, warnings := writeManifest(nil, DiskQuota{}, t.TempDir(), "1234", "result.xlsx", nil,
+		filedetector.DataProvenance{})
+	assert.Empty(t, filepath)
+	assert.Nil(t, warnings)
+
+	// The result file doesn't exist
+	filepath, warnings = writeManifest(manifest.NewGenerator(nil), DiskQuota{}, t.TempDir(), "1234",
+		"does-not-exist.xlsx", nil, filedetector.DataProvenance{})
+	assert.Empty(t, filepath)
+	assert.Len(t, warnings, 1)
+}
+
+func TestDownloadManifest(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+	handler := server.Routes()
+
+	// The same job is run with and without a manifest
+	assert.NoError(t, server.SetDuplicateJobWindow(0))
+
+	// Manifests are disabled
+	guid := submitAndWait(t, server, "e-1, e-2")
+	assert.Equal(t, http.StatusNotFound, getPage(handler, "/download/"+guid+"/manifest").Code)
+	assert.NotContains(t, getPage(handler, "/job/"+guid).Body.String(), "/manifest")
+
+	key := []byte("deployment-key")
+	server.runner.SetManifestGenerator(manifest.NewGenerator(key))
+
+	guid = submitAndWait(t, server, "e-1, e-2")
+	assert.Contains(t, getPage(handler, "/job/"+guid).Body.String(), "../download/"+guid+"/manifest")
+
+	w := getPage(handler, "/download/"+guid+"/manifest")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+	m := manifest.Manifest{}
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &m))
+	assert.Equal(t, guid, m.Guid)
+
+	valid, err := m.Verify(key)
+	assert.NoError(t, err)
+	assert.True(t, valid)
+
+	j1, err := server.runner.GetJob(guid)
+	assert.NoError(t, err)
+	assert.NoError(t, m.VerifyFile(j1.ResultFile))
+	assert.Equal(t, j1.Provenance.Signature, m.GraphSignature)
+
+	// Unknown job
+	assert.Equal(t, http.StatusNotFound, getPage(handler, "/download/1234/manifest").Code)
+}
+
+func TestSpiderJobManifest(t *testing.T) {
+	spiderJobRunner := makeSpiderJobRunner(t)
+	defer cleanUpSpiderJobRunner(t, spiderJobRunner)
+	spiderJobRunner.SetManifestGenerator(manifest.NewGenerator(nil))
+
+	conf, err := job.NewSpiderJobConfiguration(1, set.NewPopulatedSet("e-1"))
+	assert.NoError(t, err)
+
+	guid, err := spiderJobRunner.Submit(conf)
+	assert.NoError(t, err)
+	waitForSpiderJobsToFinish(spiderJobRunner)
+
+	j1, err := spiderJobRunner.GetJob(guid)
+	assert.NoError(t, err)
+
+	m, err := manifest.Read(j1.ManifestFile)
+	assert.NoError(t, err)
+	assert.Empty(t, m.Signature)
+	assert.NoError(t, m.VerifyFile(j1.ResultFile))
+}
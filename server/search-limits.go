@@ -0,0 +1,130 @@
+package server
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/cdclaxton/shortest-path-web-app/i18n"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+)
+
+var ErrInvalidSearchLimits = errors.New("invalid search limits")
+
+// SearchLimits are the permitted numbers of hops of a shortest path job (or path query) and steps of
+// a spider job, which depend on the size of a deployment's graph.
+type SearchLimits struct {
+	MinHops  int // Minimum number of hops from an entity to another
+	MaxHops  int // Maximum number of hops from an entity to another
+	MinSteps int // Minimum number of steps for spidering
+	MaxSteps int // Maximum number of steps for spidering
+}
+
+// DefaultSearchLimits if the deployment doesn't set them.
+var DefaultSearchLimits = SearchLimits{
+	MinHops:  MinimumNumberHops,
+	MaxHops:  MaximumNumberHops,
+	MinSteps: MinimumNumberSteps,
+	MaxSteps: MaximumNumberSteps,
+}
+
+// Validate the limits.
+func (l SearchLimits) Validate() error {
+	if l.MinHops < 1 || l.MaxHops < l.MinHops || l.MinSteps < 0 || l.MaxSteps < l.MinSteps {
+		return ErrInvalidSearchLimits
+	}
+
+	return nil
+}
+
+// options from the minimum to the maximum for a drop-down list.
+func options(minimum int, maximum int) []int {
+	values := []int{}
+	for value := minimum; value <= maximum; value++ {
+		values = append(values, value)
+	}
+	return values
+}
+
+// HopOptions for the drop-down lists of the number of hops.
+func (l SearchLimits) HopOptions() []int {
+	return options(l.MinHops, l.MaxHops)
+}
+
+// StepOptions for the drop-down list of the number of steps.
+func (l SearchLimits) StepOptions() []int {
+	return options(l.MinSteps, l.MaxSteps)
+}
+
+// defaultHops within the limits, i.e. the preferred number of hops unless it is out of range.
+func (l SearchLimits) defaultHops(preferred int) int {
+	if preferred < l.MinHops {
+		return l.MinHops
+	}
+	if preferred > l.MaxHops {
+		return l.MaxHops
+	}
+	return preferred
+}
+
+// parseHops from its string representation and check it is within the permitted range.
+func (l SearchLimits) parseHops(numberHops string) (int, error) {
+
+	if len(numberHops) == 0 {
+		return 0, i18n.NewMessage("error.numberOfHopsBlank")
+	}
+
+	// Convert the string version of the number of hops to an integer
+	value, err := strconv.Atoi(numberHops)
+	if err != nil {
+		return 0, i18n.NewMessage("error.invalidNumberOfHops", numberHops)
+	}
+
+	// Validate the number of hops
+	if value < l.MinHops || value > l.MaxHops {
+		return 0, i18n.NewMessage("error.invalidNumberOfHops", numberHops)
+	}
+
+	return value, nil
+}
+
+// parseSteps from its string representation and check it is within the permitted range.
+func (l SearchLimits) parseSteps(numberSteps string) (int, error) {
+
+	if len(numberSteps) == 0 {
+		return 0, i18n.NewMessage("error.numberOfStepsBlank")
+	}
+
+	// Convert the string version of the number of steps to an integer
+	value, err := strconv.Atoi(numberSteps)
+	if err != nil {
+		return 0, i18n.NewMessage("error.invalidNumberOfSteps", numberSteps)
+	}
+
+	// Validate the number of steps
+	if value < l.MinSteps || value > l.MaxSteps {
+		return 0, i18n.NewMessage("error.invalidNumberOfSteps", numberSteps)
+	}
+
+	return value, nil
+}
+
+// SetSearchLimits sets the permitted numbers of hops and steps, which are also the options of the
+// drop-down lists on the index pages.
+func (j *JobServer) SetSearchLimits(limits SearchLimits) error {
+
+	// Precondition
+	if err := limits.Validate(); err != nil {
+		return err
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Int("minHops", limits.MinHops).
+		Int("maxHops", limits.MaxHops).
+		Int("minSteps", limits.MinSteps).
+		Int("maxSteps", limits.MaxSteps).
+		Msg("Setting the search limits")
+
+	j.searchLimits = limits
+	return j.cachePages()
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/i18n"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSearchLimitsValidate(t *testing.T) {
+	testCases := []struct {
+		limits   SearchLimits
+		expected error
+	}{
+		{
+			limits:   DefaultSearchLimits,
+			expected: nil,
+		},
+		{
+			limits:   SearchLimits{MinHops: 1, MaxHops: 8, MinSteps: 0, MaxSteps: 1},
+			expected: nil,
+		},
+		{
+			limits:   SearchLimits{MinHops: 0, MaxHops: 3, MinSteps: 0, MaxSteps: 3},
+			expected: ErrInvalidSearchLimits,
+		},
+		{
+			limits:   SearchLimits{MinHops: 4, MaxHops: 3, MinSteps: 0, MaxSteps: 3},
+			expected: ErrInvalidSearchLimits,
+		},
+		{
+			limits:   SearchLimits{MinHops: 1, MaxHops: 3, MinSteps: -1, MaxSteps: 3},
+			expected: ErrInvalidSearchLimits,
+		},
+		{
+			limits:   SearchLimits{MinHops: 1, MaxHops: 3, MinSteps: 2, MaxSteps: 1},
+			expected: ErrInvalidSearchLimits,
+		},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expected, testCase.limits.Validate())
+	}
+}
+
+func TestSearchLimitsOptions(t *testing.T) {
+	limits := SearchLimits{MinHops: 2, MaxHops: 4, MinSteps: 0, MaxSteps: 1}
+
+	assert.Equal(t, []int{2, 3, 4}, limits.HopOptions())
+	assert.Equal(t, []int{0, 1}, limits.StepOptions())
+
+	assert.Equal(t, 2, limits.defaultHops(1))
+	assert.Equal(t, 3, limits.defaultHops(3))
+	assert.Equal(t, 4, limits.defaultHops(5))
+}
+
+func TestSearchLimitsParse(t *testing.T) {
+	limits := SearchLimits{MinHops: 1, MaxHops: 3, MinSteps: 0, MaxSteps: 2}
+
+	hops, err := limits.parseHops("3")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, hops)
+
+	_, err = limits.parseHops("6")
+	assert.Equal(t, i18n.NewMessage("error.invalidNumberOfHops", "6"), err)
+
+	_, err = limits.parseHops("")
+	assert.Equal(t, i18n.NewMessage("error.numberOfHopsBlank"), err)
+
+	steps, err := limits.parseSteps("0")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, steps)
+
+	_, err = limits.parseSteps("3")
+	assert.Equal(t, i18n.NewMessage("error.invalidNumberOfSteps", "3"), err)
+}
+
+func TestSetSearchLimits(t *testing.T) {
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	assert.Equal(t, ErrInvalidSearchLimits, server.SetSearchLimits(SearchLimits{}))
+	assert.Equal(t, DefaultSearchLimits, server.searchLimits)
+
+	assert.NoError(t, server.SetSearchLimits(SearchLimits{MinHops: 1, MaxHops: 8, MinSteps: 0,
+		MaxSteps: 2}))
+
+	w := getPage(server.Routes(), "/")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `<option value="8"`)
+}
@@ -0,0 +1,190 @@
+// The web-app handles sensitive identifiers, so its pages are protected from being framed or
+// loading content from other sites, and the forms that submit jobs are protected from cross-site
+// request forgery (CSRF).
+//
+// The CSRF protection uses a double-submit token: the browser is given a random token in a cookie
+// and the forms hold the same token in a hidden field. A request from a browser that changes the
+// state of the server (e.g. submits a job, saves a template or annotates an entity) must hold the
+// same token in its cookie and form. A request is from a browser if it has an Origin,
+// Sec-Fetch-Site or Cookie header, so that API clients (e.g. the Go client) can still submit jobs
+// without a token. A request from one of the origins permitted by CORS doesn't need a
+// token either, as that origin is trusted.
+
+package server
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+)
+
+// Constants associated with the security of the web-app
+const (
+	CsrfCookieName        = "csrf"                   // Name of the cookie holding the CSRF token
+	CsrfTokenInputName    = "csrfToken"              // Name of the form field holding the CSRF token
+	csrfCookieAge         = 30 * 24 * time.Hour      // Lifetime of the CSRF cookie
+	csrfTokenBytes        = 32                       // Number of random bytes of a CSRF token
+	csrfTokenPlaceholder  = "csrf-token-placeholder" // Replaced with the CSRF token when a page is served
+	corsMaxAge            = "600"                    // Seconds a browser may cache a CORS preflight response
+	corsAllowedMethods    = "GET, POST"              // Methods permitted by CORS
+	corsAllowedHeaders    = "Accept, Content-Type"   // Headers permitted by CORS
+	contentSecurityPolicy = "default-src 'self'; " +
+		"img-src 'self' data:; " +
+		"font-src 'self' data:; " +
+		"style-src 'self' 'unsafe-inline'; " +
+		"script-src 'self'; " +
+		"connect-src 'self'; " +
+		"form-action 'self'; " +
+		"frame-ancestors 'none'; " +
+		"base-uri 'self'"
+)
+
+// SetCorsOrigins that may use the JSON API from a browser. CORS is disabled if there aren't any
+// origins.
+func (j *JobServer) SetCorsOrigins(origins []string) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Strs("origins", origins).
+		Msg("Setting the origins permitted by CORS")
+
+	j.corsOrigins = map[string]struct{}{}
+	for _, origin := range origins {
+		origin = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(origin), "/"))
+		if len(origin) > 0 {
+			j.corsOrigins[origin] = struct{}{}
+		}
+	}
+}
+
+// isCorsOrigin returns true if the origin is permitted by CORS.
+func (j *JobServer) isCorsOrigin(origin string) bool {
+	_, found := j.corsOrigins[strings.ToLower(origin)]
+	return len(origin) > 0 && found
+}
+
+// securityHeaders adds the security headers to the responses of the handler and answers the CORS
+// preflight requests of the permitted origins.
+func (j *JobServer) securityHeaders(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+
+		header := w.Header()
+		header.Set("Content-Security-Policy", contentSecurityPolicy)
+		header.Set("X-Frame-Options", "DENY")
+		header.Set("X-Content-Type-Options", "nosniff")
+		header.Set("Referrer-Policy", "same-origin")
+
+		origin := req.Header.Get("Origin")
+		if j.isCorsOrigin(origin) {
+			header.Set("Access-Control-Allow-Origin", origin)
+			header.Add("Vary", "Origin")
+
+			if req.Method == http.MethodOptions && len(req.Header.Get("Access-Control-Request-Method")) > 0 {
+				header.Set("Access-Control-Allow-Methods", corsAllowedMethods)
+				header.Set("Access-Control-Allow-Headers", corsAllowedHeaders)
+				header.Set("Access-Control-Max-Age", corsMaxAge)
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+
+		handler.ServeHTTP(w, req)
+	})
+}
+
+// readCsrfToken from the request's cookie. A blank token is returned if there isn't a valid token.
+func readCsrfToken(req *http.Request) string {
+	cookie, err := req.Cookie(CsrfCookieName)
+	if err != nil || len(cookie.Value) != csrfTokenBytes*2 {
+		return ""
+	}
+
+	if _, err := hex.DecodeString(cookie.Value); err != nil {
+		return ""
+	}
+
+	return cookie.Value
+}
+
+// csrfToken of the browser, where a new token is given to the browser if it doesn't have one.
+func csrfToken(w http.ResponseWriter, req *http.Request) string {
+
+	if token := readCsrfToken(req); len(token) > 0 {
+		return token
+	}
+
+	b := make([]byte, csrfTokenBytes)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+
+	token := hex.EncodeToString(b)
+	http.SetCookie(w, &http.Cookie{
+		Name:     CsrfCookieName,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(csrfCookieAge.Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	return token
+}
+
+// withCsrfToken replaces the placeholder of the CSRF token in the page with the browser's token.
+func withCsrfToken(w http.ResponseWriter, req *http.Request, page string) string {
+	if !strings.Contains(page, csrfTokenPlaceholder) {
+		return page
+	}
+
+	return strings.ReplaceAll(page, csrfTokenPlaceholder, csrfToken(w, req))
+}
+
+// isFromBrowser returns true if the request was made by a browser.
+func isFromBrowser(req *http.Request) bool {
+	return len(req.Header.Get("Origin")) > 0 || len(req.Header.Get("Sec-Fetch-Site")) > 0 ||
+		len(req.Header.Get("Cookie")) > 0
+}
+
+// checkCsrfToken of a request that changes the state of the server, i.e. the form that was
+// submitted from a browser must hold the browser's CSRF token. The form must have been parsed. If
+// the check fails, a 403 error is returned to the client and false is returned.
+func (j *JobServer) checkCsrfToken(w http.ResponseWriter, req *http.Request) bool {
+
+	if !isFromBrowser(req) || j.isCorsOrigin(req.Header.Get("Origin")) {
+		return true
+	}
+
+	cookieToken := readCsrfToken(req)
+	formToken := req.FormValue(CsrfTokenInputName)
+	if len(cookieToken) > 0 && subtle.ConstantTimeCompare([]byte(cookieToken), []byte(formToken)) == 1 {
+		return true
+	}
+
+	logging.Logger.Warn().
+		Str(logging.ComponentField, componentName).
+		Str("url", req.URL.Path).
+		Str("origin", req.Header.Get("Origin")).
+		Msg("Request without a valid CSRF token")
+
+	http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+	return false
+}
+
+// csrfProtected checks the CSRF token of the requests to the handler other than GET and HEAD
+// requests, i.e. the forms that change the state of the server. The forms that upload files check
+// the token themselves, once the size of the request has been limited.
+func (j *JobServer) csrfProtected(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet && req.Method != http.MethodHead && !j.checkCsrfToken(w, req) {
+			return
+		}
+
+		handler(w, req)
+	}
+}
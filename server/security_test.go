@@ -0,0 +1,201 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+	handler := server.Routes()
+
+	w := getPage(handler, "/stats/")
+	assert.Equal(t, contentSecurityPolicy, w.Header().Get("Content-Security-Policy"))
+	assert.Equal(t, "DENY", w.Header().Get("X-Frame-Options"))
+	assert.Equal(t, "nosniff", w.Header().Get("X-Content-Type-Options"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestCors(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+	server.SetCorsOrigins([]string{" https://dashboard.example.com/ ", ""})
+	handler := server.Routes()
+
+	// Request from a permitted origin
+	req := httptest.NewRequest(http.MethodGet, "/stats/?format=json", nil)
+	req.Header.Set("Origin", "https://dashboard.example.com")
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "https://dashboard.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+
+	// Preflight request from a permitted origin
+	req = httptest.NewRequest(http.MethodOptions, "/upload", nil)
+	req.Header.Set("Origin", "https://dashboard.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, corsAllowedMethods, w.Header().Get("Access-Control-Allow-Methods"))
+
+	// Request from another origin
+	req = httptest.NewRequest(http.MethodGet, "/stats/?format=json", nil)
+	req.Header.Set("Origin", "https://attacker.example.com")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+// postUploadForm from a browser at the origin with the CSRF token in its cookie and form (if they
+// aren't blank).
+func postUploadForm(handler http.Handler, origin string, cookieToken string, formToken string) int {
+
+	form := buildFormData(1, "Dataset-1", "e-1, e-2", "", "", "", "")
+	if len(formToken) > 0 {
+		form.Set(CsrfTokenInputName, formToken)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if len(origin) > 0 {
+		req.Header.Set("Origin", origin)
+	}
+	if len(cookieToken) > 0 {
+		req.AddCookie(&http.Cookie{Name: CsrfCookieName, Value: cookieToken})
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestCsrfProtection(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+	server.SetCorsOrigins([]string{"https://dashboard.example.com"})
+	handler := server.Routes()
+
+	// The index page gives the browser a CSRF token and holds it in the upload form
+	w := getPage(handler, "/")
+	cookies := w.Result().Cookies()
+	assert.Len(t, cookies, 1)
+	assert.Equal(t, CsrfCookieName, cookies[0].Name)
+	token := cookies[0].Value
+	assert.Len(t, token, csrfTokenBytes*2)
+	assert.Contains(t, w.Body.String(), `name="csrfToken" value="`+token+`"`)
+	assert.NotContains(t, w.Body.String(), csrfTokenPlaceholder)
+
+	// The browser keeps its token
+	req := httptest.NewRequest(http.MethodGet, "/spider", nil)
+	req.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Len(t, w.Result().Cookies(), 0)
+	assert.Contains(t, w.Body.String(), `name="csrfToken" value="`+token+`"`)
+
+	otherToken := strings.Repeat("ab", csrfTokenBytes)
+
+	testCases := []struct {
+		description  string
+		origin       string
+		cookieToken  string
+		formToken    string
+		expectedCode int
+	}{
+		{"API client", "", "", "", http.StatusFound},
+		{"Browser with the token", "http://example.com", token, token, http.StatusFound},
+		{"Browser without the token in the form", "http://example.com", token, "", http.StatusForbidden},
+		{"Cross-site request", "https://attacker.example.com", "", "", http.StatusForbidden},
+		{"Cross-site request with a guessed token", "https://attacker.example.com", token, otherToken, http.StatusForbidden},
+		{"Browser with a cookie but no origin", "", token, "", http.StatusForbidden},
+		{"Permitted CORS origin", "https://dashboard.example.com", "", "", http.StatusFound},
+	}
+
+	for _, testCase := range testCases {
+		assert.Equal(t, testCase.expectedCode,
+			postUploadForm(handler, testCase.origin, testCase.cookieToken, testCase.formToken),
+			testCase.description)
+	}
+
+	// An invalid form is shown as an input problem whether or not it has a token
+	req = httptest.NewRequest(http.MethodPost, "/spider-upload", strings.NewReader("numberSteps=x"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Origin", "https://attacker.example.com")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// A valid spider job from another site is forbidden
+	req = httptest.NewRequest(http.MethodPost, "/spider-upload",
+		strings.NewReader(buildSpiderFormData(1, "e-1").Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Sec-Fetch-Site", "cross-site")
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestCsrfProtectedForms(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+	handler := server.Routes()
+
+	// The pages with forms hold the browser's CSRF token
+	w := getPage(handler, casesUrl)
+	cookies := w.Result().Cookies()
+	assert.Len(t, cookies, 1)
+	token := cookies[0].Value
+	assert.Contains(t, w.Body.String(), `name="csrfToken" value="`+token+`"`)
+
+	for _, page := range []string{watchlistsUrl, bulkSearchUrl} {
+		req := httptest.NewRequest(http.MethodGet, page, nil)
+		w = requestWithCookies(handler, req, cookies)
+		assert.Contains(t, w.Body.String(), `name="csrfToken" value="`+token+`"`, page)
+		assert.NotContains(t, w.Body.String(), csrfTokenPlaceholder, page)
+	}
+
+	// A form from another site is forbidden on every route that changes the state of the server
+	routes := []string{"/save-job-template", "/delete-job-template", "/create-case", "/add-case-job",
+		"/add-case-note", "/create-watchlist", "/check-watchlist", "/acknowledge-watchlist",
+		"/delete-watchlist", annotateEntityUrl, "/retry/guid-1", bulkSearchUrl}
+
+	for _, route := range routes {
+		req := httptest.NewRequest(http.MethodPost, route,
+			strings.NewReader(url.Values{
+				CaseNameInputName:           {"Operation A"},
+				BulkSearchEntitiesInputName: {"e-1"},
+			}.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("Origin", "https://attacker.example.com")
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code, route)
+	}
+
+	// A form with the browser's token is accepted
+	req := httptest.NewRequest(http.MethodPost, "/create-case", strings.NewReader(url.Values{
+		CaseNameInputName:  {"Operation A"},
+		CsrfTokenInputName: {token},
+	}.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Origin", "http://example.com")
+	w = requestWithCookies(handler, req, cookies)
+	assert.Equal(t, http.StatusFound, w.Code)
+
+	// The case's page holds the token in its forms
+	req = httptest.NewRequest(http.MethodGet, w.Header().Get("Location"), nil)
+	w = requestWithCookies(handler, req, cookies)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, 2, strings.Count(w.Body.String(), `name="csrfToken" value="`+token+`"`))
+}
@@ -1,24 +1,38 @@
 package server
 
 import (
+	"crypto/subtle"
 	"embed"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"net/http"
 	"os"
+	"path"
 	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/aymerick/raymond"
+	"github.com/cdclaxton/shortest-path-web-app/annotation"
 	"github.com/cdclaxton/shortest-path-web-app/graphbuilder"
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/i18n"
+	"github.com/cdclaxton/shortest-path-web-app/i2chart"
 	"github.com/cdclaxton/shortest-path-web-app/job"
 	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cdclaxton/shortest-path-web-app/normalisation"
+	"github.com/cdclaxton/shortest-path-web-app/pathfilter"
+	"github.com/cdclaxton/shortest-path-web-app/redaction"
 	"github.com/cdclaxton/shortest-path-web-app/search"
 	"github.com/cdclaxton/shortest-path-web-app/set"
+	"github.com/cdclaxton/shortest-path-web-app/spider"
+	"github.com/cdclaxton/shortest-path-web-app/tombstone"
 	"golang.org/x/exp/maps"
 )
 
@@ -30,16 +44,34 @@ const loggingGUIDField = "jobGUID"
 
 // Constants associated with the upload (form) page
 const (
-	MinimumNumberHops        = 1                 // Minimum number of hops from an entity to another
-	MaximumNumberHops        = 5                 // Maximum number of hops from an entity to another
-	MaxDatasetIndex          = 3                 // Maximum number of datasets on the frontend
-	NumberHopsInputName      = "numberHops"      // Name of select box for number of hops
-	DatasetNameInputName     = "datasetName"     // Prefix of the name of the text box for the dataset name
-	DatasetEntitiesInputName = "datasetEntities" // Prefix of the name of the text box containing entity IDs
-	MinimumNumberSteps       = 0                 // Minimum number of steps for spidering
-	MaximumNumberSteps       = 3                 // Maximum number of steps for spidering
-	NumberStepsInputName     = "numberSteps"     // Name of select box for number of steps for spidering
-	SeedEntitiesInputName    = "seedEntities"    // Name of the textbox containing the seed entities
+	MinimumNumberHops         = 1                     // Default minimum number of hops from an entity to another
+	MaximumNumberHops         = 5                     // Default maximum number of hops from an entity to another
+	NumberHopsInputName       = "numberHops"          // Name of select box for number of hops
+	DatasetNameInputName      = "datasetName"         // Prefix of the name of the text box for the dataset name
+	DatasetEntitiesInputName  = "datasetEntities"     // Prefix of the name of the text box containing entity IDs
+	DatasetFileInputName      = "datasetFile"         // Prefix of the name of the file input containing entity IDs
+	DatasetIndexInputName     = "dataset"             // Name of the field holding the dataset index to count
+	RetryInputName            = "retryWithFewerHops"  // Name of the checkbox to retry with fewer hops
+	DirectedInputName         = "directed"            // Name of the checkbox to only find directed paths
+	ExcludeEntitiesInputName  = "excludeEntities"     // Name of the textbox containing the entities to avoid
+	WaypointsInputName        = "waypoints"           // Name of the textbox containing the waypoint entities
+	PathFilterInputName       = "pathFilter"          // Name of the textbox containing the path filter expression
+	PathMatrixInputName       = "pathMatrix"          // Name of the checkbox to output a path matrix
+	MinDocumentsInputName     = "minDocumentsPerLink" // Name of the input for the minimum documents per link
+	TemporalInputName         = "temporal"            // Name of the checkbox to only find temporal paths
+	MinimumNumberSteps        = 0                     // Default minimum number of steps for spidering
+	MaximumNumberSteps        = 3                     // Default maximum number of steps for spidering
+	NumberStepsInputName      = "numberSteps"         // Name of select box for number of steps for spidering
+	SeedEntitiesInputName     = "seedEntities"        // Name of the textbox containing the seed entities
+	SeedEntitiesFileInputName = "seedEntitiesFile"    // Name of the file input containing the seed entities
+	StepTypesInputName        = "stepTypes"           // Prefix of the inputs for the entity types of each spider step
+	DefaultMaxSeedEntities    = 50000                 // Default maximum number of seed entities for spidering
+	DefaultMaxDatasetEntities = 5000                  // Default maximum number of entity IDs in a dataset
+	DefaultMaxEntityPairs     = 1000000               // Default maximum number of pairs of entities for a job
+	MaxSpiderUploadSize       = 32 << 20              // Maximum size (bytes) of a spider form upload
+	MaxUploadSize             = 32 << 20              // Maximum size (bytes) of a shortest path form upload
+	VerboseLoggingInputName   = "verboseLogging"      // Name of the field to request verbose logging for a job
+	AdminTokenHeader          = "X-Admin-Token"       // Header holding the token for admin-only features
 )
 
 // Locations of the HTML templates
@@ -50,6 +82,7 @@ const (
 	jobNotFoundTemplateFile         = "templates/job-not-found.html"         // For when a job cannot be found
 	processingJobTemplateFile       = "templates/processing-job.html"        // For during processing
 	jobFailedTemplateFile           = "templates/job-failed.html"            // For a failed job
+	jobTimedOutTemplateFile         = "templates/job-timed-out.html"         // For a job that timed out
 	jobNoResultsTemplateFile        = "templates/job-no-results.html"        // For a complete job
 	jobResultsTemplateFile          = "templates/job-results.html"           // For a complete job
 	statsTemplateFile               = "templates/stats.html"                 // Statistics
@@ -62,13 +95,29 @@ const (
 	spiderJobFailedTemplateFile     = "templates/spider-job-failed.html"
 	spiderJobNoResultsTemplateFile  = "templates/spider-job-no-results.html"
 	spiderJobResultsTemplateFile    = "templates/spider-job-results.html"
+	jobTemplatesTemplateFile        = "templates/job-templates.html" // Saved job templates
+	compareTemplateFile             = "templates/compare.html"       // Comparison of two jobs
+	pathTemplateFile                = "templates/path.html"          // Paths between two entities
+	bulkSearchTemplateFile          = "templates/bulk-search.html"   // Bulk search of entities
+	graphSampleTemplateFile         = "templates/graph-sample.html"  // Sample of the graph
+	myJobsTemplateFile              = "templates/my-jobs.html"       // Jobs submitted from the browser
+	casesTemplateFile               = "templates/cases.html"         // List of the cases
+	caseTemplateFile                = "templates/case.html"          // Jobs and notes of a case
+	watchlistsTemplateFile          = "templates/watchlists.html"    // List of the watchlists
+	watchlistTemplateFile           = "templates/watchlist.html"     // Connections and alerts of a watchlist
+	themeCssTemplateFile            = "templates/theme.css"          // CSS for the theme
+	partialsFolder                  = "templates/partials"           // Partials shared by the pages
 )
 
-// Errors that can occur with user-defined datasets
+// Errors that can occur with user-defined datasets. The errors that are shown to the user are
+// messages, so they can be translated.
 var (
-	ErrDatasetNoName     = errors.New("dataset has no name")
-	ErrDatasetNoEntities = errors.New("dataset has no entity IDs")
-	ErrNoSeedEntities    = errors.New("no seed entities")
+	ErrDatasetNoName     error = i18n.NewMessage("error.datasetNoName")
+	ErrDatasetNoEntities error = i18n.NewMessage("error.datasetNoEntities")
+	ErrNoSeedEntities    error = i18n.NewMessage("error.noSeedEntities")
+
+	ErrTooManySeedEntities    = errors.New("too many seed entities")
+	ErrInvalidMaxSeedEntities = errors.New("invalid maximum number of seed entities")
 )
 
 // A JobServer is responsible for providing the HTTP endpoints for running jobs.
@@ -76,26 +125,79 @@ type JobServer struct {
 	runner       *JobRunner       // Shortest path job runner
 	spiderRunner *SpiderJobRunner // Spider job runner
 
-	indexPage                   string            // Parsed index page
-	errorTemplate               *raymond.Template // Template if a system error occurs
-	inputProblemTemplate        *raymond.Template // Template if there is a problem with the user input
-	jobNotFoundTemplate         *raymond.Template // Template if the job couldn't be found
-	processingJobTemplate       *raymond.Template // Template whilst the job is processing
-	jobFailedTemplate           *raymond.Template // Template for a failed job
-	jobNoResultsTemplate        *raymond.Template // Template if the job completed and there are no results
-	jobResultsTemplate          *raymond.Template // Template if the job completed and there are results
-	statsTemplate               *raymond.Template // Template for statistics
-	entityTemplate              *raymond.Template // Template for entity search
-	spiderIndexPage             string            // Parsed the index page for spidering
-	spiderInputProblemTemplate  *raymond.Template // Template if there is a problem with the user input for spidering
+	translator   *i18n.Translator // Translator for the user-facing text
+	theme        Theme            // Theme of the pages
+	themeCss     string           // CSS for the theme
+	indexMessage string           // Static message shown on the index pages
+
+	indexTemplate               *raymond.Template       // Template for the index (landing) page
+	indexPages                  map[pageSettings]string // Cached index page for each page setting
+	errorTemplate               *raymond.Template       // Template if a system error occurs
+	inputProblemTemplate        *raymond.Template       // Template if there is a problem with the user input
+	jobNotFoundTemplate         *raymond.Template       // Template if the job couldn't be found
+	processingJobTemplate       *raymond.Template       // Template whilst the job is processing
+	jobFailedTemplate           *raymond.Template       // Template for a failed job
+	jobTimedOutTemplate         *raymond.Template       // Template for a job that timed out
+	jobNoResultsTemplate        *raymond.Template       // Template if the job completed and there are no results
+	jobResultsTemplate          *raymond.Template       // Template if the job completed and there are results
+	statsTemplate               *raymond.Template       // Template for statistics
+	entityTemplate              *raymond.Template       // Template for entity search
+	spiderIndexTemplate         *raymond.Template       // Template for the index page for spidering
+	spiderIndexPages            map[pageSettings]string // Cached index page for spidering for each page setting
+	spiderInputProblemTemplate  *raymond.Template       // Template if there is a problem with the user input for spidering
 	spiderJobNotFoundTemplate   *raymond.Template
 	spiderErrorTemplate         *raymond.Template
 	spiderProcessingJobTemplate *raymond.Template
 	spiderJobFailedTemplate     *raymond.Template
 	spiderJobNoResultsTemplate  *raymond.Template
 	spiderJobResultsTemplate    *raymond.Template
+	jobTemplatesTemplate        *raymond.Template // Template for the saved job templates
+	compareTemplate             *raymond.Template // Template for the comparison of two jobs
+	pathTemplate                *raymond.Template // Template for the paths between two entities
+	bulkSearchTemplate          *raymond.Template // Template for the bulk search of entities
+	graphSampleTemplate         *raymond.Template // Template for the sample of the graph
+	myJobsTemplate              *raymond.Template // Template for the jobs submitted from the browser
+	casesTemplate               *raymond.Template // Template for the list of the cases
+	caseTemplate                *raymond.Template // Template for the jobs and notes of a case
+	watchlistsTemplate          *raymond.Template // Template for the list of the watchlists
+	watchlistTemplate           *raymond.Template // Template for the connections and alerts of a watchlist
 
 	stats graphbuilder.GraphStats // Graph stats
+
+	basePath   string      // Path under which the pages are served (blank for the root)
+	graphLinks []graphLink // Links to select a graph (empty if there is only one graph)
+
+	jobTemplates *job.JobTemplateStore // Saved job templates
+	history      *jobHistory           // Jobs submitted from each browser session
+	cases        *job.CaseStore        // Cases grouping the jobs of investigations
+
+	watchlists     *job.WatchlistStore // Watchlists checked whenever the graph is reloaded
+	webhookHosts   map[string]struct{} // Hosts the alerts of the watchlists may be posted to
+	corsOrigins    map[string]struct{} // Origins that may use the JSON API from a browser
+	watchlistsLock sync.Mutex          // Serialises the checks of the watchlists
+
+	annotations *annotation.AnnotationStore // Tags and notes of the entities (optional)
+	tombstones  *tombstone.TombstoneStore   // Withdrawn entities (optional)
+
+	maxSeedEntities int                 // Maximum number of seed entities for a spider job
+	maxDatasets     int                 // Maximum number of datasets of a shortest path job
+	jobLimits       job.JobLimits       // Limits on the size of a shortest path job
+	searchLimits    SearchLimits        // Permitted numbers of hops and steps
+	entityIdRules   *job.EntityIdRules  // Rules the entity IDs entered by a user should pass (optional)
+	redactor        *redaction.Redactor // Redacts attributes on the entity page (optional)
+	spiderCaps      spider.SpiderCaps   // Caps on the expansion of a spider job
+	adminToken      string              // Token required for admin-only features (empty to disable them)
+	profiling       bool                // Are the pprof endpoints enabled?
+
+	pathQueryTimeout time.Duration         // Maximum time to search for the paths between two entities
+	storeLock        *graphstore.StoreLock // Queues the queries whilst the stores are updated (optional)
+
+	entityIdNormaliser *normalisation.Normaliser // Normalises the entity IDs entered by a user (optional)
+	duplicateJobWindow time.Duration             // Time a finished job is a duplicate for (0 to disable)
+
+	graph       *graphbuilder.GraphBuilder // Graph served (nil if it isn't known)
+	graphLock   sync.RWMutex               // Mutex for the graph and its stats
+	generations *graphGenerations          // Generations of the graph (nil if not enabled)
 }
 
 //go:embed templates/*
@@ -104,8 +206,32 @@ var templatesFS embed.FS
 //go:embed static/*
 var staticFS embed.FS
 
-// readTemplate from an embedded file.
-func readTemplate(filepath string) (*raymond.Template, error) {
+// readPartials from the embedded partials folder. The name of a partial is its filename without
+// the extension, e.g. {{> header}}.
+func readPartials() (map[string]string, error) {
+
+	entries, err := templatesFS.ReadDir(partialsFolder)
+	if err != nil {
+		return nil, err
+	}
+
+	partials := map[string]string{}
+	for _, entry := range entries {
+		bytes, err := templatesFS.ReadFile(path.Join(partialsFolder, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		partials[strings.TrimSuffix(entry.Name(), path.Ext(entry.Name()))] = string(bytes)
+	}
+
+	return partials, nil
+}
+
+// readTemplate from an embedded file. The template can use the shared partials and the helpers
+// {{t "key"}} to translate text into the language of the page and {{tn "key" number}} to translate
+// text holding a number.
+func readTemplate(filepath string, translator *i18n.Translator) (*raymond.Template, error) {
 
 	// Read the file from the embedded files
 	bytes, err := templatesFS.ReadFile(filepath)
@@ -117,23 +243,106 @@ func readTemplate(filepath string) (*raymond.Template, error) {
 	templateString := string(bytes)
 
 	// Parse the template
-	return raymond.Parse(templateString)
+	template, err := raymond.Parse(templateString)
+	if err != nil {
+		return nil, err
+	}
+
+	partials, err := readPartials()
+	if err != nil {
+		return nil, err
+	}
+	template.RegisterPartials(partials)
+
+	template.RegisterHelper("t", func(key string, options *raymond.Options) string {
+		return translator.Translate(options.DataStr("lang"), key)
+	})
+
+	// Translation with a number, e.g. the index of a dataset
+	template.RegisterHelper("tn", func(key string, number string, options *raymond.Options) string {
+		return translator.Translate(options.DataStr("lang"), key, number)
+	})
+
+	return template, nil
+}
+
+// pageSettings that are specific to the user rather than the content of the page.
+type pageSettings struct {
+	language string // Language of the page
+	darkMode bool   // True if the page is shown in dark mode
 }
 
-// makeIndexPage given a template file and a static message.
-func makeIndexPage(templateFile string, message string) (string, error) {
+// render the page from the template with the settings. It panics if the template can't be
+// executed (as per raymond's MustExec).
+func (j *JobServer) render(template *raymond.Template, settings pageSettings, ctx interface{}) string {
+	return j.renderWithAlerts(template, settings, ctx, j.watchlists.NumberUnread(j.basePath))
+}
 
-	// Read the template file
-	template, err := readTemplate(templateFile)
+// renderWithAlerts renders the page with the number of unread alerts of the watchlists shown in
+// the header (none if zero).
+func (j *JobServer) renderWithAlerts(template *raymond.Template, settings pageSettings,
+	ctx interface{}, alerts int) string {
+
+	frame := raymond.NewDataFrame()
+	frame.Set("lang", settings.language)
+	frame.Set("dark", settings.darkMode)
+	frame.Set("theme", j.theme.templateData(settings.darkMode))
+	frame.Set("base", j.basePath)
+	frame.Set("graphs", j.graphLinks)
+	frame.Set("alerts", alerts)
+	frame.Set("csrf", csrfTokenPlaceholder)
+
+	page, err := template.ExecWith(ctx, frame)
 	if err != nil {
-		return "", err
+		panic(err)
 	}
 
-	page := template.MustExec(map[string]string{
-		"message": message,
-	})
+	return page
+}
 
-	return page, nil
+// indexContext for the index pages.
+func (j *JobServer) indexContext() map[string]interface{} {
+	return map[string]interface{}{
+		"message":     j.indexMessage,
+		"hopOptions":  j.searchLimits.HopOptions(),
+		"stepOptions": j.searchLimits.StepOptions(),
+		"typeSteps":   options(1, j.searchLimits.MaxSteps),
+		"datasets":    prepareDatasets(nil, j.maxDatasets),
+		"newDataset":  DatasetDisplay{Index: newDatasetIndex, Optional: true},
+		"maxDatasets": j.maxDatasets,
+	}
+}
+
+// cachePages that only depend on the page settings and the theme, i.e. the theme's CSS and the
+// index pages for each language with and without dark mode.
+func (j *JobServer) cachePages() error {
+
+	themeCssTemplate, err := readTemplate(themeCssTemplateFile, j.translator)
+	if err != nil {
+		return err
+	}
+
+	themeCss, err := themeCssTemplate.Exec(j.theme)
+	if err != nil {
+		return err
+	}
+
+	indexPages := map[pageSettings]string{}
+	spiderIndexPages := map[pageSettings]string{}
+	ctx := j.indexContext()
+
+	for _, language := range j.translator.Languages() {
+		for _, darkMode := range []bool{false, true} {
+			settings := pageSettings{language: language, darkMode: darkMode}
+			indexPages[settings] = j.renderWithAlerts(j.indexTemplate, settings, ctx, 0)
+			spiderIndexPages[settings] = j.renderWithAlerts(j.spiderIndexTemplate, settings, ctx, 0)
+		}
+	}
+
+	j.themeCss = themeCss
+	j.indexPages = indexPages
+	j.spiderIndexPages = spiderIndexPages
+	return nil
 }
 
 // NewJobServer given the job runner for executing jobs. It will return an error if any of the
@@ -149,114 +358,192 @@ func NewJobServer(runner *JobRunner, spiderRunner *SpiderJobRunner, indexMessage
 		return nil, errors.New("spider job runner is nil")
 	}
 
-	// Read the index template and create a cached version of the page
-	indexPage, err := makeIndexPage(indexTemplateFile, indexMessage)
+	translator, err := i18n.NewTranslator(i18n.DefaultLanguage)
+	if err != nil {
+		return nil, err
+	}
+
+	// Read the index template (the pages are cached once the job server is made)
+	indexTemplate, err := readTemplate(indexTemplateFile, translator)
 	if err != nil {
 		return nil, err
 	}
 
 	// Read the templates
-	errorTemplate, err := readTemplate(errorTemplateFile)
+	errorTemplate, err := readTemplate(errorTemplateFile, translator)
+	if err != nil {
+		return nil, err
+	}
+
+	inputProblemTemplate, err := readTemplate(inputProblemTemplateFile, translator)
+	if err != nil {
+		return nil, err
+	}
+
+	jobNotFoundTemplate, err := readTemplate(jobNotFoundTemplateFile, translator)
+	if err != nil {
+		return nil, err
+	}
+
+	processingJobTemplate, err := readTemplate(processingJobTemplateFile, translator)
+	if err != nil {
+		return nil, err
+	}
+
+	jobFailedTemplate, err := readTemplate(jobFailedTemplateFile, translator)
+	if err != nil {
+		return nil, err
+	}
+
+	jobTimedOutTemplate, err := readTemplate(jobTimedOutTemplateFile, translator)
+	if err != nil {
+		return nil, err
+	}
+
+	jobNoResultsTemplate, err := readTemplate(jobNoResultsTemplateFile, translator)
+	if err != nil {
+		return nil, err
+	}
+
+	jobResultsTemplate, err := readTemplate(jobResultsTemplateFile, translator)
+	if err != nil {
+		return nil, err
+	}
+
+	statsTemplate, err := readTemplate(statsTemplateFile, translator)
+	if err != nil {
+		return nil, err
+	}
+
+	entityTemplate, err := readTemplate(entityTemplateFile, translator)
 	if err != nil {
 		return nil, err
 	}
 
-	inputProblemTemplate, err := readTemplate(inputProblemTemplateFile)
+	spiderIndexTemplate, err := readTemplate(spiderIndexTemplateFile, translator)
 	if err != nil {
 		return nil, err
 	}
 
-	jobNotFoundTemplate, err := readTemplate(jobNotFoundTemplateFile)
+	spiderInputProblemTemplate, err := readTemplate(spiderInputProblemTemplateFile, translator)
 	if err != nil {
 		return nil, err
 	}
 
-	processingJobTemplate, err := readTemplate(processingJobTemplateFile)
+	spiderJobNotFoundTemplate, err := readTemplate(spiderJobNotFoundTemplateFile, translator)
 	if err != nil {
 		return nil, err
 	}
 
-	jobFailedTemplate, err := readTemplate(jobFailedTemplateFile)
+	spiderErrorTemplate, err := readTemplate(spiderErrorTemplateFile, translator)
 	if err != nil {
 		return nil, err
 	}
 
-	jobNoResultsTemplate, err := readTemplate(jobNoResultsTemplateFile)
+	spiderProcessingJobTemplate, err := readTemplate(spiderProcessingJobTemplateFile, translator)
 	if err != nil {
 		return nil, err
 	}
 
-	jobResultsTemplate, err := readTemplate(jobResultsTemplateFile)
+	spiderJobFailedTemplate, err := readTemplate(spiderJobFailedTemplateFile, translator)
 	if err != nil {
 		return nil, err
 	}
 
-	statsTemplate, err := readTemplate(statsTemplateFile)
+	spiderJobNoResultsTemplate, err := readTemplate(spiderJobNoResultsTemplateFile, translator)
 	if err != nil {
 		return nil, err
 	}
 
-	entityTemplate, err := readTemplate(entityTemplateFile)
+	spiderJobResultsTemplate, err := readTemplate(spiderJobResultsTemplateFile, translator)
 	if err != nil {
 		return nil, err
 	}
 
-	// Read the index template and create a cached version of the page
-	spiderIndexPage, err := makeIndexPage(spiderIndexTemplateFile, indexMessage)
+	jobTemplatesTemplate, err := readTemplate(jobTemplatesTemplateFile, translator)
 	if err != nil {
 		return nil, err
 	}
 
-	spiderInputProblemTemplate, err := readTemplate(spiderInputProblemTemplateFile)
+	compareTemplate, err := readTemplate(compareTemplateFile, translator)
 	if err != nil {
 		return nil, err
 	}
 
-	spiderJobNotFoundTemplate, err := readTemplate(spiderJobNotFoundTemplateFile)
+	pathTemplate, err := readTemplate(pathTemplateFile, translator)
 	if err != nil {
 		return nil, err
 	}
 
-	spiderErrorTemplate, err := readTemplate(spiderErrorTemplateFile)
+	bulkSearchTemplate, err := readTemplate(bulkSearchTemplateFile, translator)
 	if err != nil {
 		return nil, err
 	}
 
-	spiderProcessingJobTemplate, err := readTemplate(spiderProcessingJobTemplateFile)
+	graphSampleTemplate, err := readTemplate(graphSampleTemplateFile, translator)
 	if err != nil {
 		return nil, err
 	}
 
-	spiderJobFailedTemplate, err := readTemplate(spiderJobFailedTemplateFile)
+	myJobsTemplate, err := readTemplate(myJobsTemplateFile, translator)
 	if err != nil {
 		return nil, err
 	}
 
-	spiderJobNoResultsTemplate, err := readTemplate(spiderJobNoResultsTemplateFile)
+	casesTemplate, err := readTemplate(casesTemplateFile, translator)
 	if err != nil {
 		return nil, err
 	}
 
-	spiderJobResultsTemplate, err := readTemplate(spiderJobResultsTemplateFile)
+	caseTemplate, err := readTemplate(caseTemplateFile, translator)
 	if err != nil {
 		return nil, err
 	}
 
-	// Return the constructed job server
-	return &JobServer{
+	watchlistsTemplate, err := readTemplate(watchlistsTemplateFile, translator)
+	if err != nil {
+		return nil, err
+	}
+
+	watchlistTemplate, err := readTemplate(watchlistTemplateFile, translator)
+	if err != nil {
+		return nil, err
+	}
+
+	// Job templates, cases and watchlists are held in memory unless persisted stores are set
+	jobTemplates, err := job.NewJobTemplateStore("")
+	if err != nil {
+		return nil, err
+	}
+
+	cases, err := job.NewCaseStore("")
+	if err != nil {
+		return nil, err
+	}
+
+	watchlists, err := job.NewWatchlistStore("")
+	if err != nil {
+		return nil, err
+	}
+
+	server := &JobServer{
 		runner:                      runner,
 		spiderRunner:                spiderRunner,
-		indexPage:                   indexPage,
+		translator:                  translator,
+		theme:                       DefaultTheme(),
+		indexMessage:                indexMessage,
+		indexTemplate:               indexTemplate,
 		errorTemplate:               errorTemplate,
 		inputProblemTemplate:        inputProblemTemplate,
 		jobNotFoundTemplate:         jobNotFoundTemplate,
 		processingJobTemplate:       processingJobTemplate,
 		jobFailedTemplate:           jobFailedTemplate,
+		jobTimedOutTemplate:         jobTimedOutTemplate,
 		jobNoResultsTemplate:        jobNoResultsTemplate,
 		jobResultsTemplate:          jobResultsTemplate,
 		statsTemplate:               statsTemplate,
 		entityTemplate:              entityTemplate,
-		spiderIndexPage:             spiderIndexPage,
+		spiderIndexTemplate:         spiderIndexTemplate,
 		spiderInputProblemTemplate:  spiderInputProblemTemplate,
 		spiderJobNotFoundTemplate:   spiderJobNotFoundTemplate,
 		spiderErrorTemplate:         spiderErrorTemplate,
@@ -264,36 +551,181 @@ func NewJobServer(runner *JobRunner, spiderRunner *SpiderJobRunner, indexMessage
 		spiderJobFailedTemplate:     spiderJobFailedTemplate,
 		spiderJobNoResultsTemplate:  spiderJobNoResultsTemplate,
 		spiderJobResultsTemplate:    spiderJobResultsTemplate,
+		jobTemplatesTemplate:        jobTemplatesTemplate,
+		compareTemplate:             compareTemplate,
+		pathTemplate:                pathTemplate,
+		bulkSearchTemplate:          bulkSearchTemplate,
+		graphSampleTemplate:         graphSampleTemplate,
+		myJobsTemplate:              myJobsTemplate,
+		casesTemplate:               casesTemplate,
+		caseTemplate:                caseTemplate,
+		watchlistsTemplate:          watchlistsTemplate,
+		watchlistTemplate:           watchlistTemplate,
+		jobTemplates:                jobTemplates,
+		history:                     newJobHistory(),
+		cases:                       cases,
+		watchlists:                  watchlists,
+		webhookHosts:                map[string]struct{}{},
+		corsOrigins:                 map[string]struct{}{},
 		stats:                       stats,
-	}, nil
+		maxSeedEntities:             DefaultMaxSeedEntities,
+		maxDatasets:                 DefaultMaxDatasets,
+		searchLimits:                DefaultSearchLimits,
+		pathQueryTimeout:            DefaultPathQueryTimeout,
+		duplicateJobWindow:          DefaultDuplicateJobWindow,
+		jobLimits: job.JobLimits{
+			MaxEntityIdsPerDataset: DefaultMaxDatasetEntities,
+			MaxEntityPairs:         DefaultMaxEntityPairs,
+		},
+	}
+
+	// Cache the pages that don't depend on a job
+	if err := server.cachePages(); err != nil {
+		return nil, err
+	}
+
+	return server, nil
 }
 
-// parseNumberOfHops in the HTTP POST form data.
-func parseNumberOfHops(req *http.Request) (int, error) {
+// SetMaxSeedEntities sets the maximum number of seed entities that can be used for a spider job.
+func (j *JobServer) SetMaxSeedEntities(maxSeedEntities int) error {
+
+	// Precondition
+	if maxSeedEntities < 1 {
+		return ErrInvalidMaxSeedEntities
+	}
 
-	// Read the number of hops from the form
-	numberHops := req.FormValue(NumberHopsInputName)
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("maxSeedEntities", strconv.Itoa(maxSeedEntities)).
+		Msg("Setting the maximum number of seed entities")
+
+	j.maxSeedEntities = maxSeedEntities
+	return nil
+}
 
-	if len(numberHops) == 0 {
-		return 0, errors.New("number of hops is blank")
+// SetSpiderCaps sets the caps on the expansion of a spider job. A cap of zero means there is no
+// limit.
+func (j *JobServer) SetSpiderCaps(caps spider.SpiderCaps) error {
+
+	// Precondition
+	if err := caps.Validate(); err != nil {
+		return err
 	}
 
-	// Convert the string version of the number of hops to an integer
-	value, err := strconv.Atoi(numberHops)
-	if err != nil {
-		return 0, fmt.Errorf("invalid number of hops: %v", value)
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Int("maxEntities", caps.MaxEntities).
+		Int("maxNeighbours", caps.MaxNeighbours).
+		Msg("Setting the spider caps")
+
+	j.spiderCaps = caps
+	return nil
+}
+
+// SetJobLimits sets the limits on the size of a shortest path job. A limit of zero means there is
+// no limit.
+func (j *JobServer) SetJobLimits(limits job.JobLimits) error {
+
+	// Precondition
+	if err := limits.Validate(); err != nil {
+		return err
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("maxEntityIdsPerDataset", strconv.Itoa(limits.MaxEntityIdsPerDataset)).
+		Str("maxEntityPairs", strconv.Itoa(limits.MaxEntityPairs)).
+		Msg("Setting the job limits")
+
+	j.jobLimits = limits
+	return nil
+}
+
+// SetEntityIdRules sets the rules that the entity IDs entered by a user should pass. Nil rules
+// accept any entity ID.
+func (j *JobServer) SetEntityIdRules(rules *job.EntityIdRules) {
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Bool("entityIdRules", rules != nil).
+		Msg("Setting the entity ID rules")
+
+	j.entityIdRules = rules
+}
+
+// SetEntityIdNormaliser sets the normalisation of the entity IDs entered by a user, which should be
+// the normalisation of the entity IDs in the graphs. A nil normaliser leaves the entity IDs unchanged.
+func (j *JobServer) SetEntityIdNormaliser(normaliser *normalisation.Normaliser) {
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Bool("entityIdNormalisation", normaliser != nil).
+		Msg("Setting the entity ID normaliser")
+
+	j.entityIdNormaliser = normaliser
+}
+
+// SetAdminToken sets the token that must be provided in the AdminTokenHeader of a request to use
+// admin-only features, such as verbose logging for a job. An empty token disables the features.
+func (j *JobServer) SetAdminToken(token string) {
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Bool("adminFeaturesEnabled", len(token) > 0).
+		Msg("Setting the admin token")
+
+	j.adminToken = token
+}
+
+// SetDefaultLanguage of the pages if the request doesn't specify a supported language.
+func (j *JobServer) SetDefaultLanguage(language string) error {
+	return j.translator.SetDefaultLanguage(language)
+}
+
+// pageSettings for the request, i.e. the negotiated language and whether dark mode is on. The
+// Content-Language header of the response is set.
+func (j *JobServer) pageSettings(w http.ResponseWriter, req *http.Request) pageSettings {
+	language := j.translator.Negotiate(req.Header.Get("Accept-Language"))
+	w.Header().Set("Content-Language", language)
+
+	return pageSettings{
+		language: language,
+		darkMode: isDarkMode(req),
+	}
+}
+
+// isAdmin returns true if the request has the admin token.
+func (j *JobServer) isAdmin(req *http.Request) bool {
+	if len(j.adminToken) == 0 {
+		return false
+	}
+
+	token := req.Header.Get(AdminTokenHeader)
+	return subtle.ConstantTimeCompare([]byte(token), []byte(j.adminToken)) == 1
+}
+
+// parseNumberOfHops in the HTTP POST form data.
+func parseNumberOfHops(req *http.Request, limits SearchLimits) (int, error) {
+	return limits.parseHops(req.FormValue(NumberHopsInputName))
+}
+
+// parseMinDocumentsPerLink in the HTTP POST form data, where blank means any number of documents.
+func parseMinDocumentsPerLink(req *http.Request) (int, error) {
+
+	minDocuments := strings.TrimSpace(req.FormValue(MinDocumentsInputName))
+	if len(minDocuments) == 0 {
+		return 0, nil
 	}
 
-	// Validate the number of hops
-	if value < MinimumNumberHops || value > MaximumNumberHops {
-		return 0, fmt.Errorf("invalid number of hops: %v", value)
+	value, err := strconv.Atoi(minDocuments)
+	if err != nil || value < 0 {
+		return 0, i18n.NewMessage("error.invalidMinDocumentsPerLink", minDocuments)
 	}
 
 	return value, nil
 }
 
-// splitEntityIDs from a string using space, newline, comma and semicolon separators.
-func splitEntityIDs(text string) []string {
+// splitEntityIDs from a string using space, newline, comma and semicolon separators. The entity IDs
+// are normalised if there is a normaliser.
+func splitEntityIDs(text string, normaliser *normalisation.Normaliser) []string {
 
 	// Split the potential entity IDs from the string
 	re := regexp.MustCompile("[ ,;\t\n]+")
@@ -307,22 +739,54 @@ func splitEntityIDs(text string) []string {
 		cleaned := strings.TrimSpace(potentialEntityIds[idx])
 
 		if len(cleaned) > 0 {
-			entityIds = append(entityIds, cleaned)
+			entityIds = append(entityIds, normaliser.Normalise(cleaned))
 		}
 	}
 
 	return entityIds
 }
 
-// parseEntitySet from the HTTP POST form data.
-func parseEntitySet(req *http.Request, index int) (*job.EntitySet, error) {
+// uniqueEntityIds returns the entity IDs with any duplicates removed, retaining the order.
+func uniqueEntityIds(entityIds []string) []string {
+	seen := set.NewSet[string]()
+	unique := []string{}
+
+	for _, entityId := range entityIds {
+		if !seen.Has(entityId) {
+			seen.Add(entityId)
+			unique = append(unique, entityId)
+		}
+	}
+
+	return unique
+}
+
+// readDatasetEntityIds returns the entity IDs for a dataset from its textbox and uploaded file.
+func readDatasetEntityIds(req *http.Request, index int, normaliser *normalisation.Normaliser) (
+	[]string, error) {
+
+	entityIds := splitEntityIDs(req.FormValue(DatasetEntitiesInputName+strconv.Itoa(index)),
+		normaliser)
+
+	fileContents, err := readEntitiesFile(req, DatasetFileInputName+strconv.Itoa(index))
+	if err != nil {
+		return nil, i18n.Wrap(err, "error.datasetFile", err)
+	}
+
+	return append(entityIds, splitEntityIDs(fileContents, normaliser)...), nil
+}
+
+// parseEntitySet from the HTTP POST form data. The entity IDs are read from the dataset's textbox
+// and uploaded file, and any duplicates are removed.
+func parseEntitySet(req *http.Request, index int, normaliser *normalisation.Normaliser) (
+	*job.EntitySet, error) {
 
 	// Preconditions
 	if req == nil {
 		return nil, fmt.Errorf("HTTP request is nil")
 	}
 
-	if index < 0 || index > MaxDatasetIndex {
+	if index < 1 {
 		return nil, fmt.Errorf("invalid dataset index: %v", index)
 	}
 
@@ -330,8 +794,11 @@ func parseEntitySet(req *http.Request, index int) (*job.EntitySet, error) {
 	name := req.FormValue(DatasetNameInputName + strconv.Itoa(index))
 
 	// Extract the entity IDs from the form
-	allEntityIds := req.FormValue(DatasetEntitiesInputName + strconv.Itoa(index))
-	entityIds := splitEntityIDs(allEntityIds)
+	entityIds, err := readDatasetEntityIds(req, index, normaliser)
+	if err != nil {
+		return nil, err
+	}
+	entityIds = uniqueEntityIds(entityIds)
 
 	// Determine if the dataset passes minimum validity tests
 	hasName := len(name) > 0
@@ -352,36 +819,78 @@ func parseEntitySet(req *http.Request, index int) (*job.EntitySet, error) {
 }
 
 // extractJobConfigurationFromForm extracts, parses and validates the configuration for a job.
-// If the job would not be valid, return an error message that should be meaningful to the user.
-func extractJobConfigurationFromForm(req *http.Request, maxDatasetIndex int) (*job.JobConfiguration, error) {
+// If the job would not be valid or exceeds the limits, return an error message that should be
+// meaningful to the user. The entity IDs are normalised if there is a normaliser.
+func extractJobConfigurationFromForm(req *http.Request, maxDatasets int,
+	limits job.JobLimits, searchLimits SearchLimits, normaliser *normalisation.Normaliser) (
+	*job.JobConfiguration, error) {
 
 	// Preconditions
 	if req == nil {
 		return nil, fmt.Errorf("HTTP request is nil")
 	}
 
-	if err := req.ParseForm(); err != nil {
-		return nil, fmt.Errorf("unable to parse form: %v", err)
+	if err := parseUploadForm(req, MaxUploadSize); err != nil {
+		return nil, i18n.Wrap(err, "error.unableToParseForm", err)
 	}
 
 	// Parse the number of hops
-	numberHops, err := parseNumberOfHops(req)
+	numberHops, err := parseNumberOfHops(req, searchLimits)
 	if err != nil {
-		return nil, fmt.Errorf("invalid number of hops: %v", err)
+		return nil, err
+	}
+
+	// Parse the minimum number of documents supporting each link
+	minDocuments, err := parseMinDocumentsPerLink(req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse the job's timeout
+	timeout, err := parseTimeout(req)
+	if err != nil {
+		return nil, err
 	}
 
 	// Initialise the job configuration
 	jobConf := job.JobConfiguration{
-		MaxNumberHops: numberHops,
-		EntitySets:    []job.EntitySet{},
+		MaxNumberHops:       numberHops,
+		EntitySets:          []job.EntitySet{},
+		RetryWithFewerHops:  req.FormValue(RetryInputName) == "true",
+		Directed:            req.FormValue(DirectedInputName) == "true",
+		VerboseLogging:      req.FormValue(VerboseLoggingInputName) == "true",
+		PathMatrix:          req.FormValue(PathMatrixInputName) == "true",
+		MinDocumentsPerLink: minDocuments,
+		Temporal:            req.FormValue(TemporalInputName) == "true",
+		Timeout:             timeout,
+		Explain:             req.FormValue(ExplainInputName) == "true",
+	}
+
+	// Parse the entities to avoid
+	if excluded := splitEntityIDs(req.FormValue(ExcludeEntitiesInputName), normaliser); len(excluded) > 0 {
+		jobConf.ExcludedEntityIds = excluded
+	}
+
+	// Parse the entities the paths must pass through
+	if waypoints := splitEntityIDs(req.FormValue(WaypointsInputName), normaliser); len(waypoints) > 0 {
+		jobConf.Waypoints = waypoints
+	}
+
+	// Parse the filter of the paths by the attributes of their entities
+	if expression := strings.TrimSpace(req.FormValue(PathFilterInputName)); len(expression) > 0 {
+		filter, err := pathfilter.Parse(expression)
+		if err != nil {
+			return nil, i18n.Wrap(err, "error.invalidPathFilter", expression)
+		}
+		jobConf.PathFilter = filter.String()
 	}
 
 	// Parse the datasets
-	for idx := 1; idx <= maxDatasetIndex; idx++ {
-		entitySet, err := parseEntitySet(req, idx)
+	for idx := 1; idx <= maxDatasets; idx++ {
+		entitySet, err := parseEntitySet(req, idx, normaliser)
 
 		if err != nil {
-			return nil, fmt.Errorf("dataset parse error: %v", err)
+			return nil, i18n.Wrap(err, "error.datasetParse", err)
 		}
 
 		if entitySet != nil {
@@ -390,7 +899,11 @@ func extractJobConfigurationFromForm(req *http.Request, maxDatasetIndex int) (*j
 	}
 
 	if len(jobConf.EntitySets) == 0 {
-		return nil, fmt.Errorf("there are no datasets")
+		return nil, i18n.NewMessage("error.noDatasets")
+	}
+
+	if err := limits.Check(&jobConf); err != nil {
+		return nil, err
 	}
 
 	return &jobConf, nil
@@ -402,7 +915,16 @@ func (j *JobServer) handleUpload(w http.ResponseWriter, req *http.Request) {
 	logging.Logger.Info().
 		Str(logging.ComponentField, componentName).
 		Msg("Handling form upload")
-	jobConf, err := extractJobConfigurationFromForm(req, MaxDatasetIndex)
+	settings := j.pageSettings(w, req)
+
+	// Limit the size of the request (which may contain files of entity IDs)
+	req.Body = http.MaxBytesReader(w, req.Body, MaxUploadSize)
+
+	jobConf, err := extractJobConfigurationFromForm(req, j.maxDatasets, j.jobLimits, j.searchLimits,
+		j.entityIdNormaliser)
+	if err == nil {
+		err = j.entityIdRules.Check(jobConf)
+	}
 
 	// If there was an input configuration error, then show the error on a dedicated page
 	// and return a 400 error
@@ -410,40 +932,122 @@ func (j *JobServer) handleUpload(w http.ResponseWriter, req *http.Request) {
 
 		w.WriteHeader(http.StatusBadRequest)
 
-		page := j.inputProblemTemplate.MustExec(map[string]string{
-			"reason": err.Error(),
-		})
+		page := j.render(j.inputProblemTemplate, settings,
+			j.inputProblemContext(settings.language, err))
 		fmt.Fprint(w, page)
 		return
 	}
 
-	// Launch the job. If it fails return a 500 error code
-	guid, err := j.runner.Submit(jobConf)
+	if !j.checkCsrfToken(w, req) {
+		return
+	}
+
+	// Verbose logging is restricted to admins as it can produce a large volume of logs
+	if jobConf.VerboseLogging && !j.isAdmin(req) {
+		logging.Logger.Warn().
+			Str(logging.ComponentField, componentName).
+			Msg("Verbose logging requested without a valid admin token")
+		jobConf.VerboseLogging = false
+	}
+
+	// Launch the job unless it duplicates a recent job (and the user hasn't asked to run it anyway).
+	// If it fails return a 500 error code
+	window := j.duplicateJobWindow
+	if req.FormValue(AllowDuplicateInputName) == "true" {
+		window = 0
+	}
+
+	guid, duplicate, err := j.runner.SubmitUnlessDuplicate(jobConf, window)
 	if err != nil {
 
 		w.WriteHeader(http.StatusInternalServerError)
 
-		page := j.errorTemplate.MustExec(map[string]string{
-			"reason": err.Error(),
+		page := j.render(j.errorTemplate, settings, map[string]string{
+			"reason": j.translator.TranslateError(settings.language, err),
 		})
 		fmt.Fprint(w, page)
 		return
 	}
 
+	if duplicate {
+		redirectUrl := fmt.Sprintf("%v/job/%v?%v=true", j.basePath, guid, DuplicateInputName)
+		http.Redirect(w, req, redirectUrl, http.StatusFound)
+		return
+	}
+
 	logging.Logger.Info().
 		Str(logging.ComponentField, componentName).
 		Str(loggingGUIDField, guid).
 		Msg("Job successfully submitted")
 
-	redirectUrl := fmt.Sprintf("/job/%v", guid)
+	j.recordJob(w, req, guid, false)
+
+	redirectUrl := fmt.Sprintf("%v/job/%v", j.basePath, guid)
 	http.Redirect(w, req, redirectUrl, http.StatusFound)
 }
 
+// EntityCount is the preview of the number of entity IDs in a dataset before the form is submitted.
+type EntityCount struct {
+	NumberOfEntityIds       int    `json:"numberOfEntityIds"`       // Number of entity IDs (including duplicates)
+	NumberOfUniqueEntityIds int    `json:"numberOfUniqueEntityIds"` // Number of unique entity IDs
+	Message                 string `json:"message"`                 // Message for the user
+}
+
+// handleCountEntities returns a preview of the number of entity IDs in the textbox and uploaded
+// file for a dataset, so the user can check the dataset before submitting the form.
+func (j *JobServer) handleCountEntities(w http.ResponseWriter, req *http.Request) {
+
+	language := j.pageSettings(w, req).language
+	req.Body = http.MaxBytesReader(w, req.Body, MaxUploadSize)
+
+	if err := parseUploadForm(req, MaxUploadSize); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	index, err := strconv.Atoi(req.FormValue(DatasetIndexInputName))
+	if err != nil || index < 1 || index > j.maxDatasets {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	entityIds, err := readDatasetEntityIds(req, index, j.entityIdNormaliser)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	count := EntityCount{
+		NumberOfEntityIds:       len(entityIds),
+		NumberOfUniqueEntityIds: len(uniqueEntityIds(entityIds)),
+	}
+
+	messages := []string{j.translator.Translate(language, "preview.entityIds",
+		count.NumberOfUniqueEntityIds)}
+
+	if duplicates := count.NumberOfEntityIds - count.NumberOfUniqueEntityIds; duplicates > 0 {
+		messages = append(messages, j.translator.Translate(language, "preview.duplicates",
+			duplicates))
+	}
+
+	maxEntityIds := j.jobLimits.MaxEntityIdsPerDataset
+	if maxEntityIds > 0 && count.NumberOfUniqueEntityIds > maxEntityIds {
+		messages = append(messages, j.translator.Translate(language, "preview.tooManyEntityIds",
+			maxEntityIds))
+	}
+
+	count.Message = strings.Join(messages, " ")
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(count)
+}
+
 // EntitySearchResultsDisplay holds data that is presented as an entities table.
 type EntitySearchResultsDisplay struct {
 	EntityId     string
 	InUnipartite bool
 	InBipartite  bool
+	Suggestions  []string // Similar entity IDs if the entity wasn't found
 }
 
 // prepareEntitySearchResults for display in HTML.
@@ -462,6 +1066,7 @@ func prepareEntitySearchResults(entityResults map[string]search.EntitySearchResu
 			EntityId:     entityId,
 			InUnipartite: result.InUnipartite,
 			InBipartite:  result.InBipartite,
+			Suggestions:  result.Suggestions,
 		})
 	}
 
@@ -477,15 +1082,43 @@ func (j *JobServer) handleEntity(w http.ResponseWriter, req *http.Request) {
 		Str(logging.ComponentField, componentName).
 		Str("entityID", entityId).
 		Msg("Received request at /entity")
+	settings := j.pageSettings(w, req)
 
-	// Try to get the entity from the entity search engine
-	entity := j.runner.searchEngine.GetEntity(entityId)
+	// Get the pages of linked documents and entities to show
+	documentsPage, entitiesPage, err := parseEntityPages(req)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		page := j.render(j.inputProblemTemplate, settings, map[string]string{
+			"reason": j.translator.TranslateError(settings.language, err),
+		})
+		fmt.Fprint(w, page)
+		return
+	}
 
-	page := j.entityTemplate.MustExec(map[string]interface{}{
-		"entity": entity,
+	// Try to get the entity from the entity search engine
+	entity := j.redactEntityPage(j.runner.searchEngine.GetEntityPage(entityId, documentsPage,
+		entitiesPage))
+
+	// The entity is shown using its canonical ID if the entity ID is an alias
+	alias := ""
+	if len(entity.Alias) > 0 {
+		alias = j.translator.Translate(settings.language, "entity.aliasOf", entity.Alias)
+		entityId = entity.EntityId
+	}
+
+	page := j.render(j.entityTemplate, settings, map[string]interface{}{
+		"entity":        entity,
+		"alias":         alias,
+		"neighbourhood": j.neighbourhoodContext(req, entityId, settings.language),
+		"annotation":    j.annotationContext(entityId),
+		"tombstone":     j.tombstoneContext(entityId, settings.language),
+		"documentsPager": j.pagerContext(req, entityId, EntityDocumentsOffsetInputName,
+			entity.BipartiteDetails.LinkedDocumentsPage, settings.language),
+		"entitiesPager": j.pagerContext(req, entityId, EntityEntitiesOffsetInputName,
+			entity.LinkedEntitiesPage, settings.language),
 	})
 
-	fmt.Fprint(w, page)
+	fmt.Fprint(w, withCsrfToken(w, req, page))
 }
 
 func (j *JobServer) handleJob(w http.ResponseWriter, req *http.Request) {
@@ -493,15 +1126,28 @@ func (j *JobServer) handleJob(w http.ResponseWriter, req *http.Request) {
 	// Extract the guid
 	guid := strings.TrimPrefix(req.URL.Path, "/job/")
 
+	// Stream the job's events if requested
+	if strings.HasSuffix(guid, jobEventsSuffix) {
+		j.handleJobEvents(w, req, strings.TrimSuffix(guid, jobEventsSuffix))
+		return
+	}
+
+	// Return the job's status as JSON if requested
+	if wantsJobStatus(req) {
+		j.handleJobStatus(w, req, guid)
+		return
+	}
+
 	logging.Logger.Info().
 		Str(logging.ComponentField, componentName).
 		Str(loggingGUIDField, guid).
 		Msg("Received request at /job")
+	settings := j.pageSettings(w, req)
 
 	finished, err := j.runner.IsJobFinished(guid)
 	if err == ErrJobNotFound {
 
-		page := j.jobNotFoundTemplate.MustExec(map[string]string{
+		page := j.render(j.jobNotFoundTemplate, settings, map[string]string{
 			"guid": guid,
 		})
 		fmt.Fprint(w, page)
@@ -509,8 +1155,8 @@ func (j *JobServer) handleJob(w http.ResponseWriter, req *http.Request) {
 	}
 
 	if err != nil {
-		page := j.errorTemplate.MustExec(map[string]string{
-			"reason": err.Error(),
+		page := j.render(j.errorTemplate, settings, map[string]string{
+			"reason": j.translator.TranslateError(settings.language, err),
 		})
 		fmt.Fprint(w, page)
 		return
@@ -522,9 +1168,19 @@ func (j *JobServer) handleJob(w http.ResponseWriter, req *http.Request) {
 		Str("finished", strconv.FormatBool(finished)).
 		Msg("Job completion state")
 
+	// Was the user taken to the job's page as the job they submitted is identical?
+	duplicate := req.URL.Query().Get(DuplicateInputName) == "true"
+
 	if !finished {
-		page := j.processingJobTemplate.MustExec(map[string]string{
-			"guid": guid,
+		var overlaps map[string]interface{}
+		if j1, err := j.runner.GetJob(guid); err == nil {
+			overlaps = j.overlapsContext(j1, settings.language)
+		}
+
+		page := j.render(j.processingJobTemplate, settings, map[string]interface{}{
+			"guid":      guid,
+			"duplicate": duplicate,
+			"overlaps":  overlaps,
 		})
 		fmt.Fprint(w, page)
 		return
@@ -534,8 +1190,8 @@ func (j *JobServer) handleJob(w http.ResponseWriter, req *http.Request) {
 	// Get the job
 	j1, err := j.runner.GetJob(guid)
 	if err != nil {
-		page := j.errorTemplate.MustExec(map[string]string{
-			"reason": err.Error(),
+		page := j.render(j.errorTemplate, settings, map[string]string{
+			"reason": j.translator.TranslateError(settings.language, err),
 		})
 		fmt.Fprint(w, page)
 		return
@@ -543,35 +1199,64 @@ func (j *JobServer) handleJob(w http.ResponseWriter, req *http.Request) {
 
 	if j1.Progress.State == job.Failed {
 
-		page := j.jobFailedTemplate.MustExec(map[string]string{
-			"reason": j1.Error.Error(),
+		page := j.render(j.jobFailedTemplate, settings, map[string]interface{}{
+			"reason":      j.translator.TranslateError(settings.language, j1.Error),
+			"guid":        guid,
+			"retry":       true,
+			"partial":     len(j1.PartialResultFile) > 0,
+			"diagnostics": len(j1.DiagnosticsFile) > 0,
+			"overlaps":    j.overlapsContext(j1, settings.language),
 		})
-		fmt.Fprint(w, page)
+		fmt.Fprint(w, withCsrfToken(w, req, page))
+		return
+
+	} else if j1.Progress.State == job.TimedOut {
+
+		fmt.Fprint(w, j.render(j.jobTimedOutTemplate, settings,
+			j.timedOutContext(j1, settings.language)))
 		return
 
 	} else if j1.Progress.State == job.CompleteNoResults {
 
-		page := j.jobNoResultsTemplate.MustExec(map[string]interface{}{
+		page := j.render(j.jobNoResultsTemplate, settings, map[string]interface{}{
 			"guid":          guid,
+			"duplicate":     duplicate,
+			"warnings":      j.translator.TranslateMessages(settings.language, j1.Warnings),
 			"entityResults": prepareEntitySearchResults(j1.EntityResults),
+			"diagnostics":   len(j1.DiagnosticsFile) > 0,
+			"overlaps":      j.overlapsContext(j1, settings.language),
 		})
-		fmt.Fprint(w, page)
+		fmt.Fprint(w, withCsrfToken(w, req, page))
 		return
 
 	} else if j1.Progress.State == job.CompleteResults {
 
-		page := j.jobResultsTemplate.MustExec(map[string]interface{}{
+		page := j.render(j.jobResultsTemplate, settings, map[string]interface{}{
 			"guid":          guid,
+			"duplicate":     duplicate,
+			"pathMatrix":    j1.Configuration.PathMatrix,
+			"anx":           len(j1.AnxResultFile) > 0,
+			"partial":       len(j1.PartialResultFile) > 0,
+			"warnings":      j.translator.TranslateMessages(settings.language, j1.Warnings),
 			"entityResults": prepareEntitySearchResults(j1.EntityResults),
+			"statistics":    j.prepareStatistics(j1.Statistics, settings.language),
+			"diagnostics":   len(j1.DiagnosticsFile) > 0,
+			"manifest":      len(j1.ManifestFile) > 0,
+			"published":     j1.PublishedResults,
+			"overlaps":      j.overlapsContext(j1, settings.language),
 		})
-		fmt.Fprint(w, page)
+		fmt.Fprint(w, withCsrfToken(w, req, page))
 		return
 	}
 
 	fmt.Fprintf(w, "Something has gone terribly wrong if you can read this")
 }
 
-const resultsFilenamePrefix = "shortest-path - "
+const (
+	resultsFilenamePrefix    = "shortest-path - "
+	pathMatrixFilenamePrefix = "path-matrix - "
+	csvContentType           = "text/csv"
+)
 
 // buildFilename for the XLSX results file for download.
 func buildFilename(jobConf *job.JobConfiguration) (string, error) {
@@ -602,7 +1287,12 @@ func buildFilename(jobConf *job.JobConfiguration) (string, error) {
 	}
 
 	// Build the complete filename
-	filename := resultsFilenamePrefix +
+	prefix := resultsFilenamePrefix
+	if jobConf.PathMatrix {
+		prefix = pathMatrixFilenamePrefix
+	}
+
+	filename := prefix +
 		strings.Join(datasetNames, " - ") +
 		hopsPart
 
@@ -614,6 +1304,12 @@ func (j *JobServer) handleDownload(w http.ResponseWriter, req *http.Request) {
 	// Extract the guid
 	guid := strings.TrimPrefix(req.URL.Path, "/download/")
 
+	// Download the manifest of the result file if requested
+	if strings.HasSuffix(guid, manifestSuffix) {
+		j.handleDownloadManifest(w, req, strings.TrimSuffix(guid, manifestSuffix))
+		return
+	}
+
 	logging.Logger.Info().
 		Str(logging.ComponentField, componentName).
 		Str(loggingGUIDField, guid).
@@ -641,8 +1337,10 @@ func (j *JobServer) handleDownload(w http.ResponseWriter, req *http.Request) {
 			Str(loggingGUIDField, guid).
 			Msg("Failed to read Excel file for job")
 
-		page := j.jobFailedTemplate.MustExec(map[string]string{
-			"reason": fmt.Sprintf("Failed to read Excel file for job %v", guid),
+		settings := j.pageSettings(w, req)
+
+		page := j.render(j.jobFailedTemplate, settings, map[string]string{
+			"reason": j.translator.Translate(settings.language, "error.readExcelFile", guid),
 		})
 
 		fmt.Fprint(w, page)
@@ -667,32 +1365,219 @@ func (j *JobServer) handleDownload(w http.ResponseWriter, req *http.Request) {
 	io.Copy(w, file)
 }
 
+// buildAnxFilename for the ANX chart file for download.
+func buildAnxFilename(jobConf *job.JobConfiguration) (string, error) {
+	filename, err := buildFilename(jobConf)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(filename, ".xlsx") + ".anx", nil
+}
+
+func (j *JobServer) handleDownloadAnx(w http.ResponseWriter, req *http.Request) {
+
+	// Extract the guid
+	guid := strings.TrimPrefix(req.URL.Path, "/download-anx/")
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str(loggingGUIDField, guid).
+		Msg("Received request at /download-anx")
+
+	j1, err := j.runner.GetJob(guid)
+	if err != nil || len(j1.AnxResultFile) == 0 {
+
+		logging.Logger.Info().
+			Str(logging.ComponentField, componentName).
+			Str(loggingGUIDField, guid).
+			Msg("Job or ANX file not found")
+
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	file, err := os.Open(j1.AnxResultFile)
+	if err != nil {
+
+		logging.Logger.Error().
+			Str(logging.ComponentField, componentName).
+			Str(loggingGUIDField, guid).
+			Msg("Failed to read ANX file for job")
+
+		settings := j.pageSettings(w, req)
+
+		page := j.render(j.jobFailedTemplate, settings, map[string]string{
+			"reason": j.translator.Translate(settings.language, "error.readAnxFile", guid),
+		})
+
+		fmt.Fprint(w, page)
+		return
+	}
+	defer file.Close()
+
+	// Make the filename
+	filename, err := buildAnxFilename(j1.Configuration)
+	if err != nil {
+
+		logging.Logger.Error().
+			Str(logging.ComponentField, componentName).
+			Str(loggingGUIDField, guid).
+			Err(err).
+			Msg("Failed to build filename")
+
+		filename = "shortest-path-results.anx"
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%v", filename))
+	w.Header().Set("Content-Type", "application/xml")
+	io.Copy(w, file)
+}
+
+// buildCsvFilename for the CSV file of a path matrix for download.
+func buildCsvFilename(jobConf *job.JobConfiguration) (string, error) {
+	filename, err := buildFilename(jobConf)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSuffix(filename, ".xlsx") + ".csv", nil
+}
+
+func (j *JobServer) handleDownloadCsv(w http.ResponseWriter, req *http.Request) {
+
+	// Extract the guid
+	guid := strings.TrimPrefix(req.URL.Path, "/download-csv/")
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str(loggingGUIDField, guid).
+		Msg("Received request at /download-csv")
+
+	j1, err := j.runner.GetJob(guid)
+	if err != nil || len(j1.CsvResultFile) == 0 {
+
+		logging.Logger.Info().
+			Str(logging.ComponentField, componentName).
+			Str(loggingGUIDField, guid).
+			Msg("Job or CSV file not found")
+
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	file, err := os.Open(j1.CsvResultFile)
+	if err != nil {
+
+		logging.Logger.Error().
+			Str(logging.ComponentField, componentName).
+			Str(loggingGUIDField, guid).
+			Msg("Failed to read CSV file for job")
+
+		settings := j.pageSettings(w, req)
+
+		page := j.render(j.jobFailedTemplate, settings, map[string]string{
+			"reason": j.translator.Translate(settings.language, "error.readCsvFile", guid),
+		})
+
+		fmt.Fprint(w, page)
+		return
+	}
+	defer file.Close()
+
+	// Make the filename
+	filename, err := buildCsvFilename(j1.Configuration)
+	if err != nil {
+
+		logging.Logger.Error().
+			Str(logging.ComponentField, componentName).
+			Str(loggingGUIDField, guid).
+			Err(err).
+			Msg("Failed to build filename")
+
+		filename = "path-matrix.csv"
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%v", filename))
+	w.Header().Set("Content-Type", csvContentType)
+	io.Copy(w, file)
+}
+
+// writeImportSpec to the response as a file to download.
+func writeImportSpec(w http.ResponseWriter, spec *i2chart.ImportSpec, filename string) {
+
+	content, err := i2chart.MarshalImportSpec(spec)
+	if err != nil {
+
+		logging.Logger.Error().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to generate i2 import specification")
+
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%v", filename))
+	w.Header().Set("Content-Type", "application/xml")
+	w.Write(content)
+}
+
+// handleImportSpec returns the i2 import specification for the shortest path Excel files.
+func (j *JobServer) handleImportSpec(w http.ResponseWriter, req *http.Request) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Msg("Received request at /import-spec")
+
+	writeImportSpec(w, j.runner.chartBuilder.ImportSpec(), "shortest-path-import-spec.ximp")
+}
+
 func (j *JobServer) handleStats(w http.ResponseWriter, req *http.Request) {
 
 	logging.Logger.Info().
 		Str(logging.ComponentField, componentName).
 		Msg("Received request at /stats")
+	settings := j.pageSettings(w, req)
+
+	j.graphLock.RLock()
+	stats := j.stats
+	j.graphLock.RUnlock()
+
+	if wantsStatsJson(req) {
+		writeStats(w, stats)
+		return
+	}
 
-	page := j.statsTemplate.MustExec(map[string]string{
-		"numberOfEntities":              strconv.Itoa(j.stats.Bipartite.NumberOfEntities),
-		"numberOfEntitiesWithDocuments": strconv.Itoa(j.stats.Bipartite.NumberOfEntitiesWithDocuments),
-		"numberOfDocuments":             strconv.Itoa(j.stats.Bipartite.NumberOfDocuments),
-		"numberOfDocumentsWithEntities": strconv.Itoa(j.stats.Bipartite.NumberOfDocumentsWithEntities),
-		"numberOfEntitiesInUnipartite":  strconv.Itoa(j.stats.Unipartite.NumberOfEntities),
+	provenance := stats.Provenance
+
+	page := j.render(j.statsTemplate, settings, map[string]interface{}{
+		"numberOfEntities":              strconv.Itoa(stats.Bipartite.NumberOfEntities),
+		"numberOfEntitiesWithDocuments": strconv.Itoa(stats.Bipartite.NumberOfEntitiesWithDocuments),
+		"numberOfDocuments":             strconv.Itoa(stats.Bipartite.NumberOfDocuments),
+		"numberOfDocumentsWithEntities": strconv.Itoa(stats.Bipartite.NumberOfDocumentsWithEntities),
+		"numberOfEntitiesInUnipartite":  strconv.Itoa(stats.Unipartite.NumberOfEntities),
+		"entityTypes":                   prepareTypeCounts(stats.Types.EntityTypes),
+		"documentTypes":                 prepareTypeCounts(stats.Types.DocumentTypes),
+		"warnings":                      stats.Warnings,
+		"provenanceKnown":               provenance.Known(),
+		"signature":                     provenance.Signature,
+		"sourceFiles":                   provenance.SourceFiles,
+		"loaded":                        provenance.Loaded.Format(myJobsTimeFormat),
 	})
 	fmt.Fprint(w, page)
 	return
 }
 
 type rootHandler struct {
-	indexPage  string
-	fileServer http.Handler
+	indexHandler http.Handler
+	fileServer   http.Handler
 }
 
-func NewRootHandler(indexPage string, fileServer http.Handler) rootHandler {
+func NewRootHandler(indexHandler http.Handler, fileServer http.Handler) rootHandler {
 	return rootHandler{
-		indexPage:  indexPage,
-		fileServer: fileServer,
+		indexHandler: indexHandler,
+		fileServer:   fileServer,
 	}
 }
 
@@ -700,44 +1585,78 @@ func (rh rootHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 
 	// If the root path is requested, then return the index.html page
 	if r.URL.Path == "/" {
-		fmt.Fprint(w, rh.indexPage)
+		rh.indexHandler.ServeHTTP(w, r)
 		return
 	}
 
 	rh.fileServer.ServeHTTP(w, r)
 }
 
+// index returns the index page.
+func (j *JobServer) index(w http.ResponseWriter, r *http.Request) {
+	if isPrepopulated(r) {
+		j.prepopulatedIndex(w, r)
+		return
+	}
+
+	settings := j.pageSettings(w, r)
+
+	// The cached page doesn't show the badge of the unread alerts of the watchlists
+	if j.watchlists.NumberUnread(j.basePath) > 0 {
+		fmt.Fprint(w, withCsrfToken(w, r, j.render(j.indexTemplate, settings, j.indexContext())))
+		return
+	}
+
+	writeCachedPage(w, r, withCsrfToken(w, r, j.indexPages[settings]))
+}
+
 // spider returns the index page for spidering.
 func (j *JobServer) spider(w http.ResponseWriter, r *http.Request) {
-	fmt.Fprint(w, j.spiderIndexPage)
+	settings := j.pageSettings(w, r)
+
+	if j.watchlists.NumberUnread(j.basePath) > 0 {
+		fmt.Fprint(w, withCsrfToken(w, r, j.render(j.spiderIndexTemplate, settings, j.indexContext())))
+		return
+	}
+
+	writeCachedPage(w, r, withCsrfToken(w, r, j.spiderIndexPages[settings]))
 }
 
 // parseNumberOfSteps in the HTTP POST form data.
-func parseNumberOfSteps(req *http.Request) (int, error) {
+func parseNumberOfSteps(req *http.Request, limits SearchLimits) (int, error) {
+	return limits.parseSteps(req.FormValue(NumberStepsInputName))
+}
 
-	// Read the number of steps from the form
-	numberSteps := req.FormValue(NumberStepsInputName)
+// readEntitiesFile returns the contents of the uploaded text or CSV file of entity IDs in the file
+// input. If no file was uploaded an empty string is returned.
+func readEntitiesFile(req *http.Request, inputName string) (string, error) {
 
-	if len(numberSteps) == 0 {
-		return 0, errors.New("number of steps is blank")
+	if req.MultipartForm == nil {
+		return "", nil
 	}
 
-	// Convert the string version of the number of steps to an integer
-	value, err := strconv.Atoi(numberSteps)
-	if err != nil {
-		return 0, fmt.Errorf("invalid number of steps: %v", value)
+	file, _, err := req.FormFile(inputName)
+	if err == http.ErrMissingFile {
+		return "", nil
+	} else if err != nil {
+		return "", err
 	}
+	defer file.Close()
 
-	// Validate the number of steps
-	if value < MinimumNumberSteps || value > MaximumNumberSteps {
-		return 0, fmt.Errorf("invalid number of steps: %v", value)
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return "", err
 	}
 
-	return value, nil
+	// Remove any byte order mark and the quotes around the CSV fields
+	text := strings.TrimPrefix(string(content), "\ufeff")
+	return strings.ReplaceAll(text, "\"", ""), nil
 }
 
-// parseSeedEntities extracts and parses the seed entities from the HTTP request.
-func parseSeedEntities(req *http.Request) (*set.Set[string], error) {
+// parseSeedEntities extracts and parses the seed entities from the textbox and the uploaded file
+// in the HTTP request. The entity IDs are normalised if there is a normaliser.
+func parseSeedEntities(req *http.Request, maxSeedEntities int,
+	normaliser *normalisation.Normaliser) (*set.Set[string], error) {
 
 	if req == nil {
 		return nil, fmt.Errorf("HTTP request is nil")
@@ -745,76 +1664,153 @@ func parseSeedEntities(req *http.Request) (*set.Set[string], error) {
 
 	// Extract the entity IDs from the form
 	allEntityIds := req.FormValue(SeedEntitiesInputName)
-	entityIds := splitEntityIDs(allEntityIds)
+	entityIds := splitEntityIDs(allEntityIds, normaliser)
+
+	// Extract the entity IDs from the file
+	fileContents, err := readEntitiesFile(req, SeedEntitiesFileInputName)
+	if err != nil {
+		return nil, err
+	}
+	entityIds = append(entityIds, splitEntityIDs(fileContents, normaliser)...)
 
 	// Determine if the seed entities pass a minimum validity test
 	if len(entityIds) == 0 {
 		return nil, ErrNoSeedEntities
 	}
 
+	seedEntities := set.NewPopulatedSet(entityIds...)
+	if seedEntities.Len() > maxSeedEntities {
+		return nil, i18n.Wrap(ErrTooManySeedEntities, "error.tooManySeedEntities",
+			seedEntities.Len(), maxSeedEntities)
+	}
+
 	// Return a set of the entity IDs
-	return set.NewPopulatedSet(entityIds...), nil
+	return seedEntities, nil
+}
+
+// parseUploadForm parses a form, which is multipart if a file of entity IDs could have been
+// uploaded.
+func parseUploadForm(req *http.Request, maxSize int64) error {
+
+	if !strings.HasPrefix(req.Header.Get("Content-Type"), "multipart/form-data") {
+		return req.ParseForm()
+	}
+
+	return req.ParseMultipartForm(maxSize)
 }
 
 // extractSpiderJobConfigurationFromForm extracts, parses and validates the configuration for a job.
 // If the job would not be valid, return an error message that should be meaningful to the user.
-func extractSpiderJobConfigurationFromForm(req *http.Request) (
+func extractSpiderJobConfigurationFromForm(req *http.Request, maxSeedEntities int,
+	searchLimits SearchLimits, normaliser *normalisation.Normaliser) (
 	*job.SpiderJobConfiguration, error) {
 
 	if req == nil {
 		return nil, fmt.Errorf("HTTP request is nil")
 	}
 
-	if err := req.ParseForm(); err != nil {
-		return nil, fmt.Errorf("unable to parse form: %v", err)
+	if err := parseUploadForm(req, MaxSpiderUploadSize); err != nil {
+		return nil, i18n.Wrap(err, "error.unableToParseForm", err)
 	}
 
 	// Parse the number of steps
-	numberSteps, err := parseNumberOfSteps(req)
+	numberSteps, err := parseNumberOfSteps(req, searchLimits)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse the job's timeout
+	timeout, err := parseTimeout(req)
 	if err != nil {
-		return nil, fmt.Errorf("invalid number of steps: %v", err)
+		return nil, err
 	}
 
 	// Extract the seed entity IDs
-	seedEntities, err := parseSeedEntities(req)
+	seedEntities, err := parseSeedEntities(req, maxSeedEntities, normaliser)
 	if err != nil {
-		return nil, fmt.Errorf("unable to parse seed entity IDs: %v", err)
+		return nil, i18n.Wrap(err, "error.seedEntities", err)
 	}
 
 	return &job.SpiderJobConfiguration{
 		NumberSteps:  numberSteps,
 		SeedEntities: seedEntities,
+		Timeout:      timeout,
+		StepTypes:    parseStepTypes(req, numberSteps),
 	}, nil
 }
 
+// parseStepTypes extracts the entity types that each step of spidering can add, which are
+// separated by commas. The types of the steps beyond the number of steps are ignored. Returns nil
+// if none of the steps are restricted.
+func parseStepTypes(req *http.Request, numberSteps int) [][]string {
+
+	stepTypes := [][]string{}
+	for step := 1; step <= numberSteps; step++ {
+		entityTypes := []string{}
+		for _, entityType := range strings.Split(req.FormValue(fmt.Sprintf("%v%d", StepTypesInputName, step)), ",") {
+			if trimmed := strings.TrimSpace(entityType); len(trimmed) > 0 {
+				entityTypes = append(entityTypes, trimmed)
+			}
+		}
+		stepTypes = append(stepTypes, entityTypes)
+	}
+
+	// Steps after the last restricted step don't need to be recorded
+	for len(stepTypes) > 0 && len(stepTypes[len(stepTypes)-1]) == 0 {
+		stepTypes = stepTypes[:len(stepTypes)-1]
+	}
+
+	if len(stepTypes) == 0 {
+		return nil
+	}
+
+	return stepTypes
+}
+
 func (j *JobServer) spiderUpload(w http.ResponseWriter, req *http.Request) {
 
 	// Extract the data from the form
 	logging.Logger.Info().
 		Str(logging.ComponentField, componentName).
 		Msg("Handling spider form upload")
+	settings := j.pageSettings(w, req)
 
-	spiderJobConf, err := extractSpiderJobConfigurationFromForm(req)
+	// Limit the size of the request (which may contain a file of seed entities)
+	req.Body = http.MaxBytesReader(w, req.Body, MaxSpiderUploadSize)
+
+	spiderJobConf, err := extractSpiderJobConfigurationFromForm(req, j.maxSeedEntities, j.searchLimits,
+		j.entityIdNormaliser)
+	if err == nil {
+		err = j.entityIdRules.CheckSpider(spiderJobConf)
+	}
+
+	if err == nil {
+		spiderJobConf.MaxEntities = j.spiderCaps.MaxEntities
+		spiderJobConf.MaxNeighbours = j.spiderCaps.MaxNeighbours
+	}
 
 	// If there was an input configuration error, then show the error on a dedicated page
 	// and return a 400 error
 	if err != nil {
 		w.WriteHeader(http.StatusBadRequest)
-		page := j.spiderInputProblemTemplate.MustExec(map[string]string{
-			"reason": err.Error(),
-		})
+		page := j.render(j.spiderInputProblemTemplate, settings,
+			j.inputProblemContext(settings.language, err))
 		fmt.Fprint(w, page)
 		return
 	}
 
+	if !j.checkCsrfToken(w, req) {
+		return
+	}
+
 	// Launch the job and if it fails return a 500 error code
 	guid, err := j.spiderRunner.Submit(spiderJobConf)
 	if err != nil {
 
 		w.WriteHeader(http.StatusInternalServerError)
 
-		page := j.errorTemplate.MustExec(map[string]string{
-			"reason": err.Error(),
+		page := j.render(j.errorTemplate, settings, map[string]string{
+			"reason": j.translator.TranslateError(settings.language, err),
 		})
 		fmt.Fprint(w, page)
 		return
@@ -825,7 +1821,9 @@ func (j *JobServer) spiderUpload(w http.ResponseWriter, req *http.Request) {
 		Str(loggingGUIDField, guid).
 		Msg("Spider job successfully submitted")
 
-	redirectUrl := fmt.Sprintf("/spider-job/%v", guid)
+	j.recordJob(w, req, guid, true)
+
+	redirectUrl := fmt.Sprintf("%v/spider-job/%v", j.basePath, guid)
 	http.Redirect(w, req, redirectUrl, http.StatusFound)
 }
 
@@ -834,15 +1832,28 @@ func (j *JobServer) spiderHandleJob(w http.ResponseWriter, req *http.Request) {
 	// Extract the guid
 	guid := strings.TrimPrefix(req.URL.Path, "/spider-job/")
 
+	// Stream the job's events if requested
+	if strings.HasSuffix(guid, jobEventsSuffix) {
+		j.spiderHandleJobEvents(w, req, strings.TrimSuffix(guid, jobEventsSuffix))
+		return
+	}
+
+	// Return the job's status as JSON if requested
+	if wantsJobStatus(req) {
+		j.spiderHandleJobStatus(w, req, guid)
+		return
+	}
+
 	logging.Logger.Info().
 		Str(logging.ComponentField, componentName).
 		Str(loggingGUIDField, guid).
 		Msg("Received request at /spider-job")
+	settings := j.pageSettings(w, req)
 
 	// Check if the spider job is finished
 	finished, err := j.spiderRunner.IsJobFinished(guid)
 	if err == ErrJobNotFound {
-		page := j.spiderJobNotFoundTemplate.MustExec(map[string]string{
+		page := j.render(j.spiderJobNotFoundTemplate, settings, map[string]string{
 			"guid": guid,
 		})
 		fmt.Fprint(w, page)
@@ -850,8 +1861,8 @@ func (j *JobServer) spiderHandleJob(w http.ResponseWriter, req *http.Request) {
 	}
 
 	if err != nil {
-		page := j.spiderErrorTemplate.MustExec(map[string]string{
-			"reason": err.Error(),
+		page := j.render(j.spiderErrorTemplate, settings, map[string]string{
+			"reason": j.translator.TranslateError(settings.language, err),
 		})
 		fmt.Fprint(w, page)
 		return
@@ -864,7 +1875,7 @@ func (j *JobServer) spiderHandleJob(w http.ResponseWriter, req *http.Request) {
 		Msg("Spider job completion state")
 
 	if !finished {
-		page := j.spiderProcessingJobTemplate.MustExec(map[string]string{
+		page := j.render(j.spiderProcessingJobTemplate, settings, map[string]string{
 			"guid": guid,
 		})
 		fmt.Fprint(w, page)
@@ -875,33 +1886,37 @@ func (j *JobServer) spiderHandleJob(w http.ResponseWriter, req *http.Request) {
 	// Get the job
 	j1, err := j.spiderRunner.GetJob(guid)
 	if err != nil {
-		page := j.spiderErrorTemplate.MustExec(map[string]string{
-			"reason": err.Error(),
+		page := j.render(j.spiderErrorTemplate, settings, map[string]string{
+			"reason": j.translator.TranslateError(settings.language, err),
 		})
 		fmt.Fprint(w, page)
 		return
 	}
 
-	if j1.Progress.State == job.Failed {
+	if j1.Progress.State == job.Failed || j1.Progress.State == job.TimedOut {
 
-		page := j.spiderJobFailedTemplate.MustExec(map[string]string{
-			"reason": j1.Error.Error(),
+		page := j.render(j.spiderJobFailedTemplate, settings, map[string]string{
+			"reason": j.translator.TranslateError(settings.language, j1.Error),
 		})
 		fmt.Fprint(w, page)
 		return
 
 	} else if j1.Progress.State == job.CompleteNoResults {
 
-		page := j.spiderJobNoResultsTemplate.MustExec(map[string]interface{}{
-			"guid": guid,
+		page := j.render(j.spiderJobNoResultsTemplate, settings, map[string]interface{}{
+			"guid":     guid,
+			"warnings": j.translator.TranslateMessages(settings.language, j1.Warnings),
 		})
 		fmt.Fprint(w, page)
 		return
 
 	} else if j1.Progress.State == job.CompleteResults {
 
-		page := j.spiderJobResultsTemplate.MustExec(map[string]interface{}{
-			"guid": guid,
+		page := j.render(j.spiderJobResultsTemplate, settings, map[string]interface{}{
+			"guid":      guid,
+			"warnings":  j.translator.TranslateMessages(settings.language, j1.Warnings),
+			"manifest":  len(j1.ManifestFile) > 0,
+			"published": j1.PublishedResults,
 		})
 		fmt.Fprint(w, page)
 		return
@@ -915,6 +1930,12 @@ func (j *JobServer) spiderHandleDownload(w http.ResponseWriter, req *http.Reques
 	// Extract the guid
 	guid := strings.TrimPrefix(req.URL.Path, "/spider-download/")
 
+	// Download the manifest of the result file if requested
+	if strings.HasSuffix(guid, manifestSuffix) {
+		j.spiderHandleDownloadManifest(w, req, strings.TrimSuffix(guid, manifestSuffix))
+		return
+	}
+
 	logging.Logger.Info().
 		Str(logging.ComponentField, componentName).
 		Str(loggingGUIDField, guid).
@@ -948,8 +1969,10 @@ func (j *JobServer) spiderHandleDownload(w http.ResponseWriter, req *http.Reques
 			Str(loggingGUIDField, guid).
 			Msg("Failed to read Excel file for spider job")
 
-		page := j.spiderJobFailedTemplate.MustExec(map[string]string{
-			"reason": fmt.Sprintf("Failed to read Excel file for spider job %v", guid),
+		settings := j.pageSettings(w, req)
+
+		page := j.render(j.spiderJobFailedTemplate, settings, map[string]string{
+			"reason": j.translator.Translate(settings.language, "error.readSpiderExcelFile", guid),
 		})
 
 		fmt.Fprint(w, page)
@@ -964,28 +1987,108 @@ func (j *JobServer) spiderHandleDownload(w http.ResponseWriter, req *http.Reques
 	io.Copy(w, file)
 }
 
-func (j *JobServer) Start() {
+// spiderHandleImportSpec returns the i2 import specification for the spider Excel files.
+func (j *JobServer) spiderHandleImportSpec(w http.ResponseWriter, req *http.Request) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Msg("Received request at /spider-import-spec")
+
+	writeImportSpec(w, j.spiderRunner.chartBuilder.ImportSpec(), "spider-import-spec.ximp")
+}
+
+// Routes returns the HTTP handler for the job server's endpoints. The handler uses its own router
+// rather than the default, so it can be embedded in other servers or wrapped with middleware.
+func (j *JobServer) Routes() http.Handler {
+
+	mux := http.NewServeMux()
 
 	// Spidering
-	http.HandleFunc("/spider", j.spider)
-	http.HandleFunc("/spider-upload", j.spiderUpload)
-	http.HandleFunc("/spider-job/", j.spiderHandleJob)
-	http.HandleFunc("/spider-download/", j.spiderHandleDownload)
+	mux.HandleFunc("/spider", j.spider)
+	mux.HandleFunc("/spider-upload", j.spiderUpload)
+	mux.HandleFunc("/spider-job/", j.spiderHandleJob)
+	mux.HandleFunc("/spider-download/", j.spiderHandleDownload)
+	mux.HandleFunc("/spider-import-spec", j.spiderHandleImportSpec)
 
 	// Uploading job configuration
-	http.HandleFunc("/upload", j.handleUpload)
+	mux.HandleFunc("/upload", j.handleUpload)
+	mux.HandleFunc("/count-entities", j.readingStores(j.handleCountEntities))
+
+	// Job templates
+	mux.HandleFunc(jobTemplatesUrl, j.handleJobTemplates)
+	mux.HandleFunc("/save-job-template", j.csrfProtected(j.handleSaveJobTemplate))
+	mux.HandleFunc("/delete-job-template", j.csrfProtected(j.handleDeleteJobTemplate))
+
+	// Comparison of jobs
+	mux.HandleFunc("/compare", j.readingStores(j.handleCompare))
+	mux.HandleFunc("/compare-download", j.readingStores(j.handleCompareDownload))
+	mux.HandleFunc(mergeDownloadUrl, j.readingStores(j.handleMergeDownload))
+
+	// Paths between two entities
+	mux.HandleFunc("/path", j.handlePath)
+	mux.HandleFunc(bulkSearchUrl, j.readingStores(j.handleBulkSearch))
+	mux.HandleFunc(graphSampleUrl, j.readingStores(j.handleGraphSample))
+
+	// Jobs submitted from the browser
+	mux.HandleFunc(myJobsUrl, j.handleMyJobs)
+
+	// Cases
+	mux.HandleFunc(casesUrl, j.handleCases)
+	mux.HandleFunc("/create-case", j.csrfProtected(j.handleCreateCase))
+	mux.HandleFunc(caseUrl, j.handleCase)
+	mux.HandleFunc("/add-case-job", j.csrfProtected(j.handleAddCaseJob))
+	mux.HandleFunc("/add-case-note", j.csrfProtected(j.handleAddCaseNote))
+	mux.HandleFunc(caseDownloadUrl, j.handleCaseDownload)
+
+	// Watchlists
+	mux.HandleFunc(watchlistsUrl, j.handleWatchlists)
+	mux.HandleFunc("/create-watchlist", j.csrfProtected(j.handleCreateWatchlist))
+	mux.HandleFunc(watchlistUrl, j.handleWatchlist)
+	mux.HandleFunc("/check-watchlist", j.csrfProtected(j.handleCheckWatchlist))
+	mux.HandleFunc("/acknowledge-watchlist", j.csrfProtected(j.handleAcknowledgeWatchlist))
+	mux.HandleFunc("/delete-watchlist", j.csrfProtected(j.handleDeleteWatchlist))
+
+	// Specification of the API
+	mux.HandleFunc(openApiUrl, j.handleOpenApi)
 
 	// Job status
-	http.HandleFunc("/job/", j.handleJob)
+	mux.HandleFunc("/job/", j.handleJob)
 
 	// Entity search
-	http.HandleFunc("/entity/", j.handleEntity)
+	mux.HandleFunc("/entity/", j.readingStores(j.handleEntity))
+	mux.HandleFunc(annotateEntityUrl, j.csrfProtected(j.handleAnnotateEntity))
+	mux.HandleFunc(neighbourhoodUrl, j.readingStores(j.handleNeighbourhoodDownload))
 
 	// Download results
-	http.HandleFunc("/download/", j.handleDownload)
+	mux.HandleFunc("/download/", j.handleDownload)
+	mux.HandleFunc("/download-anx/", j.handleDownloadAnx)
+	mux.HandleFunc("/download-csv/", j.handleDownloadCsv)
+	mux.HandleFunc("/download-partial/", j.handleDownloadPartial)
+	mux.HandleFunc("/download-diagnostics/", j.handleDownloadDiagnostics)
+	mux.HandleFunc("/retry/", j.csrfProtected(j.handleRetry))
+	mux.HandleFunc("/import-spec", j.handleImportSpec)
 
 	// Stats
-	http.HandleFunc("/stats/", j.handleStats)
+	mux.HandleFunc("/stats/", j.handleStats)
+
+	// Health
+	mux.HandleFunc("/healthz", j.handleHealthz)
+	mux.HandleFunc("/readyz", j.readingStores(j.handleReadyz))
+
+	// Maintenance of the graph stores
+	mux.HandleFunc(maintenanceUrl, j.adminOnly(j.handleMaintenance))
+	mux.HandleFunc(generationsUrl, j.adminOnly(j.handleGenerations))
+	mux.HandleFunc(tombstonesUrl, j.adminOnly(j.handleTombstones))
+
+	// Profiling
+	if j.profiling {
+		j.registerProfiling(mux)
+	}
+
+	// Theme
+	mux.HandleFunc("/theme.css", j.handleThemeCss)
+	mux.HandleFunc("/theme/logo", j.handleLogo)
+	mux.HandleFunc("/dark-mode", j.handleDarkMode)
 
 	// Static content
 	sub, err := fs.Sub(staticFS, "static")
@@ -993,9 +2096,13 @@ func (j *JobServer) Start() {
 		logging.Logger.Fatal().Msg("failed to get sub-directory of static")
 	}
 
-	fs := http.FileServer(http.FS(sub))
-	http.Handle("/", NewRootHandler(j.indexPage, fs))
+	fs := cacheStaticAssets(http.FileServer(http.FS(sub)))
+	mux.Handle("/", NewRootHandler(http.HandlerFunc(j.index), fs))
 
-	// Run the server
-	http.ListenAndServe(":8090", nil)
+	return compressResponses(j.securityHeaders(mux))
+}
+
+// Start the job server.
+func (j *JobServer) Start() {
+	http.ListenAndServe(":8090", j.Routes())
 }
@@ -1,22 +1,35 @@
 package server
 
 import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"path"
 	"regexp"
 	"strconv"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/cdclaxton/shortest-path-web-app/filedetector"
 	"github.com/cdclaxton/shortest-path-web-app/graphbuilder"
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/i18n"
 	"github.com/cdclaxton/shortest-path-web-app/i2chart"
 	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/cdclaxton/shortest-path-web-app/normalisation"
+	"github.com/cdclaxton/shortest-path-web-app/pathfilter"
+	"github.com/cdclaxton/shortest-path-web-app/pathmatrix"
 	"github.com/cdclaxton/shortest-path-web-app/search"
 	"github.com/cdclaxton/shortest-path-web-app/set"
+	"github.com/cdclaxton/shortest-path-web-app/spider"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -60,11 +73,33 @@ func TestSplitEntityIDs(t *testing.T) {
 	}
 
 	for _, testCase := range testCases {
-		actual := splitEntityIDs(testCase.text)
+		actual := splitEntityIDs(testCase.text, nil)
 		assert.Equal(t, testCase.expected, actual)
 	}
 }
 
+func TestSplitEntityIDsWithNormaliser(t *testing.T) {
+	normaliser, err := normalisation.New(normalisation.Config{
+		StripPunctuation: true,
+		CaseFold:         normalisation.CaseFoldLower,
+		PhoneCountryCode: "44",
+	})
+	assert.NoError(t, err)
+
+	assert.Equal(t, []string{"e1", "e2", "+441134960000"},
+		splitEntityIDs("E-1, e.2;01134960000", normaliser))
+
+	// The seed entities are the same once they are normalised
+	form := url.Values{}
+	form.Add(SeedEntitiesInputName, "E-1 e.1 e1")
+	req := httptest.NewRequest(http.MethodPost, "/spider-upload", strings.NewReader(form.Encode()))
+	req.Form = form
+
+	seedEntities, err := parseSeedEntities(req, 1, normaliser)
+	assert.NoError(t, err)
+	assert.True(t, set.NewPopulatedSet("e1").Equal(seedEntities))
+}
+
 func TestParseNumberOfHops(t *testing.T) {
 
 	testCases := []struct {
@@ -109,7 +144,7 @@ func TestParseNumberOfHops(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(form.Encode()))
 		req.Form = form
 
-		result, err := parseNumberOfHops(req)
+		result, err := parseNumberOfHops(req, DefaultSearchLimits)
 
 		if testCase.errorExpected {
 			assert.Error(t, err)
@@ -122,6 +157,38 @@ func TestParseNumberOfHops(t *testing.T) {
 
 }
 
+func TestParseMinDocumentsPerLink(t *testing.T) {
+
+	testCases := []struct {
+		onForm        string
+		expected      int
+		errorExpected bool
+	}{
+		{"", 0, false},
+		{" 2 ", 2, false},
+		{"0", 0, false},
+		{"-1", 0, true},
+		{"abc", 0, true},
+	}
+
+	for _, testCase := range testCases {
+		form := url.Values{}
+		form.Add(MinDocumentsInputName, testCase.onForm)
+
+		req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(form.Encode()))
+		req.Form = form
+
+		result, err := parseMinDocumentsPerLink(req)
+		if testCase.errorExpected {
+			assert.Error(t, err)
+		} else {
+			assert.NoError(t, err)
+		}
+
+		assert.Equal(t, testCase.expected, result)
+	}
+}
+
 func TestParseDataset(t *testing.T) {
 
 	testCases := []struct {
@@ -209,7 +276,7 @@ func TestParseDataset(t *testing.T) {
 		req.Form = form
 
 		// Try to parse an entity set from the form data
-		actual, err := parseEntitySet(req, testCase.datasetIndex)
+		actual, err := parseEntitySet(req, testCase.datasetIndex, nil)
 
 		if testCase.errorExpected {
 			assert.Error(t, err)
@@ -229,6 +296,10 @@ func TestExtractJobConfigurationFromForm(t *testing.T) {
 		entityIds1      string
 		name2           string
 		entityIds2      string
+		retry           string
+		directed        string
+		temporal        string
+		minDocuments    string
 		maxDatasetIndex int
 		expected        *job.JobConfiguration
 		errorExpected   bool
@@ -293,6 +364,66 @@ func TestExtractJobConfigurationFromForm(t *testing.T) {
 			},
 			errorExpected: false,
 		},
+		{
+			maxHops:         "2",
+			name1:           "Dataset 1",
+			entityIds1:      "1234",
+			name2:           "",
+			entityIds2:      "",
+			retry:           "true",
+			maxDatasetIndex: 2,
+			expected: &job.JobConfiguration{
+				MaxNumberHops: 2,
+				EntitySets: []job.EntitySet{
+					{
+						Name:      "Dataset 1",
+						EntityIds: []string{"1234"},
+					},
+				},
+				RetryWithFewerHops: true,
+			},
+			errorExpected: false,
+		},
+		{
+			maxHops:         "2",
+			name1:           "Dataset 1",
+			entityIds1:      "1234",
+			name2:           "",
+			entityIds2:      "",
+			directed:        "true",
+			maxDatasetIndex: 2,
+			expected: &job.JobConfiguration{
+				MaxNumberHops: 2,
+				EntitySets: []job.EntitySet{
+					{
+						Name:      "Dataset 1",
+						EntityIds: []string{"1234"},
+					},
+				},
+				Directed: true,
+			},
+			errorExpected: false,
+		},
+		{
+			maxHops:         "2",
+			name1:           "Dataset 1",
+			entityIds1:      "1234",
+			temporal:        "true",
+			minDocuments:    "2",
+			maxDatasetIndex: 2,
+			expected: &job.JobConfiguration{
+				MaxNumberHops: 2,
+				EntitySets: []job.EntitySet{
+					{
+						Name:      "Dataset 1",
+						EntityIds: []string{"1234"},
+					},
+				},
+				Temporal:            true,
+				MinDocumentsPerLink: 2,
+			},
+			errorExpected: false,
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -304,13 +435,18 @@ func TestExtractJobConfigurationFromForm(t *testing.T) {
 		form.Add(fmt.Sprintf("%v%v", DatasetEntitiesInputName, 1), testCase.entityIds1)
 		form.Add(fmt.Sprintf("%v%v", DatasetNameInputName, 2), testCase.name2)
 		form.Add(fmt.Sprintf("%v%v", DatasetEntitiesInputName, 2), testCase.entityIds2)
+		form.Add(RetryInputName, testCase.retry)
+		form.Add(DirectedInputName, testCase.directed)
+		form.Add(TemporalInputName, testCase.temporal)
+		form.Add(MinDocumentsInputName, testCase.minDocuments)
 
 		// Make the HTTP request
 		req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(form.Encode()))
 		req.Form = form
 
 		// Try to parse an entity set from the form data
-		actual, err := extractJobConfigurationFromForm(req, testCase.maxDatasetIndex)
+		actual, err := extractJobConfigurationFromForm(req, testCase.maxDatasetIndex, job.JobLimits{},
+			DefaultSearchLimits, nil)
 
 		if testCase.errorExpected {
 			assert.Error(t, err)
@@ -322,6 +458,131 @@ func TestExtractJobConfigurationFromForm(t *testing.T) {
 	}
 }
 
+func TestExtractJobConfigurationFromFormWithLimits(t *testing.T) {
+
+	testCases := []struct {
+		description   string
+		form          url.Values
+		limits        job.JobLimits
+		expectedError error
+	}{
+		{
+			description: "no limits",
+			form:        buildFormData(1, "D1", "e-1,e-2,e-3", "", "", "", ""),
+			limits:      job.JobLimits{},
+		},
+		{
+			description: "within the limits",
+			form:        buildFormData(1, "D1", "e-1,e-2,e-3", "", "", "", ""),
+			limits:      job.JobLimits{MaxEntityIdsPerDataset: 3, MaxEntityPairs: 6},
+		},
+		{
+			description:   "too many entity IDs in a dataset",
+			form:          buildFormData(1, "D1", "e-1", "D2", "e-1,e-2,e-3", "", ""),
+			limits:        job.JobLimits{MaxEntityIdsPerDataset: 2},
+			expectedError: job.ErrTooManyEntityIds,
+		},
+		{
+			description:   "too many pairs of entities",
+			form:          buildFormData(1, "D1", "e-1,e-2", "D2", "e-3,e-4", "D3", "e-5"),
+			limits:        job.JobLimits{MaxEntityPairs: 7},
+			expectedError: job.ErrTooManyEntityPairs,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.description, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/upload",
+				strings.NewReader(testCase.form.Encode()))
+			req.Form = testCase.form
+
+			actual, err := extractJobConfigurationFromForm(req, DefaultMaxDatasets, testCase.limits,
+				DefaultSearchLimits, nil)
+			if testCase.expectedError != nil {
+				assert.ErrorIs(t, err, testCase.expectedError)
+				assert.Nil(t, actual)
+			} else {
+				assert.NoError(t, err)
+				assert.NotNil(t, actual)
+			}
+		})
+	}
+}
+
+func TestUploadExceedingJobLimits(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	assert.ErrorIs(t, server.SetJobLimits(job.JobLimits{MaxEntityPairs: -1}),
+		job.ErrInvalidJobLimits)
+	assert.NoError(t, server.SetJobLimits(job.JobLimits{MaxEntityIdsPerDataset: 2}))
+
+	form := buildFormData(1, "Dataset-1", "e-1,e-2,e-3", "", "", "", "")
+	req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(form.Encode()))
+	req.Form = form
+
+	w := httptest.NewRecorder()
+	server.handleUpload(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "dataset Dataset-1 has 3 entity IDs, but the maximum is 2")
+}
+
+func TestUploadWithInvalidEntityIds(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	rules, err := job.NewEntityIdRules(job.EntityIdRulesConfig{
+		MaxLength: 5,
+		Schemes:   []job.EntityIdScheme{{Name: "entity", Pattern: "e-[0-9]+"}},
+	})
+	assert.NoError(t, err)
+	server.SetEntityIdRules(rules)
+
+	// Shortest path job
+	form := buildFormData(1, "Dataset-1", "e-1,e2,e-12345", "", "", "", "")
+	req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(form.Encode()))
+	req.Form = form
+
+	w := httptest.NewRecorder()
+	server.handleUpload(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	body := w.Body.String()
+	assert.Contains(t, body, "2 entity IDs in dataset Dataset-1 don&apos;t look valid")
+	assert.Contains(t, body, "<td class=\"govuk-table__cell\">e2</td>")
+	assert.Contains(t, body, "<td class=\"govuk-table__cell\">e-12345</td>")
+	assert.Contains(t, body, "too long")
+	assert.NotContains(t, body, "<td class=\"govuk-table__cell\">e-1</td>")
+
+	// Spider job
+	form = url.Values{}
+	form.Add(SeedEntitiesInputName, "e-1, x-1")
+	form.Add(NumberStepsInputName, "1")
+	req = httptest.NewRequest(http.MethodPost, "/spider-upload", strings.NewReader(form.Encode()))
+	req.Form = form
+
+	w = httptest.NewRecorder()
+	server.spiderUpload(w, req)
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "<td class=\"govuk-table__cell\">x-1</td>")
+
+	// Valid entity IDs
+	form = buildFormData(1, "Dataset-1", "e-1,e-2", "", "", "", "")
+	req = httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(form.Encode()))
+	req.Form = form
+
+	w = httptest.NewRecorder()
+	server.handleUpload(w, req)
+	assert.Equal(t, http.StatusFound, w.Code)
+	waitForJobsToFinish(server.runner)
+}
+
 func TestBuildFilename(t *testing.T) {
 	testCases := []struct {
 		jobConf          *job.JobConfiguration
@@ -367,6 +628,19 @@ func TestBuildFilename(t *testing.T) {
 			errorExpected:    false,
 			expectedFilename: "shortest-path - dataset A - dataset B - 1 hop.xlsx",
 		},
+		{
+			jobConf: &job.JobConfiguration{
+				EntitySets: []job.EntitySet{
+					{
+						Name: "dataset A",
+					},
+				},
+				MaxNumberHops: 2,
+				PathMatrix:    true,
+			},
+			errorExpected:    false,
+			expectedFilename: "path-matrix - dataset A - 2 hops.xlsx",
+		},
 		{
 			jobConf: &job.JobConfiguration{
 				EntitySets:    nil,
@@ -602,6 +876,151 @@ func TestUploadNoResults(t *testing.T) {
 	assert.True(t, webPageContainsText(w, guid, "No results"))
 }
 
+func TestUploadNoResultsWithSuggestions(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	// Upload a form with entity IDs that differ from those in the graph by case
+	form := buildFormData(1, "Dataset-1", "E-1,E-2", "", "", "", "")
+	req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(form.Encode()))
+	req.Form = form
+
+	w := httptest.NewRecorder()
+	server.handleUpload(w, req)
+	assert.Equal(t, http.StatusFound, w.Code)
+
+	location := w.Result().Header.Get("Location")
+	waitForJobsToFinish(server.runner)
+
+	// The no results page suggests the entity IDs in the graph
+	w = getPage(server.Routes(), location)
+	body := w.Body.String()
+	assert.Contains(t, body, "Did you mean")
+	assert.Contains(t, body, `<a href="../entity/e-1" class="govuk-link">e-1</a>`)
+	assert.Contains(t, body, `<a href="../entity/e-2" class="govuk-link">e-2</a>`)
+
+	// The entity page suggests the entity IDs in the graph
+	w = getPage(server.Routes(), "/entity/E-1")
+	assert.Contains(t, w.Body.String(), `<a href="e-1" class="govuk-link">e-1</a>`)
+}
+
+func TestUploadWithExcludedEntities(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	// The only path from e-1 to e-4 is through e-3
+	testCases := []struct {
+		excluded      string
+		expectedState job.JobState
+	}{
+		{excluded: "", expectedState: job.CompleteResults},
+		{excluded: "e-3", expectedState: job.CompleteNoResults},
+	}
+
+	for _, testCase := range testCases {
+		form := buildFormData(2, "Dataset-1", "e-1,e-4", "", "", "", "")
+		form.Add(ExcludeEntitiesInputName, testCase.excluded)
+
+		w := postForm(server.Routes(), "/upload", form)
+		assert.Equal(t, http.StatusFound, w.Code)
+		guid := extractGuidFromLocation(t, w.Result().Header.Get("Location"))
+		waitForJobsToFinish(server.runner)
+
+		j1, err := server.runner.GetJob(guid)
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.expectedState, j1.Progress.State)
+	}
+}
+
+func TestUploadWithWaypoints(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	// The only path from e-2 to e-4 is e-2, e-1, e-3, e-4
+	testCases := []struct {
+		waypoints     string
+		expectedState job.JobState
+	}{
+		{waypoints: "e-3", expectedState: job.CompleteResults},
+		{waypoints: "e-9, e-1", expectedState: job.CompleteResults},
+		{waypoints: "e-9", expectedState: job.CompleteNoResults},
+	}
+
+	for _, testCase := range testCases {
+		form := buildFormData(3, "Dataset-1", "e-2,e-4", "", "", "", "")
+		form.Add(WaypointsInputName, testCase.waypoints)
+
+		w := postForm(server.Routes(), "/upload", form)
+		assert.Equal(t, http.StatusFound, w.Code)
+		guid := extractGuidFromLocation(t, w.Result().Header.Get("Location"))
+		waitForJobsToFinish(server.runner)
+
+		j1, err := server.runner.GetJob(guid)
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.expectedState, j1.Progress.State, testCase.waypoints)
+	}
+}
+
+func TestExtractJobConfigurationWithPathFilter(t *testing.T) {
+
+	form := buildFormData(1, "D1", "e-1,e-2", "", "", "", "")
+	form.Add(PathFilterInputName, "Region = North AND\nStatus contains open")
+	req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(form.Encode()))
+	req.Form = form
+
+	conf, err := extractJobConfigurationFromForm(req, DefaultMaxDatasets, job.JobLimits{},
+		DefaultSearchLimits, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "Region = North and Status contains open", conf.PathFilter)
+
+	// Invalid filter
+	form.Set(PathFilterInputName, "Region")
+	conf, err = extractJobConfigurationFromForm(req, DefaultMaxDatasets, job.JobLimits{},
+		DefaultSearchLimits, nil)
+	assert.ErrorIs(t, err, pathfilter.ErrInvalidFilter)
+	assert.Contains(t, err.Error(), "invalid path filter 'Region'")
+	assert.Nil(t, conf)
+}
+
+func TestUploadWithPathFilter(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	// The only path from e-2 to e-4 is e-2, e-1, e-3, e-4
+	testCases := []struct {
+		pathFilter       string
+		expectedState    job.JobState
+		expectedWarnings int
+	}{
+		{pathFilter: "Forename = bob", expectedState: job.CompleteResults, expectedWarnings: 0},
+		{pathFilter: "First line contains field", expectedState: job.CompleteResults, expectedWarnings: 0},
+		{pathFilter: "Forename = Alice", expectedState: job.CompleteNoResults, expectedWarnings: 1},
+	}
+
+	for _, testCase := range testCases {
+		form := buildFormData(3, "Dataset-1", "e-2,e-4", "", "", "", "")
+		form.Add(PathFilterInputName, testCase.pathFilter)
+
+		w := postForm(server.Routes(), "/upload", form)
+		assert.Equal(t, http.StatusFound, w.Code)
+		guid := extractGuidFromLocation(t, w.Result().Header.Get("Location"))
+		waitForJobsToFinish(server.runner)
+
+		j1, err := server.runner.GetJob(guid)
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.expectedState, j1.Progress.State, testCase.pathFilter)
+		assert.Len(t, j1.Warnings, testCase.expectedWarnings, testCase.pathFilter)
+	}
+}
+
 func TestUploadWithResults(t *testing.T) {
 
 	// Make a valid job server
@@ -638,6 +1057,65 @@ func TestUploadWithResults(t *testing.T) {
 	assert.True(t, webPageContainsText(w, guid, "Download Excel file"))
 }
 
+func TestUploadWithVerboseLogging(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	// The same job is submitted for each test case
+	assert.NoError(t, server.SetDuplicateJobWindow(0))
+
+	testCases := []struct {
+		description     string
+		adminToken      string
+		requestToken    string
+		expectedVerbose bool
+	}{
+		{
+			description:     "admin features disabled",
+			adminToken:      "",
+			requestToken:    "",
+			expectedVerbose: false,
+		},
+		{
+			description:     "invalid token",
+			adminToken:      "secret",
+			requestToken:    "guess",
+			expectedVerbose: false,
+		},
+		{
+			description:     "valid token",
+			adminToken:      "secret",
+			requestToken:    "secret",
+			expectedVerbose: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.description, func(t *testing.T) {
+			server.SetAdminToken(testCase.adminToken)
+
+			form := buildFormData(1, "Dataset-1", "e-1, e-2", "", "", "", "")
+			form.Add(VerboseLoggingInputName, "true")
+			req := httptest.NewRequest(http.MethodPost, "/upload", strings.NewReader(form.Encode()))
+			req.Form = form
+			req.Header.Set(AdminTokenHeader, testCase.requestToken)
+
+			w := httptest.NewRecorder()
+			server.handleUpload(w, req)
+			assert.Equal(t, http.StatusFound, w.Code)
+
+			guid := extractGuidFromLocation(t, w.Result().Header.Get("Location"))
+			waitForJobsToFinish(server.runner)
+
+			j1, err := server.runner.GetJob(guid)
+			assert.NoError(t, err)
+			assert.Equal(t, testCase.expectedVerbose, j1.Configuration.VerboseLogging)
+		})
+	}
+}
+
 func TestDownloadWithResults(t *testing.T) {
 
 	// Make a valid job server
@@ -676,11 +1154,149 @@ func TestDownloadWithResults(t *testing.T) {
 
 	disposition := w.Result().Header.Get("Content-Disposition")
 	assert.Equal(t, "attachment; filename=shortest-path - Dataset-1 - 1 hop.xlsx", disposition)
-}
 
-func TestUploadFailedJob(t *testing.T) {
+	// Try to download the ANX chart
+	url = fmt.Sprintf("/download-anx/%v", guid)
+	req = httptest.NewRequest(http.MethodGet, url, nil)
+	w = httptest.NewRecorder()
 
-	// Make a valid job server, but remove the folder from the job runner so that the job errors
+	server.handleDownloadAnx(w, req)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.True(t, strings.Contains(w.Body.String(), "<Chart>"))
+
+	disposition = w.Result().Header.Get("Content-Disposition")
+	assert.Equal(t, "attachment; filename=shortest-path - Dataset-1 - 1 hop.anx", disposition)
+}
+
+func TestJobWithoutAnx(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	// The chart config doesn't have the default identity column of an ANX chart
+	content, err := os.ReadFile("../test-data-sets/set-1/i2-config.json")
+	assert.NoError(t, err)
+	i2ConfigFilepath := path.Join(t.TempDir(), "i2-config.json")
+	assert.NoError(t, os.WriteFile(i2ConfigFilepath,
+		[]byte(strings.ReplaceAll(string(content), `"id"`, `"key"`)), 0644))
+
+	chartBuilder, err := i2chart.NewI2ChartBuilder(i2ConfigFilepath)
+	assert.NoError(t, err)
+	assert.False(t, chartBuilder.AnxEnabled())
+	builder, _, err := graphbuilder.NewGraphBuilderFromJson("../test-data-sets/set-1/data-config.json")
+	assert.NoError(t, err)
+	chartBuilder.SetBipartite(builder.Bipartite)
+	server.runner.chartBuilder = chartBuilder
+
+	handler := server.Routes()
+
+	form := buildFormData(2, "Dataset-1", "e-1, e-4", "Dataset-2", "e-2, e-100", "", "")
+	w := postForm(handler, "/upload", form)
+	assert.Equal(t, http.StatusFound, w.Code)
+
+	guid := extractGuidFromLocation(t, w.Result().Header.Get("Location"))
+	waitForJobsToFinish(server.runner)
+
+	// The job completes with the Excel file, but without an ANX file
+	j1, err := server.runner.GetJob(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, job.CompleteResults, j1.Progress.State)
+	assert.NotEqual(t, "", j1.ResultFile)
+	assert.Equal(t, "", j1.AnxResultFile)
+
+	// The results page doesn't link to the ANX file
+	w = getPage(handler, "/job/"+guid)
+	assert.Contains(t, w.Body.String(), "download/"+guid)
+	assert.NotContains(t, w.Body.String(), "download-anx/"+guid)
+}
+
+func TestDownloadPathMatrix(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	handler := server.Routes()
+
+	// Upload a form requesting a path matrix
+	form := buildFormData(2, "Dataset-1", "e-1, e-4", "Dataset-2", "e-2, e-100", "", "")
+	form.Add(PathMatrixInputName, "true")
+	w := postForm(handler, "/upload", form)
+	assert.Equal(t, http.StatusFound, w.Code)
+
+	guid := extractGuidFromLocation(t, w.Result().Header.Get("Location"))
+	waitForJobsToFinish(server.runner)
+
+	j1, err := server.runner.GetJob(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, job.CompleteResults, j1.Progress.State)
+	assert.Equal(t, "", j1.AnxResultFile)
+
+	// The results page links to the CSV file instead of the i2 chart
+	w = getPage(handler, "/job/"+guid)
+	assert.Contains(t, w.Body.String(), "download-csv/"+guid)
+	assert.NotContains(t, w.Body.String(), "download-anx/"+guid)
+
+	// Download the CSV file
+	w = getPage(handler, "/download-csv/"+guid)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, csvContentType, w.Header().Get("Content-Type"))
+	assert.Equal(t, "attachment; filename=path-matrix - Dataset-1 - Dataset-2 - 2 hops.csv",
+		w.Header().Get("Content-Disposition"))
+
+	rows, err := csv.NewReader(w.Body).ReadAll()
+	assert.NoError(t, err)
+	assert.Equal(t, [][]string{
+		pathmatrix.Header,
+		{"e-1", "Dataset-1", "e-2", "Dataset-2", "Yes", "1", "1"},
+		{"e-1", "Dataset-1", "e-100", "Dataset-2", "No", "", "0"},
+		{"e-4", "Dataset-1", "e-2", "Dataset-2", "No", "", "0"},
+		{"e-4", "Dataset-1", "e-100", "Dataset-2", "No", "", "0"},
+	}, rows)
+
+	// The Excel file is also available
+	w = getPage(handler, "/download/"+guid)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.True(t, w.Body.Len() > 0)
+
+	// A job without a path matrix doesn't have a CSV file
+	w = postForm(handler, "/upload", buildFormData(1, "Dataset-1", "e-1, e-2", "", "", "", ""))
+	guid = extractGuidFromLocation(t, w.Result().Header.Get("Location"))
+	waitForJobsToFinish(server.runner)
+
+	w = getPage(handler, "/download-csv/"+guid)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestHandleImportSpec(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	req := httptest.NewRequest(http.MethodGet, "/import-spec", nil)
+	w := httptest.NewRecorder()
+
+	server.handleImportSpec(w, req)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.True(t, strings.Contains(w.Body.String(), `<ImportSpecification Name="shortest-path"`))
+	assert.Equal(t, "attachment; filename=shortest-path-import-spec.ximp",
+		w.Result().Header.Get("Content-Disposition"))
+
+	req = httptest.NewRequest(http.MethodGet, "/spider-import-spec", nil)
+	w = httptest.NewRecorder()
+
+	server.spiderHandleImportSpec(w, req)
+	assert.Equal(t, http.StatusOK, w.Result().StatusCode)
+	assert.True(t, strings.Contains(w.Body.String(), `<ImportSpecification Name="spider"`))
+	assert.Equal(t, "attachment; filename=spider-import-spec.ximp",
+		w.Result().Header.Get("Content-Disposition"))
+}
+
+func TestUploadFailedJob(t *testing.T) {
+
+	// Make a valid job server, but remove the folder from the job runner so that the job errors
 	server := makeJobServer(t)
 	cleanUpJobRunner(t, server.runner)
 
@@ -742,6 +1358,106 @@ func TestHandleStats(t *testing.T) {
 	server.handleStats(w, req)
 	assert.True(t, len(w.Body.String()) > 0)
 	assert.True(t, strings.Contains(w.Body.String(), "Statistics"))
+	assert.Contains(t, w.Body.String(), "The provenance of the data is unknown")
+
+	// Graph with a known provenance
+	server.stats.Provenance = filedetector.DataProvenance{
+		Signature:   "abc123",
+		SourceFiles: []string{"documents.csv", "links.csv"},
+		Loaded:      time.Now(),
+	}
+
+	w = httptest.NewRecorder()
+	server.handleStats(w, req)
+	assert.Contains(t, w.Body.String(), "abc123")
+	assert.Contains(t, w.Body.String(), "links.csv")
+	assert.NotContains(t, w.Body.String(), "The provenance of the data is unknown")
+	assert.NotContains(t, w.Body.String(), "expectations of its data")
+
+	// Graph that doesn't meet its expectations
+	server.stats.Warnings = []string{"no entities of type Vehicle"}
+
+	w = httptest.NewRecorder()
+	server.handleStats(w, req)
+	assert.Contains(t, w.Body.String(), "expectations of its data")
+	assert.Contains(t, w.Body.String(), "no entities of type Vehicle")
+}
+
+func TestHandleStatsJson(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	server.stats = graphbuilder.GraphStats{
+		Bipartite: graphstore.BipartiteStats{
+			NumberOfEntities:              3,
+			NumberOfEntitiesWithDocuments: 2,
+			NumberOfDocuments:             2,
+			NumberOfDocumentsWithEntities: 1,
+		},
+		Unipartite: graphstore.UnipartiteStats{NumberOfEntities: 2},
+		Types: graphstore.TypeCounts{
+			EntityTypes:   map[string]int{"Person": 2, "Vehicle": 1},
+			DocumentTypes: map[string]int{"Report": 2},
+		},
+	}
+
+	// The type counts are shown on the HTML page
+	req := httptest.NewRequest(http.MethodGet, "/stats/", nil)
+	w := httptest.NewRecorder()
+	server.handleStats(w, req)
+	assert.Contains(t, w.Body.String(), "Entities by type")
+	assert.Contains(t, w.Body.String(), "Vehicle")
+	assert.Contains(t, w.Body.String(), "Report")
+
+	expected := StatsResponse{
+		NumberOfEntities:              3,
+		NumberOfEntitiesWithDocuments: 2,
+		NumberOfDocuments:             2,
+		NumberOfDocumentsWithEntities: 1,
+		NumberOfEntitiesInUnipartite:  2,
+		EntityTypes:                   map[string]int{"Person": 2, "Vehicle": 1},
+		DocumentTypes:                 map[string]int{"Report": 2},
+		Warnings:                      []string{},
+	}
+
+	// JSON is requested with the format query parameter or the Accept header
+	requests := []*http.Request{
+		httptest.NewRequest(http.MethodGet, "/stats/?format=json", nil),
+		httptest.NewRequest(http.MethodGet, "/stats/", nil),
+	}
+	requests[1].Header.Set("Accept", "application/json")
+
+	for _, req := range requests {
+		w := httptest.NewRecorder()
+		server.handleStats(w, req)
+		assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+
+		var response StatsResponse
+		assert.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		assert.Equal(t, expected, response)
+	}
+}
+
+func TestRefreshStats(t *testing.T) {
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	// Without a graph there aren't any stats to refresh
+	server.RefreshStats()
+
+	// The stats of the graph are updated, e.g. by pruning
+	graph := &graphbuilder.GraphBuilder{}
+	server.SetGraph(graph)
+	graph.Stats.Bipartite.NumberOfDocuments = 12345
+	assert.NotEqual(t, 12345, server.stats.Bipartite.NumberOfDocuments)
+
+	server.RefreshStats()
+
+	req := httptest.NewRequest(http.MethodGet, "/stats/", nil)
+	w := httptest.NewRecorder()
+	server.handleStats(w, req)
+	assert.Contains(t, w.Body.String(), "12345")
 }
 
 func TestPrepareEntitySearchResults(t *testing.T) {
@@ -835,7 +1551,7 @@ func TestParseNumberOfSteps(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, "/spider-upload", strings.NewReader(form.Encode()))
 		req.Form = form
 
-		actual, err := parseNumberOfSteps(req)
+		actual, err := parseNumberOfSteps(req, DefaultSearchLimits)
 
 		assert.Equal(t, testCase.expectedNumberSteps, actual)
 
@@ -895,7 +1611,7 @@ func TestParseSeedEntities(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, "/spider-upload", strings.NewReader(form.Encode()))
 		req.Form = form
 
-		actual, err := parseSeedEntities(req)
+		actual, err := parseSeedEntities(req, DefaultMaxSeedEntities, nil)
 		if testCase.errorExpected {
 			assert.Error(t, err)
 			assert.Nil(t, actual)
@@ -970,7 +1686,8 @@ func TestExtractSpiderJobConfigurationFromForm(t *testing.T) {
 		req := httptest.NewRequest(http.MethodPost, "/spider-upload", strings.NewReader(form.Encode()))
 		req.Form = form
 
-		actual, err := extractSpiderJobConfigurationFromForm(req)
+		actual, err := extractSpiderJobConfigurationFromForm(req, DefaultMaxSeedEntities,
+			DefaultSearchLimits, nil)
 
 		if testCase.errorExpected {
 			assert.Error(t, err)
@@ -982,6 +1699,21 @@ func TestExtractSpiderJobConfigurationFromForm(t *testing.T) {
 	}
 }
 
+func TestParseStepTypes(t *testing.T) {
+
+	form := url.Values{}
+	form.Add(StepTypesInputName+"2", " Address, Vehicle ,")
+	form.Add(StepTypesInputName+"3", "Person")
+
+	req := httptest.NewRequest(http.MethodPost, "/spider-upload", strings.NewReader(form.Encode()))
+	req.Form = form
+
+	// The steps after the number of steps are ignored
+	assert.Nil(t, parseStepTypes(req, 1))
+	assert.Equal(t, [][]string{{}, {"Address", "Vehicle"}}, parseStepTypes(req, 2))
+	assert.Equal(t, [][]string{{}, {"Address", "Vehicle"}, {"Person"}}, parseStepTypes(req, 3))
+}
+
 func TestSpiderUpload(t *testing.T) {
 
 	// Make a valid job server
@@ -1030,6 +1762,261 @@ func TestSpiderUpload(t *testing.T) {
 	}
 }
 
+// buildMultipartSpiderRequest with a file of seed entities.
+func buildMultipartSpiderRequest(t *testing.T, numberSteps string, seedEntities string,
+	fileContents string) *http.Request {
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	assert.NoError(t, writer.WriteField(NumberStepsInputName, numberSteps))
+	assert.NoError(t, writer.WriteField(SeedEntitiesInputName, seedEntities))
+
+	part, err := writer.CreateFormFile(SeedEntitiesFileInputName, "seeds.csv")
+	assert.NoError(t, err)
+	_, err = part.Write([]byte(fileContents))
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+
+	req := httptest.NewRequest(http.MethodPost, "/spider-upload", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	return req
+}
+
+// buildMultipartUploadRequest for the upload form with one dataset given by a textbox and a file.
+func buildMultipartUploadRequest(t *testing.T, url string, fields map[string]string,
+	fileInputName string, fileContents string) *http.Request {
+
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	for name, value := range fields {
+		assert.NoError(t, writer.WriteField(name, value))
+	}
+
+	if len(fileInputName) > 0 {
+		part, err := writer.CreateFormFile(fileInputName, "entities.csv")
+		assert.NoError(t, err)
+		_, err = part.Write([]byte(fileContents))
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, writer.Close())
+
+	req := httptest.NewRequest(http.MethodPost, url, body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+
+	return req
+}
+
+func TestExtractJobConfigurationFromFile(t *testing.T) {
+	testCases := []struct {
+		description   string
+		entityIds     string
+		fileContents  string
+		expected      []string
+		errorExpected bool
+	}{
+		{
+			description:  "file only",
+			fileContents: "\ufeff\"e-1\",\"e-2\"\r\ne-3\n",
+			expected:     []string{"e-1", "e-2", "e-3"},
+		},
+		{
+			description:  "textbox and file with duplicates",
+			entityIds:    "e-3, e-1",
+			fileContents: "e-1\ne-2\ne-3\ne-2",
+			expected:     []string{"e-3", "e-1", "e-2"},
+		},
+		{
+			description:   "empty file and textbox",
+			fileContents:  " \n",
+			errorExpected: true,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.description, func(t *testing.T) {
+			req := buildMultipartUploadRequest(t, "/upload", map[string]string{
+				NumberHopsInputName:            "2",
+				DatasetNameInputName + "1":     "Dataset 1",
+				DatasetEntitiesInputName + "1": testCase.entityIds,
+			}, DatasetFileInputName+"1", testCase.fileContents)
+
+			actual, err := extractJobConfigurationFromForm(req, DefaultMaxDatasets, job.JobLimits{},
+				DefaultSearchLimits, nil)
+			if testCase.errorExpected {
+				assert.Error(t, err)
+				assert.Nil(t, actual)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, []job.EntitySet{
+					{Name: "Dataset 1", EntityIds: testCase.expected},
+				}, actual.EntitySets)
+			}
+		})
+	}
+}
+
+func TestHandleCountEntities(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	assert.NoError(t, server.SetJobLimits(job.JobLimits{MaxEntityIdsPerDataset: 2}))
+	handler := server.Routes()
+
+	testCases := []struct {
+		description    string
+		fields         map[string]string
+		fileContents   string
+		expectedCode   int
+		expectedCount  EntityCount
+		acceptLanguage string
+	}{
+		{
+			description: "textbox and file",
+			fields: map[string]string{
+				DatasetIndexInputName:          "2",
+				DatasetEntitiesInputName + "2": "e-1",
+			},
+			fileContents: "e-1\ne-2",
+			expectedCode: http.StatusOK,
+			expectedCount: EntityCount{
+				NumberOfEntityIds:       3,
+				NumberOfUniqueEntityIds: 2,
+				Message:                 "2 unique entity IDs. 1 duplicates will be removed.",
+			},
+		},
+		{
+			description: "more than the maximum in Welsh",
+			fields: map[string]string{
+				DatasetIndexInputName:          "2",
+				DatasetEntitiesInputName + "2": "e-1 e-2 e-3",
+			},
+			acceptLanguage: "cy",
+			expectedCode:   http.StatusOK,
+			expectedCount: EntityCount{
+				NumberOfEntityIds:       3,
+				NumberOfUniqueEntityIds: 3,
+				Message:                 "3 o IDs endidau unigryw. Mae hyn yn fwy na'r uchafswm o 2.",
+			},
+		},
+		{
+			description: "invalid dataset index",
+			fields: map[string]string{
+				DatasetIndexInputName: "4",
+			},
+			expectedCode: http.StatusBadRequest,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.description, func(t *testing.T) {
+			req := buildMultipartUploadRequest(t, "/count-entities", testCase.fields,
+				DatasetFileInputName+"2", testCase.fileContents)
+			req.Header.Set("Accept-Language", testCase.acceptLanguage)
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			assert.Equal(t, testCase.expectedCode, w.Code)
+
+			if testCase.expectedCode == http.StatusOK {
+				actual := EntityCount{}
+				assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &actual))
+				assert.Equal(t, testCase.expectedCount, actual)
+			}
+		})
+	}
+}
+
+func TestExtractSpiderJobConfigurationFromFile(t *testing.T) {
+	testCases := []struct {
+		description     string
+		seedEntities    string
+		fileContents    string
+		maxSeedEntities int
+		expected        *set.Set[string]
+		expectedError   error
+	}{
+		{
+			description:     "one entity per line",
+			fileContents:    "e-1\ne-2\r\ne-3\n",
+			maxSeedEntities: DefaultMaxSeedEntities,
+			expected:        set.NewPopulatedSet("e-1", "e-2", "e-3"),
+		},
+		{
+			description:     "CSV with quotes and a byte order mark",
+			fileContents:    "\ufeff\"e-1\",\"e-2\"\n\"e-2\",e-3",
+			maxSeedEntities: DefaultMaxSeedEntities,
+			expected:        set.NewPopulatedSet("e-1", "e-2", "e-3"),
+		},
+		{
+			description:     "file and textbox",
+			seedEntities:    "e-4",
+			fileContents:    "e-1",
+			maxSeedEntities: DefaultMaxSeedEntities,
+			expected:        set.NewPopulatedSet("e-1", "e-4"),
+		},
+		{
+			description:     "empty file",
+			fileContents:    " \n",
+			maxSeedEntities: DefaultMaxSeedEntities,
+			expectedError:   ErrNoSeedEntities,
+		},
+		{
+			description:     "duplicates don't count towards the limit",
+			fileContents:    "e-1\ne-2\ne-1",
+			maxSeedEntities: 2,
+			expected:        set.NewPopulatedSet("e-1", "e-2"),
+		},
+		{
+			description:     "too many seed entities",
+			fileContents:    "e-1\ne-2\ne-3",
+			maxSeedEntities: 2,
+			expectedError:   ErrTooManySeedEntities,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.description, func(t *testing.T) {
+			req := buildMultipartSpiderRequest(t, "1", testCase.seedEntities, testCase.fileContents)
+
+			actual, err := extractSpiderJobConfigurationFromForm(req, testCase.maxSeedEntities,
+				DefaultSearchLimits, nil)
+			if testCase.expectedError != nil {
+				assert.ErrorIs(t, err, testCase.expectedError)
+				assert.Nil(t, actual)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, 1, actual.NumberSteps)
+				assert.True(t, testCase.expected.Equal(actual.SeedEntities))
+			}
+		})
+	}
+}
+
+func TestSpiderUploadFile(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	assert.ErrorIs(t, server.SetMaxSeedEntities(0), ErrInvalidMaxSeedEntities)
+	assert.NoError(t, server.SetMaxSeedEntities(1))
+
+	// Too many seed entities
+	w := httptest.NewRecorder()
+	server.spiderUpload(w, buildMultipartSpiderRequest(t, "1", "", "e-1\ne-2"))
+	assert.Equal(t, http.StatusBadRequest, w.Result().StatusCode)
+
+	// Valid file
+	w = httptest.NewRecorder()
+	server.spiderUpload(w, buildMultipartSpiderRequest(t, "1", "", "e-1"))
+	assert.Equal(t, http.StatusFound, w.Result().StatusCode)
+}
+
 // extractGuidFromLocation returns the job GUID from a path for the form /job/<GUID>.
 func extractSpiderGuidFromLocation(t *testing.T, location string) string {
 	assert.True(t, strings.Contains(location, "/spider-job/"))
@@ -1178,3 +2165,214 @@ func TestRunSpiderJob(t *testing.T) {
 		})
 	}
 }
+
+func TestSetSpiderCaps(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	assert.ErrorIs(t, server.SetSpiderCaps(spider.SpiderCaps{MaxEntities: -1}),
+		spider.ErrInvalidCaps)
+	assert.NoError(t, server.SetSpiderCaps(spider.SpiderCaps{MaxEntities: 10, MaxNeighbours: 1}))
+	assert.Equal(t, spider.SpiderCaps{MaxEntities: 10, MaxNeighbours: 1}, server.spiderCaps)
+}
+
+func TestRunSpiderJobWithCaps(t *testing.T) {
+
+	// Make a valid job server where only one neighbour of an entity is expanded
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+	assert.NoError(t, server.SetSpiderCaps(spider.SpiderCaps{MaxNeighbours: 1}))
+
+	// e-1 is connected to e-2 and e-3
+	w := postForm(server.Routes(), "/spider-upload", buildSpiderFormData(1, "e-1"))
+	assert.Equal(t, http.StatusFound, w.Code)
+	guid := extractSpiderGuidFromLocation(t, w.Result().Header.Get("Location"))
+
+	waitForSpiderJobsToFinish(server.spiderRunner)
+
+	j1, err := server.spiderRunner.GetJob(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, j1.Configuration.MaxNeighbours)
+	assert.Len(t, j1.Warnings, 1)
+
+	w = getPage(server.Routes(), "/spider-job/"+guid)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(),
+		"Only the first 1 neighbours of 1 entities with more neighbours were included.")
+}
+
+func TestRoutes(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	handler := server.Routes()
+
+	testCases := []struct {
+		description  string
+		url          string
+		expectedCode int
+	}{
+		{
+			description:  "index page",
+			url:          "/",
+			expectedCode: http.StatusOK,
+		},
+		{
+			description:  "spider index page",
+			url:          "/spider",
+			expectedCode: http.StatusOK,
+		},
+		{
+			description:  "static content",
+			url:          "/govuk-frontend-4.3.1.min.css",
+			expectedCode: http.StatusOK,
+		},
+		{
+			description:  "download for a GUID that doesn't exist",
+			url:          "/download/1234",
+			expectedCode: http.StatusNotFound,
+		},
+		{
+			description:  "i2 import specification",
+			url:          "/import-spec",
+			expectedCode: http.StatusOK,
+		},
+		{
+			description:  "spider i2 import specification",
+			url:          "/spider-import-spec",
+			expectedCode: http.StatusOK,
+		},
+		{
+			description:  "unknown static content",
+			url:          "/missing.css",
+			expectedCode: http.StatusNotFound,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.description, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, testCase.url, nil)
+			w := httptest.NewRecorder()
+
+			handler.ServeHTTP(w, req)
+			assert.Equal(t, testCase.expectedCode, w.Code)
+		})
+	}
+}
+
+func TestLanguage(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	handler := server.Routes()
+
+	testCases := []struct {
+		description      string
+		method           string
+		url              string
+		form             url.Values
+		acceptLanguage   string
+		expectedLanguage string
+		expectedText     string
+	}{
+		{
+			description:      "index page without a language",
+			method:           http.MethodGet,
+			url:              "/",
+			expectedLanguage: "en",
+			expectedText:     "Find shortest paths",
+		},
+		{
+			description:      "index page in Welsh",
+			method:           http.MethodGet,
+			url:              "/",
+			acceptLanguage:   "cy-GB,cy;q=0.9,en;q=0.8",
+			expectedLanguage: "cy",
+			expectedText:     "Canfod y llwybrau byrraf",
+		},
+		{
+			description:      "index page in an unsupported language",
+			method:           http.MethodGet,
+			url:              "/",
+			acceptLanguage:   "fr",
+			expectedLanguage: "en",
+			expectedText:     "Find shortest paths",
+		},
+		{
+			description:      "spider index page in Welsh",
+			method:           http.MethodGet,
+			url:              "/spider",
+			acceptLanguage:   "cy",
+			expectedLanguage: "cy",
+			expectedText:     "Nifer y camau",
+		},
+		{
+			description:      "entity page in Welsh",
+			method:           http.MethodGet,
+			url:              "/entity/e-1",
+			acceptLanguage:   "cy",
+			expectedLanguage: "cy",
+			expectedText:     "Bodolaeth yr endid",
+		},
+		{
+			description:      "form error in Welsh",
+			method:           http.MethodPost,
+			url:              "/upload",
+			form:             url.Values{},
+			acceptLanguage:   "cy",
+			expectedLanguage: "cy",
+			expectedText:     "mae nifer y neidiau yn wag",
+		},
+		{
+			description:      "form error in English",
+			method:           http.MethodPost,
+			url:              "/upload",
+			form:             buildFormData(1, "Dataset-1", "", "", "", "", ""),
+			acceptLanguage:   "en-GB",
+			expectedLanguage: "en",
+			expectedText:     "dataset parse error: dataset has no entity IDs",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.description, func(t *testing.T) {
+			req := httptest.NewRequest(testCase.method, testCase.url,
+				strings.NewReader(testCase.form.Encode()))
+			req.Form = testCase.form
+			if len(testCase.acceptLanguage) > 0 {
+				req.Header.Set("Accept-Language", testCase.acceptLanguage)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, testCase.expectedLanguage, w.Header().Get("Content-Language"))
+			assert.Contains(t, w.Body.String(),
+				fmt.Sprintf("lang=\"%v\"", testCase.expectedLanguage))
+			assert.Contains(t, w.Body.String(), testCase.expectedText)
+		})
+	}
+}
+
+func TestSetDefaultLanguage(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	assert.ErrorIs(t, server.SetDefaultLanguage("fr"), i18n.ErrUnsupportedLanguage)
+	assert.NoError(t, server.SetDefaultLanguage("cy"))
+
+	req := httptest.NewRequest(http.MethodGet, "/stats/", nil)
+	w := httptest.NewRecorder()
+
+	server.handleStats(w, req)
+	assert.Equal(t, "cy", w.Header().Get("Content-Language"))
+	assert.Contains(t, w.Body.String(), "Ystadegau")
+}
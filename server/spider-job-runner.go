@@ -3,12 +3,17 @@ package server
 import (
 	"errors"
 	"os"
-	"sync"
 	"time"
 
+	"github.com/cdclaxton/shortest-path-web-app/filedetector"
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/i18n"
 	"github.com/cdclaxton/shortest-path-web-app/i2chart"
 	"github.com/cdclaxton/shortest-path-web-app/job"
 	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cdclaxton/shortest-path-web-app/manifest"
+	"github.com/cdclaxton/shortest-path-web-app/publish"
+	"github.com/cdclaxton/shortest-path-web-app/set"
 	"github.com/cdclaxton/shortest-path-web-app/spider"
 )
 
@@ -19,17 +24,53 @@ var (
 
 const noPathsMessageFromSpidering = "Sorry, no paths could be found by spidering from the seed entities provided."
 
+// stepTypeFilters of spidering from the entity types of the steps of a spider job.
+func stepTypeFilters(stepTypes [][]string) spider.StepTypeFilters {
+
+	filters := spider.StepTypeFilters{}
+	for _, entityTypes := range stepTypes {
+		if len(entityTypes) == 0 {
+			filters = append(filters, nil)
+		} else {
+			filters = append(filters, set.NewPopulatedSet(entityTypes...))
+		}
+	}
+
+	return filters
+}
+
+// spiderCapsWarnings builds the warnings to display to the user when the caps truncated the
+// expansion of the sub-graph.
+func spiderCapsWarnings(results *spider.SpiderResults, caps spider.SpiderCaps) []*i18n.Message {
+
+	warnings := []*i18n.Message{}
+
+	if results.EntityCapReached {
+		warnings = append(warnings, i18n.NewMessage("spiderJob.entityCapWarning", caps.MaxEntities))
+	}
+
+	if results.NeighboursCapped > 0 {
+		warnings = append(warnings, i18n.NewMessage("spiderJob.neighboursCapWarning",
+			caps.MaxNeighbours, results.NeighboursCapped))
+	}
+
+	return warnings
+}
+
 // A SpiderJobRunner is responsible for spidering and generating an Excel file for i2.
 type SpiderJobRunner struct {
 	spider       *spider.Spider              // Spider engine
 	chartBuilder *i2chart.SpiderChartBuilder // Spider chart builder
 	folder       string                      // Location for the Excel files
 
-	jobs     map[string]*job.SpiderJob // Jobs (mapping of guid to job)
-	jobsLock sync.RWMutex              // Mutex for the jobs map
+	jobRegistry[*job.SpiderJob] // Jobs, their states and the subscribers to their events
 
-	numberJobsExecuting     int          // Number of jobs being executed
-	numberJobsExecutingLock sync.RWMutex // Mutex for the numberJobsExecuting
+	provenance filedetector.DataProvenance // Data drop searched by the jobs
+	diskQuota  DiskQuota                   // Disk space the result files may use
+	timeout    time.Duration               // Default timeout of the jobs (zero for no timeout)
+	storeLock  *graphstore.StoreLock       // Queues the jobs whilst the stores are updated (optional)
+	publisher  publish.Publisher           // Copies the result files to object storage (optional)
+	manifests  *manifest.Generator         // Makes the manifests of the result files (optional)
 }
 
 // NewJobRunner instantiates a new SpiderJobRunner struct.
@@ -50,60 +91,37 @@ func NewSpiderJobRunner(spider *spider.Spider, chartBuilder *i2chart.SpiderChart
 
 	// Return a constructed job runner
 	return &SpiderJobRunner{
-		spider:                  spider,
-		chartBuilder:            chartBuilder,
-		folder:                  folder,
-		jobs:                    map[string]*job.SpiderJob{},
-		jobsLock:                sync.RWMutex{},
-		numberJobsExecuting:     0,
-		numberJobsExecutingLock: sync.RWMutex{},
+		spider:       spider,
+		chartBuilder: chartBuilder,
+		folder:       folder,
+		jobRegistry:  newJobRegistry[*job.SpiderJob]("spider job"),
 	}, nil
 }
 
-// goingToExecuteJob increments the number of jobs executing.
-func (j *SpiderJobRunner) goingToExecuteJob(guid string) {
-	j.numberJobsExecutingLock.Lock()
-	defer j.numberJobsExecutingLock.Unlock()
+// SetProvenance of the data searched by the jobs, which is recorded with each job.
+func (j *SpiderJobRunner) SetProvenance(provenance filedetector.DataProvenance) {
 
 	logging.Logger.Info().
 		Str(logging.ComponentField, componentName).
-		Str(loggingGUIDField, guid).
-		Msg("Going to execute spider job")
+		Str("signature", provenance.Signature).
+		Msg("Setting the provenance of the data for spider jobs")
 
-	j.numberJobsExecuting += 1
+	j.provenance = provenance
 }
 
-// finishedExecutingJob decrements the number of jobs executing.
-func (j *SpiderJobRunner) finishedExecutingJob(guid string) {
-	j.numberJobsExecutingLock.Lock()
-	defer j.numberJobsExecutingLock.Unlock()
+// SetDiskQuota of the result files of the jobs.
+func (j *SpiderJobRunner) SetDiskQuota(quota DiskQuota) error {
+	if err := quota.Validate(); err != nil {
+		return err
+	}
 
 	logging.Logger.Info().
 		Str(logging.ComponentField, componentName).
-		Str(loggingGUIDField, guid).
-		Msg("Finished executing spider job")
-
-	j.numberJobsExecuting -= 1
-}
-
-// GetNumberJobsExecuting returns the number of jobs being executed when the lock is acquired.
-func (j *SpiderJobRunner) GetNumberJobsExecuting() int {
-	j.numberJobsExecutingLock.RLock()
-	defer j.numberJobsExecutingLock.RUnlock()
-
-	return j.numberJobsExecuting
-}
-
-// addJob to the map of jobs once the write lock has been acquired.
-func (j *SpiderJobRunner) addJob(j1 *job.SpiderJob) error {
-	j.jobsLock.Lock()
-	defer j.jobsLock.Unlock()
+		Int64("minFreeBytes", quota.MinFreeBytes).
+		Int64("maxFolderBytes", quota.MaxFolderBytes).
+		Msg("Setting the disk quota of the spider job runner")
 
-	if !j1.HasValidGuid() {
-		return ErrInvalidGuid
-	}
-
-	j.jobs[j1.GUID] = j1
+	j.diskQuota = quota
 	return nil
 }
 
@@ -120,84 +138,73 @@ func (j *SpiderJobRunner) Submit(jobConf *job.SpiderJobConfiguration) (string, e
 	if err != nil {
 		return InvalidGUID, err
 	}
+	job.Provenance = j.provenance
 
-	// Add the job to the job runner's storage
-	err = j.addJob(&job)
-	if err != nil {
-		return InvalidGUID, err
-	}
-
-	// Execute the job (in a go routine)
-	j.goingToExecuteJob(job.GUID)
-	go j.executeJob(job.GUID)
-
-	return job.GUID, nil
+	// Add the job to the job runner's storage and execute it
+	return j.submit(&job, j.executeJob)
 }
 
 // setJobToInProgress sets the job to in progress (i.e. started).
 func (j *SpiderJobRunner) setJobToInProgress(j1 *job.SpiderJob) {
-	j.jobsLock.Lock()
-	defer j.jobsLock.Unlock()
-
 	logging.Logger.Info().
 		Str(logging.ComponentField, componentName).
 		Str(loggingGUIDField, j1.GUID).
 		Msg("Setting spider job to in progress")
 
-	j1.Progress.StartTime = time.Now()
-	j1.Progress.State = job.InProgress
+	j.start(j1)
 }
 
 // setJobToFailed sets the job to failed and stores the error in the job.
 func (j *SpiderJobRunner) setJobToFailed(failedJob *job.SpiderJob, err error) {
-	j.jobsLock.Lock()
-	defer j.jobsLock.Unlock()
-
 	logging.Logger.Info().
 		Str(logging.ComponentField, componentName).
 		Str(loggingGUIDField, failedJob.GUID).
 		Str("error", err.Error()).
 		Msg("Setting spider job to failed")
 
-	failedJob.Progress.State = job.Failed
-	failedJob.Progress.EndTime = time.Now()
-	failedJob.Error = err
-
-	j.finishedExecutingJob(failedJob.GUID)
+	j.finish(failedJob, job.Failed, err, nil)
 }
 
 // setJobToComplete sets the job to complete (finished) where there were results.
 func (j *SpiderJobRunner) setJobToCompleteResults(j1 *job.SpiderJob, filepath string) {
-	j.jobsLock.Lock()
-	defer j.jobsLock.Unlock()
-
 	logging.Logger.Info().
 		Str(logging.ComponentField, componentName).
 		Str(loggingGUIDField, j1.GUID).
 		Msg("Setting spider job to complete with results")
 
-	j1.Progress.EndTime = time.Now()
-	j1.Progress.State = job.CompleteResults
-	j1.ResultFile = filepath
+	manifestFile, manifestWarnings := writeManifest(j.manifests, j.diskQuota, j.folder, j1.GUID,
+		filepath, j1.Configuration, j1.Provenance)
+
+	published, warnings := publishResults(j.publisher, spiderKind, j1.GUID,
+		[]string{filepath, manifestFile})
 
-	j.finishedExecutingJob(j1.GUID)
+	j.finish(j1, job.CompleteResults, nil, func() {
+		j1.ResultFile = filepath
+		j1.ManifestFile = manifestFile
+		j1.PublishedResults = published
+		j1.Warnings = append(j1.Warnings, manifestWarnings...)
+		j1.Warnings = append(j1.Warnings, warnings...)
+	})
 }
 
-// setJobToCompleteNoResults sets the job to complete (finished) where there weren't any results.
-func (j *SpiderJobRunner) setJobToCompleteNoResults(j1 *job.SpiderJob) {
+// addJobWarnings to present to the user.
+func (j *SpiderJobRunner) addJobWarnings(j1 *job.SpiderJob, warnings []*i18n.Message) {
 	j.jobsLock.Lock()
 	defer j.jobsLock.Unlock()
 
+	j1.Warnings = append(j1.Warnings, warnings...)
+}
+
+// setJobToCompleteNoResults sets the job to complete (finished) where there weren't any results.
+func (j *SpiderJobRunner) setJobToCompleteNoResults(j1 *job.SpiderJob) {
 	logging.Logger.Info().
 		Str(logging.ComponentField, componentName).
 		Str(loggingGUIDField, j1.GUID).
 		Msg("Setting spider job to complete with no results")
 
-	j1.Progress.EndTime = time.Now()
-	j1.Progress.State = job.CompleteNoResults
-	j1.Message = noPathsMessageFromSpidering
-
-	j.finishedExecutingJob(j1.GUID)
+	j.finish(j1, job.CompleteNoResults, nil, func() {
+		j1.Message = noPathsMessageFromSpidering
+	})
 }
 
 // executeJob given the GUID of the job to execute.
@@ -213,16 +220,45 @@ func (j *SpiderJobRunner) executeJob(guid string) {
 		return
 	}
 
+	// Wait for an update of the stores to finish, so the job doesn't read a half-updated graph
+	j.waitForStores(guid)
+	defer j.storeLock.EndRead()
+
 	// Set the job to in progress
 	j.setJobToInProgress(job)
 
 	// Perform spidering
-	results, err := j.spider.Execute(job.Configuration.NumberSteps, job.Configuration.SeedEntities)
-	if err != nil {
+	caps := spider.SpiderCaps{
+		MaxEntities:   job.Configuration.MaxEntities,
+		MaxNeighbours: job.Configuration.MaxNeighbours,
+	}
+
+	// Spidering is abandoned if the job times out
+	timeout := jobTimeout(j.timeout, job.Configuration.Timeout)
+	ctx, cancel := jobContext(timeout)
+	defer cancel()
+
+	results, err := j.spider.ExecuteWithFilters(ctx, job.Configuration.NumberSteps,
+		job.Configuration.SeedEntities, caps, stepTypeFilters(job.Configuration.StepTypes))
+	if err != nil && isTimeout(err) {
+		j.setJobToTimedOut(job, timeout)
+		return
+	} else if err != nil {
 		j.setJobToFailed(job, err)
 		return
 	}
 
+	if results.Truncated() {
+		logging.Logger.Warn().
+			Str(logging.ComponentField, componentName).
+			Str(loggingGUIDField, guid).
+			Bool("entityCapReached", results.EntityCapReached).
+			Int("neighboursCapped", results.NeighboursCapped).
+			Msg("Spidering was truncated by the caps")
+
+		j.addJobWarnings(job, spiderCapsWarnings(results, caps))
+	}
+
 	// If there aren't any connections, there's no need to build the i2 chart
 	atLeastOneConnection, err := results.HasAtLeastOneConnection()
 	if err != nil {
@@ -241,55 +277,26 @@ func (j *SpiderJobRunner) executeJob(guid string) {
 		return
 	}
 
-	// Make the filepath for the Excel file
-	filepath := makeExcelFilepath(j.folder, guid)
-
-	// Save the table in an Excel file
-	err = i2chart.WriteToExcel(filepath, table)
+	// Build the sheets of the entities reached on each step
+	stepSheets, err := j.chartBuilder.BuildSteps(results)
 	if err != nil {
 		j.setJobToFailed(job, err)
 		return
 	}
 
-	j.setJobToCompleteResults(job, filepath)
-}
-
-// GetJob from the job runner in a thread-safe manner. The returned job should not be modified.
-func (j *SpiderJobRunner) GetJob(guid string) (*job.SpiderJob, error) {
-
-	// Get a lock to be able to read the jobs map
-	j.jobsLock.RLock()
-	defer j.jobsLock.RUnlock()
-
-	// Try to fetch the job
-	job, found := j.jobs[guid]
-	if !found {
-		return nil, ErrJobNotFound
-	}
-
-	return job, nil
-}
+	sheets := append([]i2chart.Sheet{{Name: j.chartBuilder.SheetName(), Rows: table}}, stepSheets...)
 
-// IsJobFinished given the job's GUID.
-func (j *SpiderJobRunner) IsJobFinished(guid string) (bool, error) {
-
-	// Get a lock to be able to read the jobs map
-	j.jobsLock.RLock()
-	defer j.jobsLock.RUnlock()
+	// Make the filepath for the Excel file
+	filepath := makeExcelFilepath(j.folder, guid)
 
-	// Try to fetch the job
-	j1, found := j.jobs[guid]
-	if !found {
-		return false, ErrJobNotFound
+	// Save the sheets in an Excel file
+	err = j.diskQuota.writeResultFile(j.folder, filepath, func() error {
+		return i2chart.WriteSheetsToExcel(filepath, sheets, provenanceSummary(job.Provenance))
+	})
+	if err != nil {
+		j.setJobToFailed(job, err)
+		return
 	}
 
-	// If the job is in an end state, it is finished
-	if j1.Progress.State == job.Failed ||
-		j1.Progress.State == job.CompleteNoResults ||
-		j1.Progress.State == job.CompleteResults {
-
-		return true, nil
-	} else {
-		return false, nil
-	}
+	j.setJobToCompleteResults(job, filepath)
 }
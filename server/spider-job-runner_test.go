@@ -69,6 +69,7 @@ func makeSpiderJobRunner(t *testing.T) *SpiderJobRunner {
 	// Instantiate the spider engine
 	spider, err := spider.NewSpider(builder.Unipartite)
 	assert.NoError(t, err)
+	spider.SetBipartite(builder.Bipartite)
 
 	// Make a temporary folder for the output Excel files
 	tempFolder, err := os.MkdirTemp("", "test-job-runner")
@@ -240,4 +241,49 @@ func TestSpiderJobRunner(t *testing.T) {
 	actualTable, err := i2chart.ReadFromExcel(j1.ResultFile, "Sheet1")
 	assert.NoError(t, err)
 	assert.Equal(t, expectedTable, actualTable)
+	// Both entities were reached from the seed entity on the first step
+	actualTable, err = i2chart.ReadFromExcel(j1.ResultFile, "Step 1")
+	assert.NoError(t, err)
+	assert.Equal(t, expectedTable, actualTable)
+}
+
+func TestStepTypeFilters(t *testing.T) {
+	assert.Equal(t, spider.StepTypeFilters{}, stepTypeFilters(nil))
+	assert.Equal(t, spider.StepTypeFilters{nil, set.NewPopulatedSet("Address", "Person")},
+		stepTypeFilters([][]string{{}, {"Address", "Person"}}))
+}
+
+func TestSpiderJobRunnerWithStepTypes(t *testing.T) {
+	spiderJobRunner := makeSpiderJobRunner(t)
+	defer cleanUpSpiderJobRunner(t, spiderJobRunner)
+
+	testCases := []struct {
+		stepTypes     [][]string
+		expectedState job.JobState
+	}{
+		{
+			// e-1 is connected to the address e-3
+			stepTypes:     [][]string{{"Address"}},
+			expectedState: job.CompleteResults,
+		},
+		{
+			// e-1 isn't connected to a vehicle
+			stepTypes:     [][]string{{"Vehicle"}},
+			expectedState: job.CompleteNoResults,
+		},
+	}
+
+	for _, testCase := range testCases {
+		conf, err := job.NewSpiderJobConfiguration(1, set.NewPopulatedSet("e-1"))
+		assert.NoError(t, err)
+		conf.StepTypes = testCase.stepTypes
+
+		guid, err := spiderJobRunner.Submit(conf)
+		assert.NoError(t, err)
+		waitForSpiderJobsToFinish(spiderJobRunner)
+
+		j1, err := spiderJobRunner.GetJob(guid)
+		assert.NoError(t, err)
+		assert.Equal(t, testCase.expectedState, j1.Progress.State)
+	}
 }
@@ -0,0 +1,77 @@
+// Shows a preview of the number of unique entity IDs in each dataset on the upload form before it
+// is submitted. The entity IDs are counted by the server, so files are parsed in the same way.
+//
+// Also adds another dataset to the form when the "add another dataset" button is pressed, up to the
+// server's maximum number of datasets.
+(function () {
+    "use strict";
+
+    var datasets = document.getElementById("datasets");
+    var template = document.getElementById("datasetTemplate");
+    var addButton = document.getElementById("addDataset");
+
+    function updatePreview(index) {
+        var textbox = document.getElementById("dataset" + index);
+        var fileInput = document.getElementById("datasetFile" + index);
+        var preview = document.getElementById("datasetPreview" + index);
+
+        if (textbox.value.trim().length === 0 && fileInput.files.length === 0) {
+            preview.textContent = "";
+            return;
+        }
+
+        var data = new FormData();
+        data.append("dataset", index);
+        data.append("datasetEntities" + index, textbox.value);
+        if (fileInput.files.length > 0) {
+            data.append("datasetFile" + index, fileInput.files[0]);
+        }
+
+        fetch("count-entities", { method: "POST", body: data })
+            .then(function (response) {
+                if (!response.ok) {
+                    throw new Error(response.statusText);
+                }
+                return response.json();
+            })
+            .then(function (count) {
+                preview.textContent = count.message;
+            })
+            .catch(function () {
+                preview.textContent = "";
+            });
+    }
+
+    // The preview of a dataset is updated when its textbox or file changes
+    datasets.addEventListener("change", function (event) {
+        var fieldset = event.target.closest("[data-dataset]");
+        if (fieldset !== null && event.target.type !== "text") {
+            updatePreview(fieldset.getAttribute("data-dataset"));
+        }
+    });
+
+    function numberOfDatasets() {
+        return datasets.querySelectorAll("[data-dataset]").length;
+    }
+
+    var maxDatasets = parseInt(addButton.getAttribute("data-max-datasets"), 10);
+
+    function updateAddButton() {
+        addButton.hidden = numberOfDatasets() >= maxDatasets;
+    }
+
+    addButton.addEventListener("click", function () {
+        var index = String(numberOfDatasets() + 1);
+        var wrapper = document.createElement("div");
+        wrapper.innerHTML = template.innerHTML.split("__INDEX__").join(index);
+
+        while (wrapper.firstChild) {
+            datasets.appendChild(wrapper.firstChild);
+        }
+
+        document.getElementById("datasetName" + index).focus();
+        updateAddButton();
+    });
+
+    updateAddButton();
+})();
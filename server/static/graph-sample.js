@@ -0,0 +1,126 @@
+// Draws the sample of the graph on the sample page with a simple force-directed layout, so that the
+// structure of the graph (e.g. hubs, clusters and isolated entities) can be seen at a glance. Each
+// entity links to its page.
+(function () {
+    "use strict";
+
+    var svgNamespace = "http://www.w3.org/2000/svg";
+    var iterations = 300;
+    var radius = 5;
+
+    var svg = document.getElementById("graphSample");
+    if (svg === null) {
+        return;
+    }
+
+    var width = svg.viewBox.baseVal.width;
+    var height = svg.viewBox.baseVal.height;
+
+    // layout the entities using the Fruchterman-Reingold algorithm
+    function layout(entities, edges) {
+        var k = Math.sqrt((width * height) / Math.max(entities.length, 1));
+        var positions = {};
+
+        entities.forEach(function (id, idx) {
+            var angle = (2 * Math.PI * idx) / entities.length;
+            positions[id] = {
+                x: width / 2 + (width / 3) * Math.cos(angle),
+                y: height / 2 + (height / 3) * Math.sin(angle)
+            };
+        });
+
+        for (var iteration = 0; iteration < iterations; iteration++) {
+            var temperature = (width / 10) * (1 - iteration / iterations);
+            var moves = {};
+            entities.forEach(function (id) { moves[id] = { x: 0, y: 0 }; });
+
+            // Every pair of entities repel each other
+            for (var i = 0; i < entities.length; i++) {
+                for (var j = i + 1; j < entities.length; j++) {
+                    var p = positions[entities[i]];
+                    var q = positions[entities[j]];
+                    var dx = p.x - q.x;
+                    var dy = p.y - q.y;
+                    var distance = Math.max(Math.sqrt(dx * dx + dy * dy), 0.01);
+                    var force = (k * k) / distance;
+                    moves[entities[i]].x += (dx / distance) * force;
+                    moves[entities[i]].y += (dy / distance) * force;
+                    moves[entities[j]].x -= (dx / distance) * force;
+                    moves[entities[j]].y -= (dy / distance) * force;
+                }
+            }
+
+            // Connected entities attract each other
+            edges.forEach(function (edge) {
+                var p = positions[edge.source];
+                var q = positions[edge.target];
+                var dx = p.x - q.x;
+                var dy = p.y - q.y;
+                var distance = Math.max(Math.sqrt(dx * dx + dy * dy), 0.01);
+                var force = (distance * distance) / k;
+                moves[edge.source].x -= (dx / distance) * force;
+                moves[edge.source].y -= (dy / distance) * force;
+                moves[edge.target].x += (dx / distance) * force;
+                moves[edge.target].y += (dy / distance) * force;
+            });
+
+            // Move each entity, limited by the temperature, and keep it in the drawing
+            entities.forEach(function (id) {
+                var move = moves[id];
+                var length = Math.max(Math.sqrt(move.x * move.x + move.y * move.y), 0.01);
+                var position = positions[id];
+                position.x += (move.x / length) * Math.min(length, temperature);
+                position.y += (move.y / length) * Math.min(length, temperature);
+                position.x = Math.min(width - radius, Math.max(radius, position.x));
+                position.y = Math.min(height - radius, Math.max(radius, position.y));
+            });
+        }
+
+        return positions;
+    }
+
+    function element(name, attributes) {
+        var el = document.createElementNS(svgNamespace, name);
+        Object.keys(attributes).forEach(function (key) {
+            el.setAttribute(key, attributes[key]);
+        });
+        return el;
+    }
+
+    function draw(sample) {
+        var positions = layout(sample.entities, sample.edges);
+
+        sample.edges.forEach(function (edge) {
+            var p = positions[edge.source];
+            var q = positions[edge.target];
+            svg.appendChild(element("line", {
+                x1: p.x, y1: p.y, x2: q.x, y2: q.y,
+                "class": edge.directed ? "app-graph-sample__edge app-graph-sample__edge--directed" : "app-graph-sample__edge"
+            }));
+        });
+
+        sample.entities.forEach(function (id) {
+            var link = element("a", { href: "entity/" + encodeURIComponent(id) });
+            var circle = element("circle", {
+                cx: positions[id].x, cy: positions[id].y, r: radius, "class": "app-graph-sample__entity"
+            });
+            var title = element("title", {});
+            title.textContent = id;
+            circle.appendChild(title);
+            link.appendChild(circle);
+            svg.appendChild(link);
+        });
+    }
+
+    fetch(svg.getAttribute("data-sample"))
+        .then(function (response) {
+            if (!response.ok) {
+                throw new Error(response.statusText);
+            }
+            return response.json();
+        })
+        .then(draw)
+        .catch(function () {
+            svg.hidden = true;
+        });
+})();
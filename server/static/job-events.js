@@ -0,0 +1,40 @@
+// Subscribes to the events of a job whilst it is processing, so that the page shows the state of
+// the job and flips to the results as soon as the job finishes. If the events can't be received,
+// the page falls back to reloading periodically.
+(function () {
+    "use strict";
+
+    var fallbackReloadMs = 5000;
+
+    var script = document.currentScript;
+    var status = document.getElementById("jobState");
+
+    function reloadLater() {
+        setTimeout(function () { location.reload(); }, fallbackReloadMs);
+    }
+
+    if (!window.EventSource) {
+        reloadLater();
+        return;
+    }
+
+    function showState(event) {
+        var state = JSON.parse(event.data);
+        status.textContent = script.dataset.status + " " + state.message;
+    }
+
+    var source = new EventSource(script.dataset.events);
+
+    source.addEventListener("progress", showState);
+
+    source.addEventListener("finished", function (event) {
+        showState(event);
+        source.close();
+        location.reload();
+    });
+
+    source.onerror = function () {
+        source.close();
+        reloadLater();
+    };
+})();
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/cdclaxton/shortest-path-web-app/filedetector"
+	"github.com/cdclaxton/shortest-path-web-app/graphbuilder"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+)
+
+// StatsResponse is the JSON representation of the graph stats.
+type StatsResponse struct {
+	NumberOfEntities              int                         `json:"numberOfEntities"`
+	NumberOfEntitiesWithDocuments int                         `json:"numberOfEntitiesWithDocuments"`
+	NumberOfDocuments             int                         `json:"numberOfDocuments"`
+	NumberOfDocumentsWithEntities int                         `json:"numberOfDocumentsWithEntities"`
+	NumberOfEntitiesInUnipartite  int                         `json:"numberOfEntitiesInUnipartite"`
+	EntityTypes                   map[string]int              `json:"entityTypes"`   // Number of entities of each type
+	DocumentTypes                 map[string]int              `json:"documentTypes"` // Number of documents of each type
+	Provenance                    filedetector.DataProvenance `json:"provenance"`
+	Warnings                      []string                    `json:"warnings"`
+}
+
+// newStatsResponse from the graph stats.
+func newStatsResponse(stats graphbuilder.GraphStats) StatsResponse {
+	response := StatsResponse{
+		NumberOfEntities:              stats.Bipartite.NumberOfEntities,
+		NumberOfEntitiesWithDocuments: stats.Bipartite.NumberOfEntitiesWithDocuments,
+		NumberOfDocuments:             stats.Bipartite.NumberOfDocuments,
+		NumberOfDocumentsWithEntities: stats.Bipartite.NumberOfDocumentsWithEntities,
+		NumberOfEntitiesInUnipartite:  stats.Unipartite.NumberOfEntities,
+		EntityTypes:                   stats.Types.EntityTypes,
+		DocumentTypes:                 stats.Types.DocumentTypes,
+		Provenance:                    stats.Provenance,
+		Warnings:                      stats.Warnings,
+	}
+
+	if response.EntityTypes == nil {
+		response.EntityTypes = map[string]int{}
+	}
+
+	if response.DocumentTypes == nil {
+		response.DocumentTypes = map[string]int{}
+	}
+
+	if response.Warnings == nil {
+		response.Warnings = []string{}
+	}
+
+	return response
+}
+
+// wantsStatsJson returns true if the stats are requested as JSON, either with the format query
+// parameter or the Accept header.
+func wantsStatsJson(req *http.Request) bool {
+	return req.URL.Query().Get(JobFormatInputName) == jsonFormat ||
+		strings.Contains(req.Header.Get("Accept"), "application/json")
+}
+
+// writeStats as JSON.
+func writeStats(w http.ResponseWriter, stats graphbuilder.GraphStats) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	if err := json.NewEncoder(w).Encode(newStatsResponse(stats)); err != nil {
+		logging.Logger.Error().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to write the stats as JSON")
+	}
+}
+
+// TypeCountDisplay is the number of entities or documents of a type for display in HTML.
+type TypeCountDisplay struct {
+	Type  string
+	Count int
+}
+
+// prepareTypeCounts for display in HTML, sorted by type.
+func prepareTypeCounts(counts map[string]int) []TypeCountDisplay {
+	display := []TypeCountDisplay{}
+	for t, count := range counts {
+		display = append(display, TypeCountDisplay{Type: t, Count: count})
+	}
+
+	sort.Slice(display, func(i, j int) bool {
+		return display[i].Type < display[j].Type
+	})
+
+	return display
+}
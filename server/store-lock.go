@@ -0,0 +1,60 @@
+// Whilst the stores of a graph are updated (e.g. the expired documents are pruned), the jobs and
+// queries reading the stores are queued, so they never read a half-updated graph. A job waits in
+// the not started state and a query waits for its response.
+
+package server
+
+import (
+	"net/http"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+)
+
+// SetStoreLock that coordinates the jobs and queries with the updates of the stores.
+func (j *JobServer) SetStoreLock(lock *graphstore.StoreLock) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Bool("enabled", lock != nil).
+		Msg("Setting the store lock")
+
+	j.storeLock = lock
+	j.runner.storeLock = lock
+	j.spiderRunner.storeLock = lock
+}
+
+// readingStores wraps a handler that reads the stores, so the request waits for an update of the
+// stores to finish.
+func (j *JobServer) readingStores(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		j.storeLock.BeginRead()
+		defer j.storeLock.EndRead()
+
+		handler(w, req)
+	}
+}
+
+// waitForStores begins the job's read of the stores, which waits for an update to finish.
+func (j *JobRunner) waitForStores(guid string) {
+	if j.storeLock.Updating() {
+		logging.Logger.Info().
+			Str(logging.ComponentField, componentName).
+			Str(loggingGUIDField, guid).
+			Msg("Job queued until the update of the stores has finished")
+	}
+
+	j.storeLock.BeginRead()
+}
+
+// waitForStores begins the spider job's read of the stores, which waits for an update to finish.
+func (j *SpiderJobRunner) waitForStores(guid string) {
+	if j.storeLock.Updating() {
+		logging.Logger.Info().
+			Str(logging.ComponentField, componentName).
+			Str(loggingGUIDField, guid).
+			Msg("Spider job queued until the update of the stores has finished")
+	}
+
+	j.storeLock.BeginRead()
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphstore"
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobQueuedWhilstStoresUpdated(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	lock := graphstore.NewStoreLock()
+	server.SetStoreLock(lock)
+
+	// Hold an update of the stores until it is released
+	updating := make(chan bool)
+	release := make(chan bool)
+	go func() {
+		lock.Update(func() error {
+			updating <- true
+			<-release
+			return nil
+		})
+	}()
+	<-updating
+
+	handler := server.Routes()
+	w := postForm(handler, "/upload", buildFormData(2, "Dataset-1", "e-1, e-2, e-3", "", "", "", ""))
+	assert.Equal(t, http.StatusFound, w.Code)
+
+	guid := strings.TrimPrefix(w.Header().Get("Location"), "/job/")
+	time.Sleep(50 * time.Millisecond)
+
+	j1, err := server.runner.GetJob(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, job.NotStarted, j1.Progress.State)
+
+	// The job runs once the update has finished
+	close(release)
+	waitForJobsToFinish(server.runner)
+
+	j1, err = server.runner.GetJob(guid)
+	assert.NoError(t, err)
+	assert.Equal(t, job.CompleteResults, j1.Progress.State)
+}
+
+func TestQueriesQueuedWhilstStoresUpdated(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	lock := graphstore.NewStoreLock()
+	server.SetStoreLock(lock)
+	handler := server.Routes()
+
+	urls := []string{"/count-entities", "/compare", "/compare-download", mergeDownloadUrl, "/readyz"}
+
+	for _, url := range urls {
+
+		// Hold an update of the stores until it is released
+		updating := make(chan bool)
+		release := make(chan bool)
+		go func() {
+			lock.Update(func() error {
+				updating <- true
+				<-release
+				return nil
+			})
+		}()
+		<-updating
+
+		done := make(chan bool)
+		go func(url string) {
+			getPage(handler, url)
+			close(done)
+		}(url)
+
+		// The request waits for the update to finish
+		select {
+		case <-done:
+			t.Errorf("%v answered whilst the stores were updated", url)
+		case <-time.After(50 * time.Millisecond):
+		}
+
+		close(release)
+		<-done
+	}
+}
@@ -0,0 +1,228 @@
+// The theme allows the organisation name, logo and colours of the web pages to be configured, so
+// that different deployments can be distinguished at a glance. A user can also switch to dark mode,
+// which is remembered in a cookie.
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+)
+
+// Constants associated with dark mode
+const (
+	DarkModeCookieName = "darkMode"           // Name of the cookie holding whether dark mode is on
+	DarkModeInputName  = "enabled"            // Name of the query parameter to turn dark mode on or off
+	darkModeCookieAge  = 365 * 24 * time.Hour // Lifetime of the dark mode cookie
+)
+
+var (
+	ErrInvalidThemeColour = errors.New("invalid theme colour")
+	ErrThemeLogoNotFound  = errors.New("theme logo not found")
+)
+
+// Colours must be hex colours, so they can be safely injected into the CSS
+var colourRegex = regexp.MustCompile("^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$")
+
+// ThemeColours is a colour palette for the web pages.
+type ThemeColours struct {
+	Primary    string `json:"primary"`    // Links, buttons and the line under the header
+	Header     string `json:"header"`     // Header background
+	Background string `json:"background"` // Page background
+	Text       string `json:"text"`       // Text
+}
+
+// withDefaults returns the colours with any blank colour set to its default.
+func (c ThemeColours) withDefaults(defaults ThemeColours) ThemeColours {
+	if len(c.Primary) == 0 {
+		c.Primary = defaults.Primary
+	}
+	if len(c.Header) == 0 {
+		c.Header = defaults.Header
+	}
+	if len(c.Background) == 0 {
+		c.Background = defaults.Background
+	}
+	if len(c.Text) == 0 {
+		c.Text = defaults.Text
+	}
+
+	return c
+}
+
+// validate the colours are hex colours.
+func (c ThemeColours) validate() error {
+	for _, colour := range []string{c.Primary, c.Header, c.Background, c.Text} {
+		if !colourRegex.MatchString(colour) {
+			return fmt.Errorf("%w: '%v'", ErrInvalidThemeColour, colour)
+		}
+	}
+
+	return nil
+}
+
+// Theme of the web pages.
+type Theme struct {
+	OrganisationName string       `json:"organisationName"` // Shown before the name of the tool (optional)
+	LogoPath         string       `json:"logoPath"`         // Location of a logo for the header (optional)
+	Colours          ThemeColours `json:"colours"`          // Colours in light mode
+	DarkColours      ThemeColours `json:"darkColours"`      // Colours in dark mode
+}
+
+// DefaultTheme uses the GOV.UK colours and has no organisation name or logo.
+func DefaultTheme() Theme {
+	return Theme{
+		Colours: ThemeColours{
+			Primary:    "#1d70b8",
+			Header:     "#0b0c0c",
+			Background: "#ffffff",
+			Text:       "#0b0c0c",
+		},
+		DarkColours: ThemeColours{
+			Primary:    "#5694ca",
+			Header:     "#000000",
+			Background: "#1b1b1b",
+			Text:       "#f3f2f1",
+		},
+	}
+}
+
+// withDefaults returns the theme with any blank colours set to the default colours.
+func (t Theme) withDefaults() Theme {
+	defaults := DefaultTheme()
+	t.Colours = t.Colours.withDefaults(defaults.Colours)
+	t.DarkColours = t.DarkColours.withDefaults(defaults.DarkColours)
+	return t
+}
+
+// validate the theme's colours and that the logo (if there is one) exists.
+func (t Theme) validate() error {
+	if err := t.Colours.validate(); err != nil {
+		return err
+	}
+
+	if err := t.DarkColours.validate(); err != nil {
+		return err
+	}
+
+	if len(t.LogoPath) > 0 {
+		info, err := os.Stat(t.LogoPath)
+		if err != nil || info.IsDir() {
+			return fmt.Errorf("%w: %v", ErrThemeLogoNotFound, t.LogoPath)
+		}
+	}
+
+	return nil
+}
+
+// templateData returns the theme data that is available to the HTML templates as @theme.
+func (t Theme) templateData(darkMode bool) map[string]interface{} {
+	headerColour := t.Colours.Header
+	if darkMode {
+		headerColour = t.DarkColours.Header
+	}
+
+	return map[string]interface{}{
+		"organisationName": t.OrganisationName,
+		"hasLogo":          len(t.LogoPath) > 0,
+		"headerColour":     headerColour,
+	}
+}
+
+// ReadTheme from a JSON file. Colours that aren't specified use the default colours.
+func ReadTheme(filepath string) (Theme, error) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("filepath", filepath).
+		Msg("Reading theme from JSON file")
+
+	content, err := os.ReadFile(filepath)
+	if err != nil {
+		return Theme{}, err
+	}
+
+	theme := Theme{}
+	if err := json.Unmarshal(content, &theme); err != nil {
+		return Theme{}, err
+	}
+
+	theme = theme.withDefaults()
+	if err := theme.validate(); err != nil {
+		return Theme{}, err
+	}
+
+	return theme, nil
+}
+
+// SetTheme of the web pages. Colours that aren't specified use the default colours.
+func (j *JobServer) SetTheme(theme Theme) error {
+
+	theme = theme.withDefaults()
+	if err := theme.validate(); err != nil {
+		return err
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("organisationName", theme.OrganisationName).
+		Bool("hasLogo", len(theme.LogoPath) > 0).
+		Msg("Setting the theme")
+
+	j.theme = theme
+	return j.cachePages()
+}
+
+// isDarkMode returns true if the user has turned on dark mode.
+func isDarkMode(req *http.Request) bool {
+	cookie, err := req.Cookie(DarkModeCookieName)
+	return err == nil && cookie.Value == "true"
+}
+
+// handleDarkMode turns dark mode on or off and returns the user to the page they were on.
+func (j *JobServer) handleDarkMode(w http.ResponseWriter, req *http.Request) {
+
+	enabled := req.URL.Query().Get(DarkModeInputName) == "true"
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     DarkModeCookieName,
+		Value:    fmt.Sprintf("%v", enabled),
+		Path:     "/",
+		MaxAge:   int(darkModeCookieAge.Seconds()),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	// Only redirect back to a page on this server
+	redirectUrl := "/"
+	if referer, err := url.Parse(req.Referer()); err == nil && len(referer.Path) > 0 &&
+		(len(referer.Host) == 0 || referer.Host == req.Host) {
+		redirectUrl = referer.RequestURI()
+	}
+
+	http.Redirect(w, req, redirectUrl, http.StatusFound)
+}
+
+// handleThemeCss returns the CSS for the theme's colours.
+func (j *JobServer) handleThemeCss(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/css; charset=utf-8")
+	writeCachedPage(w, req, j.themeCss)
+}
+
+// handleLogo returns the theme's logo (if there is one).
+func (j *JobServer) handleLogo(w http.ResponseWriter, req *http.Request) {
+	if len(j.theme.LogoPath) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	http.ServeFile(w, req, j.theme.LogoPath)
+}
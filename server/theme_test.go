@@ -0,0 +1,215 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestThemeValidate(t *testing.T) {
+
+	logo := filepath.Join(t.TempDir(), "logo.png")
+	assert.NoError(t, os.WriteFile(logo, []byte("logo"), 0644))
+
+	testCases := []struct {
+		description   string
+		theme         Theme
+		expectedError error
+	}{
+		{
+			description: "default theme",
+			theme:       DefaultTheme(),
+		},
+		{
+			description: "theme with a logo and short hex colours",
+			theme: Theme{
+				OrganisationName: "Team A",
+				LogoPath:         logo,
+				Colours:          ThemeColours{Primary: "#f00"},
+			}.withDefaults(),
+		},
+		{
+			description:   "invalid colour",
+			theme:         Theme{Colours: ThemeColours{Primary: "red"}}.withDefaults(),
+			expectedError: ErrInvalidThemeColour,
+		},
+		{
+			description:   "colour that would inject CSS",
+			theme:         Theme{DarkColours: ThemeColours{Text: "#fff;} body {"}}.withDefaults(),
+			expectedError: ErrInvalidThemeColour,
+		},
+		{
+			description: "logo doesn't exist",
+			theme: Theme{
+				LogoPath: filepath.Join(t.TempDir(), "missing.png"),
+			}.withDefaults(),
+			expectedError: ErrThemeLogoNotFound,
+		},
+		{
+			description:   "logo is a folder",
+			theme:         Theme{LogoPath: t.TempDir()}.withDefaults(),
+			expectedError: ErrThemeLogoNotFound,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.description, func(t *testing.T) {
+			assert.ErrorIs(t, testCase.theme.validate(), testCase.expectedError)
+		})
+	}
+}
+
+func TestReadTheme(t *testing.T) {
+
+	folder := t.TempDir()
+	themeFile := filepath.Join(folder, "theme.json")
+	content := `{"organisationName": "Team A", "colours": {"primary": "#00703c"}}`
+	assert.NoError(t, os.WriteFile(themeFile, []byte(content), 0644))
+
+	theme, err := ReadTheme(themeFile)
+	assert.NoError(t, err)
+
+	expected := DefaultTheme()
+	expected.OrganisationName = "Team A"
+	expected.Colours.Primary = "#00703c"
+	assert.Equal(t, expected, theme)
+
+	// Invalid colour
+	assert.NoError(t, os.WriteFile(themeFile, []byte(`{"colours": {"text": "black"}}`), 0644))
+	_, err = ReadTheme(themeFile)
+	assert.ErrorIs(t, err, ErrInvalidThemeColour)
+
+	// File doesn't exist
+	_, err = ReadTheme(filepath.Join(folder, "missing.json"))
+	assert.Error(t, err)
+}
+
+func TestSetTheme(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	handler := server.Routes()
+
+	// Without a logo, the logo isn't found
+	req := httptest.NewRequest(http.MethodGet, "/theme/logo", nil)
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	assert.ErrorIs(t, server.SetTheme(Theme{Colours: ThemeColours{Header: "blue"}}),
+		ErrInvalidThemeColour)
+
+	logo := filepath.Join(t.TempDir(), "logo.png")
+	assert.NoError(t, os.WriteFile(logo, []byte("logo"), 0644))
+
+	assert.NoError(t, server.SetTheme(Theme{
+		OrganisationName: "Team A",
+		LogoPath:         logo,
+		Colours:          ThemeColours{Header: "#123456"},
+	}))
+
+	// The cached index page uses the theme
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Contains(t, w.Body.String(), "Team A Shortest Path Tool")
+	assert.Contains(t, w.Body.String(), `<img src="/theme/logo"`)
+	assert.Contains(t, w.Body.String(), `content="#123456"`)
+
+	// The CSS uses the theme's colours
+	req = httptest.NewRequest(http.MethodGet, "/theme.css", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/css; charset=utf-8", w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), "--app-header: #123456;")
+
+	// The logo is served
+	req = httptest.NewRequest(http.MethodGet, "/theme/logo", nil)
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "logo", w.Body.String())
+}
+
+func TestDarkMode(t *testing.T) {
+
+	// Make a valid job server
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	handler := server.Routes()
+
+	testCases := []struct {
+		description      string
+		url              string
+		referer          string
+		expectedCookie   string
+		expectedLocation string
+	}{
+		{
+			description:      "turn on without a referer",
+			url:              "/dark-mode?enabled=true",
+			expectedCookie:   "true",
+			expectedLocation: "/",
+		},
+		{
+			description:      "turn off and return to the page",
+			url:              "/dark-mode?enabled=false",
+			referer:          "http://example.com/job/1234?a=b",
+			expectedCookie:   "false",
+			expectedLocation: "/job/1234?a=b",
+		},
+		{
+			description:      "referer on a different host",
+			url:              "/dark-mode?enabled=true",
+			referer:          "http://other.com/job/1234",
+			expectedCookie:   "true",
+			expectedLocation: "/",
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.description, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, testCase.url, nil)
+			req.Host = "example.com"
+			if len(testCase.referer) > 0 {
+				req.Header.Set("Referer", testCase.referer)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusFound, w.Code)
+			assert.Equal(t, testCase.expectedLocation, w.Header().Get("Location"))
+
+			cookies := w.Result().Cookies()
+			assert.Equal(t, 1, len(cookies))
+			assert.Equal(t, DarkModeCookieName, cookies[0].Name)
+			assert.Equal(t, testCase.expectedCookie, cookies[0].Value)
+		})
+	}
+
+	// Pages are shown in dark mode if the cookie is set
+	for _, darkMode := range []string{"true", "false"} {
+		req := httptest.NewRequest(http.MethodGet, "/stats/", nil)
+		req.AddCookie(&http.Cookie{Name: DarkModeCookieName, Value: darkMode})
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, req)
+
+		if darkMode == "true" {
+			assert.Contains(t, w.Body.String(), `class="govuk-template no-js app-dark"`)
+			assert.Contains(t, w.Body.String(), "Light mode")
+		} else {
+			assert.Contains(t, w.Body.String(), `class="govuk-template no-js"`)
+			assert.Contains(t, w.Body.String(), "Dark mode")
+		}
+	}
+}
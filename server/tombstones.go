@@ -0,0 +1,163 @@
+// Withdrawn (tombstoned) entities stay in the graph stores but are excluded from path finding and
+// spidering, e.g. because their source records were retracted. Their entity pages show a banner
+// with the reason and the history of the withdrawals. An admin withdraws and reinstates entities
+// using the tombstones endpoint.
+
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cdclaxton/shortest-path-web-app/tombstone"
+)
+
+// Constants associated with the tombstones endpoint
+const (
+	tombstonesUrl            = "/admin/tombstones"
+	TombstoneActionInput     = "action"   // Name of the input holding the action to perform
+	TombstoneEntityIdInput   = "entityId" // Name of the input holding the entity ID
+	TombstoneReasonInput     = "reason"   // Name of the input holding the reason for the action
+	tombstoneActionWithdraw  = "withdraw"
+	tombstoneActionReinstate = "reinstate"
+)
+
+// Keys of the translations of the actions in the history of a tombstone
+var tombstoneActionKeys = map[string]string{
+	tombstone.ActionWithdrawn:  "entity.tombstoneWithdrawn",
+	tombstone.ActionReinstated: "entity.tombstoneReinstated",
+}
+
+// TombstonesResponse is the JSON response of the tombstones endpoint.
+type TombstonesResponse struct {
+	Tombstone *tombstone.Tombstone   `json:"tombstone,omitempty"` // Tombstone that was changed
+	Withdrawn []*tombstone.Tombstone `json:"withdrawn,omitempty"` // Tombstones of the withdrawn entities
+	Error     string                 `json:"error,omitempty"`     // Reason the request failed
+}
+
+// SetTombstoneStore of the withdrawn entities, which are excluded from the paths and spidering. A
+// nil store disables the withdrawal of entities.
+func (j *JobServer) SetTombstoneStore(store *tombstone.TombstoneStore) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Bool("tombstones", store != nil).
+		Msg("Setting the tombstone store")
+
+	j.tombstones = store
+	j.runner.pathFinder.SetTombstones(store)
+	j.spiderRunner.spider.SetTombstones(store)
+}
+
+// tombstoneContext for the entity page, which is nil if the entity has never been withdrawn.
+func (j *JobServer) tombstoneContext(entityId string, language string) map[string]interface{} {
+
+	t, err := j.tombstones.Get(entityId)
+	if err != nil {
+		logging.Logger.Warn().
+			Str(logging.ComponentField, componentName).
+			Str("entityId", entityId).
+			Err(err).
+			Msg("Failed to read the tombstone of the entity")
+
+		return nil
+	}
+
+	if t == nil {
+		return nil
+	}
+
+	history := []map[string]string{}
+	for _, event := range t.History {
+		history = append(history, map[string]string{
+			"action": j.translator.Translate(language, tombstoneActionKeys[event.Action]),
+			"reason": event.Reason,
+			"time":   event.Time.Format(annotationTimeFormat),
+		})
+	}
+
+	return map[string]interface{}{
+		"withdrawn": t.Withdrawn,
+		"reason":    t.Reason,
+		"updated":   t.Updated.Format(annotationTimeFormat),
+		"history":   history,
+	}
+}
+
+// writeTombstones response as JSON with the HTTP status code.
+func writeTombstones(w http.ResponseWriter, code int, response TombstonesResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		logging.Logger.Error().
+			Str(logging.ComponentField, componentName).
+			Err(err).
+			Msg("Failed to write the tombstones response")
+	}
+}
+
+// tombstoneStatus returns the HTTP status code for an error from the tombstone store.
+func tombstoneStatus(err error) int {
+	switch {
+	case errors.Is(err, tombstone.ErrEntityIdEmpty), errors.Is(err, tombstone.ErrReasonEmpty),
+		errors.Is(err, tombstone.ErrReasonTooLong):
+		return http.StatusBadRequest
+	case errors.Is(err, tombstone.ErrAlreadyWithdrawn), errors.Is(err, tombstone.ErrNotWithdrawn):
+		return http.StatusConflict
+	}
+
+	return http.StatusInternalServerError
+}
+
+// handleTombstones returns the tombstones of the withdrawn entities. Posting the withdraw or
+// reinstate action with an entity ID and a reason changes the entity's tombstone.
+func (j *JobServer) handleTombstones(w http.ResponseWriter, req *http.Request) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("method", req.Method).
+		Msg("Received request at " + tombstonesUrl)
+
+	if j.tombstones == nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	response := TombstonesResponse{}
+	var err error
+
+	switch req.Method {
+	case http.MethodGet:
+		response.Withdrawn, err = j.tombstones.Withdrawn()
+	case http.MethodPost:
+		entityId := strings.TrimSpace(req.FormValue(TombstoneEntityIdInput))
+		reason := req.FormValue(TombstoneReasonInput)
+
+		switch action := req.FormValue(TombstoneActionInput); action {
+		case tombstoneActionWithdraw:
+			response.Tombstone, err = j.tombstones.Withdraw(entityId, reason)
+		case tombstoneActionReinstate:
+			response.Tombstone, err = j.tombstones.Reinstate(entityId, reason)
+		default:
+			response.Error = "unknown action: " + action
+			writeTombstones(w, http.StatusBadRequest, response)
+			return
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err != nil {
+		response.Error = err.Error()
+		writeTombstones(w, tombstoneStatus(err), response)
+		return
+	}
+
+	writeTombstones(w, http.StatusOK, response)
+}
@@ -0,0 +1,110 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/tombstone"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTombstonesEndpoint(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+	server.SetAdminToken("secret")
+
+	send := func(method string, token string, form url.Values) (int, TombstonesResponse) {
+		req := httptest.NewRequest(method, tombstonesUrl, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		if len(token) > 0 {
+			req.Header.Set(AdminTokenHeader, token)
+		}
+		w := httptest.NewRecorder()
+		server.Routes().ServeHTTP(w, req)
+
+		response := TombstonesResponse{}
+		if w.Header().Get("Content-Type") == "application/json" {
+			assert.NoError(t, json.NewDecoder(w.Body).Decode(&response))
+		}
+		return w.Code, response
+	}
+
+	change := func(action string, entityId string, reason string) url.Values {
+		form := url.Values{}
+		form.Set(TombstoneActionInput, action)
+		form.Set(TombstoneEntityIdInput, entityId)
+		form.Set(TombstoneReasonInput, reason)
+		return form
+	}
+
+	// The endpoint requires the admin token and the tombstone store
+	code, _ := send(http.MethodGet, "", url.Values{})
+	assert.Equal(t, http.StatusForbidden, code)
+
+	code, _ = send(http.MethodGet, "secret", url.Values{})
+	assert.Equal(t, http.StatusNotFound, code)
+
+	store, err := tombstone.NewTombstoneStore(t.TempDir())
+	assert.NoError(t, err)
+	defer store.Close()
+	server.SetTombstoneStore(store)
+
+	// The entities are connected before the withdrawal
+	paths, err := server.runner.pathFinder.PathsBetween(context.Background(), "e-1", "e-2", 2, false)
+	assert.NoError(t, err)
+	assert.NotEqual(t, 0, len(paths))
+
+	// Withdraw an entity
+	code, response := send(http.MethodPost, "secret", change("withdraw", "e-1", "Source retracted"))
+	assert.Equal(t, http.StatusOK, code)
+	assert.True(t, response.Tombstone.Withdrawn)
+
+	code, response = send(http.MethodPost, "secret", change("withdraw", "e-1", "Again"))
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Contains(t, response.Error, tombstone.ErrAlreadyWithdrawn.Error())
+
+	code, _ = send(http.MethodPost, "secret", change("withdraw", "e-2", " "))
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, _ = send(http.MethodPost, "secret", change("delete", "e-2", "Unknown"))
+	assert.Equal(t, http.StatusBadRequest, code)
+
+	code, response = send(http.MethodGet, "secret", url.Values{})
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, 1, len(response.Withdrawn))
+	assert.Equal(t, "e-1", response.Withdrawn[0].EntityId)
+
+	// The withdrawn entity isn't connected, but its entity page shows why it was withdrawn
+	paths, err = server.runner.pathFinder.PathsBetween(context.Background(), "e-1", "e-2", 2, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(paths))
+
+	body := getPage(server.Routes(), "/entity/e-1").Body.String()
+	assert.Contains(t, body, "This entity has been withdrawn")
+	assert.Contains(t, body, "Source retracted")
+
+	body = getPage(server.Routes(), "/entity/e-2").Body.String()
+	assert.NotContains(t, body, "This entity has been withdrawn")
+
+	// Reinstating the entity keeps its history
+	code, response = send(http.MethodPost, "secret", change("reinstate", "e-1", "Record restored"))
+	assert.Equal(t, http.StatusOK, code)
+	assert.False(t, response.Tombstone.Withdrawn)
+	assert.Equal(t, 2, len(response.Tombstone.History))
+
+	body = getPage(server.Routes(), "/entity/e-1").Body.String()
+	assert.NotContains(t, body, "This entity has been withdrawn")
+	assert.Contains(t, body, "Record restored")
+
+	code, _ = send(http.MethodPost, "secret", change("reinstate", "e-1", "Again"))
+	assert.Equal(t, http.StatusConflict, code)
+
+	code, _ = send(http.MethodDelete, "secret", url.Values{})
+	assert.Equal(t, http.StatusMethodNotAllowed, code)
+}
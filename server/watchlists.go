@@ -0,0 +1,490 @@
+// Watchlists are sets of entities that are searched between whenever the graph is reloaded (i.e. a
+// new generation is switched to or rolled back to). If entities that weren't connected become
+// connected, the watchlist raises an alert, which is shown as a badge in the header of the pages
+// and is optionally posted as JSON to a webhook.
+
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/bfs"
+	"github.com/cdclaxton/shortest-path-web-app/i18n"
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/rs/zerolog"
+)
+
+// Names of the inputs and URLs for watchlists
+const (
+	WatchlistIdInputName       = "watchlistId"   // ID of a watchlist
+	WatchlistNameInputName     = "watchlistName" // Name of a new watchlist
+	WatchlistEntitiesInputName = "entityIds"     // Entity IDs of a new watchlist
+	WatchlistWebhookInputName  = "webhook"       // URL of the webhook of a new watchlist (optional)
+	watchlistsUrl              = "/watchlists"
+	watchlistUrl               = "/watchlist/"
+	watchlistTimeFormat        = "2006-01-02 15:04:05 MST"
+	watchlistCheckTimeout      = 5 * time.Minute  // Maximum time to search between a watchlist's entities
+	webhookTimeout             = 10 * time.Second // Maximum time to post an alert to a webhook
+)
+
+var (
+	ErrWatchlistStoreIsNil = errors.New("watchlist store is nil")
+	ErrWebhookNotPermitted = errors.New("webhook URL isn't permitted")
+	ErrWebhookUnsuccessful = errors.New("webhook returned an unsuccessful status")
+	ErrWatchlistOtherGraph = errors.New("watchlist is of another graph")
+)
+
+// SetWatchlistStore in which the watchlists are saved.
+func (j *JobServer) SetWatchlistStore(store *job.WatchlistStore) error {
+
+	// Precondition
+	if store == nil {
+		return ErrWatchlistStoreIsNil
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Int("numberOfWatchlists", len(store.List(j.basePath))).
+		Msg("Setting the watchlist store")
+
+	j.watchlists = store
+	return nil
+}
+
+// SetWebhookHosts to which the alerts of the watchlists may be posted. Webhooks are disabled if
+// there aren't any hosts.
+func (j *JobServer) SetWebhookHosts(hosts []string) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Strs("hosts", hosts).
+		Msg("Setting the hosts of the webhooks of the watchlists")
+
+	j.webhookHosts = map[string]struct{}{}
+	for _, host := range hosts {
+		host = strings.ToLower(strings.TrimSpace(host))
+		if len(host) > 0 {
+			j.webhookHosts[host] = struct{}{}
+		}
+	}
+}
+
+// validateWebhookUrl returns the URL without surrounding whitespace if it is blank or is an HTTP(S)
+// URL of one of the permitted hosts.
+func (j *JobServer) validateWebhookUrl(webhookUrl string) (string, error) {
+
+	webhookUrl = strings.TrimSpace(webhookUrl)
+	if len(webhookUrl) == 0 {
+		return "", nil
+	}
+
+	parsed, err := url.Parse(webhookUrl)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return "", ErrWebhookNotPermitted
+	}
+
+	if _, found := j.webhookHosts[strings.ToLower(parsed.Hostname())]; !found {
+		return "", ErrWebhookNotPermitted
+	}
+
+	return webhookUrl, nil
+}
+
+// WatchlistConnection is a pair of entities that became connected in an alert posted to a webhook.
+type WatchlistConnection struct {
+	Entity1 string `json:"entity1"`
+	Entity2 string `json:"entity2"`
+}
+
+// WatchlistWebhook is the JSON body of an alert posted to a webhook.
+type WatchlistWebhook struct {
+	Id          string                `json:"id"`          // ID of the watchlist
+	Name        string                `json:"name"`        // Name of the watchlist
+	Graph       string                `json:"graph"`       // Base path of the graph searched
+	Checked     time.Time             `json:"checked"`     // When the watchlist was checked
+	Connections []WatchlistConnection `json:"connections"` // Pairs of entities that became connected
+}
+
+// postWebhook of the alert of the watchlist with the pairs of entities that became connected.
+func postWebhook(w job.Watchlist, checked time.Time, pairs []job.EntityPair) error {
+
+	body := WatchlistWebhook{
+		Id:          w.Id,
+		Name:        w.Name,
+		Graph:       w.Graph,
+		Checked:     checked.UTC(),
+		Connections: []WatchlistConnection{},
+	}
+
+	for _, pair := range pairs {
+		body.Connections = append(body.Connections, WatchlistConnection{
+			Entity1: pair.Entity1,
+			Entity2: pair.Entity2,
+		})
+	}
+
+	content, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	client := http.Client{Timeout: webhookTimeout}
+	resp, err := client.Post(w.WebhookUrl, "application/json", bytes.NewReader(content))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%w: %v", ErrWebhookUnsuccessful, resp.StatusCode)
+	}
+
+	return nil
+}
+
+// connectedPairs of the watchlist's entities in the graph, i.e. the pairs of entities with at
+// least one path between them within the watchlist's number of hops.
+func (j *JobServer) connectedPairs(ctx context.Context, w job.Watchlist) ([]job.EntityPair, error) {
+
+	ctx, cancel := context.WithTimeout(ctx, watchlistCheckTimeout)
+	defer cancel()
+
+	j.storeLock.BeginRead()
+	defer j.storeLock.EndRead()
+
+	entitySets := []job.EntitySet{{Name: w.Name, EntityIds: w.EntityIds}}
+	conns, err := j.runner.pathFinder.FindPathsWithContext(ctx, entitySets, w.MaxHops,
+		bfs.PathConstraints{}, logging.Logger.Level(zerolog.InfoLevel), nil)
+	if err != nil {
+		return nil, err
+	}
+	defer conns.Close()
+
+	pairs := []job.EntityPair{}
+	for src, destinations := range conns.Connections {
+		for dst := range destinations {
+			pairs = append(pairs, job.EntityPair{Entity1: src, Entity2: dst})
+		}
+	}
+
+	return pairs, nil
+}
+
+// checkWatchlist searches between the watchlist's entities and records the pairs that are
+// connected. Returns the pairs that became connected, which are posted to the watchlist's webhook
+// (if it has one). A failure to post to the webhook is logged rather than returned.
+func (j *JobServer) checkWatchlist(ctx context.Context, w job.Watchlist) ([]job.EntityPair, error) {
+
+	j.watchlistsLock.Lock()
+	defer j.watchlistsLock.Unlock()
+
+	checked := time.Now()
+	pairs, err := j.connectedPairs(ctx, w)
+	if err != nil {
+		return nil, err
+	}
+
+	newPairs, err := j.watchlists.RecordCheck(w.Id, pairs, checked)
+	if err != nil {
+		return nil, err
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("watchlistId", w.Id).
+		Int("connectedPairs", len(pairs)).
+		Int("newPairs", len(newPairs)).
+		Msg("Checked watchlist")
+
+	if len(newPairs) > 0 && len(w.WebhookUrl) > 0 {
+		if err := postWebhook(w, checked, newPairs); err != nil {
+			logging.Logger.Error().
+				Str(logging.ComponentField, componentName).
+				Str("watchlistId", w.Id).
+				Err(err).
+				Msg("Failed to post the alert of the watchlist to its webhook")
+		}
+	}
+
+	return newPairs, nil
+}
+
+// checkWatchlists of the graph, e.g. after it has been reloaded. The failures are logged.
+func (j *JobServer) checkWatchlists() {
+
+	for _, w := range j.watchlists.List(j.basePath) {
+		if _, err := j.checkWatchlist(context.Background(), w); err != nil {
+			logging.Logger.Error().
+				Str(logging.ComponentField, componentName).
+				Str("watchlistId", w.Id).
+				Err(err).
+				Msg("Failed to check the watchlist")
+		}
+	}
+}
+
+// getWatchlist of the graph with the ID.
+func (j *JobServer) getWatchlist(id string) (job.Watchlist, error) {
+
+	w, err := j.watchlists.Get(id)
+	if err != nil {
+		return job.Watchlist{}, err
+	}
+
+	if w.Graph != j.basePath {
+		return job.Watchlist{}, ErrWatchlistOtherGraph
+	}
+
+	return w, nil
+}
+
+// watchlistError returns the HTTP status and the error to show the user for an error with a
+// watchlist.
+func watchlistError(err error, watchlistId string) (int, error) {
+	switch {
+	case errors.Is(err, job.ErrWatchlistNotFound), errors.Is(err, ErrWatchlistOtherGraph):
+		return http.StatusNotFound, i18n.Wrap(err, "error.watchlistNotFound", watchlistId)
+	case errors.Is(err, job.ErrWatchlistNameEmpty), errors.Is(err, job.ErrWatchlistNameTooLong):
+		return http.StatusBadRequest, i18n.Wrap(err, "error.watchlistName",
+			job.MaxWatchlistNameLength)
+	case errors.Is(err, job.ErrWatchlistEntities):
+		return http.StatusBadRequest, i18n.Wrap(err, "error.watchlistEntities",
+			job.MinWatchlistEntities, job.MaxWatchlistEntities)
+	case errors.Is(err, ErrWebhookNotPermitted):
+		return http.StatusBadRequest, i18n.Wrap(err, "error.watchlistWebhook")
+	case errors.Is(err, context.DeadlineExceeded):
+		return http.StatusServiceUnavailable, i18n.Wrap(err, "error.watchlistTimeout",
+			watchlistCheckTimeout.String())
+	case errors.Is(err, bfs.ErrTooManyPaths):
+		return http.StatusBadRequest, i18n.Wrap(err, "error.tooManyPaths")
+	}
+
+	return http.StatusInternalServerError, err
+}
+
+// WatchlistDisplay is a watchlist in the list of watchlists presented to the user.
+type WatchlistDisplay struct {
+	Name             string
+	Url              string
+	NumberOfEntities int
+	MaxHops          int
+	LastChecked      string
+	NumberConnected  int
+	Unread           int
+}
+
+// WatchlistAlertDisplay is an alert of a watchlist presented to the user.
+type WatchlistAlertDisplay struct {
+	Checked     string
+	Connections []job.EntityPair
+	Unread      bool
+}
+
+// formatLastChecked time of the watchlist, which is blank if it hasn't been checked.
+func formatLastChecked(w job.Watchlist) string {
+	if !w.Checked() {
+		return ""
+	}
+	return w.LastChecked.Format(watchlistTimeFormat)
+}
+
+// prepareWatchlists for display.
+func (j *JobServer) prepareWatchlists(watchlists []job.Watchlist) []WatchlistDisplay {
+
+	display := []WatchlistDisplay{}
+	for _, w := range watchlists {
+		display = append(display, WatchlistDisplay{
+			Name:             w.Name,
+			Url:              j.basePath + watchlistUrl + w.Id,
+			NumberOfEntities: len(w.EntityIds),
+			MaxHops:          w.MaxHops,
+			LastChecked:      formatLastChecked(w),
+			NumberConnected:  len(w.Connected),
+			Unread:           w.Unread,
+		})
+	}
+
+	return display
+}
+
+// prepareWatchlistAlerts for display, where the most recent alerts are unread.
+func prepareWatchlistAlerts(w job.Watchlist) []WatchlistAlertDisplay {
+
+	display := []WatchlistAlertDisplay{}
+	for idx, alert := range w.Alerts {
+		display = append(display, WatchlistAlertDisplay{
+			Checked:     alert.Checked.Format(watchlistTimeFormat),
+			Connections: alert.Connections,
+			Unread:      idx < w.Unread,
+		})
+	}
+
+	return display
+}
+
+// handleWatchlists returns the page listing the watchlists of the graph.
+func (j *JobServer) handleWatchlists(w http.ResponseWriter, req *http.Request) {
+
+	settings := j.pageSettings(w, req)
+
+	page := j.render(j.watchlistsTemplate, settings, map[string]interface{}{
+		"watchlists": j.prepareWatchlists(j.watchlists.List(j.basePath)),
+		"hopOptions": j.searchLimits.HopOptions(),
+		"webhooks":   len(j.webhookHosts) > 0,
+		"maxLength":  job.MaxWatchlistNameLength,
+	})
+	fmt.Fprint(w, withCsrfToken(w, req, page))
+}
+
+// handleCreateWatchlist creates a watchlist and redirects to its page. The watchlist is checked in
+// the background to find the entities that are already connected.
+func (j *JobServer) handleCreateWatchlist(w http.ResponseWriter, req *http.Request) {
+
+	settings := j.pageSettings(w, req)
+
+	if req.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := req.FormValue(WatchlistNameInputName)
+	entityIds := uniqueEntityIds(splitEntityIDs(req.FormValue(WatchlistEntitiesInputName),
+		j.entityIdNormaliser))
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("watchlistName", name).
+		Int("numberOfEntities", len(entityIds)).
+		Msg("Creating watchlist")
+
+	maxHops, err := parseNumberOfHops(req, j.searchLimits)
+	if err != nil {
+		j.renderCaseProblem(w, settings, http.StatusBadRequest, err)
+		return
+	}
+
+	webhookUrl, err := j.validateWebhookUrl(req.FormValue(WatchlistWebhookInputName))
+	if err == nil {
+		var watchlist job.Watchlist
+		watchlist, err = j.watchlists.Create(j.basePath, name, entityIds, maxHops, webhookUrl)
+		if err == nil {
+			go j.checkWatchlistInBackground(watchlist)
+			http.Redirect(w, req, j.basePath+watchlistUrl+watchlist.Id, http.StatusFound)
+			return
+		}
+	}
+
+	status, err := watchlistError(err, "")
+	j.renderCaseProblem(w, settings, status, err)
+}
+
+// checkWatchlistInBackground logging any failure.
+func (j *JobServer) checkWatchlistInBackground(w job.Watchlist) {
+	if _, err := j.checkWatchlist(context.Background(), w); err != nil {
+		logging.Logger.Error().
+			Str(logging.ComponentField, componentName).
+			Str("watchlistId", w.Id).
+			Err(err).
+			Msg("Failed to check the new watchlist")
+	}
+}
+
+// handleWatchlist returns the page of a watchlist, i.e. its connections and alerts.
+func (j *JobServer) handleWatchlist(w http.ResponseWriter, req *http.Request) {
+
+	settings := j.pageSettings(w, req)
+	watchlistId := strings.TrimPrefix(req.URL.Path, watchlistUrl)
+
+	watchlist, err := j.getWatchlist(watchlistId)
+	if err != nil {
+		status, err := watchlistError(err, watchlistId)
+		j.renderCaseProblem(w, settings, status, err)
+		return
+	}
+
+	entityIds := append([]string{}, watchlist.EntityIds...)
+	sort.Strings(entityIds)
+
+	page := j.render(j.watchlistTemplate, settings, map[string]interface{}{
+		"id":          watchlist.Id,
+		"name":        watchlist.Name,
+		"created":     watchlist.Created.Format(watchlistTimeFormat),
+		"entityIds":   strings.Join(entityIds, ", "),
+		"maxHops":     watchlist.MaxHops,
+		"webhook":     len(watchlist.WebhookUrl) > 0,
+		"lastChecked": formatLastChecked(watchlist),
+		"connected":   watchlist.Connected,
+		"alerts":      prepareWatchlistAlerts(watchlist),
+		"unread":      watchlist.Unread,
+	})
+	fmt.Fprint(w, withCsrfToken(w, req, page))
+}
+
+// watchlistAction performs the action on the watchlist in the POSTed form and redirects to the
+// location returned by the action.
+func (j *JobServer) watchlistAction(w http.ResponseWriter, req *http.Request, action string,
+	fn func(watchlist job.Watchlist) (string, error)) {
+
+	settings := j.pageSettings(w, req)
+
+	if req.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	watchlistId := req.FormValue(WatchlistIdInputName)
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("watchlistId", watchlistId).
+		Str("action", action).
+		Msg("Received request for a watchlist")
+
+	watchlist, err := j.getWatchlist(watchlistId)
+	if err != nil {
+		status, err := watchlistError(err, watchlistId)
+		j.renderCaseProblem(w, settings, status, err)
+		return
+	}
+
+	location, err := fn(watchlist)
+	if err != nil {
+		status, err := watchlistError(err, watchlistId)
+		j.renderCaseProblem(w, settings, status, err)
+		return
+	}
+
+	http.Redirect(w, req, location, http.StatusFound)
+}
+
+// handleCheckWatchlist checks the watchlist now rather than waiting for the graph to be reloaded.
+func (j *JobServer) handleCheckWatchlist(w http.ResponseWriter, req *http.Request) {
+	j.watchlistAction(w, req, "check", func(watchlist job.Watchlist) (string, error) {
+		_, err := j.checkWatchlist(req.Context(), watchlist)
+		return j.basePath + watchlistUrl + watchlist.Id, err
+	})
+}
+
+// handleAcknowledgeWatchlist marks the alerts of the watchlist as read.
+func (j *JobServer) handleAcknowledgeWatchlist(w http.ResponseWriter, req *http.Request) {
+	j.watchlistAction(w, req, "acknowledge", func(watchlist job.Watchlist) (string, error) {
+		return j.basePath + watchlistUrl + watchlist.Id, j.watchlists.Acknowledge(watchlist.Id)
+	})
+}
+
+// handleDeleteWatchlist deletes the watchlist and returns to the list of watchlists.
+func (j *JobServer) handleDeleteWatchlist(w http.ResponseWriter, req *http.Request) {
+	j.watchlistAction(w, req, "delete", func(watchlist job.Watchlist) (string, error) {
+		return j.basePath + watchlistsUrl, j.watchlists.Delete(watchlist.Id)
+	})
+}
@@ -0,0 +1,176 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/stretchr/testify/assert"
+)
+
+// waitForWatchlistCheck returns the watchlist once it has been checked.
+func waitForWatchlistCheck(t *testing.T, server *JobServer, id string) job.Watchlist {
+	for {
+		w, err := server.watchlists.Get(id)
+		assert.NoError(t, err)
+		if err != nil || w.Checked() {
+			return w
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestWatchlists(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	handler := server.Routes()
+
+	// No watchlists and webhooks are disabled
+	w := getPage(handler, "/watchlists")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "No watchlists have been created yet.")
+	assert.NotContains(t, w.Body.String(), `name="webhook"`)
+
+	// Invalid watchlists
+	form := url.Values{
+		WatchlistNameInputName:     {"Watchlist A"},
+		WatchlistEntitiesInputName: {"e-1"},
+		NumberHopsInputName:        {"2"},
+	}
+	assert.Equal(t, http.StatusBadRequest, postForm(handler, "/create-watchlist", form).Code)
+
+	form.Set(WatchlistEntitiesInputName, "e-1, e-2, e-1")
+	form.Set(NumberHopsInputName, "100")
+	assert.Equal(t, http.StatusBadRequest, postForm(handler, "/create-watchlist", form).Code)
+
+	form.Set(NumberHopsInputName, "2")
+	form.Set(WatchlistWebhookInputName, "http://localhost/hook")
+	assert.Equal(t, http.StatusBadRequest, postForm(handler, "/create-watchlist", form).Code)
+
+	// Create a watchlist, which is checked in the background to find the existing connections
+	form.Del(WatchlistWebhookInputName)
+	w = postForm(handler, "/create-watchlist", form)
+	assert.Equal(t, http.StatusFound, w.Code)
+	location := w.Result().Header.Get("Location")
+	assert.True(t, strings.HasPrefix(location, watchlistUrl))
+	watchlistId := strings.TrimPrefix(location, watchlistUrl)
+
+	watchlist := waitForWatchlistCheck(t, server, watchlistId)
+	assert.Equal(t, []string{"e-1", "e-2"}, watchlist.EntityIds)
+	assert.Equal(t, []job.EntityPair{{Entity1: "e-1", Entity2: "e-2"}}, watchlist.Connected)
+	assert.Equal(t, 0, len(watchlist.Alerts))
+
+	w = getPage(handler, "/watchlists")
+	assert.Contains(t, w.Body.String(), `<a href="/watchlist/`+watchlistId+`"`)
+
+	w = getPage(handler, location)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "No alerts have been raised for this watchlist.")
+	assert.Contains(t, w.Body.String(), `<a href="../entity/e-2" class="govuk-link">e-2</a>`)
+
+	// Checking the watchlist again doesn't raise an alert
+	w = postForm(handler, "/check-watchlist", url.Values{WatchlistIdInputName: {watchlistId}})
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, 0, server.watchlists.NumberUnread(""))
+
+	// Unknown watchlists
+	assert.Equal(t, http.StatusNotFound, getPage(handler, watchlistUrl+"unknown").Code)
+	w = postForm(handler, "/check-watchlist", url.Values{WatchlistIdInputName: {"unknown"}})
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, http.StatusMethodNotAllowed, getPage(handler, "/check-watchlist").Code)
+
+	// Delete the watchlist
+	w = postForm(handler, "/delete-watchlist", url.Values{WatchlistIdInputName: {watchlistId}})
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, watchlistsUrl, w.Result().Header.Get("Location"))
+	assert.Equal(t, http.StatusNotFound, getPage(handler, location).Code)
+}
+
+func TestWatchlistAlert(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	handler := server.Routes()
+
+	// Receive the alerts posted to the webhook
+	received := make(chan WatchlistWebhook, 1)
+	webhook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var body WatchlistWebhook
+		assert.NoError(t, json.NewDecoder(req.Body).Decode(&body))
+		received <- body
+	}))
+	defer webhook.Close()
+
+	server.SetWebhookHosts([]string{"127.0.0.1"})
+	webhookUrl, err := server.validateWebhookUrl(webhook.URL + "/alerts")
+	assert.NoError(t, err)
+
+	// The watchlist's entities weren't connected when it was last checked
+	watchlist, err := server.watchlists.Create("", "Watchlist A", []string{"e-1", "e-2"}, 2,
+		webhookUrl)
+	assert.NoError(t, err)
+
+	_, err = server.watchlists.RecordCheck(watchlist.Id, nil, time.Now())
+	assert.NoError(t, err)
+
+	// The data is reloaded and the entities are now connected
+	server.checkWatchlists()
+
+	body := <-received
+	assert.Equal(t, watchlist.Id, body.Id)
+	assert.Equal(t, "Watchlist A", body.Name)
+	assert.Equal(t, []WatchlistConnection{{Entity1: "e-1", Entity2: "e-2"}}, body.Connections)
+
+	// The unread alert is shown as a badge in the header of the pages
+	assert.Equal(t, 1, server.watchlists.NumberUnread(""))
+	w := getPage(handler, "/")
+	assert.Contains(t, w.Body.String(), `title="Unread alerts">1</strong>`)
+
+	w = getPage(handler, watchlistUrl+watchlist.Id)
+	assert.Contains(t, w.Body.String(), "Mark the alerts as read")
+
+	// Acknowledge the alert
+	w = postForm(handler, "/acknowledge-watchlist", url.Values{WatchlistIdInputName: {watchlist.Id}})
+	assert.Equal(t, http.StatusFound, w.Code)
+	assert.Equal(t, 0, server.watchlists.NumberUnread(""))
+
+	w = getPage(handler, "/")
+	assert.NotContains(t, w.Body.String(), "Unread alerts")
+}
+
+func TestValidateWebhookUrl(t *testing.T) {
+
+	server := makeJobServer(t)
+	defer cleanUpJobRunner(t, server.runner)
+
+	// Webhooks are disabled without any hosts
+	_, err := server.validateWebhookUrl("http://example.com/hook")
+	assert.ErrorIs(t, err, ErrWebhookNotPermitted)
+
+	server.SetWebhookHosts([]string{" Example.com ", ""})
+
+	testCases := []struct {
+		webhookUrl string
+		valid      bool
+	}{
+		{"", true},
+		{"http://example.com/hook", true},
+		{"https://EXAMPLE.com:8443/hook", true},
+		{"ftp://example.com/hook", false},
+		{"http://other.com/hook", false},
+		{"example.com/hook", false},
+	}
+
+	for _, testCase := range testCases {
+		_, err := server.validateWebhookUrl(testCase.webhookUrl)
+		assert.Equal(t, testCase.valid, err == nil, testCase.webhookUrl)
+	}
+}
@@ -0,0 +1,251 @@
+// Package shortestpath is the library interface of the web-app for Go services that embed the
+// path finding. An Engine builds (or loads) the graphs from a GraphConfig and wires up the path
+// finder, the spider, the entity search and the i2 chart builders, so the caller doesn't need to.
+package shortestpath
+
+import (
+	"context"
+	"errors"
+
+	"github.com/cdclaxton/shortest-path-web-app/bfs"
+	"github.com/cdclaxton/shortest-path-web-app/graphbuilder"
+	"github.com/cdclaxton/shortest-path-web-app/i2chart"
+	"github.com/cdclaxton/shortest-path-web-app/job"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cdclaxton/shortest-path-web-app/search"
+	"github.com/cdclaxton/shortest-path-web-app/set"
+	"github.com/cdclaxton/shortest-path-web-app/spider"
+)
+
+const componentName = "shortestPath"
+
+// Types of the engine's inputs and outputs, so that a caller only needs to import this package
+type (
+	GraphConfig   = graphbuilder.GraphConfig  // Config of the input data and the graph stores
+	GraphStats    = graphbuilder.GraphStats   // Statistics of the graphs
+	EntitySet     = job.EntitySet             // Named set of entity IDs
+	Constraints   = bfs.PathConstraints       // Constraints on the paths, e.g. directed
+	Connections   = bfs.NetworkConnections    // Paths between the entities of the sets
+	Path          = bfs.Path                  // Path between two entities
+	SpiderCaps    = spider.SpiderCaps         // Caps on the expansion of a spider
+	SpiderResults = spider.SpiderResults      // Sub-graph found by spidering
+	SearchResult  = search.EntitySearchResult // Presence of an entity in the graphs
+)
+
+var (
+	ErrNoChartConfig       = errors.New("i2 chart config hasn't been set")
+	ErrNoSpiderChartConfig = errors.New("i2 spider chart config hasn't been set")
+	ErrNoSeedEntities      = errors.New("no seed entities")
+	ErrNoConnections       = errors.New("no connections to chart")
+)
+
+// An Engine finds the paths between entities, spiders out from entities, searches for entities and
+// builds i2 charts of the results in a pair of graphs. It is safe for concurrent use once it has
+// been configured.
+type Engine struct {
+	graphs             *graphbuilder.GraphBuilder  // Bipartite and unipartite graphs
+	pathFinder         *bfs.PathFinder             // Finds the paths in the unipartite graph
+	spider             *spider.Spider              // Spiders out in the unipartite graph
+	search             *search.EntitySearch        // Searches for entities in the graphs
+	chartBuilder       *i2chart.I2ChartBuilder     // Builds the charts of paths (optional)
+	spiderChartBuilder *i2chart.SpiderChartBuilder // Builds the charts of spiders (optional)
+}
+
+// New engine for the graphs in the config, which are built if their input files have changed or
+// loaded if they haven't.
+func New(config GraphConfig) (*Engine, error) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Msg("Making the shortest path engine")
+
+	graphs, _, err := graphbuilder.NewGraphBuilder(config)
+	if err != nil {
+		return nil, err
+	}
+
+	engine, err := newEngine(graphs)
+	if err != nil {
+		graphs.Close()
+		return nil, err
+	}
+
+	return engine, nil
+}
+
+// NewFromJson makes an engine for the graphs in the JSON data config file.
+func NewFromJson(dataConfigPath string) (*Engine, error) {
+
+	config, err := graphbuilder.ReadGraphConfigFromJson(dataConfigPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return New(*config)
+}
+
+// newEngine for the graphs.
+func newEngine(graphs *graphbuilder.GraphBuilder) (*Engine, error) {
+
+	pathFinder, err := bfs.NewPathFinder(graphs.Unipartite)
+	if err != nil {
+		return nil, err
+	}
+
+	spiderEngine, err := spider.NewSpider(graphs.Unipartite)
+	if err != nil {
+		return nil, err
+	}
+	spiderEngine.SetBipartite(graphs.Bipartite)
+
+	searchEngine, err := search.NewEntitySearch(graphs.Bipartite, graphs.Unipartite)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Engine{
+		graphs:     graphs,
+		pathFinder: pathFinder,
+		spider:     spiderEngine,
+		search:     searchEngine,
+	}, nil
+}
+
+// SetMaxPaths found by a search (0 for no limit).
+func (e *Engine) SetMaxPaths(maxPaths int) error {
+	return e.pathFinder.SetMaxPaths(maxPaths)
+}
+
+// SetChartConfig reads the i2 config of the charts of paths from the JSON file.
+func (e *Engine) SetChartConfig(i2ConfigPath string) error {
+
+	chartBuilder, err := i2chart.NewI2ChartBuilder(i2ConfigPath)
+	if err != nil {
+		return err
+	}
+
+	chartBuilder.SetBipartite(e.graphs.Bipartite)
+	chartBuilder.SetUnipartite(e.graphs.Unipartite)
+	e.chartBuilder = chartBuilder
+	return nil
+}
+
+// SetSpiderChartConfig reads the i2 config of the charts of spiders from the JSON file.
+func (e *Engine) SetSpiderChartConfig(i2SpiderConfigPath string) error {
+
+	spiderChartBuilder, err := i2chart.NewSpiderChartBuilder(i2SpiderConfigPath)
+	if err != nil {
+		return err
+	}
+
+	spiderChartBuilder.SetBipartite(e.graphs.Bipartite)
+	e.spiderChartBuilder = spiderChartBuilder
+	return nil
+}
+
+// Stats of the graphs.
+func (e *Engine) Stats() (GraphStats, error) {
+	if err := e.graphs.CalculateStats(); err != nil {
+		return GraphStats{}, err
+	}
+	return e.graphs.Stats, nil
+}
+
+// FindPaths between the entities of the sets within the number of hops that meet the constraints.
+// The search is abandoned with the context's error if the context is cancelled. The caller is
+// responsible for closing the connections.
+func (e *Engine) FindPaths(ctx context.Context, entitySets []EntitySet, maxHops int,
+	constraints Constraints) (*Connections, error) {
+	return e.pathFinder.FindPathsWithContext(ctx, entitySets, maxHops, constraints,
+		logging.Logger, nil)
+}
+
+// PathsBetween two entities within the number of hops.
+func (e *Engine) PathsBetween(ctx context.Context, from string, to string, maxHops int,
+	directed bool) ([]Path, error) {
+	return e.pathFinder.PathsBetween(ctx, from, to, maxHops, directed)
+}
+
+// Spider out from the seed entities for the number of steps within the caps.
+func (e *Engine) Spider(ctx context.Context, seedEntityIds []string, numberSteps int,
+	caps SpiderCaps) (*SpiderResults, error) {
+
+	// Preconditions
+	if len(seedEntityIds) == 0 {
+		return nil, ErrNoSeedEntities
+	}
+
+	return e.spider.ExecuteWithContext(ctx, numberSteps, set.NewPopulatedSet(seedEntityIds...), caps)
+}
+
+// Search for the entities in the graphs.
+func (e *Engine) Search(entityIds []string) (map[string]SearchResult, error) {
+	return e.search.Search(entityIds)
+}
+
+// BuildChart of the connections as a table (with a header row) in the format of the i2 config.
+func (e *Engine) BuildChart(conns *Connections) ([][]string, error) {
+
+	// Preconditions
+	if e.chartBuilder == nil {
+		return nil, ErrNoChartConfig
+	}
+
+	if conns == nil || !conns.HasAnyConnections() {
+		return nil, ErrNoConnections
+	}
+
+	return e.chartBuilder.Build(conns)
+}
+
+// WriteChart of the connections to an Excel file and, if the path isn't blank, an ANX file.
+func (e *Engine) WriteChart(conns *Connections, excelPath string, anxPath string) error {
+
+	table, err := e.BuildChart(conns)
+	if err != nil {
+		return err
+	}
+
+	if err := i2chart.WriteToExcel(excelPath, table); err != nil {
+		return err
+	}
+
+	if len(anxPath) == 0 {
+		return nil
+	}
+
+	chart, err := e.chartBuilder.TableToAnx(table)
+	if err != nil {
+		return err
+	}
+
+	return i2chart.WriteToAnx(anxPath, chart)
+}
+
+// BuildSpiderChart of the results of spidering as a table (with a header row) in the format of the
+// i2 spider config.
+func (e *Engine) BuildSpiderChart(results *SpiderResults) ([][]string, error) {
+
+	// Preconditions
+	if e.spiderChartBuilder == nil {
+		return nil, ErrNoSpiderChartConfig
+	}
+
+	return e.spiderChartBuilder.Build(results)
+}
+
+// WriteSpiderChart of the results of spidering to an Excel file.
+func (e *Engine) WriteSpiderChart(results *SpiderResults, excelPath string) error {
+
+	table, err := e.BuildSpiderChart(results)
+	if err != nil {
+		return err
+	}
+
+	return i2chart.WriteSheetToExcel(excelPath, e.spiderChartBuilder.SheetName(), table, nil)
+}
+
+// Close the graphs.
+func (e *Engine) Close() error {
+	return e.graphs.Close()
+}
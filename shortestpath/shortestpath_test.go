@@ -0,0 +1,99 @@
+package shortestpath
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testDataFolder = "../test-data-sets/set-1/"
+
+// newTestEngine for the first test dataset.
+func newTestEngine(t *testing.T) *Engine {
+	engine, err := NewFromJson(filepath.Join(testDataFolder, "data-config.json"))
+	assert.NoError(t, err)
+	t.Cleanup(func() { engine.Close() })
+	return engine
+}
+
+func TestNewFromJson(t *testing.T) {
+	_, err := NewFromJson(filepath.Join(testDataFolder, "missing.json"))
+	assert.Error(t, err)
+
+	stats, err := newTestEngine(t).Stats()
+	assert.NoError(t, err)
+	assert.True(t, stats.Unipartite.NumberOfEntities > 0)
+}
+
+func TestFindPathsAndBuildChart(t *testing.T) {
+
+	engine := newTestEngine(t)
+	ctx := context.Background()
+
+	entitySets := []EntitySet{
+		{Name: "Dataset-1", EntityIds: []string{"e-1"}},
+		{Name: "Dataset-2", EntityIds: []string{"e-2"}},
+	}
+
+	conns, err := engine.FindPaths(ctx, entitySets, 2, Constraints{})
+	assert.NoError(t, err)
+	defer conns.Close()
+	assert.True(t, conns.HasAnyConnections())
+
+	// A chart can't be built without the i2 config
+	_, err = engine.BuildChart(conns)
+	assert.ErrorIs(t, err, ErrNoChartConfig)
+
+	assert.NoError(t, engine.SetChartConfig(filepath.Join(testDataFolder, "i2-config.json")))
+	table, err := engine.BuildChart(conns)
+	assert.NoError(t, err)
+	assert.True(t, len(table) > 1)
+
+	folder := t.TempDir()
+	excelPath := filepath.Join(folder, "chart.xlsx")
+	anxPath := filepath.Join(folder, "chart.anx")
+	assert.NoError(t, engine.WriteChart(conns, excelPath, anxPath))
+	assert.FileExists(t, excelPath)
+	assert.FileExists(t, anxPath)
+
+	// Paths between two entities
+	paths, err := engine.PathsBetween(ctx, "e-1", "e-2", 2, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"e-1", "e-2"}, paths[0].Route)
+}
+
+func TestSpider(t *testing.T) {
+
+	engine := newTestEngine(t)
+	ctx := context.Background()
+
+	_, err := engine.Spider(ctx, nil, 1, SpiderCaps{})
+	assert.ErrorIs(t, err, ErrNoSeedEntities)
+
+	results, err := engine.Spider(ctx, []string{"e-1", "e-unknown"}, 1, SpiderCaps{})
+	assert.NoError(t, err)
+	assert.True(t, results.SeedEntitiesNotFound.Has("e-unknown"))
+
+	connected, err := results.HasAtLeastOneConnection()
+	assert.NoError(t, err)
+	assert.True(t, connected)
+
+	_, err = engine.BuildSpiderChart(results)
+	assert.ErrorIs(t, err, ErrNoSpiderChartConfig)
+
+	assert.NoError(t, engine.SetSpiderChartConfig(filepath.Join(testDataFolder, "i2-spider-config.json")))
+	excelPath := filepath.Join(t.TempDir(), "spider.xlsx")
+	assert.NoError(t, engine.WriteSpiderChart(results, excelPath))
+	assert.FileExists(t, excelPath)
+}
+
+func TestSearch(t *testing.T) {
+
+	results, err := newTestEngine(t).Search([]string{"e-1", "e-unknown"})
+	assert.NoError(t, err)
+	assert.True(t, results["e-1"].InBipartite)
+	assert.True(t, results["e-1"].InUnipartite)
+	assert.False(t, results["e-unknown"].InBipartite)
+}
@@ -0,0 +1,419 @@
+// Package snapshot exports the persisted (Pebble or bbolt) bipartite and unipartite graph stores,
+// along with the signature file, to a single versioned archive and imports them on another
+// machine. This allows the graphs to be built once and then shipped to the web servers.
+//
+// The archive is a gzipped tar file with the layout:
+//
+//	manifest.json
+//	bipartite/<store files>
+//	unipartite/<store files>
+//	signatures.json (if the graph config has a signature file)
+//
+// The graph stores must be closed (i.e. the web-app mustn't be running) whilst a snapshot is
+// exported or imported.
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphbuilder"
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+)
+
+// Component name used in logging
+const componentName = "snapshot"
+
+// Version of the snapshot archive format
+const Version = 1
+
+// Names of the entries in the archive
+const (
+	manifestFilename   = "manifest.json"
+	bipartiteFolder    = "bipartite"
+	unipartiteFolder   = "unipartite"
+	signaturesFilename = "signatures.json"
+)
+
+var (
+	ErrStoreNotPersisted     = errors.New("graph store is not persisted to disk")
+	ErrTempFolder            = errors.New("graph store uses a temporary folder")
+	ErrManifestNotFound      = errors.New("snapshot manifest not found")
+	ErrUnsupportedVersion    = errors.New("unsupported snapshot version")
+	ErrStoreTypeMismatch     = errors.New("snapshot store type doesn't match the graph config")
+	ErrFolderNotEmpty        = errors.New("folder for the graph store isn't empty")
+	ErrIllegalPathInSnapshot = errors.New("illegal path in snapshot")
+)
+
+// Manifest describes the contents of a snapshot.
+type Manifest struct {
+	Version        int       `json:"version"`        // Version of the archive format
+	DateCreated    time.Time `json:"dateCreated"`    // Date and time the snapshot was created
+	BipartiteType  string    `json:"bipartiteType"`  // Backend of the bipartite store
+	UnipartiteType string    `json:"unipartiteType"` // Backend of the unipartite store
+	HasSignatures  bool      `json:"hasSignatures"`  // Does the snapshot contain a signature file?
+}
+
+// checkStoreConfig returns an error if the store can't be held in a snapshot.
+func checkStoreConfig(storeType string, folder string) error {
+
+	if storeType != graphbuilder.StorageTypePebble && storeType != graphbuilder.StorageTypeBolt {
+		return fmt.Errorf("%w: %v", ErrStoreNotPersisted, storeType)
+	}
+
+	if folder == graphbuilder.UseTempFolder || len(folder) == 0 {
+		return ErrTempFolder
+	}
+
+	return nil
+}
+
+// addFileToArchive adds the file on disk to the archive under the given name.
+func addFileToArchive(writer *tar.Writer, filepath string, name string) error {
+
+	file, err := os.Open(filepath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = name
+
+	if err := writer.WriteHeader(header); err != nil {
+		return err
+	}
+
+	_, err = io.Copy(writer, file)
+	return err
+}
+
+// addFolderToArchive adds the files in the folder (recursively) to the archive under the prefix.
+func addFolderToArchive(writer *tar.Writer, folder string, prefix string) error {
+	return filepath.WalkDir(folder, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+
+		relativePath, err := filepath.Rel(folder, path)
+		if err != nil {
+			return err
+		}
+
+		return addFileToArchive(writer, path, prefix+"/"+filepath.ToSlash(relativePath))
+	})
+}
+
+// addManifestToArchive adds the manifest as the first entry in the archive.
+func addManifestToArchive(writer *tar.Writer, manifest Manifest) error {
+
+	content, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	header := &tar.Header{
+		Name:    manifestFilename,
+		Mode:    0644,
+		Size:    int64(len(content)),
+		ModTime: manifest.DateCreated,
+	}
+
+	if err := writer.WriteHeader(header); err != nil {
+		return err
+	}
+
+	_, err = writer.Write(content)
+	return err
+}
+
+// Export the graph stores defined in the config (and the signature file) to an archive.
+func Export(config graphbuilder.GraphConfig, archivePath string) error {
+
+	// Preconditions
+	if err := checkStoreConfig(config.BipartiteConfig.Type, config.BipartiteConfig.Folder); err != nil {
+		return fmt.Errorf("bipartite: %w", err)
+	}
+
+	if err := checkStoreConfig(config.UnipartiteConfig.Type, config.UnipartiteConfig.Folder); err != nil {
+		return fmt.Errorf("unipartite: %w", err)
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("archive", archivePath).
+		Str("bipartiteFolder", config.BipartiteConfig.Folder).
+		Str("unipartiteFolder", config.UnipartiteConfig.Folder).
+		Msg("Exporting graph snapshot")
+
+	// Is there a signature file to include?
+	hasSignatures := false
+	if len(config.SignatureFile) > 0 {
+		if _, err := os.Stat(config.SignatureFile); err == nil {
+			hasSignatures = true
+		}
+	}
+
+	file, err := os.Create(archivePath)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	gzipWriter := gzip.NewWriter(file)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	manifest := Manifest{
+		Version:        Version,
+		DateCreated:    time.Now().UTC(),
+		BipartiteType:  config.BipartiteConfig.Type,
+		UnipartiteType: config.UnipartiteConfig.Type,
+		HasSignatures:  hasSignatures,
+	}
+
+	if err := addManifestToArchive(tarWriter, manifest); err != nil {
+		return err
+	}
+
+	if err := addFolderToArchive(tarWriter, config.BipartiteConfig.Folder, bipartiteFolder); err != nil {
+		return err
+	}
+
+	if err := addFolderToArchive(tarWriter, config.UnipartiteConfig.Folder, unipartiteFolder); err != nil {
+		return err
+	}
+
+	if hasSignatures {
+		if err := addFileToArchive(tarWriter, config.SignatureFile, signaturesFilename); err != nil {
+			return err
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return err
+	}
+
+	if err := gzipWriter.Close(); err != nil {
+		return err
+	}
+
+	return file.Close()
+}
+
+// prepareFolder for the import by making sure it exists and is empty.
+func prepareFolder(folder string, deleteFilesInFolder bool) error {
+
+	if err := os.MkdirAll(folder, 0755); err != nil {
+		return err
+	}
+
+	entries, err := os.ReadDir(folder)
+	if err != nil {
+		return err
+	}
+
+	if len(entries) == 0 {
+		return nil
+	}
+
+	if !deleteFilesInFolder {
+		return fmt.Errorf("%w: %v", ErrFolderNotEmpty, folder)
+	}
+
+	for _, entry := range entries {
+		if err := os.RemoveAll(filepath.Join(folder, entry.Name())); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// readManifest from the first entry of the archive.
+func readManifest(reader *tar.Reader) (*Manifest, error) {
+
+	header, err := reader.Next()
+	if err == io.EOF {
+		return nil, ErrManifestNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	if header.Name != manifestFilename {
+		return nil, ErrManifestNotFound
+	}
+
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := Manifest{}
+	if err := json.Unmarshal(content, &manifest); err != nil {
+		return nil, err
+	}
+
+	return &manifest, nil
+}
+
+// extractFile from the archive to the filepath.
+func extractFile(reader *tar.Reader, path string, mode int64) error {
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, fs.FileMode(mode).Perm())
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(file, reader); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// isLocalPath returns true if the path is relative and doesn't escape the folder it's joined to.
+func isLocalPath(path string) bool {
+	cleaned := filepath.Clean(filepath.FromSlash(path))
+
+	return len(path) > 0 && !filepath.IsAbs(cleaned) && cleaned != ".." &&
+		!strings.HasPrefix(cleaned, ".."+string(filepath.Separator))
+}
+
+// destination of an archive entry given the graph config.
+func destination(name string, config graphbuilder.GraphConfig) (string, error) {
+
+	if name == signaturesFilename {
+		return config.SignatureFile, nil
+	}
+
+	prefix, relativePath, found := strings.Cut(name, "/")
+	if !found || !isLocalPath(relativePath) {
+		return "", fmt.Errorf("%w: %v", ErrIllegalPathInSnapshot, name)
+	}
+
+	switch prefix {
+	case bipartiteFolder:
+		return filepath.Join(config.BipartiteConfig.Folder, relativePath), nil
+	case unipartiteFolder:
+		return filepath.Join(config.UnipartiteConfig.Folder, relativePath), nil
+	}
+
+	return "", fmt.Errorf("%w: %v", ErrIllegalPathInSnapshot, name)
+}
+
+// Import the graph stores (and the signature file) from an archive to the locations defined in
+// the config.
+func Import(archivePath string, config graphbuilder.GraphConfig) (*Manifest, error) {
+
+	// Preconditions
+	if err := checkStoreConfig(config.BipartiteConfig.Type, config.BipartiteConfig.Folder); err != nil {
+		return nil, fmt.Errorf("bipartite: %w", err)
+	}
+
+	if err := checkStoreConfig(config.UnipartiteConfig.Type, config.UnipartiteConfig.Folder); err != nil {
+		return nil, fmt.Errorf("unipartite: %w", err)
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("archive", archivePath).
+		Str("bipartiteFolder", config.BipartiteConfig.Folder).
+		Str("unipartiteFolder", config.UnipartiteConfig.Folder).
+		Msg("Importing graph snapshot")
+
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	gzipReader, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer gzipReader.Close()
+
+	tarReader := tar.NewReader(gzipReader)
+
+	// Check the snapshot is compatible with the config before touching any files
+	manifest, err := readManifest(tarReader)
+	if err != nil {
+		return nil, err
+	}
+
+	if manifest.Version != Version {
+		return nil, fmt.Errorf("%w: %v", ErrUnsupportedVersion, manifest.Version)
+	}
+
+	if manifest.BipartiteType != config.BipartiteConfig.Type ||
+		manifest.UnipartiteType != config.UnipartiteConfig.Type {
+		return nil, fmt.Errorf("%w: snapshot has %v (bipartite) and %v (unipartite)",
+			ErrStoreTypeMismatch, manifest.BipartiteType, manifest.UnipartiteType)
+	}
+
+	if manifest.HasSignatures && len(config.SignatureFile) == 0 {
+		logging.Logger.Warn().
+			Str(logging.ComponentField, componentName).
+			Msg("Snapshot contains a signature file, but the config doesn't specify where to write it")
+	}
+
+	if err := prepareFolder(config.BipartiteConfig.Folder, config.BipartiteConfig.DeleteFilesInFolder); err != nil {
+		return nil, err
+	}
+
+	if err := prepareFolder(config.UnipartiteConfig.Folder, config.UnipartiteConfig.DeleteFilesInFolder); err != nil {
+		return nil, err
+	}
+
+	// Extract the files
+	for {
+		header, err := tarReader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		path, err := destination(header.Name, config)
+		if err != nil {
+			return nil, err
+		}
+
+		// Skip the signature file if there's nowhere to put it
+		if len(path) == 0 {
+			continue
+		}
+
+		if err := extractFile(tarReader, path, header.Mode); err != nil {
+			return nil, err
+		}
+	}
+
+	return manifest, nil
+}
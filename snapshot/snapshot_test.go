@@ -0,0 +1,212 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/graphbuilder"
+	"github.com/stretchr/testify/assert"
+)
+
+// buildGraphs in folders for the snapshot tests and return the config.
+func buildGraphs(t *testing.T, storeType string) graphbuilder.GraphConfig {
+
+	config, err := graphbuilder.ReadGraphConfigFromJson("../test-data-sets/set-0/config-pebble.json")
+	assert.NoError(t, err)
+
+	config.BipartiteConfig.Type = storeType
+	config.BipartiteConfig.Folder = t.TempDir()
+	config.UnipartiteConfig.Type = storeType
+	config.UnipartiteConfig.Folder = t.TempDir()
+	config.SignatureFile = filepath.Join(t.TempDir(), "signatures.json")
+
+	builder, build, err := graphbuilder.NewGraphBuilder(*config)
+	assert.NoError(t, err)
+	assert.True(t, build)
+
+	assert.NoError(t, builder.Bipartite.Finalise())
+	assert.NoError(t, builder.Unipartite.Finalise())
+	assert.NoError(t, builder.Bipartite.(interface{ Close() error }).Close())
+	assert.NoError(t, builder.Unipartite.(interface{ Close() error }).Close())
+
+	return *config
+}
+
+func TestExportImport(t *testing.T) {
+
+	for _, storeType := range []string{graphbuilder.StorageTypePebble, graphbuilder.StorageTypeBolt} {
+		t.Run(storeType, func(t *testing.T) {
+
+			// Build the graphs and export them
+			config := buildGraphs(t, storeType)
+			archivePath := filepath.Join(t.TempDir(), "snapshot.tar.gz")
+			assert.NoError(t, Export(config, archivePath))
+
+			// Import the graphs on 'another machine'
+			importConfig := config
+			importConfig.BipartiteConfig.Folder = filepath.Join(t.TempDir(), "bipartite")
+			importConfig.UnipartiteConfig.Folder = filepath.Join(t.TempDir(), "unipartite")
+			importConfig.SignatureFile = filepath.Join(t.TempDir(), "signatures.json")
+
+			manifest, err := Import(archivePath, importConfig)
+			assert.NoError(t, err)
+			assert.Equal(t, Version, manifest.Version)
+			assert.True(t, manifest.HasSignatures)
+
+			// The signature file should be identical
+			expectedSignatures, err := os.ReadFile(config.SignatureFile)
+			assert.NoError(t, err)
+			actualSignatures, err := os.ReadFile(importConfig.SignatureFile)
+			assert.NoError(t, err)
+			assert.Equal(t, expectedSignatures, actualSignatures)
+
+			// Loading the imported graphs shouldn't require them to be rebuilt
+			builder, build, err := graphbuilder.NewGraphBuilder(importConfig)
+			assert.NoError(t, err)
+			assert.False(t, build)
+			assert.Equal(t, 4, builder.Stats.Bipartite.NumberOfEntities)
+			assert.Equal(t, 4, builder.Stats.Unipartite.NumberOfEntities)
+			assert.NoError(t, builder.Destroy())
+
+			// Importing into folders that aren't empty fails unless they can be cleared
+			config.BipartiteConfig.DeleteFilesInFolder = false
+			config.UnipartiteConfig.DeleteFilesInFolder = false
+			_, err = Import(archivePath, config)
+			assert.ErrorIs(t, err, ErrFolderNotEmpty)
+
+			config.BipartiteConfig.DeleteFilesInFolder = true
+			config.UnipartiteConfig.DeleteFilesInFolder = true
+			_, err = Import(archivePath, config)
+			assert.NoError(t, err)
+
+			// The store type must match the config
+			importConfig.UnipartiteConfig.Type = graphbuilder.StorageTypeInMemory
+			_, err = Import(archivePath, importConfig)
+			assert.ErrorIs(t, err, ErrStoreNotPersisted)
+		})
+	}
+}
+
+func TestExportInvalidConfig(t *testing.T) {
+	archivePath := filepath.Join(t.TempDir(), "snapshot.tar.gz")
+
+	config := graphbuilder.GraphConfig{
+		BipartiteConfig: graphbuilder.BipartiteGraphConfig{
+			Type: graphbuilder.StorageTypeInMemory,
+		},
+	}
+	assert.ErrorIs(t, Export(config, archivePath), ErrStoreNotPersisted)
+
+	config.BipartiteConfig = graphbuilder.BipartiteGraphConfig{
+		Type:   graphbuilder.StorageTypePebble,
+		Folder: graphbuilder.UseTempFolder,
+	}
+	assert.ErrorIs(t, Export(config, archivePath), ErrTempFolder)
+}
+
+// writeArchive with the given entries for testing.
+func writeArchive(t *testing.T, entries map[string]string, order []string) string {
+	archivePath := filepath.Join(t.TempDir(), "snapshot.tar.gz")
+
+	file, err := os.Create(archivePath)
+	assert.NoError(t, err)
+	defer file.Close()
+
+	gzipWriter := gzip.NewWriter(file)
+	tarWriter := tar.NewWriter(gzipWriter)
+
+	for _, name := range order {
+		content := entries[name]
+		assert.NoError(t, tarWriter.WriteHeader(&tar.Header{
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(content)),
+			Typeflag: tar.TypeReg,
+		}))
+		_, err := tarWriter.Write([]byte(content))
+		assert.NoError(t, err)
+	}
+
+	assert.NoError(t, tarWriter.Close())
+	assert.NoError(t, gzipWriter.Close())
+
+	return archivePath
+}
+
+func TestImportInvalidArchive(t *testing.T) {
+
+	config := graphbuilder.GraphConfig{
+		BipartiteConfig: graphbuilder.BipartiteGraphConfig{
+			Type:   graphbuilder.StorageTypePebble,
+			Folder: t.TempDir(),
+		},
+		UnipartiteConfig: graphbuilder.UnipartiteGraphConfig{
+			Type:   graphbuilder.StorageTypePebble,
+			Folder: t.TempDir(),
+		},
+	}
+
+	validManifest := `{"version": 1, "bipartiteType": "pebble", "unipartiteType": "pebble"}`
+
+	testCases := []struct {
+		description   string
+		entries       map[string]string
+		order         []string
+		expectedError error
+	}{
+		{
+			description:   "no manifest",
+			entries:       map[string]string{"bipartite/000001.sst": ""},
+			order:         []string{"bipartite/000001.sst"},
+			expectedError: ErrManifestNotFound,
+		},
+		{
+			description:   "unsupported version",
+			entries:       map[string]string{manifestFilename: `{"version": 99}`},
+			order:         []string{manifestFilename},
+			expectedError: ErrUnsupportedVersion,
+		},
+		{
+			description: "store type mismatch",
+			entries: map[string]string{
+				manifestFilename: `{"version": 1, "bipartiteType": "bolt", "unipartiteType": "pebble"}`,
+			},
+			order:         []string{manifestFilename},
+			expectedError: ErrStoreTypeMismatch,
+		},
+		{
+			description: "path escapes the folder",
+			entries: map[string]string{
+				manifestFilename:          validManifest,
+				"bipartite/../../evil.sh": "",
+			},
+			order:         []string{manifestFilename, "bipartite/../../evil.sh"},
+			expectedError: ErrIllegalPathInSnapshot,
+		},
+		{
+			description: "unknown folder",
+			entries: map[string]string{
+				manifestFilename: validManifest,
+				"other/file":     "",
+			},
+			order:         []string{manifestFilename, "other/file"},
+			expectedError: ErrIllegalPathInSnapshot,
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.description, func(t *testing.T) {
+			archivePath := writeArchive(t, testCase.entries, testCase.order)
+
+			_, err := Import(archivePath, config)
+			assert.ErrorIs(t, err, testCase.expectedError)
+		})
+	}
+
+	// Check the file outside of the folder wasn't written
+	_, err := os.Stat(filepath.Join(config.BipartiteConfig.Folder, "..", "..", "evil.sh"))
+	assert.True(t, os.IsNotExist(err))
+}
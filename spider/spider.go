@@ -8,11 +8,14 @@
 package spider
 
 import (
+	"context"
 	"errors"
+	"sort"
 
 	"github.com/cdclaxton/shortest-path-web-app/graphstore"
 	"github.com/cdclaxton/shortest-path-web-app/logging"
 	"github.com/cdclaxton/shortest-path-web-app/set"
+	"github.com/cdclaxton/shortest-path-web-app/tombstone"
 )
 
 // Component name to use in logging
@@ -23,14 +26,82 @@ var (
 	ErrUnipartiteIsNil    = errors.New("unipartite graph is nil")
 	ErrInvalidNumberSteps = errors.New("invalid number of steps")
 	ErrNoSeedEntities     = errors.New("no seed entities")
+	ErrInvalidCaps        = errors.New("invalid spider caps")
+	ErrBipartiteIsNil     = errors.New("bipartite graph is nil")
+	ErrInvalidTypeFilters = errors.New("invalid entity type filters")
 )
 
+// SpiderCaps limit the expansion when spidering, so that a seed entity connected to a super-node
+// doesn't pull in millions of entities. A cap of zero means there is no limit.
+type SpiderCaps struct {
+	MaxEntities   int // Maximum number of entities in the sub-graph
+	MaxNeighbours int // Maximum number of neighbours of an entity to expand
+}
+
+// Validate the caps.
+func (c SpiderCaps) Validate() error {
+	if c.MaxEntities < 0 || c.MaxNeighbours < 0 {
+		return ErrInvalidCaps
+	}
+	return nil
+}
+
+// StepTypeFilters restrict the entity types that each step of spidering can add to the sub-graph,
+// e.g. only people on the first step and only addresses on the second. The first filter is for the
+// first step. A step without a filter (or with a nil filter) can add entities of any type. The
+// connections between entities already in the sub-graph are always added.
+type StepTypeFilters []*set.Set[string]
+
+// Validate the filters for spidering the number of steps.
+func (f StepTypeFilters) Validate(numberSteps int) error {
+	if len(f) > numberSteps {
+		return ErrInvalidTypeFilters
+	}
+	return nil
+}
+
+// step returns the entity types the step (starting from 1) can add (nil for any type).
+func (f StepTypeFilters) step(step int) *set.Set[string] {
+	if step < 1 || step > len(f) {
+		return nil
+	}
+	return f[step-1]
+}
+
+// A StepEntity is an entity that was first reached on a step of spidering.
+type StepEntity struct {
+	EntityId     string // Entity newly reached on the step
+	IntroducedBy string // Entity already in the sub-graph from which it was reached
+}
+
 // SpiderResults holds the sub-graph generated by spidering out from the seed entities.
 type SpiderResults struct {
-	NumberSteps          int
-	Subgraph             *graphstore.InMemoryUnipartiteGraphStore // Sub-graph from spidering from seeds
-	SeedEntities         *set.Set[string]                         // All entities set as seeds (even if they don't exist)
-	SeedEntitiesNotFound *set.Set[string]                         // Entity IDs not found in unipartite graph
+	NumberSteps           int
+	Subgraph              *graphstore.InMemoryUnipartiteGraphStore // Sub-graph from spidering from seeds
+	SeedEntities          *set.Set[string]                         // All entities set as seeds (even if they don't exist)
+	SeedEntitiesNotFound  *set.Set[string]                         // Entity IDs not found in unipartite graph
+	SeedEntitiesWithdrawn *set.Set[string]                         // Withdrawn entity IDs that weren't spidered from
+	EntityCapReached      bool                                     // Were entities left out because of the entity cap?
+	NeighboursCapped      int                                      // Number of entities whose neighbours were capped
+	Steps                 [][]StepEntity                           // Entities newly reached on each step (sorted by ID)
+
+	cappedEntityIds *set.Set[string] // Entities whose neighbours were capped
+}
+
+// addNeighboursCapped records that the neighbours of the entity were capped. An entity is only
+// counted once, even though it is expanded on each step.
+func (s *SpiderResults) addNeighboursCapped(entityId string) {
+	if s.cappedEntityIds == nil {
+		s.cappedEntityIds = set.NewSet[string]()
+	}
+
+	s.cappedEntityIds.Add(entityId)
+	s.NeighboursCapped = s.cappedEntityIds.Len()
+}
+
+// Truncated returns true if the caps stopped the sub-graph from being fully expanded.
+func (s *SpiderResults) Truncated() bool {
+	return s.EntityCapReached || s.NeighboursCapped > 0
 }
 
 // NewSpiderResults returns a new SpiderResults struct with an empty sub-graph.
@@ -38,10 +109,11 @@ func NewSpiderResults(numberSteps int, seedEntities *set.Set[string]) *SpiderRes
 
 	// Instantiate a struct to hold the results
 	results := SpiderResults{
-		NumberSteps:          numberSteps,
-		Subgraph:             graphstore.NewInMemoryUnipartiteGraphStore(),
-		SeedEntities:         seedEntities,
-		SeedEntitiesNotFound: set.NewSet[string](),
+		NumberSteps:           numberSteps,
+		Subgraph:              graphstore.NewInMemoryUnipartiteGraphStore(),
+		SeedEntities:          seedEntities,
+		SeedEntitiesNotFound:  set.NewSet[string](),
+		SeedEntitiesWithdrawn: set.NewSet[string](),
 	}
 
 	return &results
@@ -78,6 +150,8 @@ func (s *SpiderResults) Equal(s2 *SpiderResults) (bool, error) {
 	}
 
 	return s.NumberSteps == s2.NumberSteps &&
+		s.EntityCapReached == s2.EntityCapReached &&
+		s.NeighboursCapped == s2.NeighboursCapped &&
 		s.SeedEntities.Equal(s2.SeedEntities) &&
 		s.SeedEntitiesNotFound.Equal(s2.SeedEntitiesNotFound) &&
 		graphsEqual, nil
@@ -87,6 +161,8 @@ func (s *SpiderResults) Equal(s2 *SpiderResults) (bool, error) {
 // 'seed' entities.
 type Spider struct {
 	unipartiteGraph graphstore.UnipartiteGraphStore
+	bipartiteGraph  graphstore.BipartiteGraphStore // Entity types for the step filters and aliases of the seeds (optional)
+	tombstones      *tombstone.TombstoneStore      // Withdrawn entities that aren't spidered to or from (optional)
 }
 
 // NewSpider given a unipartite graph.
@@ -105,12 +181,72 @@ func NewSpider(graph graphstore.UnipartiteGraphStore) (*Spider, error) {
 	}, nil
 }
 
+// SetBipartite graph holding the entity types, which is required to spider with step filters. The
+// seed entities that are aliases are resolved using the bipartite graph.
+func (s *Spider) SetBipartite(graph graphstore.BipartiteGraphStore) {
+	s.bipartiteGraph = graph
+}
+
+// SetTombstones of the withdrawn entities, which aren't added to the sub-graph. A nil store means
+// there aren't any withdrawn entities.
+func (s *Spider) SetTombstones(tombstones *tombstone.TombstoneStore) {
+	s.tombstones = tombstones
+}
+
+// resolveSeedAliases returns the seed entities with any aliases resolved to their canonical entity
+// IDs. The seeds are unchanged if the bipartite graph isn't set.
+func (s *Spider) resolveSeedAliases(seedEntities *set.Set[string]) (*set.Set[string], error) {
+
+	if s.bipartiteGraph == nil {
+		return seedEntities, nil
+	}
+
+	entityIds, err := graphstore.ResolveAliases(s.bipartiteGraph, seedEntities.ToSlice())
+	if err != nil {
+		return nil, err
+	}
+
+	return set.NewPopulatedSet(entityIds...), nil
+}
+
+// entityTypes of the entities in the bipartite graph, where the types are cached for a spidering
+// run. An entity that isn't in the bipartite graph doesn't have a type.
+type entityTypes struct {
+	bipartite graphstore.BipartiteGraphStore
+	cache     map[string]string
+}
+
+// get the type of an entity.
+func (e *entityTypes) get(entityId string) (string, error) {
+
+	if entityType, found := e.cache[entityId]; found {
+		return entityType, nil
+	}
+
+	entityType := ""
+	entity, err := e.bipartite.GetEntity(entityId)
+	if err == nil {
+		entityType = entity.EntityType
+	} else if !errors.Is(err, graphstore.ErrEntityNotFound) {
+		return "", err
+	}
+
+	e.cache[entityId] = entityType
+	return entityType, nil
+}
+
 // addSeedsAndConnections adds the seed entity to the unipartite sub-graph and the connections
-// between seeds where present in the full graph.
-func (s *Spider) addSeedsAndConnections(results *SpiderResults) error {
+// between seeds where present in the full graph. Withdrawn seeds are recorded in the results
+// instead.
+func (s *Spider) addSeedsAndConnections(results *SpiderResults, withdrawn *set.Set[string]) error {
 
 	for _, seedEntityId := range results.SeedEntities.ToSlice() {
 
+		if withdrawn.Has(seedEntityId) {
+			results.SeedEntitiesWithdrawn.Add(seedEntityId)
+			continue
+		}
+
 		// If the seed entity ID cannot be found in the unipartite graph store, record it in the
 		// results
 		found, err := s.unipartiteGraph.HasEntity(seedEntityId)
@@ -135,7 +271,7 @@ func (s *Spider) addSeedsAndConnections(results *SpiderResults) error {
 	}
 
 	for _, seedEntityId := range seedEntitiesInFullGraph.ToSlice() {
-		adjacentEntityIds, err := s.unipartiteGraph.EntityIdsAdjacentTo(seedEntityId)
+		adjacentEntityIds, err := s.unipartiteGraph.EntityIdsConnectedTo(seedEntityId)
 		if err != nil {
 			return err
 		}
@@ -151,55 +287,184 @@ func (s *Spider) addSeedsAndConnections(results *SpiderResults) error {
 	return nil
 }
 
-// spiderOutOneStep from all of the entities in the sub-graph in the results.
-func (s *Spider) spiderOutOneStep(results *SpiderResults) error {
+// newStepEntities sorted by entity ID from the entity that introduced each of them.
+func newStepEntities(introducedBy map[string]string) []StepEntity {
+	entities := make([]StepEntity, 0, len(introducedBy))
+	for entityId, introducer := range introducedBy {
+		entities = append(entities, StepEntity{
+			EntityId:     entityId,
+			IntroducedBy: introducer,
+		})
+	}
+
+	sort.Slice(entities, func(i, j int) bool {
+		return entities[i].EntityId < entities[j].EntityId
+	})
+
+	return entities
+}
+
+// spiderOutOneStep from all of the entities in the sub-graph in the results. If there are caps, the
+// entities and their neighbours are expanded in sorted order so that the same sub-graph is always
+// produced. If the step has a filter, only entities of the filter's types are added. The entities
+// newly reached on the step are appended to the steps of the results, where an entity reached from
+// more than one entity is introduced by the entity with the lowest ID. Withdrawn entities aren't
+// added.
+func (s *Spider) spiderOutOneStep(ctx context.Context, results *SpiderResults, caps SpiderCaps,
+	filter *set.Set[string], types *entityTypes, withdrawn *set.Set[string]) error {
 
 	entityIdInSubGraph, err := results.Subgraph.EntityIds()
 	if err != nil {
 		return err
 	}
 
-	for _, entityId := range entityIdInSubGraph.ToSlice() {
+	numberEntities := entityIdInSubGraph.Len()
+	introducedBy := map[string]string{}
+	entityIds := entityIdInSubGraph.ToSlice()
+	if caps.MaxEntities > 0 {
+		sort.Strings(entityIds)
+	}
+
+	for _, entityId := range entityIds {
 
-		// Find the adjacent entity IDs
-		adjEntityIds, err := s.unipartiteGraph.EntityIdsAdjacentTo(entityId)
+		// Stop if spidering has been cancelled
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		// Find the connected entity IDs (ignoring the direction of any directed edges)
+		adjEntityIds, err := s.unipartiteGraph.EntityIdsConnectedTo(entityId)
 		if err != nil {
 			return err
 		}
 
-		// Add connections from the entity to all of its adjacent entities in the sub-graph
-		for _, adjEntityId := range adjEntityIds.ToSlice() {
+		adjacent := adjEntityIds.ToSlice()
+		if caps.MaxNeighbours > 0 && len(adjacent) > caps.MaxNeighbours {
+			sort.Strings(adjacent)
+			adjacent = adjacent[:caps.MaxNeighbours]
+			results.addNeighboursCapped(entityId)
+		} else if caps.MaxEntities > 0 {
+			sort.Strings(adjacent)
+		}
+
+		// Add connections from the entity to its adjacent entities in the sub-graph
+		for _, adjEntityId := range adjacent {
+
+			if withdrawn.Has(adjEntityId) {
+				continue
+			}
+
+			if caps.MaxEntities > 0 || filter != nil {
+				inSubgraph, err := results.Subgraph.HasEntity(adjEntityId)
+				if err != nil {
+					return err
+				}
+
+				if !inSubgraph && filter != nil {
+					entityType, err := types.get(adjEntityId)
+					if err != nil {
+						return err
+					}
+
+					if !filter.Has(entityType) {
+						continue
+					}
+				}
+
+				if !inSubgraph && caps.MaxEntities > 0 {
+					if numberEntities >= caps.MaxEntities {
+						results.EntityCapReached = true
+						continue
+					}
+					numberEntities++
+				}
+			}
+
 			results.Subgraph.AddUndirected(entityId, adjEntityId)
+
+			if !entityIdInSubGraph.Has(adjEntityId) {
+				if introducer, found := introducedBy[adjEntityId]; !found || entityId < introducer {
+					introducedBy[adjEntityId] = entityId
+				}
+			}
 		}
 	}
 
+	results.Steps = append(results.Steps, newStepEntities(introducedBy))
 	return nil
 }
 
 // Execute spidering from a set of seed entities.
 func (s *Spider) Execute(numberSteps int, seedEntities *set.Set[string]) (*SpiderResults, error) {
+	return s.ExecuteWithCaps(numberSteps, seedEntities, SpiderCaps{})
+}
+
+// ExecuteWithCaps spiders from a set of seed entities, limiting the expansion with the caps. The
+// seed entities are always in the sub-graph, even if there are more of them than the entity cap.
+func (s *Spider) ExecuteWithCaps(numberSteps int, seedEntities *set.Set[string],
+	caps SpiderCaps) (*SpiderResults, error) {
+	return s.ExecuteWithContext(context.Background(), numberSteps, seedEntities, caps)
+}
+
+// ExecuteWithContext spiders from a set of seed entities, limiting the expansion with the caps,
+// where spidering is abandoned with the context's error if the context is cancelled (e.g. because
+// the job has timed out).
+func (s *Spider) ExecuteWithContext(ctx context.Context, numberSteps int,
+	seedEntities *set.Set[string], caps SpiderCaps) (*SpiderResults, error) {
+	return s.ExecuteWithFilters(ctx, numberSteps, seedEntities, caps, nil)
+}
+
+// ExecuteWithFilters spiders from a set of seed entities in the same way as ExecuteWithContext,
+// where each step only adds the entity types allowed by its filter. The spider must have the
+// bipartite graph if there are filters.
+func (s *Spider) ExecuteWithFilters(ctx context.Context, numberSteps int,
+	seedEntities *set.Set[string], caps SpiderCaps, filters StepTypeFilters) (*SpiderResults, error) {
+
+	if err := caps.Validate(); err != nil {
+		return nil, err
+	}
 
 	// Check the number of steps is valid
 	if numberSteps < 0 {
 		return nil, ErrInvalidNumberSteps
 	}
 
+	// Check the filters of the steps
+	if err := filters.Validate(numberSteps); err != nil {
+		return nil, err
+	}
+
+	if len(filters) > 0 && s.bipartiteGraph == nil {
+		return nil, ErrBipartiteIsNil
+	}
+
 	// Check the seed entities
 	if seedEntities.Len() == 0 {
 		return nil, ErrNoSeedEntities
 	}
 
+	seedEntities, err := s.resolveSeedAliases(seedEntities)
+	if err != nil {
+		return nil, err
+	}
+
 	// Initialise the results
 	results := NewSpiderResults(numberSteps, seedEntities)
 
 	// Add connections between seed entities
-	if err := s.addSeedsAndConnections(results); err != nil {
+	withdrawn := s.tombstones.WithdrawnIds()
+	if err := s.addSeedsAndConnections(results, withdrawn); err != nil {
 		return nil, err
 	}
 
 	// Add the directly connected entities
+	types := &entityTypes{
+		bipartite: s.bipartiteGraph,
+		cache:     map[string]string{},
+	}
+
 	for i := 1; i <= numberSteps; i++ {
-		if err := s.spiderOutOneStep(results); err != nil {
+		if err := s.spiderOutOneStep(ctx, results, caps, filters.step(i), types, withdrawn); err != nil {
 			return nil, err
 		}
 	}
@@ -1,10 +1,12 @@
 package spider
 
 import (
+	"context"
 	"testing"
 
 	"github.com/cdclaxton/shortest-path-web-app/graphstore"
 	"github.com/cdclaxton/shortest-path-web-app/set"
+	"github.com/cdclaxton/shortest-path-web-app/tombstone"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -260,3 +262,237 @@ func TestHasAtLeastOneConnection(t *testing.T) {
 		assert.Equal(t, testCase.expected, actual)
 	}
 }
+
+func TestExecuteWithCaps(t *testing.T) {
+
+	s, err := NewSpider(makeTestGraph(t))
+	assert.NoError(t, err)
+
+	// Invalid caps
+	_, err = s.ExecuteWithCaps(1, set.NewPopulatedSet("1"), SpiderCaps{MaxEntities: -1})
+	assert.ErrorIs(t, err, ErrInvalidCaps)
+
+	// No caps
+	result, err := s.ExecuteWithCaps(2, set.NewPopulatedSet("1"), SpiderCaps{})
+	assert.NoError(t, err)
+	assert.False(t, result.Truncated())
+
+	// Neighbours of entity 1 (2, 7, 8, 9) are capped to the first two in sorted order
+	result, err = s.ExecuteWithCaps(1, set.NewPopulatedSet("1"), SpiderCaps{MaxNeighbours: 2})
+	assert.NoError(t, err)
+
+	expected := graphstore.NewInMemoryUnipartiteGraphStore()
+	assert.NoError(t, expected.AddUndirected("1", "2"))
+	assert.NoError(t, expected.AddUndirected("1", "7"))
+
+	equal, _, err := graphstore.UnipartiteGraphStoresEqual(expected, result.Subgraph)
+	assert.NoError(t, err)
+	assert.True(t, equal)
+	assert.Equal(t, 1, result.NeighboursCapped)
+	assert.False(t, result.EntityCapReached)
+	assert.True(t, result.Truncated())
+
+	// An entity is only counted once, even though it is expanded on each step
+	result, err = s.ExecuteWithCaps(2, set.NewPopulatedSet("1"), SpiderCaps{MaxNeighbours: 3})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, result.NeighboursCapped) // Entities 1 and 2
+
+	// Entity cap
+	result, err = s.ExecuteWithCaps(2, set.NewPopulatedSet("1"), SpiderCaps{MaxEntities: 3})
+	assert.NoError(t, err)
+
+	numberEntities, err := result.Subgraph.NumberEntities()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, numberEntities)
+	assert.True(t, result.EntityCapReached)
+	assert.Equal(t, 0, result.NeighboursCapped)
+
+	// The seed entities are always in the sub-graph
+	result, err = s.ExecuteWithCaps(0, set.NewPopulatedSet("1", "2", "3"), SpiderCaps{MaxEntities: 1})
+	assert.NoError(t, err)
+	numberEntities, err = result.Subgraph.NumberEntities()
+	assert.NoError(t, err)
+	assert.Equal(t, 3, numberEntities)
+}
+
+func TestExecuteWithContext(t *testing.T) {
+
+	s, err := NewSpider(makeTestGraph(t))
+	assert.NoError(t, err)
+
+	// Spidering that isn't cancelled
+	result, err := s.ExecuteWithContext(context.Background(), 1, set.NewPopulatedSet("1"),
+		SpiderCaps{})
+	assert.NoError(t, err)
+	assert.NotNil(t, result)
+
+	// Cancelled spidering
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	result, err = s.ExecuteWithContext(ctx, 1, set.NewPopulatedSet("1"), SpiderCaps{})
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, result)
+
+	// Zero steps don't expand the sub-graph, so there's nothing to cancel
+	_, err = s.ExecuteWithContext(ctx, 0, set.NewPopulatedSet("1"), SpiderCaps{})
+	assert.NoError(t, err)
+}
+
+// makeTestEntityTypes constructs a bipartite graph holding the types of some of the entities of
+// the test graph.
+func makeTestEntityTypes(t *testing.T) graphstore.BipartiteGraphStore {
+
+	bipartite := graphstore.NewInMemoryBipartiteGraphStore()
+	entityTypes := map[string]string{
+		"1": "Person", "2": "Person", "7": "Person", "11": "Person",
+		"3": "Address", "9": "Address", "10": "Address",
+		"8": "Vehicle",
+	}
+
+	for entityId, entityType := range entityTypes {
+		entity, err := graphstore.NewEntity(entityId, entityType, map[string]string{})
+		assert.NoError(t, err)
+		assert.NoError(t, bipartite.AddEntity(entity))
+	}
+
+	return bipartite
+}
+
+func TestExecuteWithFilters(t *testing.T) {
+
+	s, err := NewSpider(makeTestGraph(t))
+	assert.NoError(t, err)
+
+	filters := StepTypeFilters{
+		set.NewPopulatedSet("Person"),
+		set.NewPopulatedSet("Address"),
+	}
+
+	// The spider needs the entity types
+	_, err = s.ExecuteWithFilters(context.Background(), 2, set.NewPopulatedSet("1"), SpiderCaps{},
+		filters)
+	assert.ErrorIs(t, err, ErrBipartiteIsNil)
+
+	s.SetBipartite(makeTestEntityTypes(t))
+
+	// More filters than steps
+	_, err = s.ExecuteWithFilters(context.Background(), 1, set.NewPopulatedSet("1"), SpiderCaps{},
+		filters)
+	assert.ErrorIs(t, err, ErrInvalidTypeFilters)
+
+	// People on the first step (2 and 7) and addresses on the second step (3, 9 and 10)
+	result, err := s.ExecuteWithFilters(context.Background(), 2, set.NewPopulatedSet("1"),
+		SpiderCaps{}, filters)
+	assert.NoError(t, err)
+
+	expected := graphstore.NewInMemoryUnipartiteGraphStore()
+	assert.NoError(t, expected.AddUndirected("1", "2"))
+	assert.NoError(t, expected.AddUndirected("1", "7"))
+	assert.NoError(t, expected.AddUndirected("1", "9"))
+	assert.NoError(t, expected.AddUndirected("2", "3"))
+	assert.NoError(t, expected.AddUndirected("2", "10"))
+
+	equal, _, err := graphstore.UnipartiteGraphStoresEqual(expected, result.Subgraph)
+	assert.NoError(t, err)
+	assert.True(t, equal)
+
+	// A step without a filter adds any entity type, whereas an entity without a type is never
+	// added by a filtered step
+	result, err = s.ExecuteWithFilters(context.Background(), 2, set.NewPopulatedSet("9"),
+		SpiderCaps{}, StepTypeFilters{nil, set.NewPopulatedSet("Person", "Address")})
+	assert.NoError(t, err)
+
+	for _, entityId := range []string{"9", "1", "10", "12", "2", "7"} {
+		found, err := result.Subgraph.HasEntity(entityId)
+		assert.NoError(t, err)
+		assert.True(t, found, entityId)
+	}
+
+	for _, entityId := range []string{"8", "13", "14"} {
+		found, err := result.Subgraph.HasEntity(entityId)
+		assert.NoError(t, err)
+		assert.False(t, found, entityId)
+	}
+}
+
+func TestExecuteWithAliases(t *testing.T) {
+
+	s, err := NewSpider(makeTestGraph(t))
+	assert.NoError(t, err)
+
+	bipartite := makeTestEntityTypes(t)
+	assert.NoError(t, bipartite.AddAlias("old-1", "1"))
+	s.SetBipartite(bipartite)
+
+	expected, err := s.ExecuteWithContext(context.Background(), 1, set.NewPopulatedSet("1"),
+		SpiderCaps{})
+	assert.NoError(t, err)
+
+	// The seed entity is an alias of entity 1
+	result, err := s.ExecuteWithContext(context.Background(), 1, set.NewPopulatedSet("old-1"),
+		SpiderCaps{})
+	assert.NoError(t, err)
+
+	equal, _, err := graphstore.UnipartiteGraphStoresEqual(expected.Subgraph, result.Subgraph)
+	assert.NoError(t, err)
+	assert.True(t, equal)
+
+	found, err := result.Subgraph.HasEntity("old-1")
+	assert.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestExecuteWithoutWithdrawn(t *testing.T) {
+
+	s, err := NewSpider(makeTestGraph(t))
+	assert.NoError(t, err)
+
+	tombstones, err := tombstone.NewTombstoneStore(t.TempDir())
+	assert.NoError(t, err)
+	defer tombstones.Close()
+	s.SetTombstones(tombstones)
+
+	_, err = tombstones.Withdraw("2", "Source record retracted")
+	assert.NoError(t, err)
+
+	// The withdrawn seed isn't spidered from and the withdrawn neighbour isn't reached
+	result, err := s.Execute(2, set.NewPopulatedSet("1", "2"))
+	assert.NoError(t, err)
+	assert.True(t, result.SeedEntitiesWithdrawn.Equal(set.NewPopulatedSet("2")))
+	assert.Equal(t, 0, result.SeedEntitiesNotFound.Len())
+
+	found, err := result.Subgraph.HasEntity("2")
+	assert.NoError(t, err)
+	assert.False(t, found)
+
+	assert.Equal(t, [][]StepEntity{
+		{{"7", "1"}, {"8", "1"}, {"9", "1"}},
+		{{"10", "9"}, {"12", "9"}},
+	}, result.Steps)
+}
+
+func TestExecuteSteps(t *testing.T) {
+
+	s, err := NewSpider(makeTestGraph(t))
+	assert.NoError(t, err)
+
+	result, err := s.Execute(2, set.NewPopulatedSet("1"))
+	assert.NoError(t, err)
+
+	// Entity 10 is reached from both 2 and 9, so it is introduced by the lower ID
+	assert.Equal(t, [][]StepEntity{
+		{{"2", "1"}, {"7", "1"}, {"8", "1"}, {"9", "1"}},
+		{{"10", "2"}, {"11", "2"}, {"12", "9"}, {"3", "2"}},
+	}, result.Steps)
+
+	// A step that doesn't reach any new entities is empty
+	result, err = s.Execute(2, set.NewPopulatedSet("6"))
+	assert.NoError(t, err)
+	assert.Equal(t, [][]StepEntity{{}, {}}, result.Steps)
+
+	// No steps
+	result, err = s.Execute(0, set.NewPopulatedSet("1"))
+	assert.NoError(t, err)
+	assert.Empty(t, result.Steps)
+}
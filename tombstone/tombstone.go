@@ -0,0 +1,335 @@
+// Package tombstone holds the entities that have been withdrawn (tombstoned), e.g. because their
+// source records were retracted. A withdrawn entity stays in the graph stores, so it can still be
+// looked up, but it is excluded from path finding and spidering. The tombstones are persisted in a
+// small Pebble store with the history of each entity's withdrawals and reinstatements as an audit
+// trail, so they survive a restart of the web-app and are shared by all of the graphs.
+package tombstone
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cdclaxton/shortest-path-web-app/logging"
+	"github.com/cdclaxton/shortest-path-web-app/set"
+	"github.com/cockroachdb/pebble"
+)
+
+const componentName = "tombstone"
+
+// MaxReasonLength is the maximum number of characters of the reason for a change.
+const MaxReasonLength = 500
+
+// Actions recorded in the history of a tombstone
+const (
+	ActionWithdrawn  = "withdrawn"
+	ActionReinstated = "reinstated"
+)
+
+var (
+	ErrEntityIdEmpty       = errors.New("entity ID is empty")
+	ErrReasonEmpty         = errors.New("reason is empty")
+	ErrReasonTooLong       = errors.New("reason is too long")
+	ErrAlreadyWithdrawn    = errors.New("entity is already withdrawn")
+	ErrNotWithdrawn        = errors.New("entity isn't withdrawn")
+	ErrStoreNotOpen        = errors.New("tombstone store isn't open")
+	ErrCorruptedTombstones = errors.New("tombstone can't be read")
+)
+
+// An Event in the history of a tombstone.
+type Event struct {
+	Action string    `json:"action"` // ActionWithdrawn or ActionReinstated
+	Reason string    `json:"reason"` // Why the entity was withdrawn or reinstated
+	Time   time.Time `json:"time"`   // When the change was made
+}
+
+// A Tombstone of an entity that is (or was) withdrawn.
+type Tombstone struct {
+	EntityId  string    `json:"entityId"`  // ID of the entity
+	Withdrawn bool      `json:"withdrawn"` // Is the entity currently withdrawn?
+	Reason    string    `json:"reason"`    // Reason for the latest change
+	Updated   time.Time `json:"updated"`   // When the latest change was made
+	History   []Event   `json:"history"`   // All of the changes in the order they were made
+}
+
+// validate the entity ID and the reason for a change, returning them trimmed.
+func validate(entityId string, reason string) (string, string, error) {
+
+	entityId = strings.TrimSpace(entityId)
+	if len(entityId) == 0 {
+		return "", "", ErrEntityIdEmpty
+	}
+
+	reason = strings.TrimSpace(reason)
+	if len(reason) == 0 {
+		return "", "", ErrReasonEmpty
+	}
+
+	if len([]rune(reason)) > MaxReasonLength {
+		return "", "", fmt.Errorf("%w: maximum %v characters", ErrReasonTooLong, MaxReasonLength)
+	}
+
+	return entityId, reason, nil
+}
+
+// A TombstoneStore holds the tombstones of the entities in a Pebble store, keyed by entity ID. The
+// IDs of the withdrawn entities are also held in memory, as they are needed by every search.
+type TombstoneStore struct {
+	folder    string           // Location of the Pebble files
+	db        *pebble.DB       // Pebble store (nil once closed)
+	withdrawn *set.Set[string] // IDs of the withdrawn entities
+	lock      sync.Mutex       // Mutex for the changes of the tombstones
+}
+
+// NewTombstoneStore in the folder, which is created if it doesn't exist.
+func NewTombstoneStore(folder string) (*TombstoneStore, error) {
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("folder", folder).
+		Msg("Opening tombstone Pebble store")
+
+	db, err := pebble.Open(folder, &pebble.Options{})
+	if err != nil {
+		return nil, err
+	}
+
+	withdrawn, err := readWithdrawn(db)
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Int("numberOfWithdrawnEntities", withdrawn.Len()).
+		Msg("Read the withdrawn entities")
+
+	return &TombstoneStore{
+		folder:    folder,
+		db:        db,
+		withdrawn: withdrawn,
+		lock:      sync.Mutex{},
+	}, nil
+}
+
+// readWithdrawn returns the IDs of the withdrawn entities in the Pebble store.
+func readWithdrawn(db *pebble.DB) (*set.Set[string], error) {
+
+	withdrawn := set.NewSet[string]()
+
+	iter := db.NewIter(nil)
+	for iter.First(); iter.Valid(); iter.Next() {
+		var tombstone Tombstone
+		if err := json.Unmarshal(iter.Value(), &tombstone); err != nil {
+			iter.Close()
+			return nil, fmt.Errorf("%w: %v", ErrCorruptedTombstones, string(iter.Key()))
+		}
+
+		if tombstone.Withdrawn {
+			withdrawn.Add(tombstone.EntityId)
+		}
+	}
+
+	return withdrawn, iter.Close()
+}
+
+// Close the store.
+func (s *TombstoneStore) Close() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.db == nil {
+		return nil
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("folder", s.folder).
+		Msg("Closing tombstone Pebble store")
+
+	err := s.db.Close()
+	s.db = nil
+	return err
+}
+
+// get the tombstone of the entity, which is nil if the entity has never been withdrawn. The lock
+// must be held.
+func (s *TombstoneStore) get(entityId string) (*Tombstone, error) {
+
+	if s.db == nil {
+		return nil, ErrStoreNotOpen
+	}
+
+	value, closer, err := s.db.Get([]byte(entityId))
+	if err == pebble.ErrNotFound {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer closer.Close()
+
+	var tombstone Tombstone
+	if err := json.Unmarshal(value, &tombstone); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrCorruptedTombstones, entityId)
+	}
+
+	return &tombstone, nil
+}
+
+// Get the tombstone of the entity, which is nil if the entity has never been withdrawn. A nil store
+// doesn't have any tombstones.
+func (s *TombstoneStore) Get(entityId string) (*Tombstone, error) {
+
+	if s == nil {
+		return nil, nil
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.get(entityId)
+}
+
+// change the tombstone of the entity by recording the action, where the entity must (or mustn't)
+// be withdrawn beforehand. Returns the updated tombstone.
+func (s *TombstoneStore) change(entityId string, reason string, action string) (*Tombstone, error) {
+
+	entityId, reason, err := validate(entityId, reason)
+	if err != nil {
+		return nil, err
+	}
+
+	if s == nil {
+		return nil, ErrStoreNotOpen
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	tombstone, err := s.get(entityId)
+	if err != nil {
+		return nil, err
+	}
+
+	if tombstone == nil {
+		tombstone = &Tombstone{
+			EntityId: entityId,
+			History:  []Event{},
+		}
+	}
+
+	withdraw := action == ActionWithdrawn
+	if withdraw && tombstone.Withdrawn {
+		return nil, ErrAlreadyWithdrawn
+	} else if !withdraw && !tombstone.Withdrawn {
+		return nil, ErrNotWithdrawn
+	}
+
+	now := time.Now().UTC()
+	tombstone.Withdrawn = withdraw
+	tombstone.Reason = reason
+	tombstone.Updated = now
+	tombstone.History = append(tombstone.History, Event{
+		Action: action,
+		Reason: reason,
+		Time:   now,
+	})
+
+	value, err := json.Marshal(tombstone)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.db.Set([]byte(entityId), value, pebble.Sync); err != nil {
+		return nil, err
+	}
+
+	if withdraw {
+		s.withdrawn.Add(entityId)
+	} else {
+		s.withdrawn.Remove(entityId)
+	}
+
+	logging.Logger.Info().
+		Str(logging.ComponentField, componentName).
+		Str("entityId", entityId).
+		Str("action", action).
+		Str("reason", reason).
+		Msg("Changed the tombstone of an entity")
+
+	return tombstone, nil
+}
+
+// Withdraw the entity for the reason. Returns the entity's tombstone.
+func (s *TombstoneStore) Withdraw(entityId string, reason string) (*Tombstone, error) {
+	return s.change(entityId, reason, ActionWithdrawn)
+}
+
+// Reinstate the withdrawn entity for the reason. The tombstone is kept for its history. Returns
+// the entity's tombstone.
+func (s *TombstoneStore) Reinstate(entityId string, reason string) (*Tombstone, error) {
+	return s.change(entityId, reason, ActionReinstated)
+}
+
+// WithdrawnIds returns a copy of the IDs of the withdrawn entities. A nil store doesn't have any
+// withdrawn entities.
+func (s *TombstoneStore) WithdrawnIds() *set.Set[string] {
+
+	if s == nil {
+		return set.NewSet[string]()
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return set.NewPopulatedSet(s.withdrawn.ToSlice()...)
+}
+
+// IsWithdrawn returns true if the entity is withdrawn.
+func (s *TombstoneStore) IsWithdrawn(entityId string) bool {
+
+	if s == nil {
+		return false
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	return s.withdrawn.Has(entityId)
+}
+
+// Withdrawn returns the tombstones of the withdrawn entities.
+func (s *TombstoneStore) Withdrawn() ([]*Tombstone, error) {
+
+	if s == nil {
+		return []*Tombstone{}, nil
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.db == nil {
+		return nil, ErrStoreNotOpen
+	}
+
+	tombstones := []*Tombstone{}
+
+	iter := s.db.NewIter(nil)
+	for iter.First(); iter.Valid(); iter.Next() {
+		var tombstone Tombstone
+		if err := json.Unmarshal(iter.Value(), &tombstone); err != nil {
+			iter.Close()
+			return nil, fmt.Errorf("%w: %v", ErrCorruptedTombstones, string(iter.Key()))
+		}
+
+		if tombstone.Withdrawn {
+			tombstones = append(tombstones, &tombstone)
+		}
+	}
+
+	return tombstones, iter.Close()
+}
@@ -0,0 +1,113 @@
+package tombstone
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/cdclaxton/shortest-path-web-app/set"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNilTombstoneStore(t *testing.T) {
+
+	var store *TombstoneStore
+
+	tombstone, err := store.Get("e-1")
+	assert.NoError(t, err)
+	assert.Nil(t, tombstone)
+
+	assert.False(t, store.IsWithdrawn("e-1"))
+	assert.Equal(t, 0, store.WithdrawnIds().Len())
+
+	_, err = store.Withdraw("e-1", "Retracted")
+	assert.ErrorIs(t, err, ErrStoreNotOpen)
+}
+
+func TestTombstoneStore(t *testing.T) {
+
+	folder := t.TempDir()
+	store, err := NewTombstoneStore(folder)
+	assert.NoError(t, err)
+
+	// An entity that has never been withdrawn
+	tombstone, err := store.Get("e-1")
+	assert.NoError(t, err)
+	assert.Nil(t, tombstone)
+
+	_, err = store.Reinstate("e-1", "Mistake")
+	assert.ErrorIs(t, err, ErrNotWithdrawn)
+
+	// Withdraw the entity
+	tombstone, err = store.Withdraw(" e-1 ", " Source record retracted ")
+	assert.NoError(t, err)
+	assert.Equal(t, "e-1", tombstone.EntityId)
+	assert.True(t, tombstone.Withdrawn)
+	assert.Equal(t, "Source record retracted", tombstone.Reason)
+	assert.Equal(t, 1, len(tombstone.History))
+
+	assert.True(t, store.IsWithdrawn("e-1"))
+	assert.True(t, store.WithdrawnIds().Equal(set.NewPopulatedSet("e-1")))
+
+	_, err = store.Withdraw("e-1", "Again")
+	assert.ErrorIs(t, err, ErrAlreadyWithdrawn)
+
+	// The returned IDs are a copy
+	store.WithdrawnIds().Add("e-2")
+	assert.False(t, store.IsWithdrawn("e-2"))
+
+	// The tombstones are persisted
+	assert.NoError(t, store.Close())
+	store, err = NewTombstoneStore(folder)
+	assert.NoError(t, err)
+	defer store.Close()
+
+	assert.True(t, store.IsWithdrawn("e-1"))
+
+	tombstones, err := store.Withdrawn()
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(tombstones))
+	assert.Equal(t, "e-1", tombstones[0].EntityId)
+
+	// Reinstating the entity keeps the history
+	tombstone, err = store.Reinstate("e-1", "Record restored")
+	assert.NoError(t, err)
+	assert.False(t, tombstone.Withdrawn)
+	assert.Equal(t, []string{ActionWithdrawn, ActionReinstated},
+		[]string{tombstone.History[0].Action, tombstone.History[1].Action})
+	assert.Equal(t, "Record restored", tombstone.History[1].Reason)
+
+	assert.False(t, store.IsWithdrawn("e-1"))
+	tombstones, err = store.Withdrawn()
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(tombstones))
+
+	tombstone, err = store.Get("e-1")
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(tombstone.History))
+}
+
+func TestTombstoneValidation(t *testing.T) {
+
+	store, err := NewTombstoneStore(t.TempDir())
+	assert.NoError(t, err)
+	defer store.Close()
+
+	_, err = store.Withdraw(" ", "Retracted")
+	assert.ErrorIs(t, err, ErrEntityIdEmpty)
+
+	_, err = store.Withdraw("e-1", " ")
+	assert.ErrorIs(t, err, ErrReasonEmpty)
+
+	_, err = store.Withdraw("e-1", strings.Repeat("x", MaxReasonLength+1))
+	assert.ErrorIs(t, err, ErrReasonTooLong)
+
+	assert.False(t, store.IsWithdrawn("e-1"))
+
+	// A closed store
+	assert.NoError(t, store.Close())
+	_, err = store.Withdraw("e-1", "Retracted")
+	assert.ErrorIs(t, err, ErrStoreNotOpen)
+
+	_, err = store.Get("e-1")
+	assert.ErrorIs(t, err, ErrStoreNotOpen)
+}